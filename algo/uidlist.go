@@ -152,15 +152,13 @@ func IntersectWith(u, v, o *pb.List) {
 	if n == 0 {
 		n = 1
 	}
-	// Select appropriate function based on heuristics.
+	// Select appropriate function based on heuristics. Galloping beats both the jump and the
+	// binary intersections once one list is more than a few times longer than the other.
 	ratio := float64(m) / float64(n)
-	switch {
-	case ratio < 100:
+	if ratio < 16 {
 		IntersectWithLin(u.Uids, v.Uids, &dst)
-	case ratio < 500:
-		IntersectWithJump(u.Uids, v.Uids, &dst)
-	default:
-		IntersectWithBin(u.Uids, v.Uids, &dst)
+	} else {
+		IntersectWithGallop(u.Uids, v.Uids, &dst)
 	}
 	o.Uids = dst
 }
@@ -218,6 +216,55 @@ func IntersectWithJump(u, v []uint64, o *[]uint64) (int, int) {
 	return i, k
 }
 
+// IntersectWithGallop intersects the shorter of u and v with the longer one, by galloping
+// through the longer list for every UID of the shorter one. Its cost grows with the
+// logarithm of the gaps between the matches, which makes it the fastest method for lists of
+// skewed sizes.
+func IntersectWithGallop(u, v []uint64, o *[]uint64) {
+	if len(u) > len(v) {
+		u, v = v, u
+	}
+	for _, uid := range u {
+		k := gallop(v, uid)
+		if k == len(v) {
+			return
+		}
+		if v[k] == uid {
+			*o = append(*o, uid)
+			k++
+		}
+		v = v[k:]
+	}
+}
+
+// gallop returns the index of the first UID in v which is >= uid, or len(v) if there's none.
+// It probes v at exponentially growing offsets and then does a binary search between the
+// last two probes, so it's fast when the index is close to the start of v.
+func gallop(v []uint64, uid uint64) int {
+	if len(v) == 0 || v[0] >= uid {
+		return 0
+	}
+	// Invariant: v[lo] < uid, and v[hi] >= uid if hi < len(v).
+	lo, hi := 0, 1
+	for hi < len(v) && v[hi] < uid {
+		lo = hi
+		hi <<= 1
+	}
+	if hi > len(v) {
+		hi = len(v)
+	}
+	lo++
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if v[mid] < uid {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
 // IntersectWithBin is based on the paper
 // "Fast Intersection Algorithms for Sorted Sequences"
 // https://link.springer.com/chapter/10.1007/978-3-642-12476-1_3
@@ -332,6 +379,9 @@ func Difference(u, v *pb.List) *pb.List {
 	}
 	n := len(u.Uids)
 	m := len(v.Uids)
+	if n >= 16*m || m >= 16*n {
+		return &pb.List{Uids: differenceGallop(u.Uids, v.Uids)}
+	}
 	out := make([]uint64, 0, n/2)
 	i, k := 0, 0
 	for i < n && k < m {
@@ -358,12 +408,43 @@ func Difference(u, v *pb.List) *pb.List {
 	return &pb.List{Uids: out}
 }
 
+// differenceGallop returns the UIDs of u which aren't in v, galloping through both lists. It's
+// used instead of a linear scan when one of the lists is much longer than the other.
+func differenceGallop(u, v []uint64) []uint64 {
+	out := make([]uint64, 0, len(u)/2)
+	for len(u) > 0 && len(v) > 0 {
+		// Copy the UIDs of u which are before the next UID of v.
+		i := gallop(u, v[0])
+		out = append(out, u[:i]...)
+		if u = u[i:]; len(u) == 0 {
+			break
+		}
+		// Skip the UIDs of v which are before the next UID of u.
+		v = v[gallop(v, u[0]):]
+		if len(v) > 0 && v[0] == u[0] {
+			u, v = u[1:], v[1:]
+		}
+	}
+	return append(out, u...)
+}
+
 // MergeSorted merges sorted lists.
 func MergeSorted(lists []*pb.List) *pb.List {
 	if len(lists) == 0 {
 		return new(pb.List)
 	}
 
+	// Merging two lists is by far the most common case, and doesn't need a heap.
+	var nonEmpty []*pb.List
+	for _, l := range lists {
+		if l != nil && len(l.Uids) > 0 {
+			nonEmpty = append(nonEmpty, l)
+		}
+	}
+	if len(nonEmpty) == 2 {
+		return &pb.List{Uids: mergeTwo(nonEmpty[0].Uids, nonEmpty[1].Uids)}
+	}
+
 	h := &uint64Heap{}
 	heap.Init(h)
 	maxSz := 0
@@ -408,6 +489,35 @@ func MergeSorted(lists []*pb.List) *pb.List {
 	return &pb.List{Uids: output}
 }
 
+// mergeTwo returns the sorted union of u and v, without duplicates.
+func mergeTwo(u, v []uint64) []uint64 {
+	sz := len(u)
+	if len(v) > sz {
+		sz = len(v)
+	}
+	out := make([]uint64, 0, sz)
+	i, k := 0, 0
+	for i < len(u) || k < len(v) {
+		var uid uint64
+		switch {
+		case k == len(v) || (i < len(u) && u[i] < v[k]):
+			uid = u[i]
+			i++
+		case i == len(u) || v[k] < u[i]:
+			uid = v[k]
+			k++
+		default:
+			uid = u[i]
+			i++
+			k++
+		}
+		if len(out) == 0 || uid != out[len(out)-1] {
+			out = append(out, uid)
+		}
+	}
+	return out
+}
+
 // IndexOf performs a binary search on the uids slice and returns the index at
 // which it finds the uid, else returns -1
 func IndexOf(u *pb.List, uid uint64) int {
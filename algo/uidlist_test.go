@@ -523,3 +523,75 @@ func TestIntersectCompressedWithBinMissingSize(t *testing.T) {
 		}
 	}
 }
+
+// randomSorted returns up to n unique sorted UIDs below limit.
+func randomSorted(n int, limit int64) []uint64 {
+	nums := make([]uint64, n)
+	for i := range nums {
+		nums[i] = uint64(rand.Int63n(limit))
+	}
+	sortUint64(nums)
+	out := nums[:0]
+	for i, uid := range nums {
+		if i == 0 || uid != nums[i-1] {
+			out = append(out, uid)
+		}
+	}
+	return out
+}
+
+func TestGallop(t *testing.T) {
+	v := []uint64{2, 4, 6, 8, 10, 12, 14}
+	for uid := uint64(0); uid <= 16; uid++ {
+		exp := sort.Search(len(v), func(i int) bool { return v[i] >= uid })
+		require.Equal(t, exp, gallop(v, uid), "uid: %d", uid)
+	}
+	require.Equal(t, 0, gallop(nil, 1))
+}
+
+func TestIntersectWithGallop(t *testing.T) {
+	for _, sz := range [][2]int{{0, 10}, {10, 10}, {10, 1000}, {1000, 10}, {100, 100000}} {
+		u, v := randomSorted(sz[0], 10000), randomSorted(sz[1], 10000)
+		var exp, got []uint64
+		IntersectWithLin(u, v, &exp)
+		IntersectWithGallop(u, v, &got)
+		require.Equal(t, exp, got, "sizes: %v", sz)
+	}
+}
+
+func TestDifferenceSkewed(t *testing.T) {
+	for _, sz := range [][2]int{{10, 1000}, {1000, 10}, {10, 100000}, {100000, 10}} {
+		u, v := randomSorted(sz[0], 100000), randomSorted(sz[1], 100000)
+		var exp []uint64
+		for _, uid := range u {
+			if IndexOf(newList(v), uid) < 0 {
+				exp = append(exp, uid)
+			}
+		}
+		got := Difference(newList(u), newList(v)).Uids
+		require.Equal(t, len(exp), len(got), "sizes: %v", sz)
+		if len(exp) > 0 {
+			require.Equal(t, exp, got, "sizes: %v", sz)
+		}
+	}
+}
+
+func TestMergeTwo(t *testing.T) {
+	u, v := randomSorted(1000, 1500), randomSorted(300, 1500)
+	// Merging with a third list which only holds duplicates goes through the heap.
+	exp := MergeSorted([]*pb.List{newList(u), newList(v), newList(u[:1])})
+	require.Equal(t, exp.Uids, MergeSorted([]*pb.List{newList(u), newList(nil), newList(v)}).Uids)
+	require.Equal(t, exp.Uids, mergeTwo(v, u))
+	require.Equal(t, []uint64{1, 2, 3}, mergeTwo([]uint64{1, 1, 3}, []uint64{2, 2, 3}))
+}
+
+func BenchmarkListDifferenceRatio(b *testing.B) {
+	for _, r := range []int{1, 10, 100, 1000} {
+		u, v := randomSorted(1000, 1000000), randomSorted(1000*r, 1000000)
+		b.Run(fmt.Sprintf(":ratio=%d:", r), func(b *testing.B) {
+			for k := 0; k < b.N; k++ {
+				Difference(newList(u), newList(v))
+			}
+		})
+	}
+}
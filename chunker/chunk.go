@@ -22,7 +22,7 @@ import (
 	"compress/gzip"
 	encjson "encoding/json"
 	"io"
-	"net/http"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -32,7 +32,9 @@ import (
 	"github.com/dgraph-io/dgraph/lex"
 	"github.com/dgraph-io/dgraph/x"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/pkg/errors"
+	"github.com/ulikunitz/xz"
 )
 
 // Chunker describes the interface to parse and process the input to the live and bulk loaders.
@@ -70,6 +72,12 @@ const (
 	RdfFormat
 	// JsonFormat is a constant to denote the input to the live/bulk loader is in the JSON format.
 	JsonFormat
+	// TurtleFormat is a constant to denote the input to the live/bulk loader is in the Turtle
+	// format. It's translated to N-Quads while being chunked.
+	TurtleFormat
+	// HdtFormat is a constant to denote the input to the live/bulk loader is an HDT file. It's
+	// translated to N-Quads while being chunked.
+	HdtFormat
 )
 
 // NewChunker returns a new chunker for the specified format.
@@ -84,6 +92,14 @@ func NewChunker(inputFormat InputFormat, batchSize int) Chunker {
 		return &jsonChunker{
 			nqs: NewNQuadBuffer(batchSize),
 		}
+	case TurtleFormat:
+		return &turtleChunker{
+			rdfChunker: NewChunker(RdfFormat, batchSize).(*rdfChunker),
+		}
+	case HdtFormat:
+		return &hdtChunker{
+			rdfChunker: NewChunker(RdfFormat, batchSize).(*rdfChunker),
+		}
 	default:
 		x.Panic(errors.New("unknown input format"))
 		return nil
@@ -231,10 +247,17 @@ func (jc *jsonChunker) Chunk(r *bufio.Reader) (*bytes.Buffer, error) {
 		}
 
 		// In the non termination cases, ensure at least one map has been consumed, and
-		// the only allowed char after the map is ",".
+		// the only allowed char after the map is ",". Outside of a list, maps may also follow
+		// each other directly, one per line in JSON Lines.
 		if out.Len() == 1 { // 1 represents the [ inserted before the for loop
 			return nil, errors.Errorf("Illegal rune found \"%c\", expecting {", ch)
 		}
+		if ch == '{' && !jc.inList {
+			if err := r.UnreadRune(); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		if ch != ',' {
 			return nil, errors.Errorf("JSON map is followed by illegal rune \"%c\"", ch)
 		}
@@ -348,8 +371,8 @@ func slurpQuoted(r *bufio.Reader, out *bytes.Buffer) error {
 	}
 }
 
-// FileReader returns an open reader on the given file. Gzip-compressed input is detected
-// and decompressed automatically even without the gz extension. The key, if non-nil,
+// FileReader returns an open reader on the given file. Compressed input is detected and
+// decompressed automatically even without the gz, zst or xz extension. The key, if non-nil,
 // is used to decrypt the file. The caller is responsible for calling the returned cleanup
 // function when done with the reader.
 func FileReader(file string, key x.SensitiveByteSlice) (*bufio.Reader, func()) {
@@ -366,34 +389,104 @@ func FileReader(file string, key x.SensitiveByteSlice) (*bufio.Reader, func()) {
 	return StreamReader(file, key, f)
 }
 
-// StreamReader returns a bufio given a ReadCloser. The file is passed just to check for .gz files
+// StreamReader returns a bufio given a ReadCloser. The file is passed just to check for the
+// extension of a compressed file. The input is decompressed as it's read.
 func StreamReader(file string, key x.SensitiveByteSlice, f io.ReadCloser) (
 	rd *bufio.Reader, cleanup func()) {
 	cleanup = func() { _ = f.Close() }
 
-	if filepath.Ext(file) == ".gz" {
-		r, err := enc.GetReader(key, f)
-		x.Check(err)
-		gzr, err := gzip.NewReader(r)
+	var r io.Reader
+	c := compressionOf(file)
+	if c != nil {
+		var err error
+		r, err = enc.GetReader(key, f)
 		x.Check(err)
-		rd = bufio.NewReader(gzr)
-		cleanup = func() { _ = f.Close(); _ = gzr.Close() }
 	} else {
 		rd = bufio.NewReader(f)
-		buf, _ := rd.Peek(512)
-
-		typ := http.DetectContentType(buf)
-		if typ == "application/x-gzip" {
-			gzr, err := gzip.NewReader(rd)
-			x.Check(err)
-			rd = bufio.NewReader(gzr)
-			cleanup = func() { _ = f.Close(); _ = gzr.Close() }
+		buf, _ := rd.Peek(8)
+		if c = compressionOfData(buf); c == nil {
+			return rd, cleanup
 		}
+		r = rd
 	}
 
+	dr, err := c.open(r)
+	x.Check(err)
+	rd = bufio.NewReader(dr)
+	cleanup = func() { _ = f.Close(); _ = dr.Close() }
 	return rd, cleanup
 }
 
+// compression is a compression format the loaders read transparently.
+type compression struct {
+	ext   string
+	magic []byte
+	open  func(r io.Reader) (io.ReadCloser, error)
+}
+
+var compressions = []compression{
+	{
+		ext:   ".gz",
+		magic: []byte{0x1f, 0x8b},
+		open: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	{
+		ext:   ".zst",
+		magic: []byte{0x28, 0xb5, 0x2f, 0xfd},
+		open: func(r io.Reader) (io.ReadCloser, error) {
+			d, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		},
+	},
+	{
+		ext:   ".xz",
+		magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00},
+		open: func(r io.Reader) (io.ReadCloser, error) {
+			xr, err := xz.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(xr), nil
+		},
+	},
+}
+
+// compressionOf returns the compression of the file based on its extension, or nil if it
+// doesn't have the extension of a compressed file.
+func compressionOf(file string) *compression {
+	ext := strings.ToLower(filepath.Ext(file))
+	for i := range compressions {
+		if compressions[i].ext == ext {
+			return &compressions[i]
+		}
+	}
+	return nil
+}
+
+// compressionOfData returns the compression of the data based on its first bytes, or nil.
+func compressionOfData(buf []byte) *compression {
+	for i := range compressions {
+		if bytes.HasPrefix(buf, compressions[i].magic) {
+			return &compressions[i]
+		}
+	}
+	return nil
+}
+
+// DecompressReader returns a reader decompressing r if the file has the extension of a
+// compressed file, and r itself otherwise.
+func DecompressReader(file string, r io.Reader) (io.ReadCloser, error) {
+	if c := compressionOf(file); c != nil {
+		return c.open(r)
+	}
+	return ioutil.NopCloser(r), nil
+}
+
 // IsJSONData returns true if the reader, which should be at the start of the stream, is reading
 // a JSON stream, false otherwise.
 func IsJSONData(r *bufio.Reader) (bool, error) {
@@ -408,16 +501,36 @@ func IsJSONData(r *bufio.Reader) (bool, error) {
 	return err == nil, nil
 }
 
-// DataFormat returns a file's data format (RDF, JSON, or unknown) based on the filename
-// or the user-provided format option. The file extension has precedence.
+// DataFileExtensions are the extensions of the files the loaders look for in a directory.
+var DataFileExtensions = func() []string {
+	var exts []string
+	for _, ext := range []string{".rdf", ".json", ".jsonl", ".ttl", ".hdt"} {
+		exts = append(exts, ext)
+		for _, c := range compressions {
+			exts = append(exts, ext+c.ext)
+		}
+	}
+	return exts
+}()
+
+// DataFormat returns a file's data format (RDF, JSON, Turtle, HDT or unknown) based on the
+// filename or the user-provided format option. The file extension has precedence.
 func DataFormat(filename string, format string) InputFormat {
 	format = strings.ToLower(format)
-	filename = strings.TrimSuffix(strings.ToLower(filename), ".gz")
+	filename = strings.ToLower(filename)
+	if c := compressionOf(filename); c != nil {
+		filename = strings.TrimSuffix(filename, c.ext)
+	}
 	switch {
 	case strings.HasSuffix(filename, ".rdf") || format == "rdf":
 		return RdfFormat
-	case strings.HasSuffix(filename, ".json") || format == "json":
+	case strings.HasSuffix(filename, ".json") || strings.HasSuffix(filename, ".jsonl") ||
+		format == "json" || format == "jsonl":
 		return JsonFormat
+	case strings.HasSuffix(filename, ".ttl") || format == "turtle" || format == "ttl":
+		return TurtleFormat
+	case strings.HasSuffix(filename, ".hdt") || format == "hdt":
+		return HdtFormat
 	default:
 		return UnknownFormat
 	}
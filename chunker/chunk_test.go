@@ -19,12 +19,16 @@ package chunker
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strings"
 	"testing"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
 )
 
 func bufioReader(str string) *bufio.Reader {
@@ -225,3 +229,63 @@ func TestJSONLoadSuccessAll(t *testing.T) {
 	}
 	require.Equal(t, io.EOF, err, "end reading JSON document")
 }
+
+func TestChunkJSONLines(t *testing.T) {
+	chunker := NewChunker(JsonFormat, 1000)
+	r := bufioReader("{\"name\": \"alice\"}\n{\"name\": \"bob\"}\n\n{\"age\": 26}\n")
+	chunkBuf, err := chunker.Chunk(r)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, `[{"name":"alice"},{"name":"bob"},{"age":26}]`, chunkBuf.String())
+
+	// Maps in a list still need to be separated by commas.
+	_, err = NewChunker(JsonFormat, 1000).Chunk(bufioReader(`[{"a": 1} {"b": 2}]`))
+	require.Error(t, err)
+}
+
+func TestStreamReaderDecompresses(t *testing.T) {
+	data := "<_:a> <name> \"alice\" .\n"
+	var gz, zst, xzBuf bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, err := gw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	zw, err := zstd.NewWriter(&zst)
+	require.NoError(t, err)
+	_, err = zw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	xw, err := xz.NewWriter(&xzBuf)
+	require.NoError(t, err)
+	_, err = xw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, xw.Close())
+
+	for _, test := range []struct {
+		file string
+		data []byte
+	}{
+		{"data.rdf", []byte(data)},
+		{"data.rdf.gz", gz.Bytes()},
+		{"data.rdf.zst", zst.Bytes()},
+		{"data.rdf.xz", xzBuf.Bytes()},
+		// Compressed input is detected without the extension too.
+		{"data.rdf", gz.Bytes()},
+		{"data.rdf", zst.Bytes()},
+		{"data.rdf", xzBuf.Bytes()},
+	} {
+		rd, cleanup := StreamReader(test.file, nil, ioutil.NopCloser(bytes.NewReader(test.data)))
+		got, err := ioutil.ReadAll(rd)
+		cleanup()
+		require.NoError(t, err, test.file)
+		require.Equal(t, data, string(got), test.file)
+	}
+}
+
+func TestDataFormat(t *testing.T) {
+	require.Equal(t, RdfFormat, DataFormat("data.rdf.zst", ""))
+	require.Equal(t, JsonFormat, DataFormat("data.jsonl.xz", ""))
+	require.Equal(t, JsonFormat, DataFormat("data", "jsonl"))
+	require.Equal(t, TurtleFormat, DataFormat("data.ttl.gz", ""))
+	require.Equal(t, HdtFormat, DataFormat("data.hdt", ""))
+	require.Equal(t, UnknownFormat, DataFormat("data.txt.gz", ""))
+}
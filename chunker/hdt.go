@@ -0,0 +1,459 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunker
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// HDT (http://www.rdfhdt.org/hdt-binary-format/) keeps the terms of a dataset in a dictionary
+// and the triples as compact arrays of term ids. The components of an HDT file are identified
+// by these formats.
+const (
+	hdtCookie         = "$HDT"
+	hdtDictionaryFour = "<http://purl.org/HDT/hdt#dictionaryFour>"
+	hdtTriplesBitmap  = "<http://purl.org/HDT/hdt#triplesBitmap>"
+
+	hdtGlobal     byte = 1
+	hdtHeader     byte = 2
+	hdtDictionary byte = 3
+	hdtTriples    byte = 4
+
+	hdtSequenceLog byte = 1
+	hdtBitmapPlain byte = 1
+	hdtSectionPFC  byte = 2
+)
+
+// hdtOrders maps the values of the order property of the triples to the roles of their three
+// components.
+var hdtOrders = map[string]string{
+	"1": "SPO", "2": "SOP", "3": "PSO", "4": "POS", "5": "OSP", "6": "OPS",
+}
+
+// hdtChunker reads an HDT file and turns its triples into chunks of N-Quads. The whole file is
+// read into memory before the first chunk is returned, because the triples can refer to any
+// term of the dictionary.
+type hdtChunker struct {
+	*rdfChunker
+	r   *bufio.Reader
+	hdt *hdtFile
+}
+
+// Chunk returns the next 1e5 triples of the file.
+func (hc *hdtChunker) Chunk(r *bufio.Reader) (*bytes.Buffer, error) {
+	if hc.hdt == nil || hc.r != r {
+		hdt, err := readHDT(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while reading HDT")
+		}
+		hc.r, hc.hdt = r, hdt
+	}
+
+	batch := new(bytes.Buffer)
+	batch.Grow(1 << 20)
+	for i := 0; i < 1e5; i++ {
+		ok, err := hc.hdt.next(batch)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return batch, io.EOF
+		}
+	}
+	return batch, nil
+}
+
+type hdtFile struct {
+	shared, subjects, predicates, objects *hdtSection
+
+	// roles holds the role (S, P or O) of the x, y and z components of the triples.
+	roles            string
+	bitmapY, bitmapZ hdtBitmap
+	arrayY, arrayZ   hdtLogArray
+
+	// x, posY and posZ are the position of the iteration over the triples.
+	x, posY, posZ uint64
+}
+
+func readHDT(r *bufio.Reader) (*hdtFile, error) {
+	if _, _, err := readHDTControl(r, hdtGlobal); err != nil {
+		return nil, err
+	}
+	_, props, err := readHDTControl(r, hdtHeader)
+	if err != nil {
+		return nil, err
+	}
+	headerLen, err := strconv.ParseInt(props["length"], 10, 64)
+	if err != nil {
+		return nil, errors.Errorf("invalid length of the header: %q", props["length"])
+	}
+	if _, err := io.CopyN(ioutil.Discard, r, headerLen); err != nil {
+		return nil, err
+	}
+
+	h := &hdtFile{}
+	format, _, err := readHDTControl(r, hdtDictionary)
+	if err != nil {
+		return nil, err
+	}
+	if format != hdtDictionaryFour {
+		return nil, errors.Errorf("unsupported dictionary %s", format)
+	}
+	for _, s := range []**hdtSection{&h.shared, &h.subjects, &h.predicates, &h.objects} {
+		if *s, err = readHDTSection(r); err != nil {
+			return nil, err
+		}
+	}
+
+	format, props, err = readHDTControl(r, hdtTriples)
+	if err != nil {
+		return nil, err
+	}
+	if format != hdtTriplesBitmap {
+		return nil, errors.Errorf("unsupported triples %s", format)
+	}
+	var ok bool
+	if h.roles, ok = hdtOrders[props["order"]]; !ok {
+		return nil, errors.Errorf("unsupported order of the triples %q", props["order"])
+	}
+	if h.bitmapY, err = readHDTBitmap(r); err != nil {
+		return nil, err
+	}
+	if h.bitmapZ, err = readHDTBitmap(r); err != nil {
+		return nil, err
+	}
+	if h.arrayY, err = readHDTLogArray(r); err != nil {
+		return nil, err
+	}
+	if h.arrayZ, err = readHDTLogArray(r); err != nil {
+		return nil, err
+	}
+	if h.arrayY.n > h.bitmapY.n || h.arrayZ.n > h.bitmapZ.n {
+		return nil, errors.Errorf("the bitmaps of the triples are shorter than their arrays")
+	}
+	h.x = 1
+	return h, nil
+}
+
+// next writes the next triple to out. It returns false once all the triples have been written.
+func (h *hdtFile) next(out *bytes.Buffer) (bool, error) {
+	if h.posZ >= h.arrayZ.n || h.posY >= h.arrayY.n {
+		return false, nil
+	}
+	ids := [3]uint64{h.x, h.arrayY.get(h.posY), h.arrayZ.get(h.posZ)}
+	// A set bit marks the last z of a y, and the last y of an x.
+	if h.bitmapZ.get(h.posZ) {
+		if h.bitmapY.get(h.posY) {
+			h.x++
+		}
+		h.posY++
+	}
+	h.posZ++
+
+	var spo [3]string
+	for i, id := range ids {
+		var err error
+		switch h.roles[i] {
+		case 'S':
+			spo[0], err = h.term(h.subjects, id)
+		case 'P':
+			spo[1], err = h.predicates.get(id)
+		case 'O':
+			spo[2], err = h.term(h.objects, id)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	for i, term := range spo {
+		out.WriteString(hdtTermToNTriples(term))
+		if i < 2 {
+			out.WriteByte(' ')
+		}
+	}
+	out.WriteString(" .\n")
+	return true, nil
+}
+
+// term returns the subject or the object with the given id. The ids of the terms which are both
+// subjects and objects come first.
+func (h *hdtFile) term(section *hdtSection, id uint64) (string, error) {
+	if id <= h.shared.n {
+		return h.shared.get(id)
+	}
+	return section.get(id - h.shared.n)
+}
+
+// hdtTermToNTriples turns a term of the dictionary into its N-Triples syntax. The dictionary
+// keeps IRIs without their brackets and literals without escaping their lexical form.
+func hdtTermToNTriples(term string) string {
+	switch {
+	case strings.HasPrefix(term, "_:"):
+		return term
+	case strings.HasPrefix(term, `"`):
+		end := strings.LastIndexByte(term, '"')
+		if end == 0 {
+			return quoteLiteral(term[1:])
+		}
+		return quoteLiteral(term[1:end]) + term[end+1:]
+	}
+	var sb strings.Builder
+	sb.WriteByte('<')
+	for _, r := range term {
+		if isIRIChar(r) {
+			sb.WriteRune(r)
+		} else {
+			fmt.Fprintf(&sb, `\u%04X`, r)
+		}
+	}
+	sb.WriteByte('>')
+	return sb.String()
+}
+
+// readHDTControl reads the control information of a component of the given type and returns its
+// format and properties. The checksums of the file aren't verified.
+func readHDTControl(r *bufio.Reader, typ byte) (string, map[string]string, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return "", nil, err
+	}
+	if string(hdr[:4]) != hdtCookie {
+		return "", nil, errors.Errorf("not an HDT file")
+	}
+	if hdr[4] != typ {
+		return "", nil, errors.Errorf("expected a component of type %d, found %d", typ, hdr[4])
+	}
+	format, err := r.ReadString(0)
+	if err != nil {
+		return "", nil, err
+	}
+	rawProps, err := r.ReadString(0)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := r.Discard(2); err != nil { // CRC16
+		return "", nil, err
+	}
+
+	props := make(map[string]string)
+	for _, prop := range strings.Split(strings.TrimSuffix(rawProps, "\x00"), ";") {
+		if kv := strings.SplitN(prop, "=", 2); len(kv) == 2 {
+			props[kv[0]] = kv[1]
+		}
+	}
+	return strings.TrimSuffix(format, "\x00"), props, nil
+}
+
+// readVByte reads a variable length integer. Unlike protobuf varints, the high bit is set on the
+// last byte.
+func readVByte(r io.ByteReader) (uint64, error) {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(b&0x7f) << shift
+		if b&0x80 != 0 {
+			return v, nil
+		}
+	}
+	return 0, errors.Errorf("VByte overflows 64 bits")
+}
+
+func readHDTBytes(r *bufio.Reader, n uint64) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		return nil, errors.Wrapf(err, "while reading %d bytes", n)
+	}
+	return buf.Bytes(), nil
+}
+
+type hdtBitmap struct {
+	n    uint64
+	data []byte
+}
+
+func readHDTBitmap(r *bufio.Reader) (hdtBitmap, error) {
+	var b hdtBitmap
+	typ, err := r.ReadByte()
+	if err != nil {
+		return b, err
+	}
+	if typ != hdtBitmapPlain {
+		return b, errors.Errorf("unsupported bitmap of type %d", typ)
+	}
+	if b.n, err = readVByte(r); err != nil {
+		return b, err
+	}
+	if _, err := r.Discard(1); err != nil { // CRC8
+		return b, err
+	}
+	if b.data, err = readHDTBytes(r, (b.n+7)/8); err != nil {
+		return b, err
+	}
+	_, err = r.Discard(4) // CRC32
+	return b, err
+}
+
+func (b hdtBitmap) get(i uint64) bool {
+	return b.data[i/8]>>(i%8)&1 == 1
+}
+
+// hdtLogArray holds n integers of the given number of bits each, packed in little endian order.
+type hdtLogArray struct {
+	n    uint64
+	bits uint64
+	data []byte
+}
+
+func readHDTLogArray(r *bufio.Reader) (hdtLogArray, error) {
+	var a hdtLogArray
+	typ, err := r.ReadByte()
+	if err != nil {
+		return a, err
+	}
+	if typ != hdtSequenceLog {
+		return a, errors.Errorf("unsupported sequence of type %d", typ)
+	}
+	bits, err := r.ReadByte()
+	if err != nil {
+		return a, err
+	}
+	if bits > 64 {
+		return a, errors.Errorf("invalid number of bits %d in a sequence", bits)
+	}
+	a.bits = uint64(bits)
+	if a.n, err = readVByte(r); err != nil {
+		return a, err
+	}
+	if _, err := r.Discard(1); err != nil { // CRC8
+		return a, err
+	}
+	if a.data, err = readHDTBytes(r, (a.bits*a.n+7)/8); err != nil {
+		return a, err
+	}
+	_, err = r.Discard(4) // CRC32
+	return a, err
+}
+
+func (a hdtLogArray) get(i uint64) uint64 {
+	var v uint64
+	pos := i * a.bits
+	for n := uint64(0); n < a.bits; {
+		take := 8 - pos%8
+		if take > a.bits-n {
+			take = a.bits - n
+		}
+		b := uint64(a.data[pos/8]) >> (pos % 8)
+		v |= (b & (1<<take - 1)) << n
+		n += take
+		pos += take
+	}
+	return v
+}
+
+// hdtSection is a section of the dictionary holding sorted strings with plain front coding:
+// the strings are split in blocks, each starting with a full string, and the other strings
+// only keep what follows the prefix they share with the one before them.
+type hdtSection struct {
+	n         uint64
+	blockSize uint64
+	blocks    hdtLogArray
+	data      []byte
+}
+
+func readHDTSection(r *bufio.Reader) (*hdtSection, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if typ != hdtSectionPFC {
+		return nil, errors.Errorf("unsupported dictionary section of type %d", typ)
+	}
+	s := &hdtSection{}
+	if s.n, err = readVByte(r); err != nil {
+		return nil, err
+	}
+	size, err := readVByte(r)
+	if err != nil {
+		return nil, err
+	}
+	if s.blockSize, err = readVByte(r); err != nil {
+		return nil, err
+	}
+	if s.n > 0 && s.blockSize == 0 {
+		return nil, errors.Errorf("invalid block size 0 in a dictionary section")
+	}
+	if _, err := r.Discard(1); err != nil { // CRC8
+		return nil, err
+	}
+	if s.blocks, err = readHDTLogArray(r); err != nil {
+		return nil, err
+	}
+	if s.data, err = readHDTBytes(r, size); err != nil {
+		return nil, err
+	}
+	_, err = r.Discard(4) // CRC32
+	return s, err
+}
+
+// get returns the string with the given id, starting at 1.
+func (s *hdtSection) get(id uint64) (string, error) {
+	if id == 0 || id > s.n {
+		return "", errors.Errorf("term id %d is out of the range [1, %d]", id, s.n)
+	}
+	block, idx := (id-1)/s.blockSize, (id-1)%s.blockSize
+	if block >= s.blocks.n {
+		return "", errors.Errorf("term id %d is past the last block", id)
+	}
+	data := s.data
+	if off := s.blocks.get(block); off < uint64(len(data)) {
+		data = data[off:]
+	} else {
+		return "", errors.Errorf("invalid offset %d of block %d", off, block)
+	}
+
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return "", errors.Errorf("unterminated string in block %d", block)
+	}
+	str := append([]byte(nil), data[:end]...)
+	data = data[end+1:]
+	for i := uint64(0); i < idx; i++ {
+		rd := bytes.NewReader(data)
+		shared, err := readVByte(rd)
+		if err != nil {
+			return "", err
+		}
+		data = data[len(data)-rd.Len():]
+		if end = bytes.IndexByte(data, 0); end < 0 || shared > uint64(len(str)) {
+			return "", errors.Errorf("invalid string in block %d", block)
+		}
+		str = append(str[:shared], data[:end]...)
+		data = data[end+1:]
+	}
+	return string(str), nil
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunker
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// hdtWriter writes the components of an HDT file like the reference implementation, with zeroed
+// checksums.
+type hdtWriter struct {
+	bytes.Buffer
+}
+
+func (w *hdtWriter) vbyte(v uint64) {
+	for ; v > 127; v >>= 7 {
+		w.WriteByte(byte(v & 127))
+	}
+	w.WriteByte(byte(v | 0x80))
+}
+
+func (w *hdtWriter) control(typ byte, format, props string) {
+	w.WriteString(hdtCookie)
+	w.WriteByte(typ)
+	w.WriteString(format + "\x00" + props + "\x00")
+	w.Write(make([]byte, 2))
+}
+
+func (w *hdtWriter) logArray(vals []uint64, bits uint64) {
+	w.WriteByte(hdtSequenceLog)
+	w.WriteByte(byte(bits))
+	w.vbyte(uint64(len(vals)))
+	w.WriteByte(0)
+	data := make([]byte, (bits*uint64(len(vals))+7)/8)
+	for i, v := range vals {
+		for b := uint64(0); b < bits; b++ {
+			if v>>b&1 == 1 {
+				pos := uint64(i)*bits + b
+				data[pos/8] |= 1 << (pos % 8)
+			}
+		}
+	}
+	w.Write(data)
+	w.Write(make([]byte, 4))
+}
+
+func (w *hdtWriter) bitmap(bits []int) {
+	w.WriteByte(hdtBitmapPlain)
+	w.vbyte(uint64(len(bits)))
+	w.WriteByte(0)
+	data := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		data[i/8] |= byte(b) << uint(i%8)
+	}
+	w.Write(data)
+	w.Write(make([]byte, 4))
+}
+
+func (w *hdtWriter) section(strs []string, blockSize int) {
+	var data bytes.Buffer
+	var blocks []uint64
+	for i, s := range strs {
+		if i%blockSize == 0 {
+			blocks = append(blocks, uint64(data.Len()))
+			data.WriteString(s + "\x00")
+			continue
+		}
+		prev, shared := strs[i-1], 0
+		for shared < len(prev) && shared < len(s) && prev[shared] == s[shared] {
+			shared++
+		}
+		var vb hdtWriter
+		vb.vbyte(uint64(shared))
+		data.Write(vb.Bytes())
+		data.WriteString(s[shared:] + "\x00")
+	}
+	blocks = append(blocks, uint64(data.Len()))
+
+	w.WriteByte(hdtSectionPFC)
+	w.vbyte(uint64(len(strs)))
+	w.vbyte(uint64(data.Len()))
+	w.vbyte(uint64(blockSize))
+	w.WriteByte(0)
+	w.logArray(blocks, 16)
+	w.Write(data.Bytes())
+	w.Write(make([]byte, 4))
+}
+
+func TestHDT(t *testing.T) {
+	var w hdtWriter
+	w.control(hdtGlobal, "<http://purl.org/HDT/hdt#HDTv1>", "")
+	header := "<file> <http://rdfs.org/ns/void#triples> \"6\" .\n"
+	w.control(hdtHeader, "ntriples", "length=47;")
+	w.WriteString(header)
+	w.control(hdtDictionary, hdtDictionaryFour, "mapping=1;")
+	w.section([]string{"http://ex.org/alice", "http://ex.org/bob"}, 2)
+	w.section([]string{"_:b1"}, 2)
+	w.section([]string{"http://ex.org/knows", "http://ex.org/name"}, 2)
+	w.section([]string{
+		`"42"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+		`"Alice"@en`,
+		`"Bob "the" builder"`,
+	}, 2)
+	w.control(hdtTriples, hdtTriplesBitmap, "order=1;numTriples=6;")
+	w.bitmap([]int{0, 1, 0, 1, 1})
+	w.bitmap([]int{0, 1, 1, 1, 1, 1})
+	w.logArray([]uint64{1, 2, 1, 2, 2}, 2)
+	w.logArray([]uint64{1, 2, 4, 1, 5, 3}, 3)
+
+	ck := NewChunker(HdtFormat, 1000)
+	chunkBuf, err := ck.Chunk(bufioReader(w.String()))
+	require.Equal(t, io.EOF, err)
+	want := `<http://ex.org/alice> <http://ex.org/knows> <http://ex.org/alice> .
+<http://ex.org/alice> <http://ex.org/knows> <http://ex.org/bob> .
+<http://ex.org/alice> <http://ex.org/name> "Alice"@en .
+<http://ex.org/bob> <http://ex.org/knows> <http://ex.org/alice> .
+<http://ex.org/bob> <http://ex.org/name> "Bob \"the\" builder" .
+_:b1 <http://ex.org/name> "42"^^<http://www.w3.org/2001/XMLSchema#integer> .
+`
+	require.Equal(t, want, chunkBuf.String())
+	require.NoError(t, ck.Parse(chunkBuf))
+
+	_, err = NewChunker(HdtFormat, 1000).Chunk(bufioReader("<a> <b> <c> .\n"))
+	require.Contains(t, err.Error(), "not an HDT file")
+}
@@ -0,0 +1,370 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunker
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgryski/go-farm"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxTrackedSubjects bounds the subjects remembered for each predicate to find out whether
+	// it holds lists. Past it, the predicate is taken to hold lists, as a list schema accepts
+	// single values while a scalar one would drop all but one value of a list.
+	maxTrackedSubjects = 1 << 16
+	// maxTrackedValues bounds the distinct string values remembered for each predicate to
+	// estimate their cardinality.
+	maxTrackedValues = 1 << 14
+)
+
+// SchemaInferrer gathers statistics about the predicates of the N-Quads it's given and infers
+// their types, whether they hold lists and which indexes would suit them. It isn't safe for
+// concurrent use.
+type SchemaInferrer struct {
+	preds map[string]*predicateStats
+}
+
+type predicateStats struct {
+	count uint64
+	uids  uint64
+	kinds map[types.TypeID]uint64
+	lang  bool
+	list  bool
+
+	subjects map[uint64]struct{}
+	values   map[uint64]struct{}
+	strCount uint64
+	strLen   uint64
+}
+
+// InferredPredicate is what a SchemaInferrer inferred about a predicate.
+type InferredPredicate struct {
+	Name  string
+	Type  types.TypeID
+	List  bool
+	Lang  bool
+	Index string
+	// Count is the number of values and edges of the predicate in the input.
+	Count uint64
+	// Note explains the conflicting types found for the predicate, if any.
+	Note string
+}
+
+// NewSchemaInferrer returns a SchemaInferrer which hasn't seen any N-Quad yet.
+func NewSchemaInferrer() *SchemaInferrer {
+	return &SchemaInferrer{preds: make(map[string]*predicateStats)}
+}
+
+// InferSchema reads the given files in the format given by their extension or by format and
+// returns what it inferred about their predicates. The open function returns a reader on a file
+// and the function to call once done with it.
+func InferSchema(files []string, format string,
+	open func(file string) (*bufio.Reader, func())) (*SchemaInferrer, error) {
+
+	si := NewSchemaInferrer()
+	for _, file := range files {
+		rd, cleanup := open(file)
+		loadType := DataFormat(file, format)
+		if loadType == UnknownFormat {
+			if isJSON, err := IsJSONData(rd); err == nil && isJSON {
+				loadType = JsonFormat
+			} else {
+				cleanup()
+				return nil, errors.Errorf("need --format to infer the schema of %s", file)
+			}
+		}
+		err := si.ReadFile(rd, loadType)
+		cleanup()
+		if err != nil {
+			return nil, errors.Wrapf(err, "while inferring the schema of %s", file)
+		}
+	}
+	return si, nil
+}
+
+// ReadFile adds all the N-Quads of the input in the given format.
+func (si *SchemaInferrer) ReadFile(rd *bufio.Reader, format InputFormat) error {
+	ck := NewChunker(format, 1000)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for nqs := range ck.NQuads().Ch() {
+			si.Add(nqs)
+		}
+	}()
+
+	var err error
+	for err == nil {
+		var chunkBuf *bytes.Buffer
+		chunkBuf, err = ck.Chunk(rd)
+		if chunkBuf != nil && chunkBuf.Len() > 0 {
+			if perr := ck.Parse(chunkBuf); perr != nil {
+				err = perr
+			}
+		}
+	}
+	ck.NQuads().Flush()
+	<-done
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// Add gathers the statistics of the given N-Quads.
+func (si *SchemaInferrer) Add(nqs []*api.NQuad) {
+	for _, nq := range nqs {
+		if nq.Predicate == x.Star || nq.ObjectId == x.Star ||
+			x.IsReservedPredicate(x.GalaxyAttr(nq.Predicate)) {
+			continue
+		}
+		ps, ok := si.preds[nq.Predicate]
+		if !ok {
+			ps = &predicateStats{
+				kinds:    make(map[types.TypeID]uint64),
+				subjects: make(map[uint64]struct{}),
+				values:   make(map[uint64]struct{}),
+			}
+			si.preds[nq.Predicate] = ps
+		}
+		ps.add(nq)
+	}
+}
+
+func (ps *predicateStats) add(nq *api.NQuad) {
+	ps.count++
+	if nq.Lang != "" {
+		// Values in different languages are alternatives of the same value, not a list.
+		ps.lang = true
+		ps.addString(nq.GetObjectValue().GetDefaultVal() + nq.GetObjectValue().GetStrVal())
+		ps.kinds[types.StringID]++
+		return
+	}
+
+	if !ps.list {
+		subj := farm.Fingerprint64([]byte(nq.Subject))
+		_, seen := ps.subjects[subj]
+		if seen || len(ps.subjects) >= maxTrackedSubjects {
+			ps.list = true
+			ps.subjects = nil
+		} else {
+			ps.subjects[subj] = struct{}{}
+		}
+	}
+
+	if nq.ObjectValue == nil {
+		ps.uids++
+		return
+	}
+	typ := valueType(nq.ObjectValue)
+	ps.kinds[typ]++
+	if typ == types.StringID {
+		ps.addString(nq.ObjectValue.GetDefaultVal() + nq.ObjectValue.GetStrVal())
+	}
+}
+
+func (ps *predicateStats) addString(s string) {
+	ps.strCount++
+	ps.strLen += uint64(len(s))
+	if len(ps.values) < maxTrackedValues {
+		ps.values[farm.Fingerprint64([]byte(s))] = struct{}{}
+	}
+}
+
+// valueType returns the type of the value. The type of untyped values is guessed from the way
+// they look.
+func valueType(v *api.Value) types.TypeID {
+	switch val := v.Val.(type) {
+	case *api.Value_DefaultVal:
+		return guessType(val.DefaultVal)
+	case *api.Value_IntVal:
+		return types.IntID
+	case *api.Value_DoubleVal:
+		return types.FloatID
+	case *api.Value_BoolVal:
+		return types.BoolID
+	case *api.Value_DatetimeVal, *api.Value_DateVal:
+		return types.DateTimeID
+	case *api.Value_GeoVal:
+		return types.GeoID
+	case *api.Value_PasswordVal:
+		return types.PasswordID
+	case *api.Value_BytesVal:
+		return types.BinaryID
+	case *api.Value_UidVal:
+		return types.UidID
+	default:
+		return types.StringID
+	}
+}
+
+func guessType(s string) types.TypeID {
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return types.IntID
+	}
+	// ParseFloat also accepts words like Inf and NaN, which are more likely to be strings.
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) &&
+		strings.ContainsAny(s, "0123456789") {
+		return types.FloatID
+	}
+	if s == "true" || s == "false" {
+		return types.BoolID
+	}
+	if len(s) >= len("2006-01-02") && strings.ContainsAny(s[:1], "0123456789") {
+		if _, err := types.ParseTime(s); err == nil {
+			return types.DateTimeID
+		}
+	}
+	return types.StringID
+}
+
+// Predicates returns what was inferred about each predicate, sorted by name.
+func (si *SchemaInferrer) Predicates() []InferredPredicate {
+	preds := make([]InferredPredicate, 0, len(si.preds))
+	for name, ps := range si.preds {
+		preds = append(preds, ps.infer(name))
+	}
+	sort.Slice(preds, func(i, j int) bool { return preds[i].Name < preds[j].Name })
+	return preds
+}
+
+func (ps *predicateStats) infer(name string) InferredPredicate {
+	p := InferredPredicate{Name: name, List: ps.list, Lang: ps.lang, Count: ps.count}
+
+	var values uint64
+	var kinds []string
+	for kind, n := range ps.kinds {
+		values += n
+		kinds = append(kinds, fmt.Sprintf("%s=%d", kind.Name(), n))
+	}
+	if ps.uids > 0 && values > 0 {
+		kinds = append(kinds, fmt.Sprintf("uid=%d", ps.uids))
+	}
+	if len(kinds) > 1 {
+		sort.Strings(kinds)
+		p.Note = "mixed " + strings.Join(kinds, " ")
+	}
+
+	switch {
+	case ps.uids >= values:
+		// Edges win over values, which are more likely to be the mistakes.
+		p.Type = types.UidID
+		p.Lang = false
+		return p
+	case len(ps.kinds) == 1:
+		for kind := range ps.kinds {
+			p.Type = kind
+		}
+	case len(ps.kinds) == 2 && ps.kinds[types.IntID] > 0 && ps.kinds[types.FloatID] > 0:
+		p.Type = types.FloatID
+	default:
+		p.Type = types.StringID
+	}
+
+	switch p.Type {
+	case types.StringID:
+		avgLen := ps.strLen / x.Max(ps.strCount, 1)
+		lowCardinality := ps.strCount >= 10 && len(ps.values) < maxTrackedValues &&
+			uint64(len(ps.values))*10 <= ps.strCount
+		switch {
+		case avgLen >= 50:
+			p.Index = "fulltext"
+		case lowCardinality:
+			p.Index = "exact"
+		default:
+			p.Index = "hash"
+		}
+	case types.IntID:
+		p.Index = "int"
+	case types.FloatID:
+		p.Index = "float"
+	case types.DateTimeID:
+		p.Index = "year"
+	case types.BoolID:
+		p.Index = "bool"
+	case types.GeoID:
+		p.Index = "geo"
+	}
+	if p.Type != types.StringID {
+		p.Lang = false
+	}
+	return p
+}
+
+// SchemaLine returns the schema of the predicate.
+func (p InferredPredicate) SchemaLine() string {
+	var sb strings.Builder
+	name := p.Name
+	if strings.IndexFunc(name, func(r rune) bool {
+		return !(r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' ||
+			r >= 'A' && r <= 'Z')
+	}) >= 0 {
+		name = "<" + name + ">"
+	}
+	sb.WriteString(name + ": ")
+	if p.List {
+		sb.WriteString("[" + p.Type.Name() + "]")
+	} else {
+		sb.WriteString(p.Type.Name())
+	}
+	if p.Index != "" {
+		sb.WriteString(" @index(" + p.Index + ")")
+	}
+	if p.Lang {
+		sb.WriteString(" @lang")
+	}
+	sb.WriteString(" .")
+	return sb.String()
+}
+
+// Schema returns the inferred schema of the predicates for which skip returns false. A nil skip
+// keeps all of them.
+func (si *SchemaInferrer) Schema(skip func(pred string) bool) string {
+	var sb strings.Builder
+	for _, p := range si.Predicates() {
+		if skip != nil && skip(p.Name) {
+			continue
+		}
+		sb.WriteString(p.SchemaLine())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// WriteReport writes a table of what was inferred about each predicate.
+func (si *SchemaInferrer) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PREDICATE\tTYPE\tLIST\tLANG\tCOUNT\tINDEX\tNOTE")
+	for _, p := range si.Predicates() {
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%v\t%d\t%s\t%s\n",
+			p.Name, p.Type.Name(), p.List, p.Lang, p.Count, p.Index, p.Note)
+	}
+	return tw.Flush()
+}
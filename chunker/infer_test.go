@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunker
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferSchema(t *testing.T) {
+	var rdf strings.Builder
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&rdf, "_:p%d <name> \"Person %d\" .\n", i, i)
+		fmt.Fprintf(&rdf, "_:p%d <name> \"Personne %d\"@fr .\n", i, i)
+		fmt.Fprintf(&rdf, "_:p%d <age> \"%d\" .\n", i, 20+i)
+		fmt.Fprintf(&rdf, "_:p%d <score> \"%d.5\" .\n", i, i)
+		fmt.Fprintf(&rdf, "_:p%d <country> \"country %d\" .\n", i, i%2)
+		fmt.Fprintf(&rdf, "_:p%d <born> \"19%02d-01-02\" .\n", i, 50+i)
+		fmt.Fprintf(&rdf, "_:p%d <http://ex.org/bio> \"%s\" .\n", i, strings.Repeat("word ", 12))
+		fmt.Fprintf(&rdf, "_:p%d <friend> _:p%d .\n", i, (i+1)%20)
+		fmt.Fprintf(&rdf, "_:p%d <friend> _:p%d .\n", i, (i+2)%20)
+		fmt.Fprintf(&rdf, "_:p%d <dgraph.type> \"Person\" .\n", i)
+	}
+	rdf.WriteString("_:p0 <score> \"12\" .\n")
+	rdf.WriteString("_:p1 <age> \"unknown\" .\n")
+
+	si := NewSchemaInferrer()
+	require.NoError(t, si.ReadFile(bufioReader(rdf.String()), RdfFormat))
+	require.NoError(t, si.ReadFile(bufioReader(`{"nick": ["al", "ally"], "admin": true}`), JsonFormat))
+
+	require.Equal(t, `admin: bool @index(bool) .
+age: [string] @index(hash) .
+born: datetime @index(year) .
+country: string @index(exact) .
+friend: [uid] .
+<http://ex.org/bio>: string @index(fulltext) .
+name: string @index(hash) @lang .
+nick: [string] @index(hash) .
+score: [float] @index(float) .
+`, si.Schema(nil))
+	require.Equal(t, "admin: bool @index(bool) .\n",
+		si.Schema(func(pred string) bool { return pred != "admin" }))
+
+	var report bytes.Buffer
+	require.NoError(t, si.WriteReport(&report))
+	require.Contains(t, report.String(), "mixed int=20 string=1")
+}
+
+func TestInferSchemaTooManySubjects(t *testing.T) {
+	si := NewSchemaInferrer()
+	nq := &api.NQuad{Predicate: "name", ObjectValue: &api.Value{Val: &api.Value_StrVal{}}}
+	for i := 0; i < maxTrackedSubjects; i++ {
+		nq.Subject = fmt.Sprintf("_:s%d", i)
+		si.Add([]*api.NQuad{nq})
+	}
+	require.Equal(t, "name: string @index(exact) .\n", si.Schema(nil))
+
+	// Past the tracked subjects, a list can't be ruled out anymore.
+	nq.Subject = "_:last"
+	si.Add([]*api.NQuad{nq})
+	require.Equal(t, "name: [string] @index(exact) .\n", si.Schema(nil))
+}
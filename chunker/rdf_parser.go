@@ -91,7 +91,7 @@ func ParseRDF(line string, l *lex.Lexer) (api.NQuad, error) {
 		return rnq, err
 	}
 	it := l.NewIterator()
-	var oval string
+	var oval, graph string
 	var seenOval bool
 	var vend bool
 	isCommentLine := false
@@ -109,6 +109,12 @@ L:
 				return rnq, err
 			}
 
+		case itemQuotedTriple:
+			var err error
+			if rnq.Subject, err = parseQuotedTriple(item.Val); err != nil {
+				return rnq, err
+			}
+
 		case itemObjectFunc:
 			var err error
 			if rnq.ObjectId, err = parseFunction(it); err != nil {
@@ -194,12 +200,16 @@ L:
 			break L
 
 		case itemLabel:
+			// A numeric label is the namespace of the N-Quad, any other label is the named
+			// graph of the edge.
 			s := strings.TrimFunc(item.Val, isSpaceRune)
-			namespace, err := strconv.ParseUint(s, 0, 64)
-			if err != nil {
-				return rnq, errors.Errorf("Invalid namespace ID. Input: [%s]", line)
+			if namespace, err := strconv.ParseUint(s, 0, 64); err == nil {
+				rnq.Namespace = namespace
+			} else if sane(s) {
+				graph = s
+			} else {
+				return rnq, errors.Errorf("Invalid graph label. Input: [%s]", line)
 			}
-			rnq.Namespace = namespace
 
 		case itemLeftRound:
 			it.Prev() // backup '('
@@ -220,6 +230,13 @@ L:
 	if seenOval && rnq.ObjectValue == nil {
 		rnq.ObjectValue = &api.Value{Val: &api.Value_DefaultVal{DefaultVal: oval}}
 	}
+	if graph != "" {
+		facet, err := facets.FacetFor(x.GraphFacet, strconv.Quote(graph))
+		if err != nil {
+			return rnq, err
+		}
+		rnq.Facets = append(rnq.Facets, facet)
+	}
 	if len(rnq.Subject) == 0 || len(rnq.Predicate) == 0 {
 		return rnq, errors.Errorf("Empty required fields in NQuad. Input: [%s]", line)
 	}
@@ -233,6 +250,46 @@ L:
 	return rnq, nil
 }
 
+// QuotedTriple returns the subject used for an N-Quad whose subject is the quoted
+// triple << subject predicate object >>. Such an N-Quad sets a property of that edge.
+func QuotedTriple(subject, predicate, object string) string {
+	return "<<" + subject + " " + predicate + " " + object + ">>"
+}
+
+// ParseQuotedTriple splits a subject built by QuotedTriple back into the subject,
+// predicate and object of the quoted edge. It returns false if the subject isn't a
+// quoted triple.
+func ParseQuotedTriple(subject string) (string, string, string, bool) {
+	if !strings.HasPrefix(subject, "<<") || !strings.HasSuffix(subject, ">>") {
+		return "", "", "", false
+	}
+	parts := strings.Split(subject[2:len(subject)-2], " ")
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// parseQuotedTriple parses the triple quoted between << and >> and returns it in the
+// form built by QuotedTriple.
+func parseQuotedTriple(in string) (string, error) {
+	var l lex.Lexer
+	nq, err := ParseRDF(in+" .", &l)
+	switch {
+	case err != nil:
+		return "", errors.Wrapf(err, "while parsing quoted triple <<%s>>", in)
+	case strings.HasPrefix(nq.Subject, "<<"):
+		return "", errors.Errorf("Quoted triples can't be nested. Input: [<<%s>>]", in)
+	case nq.Subject == x.Star || nq.Predicate == x.Star || nq.ObjectId == "":
+		return "", errors.Errorf("Quoted triple should be an edge between two nodes."+
+			" Input: [<<%s>>]", in)
+	case nq.Lang != "" || nq.Namespace != 0 || len(nq.Facets) > 0:
+		return "", errors.Errorf("Quoted triple can't have a language, label or facets."+
+			" Input: [<<%s>>]", in)
+	}
+	return QuotedTriple(nq.Subject, nq.Predicate, nq.ObjectId), nil
+}
+
 // parseFunction parses uid(<var name>) and returns
 // uid(<var name>) after striping whitespace if any
 func parseFunction(it *lex.ItemIterator) (string, error) {
@@ -362,6 +419,7 @@ var typeMap = map[string]types.TypeID{
 	"xs:positiveInteger": types.IntID,
 	"xs:boolean":         types.BoolID,
 	"xs:double":          types.FloatID,
+	"xs:decimal":         types.FloatID,
 	"xs:float":           types.FloatID,
 	"xs:base64Binary":    types.BinaryID,
 	"geo:geojson":        types.GeoID,
@@ -373,6 +431,7 @@ var typeMap = map[string]types.TypeID{
 	"http://www.w3.org/2001/XMLSchema#integer":         types.IntID,
 	"http://www.w3.org/2001/XMLSchema#boolean":         types.BoolID,
 	"http://www.w3.org/2001/XMLSchema#double":          types.FloatID,
+	"http://www.w3.org/2001/XMLSchema#decimal":         types.FloatID,
 	"http://www.w3.org/2001/XMLSchema#float":           types.FloatID,
 	"http://www.w3.org/2001/XMLSchema#gYear":           types.DateTimeID,
 	"http://www.w3.org/2001/XMLSchema#gYearMonth":      types.DateTimeID,
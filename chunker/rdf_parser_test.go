@@ -1002,6 +1002,49 @@ var testNQuads = []struct {
 		input:       `uid(a)   lives> uid (  )  .`,
 		expectedErr: true,
 	},
+	{
+		input: `<<_:alice <friend> _:bob>> <since> "2019"^^<xs:int> .`,
+		nq: api.NQuad{
+			Subject:     "<<_:alice friend _:bob>>",
+			Predicate:   "since",
+			ObjectValue: &api.Value{Val: &api.Value_IntVal{IntVal: 2019}},
+		},
+		expectedErr: false,
+	},
+	{
+		input: `<< <0x1>   <friend> <0x2>>> <weight> "0.5" .`,
+		nq: api.NQuad{
+			Subject:     "<<0x1 friend 0x2>>",
+			Predicate:   "weight",
+			ObjectValue: &api.Value{Val: &api.Value_DefaultVal{DefaultVal: "0.5"}},
+		},
+		expectedErr: false,
+	},
+	{
+		input: `<<<0x1> <friend> <0x2>>> <source> _:src .`,
+		nq: api.NQuad{
+			Subject:   "<<0x1 friend 0x2>>",
+			Predicate: "source",
+			ObjectId:  "_:src",
+		},
+		expectedErr: false,
+	},
+	{
+		input:       `<<_:alice <name> "Alice">> <since> "2019" .`,
+		expectedErr: true,
+	},
+	{
+		input:       `<<<<_:a <friend> _:b>> <by> _:c>> <since> "2019" .`,
+		expectedErr: true,
+	},
+	{
+		input:       `<<_:alice <friend> _:bob <since> "2019" .`,
+		expectedErr: true,
+	},
+	{
+		input:       `<<_:alice <friend> _:bob (weight=0.5)>> <since> "2019" .`,
+		expectedErr: true,
+	},
 }
 
 func TestLex(t *testing.T) {
@@ -1022,3 +1065,30 @@ func TestLex(t *testing.T) {
 		}
 	}
 }
+
+func TestParseGraphLabel(t *testing.T) {
+	l := &lex.Lexer{}
+	for _, in := range []string{
+		`_:alice <knows> _:bob <g1> .`,
+		`_:alice <knows> _:bob (dgraph.graph="g1") .`,
+	} {
+		nq, err := ParseRDF(in, l)
+		require.NoError(t, err, in)
+		require.Zero(t, nq.Namespace)
+		require.Len(t, nq.Facets, 1)
+		require.Equal(t, x.GraphFacet, nq.Facets[0].Key)
+		require.Equal(t, "g1", string(nq.Facets[0].Value))
+	}
+
+	nq, err := ParseRDF(`_:alice <name> "Alice" <http://example.org/people> (since=2006) .`, l)
+	require.NoError(t, err)
+	require.Len(t, nq.Facets, 2)
+	require.Equal(t, x.GraphFacet, nq.Facets[1].Key)
+	require.Equal(t, "http://example.org/people", string(nq.Facets[1].Value))
+
+	// A numeric label is still the namespace.
+	nq, err = ParseRDF(`_:alice <knows> _:bob <0x2> .`, l)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), nq.Namespace)
+	require.Empty(t, nq.Facets)
+}
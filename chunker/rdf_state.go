@@ -18,30 +18,32 @@ package chunker
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/dgraph-io/dgraph/lex"
 )
 
 // The constants represent different types of lexed Items possible for an rdf N-Quad.
 const (
-	itemText        lex.ItemType = 5 + iota // plain text
-	itemSubject                             // subject, 6
-	itemPredicate                           // predicate, 7
-	itemObject                              // object, 8
-	itemLabel                               // label, 9
-	itemLiteral                             // literal, 10
-	itemLanguage                            // language, 11
-	itemObjectType                          // object type, 12
-	itemValidEnd                            // end with dot, 13
-	itemComment                             // comment, 14
-	itemComma                               // comma, 15
-	itemEqual                               // equal, 16
-	itemLeftRound                           // '(', 17
-	itemRightRound                          // ')', 18
-	itemStar                                // *, 19
-	itemSubjectFunc                         // uid, 20
-	itemObjectFunc                          // uid, 21
-	itemVarName                             // 22
+	itemText         lex.ItemType = 5 + iota // plain text
+	itemSubject                              // subject, 6
+	itemPredicate                            // predicate, 7
+	itemObject                               // object, 8
+	itemLabel                                // label, 9
+	itemLiteral                              // literal, 10
+	itemLanguage                             // language, 11
+	itemObjectType                           // object type, 12
+	itemValidEnd                             // end with dot, 13
+	itemComment                              // comment, 14
+	itemComma                                // comma, 15
+	itemEqual                                // equal, 16
+	itemLeftRound                            // '(', 17
+	itemRightRound                           // ')', 18
+	itemStar                                 // *, 19
+	itemSubjectFunc                          // uid, 20
+	itemObjectFunc                           // uid, 21
+	itemVarName                              // 22
+	itemQuotedTriple                         // quoted triple, 23
 )
 
 // These constants keep a track of the depth while parsing an rdf N-Quad.
@@ -221,6 +223,13 @@ func lexBlankNode(l *lex.Lexer, styp lex.ItemType,
 
 func lexSubject(l *lex.Lexer) lex.StateFn {
 	r := l.Next()
+	// The subject is a quoted triple (RDF-star), so we lex till we encounter '>>'.
+	if r == lsThan && l.Peek() == lsThan {
+		l.Next()
+		l.Depth++
+		return lexQuotedTriple(l, lexText)
+	}
+
 	// The subject is an IRI, so we lex till we encounter '>'.
 	if r == lsThan {
 		l.Depth++
@@ -236,6 +245,30 @@ func lexSubject(l *lex.Lexer) lex.StateFn {
 	return lexUidNode(l, itemSubject, lexText)
 }
 
+// Assumes that caller has consumed '<<'. The quoted triple is emitted without
+// '<<' and '>>' and is parsed separately by the parser.
+func lexQuotedTriple(l *lex.Lexer, sfn lex.StateFn) lex.StateFn {
+	l.Ignore() // ignore '<<'
+	rest := l.Input[l.Pos:]
+	end := strings.Index(rest, ">>")
+	if end < 0 {
+		return l.Errorf("Unexpected end of quoted triple")
+	}
+	// The object of the quoted triple can be an IRI, in which case its closing '>'
+	// immediately precedes the closing '>>'.
+	for end+2 < len(rest) && rest[end+2] == '>' {
+		end++
+	}
+	for end += l.Pos; l.Pos < end; {
+		l.Next()
+	}
+	l.Emit(itemQuotedTriple)
+	l.Next()
+	l.Next()
+	l.Ignore() // ignore '>>'
+	return sfn
+}
+
 func lexPredicate(l *lex.Lexer) lex.StateFn {
 	r := l.Next()
 	// The predicate can only be an IRI according to the spec.
@@ -364,7 +397,8 @@ func lexLabel(l *lex.Lexer) lex.StateFn {
 
 // lexFacets parses key-value pairs of Facets. sample is :
 // ( key1 = "value1", key2=13, key3=, key4 =2.4, key5=2006-01-02T15:04:05,
-//   key6=2006-01-02 )
+//
+//	key6=2006-01-02 )
 func lexFacets(l *lex.Lexer) lex.StateFn {
 	r := l.Next()
 	if r != leftRound {
@@ -0,0 +1,804 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunker
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	rdfNS = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xsdNS = "http://www.w3.org/2001/XMLSchema#"
+
+	// eof is returned by peek at the end of the input.
+	eof = -1
+)
+
+var (
+	// anonPrefix and anonCount are used to label the blank nodes which have no label in the
+	// input, like [] in Turtle. The prefix is unique to the process so that the labels don't
+	// collide with the ones of earlier loads sharing an xidmap.
+	anonPrefix = "_:genid" + strconv.FormatInt(time.Now().UnixNano(), 36) + "x"
+	anonCount  uint64
+)
+
+func newAnonNode() string {
+	return anonPrefix + strconv.FormatUint(atomic.AddUint64(&anonCount, 1), 10)
+}
+
+// turtleChunker reads Turtle (https://www.w3.org/TR/turtle/) and turns it into chunks of
+// N-Quads. Turtle statements depend on the prefixes and base declared before them, so they
+// can't be parsed out of order. The translation happens while chunking, which is done serially
+// for each file, and the chunks are then parsed like any other RDF.
+type turtleChunker struct {
+	*rdfChunker
+	p *turtleParser
+}
+
+// Chunk translates the statements of the input until 1e5 triples have been written or the
+// end of the input is reached.
+func (tc *turtleChunker) Chunk(r *bufio.Reader) (*bytes.Buffer, error) {
+	if tc.p == nil || tc.p.r != r {
+		tc.p = newTurtleParser(r)
+	}
+	batch := new(bytes.Buffer)
+	batch.Grow(1 << 20)
+	tc.p.out = batch
+	for tc.p.count = 0; tc.p.count < 1e5; {
+		switch err := tc.p.statement(); {
+		case err == io.EOF:
+			return batch, err
+		case err != nil:
+			return nil, err
+		}
+	}
+	return batch, nil
+}
+
+type turtleParser struct {
+	r        *bufio.Reader
+	line     int
+	base     *url.URL
+	prefixes map[string]string
+
+	// out receives the N-Triples and count is the number of triples written to it.
+	out   *bytes.Buffer
+	count int
+}
+
+func newTurtleParser(r *bufio.Reader) *turtleParser {
+	return &turtleParser{r: r, line: 1, prefixes: make(map[string]string)}
+}
+
+func (p *turtleParser) errorf(format string, args ...interface{}) error {
+	return errors.Errorf("turtle: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *turtleParser) next() (rune, error) {
+	r, _, err := p.r.ReadRune()
+	switch {
+	case err == io.EOF:
+		return 0, p.errorf("unexpected end of input")
+	case err != nil:
+		return 0, err
+	case r == '\n':
+		p.line++
+	}
+	return r, nil
+}
+
+// peek returns the next rune without consuming it, or eof.
+func (p *turtleParser) peek() rune {
+	b, _ := p.r.Peek(1)
+	if len(b) == 0 {
+		return eof
+	}
+	if b[0] < utf8.RuneSelf {
+		return rune(b[0])
+	}
+	b, _ = p.r.Peek(utf8.UTFMax)
+	r, _ := utf8.DecodeRune(b)
+	return r
+}
+
+// peekByte returns the i-th byte after the current position, or 0 past the end of the input.
+func (p *turtleParser) peekByte(i int) byte {
+	b, _ := p.r.Peek(i + 1)
+	if len(b) <= i {
+		return 0
+	}
+	return b[i]
+}
+
+func (p *turtleParser) expect(want rune) error {
+	if err := p.skipWS(); err != nil {
+		return p.errorf("expected '%c', found end of input", want)
+	}
+	r, err := p.next()
+	if err != nil {
+		return err
+	}
+	if r != want {
+		return p.errorf("expected '%c', found '%c'", want, r)
+	}
+	return nil
+}
+
+// skipWS skips white space and comments. It returns io.EOF if nothing else is left.
+func (p *turtleParser) skipWS() error {
+	for {
+		switch p.peek() {
+		case eof:
+			return io.EOF
+		case ' ', '\t', '\r', '\n':
+			if _, err := p.next(); err != nil {
+				return err
+			}
+		case '#':
+			for r := p.peek(); r != '\n' && r != eof; r = p.peek() {
+				if _, err := p.next(); err != nil {
+					return err
+				}
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *turtleParser) emit(subj, pred, obj string) {
+	p.out.WriteString(subj)
+	p.out.WriteByte(' ')
+	p.out.WriteString(pred)
+	p.out.WriteByte(' ')
+	p.out.WriteString(obj)
+	p.out.WriteString(" .\n")
+	p.count++
+}
+
+// statement parses the next directive or the next triples followed by a dot. It returns
+// io.EOF at the end of the input.
+func (p *turtleParser) statement() error {
+	if err := p.skipWS(); err != nil {
+		return err
+	}
+	switch p.peek() {
+	case '@':
+		if _, err := p.next(); err != nil {
+			return err
+		}
+		switch word := p.readWord(); word {
+		case "prefix":
+			if err := p.prefixDirective(); err != nil {
+				return err
+			}
+		case "base":
+			if err := p.baseDirective(); err != nil {
+				return err
+			}
+		default:
+			return p.errorf("unknown directive @%s", word)
+		}
+		return p.expect('.')
+
+	case '[':
+		if _, err := p.next(); err != nil {
+			return err
+		}
+		subj, err := p.blankNodePropertyList()
+		if err != nil {
+			return err
+		}
+		// The predicates are optional after a blank node property list.
+		if err := p.skipWS(); err == nil && p.peek() != '.' {
+			if err := p.predicateObjectList(subj); err != nil {
+				return err
+			}
+		}
+		return p.expect('.')
+	}
+
+	subj, keyword, err := p.term()
+	switch {
+	case err != nil:
+		return err
+	case strings.EqualFold(keyword, "prefix"):
+		return p.prefixDirective()
+	case strings.EqualFold(keyword, "base"):
+		return p.baseDirective()
+	case keyword != "":
+		return p.errorf("unexpected %q", keyword)
+	case strings.HasPrefix(subj, `"`):
+		return p.errorf("a literal can't be the subject of a triple")
+	}
+	if err := p.predicateObjectList(subj); err != nil {
+		return err
+	}
+	return p.expect('.')
+}
+
+func (p *turtleParser) prefixDirective() error {
+	if err := p.skipWS(); err != nil {
+		return p.errorf("expected a prefix, found end of input")
+	}
+	prefix := p.readPrefix()
+	if err := p.expect(':'); err != nil {
+		return err
+	}
+	iri, err := p.iriDirective()
+	if err != nil {
+		return err
+	}
+	p.prefixes[prefix] = iri
+	return nil
+}
+
+func (p *turtleParser) baseDirective() error {
+	iri, err := p.iriDirective()
+	if err != nil {
+		return err
+	}
+	base, err := url.Parse(iri)
+	if err != nil {
+		return p.errorf("invalid base IRI %q: %v", iri, err)
+	}
+	p.base = base
+	return nil
+}
+
+// iriDirective reads the IRI reference of a directive and returns it without the brackets.
+func (p *turtleParser) iriDirective() (string, error) {
+	if err := p.expect('<'); err != nil {
+		return "", err
+	}
+	iri, err := p.iriRef()
+	if err != nil {
+		return "", err
+	}
+	return iri[1 : len(iri)-1], nil
+}
+
+// predicateObjectList parses the predicates and objects of subj and emits their triples.
+func (p *turtleParser) predicateObjectList(subj string) error {
+	for {
+		verb, err := p.verb()
+		if err != nil {
+			return err
+		}
+		for {
+			obj, err := p.object()
+			if err != nil {
+				return err
+			}
+			p.emit(subj, verb, obj)
+			if err := p.skipWS(); err != nil || p.peek() != ',' {
+				break
+			}
+			if _, err := p.next(); err != nil {
+				return err
+			}
+		}
+		if err := p.skipWS(); err != nil || p.peek() != ';' {
+			return nil
+		}
+		for p.peek() == ';' {
+			if _, err := p.next(); err != nil {
+				return err
+			}
+			if err := p.skipWS(); err != nil {
+				return nil
+			}
+		}
+		if r := p.peek(); r == '.' || r == ']' {
+			return nil
+		}
+	}
+}
+
+func (p *turtleParser) verb() (string, error) {
+	if err := p.skipWS(); err != nil {
+		return "", p.errorf("expected a predicate, found end of input")
+	}
+	pred, keyword, err := p.term()
+	switch {
+	case err != nil:
+		return "", err
+	case keyword == "a":
+		return "<" + rdfNS + "type>", nil
+	case keyword != "":
+		return "", p.errorf("unexpected %q", keyword)
+	case !strings.HasPrefix(pred, "<"):
+		return "", p.errorf("a predicate must be an IRI, found %s", pred)
+	}
+	return pred, nil
+}
+
+func (p *turtleParser) object() (string, error) {
+	if err := p.skipWS(); err != nil {
+		return "", p.errorf("expected an object, found end of input")
+	}
+	obj, keyword, err := p.term()
+	switch {
+	case err != nil:
+		return "", err
+	case keyword == "true" || keyword == "false":
+		return `"` + keyword + `"^^<` + xsdNS + "boolean>", nil
+	case keyword != "":
+		return "", p.errorf("unexpected %q", keyword)
+	}
+	return obj, nil
+}
+
+// term parses an IRI, a blank node, a collection or a literal and returns it in the N-Triples
+// syntax. A bare word which isn't a prefixed name is returned as a keyword instead.
+func (p *turtleParser) term() (string, string, error) {
+	switch r := p.peek(); {
+	case r == '<':
+		if _, err := p.next(); err != nil {
+			return "", "", err
+		}
+		iri, err := p.iriRef()
+		return iri, "", err
+	case r == '_':
+		node, err := p.blankNode()
+		return node, "", err
+	case r == '[':
+		if _, err := p.next(); err != nil {
+			return "", "", err
+		}
+		node, err := p.blankNodePropertyList()
+		return node, "", err
+	case r == '(':
+		if _, err := p.next(); err != nil {
+			return "", "", err
+		}
+		node, err := p.collection()
+		return node, "", err
+	case r == '"' || r == '\'':
+		lit, err := p.literal()
+		return lit, "", err
+	case r == '+' || r == '-' || r == '.' || (r >= '0' && r <= '9'):
+		num, err := p.number()
+		return num, "", err
+	case r == ':' || isPnCharsBase(r):
+		prefix := p.readPrefix()
+		if p.peek() != ':' {
+			return "", prefix, nil
+		}
+		if _, err := p.next(); err != nil {
+			return "", "", err
+		}
+		iri, err := p.prefixedName(prefix)
+		return iri, "", err
+	case r == eof:
+		return "", "", p.errorf("unexpected end of input")
+	default:
+		return "", "", p.errorf("unexpected character '%c'", r)
+	}
+}
+
+// iriRef reads an IRI reference after its '<' and resolves it against the base IRI.
+func (p *turtleParser) iriRef() (string, error) {
+	var sb strings.Builder
+	for {
+		r, err := p.next()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case r == '>':
+			return "<" + p.resolve(sb.String()) + ">", nil
+		case r == '\\':
+			u, err := p.uchar()
+			if err != nil {
+				return "", err
+			}
+			r = u
+		}
+		if !isIRIChar(r) {
+			return "", p.errorf("invalid character %q in IRI", r)
+		}
+		sb.WriteRune(r)
+	}
+}
+
+func (p *turtleParser) resolve(iri string) string {
+	if p.base == nil {
+		return iri
+	}
+	u, err := url.Parse(iri)
+	if err != nil || u.IsAbs() {
+		return iri
+	}
+	return p.base.ResolveReference(u).String()
+}
+
+// uchar reads the \u or \U escape sequence after its backslash.
+func (p *turtleParser) uchar() (rune, error) {
+	r, err := p.next()
+	if err != nil {
+		return 0, err
+	}
+	n := 4
+	switch r {
+	case 'u':
+	case 'U':
+		n = 8
+	default:
+		return 0, p.errorf("invalid escape sequence \\%c", r)
+	}
+	hex := make([]byte, 0, n)
+	for i := 0; i < n; i++ {
+		r, err := p.next()
+		if err != nil {
+			return 0, err
+		}
+		hex = append(hex, byte(r))
+	}
+	code, err := strconv.ParseUint(string(hex), 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return 0, p.errorf("invalid escape sequence \\%c%s", r, hex)
+	}
+	return rune(code), nil
+}
+
+func (p *turtleParser) blankNode() (string, error) {
+	if _, err := p.next(); err != nil {
+		return "", err
+	}
+	if r, err := p.next(); err != nil {
+		return "", err
+	} else if r != ':' {
+		return "", p.errorf("expected ':' after '_', found '%c'", r)
+	}
+	r := p.peek()
+	if !isPNCharsU(r) && !(r >= '0' && r <= '9') {
+		return "", p.errorf("invalid blank node label")
+	}
+	var sb strings.Builder
+	for r := p.peek(); p.nameGoesOn(r); r = p.peek() {
+		if _, err := p.next(); err != nil {
+			return "", err
+		}
+		sb.WriteRune(r)
+	}
+	return "_:" + sb.String(), nil
+}
+
+// blankNodePropertyList parses the predicates and objects of a blank node after its '[' and
+// returns the new blank node.
+func (p *turtleParser) blankNodePropertyList() (string, error) {
+	node := newAnonNode()
+	if err := p.skipWS(); err != nil {
+		return "", p.errorf("expected ']', found end of input")
+	}
+	if p.peek() != ']' {
+		if err := p.predicateObjectList(node); err != nil {
+			return "", err
+		}
+	}
+	return node, p.expect(']')
+}
+
+// collection parses the objects of a collection after its '(' and returns the head of the
+// RDF list holding them.
+func (p *turtleParser) collection() (string, error) {
+	var items []string
+	for {
+		if err := p.skipWS(); err != nil {
+			return "", p.errorf("expected ')', found end of input")
+		}
+		if p.peek() == ')' {
+			if _, err := p.next(); err != nil {
+				return "", err
+			}
+			break
+		}
+		item, err := p.object()
+		if err != nil {
+			return "", err
+		}
+		items = append(items, item)
+	}
+
+	head := "<" + rdfNS + "nil>"
+	for i := len(items) - 1; i >= 0; i-- {
+		node := newAnonNode()
+		p.emit(node, "<"+rdfNS+"first>", items[i])
+		p.emit(node, "<"+rdfNS+"rest>", head)
+		head = node
+	}
+	return head, nil
+}
+
+func (p *turtleParser) literal() (string, error) {
+	q, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	long := p.peekByte(0) == byte(q) && p.peekByte(1) == byte(q)
+	if long {
+		if _, err := p.r.Discard(2); err != nil {
+			return "", err
+		}
+	}
+
+	var sb strings.Builder
+	for {
+		r, err := p.next()
+		if err != nil {
+			return "", err
+		}
+		if r == q {
+			if !long {
+				break
+			}
+			// Closing quotes may follow quotes which are part of the string.
+			if p.peekByte(0) == byte(q) && p.peekByte(1) == byte(q) && p.peekByte(2) != byte(q) {
+				if _, err := p.r.Discard(2); err != nil {
+					return "", err
+				}
+				break
+			}
+		}
+		switch {
+		case r == '\\':
+			e, err := p.echar()
+			if err != nil {
+				return "", err
+			}
+			r = e
+		case !long && (r == '\n' || r == '\r'):
+			return "", p.errorf("line break in a short string")
+		}
+		sb.WriteRune(r)
+	}
+
+	lit := quoteLiteral(sb.String())
+	switch p.peek() {
+	case '@':
+		if _, err := p.next(); err != nil {
+			return "", err
+		}
+		lang := p.readLang()
+		if lang == "" {
+			return "", p.errorf("invalid language tag")
+		}
+		return lit + "@" + lang, nil
+	case '^':
+		if p.peekByte(1) != '^' {
+			return "", p.errorf("expected '^^' after a literal")
+		}
+		if _, err := p.r.Discard(2); err != nil {
+			return "", err
+		}
+		dt, keyword, err := p.term()
+		switch {
+		case err != nil:
+			return "", err
+		case keyword != "" || !strings.HasPrefix(dt, "<"):
+			return "", p.errorf("a datatype must be an IRI")
+		}
+		return lit + "^^" + dt, nil
+	}
+	return lit, nil
+}
+
+// echar reads the escape sequence of a string after its backslash.
+func (p *turtleParser) echar() (rune, error) {
+	r := p.peek()
+	if r == 'u' || r == 'U' {
+		return p.uchar()
+	}
+	if _, err := p.next(); err != nil {
+		return 0, err
+	}
+	switch r {
+	case 't':
+		return '\t', nil
+	case 'b':
+		return '\b', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 'f':
+		return '\f', nil
+	case '"', '\'', '\\':
+		return r, nil
+	}
+	return 0, p.errorf("invalid escape sequence \\%c", r)
+}
+
+// number reads a numeric literal and returns it with its XML Schema datatype.
+func (p *turtleParser) number() (string, error) {
+	var sb strings.Builder
+	digits := func() int {
+		n := 0
+		for r := p.peek(); r >= '0' && r <= '9'; r = p.peek() {
+			r, _ = p.next()
+			sb.WriteRune(r)
+			n++
+		}
+		return n
+	}
+
+	if r := p.peek(); r == '+' || r == '-' {
+		r, _ = p.next()
+		sb.WriteRune(r)
+	}
+	typ := "integer"
+	n := digits()
+	// A dot is only part of the number if a digit follows it. Otherwise it ends the statement.
+	if p.peek() == '.' && p.peekByte(1) >= '0' && p.peekByte(1) <= '9' {
+		r, _ := p.next()
+		sb.WriteRune(r)
+		typ = "decimal"
+		n += digits()
+	}
+	if n == 0 {
+		return "", p.errorf("invalid number %q", sb.String())
+	}
+	if r := p.peek(); r == 'e' || r == 'E' {
+		r, _ = p.next()
+		sb.WriteRune(r)
+		if r := p.peek(); r == '+' || r == '-' {
+			r, _ = p.next()
+			sb.WriteRune(r)
+		}
+		if digits() == 0 {
+			return "", p.errorf("invalid number %q", sb.String())
+		}
+		typ = "double"
+	}
+	return `"` + sb.String() + `"^^<` + xsdNS + typ + ">", nil
+}
+
+// readPrefix reads the prefix of a prefixed name, or a keyword, up to the next character
+// which can't be part of it.
+func (p *turtleParser) readPrefix() string {
+	var sb strings.Builder
+	if r := p.peek(); !isPnCharsBase(r) {
+		return ""
+	}
+	for r := p.peek(); r != ':' && p.nameGoesOn(r); r = p.peek() {
+		r, _ = p.next()
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// prefixedName reads the local part of a prefixed name after its ':' and returns the full IRI.
+func (p *turtleParser) prefixedName(prefix string) (string, error) {
+	ns, ok := p.prefixes[prefix]
+	if !ok {
+		return "", p.errorf("undefined prefix %q", prefix)
+	}
+
+	var sb strings.Builder
+	for {
+		r := p.peek()
+		switch {
+		case r == '\\':
+			if _, err := p.next(); err != nil {
+				return "", err
+			}
+			e, err := p.next()
+			if err != nil {
+				return "", err
+			}
+			if !strings.ContainsRune("_~.-!$&'()*+,;=/?#@%", e) {
+				return "", p.errorf("invalid escape sequence \\%c in a local name", e)
+			}
+			sb.WriteRune(e)
+			continue
+		case r == '.' && isNameByte(p.peekByte(1)):
+		case r == ':' || r == '%' || isPNChar(r):
+			if sb.Len() == 0 && r == '-' {
+				return "", p.errorf("a local name can't start with '-'")
+			}
+		default:
+			return "<" + ns + sb.String() + ">", nil
+		}
+		r, err := p.next()
+		if err != nil {
+			return "", err
+		}
+		sb.WriteRune(r)
+	}
+}
+
+func (p *turtleParser) readWord() string {
+	var sb strings.Builder
+	for r := p.peek(); (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'); r = p.peek() {
+		r, _ = p.next()
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func (p *turtleParser) readLang() string {
+	var sb strings.Builder
+	for r := p.peek(); isLangTag(r); r = p.peek() {
+		r, _ = p.next()
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// nameGoesOn returns whether the next rune r is part of the prefix or blank node label being
+// read. A dot is only part of it if the name goes on after it.
+func (p *turtleParser) nameGoesOn(r rune) bool {
+	return isPNChar(r) || (r == '.' && isNameByte(p.peekByte(1)))
+}
+
+// isNameByte returns whether a name can go on with the byte b after a dot.
+func isNameByte(b byte) bool {
+	return b >= utf8.RuneSelf || b == '_' || b == '-' || b == ':' || b == '%' || b == '\\' ||
+		(b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// isIRIChar returns whether r is allowed in an N-Triples IRI reference.
+func isIRIChar(r rune) bool {
+	if r <= 0x20 {
+		return false
+	}
+	switch r {
+	case '<', '>', '"', '{', '}', '|', '^', '`', '\\':
+		return false
+	}
+	return true
+}
+
+// quoteLiteral returns s as an N-Triples string, escaping the characters which can't appear
+// in it as they are.
+func quoteLiteral(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s) + 2)
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"':
+			sb.WriteString(`\"`)
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&sb, `\u%04X`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chunker
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func chunkTurtle(t *testing.T, ttl string) (string, error) {
+	anonPrefix, anonCount = "_:anon", 0
+	ck := NewChunker(TurtleFormat, 1000)
+	r := bufioReader(ttl)
+	var out bytes.Buffer
+	for {
+		chunkBuf, err := ck.Chunk(r)
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		out.Write(chunkBuf.Bytes())
+		// Every chunk must be valid N-Quads.
+		require.NoError(t, ck.Parse(chunkBuf))
+		if err == io.EOF {
+			return out.String(), nil
+		}
+	}
+}
+
+func TestTurtle(t *testing.T) {
+	ttl := `@base <http://example.org/> .
+@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+PREFIX : <http://example.org/people#>
+
+# Comments are skipped.
+:alice a foaf:Person ;
+	foaf:name "Alice"@en, 'Alicia'@es ;
+	foaf:age 42 ;
+	foaf:height 1.68 ;
+	foaf:score -1.5e3 ;
+	foaf:member true ;
+	foaf:knows [ foaf:name """Bob
+"the builder\"""" ] ;
+	foaf:interest ( <#go> :rdf.x ) ;
+	foaf:page <alice.html> ;
+	.
+_:c.1 foaf:nick "c"^^<http://www.w3.org/2001/XMLSchema#string> .
+[ foaf:name "anoné\t" ] .
+`
+	got, err := chunkTurtle(t, ttl)
+	require.NoError(t, err)
+
+	want := `<http://example.org/people#alice> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://xmlns.com/foaf/0.1/Person> .
+<http://example.org/people#alice> <http://xmlns.com/foaf/0.1/name> "Alice"@en .
+<http://example.org/people#alice> <http://xmlns.com/foaf/0.1/name> "Alicia"@es .
+<http://example.org/people#alice> <http://xmlns.com/foaf/0.1/age> "42"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/people#alice> <http://xmlns.com/foaf/0.1/height> "1.68"^^<http://www.w3.org/2001/XMLSchema#decimal> .
+<http://example.org/people#alice> <http://xmlns.com/foaf/0.1/score> "-1.5e3"^^<http://www.w3.org/2001/XMLSchema#double> .
+<http://example.org/people#alice> <http://xmlns.com/foaf/0.1/member> "true"^^<http://www.w3.org/2001/XMLSchema#boolean> .
+_:anon1 <http://xmlns.com/foaf/0.1/name> "Bob\n\"the builder\"" .
+<http://example.org/people#alice> <http://xmlns.com/foaf/0.1/knows> _:anon1 .
+_:anon2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://example.org/people#rdf.x> .
+_:anon2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+_:anon3 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://example.org/#go> .
+_:anon3 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:anon2 .
+<http://example.org/people#alice> <http://xmlns.com/foaf/0.1/interest> _:anon3 .
+<http://example.org/people#alice> <http://xmlns.com/foaf/0.1/page> <http://example.org/alice.html> .
+_:c.1 <http://xmlns.com/foaf/0.1/nick> "c"^^<http://www.w3.org/2001/XMLSchema#string> .
+_:anon4 <http://xmlns.com/foaf/0.1/name> "anoné\t" .
+`
+	require.Equal(t, want, got)
+}
+
+func TestTurtleErrors(t *testing.T) {
+	tests := []struct {
+		ttl string
+		err string
+	}{
+		{`ex:a ex:b ex:c .`, `undefined prefix "ex"`},
+		{`<a> <b> "c"`, "expected '.'"},
+		{"<a> <b> \"c\nd\" .", "line break in a short string"},
+		{`"a" <b> <c> .`, "a literal can't be the subject"},
+		{`<a> "b" <c> .`, "a predicate must be an IRI"},
+		{`<a b> <b> <c> .`, "invalid character"},
+		{`@foo <a> .`, "unknown directive @foo"},
+		{"<a> <b> <c> .\n<a> <b> [ <c> <d> .", "turtle: line 2: expected ']'"},
+	}
+	for _, test := range tests {
+		_, err := chunkTurtle(t, test.ttl)
+		require.Error(t, err, test.ttl)
+		require.Contains(t, err.Error(), test.err, test.ttl)
+	}
+}
+
+func TestTurtleChunks(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("@prefix ex: <http://example.org/> .\n")
+	for i := 0; i < 1e5+10; i++ {
+		sb.WriteString("ex:a ex:b ex:c .\n")
+	}
+	ck := NewChunker(TurtleFormat, 1000)
+	r := bufioReader(sb.String())
+
+	chunkBuf, err := ck.Chunk(r)
+	require.NoError(t, err)
+	require.Equal(t, int(1e5), strings.Count(chunkBuf.String(), "\n"))
+	// The prefixes declared in the first chunk still apply in the next ones.
+	chunkBuf, err = ck.Chunk(r)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 10, strings.Count(chunkBuf.String(), "<http://example.org/a>"))
+}
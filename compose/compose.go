@@ -249,6 +249,9 @@ func getZero(idx int, raft string) service {
 	} else {
 		svc.Command += fmt.Sprintf(" --peer=%s:%d", name(basename, 1), basePort)
 	}
+	if opts.WhiteList {
+		svc.Command += " --whitelist=10.0.0.0/8,172.16.0.0/12,192.168.0.0/16"
+	}
 	if len(opts.MemLimit) > 0 {
 		svc.Deploy.Resources = res{
 			Limits: limit{Memory: opts.MemLimit},
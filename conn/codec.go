@@ -0,0 +1,100 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conn
+
+import (
+	"io"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/encoding"
+)
+
+// codec is the gRPC codec used for all the connections. It's the default proto codec, except
+// that the data of the KVS messages streamed during snapshots and predicate moves isn't copied
+// on receive, but points into the buffer the message was read into. gRPC allocates a new buffer
+// for every message it receives, so the data stays valid after the next message is received.
+type codec struct {
+	encoding.Codec
+}
+
+func (c codec) Unmarshal(data []byte, v interface{}) error {
+	if kvs, ok := v.(*pb.KVS); ok {
+		return unmarshalKVS(data, kvs)
+	}
+	return c.Codec.Unmarshal(data, v)
+}
+
+// unmarshalKVS unmarshals data into kvs, with kvs.Data pointing into data.
+func unmarshalKVS(data []byte, kvs *pb.KVS) error {
+	kvs.Reset()
+
+	// rest has the fields other than the data, which are small and unmarshalled as usual.
+	var rest []byte
+	var kvData []byte
+	for i := 0; i < len(data); {
+		start := i
+		tag, n := proto.DecodeVarint(data[i:])
+		if n == 0 {
+			return io.ErrUnexpectedEOF
+		}
+		i += n
+
+		var end int
+		switch wire := tag & 7; wire {
+		case proto.WireVarint:
+			if _, n = proto.DecodeVarint(data[i:]); n == 0 {
+				return io.ErrUnexpectedEOF
+			}
+			end = i + n
+		case proto.WireFixed64:
+			end = i + 8
+		case proto.WireFixed32:
+			end = i + 4
+		case proto.WireBytes:
+			l, n := proto.DecodeVarint(data[i:])
+			if n == 0 || l > uint64(len(data)) {
+				return io.ErrUnexpectedEOF
+			}
+			i += n
+			end = i + int(l)
+		default:
+			return errors.Errorf("unexpected wire type %d while unmarshalling KVS", wire)
+		}
+		if end > len(data) {
+			return io.ErrUnexpectedEOF
+		}
+
+		if tag == 5<<3|proto.WireBytes {
+			kvData = data[i:end:end]
+		} else {
+			rest = append(rest, data[start:end]...)
+		}
+		i = end
+	}
+
+	if err := kvs.Unmarshal(rest); err != nil {
+		return err
+	}
+	kvs.Data = kvData
+	return nil
+}
+
+func init() {
+	encoding.RegisterCodec(codec{encoding.GetCodec("proto")})
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conn
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/encoding"
+)
+
+func TestCodecKVS(t *testing.T) {
+	c := encoding.GetCodec("proto")
+	require.IsType(t, codec{}, c)
+
+	in := &pb.KVS{
+		Data:       []byte("some key-values"),
+		Done:       true,
+		Predicates: []string{"name", "age"},
+		Types:      []string{"Person"},
+	}
+	data, err := c.Marshal(in)
+	require.NoError(t, err)
+
+	out := &pb.KVS{Data: []byte("stale")}
+	require.NoError(t, c.Unmarshal(data, out))
+	require.Equal(t, in, out)
+	// The data points into the buffer instead of being copied.
+	data[len(data)-1] = 'S'
+	require.Equal(t, "some key-valueS", string(out.Data))
+
+	require.Error(t, c.Unmarshal(data[:len(data)-1], out))
+
+	// Other messages are unmarshalled as usual.
+	snap := &pb.Snapshot{Index: 10, ReadTs: 5}
+	data, err = c.Marshal(snap)
+	require.NoError(t, err)
+	got := &pb.Snapshot{}
+	require.NoError(t, c.Unmarshal(data, got))
+	require.Equal(t, snap, got)
+}
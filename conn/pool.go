@@ -19,7 +19,9 @@ package conn
 import (
 	"context"
 	"crypto/tls"
+	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dgraph-io/dgo/v200/protos/api"
@@ -28,10 +30,23 @@ import (
 	"github.com/dgraph-io/ristretto/z"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
 	"go.opencensus.io/plugin/ocgrpc"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// PoolDefaults are the default values for the --pool superflag.
+	PoolDefaults = "connections=1; max-msg-size-mb=0; keepalive-time=0s; keepalive-timeout=20s; " +
+		"breaker-failures=0; breaker-cooldown=10s"
+
+	// minKeepaliveTime is the minimum interval between keep-alive pings allowed by the servers.
+	minKeepaliveTime = 10 * time.Second
 )
 
 var (
@@ -39,21 +54,122 @@ var (
 	ErrNoConnection = errors.New("No connection exists")
 	// ErrUnhealthyConnection indicates the connection to a node is unhealthy.
 	ErrUnhealthyConnection = errors.New("Unhealthy connection")
-	echoDuration           = 500 * time.Millisecond
+	// ErrCircuitOpen indicates calls to a node are being refused because of repeated failures.
+	ErrCircuitOpen = status.Error(codes.Unavailable,
+		"Circuit to the node is open because of repeated failures")
+	echoDuration = 500 * time.Millisecond
 )
 
+// PoolConfig is the configuration of the pools of connections to other nodes.
+type PoolConfig struct {
+	// Connections is the number of gRPC connections to each node. Calls are distributed over
+	// them in a round-robin manner.
+	Connections int
+	// MaxMsgSize is the maximum size of the messages sent and received over the connections.
+	MaxMsgSize int
+	// KeepaliveTime is the interval at which keep-alive pings are sent on idle connections, and
+	// KeepaliveTimeout how long to wait for their acknowledgement before closing the connection.
+	// Zero disables keep-alive pings.
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+	// BreakerFailures is the number of consecutive failed calls to a node after which calls to
+	// it are refused for BreakerCooldown. Zero disables circuit breaking.
+	BreakerFailures int
+	BreakerCooldown time.Duration
+}
+
+var poolConfig = PoolConfig{
+	Connections:      1,
+	MaxMsgSize:       x.GrpcMaxSize,
+	KeepaliveTimeout: 20 * time.Second,
+	BreakerCooldown:  10 * time.Second,
+}
+
+// RegisterPoolFlag registers the --pool superflag used to configure the pools of connections to
+// other nodes.
+func RegisterPoolFlag(flag *pflag.FlagSet) {
+	flag.String("pool", PoolDefaults,
+		`Options for the gRPC connections to the other nodes of the cluster.
+	connections=N is the number of connections to each node. Calls are distributed over them
+		in a round-robin manner.
+	max-msg-size-mb=N is the maximum size of the messages sent and received over the
+		connections. Zero means the maximum allowed by gRPC.
+	keepalive-time=D is the interval at which keep-alive pings are sent on idle connections. It
+		must be at least 10s. Zero disables keep-alive pings.
+	keepalive-timeout=D is how long to wait for a keep-alive ping to be acknowledged before
+		closing the connection.
+	breaker-failures=N refuses calls to a node for breaker-cooldown after N consecutive calls
+		to it failed because it was unavailable. Zero disables circuit breaking.
+	`)
+}
+
+// SetPoolConfig parses the --pool superflag and uses it for the pools created afterwards. It
+// should be called before connecting to any node.
+func SetPoolConfig(sf *z.SuperFlag) error {
+	c := PoolConfig{
+		Connections:     int(sf.GetInt64("connections")),
+		MaxMsgSize:      int(sf.GetInt64("max-msg-size-mb") << 20),
+		BreakerFailures: int(sf.GetInt64("breaker-failures")),
+	}
+	var err error
+	if c.KeepaliveTime, err = time.ParseDuration(sf.GetString("keepalive-time")); err != nil {
+		return errors.Wrapf(err, "while parsing keepalive-time")
+	}
+	if c.KeepaliveTimeout, err = time.ParseDuration(sf.GetString("keepalive-timeout")); err != nil {
+		return errors.Wrapf(err, "while parsing keepalive-timeout")
+	}
+	if c.BreakerCooldown, err = time.ParseDuration(sf.GetString("breaker-cooldown")); err != nil {
+		return errors.Wrapf(err, "while parsing breaker-cooldown")
+	}
+	switch {
+	case c.Connections < 1:
+		return errors.Errorf("connections must be at least 1")
+	case c.MaxMsgSize < 0 || c.MaxMsgSize > x.GrpcMaxSize:
+		return errors.Errorf("max-msg-size-mb must be in the range [0, %d]", x.GrpcMaxSize>>20)
+	case c.KeepaliveTime != 0 && c.KeepaliveTime < minKeepaliveTime:
+		return errors.Errorf("keepalive-time must be zero or at least %s", minKeepaliveTime)
+	case c.KeepaliveTimeout <= 0:
+		return errors.Errorf("keepalive-timeout must be positive")
+	case c.BreakerFailures < 0:
+		return errors.Errorf("breaker-failures must be non-negative")
+	case c.BreakerCooldown <= 0:
+		return errors.Errorf("breaker-cooldown must be positive")
+	}
+	if c.MaxMsgSize == 0 {
+		c.MaxMsgSize = x.GrpcMaxSize
+	}
+	poolConfig = c
+	return nil
+}
+
+// KeepaliveEnforcement returns the server option which allows the keep-alive pings sent by the
+// pools of the other nodes.
+func KeepaliveEnforcement() grpc.ServerOption {
+	return grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+		MinTime:             minKeepaliveTime,
+		PermitWithoutStream: true,
+	})
+}
+
 // Pool is used to manage the grpc client connection(s) for communicating with other
-// worker instances.  Right now it just holds one of them.
+// worker instances.
 type Pool struct {
 	sync.RWMutex
-	// A pool now consists of one connection. gRPC uses HTTP2 transport to combine
-	// messages in the same TCP stream.
-	conn *grpc.ClientConn
+	// gRPC uses HTTP2 transport to combine messages in the same TCP stream. A single stream
+	// can become a bottleneck on fast links, so a pool can have multiple connections which
+	// are used in a round-robin manner. The first one is also used for the heartbeats.
+	conns []*grpc.ClientConn
+	next  uint32
 
 	lastEcho   time.Time
 	Addr       string
 	closer     *z.Closer
 	healthInfo pb.HealthInfo
+
+	// failures is the number of consecutive calls which failed because the node was
+	// unavailable, and openUntil the time in unix nanoseconds until which calls are refused.
+	failures  int32
+	openUntil int64
 }
 
 // Pools manages a concurrency-safe set of Pool.
@@ -165,15 +281,27 @@ func (p *Pools) Connect(addr string, tlsClientConf *tls.Config) *Pool {
 
 }
 
-// newPool creates a new "pool" with one gRPC connection, refcount 0.
+// newPool creates a new "pool" with the number of gRPC connections set in the pool config.
 func newPool(addr string, tlsClientConf *tls.Config) (*Pool, error) {
+	cfg := poolConfig
+	pl := &Pool{Addr: addr, lastEcho: time.Now(), closer: z.NewCloser(1)}
+
 	conOpts := []grpc.DialOption{
 		grpc.WithStatsHandler(&ocgrpc.ClientHandler{}),
 		grpc.WithDefaultCallOptions(
-			grpc.MaxCallRecvMsgSize(x.GrpcMaxSize),
-			grpc.MaxCallSendMsgSize(x.GrpcMaxSize),
+			grpc.MaxCallRecvMsgSize(cfg.MaxMsgSize),
+			grpc.MaxCallSendMsgSize(cfg.MaxMsgSize),
 			grpc.UseCompressor((snappyCompressor{}).Name())),
 		grpc.WithBackoffMaxDelay(time.Second),
+		grpc.WithUnaryInterceptor(pl.breakerInterceptor),
+		grpc.WithStreamInterceptor(pl.breakerStreamInterceptor),
+	}
+	if cfg.KeepaliveTime > 0 {
+		conOpts = append(conOpts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                cfg.KeepaliveTime,
+			Timeout:             cfg.KeepaliveTimeout,
+			PermitWithoutStream: true,
+		}))
 	}
 
 	if tlsClientConf != nil {
@@ -182,13 +310,16 @@ func newPool(addr string, tlsClientConf *tls.Config) (*Pool, error) {
 		conOpts = append(conOpts, grpc.WithInsecure())
 	}
 
-	conn, err := grpc.Dial(addr, conOpts...)
-	if err != nil {
-		glog.Errorf("unable to connect with %s : %s", addr, err)
-		return nil, err
+	for i := 0; i < cfg.Connections; i++ {
+		conn, err := grpc.Dial(addr, conOpts...)
+		if err != nil {
+			glog.Errorf("unable to connect with %s : %s", addr, err)
+			pl.closeConns()
+			return nil, err
+		}
+		pl.conns = append(pl.conns, conn)
 	}
 
-	pl := &Pool{conn: conn, Addr: addr, lastEcho: time.Now(), closer: z.NewCloser(1)}
 	go pl.MonitorHealth()
 	return pl, nil
 }
@@ -197,15 +328,90 @@ func newPool(addr string, tlsClientConf *tls.Config) (*Pool, error) {
 func (p *Pool) Get() *grpc.ClientConn {
 	p.RLock()
 	defer p.RUnlock()
-	return p.conn
+	if len(p.conns) == 1 {
+		return p.conns[0]
+	}
+	return p.conns[atomic.AddUint32(&p.next, 1)%uint32(len(p.conns))]
 }
 
 func (p *Pool) shutdown() {
 	glog.Warningf("Shutting down extra connection to %s", p.Addr)
 	p.closer.SignalAndWait()
-	if err := p.conn.Close(); err != nil {
-		glog.Warningf("Could not close pool connection with error: %s", err)
+	p.closeConns()
+}
+
+func (p *Pool) closeConns() {
+	for _, conn := range p.conns {
+		if err := conn.Close(); err != nil {
+			glog.Warningf("Could not close pool connection with error: %s", err)
+		}
+	}
+}
+
+// breakerInterceptor refuses calls while the circuit to the node is open, and keeps track of
+// the consecutive calls which failed because the node was unavailable.
+func (p *Pool) breakerInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if p.circuitOpen() {
+		return ErrCircuitOpen
+	}
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	p.recordCall(err)
+	return err
+}
+
+// breakerStreamInterceptor is like breakerInterceptor, for the streaming calls. The stream
+// fails if it can't be opened, or if it can't be read from later on.
+func (p *Pool) breakerStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc,
+	cc *grpc.ClientConn, method string, streamer grpc.Streamer,
+	opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if p.circuitOpen() {
+		return nil, ErrCircuitOpen
 	}
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	p.recordCall(err)
+	if err != nil {
+		return nil, err
+	}
+	return &breakerStream{ClientStream: stream, pool: p}, nil
+}
+
+// breakerStream records the failures of a stream which has been opened.
+type breakerStream struct {
+	grpc.ClientStream
+	pool *Pool
+}
+
+func (s *breakerStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil && err != io.EOF {
+		s.pool.recordCall(err)
+	}
+	return err
+}
+
+func (p *Pool) recordCall(err error) {
+	threshold := int32(poolConfig.BreakerFailures)
+	if threshold == 0 {
+		return
+	}
+	if status.Code(err) != codes.Unavailable {
+		atomic.StoreInt32(&p.failures, 0)
+		return
+	}
+	// Once the cooldown is over, a single failure opens the circuit again.
+	if failures := atomic.AddInt32(&p.failures, 1); failures >= threshold {
+		if failures == threshold {
+			glog.Warningf("Refusing calls to %s for %s after %d consecutive failures",
+				p.Addr, poolConfig.BreakerCooldown, failures)
+		}
+		atomic.StoreInt64(&p.openUntil, time.Now().Add(poolConfig.BreakerCooldown).UnixNano())
+	}
+}
+
+// circuitOpen returns whether calls to the node are being refused.
+func (p *Pool) circuitOpen() bool {
+	return time.Now().UnixNano() < atomic.LoadInt64(&p.openUntil)
 }
 
 // SetUnhealthy marks a pool as unhealthy.
@@ -216,8 +422,7 @@ func (p *Pool) SetUnhealthy() {
 }
 
 func (p *Pool) listenToHeartbeat() error {
-	conn := p.Get()
-	c := pb.NewRaftClient(conn)
+	c := pb.NewRaftClient(p.conns[0])
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -278,6 +483,9 @@ func (p *Pool) IsHealthy() bool {
 	if p == nil {
 		return false
 	}
+	if p.circuitOpen() {
+		return false
+	}
 	p.RLock()
 	defer p.RUnlock()
 	return time.Since(p.lastEcho) < 4*echoDuration
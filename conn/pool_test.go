@@ -0,0 +1,116 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSetPoolConfig(t *testing.T) {
+	defer func(c PoolConfig) { poolConfig = c }(poolConfig)
+
+	sf := z.NewSuperFlag("connections=4; keepalive-time=30s; breaker-failures=3").
+		MergeAndCheckDefault(PoolDefaults)
+	require.NoError(t, SetPoolConfig(sf))
+	require.Equal(t, PoolConfig{
+		Connections:      4,
+		MaxMsgSize:       x.GrpcMaxSize,
+		KeepaliveTime:    30 * time.Second,
+		KeepaliveTimeout: 20 * time.Second,
+		BreakerFailures:  3,
+		BreakerCooldown:  10 * time.Second,
+	}, poolConfig)
+
+	for _, flag := range []string{"connections=0", "keepalive-time=1s", "breaker-failures=-1"} {
+		sf := z.NewSuperFlag(flag).MergeAndCheckDefault(PoolDefaults)
+		require.Error(t, SetPoolConfig(sf), flag)
+	}
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	defer func(c PoolConfig) { poolConfig = c }(poolConfig)
+	poolConfig.Connections = 3
+
+	p, err := newPool("localhost:1", nil)
+	require.NoError(t, err)
+	defer p.shutdown()
+	require.Len(t, p.conns, 3)
+
+	seen := make(map[interface{}]bool)
+	for i := 0; i < 3; i++ {
+		seen[p.Get()] = true
+	}
+	require.Len(t, seen, 3)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	defer func(c PoolConfig) { poolConfig = c }(poolConfig)
+	poolConfig.BreakerFailures = 2
+	poolConfig.BreakerCooldown = 50 * time.Millisecond
+
+	p := &Pool{Addr: "localhost:1", lastEcho: time.Now()}
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	p.recordCall(unavailable)
+	require.False(t, p.circuitOpen())
+	p.recordCall(nil)
+	p.recordCall(unavailable)
+	require.False(t, p.circuitOpen())
+	p.recordCall(unavailable)
+	require.True(t, p.circuitOpen())
+	require.False(t, p.IsHealthy())
+
+	time.Sleep(60 * time.Millisecond)
+	require.False(t, p.circuitOpen())
+	require.True(t, p.IsHealthy())
+	// A single failure after the cooldown opens the circuit again.
+	p.recordCall(unavailable)
+	require.True(t, p.circuitOpen())
+}
+
+func TestCircuitBreakerStream(t *testing.T) {
+	defer func(c PoolConfig) { poolConfig = c }(poolConfig)
+	poolConfig.BreakerFailures = 1
+	poolConfig.BreakerCooldown = time.Minute
+
+	p := &Pool{Addr: "localhost:1", lastEcho: time.Now()}
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	var opened int
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+		method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		opened++
+		return nil, unavailable
+	}
+
+	_, err := p.breakerStreamInterceptor(context.Background(), &grpc.StreamDesc{}, nil,
+		"/pb.Worker/StreamSnapshot", streamer)
+	require.Equal(t, unavailable, err)
+	require.True(t, p.circuitOpen())
+	_, err = p.breakerStreamInterceptor(context.Background(), &grpc.StreamDesc{}, nil,
+		"/pb.Worker/StreamSnapshot", streamer)
+	require.Equal(t, ErrCircuitOpen, err)
+	require.Equal(t, 1, opened)
+}
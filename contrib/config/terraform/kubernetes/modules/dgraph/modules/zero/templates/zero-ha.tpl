@@ -6,9 +6,9 @@ ordinal=$${BASH_REMATCH[1]}
 idx=$(($ordinal + 1))
 
 if [[ $ordinal -eq 0 ]]; then
-  exec dgraph zero --my=$(hostname -f):5080 --raft="idx=$idx" --replicas ${replicas}
+  exec dgraph zero --my=$(hostname -f):5080 --raft="idx=$idx" --replicas ${replicas} --whitelist=10.0.0.0/8,172.16.0.0/12,192.168.0.0/16
 else
   exec dgraph zero --my=$(hostname -f):5080 --peer
   ${prefix}-dgraph-zero-0.${prefix}-dgraph-zero.${namespace}.svc.cluster.local:5080
-  --raft="idx=$idx" --replicas ${replicas}
+  --raft="idx=$idx" --replicas ${replicas} --whitelist=10.0.0.0/8,172.16.0.0/12,192.168.0.0/16
 fi
@@ -0,0 +1,38 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package main is the HTTP/3 plugin of Alpha, see the --http3 flag. It's a module of its own, so
+// that quic-go and the Go version it needs don't affect the Dgraph module.
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// Serve serves the handler over HTTP/3 on the UDP connection until closed is closed.
+func Serve(conn net.PacketConn, tlsCfg *tls.Config, handler http.Handler,
+	closed <-chan struct{}) error {
+	srv := &http3.Server{TLSConfig: tlsCfg, Handler: handler}
+	go func() {
+		<-closed
+		_ = srv.Close()
+	}()
+	return srv.Serve(conn)
+}
@@ -336,7 +336,7 @@ func grpcConnection(one string) (*grpc.ClientConn, error) {
 		one,
 		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
 			RootCAs:    pool,
-			ServerName: strings.Split(one, ":")[0],
+			ServerName: x.HostFromAddress(one),
 		})),
 		grpc.WithPerRPCCredentials(&authorizationCredentials{*slashToken}),
 	)
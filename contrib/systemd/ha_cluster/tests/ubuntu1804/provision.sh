@@ -99,10 +99,10 @@ setup_systemd_zero() {
   IDX=$(( $(grep -o '[0-9]' <<< $HOSTNAME) + 1 ))
   if [[ $TYPE == "leader" ]]; then
     EXEC="/bin/bash -c '/usr/local/bin/dgraph zero --my=\$(hostname):5080 --wal $WAL
-    --raft="idx=$IDX" --replicas $REPLICAS'"
+    --raft="idx=$IDX" --replicas $REPLICAS --whitelist=10.0.0.0/8,172.16.0.0/12,192.168.0.0/16'"
   else
     EXEC="/bin/bash -c '/usr/local/bin/dgraph zero --my=\$(hostname):5080 --peer $LDR --wal $WAL
-    --raft="idx=$IDX" --replicas $REPLICAS'"
+    --raft="idx=$IDX" --replicas $REPLICAS --whitelist=10.0.0.0/8,172.16.0.0/12,192.168.0.0/16'"
   fi
 
   mkdir -p /var/{log/dgraph,lib/dgraph/zw}
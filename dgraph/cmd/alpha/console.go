@@ -0,0 +1,363 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alpha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/edgraph"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// consoleHandler serves the web console enabled by the --console flag. The page itself holds no
+// data: it calls /state, /query, /alter and /admin from the browser, with the ACL access token
+// and the auth token given by the user, so those endpoints check the user as they do for any
+// other client.
+func consoleHandler(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Cache-Control", "no-store")
+	h.Set("X-Frame-Options", "DENY")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Content-Security-Policy",
+		"default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; "+
+			"connect-src 'self'; frame-ancestors 'none'")
+	x.Check2(w.Write([]byte(consolePage)))
+}
+
+// consoleSchemaHandler serves the DQL schema edited in the console, as {"schema": text}. The
+// schema is read with a schema {} query run by query, with the credentials of the request, and
+// every directive of the predicates is kept, so that applying the text unchanged through /alter
+// leaves the schema as it is.
+func consoleSchemaHandler(
+	query func(context.Context, *api.Request) (*api.Response, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := x.AttachAccessJwt(r.Context(), r)
+		ctx = x.AttachRemoteIP(ctx, r)
+		resp, err := query(ctx, &api.Request{Query: "schema {}", ReadOnly: true})
+		if err != nil {
+			x.SetStatusWithData(w, x.ErrorInvalidRequest, err.Error())
+			return
+		}
+		text, err := dqlSchemaText(resp.GetJson())
+		if err != nil {
+			x.SetStatus(w, x.Error, err.Error())
+			return
+		}
+		js, err := json.Marshal(map[string]string{"schema": text})
+		if err != nil {
+			x.SetStatus(w, x.Error, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		x.Check2(w.Write(js))
+	}
+}
+
+// dqlSchemaText turns the JSON response of a schema {} query into the text of a DQL schema.
+func dqlSchemaText(js []byte) (string, error) {
+	var res struct {
+		Schema []*pb.SchemaNode `json:"schema"`
+		Types  []struct {
+			Name   string `json:"name"`
+			Fields []struct {
+				Name string `json:"name"`
+			} `json:"fields"`
+		} `json:"types"`
+	}
+	if len(js) > 0 {
+		if err := json.Unmarshal(js, &res); err != nil {
+			return "", err
+		}
+	}
+
+	var b strings.Builder
+	for _, node := range res.Schema {
+		b.WriteString(edgraph.SchemaNodeString(node.Predicate, node))
+		b.WriteString("\n")
+	}
+	for _, typ := range res.Types {
+		fmt.Fprintf(&b, "\ntype <%s> {\n", typ.Name)
+		for _, field := range typ.Fields {
+			fmt.Fprintf(&b, "\t<%s>\n", field.Name)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String(), nil
+}
+
+const consolePage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Dgraph Console</title>
+<style>
+body { font-family: sans-serif; margin: 0; }
+header { background: #222; color: #eee; padding: 8px 16px; display: flex; gap: 8px;
+  align-items: center; flex-wrap: wrap; }
+header input { width: 120px; }
+nav button.active { font-weight: bold; }
+main { padding: 16px; }
+section { display: none; }
+section.active { display: block; }
+textarea { width: 100%; height: 200px; font-family: monospace; }
+pre { background: #f4f4f4; padding: 8px; overflow: auto; max-height: 60vh; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+#status { margin-left: auto; }
+</style>
+</head>
+<body>
+<header>
+  <strong>Dgraph Console</strong>
+  <input id="user" placeholder="user">
+  <input id="password" type="password" placeholder="password">
+  <input id="namespace" placeholder="namespace" value="0">
+  <button onclick="login()">Log in</button>
+  <input id="authToken" type="password" placeholder="auth token">
+  <span id="status"></span>
+</header>
+<main>
+<nav>
+  <button data-tab="state" class="active">Cluster</button>
+  <button data-tab="schema">Schema</button>
+  <button data-tab="query">Query</button>
+  <button data-tab="tasks">Tasks</button>
+</nav>
+
+<section id="state" class="active">
+  <p><button onclick="loadState()">Refresh</button></p>
+  <div id="groups"></div>
+  <pre id="stateOut"></pre>
+</section>
+
+<section id="schema">
+  <p>
+    <button onclick="loadSchema()">Load</button>
+    <label><input type="radio" name="schemaKind" value="dql" checked> DQL</label>
+    <label><input type="radio" name="schemaKind" value="graphql"> GraphQL</label>
+    <button onclick="saveSchema()">Apply</button>
+  </p>
+  <textarea id="schemaIn"></textarea>
+  <pre id="schemaOut"></pre>
+</section>
+
+<section id="query">
+  <p>
+    <label><input type="radio" name="queryKind" value="dql" checked> DQL query</label>
+    <label><input type="radio" name="queryKind" value="mutate"> DQL mutation (RDF)</label>
+    <label><input type="radio" name="queryKind" value="graphql"> GraphQL</label>
+    <button onclick="runQuery()">Run</button>
+  </p>
+  <textarea id="queryIn">{
+  q(func: has(dgraph.type), first: 10) {
+    uid
+    dgraph.type
+  }
+}</textarea>
+  <pre id="queryOut"></pre>
+</section>
+
+<section id="tasks">
+  <p><button onclick="loadTasks()">Refresh</button></p>
+  <table id="tasksTable"></table>
+  <pre id="tasksOut"></pre>
+</section>
+</main>
+
+<script>
+var accessToken = sessionStorage.getItem("accessToken") || "";
+
+function $(id) { return document.getElementById(id); }
+
+function setStatus(msg) { $("status").textContent = msg; }
+
+function headers(contentType) {
+  var h = {"Content-Type": contentType};
+  if (accessToken) { h["X-Dgraph-AccessToken"] = accessToken; }
+  if ($("authToken").value) { h["X-Dgraph-AuthToken"] = $("authToken").value; }
+  return h;
+}
+
+function call(path, contentType, body) {
+  var opts = {method: body === undefined ? "GET" : "POST", headers: headers(contentType),
+    credentials: "same-origin"};
+  if (body !== undefined) { opts.body = body; }
+  return fetch(path, opts).then(function(resp) {
+    return resp.text().then(function(text) {
+      try { return JSON.parse(text); } catch (e) { return {errors: [{message: text}]}; }
+    });
+  });
+}
+
+function admin(query, variables) {
+  return call("/admin", "application/json",
+    JSON.stringify({query: query, variables: variables || {}}));
+}
+
+function show(id, res) { $(id).textContent = JSON.stringify(res, null, 2); }
+
+function el(tag, text) {
+  var e = document.createElement(tag);
+  if (text !== undefined && text !== null) { e.textContent = String(text); }
+  return e;
+}
+
+function login() {
+  admin("mutation($u: String, $p: String, $n: Int) { login(userId: $u, password: $p, " +
+    "namespace: $n) { response { accessJWT } } }",
+    {u: $("user").value, p: $("password").value, n: parseInt($("namespace").value, 10) || 0})
+    .then(function(res) {
+      if (res.errors) { setStatus("Login failed: " + res.errors[0].message); return; }
+      accessToken = res.data.login.response.accessJWT;
+      sessionStorage.setItem("accessToken", accessToken);
+      $("password").value = "";
+      setStatus("Logged in as " + $("user").value);
+    });
+}
+
+function loadState() {
+  call("/state").then(function(res) {
+    var groups = $("groups");
+    groups.textContent = "";
+    if (res.groups) {
+      var table = el("table");
+      var head = el("tr");
+      ["Group", "Member", "Address", "Leader", "Tablets"].forEach(function(h) {
+        head.appendChild(el("th", h));
+      });
+      table.appendChild(head);
+      Object.keys(res.groups).forEach(function(gid) {
+        var g = res.groups[gid];
+        Object.keys(g.members || {}).forEach(function(id) {
+          var m = g.members[id];
+          var row = el("tr");
+          [gid, id, m.addr, m.leader ? "yes" : "", Object.keys(g.tablets || {}).length]
+            .forEach(function(v) { row.appendChild(el("td", v)); });
+          table.appendChild(row);
+        });
+      });
+      groups.appendChild(table);
+    }
+    show("stateOut", res);
+  });
+}
+
+function kind(name) {
+  return document.querySelector("input[name=" + name + "]:checked").value;
+}
+
+function loadSchema() {
+  var done = function(text, res) {
+    $("schemaIn").value = text;
+    show("schemaOut", res);
+  };
+  if (kind("schemaKind") === "graphql") {
+    admin("{ getGQLSchema { schema } }").then(function(res) {
+      done(res.data && res.data.getGQLSchema ? res.data.getGQLSchema.schema : "", res);
+    });
+    return;
+  }
+  call("/ui/console/schema").then(function(res) {
+    done(res.schema || "", res);
+  });
+}
+
+function saveSchema() {
+  if (kind("schemaKind") === "graphql") {
+    admin("mutation($s: String!) { updateGQLSchema(input: {set: {schema: $s}}) " +
+      "{ gqlSchema { id } } }", {s: $("schemaIn").value})
+      .then(function(res) { show("schemaOut", res); });
+    return;
+  }
+  call("/alter", "application/dql", $("schemaIn").value)
+    .then(function(res) { show("schemaOut", res); });
+}
+
+function runQuery() {
+  var q = $("queryIn").value;
+  var res;
+  switch (kind("queryKind")) {
+  case "graphql":
+    res = call("/graphql", "application/json", JSON.stringify({query: q}));
+    break;
+  case "mutate":
+    res = call("/mutate?commitNow=true", "application/rdf", q);
+    break;
+  default:
+    res = call("/query", "application/dql", q);
+  }
+  res.then(function(r) { show("queryOut", r); });
+}
+
+function loadTasks() {
+  admin("{ tasks { id kind description groupId owner state progress message startedAt " +
+    "updatedAt pausable cancellable } }").then(function(res) {
+    var table = $("tasksTable");
+    table.textContent = "";
+    var cols = ["id", "kind", "description", "groupId", "owner", "state", "progress",
+      "startedAt", "updatedAt"];
+    var head = el("tr");
+    cols.concat([""]).forEach(function(c) { head.appendChild(el("th", c)); });
+    table.appendChild(head);
+    ((res.data && res.data.tasks) || []).forEach(function(t) {
+      var row = el("tr");
+      cols.forEach(function(c) { row.appendChild(el("td", t[c])); });
+      var actions = el("td");
+      var add = function(label, mutation) {
+        var b = el("button", label);
+        b.onclick = function() {
+          admin("mutation($id: String!) { " + mutation + "(id: $id) { task { state } } }",
+            {id: t.id}).then(function(r) { show("tasksOut", r); loadTasks(); });
+        };
+        actions.appendChild(b);
+      };
+      if (t.pausable) {
+        add("Pause", "pauseTask");
+        add("Resume", "resumeTask");
+      }
+      if (t.cancellable) { add("Cancel", "cancelTask"); }
+      row.appendChild(actions);
+      table.appendChild(row);
+    });
+    show("tasksOut", res);
+  });
+}
+
+document.querySelectorAll("nav button").forEach(function(b) {
+  b.onclick = function() {
+    document.querySelectorAll("nav button, section").forEach(function(e) {
+      e.classList.remove("active");
+    });
+    b.classList.add("active");
+    $(b.dataset.tab).classList.add("active");
+  };
+});
+
+if (accessToken) { setStatus("Using the access token of the last login"); }
+loadState();
+</script>
+</body>
+</html>
+`
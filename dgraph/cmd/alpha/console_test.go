@@ -0,0 +1,145 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alpha
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+// consoleTestAlpha holds the schema of a fake Alpha, which answers schema {} queries like Alpha
+// does and applies the schemas sent to /alter.
+type consoleTestAlpha struct {
+	schema *schema.ParsedSchema
+}
+
+func (a *consoleTestAlpha) query(ctx context.Context, req *api.Request) (*api.Response, error) {
+	var nodes []*pb.SchemaNode
+	for _, su := range a.schema.Preds {
+		node := &pb.SchemaNode{
+			Predicate:  x.ParseAttr(su.Predicate),
+			Type:       types.TypeID(su.ValueType).Name(),
+			List:       su.List,
+			Count:      su.Count,
+			Upsert:     su.Upsert,
+			Lang:       su.Lang,
+			NoConflict: su.NoConflict,
+			Presence:   su.Presence,
+			Collation:  su.Collation,
+			Reverse:    su.Directive == pb.SchemaUpdate_REVERSE,
+		}
+		if su.Directive == pb.SchemaUpdate_INDEX {
+			node.Index = true
+			node.Tokenizer = su.Tokenizer
+		}
+		nodes = append(nodes, node)
+	}
+	var typs []map[string]interface{}
+	for _, typ := range a.schema.Types {
+		var fields []map[string]string
+		for _, field := range typ.Fields {
+			fields = append(fields, map[string]string{"name": x.ParseAttr(field.Predicate)})
+		}
+		typs = append(typs, map[string]interface{}{
+			"name":   x.ParseAttr(typ.TypeName),
+			"fields": fields,
+		})
+	}
+	js, err := json.Marshal(map[string]interface{}{"schema": nodes, "types": typs})
+	return &api.Response{Json: js}, err
+}
+
+func (a *consoleTestAlpha) alter(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	parsed, err := schema.ParseWithNamespace(string(body), x.GalaxyNamespace)
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+	a.schema = parsed
+}
+
+func TestConsoleSchemaRoundTrip(t *testing.T) {
+	initial := `
+		name: string @index(exact, term) @lang @upsert .
+		nick: string @index(hash) @collate(de) @noconflict .
+		friend: [uid] @reverse @count .
+		age: int @index(int) @presence .
+		tags: [string] @count @noconflict .
+		type <Person> {
+			name
+			friend
+			<~friend>
+		}
+	`
+	parsed, err := schema.ParseWithNamespace(initial, x.GalaxyNamespace)
+	require.NoError(t, err)
+	alpha := &consoleTestAlpha{schema: parsed}
+
+	mux := http.NewServeMux()
+	mux.Handle("/ui/console/schema", consoleSchemaHandler(alpha.query))
+	mux.HandleFunc("/alter", alpha.alter)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	load := func() string {
+		resp, err := http.Get(srv.URL + "/ui/console/schema")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var res struct {
+			Schema string `json:"schema"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		return res.Schema
+	}
+	save := func(text string) {
+		resp, err := http.Post(srv.URL+"/alter", "application/dql", strings.NewReader(text))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Empty(t, string(body))
+	}
+
+	text := load()
+	for _, directive := range []string{"@index(exact, term)", "@lang", "@upsert",
+		"@index(hash)", "@collate(de)", "@noconflict", "@reverse", "@count", "@presence",
+		"<~friend>"} {
+		require.Contains(t, text, directive)
+	}
+
+	// Saving the loaded schema leaves it unchanged.
+	save(text)
+	require.Equal(t, parsed, alpha.schema)
+	require.Equal(t, text, load())
+}
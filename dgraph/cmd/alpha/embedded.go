@@ -0,0 +1,50 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alpha
+
+import (
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// embedding holds the state of an Alpha running in the process of an application.
+type embedding struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// RunEmbedded runs Alpha in this process with the configuration, which must hold all the flags
+// of the alpha command. It returns right away, x.HealthCheck tells when Alpha is ready. Alpha
+// doesn't handle the signals, it runs until stop is called. stop returns once Alpha is done.
+func RunEmbedded(conf *viper.Viper) (stop func()) {
+	Alpha.Conf = conf
+	emb := &embedding{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(emb.done)
+		run(emb)
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(emb.stop) })
+		<-emb.done
+	}
+}
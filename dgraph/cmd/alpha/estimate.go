@@ -0,0 +1,55 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alpha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/edgraph"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// isEstimate returns whether the request was sent to /query/estimate or /mutate/estimate, which
+// return the estimated cost of the query or of the mutations instead of running them.
+func isEstimate(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, "/estimate")
+}
+
+func writeCostEstimate(ctx context.Context, w http.ResponseWriter, r *http.Request,
+	req *api.Request) {
+	est, err := (&edgraph.Server{}).EstimateCost(ctx, req)
+	if err != nil {
+		x.SetStatusWithData(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+	js, err := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{
+			"code":    x.Success,
+			"message": "Done",
+			"cost":    est,
+		},
+	})
+	if err != nil {
+		x.SetStatusWithData(w, x.Error, err.Error())
+		return
+	}
+	_, _ = x.WriteResponse(w, r, js)
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alpha
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dgraph-io/dgraph/dql"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// formatHandler validates, lints and pretty-prints the query, mutation or upsert block in the
+// request body without running it. Problems found in it are returned as diagnostics, so the
+// request itself only fails if the body can't be read.
+func formatHandler(w http.ResponseWriter, r *http.Request) {
+	if commonHandler(w, r) {
+		return
+	}
+
+	query, ok := readQueryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	formatted, diags := dql.Format(query)
+	if diags == nil {
+		diags = []dql.Diagnostic{}
+	}
+	js, err := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{
+			"code":        x.Success,
+			"message":     "Done",
+			"formatted":   formatted,
+			"diagnostics": diags,
+		},
+	})
+	if err != nil {
+		x.SetStatusWithData(w, x.Error, err.Error())
+		return
+	}
+	_, _ = x.WriteResponse(w, r, js)
+}
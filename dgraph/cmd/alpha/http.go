@@ -70,6 +70,32 @@ func commonHandler(w http.ResponseWriter, r *http.Request) bool {
 	return false
 }
 
+// queryVariables converts the variables of a JSON query request to the string
+// values expected by api.Request. Non-string values, like the JSON arrays given
+// to list variables, are passed on as their JSON text.
+func queryVariables(raw map[string]json.RawMessage) (map[string]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	vars := make(map[string]string, len(raw))
+	for k, v := range raw {
+		v = bytes.TrimSpace(v)
+		switch {
+		case len(v) > 0 && v[0] == '"':
+			var s string
+			if err := json.Unmarshal(v, &s); err != nil {
+				return nil, errors.Wrapf(err, "while reading variable %s", k)
+			}
+			vars[k] = s
+		case string(v) == "null":
+			vars[k] = ""
+		default:
+			vars[k] = string(v)
+		}
+	}
+	return vars, nil
+}
+
 // Read request body, transparently decompressing if necessary. Return nil on error.
 func readRequest(w http.ResponseWriter, r *http.Request) []byte {
 	var in io.Reader = r.Body
@@ -175,8 +201,11 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var params struct {
-		Query     string            `json:"query"`
-		Variables map[string]string `json:"variables"`
+		Query     string                     `json:"query"`
+		Variables map[string]json.RawMessage `json:"variables"`
+		// Prepared is the id of a statement prepared with /query/prepare, to be executed
+		// instead of Query.
+		Prepared string `json:"prepared"`
 	}
 
 	contentType := r.Header.Get("Content-Type")
@@ -208,6 +237,19 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := context.WithValue(r.Context(), query.DebugKey, isDebugMode)
 	ctx = x.AttachAccessJwt(ctx, r)
 	ctx = x.AttachRemoteIP(ctx, r)
+	ctx = x.AttachPriority(ctx, r)
+
+	if params.Prepared != "" {
+		if params.Query != "" {
+			x.SetStatus(w, x.ErrorInvalidRequest,
+				"Only one of query and prepared can be given")
+			return
+		}
+		if params.Query, err = edgraph.PreparedQuery(ctx, params.Prepared); err != nil {
+			x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+			return
+		}
+	}
 
 	if queryTimeout != 0 {
 		var cancel context.CancelFunc
@@ -215,8 +257,13 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 		defer cancel()
 	}
 
+	vars, err := queryVariables(params.Variables)
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
 	req := api.Request{
-		Vars:    params.Variables,
+		Vars:    vars,
 		Query:   params.Query,
 		StartTs: startTs,
 	}
@@ -244,6 +291,11 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if isEstimate(r) {
+		writeCostEstimate(ctx, w, r, &req)
+		return
+	}
+
 	// Core processing happens here.
 	resp, err := (&edgraph.Server{}).Query(ctx, &req)
 	if err != nil {
@@ -258,6 +310,13 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 		Latency: resp.Latency,
 		Metrics: resp.Metrics,
 	}
+	if cursor := resp.Hdrs[query.CursorKey].GetValue(); len(cursor) > 0 {
+		e.Partial = true
+		e.Cursor = cursor[0]
+	}
+	if staleness := resp.Hdrs[query.StalenessKey].GetValue(); len(staleness) > 0 {
+		e.Staleness, _ = strconv.ParseUint(staleness[0], 10, 64)
+	}
 	js, err := json.Marshal(e)
 	if err != nil {
 		x.SetStatusWithData(w, x.Error, err.Error())
@@ -285,6 +344,75 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// readQueryRequest reads a query sent alone in the body, either as DQL or as the query field of
+// a JSON object. It returns false if the request couldn't be read, in which case the error has
+// already been written.
+func readQueryRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	body := readRequest(w, r)
+	if body == nil {
+		return "", false
+	}
+
+	var params struct {
+		Query string `json:"query"`
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, "Invalid Content-Type")
+		return "", false
+	}
+	switch mediaType {
+	case "application/json":
+		if err := json.Unmarshal(body, &params); err != nil {
+			jsonErr := convertJSONError(string(body), err)
+			x.SetStatus(w, x.ErrorInvalidRequest, jsonErr.Error())
+			return "", false
+		}
+	case "application/graphql+-", "application/dql":
+		params.Query = string(body)
+	default:
+		x.SetStatus(w, x.ErrorInvalidRequest, "Unsupported Content-Type. "+
+			"Supported content types are application/json, application/graphql+-,application/dql")
+		return "", false
+	}
+
+	return params.Query, true
+}
+
+// prepareHandler prepares the query in the request body, so that it can be executed many
+// times through /query with different variables without being parsed and validated again.
+// With ACL, the statement belongs to the namespace of the access JWT, which is required.
+func prepareHandler(w http.ResponseWriter, r *http.Request) {
+	if commonHandler(w, r) {
+		return
+	}
+
+	query, ok := readQueryRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := x.AttachAccessJwt(r.Context(), r)
+	id, err := (&edgraph.Server{}).Prepare(ctx, query)
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
+
+	js, err := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{
+			"code":    x.Success,
+			"message": "Done",
+			"id":      id,
+		},
+	})
+	if err != nil {
+		x.SetStatusWithData(w, x.Error, err.Error())
+		return
+	}
+	_, _ = x.WriteResponse(w, r, js)
+}
+
 func mutationHandler(w http.ResponseWriter, r *http.Request) {
 	if commonHandler(w, r) {
 		return
@@ -300,6 +428,11 @@ func mutationHandler(w http.ResponseWriter, r *http.Request) {
 		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
 		return
 	}
+	dryRun, err := parseBool(r, "dryRun")
+	if err != nil {
+		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
+		return
+	}
 	body := readRequest(w, r)
 	if body == nil {
 		return
@@ -409,6 +542,16 @@ func mutationHandler(w http.ResponseWriter, r *http.Request) {
 	req.CommitNow = commitNow
 
 	ctx := x.AttachAccessJwt(context.Background(), r)
+	ctx = x.AttachPriority(ctx, r)
+	ctx = x.AttachDurability(ctx, r)
+	ctx = x.AttachLineage(ctx, r)
+	if dryRun {
+		ctx = x.WithDryRun(ctx)
+	}
+	if isEstimate(r) {
+		writeCostEstimate(ctx, w, r, req)
+		return
+	}
 	resp, err := (&edgraph.Server{}).Query(ctx, req)
 	if err != nil {
 		x.SetStatusWithData(w, x.ErrorInvalidRequest, err.Error())
@@ -425,8 +568,9 @@ func mutationHandler(w http.ResponseWriter, r *http.Request) {
 	sort.Strings(e.Txn.Keys)
 	sort.Strings(e.Txn.Preds)
 
-	// Don't send keys array which is part of txn context if its commit immediately.
-	if req.CommitNow {
+	// Don't send keys array which is part of txn context if its commit immediately. A dry run
+	// sends them to show the conflict keys of the mutations.
+	if req.CommitNow && !dryRun {
 		e.Txn.Keys = e.Txn.Keys[:0]
 	}
 
@@ -437,6 +581,9 @@ func mutationHandler(w http.ResponseWriter, r *http.Request) {
 	mp["message"] = "Done"
 	mp["uids"] = resp.Uids
 	mp["queries"] = json.RawMessage(resp.Json)
+	if dryRun {
+		mp["dry_run"] = string(resp.Rdf)
+	}
 	response["data"] = mp
 
 	js, err := json.Marshal(response)
@@ -480,7 +627,7 @@ func commitHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		response, err = handleCommit(startTs, reqText)
+		response, err = handleCommit(x.AttachDurability(context.Background(), r), startTs, reqText)
 	}
 	if err != nil {
 		x.SetStatus(w, x.ErrorInvalidRequest, err.Error())
@@ -516,7 +663,8 @@ func handleAbort(startTs uint64) (map[string]interface{}, error) {
 	}
 }
 
-func handleCommit(startTs uint64, reqText []byte) (map[string]interface{}, error) {
+func handleCommit(ctx context.Context, startTs uint64,
+	reqText []byte) (map[string]interface{}, error) {
 	tc := &api.TxnContext{
 		StartTs: startTs,
 	}
@@ -539,7 +687,7 @@ func handleCommit(startTs uint64, reqText []byte) (map[string]interface{}, error
 		tc.Preds = reqMap["preds"]
 	}
 
-	cts, err := worker.CommitOverNetwork(context.Background(), tc)
+	cts, err := worker.CommitOverNetwork(ctx, tc)
 	if err != nil {
 		return nil, err
 	}
@@ -593,6 +741,7 @@ func alterHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := x.AttachAuthToken(context.Background(), r)
 	ctx = x.AttachAccessJwt(ctx, r)
 	ctx = x.AttachRemoteIP(ctx, r)
+	ctx = x.AttachPriority(ctx, r)
 	if _, err := (&edgraph.Server{}).Alter(ctx, op); err != nil {
 		x.SetStatus(w, x.Error, err.Error())
 		return
@@ -644,6 +793,7 @@ func resolveWithAdminServer(gqlReq *schema.Request, r *http.Request,
 	ctx = x.AttachRemoteIP(ctx, r)
 	ctx = x.AttachAuthToken(ctx, r)
 	ctx = x.AttachJWTNamespace(ctx)
+	ctx = x.WithPriority(ctx, x.PriorityAdmin)
 
 	return adminServer.ResolveWithNs(ctx, x.GalaxyNamespace, gqlReq)
 }
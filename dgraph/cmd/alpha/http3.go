@@ -0,0 +1,56 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alpha
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"plugin"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+)
+
+// http3Serve is the Serve function of the HTTP/3 plugin, built from contrib/http3. It serves the
+// handler over HTTP/3 on the UDP connection until closed is closed.
+type http3Serve = func(conn net.PacketConn, tlsCfg *tls.Config, handler http.Handler,
+	closed <-chan struct{}) error
+
+// loadHTTP3 reads the HTTP/3 server from the plugin file. The server lives in a plugin, with its
+// own module, so that its dependency on quic-go doesn't affect the Dgraph module.
+func loadHTTP3(soFile string) http3Serve {
+	glog.Infof("Loading HTTP/3 server from %q", soFile)
+	pl, err := plugin.Open(soFile)
+	x.Checkf(err, "could not open HTTP/3 plugin file")
+	symb, err := pl.Lookup("Serve")
+	x.Checkf(err, `could not find symbol "Serve" while loading HTTP/3 plugin: %v`, err)
+
+	// Let the type assertion panic occur, like for the custom tokenizers, since it tells the user
+	// what went wrong.
+	return symb.(http3Serve)
+}
+
+// serveHTTP3 serves the handler over HTTP/3 on the UDP connection until the closer is signaled.
+func serveHTTP3(serve http3Serve, conn net.PacketConn, tlsCfg *tls.Config, handler http.Handler,
+	closer *z.Closer) {
+	defer closer.Done()
+
+	err := serve(conn, tlsCfg, handler, closer.HasBeenClosed())
+	glog.Infof("HTTP/3 server stopped: %v", err)
+}
@@ -872,6 +872,68 @@ func TestDrainingMode(t *testing.T) {
 	runRequests(false)
 }
 
+func setReadOnlyMode(t *testing.T, enable bool, accessJwt string) {
+	readOnlyRequest := `mutation readOnly($enable: Boolean!) {
+		readOnly(enable: $enable, reason: "maintenance") {
+			response {
+				code
+			}
+		}
+	}`
+	params := &testutil.GraphQLParams{
+		Query:     readOnlyRequest,
+		Variables: map[string]interface{}{"enable": enable},
+	}
+	resp := testutil.MakeGQLRequestWithAccessJwt(t, params, accessJwt)
+	resp.RequireNoGraphQLErrors(t)
+	require.JSONEq(t, `{"readOnly":{"response":{"code":"Success"}}}`, string(resp.Data))
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	runRequests := func(expectErr bool) {
+		q1 := `
+	{
+	  alice(func: has(name)) {
+	    name
+	  }
+	}
+	`
+		// Queries are served in read-only mode.
+		_, _, err := queryWithTs(q1, "application/dql", "", 0)
+		require.NoError(t, err, "Got error while running query: %v", err)
+
+		m1 := `
+    {
+	  set {
+		_:alice <name> "Alice" .
+	  }
+	}
+	`
+		_, err = mutationWithTs(m1, "application/rdf", false, true, ts)
+		if expectErr {
+			require.True(t, err != nil && strings.Contains(err.Error(), "read-only mode"), err)
+			require.Contains(t, err.Error(), "Reason: maintenance")
+		} else {
+			require.NoError(t, err, "Got error while running mutation: %v", err)
+		}
+
+		err = alterSchema(`name: string @index(term) .`)
+		if expectErr {
+			require.True(t, err != nil && strings.Contains(err.Error(), "read-only mode"), err)
+		} else {
+			require.NoError(t, err, "Got error while running alter: %v", err)
+		}
+	}
+
+	token := testutil.GrootHttpLogin(addr + "/admin")
+
+	setReadOnlyMode(t, true, token.AccessJwt)
+	runRequests(true)
+
+	setReadOnlyMode(t, false, token.AccessJwt)
+	runRequests(false)
+}
+
 func TestOptionsForUiKeywords(t *testing.T) {
 	req, err := http.NewRequest(http.MethodOptions, fmt.Sprintf("%s/ui/keywords", addr), nil)
 	require.NoError(t, err)
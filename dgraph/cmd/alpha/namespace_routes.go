@@ -0,0 +1,119 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alpha
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// namespaceRoutes maps hostnames and URL prefixes to the namespaces whose GraphQL API they
+// serve, so that multi-tenant deployments don't need a proxy to route requests by namespace.
+type namespaceRoutes struct {
+	hosts    map[string]uint64
+	prefixes map[string]uint64
+}
+
+// nsRoutes holds the routes of --graphql_namespace_routes.
+var nsRoutes *namespaceRoutes
+
+// parseNamespaceRoutes parses the comma separated host=namespace and /prefix=namespace pairs
+// of --graphql_namespace_routes.
+func parseNamespaceRoutes(s string) (*namespaceRoutes, error) {
+	routes := &namespaceRoutes{
+		hosts:    make(map[string]uint64),
+		prefixes: make(map[string]uint64),
+	}
+	for _, route := range strings.Split(s, ",") {
+		route = strings.TrimSpace(route)
+		if route == "" {
+			continue
+		}
+		kv := strings.SplitN(route, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("Invalid namespace route %q. Expected host=namespace or "+
+				"/prefix=namespace", route)
+		}
+		from := strings.ToLower(strings.TrimSpace(kv[0]))
+		ns, err := strconv.ParseUint(strings.TrimSpace(kv[1]), 0, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing the namespace of route %q", route)
+		}
+
+		if !strings.HasPrefix(from, "/") {
+			if from == "" || strings.ContainsAny(from, "/:") {
+				return nil, errors.Errorf("Invalid host %q in namespace route %q", from, route)
+			}
+			routes.hosts[from] = ns
+			continue
+		}
+		from = strings.TrimSuffix(from, "/")
+		switch {
+		case from == "" || strings.Contains(from[1:], "/"):
+			return nil, errors.Errorf("Invalid prefix %q in namespace route %q. It should be a "+
+				"single path segment", kv[0], route)
+		case from == "/admin" || from == "/probe":
+			return nil, errors.Errorf("Prefix %s of namespace route %q is reserved", from, route)
+		}
+		routes.prefixes[from] = ns
+	}
+	return routes, nil
+}
+
+// hasNamespaces returns whether any route is to a namespace other than the galaxy namespace.
+func (nr *namespaceRoutes) hasNamespaces() bool {
+	for _, routes := range []map[string]uint64{nr.hosts, nr.prefixes} {
+		for _, ns := range routes {
+			if ns != x.GalaxyNamespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hostNamespace returns the namespace that the host of r is routed to, if any.
+func (nr *namespaceRoutes) hostNamespace(r *http.Request) (uint64, bool) {
+	if nr == nil || len(nr.hosts) == 0 {
+		return 0, false
+	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	ns, ok := nr.hosts[strings.ToLower(host)]
+	return ns, ok
+}
+
+// withNamespace returns r with namespace ns attached to its context, for serving it from the
+// GraphQL API of the namespace it was routed to. It fails if r carries an access JWT of
+// another namespace.
+func withNamespace(r *http.Request, ns uint64) (*http.Request, error) {
+	jwtNs, err := x.ExtractJWTNamespace(x.AttachAccessJwt(context.Background(), r))
+	if err == nil && jwtNs != ns {
+		return nil, errors.Errorf("The access JWT is for namespace %d, but the request is "+
+			"routed to namespace %d", jwtNs, ns)
+	}
+	return r.WithContext(x.AttachNamespace(r.Context(), ns)), nil
+}
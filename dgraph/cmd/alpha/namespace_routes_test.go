@@ -0,0 +1,43 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alpha
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNamespaceRoutes(t *testing.T) {
+	routes, err := parseNamespaceRoutes("Tenant1.api.example.com=7, /tenant2/=0x8,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]uint64{"tenant1.api.example.com": 7}, routes.hosts)
+	require.Equal(t, map[string]uint64{"/tenant2": 8}, routes.prefixes)
+	require.True(t, routes.hasNamespaces())
+
+	ns, ok := routes.hostNamespace(&http.Request{Host: "TENANT1.api.example.com:8080"})
+	require.True(t, ok)
+	require.Equal(t, uint64(7), ns)
+	_, ok = routes.hostNamespace(&http.Request{Host: "api.example.com"})
+	require.False(t, ok)
+
+	for _, s := range []string{"host", "host=ns", "/a/b=1", "/admin=1", "host:80=1", "=1"} {
+		_, err := parseNamespaceRoutes(s)
+		require.Error(t, err, s)
+	}
+}
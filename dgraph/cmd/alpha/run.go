@@ -17,7 +17,6 @@
 package alpha
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
 	"fmt"
@@ -39,17 +38,19 @@ import (
 	"github.com/dgraph-io/dgraph/ee/audit"
 
 	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/conn"
 	"github.com/dgraph-io/dgraph/edgraph"
 	"github.com/dgraph-io/dgraph/ee/enc"
 	"github.com/dgraph-io/dgraph/graphql/admin"
+	"github.com/dgraph-io/dgraph/graphql/lambda"
 	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/schema"
 	"github.com/dgraph-io/dgraph/tok"
 	"github.com/dgraph-io/dgraph/worker"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/dgraph-io/ristretto/z"
 	"github.com/golang/glog"
-	"github.com/pkg/errors"
 	"github.com/spf13/cast"
 	"github.com/spf13/cobra"
 	"go.opencensus.io/plugin/ocgrpc"
@@ -90,7 +91,7 @@ they form a Raft group and provide synchronous replication.
 `,
 		Run: func(cmd *cobra.Command, args []string) {
 			defer x.StartProfile(Alpha.Conf).Stop()
-			run()
+			run(nil)
 		},
 		Annotations: map[string]string{"group": "core"},
 	}
@@ -111,7 +112,24 @@ they form a Raft group and provide synchronous replication.
 		"[none, zstd:level, snappy] Specifies the compression algorithm and the compression"+
 			"level (if applicable) for the postings directory. none would disable compression,"+
 			" while zstd:1 would set zstd compression at level 1.")
+	flag.Bool("badger.in_memory", false,
+		"Keep the postings and the raft write-ahead logs in memory instead of on disk. All the"+
+			" data is lost when Alpha stops. Meant for ephemeral clusters, e.g. in tests and CI."+
+			" Only supported on Linux. The values are limited to 1MB in memory, so a transaction"+
+			" can't add more than about 50k edges to a single posting list.")
+	flag.String("storage_engine", worker.DefaultStorageEngine,
+		"The engine storing the postings and the raft write-ahead logs. badger keeps them on "+
+			"disk, and memory keeps them in memory like badger.in_memory.")
 	enc.RegisterFlags(flag)
+	flag.String("backup_public_key_file", "",
+		"The PEM file that stores the RSA or EC public key, or a certificate, to encrypt backups"+
+			" with instead of the encryption key. Each backup file gets a random data key that is"+
+			" encrypted with the public key, so restoring needs the matching private key, which"+
+			" Alpha never holds. Enterprise feature.")
+	flag.String("backup_signing_key_file", "",
+		"The PKCS #8 PEM file that stores the Ed25519 private key to sign the backup manifests"+
+			" with. The manifests hold the checksums of the backup files, so restoring with the"+
+			" matching public key rejects tampered backups. Enterprise feature.")
 
 	// Snapshot and Transactions.
 	flag.String("abort_older_than", "5m",
@@ -137,6 +155,182 @@ they form a Raft group and provide synchronous replication.
 		not participate in Raft elections. This can be used to achieve a read-only replica.
 	snapshot-after=N would create a new Raft snapshot after N number of Raft entries.
 		The lower this number, the more frequent snapshot creation would be.
+	tags=T:V,... sets tags of this Alpha, like zone:us-east-1a,disk:nvme. The --placement
+		constraints of Zero refer to them.
+	replay-concurrency=N applies the mutations of up to N transactions at once while replaying
+		the write-ahead log on restart. Set it to 1 to replay it serially.
+	`)
+	flag.String("disk", worker.DiskDefaults,
+		`Disk usage limits and forecasting options.
+	max-mb=N refuses writes once the postings and WAL directories take up N MB.
+		Zero means no limit.
+	min-free-percent=N refuses writes once the free space on the file system holding the
+		postings directory drops below N percent.
+	compact-percent=N triggers value log GC and compaction once N percent of the allowed
+		space is used.
+	window=D is the duration over which growth rates are computed for forecasting.
+	purge-interval=D compacts the postings directory every D if it holds expired entries,
+		which are hidden from reads but only dropped by compactions. Zero disables it.
+	`)
+	flag.String("cache_tier", posting.CacheTierDefaults,
+		`Options of the cache tier, an external cache shared by the Alphas for the posting
+	lists read from disk, for the clusters whose working set doesn't fit in memory. The
+	entries are keyed by the cluster ID and the commit timestamps of the lists, so new commits
+	and rollups invalidate them, as do drops and schema changes. With encryption at rest, the
+	entries are encrypted with the same key.
+	memcached=host1:port,host2:port are the memcached servers. The keys are spread across
+		them with consistent hashing. No cache tier is used if it's empty.
+	timeout=D is how long a request to a memcached server may take.
+	ttl=D is how long the entries are kept.
+	min-kb=N and max-kb=N are the bounds of the sizes of the posting lists which are cached.
+	prefix=P starts all the keys.
+	`)
+	flag.String("hot_keys", posting.HotKeysDefaults,
+		`Options of the detection of hot keys, i.e. of the posting lists receiving a
+	disproportionate share of the reads or writes of this node. They're listed by the hotKeys
+	query of the admin API.
+	sample=N counts one access out of N. Hot keys aren't tracked if it's zero.
+	window=D is the duration over which the accesses are counted.
+	min-share=N makes a key hot if it gets at least N percent of the sampled reads or writes.
+	top=N is the max number of hot keys reported.
+	mitigate=true rolls up the keys which are hot for writes ahead of the other keys, and
+		spreads the reads of the predicates with keys hot for reads over all the replicas of
+		their group.
+	`)
+	flag.String("rollup", posting.RollupDefaults,
+		`Options of the rollups of posting lists, which merge the deltas written on top of a list
+	into it. Until then, every read of the list also reads its deltas.
+	adaptive=true adapts the rollups to how often the predicates are written. Otherwise, keys
+		are rolled up at most every 10s, after writes and after reads finding deltas.
+	window=D is the duration over which the writes of the predicates are counted.
+	hot-writes=N makes a predicate hot if at least N of its keys were written in the last
+		window. Hot keys are rolled up ahead of the others, at most every hot-interval.
+	cold-writes=N makes a predicate cold if at most N of its keys were written in the last
+		window. Cold keys are rolled up only after reads finding deltas, at most every
+		cold-interval.
+	hot-interval=D and cold-interval=D are the min durations between two rollups of a key of a
+		hot and of a cold predicate.
+	`)
+	flag.String("commit_hook", worker.CommitHookDefaults,
+		`Options of the commit hook, a gRPC service called for every transaction committed by a
+	client before the commit is acknowledged, e.g. to implement a transactional outbox. The
+	service implements `+worker.CommitHookMethod+`, which takes an api.TxnContext holding the
+	start and commit timestamps and the predicates of the transaction, and returns an
+	api.Payload. The namespace is sent in the namespace key of the gRPC metadata.
+	addr=host:port is the address of the service. No hook is called if it's empty.
+	timeout=D is how long a call of the hook may take.
+	policy=fail returns an error holding the commit timestamp to the client if the hook fails.
+		The transaction stays committed. policy=ignore only logs the failures.
+	retries=N is the number of times a failed call is retried.
+	tls=true connects to the service with TLS.
+	`)
+	flag.String("audit_trail", edgraph.AuditTrailDefaults,
+		`Options of the audit trail, which records who created and last updated the nodes of some
+	types and when, in predicates of those nodes maintained by the mutations. The predicates sent
+	by clients for the trail of these nodes are ignored. Since every mutation of a node updates its
+	trail, concurrent mutations of the same node conflict.
+	types=T1,T2 are the types whose nodes get an audit trail. No trail is kept if it's empty.
+	claim=C is the claim of the JWT of GraphQL requests recorded as the user when the request
+		has no ACL user.
+	created-by=P and updated-by=P are the predicates holding the user who created the node and
+		the user who last updated it.
+	created-at=P and updated-at=P are the predicates holding the datetimes at which the node was
+		created and last updated.
+	`)
+	flag.String("erasure", edgraph.ErasureDefaults,
+		`Policy of the erasures of data subjects run with the eraseSubject admin mutation.
+	follow=P1,~P2 are the uid predicates, or the reverse ones, leading from a node to the nodes
+		erased along with it. The mutation may give others.
+	depth=N is how many edges away from the subject the followed nodes may be.
+	signing-key-file=F is the PEM file of the Ed25519 key signing the erasure reports. The
+		--backup_signing_key_file key is used if it's empty. (Enterprise feature)
+	`)
+	flag.String("slow_query", edgraph.SlowQueryDefaults,
+		`Options of the slow query log, which records the DQL queries slower than the threshold
+	with their read timestamp, so that they can be run again with dgraph replay.
+	file=F is the file the queries are appended to, one JSON object per line. Queries aren't
+		logged if it's empty.
+	threshold=D is the time a query must take to be logged, like 500ms or 2s.
+	redact-vars=B replaces the variables of the queries by their hash, as they may hold
+		passwords or personal data. The queries can't be replayed then.
+	size=N is the size in MB at which the file is rotated.
+	days=N is the number of days the rotated files are kept.
+	`)
+	flag.String("query_policy", "",
+		"Path to a JSON file with the policies restricting the DQL queries of namespaces, e.g. "+
+			`{"0": {"allow": ["{ q(func: eq(name, \"x\")) { name } }"], `+
+			`"deny": ["unindexed_regexp", "unbounded_recurse"]}}. `+
+			"If a namespace has allowed queries, its queries must have the shape of one of them, "+
+			"that is be the same but for the values they pass. Denied constructs are rejected. "+
+			"They are unindexed_regexp, for regexp on predicates without a trigram index, and "+
+			"unbounded_recurse, for @recurse without a depth. Queries from GraphQL aren't checked.")
+	flag.String("tiered_storage", worker.TieredStorageDefaults,
+		`Options of tiered storage, which moves the data of cold predicates to object storage.
+	Cold predicates are read-only, and are read from a local copy of their data fetched on
+	demand. They are demoted and promoted back via the admin API, or demoted automatically.
+	dest=URI is where the data goes, as s3:///bucket/path, minio://host/bucket/path,
+		gs:///bucket/path or a local or NFS directory. It must be the same for every Alpha.
+		Tiered storage is disabled if it's empty.
+	cache-dir=path is the directory holding the local copies of the cold predicates.
+	cache-mb=N is the size above which the least recently used local copies are evicted.
+	cold-after=D demotes the predicates which weren't read by the queries served by the
+		group leader for D. Zero disables automatic demotion.
+	check-every=D is how often the predicates to demote are looked for.
+	`)
+	flag.String("wal_archive", worker.WALArchiveDefaults,
+		`Options to archive the segments of the Raft write-ahead log to object storage before
+	they're truncated, e.g. for point-in-time recovery tooling replaying them on top of a backup.
+	The segments are uploaded as wal/group-<gid>/node-<raft id>/<first index>-<last index>.wal.
+	They're encrypted if the write-ahead log is, with the keys of its KEYREGISTRY file.
+	dest=URI is where the segments go, in the same forms as for tiered_storage. Archiving is
+		disabled if it's empty.
+	spool-dir=path is the directory holding copies of the segments until they're uploaded.
+	upload-every=D is how often uploads which failed are retried.
+	`)
+	flag.String("index_verify", worker.IndexVerifyDefaults,
+		`Options of the background verification of the indexes of the predicates served by this
+	node against their data. The entries found missing from or dangling in an index are counted
+	in the num_index_divergences_total metric and listed by the indexVerification query of the
+	admin API. Every replica verifies its own copy of the data.
+	every=D is how often a round of verification runs. Indexes aren't verified if it's zero.
+	sample=N is the max number of data keys and of index entries of every indexed predicate
+		checked per round. The next round picks up where the last one stopped.
+	repair=true adds the missing entries to the index and removes the dangling ones, in a
+		transaction replicated to the whole group.
+	`)
+	flag.String("http3", "", "Experimental. The HTTP/3 plugin file, built from contrib/http3. If "+
+		"set, /query and /graphql are served over HTTP/3 (QUIC) too, on the UDP port with the "+
+		"number of the HTTP port, with the TLS config of the HTTP API. The HTTP responses of "+
+		"these endpoints advertise it in the Alt-Svc header. Needs TLS.")
+	flag.String("proxy_protocol", x.ProxyProtocolDefaults,
+		`PROXY protocol options, for the Alphas behind L4 load balancers. The clients' addresses
+	in the header sent by the load balancers are checked against the IP whitelist and written in
+	the audit logs, instead of the load balancers' addresses.
+	trusted=IPs is a comma separated list of the IP addresses, IP ranges, CIDR blocks, or hostnames
+		of the load balancers, like --whitelist. The connections from them must start with a
+		PROXY protocol v1 or v2 header, the other connections are served as they are. The PROXY
+		protocol is disabled if it's empty.
+	timeout=D is how long to wait for the header of a connection.
+	`)
+	flag.String("unix_socket", unixSocketDefaults,
+		`Unix domain sockets the APIs are served on too, for the clients on the same host, e.g. in
+	a sidecar. The sockets are served without TLS, their permissions control who can connect, and
+	their clients pass the IP whitelist of the admin operations like the local ones.
+	grpc=/path/to/grpc.sock serves the gRPC API on the socket.
+	http=/path/to/http.sock serves the HTTP API on the socket.
+	mode=0660 sets the permissions of the sockets.
+	`)
+	flag.String("shedding", x.ShedDefaults,
+		`Load shedding options. Requests carry a priority class of interactive (the default),
+	batch or admin, set via the X-Dgraph-Priority HTTP header or the priority key in the gRPC
+	metadata. While memory or CPU usage is above a limit, batch requests are shed, interactive
+	requests are queued and admin requests are always served.
+	memory-mb=N sheds requests while the process uses more than N MB of memory. Zero means
+		no limit.
+	cpu-percent=N sheds requests while the process uses more than N percent of the CPUs. Zero
+		means no limit.
+	queue-timeout=D is how long interactive requests wait for the pressure to go away before
+		being shed.
 	`)
 	flag.Int("max_retries", -1,
 		"Commits to disk will give up after these number of retries to prevent locking the worker"+
@@ -153,6 +347,15 @@ they form a Raft group and provide synchronous replication.
 		"Enterprise feature.")
 	flag.Duration("acl_refresh_ttl", 30*24*time.Hour, "The TTL for the refresh jwt. "+
 		"Enterprise feature.")
+	flag.String("acl_guest", "",
+		"Semicolon separated list of guest profiles, which serve requests without an access "+
+			"JWT to a namespace. A profile is a space separated list of namespace=N (required), "+
+			"group=G (default guest): the ACL group of the namespace whose permissions guests "+
+			"get, ops=query,mutate,alter (default query): the operations guests can do, and "+
+			"rate=R (default unlimited): the guest requests per second served, above zero. E.g. "+
+			"\"namespace=0 ops=query rate=20\". Guests are served in the namespace their "+
+			"GraphQL request is routed to by --graphql_namespace_routes, or else in namespace 0. "+
+			"Enterprise feature.")
 	flag.String("mutations", "allow",
 		"Set mutation mode to allow, disallow, or strict.")
 
@@ -163,11 +366,26 @@ they form a Raft group and provide synchronous replication.
 	flag.Uint64("query_edge_limit", 1e6,
 		"Limit for the maximum number of edges that can be returned in a query."+
 			" This applies to shortest path and recursive queries.")
+	flag.Int64("query_memory_mb", 0,
+		"Limit for the memory a single query can use for the posting lists it reads and the "+
+			"response it builds. Queries going above it are cancelled. Zero means no limit.")
+	flag.Uint64("best_effort_max_lag", 0,
+		"Number of timestamps a replica can lag behind the read timestamp of a best effort query "+
+			"and still serve it, instead of waiting to catch up. Best effort queries prefer "+
+			"followers, and report how stale they are in the staleness extension.")
+	flag.Uint64("max_response_bytes", 0,
+		"Size after which query responses are truncated at a root node boundary. Truncated "+
+			"responses set partial to true and a cursor to continue from in their extensions. "+
+			"Zero means no limit.")
 	flag.Uint64("normalize_node_limit", 1e4,
 		"Limit for the maximum number of nodes that can be returned in a query that uses the "+
 			"normalize directive.")
 	flag.Uint64("mutations_nquad_limit", 1e6,
 		"Limit for the maximum number of nquads that can be inserted in a mutation request")
+	flag.Int64("mutation_stream_mb", 1024,
+		"Limit for the size of a mutation streamed in chunks, which the Alpha holds in memory "+
+			"until the stream ends. Zero means no limit. The nquads are also limited by "+
+			"mutations_nquad_limit.")
 
 	//Custom plugins.
 	flag.String("custom_tokenizers", "",
@@ -187,6 +405,35 @@ they form a Raft group and provide synchronous replication.
 	flag.Duration("graphql_poll_interval", time.Second, "polling interval for graphql subscription.")
 	flag.String("graphql_lambda_url", "",
 		"URL of lambda server that implements custom GraphQL JavaScript resolvers")
+	flag.Int("graphql_lambda_num", 0,
+		"Number of lambda servers to run with --graphql_lambda_cmd. Alpha health-checks and "+
+			"restarts them, and sends the requests for the @lambda fields to them. It can't be "+
+			"used with --graphql_lambda_url.")
+	flag.Int("graphql_lambda_port", 20000,
+		"Port of the first lambda server run by Alpha. The others listen on the ports following it.")
+	flag.String("graphql_lambda_cmd", "",
+		"Command to run a lambda server with. The port to listen on and the URL of Alpha are "+
+			"passed to it in the PORT and DGRAPH_URL environment variables.")
+	flag.Duration("graphql_lambda_restart_after", 5*time.Second,
+		"Time to wait before restarting a lambda server run by Alpha which exited or became "+
+			"unhealthy.")
+	flag.Duration("graphql_lambda_timeout", time.Minute,
+		"Timeout for the requests to the lambda server, and for the runs of WASM lambda scripts.")
+	flag.Int64("graphql_lambda_wasm_memory_mb", 64,
+		"Memory limit in MB of a run of a WASM lambda script.")
+	flag.String("graphql_namespace_routes", "",
+		"Comma separated list of host=namespace and /prefix=namespace pairs. GraphQL requests "+
+			"to the host, or to /prefix/graphql, are served by the GraphQL API of the namespace, "+
+			"and their access JWT must be for that namespace. E.g. "+
+			"tenant1.api.example.com=7,/tenant2=8. Namespaces other than 0 require ACL.")
+	flag.Int64("graphql_cache_mb", 64,
+		"Size of the cache in MB for the results of GraphQL queries with the @cache directive. "+
+			"Set it to 0 to disable the cache.")
+
+	flag.Bool("console", false,
+		"Serve a web console at /ui/console on the HTTP port, to see the state of the cluster, "+
+			"edit the schema, run queries and control tasks. Requests from the console are "+
+			"checked with ACL and the auth token like any other request. Use it with TLS.")
 
 	// Cache flags
 	flag.String("cache_percentage", "0,65,35,0",
@@ -201,6 +448,17 @@ they form a Raft group and provide synchronous replication.
 	flag.
 	days=10 is the number of days audit logs will be preserved (default 10).
 	size=100 is the size of each file in mb after which it will be rolled over (default 100).
+	syslog=host:port sends the audit logs to a syslog server over TCP, for SIEM ingestion, along
+	with or instead of the files in dir.
+	syslog-format=cef/leef is the format of the syslog messages, the ArcSight Common Event Format
+	or the QRadar Log Event Extended Format (default cef).
+	syslog-tls=true/false connects to the syslog server with TLS (default true), verified with
+	syslog-ca-cert=/path/to/ca.crt or the system CAs. syslog-client-cert and syslog-client-key are
+	the client certificate and key, if the server requires one.
+	syslog-buffer=10000 is the number of audit events buffered while the syslog server is slow or
+	unreachable (default 10000).
+	syslog-full=drop/block drops the audit events when the buffer is full, or makes requests wait
+	for room (default drop).
 	Sample flag would be --audit dir=aa;encrypt-file=/filepath;compress=true;days=10;size=100`)
 
 	flag.String("cdc", "",
@@ -213,9 +471,22 @@ they form a Raft group and provide synchronous replication.
 	client-cert=/path/to/client/cert/file to define the client certificate for tls encryption.
 	client-key=/path/to/client/key/file to define the client key for tls encryption.
 	`)
+	flag.String("search_sync", worker.SearchSyncDefaults,
+		`Options used to reach the Elasticsearch or OpenSearch clusters of the search connectors,
+	which are managed via the admin API. A connector mirrors predicates into an index, from the
+	change data capture events of the groups.
+	username=name and password=secret set the credentials of basic authentication.
+	api-key=key authenticates with an API key instead.
+	ca-cert=/path/to/ca/crt/file is the CA certificate of the clusters, if not a system one.
+	client-cert=/path/to/client/cert/file and client-key=/path/to/client/key/file set the client
+		certificate, if the clusters require one.
+	batch-size=N is the number of documents sent per bulk request.
+	timeout=D is the timeout of the requests.
+	`)
 
 	// TLS configurations
 	x.RegisterServerTLSFlags(flag)
+	conn.RegisterPoolFlag(flag)
 }
 
 func setupCustomTokenizers() {
@@ -228,81 +499,20 @@ func setupCustomTokenizers() {
 	}
 }
 
-// Parses a comma-delimited list of IP addresses, IP ranges, CIDR blocks, or hostnames
-// and returns a slice of []IPRange.
-//
-// e.g. "144.142.126.222:144.142.126.244,144.142.126.254,192.168.0.0/16,host.docker.internal"
-func getIPsFromString(str string) ([]x.IPRange, error) {
-	if str == "" {
-		return []x.IPRange{}, nil
-	}
-
-	var ipRanges []x.IPRange
-	rangeStrings := strings.Split(str, ",")
-
-	for _, s := range rangeStrings {
-		isIPv6 := strings.Contains(s, "::")
-		tuple := strings.Split(s, ":")
-		switch {
-		case isIPv6 || len(tuple) == 1:
-			if !strings.Contains(s, "/") {
-				// string is hostname like host.docker.internal,
-				// or IPv4 address like 144.124.126.254,
-				// or IPv6 address like fd03:b188:0f3c:9ec4::babe:face
-				ipAddr := net.ParseIP(s)
-				if ipAddr != nil {
-					ipRanges = append(ipRanges, x.IPRange{Lower: ipAddr, Upper: ipAddr})
-				} else {
-					ipAddrs, err := net.LookupIP(s)
-					if err != nil {
-						return nil, errors.Errorf("invalid IP address or hostname: %s", s)
-					}
-
-					for _, addr := range ipAddrs {
-						ipRanges = append(ipRanges, x.IPRange{Lower: addr, Upper: addr})
-					}
-				}
-			} else {
-				// string is CIDR block like 192.168.0.0/16 or fd03:b188:0f3c:9ec4::/64
-				rangeLo, network, err := net.ParseCIDR(s)
-				if err != nil {
-					return nil, errors.Errorf("invalid CIDR block: %s", s)
-				}
-
-				addrLen, maskLen := len(rangeLo), len(network.Mask)
-				rangeHi := make(net.IP, len(rangeLo))
-				copy(rangeHi, rangeLo)
-				for i := 1; i <= maskLen; i++ {
-					rangeHi[addrLen-i] |= ^network.Mask[maskLen-i]
-				}
-
-				ipRanges = append(ipRanges, x.IPRange{Lower: rangeLo, Upper: rangeHi})
-			}
-		case len(tuple) == 2:
-			// string is range like a.b.c.d:w.x.y.z
-			rangeLo := net.ParseIP(tuple[0])
-			rangeHi := net.ParseIP(tuple[1])
-			switch {
-			case rangeLo == nil:
-				return nil, errors.Errorf("invalid IP address: %s", tuple[0])
-			case rangeHi == nil:
-				return nil, errors.Errorf("invalid IP address: %s", tuple[1])
-			case bytes.Compare(rangeLo, rangeHi) > 0:
-				return nil, errors.Errorf("inverted IP address range: %s", s)
-			}
-			ipRanges = append(ipRanges, x.IPRange{Lower: rangeLo, Upper: rangeHi})
-		default:
-			return nil, errors.Errorf("invalid IP address range: %s", s)
-		}
-	}
-
-	return ipRanges, nil
-}
-
 func httpPort() int {
 	return x.Config.PortOffset + x.PortHTTP
 }
 
+// localHTTPURL returns the URL of the HTTP endpoint of this Alpha. Once TLS is configured, it's
+// only served over HTTPS.
+func localHTTPURL() string {
+	scheme := "http"
+	if tlsCfg, err := x.LoadServerTLSConfig(Alpha.Conf); err == nil && tlsCfg != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://localhost:%d", scheme, httpPort())
+}
+
 func grpcPort() int {
 	return x.Config.PortOffset + x.PortGrpc
 }
@@ -389,20 +599,25 @@ func storeStatsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func setupListener(addr string, port int) (net.Listener, error) {
-	return net.Listen("tcp", fmt.Sprintf("%s:%d", addr, port))
+	return net.Listen("tcp", net.JoinHostPort(addr, strconv.Itoa(port)))
 }
 
 func serveGRPC(l net.Listener, tlsCfg *tls.Config, closer *z.Closer) {
 	defer closer.Done()
 
-	x.RegisterExporters(Alpha.Conf, "dgraph.alpha")
-
 	opt := []grpc.ServerOption{
 		grpc.MaxRecvMsgSize(x.GrpcMaxSize),
 		grpc.MaxSendMsgSize(x.GrpcMaxSize),
 		grpc.MaxConcurrentStreams(1000),
 		grpc.StatsHandler(&ocgrpc.ServerHandler{}),
-		grpc.UnaryInterceptor(audit.AuditRequestGRPC),
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{},
+			info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			// Audit the request first, so that the audit log has the errors without details.
+			return edgraph.ErrorDetailsInterceptor(ctx, req, info,
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					return audit.AuditRequestGRPC(ctx, req, info, handler)
+				})
+		}),
 	}
 	if tlsCfg != nil {
 		opt = append(opt, grpc.Creds(credentials.NewTLS(tlsCfg)))
@@ -410,6 +625,12 @@ func serveGRPC(l net.Listener, tlsCfg *tls.Config, closer *z.Closer) {
 
 	s := grpc.NewServer(opt...)
 	api.RegisterDgraphServer(s, &edgraph.Server{})
+	pb.RegisterTopologyServer(s, &edgraph.Server{})
+	pb.RegisterBackpressureServer(s, &edgraph.Server{})
+	pb.RegisterUpsertServer(s, &edgraph.Server{})
+	pb.RegisterMutationStreamServer(s, &edgraph.Server{})
+	pb.RegisterScanServer(s, &edgraph.Server{})
+	pb.RegisterTypedQueryServer(s, &edgraph.Server{})
 	hapi.RegisterHealthServer(s, health.NewServer())
 	worker.RegisterZeroProxyServer(s)
 
@@ -441,11 +662,64 @@ func setupServer(closer *z.Closer) {
 		log.Fatal(err)
 	}
 
+	proxy := z.NewSuperFlag(Alpha.Conf.GetString("proxy_protocol")).MergeAndCheckDefault(
+		x.ProxyProtocolDefaults)
+	trustedProxies, proxyTimeout, err := x.ParseProxyProtocol(proxy)
+	if err != nil {
+		log.Fatalf("Invalid --proxy_protocol flag: %v", err)
+	}
+	if len(trustedProxies) > 0 {
+		glog.Infof("Reading the PROXY protocol headers of the connections from %s",
+			proxy.GetString("trusted"))
+		httpListener = x.NewProxyProtocolListener(httpListener, trustedProxies, proxyTimeout)
+		grpcListener = x.NewProxyProtocolListener(grpcListener, trustedProxies, proxyTimeout)
+	}
+
+	var http3Conn net.PacketConn
+	var http3 http3Serve
+	if soFile := Alpha.Conf.GetString("http3"); soFile != "" {
+		if tlsCfg == nil {
+			log.Fatalf("--http3 needs TLS, see the --tls flags")
+		}
+		http3 = loadHTTP3(soFile)
+		if http3Conn, err = net.ListenPacket("udp",
+			net.JoinHostPort(laddr, strconv.Itoa(httpPort()))); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// withAltSvc advertises the HTTP/3 endpoint in the responses of the endpoints served on it.
+	withAltSvc := func(h http.HandlerFunc) http.HandlerFunc {
+		if http3Conn == nil {
+			return h
+		}
+		altSvc := fmt.Sprintf(`h3=":%d"; ma=86400`, httpPort())
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Alt-Svc", altSvc)
+			h(w, r)
+		}
+	}
+	// http3Mux serves the endpoints for which HTTP/3 is worth it, the queries of the clients
+	// on lossy networks.
+	http3Mux := http.NewServeMux()
+
+	unixSocket := z.NewSuperFlag(Alpha.Conf.GetString("unix_socket")).MergeAndCheckDefault(
+		unixSocketDefaults)
+	grpcUnixListener, httpUnixListener, err := unixListeners(unixSocket)
+	if err != nil {
+		log.Fatalf("Invalid --unix_socket flag: %v", err)
+	}
+
 	baseMux := http.NewServeMux()
 	http.Handle("/", audit.AuditRequestHttp(baseMux))
 
-	baseMux.HandleFunc("/query", queryHandler)
-	baseMux.HandleFunc("/query/", queryHandler)
+	baseMux.HandleFunc("/query", withAltSvc(queryHandler))
+	baseMux.HandleFunc("/query/", withAltSvc(queryHandler))
+	http3Mux.HandleFunc("/query", queryHandler)
+	http3Mux.HandleFunc("/query/", queryHandler)
+	baseMux.HandleFunc("/query/prepare", prepareHandler)
+	baseMux.HandleFunc("/query/estimate", queryHandler)
+	baseMux.HandleFunc("/mutate/estimate", mutationHandler)
+	baseMux.HandleFunc("/format", formatHandler)
 	baseMux.HandleFunc("/mutate", mutationHandler)
 	baseMux.HandleFunc("/mutate/", mutationHandler)
 	baseMux.HandleFunc("/commit", commitHandler)
@@ -482,37 +756,77 @@ func setupServer(closer *z.Closer) {
 	// Do not use := notation here because adminServer is a global variable.
 	mainServer, adminServer, gqlHealthStore = admin.NewServers(introspection,
 		globalEpoch, closer)
-	baseMux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
-		namespace := x.ExtractNamespaceHTTP(r)
-		r.Header.Set("resolver", strconv.FormatUint(namespace, 10))
-		admin.LazyLoadSchema(namespace)
-		mainServer.HTTPHandler().ServeHTTP(w, r)
-	})
-
-	baseMux.HandleFunc("/probe/graphql", func(w http.ResponseWriter, r *http.Request) {
-		// lazy load the schema so that just by making a probe request,
-		// one can boot up GraphQL for their namespace
-		namespace := x.ExtractNamespaceHTTP(r)
-		admin.LazyLoadSchema(namespace)
-
-		healthStatus := gqlHealthStore.GetHealth()
-		httpStatusCode := http.StatusOK
-		if !healthStatus.Healthy {
-			httpStatusCode = http.StatusServiceUnavailable
+	// routedNamespace returns the namespace whose GraphQL API serves r. A request is routed to
+	// a namespace by its URL prefix, passed as prefixNs, or by its host. Otherwise, it's the
+	// namespace of its access JWT.
+	routedNamespace := func(w http.ResponseWriter, r *http.Request,
+		prefixNs *uint64) (*http.Request, uint64, bool) {
+		ns, ok := nsRoutes.hostNamespace(r)
+		if prefixNs != nil {
+			ns, ok = *prefixNs, true
 		}
-		w.Header().Set("Content-Type", "application/json")
-		x.AddCorsHeaders(w)
-		w.WriteHeader(httpStatusCode)
-		e = globalEpoch[namespace]
-		var counter uint64
-		if e != nil {
-			counter = atomic.LoadUint64(e)
+		if !ok {
+			return r, x.ExtractNamespaceHTTP(r), true
 		}
-		x.Check2(w.Write([]byte(fmt.Sprintf(`{"status":"%s","schemaUpdateCounter":%d}`,
-			healthStatus.StatusMsg, counter))))
-	})
+		r, err := withNamespace(r, ns)
+		if err != nil {
+			x.AddCorsHeaders(w)
+			x.SetStatus(w, x.ErrorUnauthorized, err.Error())
+			return nil, 0, false
+		}
+		return r, ns, true
+	}
+	graphqlHandler := func(prefixNs *uint64) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			r, namespace, ok := routedNamespace(w, r, prefixNs)
+			if !ok {
+				return
+			}
+			r.Header.Set("resolver", strconv.FormatUint(namespace, 10))
+			admin.LazyLoadSchema(namespace)
+			mainServer.HTTPHandler().ServeHTTP(w, r)
+		}
+	}
+	baseMux.HandleFunc("/graphql", withAltSvc(graphqlHandler(nil)))
+	http3Mux.HandleFunc("/graphql", graphqlHandler(nil))
+
+	probeHandler := func(prefixNs *uint64) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			// lazy load the schema so that just by making a probe request,
+			// one can boot up GraphQL for their namespace
+			_, namespace, ok := routedNamespace(w, r, prefixNs)
+			if !ok {
+				return
+			}
+			admin.LazyLoadSchema(namespace)
+
+			healthStatus := gqlHealthStore.GetHealth()
+			httpStatusCode := http.StatusOK
+			if !healthStatus.Healthy {
+				httpStatusCode = http.StatusServiceUnavailable
+			}
+			w.Header().Set("Content-Type", "application/json")
+			x.AddCorsHeaders(w)
+			w.WriteHeader(httpStatusCode)
+			e = globalEpoch[namespace]
+			var counter uint64
+			if e != nil {
+				counter = atomic.LoadUint64(e)
+			}
+			x.Check2(w.Write([]byte(fmt.Sprintf(`{"status":"%s","schemaUpdateCounter":%d}`,
+				healthStatus.StatusMsg, counter))))
+		}
+	}
+	baseMux.HandleFunc("/probe/graphql", probeHandler(nil))
+	for prefix, ns := range nsRoutes.prefixes {
+		ns := ns
+		baseMux.HandleFunc(prefix+"/graphql", withAltSvc(graphqlHandler(&ns)))
+		http3Mux.HandleFunc(prefix+"/graphql", graphqlHandler(&ns))
+		baseMux.HandleFunc(prefix+"/probe/graphql", probeHandler(&ns))
+	}
 	baseMux.HandleFunc("/admin", func(w http.ResponseWriter, r *http.Request) {
 		r.Header.Set("resolver", "0")
+		r = r.WithContext(x.WithPriority(r.Context(), x.PriorityAdmin))
 		// We don't need to load the schema for all the admin operations.
 		// Only a few like getUser, queryGroup require this. So, this can be optimized.
 		admin.LazyLoadSchema(x.ExtractNamespaceHTTP(r))
@@ -572,7 +886,7 @@ func setupServer(closer *z.Closer) {
 		memoryLimitHandler(w, r, adminServer)
 	}))))
 
-	addr := fmt.Sprintf("%s:%d", laddr, httpPort())
+	addr := net.JoinHostPort(laddr, strconv.Itoa(httpPort()))
 	glog.Infof("Bringing up GraphQL HTTP API at %s/graphql", addr)
 	glog.Infof("Bringing up GraphQL HTTP admin API at %s/admin", addr)
 
@@ -581,11 +895,42 @@ func setupServer(closer *z.Closer) {
 
 	baseMux.Handle("/", http.HandlerFunc(homeHandler))
 	baseMux.Handle("/ui/keywords", http.HandlerFunc(keywordHandler))
+	if Alpha.Conf.GetBool("console") {
+		if tlsCfg == nil {
+			glog.Warningf("The console is served without TLS, so the passwords and tokens " +
+				"sent from it aren't encrypted")
+		}
+		baseMux.Handle("/ui/console", allowedMethodsHandler(allowedMethods{
+			http.MethodGet: true,
+		}, http.HandlerFunc(consoleHandler)))
+		baseMux.Handle("/ui/console/schema", allowedMethodsHandler(allowedMethods{
+			http.MethodGet: true,
+		}, consoleSchemaHandler((&edgraph.Server{}).Query)))
+		glog.Infof("Bringing up the console at %s/ui/console", addr)
+	}
 
 	// Initialize the servers.
+	x.RegisterExporters(Alpha.Conf, "dgraph.alpha")
 	admin.ServerCloser.AddRunning(3)
 	go serveGRPC(grpcListener, tlsCfg, admin.ServerCloser)
 	go x.StartListenHttpAndHttps(httpListener, tlsCfg, admin.ServerCloser)
+	// The Unix domain sockets are only reachable from this host, so they're served without TLS.
+	if grpcUnixListener != nil {
+		admin.ServerCloser.AddRunning(1)
+		go serveGRPC(grpcUnixListener, nil, admin.ServerCloser)
+		glog.Infof("gRPC server started.  Listening on socket %s", grpcUnixListener.Addr())
+	}
+	if http3Conn != nil {
+		admin.ServerCloser.AddRunning(1)
+		go serveHTTP3(http3, http3Conn, tlsCfg, audit.AuditRequestHttp(http3Mux),
+			admin.ServerCloser)
+		glog.Infof("HTTP/3 server started.  Listening on UDP port %d", httpPort())
+	}
+	if httpUnixListener != nil {
+		admin.ServerCloser.AddRunning(1)
+		go x.StartListenHttpAndHttps(httpUnixListener, nil, admin.ServerCloser)
+		glog.Infof("HTTP server started.  Listening on socket %s", httpUnixListener.Addr())
+	}
 
 	if Alpha.Conf.GetBool("telemetry") {
 		go edgraph.PeriodicallyPostTelemetry()
@@ -606,6 +951,19 @@ func setupServer(closer *z.Closer) {
 		if err := httpListener.Close(); err != nil {
 			glog.Warningf("Error while closing HTTP listener: %s", err)
 		}
+		if http3Conn != nil {
+			if err := http3Conn.Close(); err != nil {
+				glog.Warningf("Error while closing HTTP/3 connection: %s", err)
+			}
+		}
+		for _, l := range []net.Listener{grpcUnixListener, httpUnixListener} {
+			if l == nil {
+				continue
+			}
+			if err := l.Close(); err != nil {
+				glog.Warningf("Error while closing Unix socket listener: %s", err)
+			}
+		}
 	}()
 
 	glog.Infoln("gRPC server started.  Listening on port", grpcPort())
@@ -615,7 +973,8 @@ func setupServer(closer *z.Closer) {
 	admin.ServerCloser.Wait()
 }
 
-func run() {
+// run runs Alpha until it's signaled to stop. A non-nil emb runs it embedded, see RunEmbedded.
+func run(emb *embedding) {
 	var err error
 	if Alpha.Conf.GetBool("enable_sentry") {
 		x.InitSentry(enc.EeBuild)
@@ -645,10 +1004,18 @@ func run() {
 
 	ctype, clevel := x.ParseCompression(Alpha.Conf.GetString("badger.compression"))
 
+	engine := Alpha.Conf.GetString("storage_engine")
+	if Alpha.Conf.GetBool("badger.in_memory") {
+		x.AssertTruef(engine == worker.DefaultStorageEngine || engine == worker.MemoryStorageEngine,
+			"badger.in_memory can't be used with --storage_engine=%s", engine)
+		engine = worker.MemoryStorageEngine
+	}
+
 	conf := audit.GetAuditConf(Alpha.Conf.GetString("audit"))
 	opts := worker.Options{
 		PostingDir:                 Alpha.Conf.GetString("postings"),
 		WALDir:                     Alpha.Conf.GetString("wal"),
+		StorageEngine:              engine,
 		PostingDirCompression:      ctype,
 		PostingDirCompressionLevel: clevel,
 		CachePercentage:            cachePercentage,
@@ -693,7 +1060,7 @@ func run() {
 
 	worker.SetConfiguration(&opts)
 
-	ips, err := getIPsFromString(Alpha.Conf.GetString("whitelist"))
+	ips, err := x.GetIPsFromString(Alpha.Conf.GetString("whitelist"))
 	x.Check(err)
 
 	abortDur, err := time.ParseDuration(Alpha.Conf.GetString("abort_older_than"))
@@ -705,12 +1072,60 @@ func run() {
 	x.Check(err)
 
 	raft := z.NewSuperFlag(Alpha.Conf.GetString("raft")).MergeAndCheckDefault(worker.RaftDefaults)
+	x.AssertTruef(raft.GetInt64("replay-concurrency") > 0,
+		"raft.replay-concurrency must be a positive number")
+	disk := z.NewSuperFlag(Alpha.Conf.GetString("disk")).MergeAndCheckDefault(worker.DiskDefaults)
+	shedding := z.NewSuperFlag(Alpha.Conf.GetString("shedding")).MergeAndCheckDefault(
+		x.ShedDefaults)
+	cacheTier := z.NewSuperFlag(Alpha.Conf.GetString("cache_tier")).MergeAndCheckDefault(
+		posting.CacheTierDefaults)
+	hotKeys := z.NewSuperFlag(Alpha.Conf.GetString("hot_keys")).MergeAndCheckDefault(
+		posting.HotKeysDefaults)
+	rollup := z.NewSuperFlag(Alpha.Conf.GetString("rollup")).MergeAndCheckDefault(
+		posting.RollupDefaults)
+	commitHook := z.NewSuperFlag(Alpha.Conf.GetString("commit_hook")).MergeAndCheckDefault(
+		worker.CommitHookDefaults)
+	tieredStorage := z.NewSuperFlag(Alpha.Conf.GetString("tiered_storage")).MergeAndCheckDefault(
+		worker.TieredStorageDefaults)
+	walArchive := z.NewSuperFlag(Alpha.Conf.GetString("wal_archive")).MergeAndCheckDefault(
+		worker.WALArchiveDefaults)
+	searchSync := z.NewSuperFlag(Alpha.Conf.GetString("search_sync")).MergeAndCheckDefault(
+		worker.SearchSyncDefaults)
+	indexVerify := z.NewSuperFlag(Alpha.Conf.GetString("index_verify")).MergeAndCheckDefault(
+		worker.IndexVerifyDefaults)
+	pool := z.NewSuperFlag(Alpha.Conf.GetString("pool")).MergeAndCheckDefault(conn.PoolDefaults)
+	x.Checkf(conn.SetPoolConfig(pool), "Invalid --pool flag")
+	auditTrail := z.NewSuperFlag(Alpha.Conf.GetString("audit_trail")).MergeAndCheckDefault(
+		edgraph.AuditTrailDefaults)
+	x.Checkf(edgraph.SetAuditTrail(auditTrail), "Invalid --audit_trail flag")
+	erasure := z.NewSuperFlag(Alpha.Conf.GetString("erasure")).MergeAndCheckDefault(
+		edgraph.ErasureDefaults)
+	x.Checkf(edgraph.SetErasure(erasure), "Invalid --erasure flag")
+	slowQuery := z.NewSuperFlag(Alpha.Conf.GetString("slow_query")).MergeAndCheckDefault(
+		edgraph.SlowQueryDefaults)
+	x.Checkf(edgraph.SetSlowQueryLog(slowQuery), "Invalid --slow_query flag")
+	x.Checkf(edgraph.SetQueryPolicies(Alpha.Conf.GetString("query_policy")),
+		"Invalid --query_policy flag")
+	zeroAddrs := strings.Split(Alpha.Conf.GetString("zero"), ",")
+	for i, addr := range zeroAddrs {
+		zeroAddrs[i] = x.NormalizeAddress(strings.TrimSpace(addr))
+	}
 	x.WorkerConfig = x.WorkerOptions{
 		TmpDir:               Alpha.Conf.GetString("tmp"),
 		ExportPath:           Alpha.Conf.GetString("export"),
 		NumPendingProposals:  Alpha.Conf.GetInt("pending_proposals"),
-		ZeroAddr:             strings.Split(Alpha.Conf.GetString("zero"), ","),
+		ZeroAddr:             zeroAddrs,
 		Raft:                 raft,
+		Disk:                 disk,
+		Shedding:             shedding,
+		CacheTier:            cacheTier,
+		HotKeys:              hotKeys,
+		Rollup:               rollup,
+		CommitHook:           commitHook,
+		TieredStorage:        tieredStorage,
+		WALArchive:           walArchive,
+		SearchSync:           searchSync,
+		IndexVerify:          indexVerify,
 		WhiteListedIPRanges:  ips,
 		MaxRetries:           Alpha.Conf.GetInt("max_retries"),
 		StrictMutations:      opts.MutationsMode == worker.StrictMutations,
@@ -733,17 +1148,56 @@ func run() {
 		glog.Infof("unable to read key %v", err)
 		return
 	}
+	if keyFile := Alpha.Conf.GetString("backup_public_key_file"); keyFile != "" {
+		if x.WorkerConfig.BackupPublicKey, err = enc.ReadPublicKey(keyFile); err != nil {
+			glog.Infof("unable to read backup public key %v", err)
+			return
+		}
+	}
+	if keyFile := Alpha.Conf.GetString("backup_signing_key_file"); keyFile != "" {
+		if x.WorkerConfig.BackupSigningKey, err = enc.ReadSigningKey(keyFile); err != nil {
+			glog.Infof("unable to read backup signing key %v", err)
+			return
+		}
+	}
 
 	setupCustomTokenizers()
 	x.Init()
 	x.Config.PortOffset = Alpha.Conf.GetInt("port_offset")
 	x.Config.QueryEdgeLimit = cast.ToUint64(Alpha.Conf.GetString("query_edge_limit"))
+	x.Config.QueryMemoryLimit = Alpha.Conf.GetInt64("query_memory_mb") << 20
+	x.Config.MaxResponseBytes = cast.ToUint64(Alpha.Conf.GetString("max_response_bytes"))
+	x.Config.BestEffortMaxLag = cast.ToUint64(Alpha.Conf.GetString("best_effort_max_lag"))
 	x.Config.NormalizeNodeLimit = cast.ToInt(Alpha.Conf.GetString("normalize_node_limit"))
 	x.Config.MutationsNQuadLimit = cast.ToInt(Alpha.Conf.GetString("mutations_nquad_limit"))
+	x.Config.MutationStreamLimit = Alpha.Conf.GetInt64("mutation_stream_mb") << 20
 	x.Config.PollInterval = Alpha.Conf.GetDuration("graphql_poll_interval")
 	x.Config.GraphqlExtension = Alpha.Conf.GetBool("graphql_extensions")
 	x.Config.GraphqlDebug = Alpha.Conf.GetBool("graphql_debug")
+	x.Config.GraphqlCacheMB = Alpha.Conf.GetInt64("graphql_cache_mb")
 	x.Config.GraphqlLambdaUrl = Alpha.Conf.GetString("graphql_lambda_url")
+	x.Config.GraphqlLambdaTimeout = Alpha.Conf.GetDuration("graphql_lambda_timeout")
+	x.Config.GraphqlLambdaWasmMemoryMB = Alpha.Conf.GetInt64("graphql_lambda_wasm_memory_mb")
+	lambdaConf := lambda.Config{
+		Num:          Alpha.Conf.GetInt("graphql_lambda_num"),
+		Port:         Alpha.Conf.GetInt("graphql_lambda_port") + x.Config.PortOffset,
+		Cmd:          Alpha.Conf.GetString("graphql_lambda_cmd"),
+		DgraphURL:    localHTTPURL(),
+		RestartAfter: Alpha.Conf.GetDuration("graphql_lambda_restart_after"),
+	}
+	if lambdaConf.Num > 0 {
+		if x.Config.GraphqlLambdaUrl != "" {
+			glog.Errorf("graphql_lambda_url can't be used with graphql_lambda_num")
+			return
+		}
+		if strings.TrimSpace(lambdaConf.Cmd) == "" {
+			glog.Errorf("graphql_lambda_cmd must be set to run lambda servers")
+			return
+		}
+		// The URL of the lambda server is part of the GraphQL schema, but the requests are sent
+		// to whichever of the lambda servers run by Alpha is healthy.
+		x.Config.GraphqlLambdaUrl = lambda.FirstURL(lambdaConf)
+	}
 	if x.Config.GraphqlLambdaUrl != "" {
 		graphqlLambdaUrl, err := url.Parse(x.Config.GraphqlLambdaUrl)
 		if err != nil {
@@ -757,6 +1211,27 @@ func run() {
 		}
 	}
 
+	if nsRoutes, err = parseNamespaceRoutes(
+		Alpha.Conf.GetString("graphql_namespace_routes")); err != nil {
+		glog.Errorf("unable to parse graphql_namespace_routes: %v", err)
+		return
+	}
+	if !x.WorkerConfig.AclEnabled && nsRoutes.hasNamespaces() {
+		glog.Errorf("graphql_namespace_routes can only route to namespaces other than 0 " +
+			"with ACL enabled")
+		return
+	}
+
+	guests := Alpha.Conf.GetString("acl_guest")
+	if err := edgraph.SetGuestProfiles(guests); err != nil {
+		glog.Errorf("unable to parse acl_guest: %v", err)
+		return
+	}
+	if !x.WorkerConfig.AclEnabled && strings.TrimSpace(guests) != "" {
+		glog.Errorf("acl_guest can only be used with ACL enabled")
+		return
+	}
+
 	x.PrintVersion()
 	glog.Infof("x.Config: %+v", x.Config)
 	glog.Infof("x.WorkerConfig: %+v", x.WorkerConfig)
@@ -782,6 +1257,10 @@ func run() {
 	// schema before calling posting.Init().
 	schema.Init(worker.State.Pstore)
 	posting.Init(worker.State.Pstore, postingListCacheSize)
+	x.Checkf(posting.InitCacheTier(x.WorkerConfig.CacheTier,
+		x.WorkerConfig.EncryptionKey), "Invalid --cache_tier flag")
+	x.Checkf(posting.InitHotKeys(x.WorkerConfig.HotKeys), "Invalid --hot_keys flag")
+	x.Checkf(posting.InitRollups(x.WorkerConfig.Rollup), "Invalid --rollup flag")
 	defer posting.Cleanup()
 	worker.Init(worker.State.Pstore)
 
@@ -792,8 +1271,18 @@ func run() {
 		signal.Stop(sdCh)
 		close(sdCh)
 	}()
-	// sigint : Ctrl-C, sigterm : kill command.
-	signal.Notify(sdCh, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	if emb == nil {
+		// sigint : Ctrl-C, sigterm : kill command.
+		signal.Notify(sdCh, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	} else {
+		go func() {
+			select {
+			case <-emb.stop:
+				admin.ServerCloser.Signal()
+			case <-admin.ServerCloser.HasBeenClosed():
+			}
+		}()
+	}
 	go func() {
 		var numShutDownSig int
 		for range sdCh {
@@ -818,7 +1307,8 @@ func run() {
 		}
 	}()
 
-	updaters := z.NewCloser(2)
+	updaters := z.NewCloser(3)
+	x.Checkf(x.InitGovernor(x.WorkerConfig.Shedding), "Invalid --shedding flag")
 	go func() {
 		worker.StartRaftNodes(worker.State.WALstore, bindall)
 		atomic.AddUint32(&initDone, 1)
@@ -826,6 +1316,8 @@ func run() {
 		// initialization of the admin account can only be done after raft nodes are running
 		// and health check passes
 		edgraph.ResetAcl(updaters)
+		// The erasures are applied again if the restore command ran while the Alphas were down.
+		go edgraph.ReapplyPendingErasures(updaters)
 		edgraph.RefreshAcls(updaters)
 	}()
 
@@ -833,6 +1325,10 @@ func run() {
 	// close alpha. This closer is for closing and waiting that subscription.
 	adminCloser := z.NewCloser(1)
 
+	lambda.SetLoader(edgraph.GetLambdaScripts)
+	lambdaCloser := z.NewCloser(0)
+	x.Checkf(lambda.Start(lambdaConf, lambdaCloser), "Unable to start the lambda servers")
+
 	setupServer(adminCloser)
 	glog.Infoln("GRPC and HTTP stopped.")
 
@@ -846,6 +1342,9 @@ func run() {
 	adminCloser.SignalAndWait()
 	glog.Infoln("adminCloser closed.")
 
+	lambdaCloser.SignalAndWait()
+	glog.Infoln("lambda servers stopped.")
+
 	audit.Close()
 
 	worker.State.Dispose()
@@ -1437,36 +1437,36 @@ func TestIPStringParsing(t *testing.T) {
 	var addrRange []x.IPRange
 	var err error
 
-	addrRange, err = getIPsFromString("144.142.126.222:144.142.126.244")
+	addrRange, err = x.GetIPsFromString("144.142.126.222:144.142.126.244")
 	require.NoError(t, err)
 	require.Equal(t, net.IPv4(144, 142, 126, 222), addrRange[0].Lower)
 	require.Equal(t, net.IPv4(144, 142, 126, 244), addrRange[0].Upper)
 
-	addrRange, err = getIPsFromString("144.142.126.254")
+	addrRange, err = x.GetIPsFromString("144.142.126.254")
 	require.NoError(t, err)
 	require.Equal(t, net.IPv4(144, 142, 126, 254), addrRange[0].Lower)
 	require.Equal(t, net.IPv4(144, 142, 126, 254), addrRange[0].Upper)
 
-	addrRange, err = getIPsFromString("192.168.0.0/16")
+	addrRange, err = x.GetIPsFromString("192.168.0.0/16")
 	require.NoError(t, err)
 	require.Equal(t, net.IPv4(192, 168, 0, 0), addrRange[0].Lower)
 	require.Equal(t, net.IPv4(192, 168, 255, 255), addrRange[0].Upper)
 
-	addrRange, err = getIPsFromString("example.org")
+	addrRange, err = x.GetIPsFromString("example.org")
 	require.NoError(t, err)
 	require.NotEqual(t, net.IPv4zero, addrRange[0].Lower)
 
-	addrRange, err = getIPsFromString("144.142.126.222:144.142.126.244,144.142.126.254" +
+	addrRange, err = x.GetIPsFromString("144.142.126.222:144.142.126.244,144.142.126.254" +
 		",192.168.0.0/16,example.org")
 	require.NoError(t, err)
 	require.NotEqual(t, 0, len(addrRange))
 
-	addrRange, err = getIPsFromString("fd03:b188:0f3c:9ec4::babe:face")
+	addrRange, err = x.GetIPsFromString("fd03:b188:0f3c:9ec4::babe:face")
 	require.NoError(t, err)
 	require.NotEqual(t, net.IPv6zero, addrRange[0].Lower)
 	require.Equal(t, addrRange[0].Lower, addrRange[0].Upper)
 
-	addrRange, err = getIPsFromString("fd03:b188:0f3c:9ec4::/64")
+	addrRange, err = x.GetIPsFromString("fd03:b188:0f3c:9ec4::/64")
 	require.NoError(t, err)
 	require.NotEqual(t, net.IPv6zero, addrRange[0].Lower)
 	require.NotEqual(t, addrRange[0].Lower, addrRange[0].Upper)
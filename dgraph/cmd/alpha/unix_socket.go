@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alpha
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/pkg/errors"
+)
+
+const unixSocketDefaults = `grpc=; http=; mode=0660;`
+
+// unixListeners returns the listeners on the Unix domain sockets set in the --unix_socket flag,
+// for the gRPC and the HTTP APIs. A listener is nil if its socket isn't set.
+func unixListeners(sf *z.SuperFlag) (grpcListener, httpListener net.Listener, err error) {
+	mode, err := strconv.ParseUint(sf.GetString("mode"), 8, 32)
+	if err != nil || os.FileMode(mode)&^os.ModePerm != 0 {
+		return nil, nil, errors.Errorf("Invalid mode %q, it must be octal permission bits like "+
+			"0660", sf.GetString("mode"))
+	}
+	grpcPath, httpPath := sf.GetString("grpc"), sf.GetString("http")
+	if grpcPath != "" && grpcPath == httpPath {
+		return nil, nil, errors.Errorf("The gRPC and the HTTP sockets can't be the same")
+	}
+	if grpcPath != "" {
+		if grpcListener, err = setupUnixListener(grpcPath, os.FileMode(mode)); err != nil {
+			return nil, nil, err
+		}
+	}
+	if httpPath != "" {
+		if httpListener, err = setupUnixListener(httpPath, os.FileMode(mode)); err != nil {
+			if grpcListener != nil {
+				_ = grpcListener.Close()
+			}
+			return nil, nil, err
+		}
+	}
+	return grpcListener, httpListener, nil
+}
+
+// setupUnixListener listens on the Unix domain socket at path, with the permissions mode. A socket
+// left behind by a previous run is replaced, unless a server is still listening on it.
+func setupUnixListener(path string, mode os.FileMode) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, errors.Errorf("%s already exists and isn't a socket", path)
+		}
+		if c, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			_ = c.Close()
+			return nil, errors.Errorf("%s is already in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, errors.Wrapf(err, "while removing the stale socket %s", path)
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	// The socket is created with the permissions allowed by the umask, so set them afterwards.
+	if err := os.Chmod(path, mode); err != nil {
+		_ = l.Close()
+		return nil, errors.Wrapf(err, "while setting the permissions of %s", path)
+	}
+	return l, nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alpha
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnixListeners(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sockets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	grpcPath, httpPath := filepath.Join(dir, "grpc.sock"), filepath.Join(dir, "http.sock")
+
+	sf := z.NewSuperFlag("grpc=" + grpcPath + "; http=" + httpPath + "; mode=0600").
+		MergeAndCheckDefault(unixSocketDefaults)
+	grpcListener, httpListener, err := unixListeners(sf)
+	require.NoError(t, err)
+	fi, err := os.Stat(grpcPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	// The sockets in use can't be taken over.
+	_, _, err = unixListeners(sf)
+	require.Error(t, err)
+	require.NoError(t, grpcListener.Close())
+	require.NoError(t, httpListener.Close())
+
+	// A stale socket is replaced.
+	l, err := net.Listen("unix", grpcPath)
+	require.NoError(t, err)
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, l.Close())
+	grpcListener, httpListener, err = unixListeners(sf)
+	require.NoError(t, err)
+	require.NoError(t, grpcListener.Close())
+	require.NoError(t, httpListener.Close())
+
+	grpcListener, httpListener, err = unixListeners(z.NewSuperFlag("").
+		MergeAndCheckDefault(unixSocketDefaults))
+	require.NoError(t, err)
+	require.Nil(t, grpcListener)
+	require.Nil(t, httpListener)
+
+	for _, flag := range []string{"mode=rw", "mode=01777", "grpc=" + grpcPath + "; http=" +
+		grpcPath, "grpc=" + filepath.Join(dir, "missing", "grpc.sock")} {
+		_, _, err := unixListeners(z.NewSuperFlag(flag).MergeAndCheckDefault(unixSocketDefaults))
+		require.Error(t, err, flag)
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0600))
+	_, _, err = unixListeners(z.NewSuperFlag("http=" + filepath.Join(dir, "file")).
+		MergeAndCheckDefault(unixSocketDefaults))
+	require.Error(t, err)
+}
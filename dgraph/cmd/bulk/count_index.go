@@ -100,7 +100,8 @@ func (c *countIndexer) addCountEntry(ce countEntry) {
 		}
 		c.cur.pred = pk.Attr
 		c.cur.rev = pk.IsReverse()
-		c.cur.track = c.schema.getSchema(pk.Attr).GetCount()
+		su := c.schema.getSchema(pk.Attr)
+		c.cur.track = su.GetCount() || su.GetPresence()
 	}
 	if c.cur.track {
 		dst := c.countBuf.SliceAllocate(len(ce))
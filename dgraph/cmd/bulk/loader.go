@@ -17,8 +17,8 @@
 package bulk
 
 import (
+	"bufio"
 	"bytes"
-	"compress/gzip"
 	"context"
 	"fmt"
 	"hash/adler32"
@@ -70,6 +70,7 @@ type options struct {
 	CustomTokenizers string
 	NewUids          bool
 	ClientDir        string
+	XidRegistry      bool
 	Encrypted        bool
 	EncryptedOut     bool
 
@@ -77,6 +78,17 @@ type options struct {
 	ReduceShards int
 
 	Namespace uint64
+	// fileNamespaces maps the data files to the namespaces they are loaded into, instead of
+	// Namespace.
+	fileNamespaces []fileNamespace
+	// grootPassword is the password of the groot users created in the namespaces the data is
+	// loaded into, other than the galaxy one. No groot is created if empty.
+	grootPassword string
+
+	// InferSchema is report to print the schema inferred from the input and exit, or apply to
+	// add it for the predicates missing from the schema file.
+	InferSchema string
+	inferred    *chunker.SchemaInferrer
 
 	shardOutputDirs []string
 
@@ -97,7 +109,7 @@ type state struct {
 	xids          *xidmap.XidMap
 	schema        *schemaStore
 	shards        *shardMap
-	readerChunkCh chan *bytes.Buffer
+	readerChunkCh chan *loadChunk
 	mapFileId     uint32 // Used atomically to name the output files of the mappers.
 	dbs           []*badger.DB
 	tmpDbs        []*badger.DB // Temporary DB to write the split lists to avoid ordering issues.
@@ -105,6 +117,12 @@ type state struct {
 	namespaces    *sync.Map    // To store the encountered namespaces.
 }
 
+// loadChunk is a chunk of a data file, along with the namespace its N-Quads are loaded into.
+type loadChunk struct {
+	buf *bytes.Buffer
+	ns  uint64 // math.MaxUint64 to keep the namespaces of the N-Quads.
+}
+
 type loader struct {
 	*state
 	mappers []*mapper
@@ -137,7 +155,7 @@ func newLoader(opt *options) *loader {
 		prog:   newProgress(),
 		shards: newShardMap(opt.MapShards),
 		// Lots of gz readers, so not much channel buffer needed.
-		readerChunkCh: make(chan *bytes.Buffer, opt.NumGoroutines),
+		readerChunkCh: make(chan *loadChunk, opt.NumGoroutines),
 		writeTs:       getWriteTimestamp(zero),
 		namespaces:    &sync.Map{},
 	}
@@ -199,27 +217,76 @@ func (ld *loader) leaseNamespaces() {
 }
 
 func readSchema(opt *options) *schema.ParsedSchema {
-	f, err := filestore.Open(opt.SchemaFile)
-	x.Check(err)
-	defer f.Close()
+	var buf []byte
+	if opt.SchemaFile != "" {
+		f, err := filestore.Open(opt.SchemaFile)
+		x.Check(err)
+		defer f.Close()
 
+		key := opt.EncryptionKey
+		if !opt.Encrypted {
+			key = nil
+		}
+		r, err := enc.GetReader(key, f)
+		x.Check(err)
+		r, err = chunker.DecompressReader(opt.SchemaFile, r)
+		x.Check(err)
+
+		buf, err = ioutil.ReadAll(r)
+		x.Check(err)
+	}
+
+	text := string(buf)
+	if opt.inferred != nil {
+		result, err := schema.ParseWithNamespace(text, opt.Namespace)
+		x.Check(err)
+		// The schema file has the last word on the predicates it defines.
+		defined := make(map[string]bool)
+		for _, su := range result.Preds {
+			defined[x.ParseAttr(su.Predicate)] = true
+		}
+		inferred := opt.inferred.Schema(func(pred string) bool { return defined[pred] })
+		fmt.Printf("Adding the inferred schema:\n%s\n", inferred)
+		text += "\n" + inferred
+	}
+
+	result := &schema.ParsedSchema{}
+	for _, ns := range opt.schemaNamespaces() {
+		parsed, err := schema.ParseWithNamespace(text, ns)
+		x.Check(err)
+		result.Preds = append(result.Preds, parsed.Preds...)
+		result.Types = append(result.Types, parsed.Types...)
+	}
+	return result
+}
+
+// inferSchema reads the data files to infer the schema of their predicates and prints it. It
+// exits in the report mode.
+func inferSchema(opt *options) {
+	fs := filestore.NewFileStore(opt.DataFiles)
+	files := fs.FindDataFiles(opt.DataFiles, chunker.DataFileExtensions)
+	if len(files) == 0 {
+		fmt.Printf("No data files found in %s.\n", opt.DataFiles)
+		os.Exit(1)
+	}
 	key := opt.EncryptionKey
 	if !opt.Encrypted {
 		key = nil
 	}
-	r, err := enc.GetReader(key, f)
-	x.Check(err)
-	if filepath.Ext(opt.SchemaFile) == ".gz" {
-		r, err = gzip.NewReader(r)
-		x.Check(err)
-	}
 
-	buf, err := ioutil.ReadAll(r)
+	fmt.Printf("Inferring the schema of %d data file(s)\n", len(files))
+	si, err := chunker.InferSchema(files, opt.DataFormat, func(file string) (*bufio.Reader, func()) {
+		return fs.ChunkReader(file, key)
+	})
 	x.Check(err)
+	x.Check(si.WriteReport(os.Stdout))
+	fmt.Println()
 
-	result, err := schema.ParseWithNamespace(string(buf), opt.Namespace)
-	x.Check(err)
-	return result
+	if opt.InferSchema == "report" {
+		fmt.Printf("Inferred schema:\n%s", si.Schema(nil))
+		os.Exit(0)
+	}
+	opt.inferred = si
 }
 
 func (ld *loader) mapStage() {
@@ -233,22 +300,26 @@ func (ld *loader) mapStage() {
 		x.Checkf(err, "Error while creating badger KV posting store")
 	}
 	ld.xids = xidmap.New(ld.zero, db, filepath.Join(ld.opt.TmpDir, bufferDir))
+	if ld.opt.XidRegistry {
+		ld.xids.UseRegistry()
+	}
 
 	fs := filestore.NewFileStore(ld.opt.DataFiles)
 
-	files := fs.FindDataFiles(ld.opt.DataFiles, []string{".rdf", ".rdf.gz", ".json", ".json.gz"})
+	files := fs.FindDataFiles(ld.opt.DataFiles, chunker.DataFileExtensions)
 	if len(files) == 0 {
 		fmt.Printf("No data files found in %s.\n", ld.opt.DataFiles)
 		os.Exit(1)
 	}
 
 	// Because mappers must handle chunks that may be from different input files, they must all
-	// assume the same data format, either RDF or JSON. Use the one specified by the user or by
-	// the first load file.
+	// assume the same data format, either RDF or JSON. Turtle and HDT files are chunked into
+	// N-Quads, so they count as RDF. Use the one specified by the user or by the first load file.
 	loadType := chunker.DataFormat(files[0], ld.opt.DataFormat)
 	if loadType == chunker.UnknownFormat {
 		// Dont't try to detect JSON input in bulk loader.
-		fmt.Printf("Need --format=rdf or --format=json to load %s", files[0])
+		fmt.Printf("Need --format=rdf, --format=json, --format=turtle or --format=hdt to load %s",
+			files[0])
 		os.Exit(1)
 	}
 
@@ -269,6 +340,7 @@ func (ld *loader) mapStage() {
 
 		go func(file string) {
 			defer thr.Done(nil)
+			ns := ld.opt.fileNamespace(file)
 
 			key := ld.opt.EncryptionKey
 			if !ld.opt.Encrypted {
@@ -281,7 +353,7 @@ func (ld *loader) mapStage() {
 			for {
 				chunkBuf, err := chunk.Chunk(r)
 				if chunkBuf != nil && chunkBuf.Len() > 0 {
-					ld.readerChunkCh <- chunkBuf
+					ld.readerChunkCh <- &loadChunk{buf: chunkBuf, ns: ns}
 				}
 				if err == io.EOF {
 					break
@@ -293,11 +365,11 @@ func (ld *loader) mapStage() {
 	}
 	x.Check(thr.Finish())
 
-	// Send the graphql triples
+	// Send the graphql triples, into the namespace given by --force-namespace.
 	// TODO(Naman): Handle this. Currently we are not attaching the namespace info with the exported
-	// graphql schema (See exportInternal). Also, attach the namespace information once for the
-	// namespace we are loading into.
+	// graphql schema (See exportInternal).
 	ld.processGqlSchema(loadType)
+	ld.processGroots(loadType)
 
 	close(ld.readerChunkCh)
 	mapperWg.Wait()
@@ -329,10 +401,8 @@ func (ld *loader) processGqlSchema(loadType chunker.InputFormat) {
 	}
 	r, err := enc.GetReader(key, f)
 	x.Check(err)
-	if filepath.Ext(ld.opt.GqlSchemaFile) == ".gz" {
-		r, err = gzip.NewReader(r)
-		x.Check(err)
-	}
+	r, err = chunker.DecompressReader(ld.opt.GqlSchemaFile, r)
+	x.Check(err)
 
 	buf, err := ioutil.ReadAll(r)
 	x.Check(err)
@@ -354,12 +424,12 @@ func (ld *loader) processGqlSchema(loadType chunker.InputFormat) {
 	gqlBuf := &bytes.Buffer{}
 	schema := strconv.Quote(string(buf))
 	switch loadType {
-	case chunker.RdfFormat:
+	case chunker.RdfFormat, chunker.TurtleFormat, chunker.HdtFormat:
 		x.Check2(gqlBuf.Write([]byte(fmt.Sprintf(rdfSchema, schema))))
 	case chunker.JsonFormat:
 		x.Check2(gqlBuf.Write([]byte(fmt.Sprintf(jsonSchema, schema))))
 	}
-	ld.readerChunkCh <- gqlBuf
+	ld.readerChunkCh <- &loadChunk{buf: gqlBuf, ns: ld.opt.Namespace}
 }
 
 func (ld *loader) reduceStage() {
@@ -20,7 +20,9 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"math"
@@ -56,6 +58,12 @@ type shardState struct {
 	mu   sync.Mutex // Allow only 1 write per shard at a time.
 }
 
+// nquadBatch holds parsed N-Quads, along with the namespace they are loaded into.
+type nquadBatch struct {
+	ns  uint64 // math.MaxUint64 to keep the namespaces of the N-Quads.
+	nqs []*api.NQuad
+}
+
 func newMapperBuffer(opt *options) *z.Buffer {
 	sz := float64(opt.MapBufSize) * 1.1
 	buf, err := z.NewBufferWithDir(int(sz), 2*int(opt.MapBufSize), z.UseMmap,
@@ -220,29 +228,64 @@ func (m *mapper) writeMapEntriesToFile(cbuf *z.Buffer, shardIdx int) {
 }
 
 func (m *mapper) run(inputFormat chunker.InputFormat) {
-	chunk := chunker.NewChunker(inputFormat, 1000)
-	nquads := chunk.NQuads()
+	batches := make(chan *nquadBatch, 10)
 	go func() {
-		for chunkBuf := range m.readerChunkCh {
-			if err := chunk.Parse(chunkBuf); err != nil {
+		// The chunks are parsed by a chunker per namespace, so that the N-Quads of a batch are
+		// all loaded into the same namespace.
+		chunkers := make(map[uint64]chunker.Chunker)
+		var wg sync.WaitGroup
+		for c := range m.readerChunkCh {
+			chunk, ok := chunkers[c.ns]
+			if !ok {
+				chunk = chunker.NewChunker(inputFormat, 1000)
+				chunkers[c.ns] = chunk
+				wg.Add(1)
+				go func(ns uint64, nquads <-chan []*api.NQuad) {
+					defer wg.Done()
+					for nqs := range nquads {
+						batches <- &nquadBatch{ns: ns, nqs: nqs}
+					}
+				}(c.ns, chunk.NQuads().Ch())
+			}
+			if err := chunk.Parse(c.buf); err != nil {
 				atomic.AddInt64(&m.prog.errCount, 1)
 				if !m.opt.IgnoreErrors {
 					x.Check(err)
 				}
 			}
 		}
-		nquads.Flush()
+		for _, chunk := range chunkers {
+			chunk.NQuads().Flush()
+		}
+		wg.Wait()
+		close(batches)
 	}()
 
-	for nqs := range nquads.Ch() {
-		for _, nq := range nqs {
+	for batch := range batches {
+		if m.opt.XidRegistry {
+			m.prefetchUids(batch)
+		}
+		for _, nq := range batch.nqs {
+			if batch.ns != math.MaxUint64 {
+				nq.Namespace = batch.ns
+			}
 			if err := facets.SortAndValidate(nq.Facets); err != nil {
 				atomic.AddInt64(&m.prog.errCount, 1)
 				if !m.opt.IgnoreErrors {
 					x.Check(err)
 				}
 			}
+			if _, _, _, ok := chunker.ParseQuotedTriple(nq.Subject); ok {
+				// Edge properties are stored on edge nodes which are only maintained by Alpha.
+				atomic.AddInt64(&m.prog.errCount, 1)
+				if !m.opt.IgnoreErrors {
+					x.Check(fmt.Errorf("Edge properties can't be bulk loaded, use the live"+
+						" loader: %s", nq.Subject))
+				}
+				continue
+			}
 
+			m.addLineage(nq)
 			m.processNQuad(gql.NQuad{NQuad: nq})
 			atomic.AddInt64(&m.prog.nquadCount, 1)
 		}
@@ -290,10 +333,6 @@ func (m *mapper) addMapEntry(key []byte, p *pb.Posting, shard int) {
 }
 
 func (m *mapper) processNQuad(nq gql.NQuad) {
-	if m.opt.Namespace != math.MaxUint64 {
-		// Use the specified namespace passed through '--force-namespace' flag.
-		nq.Namespace = m.opt.Namespace
-	}
 	sid := m.uid(nq.GetSubject(), nq.Namespace)
 	if sid == 0 {
 		panic(fmt.Sprintf("invalid UID with value 0 for %v", nq.GetSubject()))
@@ -328,6 +367,77 @@ func (m *mapper) processNQuad(nq gql.NQuad) {
 	m.addIndexMapEntries(nq, de)
 }
 
+// addLineage replaces the provenance held by the lineage facet of an exported N-Quad with the
+// uid of a lineage node holding it, like the Alpha does for the lineage of a mutation. The
+// N-Quads with the same provenance share the lineage node, which links to their subjects.
+func (m *mapper) addLineage(nq *api.NQuad) {
+	for i, f := range nq.Facets {
+		if f.Key != x.LineageFacet {
+			continue
+		}
+		if f.ValType != api.Facet_STRING {
+			// The uid of a lineage node can't be carried over, drop it.
+			nq.Facets = append(nq.Facets[:i], nq.Facets[i+1:]...)
+			return
+		}
+
+		sum := sha256.Sum256(f.Value)
+		xid := "_:dgraph.lineage." + hex.EncodeToString(sum[:])
+		uid, isNew := m.xids.AssignUid(x.NamespaceAttr(nq.Namespace, xid))
+		if isNew {
+			m.processNQuad(gql.NQuad{NQuad: &api.NQuad{
+				Subject:     xid,
+				Predicate:   x.LineageBlob,
+				ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: string(f.Value)}},
+				Namespace:   nq.Namespace,
+			}})
+			m.processNQuad(gql.NQuad{NQuad: &api.NQuad{
+				Subject:     xid,
+				Predicate:   "dgraph.type",
+				ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: "dgraph.lineage"}},
+				Namespace:   nq.Namespace,
+			}})
+		}
+		m.processNQuad(gql.NQuad{NQuad: &api.NQuad{
+			Subject:   xid,
+			Predicate: x.LineageSubject,
+			ObjectId:  nq.Subject,
+			Namespace: nq.Namespace,
+		}})
+
+		fct, err := facets.FacetFor(x.LineageFacet, strconv.FormatUint(uid, 10))
+		x.Check(err)
+		nq.Facets[i] = fct
+		return
+	}
+}
+
+// prefetchUids gets the UIDs of the xids of the batch from the xid registry of Zero, with one
+// request per namespace.
+func (m *mapper) prefetchUids(batch *nquadBatch) {
+	var keys []string
+	add := func(xid string, ns uint64) {
+		// UIDs are kept as they are, see uid.
+		if !m.opt.NewUids {
+			if _, err := strconv.ParseUint(xid, 0, 64); err == nil {
+				return
+			}
+		}
+		keys = append(keys, x.NamespaceAttr(ns, xid))
+	}
+	for _, nq := range batch.nqs {
+		ns := nq.Namespace
+		if batch.ns != math.MaxUint64 {
+			ns = batch.ns
+		}
+		add(nq.Subject, ns)
+		if len(nq.ObjectId) > 0 {
+			add(nq.ObjectId, ns)
+		}
+	}
+	m.xids.Prefetch(keys)
+}
+
 func (m *mapper) uid(xid string, ns uint64) uint64 {
 	if !m.opt.NewUids {
 		if uid, err := strconv.ParseUint(xid, 0, 64); err == nil {
@@ -437,10 +547,12 @@ func (m *mapper) addIndexMapEntries(nq gql.NQuad, de *pb.DirectedEdge) {
 		x.Check(err)
 
 		// Extract tokens.
-		toks, err := tok.BuildTokens(schemaVal.Value, tok.GetTokenizerForLang(toker, nq.Lang))
+		attr := x.NamespaceAttr(nq.Namespace, nq.Predicate)
+		toks, err := tok.BuildTokens(schemaVal.Value, tok.GetTokenizerForDictionary(
+			tok.GetTokenizerForCollation(toker, nq.Lang, sch.GetCollation()),
+			m.schema.dictionary(attr)))
 		x.Check(err)
 
-		attr := x.NamespaceAttr(nq.Namespace, nq.Predicate)
 		// Store index posting.
 		for _, t := range toks {
 			m.addMapEntry(
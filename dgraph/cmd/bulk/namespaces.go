@@ -0,0 +1,143 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bulk
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/chunker"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+)
+
+// fileNamespace loads the data files matching the pattern into the namespace.
+type fileNamespace struct {
+	pattern string
+	ns      uint64
+}
+
+// parseFileNamespaces parses the --file-namespaces flag, a comma separated list of
+// pattern=namespace pairs.
+func parseFileNamespaces(flag string) ([]fileNamespace, error) {
+	var fns []fileNamespace
+	for _, pair := range strings.Split(flag, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 {
+			return nil, errors.Errorf("invalid file namespace %q, must be pattern=namespace", pair)
+		}
+		pattern := strings.TrimSpace(pair[:i])
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern %q", pattern)
+		}
+		ns, err := strconv.ParseUint(strings.TrimSpace(pair[i+1:]), 0, 64)
+		if err != nil || ns == math.MaxUint64 {
+			return nil, errors.Errorf("invalid namespace in %q", pair)
+		}
+		fns = append(fns, fileNamespace{pattern: pattern, ns: ns})
+	}
+	return fns, nil
+}
+
+// fileNamespace returns the namespace the file is loaded into, which is the one of the first
+// pattern matching its path or its name, or --force-namespace if none does.
+func (opt *options) fileNamespace(file string) uint64 {
+	for _, fn := range opt.fileNamespaces {
+		if ok, _ := filepath.Match(fn.pattern, file); ok {
+			return fn.ns
+		}
+		if ok, _ := filepath.Match(fn.pattern, filepath.Base(file)); ok {
+			return fn.ns
+		}
+	}
+	return opt.Namespace
+}
+
+// schemaNamespaces returns the namespaces the data is loaded into, which the schema file applies
+// to. They include math.MaxUint64 if no namespace is forced, to keep the namespaces of the schema
+// file.
+func (opt *options) schemaNamespaces() []uint64 {
+	seen := map[uint64]bool{opt.Namespace: true}
+	namespaces := []uint64{opt.Namespace}
+	for _, fn := range opt.fileNamespaces {
+		if !seen[fn.ns] {
+			seen[fn.ns] = true
+			namespaces = append(namespaces, fn.ns)
+		}
+	}
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i] < namespaces[j] })
+	return namespaces
+}
+
+// processGroots creates a guardians group and a groot user in each namespace the data is loaded
+// into other than the galaxy one, like Alphas do when a namespace is created.
+func (ld *loader) processGroots(loadType chunker.InputFormat) {
+	if ld.opt.grootPassword == "" {
+		return
+	}
+	rdfGroot := `_:dgraph.guardians <dgraph.xid> %[1]s .
+	_:dgraph.guardians <dgraph.type> "dgraph.type.Group" .
+	_:dgraph.groot <dgraph.xid> %[2]s .
+	_:dgraph.groot <dgraph.password> %[3]s .
+	_:dgraph.groot <dgraph.type> "dgraph.type.User" .
+	_:dgraph.groot <dgraph.user.group> _:dgraph.guardians .
+	`
+
+	jsonGroot := `{
+		"uid": "_:dgraph.groot",
+		"dgraph.xid": %[2]s,
+		"dgraph.password": %[3]s,
+		"dgraph.type": "dgraph.type.User",
+		"dgraph.user.group": [{
+			"uid": "_:dgraph.guardians",
+			"dgraph.xid": %[1]s,
+			"dgraph.type": "dgraph.type.Group"
+		}]
+	}`
+
+	for _, ns := range ld.opt.schemaNamespaces() {
+		if ns == x.GalaxyNamespace || ns == math.MaxUint64 {
+			continue
+		}
+		buf := &bytes.Buffer{}
+		guardians, groot := strconv.Quote(x.GuardiansId), strconv.Quote(x.GrootId)
+		passwd := strconv.Quote(ld.opt.grootPassword)
+		switch loadType {
+		case chunker.RdfFormat, chunker.TurtleFormat:
+			x.Check2(fmt.Fprintf(buf, rdfGroot, guardians, groot, passwd))
+		case chunker.HdtFormat:
+			// The HDT chunker decodes the triples of the file into N-Quads and parses them with
+			// the RDF parser, so the groot is sent as N-Quads too.
+			x.Check2(fmt.Fprintf(buf, rdfGroot, guardians, groot, passwd))
+		case chunker.JsonFormat:
+			x.Check2(fmt.Fprintf(buf, jsonGroot, guardians, groot, passwd))
+		default:
+			x.Panic(errors.Errorf("unknown input format %d", loadType))
+		}
+		fmt.Printf("Creating the groot user of namespace %#x\n", ns)
+		ld.readerChunkCh <- &loadChunk{buf: buf, ns: ns}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bulk
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/chunker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestParseFileNamespaces(t *testing.T) {
+	tests := []struct {
+		flag string
+		want []fileNamespace
+		err  bool
+	}{
+		{flag: "", want: nil},
+		{flag: "a.rdf=1, b/*.rdf=2,", want: []fileNamespace{{"a.rdf", 1}, {"b/*.rdf", 2}}},
+		{flag: "x=y.rdf=3", want: []fileNamespace{{"x=y.rdf", 3}}},
+		{flag: "*.json=0x10", want: []fileNamespace{{"*.json", 16}}},
+		{flag: "a.rdf=0xfffffffffffffffe", want: []fileNamespace{{"a.rdf", math.MaxUint64 - 1}}},
+		{flag: "a.rdf", err: true},
+		{flag: "=1", err: true},
+		{flag: "a.rdf=", err: true},
+		{flag: "a.rdf=one", err: true},
+		{flag: "a.rdf=-1", err: true},
+		{flag: "[a.rdf=1", err: true},
+		{flag: "a.rdf=0xffffffffffffffff", err: true},
+		{flag: "a.rdf=18446744073709551615", err: true},
+	}
+	for _, tc := range tests {
+		fns, err := parseFileNamespaces(tc.flag)
+		if tc.err {
+			require.Error(t, err, tc.flag)
+			continue
+		}
+		require.NoError(t, err, tc.flag)
+		require.Equal(t, tc.want, fns, tc.flag)
+	}
+}
+
+func TestFileNamespace(t *testing.T) {
+	opt := &options{
+		Namespace: 5,
+		fileNamespaces: []fileNamespace{
+			{"data/a/*.rdf", 1},
+			{"b.rdf", 2},
+			{"*.json", 3},
+		},
+	}
+	tests := []struct {
+		file string
+		ns   uint64
+	}{
+		// The full path matches.
+		{"data/a/x.rdf", 1},
+		// The base name matches.
+		{"data/c/b.rdf", 2},
+		{"b.rdf", 2},
+		{"/tmp/y.json", 3},
+		// The base name alone doesn't match a pattern with a directory.
+		{"x.rdf", 5},
+		{"data/b/x.rdf", 5},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.ns, opt.fileNamespace(tc.file), tc.file)
+	}
+}
+
+func TestSchemaNamespaces(t *testing.T) {
+	opt := &options{
+		Namespace:      math.MaxUint64,
+		fileNamespaces: []fileNamespace{{"a", 7}, {"b", 2}, {"c", 7}},
+	}
+	require.Equal(t, []uint64{2, 7, math.MaxUint64}, opt.schemaNamespaces())
+
+	opt.Namespace = 2
+	require.Equal(t, []uint64{2, 7}, opt.schemaNamespaces())
+
+	opt.fileNamespaces = nil
+	require.Equal(t, []uint64{2}, opt.schemaNamespaces())
+}
+
+func TestProcessGroots(t *testing.T) {
+	for _, format := range []chunker.InputFormat{chunker.RdfFormat, chunker.TurtleFormat,
+		chunker.HdtFormat, chunker.JsonFormat} {
+		opt := &options{
+			Namespace:      x.GalaxyNamespace,
+			fileNamespaces: []fileNamespace{{"a", 3}},
+			grootPassword:  "password",
+		}
+		ld := &loader{state: &state{opt: opt, readerChunkCh: make(chan *loadChunk, 10)}}
+		ld.processGroots(format)
+		close(ld.readerChunkCh)
+
+		var chunks []*loadChunk
+		for c := range ld.readerChunkCh {
+			chunks = append(chunks, c)
+		}
+		// No groot is created in the galaxy namespace.
+		require.Len(t, chunks, 1)
+		require.Equal(t, uint64(3), chunks[0].ns)
+
+		ck := chunker.NewChunker(format, 1000)
+		require.NoError(t, ck.Parse(chunks[0].buf), format)
+		ck.NQuads().Flush()
+		var n int
+		for nqs := range ck.NQuads().Ch() {
+			n += len(nqs)
+		}
+		require.Equal(t, 6, n, format)
+	}
+}
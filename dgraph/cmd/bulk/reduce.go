@@ -551,6 +551,7 @@ func (r *reducer) toList(req *encodeRequest) {
 
 	kvBuf := z.NewBuffer(260 << 20)
 	trackCountIndex := make(map[string]bool)
+	trackPresenceIndex := make(map[string]bool)
 
 	var freePostings []*pb.Posting
 
@@ -599,6 +600,19 @@ func (r *reducer) toList(req *encodeRequest) {
 				marshalCountEntry(dst, ck, pk.Uid)
 			}
 		}
+		// The presence index is built along with the count index.
+		if pk.IsData() {
+			doPresence, ok := trackPresenceIndex[pk.Attr]
+			if !ok {
+				doPresence = r.schema.getSchema(pk.Attr).GetPresence()
+				trackPresenceIndex[pk.Attr] = doPresence
+			}
+			if doPresence {
+				presenceKey := posting.PresenceKey(pk.Attr)
+				dst := req.countBuf.SliceAllocate(countEntrySize(presenceKey))
+				marshalCountEntry(dst, presenceKey, pk.Uid)
+			}
+		}
 
 		alloc.Reset()
 		enc := codec.Encoder{BlockSize: 256, Alloc: alloc}
@@ -60,12 +60,17 @@ func init() {
 
 	flag := Bulk.Cmd.Flags()
 	flag.StringP("files", "f", "",
-		"Location of *.rdf(.gz) or *.json(.gz) file(s) to load.")
+		"Location of *.rdf, *.json, *.jsonl, *.ttl or *.hdt file(s) to load, optionally "+
+			"compressed with gzip (.gz), zstd (.zst) or xz (.xz).")
 	flag.StringP("schema", "s", "",
 		"Location of schema file.")
 	flag.StringP("graphql_schema", "g", "", "Location of the GraphQL schema file.")
+	flag.String("infer-schema", "",
+		"Scan the data files to infer the types, list-ness and indexes of their predicates. "+
+			"report prints the inferred schema and exits, apply adds it for the predicates "+
+			"missing from the schema file and loads the data.")
 	flag.String("format", "",
-		"Specify file format (rdf or json) instead of getting it from filename.")
+		"Specify file format (rdf, json, turtle or hdt) instead of getting it from filename.")
 	flag.Bool("encrypted", false,
 		"Flag to indicate whether schema and data files are encrypted. "+
 			"Must be specified with --encryption_key_file or vault option(s).")
@@ -97,6 +102,9 @@ func init() {
 	flag.Bool("store_xids", false, "Generate an xid edge for each node.")
 	flag.StringP("zero", "z", "localhost:5080", "gRPC address for Dgraph zero")
 	flag.String("xidmap", "", "Directory to store xid to uid mapping")
+	flag.Bool("xid_registry", false, "Get the UIDs of the xids from the xid registry of Zero, so "+
+		"that they match the UIDs assigned by other loaders. Blank nodes still get new UIDs. Zero "+
+		"must run with --xid_registry.")
 	// TODO: Potentially move http server to main.
 	flag.String("http", "localhost:8080",
 		"Address to serve http (pprof).")
@@ -115,6 +123,15 @@ func init() {
 		"Ignore UIDs in load files and assign new ones.")
 	flag.Uint64("force-namespace", math.MaxUint64,
 		"Namespace onto which to load the data. If not set, will preserve the namespace.")
+	flag.String("file-namespaces", "",
+		"Comma separated list of pattern=namespace pairs, loading the data files whose path or "+
+			"name matches the glob pattern into the namespace instead of --force-namespace, like "+
+			"\"acme-*.rdf.gz=1,globex-*.rdf.gz=2\". The schema file applies to each namespace.")
+	flag.String("groot-password", "",
+		"If set, a guardians group and a groot user with this password are created in each "+
+			"namespace other than the galaxy one which --force-namespace or --file-namespaces "+
+			"load data into. Leave it empty if the data holds the ACL users and groups already, "+
+			"as exports do.")
 
 	// Options around how to set up Badger.
 	flag.String("badger.compression", "snappy",
@@ -158,7 +175,10 @@ func run() {
 		CustomTokenizers: Bulk.Conf.GetString("custom_tokenizers"),
 		NewUids:          Bulk.Conf.GetBool("new_uids"),
 		ClientDir:        Bulk.Conf.GetString("xidmap"),
+		XidRegistry:      Bulk.Conf.GetBool("xid_registry"),
 		Namespace:        Bulk.Conf.GetUint64("force-namespace"),
+		InferSchema:      Bulk.Conf.GetString("infer-schema"),
+		grootPassword:    Bulk.Conf.GetString("groot-password"),
 
 		// Badger options
 		BadgerCompression:      ctype,
@@ -213,11 +233,18 @@ func run() {
 	}
 	fmt.Printf("Encrypted input: %v; Encrypted output: %v\n", opt.Encrypted, opt.EncryptedOut)
 
-	if opt.SchemaFile == "" {
+	switch opt.InferSchema {
+	case "", "report", "apply":
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid --infer-schema %q, must be report or apply.\n",
+			opt.InferSchema)
+		os.Exit(1)
+	}
+	if opt.SchemaFile == "" && opt.InferSchema == "" {
 		fmt.Fprint(os.Stderr, "Schema file must be specified.\n")
 		os.Exit(1)
 	}
-	if !filestore.Exists(opt.SchemaFile) {
+	if opt.SchemaFile != "" && !filestore.Exists(opt.SchemaFile) {
 		fmt.Fprintf(os.Stderr, "Schema path(%v) does not exist.\n", opt.SchemaFile)
 		os.Exit(1)
 	}
@@ -234,6 +261,16 @@ func run() {
 		}
 	}
 
+	opt.fileNamespaces, err = parseFileNamespaces(Bulk.Conf.GetString("file-namespaces"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --file-namespaces: %v\n", err)
+		os.Exit(1)
+	}
+
+	if opt.InferSchema != "" {
+		inferSchema(&opt)
+	}
+
 	if opt.ReduceShards > opt.MapShards {
 		fmt.Fprintf(os.Stderr, "Invalid flags: reduce_shards(%d) should be <= map_shards(%d)\n",
 			opt.ReduceShards, opt.MapShards)
@@ -26,6 +26,7 @@ import (
 	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/tok"
 	wk "github.com/dgraph-io/dgraph/worker"
 	"github.com/dgraph-io/dgraph/x"
 )
@@ -34,6 +35,8 @@ type schemaStore struct {
 	sync.RWMutex
 	schemaMap map[string]*pb.SchemaUpdate
 	types     []*pb.TypeUpdate
+	// dicts holds the dictionaries of the fulltext indexes, given in the schema file.
+	dicts map[string]*tok.Dictionary
 	*state
 }
 
@@ -44,6 +47,7 @@ func newSchemaStore(initial *schema.ParsedSchema, opt *options, state *state) *s
 
 	s := &schemaStore{
 		schemaMap: map[string]*pb.SchemaUpdate{},
+		dicts:     map[string]*tok.Dictionary{},
 		state:     state,
 	}
 
@@ -61,6 +65,17 @@ func newSchemaStore(initial *schema.ParsedSchema, opt *options, state *state) *s
 		}
 		s.checkAndSetInitialSchema(x.ParseNamespace(p))
 		s.schemaMap[p] = sch
+
+		if len(sch.FulltextSynonyms) > 0 || len(sch.FulltextProtected) > 0 {
+			synonyms := make([][]string, 0, len(sch.FulltextSynonyms))
+			for _, group := range sch.FulltextSynonyms {
+				synonyms = append(synonyms, group.Words)
+			}
+			// The dictionary is validated when the schema is parsed.
+			dict, err := tok.NewDictionary(synonyms, sch.FulltextProtected)
+			x.Check(err)
+			s.dicts[p] = dict
+		}
 	}
 
 	s.types = initial.Types
@@ -74,6 +89,11 @@ func (s *schemaStore) getSchema(pred string) *pb.SchemaUpdate {
 	return s.schemaMap[pred]
 }
 
+// dictionary returns the dictionary of the fulltext index of the predicate, or nil.
+func (s *schemaStore) dictionary(pred string) *tok.Dictionary {
+	return s.dicts[pred]
+}
+
 func (s *schemaStore) setSchemaAsList(pred string) {
 	s.Lock()
 	defer s.Unlock()
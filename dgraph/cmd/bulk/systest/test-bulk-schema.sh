@@ -202,14 +202,18 @@ EOF
       1 dgraph.acl.rule
       1 dgraph.cors
       1 dgraph.drop.op
+      1 dgraph.graphql.lambda_scripts
       1 dgraph.graphql.p_query
       1 dgraph.graphql.schema
       1 dgraph.graphql.schema_created_at
       1 dgraph.graphql.schema_history
       1 dgraph.graphql.xid
+      1 dgraph.lineage.blob
+      1 dgraph.lineage.subject
       1 dgraph.password
       1 dgraph.rule.permission
       1 dgraph.rule.predicate
+      1 dgraph.schema_history
       1 dgraph.type
       1 dgraph.user.group
       1 dgraph.xid
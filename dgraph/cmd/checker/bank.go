@@ -0,0 +1,159 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200"
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/pkg/errors"
+)
+
+// bank transfers money between accounts, and reads all the balances. Under snapshot isolation,
+// every read sees the same total, and no negative balance.
+type bank struct {
+	accounts int
+	balance  int
+}
+
+type account struct {
+	Uid     string `json:"uid"`
+	Key     int    `json:"key"`
+	Balance int    `json:"balance"`
+}
+
+const bankSchema = `
+	checker.key: int @index(int) @upsert .
+	checker.balance: int .
+`
+
+func (b *bank) setup(ctx context.Context, dg *dgo.Dgraph) error {
+	if err := dropPredicates(ctx, dg, "checker.key", "checker.balance"); err != nil {
+		return err
+	}
+	if err := dg.Alter(ctx, &api.Operation{Schema: bankSchema}); err != nil {
+		return errors.Wrap(err, "while setting the schema")
+	}
+	var nquads strings.Builder
+	for i := 0; i < b.accounts; i++ {
+		fmt.Fprintf(&nquads, "_:a%d <checker.key> \"%d\" .\n", i, i)
+		fmt.Fprintf(&nquads, "_:a%d <checker.balance> \"%d\" .\n", i, b.balance)
+	}
+	_, err := dg.NewTxn().Mutate(ctx, &api.Mutation{SetNquads: []byte(nquads.String()),
+		CommitNow: true})
+	return errors.Wrap(err, "while creating the accounts")
+}
+
+// readAccounts reads the accounts with the keys, or all of them if there's none.
+func readAccounts(ctx context.Context, txn *dgo.Txn, keys ...int) ([]account, error) {
+	fn := "has(checker.key)"
+	if len(keys) > 0 {
+		strs := make([]string, len(keys))
+		for i, key := range keys {
+			strs[i] = strconv.Itoa(key)
+		}
+		fn = fmt.Sprintf("eq(checker.key, [%s])", strings.Join(strs, ", "))
+	}
+	q := fmt.Sprintf(`{ q(func: %s) { uid key: checker.key balance: checker.balance } }`, fn)
+	resp, err := txn.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	var r struct{ Q []account }
+	if err := json.Unmarshal(resp.Json, &r); err != nil {
+		return nil, err
+	}
+	return r.Q, nil
+}
+
+func (b *bank) run(ctx context.Context, dg *dgo.Dgraph, process int, rng *rand.Rand) *op {
+	if rng.Intn(2) == 0 {
+		o := &op{Process: process, Kind: "read", Start: time.Now()}
+		txn := dg.NewReadOnlyTxn()
+		accounts, err := readAccounts(ctx, txn)
+		if err == nil {
+			o.Balances = make(map[int]int)
+			for _, a := range accounts {
+				o.Balances[a.Key] = a.Balance
+			}
+		}
+		o.finish(err, false)
+		return o
+	}
+
+	o := &op{Process: process, Kind: "transfer", Start: time.Now(),
+		From: rng.Intn(b.accounts), To: rng.Intn(b.accounts), Amount: 1 + rng.Intn(5)}
+	txn := dg.NewTxn()
+	defer func() { _ = txn.Discard(ctx) }()
+	accounts, err := readAccounts(ctx, txn, o.From, o.To)
+	if err != nil {
+		o.finish(err, false)
+		return o
+	}
+	balances := make(map[int]account)
+	for _, a := range accounts {
+		balances[a.Key] = a
+	}
+	from, to := balances[o.From], balances[o.To]
+	switch {
+	case from.Uid == "" || to.Uid == "":
+		o.finish(errors.Errorf("accounts %d and %d not found", o.From, o.To), false)
+		return o
+	case o.From == o.To || from.Balance < o.Amount:
+		o.finish(errors.New("transfer not allowed"), false)
+		return o
+	}
+	_, err = txn.Mutate(ctx, &api.Mutation{CommitNow: true, SetNquads: []byte(fmt.Sprintf(
+		"<%s> <checker.balance> \"%d\" .\n<%s> <checker.balance> \"%d\" .",
+		from.Uid, from.Balance-o.Amount, to.Uid, to.Balance+o.Amount))})
+	o.finish(err, true)
+	return o
+}
+
+// check checks that every read saw all the accounts, with the same total, and no negative
+// balance.
+func (b *bank) check(ops []*op) []string {
+	var violations []string
+	for _, o := range ops {
+		if o.Kind != "read" || o.Result != resultOk {
+			continue
+		}
+		total := 0
+		for key, balance := range o.Balances {
+			total += balance
+			if balance < 0 {
+				violations = append(violations, fmt.Sprintf(
+					"read of process %d at %s saw balance %d in account %d",
+					o.Process, o.Start.Format(time.RFC3339Nano), balance, key))
+			}
+		}
+		if len(o.Balances) != b.accounts || total != b.accounts*b.balance {
+			violations = append(violations, fmt.Sprintf(
+				"read of process %d at %s saw %d accounts with total %d, expected %d with total %d",
+				o.Process, o.Start.Format(time.RFC3339Nano), len(o.Balances), total,
+				b.accounts, b.accounts*b.balance))
+		}
+	}
+	return violations
+}
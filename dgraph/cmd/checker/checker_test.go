@@ -0,0 +1,98 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/stretchr/testify/require"
+)
+
+var t0 = time.Unix(0, 0)
+
+// at returns the time of the tick.
+func at(tick int) time.Time {
+	return t0.Add(time.Duration(tick) * time.Second)
+}
+
+func TestBankCheck(t *testing.T) {
+	b := &bank{accounts: 2, balance: 10}
+	read := func(balances map[int]int) *op {
+		return &op{Kind: "read", Result: resultOk, Balances: balances}
+	}
+	require.Empty(t, b.check([]*op{
+		read(map[int]int{0: 10, 1: 10}),
+		{Kind: "transfer", Result: resultOk, From: 0, To: 1, Amount: 5},
+		read(map[int]int{0: 5, 1: 15}),
+		{Kind: "read", Result: resultFail},
+	}))
+	require.Len(t, b.check([]*op{read(map[int]int{0: 5, 1: 10})}), 1)
+	require.Len(t, b.check([]*op{read(map[int]int{0: 10})}), 1)
+	require.Len(t, b.check([]*op{read(map[int]int{0: -1, 1: 21})}), 1)
+}
+
+func TestCounterCheck(t *testing.T) {
+	c := &counter{}
+	newOp := func(kind, result string, start, end, value int) *op {
+		return &op{Kind: kind, Result: result, Start: at(start), End: at(end), Value: value}
+	}
+	require.Empty(t, c.check([]*op{
+		newOp("incr", resultOk, 0, 2, 1),
+		newOp("read", resultOk, 1, 3, 0),
+		newOp("read", resultOk, 3, 4, 1),
+		newOp("incr", resultInfo, 4, 6, 2),
+		newOp("read", resultOk, 5, 7, 2),
+		newOp("incr", resultFail, 6, 8, 3),
+		newOp("read", resultOk, 9, 10, 2),
+	}))
+	// A lost increment.
+	require.Len(t, c.check([]*op{
+		newOp("incr", resultOk, 0, 2, 1),
+		newOp("incr", resultOk, 1, 3, 1),
+	}), 1)
+	// A stale read.
+	require.Len(t, c.check([]*op{
+		newOp("incr", resultOk, 0, 2, 1),
+		newOp("read", resultOk, 3, 4, 0),
+	}), 1)
+	// A read of a value not written yet.
+	require.Len(t, c.check([]*op{
+		newOp("read", resultOk, 0, 1, 1),
+		newOp("incr", resultOk, 2, 3, 1),
+	}), 1)
+}
+
+func TestParseFaults(t *testing.T) {
+	parse := func(flag string) (*faults, error) {
+		return parseFaults(z.NewSuperFlag(flag).MergeAndCheckDefault(FaultsDefaults))
+	}
+	f, err := parse("")
+	require.NoError(t, err)
+	require.Nil(t, f)
+
+	f, err = parse("start=docker kill alpha2; stop=docker start alpha2; every=30s; for=10s")
+	require.NoError(t, err)
+	require.Equal(t, &faults{start: "docker kill alpha2", stop: "docker start alpha2",
+		every: 30 * time.Second, dur: 10 * time.Second}, f)
+
+	_, err = parse("start=docker kill alpha2")
+	require.Error(t, err)
+	_, err = parse("start=a; stop=b; every=10s; for=20s")
+	require.Error(t, err)
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200"
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/pkg/errors"
+)
+
+// counter increments a counter, and reads it. The history is linearizable if no increment is
+// lost, and every read sees all the increments done before it started, and none that started
+// after it ended.
+type counter struct {
+	uid string
+}
+
+func (c *counter) setup(ctx context.Context, dg *dgo.Dgraph) error {
+	if err := dropPredicates(ctx, dg, "checker.counter"); err != nil {
+		return err
+	}
+	if err := dg.Alter(ctx, &api.Operation{Schema: "checker.counter: int ."}); err != nil {
+		return errors.Wrap(err, "while setting the schema")
+	}
+	resp, err := dg.NewTxn().Mutate(ctx, &api.Mutation{
+		SetNquads: []byte(`_:c <checker.counter> "0" .`), CommitNow: true})
+	if err != nil {
+		return errors.Wrap(err, "while creating the counter")
+	}
+	c.uid = resp.Uids["c"]
+	return nil
+}
+
+func (c *counter) read(ctx context.Context, txn *dgo.Txn) (int, error) {
+	resp, err := txn.Query(ctx, fmt.Sprintf(`{ q(func: uid(%s)) { val: checker.counter } }`, c.uid))
+	if err != nil {
+		return 0, err
+	}
+	var r struct{ Q []struct{ Val int } }
+	if err := json.Unmarshal(resp.Json, &r); err != nil {
+		return 0, err
+	}
+	if len(r.Q) != 1 {
+		return 0, errors.Errorf("counter not found: %s", resp.Json)
+	}
+	return r.Q[0].Val, nil
+}
+
+func (c *counter) run(ctx context.Context, dg *dgo.Dgraph, process int, rng *rand.Rand) *op {
+	if rng.Intn(2) == 0 {
+		o := &op{Process: process, Kind: "read", Start: time.Now()}
+		val, err := c.read(ctx, dg.NewReadOnlyTxn())
+		o.Value = val
+		o.finish(err, false)
+		return o
+	}
+
+	o := &op{Process: process, Kind: "incr", Start: time.Now()}
+	txn := dg.NewTxn()
+	defer func() { _ = txn.Discard(ctx) }()
+	val, err := c.read(ctx, txn)
+	if err != nil {
+		o.finish(err, false)
+		return o
+	}
+	o.Value = val + 1
+	_, err = txn.Mutate(ctx, &api.Mutation{CommitNow: true, SetNquads: []byte(
+		fmt.Sprintf(`<%s> <checker.counter> "%d" .`, c.uid, o.Value))})
+	o.finish(err, true)
+	return o
+}
+
+// check checks that no two increments wrote the same value, and that the reads returned values
+// between the last increment done before they started, and the number of increments which
+// started before they ended.
+func (c *counter) check(ops []*op) []string {
+	var violations []string
+	var done, started []*op
+	written := make(map[int]*op)
+	for _, o := range ops {
+		if o.Kind != "incr" || o.Result == resultFail {
+			continue
+		}
+		started = append(started, o)
+		if o.Result != resultOk {
+			continue
+		}
+		done = append(done, o)
+		if prev, ok := written[o.Value]; ok {
+			violations = append(violations, fmt.Sprintf(
+				"increments of processes %d and %d both wrote %d", prev.Process, o.Process, o.Value))
+		}
+		written[o.Value] = o
+	}
+	sort.Slice(done, func(i, j int) bool { return done[i].End.Before(done[j].End) })
+	sort.Slice(started, func(i, j int) bool { return started[i].Start.Before(started[j].Start) })
+	// maxDone[i] is the highest value written by the first i increments done.
+	maxDone := make([]int, len(done)+1)
+	for i, o := range done {
+		maxDone[i+1] = maxDone[i]
+		if o.Value > maxDone[i+1] {
+			maxDone[i+1] = o.Value
+		}
+	}
+
+	for _, o := range ops {
+		if o.Kind != "read" || o.Result != resultOk {
+			continue
+		}
+		numDone := sort.Search(len(done), func(i int) bool { return !done[i].End.Before(o.Start) })
+		if min := maxDone[numDone]; o.Value < min {
+			violations = append(violations, fmt.Sprintf(
+				"stale read of process %d at %s saw %d, but %d was written before",
+				o.Process, o.Start.Format(time.RFC3339Nano), o.Value, min))
+		}
+		numStarted := sort.Search(len(started), func(i int) bool {
+			return !started[i].Start.Before(o.End)
+		})
+		if o.Value > numStarted {
+			violations = append(violations, fmt.Sprintf(
+				"read of process %d at %s saw %d, but only %d increments started before it ended",
+				o.Process, o.Start.Format(time.RFC3339Nano), o.Value, numStarted))
+		}
+	}
+	return violations
+}
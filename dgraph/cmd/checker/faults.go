@@ -0,0 +1,114 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checker
+
+import (
+	"context"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// FaultsDefaults are the default options of the fault injection.
+const FaultsDefaults = "start=; stop=; every=1m; for=20s"
+
+// faults injects the faults into the cluster through an agent: either a shell command, or the
+// URL of an HTTP endpoint which is sent a POST request.
+type faults struct {
+	start, stop string
+	every, dur  time.Duration
+}
+
+func parseFaults(sf *z.SuperFlag) (*faults, error) {
+	f := &faults{start: sf.GetString("start"), stop: sf.GetString("stop")}
+	var err error
+	if f.every, err = time.ParseDuration(sf.GetString("every")); err != nil {
+		return nil, errors.Wrapf(err, "faults: invalid every")
+	}
+	if f.dur, err = time.ParseDuration(sf.GetString("for")); err != nil {
+		return nil, errors.Wrapf(err, "faults: invalid for")
+	}
+	switch {
+	case f.start == "":
+		return nil, nil
+	case f.stop == "":
+		return nil, errors.New("faults: stop must be set along with start")
+	case f.every <= 0 || f.dur <= 0 || f.dur > f.every:
+		return nil, errors.Errorf("faults: for=%s must be positive and at most every=%s",
+			f.dur, f.every)
+	}
+	return f, nil
+}
+
+// do runs the action through the agent.
+func do(ctx context.Context, action string) error {
+	if strings.HasPrefix(action, "http://") || strings.HasPrefix(action, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, action, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return errors.Errorf("%s returned %s", action, resp.Status)
+		}
+		return nil
+	}
+	out, err := exec.CommandContext(ctx, "sh", "-c", action).CombinedOutput()
+	return errors.Wrapf(err, "%s: %s", action, out)
+}
+
+// inject injects a fault every so often until ctx is done, and records them in the history.
+// The last fault is always stopped.
+func (f *faults) inject(ctx context.Context, h *history) {
+	run := func(kind, action string) {
+		// The faults are stopped even once ctx is done.
+		actx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		o := &op{Process: -1, Kind: kind, Start: time.Now()}
+		err := do(actx, action)
+		if err != nil {
+			glog.Errorf("While running %s: %v", kind, err)
+		}
+		o.finish(err, true)
+		h.add(o)
+	}
+
+	ticker := time.NewTicker(f.every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		run("fault-start", f.start)
+		select {
+		case <-ctx.Done():
+		case <-time.After(f.dur):
+		}
+		run("fault-stop", f.stop)
+	}
+}
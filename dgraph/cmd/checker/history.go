@@ -0,0 +1,116 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checker
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200"
+	"github.com/pkg/errors"
+)
+
+// The results of the operations.
+const (
+	// resultOk means the operation took effect.
+	resultOk = "ok"
+	// resultFail means the operation didn't take effect.
+	resultFail = "fail"
+	// resultInfo means it's unknown whether the operation took effect, e.g. when the commit
+	// timed out.
+	resultInfo = "info"
+)
+
+// op is an operation of the history.
+type op struct {
+	Process int       `json:"process"`
+	Kind    string    `json:"kind"`
+	Result  string    `json:"result"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Error   string    `json:"error,omitempty"`
+
+	// Balances are the balances of the accounts read by the bank workload.
+	Balances map[int]int `json:"balances,omitempty"`
+	// From, To and Amount describe a transfer of the bank workload.
+	From   int `json:"from,omitempty"`
+	To     int `json:"to,omitempty"`
+	Amount int `json:"amount,omitempty"`
+	// Value is the value read or written by the counter workload.
+	Value int `json:"value,omitempty"`
+}
+
+// finish records the end of the operation, and its result given the error. Only the errors of
+// commits can leave it unknown whether the operation took effect.
+func (o *op) finish(err error, committing bool) {
+	o.End = time.Now()
+	switch {
+	case err == nil:
+		o.Result = resultOk
+	case !committing || errors.Is(err, dgo.ErrAborted):
+		o.Result = resultFail
+	default:
+		o.Result = resultInfo
+	}
+	if err != nil {
+		o.Error = err.Error()
+	}
+}
+
+// history is the history of the operations run against the cluster.
+type history struct {
+	sync.Mutex
+	ops []*op
+}
+
+func (h *history) add(o *op) {
+	h.Lock()
+	defer h.Unlock()
+	h.ops = append(h.ops, o)
+}
+
+// counts returns the number of operations by kind and result.
+func (h *history) counts() map[string]int {
+	h.Lock()
+	defer h.Unlock()
+	counts := make(map[string]int)
+	for _, o := range h.ops {
+		counts[o.Kind+" "+o.Result]++
+	}
+	return counts
+}
+
+// write writes the history to the file, one operation per line.
+func (h *history) write(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "while creating %s", path)
+	}
+	defer f.Close()
+
+	h.Lock()
+	defer h.Unlock()
+	enc := json.NewEncoder(f)
+	for _, o := range h.ops {
+		if err := enc.Encode(o); err != nil {
+			return errors.Wrapf(err, "while writing %s", path)
+		}
+	}
+	return f.Sync()
+}
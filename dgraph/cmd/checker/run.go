@@ -0,0 +1,199 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package checker runs Jepsen-style workloads against a cluster while injecting faults, and
+// verifies the consistency of the history of the operations.
+package checker
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200"
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Checker is the sub-command invoked when calling "dgraph checker".
+var Checker x.SubCommand
+
+func init() {
+	Checker.Cmd = &cobra.Command{
+		Use:   "checker",
+		Short: "Check the consistency of a cluster under faults",
+		Long: `
+Checker runs a workload against the cluster with concurrent clients, while
+injecting faults through an agent, and verifies the invariants of the workload
+on the history of the operations. It exits with an error if they don't hold.
+
+The bank workload transfers money between accounts, and checks that the reads
+always see the same total. The counter workload increments a counter, and checks
+that the history is linearizable.
+
+The workloads drop the data of their checker.* predicates before starting.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := run(Checker.Conf); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		},
+		Annotations: map[string]string{"group": "tool"},
+	}
+	Checker.EnvPrefix = "DGRAPH_CHECKER"
+	Checker.Cmd.SetHelpTemplate(x.NonRootTemplate)
+
+	flag := Checker.Cmd.Flags()
+	flag.String("alpha", "localhost:9080", "Comma separated addresses of Dgraph Alphas.")
+	flag.Int("retries", 10, "How many times to retry setting up the connection.")
+	flag.String("creds", "",
+		`Various login credentials if login is required.
+	user defines the username to login.
+	password defines the password of the user.
+	namespace defines the namespace to log into.
+	Sample flag could look like --creds user=username;password=mypass;namespace=2`)
+	flag.String("workload", "bank", "Workload to run: bank or counter.")
+	flag.Duration("duration", time.Minute, "How long to run the workload.")
+	flag.Int("concurrency", 5, "Number of concurrent clients.")
+	flag.Int("accounts", 5, "Number of accounts of the bank workload.")
+	flag.Int("balance", 100, "Initial balance of the accounts of the bank workload.")
+	flag.Int64("seed", 0, "Seed of the random operations. The current time is used if 0.")
+	flag.String("history", "", "File to write the history of the operations to, as JSON lines.")
+	flag.String("faults", FaultsDefaults,
+		`Options of the fault injection. The actions are shell commands, e.g. to kill or
+	partition the nodes, or URLs of an agent running on the nodes, which is sent POST requests.
+	start=action starts a fault. No fault is injected if it's empty.
+	stop=action stops the fault, e.g. restarts the nodes or heals the network.
+	every=D is how often a fault is started.
+	for=D is how long the faults last.
+	`)
+	x.RegisterClientTLSFlags(flag)
+}
+
+// workload is a workload run by the checker.
+type workload interface {
+	// setup creates the data of the workload.
+	setup(ctx context.Context, dg *dgo.Dgraph) error
+	// run runs a random operation.
+	run(ctx context.Context, dg *dgo.Dgraph, process int, rng *rand.Rand) *op
+	// check returns the violations of the invariants of the workload in the history.
+	check(ops []*op) []string
+}
+
+func dropPredicates(ctx context.Context, dg *dgo.Dgraph, preds ...string) error {
+	for _, pred := range preds {
+		if err := dg.Alter(ctx, &api.Operation{DropOp: api.Operation_ATTR,
+			DropValue: pred}); err != nil {
+			return errors.Wrapf(err, "while dropping %s", pred)
+		}
+	}
+	return nil
+}
+
+func run(conf *viper.Viper) error {
+	var w workload
+	switch conf.GetString("workload") {
+	case "bank":
+		b := &bank{accounts: conf.GetInt("accounts"), balance: conf.GetInt("balance")}
+		if b.accounts < 2 || b.balance < 0 {
+			return errors.New("The bank workload needs at least 2 accounts, and no debt")
+		}
+		w = b
+	case "counter":
+		w = &counter{}
+	default:
+		return errors.Errorf("Unknown workload: %s", conf.GetString("workload"))
+	}
+	f, err := parseFaults(z.NewSuperFlag(conf.GetString("faults")).MergeAndCheckDefault(
+		FaultsDefaults))
+	if err != nil {
+		return err
+	}
+	seed := conf.GetInt64("seed")
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	fmt.Printf("Seed: %d\n", seed)
+
+	// Do a sanity check on the passed credentials.
+	_ = z.NewSuperFlag(conf.GetString("creds")).MergeAndCheckDefault(x.DefaultCreds)
+	dg, closeFunc := x.GetDgraphClient(conf, true)
+	defer closeFunc()
+
+	if err := w.setup(context.Background(), dg); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), conf.GetDuration("duration"))
+	defer cancel()
+	var h history
+	var wg sync.WaitGroup
+	if f != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.inject(ctx, &h)
+		}()
+	}
+	for p := 0; p < conf.GetInt("concurrency"); p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed + int64(p)))
+			for ctx.Err() == nil {
+				// The operations aren't canceled at the end, so that their result is known.
+				octx, ocancel := context.WithTimeout(context.Background(), 10*time.Second)
+				h.add(w.run(octx, dg, p, rng))
+				ocancel()
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	if path := conf.GetString("history"); path != "" {
+		if err := h.write(path); err != nil {
+			return err
+		}
+	}
+	counts := h.counts()
+	var kinds []string
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Printf("%-20s %d\n", kind, counts[kind])
+	}
+
+	violations := w.check(h.ops)
+	if len(violations) == 0 {
+		fmt.Println("The history is valid.")
+		return nil
+	}
+	for _, v := range violations {
+		fmt.Println(v)
+	}
+	return errors.Errorf("The history has %d violations.", len(violations))
+}
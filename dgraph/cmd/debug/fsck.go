@@ -0,0 +1,550 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debug
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/dgraph-io/badger/v3"
+	bpb "github.com/dgraph-io/badger/v3/pb"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+)
+
+// maxFsckExamples is the max number of inconsistencies printed per predicate and kind of key.
+const maxFsckExamples = 10
+
+// fsckOut is where the inconsistencies found by fsck are printed.
+var fsckOut io.Writer = os.Stdout
+
+// fsckChecker checks one predicate. It computes the lists the index, reverse and count keys of
+// the predicate should have from its data keys, and compares them with the lists they have.
+// The expected lists are written as deltas to a temporary badger, like the index rebuild does,
+// so that they're compared with the derived keys in key order without holding them in memory.
+type fsckChecker struct {
+	db   *badger.DB
+	tmp  *badger.DB
+	attr string
+	su   pb.SchemaUpdate
+	// tmpWriter writes the expected UIDs of the derived keys to tmp, each one at its own version.
+	tmpWriter  *badger.WriteBatch
+	tmpVersion *uint64
+	// issues counts the inconsistencies, by kind of key.
+	issues map[string]int
+	// repairTs is the timestamp of the repairs. Nothing is repaired if it's zero.
+	repairTs uint64
+}
+
+// fsck checks that the index, reverse and count keys of the predicates agree with their data,
+// and that the types the nodes have are defined. With --fsck_repair, it rewrites the derived keys
+// which don't agree with the data. The other inconsistencies are only reported.
+func fsck(db *badger.DB) {
+	total, repaired, repairTs := checkStore(db)
+	fmt.Fprintf(fsckOut, "\nfsck found %d inconsistencies at ts %d", total, opt.readTs)
+	if opt.fsckRepair {
+		fmt.Fprintf(fsckOut, ", repaired %d at ts %d", repaired, repairTs)
+	}
+	fmt.Fprintln(fsckOut, ".")
+	if total > repaired {
+		os.Exit(1)
+	}
+}
+
+// openFsckTmp opens the temporary badger holding the expected lists of the derived keys. The
+// returned function closes and removes it.
+func openFsckTmp() (*badger.DB, func(), error) {
+	dir, err := ioutil.TempDir("", "dgraph_fsck_")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "while creating the temporary directory of fsck")
+	}
+	bopts := badger.DefaultOptions(dir).
+		WithSyncWrites(false).
+		WithNumVersionsToKeep(math.MaxInt32).
+		WithLogger(&x.ToGlog{}).
+		WithLoggingLevel(badger.WARNING).
+		WithEncryptionKey(opt.key)
+	if len(opt.key) > 0 {
+		bopts = bopts.WithBlockCacheSize(100 << 20).WithIndexCacheSize(100 << 20)
+	}
+	tmp, err := badger.OpenManaged(bopts)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, errors.Wrap(err, "while opening the temporary badger of fsck")
+	}
+	return tmp, func() {
+		if err := tmp.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "While closing the temporary badger of fsck: %v\n", err)
+		}
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// checkStore runs the checks of fsck, and the repairs if asked for. It returns the number of
+// inconsistencies found and repaired, and the timestamp of the repairs.
+func checkStore(db *badger.DB) (total, repaired int, repairTs uint64) {
+	schema.Init(db)
+	x.Check(schema.LoadFromDb())
+
+	if opt.fsckRepair {
+		x.AssertTruef(!opt.readOnly, "--fsck_repair needs --readonly=false")
+		// Write the repairs on top of every version in the directory.
+		repairTs = db.MaxVersion() + 1
+	}
+
+	tmp, closeTmp, err := openFsckTmp()
+	x.Check(err)
+	defer closeTmp()
+	var tmpVersion uint64
+
+	preds := schema.State().Predicates()
+	sort.Strings(preds)
+	for _, attr := range preds {
+		if opt.predicate != "" && attr != opt.predicate && x.ParseAttr(attr) != opt.predicate {
+			continue
+		}
+		su, ok := schema.State().Get(context.Background(), attr)
+		if !ok {
+			continue
+		}
+		c := &fsckChecker{
+			db:         db,
+			tmp:        tmp,
+			attr:       attr,
+			su:         su,
+			tmpVersion: &tmpVersion,
+			issues:     make(map[string]int),
+			repairTs:   repairTs,
+		}
+		x.Check(c.scan())
+		n, err := c.compare()
+		x.Check(err)
+		if n > 0 && opt.fsckRepair {
+			repaired += n
+		}
+		total += n
+	}
+	total += fsckTypes(db)
+	return total, repaired, repairTs
+}
+
+// expect records that the derived key should have the uid.
+func (c *fsckChecker) expect(key []byte, uid uint64) error {
+	*c.tmpVersion++
+	pl := &pb.PostingList{Postings: []*pb.Posting{{Uid: uid, Op: posting.Set}}}
+	val, err := pl.Marshal()
+	if err != nil {
+		return err
+	}
+	e := badger.NewEntry(key, val).WithMeta(posting.BitDeltaPosting)
+	return c.tmpWriter.SetEntryAt(e, *c.tmpVersion)
+}
+
+func (c *fsckChecker) report(kind, format string, args ...interface{}) {
+	c.issues[kind]++
+	if c.issues[kind] <= maxFsckExamples {
+		fmt.Fprintf(fsckOut, "[%s] %s: %s\n", x.ParseAttr(c.attr), kind,
+			fmt.Sprintf(format, args...))
+	}
+}
+
+// scan reads the data keys of the predicate, and writes the UIDs the derived keys should have
+// to the temporary badger.
+func (c *fsckChecker) scan() error {
+	ctx := context.Background()
+	prefix := x.ParsedKey{Attr: c.attr}.DataPrefix()
+	txn := c.db.NewTransactionAt(opt.readTs, false)
+	defer txn.Discard()
+	iopt := badger.DefaultIteratorOptions
+	iopt.AllVersions = true
+	iopt.Prefix = prefix
+	itr := txn.NewIterator(iopt)
+	defer itr.Close()
+
+	c.tmpWriter = c.tmp.NewManagedWriteBatch()
+	for itr.Seek(prefix); itr.ValidForPrefix(prefix); {
+		key := itr.Item().KeyCopy(nil)
+		pk, err := x.Parse(key)
+		if err != nil {
+			return err
+		}
+		pl, err := posting.ReadPostingList(key, itr)
+		if err != nil {
+			return err
+		}
+		for ; itr.Valid() && bytes.Equal(itr.Item().Key(), key); itr.Next() {
+		}
+		if err := c.checkData(ctx, pk.Uid, pl); err != nil {
+			return err
+		}
+	}
+	if err := c.tmpWriter.Flush(); err != nil {
+		return err
+	}
+
+	if c.su.Directive == pb.SchemaUpdate_REVERSE && c.su.Count {
+		// The reverse count index holds the objects by the number of subjects pointing to them.
+		c.tmpWriter = c.tmp.NewManagedWriteBatch()
+		err := c.iterateTmp(x.ParsedKey{Attr: c.attr}.ReversePrefix(),
+			func(key []byte, uids []uint64) error {
+				pk, err := x.Parse(key)
+				if err != nil {
+					return err
+				}
+				return c.expect(x.CountKey(c.attr, uint32(len(uids)), true), pk.Uid)
+			})
+		if err != nil {
+			return err
+		}
+		return c.tmpWriter.Flush()
+	}
+	return nil
+}
+
+// iterateTmp calls fn with the expected UIDs of the derived keys of the prefix, in key order.
+func (c *fsckChecker) iterateTmp(prefix []byte, fn func(key []byte, uids []uint64) error) error {
+	txn := c.tmp.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+	iopt := badger.DefaultIteratorOptions
+	iopt.AllVersions = true
+	iopt.Prefix = prefix
+	itr := txn.NewIterator(iopt)
+	defer itr.Close()
+
+	for itr.Seek(prefix); itr.ValidForPrefix(prefix); {
+		key := itr.Item().KeyCopy(nil)
+		pl, err := posting.ReadPostingList(key, itr)
+		if err != nil {
+			return err
+		}
+		// Only deltas are written to the temporary badger, so ReadPostingList has read all the
+		// versions of the key.
+		uids, err := pl.Uids(posting.ListOptions{ReadTs: math.MaxUint64})
+		if err != nil {
+			return err
+		}
+		if err := fn(key, uids.Uids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkData derives the index, reverse and count entries of the data key of the uid.
+func (c *fsckChecker) checkData(ctx context.Context, uid uint64, pl *posting.List) error {
+	var count int
+	err := pl.Iterate(opt.readTs, 0, func(p *pb.Posting) error {
+		count++
+		if p.PostingType == pb.Posting_REF {
+			if c.su.Directive == pb.SchemaUpdate_REVERSE {
+				return c.expect(x.ReverseKey(c.attr, p.Uid), uid)
+			}
+			return nil
+		}
+		if c.su.Directive == pb.SchemaUpdate_INDEX {
+			val := types.Val{Tid: types.TypeID(p.ValType), Value: p.Value}
+			keys, err := posting.IndexKeys(ctx, c.attr, string(p.LangTag), val)
+			if err != nil {
+				c.report("data", "uid %#x has a value which can't be indexed: %v", uid, err)
+				return nil
+			}
+			for _, key := range keys {
+				if err := c.expect(key, uid); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	if c.su.Count {
+		if err := c.expect(x.CountKey(c.attr, uint32(count), false), uid); err != nil {
+			return err
+		}
+	}
+	if c.su.Presence {
+		return c.expect(posting.PresenceKey(c.attr), uid)
+	}
+	return nil
+}
+
+func keyKind(pk x.ParsedKey) string {
+	switch {
+	case pk.IsIndex():
+		return "index"
+	case pk.IsReverse():
+		return "reverse"
+	}
+	return "count"
+}
+
+func describeKey(pk x.ParsedKey) string {
+	switch {
+	case pk.IsIndex():
+		return fmt.Sprintf("term %q", pk.Term)
+	case pk.IsReverse():
+		return fmt.Sprintf("object %#x", pk.Uid)
+	}
+	return fmt.Sprintf("count %d", pk.Count)
+}
+
+// compare walks the derived keys of the predicate and the expected ones in key order. It reports
+// the UIDs missing from the derived keys, and the UIDs they have which the data doesn't explain,
+// and repairs them if asked for. It returns the number of keys which need to be repaired.
+func (c *fsckChecker) compare() (int, error) {
+	txn := c.db.NewTransactionAt(opt.readTs, false)
+	defer txn.Discard()
+	iopt := badger.DefaultIteratorOptions
+	iopt.AllVersions = true
+	prefix := x.PredicatePrefix(c.attr)
+	iopt.Prefix = prefix
+	itr := txn.NewIterator(iopt)
+	defer itr.Close()
+
+	// next returns the next derived key of the predicate and its UIDs, skipping the empty lists.
+	// It returns a nil key once there are no more.
+	itr.Seek(prefix)
+	next := func() ([]byte, []uint64, error) {
+		for itr.ValidForPrefix(prefix) {
+			key := itr.Item().KeyCopy(nil)
+			pk, err := x.Parse(key)
+			if err != nil {
+				return nil, nil, err
+			}
+			if pk.IsData() {
+				itr.Next()
+				continue
+			}
+			pl, err := posting.ReadPostingList(key, itr)
+			if err != nil {
+				return nil, nil, err
+			}
+			for ; itr.Valid() && bytes.Equal(itr.Item().Key(), key); itr.Next() {
+			}
+			uids, err := pl.Uids(posting.ListOptions{ReadTs: opt.readTs})
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(uids.Uids) > 0 {
+				return key, uids.Uids, nil
+			}
+		}
+		return nil, nil, nil
+	}
+
+	var broken int
+	check := func(key []byte, actual, expected []uint64) error {
+		missing, extra := diffUids(expected, actual)
+		if len(missing) == 0 && len(extra) == 0 {
+			return nil
+		}
+		pk, err := x.Parse(key)
+		if err != nil {
+			return err
+		}
+		broken++
+		c.report(keyKind(pk), "%s is missing %d UIDs %s and has %d dangling UIDs %s",
+			describeKey(pk), len(missing), sampleUids(missing), len(extra), sampleUids(extra))
+		if c.repairTs == 0 {
+			return nil
+		}
+		return c.repair(key, missing, extra)
+	}
+
+	key, actual, err := next()
+	if err != nil {
+		return 0, err
+	}
+	err = c.iterateTmp(prefix, func(expKey []byte, expected []uint64) error {
+		// The derived keys before expKey shouldn't have any UIDs.
+		for key != nil && bytes.Compare(key, expKey) < 0 {
+			if err := check(key, actual, nil); err != nil {
+				return err
+			}
+			if key, actual, err = next(); err != nil {
+				return err
+			}
+		}
+		if key != nil && bytes.Equal(key, expKey) {
+			if err := check(key, actual, expected); err != nil {
+				return err
+			}
+			key, actual, err = next()
+			return err
+		}
+		return check(expKey, nil, expected)
+	})
+	if err != nil {
+		return 0, err
+	}
+	for ; key != nil; key, actual, err = next() {
+		if err := check(key, actual, nil); err != nil {
+			return 0, err
+		}
+	}
+	return broken, err
+}
+
+// diffUids returns the UIDs of expected which actual doesn't have, and the UIDs of actual which
+// expected doesn't have. Both must be sorted.
+func diffUids(expected, actual []uint64) (missing, extra []uint64) {
+	var i, j int
+	for i < len(expected) && j < len(actual) {
+		switch {
+		case expected[i] < actual[j]:
+			missing = append(missing, expected[i])
+			i++
+		case expected[i] > actual[j]:
+			extra = append(extra, actual[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	missing = append(missing, expected[i:]...)
+	extra = append(extra, actual[j:]...)
+	return missing, extra
+}
+
+func sampleUids(uids []uint64) string {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, uid := range uids {
+		if i == maxFsckExamples {
+			buf.WriteString(" ...")
+			break
+		}
+		if i > 0 {
+			buf.WriteString(" ")
+		}
+		fmt.Fprintf(&buf, "%#x", uid)
+	}
+	buf.WriteString("]")
+	return buf.String()
+}
+
+// repair adds the missing UIDs to the derived key and removes the dangling ones at the repair
+// timestamp, like a transaction would, and then rolls the list up, so that it's split if it
+// has become too big.
+func (c *fsckChecker) repair(key []byte, missing, extra []uint64) error {
+	repairs := make([]*pb.IndexRepair, 0, len(missing)+len(extra))
+	for _, uid := range missing {
+		repairs = append(repairs, &pb.IndexRepair{Key: key, Uid: uid, Missing: true})
+	}
+	for _, uid := range extra {
+		repairs = append(repairs, &pb.IndexRepair{Key: key, Uid: uid})
+	}
+	txn := posting.NewTxn(c.repairTs)
+	if err := txn.RepairIndex(context.Background(), repairs); err != nil {
+		return err
+	}
+	txn.Update()
+	writer := posting.NewTxnWriter(c.db)
+	if err := txn.CommitToDisk(writer, c.repairTs); err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	pl, err := posting.GetNoStore(key, math.MaxUint64)
+	if err != nil {
+		return err
+	}
+	kvs, err := pl.Rollup(nil)
+	if err != nil {
+		return err
+	}
+	wb := c.db.NewManagedWriteBatch()
+	if err := wb.WriteList(&bpb.KVList{Kv: kvs}); err != nil {
+		return err
+	}
+	return wb.Flush()
+}
+
+// fsckTypes reports the type definitions using predicates without a schema, and the nodes with
+// types which aren't defined. It returns the number of inconsistencies found.
+func fsckTypes(db *badger.DB) int {
+	var issues int
+	for _, name := range schema.State().Types() {
+		tu, _ := schema.State().GetType(name)
+		for _, field := range tu.Fields {
+			if _, ok := schema.State().Get(context.Background(), field.Predicate); !ok {
+				issues++
+				fmt.Fprintf(fsckOut, "[type %s] field %s has no schema\n", x.ParseAttr(name),
+					x.ParseAttr(field.Predicate))
+			}
+		}
+	}
+
+	for _, attr := range schema.State().Predicates() {
+		if x.ParseAttr(attr) != "dgraph.type" {
+			continue
+		}
+		ns, _ := x.ParseNamespaceAttr(attr)
+		prefix := x.PredicatePrefix(attr)
+		txn := db.NewTransactionAt(opt.readTs, false)
+		iopt := badger.DefaultIteratorOptions
+		iopt.AllVersions = true
+		iopt.Prefix = prefix
+		itr := txn.NewIterator(iopt)
+		for itr.Seek(prefix); itr.ValidForPrefix(prefix); {
+			key := itr.Item().KeyCopy(nil)
+			pk, err := x.Parse(key)
+			x.Check(err)
+			var pl *posting.List
+			if pk.IsData() {
+				pl, err = posting.ReadPostingList(key, itr)
+				x.Check(err)
+			}
+			for ; itr.Valid() && bytes.Equal(itr.Item().Key(), key); itr.Next() {
+			}
+			if pl == nil {
+				continue
+			}
+			x.Check(pl.Iterate(opt.readTs, 0, func(p *pb.Posting) error {
+				name := string(p.Value)
+				if _, ok := schema.State().GetType(x.NamespaceAttr(ns, name)); !ok {
+					issues++
+					if issues <= maxFsckExamples {
+						fmt.Fprintf(fsckOut, "[dgraph.type] uid %#x has undefined type %s\n", pk.Uid, name)
+					}
+				}
+				return nil
+			}))
+		}
+		itr.Close()
+		txn.Discard()
+	}
+	return issues
+}
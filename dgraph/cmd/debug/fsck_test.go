@@ -0,0 +1,137 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debug
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/codec"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFsck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsck")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := badger.OpenManaged(badger.DefaultOptions(dir))
+	require.NoError(t, err)
+	defer db.Close()
+	posting.Init(db, 0)
+
+	defer func(o flagOptions) {
+		opt = o
+		fsckOut = os.Stdout
+	}(opt)
+	opt.readTs = math.MaxUint64
+	opt.readOnly = false
+	var out bytes.Buffer
+	fsckOut = &out
+
+	ctx := context.Background()
+	name, friend := x.GalaxyAttr("name"), x.GalaxyAttr("friend")
+	txn := db.NewTransactionAt(1, true)
+	for _, su := range []*pb.SchemaUpdate{
+		{Predicate: name, ValueType: pb.Posting_STRING, Directive: pb.SchemaUpdate_INDEX,
+			Tokenizer: []string{"exact"}},
+		{Predicate: friend, ValueType: pb.Posting_UID, Directive: pb.SchemaUpdate_REVERSE,
+			List: true},
+	} {
+		val, err := su.Marshal()
+		require.NoError(t, err)
+		require.NoError(t, txn.SetEntry(badger.NewEntry(x.SchemaKey(su.Predicate), val).
+			WithMeta(posting.BitSchemaPosting)))
+	}
+	require.NoError(t, txn.CommitAt(1, nil))
+	schema.Init(db)
+	require.NoError(t, schema.LoadFromDb())
+
+	ts := uint64(10)
+	ptxn := posting.Oracle().RegisterStartTs(ts)
+	for _, edge := range []*pb.DirectedEdge{
+		{Entity: 1, Attr: name, Value: []byte("alice"), ValueType: pb.Posting_STRING},
+		{Entity: 2, Attr: name, Value: []byte("bob"), ValueType: pb.Posting_STRING},
+		{Entity: 1, Attr: friend, ValueId: 2},
+	} {
+		l, err := ptxn.Get(x.DataKey(edge.Attr, edge.Entity))
+		require.NoError(t, err)
+		require.NoError(t, l.AddMutationWithIndex(ctx, edge, ptxn))
+	}
+	ptxn.Update()
+	writer := posting.NewTxnWriter(db)
+	require.NoError(t, ptxn.CommitToDisk(writer, ts+1))
+	require.NoError(t, writer.Flush())
+
+	total, _, _ := checkStore(db)
+	require.Equal(t, 0, total, out.String())
+
+	// Drop alice from the index, and point a reverse edge at a node no one points to.
+	keys, err := posting.IndexKeys(ctx, name, "",
+		types.Val{Tid: types.StringID, Value: []byte("alice")})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(keys))
+	pl := &pb.PostingList{Pack: codec.Encode([]uint64{5}, 256)}
+	val, err := pl.Marshal()
+	require.NoError(t, err)
+	writer = posting.NewTxnWriter(db)
+	require.NoError(t, writer.SetAt(keys[0], nil, posting.BitEmptyPosting, ts+2))
+	require.NoError(t, writer.SetAt(x.ReverseKey(friend, 9), val, posting.BitCompletePosting,
+		ts+2))
+	require.NoError(t, writer.Flush())
+
+	out.Reset()
+	total, _, _ = checkStore(db)
+	require.Equal(t, 2, total)
+	require.Contains(t, out.String(), "[name] index: term")
+	require.Contains(t, out.String(), "is missing 1 UIDs [0x1]")
+	require.Contains(t, out.String(),
+		"[friend] reverse: object 0x9 is missing 0 UIDs [] and has 1 dangling UIDs [0x5]")
+
+	opt.fsckRepair = true
+	total, repaired, repairTs := checkStore(db)
+	require.Equal(t, 2, total)
+	require.Equal(t, 2, repaired)
+	require.Greater(t, repairTs, ts+2)
+
+	// The repairs are rolled up into complete lists.
+	rtxn := db.NewTransactionAt(math.MaxUint64, false)
+	item, err := rtxn.Get(keys[0])
+	require.NoError(t, err)
+	require.Equal(t, posting.BitCompletePosting, item.UserMeta())
+	require.Equal(t, repairTs, item.Version())
+	rtxn.Discard()
+	l, err := posting.GetNoStore(keys[0], math.MaxUint64)
+	require.NoError(t, err)
+	uids, err := l.Uids(posting.ListOptions{ReadTs: math.MaxUint64})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, uids.Uids)
+
+	opt.fsckRepair = false
+	out.Reset()
+	total, _, _ = checkStore(db)
+	require.Equal(t, 0, total, out.String())
+}
@@ -67,6 +67,8 @@ type flagOptions struct {
 	readTs        uint64
 	sizeHistogram bool
 	noKeys        bool
+	fsck          bool
+	fsckRepair    bool
 	key           x.SensitiveByteSlice
 
 	// Options related to the WAL.
@@ -102,6 +104,14 @@ func init() {
 	flag.StringVarP(&opt.pdir, "postings", "p", "", "Directory where posting lists are stored.")
 	flag.BoolVar(&opt.sizeHistogram, "histogram", false,
 		"Show a histogram of the key and value sizes.")
+	flag.BoolVar(&opt.fsck, "fsck", false,
+		"Check that the index, reverse and count keys agree with the data keys, and that the "+
+			"types of the nodes are defined. Can be limited to a predicate with --pred. To check "+
+			"a live replica, e.g. a learner, run it on a copy taken with the snapshot mutation "+
+			"of the admin API.")
+	flag.BoolVar(&opt.fsckRepair, "fsck_repair", false,
+		"Rewrite the index, reverse and count keys which don't agree with the data keys found "+
+			"by --fsck. Needs --readonly=false, and the Alpha must not be running on the directory.")
 	flag.StringVarP(&opt.wdir, "wal", "w", "", "Directory where Raft write-ahead logs are stored.")
 	flag.Uint64VarP(&opt.wtruncateUntil, "truncate", "t", 0,
 		"Remove data from Raft entries until but not including this index.")
@@ -938,6 +948,8 @@ func run() {
 		fmt.Printf("Total: %d\n", total)
 	case opt.sizeHistogram:
 		sizeHistogram(db)
+	case opt.fsck || opt.fsckRepair:
+		fsck(db)
 	default:
 		printKeys(db)
 	}
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package live
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+)
+
+const (
+	// Above highPressure the concurrency is halved, below lowPressure it is increased by one.
+	highPressure = 0.8
+	lowPressure  = 0.5
+
+	backpressurePollInterval = time.Second
+)
+
+// concLimiter limits the number of mutations in flight. The limit is adapted to the backpressure
+// reported by the Alphas, between 1 and max.
+type concLimiter struct {
+	c        *sync.Cond
+	limit    int
+	max      int
+	inflight int
+}
+
+func newConcLimiter(limit, max int) *concLimiter {
+	if limit > max {
+		limit = max
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	return &concLimiter{c: sync.NewCond(&sync.Mutex{}), limit: limit, max: max}
+}
+
+func (cl *concLimiter) acquire() {
+	cl.c.L.Lock()
+	for cl.inflight >= cl.limit {
+		cl.c.Wait()
+	}
+	cl.inflight++
+	cl.c.L.Unlock()
+}
+
+func (cl *concLimiter) release() {
+	cl.c.L.Lock()
+	cl.inflight--
+	cl.c.L.Unlock()
+	cl.c.Signal()
+}
+
+// adapt adjusts the limit to the given pressure, by increasing it additively while the Alphas
+// keep up, and decreasing it multiplicatively once they don't. It returns the previous and the
+// new limit.
+func (cl *concLimiter) adapt(pressure float64) (int, int) {
+	cl.c.L.Lock()
+	defer cl.c.L.Unlock()
+	prev := cl.limit
+	switch {
+	case pressure >= highPressure:
+		cl.limit /= 2
+		if cl.limit < 1 {
+			cl.limit = 1
+		}
+	case pressure < lowPressure && cl.limit < cl.max:
+		cl.limit++
+		cl.c.Broadcast()
+	}
+	return prev, cl.limit
+}
+
+// adaptConcurrency polls the backpressure of the Alphas and adapts the concurrency to the most
+// loaded of them, until ctx is done.
+func (l *loader) adaptConcurrency(ctx context.Context, clients []pb.BackpressureClient) {
+	ticker := time.NewTicker(backpressurePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var pressure float64
+		var polled bool
+		for _, c := range clients {
+			state, err := c.Backpressure(ctx, &pb.BackpressureRequest{})
+			if err != nil {
+				if opt.verbose {
+					fmt.Printf("Unable to get backpressure from Alpha: %v\n", err)
+				}
+				continue
+			}
+			polled = true
+			if state.Pressure > pressure {
+				pressure = state.Pressure
+			}
+		}
+		if !polled {
+			continue
+		}
+		if prev, limit := l.conc.adapt(pressure); limit != prev && opt.verbose {
+			fmt.Printf("Pressure on Alphas is %.2f. Changed concurrency from %d to %d.\n",
+				pressure, prev, limit)
+		}
+	}
+}
+
+// backpressureClients connects to the Alphas given by --alpha to poll their backpressure.
+func backpressureClients(conf *viper.Viper) ([]pb.BackpressureClient, func(), error) {
+	tlsCfg, err := x.LoadClientTLSConfig(conf)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "while loading TLS configuration")
+	}
+	var conns []*grpc.ClientConn
+	closeConns := func() {
+		for _, c := range conns {
+			if err := c.Close(); err != nil {
+				glog.Warningf("Error while closing connection: %v", err)
+			}
+		}
+	}
+	var clients []pb.BackpressureClient
+	for _, addr := range strings.Split(conf.GetString("alpha"), ",") {
+		c, err := x.SetupConnection(addr, tlsCfg, false)
+		if err != nil {
+			closeConns()
+			return nil, nil, errors.Wrapf(err, "while connecting to Alpha %s", addr)
+		}
+		conns = append(conns, c)
+		clients = append(clients, pb.NewBackpressureClient(c))
+	}
+	return clients, closeConns, nil
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package live
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcLimiterAdapt(t *testing.T) {
+	cl := newConcLimiter(4, 6)
+	prev, limit := cl.adapt(0.1)
+	require.Equal(t, 4, prev)
+	require.Equal(t, 5, limit)
+	cl.adapt(0.1)
+	_, limit = cl.adapt(0.1)
+	require.Equal(t, 6, limit, "limit must not go above max")
+
+	_, limit = cl.adapt(0.6)
+	require.Equal(t, 6, limit, "limit must stay between the thresholds")
+
+	_, limit = cl.adapt(1.2)
+	require.Equal(t, 3, limit)
+	cl.adapt(0.9)
+	_, limit = cl.adapt(0.9)
+	require.Equal(t, 1, limit, "limit must not go below 1")
+}
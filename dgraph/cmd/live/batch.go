@@ -29,11 +29,13 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	"github.com/dgraph-io/badger/v3"
 	"github.com/dgraph-io/dgo/v200"
 	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/chunker"
 	"github.com/dgraph-io/dgraph/gql"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/tok"
@@ -78,6 +80,8 @@ type loader struct {
 	txns uint64
 	// Num of aborts
 	aborts uint64
+	// Num of N-Quads skipped by the transform
+	skipped uint64
 	// To get time elapsed
 	start time.Time
 
@@ -88,6 +92,9 @@ type loader struct {
 	reqs     chan *request
 	zeroconn *grpc.ClientConn
 	schema   *schema
+	// conc limits the mutations in flight if the concurrency is adapted to the backpressure of
+	// the Alphas. It is nil otherwise.
+	conc *concLimiter
 
 	upsertLock sync.RWMutex
 }
@@ -101,6 +108,8 @@ type Counter struct {
 	TxnsDone uint64
 	// Number of Aborts
 	Aborts uint64
+	// Number of N-Quads skipped by the transform.
+	Skipped uint64
 	// Time elapsed since the batch started.
 	Elapsed time.Duration
 }
@@ -168,13 +177,23 @@ func (l *loader) mutate(req *request) error {
 		CommitNow: true,
 		Mutations: []*api.Mutation{req.Mutation},
 	}
-	_, err := txn.Do(l.opts.Ctx, request)
+	ctx := l.opts.Ctx
+	if req.lineage != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "lineage", req.lineage)
+	}
+	_, err := txn.Do(ctx, request)
 	return err
 }
 
 func (l *loader) request(req *request) {
 	atomic.AddUint64(&l.reqNum, 1)
+	if l.conc != nil {
+		l.conc.acquire()
+	}
 	err := l.mutate(req)
+	if l.conc != nil {
+		l.conc.release()
+	}
 	if err == nil {
 		atomic.AddUint64(&l.nquads, uint64(len(req.Set)))
 		atomic.AddUint64(&l.txns, 1)
@@ -250,6 +269,10 @@ func fingerprintEdge(t *pb.DirectedEdge, pred *predicate) uint64 {
 }
 
 func (l *loader) conflictKeysForNQuad(nq *api.NQuad) ([]uint64, error) {
+	// The properties of an edge conflict on the edge they are set on.
+	if s, p, o, ok := chunker.ParseQuotedTriple(nq.Subject); ok {
+		nq = &api.NQuad{Subject: s, Predicate: p, ObjectId: o, Namespace: nq.Namespace}
+	}
 	attr := x.NamespaceAttr(nq.Namespace, nq.Predicate)
 	pred, found := l.schema.preds[attr]
 
@@ -439,5 +462,6 @@ func (l *loader) Counter() Counter {
 		TxnsDone: atomic.LoadUint64(&l.txns),
 		Elapsed:  time.Since(l.start),
 		Aborts:   atomic.LoadUint64(&l.aborts),
+		Skipped:  atomic.LoadUint64(&l.skipped),
 	}
 }
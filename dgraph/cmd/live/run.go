@@ -18,7 +18,6 @@ package live
 
 import (
 	"bufio"
-	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -34,6 +33,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc"
@@ -65,6 +65,7 @@ type options struct {
 	schemaFile      string
 	zero            string
 	concurrent      int
+	maxConcurrent   int
 	batchSize       int
 	clientDir       string
 	authToken       string
@@ -75,9 +76,12 @@ type options struct {
 	bufferSize      int
 	ludicrousMode   bool
 	upsertPredicate string
+	xidRegistry     bool
 	tmpDir          string
+	inferSchema     string
 	key             x.SensitiveByteSlice
 	namespaceToLoad uint64
+	transform       *transform
 }
 
 type predicate struct {
@@ -102,6 +106,28 @@ type schema struct {
 type request struct {
 	*api.Mutation
 	conflicts []uint64
+	// lineage is the provenance of the mutation, which the N-Quads carried in their
+	// dgraph.lineage facet.
+	lineage string
+}
+
+// takeLineage removes the lineage facet from the N-Quad and returns the provenance it holds. An
+// export carries the provenance of an edge in this facet, which is sent back as the lineage of
+// the mutation so that the Alpha recreates the lineage node.
+func takeLineage(nq *api.NQuad) string {
+	var lineage string
+	out := nq.Facets[:0]
+	for _, f := range nq.Facets {
+		if f.Key != x.LineageFacet {
+			out = append(out, f)
+			continue
+		}
+		if f.ValType == api.Facet_STRING {
+			lineage = string(f.Value)
+		}
+	}
+	nq.Facets = out
+	return lineage
 }
 
 func (l *schema) init(ns uint64) {
@@ -140,15 +166,23 @@ func init() {
 	Live.Cmd.SetHelpTemplate(x.NonRootTemplate)
 
 	flag := Live.Cmd.Flags()
-	flag.StringP("files", "f", "", "Location of *.rdf(.gz) or *.json(.gz) file(s) to load")
+	flag.StringP("files", "f", "",
+		"Location of *.rdf, *.json, *.jsonl, *.ttl or *.hdt file(s) to load, optionally "+
+			"compressed with gzip (.gz), zstd (.zst) or xz (.xz)")
 	flag.StringP("schema", "s", "", "Location of schema file")
-	flag.String("format", "", "Specify file format (rdf or json) instead of getting it "+
-		"from filename")
+	flag.String("infer-schema", "", "Scan the data files to infer the types, list-ness and "+
+		"indexes of their predicates. report prints the inferred schema and exits, apply "+
+		"alters the schema of the predicates missing from it and loads the data")
+	flag.String("format", "", "Specify file format (rdf, json, turtle or hdt) instead of "+
+		"getting it from filename")
 	flag.StringP("alpha", "a", "127.0.0.1:9080",
 		"Comma-separated list of Dgraph alpha gRPC server addresses")
 	flag.StringP("zero", "z", "127.0.0.1:5080", "Dgraph zero gRPC server address")
 	flag.IntP("conc", "c", 10,
 		"Number of concurrent requests to make to Dgraph")
+	flag.Int("max_conc", 0,
+		"If set, the number of concurrent requests starts at --conc and is adapted to the "+
+			"backpressure reported by the Alphas, up to this value.")
 	flag.IntP("batch", "b", 1000,
 		"Number of N-Quads to send as part of a mutation.")
 	flag.StringP("xidmap", "x", "", "Directory to store xid to uid mapping")
@@ -178,11 +212,19 @@ func init() {
 		"only be done when alpha is under ludicrous mode)")
 	flag.StringP("upsertPredicate", "U", "", "run in upsertPredicate mode. the value would "+
 		"be used to store blank nodes as an xid")
+	flag.Bool("xid_registry", false, "Get the UIDs of the xids from the xid registry of Zero, so "+
+		"that concurrent loaders assign the same UID to the same xid. Blank nodes still get new "+
+		"UIDs. Zero must run with --xid_registry.")
 	flag.String("tmp", "t", "Directory to store temporary buffers.")
 	flag.Int64("force-namespace", 0, "Namespace onto which to load the data."+
 		"This flag will be ignored when not logging into galaxy namespace."+
 		"Only guardian of galaxy should use this for loading data into multiple namespaces."+
 		"Setting it to negative value will preserve the namespace.")
+	flag.String("transform", "", "Rules applied in order to each N-Quad before it is loaded, "+
+		"separated by semicolons, like \"rename name@en title; coerce age int; "+
+		"skip if predicate == password || lang == fr\". Conditions compare predicate, subject, "+
+		"object, value and lang with ==, !=, =~, !~, <, <=, > and >=, combined with &&, || and !. "+
+		"A value starting with @ names a file holding one rule per line.")
 
 	// Encryption and Vault options
 	enc.RegisterFlags(flag)
@@ -225,10 +267,8 @@ func processSchemaFile(ctx context.Context, file string, key x.SensitiveByteSlic
 
 	reader, err := enc.GetReader(key, f)
 	x.Check(err)
-	if strings.HasSuffix(strings.ToLower(file), ".gz") {
-		reader, err = gzip.NewReader(reader)
-		x.Check(err)
-	}
+	reader, err = chunker.DecompressReader(file, reader)
+	x.Check(err)
 
 	b, err := ioutil.ReadAll(reader)
 	if err != nil {
@@ -240,6 +280,59 @@ func processSchemaFile(ctx context.Context, file string, key x.SensitiveByteSlic
 	return dgraphClient.Alter(ctx, op)
 }
 
+// inferSchema reads the data files to infer the schema of their predicates and prints a report
+// of it.
+func inferSchema() (*chunker.SchemaInferrer, error) {
+	if opt.dataFiles == "" {
+		return nil, errors.New("RDF or JSON file(s) location must be specified")
+	}
+	fs := filestore.NewFileStore(opt.dataFiles)
+	files := fs.FindDataFiles(opt.dataFiles, chunker.DataFileExtensions)
+	if len(files) == 0 {
+		return nil, errors.Errorf("No data files found in %s", opt.dataFiles)
+	}
+
+	fmt.Printf("Inferring the schema of %d data file(s)\n", len(files))
+	si, err := chunker.InferSchema(files, opt.dataFormat, func(file string) (*bufio.Reader, func()) {
+		return fs.ChunkReader(strings.Trim(file, " \t"), opt.key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := si.WriteReport(os.Stdout); err != nil {
+		return nil, err
+	}
+	fmt.Println()
+	return si, nil
+}
+
+// applyInferredSchema alters the schema of the predicates of the data files which the schema
+// doesn't define yet with the inferred one.
+func (l *loader) applyInferredSchema(ctx context.Context, dg *dgo.Dgraph) error {
+	si, err := inferSchema()
+	if err != nil {
+		return err
+	}
+	inferred := si.Schema(func(pred string) bool {
+		if opt.namespaceToLoad != math.MaxUint64 {
+			pred = x.NamespaceAttr(opt.namespaceToLoad, pred)
+		}
+		_, ok := l.schema.preds[pred]
+		return ok
+	})
+	if inferred == "" {
+		fmt.Printf("The schema already defines all the predicates of the data files\n\n")
+		return nil
+	}
+	if err := dg.Alter(ctx, &api.Operation{Schema: inferred}); err != nil {
+		return err
+	}
+	fmt.Printf("Applied the inferred schema:\n%s\n", inferred)
+
+	l.schema, err = getSchema(ctx, dg, opt.namespaceToLoad)
+	return err
+}
+
 func (l *loader) uid(val string, ns uint64) string {
 	// Attempt to parse as a UID (in the same format that dgraph outputs - a
 	// hex number prefixed by "0x"). If parsing succeeds, then this is assumed
@@ -261,6 +354,33 @@ func (l *loader) uid(val string, ns uint64) string {
 	return fmt.Sprintf("%#x", uint64(uid))
 }
 
+// prefetchUids gets the UIDs of the xids of the NQuads from the xid registry of Zero, with one
+// request for the whole batch.
+func (l *loader) prefetchUids(nqs []*api.NQuad) {
+	var keys []string
+	add := func(val string, ns uint64) {
+		// UIDs are kept as they are, see uid.
+		if !opt.newUids {
+			if _, err := strconv.ParseUint(val, 0, 64); err == nil {
+				return
+			}
+		}
+		keys = append(keys, x.NamespaceAttr(ns, val))
+	}
+	for _, nq := range nqs {
+		if s, _, o, ok := chunker.ParseQuotedTriple(nq.Subject); ok {
+			add(s, nq.Namespace)
+			add(o, nq.Namespace)
+		} else {
+			add(nq.Subject, nq.Namespace)
+		}
+		if len(nq.ObjectId) > 0 {
+			add(nq.ObjectId, nq.Namespace)
+		}
+	}
+	l.alloc.Prefetch(keys)
+}
+
 func generateBlankNode(val string) string {
 	// generates "u_hash(val)"
 
@@ -444,7 +564,8 @@ func (l *loader) processFile(ctx context.Context, fs filestore.FileStore, filena
 			if isJson {
 				loadType = chunker.JsonFormat
 			} else {
-				return errors.Errorf("need --format=rdf or --format=json to load %s", filename)
+				return errors.Errorf("need --format=rdf, --format=json, --format=turtle or "+
+					"--format=hdt to load %s", filename)
 			}
 		}
 	}
@@ -462,10 +583,18 @@ func (l *loader) processLoadFile(ctx context.Context, rd *bufio.Reader, ck chunk
 		buffer := make([]*api.NQuad, 0, opt.bufferSize*opt.batchSize)
 
 		drain := func() {
+			lineages := make(map[*api.NQuad]string)
+			for _, nq := range buffer {
+				if lineage := takeLineage(nq); lineage != "" {
+					lineages[nq] = lineage
+				}
+			}
+
 			// We collect opt.bufferSize requests and preprocess them. For the requests
 			// to not confict between themself, we sort them on the basis of their predicates.
 			// Predicates with count index will conflict among themselves, so we keep them at
-			// end, making room for other predicates to load quickly.
+			// end, making room for other predicates to load quickly. The N-Quads with the same
+			// lineage are kept together, as a mutation has a single lineage.
 			sort.Slice(buffer, func(i, j int) bool {
 				iPred := sch.preds[x.NamespaceAttr(buffer[i].Namespace, buffer[i].Predicate)]
 				jPred := sch.preds[x.NamespaceAttr(buffer[j].Namespace, buffer[j].Predicate)]
@@ -481,6 +610,9 @@ func (l *loader) processLoadFile(ctx context.Context, rd *bufio.Reader, ck chunk
 				if t(iPred) != t(jPred) {
 					return t(iPred) < t(jPred)
 				}
+				if iLineage, jLineage := lineages[buffer[i]], lineages[buffer[j]]; iLineage != jLineage {
+					return iLineage < jLineage
+				}
 				return buffer[i].Predicate < buffer[j].Predicate
 			})
 			for len(buffer) > 0 {
@@ -488,13 +620,22 @@ func (l *loader) processLoadFile(ctx context.Context, rd *bufio.Reader, ck chunk
 				if len(buffer) < opt.batchSize {
 					sz = len(buffer)
 				}
-				mu := &request{Mutation: &api.Mutation{Set: buffer[:sz]}}
+				lineage := lineages[buffer[0]]
+				for k := 1; k < sz; k++ {
+					if lineages[buffer[k]] != lineage {
+						sz = k
+						break
+					}
+				}
+				mu := &request{Mutation: &api.Mutation{Set: buffer[:sz]}, lineage: lineage}
 				l.reqs <- mu
 				buffer = buffer[sz:]
 			}
 		}
 
 		for nqs := range nqbuf.Ch() {
+			nqs, skipped := opt.transform.apply(nqs)
+			atomic.AddUint64(&l.skipped, uint64(skipped))
 			if len(nqs) == 0 {
 				continue
 			}
@@ -507,6 +648,9 @@ func (l *loader) processLoadFile(ctx context.Context, rd *bufio.Reader, ck chunk
 				}
 			}
 
+			if opt.xidRegistry {
+				l.prefetchUids(nqs)
+			}
 			if opt.upsertPredicate == "" {
 				l.allocateUids(nqs)
 			} else {
@@ -519,7 +663,12 @@ func (l *loader) processLoadFile(ctx context.Context, rd *bufio.Reader, ck chunk
 			}
 
 			for _, nq := range nqs {
-				nq.Subject = l.uid(nq.Subject, nq.Namespace)
+				if s, p, o, ok := chunker.ParseQuotedTriple(nq.Subject); ok {
+					nq.Subject = chunker.QuotedTriple(l.uid(s, nq.Namespace), p,
+						l.uid(o, nq.Namespace))
+				} else {
+					nq.Subject = l.uid(nq.Subject, nq.Namespace)
+				}
 				if len(nq.ObjectId) > 0 {
 					nq.ObjectId = l.uid(nq.ObjectId, nq.Namespace)
 				}
@@ -598,6 +747,9 @@ func setup(opts batchMutationOptions, dc *dgo.Dgraph, conf *viper.Viper) *loader
 	x.Checkf(err, "Unable to connect to zero, Is it running at %s?", opt.zero)
 
 	alloc := xidmap.New(connzero, db, "")
+	if opt.xidRegistry {
+		alloc.UseRegistry()
+	}
 	l := &loader{
 		opts:      opts,
 		dc:        dc,
@@ -636,6 +788,7 @@ func run() error {
 		schemaFile:      Live.Conf.GetString("schema"),
 		zero:            zero,
 		concurrent:      Live.Conf.GetInt("conc"),
+		maxConcurrent:   Live.Conf.GetInt("max_conc"),
 		batchSize:       Live.Conf.GetInt("batch"),
 		clientDir:       Live.Conf.GetString("xidmap"),
 		authToken:       Live.Conf.GetString("auth_token"),
@@ -646,7 +799,13 @@ func run() error {
 		bufferSize:      Live.Conf.GetInt("bufferSize"),
 		ludicrousMode:   Live.Conf.GetBool("ludicrous_mode"),
 		upsertPredicate: Live.Conf.GetString("upsertPredicate"),
+		xidRegistry:     Live.Conf.GetBool("xid_registry"),
 		tmpDir:          Live.Conf.GetString("tmp"),
+		inferSchema:     Live.Conf.GetString("infer-schema"),
+	}
+
+	if opt.transform, err = parseTransform(Live.Conf.GetString("transform")); err != nil {
+		return err
 	}
 
 	switch creds.GetUint64("namespace") {
@@ -667,6 +826,18 @@ func run() error {
 		fmt.Printf("unable to read key %v", err)
 		return err
 	}
+	switch opt.inferSchema {
+	case "", "apply":
+	case "report":
+		si, err := inferSchema()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Inferred schema:\n%s", si.Schema(nil))
+		return nil
+	default:
+		return errors.Errorf("invalid --infer-schema %q, must be report or apply", opt.inferSchema)
+	}
 	go func() {
 		if err := http.ListenAndServe(opt.httpAddr, nil); err != nil {
 			glog.Errorf("Error while starting HTTP server: %+v", err)
@@ -685,6 +856,10 @@ func run() error {
 		}
 	}
 
+	if opt.xidRegistry && len(opt.upsertPredicate) > 0 {
+		return errors.Errorf("The xid registry can't be used along with the upsert predicate.")
+	}
+
 	bmOpts := batchMutationOptions{
 		Size:          opt.batchSize,
 		Pending:       opt.concurrent,
@@ -697,12 +872,29 @@ func run() error {
 	// Create directory for temporary buffers.
 	x.Check(os.MkdirAll(opt.tmpDir, 0700))
 
+	adaptive := opt.maxConcurrent > 0 && Live.Conf.GetString("slash_grpc_endpoint") == ""
+	if adaptive && opt.maxConcurrent > bmOpts.Pending {
+		bmOpts.Pending = opt.maxConcurrent
+	}
+
 	dg, closeFunc := x.GetDgraphClient(Live.Conf, true)
 	defer closeFunc()
 
 	l := setup(bmOpts, dg, Live.Conf)
 	defer l.zeroconn.Close()
 
+	if adaptive {
+		clients, closeConns, err := backpressureClients(Live.Conf)
+		if err != nil {
+			return err
+		}
+		defer closeConns()
+		l.conc = newConcLimiter(opt.concurrent, opt.maxConcurrent)
+		adaptCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go l.adaptConcurrency(adaptCtx, clients)
+	}
+
 	if len(opt.schemaFile) > 0 {
 		err := processSchemaFile(ctx, opt.schemaFile, opt.key, dg)
 		if err != nil {
@@ -721,6 +913,12 @@ func run() error {
 		fmt.Printf("Error while loading schema from alpha %s\n", err)
 		return err
 	}
+	if opt.inferSchema == "apply" {
+		if err := l.applyInferredSchema(ctx, dg); err != nil {
+			fmt.Printf("Error while applying the inferred schema: %s\n", err)
+			return err
+		}
+	}
 
 	if opt.dataFiles == "" {
 		return errors.New("RDF or JSON file(s) location must be specified")
@@ -728,7 +926,7 @@ func run() error {
 
 	fs := filestore.NewFileStore(opt.dataFiles)
 
-	filesList := fs.FindDataFiles(opt.dataFiles, []string{".rdf", ".rdf.gz", ".json", ".json.gz"})
+	filesList := fs.FindDataFiles(opt.dataFiles, chunker.DataFileExtensions)
 	totalFiles := len(filesList)
 	if totalFiles == 0 {
 		return errors.Errorf("No data files found in %s", opt.dataFiles)
@@ -774,6 +972,9 @@ func run() error {
 	fmt.Printf("%100s\r", "")
 	fmt.Printf("Number of TXs run            : %d\n", c.TxnsDone)
 	fmt.Printf("Number of N-Quads processed  : %d\n", c.Nquads)
+	if len(opt.transform.rules) > 0 {
+		fmt.Printf("Number of N-Quads skipped    : %d\n", c.Skipped)
+	}
 	fmt.Printf("Time spent                   : %v\n", c.Elapsed)
 	fmt.Printf("N-Quads processed per second : %d\n", rate)
 
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package live
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestTakeLineage(t *testing.T) {
+	weight := &api.Facet{Key: "weight", Value: []byte("5"), ValType: api.Facet_STRING}
+	nq := strNQuad("_:a", "name", "", "Alice")
+	nq.Facets = []*api.Facet{
+		{Key: x.LineageFacet, Value: []byte(`{"source":"crm"}`), ValType: api.Facet_STRING},
+		weight,
+	}
+	require.Equal(t, `{"source":"crm"}`, takeLineage(nq))
+	require.Equal(t, []*api.Facet{weight}, nq.Facets)
+
+	// The uid of a lineage node, from an older export, is dropped as it can't be carried over.
+	nq.Facets = []*api.Facet{{Key: x.LineageFacet, Value: []byte{0x10}, ValType: api.Facet_INT}}
+	require.Equal(t, "", takeLineage(nq))
+	require.Empty(t, nq.Facets)
+}
@@ -0,0 +1,497 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package live
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/pkg/errors"
+)
+
+// A transform is a list of rules applied in order to each N-Quad before it is loaded. The rules
+// are:
+//
+//	rename <predicate> <new predicate> [if <condition>]
+//	coerce <predicate> <type> [if <condition>]
+//	skip if <condition>
+//
+// A predicate without a language, like name, matches all its languages, and rename keeps them.
+// A condition compares the fields predicate, subject, object, value and lang of the N-Quad to
+// literals with ==, !=, =~ (matches a regular expression), !~, <, <=, > and >=, and combines the
+// comparisons with &&, || and !, and parentheses. Literals are double quoted strings, numbers or
+// bare words. For example:
+//
+//	rename name@en title
+//	coerce age int if value =~ "^[0-9]+$"
+//	skip if predicate == "password" || (predicate == "age" && value < 0)
+type transform struct {
+	rules []*transformRule
+}
+
+type transformRule struct {
+	action string // rename, coerce or skip.
+	pred   string
+	arg    string // The new predicate for rename, the type for coerce.
+	tid    types.TypeID
+	cond   condition // nil if the rule applies to all the N-Quads.
+}
+
+// parseTransform parses the rules of the --transform flag, which are separated by semicolons. If
+// the flag starts with @, it names a file holding one rule per line instead, where empty lines and
+// lines starting with # are ignored.
+func parseTransform(flag string) (*transform, error) {
+	var rules []string
+	if strings.HasPrefix(flag, "@") {
+		data, err := ioutil.ReadFile(flag[1:])
+		if err != nil {
+			return nil, errors.Wrapf(err, "while reading the transform file")
+		}
+		rules = strings.Split(string(data), "\n")
+	} else {
+		rules = splitRules(flag)
+	}
+
+	t := &transform{}
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" || strings.HasPrefix(rule, "#") {
+			continue
+		}
+		r, err := parseTransformRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		t.rules = append(t.rules, r)
+	}
+	return t, nil
+}
+
+// splitRules splits the rules at the semicolons which aren't in a quoted string.
+func splitRules(s string) []string {
+	var rules []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == ';' && !quoted:
+			rules = append(rules, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(rules, s[start:])
+}
+
+func parseTransformRule(rule string) (*transformRule, error) {
+	p := &condParser{}
+	if err := p.lex(rule); err != nil {
+		return nil, errors.Wrapf(err, "invalid transform %q", rule)
+	}
+	r := &transformRule{action: p.next().val}
+	var err error
+	switch r.action {
+	case "rename", "coerce":
+		pred, arg := p.next(), p.next()
+		if pred.kind != tokWord || arg.kind != tokWord {
+			return nil, errors.Errorf("invalid transform %q: %s takes two arguments", rule,
+				r.action)
+		}
+		r.pred, r.arg = pred.val, arg.val
+		if r.action == "coerce" {
+			var ok bool
+			if r.tid, ok = types.TypeForName(r.arg); !ok || r.tid == types.UidID ||
+				r.tid == types.PasswordID {
+				return nil, errors.Errorf("invalid transform %q: can't coerce to %s", rule, r.arg)
+			}
+		}
+		if p.peek().kind == tokEOF {
+			return r, nil
+		}
+		if t := p.next(); t.kind != tokWord || t.val != "if" {
+			return nil, errors.Errorf("invalid transform %q: expected if, got %s", rule, t.val)
+		}
+	case "skip":
+		if t := p.next(); t.kind != tokWord || t.val != "if" {
+			return nil, errors.Errorf("invalid transform %q: expected skip if <condition>", rule)
+		}
+	default:
+		return nil, errors.Errorf("invalid transform %q: unknown action %s, must be rename, "+
+			"coerce or skip", rule, r.action)
+	}
+	if r.cond, err = p.parse(); err != nil {
+		return nil, errors.Wrapf(err, "invalid transform %q", rule)
+	}
+	return r, nil
+}
+
+// apply transforms the N-Quads in place, and returns the ones which aren't skipped along with
+// the number of skipped N-Quads. N-Quads whose value can't be coerced are skipped too.
+func (t *transform) apply(nqs []*api.NQuad) ([]*api.NQuad, int) {
+	if t == nil || len(t.rules) == 0 {
+		return nqs, 0
+	}
+	kept := nqs[:0]
+	skipped := 0
+	for _, nq := range nqs {
+		if t.applyOne(nq) {
+			kept = append(kept, nq)
+		} else {
+			skipped++
+		}
+	}
+	return kept, skipped
+}
+
+func (t *transform) applyOne(nq *api.NQuad) bool {
+	for _, r := range t.rules {
+		if r.action != "skip" && !r.matches(nq) {
+			continue
+		}
+		if r.cond != nil && !r.cond.eval(nq) {
+			continue
+		}
+		switch r.action {
+		case "skip":
+			return false
+		case "rename":
+			// A rule on the base predicate keeps the language of the N-Quad, unless the new
+			// name sets one.
+			nq.Predicate = r.arg
+			if i := strings.LastIndex(r.arg, "@"); i > 0 {
+				nq.Predicate, nq.Lang = r.arg[:i], r.arg[i+1:]
+			} else if strings.Contains(r.pred, "@") {
+				nq.Lang = ""
+			}
+		case "coerce":
+			if nq.ObjectValue == nil {
+				return false
+			}
+			src := types.Val{Tid: types.StringID, Value: []byte(nquadValue(nq))}
+			v, err := types.Convert(src, r.tid)
+			if err != nil {
+				return false
+			}
+			if nq.ObjectValue, err = types.ObjectValue(r.tid, v.Value); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matches returns whether the rule applies to the predicate of the N-Quad. A rule without a
+// language applies to all the languages of the predicate.
+func (r *transformRule) matches(nq *api.NQuad) bool {
+	if strings.Contains(r.pred, "@") {
+		return nquadPredicate(nq) == r.pred
+	}
+	return nq.Predicate == r.pred
+}
+
+// nquadPredicate returns the predicate of the N-Quad along with its language, like name@en.
+func nquadPredicate(nq *api.NQuad) string {
+	if nq.Lang != "" {
+		return nq.Predicate + "@" + nq.Lang
+	}
+	return nq.Predicate
+}
+
+// nquadValue returns the value of the N-Quad as a string, or an empty string for edges to nodes.
+func nquadValue(nq *api.NQuad) string {
+	switch v := nq.GetObjectValue().GetVal().(type) {
+	case *api.Value_StrVal:
+		return v.StrVal
+	case *api.Value_DefaultVal:
+		return v.DefaultVal
+	case *api.Value_IntVal:
+		return strconv.FormatInt(v.IntVal, 10)
+	case *api.Value_DoubleVal:
+		return strconv.FormatFloat(v.DoubleVal, 'g', -1, 64)
+	case *api.Value_BoolVal:
+		return strconv.FormatBool(v.BoolVal)
+	case *api.Value_DatetimeVal:
+		return binaryValueString(types.DateTimeID, v.DatetimeVal)
+	case *api.Value_GeoVal:
+		return binaryValueString(types.GeoID, v.GeoVal)
+	case *api.Value_BytesVal:
+		return string(v.BytesVal)
+	case *api.Value_PasswordVal:
+		return v.PasswordVal
+	}
+	return ""
+}
+
+func binaryValueString(tid types.TypeID, b []byte) string {
+	v, err := types.Convert(types.Val{Tid: tid, Value: b}, types.StringID)
+	if err != nil {
+		return ""
+	}
+	return v.Value.(string)
+}
+
+// condition is a parsed transform condition.
+type condition interface {
+	eval(nq *api.NQuad) bool
+}
+
+type notCond struct{ c condition }
+
+func (c notCond) eval(nq *api.NQuad) bool { return !c.c.eval(nq) }
+
+type andCond struct{ l, r condition }
+
+func (c andCond) eval(nq *api.NQuad) bool { return c.l.eval(nq) && c.r.eval(nq) }
+
+type orCond struct{ l, r condition }
+
+func (c orCond) eval(nq *api.NQuad) bool { return c.l.eval(nq) || c.r.eval(nq) }
+
+// cmpCond compares a field of the N-Quad to a literal. Ordering comparisons are numeric if both
+// sides are numbers, and lexical otherwise.
+type cmpCond struct {
+	field string
+	op    string
+	lit   string
+	num   float64
+	isNum bool
+	re    *regexp.Regexp
+}
+
+var transformFields = map[string]func(nq *api.NQuad) string{
+	"predicate": func(nq *api.NQuad) string { return nq.Predicate },
+	"subject":   func(nq *api.NQuad) string { return nq.Subject },
+	"object":    func(nq *api.NQuad) string { return nq.ObjectId },
+	"value":     nquadValue,
+	"lang":      func(nq *api.NQuad) string { return nq.Lang },
+}
+
+func (c *cmpCond) eval(nq *api.NQuad) bool {
+	v := transformFields[c.field](nq)
+	switch c.op {
+	case "==":
+		return v == c.lit
+	case "!=":
+		return v != c.lit
+	case "=~":
+		return c.re.MatchString(v)
+	case "!~":
+		return !c.re.MatchString(v)
+	}
+	cmp := strings.Compare(v, c.lit)
+	if f, err := strconv.ParseFloat(v, 64); err == nil && c.isNum {
+		switch {
+		case f < c.num:
+			cmp = -1
+		case f > c.num:
+			cmp = 1
+		default:
+			cmp = 0
+		}
+	}
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+const (
+	tokEOF = iota
+	tokWord
+	tokString
+	tokOp
+)
+
+type condToken struct {
+	kind int
+	val  string
+}
+
+// condParser is a recursive descent parser of the rules, with the grammar:
+//
+//	or   := and ('||' and)*
+//	and  := not ('&&' not)*
+//	not  := '!' not | '(' or ')' | field op literal
+type condParser struct {
+	toks []condToken
+	pos  int
+}
+
+func (p *condParser) lex(s string) error {
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return errors.Errorf("unterminated string at %d", i)
+			}
+			str, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return errors.Wrapf(err, "invalid string at %d", i)
+			}
+			p.toks = append(p.toks, condToken{tokString, str})
+			i = j + 1
+		case strings.ContainsRune("=!<>&|()", c):
+			op := string(c)
+			for _, two := range []string{"==", "!=", "=~", "!~", "<=", ">=", "&&", "||"} {
+				if strings.HasPrefix(s[i:], two) {
+					op = two
+					break
+				}
+			}
+			if op == "=" || op == "&" || op == "|" {
+				return errors.Errorf("invalid operator %s at %d", op, i)
+			}
+			p.toks = append(p.toks, condToken{tokOp, op})
+			i += len(op)
+		default:
+			j := i
+			for j < len(s) && !unicode.IsSpace(rune(s[j])) &&
+				!strings.ContainsRune("\"=!<>&|()", rune(s[j])) {
+				j++
+			}
+			p.toks = append(p.toks, condToken{tokWord, s[i:j]})
+			i = j
+		}
+	}
+	return nil
+}
+
+func (p *condParser) peek() condToken {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return condToken{kind: tokEOF}
+}
+
+func (p *condParser) next() condToken {
+	t := p.peek()
+	if p.pos < len(p.toks) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *condParser) parse() (condition, error) {
+	c, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errors.Errorf("unexpected %s", t.val)
+	}
+	return c, nil
+}
+
+func (p *condParser) parseOr() (condition, error) {
+	l, err := p.parseAnd()
+	for err == nil && p.peek() == (condToken{tokOp, "||"}) {
+		p.next()
+		var r condition
+		if r, err = p.parseAnd(); err == nil {
+			l = orCond{l, r}
+		}
+	}
+	return l, err
+}
+
+func (p *condParser) parseAnd() (condition, error) {
+	l, err := p.parseNot()
+	for err == nil && p.peek() == (condToken{tokOp, "&&"}) {
+		p.next()
+		var r condition
+		if r, err = p.parseNot(); err == nil {
+			l = andCond{l, r}
+		}
+	}
+	return l, err
+}
+
+func (p *condParser) parseNot() (condition, error) {
+	t := p.next()
+	switch {
+	case t == condToken{tokOp, "!"}:
+		c, err := p.parseNot()
+		return notCond{c}, err
+	case t == condToken{tokOp, "("}:
+		c, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t != (condToken{tokOp, ")"}) {
+			return nil, errors.Errorf("expected ), got %q", t.val)
+		}
+		return c, nil
+	case t.kind == tokWord:
+		if _, ok := transformFields[t.val]; !ok {
+			return nil, errors.Errorf("unknown field %s, must be predicate, subject, object, "+
+				"value or lang", t.val)
+		}
+		c := &cmpCond{field: t.val}
+		op := p.next()
+		switch op.val {
+		case "==", "!=", "=~", "!~", "<", "<=", ">", ">=":
+			if op.kind == tokOp {
+				break
+			}
+			fallthrough
+		default:
+			return nil, errors.Errorf("expected a comparison after %s, got %q", t.val, op.val)
+		}
+		c.op = op.val
+		lit := p.next()
+		if lit.kind != tokWord && lit.kind != tokString {
+			return nil, errors.Errorf("expected a literal after %s %s", t.val, op.val)
+		}
+		c.lit = lit.val
+		if f, err := strconv.ParseFloat(lit.val, 64); err == nil {
+			c.num, c.isNum = f, true
+		}
+		if c.op == "=~" || c.op == "!~" {
+			re, err := regexp.Compile(c.lit)
+			if err != nil {
+				return nil, err
+			}
+			c.re = re
+		}
+		return c, nil
+	case t.kind == tokEOF:
+		return nil, errors.New("unexpected end of condition")
+	}
+	return nil, errors.Errorf("unexpected %q", t.val)
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package live
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/stretchr/testify/require"
+)
+
+func strNQuad(subject, pred, lang, val string) *api.NQuad {
+	return &api.NQuad{Subject: subject, Predicate: pred, Lang: lang,
+		ObjectValue: &api.Value{Val: &api.Value_DefaultVal{DefaultVal: val}}}
+}
+
+func TestTransform(t *testing.T) {
+	tr, err := parseTransform(`rename name@en title; coerce age int if value =~ "^[0-9]+$";` +
+		`skip if predicate == password || (predicate == age && value < 0);` +
+		`skip if lang == "fr" && !(value == "Paris; France")`)
+	require.NoError(t, err)
+	require.Len(t, tr.rules, 4)
+
+	nqs := []*api.NQuad{
+		strNQuad("_:a", "name", "en", "Alice"),
+		strNQuad("_:a", "name", "fr", "Alicia"),
+		strNQuad("_:a", "name", "fr", "Paris; France"),
+		strNQuad("_:a", "age", "", "30"),
+		strNQuad("_:b", "age", "", "-3"),
+		strNQuad("_:b", "age", "", "unknown"),
+		strNQuad("_:b", "password", "", "secret"),
+		{Subject: "_:a", Predicate: "friend", ObjectId: "_:b"},
+	}
+	kept, skipped := tr.apply(nqs)
+	require.Equal(t, 3, skipped)
+	require.Len(t, kept, 5)
+
+	require.Equal(t, "title", kept[0].Predicate)
+	require.Empty(t, kept[0].Lang)
+	require.Equal(t, "Paris; France", nquadValue(kept[1]))
+	require.Equal(t, &api.Value{Val: &api.Value_IntVal{IntVal: 30}}, kept[2].ObjectValue)
+	// Values which don't match the condition of coerce are left as they are.
+	require.Equal(t, "unknown", nquadValue(kept[3]))
+	require.Equal(t, "friend", kept[4].Predicate)
+
+	// Rules without a language apply to all the languages of the predicate, and keep them.
+	tr, err = parseTransform("rename name title; coerce age int")
+	require.NoError(t, err)
+	nqs = []*api.NQuad{
+		strNQuad("_:a", "name", "en", "Alice"),
+		strNQuad("_:a", "name", "", "Alice"),
+		strNQuad("_:a", "age", "en", "30"),
+	}
+	kept, skipped = tr.apply(nqs)
+	require.Zero(t, skipped)
+	require.Equal(t, "title", kept[0].Predicate)
+	require.Equal(t, "en", kept[0].Lang)
+	require.Equal(t, "title", kept[1].Predicate)
+	require.Empty(t, kept[1].Lang)
+	require.Equal(t, &api.Value{Val: &api.Value_IntVal{IntVal: 30}}, kept[2].ObjectValue)
+}
+
+func TestTransformErrors(t *testing.T) {
+	for rule, msg := range map[string]string{
+		"drop name":                  "unknown action drop",
+		"rename name":                "rename takes two arguments",
+		"coerce age uid":             "can't coerce to uid",
+		"skip predicate == name":     "expected skip if <condition>",
+		"skip if color == red":       "unknown field color",
+		"skip if value = 1":          "invalid operator =",
+		"skip if (value == 1":        "expected ), got \"\"",
+		`skip if value == "1`:        "unterminated string",
+		"skip if value =~ (":         "expected a literal",
+		"rename a b if lang == en x": "unexpected x",
+	} {
+		_, err := parseTransform(rule)
+		require.Error(t, err, rule)
+		require.Contains(t, err.Error(), msg, rule)
+	}
+}
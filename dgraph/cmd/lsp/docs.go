@@ -0,0 +1,200 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lsp
+
+// help describes a function or a directive for completions and hovers.
+type help struct {
+	signature string
+	text      string
+}
+
+var functionDocs = map[string]help{
+	"allof": {
+		"allof(predicate, \"tokenizer\", \"value\")",
+		"Matches values that contain all of the tokens of value, using a custom tokenizer.",
+	},
+	"allofterms": {
+		"allofterms(predicate, \"terms\")",
+		"Matches strings that contain all of the terms, in any order. Needs a term index.",
+	},
+	"alloftext": {
+		"alloftext(predicate, \"text\")",
+		"Full-text search matching all of the stemmed words, without stop words. " +
+			"Needs a fulltext index.",
+	},
+	"anyof": {
+		"anyof(predicate, \"tokenizer\", \"value\")",
+		"Matches values that contain any of the tokens of value, using a custom tokenizer.",
+	},
+	"anyofterms": {
+		"anyofterms(predicate, \"terms\")",
+		"Matches strings that contain any of the terms, in any order. Needs a term index.",
+	},
+	"anyoftext": {
+		"anyoftext(predicate, \"text\")",
+		"Full-text search matching any of the stemmed words, without stop words. " +
+			"Needs a fulltext index.",
+	},
+	"between": {
+		"between(predicate, from, to)",
+		"Matches values in the inclusive range from the lower to the upper bound. Needs an index.",
+	},
+	"contains": {
+		"contains(predicate, [long, lat])",
+		"Matches geo polygons that contain the point, or the polygon. Needs a geo index.",
+	},
+	"eq": {
+		"eq(predicate, value)",
+		"Matches values equal to value, or to any of a list of values. " +
+			"Needs an index, except in filters.",
+	},
+	"fuzzy": {"fuzzy(predicate, \"value\", distance)", "Alias of match()."},
+	"ge": {
+		"ge(predicate, value)",
+		"Matches values greater than or equal to value. Needs an index, except in filters.",
+	},
+	"gt": {
+		"gt(predicate, value)",
+		"Matches values greater than value. Needs an index, except in filters.",
+	},
+	"has": {
+		"has(predicate)",
+		"Matches nodes that have a value for the predicate. " +
+			"At the root, page through the results with first.",
+	},
+	"intersects": {
+		"intersects(predicate, [[[long, lat], ...]])",
+		"Matches geo polygons that intersect the polygon. Needs a geo index.",
+	},
+	"le": {
+		"le(predicate, value)",
+		"Matches values less than or equal to value. Needs an index, except in filters.",
+	},
+	"lt": {
+		"lt(predicate, value)",
+		"Matches values less than value. Needs an index, except in filters.",
+	},
+	"match": {
+		"match(predicate, \"value\", distance)",
+		"Matches strings within the Levenshtein distance of value. Needs a trigram index.",
+	},
+	"near": {
+		"near(predicate, [long, lat], distance)",
+		"Matches geo locations within distance meters of the point. Needs a geo index.",
+	},
+	"regexp": {
+		"regexp(predicate, /regex/flags)",
+		"Matches strings against a regular expression. Needs a trigram index.",
+	},
+	"type": {
+		"type(Type)",
+		"Matches nodes of the type, set through their dgraph.type predicate.",
+	},
+	"uid": {
+		"uid(0x1, ...) or uid(variable)",
+		"Matches the given UIDs, or the UIDs in a variable.",
+	},
+	"uid_in": {
+		"uid_in(predicate, uid)",
+		"Matches nodes that have an edge of the predicate to the UID. Only valid in filters.",
+	},
+	"within": {
+		"within(predicate, [[[long, lat], ...]])",
+		"Matches geo locations within the polygon. Needs a geo index.",
+	},
+}
+
+var directiveDocs = map[string]help{
+	"cascade": {
+		"@cascade or @cascade(predicate, ...)",
+		"Removes the nodes that don't have all of the predicates of the block, or the ones given.",
+	},
+	"facets": {
+		"@facets or @facets(name, ...) or @facets(filter)",
+		"Returns the facets of the edge, or filters edges on them.",
+	},
+	"filter": {
+		"@filter(function)",
+		"Keeps the nodes that match the function. Combine functions with AND, OR and NOT.",
+	},
+	"graph": {"@graph(name, ...)", "Only follows the edges of the given named graphs."},
+	"groupby": {
+		"@groupby(predicate, ...)",
+		"Groups the nodes of the block by the values of the predicates.",
+	},
+	"ignorereflex": {"@ignorereflex", "Removes the parent node from the children of the block."},
+	"normalize":    {"@normalize", "Flattens the result, only returning the aliased predicates."},
+	"recurse": {
+		"@recurse(depth: n, loop: bool)",
+		"Follows the predicates of the block recursively.",
+	},
+}
+
+var graphqlDirectiveDocs = map[string]help{
+	"auth": {
+		"@auth(query: ..., add: ..., update: ..., delete: ...)",
+		"Authorization rules of the type, based on the claims of the JWT.",
+	},
+	"cascade": {
+		"@cascade(fields: [...])",
+		"Removes the objects that don't have all of the fields, or the ones given.",
+	},
+	"custom": {
+		"@custom(http: {...} or dql: \"...\")",
+		"Resolves the field or the query with an HTTP endpoint or a DQL query.",
+	},
+	"dgraph": {
+		"@dgraph(type: \"...\", pred: \"...\")",
+		"Maps the type or the field to a DQL type or predicate.",
+	},
+	"generate": {
+		"@generate(query: {...}, mutation: {...}, subscription: bool)",
+		"Selects the queries and mutations generated for the type.",
+	},
+	"hasInverse": {
+		"@hasInverse(field: name)",
+		"Keeps the edge and the field of the other type in sync.",
+	},
+	"id": {
+		"@id",
+		"Makes the field an identifier: its values are unique, " +
+			"and it can be used to get an object.",
+	},
+	"lambda": {"@lambda", "Resolves the field or the query with the lambda server."},
+	"remote": {
+		"@remote",
+		"Marks the type as only being returned by custom resolvers, so it isn't stored.",
+	},
+	"search": {
+		"@search(by: [...])",
+		"Indexes the field so that it can be used in filters.",
+	},
+	"secret": {
+		"@secret(field: name)",
+		"Adds a password field to the type, checked with checkPassword queries.",
+	},
+	"withSubscription": {
+		"@withSubscription",
+		"Generates subscriptions for the queries of the type.",
+	},
+}
+
+// graphqlScalars are the scalar types that can be used in a GraphQL schema.
+var graphqlScalars = []string{
+	"Boolean", "DateTime", "Float", "ID", "Int", "Int64", "MultiPolygon", "Point", "Polygon",
+	"String",
+}
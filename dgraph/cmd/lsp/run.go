@@ -0,0 +1,145 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package lsp implements "dgraph lsp", a language server that gives editors completions, hover
+// docs and diagnostics for DQL queries and GraphQL schemas. Completions and hovers include the
+// predicates and types of the schema of a running Alpha.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200"
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+)
+
+// LSP is the sub-command invoked when calling "dgraph lsp".
+var LSP x.SubCommand
+
+func init() {
+	LSP.Cmd = &cobra.Command{
+		Use:   "lsp",
+		Short: "Run a language server for DQL queries and GraphQL schemas",
+		Long: `
+Run a language server that speaks the Language Server Protocol over stdin and stdout,
+so that editors like VS Code get completions, hover docs and diagnostics for DQL
+queries and GraphQL schemas. Documents with the graphql language ID, or with a
+.graphql or .gql extension, are GraphQL schemas. Other documents are DQL queries.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := run(); err != nil {
+				glog.Errorf("%v", err)
+				os.Exit(1)
+			}
+		},
+		Annotations: map[string]string{"group": "tool"},
+	}
+	LSP.EnvPrefix = "DGRAPH_LSP"
+	LSP.Cmd.SetHelpTemplate(x.NonRootTemplate)
+
+	flag := LSP.Cmd.Flags()
+	flag.String("alpha", "localhost:9080",
+		"Address of the Dgraph Alpha whose schema is used for completions. Empty to not use one.")
+	flag.Duration("schema_refresh", 30*time.Second,
+		"How often to fetch the schema from the Alpha again.")
+	flag.String("creds", "",
+		`Various login credentials if login is required.
+	user defines the username to login.
+	password defines the password of the user.
+	namespace defines the namespace to log into.
+	Sample flag could look like --creds user=username;password=mypass;namespace=2`)
+	x.RegisterClientTLSFlags(flag)
+}
+
+func run() error {
+	s := newServer(os.Stdin, os.Stdout)
+	s.refresh = LSP.Conf.GetDuration("schema_refresh")
+	if alpha := LSP.Conf.GetString("alpha"); alpha != "" {
+		tlsCfg, err := x.LoadClientTLSConfig(LSP.Conf)
+		if err != nil {
+			return err
+		}
+		conn, err := x.SetupConnection(alpha, tlsCfg, false)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		dg := dgo.NewDgraphClient(api.NewDgraphClient(conn))
+		s.fetch = schemaFetcher(dg)
+	}
+	return s.serve()
+}
+
+// schemaFetcher returns a function that fetches the schema through the client. It logs in first,
+// if credentials were given. Unlike the other tools, it never asks for a password, because stdin
+// is used by the protocol.
+func schemaFetcher(dg *dgo.Dgraph) func(ctx context.Context) (*liveSchema, error) {
+	creds := z.NewSuperFlag(LSP.Conf.GetString("creds")).MergeAndCheckDefault(x.DefaultCreds)
+	loggedIn := false
+	return func(ctx context.Context) (*liveSchema, error) {
+		if user := creds.GetString("user"); user != "" && !loggedIn {
+			if err := dg.LoginIntoNamespace(ctx, user, creds.GetString("password"),
+				creds.GetUint64("namespace")); err != nil {
+				return nil, err
+			}
+			loggedIn = true
+		}
+
+		resp, err := dg.NewReadOnlyTxn().Query(ctx, "schema {}")
+		if err != nil {
+			return nil, err
+		}
+		return parseSchema(resp.Json)
+	}
+}
+
+// parseSchema parses the response of a schema query.
+func parseSchema(js []byte) (*liveSchema, error) {
+	var resp struct {
+		Schema []predicate `json:"schema"`
+		Types  []struct {
+			Name   string `json:"name"`
+			Fields []struct {
+				Name string `json:"name"`
+			} `json:"fields"`
+		} `json:"types"`
+	}
+	if err := json.Unmarshal(js, &resp); err != nil {
+		return nil, err
+	}
+
+	sch := &liveSchema{
+		predicates: make(map[string]predicate, len(resp.Schema)),
+		types:      make(map[string][]string, len(resp.Types)),
+	}
+	for _, p := range resp.Schema {
+		sch.predicates[p.Predicate] = p
+	}
+	for _, t := range resp.Types {
+		fields := make([]string, 0, len(t.Fields))
+		for _, f := range t.Fields {
+			fields = append(fields, f.Name)
+		}
+		sch.types[t.Name] = fields
+	}
+	return sch, nil
+}
@@ -0,0 +1,474 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgraph/dql"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/gqlparser/v2/gqlerror"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+const (
+	langDQL     = "dql"
+	langGraphQL = "graphql"
+)
+
+// predicate is the schema of a predicate, as returned by a schema query.
+type predicate struct {
+	Predicate string   `json:"predicate"`
+	Type      string   `json:"type"`
+	Index     bool     `json:"index"`
+	Tokenizer []string `json:"tokenizer"`
+	List      bool     `json:"list"`
+	Reverse   bool     `json:"reverse"`
+	Count     bool     `json:"count"`
+	Upsert    bool     `json:"upsert"`
+	Lang      bool     `json:"lang"`
+}
+
+// String returns the predicate as it is written in a DQL schema.
+func (p predicate) String() string {
+	var b strings.Builder
+	typ := p.Type
+	if p.List {
+		typ = "[" + typ + "]"
+	}
+	fmt.Fprintf(&b, "%s: %s", p.Predicate, typ)
+	if p.Index {
+		fmt.Fprintf(&b, " @index(%s)", strings.Join(p.Tokenizer, ", "))
+	}
+	for _, d := range []struct {
+		set  bool
+		name string
+	}{{p.Reverse, "reverse"}, {p.Count, "count"}, {p.Upsert, "upsert"}, {p.Lang, "lang"}} {
+		if d.set {
+			b.WriteString(" @" + d.name)
+		}
+	}
+	b.WriteString(" .")
+	return b.String()
+}
+
+// liveSchema is the schema of the Alpha the server is connected to.
+type liveSchema struct {
+	predicates map[string]predicate
+	types      map[string][]string
+}
+
+type document struct {
+	lang string
+	text string
+}
+
+type server struct {
+	conn *conn
+	// fetch returns the current schema. It is nil if the server isn't connected to an Alpha.
+	fetch     func(ctx context.Context) (*liveSchema, error)
+	refresh   time.Duration
+	schema    *liveSchema
+	fetchedAt time.Time
+
+	docs     map[string]*document
+	shutdown bool
+}
+
+func newServer(r io.Reader, w io.Writer) *server {
+	return &server{conn: newConn(r, w), docs: make(map[string]*document)}
+}
+
+// serve handles messages until the client exits or closes the connection. Messages are handled
+// one at a time, in the order they are received.
+func (s *server) serve() error {
+	for {
+		msg, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if rerr, ok := err.(*responseError); ok {
+			if err := s.conn.write(&errorResponse{JSONRPC: "2.0", Error: rerr}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+			return nil
+		}
+
+		result, err := s.handle(msg)
+		if msg.ID == nil {
+			if err != nil {
+				glog.Warningf("While handling %s: %v", msg.Method, err)
+			}
+			continue
+		}
+		if err != nil {
+			rerr, ok := err.(*responseError)
+			if !ok {
+				rerr = &responseError{Code: codeInvalidParams, Message: err.Error()}
+			}
+			err = s.conn.write(&errorResponse{JSONRPC: "2.0", ID: msg.ID, Error: rerr})
+		} else {
+			err = s.conn.write(&response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) handle(msg *message) (interface{}, error) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				// Documents are always sent in full.
+				"textDocumentSync": 1,
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{"@", "("},
+				},
+				"hoverProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "dgraph", "version": x.Version()},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc := &document{lang: language(params.TextDocument), text: params.TextDocument.Text}
+		s.docs[params.TextDocument.URI] = doc
+		return nil, s.publishDiagnostics(params.TextDocument.URI, doc)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok || len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		doc.text = params.ContentChanges[len(params.ContentChanges)-1].Text
+		return nil, s.publishDiagnostics(params.TextDocument.URI, doc)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.conn.write(&message{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics",
+			Params: mustMarshal(publishDiagnosticsParams{
+				URI: params.TextDocument.URI, Diagnostics: []diagnostic{}})})
+	case "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return []completionItem{}, nil
+		}
+		return s.complete(doc, params.Position), nil
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		}
+		if h := s.hover(doc, params.Position); h != nil {
+			return h, nil
+		}
+		return nil, nil
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+// language returns whether the document is a DQL query or a GraphQL schema.
+func language(doc textDocumentItem) string {
+	if doc.LanguageID == langGraphQL || strings.HasSuffix(doc.URI, ".graphql") ||
+		strings.HasSuffix(doc.URI, ".gql") {
+		return langGraphQL
+	}
+	return langDQL
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	x.Check(err)
+	return b
+}
+
+// liveSchema returns the schema of the Alpha, fetching it again once it is older than the
+// refresh interval. It returns the last schema fetched if the Alpha can't be reached.
+func (s *server) liveSchema() *liveSchema {
+	if s.fetch == nil || time.Since(s.fetchedAt) < s.refresh {
+		return s.schema
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sch, err := s.fetch(ctx)
+	// Don't try again before the refresh interval, so that an Alpha that is down doesn't slow
+	// every request down.
+	s.fetchedAt = time.Now()
+	if err != nil {
+		glog.Warningf("While fetching the schema: %v", err)
+		return s.schema
+	}
+	s.schema = sch
+	return sch
+}
+
+func (s *server) publishDiagnostics(uri string, doc *document) error {
+	diags := []diagnostic{}
+	lines := strings.Split(doc.text, "\n")
+	add := func(line, col int, sev int, code, msg string) {
+		// line and col are 1-based, and col counts bytes.
+		d := diagnostic{Severity: sev, Code: code, Source: "dgraph", Message: msg}
+		if line > 0 && line <= len(lines) {
+			text := lines[line-1]
+			start := col - 1
+			if start < 0 {
+				start = 0
+			}
+			end := wordEnd(text, start)
+			if end == start && end < len(text) {
+				end++
+			}
+			d.Range.Start = position{Line: line - 1, Character: toCharacter(text, start)}
+			d.Range.End = position{Line: line - 1, Character: toCharacter(text, end)}
+		}
+		diags = append(diags, d)
+	}
+
+	switch doc.lang {
+	case langGraphQL:
+		for _, err := range graphqlErrors(doc.text) {
+			var line, col int
+			if len(err.Locations) > 0 {
+				line, col = err.Locations[0].Line, err.Locations[0].Column
+			}
+			add(line, col, severityError, "", err.Message)
+		}
+	default:
+		for _, d := range dql.Lint(doc.text) {
+			sev := severityError
+			if d.Severity == dql.SeverityWarning {
+				sev = severityWarning
+			}
+			msg := d.Message
+			if d.Suggestion != "" {
+				msg += " (" + d.Suggestion + ")"
+			}
+			add(d.Line, d.Column, sev, d.Rule, msg)
+		}
+	}
+
+	return s.conn.write(&message{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics",
+		Params: mustMarshal(publishDiagnosticsParams{URI: uri, Diagnostics: diags})})
+}
+
+// graphqlErrors returns the errors in a GraphQL schema.
+func graphqlErrors(text string) gqlerror.List {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	_, err := schema.NewHandler(text, false)
+	switch err := err.(type) {
+	case nil:
+		return nil
+	case gqlerror.List:
+		return err
+	case *gqlerror.Error:
+		return gqlerror.List{err}
+	default:
+		return gqlerror.List{{Message: err.Error()}}
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' ||
+		b == '_' || b == '.' || b == '~'
+}
+
+func wordEnd(line string, i int) int {
+	for i < len(line) && isWordByte(line[i]) {
+		i++
+	}
+	return i
+}
+
+func wordStart(line string, i int) int {
+	for i > 0 && isWordByte(line[i-1]) {
+		i--
+	}
+	return i
+}
+
+// wordAt returns the word at the position, and the byte offsets of its bounds in the line.
+func wordAt(doc *document, pos position) (line string, start, end int) {
+	lines := strings.Split(doc.text, "\n")
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return "", 0, 0
+	}
+	line = lines[pos.Line]
+	i := byteOffset(line, pos.Character)
+	return line, wordStart(line, i), wordEnd(line, i)
+}
+
+func docItem(label string, kind int, d help) completionItem {
+	return completionItem{Label: label, Kind: kind, Detail: d.signature,
+		Documentation: markdown(d.text)}
+}
+
+func (s *server) complete(doc *document, pos position) []completionItem {
+	lines := strings.Split(doc.text, "\n")
+	var line string
+	if pos.Line >= 0 && pos.Line < len(lines) {
+		line = lines[pos.Line]
+	}
+	start := wordStart(line, byteOffset(line, pos.Character))
+	directive := start > 0 && line[start-1] == '@'
+
+	items := []completionItem{}
+	switch {
+	case doc.lang == langGraphQL && directive:
+		for name, d := range graphqlDirectiveDocs {
+			items = append(items, docItem(name, kindKeyword, d))
+		}
+	case doc.lang == langGraphQL:
+		for _, name := range graphqlScalars {
+			items = append(items, completionItem{Label: name, Kind: kindClass, Detail: "scalar"})
+		}
+		for _, m := range graphqlTypes.FindAllStringSubmatch(doc.text, -1) {
+			items = append(items, completionItem{Label: m[2], Kind: kindClass, Detail: m[1]})
+		}
+	case directive:
+		for name, d := range directiveDocs {
+			items = append(items, docItem(name, kindKeyword, d))
+		}
+	default:
+		for name, d := range functionDocs {
+			items = append(items, docItem(name, kindFunction, d))
+		}
+		if sch := s.liveSchema(); sch != nil {
+			for name, p := range sch.predicates {
+				if hidden(name) {
+					continue
+				}
+				items = append(items, completionItem{Label: name, Kind: kindField,
+					Detail: p.String()})
+			}
+			for name := range sch.types {
+				if hidden(name) {
+					continue
+				}
+				items = append(items, completionItem{Label: name, Kind: kindClass,
+					Detail: "type " + name})
+			}
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Kind != items[j].Kind {
+			return items[i].Kind < items[j].Kind
+		}
+		return items[i].Label < items[j].Label
+	})
+	return items
+}
+
+// hidden returns whether the predicate or the type is only used internally by Dgraph, and so
+// isn't worth completing.
+func hidden(name string) bool {
+	return strings.HasPrefix(name, "dgraph.") && name != "dgraph.type"
+}
+
+var graphqlTypes = regexp.MustCompile(`(?m)^\s*(type|interface|enum|union|input)\s+(\w+)`)
+
+func (s *server) hover(doc *document, pos position) *hover {
+	line, start, end := wordAt(doc, pos)
+	if start == end {
+		return nil
+	}
+	word := line[start:end]
+	directive := start > 0 && line[start-1] == '@'
+
+	var contents string
+	docText := func(d help) string {
+		return fmt.Sprintf("```\n%s\n```\n%s", d.signature, d.text)
+	}
+	switch {
+	case doc.lang == langGraphQL:
+		if d, ok := graphqlDirectiveDocs[word]; ok && directive {
+			contents = docText(d)
+		}
+	case directive:
+		if d, ok := directiveDocs[word]; ok {
+			contents = docText(d)
+		}
+	default:
+		call := strings.HasPrefix(strings.TrimLeft(line[end:], " \t"), "(")
+		if d, ok := functionDocs[word]; ok && call {
+			contents = docText(d)
+			break
+		}
+		sch := s.liveSchema()
+		if sch == nil {
+			break
+		}
+		if p, ok := sch.predicates[word]; ok {
+			contents = fmt.Sprintf("```\n%s\n```", p)
+		} else if fields, ok := sch.types[word]; ok {
+			contents = fmt.Sprintf("```\ntype %s {\n  %s\n}\n```", word,
+				strings.Join(fields, "\n  "))
+		}
+	}
+	if contents == "" {
+		return nil
+	}
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: contents},
+		Range: &lspRange{
+			Start: position{Line: pos.Line, Character: toCharacter(line, start)},
+			End:   position{Line: pos.Line, Character: toCharacter(line, end)},
+		},
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+	"schema": [
+		{"predicate": "name", "type": "string", "index": true, "tokenizer": ["exact", "term"]},
+		{"predicate": "friend", "type": "uid", "list": true, "reverse": true, "count": true}
+	],
+	"types": [{"name": "Person", "fields": [{"name": "name"}, {"name": "friend"}]}]
+}`
+
+// session runs the server on the given requests, and returns what it wrote back.
+func session(t *testing.T, reqs ...map[string]interface{}) []map[string]interface{} {
+	var in bytes.Buffer
+	for _, req := range reqs {
+		req["jsonrpc"] = "2.0"
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	var out bytes.Buffer
+	s := newServer(&in, &out)
+	s.fetch = func(ctx context.Context) (*liveSchema, error) {
+		return parseSchema([]byte(testSchema))
+	}
+	require.NoError(t, s.serve())
+
+	var msgs []map[string]interface{}
+	c := newConn(&out, ioutil.Discard)
+	for {
+		header, err := c.r.ReadMIMEHeader()
+		if err == io.EOF {
+			return msgs
+		}
+		require.NoError(t, err)
+		var length int
+		_, err = fmt.Sscan(header.Get("Content-Length"), &length)
+		require.NoError(t, err)
+		body := make([]byte, length)
+		_, err = io.ReadFull(c.r.R, body)
+		require.NoError(t, err)
+		var msg map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &msg))
+		msgs = append(msgs, msg)
+	}
+}
+
+func open(uri, text string) map[string]interface{} {
+	return map[string]interface{}{"method": "textDocument/didOpen", "params": map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "", "text": text}}}
+}
+
+func at(id int, method, uri string, line, char int) map[string]interface{} {
+	return map[string]interface{}{"id": id, "method": method, "params": map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     map[string]interface{}{"line": line, "character": char}}}
+}
+
+func labels(result interface{}) []string {
+	var out []string
+	for _, item := range result.([]interface{}) {
+		out = append(out, item.(map[string]interface{})["label"].(string))
+	}
+	return out
+}
+
+func TestDiagnostics(t *testing.T) {
+	msgs := session(t,
+		open("file:///q.dql", "{\n  q(func: has(name)) @filtr(eq(name, \"a\")) {\n    name\n  }\n}"),
+		open("file:///schema.graphql", "type Person {\n  name: Strin\n}"),
+		open("file:///ok.dql", "{ q(func: uid(0x1)) { name } }"),
+	)
+	require.Len(t, msgs, 3)
+
+	params := msgs[0]["params"].(map[string]interface{})
+	require.Equal(t, "file:///q.dql", params["uri"])
+	diags := params["diagnostics"].([]interface{})
+	require.Len(t, diags, 1)
+	d := diags[0].(map[string]interface{})
+	require.Equal(t, "Unknown directive [filtr] (did you mean @filter?)", d["message"])
+	require.Equal(t, map[string]interface{}{
+		"start": map[string]interface{}{"line": 1.0, "character": 22.0},
+		"end":   map[string]interface{}{"line": 1.0, "character": 27.0},
+	}, d["range"])
+
+	params = msgs[1]["params"].(map[string]interface{})
+	diags = params["diagnostics"].([]interface{})
+	require.Len(t, diags, 1)
+	d = diags[0].(map[string]interface{})
+	require.Contains(t, d["message"], "Strin")
+	start := d["range"].(map[string]interface{})["start"]
+	require.Equal(t, 1.0, start.(map[string]interface{})["line"])
+
+	params = msgs[2]["params"].(map[string]interface{})
+	require.Empty(t, params["diagnostics"])
+}
+
+func TestCompletion(t *testing.T) {
+	msgs := session(t,
+		open("file:///q.dql", "{\n  q(func: ha\n  q(func: uid(1)) @fil"),
+		at(1, "textDocument/completion", "file:///q.dql", 1, 12),
+		at(2, "textDocument/completion", "file:///q.dql", 2, 22),
+		open("file:///s.graphql", "type Person {\n  name: Str\n}\nenum Kind { A }"),
+		at(3, "textDocument/completion", "file:///s.graphql", 1, 11),
+	)
+	require.Len(t, msgs, 5)
+
+	got := labels(msgs[1]["result"])
+	require.Contains(t, got, "has")
+	require.Contains(t, got, "name")
+	require.Contains(t, got, "Person")
+	require.NotContains(t, got, "filter")
+
+	got = labels(msgs[2]["result"])
+	require.Contains(t, got, "filter")
+	require.NotContains(t, got, "has")
+
+	got = labels(msgs[4]["result"])
+	require.Contains(t, got, "String")
+	require.Contains(t, got, "Kind")
+}
+
+func TestHover(t *testing.T) {
+	msgs := session(t,
+		open("file:///q.dql", "{ q(func: has(name)) @cascade { friend Person } }"),
+		at(1, "textDocument/hover", "file:///q.dql", 0, 11),
+		at(2, "textDocument/hover", "file:///q.dql", 0, 16),
+		at(3, "textDocument/hover", "file:///q.dql", 0, 24),
+		at(4, "textDocument/hover", "file:///q.dql", 0, 34),
+		at(5, "textDocument/hover", "file:///q.dql", 0, 42),
+		at(6, "textDocument/hover", "file:///q.dql", 0, 0),
+	)
+	require.Len(t, msgs, 7)
+
+	value := func(msg map[string]interface{}) string {
+		contents := msg["result"].(map[string]interface{})["contents"]
+		return contents.(map[string]interface{})["value"].(string)
+	}
+	require.Contains(t, value(msgs[1]), "has(predicate)")
+	require.Equal(t, "```\nname: string @index(exact, term) .\n```", value(msgs[2]))
+	require.Contains(t, value(msgs[3]), "@cascade")
+	require.Equal(t, "```\nfriend: [uid] @reverse @count .\n```", value(msgs[4]))
+	require.Equal(t, "```\ntype Person {\n  name\n  friend\n}\n```", value(msgs[5]))
+	require.Nil(t, msgs[6]["result"])
+}
+
+func TestProtocol(t *testing.T) {
+	msgs := session(t,
+		map[string]interface{}{"id": 1, "method": "initialize", "params": map[string]interface{}{}},
+		map[string]interface{}{"method": "initialized", "params": map[string]interface{}{}},
+		map[string]interface{}{"id": 2, "method": "textDocument/rename"},
+		map[string]interface{}{"id": 3, "method": "shutdown"},
+		map[string]interface{}{"method": "exit"},
+	)
+	require.Len(t, msgs, 3)
+	caps := msgs[0]["result"].(map[string]interface{})["capabilities"].(map[string]interface{})
+	require.Equal(t, true, caps["hoverProvider"])
+	require.Equal(t, float64(codeMethodNotFound),
+		msgs[1]["error"].(map[string]interface{})["code"])
+	require.Equal(t, 3.0, msgs[2]["id"])
+	require.Contains(t, msgs[2], "result")
+	require.Nil(t, msgs[2]["result"])
+}
@@ -0,0 +1,221 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/edgraph"
+	"github.com/pkg/errors"
+)
+
+// outcome is the outcome of the replay of a query, written to the --out file.
+type outcome struct {
+	Line     int     `json:"line"`
+	ReadTs   uint64  `json:"read_ts"`
+	Skipped  string  `json:"skipped,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Recorded uint64  `json:"recorded_ns"`
+	Replayed uint64  `json:"replayed_ns,omitempty"`
+	Ratio    float64 `json:"ratio,omitempty"`
+	// SameResult is nil if the results weren't compared.
+	SameResult *bool `json:"same_result,omitempty"`
+}
+
+type report struct {
+	total       int
+	skipped     int
+	failed      int
+	regressions int
+	improved    int
+	mismatches  int
+	recorded    []time.Duration
+	replayed    []time.Duration
+	// notable are the outcomes of the queries that failed, regressed or returned another result.
+	notable []*outcome
+}
+
+// replay runs the queries of the entries one at a time, so that they don't slow each other down,
+// and writes the outcome of each of them to out if it isn't nil.
+func replay(ctx context.Context, entries []entry, q querier, opts options,
+	out io.Writer) (*report, error) {
+	// Queries can't read at a timestamp the Alpha hasn't reached, as they would wait for it.
+	var maxTs uint64
+	if !opts.latest {
+		resp, err := q(ctx, &api.Request{Query: "{ q(func: uid(0x1)) { uid } }", ReadOnly: true})
+		if err != nil {
+			return nil, errors.Wrapf(err, "while getting the latest timestamp")
+		}
+		maxTs = resp.GetTxn().GetStartTs()
+	}
+
+	var enc *json.Encoder
+	if out != nil {
+		enc = json.NewEncoder(out)
+	}
+	rep := &report{}
+	for _, e := range entries {
+		rep.total++
+		o := replayEntry(ctx, e, q, opts, maxTs)
+		switch {
+		case o.Skipped != "":
+			rep.skipped++
+		case o.Error != "":
+			rep.failed++
+			rep.notable = append(rep.notable, o)
+		default:
+			rep.recorded = append(rep.recorded, time.Duration(o.Recorded))
+			rep.replayed = append(rep.replayed, time.Duration(o.Replayed))
+			regressed := o.Ratio >= opts.regression
+			if regressed {
+				rep.regressions++
+			} else if o.Ratio <= 1/opts.regression {
+				rep.improved++
+			}
+			differs := o.SameResult != nil && !*o.SameResult
+			if differs {
+				rep.mismatches++
+			}
+			if regressed || differs {
+				rep.notable = append(rep.notable, o)
+			}
+		}
+		if enc != nil {
+			if err := enc.Encode(o); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rep, nil
+}
+
+func replayEntry(ctx context.Context, e entry, q querier, opts options, maxTs uint64) *outcome {
+	o := &outcome{Line: e.line, ReadTs: e.ReadTs, Recorded: e.Latency}
+	switch {
+	case e.Namespace != opts.namespace:
+		o.Skipped = fmt.Sprintf("the query was run in namespace %d", e.Namespace)
+		return o
+	case e.VarsHash != "" && len(e.Vars) == 0:
+		o.Skipped = "the variables of the query were redacted"
+		return o
+	case !opts.latest && e.ReadTs > maxTs:
+		o.Skipped = fmt.Sprintf("the Alpha is at timestamp %d, before the read timestamp", maxTs)
+		return o
+	case !opts.latest && e.ReadTs < opts.snapshotTs:
+		o.Skipped = fmt.Sprintf("the read timestamp is before the snapshot at timestamp %d, "+
+			"so the data read may be gone", opts.snapshotTs)
+		return o
+	}
+
+	req := &api.Request{Query: e.Query, Vars: e.Vars, ReadOnly: true, BestEffort: e.BestEffort}
+	if !opts.latest {
+		req.StartTs = e.ReadTs
+	}
+	var latencies []uint64
+	var result []byte
+	for i := 0; i < opts.runs; i++ {
+		qctx, cancel := context.WithTimeout(ctx, opts.timeout)
+		resp, err := q(qctx, req)
+		cancel()
+		if err != nil {
+			o.Error = err.Error()
+			return o
+		}
+		latencies = append(latencies, resp.GetLatency().GetTotalNs())
+		result = resp.Json
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	o.Replayed = latencies[len(latencies)/2]
+	if o.Recorded > 0 {
+		o.Ratio = float64(o.Replayed) / float64(o.Recorded)
+	}
+	if !opts.latest {
+		same := edgraph.HashResult(result) == e.ResultHash
+		o.SameResult = &same
+	}
+	return o
+}
+
+// percentile returns the p-th percentile of the durations, which must be sorted.
+func percentile(ds []time.Duration, p float64) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(ds))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(ds) {
+		i = len(ds) - 1
+	}
+	return ds[i]
+}
+
+func (r *report) print(w io.Writer, opts options) {
+	for _, o := range r.notable {
+		switch {
+		case o.Error != "":
+			fmt.Fprintf(w, "Line %d: failed: %s\n", o.Line, o.Error)
+			continue
+		case o.SameResult != nil && !*o.SameResult:
+			fmt.Fprintf(w, "Line %d: the result differs from the recorded one.\n", o.Line)
+		}
+		if o.Ratio >= opts.regression {
+			fmt.Fprintf(w, "Line %d: %v instead of %v, %.2fx slower.\n", o.Line,
+				time.Duration(o.Replayed).Round(time.Microsecond),
+				time.Duration(o.Recorded).Round(time.Microsecond), o.Ratio)
+		}
+	}
+	fmt.Fprintf(w, "Queries: %d replayed, %d skipped, %d failed, out of %d.\n",
+		len(r.replayed), r.skipped, r.failed, r.total)
+	if len(r.replayed) == 0 {
+		return
+	}
+
+	row := func(name string, ds []time.Duration) {
+		sorted := append([]time.Duration{}, ds...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		var total time.Duration
+		for _, d := range sorted {
+			total += d
+		}
+		fmt.Fprintf(w, "%-9s %10v %10v %10v %10v %12v\n", name,
+			percentile(sorted, 0.5).Round(time.Microsecond),
+			percentile(sorted, 0.95).Round(time.Microsecond),
+			percentile(sorted, 0.99).Round(time.Microsecond),
+			sorted[len(sorted)-1].Round(time.Microsecond), total.Round(time.Microsecond))
+	}
+	fmt.Fprintf(w, "%-9s %10s %10s %10s %10s %12s\n", "Latency", "p50", "p95", "p99", "max",
+		"total")
+	row("recorded", r.recorded)
+	row("replayed", r.replayed)
+
+	fmt.Fprintf(w, "Regressions: %d queries at least %.2fx slower.\n", r.regressions,
+		opts.regression)
+	fmt.Fprintf(w, "Improvements: %d queries at least %.2fx faster.\n", r.improved,
+		opts.regression)
+	if !opts.latest {
+		fmt.Fprintf(w, "Results: %d differ from the recorded ones.\n", r.mismatches)
+	}
+}
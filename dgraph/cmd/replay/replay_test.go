@@ -0,0 +1,139 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/edgraph"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	same := edgraph.HashResult([]byte(`{"q":[{"uid":"0x1"}]}`))
+	log := strings.Join([]string{
+		`{"query":"{ a(func: uid(1)) { uid } }","read_ts":10,"latency_ns":1000000,` +
+			`"result_hash":"` + same + `"}`,
+		`{"query":"{ b(func: uid(1)) { uid } }","read_ts":10,"latency_ns":1000000,` +
+			`"result_hash":"` + same + `"}`,
+		``,
+		`{"query":"{ c(func: uid(1)) { uid } }","read_ts":10,"latency_ns":1000000,` +
+			`"result_hash":"other"}`,
+		`{"query":"{ d(func: uid(1)) { uid } }","read_ts":10,"namespace":2}`,
+		`{"query":"{ e(func: uid(1)) { uid } }","read_ts":200}`,
+		`{"query":"{ f(func: uid(1)) { uid } }","read_ts":10}`,
+		`{"query":"query q($a: int) { g(func: uid($a)) { uid } }","read_ts":10,` +
+			`"vars_hash":"hash"}`,
+	}, "\n")
+	entries, err := readLog(strings.NewReader(log))
+	require.NoError(t, err)
+	require.Len(t, entries, 7)
+	require.Equal(t, 4, entries[2].line)
+
+	latencies := map[string]time.Duration{"a": time.Millisecond, "b": 3 * time.Millisecond,
+		"c": time.Millisecond / 4}
+	var readTs []uint64
+	q := func(ctx context.Context, req *api.Request) (*api.Response, error) {
+		readTs = append(readTs, req.StartTs)
+		if req.StartTs == 0 {
+			return &api.Response{Txn: &api.TxnContext{StartTs: 100}}, nil
+		}
+		name := req.Query[2:3]
+		if name == "f" {
+			return nil, errors.New("f failed")
+		}
+		return &api.Response{
+			Json:    []byte(`{"q":[{"uid":"0x1"}]}`),
+			Latency: &api.Latency{TotalNs: uint64(latencies[name])},
+		}, nil
+	}
+
+	var out bytes.Buffer
+	opts := options{runs: 1, regression: 1.5, timeout: time.Second}
+	rep, err := replay(context.Background(), entries, q, opts, &out)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0, 10, 10, 10, 10}, readTs)
+	require.Equal(t, 7, rep.total)
+	require.Equal(t, 3, rep.skipped)
+	require.Equal(t, 1, rep.failed)
+	require.Equal(t, 1, rep.regressions)
+	require.Equal(t, 1, rep.improved)
+	require.Equal(t, 1, rep.mismatches)
+
+	var outcomes []outcome
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var o outcome
+		require.NoError(t, dec.Decode(&o))
+		outcomes = append(outcomes, o)
+	}
+	require.Len(t, outcomes, 7)
+	require.Equal(t, 3.0, outcomes[1].Ratio)
+	require.True(t, *outcomes[0].SameResult)
+	require.False(t, *outcomes[2].SameResult)
+	require.Contains(t, outcomes[3].Skipped, "namespace 2")
+	require.Contains(t, outcomes[4].Skipped, "timestamp 100")
+	require.Equal(t, "f failed", outcomes[5].Error)
+	require.Contains(t, outcomes[6].Skipped, "redacted")
+
+	var printed bytes.Buffer
+	rep.print(&printed, opts)
+	require.Contains(t, printed.String(), "Line 2: 3ms instead of 1ms, 3.00x slower.")
+	require.Contains(t, printed.String(), "Line 4: the result differs from the recorded one.")
+	require.Contains(t, printed.String(), "Line 7: failed: f failed")
+	require.Contains(t, printed.String(), "Queries: 3 replayed, 3 skipped, 1 failed, out of 7.")
+}
+
+func TestReplaySnapshotTs(t *testing.T) {
+	entries, err := readLog(strings.NewReader(`{"query":"{ a(func: uid(1)) { uid } }",` +
+		`"read_ts":5}` + "\n" + `{"query":"{ b(func: uid(1)) { uid } }","read_ts":8}`))
+	require.NoError(t, err)
+	var readTs []uint64
+	q := func(ctx context.Context, req *api.Request) (*api.Response, error) {
+		readTs = append(readTs, req.StartTs)
+		return &api.Response{Txn: &api.TxnContext{StartTs: 100}, Latency: &api.Latency{}}, nil
+	}
+
+	var out bytes.Buffer
+	opts := options{runs: 1, regression: 1.5, timeout: time.Second, snapshotTs: 8}
+	rep, err := replay(context.Background(), entries, q, opts, &out)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0, 8}, readTs)
+	require.Equal(t, 1, rep.skipped)
+	var o outcome
+	require.NoError(t, json.NewDecoder(&out).Decode(&o))
+	require.Equal(t, uint64(5), o.ReadTs)
+	require.Contains(t, o.Skipped, "before the snapshot at timestamp 8")
+}
+
+func TestPercentile(t *testing.T) {
+	var ds []time.Duration
+	for i := 1; i <= 100; i++ {
+		ds = append(ds, time.Duration(i))
+	}
+	require.Equal(t, time.Duration(50), percentile(ds, 0.5))
+	require.Equal(t, time.Duration(99), percentile(ds, 0.99))
+	require.Equal(t, time.Duration(1), percentile(ds[:1], 0.95))
+	require.Equal(t, time.Duration(0), percentile(nil, 0.5))
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package replay implements "dgraph replay", which runs the queries of a slow query log again at
+// their recorded read timestamps, to compare their latencies and results before and after a
+// change of configuration or of version.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/edgraph"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/metadata"
+)
+
+// Replay is the sub-command invoked when calling "dgraph replay".
+var Replay x.SubCommand
+
+func init() {
+	Replay.Cmd = &cobra.Command{
+		Use:   "replay",
+		Short: "Replay a slow query log and compare the latencies",
+		Long: `
+Replay runs the queries of a slow query log, written by an Alpha with --slow_query, against
+an Alpha serving the same data, like a copy of the p directory of the original cluster. Each
+query runs at its recorded read timestamp, so that it reads the same data and must return the
+same result. The latencies are compared to the recorded ones, so that the effect of a change
+of configuration or of version can be measured. The exit status is 1 if a result differs or if
+a query got slower than --regression times its recorded latency.
+
+The versions older than the latest snapshot of the p directory, or than the timestamp of a
+restore, may be gone, so the queries recorded at an earlier read timestamp would read other
+data. Pass that timestamp with --snapshot_ts to skip them.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := run(Replay.Conf.GetString("log")); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+		},
+		Annotations: map[string]string{"group": "tool"},
+	}
+	Replay.EnvPrefix = "DGRAPH_REPLAY"
+	Replay.Cmd.SetHelpTemplate(x.NonRootTemplate)
+
+	flag := Replay.Cmd.Flags()
+	flag.StringP("log", "l", "", "Slow query log to replay.")
+	flag.String("alpha", "localhost:9080", "Address of Dgraph Alpha.")
+	flag.Int("runs", 1, "How many times to run each query. The median latency is compared.")
+	flag.Float64("regression", 1.5,
+		"Ratio of the replayed latency to the recorded one above which a query has regressed.")
+	flag.Duration("timeout", time.Minute, "Timeout of each query.")
+	flag.Uint64("snapshot_ts", 0,
+		"Timestamp of the latest snapshot of the p directory served by the Alpha, or of the "+
+			"restore of its data. The queries recorded at an earlier read timestamp are skipped, "+
+			"as the data they read may be gone.")
+	flag.Bool("latest", false,
+		"Run the queries at the latest timestamp instead of the recorded one, for data restored "+
+			"from a backup. The results aren't compared then.")
+	flag.String("out", "",
+		"File to write the outcome of every query to, one JSON object per line.")
+	flag.String("creds", "",
+		`Various login credentials if login is required.
+	user defines the username to login.
+	password defines the password of the user.
+	namespace defines the namespace to log into. Queries of other namespaces are skipped.
+	Sample flag could look like --creds user=username;password=mypass;namespace=2`)
+	x.RegisterClientTLSFlags(flag)
+}
+
+type options struct {
+	runs       int
+	regression float64
+	timeout    time.Duration
+	latest     bool
+	snapshotTs uint64
+	namespace  uint64
+}
+
+// querier runs a request against the Alpha.
+type querier func(ctx context.Context, req *api.Request) (*api.Response, error)
+
+func run(logFile string) error {
+	if logFile == "" {
+		return errors.Errorf("The --log flag must be set")
+	}
+	creds := z.NewSuperFlag(Replay.Conf.GetString("creds")).MergeAndCheckDefault(x.DefaultCreds)
+	opts := options{
+		runs:       Replay.Conf.GetInt("runs"),
+		regression: Replay.Conf.GetFloat64("regression"),
+		timeout:    Replay.Conf.GetDuration("timeout"),
+		latest:     Replay.Conf.GetBool("latest"),
+		snapshotTs: Replay.Conf.GetUint64("snapshot_ts"),
+		namespace:  creds.GetUint64("namespace"),
+	}
+	if opts.runs < 1 || opts.regression <= 1 {
+		return errors.Errorf("--runs must be positive and --regression greater than 1")
+	}
+
+	f, err := os.Open(logFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	entries, err := readLog(f)
+	if err != nil {
+		return err
+	}
+
+	tlsCfg, err := x.LoadClientTLSConfig(Replay.Conf)
+	if err != nil {
+		return err
+	}
+	conn, err := x.SetupConnection(Replay.Conf.GetString("alpha"), tlsCfg, false)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	q, err := newQuerier(api.NewDgraphClient(conn), creds)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer
+	if file := Replay.Conf.GetString("out"); file != "" {
+		of, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer of.Close()
+		out = of
+	}
+
+	rep, err := replay(context.Background(), entries, q, opts, out)
+	if err != nil {
+		return err
+	}
+	rep.print(os.Stdout, opts)
+	if rep.regressions > 0 || rep.mismatches > 0 {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// entry is a query of the slow query log, along with its line in the log.
+type entry struct {
+	edgraph.SlowQuery
+	line int
+}
+
+func readLog(r io.Reader) ([]entry, error) {
+	var entries []entry
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64<<10), 64<<20)
+	for line := 1; s.Scan(); line++ {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+		e := entry{line: line}
+		if err := json.Unmarshal(s.Bytes(), &e.SlowQuery); err != nil {
+			return nil, errors.Wrapf(err, "while reading line %d of the log", line)
+		}
+		entries = append(entries, e)
+	}
+	return entries, s.Err()
+}
+
+// newQuerier returns a querier that logs in with the credentials, if any, and logs in again when
+// the access JWT expires. The client is used directly rather than through dgo, because dgo
+// doesn't let a transaction choose its read timestamp.
+func newQuerier(dc api.DgraphClient, creds *z.SuperFlag) (querier, error) {
+	var jwt string
+	login := func(ctx context.Context) error {
+		user := creds.GetString("user")
+		if user == "" {
+			return nil
+		}
+		resp, err := dc.Login(ctx, &api.LoginRequest{
+			Userid:    user,
+			Password:  creds.GetString("password"),
+			Namespace: creds.GetUint64("namespace"),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "while logging in")
+		}
+		var token api.Jwt
+		if err := token.Unmarshal(resp.Json); err != nil {
+			return err
+		}
+		jwt = token.AccessJwt
+		return nil
+	}
+	if err := login(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, req *api.Request) (*api.Response, error) {
+		do := func() (*api.Response, error) {
+			qctx := ctx
+			if jwt != "" {
+				qctx = metadata.AppendToOutgoingContext(ctx, "accessJwt", jwt)
+			}
+			return dc.Query(qctx, req)
+		}
+		resp, err := do()
+		if err != nil && strings.Contains(err.Error(), "Token is expired") {
+			if err := login(ctx); err != nil {
+				return nil, err
+			}
+			resp, err = do()
+		}
+		return resp, err
+	}, nil
+}
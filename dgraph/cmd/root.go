@@ -24,14 +24,18 @@ import (
 	"github.com/dgraph-io/dgraph/dgraph/cmd/alpha"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/bulk"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/cert"
+	"github.com/dgraph-io/dgraph/dgraph/cmd/checker"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/conv"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/debug"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/debuginfo"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/decrypt"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/increment"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/live"
+	"github.com/dgraph-io/dgraph/dgraph/cmd/lsp"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/migrate"
 	raftmigrate "github.com/dgraph-io/dgraph/dgraph/cmd/raft-migrate"
+	"github.com/dgraph-io/dgraph/dgraph/cmd/replay"
+	"github.com/dgraph-io/dgraph/dgraph/cmd/standalone"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/version"
 	"github.com/dgraph-io/dgraph/dgraph/cmd/zero"
 	"github.com/dgraph-io/dgraph/upgrade"
@@ -79,6 +83,7 @@ var subcommands = []*x.SubCommand{
 	&bulk.Bulk, &cert.Cert, &conv.Conv, &live.Live, &alpha.Alpha, &zero.Zero, &version.Version,
 	&debug.Debug, &migrate.Migrate, &debuginfo.DebugInfo, &upgrade.Upgrade,
 	&raftmigrate.RaftMigrate, &decrypt.Decrypt, &increment.Increment,
+	&standalone.Standalone, &checker.Checker, &lsp.LSP, &replay.Replay,
 }
 
 func initCmds() {
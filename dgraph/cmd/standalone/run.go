@@ -0,0 +1,73 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package standalone
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dgraph-io/dgraph/embedded"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+)
+
+// Standalone is the sub-command invoked when running "dgraph standalone".
+var Standalone x.SubCommand
+
+func init() {
+	Standalone.Cmd = &cobra.Command{
+		Use:   "standalone",
+		Short: "Run Dgraph Zero and Alpha in a single process",
+		Long: `
+Standalone runs a Dgraph Zero and a Dgraph Alpha in this process, which is
+handy for development and tests. Alpha serves its HTTP and gRPC endpoints as
+usual. Zero only serves gRPC.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer x.StartProfile(Standalone.Conf).Stop()
+			run()
+		},
+		Annotations: map[string]string{"group": "core"},
+	}
+	Standalone.EnvPrefix = "DGRAPH_STANDALONE"
+	Standalone.Cmd.SetHelpTemplate(x.NonRootTemplate)
+
+	flag := Standalone.Cmd.Flags()
+	flag.StringP("dir", "d", "",
+		"Directory to store the data in. The data is kept in memory if it's empty (Linux only).")
+	flag.IntP("port_offset", "o", 0,
+		"Value added to all listening port numbers. [Zero Grpc=5080, Internal=7080, "+
+			"HTTP=8080, Grpc=9080]")
+}
+
+func run() {
+	d, err := embedded.Open(embedded.Options{
+		Dir:        Standalone.Conf.GetString("dir"),
+		PortOffset: Standalone.Conf.GetInt("port_offset"),
+	})
+	x.Check(err)
+
+	sdCh := make(chan os.Signal, 1)
+	signal.Notify(sdCh, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	<-sdCh
+	signal.Stop(sdCh)
+	glog.Infoln("Shutting down...")
+	x.Check(d.Close())
+	glog.Infoln("Server shutdown. Bye!")
+}
@@ -0,0 +1,374 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dgraph-io/dgraph/ee/audit"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// authorizeAdmin checks that the admin request in ctx may change the cluster. Each of the
+// checks is only done if the flag it depends on is set: the source IP must be whitelisted with
+// --whitelist, the auth token must match --auth_token, and the access JWT, signed with the
+// secret in --acl_secret_file, must belong to a guardian of the galaxy namespace. If none of
+// them is set, and the request has no client certificate verified by the CA of the cluster, only
+// the requests from the loopback address are allowed.
+func authorizeAdmin(ctx context.Context, tag string) error {
+	if p, ok := peer.FromContext(ctx); ok {
+		glog.Infof("Got %s request from: %q", tag, p.Addr)
+	}
+	open := len(opts.authToken) == 0 && len(opts.hmacSecret) == 0 && !hasVerifiedCert(ctx)
+	if len(opts.whitelist) > 0 || open {
+		if _, err := x.HasWhitelistedIPIn(ctx, opts.whitelist); err != nil {
+			return status.Error(codes.PermissionDenied, err.Error())
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(opts.authToken) > 0 {
+		tokens := md.Get("auth-token")
+		if len(tokens) == 0 {
+			return status.Error(codes.Unauthenticated,
+				"No auth token found. Token needed for admin operations.")
+		}
+		if subtle.ConstantTimeCompare([]byte(tokens[0]), opts.authToken) != 1 {
+			return status.Error(codes.Unauthenticated, "Invalid auth token")
+		}
+	}
+	if len(opts.hmacSecret) > 0 {
+		jwts := md.Get("accessJwt")
+		if len(jwts) == 0 {
+			return status.Error(codes.Unauthenticated,
+				"No access JWT found. Access JWT needed for admin operations.")
+		}
+		return authorizeGuardian(jwts[0])
+	}
+	return nil
+}
+
+// authorizeGuardian checks that the access JWT is valid and belongs to a guardian of the galaxy
+// namespace.
+func authorizeGuardian(accessJwt string) error {
+	claims, err := x.ParseJWTWithSecret(accessJwt, opts.hmacSecret)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return status.Error(codes.Unauthenticated, "Token is expired")
+	}
+	if ns, ok := claims["namespace"].(float64); !ok || uint64(ns) != x.GalaxyNamespace {
+		return status.Error(codes.PermissionDenied,
+			"Only guardians of the galaxy namespace are allowed admin operations on Zero")
+	}
+	groups, _ := claims["groups"].([]interface{})
+	for _, group := range groups {
+		if group == x.GuardiansId {
+			return nil
+		}
+	}
+	return status.Error(codes.PermissionDenied,
+		"Only guardians of the galaxy namespace are allowed admin operations on Zero")
+}
+
+// peerMethods are the methods of the Zero service which change or stream the state of the
+// cluster. Only the Alphas and Zeros of the cluster may call them, see authorizePeer.
+var peerMethods = map[string]bool{
+	"/pb.Zero/Connect":               true,
+	"/pb.Zero/UpdateMembership":      true,
+	"/pb.Zero/StreamMembership":      true,
+	"/pb.Zero/Oracle":                true,
+	"/pb.Zero/ShouldServe":           true,
+	"/pb.Zero/AssignIds":             true,
+	"/pb.Zero/AssignIdsBatch":        true,
+	"/pb.Zero/Timestamps":            true,
+	"/pb.Zero/CommitOrAbort":         true,
+	"/pb.Zero/TryAbort":              true,
+	"/pb.Zero/AssignUidForXid":       true,
+	"/pb.Zero/BlockMoves":            true,
+	"/pb.Zero/UpdateTask":            true,
+	"/pb.Zero/ControlTask":           true,
+	"/pb.Zero/UpdateSearchConnector": true,
+	"/pb.Zero/UpdateErasures":        true,
+	"/pb.Zero/Erasures":              true,
+}
+
+// hasVerifiedCert returns whether the request in ctx was made with a client certificate
+// verified by the CA of the cluster.
+func hasVerifiedCert(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	return ok && len(info.State.VerifiedChains) > 0
+}
+
+// authorizePeer checks that the request in ctx comes from a node of the cluster. If mtls is
+// set, the internal port requires a client certificate signed by the CA of the cluster, and
+// only the requests with a verified certificate are allowed. Otherwise, if --whitelist is set,
+// the source IP must be whitelisted, or be the loopback address. The requests of the nodes are
+// open if neither is set, see serveGRPC.
+func authorizePeer(ctx context.Context, mtls bool) error {
+	if mtls {
+		if !hasVerifiedCert(ctx) {
+			return status.Error(codes.Unauthenticated,
+				"Only the nodes of the cluster, with a verified client certificate, are allowed")
+		}
+		return nil
+	}
+	if len(opts.whitelist) == 0 {
+		return nil
+	}
+	if _, err := x.HasWhitelistedIPIn(ctx, opts.whitelist); err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
+
+// peerInterceptor returns the unary interceptor of the gRPC server of Zero. It authorizes the
+// calls to peerMethods before auditing them.
+func peerInterceptor(mtls bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if peerMethods[info.FullMethod] {
+			if err := authorizePeer(ctx, mtls); err != nil {
+				glog.Warningf("Rejected %s request: %v", info.FullMethod, err)
+				return nil, err
+			}
+		}
+		return audit.AuditRequestGRPC(ctx, req, info, handler)
+	}
+}
+
+// peerStreamInterceptor returns the stream interceptor of the gRPC server of Zero. It authorizes
+// the streams of peerMethods, like Oracle and StreamMembership.
+func peerStreamInterceptor(mtls bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		if peerMethods[info.FullMethod] {
+			if err := authorizePeer(ss.Context(), mtls); err != nil {
+				glog.Warningf("Rejected %s stream: %v", info.FullMethod, err)
+				return err
+			}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// adminContext returns a context holding the source IP and the credentials of the request, to
+// be authorized by authorizeAdmin.
+func adminContext(r *http.Request) context.Context {
+	ctx := x.AttachRemoteIP(context.Background(), r)
+	ctx = x.AttachAuthToken(ctx, r)
+	return x.AttachAccessJwt(ctx, r)
+}
+
+// adminAuthHandler authorizes the requests to an admin HTTP endpoint which isn't served through
+// the ZeroAdmin service.
+func adminAuthHandler(tag string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			if err := authorizeAdmin(adminContext(r), tag); err != nil {
+				x.AddCorsHeaders(w)
+				writeAdminError(w, err)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// writeAdminError writes the error returned by a ZeroAdmin method to the HTTP response.
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		w.WriteHeader(http.StatusUnauthorized)
+		x.SetStatus(w, x.ErrorUnauthorized, status.Convert(err).Message())
+	case codes.PermissionDenied:
+		w.WriteHeader(http.StatusForbidden)
+		x.SetStatus(w, x.ErrorUnauthorized, status.Convert(err).Message())
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.NotFound, codes.AlreadyExists:
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidRequest, status.Convert(err).Message())
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		x.SetStatus(w, x.Error, status.Convert(err).Message())
+	}
+}
+
+// writeAdminResponse writes the message of the response of a ZeroAdmin method to the HTTP
+// response.
+func writeAdminResponse(w http.ResponseWriter, resp *pb.ZeroAdminResponse) {
+	if _, err := fmt.Fprint(w, resp.Message); err != nil {
+		glog.Warningf("Error while writing response: %+v", err)
+	}
+}
+
+// adminServer serves the ZeroAdmin service. The admin HTTP endpoints call it too, with the
+// credentials of the HTTP request in the context, see adminContext.
+type adminServer struct {
+	zero *Server
+}
+
+// Assign leases UIDs, timestamps or namespace ids, depending on the type of num.
+func (a *adminServer) Assign(ctx context.Context, num *pb.Num) (*pb.AssignedIds, error) {
+	if err := authorizeAdmin(ctx, "Assign"); err != nil {
+		return nil, err
+	}
+	num = &pb.Num{Val: num.Val, Type: num.Type}
+	switch num.Type {
+	case pb.Num_UID, pb.Num_NS_ID:
+		return a.zero.AssignIds(ctx, num)
+	case pb.Num_TXN_TS:
+		num.ReadOnly = num.Val == 0
+		return a.zero.Timestamps(ctx, num)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid lease type: %v", num.Type)
+	}
+}
+
+// RemoveNode removes a node from the cluster. Zero nodes are in group 0.
+func (a *adminServer) RemoveNode(ctx context.Context,
+	req *pb.RemoveNodeRequest) (*pb.ZeroAdminResponse, error) {
+	if err := authorizeAdmin(ctx, "RemoveNode"); err != nil {
+		return nil, err
+	}
+	if err := a.zero.removeNode(ctx, req.NodeId, req.GroupId); err != nil {
+		return nil, err
+	}
+	return &pb.ZeroAdminResponse{
+		Message: fmt.Sprintf("Removed node with group: %v, idx: %v", req.GroupId, req.NodeId),
+	}, nil
+}
+
+// MoveTablet moves a tablet of the galaxy namespace to another group. It must be called on the
+// leader.
+func (a *adminServer) MoveTablet(ctx context.Context,
+	req *pb.MoveTabletRequest) (*pb.ZeroAdminResponse, error) {
+	if err := authorizeAdmin(ctx, "MoveTablet"); err != nil {
+		return nil, err
+	}
+	s := a.zero
+	if !s.Node.AmLeader() {
+		return nil, status.Error(codes.FailedPrecondition,
+			"This Zero server is not the leader. Re-run command on leader.")
+	}
+	if len(req.Tablet) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "tablet is a mandatory parameter")
+	}
+	// TODO(Ahsan): The move tablet request should be namespace aware.
+	tablet := x.NamespaceAttr(x.GalaxyNamespace, req.Tablet)
+
+	dstGroup := req.GroupId
+	var isKnown bool
+	for _, grp := range s.KnownGroups() {
+		if grp == dstGroup {
+			isKnown = true
+			break
+		}
+	}
+	if !isKnown {
+		return nil, status.Errorf(codes.InvalidArgument, "Group: [%d] is not a known group.",
+			dstGroup)
+	}
+
+	tab := s.ServingTablet(tablet)
+	if tab == nil {
+		return nil, status.Errorf(codes.NotFound, "No tablet found for: %s", tablet)
+	}
+	srcGroup := tab.GroupId
+	if srcGroup == dstGroup {
+		return nil, status.Errorf(codes.AlreadyExists,
+			"Tablet: [%s] is already being served by group: [%d]", tablet, srcGroup)
+	}
+	if err := s.canServe(tablet, dstGroup); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	if err := s.movePredicate(tablet, srcGroup, dstGroup); err != nil {
+		glog.Errorf("While moving predicate %s from %d -> %d. Error: %v",
+			tablet, srcGroup, dstGroup, err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.ZeroAdminResponse{
+		Message: fmt.Sprintf("Predicate: [%s] moved from group [%d] to [%d]",
+			tablet, srcGroup, dstGroup),
+	}, nil
+}
+
+// RemoveGroup moves all the tablets of a group to the remaining groups, and then removes its
+// members, in a task.
+func (a *adminServer) RemoveGroup(ctx context.Context,
+	req *pb.RemoveGroupRequest) (*pb.ZeroAdminResponse, error) {
+	if err := authorizeAdmin(ctx, "RemoveGroup"); err != nil {
+		return nil, err
+	}
+	taskId, err := a.zero.removeGroup(ctx, req.GroupId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.ZeroAdminResponse{
+		Message: fmt.Sprintf("Removing group %d. Its progress can be followed in task %#x",
+			req.GroupId, taskId),
+		TaskId: taskId,
+	}, nil
+}
+
+// SetReplicas changes the number of replicas per group. The extra members are removed in a task.
+func (a *adminServer) SetReplicas(ctx context.Context,
+	req *pb.SetReplicasRequest) (*pb.ZeroAdminResponse, error) {
+	if err := authorizeAdmin(ctx, "SetReplicas"); err != nil {
+		return nil, err
+	}
+	taskId, err := a.zero.updateReplicas(ctx, int(req.Replicas))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	msg := fmt.Sprintf("Number of replicas set to %d", req.Replicas)
+	if taskId != 0 {
+		msg += fmt.Sprintf(". The extra members are removed by task %#x", taskId)
+	}
+	return &pb.ZeroAdminResponse{Message: msg, TaskId: taskId}, nil
+}
+
+// UpdateReadOnly enables or disables read-only mode for the whole cluster.
+func (a *adminServer) UpdateReadOnly(ctx context.Context,
+	req *pb.ReadOnlyMode) (*pb.ZeroAdminResponse, error) {
+	if err := authorizeAdmin(ctx, "UpdateReadOnly"); err != nil {
+		return nil, err
+	}
+	if err := a.zero.updateReadOnly(ctx, req); err != nil {
+		return nil, err
+	}
+	return &pb.ZeroAdminResponse{
+		Message: fmt.Sprintf("Read-only mode set to %v", req.Enabled),
+	}, nil
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func adminTestContext(ip string, kvs ...string) context.Context {
+	ctx := peer.NewContext(context.Background(),
+		&peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234}})
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(kvs...))
+}
+
+func adminTestJwt(t *testing.T, secret []byte, ns uint64, groups []string, ttl time.Duration) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userid":    "groot",
+		"groups":    groups,
+		"namespace": ns,
+		"exp":       time.Now().Add(ttl).Unix(),
+	})
+	jwtStr, err := token.SignedString(secret)
+	require.NoError(t, err)
+	return jwtStr
+}
+
+func TestAuthorizeAdmin(t *testing.T) {
+	defer func(o options) { opts = o }(opts)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	whitelist, err := x.GetIPsFromString("10.0.0.0/8")
+	require.NoError(t, err)
+
+	// With the default flags, only the local requests are allowed.
+	opts = options{}
+	require.NoError(t, authorizeAdmin(adminTestContext("127.0.0.1"), "Test"))
+	require.NoError(t, authorizeAdmin(adminTestContext("::1"), "Test"))
+	err = authorizeAdmin(adminTestContext("192.168.1.1"), "Test")
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	err = authorizeAdmin(context.Background(), "Test")
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	// A client certificate verified by the CA of the cluster is enough.
+	require.NoError(t, authorizeAdmin(adminTestTLSContext("192.168.1.1"), "Test"))
+
+	opts = options{whitelist: whitelist}
+	require.NoError(t, authorizeAdmin(adminTestContext("127.0.0.1"), "Test"))
+	require.NoError(t, authorizeAdmin(adminTestContext("10.1.2.3"), "Test"))
+	err = authorizeAdmin(adminTestContext("192.168.1.1"), "Test")
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	opts = options{authToken: x.SensitiveByteSlice("token")}
+	require.NoError(t, authorizeAdmin(adminTestContext("10.1.2.3", "auth-token", "token"), "Test"))
+	err = authorizeAdmin(adminTestContext("10.1.2.3", "auth-token", "wrong"), "Test")
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	err = authorizeAdmin(adminTestContext("10.1.2.3"), "Test")
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	opts = options{hmacSecret: secret}
+	guardians := []string{x.GuardiansId}
+	for _, tc := range []struct {
+		jwt  string
+		code codes.Code
+	}{
+		{adminTestJwt(t, secret, x.GalaxyNamespace, guardians, time.Hour), codes.OK},
+		{adminTestJwt(t, secret, x.GalaxyNamespace, []string{"dev"}, time.Hour),
+			codes.PermissionDenied},
+		{adminTestJwt(t, secret, 1, guardians, time.Hour), codes.PermissionDenied},
+		{adminTestJwt(t, secret, x.GalaxyNamespace, guardians, -time.Hour), codes.Unauthenticated},
+		{adminTestJwt(t, []byte("some other secret of enough length"), x.GalaxyNamespace,
+			guardians, time.Hour), codes.Unauthenticated},
+	} {
+		err := authorizeAdmin(adminTestContext("10.1.2.3", "accessJwt", tc.jwt), "Test")
+		require.Equal(t, tc.code, status.Code(err), "%v", err)
+	}
+	err = authorizeAdmin(adminTestContext("10.1.2.3"), "Test")
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func adminTestTLSContext(ip string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234},
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{}}},
+		}},
+	})
+}
+
+// testServerStream is a grpc.ServerStream with the given context.
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestPeerInterceptorDefaultFlags(t *testing.T) {
+	defer func(o options) { opts = o }(opts)
+	opts = options{}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	call := func(ctx context.Context, method string) error {
+		_, err := peerInterceptor(false)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+			handler)
+		return err
+	}
+
+	for _, method := range []string{"/pb.Zero/Connect", "/pb.Zero/AssignIds",
+		"/pb.Zero/Timestamps", "/pb.Zero/CommitOrAbort"} {
+		// Without --whitelist or mutual TLS, the nodes of the cluster aren't authorized.
+		require.NoError(t, call(adminTestContext("127.0.0.1"), method), method)
+		require.NoError(t, call(adminTestContext("192.168.1.1"), method), method)
+	}
+}
+
+func TestPeerStreamInterceptor(t *testing.T) {
+	defer func(o options) { opts = o }(opts)
+	whitelist, err := x.GetIPsFromString("10.0.0.0/8")
+	require.NoError(t, err)
+
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		return nil
+	}
+	call := func(ctx context.Context, method string, mtls bool) error {
+		return peerStreamInterceptor(mtls)(nil, &testServerStream{ctx: ctx},
+			&grpc.StreamServerInfo{FullMethod: method, IsServerStream: true}, handler)
+	}
+
+	for _, method := range []string{"/pb.Zero/Oracle", "/pb.Zero/StreamMembership"} {
+		// With the default flags, any node may stream.
+		opts = options{}
+		require.NoError(t, call(adminTestContext("127.0.0.1"), method, false), method)
+		require.NoError(t, call(adminTestContext("10.1.2.3"), method, false), method)
+
+		opts = options{whitelist: whitelist}
+		require.NoError(t, call(adminTestContext("127.0.0.1"), method, false), method)
+		require.NoError(t, call(adminTestContext("10.1.2.3"), method, false), method)
+		err := call(adminTestContext("192.168.1.1"), method, false)
+		require.Equal(t, codes.PermissionDenied, status.Code(err), method)
+
+		err = call(adminTestContext("10.1.2.3"), method, true)
+		require.Equal(t, codes.Unauthenticated, status.Code(err), method)
+		require.NoError(t, call(adminTestTLSContext("192.168.1.1"), method, true), method)
+	}
+
+	// The other streams aren't authorized by the interceptor.
+	opts = options{}
+	require.NoError(t, call(adminTestContext("192.168.1.1"), "/pb.Raft/Heartbeat", false))
+}
+
+func TestPeerInterceptor(t *testing.T) {
+	defer func(o options) { opts = o }(opts)
+	whitelist, err := x.GetIPsFromString("10.0.0.0/8")
+	require.NoError(t, err)
+	opts = options{whitelist: whitelist}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	call := func(ctx context.Context, method string, mtls bool) error {
+		_, err := peerInterceptor(mtls)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+			handler)
+		return err
+	}
+
+	// The methods which aren't served to the nodes of the cluster only are open.
+	require.NoError(t, call(adminTestContext("192.168.1.1"), "/pb.ZeroAdmin/Assign", false))
+	require.NoError(t, call(adminTestContext("10.1.2.3"), "/pb.Zero/Timestamps", false))
+	err = call(adminTestContext("192.168.1.1"), "/pb.Zero/Timestamps", false)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	require.NoError(t, call(adminTestContext("10.1.2.3"), "/pb.Zero/AssignIds", false))
+	err = call(adminTestContext("192.168.1.1"), "/pb.Zero/AssignIds", false)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// With TLS on the internal port, only the peers with a verified certificate are allowed,
+	// whatever their IP.
+	err = call(adminTestContext("10.1.2.3"), "/pb.Zero/Connect", true)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.NoError(t, call(adminTestTLSContext("192.168.1.1"), "/pb.Zero/Connect", true))
+}
@@ -18,7 +18,10 @@ package zero
 
 import (
 	"context"
+	"time"
 
+	ostats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 	otrace "go.opencensus.io/trace"
 
 	"github.com/dgraph-io/dgraph/protos/pb"
@@ -68,9 +71,19 @@ var errServedFromMemory = errors.New("Lease was served from memory")
 // This function is triggered by an RPC call. We ensure that only leader can assign new UIDs,
 // so we can tackle any collisions that might happen with the leasemanager
 // In essence, we just want one server to be handing out new uids.
-func (s *Server) lease(ctx context.Context, num *pb.Num) (*pb.AssignedIds, error) {
+func (s *Server) lease(ctx context.Context, num *pb.Num) (out *pb.AssignedIds, err error) {
 	typ := num.GetType()
 	node := s.Node
+	start := time.Now()
+	defer func() {
+		status := x.TagValueStatusOK
+		if err != nil && err != errServedFromMemory {
+			status = x.TagValueStatusError
+		}
+		mctx, _ := tag.New(context.Background(), tag.Upsert(x.KeyLease, leaseName(typ)),
+			tag.Upsert(x.KeyStatus, status))
+		ostats.Record(mctx, x.ZeroLeaseLatencyMs.M(x.SinceMs(start)))
+	}()
 	// TODO: Fix when we move to linearizable reads, need to check if we are the leader, might be
 	// based on leader leases. If this node gets partitioned and unless checkquorum is enabled, this
 	// node would still think that it's the leader.
@@ -139,9 +152,11 @@ func (s *Server) lease(ctx context.Context, num *pb.Num) (*pb.AssignedIds, error
 		if err := s.Node.proposeAndWait(ctx, &proposal); err != nil {
 			return nil, err
 		}
+		mctx, _ := tag.New(context.Background(), tag.Upsert(x.KeyLease, leaseName(typ)))
+		ostats.Record(mctx, x.ZeroLeaseExtensions.M(1))
 	}
 
-	out := &pb.AssignedIds{}
+	out = &pb.AssignedIds{}
 	if typ == pb.Num_TXN_TS {
 		if num.Val > 0 {
 			out.StartId = s.nextLease[pb.Num_TXN_TS]
@@ -166,6 +181,13 @@ func (s *Server) lease(ctx context.Context, num *pb.Num) (*pb.AssignedIds, error
 	} else {
 		return out, errors.Errorf("Unknown lease type: %v\n", typ)
 	}
+
+	leased := int64(num.Val)
+	if out.ReadOnly > 0 {
+		leased++
+	}
+	mctx, _ := tag.New(context.Background(), tag.Upsert(x.KeyLease, leaseName(typ)))
+	ostats.Record(mctx, x.ZeroLeasedIds.M(leased))
 	return out, nil
 }
 
@@ -217,3 +239,121 @@ func (s *Server) AssignIds(ctx context.Context, num *pb.Num) (*pb.AssignedIds, e
 		return reply, err
 	}
 }
+
+// maxBatchRanges is the maximum number of ranges a single AssignIdsBatch request can ask for.
+const maxBatchRanges = 1024
+
+// validateBatch returns how many ids of each type the batch asks for in total.
+func validateBatch(batch *pb.NumBatch) (map[pb.NumLeaseType]uint64, error) {
+	if len(batch.GetNums()) == 0 {
+		return nil, errors.Errorf("Nothing to be leased")
+	}
+	if len(batch.Nums) > maxBatchRanges {
+		return nil, errors.Errorf("Batch asks for %d ranges, more than the maximum of %d",
+			len(batch.Nums), maxBatchRanges)
+	}
+	totals := make(map[pb.NumLeaseType]uint64)
+	for _, num := range batch.Nums {
+		switch typ := num.GetType(); typ {
+		case pb.Num_UID, pb.Num_NS_ID:
+			if num.Val == 0 {
+				return nil, errors.Errorf("Nothing to be leased for range of type %v", typ)
+			}
+			if totals[typ]+num.Val < totals[typ] {
+				return nil, errors.Errorf("Batch asks for too many ids of type %v", typ)
+			}
+			totals[typ] += num.Val
+		default:
+			// Timestamps must go through the oracle one request at a time.
+			return nil, errors.Errorf("Lease type %v can't be batched", typ)
+		}
+	}
+	return totals, nil
+}
+
+// splitBatch carves the contiguous range leased for typ into the ranges asked for by the batch,
+// in the order they were asked for.
+func splitBatch(batch *pb.NumBatch, typ pb.NumLeaseType, leased *pb.AssignedIds,
+	out []*pb.AssignedIds) {
+	next := leased.StartId
+	for i, num := range batch.Nums {
+		if num.GetType() != typ {
+			continue
+		}
+		out[i] = &pb.AssignedIds{StartId: next, EndId: next + num.Val - 1}
+		next += num.Val
+	}
+	x.AssertTrue(next == leased.EndId+1)
+}
+
+// leaseBatch leases all the ranges of a batch. The ranges of each type are carved out of a single
+// lease, so a batch needs at most one Raft proposal per type, however many ranges it asks for.
+func (s *Server) leaseBatch(ctx context.Context, batch *pb.NumBatch) (*pb.AssignedIdsBatch, error) {
+	totals, err := validateBatch(batch)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]*pb.AssignedIds, len(batch.Nums))
+	for _, typ := range []pb.NumLeaseType{pb.Num_UID, pb.Num_NS_ID} {
+		total, ok := totals[typ]
+		if !ok {
+			continue
+		}
+		leased, err := s.lease(ctx, &pb.Num{Val: total, Type: typ})
+		if err != nil {
+			return nil, err
+		}
+		splitBatch(batch, typ, leased, ids)
+	}
+	return &pb.AssignedIdsBatch{Ids: ids}, nil
+}
+
+// AssignIdsBatch is used to assign many ranges of ids (UIDs, NsIDs) in a single round trip. Bulk
+// loaders use it to keep a pipeline of ranges at hand instead of waiting on Zero for every range.
+func (s *Server) AssignIdsBatch(ctx context.Context,
+	batch *pb.NumBatch) (*pb.AssignedIdsBatch, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	ctx, span := otrace.StartSpan(ctx, "Zero.AssignIdsBatch")
+	defer span.End()
+
+	var reply *pb.AssignedIdsBatch
+	lease := func() error {
+		var err error
+		if s.Node.AmLeader() {
+			span.Annotatef(nil, "Zero leader leasing %d ranges", len(batch.GetNums()))
+			reply, err = s.leaseBatch(ctx, batch)
+			return err
+		}
+		span.Annotate(nil, "Not Zero leader")
+		if batch.Forwarded {
+			return errors.Errorf(
+				"Invalid Zero received AssignIdsBatch request forward. Please retry")
+		}
+		pl := s.Leader(0)
+		if pl == nil {
+			return errors.Errorf("No healthy connection found to Leader of group zero")
+		}
+		span.Annotatef(nil, "Sending request to %v", pl.Addr)
+		zc := pb.NewZeroClient(pl.Get())
+		batch.Forwarded = true
+		reply, err = zc.AssignIdsBatch(ctx, batch)
+		return err
+	}
+
+	c := make(chan error, 1)
+	go func() {
+		c <- lease()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case err := <-c:
+		if err != nil {
+			span.Annotatef(nil, "Error while leasing batch: %v", err)
+		}
+		return reply, err
+	}
+}
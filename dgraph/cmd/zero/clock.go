@@ -0,0 +1,61 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/golang/glog"
+)
+
+// Transaction timestamps are handed out by the Zero leader from a logical counter, so clock skew
+// can't reorder commits. It does affect everything based on wall clocks though, like the
+// last update of members, license expiry and TTLs in logs. So, the Alphas measure their skew
+// relative to the Zero leader on every membership update and report it back.
+
+// recordClockSkew records the clock skew reported by the members of the group, and warns if
+// it is above the threshold.
+func (s *Server) recordClockSkew(group *pb.Group) {
+	s.Lock()
+	defer s.Unlock()
+	for id, m := range group.GetMembers() {
+		s.clockSkew[id] = m.ClockSkewMs
+		skew := time.Duration(m.ClockSkewMs) * time.Millisecond
+		if skew < 0 {
+			skew = -skew
+		}
+		if opts.maxClockSkew > 0 && skew > opts.maxClockSkew {
+			glog.Warningf("Clock of member %#x at %s is off by %s from the Zero leader, which "+
+				"is more than the allowed %s. Please check NTP on the nodes.",
+				id, m.Addr, time.Duration(m.ClockSkewMs)*time.Millisecond, opts.maxClockSkew)
+		}
+	}
+}
+
+// fillClockSkew sets the last clock skew reported by the members in the given state.
+func (s *Server) fillClockSkew(state *pb.MembershipState) {
+	s.RLock()
+	defer s.RUnlock()
+	for _, g := range state.GetGroups() {
+		for id, m := range g.GetMembers() {
+			if skew, ok := s.clockSkew[id]; ok {
+				m.ClockSkewMs = skew
+			}
+		}
+	}
+}
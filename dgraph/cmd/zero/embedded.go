@@ -0,0 +1,53 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// embedding holds the state of a Zero running in the process of an application.
+type embedding struct {
+	started chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// RunEmbedded runs Zero in this process with the configuration, which must hold all the flags
+// of the zero command. It returns once Zero is running. Zero doesn't serve its HTTP endpoints
+// and doesn't handle the signals, it runs until stop is called. stop returns once Zero is done.
+func RunEmbedded(conf *viper.Viper) (stop func()) {
+	Zero.Conf = conf
+	emb := &embedding{
+		started: make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(emb.done)
+		run(emb)
+	}()
+	<-emb.started
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(emb.stop) })
+		<-emb.done
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// UpdateErasures records the marker of an erasure of a data subject, or a restore of the data,
+// after which the Alphas apply all the erasures again. Zero keeps the markers so that restoring a
+// backup taken before an erasure can't bring back the erased data.
+func (s *Server) UpdateErasures(ctx context.Context, u *pb.ErasureUpdate) (*api.Payload, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if m := u.GetMarker(); m != nil && (m.Report == "" || m.Signature == "") {
+		return nil, errors.Errorf("The erasure marker must have a report and a signature")
+	}
+	if !s.Node.AmLeader() {
+		if u.Forwarded {
+			return nil, errors.Errorf("Invalid Zero received UpdateErasures request forward. " +
+				"Please retry")
+		}
+		pl := s.Leader(0)
+		if pl == nil {
+			return nil, errors.Errorf("No healthy connection found to Leader of group zero")
+		}
+		u.Forwarded = true
+		return pb.NewZeroClient(pl.Get()).UpdateErasures(ctx, u)
+	}
+	u.Forwarded = false
+	if err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{Erasure: u}); err != nil {
+		return nil, err
+	}
+	return &api.Payload{}, nil
+}
+
+// Erasures returns the markers of all the erasures, along with the number of restores recorded
+// and the number after which the erasures were applied again.
+func (s *Server) Erasures(ctx context.Context, _ *api.Payload) (*pb.ErasureState, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return s.erasureSnapshot(), nil
+}
+
+// handleErasureProposal applies the update to the erasure state. A marker recorded twice is kept
+// once. The caller must hold the server lock.
+func (n *node) handleErasureProposal(u *pb.ErasureUpdate) {
+	es := n.server.erasures
+	if m := u.Marker; m != nil && !hasErasureMarker(es, m) {
+		glog.Infof("Recording the marker of an erasure")
+		es.Markers = append(es.Markers, m)
+	}
+	if u.Restored {
+		es.Restores++
+		glog.Infof("Recording restore %d, the erasures must be applied again", es.Restores)
+	}
+	if u.Reapplied > es.Reapplied && u.Reapplied <= es.Restores {
+		es.Reapplied = u.Reapplied
+	}
+}
+
+func hasErasureMarker(es *pb.ErasureState, m *pb.ErasureMarker) bool {
+	for _, have := range es.Markers {
+		if have.Report == m.Report {
+			return true
+		}
+	}
+	return false
+}
+
+// erasureSnapshot returns a copy of the erasure state, to be stored along with the Zero snapshot.
+func (s *Server) erasureSnapshot() *pb.ErasureState {
+	s.RLock()
+	defer s.RUnlock()
+	return proto.Clone(s.erasures).(*pb.ErasureState)
+}
+
+// setErasureSnapshot replaces the erasure state with the one found in a Zero snapshot.
+func (s *Server) setErasureSnapshot(es *pb.ErasureState) {
+	s.Lock()
+	defer s.Unlock()
+	if es == nil {
+		es = &pb.ErasureState{}
+	}
+	s.erasures = es
+}
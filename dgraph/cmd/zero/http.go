@@ -26,7 +26,6 @@ import (
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/gogo/protobuf/jsonpb"
-	"github.com/golang/glog"
 )
 
 // intFromQueryParam checks for name as a query param, converts it to uint64 and returns it.
@@ -64,33 +63,25 @@ func (st *state) assign(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	num := &pb.Num{Val: uint64(val)}
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	var ids *pb.AssignedIds
-	var err error
-	what := r.URL.Query().Get("what")
-	switch what {
+	num := &pb.Num{Val: val}
+	switch what := r.URL.Query().Get("what"); what {
 	case "uids":
 		num.Type = pb.Num_UID
-		ids, err = st.zero.AssignIds(ctx, num)
 	case "timestamps":
 		num.Type = pb.Num_TXN_TS
-		if num.Val == 0 {
-			num.ReadOnly = true
-		}
-		ids, err = st.zero.Timestamps(ctx, num)
 	case "nsids":
 		num.Type = pb.Num_NS_ID
-		ids, err = st.zero.AssignIds(ctx, num)
 	default:
 		x.SetStatus(w, x.Error,
 			fmt.Sprintf("Invalid what: [%s]. Must be one of uids or timestamps", what))
 		return
 	}
+
+	ctx, cancel := context.WithTimeout(adminContext(r), 30*time.Second)
+	defer cancel()
+	ids, err := st.admin.Assign(ctx, num)
 	if err != nil {
-		x.SetStatus(w, x.Error, err.Error())
+		writeAdminError(w, err)
 		return
 	}
 
@@ -123,19 +114,18 @@ func (st *state) removeNode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := st.zero.removeNode(context.Background(), nodeId, uint32(groupId)); err != nil {
-		x.SetStatus(w, x.Error, err.Error())
-		return
-	}
-	_, err := fmt.Fprintf(w, "Removed node with group: %v, idx: %v", groupId, nodeId)
+	resp, err := st.admin.RemoveNode(adminContext(r),
+		&pb.RemoveNodeRequest{NodeId: nodeId, GroupId: uint32(groupId)})
 	if err != nil {
-		glog.Warningf("Error while writing response: %+v", err)
+		writeAdminError(w, err)
+		return
 	}
+	writeAdminResponse(w, resp)
 }
 
-// moveTablet can be used to move a tablet to a specific group. It takes in tablet and group as
-// argument.
-func (st *state) moveTablet(w http.ResponseWriter, r *http.Request) {
+// removeGroup moves all the tablets of a group to the remaining groups, and then removes its
+// members. It takes in group as argument, and returns the id of the task doing the removal.
+func (st *state) removeGroup(w http.ResponseWriter, r *http.Request) {
 	x.AddCorsHeaders(w)
 	if r.Method == "OPTIONS" {
 		return
@@ -146,73 +136,46 @@ func (st *state) moveTablet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !st.node.AmLeader() {
-		w.WriteHeader(http.StatusBadRequest)
-		x.SetStatus(w, x.ErrorInvalidRequest,
-			"This Zero server is not the leader. Re-run command on leader.")
-		return
-	}
-
-	tablet := r.URL.Query().Get("tablet")
-
-	// TODO(Ahsan): The move tablet request should be namespace aware.
-	tablet = x.NamespaceAttr(x.GalaxyNamespace, tablet)
-	if len(tablet) == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		x.SetStatus(w, x.ErrorInvalidRequest, "tablet is a mandatory query parameter")
-		return
-	}
-
 	groupId, ok := intFromQueryParam(w, r, "group")
 	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		x.SetStatus(w, x.ErrorInvalidRequest, fmt.Sprintf(
-			"Query parameter 'group' should contain a valid integer."))
 		return
 	}
-	dstGroup := uint32(groupId)
-	knownGroups := st.zero.KnownGroups()
-	var isKnown bool
-	for _, grp := range knownGroups {
-		if grp == dstGroup {
-			isKnown = true
-			break
-		}
-	}
-	if !isKnown {
-		w.WriteHeader(http.StatusBadRequest)
-		x.SetStatus(w, x.ErrorInvalidRequest, fmt.Sprintf("Group: [%d] is not a known group.",
-			dstGroup))
+
+	resp, err := st.admin.RemoveGroup(adminContext(r),
+		&pb.RemoveGroupRequest{GroupId: uint32(groupId)})
+	if err != nil {
+		writeAdminError(w, err)
 		return
 	}
+	writeAdminResponse(w, resp)
+}
 
-	tab := st.zero.ServingTablet(tablet)
-	if tab == nil {
-		w.WriteHeader(http.StatusBadRequest)
-		x.SetStatus(w, x.ErrorInvalidRequest, fmt.Sprintf("No tablet found for: %s", tablet))
+// moveTablet can be used to move a tablet to a specific group. It takes in tablet and group as
+// argument.
+func (st *state) moveTablet(w http.ResponseWriter, r *http.Request) {
+	x.AddCorsHeaders(w)
+	if r.Method == "OPTIONS" {
 		return
 	}
-
-	srcGroup := tab.GroupId
-	if srcGroup == dstGroup {
-		w.WriteHeader(http.StatusInternalServerError)
-		x.SetStatus(w, x.ErrorInvalidRequest,
-			fmt.Sprintf("Tablet: [%s] is already being served by group: [%d]", tablet, srcGroup))
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
 		return
 	}
 
-	if err := st.zero.movePredicate(tablet, srcGroup, dstGroup); err != nil {
-		glog.Errorf("While moving predicate %s from %d -> %d. Error: %v",
-			tablet, srcGroup, dstGroup, err)
-		w.WriteHeader(http.StatusInternalServerError)
-		x.SetStatus(w, x.Error, err.Error())
+	groupId, ok := intFromQueryParam(w, r, "group")
+	if !ok {
 		return
 	}
-	_, err := fmt.Fprintf(w, "Predicate: [%s] moved from group [%d] to [%d]",
-		tablet, srcGroup, dstGroup)
+	resp, err := st.admin.MoveTablet(adminContext(r), &pb.MoveTabletRequest{
+		Tablet:  r.URL.Query().Get("tablet"),
+		GroupId: uint32(groupId),
+	})
 	if err != nil {
-		glog.Warningf("Error while writing response: %+v", err)
+		writeAdminError(w, err)
+		return
 	}
+	writeAdminResponse(w, resp)
 }
 
 func (st *state) getState(w http.ResponseWriter, r *http.Request) {
@@ -231,6 +194,7 @@ func (st *state) getState(w http.ResponseWriter, r *http.Request) {
 		x.SetStatus(w, x.ErrorNoData, "No membership state found.")
 		return
 	}
+	st.zero.fillClockSkew(mstate)
 
 	m := jsonpb.Marshaler{EmitDefaults: true}
 	if err := m.Marshal(w, mstate); err != nil {
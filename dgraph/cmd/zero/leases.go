@@ -0,0 +1,272 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// leaseAdjustTimeout is how long an advance of a lease waits for its confirmation.
+const leaseAdjustTimeout = time.Minute
+
+// leaseTypes maps the names used by the HTTP endpoints to the lease types.
+var leaseTypes = map[string]pb.NumLeaseType{
+	"uids":       pb.Num_UID,
+	"timestamps": pb.Num_TXN_TS,
+	"nsids":      pb.Num_NS_ID,
+}
+
+// leaseName returns the name used by the HTTP endpoints and the metrics for the lease type.
+func leaseName(typ pb.NumLeaseType) string {
+	for name, t := range leaseTypes {
+		if t == typ {
+			return name
+		}
+	}
+	return typ.String()
+}
+
+// LeaseState is the state of a lease as seen by Zero.
+type LeaseState struct {
+	// Leased is the max value leased out by Zero and recorded via Raft.
+	Leased uint64 `json:"leased"`
+	// Next is the next value which would be handed out. Only the leader knows about it.
+	Next uint64 `json:"next,omitempty"`
+	// Observed is the max value that the Alphas have reported to be in use.
+	Observed uint64 `json:"observed"`
+}
+
+type leaseAdjust struct {
+	typ     pb.NumLeaseType
+	to      uint64
+	token   string
+	expires time.Time
+}
+
+// observeGroup records the max timestamp seen by the leader of a group. It returns the proposal
+// recording the max UID and namespace ID used by the group, if they are above the ones already
+// recorded in the membership state, or nil.
+func (s *Server) observeGroup(group *pb.Group) *pb.ZeroProposal {
+	s.Lock()
+	defer s.Unlock()
+	if ts := x.Max(group.CheckpointTs, group.SnapshotTs); ts > s.observed[pb.Num_TXN_TS] {
+		s.observed[pb.Num_TXN_TS] = ts
+	}
+
+	var p pb.ZeroProposal
+	if group.MaxUid > s.state.GetObservedUid() {
+		p.ObservedUid = group.MaxUid
+	}
+	if group.MaxNsid > s.state.GetObservedNsId() {
+		p.ObservedNsId = group.MaxNsid
+	}
+	if p.ObservedUid == 0 && p.ObservedNsId == 0 {
+		return nil
+	}
+	return &p
+}
+
+// observedLease returns the max value of the lease of typ which is known to be in use. The UIDs
+// and namespace IDs are kept in the membership state, so that they survive restarts of Zero.
+func (s *Server) observedLease(typ pb.NumLeaseType) uint64 {
+	s.RLock()
+	defer s.RUnlock()
+	switch typ {
+	case pb.Num_UID:
+		return s.state.GetObservedUid()
+	case pb.Num_NS_ID:
+		return s.state.GetObservedNsId()
+	default:
+		return s.observed[typ]
+	}
+}
+
+func (s *Server) leaseState(typ pb.NumLeaseType) LeaseState {
+	st := LeaseState{Leased: s.maxLease(typ), Observed: s.observedLease(typ)}
+	if s.Node.AmLeader() {
+		s.leaseLock.Lock()
+		st.Next = s.nextLease[typ]
+		s.leaseLock.Unlock()
+	}
+	return st
+}
+
+// validateLeaseAdvance checks that moving the lease of typ to the given value would not hand out
+// ids or timestamps which have already been used.
+func (s *Server) validateLeaseAdvance(typ pb.NumLeaseType, to uint64) error {
+	observed := s.observedLease(typ)
+	if leased := s.maxLease(typ); to <= leased {
+		return errors.Errorf("Lease can only be advanced. Requested: %d, leased so far: %d",
+			to, leased)
+	}
+	if to < observed {
+		return errors.Errorf("Requested lease %d is below %d, which is already in use by the "+
+			"Alphas", to, observed)
+	}
+	return nil
+}
+
+// prepareLeaseAdvance validates an advance of the lease and returns the token which must be
+// passed back to confirm it. Only the leader keeps the pending advance, so it must be prepared
+// on the leader too.
+func (s *Server) prepareLeaseAdvance(typ pb.NumLeaseType, to uint64) (string, error) {
+	if !s.Node.AmLeader() {
+		return "", errors.Errorf("Leases can only be advanced on the Zero leader")
+	}
+	if err := s.validateLeaseAdvance(typ, to); err != nil {
+		return "", err
+	}
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	adj := &leaseAdjust{
+		typ:     typ,
+		to:      to,
+		token:   hex.EncodeToString(buf),
+		expires: time.Now().Add(leaseAdjustTimeout),
+	}
+	s.Lock()
+	s.pendingLease = adj
+	s.Unlock()
+	return adj.token, nil
+}
+
+// advanceLease moves the lease of typ to the given value, provided that it was prepared before
+// with the same token.
+func (s *Server) advanceLease(ctx context.Context, typ pb.NumLeaseType, to uint64,
+	token string) error {
+	if !s.Node.AmLeader() {
+		return errors.Errorf("Leases can only be advanced on the Zero leader")
+	}
+
+	s.Lock()
+	adj := s.pendingLease
+	s.pendingLease = nil
+	s.Unlock()
+	switch {
+	case adj == nil || adj.token != token:
+		return errors.Errorf("Invalid confirmation token. Please prepare the advance again")
+	case time.Now().After(adj.expires):
+		return errors.Errorf("Confirmation token has expired. Please prepare the advance again")
+	case adj.typ != typ || adj.to != to:
+		return errors.Errorf("Confirmation token was issued for a different advance")
+	}
+
+	s.leaseLock.Lock()
+	defer s.leaseLock.Unlock()
+	// The state might have changed since the advance was prepared.
+	if err := s.validateLeaseAdvance(typ, to); err != nil {
+		return err
+	}
+
+	var proposal pb.ZeroProposal
+	switch typ {
+	case pb.Num_UID:
+		proposal.MaxUID = to
+	case pb.Num_TXN_TS:
+		proposal.MaxTxnTs = to
+	case pb.Num_NS_ID:
+		proposal.MaxNsID = to
+	}
+	if err := s.Node.proposeAndWait(ctx, &proposal); err != nil {
+		return err
+	}
+	s.nextLease[typ] = to + 1
+	glog.Infof("Advanced lease for %v to %d", typ, to)
+	return nil
+}
+
+// leases shows the state of the UID, timestamp and namespace ID leases. A lease can be advanced
+// in two steps: passing what and to returns a confirmation token after validating the request,
+// and passing the same what and to along with confirm=<token> applies it.
+func (st *state) leases(w http.ResponseWriter, r *http.Request) {
+	x.AddCorsHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+
+	var resp interface{}
+	what := r.URL.Query().Get("what")
+	if len(what) == 0 {
+		states := make(map[string]LeaseState)
+		for name, typ := range leaseTypes {
+			states[name] = st.zero.leaseState(typ)
+		}
+		resp = states
+	} else {
+		typ, ok := leaseTypes[what]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			x.SetStatus(w, x.ErrorInvalidRequest,
+				fmt.Sprintf("Invalid what: [%s]. Must be one of uids, timestamps or nsids", what))
+			return
+		}
+		to, ok := intFromQueryParam(w, r, "to")
+		if !ok {
+			return
+		}
+
+		token := r.URL.Query().Get("confirm")
+		if len(token) == 0 {
+			token, err := st.zero.prepareLeaseAdvance(typ, to)
+			if err != nil {
+				x.SetStatus(w, x.Error, err.Error())
+				return
+			}
+			resp = map[string]interface{}{
+				"state": st.zero.leaseState(typ),
+				"to":    to,
+				"message": fmt.Sprintf("Confirm within %s by calling "+
+					"/leases?what=%s&to=%d&confirm=%s", leaseAdjustTimeout, what, to, token),
+				"confirm": token,
+			}
+		} else {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := st.zero.advanceLease(ctx, typ, to, token); err != nil {
+				x.SetStatus(w, x.Error, err.Error())
+				return
+			}
+			resp = map[string]interface{}{
+				"state":   st.zero.leaseState(typ),
+				"message": fmt.Sprintf("Advanced %s lease to %d", what, to),
+			}
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		glog.Warningf("Error while writing response: %+v", err)
+	}
+}
@@ -20,24 +20,23 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/dgraph-io/dgraph/ee/audit"
-
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/dgraph-io/ristretto/z"
 	humanize "github.com/dustin/go-humanize"
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/glog"
+	ostats "go.opencensus.io/stats"
 )
 
 // proposeTrialLicense proposes an enterprise license valid for 30 days.
 func (n *node) proposeTrialLicense() error {
 	// Apply enterprise license valid for 30 days from now.
 	proposal := &pb.ZeroProposal{
-		License: &pb.License{
+		License: withLicenseStatus(&pb.License{
 			MaxNodes: math.MaxUint64,
 			ExpiryTs: time.Now().UTC().Add(humanize.Month).Unix(),
-		},
+		}),
 	}
 	err := n.proposeAndWait(context.Background(), proposal)
 	if err != nil {
@@ -54,14 +53,41 @@ func (s *Server) license() *pb.License {
 	return proto.Clone(s.state.GetLicense()).(*pb.License)
 }
 
-func (s *Server) expireLicense() {
-	s.Lock()
-	defer s.Unlock()
-	s.state.License.Enabled = false
+// setLicenseStatus sets whether the license is enabled and has expired. It returns whether that
+// changed anything.
+func setLicenseStatus(l *pb.License, enabled, expired bool) bool {
+	if l == nil || (l.Enabled == enabled && l.Expired == expired) {
+		return false
+	}
+	l.Enabled, l.Expired = enabled, expired
+	return true
+}
+
+// recordLicenseMetrics records whether the license is enabled, when it expires and how many of
+// the nodes it allows are used.
+func (s *Server) recordLicenseMetrics(license *pb.License) {
+	s.RLock()
+	numNodes := len(s.state.GetZeros())
+	for _, group := range s.state.GetGroups() {
+		numNodes += len(group.GetMembers())
+	}
+	s.RUnlock()
+
+	var enabled int64
+	if license.GetEnabled() {
+		enabled = 1
+	}
+	ctx := context.Background()
+	ostats.Record(ctx, x.LicenseEnabled.M(enabled), x.LicenseNodes.M(int64(numNodes)))
+	if license.GetExpiryTs() > 0 {
+		ostats.Record(ctx, x.LicenseExpiresIn.M(license.GetExpiryTs()-time.Now().Unix()))
+	}
 }
 
 // periodically checks the validity of the enterprise license and
-// 1. Sets license.Enabled to false in membership state if license has expired.
+// 1. Has the leader propose license.Enabled set to false if license has expired, unless it runs
+// with --license_expiry=warn, in which case it prints out a warning once every day instead until
+// --license_grace is over. The license is enabled again if that's changed by a restart.
 // 2. Prints out warning once every day a week before the license is set to expire.
 func (n *node) updateEnterpriseState(closer *z.Closer) {
 	defer closer.Done()
@@ -77,12 +103,14 @@ func (n *node) updateEnterpriseState(closer *z.Closer) {
 		case <-ticker.C:
 			counter++
 			license := n.server.license()
-			if !license.GetEnabled() {
+			n.server.recordLicenseMetrics(license)
+			if license.GetExpiryTs() == 0 {
 				continue
 			}
 
+			now := time.Now().UTC()
 			expiry := time.Unix(license.GetExpiryTs(), 0).UTC()
-			timeToExpire := expiry.Sub(time.Now().UTC())
+			timeToExpire := expiry.Sub(now)
 			// We only want to print this log once a day.
 			if counter%intervalsInDay == 0 && timeToExpire > 0 && timeToExpire < humanize.Week {
 				glog.Warningf("Your enterprise license will expire in %s. To continue using enterprise "+
@@ -90,13 +118,33 @@ func (n *node) updateEnterpriseState(closer *z.Closer) {
 					"https://dgraph.io/contact.", humanize.Time(expiry), humanize.Time(expiry))
 			}
 
-			active := time.Now().UTC().Before(expiry)
-			if !active {
-				n.server.expireLicense()
-				audit.Close()
+			// Only the leader updates the status, and proposes it so that all the replicas
+			// apply the same one.
+			if !n.AmLeader() {
+				continue
+			}
+			enabled, expired := licenseStatus(expiry, now)
+			changed := setLicenseStatus(license, enabled, expired)
+			if changed {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				err := n.proposeAndWait(ctx, &pb.ZeroProposal{License: license})
+				cancel()
+				if err != nil {
+					glog.Errorf("While proposing the license status: %v", err)
+					continue
+				}
+			}
+			switch {
+			case !enabled && changed:
 				glog.Warningf("Your enterprise license has expired and enterprise features are " +
 					"disabled. To continue using enterprise features, apply a valid license. To receive " +
 					"a new license, contact us at https://dgraph.io/contact.")
+			case enabled && expired && (changed || counter%intervalsInDay == 0):
+				glog.Warningf("Your enterprise license expired %s. Enterprise features stay "+
+					"enabled until %s because of --license_expiry=warn. To keep using them, apply a "+
+					"valid license. To receive a new license, contact us at "+
+					"https://dgraph.io/contact.", humanize.Time(expiry),
+					expiry.Add(opts.licenseGrace).Format(time.RFC3339))
 			}
 		case <-closer.HasBeenClosed():
 			return
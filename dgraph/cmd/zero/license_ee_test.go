@@ -0,0 +1,42 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+
+package zero
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetLicenseStatus(t *testing.T) {
+	require.False(t, setLicenseStatus(nil, false, true))
+
+	l := &pb.License{Enabled: true}
+	require.False(t, setLicenseStatus(l, true, false))
+
+	// The license expires but stays enabled, as with --license_expiry=warn.
+	require.True(t, setLicenseStatus(l, true, true))
+	require.False(t, setLicenseStatus(l, true, true))
+	require.True(t, l.Enabled)
+	require.True(t, l.Expired)
+
+	// Once the grace period is over, the license gets disabled.
+	require.True(t, setLicenseStatus(l, false, true))
+	require.False(t, l.Enabled)
+
+	// It's enabled again if Zero restarts with a longer grace period.
+	require.True(t, setLicenseStatus(l, true, true))
+	require.True(t, l.Enabled)
+}
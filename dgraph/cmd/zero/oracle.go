@@ -18,7 +18,10 @@ package zero
 
 import (
 	"context"
+	"encoding/json"
 	"math/rand"
+	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +34,8 @@ import (
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 	otrace "go.opencensus.io/trace"
 )
 
@@ -52,6 +57,9 @@ type Oracle struct {
 	subscribers map[int]chan pb.OracleDelta
 	updates     chan *pb.OracleDelta
 	doneUntil   y.WaterMark
+	// committing maps the start ts of the transactions being committed to when their commit
+	// started.
+	committing map[uint64]time.Time
 }
 
 // Init initializes the oracle.
@@ -61,6 +69,7 @@ func (o *Oracle) Init() {
 	// to wrong results.
 	o.keyCommit = z.NewTree()
 	o.subscribers = make(map[int]chan pb.OracleDelta)
+	o.committing = make(map[uint64]time.Time)
 	o.updates = make(chan *pb.OracleDelta, 100000) // Keeping 1 second worth of updates.
 	o.doneUntil.Init(nil)
 	go o.sendDeltasToSubscribers()
@@ -113,6 +122,7 @@ func (o *Oracle) purgeBelow(minTs uint64) {
 		}
 	}
 	timer.Record("commits")
+	ostats.Record(context.Background(), x.ZeroOracleCommits.M(int64(len(o.commits))))
 
 	// There is no transaction running with startTs less than minTs
 	// So we can delete everything from rowCommit whose commitTs < minTs
@@ -301,6 +311,74 @@ func (o *Oracle) storePending(ids *pb.AssignedIds) {
 	o.Lock()
 	defer o.Unlock()
 	o.maxAssigned = x.Max(o.maxAssigned, max)
+	ostats.Record(context.Background(), x.MaxAssignedTs.M(int64(o.maxAssigned)))
+}
+
+// CommittingTxn is a transaction whose commit has started, but not finished yet.
+type CommittingTxn struct {
+	StartTs uint64    `json:"startTs"`
+	Since   time.Time `json:"since"`
+}
+
+// OracleState is the state of the oracle, as shown by the /oracle endpoint to debug stuck
+// transactions.
+type OracleState struct {
+	// MaxAssigned is the max timestamp sent to the Alphas.
+	MaxAssigned uint64 `json:"maxAssigned"`
+	// DoneUntil is the timestamp up to which all the timestamps handed out are done. MaxAssigned
+	// can't move past it, so a DoneUntil which lags behind means that a transaction is stuck.
+	DoneUntil uint64 `json:"doneUntil"`
+	// StartTxnTs is the timestamp below which all the transactions get aborted.
+	StartTxnTs uint64 `json:"startTxnTs"`
+	// NumTxns is the number of committed or aborted transactions kept until the Alphas have
+	// applied them.
+	NumTxns int `json:"numTxns"`
+	// Txns are those transactions. They are only returned if asked for.
+	Txns []*pb.TxnStatus `json:"txns,omitempty"`
+	// Committing are the transactions being committed, oldest first.
+	Committing    []CommittingTxn `json:"committing"`
+	Subscribers   int             `json:"subscribers"`
+	QueuedUpdates int             `json:"queuedUpdates"`
+}
+
+// state returns the state of the oracle, along with the statuses of the transactions if
+// withTxns is true.
+func (o *Oracle) state(withTxns bool) *OracleState {
+	o.RLock()
+	defer o.RUnlock()
+	st := &OracleState{
+		MaxAssigned:   o.maxAssigned,
+		DoneUntil:     o.doneUntil.DoneUntil(),
+		StartTxnTs:    o.startTxnTs,
+		NumTxns:       len(o.commits),
+		Committing:    []CommittingTxn{},
+		Subscribers:   len(o.subscribers),
+		QueuedUpdates: len(o.updates),
+	}
+	if withTxns {
+		st.Txns = o.currentState().Txns
+		sort.Slice(st.Txns, func(i, j int) bool { return st.Txns[i].StartTs < st.Txns[j].StartTs })
+	}
+	for startTs, since := range o.committing {
+		st.Committing = append(st.Committing, CommittingTxn{StartTs: startTs, Since: since})
+	}
+	sort.Slice(st.Committing, func(i, j int) bool {
+		return st.Committing[i].StartTs < st.Committing[j].StartTs
+	})
+	return st
+}
+
+// trackCommit records that the commit of the transaction has started, and returns the function
+// to call once it's done.
+func (o *Oracle) trackCommit(startTs uint64) func() {
+	o.Lock()
+	o.committing[startTs] = time.Now()
+	o.Unlock()
+	return func() {
+		o.Lock()
+		delete(o.committing, startTs)
+		o.Unlock()
+	}
 }
 
 // MaxPending returns the maximum assigned timestamp.
@@ -347,10 +425,40 @@ func (s *Server) proposeTxn(ctx context.Context, src *api.TxnContext) error {
 	return nil
 }
 
-func (s *Server) commit(ctx context.Context, src *api.TxnContext) error {
+// Reasons why Zero aborts a transaction, used to tag the aborts metric.
+const (
+	abortByClient   = "client"
+	abortConflict   = "conflict"
+	abortReadOnly   = "read_only"
+	abortTabletMove = "tablet_move"
+	abortTimeout    = "timeout"
+	// abortProposal is for a transaction aborted while its commit was being proposed, e.g. by a
+	// predicate move.
+	abortProposal = "proposal"
+)
+
+func (s *Server) commit(ctx context.Context, src *api.TxnContext) (err error) {
 	span := otrace.FromContext(ctx)
 	span.Annotate([]otrace.Attribute{otrace.Int64Attribute("startTs", int64(src.StartTs))}, "")
+	defer s.orc.trackCommit(src.StartTs)()
+
+	var reason string
+	defer func() {
+		switch {
+		case err != nil:
+		case !src.Aborted:
+			ostats.Record(context.Background(), x.ZeroTxnCommits.M(1))
+		default:
+			if reason == "" {
+				reason = abortProposal
+			}
+			mctx, _ := tag.New(context.Background(), tag.Upsert(x.KeyReason, reason))
+			ostats.Record(mctx, x.ZeroTxnAborts.M(1))
+		}
+	}()
+
 	if src.Aborted {
+		reason = abortByClient
 		return s.proposeTxn(ctx, src)
 	}
 
@@ -362,6 +470,15 @@ func (s *Server) commit(ctx context.Context, src *api.TxnContext) error {
 		span.Annotate([]otrace.Attribute{otrace.BoolAttribute("abort", true)},
 			"Oracle found conflict")
 		src.Aborted = true
+		reason = abortConflict
+		return s.proposeTxn(ctx, src)
+	}
+
+	if s.readOnly() {
+		span.Annotate([]otrace.Attribute{otrace.BoolAttribute("abort", true)},
+			"Cluster is in read-only mode")
+		src.Aborted = true
+		reason = abortReadOnly
 		return s.proposeTxn(ctx, src)
 	}
 
@@ -399,6 +516,7 @@ func (s *Server) commit(ctx context.Context, src *api.TxnContext) error {
 	if err := checkPreds(); err != nil {
 		span.Annotate([]otrace.Attribute{otrace.BoolAttribute("abort", true)}, err.Error())
 		src.Aborted = true
+		reason = abortTabletMove
 		return s.proposeTxn(ctx, src)
 	}
 
@@ -416,10 +534,14 @@ func (s *Server) commit(ctx context.Context, src *api.TxnContext) error {
 	if err := s.orc.commit(src); err != nil {
 		span.Annotatef(nil, "Found a conflict. Aborting.")
 		src.Aborted = true
+		reason = abortConflict
 	}
 	if err := ctx.Err(); err != nil {
 		span.Annotatef(nil, "Aborting txn due to context timing out.")
 		src.Aborted = true
+		if reason == "" {
+			reason = abortTimeout
+		}
 	}
 	// Propose txn should be used to set watermark as done.
 	return s.proposeTxn(ctx, src)
@@ -528,3 +650,37 @@ func (s *Server) Timestamps(ctx context.Context, num *pb.Num) (*pb.AssignedIds,
 	}
 	return reply, err
 }
+
+// oracle shows the state of the oracle, to debug stuck transactions. Passing txns=true includes
+// the statuses of the transactions which haven't been purged yet.
+func (st *state) oracle(w http.ResponseWriter, r *http.Request) {
+	x.AddCorsHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+
+	withTxns := r.URL.Query().Get("txns") == "true"
+	resp := struct {
+		*OracleState
+		Leader bool `json:"leader"`
+		// NextTs is the next timestamp which would be handed out. Only the leader knows about it.
+		NextTs uint64 `json:"nextTs,omitempty"`
+		// CheckpointTs maps the groups to the timestamps up to which they have applied the
+		// transactions.
+		CheckpointTs map[uint32]uint64 `json:"checkpointTs"`
+	}{
+		OracleState:  st.zero.orc.state(withTxns),
+		Leader:       st.node.AmLeader(),
+		NextTs:       st.zero.leaseState(pb.Num_TXN_TS).Next,
+		CheckpointTs: st.zero.checkpoints(),
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		glog.Warningf("Error while writing response: %+v", err)
+	}
+}
@@ -0,0 +1,246 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+var placementDefaults = "affinity=; spread=; target-size="
+
+// placement holds the constraints on where Zero puts the tablets and the members of the groups.
+// They refer to the tags the Alphas are started with.
+type placement struct {
+	// affinity maps a predicate to the tags which all the members of the group serving it must
+	// have, e.g. the hot predicates to the Alphas with disk:nvme.
+	affinity map[string]map[string]string
+	// spread is the tag whose values the replicas of a group are spread across, e.g. zone.
+	spread string
+	// targetSize maps a group to its target size on disk. The rebalancer keeps the sizes of the
+	// groups proportional to their targets.
+	targetSize map[uint32]int64
+}
+
+func parsePlacement(sf *z.SuperFlag) (*placement, error) {
+	p := &placement{
+		affinity:   make(map[string]map[string]string),
+		spread:     sf.GetString("spread"),
+		targetSize: make(map[uint32]int64),
+	}
+	for _, rule := range splitList(sf.GetString("affinity")) {
+		// Predicates might contain colons, so the tag and the value are taken from the end.
+		parts := strings.Split(rule, ":")
+		n := len(parts)
+		if n < 3 || parts[n-1] == "" || parts[n-2] == "" {
+			return nil, errors.Errorf("Invalid affinity %q. It must be of the form "+
+				"predicate:tag:value", rule)
+		}
+		pred := strings.Join(parts[:n-2], ":")
+		if p.affinity[pred] == nil {
+			p.affinity[pred] = make(map[string]string)
+		}
+		p.affinity[pred][parts[n-2]] = parts[n-1]
+	}
+	for _, target := range splitList(sf.GetString("target-size")) {
+		parts := strings.SplitN(target, ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("Invalid target size %q. It must be of the form "+
+				"group:size", target)
+		}
+		gid, err := strconv.ParseUint(parts[0], 10, 32)
+		if err != nil || gid == 0 {
+			return nil, errors.Errorf("Invalid group in target size %q", target)
+		}
+		size, err := humanize.ParseBytes(parts[1])
+		if err != nil || size == 0 {
+			return nil, errors.Errorf("Invalid size in target size %q", target)
+		}
+		p.targetSize[uint32(gid)] = int64(size)
+	}
+	return p, nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// allows returns whether all the members of the group have the tags which the predicate needs.
+// The affinities apply to the predicate in every namespace.
+func (p *placement) allows(pred string, group *pb.Group) bool {
+	if p == nil {
+		return true
+	}
+	tags := p.affinity[x.ParseAttr(pred)]
+	if len(tags) == 0 {
+		return true
+	}
+	if len(group.GetMembers()) == 0 {
+		return false
+	}
+	for _, m := range group.Members {
+		for tag, val := range tags {
+			if m.Tags[tag] != val {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// crowded returns whether the group already has a member with the same value of the spread tag as
+// the given member.
+func (p *placement) crowded(group *pb.Group, m *pb.Member) bool {
+	if p == nil || p.spread == "" {
+		return false
+	}
+	val, ok := m.Tags[p.spread]
+	if !ok {
+		return false
+	}
+	for _, gm := range group.GetMembers() {
+		if gm.Id != m.Id && gm.Tags[p.spread] == val {
+			return true
+		}
+	}
+	return false
+}
+
+// weight returns the target size of the group, relative to the other groups. The groups without
+// a target get the average target of the others.
+func (p *placement) weight(gid uint32) float64 {
+	if p == nil || len(p.targetSize) == 0 {
+		return 1
+	}
+	if size, ok := p.targetSize[gid]; ok {
+		return float64(size)
+	}
+	var total int64
+	for _, size := range p.targetSize {
+		total += size
+	}
+	return float64(total) / float64(len(p.targetSize))
+}
+
+// groupLoad is the size of a group relative to its target.
+type groupLoad struct {
+	gid    uint32
+	size   int64 // in bytes
+	weight float64
+}
+
+func (g groupLoad) load() float64 {
+	return float64(g.size) / g.weight
+}
+
+// groupLoads returns the loads of the groups which can take tablets, from the least loaded to the
+// most loaded one. The groups without members, or which are being removed, can't.
+func (s *Server) groupLoads() []groupLoad {
+	s.AssertRLock()
+	var loads []groupLoad
+	for gid, group := range s.state.Groups {
+		if group.Removing || len(group.Members) == 0 {
+			continue
+		}
+		size := int64(0)
+		for _, tab := range group.Tablets {
+			size += tab.OnDiskBytes
+		}
+		loads = append(loads, groupLoad{gid: gid, size: size, weight: s.placement.weight(gid)})
+	}
+	sort.Slice(loads, func(i, j int) bool {
+		if loads[i].load() != loads[j].load() {
+			return loads[i].load() < loads[j].load()
+		}
+		return loads[i].gid < loads[j].gid
+	})
+	return loads
+}
+
+// allowedGroup returns the least loaded group which the predicate can be placed in, or 0 if
+// there's none.
+func (s *Server) allowedGroup(pred string, loads []groupLoad) uint32 {
+	s.AssertRLock()
+	for _, l := range loads {
+		if s.placement.allows(pred, s.state.Groups[l.gid]) {
+			return l.gid
+		}
+	}
+	return 0
+}
+
+// misplacedTablet returns a tablet served by a group which doesn't satisfy its affinity, along
+// with a group which does.
+func (s *Server) misplacedTablet(loads []groupLoad) (predicate string, srcGroup, dstGroup uint32) {
+	s.AssertRLock()
+	if s.placement == nil || len(s.placement.affinity) == 0 {
+		return
+	}
+	gids := make([]uint32, 0, len(loads))
+	for _, l := range loads {
+		gids = append(gids, l.gid)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+	for _, gid := range gids {
+		group := s.state.Groups[gid]
+		preds := make([]string, 0, len(group.Tablets))
+		for pred := range group.Tablets {
+			preds = append(preds, pred)
+		}
+		sort.Strings(preds)
+		for _, pred := range preds {
+			if x.IsReservedPredicate(pred) || s.placement.allows(pred, group) {
+				continue
+			}
+			dst := s.allowedGroup(pred, loads)
+			if dst == 0 || !s.hasLeader(dst) {
+				glog.Warningf("No group satisfies the affinity of predicate %s", pred)
+				continue
+			}
+			return pred, gid, dst
+		}
+	}
+	return
+}
+
+// canServe returns an error if the group can't take the tablet.
+func (s *Server) canServe(pred string, gid uint32) error {
+	s.RLock()
+	defer s.RUnlock()
+	if s.state.Groups[gid].GetRemoving() {
+		return errors.Errorf("Group %d is being removed", gid)
+	}
+	if !s.placement.allows(pred, s.state.Groups[gid]) {
+		return errors.Errorf("Group %d doesn't satisfy the placement affinity of predicate %s",
+			gid, x.ParseAttr(pred))
+	}
+	return nil
+}
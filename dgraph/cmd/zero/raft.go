@@ -106,6 +106,11 @@ func (n *node) proposeAndWait(ctx context.Context, proposal *pb.ZeroProposal) er
 
 	stop := x.SpanTimer(span, "n.proposeAndWait")
 	defer stop()
+	start := time.Now()
+	defer func() {
+		mctx, _ := tag.New(context.Background(), tag.Upsert(x.KeyMethod, proposalKind(proposal)))
+		ostats.Record(mctx, x.ZeroProposalLatencyMs.M(x.SinceMs(start)))
+	}()
 
 	// propose runs in a loop. So, we should not do any checks inside, including n.AmLeader. This is
 	// to avoid the scenario where the first proposal times out and the second one gets returned
@@ -172,6 +177,28 @@ func (n *node) proposeAndWait(ctx context.Context, proposal *pb.ZeroProposal) er
 	return err
 }
 
+// proposalKind returns the kind of the proposal, which is used to tag its latency.
+func proposalKind(p *pb.ZeroProposal) string {
+	switch {
+	case p.Txn != nil:
+		return "txn"
+	case p.MaxUID > 0 || p.MaxTxnTs > 0 || p.MaxNsID > 0 || p.MaxRaftId > 0 ||
+		p.ObservedUid > 0 || p.ObservedNsId > 0:
+		return "lease"
+	case p.Member != nil:
+		return "member"
+	case p.Tablet != nil:
+		return "tablet"
+	case len(p.SnapshotTs) > 0 || p.Snapshot != nil:
+		return "snapshot"
+	case p.Xids != nil:
+		return "xids"
+	case p.Task != nil || p.TaskControl != nil:
+		return "task"
+	}
+	return "other"
+}
+
 var (
 	errInvalidProposal     = errors.New("Invalid group proposal")
 	errTabletAlreadyServed = errors.New("Tablet is already being served")
@@ -342,6 +369,33 @@ func (n *node) applySnapshot(snap *pb.ZeroSnapshot) error {
 	return nil
 }
 
+// maxLicenseGrace bounds --license_grace.
+const maxLicenseGrace = 30 * 24 * time.Hour
+
+// licenseStatus returns whether a license expiring at expiry has expired by now, and whether it
+// keeps the enterprise features enabled. After the expiry, they only stay enabled with
+// --license_expiry=warn, and for --license_grace at most.
+func licenseStatus(expiry, now time.Time) (enabled, expired bool) {
+	if now.Before(expiry) {
+		return true, false
+	}
+	return opts.licenseExpiry == "warn" && now.Before(expiry.Add(opts.licenseGrace)), true
+}
+
+// withLicenseStatus sets whether the license is enabled and has expired as of now, before it's
+// proposed.
+func withLicenseStatus(l *pb.License) *pb.License {
+	expiry := time.Unix(l.ExpiryTs, 0).UTC()
+	l.Enabled, l.Expired = licenseStatus(expiry, time.Now().UTC())
+	return l
+}
+
+// sameLicense returns whether both licenses are the same, regardless of their status.
+func sameLicense(a, b *pb.License) bool {
+	return a.GetUser() == b.GetUser() && a.GetMaxNodes() == b.GetMaxNodes() &&
+		a.GetExpiryTs() == b.GetExpiryTs()
+}
+
 func (n *node) applyProposal(e raftpb.Entry) (uint64, error) {
 	x.AssertTrue(len(e.Data) > 0)
 
@@ -398,19 +452,54 @@ func (n *node) applyProposal(e raftpb.Entry) (uint64, error) {
 		for _, group := range state.GetGroups() {
 			numNodes += len(group.GetMembers())
 		}
-		if uint64(numNodes) > p.GetLicense().GetMaxNodes() {
+		// A proposal which only updates the status of the current license is always accepted.
+		if uint64(numNodes) > p.GetLicense().GetMaxNodes() && !sameLicense(state.License, p.License) {
 			return key, errInvalidProposal
 		}
+		// The status of the license is set by the leader when proposing it, so that all the
+		// replicas apply the same one regardless of their flags and clocks.
+		wasEnabled := state.GetLicense().GetEnabled()
 		state.License = p.License
-		// Check expiry and set enabled accordingly.
-		expiry := time.Unix(state.License.ExpiryTs, 0).UTC()
-		state.License.Enabled = time.Now().UTC().Before(expiry)
-		if state.License.Enabled && opts.audit != nil {
+		switch {
+		case state.License.Enabled && opts.audit != nil:
 			if err := audit.InitAuditor(opts.audit); err != nil {
 				glog.Errorf("error while initializing audit logs %+v", err)
 			}
+		case !state.License.Enabled && wasEnabled:
+			audit.Close()
+		}
+	}
+	if p.ReadOnly != nil {
+		state.ReadOnly = p.ReadOnly
+	}
+	if p.Replicas > 0 {
+		state.Replicas = p.Replicas
+		n.server.NumReplicas = int(p.Replicas)
+	}
+	if p.GroupRemoval != nil {
+		group, ok := state.Groups[p.GroupRemoval.GroupId]
+		if !ok {
+			return key, errInvalidProposal
 		}
+		group.Removing = p.GroupRemoval.Removing
+	}
+	if p.Erasure != nil {
+		n.handleErasureProposal(p.Erasure)
+	}
+	if p.Xids != nil {
+		n.handleXidProposal(p.Xids)
+	}
+	if p.Task != nil {
+		n.handleTaskProposal(p.Task)
+	}
+	if p.TaskControl != nil {
+		n.handleTaskControl(p.TaskControl)
+	}
+	if p.SearchConnector != nil {
+		n.handleSearchConnector(p.SearchConnector)
 	}
+	state.ObservedUid = x.Max(state.ObservedUid, p.ObservedUid)
+	state.ObservedNsId = x.Max(state.ObservedNsId, p.ObservedNsId)
 	if p.Snapshot != nil {
 		if err := n.applySnapshot(p.Snapshot); err != nil {
 			glog.Errorf("While applying snapshot: %v\n", err)
@@ -576,6 +665,8 @@ func (n *node) initAndStartNode() error {
 			var zs pb.ZeroSnapshot
 			x.Check(zs.Unmarshal(sp.Data))
 			n.server.SetMembershipState(zs.State)
+			n.server.setXidSnapshot(zs.Xids)
+			n.server.setErasureSnapshot(zs.Erasures)
 			for _, id := range sp.Metadata.ConfState.Nodes {
 				n.Connect(id, zs.State.Zeros[id].Addr)
 			}
@@ -803,6 +894,8 @@ func (n *node) calculateAndProposeSnapshot() error {
 		Index:        snapshotIndex,
 		CheckpointTs: discardBelow,
 		State:        state,
+		Xids:         n.server.xidSnapshot(),
+		Erasures:     n.server.erasureSnapshot(),
 	}
 	glog.V(2).Infof("Proposing snapshot at index: %d, checkpoint ts: %d\n",
 		zs.Index, zs.CheckpointTs)
@@ -845,7 +938,7 @@ func (n *node) Run() {
 	go n.updateZeroMembershipPeriodically(closer)
 	go n.checkQuorum(closer)
 	go n.RunReadIndexLoop(closer, readStateCh)
-	if !x.WorkerConfig.HardSync {
+	if !opts.hardSync {
 		closer.AddRunning(1)
 		go x.StoreSync(n.Store, closer)
 	}
@@ -906,7 +999,7 @@ func (n *node) Run() {
 			n.SaveToStorage(&rd.HardState, rd.Entries, &rd.Snapshot)
 			timer.Record("disk")
 			span.Annotatef(nil, "Saved to storage")
-			for x.WorkerConfig.HardSync && rd.MustSync {
+			for opts.hardSync && rd.MustSync {
 				if err := n.Store.Sync(); err != nil {
 					glog.Errorf("Error while calling Store.Sync: %v", err)
 					time.Sleep(10 * time.Millisecond)
@@ -920,6 +1013,8 @@ func (n *node) Run() {
 				var zs pb.ZeroSnapshot
 				x.Check(zs.Unmarshal(rd.Snapshot.Data))
 				n.server.SetMembershipState(zs.State)
+				n.server.setXidSnapshot(zs.Xids)
+				n.server.setErasureSnapshot(zs.Erasures)
 			}
 
 			for _, entry := range rd.CommittedEntries {
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+)
+
+// updateReadOnly enables or disables read-only mode for the whole cluster. While it is enabled,
+// Alphas reject writes and Zero aborts every commit. It is served by ZeroAdmin.UpdateReadOnly.
+func (s *Server) updateReadOnly(ctx context.Context, req *pb.ReadOnlyMode) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	mode := &pb.ReadOnlyMode{Enabled: req.Enabled}
+	if mode.Enabled {
+		mode.Reason = req.Reason
+		mode.Since = time.Now().Unix()
+	}
+	glog.Infof("Setting read-only mode to %v. Reason: %q", mode.Enabled, mode.Reason)
+	return s.Node.proposeAndWait(ctx, &pb.ZeroProposal{ReadOnly: mode})
+}
+
+func (s *Server) readOnly() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.state.GetReadOnly().GetEnabled()
+}
+
+// readOnlyMode can be used to enable or disable read-only mode for the cluster. It takes in
+// enable and an optional reason as arguments.
+func (st *state) readOnlyMode(w http.ResponseWriter, r *http.Request) {
+	x.AddCorsHeaders(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+
+	enable, err := strconv.ParseBool(r.URL.Query().Get("enable"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidRequest, "enable should be true or false")
+		return
+	}
+	req := &pb.ReadOnlyMode{Enabled: enable, Reason: r.URL.Query().Get("reason")}
+
+	ctx, cancel := context.WithTimeout(adminContext(r), time.Minute)
+	defer cancel()
+	resp, err := st.admin.UpdateReadOnly(ctx, req)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeAdminResponse(w, resp)
+}
@@ -0,0 +1,245 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// tabletMoveAttempts is the number of times the move of a tablet out of a group being removed
+	// is attempted, before the removal gives up on it.
+	tabletMoveAttempts = 5
+	// tabletMoveRetryDelay is how long the removal of a group waits before retrying a tablet move.
+	tabletMoveRetryDelay = 30 * time.Second
+	// groupRemovalCheck is how often the Zero leader looks for the removals it has to resume,
+	// e.g. after a leader change.
+	groupRemovalCheck = time.Minute
+)
+
+// removeGroup starts moving all the tablets of the group to the remaining groups. Once the group
+// doesn't serve any tablet, its members are removed, so that the Alphas can be shut down. The
+// removal is recorded in the membership state, so that the next Zero leader resumes it, and runs
+// as a task, whose id is returned. It can be cancelled between two tablet moves.
+func (s *Server) removeGroup(ctx context.Context, gid uint32) (uint64, error) {
+	if !s.Node.AmLeader() {
+		return 0, errors.Errorf("Groups can only be removed by the Zero leader")
+	}
+
+	s.RLock()
+	group, ok := s.state.Groups[gid]
+	var others int
+	for id, g := range s.state.Groups {
+		if !g.Removing && id != gid && len(g.Members) > 0 {
+			others++
+		}
+	}
+	s.RUnlock()
+	switch {
+	case !ok:
+		return 0, errors.Errorf("No group with groupId %d found", gid)
+	case gid == 1:
+		// The reserved predicates always stay in group 1.
+		return 0, errors.Errorf("Group 1 can't be removed, it serves the reserved predicates")
+	case group.Removing:
+		return 0, errors.Errorf("Group %d is already being removed", gid)
+	case others == 0:
+		return 0, errors.Errorf("There's no other group to move the tablets of group %d to", gid)
+	}
+	if err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{
+		GroupRemoval: &pb.GroupRemoval{GroupId: gid, Removing: true}}); err != nil {
+		return 0, err
+	}
+	task := s.runGroupRemoval(gid)
+	if task == nil {
+		return 0, errors.Errorf("Group %d is already being removed", gid)
+	}
+	return task.task.Id, nil
+}
+
+// runGroupRemoval runs the removal of the group in a task, unless this node already runs it.
+func (s *Server) runGroupRemoval(gid uint32) *zeroTask {
+	s.Lock()
+	if _, ok := s.groupRemovals[gid]; ok {
+		s.Unlock()
+		return nil
+	}
+	s.groupRemovals[gid] = struct{}{}
+	numTablets := len(s.state.Groups[gid].GetTablets())
+	s.Unlock()
+
+	task := s.startTask("group-removal", fmt.Sprintf("Move the %d tablets of group %d to the "+
+		"remaining groups and remove its members", numTablets, gid), true)
+	task.progress(0, "")
+	go func() {
+		err := s.moveTabletsOut(gid, task)
+		if err == nil {
+			err = s.removeMembers(gid)
+		}
+		switch {
+		case err == nil:
+			glog.Infof("Removed group %d", gid)
+		case !s.Node.AmLeader():
+			// The next leader resumes the removal.
+			glog.Errorf("While removing group %d: %v", gid, err)
+		default:
+			glog.Errorf("While removing group %d: %v", gid, err)
+			// The group can take tablets again.
+			if perr := s.Node.proposeAndWait(context.Background(), &pb.ZeroProposal{
+				GroupRemoval: &pb.GroupRemoval{GroupId: gid}}); perr != nil {
+				glog.Errorf("While stopping the removal of group %d: %v", gid, perr)
+			}
+		}
+		s.Lock()
+		delete(s.groupRemovals, gid)
+		s.Unlock()
+		task.finish(err)
+	}()
+	return task
+}
+
+// resumeGroupRemovals periodically resumes the removals of groups recorded in the membership
+// state, which this node doesn't run, e.g. because they were started by the previous leader.
+func (s *Server) resumeGroupRemovals() {
+	ticker := time.NewTicker(groupRemovalCheck)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !s.Node.AmLeader() {
+			continue
+		}
+		var gids []uint32
+		s.RLock()
+		for gid, group := range s.state.GetGroups() {
+			if _, ok := s.groupRemovals[gid]; ok || !group.Removing {
+				continue
+			}
+			if len(group.Tablets) > 0 || len(group.Members) > 0 {
+				gids = append(gids, gid)
+			}
+		}
+		s.RUnlock()
+
+		for _, gid := range gids {
+			if task := s.runGroupRemoval(gid); task != nil {
+				glog.Infof("Resumed the removal of group %d in task %#x", gid, task.task.Id)
+			}
+		}
+	}
+}
+
+// moveTabletsOut moves the tablets of the group one by one, each to the least loaded group which
+// satisfies its affinity. A move which fails is retried, and the tablets which can't be moved are
+// skipped, so that the removal moves as many tablets as possible.
+func (s *Server) moveTabletsOut(gid uint32, task *zeroTask) error {
+	failed := make(map[string]error)
+	for moved := 0; ; {
+		if task.cancelRequested() {
+			return errors.Errorf("Cancelled after moving %d tablets", moved)
+		}
+
+		s.RLock()
+		var preds []string
+		for pred := range s.state.Groups[gid].GetTablets() {
+			if _, ok := failed[pred]; !ok {
+				preds = append(preds, pred)
+			}
+		}
+		var pred string
+		var dst uint32
+		if len(preds) > 0 {
+			sort.Strings(preds)
+			pred = preds[0]
+			dst = s.allowedGroup(pred, s.groupLoads())
+		}
+		s.RUnlock()
+
+		switch {
+		case len(preds) == 0 && len(failed) == 0:
+			return nil
+		case len(preds) == 0:
+			var msgs []string
+			for pred, err := range failed {
+				msgs = append(msgs, fmt.Sprintf("%s: %v", x.ParseAttr(pred), err))
+			}
+			sort.Strings(msgs)
+			return errors.Errorf("Couldn't move %d tablets: %s", len(failed),
+				strings.Join(msgs, "; "))
+		case dst == 0:
+			failed[pred] = errors.Errorf("No group can serve it")
+			continue
+		}
+		if err := s.moveTablet(pred, gid, dst, task); err != nil {
+			if !s.Node.AmLeader() || task.cancelRequested() {
+				return errors.Wrapf(err, "while moving predicate %s", x.ParseAttr(pred))
+			}
+			failed[pred] = err
+			continue
+		}
+		moved++
+		task.progress(float64(moved)/float64(moved+len(preds)-1+len(failed)),
+			fmt.Sprintf("Moved %d tablets, %d left", moved, len(preds)-1+len(failed)))
+	}
+}
+
+// moveTablet moves the tablet out of the group being removed, and retries the move if it fails,
+// e.g. because predicate moves are blocked by an export.
+func (s *Server) moveTablet(pred string, src, dst uint32, task *zeroTask) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = s.movePredicate(pred, src, dst); err == nil {
+			return nil
+		}
+		if attempt == tabletMoveAttempts || !s.Node.AmLeader() {
+			return err
+		}
+		glog.Warningf("While moving predicate %s out of group %d, attempt %d of %d: %v",
+			x.ParseAttr(pred), src, attempt, tabletMoveAttempts, err)
+		for wait := time.Now().Add(tabletMoveRetryDelay); time.Now().Before(wait); {
+			if task.cancelRequested() {
+				return err
+			}
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// removeMembers removes all the members of the group, which doesn't serve any tablet anymore.
+func (s *Server) removeMembers(gid uint32) error {
+	s.RLock()
+	var ids []uint64
+	for id := range s.state.Groups[gid].GetMembers() {
+		ids = append(ids, id)
+	}
+	s.RUnlock()
+
+	for _, id := range ids {
+		if err := s.removeNode(context.Background(), id, gid); err != nil {
+			return errors.Wrapf(err, "while removing node %#x", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// updateReplicas changes the number of replicas per group of a live cluster. When the number
+// grows, the new Alphas join the existing groups until they reach it, before any new group is
+// created. When it shrinks, the extra members of every group are removed one at a time by a
+// task, whose id is returned. It is zero if no member has to be removed.
+func (s *Server) updateReplicas(ctx context.Context, replicas int) (uint64, error) {
+	switch {
+	case !s.Node.AmLeader():
+		return 0, errors.Errorf("The number of replicas can only be changed by the Zero leader")
+	case replicas <= 0 || replicas%2 == 0:
+		return 0, errors.Errorf("The number of replicas must be a positive odd number, got %d",
+			replicas)
+	}
+
+	glog.Infof("Setting the number of replicas per group to %d", replicas)
+	if err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{Replicas: uint32(replicas)}); err != nil {
+		return 0, err
+	}
+
+	s.RLock()
+	var extra int
+	for _, group := range s.state.Groups {
+		extra += len(extraMembers(group, replicas))
+	}
+	s.RUnlock()
+	if extra == 0 {
+		return 0, nil
+	}
+
+	task := s.startTask("replica-removal", fmt.Sprintf("Remove %d members to bring every group "+
+		"down to %d replicas", extra, replicas), false)
+	task.progress(0, "")
+	go func() {
+		err := s.removeExtraMembers(replicas, extra, task)
+		if err != nil {
+			glog.Errorf("While removing the extra replicas: %v", err)
+		}
+		task.finish(err)
+	}()
+	return task.task.Id, nil
+}
+
+// extraMembers returns the members of the group to remove to bring it down to the given number
+// of replicas. Learners don't count as replicas, and the leader is never picked, so that the
+// group doesn't have to elect a new one.
+func extraMembers(group *pb.Group, replicas int) []*pb.Member {
+	var voters []*pb.Member
+	for _, m := range group.GetMembers() {
+		if !m.Learner && !m.Leader {
+			voters = append(voters, m)
+		}
+	}
+	for _, m := range group.GetMembers() {
+		if !m.Learner && m.Leader {
+			replicas--
+		}
+	}
+	if len(voters) <= replicas {
+		return nil
+	}
+	// The members which joined last are removed first.
+	sort.Slice(voters, func(i, j int) bool { return voters[i].Id > voters[j].Id })
+	return voters[:len(voters)-replicas]
+}
+
+// removeExtraMembers removes the extra members of the groups one by one. After each removal, it
+// waits for the group to have a leader again before going on.
+func (s *Server) removeExtraMembers(replicas, extra int, task *zeroTask) error {
+	for removed := 0; ; removed++ {
+		if s.replicasChanged(replicas) {
+			return errors.Errorf("The number of replicas was changed again after removing %d "+
+				"members", removed)
+		}
+
+		s.RLock()
+		var gids []uint32
+		for gid := range s.state.Groups {
+			gids = append(gids, gid)
+		}
+		sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+		var member *pb.Member
+		for _, gid := range gids {
+			if members := extraMembers(s.state.Groups[gid], replicas); len(members) > 0 {
+				member = members[0]
+				break
+			}
+		}
+		s.RUnlock()
+
+		if member == nil {
+			return nil
+		}
+		if removed >= extra {
+			// Members were added since the task started.
+			extra = removed + 1
+		}
+		glog.Infof("Removing member %#x of group %d to bring it down to %d replicas",
+			member.Id, member.GroupId, replicas)
+		if err := s.removeNode(context.Background(), member.Id, member.GroupId); err != nil {
+			return errors.Wrapf(err, "while removing node %#x", member.Id)
+		}
+		if err := s.waitForLeader(member.GroupId, time.Minute); err != nil {
+			return err
+		}
+		task.progress(float64(removed+1)/float64(extra),
+			fmt.Sprintf("Removed %d members", removed+1))
+	}
+}
+
+// replicasChanged returns true if the number of replicas per group is not the given one anymore.
+func (s *Server) replicasChanged(replicas int) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.NumReplicas != replicas
+}
+
+// waitForLeader waits until one of the members of the group reports being its leader.
+func (s *Server) waitForLeader(gid uint32, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		s.RLock()
+		var hasLeader bool
+		for _, m := range s.state.Groups[gid].GetMembers() {
+			hasLeader = hasLeader || m.Leader
+		}
+		s.RUnlock()
+
+		switch {
+		case hasLeader:
+			return nil
+		case time.Now().After(deadline):
+			return errors.Errorf("Group %d has no leader after %s", gid, timeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// replicas changes the number of replicas per group. It takes in n as argument.
+func (st *state) replicas(w http.ResponseWriter, r *http.Request) {
+	x.AddCorsHeaders(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusBadRequest)
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+
+	replicas, ok := intFromQueryParam(w, r, "n")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(adminContext(r), time.Minute)
+	defer cancel()
+	resp, err := st.admin.SetReplicas(ctx, &pb.SetReplicasRequest{Replicas: uint32(replicas)})
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeAdminResponse(w, resp)
+}
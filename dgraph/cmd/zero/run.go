@@ -20,12 +20,14 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -55,9 +57,20 @@ type options struct {
 	numReplicas       int
 	peer              string
 	w                 string
+	inMemory          bool
+	hardSync          bool
 	rebalanceInterval time.Duration
 	tlsClientConfig   *tls.Config
 	audit             *x.LoggerConf
+	xidRegistry       bool
+	xidRegistryLimit  int
+	maxClockSkew      time.Duration
+	placement         *placement
+	authToken         x.SensitiveByteSlice
+	whitelist         []x.IPRange
+	hmacSecret        x.SensitiveByteSlice
+	licenseExpiry     string
+	licenseGrace      time.Duration
 }
 
 var opts options
@@ -76,7 +89,7 @@ instances to achieve high-availability.
 `,
 		Run: func(cmd *cobra.Command, args []string) {
 			defer x.StartProfile(Zero.Conf).Stop()
-			run()
+			run(nil)
 		},
 		Annotations: map[string]string{"group": "core"},
 	}
@@ -96,11 +109,55 @@ instances to achieve high-availability.
 			participate in Raft elections. This can be used to achieve a read-only replica.
 		`)
 	flag.Int("replicas", 1, "How many Dgraph Alpha replicas to run per data shard group."+
-		" The count includes the original shard.")
+		" The count includes the original shard. It can be changed on a live cluster"+
+		" through the /replicas endpoint.")
 	flag.String("peer", "", "Address of another dgraphzero server.")
 	flag.StringP("wal", "w", "zw", "Directory storing WAL.")
+	flag.Bool("badger.in_memory", false, "Keep the WAL in memory instead of on disk. The state of"+
+		" the cluster is lost when Zero stops. Meant for ephemeral clusters, e.g. in tests and CI."+
+		" Only supported on Linux.")
 	flag.Duration("rebalance_interval", 8*time.Minute, "Interval for trying a predicate move.")
+	flag.String("placement", placementDefaults,
+		`Constraints on where the tablets and the Alphas go. They refer to the tags which the Alphas
+	are started with, e.g. --raft "tags=zone:us-east-1a,disk:nvme".
+	affinity=P:T:V,... keeps predicate P in a group whose members all have the tag T set to V.
+		The rebalancer moves the predicates out of the groups which don't satisfy it.
+	spread=T adds a new Alpha to a group which doesn't have a member with the same value of the
+		tag T yet, if there's one. This spreads the replicas of the groups across zones.
+	target-size=G:S,... sets the target size of group G to S, e.g. 1:500GB. The rebalancer keeps
+		the sizes of the groups proportional to their targets.
+	`)
 	flag.String("enterprise_license", "", "Path to the enterprise license file.")
+	flag.String("license_expiry", "degrade", "What to do when the enterprise license expires."+
+		" degrade disables the enterprise features. warn keeps them enabled for --license_grace"+
+		" and logs a warning every day, giving time to apply a new license.")
+	flag.Duration("license_grace", 14*24*time.Hour, "With --license_expiry=warn, how long the"+
+		" enterprise features stay enabled after the license expires. It can't be longer than"+
+		" 30 days.")
+	flag.Duration("max_clock_skew", 500*time.Millisecond, "Log a warning if the clock of an "+
+		"Alpha differs from the clock of the Zero leader by more than this.")
+	flag.String("auth_token", "",
+		"If set, all admin requests to Zero would need to have this token. The token can be"+
+			" passed as follows: For HTTP requests, in X-Dgraph-AuthToken header. For Grpc, in"+
+			" auth-token key in the context.")
+	flag.String("whitelist", "",
+		"A comma separated list of IP addresses, IP ranges, CIDR blocks, or hostnames you wish"+
+			" to whitelist for admin requests to Zero, and for the requests of the nodes of the"+
+			" cluster if the internal port doesn't use TLS. The requests from other addresses,"+
+			" except loopback, are rejected. If it isn't set, any node can join the cluster, and"+
+			" only local admin requests are allowed unless --auth_token or --acl_secret_file is"+
+			" set. e.g., --whitelist 144.142.126.254,"+
+			"127.0.0.1:127.0.0.3,192.168.0.0/16,host.docker.internal")
+	flag.String("acl_secret_file", "", "The file that stores the HMAC secret of the Alphas. If"+
+		" set, all admin requests to Zero would need the access JWT of a guardian of the galaxy"+
+		" namespace, in X-Dgraph-AccessToken header for HTTP requests, or in accessJwt key in"+
+		" the context for Grpc. Enterprise feature.")
+	flag.Bool("xid_registry", false, "Maintain an xid -> uid registry, so that loaders and "+
+		"upserts can get stable UIDs for external IDs via the AssignUidForXid RPC. The live and "+
+		"bulk loaders use it with --xid_registry.")
+	flag.Int("xid_registry_limit", 1000000, "Maximum number of xids kept in the xid registry "+
+		"of each namespace. The registry is held in memory and copied into every snapshot, so "+
+		"UIDs are refused to new xids once it's full.")
 
 	flag.String("audit", "",
 		`Various audit options.
@@ -110,22 +167,35 @@ instances to achieve high-availability.
 	flag.
 	days=10 is the number of days audit logs will be preserved (default 10).
 	size=100 is the size of each file in mb after which it will be rolled over (default 100).
+	syslog=host:port sends the audit logs to a syslog server over TCP, for SIEM ingestion, along
+	with or instead of the files in dir.
+	syslog-format=cef/leef is the format of the syslog messages, the ArcSight Common Event Format
+	or the QRadar Log Event Extended Format (default cef).
+	syslog-tls=true/false connects to the syslog server with TLS (default true), verified with
+	syslog-ca-cert=/path/to/ca.crt or the system CAs. syslog-client-cert and syslog-client-key are
+	the client certificate and key, if the server requires one.
+	syslog-buffer=10000 is the number of audit events buffered while the syslog server is slow or
+	unreachable (default 10000).
+	syslog-full=drop/block drops the audit events when the buffer is full, or makes requests wait
+	for room (default drop).
 	Sample flag would be --audit dir=aa;encrypt-file=/filepath;compress=true;days=10;size=100`)
 
 	// TLS configurations
 	x.RegisterServerTLSFlags(flag)
+	conn.RegisterPoolFlag(flag)
 }
 
 func setupListener(addr string, port int, kind string) (listener net.Listener, err error) {
-	laddr := fmt.Sprintf("%s:%d", addr, port)
+	laddr := net.JoinHostPort(addr, strconv.Itoa(port))
 	glog.Infof("Setting up %s listener at: %v\n", kind, laddr)
 	return net.Listen("tcp", laddr)
 }
 
 type state struct {
-	node *node
-	rs   *conn.RaftServer
-	zero *Server
+	node  *node
+	rs    *conn.RaftServer
+	zero  *Server
+	admin *adminServer
 }
 
 func (st *state) serveGRPC(l net.Listener, store *raftwal.DiskStorage) {
@@ -135,11 +205,18 @@ func (st *state) serveGRPC(l net.Listener, store *raftwal.DiskStorage) {
 		grpc.MaxSendMsgSize(x.GrpcMaxSize),
 		grpc.MaxConcurrentStreams(1000),
 		grpc.StatsHandler(&ocgrpc.ServerHandler{}),
-		grpc.UnaryInterceptor(audit.AuditRequestGRPC),
+		conn.KeepaliveEnforcement(),
 	}
 
 	tlsConf, err := x.LoadServerTLSConfigForInternalPort(Zero.Conf)
 	x.Check(err)
+	grpcOpts = append(grpcOpts, grpc.UnaryInterceptor(peerInterceptor(tlsConf != nil)),
+		grpc.StreamInterceptor(peerStreamInterceptor(tlsConf != nil)))
+	if tlsConf == nil && len(opts.whitelist) == 0 {
+		glog.Warningf("The internal port isn't using TLS and --whitelist isn't set: any " +
+			"client which can reach Zero may join the cluster and change its state. Set " +
+			"--whitelist to the addresses of the Alphas and Zeros, or use mutual TLS.")
+	}
 	if tlsConf != nil {
 		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsConf)))
 	}
@@ -160,12 +237,19 @@ func (st *state) serveGRPC(l net.Listener, store *raftwal.DiskStorage) {
 	st.rs = conn.NewRaftServer(m)
 
 	st.node = &node{Node: m, ctx: context.Background(), closer: z.NewCloser(1)}
-	st.zero = &Server{NumReplicas: opts.numReplicas, Node: st.node, tlsClientConfig: opts.tlsClientConfig}
+	st.zero = &Server{
+		NumReplicas:     opts.numReplicas,
+		Node:            st.node,
+		tlsClientConfig: opts.tlsClientConfig,
+		placement:       opts.placement,
+	}
 	st.zero.Init()
 	st.node.server = st.zero
+	st.admin = &adminServer{zero: st.zero}
 
 	pb.RegisterZeroServer(s, st.zero)
 	pb.RegisterRaftServer(s, st.rs)
+	pb.RegisterZeroAdminServer(s, st.admin)
 
 	go func() {
 		defer st.zero.closer.Done()
@@ -190,7 +274,33 @@ func (st *state) serveGRPC(l net.Listener, store *raftwal.DiskStorage) {
 	}()
 }
 
-func run() {
+// serveHTTP serves the HTTP endpoints of Zero on the listener.
+func (st *state) serveHTTP(l net.Listener) {
+	tlsCfg, err := x.LoadServerTLSConfig(Zero.Conf)
+	x.Check(err)
+	go x.StartListenHttpAndHttps(l, tlsCfg, st.zero.closer)
+
+	baseMux := http.NewServeMux()
+	http.Handle("/", audit.AuditRequestHttp(baseMux))
+
+	baseMux.HandleFunc("/health", st.pingResponse)
+	baseMux.HandleFunc("/state", st.getState)
+	baseMux.HandleFunc("/removeNode", st.removeNode)
+	baseMux.HandleFunc("/moveTablet", st.moveTablet)
+	baseMux.HandleFunc("/removeGroup", st.removeGroup)
+	baseMux.HandleFunc("/replicas", st.replicas)
+	baseMux.HandleFunc("/assign", st.assign)
+	baseMux.HandleFunc("/leases", st.leases)
+	baseMux.HandleFunc("/oracle", st.oracle)
+	baseMux.HandleFunc("/enterpriseLicense",
+		adminAuthHandler("EnterpriseLicense", st.applyEnterpriseLicense))
+	baseMux.HandleFunc("/readOnly", st.readOnlyMode)
+	baseMux.HandleFunc("/jemalloc", x.JemallocHandler)
+	zpages.Handle(baseMux, "/z")
+}
+
+// run runs Zero until it's signaled to stop. A non-nil emb runs it embedded, see RunEmbedded.
+func run(emb *embedding) {
 	if Zero.Conf.GetBool("enable_sentry") {
 		x.InitSentry(enc.EeBuild)
 		defer x.FlushSentry()
@@ -204,25 +314,66 @@ func run() {
 	x.Check(err)
 
 	raft := z.NewSuperFlag(Zero.Conf.GetString("raft")).MergeAndCheckDefault(raftDefault)
+	pool := z.NewSuperFlag(Zero.Conf.GetString("pool")).MergeAndCheckDefault(conn.PoolDefaults)
+	x.Checkf(conn.SetPoolConfig(pool), "Invalid --pool flag")
 	conf := audit.GetAuditConf(Zero.Conf.GetString("audit"))
+	placement, err := parsePlacement(z.NewSuperFlag(
+		Zero.Conf.GetString("placement")).MergeAndCheckDefault(placementDefaults))
+	x.Checkf(err, "Invalid --placement flag")
+	whitelist, err := x.GetIPsFromString(Zero.Conf.GetString("whitelist"))
+	x.Checkf(err, "Invalid --whitelist flag")
+	var hmacSecret []byte
+	if secretFile := Zero.Conf.GetString("acl_secret_file"); secretFile != "" {
+		if !enc.EeBuild {
+			log.Fatalf("ERROR: acl_secret_file option cannot be applied to OSS builds. ")
+		}
+		hmacSecret, err = ioutil.ReadFile(secretFile)
+		if err != nil {
+			glog.Fatalf("Unable to read HMAC secret from file: %v", secretFile)
+		}
+		if len(hmacSecret) < 32 {
+			glog.Fatalf("The HMAC secret file should contain at least 256 bits (32 ascii chars)")
+		}
+	}
 	opts = options{
 		bindall:           Zero.Conf.GetBool("bindall"),
 		portOffset:        Zero.Conf.GetInt("port_offset"),
 		Raft:              raft,
 		numReplicas:       Zero.Conf.GetInt("replicas"),
-		peer:              Zero.Conf.GetString("peer"),
+		peer:              x.NormalizeAddress(Zero.Conf.GetString("peer")),
 		w:                 Zero.Conf.GetString("wal"),
+		inMemory:          Zero.Conf.GetBool("badger.in_memory"),
 		rebalanceInterval: Zero.Conf.GetDuration("rebalance_interval"),
 		tlsClientConfig:   tlsConf,
 		audit:             conf,
+		xidRegistry:       Zero.Conf.GetBool("xid_registry"),
+		xidRegistryLimit:  Zero.Conf.GetInt("xid_registry_limit"),
+		maxClockSkew:      Zero.Conf.GetDuration("max_clock_skew"),
+		placement:         placement,
+		authToken:         x.SensitiveByteSlice(Zero.Conf.GetString("auth_token")),
+		whitelist:         whitelist,
+		hmacSecret:        hmacSecret,
+		licenseExpiry:     Zero.Conf.GetString("license_expiry"),
+		licenseGrace:      Zero.Conf.GetDuration("license_grace"),
 	}
 	glog.Infof("Setting Config to: %+v", opts)
 	x.WorkerConfig.Parse(Zero.Conf)
+	// An embedded Alpha replaces x.WorkerConfig with its own, so Zero keeps a copy of what it uses
+	// once running.
+	opts.hardSync = x.WorkerConfig.HardSync
 
 	if !enc.EeBuild && Zero.Conf.GetString("enterprise_license") != "" {
 		log.Fatalf("ERROR: enterprise_license option cannot be applied to OSS builds. ")
 	}
 
+	if opts.licenseExpiry != "degrade" && opts.licenseExpiry != "warn" {
+		log.Fatalf("ERROR: license_expiry must be degrade or warn. Found: %q", opts.licenseExpiry)
+	}
+	if opts.licenseGrace <= 0 || opts.licenseGrace > maxLicenseGrace {
+		log.Fatalf("ERROR: license_grace must be positive and at most %s. Found: %s",
+			maxLicenseGrace, opts.licenseGrace)
+	}
+
 	if opts.numReplicas < 0 || opts.numReplicas%2 == 0 {
 		log.Fatalf("ERROR: Number of replicas must be odd for consensus. Found: %d",
 			opts.numReplicas)
@@ -259,6 +410,8 @@ func run() {
 	}
 	if x.WorkerConfig.MyAddr == "" {
 		x.WorkerConfig.MyAddr = fmt.Sprintf("localhost:%d", x.PortZeroGrpc+opts.portOffset)
+	} else {
+		x.Check(x.ValidateAddress(x.WorkerConfig.MyAddr))
 	}
 
 	nodeId := opts.Raft.GetUint64("idx")
@@ -267,12 +420,23 @@ func run() {
 	}
 	grpcListener, err := setupListener(addr, x.PortZeroGrpc+opts.portOffset, "grpc")
 	x.Check(err)
-	httpListener, err := setupListener(addr, x.PortZeroHTTP+opts.portOffset, "http")
-	x.Check(err)
+	var httpListener net.Listener
+	if emb == nil {
+		httpListener, err = setupListener(addr, x.PortZeroHTTP+opts.portOffset, "http")
+		x.Check(err)
+	}
 
 	// Create and initialize write-ahead log.
-	x.Checkf(os.MkdirAll(opts.w, 0700), "Error while creating WAL dir.")
-	store := raftwal.Init(opts.w)
+	var store *raftwal.DiskStorage
+	if opts.inMemory {
+		glog.Warningf("Keeping the WAL in memory. The state of the cluster is lost when Zero " +
+			"stops.")
+		store, err = raftwal.InitInMemory(nil)
+		x.Check(err)
+	} else {
+		x.Checkf(os.MkdirAll(opts.w, 0700), "Error while creating WAL dir.")
+		store = raftwal.Init(opts.w)
+	}
 	store.SetUint(raftwal.RaftId, nodeId)
 	store.SetUint(raftwal.GroupId, 0) // All zeros have group zero.
 
@@ -280,21 +444,12 @@ func run() {
 	var st state
 	st.serveGRPC(grpcListener, store)
 
-	tlsCfg, err := x.LoadServerTLSConfig(Zero.Conf)
-	x.Check(err)
-	go x.StartListenHttpAndHttps(httpListener, tlsCfg, st.zero.closer)
-
-	baseMux := http.NewServeMux()
-	http.Handle("/", audit.AuditRequestHttp(baseMux))
-
-	baseMux.HandleFunc("/health", st.pingResponse)
-	baseMux.HandleFunc("/state", st.getState)
-	baseMux.HandleFunc("/removeNode", st.removeNode)
-	baseMux.HandleFunc("/moveTablet", st.moveTablet)
-	baseMux.HandleFunc("/assign", st.assign)
-	baseMux.HandleFunc("/enterpriseLicense", st.applyEnterpriseLicense)
-	baseMux.HandleFunc("/jemalloc", x.JemallocHandler)
-	zpages.Handle(baseMux, "/z")
+	if emb == nil {
+		st.serveHTTP(httpListener)
+	} else {
+		// The HTTP endpoints are Alpha's.
+		st.zero.closer.Done()
+	}
 
 	// This must be here. It does not work if placed before Grpc init.
 	x.Check(st.node.initAndStartNode())
@@ -304,7 +459,17 @@ func run() {
 	}
 
 	sdCh := make(chan os.Signal, 1)
-	signal.Notify(sdCh, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	if emb == nil {
+		signal.Notify(sdCh, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	} else {
+		go func() {
+			select {
+			case <-emb.stop:
+				st.zero.closer.Signal()
+			case <-st.zero.closer.HasBeenClosed():
+			}
+		}()
+	}
 
 	// handle signals
 	go func() {
@@ -334,7 +499,9 @@ func run() {
 		// Close doesn't close already opened connections.
 
 		// Stop all HTTP requests.
-		_ = httpListener.Close()
+		if httpListener != nil {
+			_ = httpListener.Close()
+		}
 		// Stop Raft.
 		st.node.closer.SignalAndWait()
 		// Stop all internal requests.
@@ -343,11 +510,17 @@ func run() {
 		x.RemoveCidFile()
 	}()
 
-	st.zero.closer.AddRunning(2)
-	go x.MonitorMemoryMetrics(st.zero.closer)
-	go x.MonitorDiskMetrics("wal_fs", opts.w, st.zero.closer)
+	st.zero.closer.AddRunning(1)
+	go x.RunGovernor(st.zero.closer)
+	if !opts.inMemory {
+		st.zero.closer.AddRunning(1)
+		go x.MonitorDiskMetrics("wal_fs", opts.w, st.zero.closer)
+	}
 
 	glog.Infoln("Running Dgraph Zero...")
+	if emb != nil {
+		close(emb.started)
+	}
 	st.zero.closer.Wait()
 	glog.Infoln("Closer closed.")
 
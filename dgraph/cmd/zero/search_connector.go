@@ -0,0 +1,70 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// UpdateSearchConnector adds, replaces or removes a search connector. The group leaders also
+// call it to record that they completed a backfill of the connector.
+func (s *Server) UpdateSearchConnector(ctx context.Context,
+	u *pb.SearchConnectorUpdate) (*api.Payload, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if u.GetConnector().GetName() == "" {
+		return nil, errors.Errorf("Search connector name must be set")
+	}
+	if err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{SearchConnector: u}); err != nil {
+		return nil, err
+	}
+	return &api.Payload{}, nil
+}
+
+func (n *node) handleSearchConnector(u *pb.SearchConnectorUpdate) {
+	state := n.server.state
+	c := u.Connector
+	key := x.NamespaceAttr(c.Namespace, c.Name)
+	switch {
+	case u.Remove:
+		glog.Infof("Removing search connector %s of namespace %#x", c.Name, c.Namespace)
+		delete(state.SearchConnectors, key)
+	case u.BackfilledGroup != 0:
+		cur := state.SearchConnectors[key]
+		if cur == nil || cur.BackfillId != c.BackfillId {
+			// The connector was removed, or another backfill was requested since.
+			return
+		}
+		if cur.Backfilled == nil {
+			cur.Backfilled = make(map[uint32]uint64)
+		}
+		cur.Backfilled[u.BackfilledGroup] = c.BackfillId
+	default:
+		glog.Infof("Updating search connector %s of namespace %#x", c.Name, c.Namespace)
+		if state.SearchConnectors == nil {
+			state.SearchConnectors = make(map[string]*pb.SearchConnector)
+		}
+		state.SearchConnectors[key] = c
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/conn"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/testutil/sim"
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/raft/raftpb"
+)
+
+// simZero is the state of a simulated Zero. It applies the committed entries with applyProposal,
+// like a Zero does.
+type simZero struct {
+	n *node
+	// errs are the errors of applyProposal, by index. Invalid proposals must fail on every Zero.
+	errs map[uint64]string
+}
+
+func newSimZero(id uint64) sim.StateMachine {
+	orc := &Oracle{}
+	orc.Init()
+	server := &Server{
+		NumReplicas: 1,
+		orc:         orc,
+		state: &pb.MembershipState{
+			Groups: make(map[uint32]*pb.Group),
+			Zeros:  make(map[uint64]*pb.Member),
+		},
+		nextGroup: 1,
+	}
+	n := &node{Node: &conn.Node{Id: id}, server: server}
+	server.Node = n
+	return &simZero{n: n, errs: make(map[uint64]string)}
+}
+
+func (z *simZero) Apply(index uint64, data []byte) {
+	if _, err := z.n.applyProposal(raftpb.Entry{Index: index, Data: data}); err != nil {
+		z.errs[index] = err.Error()
+	}
+}
+
+func zeroProposal(t *testing.T, p *pb.ZeroProposal, key uint64) []byte {
+	data := make([]byte, 8+p.Size())
+	binary.BigEndian.PutUint64(data[:8], key)
+	_, err := p.MarshalToSizedBuffer(data[8:])
+	require.NoError(t, err)
+	return data
+}
+
+// randomZeroProposal returns a proposal racing with the others: the Alphas join the same groups,
+// the groups claim the same tablets and the transactions get different commit decisions.
+func randomZeroProposal(rng *rand.Rand) *pb.ZeroProposal {
+	switch rng.Intn(4) {
+	case 0:
+		id := uint64(rng.Intn(6) + 1)
+		return &pb.ZeroProposal{Member: &pb.Member{
+			Id: id, GroupId: uint32(rng.Intn(3) + 1), Addr: fmt.Sprintf("alpha%d:7080", id)}}
+	case 1:
+		return &pb.ZeroProposal{Tablet: &pb.Tablet{
+			Predicate: fmt.Sprintf("p%d", rng.Intn(10)), GroupId: uint32(rng.Intn(3) + 1)}}
+	case 2:
+		return &pb.ZeroProposal{MaxUID: uint64(rng.Intn(1000))}
+	default:
+		txn := &api.TxnContext{StartTs: uint64(rng.Intn(20) + 1)}
+		if rng.Intn(2) == 0 {
+			txn.Aborted = true
+		} else {
+			txn.CommitTs = txn.StartTs + uint64(rng.Intn(10)+1)
+		}
+		return &pb.ZeroProposal{Txn: txn}
+	}
+}
+
+// TestSimulatedZeros runs the proposals of Zero through a cluster with a lossy network,
+// partitions and crashes, and checks that all the Zeros end up in the same state.
+func TestSimulatedZeros(t *testing.T) {
+	for seed := int64(1); seed <= 3; seed++ {
+		c, err := sim.New(sim.Options{Seed: seed, Nodes: 5, MaxLatency: 5, DropRate: 0.05,
+			NewStateMachine: newSimZero})
+		require.NoError(t, err)
+
+		rng := rand.New(rand.NewSource(seed))
+		var key uint64
+		propose := func(ticks int) {
+			for i := 0; i < ticks; i++ {
+				if leader := c.Leader(); leader != 0 {
+					key++
+					data := zeroProposal(t, randomZeroProposal(rng), key)
+					require.NoError(t, c.Propose(leader, data))
+				}
+				c.Step()
+			}
+		}
+		require.True(t, c.RunUntil(func() bool { return c.Leader() != 0 }, 1000))
+		propose(50)
+		leader := c.Leader()
+		c.Partition([]uint64{leader}, nil)
+		propose(200)
+		c.Heal()
+		c.Stop(leader)
+		propose(50)
+		require.NoError(t, c.Restart(leader))
+		propose(50)
+
+		c.SetDropRate(0)
+		c.Run(10)
+		lastIndex := c.Node(c.Leader()).Status().Commit
+		require.True(t, c.RunUntil(func() bool {
+			for _, n := range c.Nodes() {
+				if n.Status().Applied < lastIndex {
+					return false
+				}
+			}
+			return true
+		}, 1000))
+		require.NoError(t, c.Err())
+
+		want := c.Node(1).StateMachine().(*simZero)
+		require.NotEmpty(t, want.n.server.state.Groups)
+		require.NotEmpty(t, want.errs)
+		for _, n := range c.Nodes()[1:] {
+			got := n.StateMachine().(*simZero)
+			require.True(t, proto.Equal(want.n.server.state, got.n.server.state),
+				"seed %d: the state of Zero %d differs from Zero 1", seed, n.ID)
+			require.Equal(t, want.n.server.orc.commits, got.n.server.orc.commits)
+			require.Equal(t, want.errs, got.errs)
+		}
+		c.Close()
+	}
+}
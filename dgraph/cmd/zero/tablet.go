@@ -19,19 +19,27 @@ package zero
 import (
 	"context"
 	"fmt"
-	"sort"
+	"strconv"
 	"time"
 
+	"github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/x"
 	humanize "github.com/dustin/go-humanize"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 	otrace "go.opencensus.io/trace"
 )
 
 const (
 	predicateMoveTimeout = 120 * time.Minute
+	// moveHandoverWindow is how long the source group of a move keeps serving the reads which
+	// started before the move.
+	moveHandoverWindow = time.Minute
+	// tabletSizeInterval is how often the sizes of the tablets are recorded in the metrics.
+	tabletSizeInterval = time.Minute
 )
 
 /*
@@ -58,7 +66,7 @@ This would trigger G1 to get latest state. Wait for it.
 
 */
 
-//  TODO: Have a event log for everything.
+// TODO: Have a event log for everything.
 func (s *Server) rebalanceTablets() {
 	ticker := time.NewTicker(opts.rebalanceInterval)
 	for range ticker.C {
@@ -72,15 +80,42 @@ func (s *Server) rebalanceTablets() {
 	}
 }
 
+// recordTabletSizes periodically records the sizes of the tablets reported by the Alphas, so
+// that their growth can be followed over time.
+func (s *Server) recordTabletSizes() {
+	ticker := time.NewTicker(tabletSizeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !s.Node.AmLeader() {
+			continue
+		}
+		s.RLock()
+		for gid, group := range s.state.GetGroups() {
+			for pred, tab := range group.GetTablets() {
+				ctx, _ := tag.New(context.Background(),
+					tag.Upsert(x.KeyGroup, strconv.FormatUint(uint64(gid), 10)),
+					tag.Upsert(x.KeyPredicate, pred))
+				ostats.Record(ctx, x.ZeroTabletSize.M(tab.OnDiskBytes))
+			}
+		}
+		s.RUnlock()
+	}
+}
+
 // movePredicate is the main entry point for move predicate logic. This Zero must remain the leader
 // for the entire duration of predicate move. If this Zero stops being the leader, the final
 // proposal of reassigning the tablet to the destination would fail automatically.
-func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) error {
+func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) (err error) {
 	s.moveOngoing <- struct{}{}
 	defer func() {
 		<-s.moveOngoing
 	}()
 
+	if id := s.moveBlockedBy(); len(id) > 0 {
+		return errors.Errorf("Predicate moves are blocked by %s", id)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), predicateMoveTimeout)
 	defer cancel()
 
@@ -109,6 +144,13 @@ func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) erro
 	glog.Info(msg)
 	span.Annotate([]otrace.Attribute{otrace.StringAttribute("tablet", predicate)}, msg)
 
+	ns, attr := x.ParseNamespaceAttr(predicate)
+	task := s.startTask("tablet-move", fmt.Sprintf("Move predicate %s of namespace %#x from "+
+		"group %d to %d", attr, ns, srcGroup, dstGroup), false)
+	defer func() {
+		task.finish(err)
+	}()
+
 	// Block all commits on this predicate. Keep them blocked until we return from this function.
 	unblock := s.blockTablet(predicate)
 	defer unblock()
@@ -137,6 +179,7 @@ func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) erro
 	if _, err := wc.MovePredicate(ctx, in); err != nil {
 		return errors.Wrapf(err, "while calling MovePredicate")
 	}
+	task.progress(0.5, "Streamed the predicate to the destination group")
 
 	p := &pb.ZeroProposal{}
 	p.Tablet = &pb.Tablet{
@@ -146,6 +189,7 @@ func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) erro
 		UncompressedBytes: tab.UncompressedBytes,
 		Force:             true,
 		MoveTs:            in.TxnTs,
+		MovedFrom:         srcGroup,
 	}
 	msg = fmt.Sprintf("Move at Alpha done. Now proposing: %+v", p)
 	span.Annotate(nil, msg)
@@ -158,6 +202,33 @@ func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) erro
 	glog.Info(msg)
 	span.Annotate(nil, msg)
 
+	// The move is done in two phases. The destination group now serves the predicate, so let the
+	// commits on it through again. Reads which started before the move get forwarded to the source
+	// group, which keeps its copy of the data until the handover window is over.
+	unblock()
+	task.progress(0.75, "Waiting for the handover window to pass")
+	select {
+	case <-time.After(moveHandoverWindow):
+	case <-ctx.Done():
+		return errors.Wrapf(ctx.Err(), "while waiting for handover of predicate %s", predicate)
+	}
+
+	// The source group doesn't serve the predicate anymore, not even for old reads. This must be
+	// proposed before the data is deleted, so that no read gets forwarded to the source group once
+	// it has deleted the data.
+	p = &pb.ZeroProposal{}
+	p.Tablet = &pb.Tablet{
+		GroupId:           dstGroup,
+		Predicate:         predicate,
+		OnDiskBytes:       tab.OnDiskBytes,
+		UncompressedBytes: tab.UncompressedBytes,
+		Force:             true,
+		MoveTs:            in.TxnTs,
+	}
+	if err := s.Node.proposeAndWait(ctx, p); err != nil {
+		return errors.Wrapf(err, "while finishing handover. Proposal: %+v", p)
+	}
+
 	// Now that the move has happened, we can delete the predicate from the source group. But before
 	// doing that, we should ensure the source group understands that the predicate is now being
 	// served by the destination group. For that, we pass in the expected checksum for the source
@@ -179,50 +250,101 @@ func (s *Server) movePredicate(predicate string, srcGroup, dstGroup uint32) erro
 	return nil
 }
 
+// BlockMoves keeps Zero from moving predicates until the block is released or expires. It waits
+// for any ongoing move to finish, so that once it returns, the tablets stay where they are. This is
+// used by exports to get a consistent snapshot across all the groups.
+func (s *Server) BlockMoves(ctx context.Context, req *pb.BlockMovesRequest) (*api.Payload, error) {
+	if len(req.Id) == 0 {
+		return nil, errors.Errorf("Block id must be set")
+	}
+	if req.Unblock {
+		s.Lock()
+		delete(s.moveBlocks, req.Id)
+		s.Unlock()
+		glog.Infof("Predicate moves unblocked by %s", req.Id)
+		return &api.Payload{}, nil
+	}
+	if !s.Node.AmLeader() {
+		return nil, errors.Errorf("Predicate moves can only be blocked on the Zero leader")
+	}
+	if req.TtlSecs <= 0 {
+		return nil, errors.Errorf("Invalid ttl for move block: %d", req.TtlSecs)
+	}
+
+	select {
+	case s.moveOngoing <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() {
+		<-s.moveOngoing
+	}()
+
+	s.Lock()
+	_, renew := s.moveBlocks[req.Id]
+	s.moveBlocks[req.Id] = time.Now().Add(time.Duration(req.TtlSecs) * time.Second)
+	s.Unlock()
+	if !renew {
+		glog.Infof("Predicate moves blocked by %s", req.Id)
+	}
+	return &api.Payload{}, nil
+}
+
+// moveBlockedBy returns the id of a block which keeps predicates from being moved, if any.
+func (s *Server) moveBlockedBy() string {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now()
+	for id, expiry := range s.moveBlocks {
+		if now.After(expiry) {
+			glog.Warningf("Move block %s expired without being released", id)
+			delete(s.moveBlocks, id)
+			continue
+		}
+		return id
+	}
+	return ""
+}
+
 func (s *Server) chooseTablet() (predicate string, srcGroup uint32, dstGroup uint32) {
 	s.RLock()
 	defer s.RUnlock()
 	if s.state == nil {
 		return
 	}
-	numGroups := len(s.state.Groups)
-	if !s.Node.AmLeader() || numGroups <= 1 {
+	if !s.Node.AmLeader() || len(s.state.Groups) <= 1 {
 		return
 	}
 
-	// Sort all groups by their sizes.
-	type kv struct {
-		gid  uint32
-		size int64 // in bytes
-	}
-	var groups []kv
-	for k, v := range s.state.Groups {
-		space := int64(0)
-		for _, tab := range v.Tablets {
-			space += tab.OnDiskBytes
-		}
-		groups = append(groups, kv{k, space})
+	// Groups sorted by their sizes relative to their targets.
+	groups := s.groupLoads()
+	glog.Infof("\n\nGroups sorted by load: %+v\n\n", groups)
+	numGroups := len(groups)
+
+	// The tablets which are in a group not satisfying their affinity are moved first.
+	if predicate, srcGroup, dstGroup = s.misplacedTablet(groups); len(predicate) > 0 {
+		return
 	}
-	sort.Slice(groups, func(i, j int) bool {
-		return groups[i].size < groups[j].size
-	})
 
-	glog.Infof("\n\nGroups sorted by size: %+v\n\n", groups)
 	for lastGroup := numGroups - 1; lastGroup > 0; lastGroup-- {
-		srcGroup = groups[lastGroup].gid
-		dstGroup = groups[0].gid
-		sizeDiff := groups[lastGroup].size - groups[0].size
-		glog.Infof("size_diff %v\n", sizeDiff)
+		src, dst := groups[lastGroup], groups[0]
+		srcGroup, dstGroup = src.gid, dst.gid
+		loadDiff := src.load() - dst.load()
+		glog.Infof("load_diff %v\n", loadDiff)
 		// Don't move a node unless you receive atleast one update regarding tablet size.
 		// Tablet size would have come up with leader update.
 		if !s.hasLeader(dstGroup) {
 			return
 		}
-		// We move the predicate only if the difference between size of both machines is
-		// atleast 10% of dst group.
-		if float64(sizeDiff) < 0.1*float64(groups[0].size) {
+		// We move the predicate only if the difference between the loads of both groups is
+		// atleast 10% of the load of the dst group.
+		if loadDiff < 0.1*dst.load() {
 			continue
 		}
+		// The largest tablet which can be moved without making dstGroup more loaded than
+		// srcGroup. It's half the size difference if both groups have the same target.
+		maxSize := int64((float64(src.size)*dst.weight - float64(dst.size)*src.weight) /
+			(src.weight + dst.weight))
 
 		// Try to find a predicate which we can move.
 		size := int64(0)
@@ -232,10 +354,14 @@ func (s *Server) chooseTablet() (predicate string, srcGroup uint32, dstGroup uin
 			if x.IsReservedPredicate(tab.Predicate) {
 				continue
 			}
+			// Don't break the affinity of the predicate.
+			if !s.placement.allows(tab.Predicate, s.state.Groups[dstGroup]) {
+				continue
+			}
 
-			// Finds a tablet as big a possible such that on moving it dstGroup's size is
-			// less than or equal to srcGroup.
-			if tab.OnDiskBytes <= sizeDiff/2 && tab.OnDiskBytes > size {
+			// Finds a tablet as big a possible such that on moving it dstGroup's load is
+			// less than or equal to srcGroup's.
+			if tab.OnDiskBytes <= maxSize && tab.OnDiskBytes > size {
 				predicate = tab.Predicate
 				size = tab.OnDiskBytes
 			}
@@ -0,0 +1,262 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxFinishedTasks is the number of finished tasks kept in the membership state.
+	maxFinishedTasks = 100
+	// taskExpiry is how long an unfinished task can go without a report from the node running it,
+	// before it is marked as failed.
+	taskExpiry = 2 * time.Minute
+	// taskHeartbeat is how often the tasks run by Zero are reported while they're running, so that
+	// the long ones aren't expired.
+	taskHeartbeat = taskExpiry / 4
+)
+
+func taskDone(t *pb.Task) bool {
+	switch t.State {
+	case pb.Task_SUCCEEDED, pb.Task_FAILED, pb.Task_CANCELLED:
+		return true
+	}
+	return false
+}
+
+// UpdateTask records the state of a task, as reported by the node running it.
+func (s *Server) UpdateTask(ctx context.Context, t *pb.Task) (*api.Payload, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if t.Id == 0 {
+		return nil, errors.Errorf("Task id must be set")
+	}
+	if err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{Task: t}); err != nil {
+		return nil, err
+	}
+	return &api.Payload{}, nil
+}
+
+// ControlTask requests a task to be paused, resumed or cancelled. The node running the task acts
+// on the request the next time it checks in.
+func (s *Server) ControlTask(ctx context.Context, req *pb.TaskControl) (*pb.Task, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	s.RLock()
+	t := s.state.GetTasks()[req.Id]
+	s.RUnlock()
+	switch {
+	case t == nil:
+		return nil, errors.Errorf("Task %#x not found", req.Id)
+	case taskDone(t):
+		return nil, errors.Errorf("Task %#x has already finished", req.Id)
+	case req.Op == pb.TaskControl_CANCEL && !t.Cancellable:
+		return nil, errors.Errorf("Task %#x of kind %s can't be cancelled", req.Id, t.Kind)
+	case req.Op != pb.TaskControl_CANCEL && !t.Pausable:
+		return nil, errors.Errorf("Task %#x of kind %s can't be paused", req.Id, t.Kind)
+	}
+
+	glog.Infof("Got request to %s task %#x of kind %s", req.Op, req.Id, t.Kind)
+	if err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{TaskControl: req}); err != nil {
+		return nil, err
+	}
+	s.RLock()
+	defer s.RUnlock()
+	return proto.Clone(s.state.GetTasks()[req.Id]).(*pb.Task), nil
+}
+
+func (n *node) handleTaskProposal(t *pb.Task) {
+	state := n.server.state
+	if state.Tasks == nil {
+		state.Tasks = make(map[uint64]*pb.Task)
+	}
+	if cur, ok := state.Tasks[t.Id]; ok {
+		if taskDone(cur) {
+			// Late reports for a task which has finished, or which was expired, are ignored.
+			return
+		}
+		// The requests to pause or cancel are made via TaskControl, not by the node running the
+		// task.
+		t.PauseRequested = cur.PauseRequested
+		t.CancelRequested = cur.CancelRequested
+	}
+	state.Tasks[t.Id] = t
+	if taskDone(t) {
+		pruneTasks(state.Tasks)
+	}
+}
+
+func (n *node) handleTaskControl(ctl *pb.TaskControl) {
+	t := n.server.state.GetTasks()[ctl.Id]
+	if t == nil || taskDone(t) {
+		return
+	}
+	switch ctl.Op {
+	case pb.TaskControl_PAUSE:
+		t.PauseRequested = true
+	case pb.TaskControl_RESUME:
+		t.PauseRequested = false
+	case pb.TaskControl_CANCEL:
+		t.CancelRequested = true
+	}
+}
+
+// pruneTasks removes the oldest finished tasks, keeping at most maxFinishedTasks of them.
+func pruneTasks(tasks map[uint64]*pb.Task) {
+	var done []*pb.Task
+	for _, t := range tasks {
+		if taskDone(t) {
+			done = append(done, t)
+		}
+	}
+	if len(done) <= maxFinishedTasks {
+		return
+	}
+	sort.Slice(done, func(i, j int) bool { return done[i].UpdatedAt > done[j].UpdatedAt })
+	for _, t := range done[maxFinishedTasks:] {
+		delete(tasks, t.Id)
+	}
+}
+
+// expireTasks marks the unfinished tasks whose node stopped reporting as failed, so that tasks
+// interrupted by a crash or a restart don't look like they are still running.
+func (s *Server) expireTasks() {
+	ticker := time.NewTicker(taskExpiry / 4)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !s.Node.AmLeader() {
+			continue
+		}
+		var expired []*pb.Task
+		deadline := time.Now().Add(-taskExpiry).Unix()
+		s.RLock()
+		for _, t := range s.state.GetTasks() {
+			if !taskDone(t) && t.UpdatedAt < deadline {
+				t = proto.Clone(t).(*pb.Task)
+				t.State = pb.Task_FAILED
+				t.Message = "Lost contact with the node running the task"
+				t.UpdatedAt = time.Now().Unix()
+				expired = append(expired, t)
+			}
+		}
+		s.RUnlock()
+
+		for _, t := range expired {
+			glog.Warningf("Task %#x of kind %s on node %#x expired", t.Id, t.Kind, t.Owner)
+			if err := s.Node.proposeAndWait(context.Background(),
+				&pb.ZeroProposal{Task: t}); err != nil {
+				glog.Errorf("While expiring task %#x: %v", t.Id, err)
+			}
+		}
+	}
+}
+
+// zeroTask is a task run by Zero itself, like a predicate move.
+type zeroTask struct {
+	sync.Mutex
+	s    *Server
+	task *pb.Task
+	done chan struct{}
+}
+
+func (s *Server) startTask(kind, description string, cancellable bool) *zeroTask {
+	now := time.Now().Unix()
+	t := &zeroTask{s: s, done: make(chan struct{}), task: &pb.Task{
+		Id:          rand.Uint64(),
+		Kind:        kind,
+		Description: description,
+		Owner:       s.Node.Id,
+		State:       pb.Task_RUNNING,
+		StartedAt:   now,
+		UpdatedAt:   now,
+		Cancellable: cancellable,
+	}}
+	t.report()
+	go t.heartbeat()
+	return t
+}
+
+// heartbeat reports the task until it finishes, so that it isn't expired while it's running.
+func (t *zeroTask) heartbeat() {
+	ticker := time.NewTicker(taskHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			t.report()
+		}
+	}
+}
+
+func (t *zeroTask) progress(progress float64, message string) {
+	t.Lock()
+	t.task.Progress = progress
+	t.task.Message = message
+	t.Unlock()
+	t.report()
+}
+
+func (t *zeroTask) finish(err error) {
+	close(t.done)
+	t.Lock()
+	if err != nil {
+		t.task.State = pb.Task_FAILED
+		t.task.Message = err.Error()
+	} else {
+		t.task.State = pb.Task_SUCCEEDED
+		t.task.Progress = 1
+		t.task.Message = ""
+	}
+	t.Unlock()
+	t.report()
+}
+
+// cancelRequested returns whether the task has been asked to be cancelled.
+func (t *zeroTask) cancelRequested() bool {
+	t.s.RLock()
+	defer t.s.RUnlock()
+	return t.s.state.GetTasks()[t.task.Id].GetCancelRequested()
+}
+
+// report records the task. Failing to do so doesn't fail the task.
+func (t *zeroTask) report() {
+	t.Lock()
+	t.task.UpdatedAt = time.Now().Unix()
+	task := proto.Clone(t.task).(*pb.Task)
+	t.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := t.s.Node.proposeAndWait(ctx, &pb.ZeroProposal{Task: task}); err != nil {
+		glog.Warningf("While recording task %#x of kind %s: %v", task.Id, task.Kind, err)
+	}
+}
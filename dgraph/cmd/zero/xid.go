@@ -0,0 +1,176 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/pkg/errors"
+	otrace "go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errXidRegistryDisabled = errors.New("xid registry is disabled. Start Zero with " +
+	"--xid_registry to enable it")
+
+// AssignUidForXid returns the UIDs assigned to the given external IDs within a namespace. External
+// IDs which have not been seen before are assigned new UIDs, which are recorded via Raft so that
+// every loader or upsert asking for the same xid gets the same UID back, without any locking on
+// the client side.
+func (s *Server) AssignUidForXid(ctx context.Context, req *pb.XidRequest) (*pb.XidMap, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if !opts.xidRegistry {
+		return nil, errXidRegistryDisabled
+	}
+	ctx, span := otrace.StartSpan(ctx, "Zero.AssignUidForXid")
+	defer span.End()
+
+	if !s.Node.AmLeader() {
+		// Just like AssignIds, only the leader hands out UIDs. Forward the request to it.
+		if req.Forwarded {
+			return nil, errors.Errorf("Invalid Zero received AssignUidForXid request forward. " +
+				"Please retry")
+		}
+		pl := s.Leader(0)
+		if pl == nil {
+			return nil, errors.Errorf("No healthy connection found to Leader of group zero")
+		}
+		span.Annotatef(nil, "Sending request to %v", pl.Addr)
+		zc := pb.NewZeroClient(pl.Get())
+		req.Forwarded = true
+		return zc.AssignUidForXid(ctx, req)
+	}
+
+	// Serialize assignments, so that two concurrent requests for the same new xid don't end up
+	// leasing different UIDs for it.
+	s.xidLock.Lock()
+	defer s.xidLock.Unlock()
+
+	missing, err := s.newXids(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		span.Annotatef(nil, "Assigning UIDs for %d new xids", len(missing))
+		ids, err := s.lease(ctx, &pb.Num{Val: uint64(len(missing)), Type: pb.Num_UID})
+		if err != nil {
+			return nil, err
+		}
+		assigned := &pb.XidAssignment{
+			Namespace: req.Namespace,
+			Uids:      make(map[string]uint64, len(missing)),
+		}
+		for i, xid := range missing {
+			assigned.Uids[xid] = ids.StartId + uint64(i)
+		}
+		if err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{Xids: assigned}); err != nil {
+			return nil, err
+		}
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+	out := &pb.XidMap{Uids: make(map[string]uint64, len(req.Xids))}
+	for _, xid := range req.Xids {
+		uid, ok := s.xids[req.Namespace][xid]
+		if !ok {
+			return nil, errors.Errorf("Unable to find UID assigned to xid: %q", xid)
+		}
+		out.Uids[xid] = uid
+	}
+	return out, nil
+}
+
+// newXids returns the xids of the request which don't have a UID yet. It fails if the registry of
+// the namespace can't hold them.
+func (s *Server) newXids(req *pb.XidRequest) ([]string, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	var missing []string
+	seen := make(map[string]struct{})
+	for _, xid := range req.Xids {
+		if len(xid) == 0 {
+			return nil, errors.Errorf("Empty xid is not allowed")
+		}
+		if _, ok := s.xids[req.Namespace][xid]; ok {
+			continue
+		}
+		if _, ok := seen[xid]; ok {
+			continue
+		}
+		seen[xid] = struct{}{}
+		missing = append(missing, xid)
+	}
+	if n := len(s.xids[req.Namespace]) + len(missing); n > opts.xidRegistryLimit {
+		return nil, status.Errorf(codes.ResourceExhausted, "xid registry of namespace %#x "+
+			"would hold %d xids, over the limit of %d set by --xid_registry_limit",
+			req.Namespace, n, opts.xidRegistryLimit)
+	}
+	return missing, nil
+}
+
+// handleXidProposal records the xid assignments in the registry. An xid which already has a UID
+// keeps it, so that the first assignment always wins. The caller must hold the server lock.
+func (n *node) handleXidProposal(assigned *pb.XidAssignment) {
+	s := n.server
+	m, ok := s.xids[assigned.Namespace]
+	if !ok {
+		m = make(map[string]uint64, len(assigned.Uids))
+		s.xids[assigned.Namespace] = m
+	}
+	for xid, uid := range assigned.Uids {
+		if _, ok := m[xid]; !ok {
+			m[xid] = uid
+		}
+	}
+}
+
+// xidSnapshot returns a copy of the xid registry, to be stored along with the Zero snapshot.
+func (s *Server) xidSnapshot() map[uint64]*pb.XidMap {
+	s.RLock()
+	defer s.RUnlock()
+	if len(s.xids) == 0 {
+		return nil
+	}
+	out := make(map[uint64]*pb.XidMap, len(s.xids))
+	for ns, m := range s.xids {
+		uids := make(map[string]uint64, len(m))
+		for xid, uid := range m {
+			uids[xid] = uid
+		}
+		out[ns] = &pb.XidMap{Uids: uids}
+	}
+	return out
+}
+
+// setXidSnapshot replaces the xid registry with the one found in a Zero snapshot.
+func (s *Server) setXidSnapshot(xids map[uint64]*pb.XidMap) {
+	s.Lock()
+	defer s.Unlock()
+	s.xids = make(map[uint64]map[string]uint64, len(xids))
+	for ns, m := range xids {
+		if m.GetUids() == nil {
+			continue
+		}
+		s.xids[ns] = m.Uids
+	}
+}
@@ -56,6 +56,7 @@ type Server struct {
 	orc  *Oracle
 
 	NumReplicas int
+	placement   *placement
 	state       *pb.MembershipState
 	nextRaftId  uint64
 
@@ -73,9 +74,19 @@ type Server struct {
 	tlsClientConfig *tls.Config
 
 	moveOngoing    chan struct{}
+	moveBlocks     map[string]time.Time // Block id -> expiry, used to stop predicate moves.
+	groupRemovals  map[uint32]struct{}  // Groups whose removal is run by this node.
 	blockCommitsOn *sync.Map
 
 	checkpointPerGroup map[uint32]uint64
+	observed           map[pb.NumLeaseType]uint64 // Max timestamps used by Alphas.
+	pendingLease       *leaseAdjust               // Lease advance waiting for confirmation.
+	clockSkew          map[uint64]int64           // Raft ID -> clock skew in ms.
+
+	xids    map[uint64]map[string]uint64 // Namespace -> xid -> uid.
+	xidLock sync.Mutex                   // Serializes xid assignments on the leader.
+
+	erasures *pb.ErasureState // The markers of the erasures, and the restores since.
 }
 
 // Init initializes the zero server.
@@ -90,6 +101,8 @@ func (s *Server) Init() {
 		Zeros:  make(map[uint64]*pb.Member),
 	}
 	s.nextLease = make(map[pb.NumLeaseType]uint64)
+	s.xids = make(map[uint64]map[string]uint64)
+	s.erasures = &pb.ErasureState{}
 	s.nextRaftId = 1
 	s.nextLease[pb.Num_UID] = 1
 	s.nextLease[pb.Num_TXN_TS] = 1
@@ -99,9 +112,16 @@ func (s *Server) Init() {
 	s.closer = z.NewCloser(2) // grpc and http
 	s.blockCommitsOn = new(sync.Map)
 	s.moveOngoing = make(chan struct{}, 1)
+	s.moveBlocks = make(map[string]time.Time)
+	s.groupRemovals = make(map[uint32]struct{})
 	s.checkpointPerGroup = make(map[uint32]uint64)
+	s.observed = make(map[pb.NumLeaseType]uint64)
+	s.clockSkew = make(map[uint64]int64)
 
 	go s.rebalanceTablets()
+	go s.expireTasks()
+	go s.resumeGroupRemovals()
+	go s.recordTabletSizes()
 }
 
 func (s *Server) periodicallyPostTelemetry() {
@@ -230,6 +250,9 @@ func (s *Server) SetMembershipState(state *pb.MembershipState) {
 
 	s.state = state
 	s.nextRaftId = x.Max(s.nextRaftId, s.state.MaxRaftId+1)
+	if state.Replicas > 0 {
+		s.NumReplicas = int(state.Replicas)
+	}
 
 	if state.Zeros == nil {
 		state.Zeros = make(map[uint64]*pb.Member)
@@ -265,6 +288,17 @@ func (s *Server) membershipState() *pb.MembershipState {
 	return proto.Clone(s.state).(*pb.MembershipState)
 }
 
+// checkpoints returns a copy of the checkpoint timestamps reported by the groups.
+func (s *Server) checkpoints() map[uint32]uint64 {
+	s.RLock()
+	defer s.RUnlock()
+	res := make(map[uint32]uint64, len(s.checkpointPerGroup))
+	for gid, ts := range s.checkpointPerGroup {
+		res[gid] = ts
+	}
+	return res
+}
+
 func (s *Server) groupChecksums() map[uint32]uint64 {
 	s.RLock()
 	defer s.RUnlock()
@@ -361,7 +395,9 @@ func (s *Server) createProposals(dst *pb.Group) ([]*pb.ZeroProposal, error) {
 			return res, errors.Errorf("Unknown member: %+v", dstMember)
 		}
 		if srcMember.Addr != dstMember.Addr ||
-			srcMember.Leader != dstMember.Leader {
+			srcMember.Leader != dstMember.Leader ||
+			srcMember.GrpcAddr != dstMember.GrpcAddr ||
+			!sameTags(srcMember.Tags, dstMember.Tags) {
 
 			proposal := &pb.ZeroProposal{
 				Member: dstMember,
@@ -402,6 +438,18 @@ func (s *Server) createProposals(dst *pb.Group) ([]*pb.ZeroProposal, error) {
 	return res, nil
 }
 
+func sameTags(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
 // removeNode removes the given node from the given group.
 // It's the user's responsibility to ensure that node doesn't come back again
 // before calling the api.
@@ -453,6 +501,7 @@ func (s *Server) Connect(ctx context.Context,
 	if m.Addr == "" {
 		return &emptyConnectionState, errors.Errorf("NO_ADDR: No address provided: %+v", m)
 	}
+	m.Addr = x.NormalizeAddress(m.Addr)
 
 	for _, member := range ms.Removed {
 		// It is not recommended to reuse RAFT ids.
@@ -552,13 +601,27 @@ func (s *Server) Connect(ctx context.Context,
 			}
 			// Already have plenty of servers serving this group.
 		}
-		// Let's assign this server to a new group.
+		// Let's assign this server to a new group. Prefer the groups which don't have a replica
+		// with the same value of the spread tag yet.
+		var crowdedGroup uint32
 		for gid, group := range s.state.Groups {
-			if len(group.Members) < s.NumReplicas {
-				m.GroupId = gid
-				proposal.Member = m
-				return proposal
+			if group.Removing || len(group.Members) >= s.NumReplicas {
+				continue
+			}
+			if s.placement.crowded(group, m) {
+				crowdedGroup = gid
+				continue
 			}
+			m.GroupId = gid
+			proposal.Member = m
+			return proposal
+		}
+		if crowdedGroup > 0 {
+			glog.Warningf("Unable to spread the replicas of group %d by %s. Adding %+v anyway.",
+				crowdedGroup, s.placement.spread, m)
+			m.GroupId = crowdedGroup
+			proposal.Member = m
+			return proposal
 		}
 		// We either don't have any groups, or don't have any groups which need another member.
 		m.GroupId = s.nextGroup
@@ -631,6 +694,8 @@ func (s *Server) ShouldServe(
 		// This will also make it easier to restore the reserved predicates after
 		// a DropAll operation.
 		tablet.GroupId = 1
+	} else if !tablet.Force {
+		s.placeTablet(tablet)
 	}
 	proposal.Tablet = tablet
 	if err := s.Node.proposeAndWait(ctx, &proposal); err != nil && err != errTabletAlreadyServed {
@@ -643,9 +708,29 @@ func (s *Server) ShouldServe(
 	return tab, nil
 }
 
+// placeTablet moves a new tablet to another group if the one asking for it is being removed, or
+// doesn't satisfy the affinity of its predicate.
+func (s *Server) placeTablet(tablet *pb.Tablet) {
+	s.RLock()
+	defer s.RUnlock()
+	removing := s.state.Groups[tablet.GroupId].GetRemoving()
+	if !removing && s.placement.allows(tablet.Predicate, s.state.Groups[tablet.GroupId]) {
+		return
+	}
+	if gid := s.allowedGroup(tablet.Predicate, s.groupLoads()); gid > 0 {
+		glog.Infof("Placing predicate %s in group %d instead of %d for its affinity",
+			tablet.Predicate, gid, tablet.GroupId)
+		tablet.GroupId = gid
+	}
+}
+
 // UpdateMembership updates the membership of the given group.
 func (s *Server) UpdateMembership(ctx context.Context, group *pb.Group) (*api.Payload, error) {
 	// Only Zero leader would get these membership updates.
+	if err := x.SetClockHeader(ctx); err != nil {
+		glog.V(2).Infof("Unable to send clock header: %v", err)
+	}
+	s.recordClockSkew(group)
 	if ts := group.GetCheckpointTs(); ts > 0 {
 		for _, m := range group.GetMembers() {
 			s.Lock()
@@ -653,6 +738,7 @@ func (s *Server) UpdateMembership(ctx context.Context, group *pb.Group) (*api.Pa
 			s.Unlock()
 		}
 	}
+	observed := s.observeGroup(group)
 	proposals, err := s.createProposals(group)
 	if err != nil {
 		// Sleep here so the caller doesn't keep on retrying indefinitely, creating a busy
@@ -661,6 +747,9 @@ func (s *Server) UpdateMembership(ctx context.Context, group *pb.Group) (*api.Pa
 		glog.Errorf("Error while creating proposals in Update: %v\n", err)
 		return nil, err
 	}
+	if observed != nil {
+		proposals = append(proposals, observed)
+	}
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -805,11 +894,11 @@ func (s *Server) applyLicense(ctx context.Context, signedData io.Reader) error {
 	}
 
 	proposal := &pb.ZeroProposal{
-		License: &pb.License{
+		License: withLicenseStatus(&pb.License{
 			User:     l.User,
 			MaxNodes: l.MaxNodes,
 			ExpiryTs: l.Expiry.Unix(),
-		},
+		}),
 	}
 
 	err := s.Node.proposeAndWait(ctx, proposal)
@@ -18,9 +18,15 @@ package zero
 
 import (
 	"context"
+	"math"
 	"testing"
+	"time"
 
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/conn"
 	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
 	"github.com/stretchr/testify/require"
 )
 
@@ -35,3 +41,355 @@ func TestRemoveNode(t *testing.T) {
 	err = server.removeNode(context.TODO(), 1, 2)
 	require.Error(t, err)
 }
+
+func TestXidRegistry(t *testing.T) {
+	server := &Server{xids: make(map[uint64]map[string]uint64)}
+	n := &node{server: server}
+
+	n.handleXidProposal(&pb.XidAssignment{Namespace: 0, Uids: map[string]uint64{"a": 1, "b": 2}})
+	// The first assignment for an xid wins.
+	n.handleXidProposal(&pb.XidAssignment{Namespace: 0, Uids: map[string]uint64{"a": 5, "c": 6}})
+	n.handleXidProposal(&pb.XidAssignment{Namespace: 1, Uids: map[string]uint64{"a": 7}})
+	require.Equal(t, map[string]uint64{"a": 1, "b": 2, "c": 6}, server.xids[0])
+	require.Equal(t, map[string]uint64{"a": 7}, server.xids[1])
+
+	// Only the new xids need UIDs, as long as the registry of the namespace can hold them.
+	defer func(limit int) { opts.xidRegistryLimit = limit }(opts.xidRegistryLimit)
+	opts.xidRegistryLimit = 4
+	missing, err := server.newXids(&pb.XidRequest{Namespace: 0, Xids: []string{"a", "d", "d"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"d"}, missing)
+	_, err = server.newXids(&pb.XidRequest{Namespace: 0, Xids: []string{"d", "e"}})
+	require.Error(t, err)
+	_, err = server.newXids(&pb.XidRequest{Namespace: 0, Xids: []string{""}})
+	require.Error(t, err)
+
+	snap := server.xidSnapshot()
+	restored := &Server{}
+	restored.setXidSnapshot(snap)
+	require.Equal(t, server.xids, restored.xids)
+}
+
+func TestErasureProposals(t *testing.T) {
+	server := &Server{erasures: &pb.ErasureState{}}
+	n := &node{server: server}
+
+	m := &pb.ErasureMarker{Report: `{"subject":"0x1"}`, Signature: "sig"}
+	n.handleErasureProposal(&pb.ErasureUpdate{Marker: m})
+	// A marker recorded twice is kept once.
+	n.handleErasureProposal(&pb.ErasureUpdate{Marker: m})
+	require.Len(t, server.erasures.Markers, 1)
+
+	n.handleErasureProposal(&pb.ErasureUpdate{Restored: true})
+	n.handleErasureProposal(&pb.ErasureUpdate{Restored: true})
+	require.Equal(t, uint64(2), server.erasures.Restores)
+	// The erasures can't be applied again after restores which didn't happen, nor before the
+	// ones they were already applied after.
+	n.handleErasureProposal(&pb.ErasureUpdate{Reapplied: 3})
+	require.Equal(t, uint64(0), server.erasures.Reapplied)
+	n.handleErasureProposal(&pb.ErasureUpdate{Reapplied: 2})
+	n.handleErasureProposal(&pb.ErasureUpdate{Reapplied: 1})
+	require.Equal(t, uint64(2), server.erasures.Reapplied)
+
+	snap := server.erasureSnapshot()
+	restored := &Server{}
+	restored.setErasureSnapshot(snap)
+	require.Equal(t, server.erasures, restored.erasures)
+	restored.setErasureSnapshot(nil)
+	require.NotNil(t, restored.erasures)
+}
+
+func TestLeaseAdvanceValidation(t *testing.T) {
+	server := &Server{
+		Node:     &node{Node: &conn.Node{}},
+		state:    &pb.MembershipState{MaxUID: 100, MaxTxnTs: 50, ObservedUid: 300},
+		observed: make(map[pb.NumLeaseType]uint64),
+	}
+	// The ids already recorded in the state don't need to be proposed again.
+	require.Nil(t, server.observeGroup(&pb.Group{MaxUid: 200}))
+	p := server.observeGroup(&pb.Group{MaxUid: 500, MaxNsid: 2, CheckpointTs: 40, SnapshotTs: 30})
+	require.Equal(t, &pb.ZeroProposal{ObservedUid: 500, ObservedNsId: 2}, p)
+	// The proposal isn't applied yet.
+	require.Equal(t, uint64(300), server.leaseState(pb.Num_UID).Observed)
+	server.state.ObservedUid = p.ObservedUid
+
+	// Leases can only move forward.
+	require.Error(t, server.validateLeaseAdvance(pb.Num_UID, 100))
+	// They can't stay below what the Alphas have already used.
+	require.Error(t, server.validateLeaseAdvance(pb.Num_UID, 200))
+	require.NoError(t, server.validateLeaseAdvance(pb.Num_UID, 500))
+	require.Error(t, server.validateLeaseAdvance(pb.Num_TXN_TS, 30))
+	require.NoError(t, server.validateLeaseAdvance(pb.Num_TXN_TS, 60))
+
+	// Only the leader can prepare an advance, since it's the one applying it.
+	_, err := server.prepareLeaseAdvance(pb.Num_UID, 1000)
+	require.Error(t, err)
+	require.Nil(t, server.pendingLease)
+}
+
+func TestClockSkew(t *testing.T) {
+	server := &Server{clockSkew: make(map[uint64]int64)}
+	server.recordClockSkew(&pb.Group{
+		Members: map[uint64]*pb.Member{1: {Id: 1, GroupId: 1, ClockSkewMs: -1500}},
+	})
+
+	state := &pb.MembershipState{Groups: map[uint32]*pb.Group{1: {
+		Members: map[uint64]*pb.Member{1: {Id: 1, GroupId: 1}, 2: {Id: 2, GroupId: 1}},
+	}}}
+	server.fillClockSkew(state)
+	require.Equal(t, int64(-1500), state.Groups[1].Members[1].ClockSkewMs)
+	require.Zero(t, state.Groups[1].Members[2].ClockSkewMs)
+}
+
+func TestMoveBlocks(t *testing.T) {
+	server := &Server{moveBlocks: make(map[string]time.Time)}
+	require.Empty(t, server.moveBlockedBy())
+
+	server.moveBlocks["expired"] = time.Now().Add(-time.Second)
+	require.Empty(t, server.moveBlockedBy())
+	require.NotContains(t, server.moveBlocks, "expired")
+
+	server.moveBlocks["export"] = time.Now().Add(time.Minute)
+	require.Equal(t, "export", server.moveBlockedBy())
+}
+
+func TestTaskProposals(t *testing.T) {
+	server := &Server{state: &pb.MembershipState{}}
+	n := &node{server: server}
+
+	n.handleTaskProposal(&pb.Task{Id: 1, State: pb.Task_RUNNING, Pausable: true})
+	n.handleTaskControl(&pb.TaskControl{Id: 1, Op: pb.TaskControl_PAUSE})
+	require.True(t, server.state.Tasks[1].PauseRequested)
+
+	// Reports from the node running the task keep the requests made via TaskControl.
+	n.handleTaskProposal(&pb.Task{Id: 1, State: pb.Task_PAUSED, Progress: 0.5})
+	require.True(t, server.state.Tasks[1].PauseRequested)
+	n.handleTaskControl(&pb.TaskControl{Id: 1, Op: pb.TaskControl_RESUME})
+	require.False(t, server.state.Tasks[1].PauseRequested)
+
+	// Finished tasks don't change anymore.
+	n.handleTaskProposal(&pb.Task{Id: 1, State: pb.Task_SUCCEEDED, Progress: 1})
+	n.handleTaskProposal(&pb.Task{Id: 1, State: pb.Task_FAILED})
+	require.Equal(t, pb.Task_SUCCEEDED, server.state.Tasks[1].State)
+
+	// Only the most recent finished tasks are kept.
+	for i := 0; i < maxFinishedTasks+10; i++ {
+		n.handleTaskProposal(&pb.Task{Id: uint64(i + 10), State: pb.Task_FAILED,
+			UpdatedAt: int64(i + 10)})
+	}
+	n.handleTaskProposal(&pb.Task{Id: 2, State: pb.Task_RUNNING})
+	require.Len(t, server.state.Tasks, maxFinishedTasks+1)
+	require.Contains(t, server.state.Tasks, uint64(2))
+	require.NotContains(t, server.state.Tasks, uint64(1))
+}
+
+func TestOracleState(t *testing.T) {
+	var o Oracle
+	o.Init()
+	o.maxAssigned = 20
+	o.commits[12] = 15
+	o.commits[10] = 0
+
+	done := o.trackCommit(14)
+	st := o.state(false)
+	require.Equal(t, uint64(20), st.MaxAssigned)
+	require.Equal(t, 2, st.NumTxns)
+	require.Empty(t, st.Txns)
+	require.Len(t, st.Committing, 1)
+	require.Equal(t, uint64(14), st.Committing[0].StartTs)
+
+	done()
+	st = o.state(true)
+	require.Empty(t, st.Committing)
+	require.Equal(t, []*pb.TxnStatus{{StartTs: 10}, {StartTs: 12, CommitTs: 15}}, st.Txns)
+
+	require.Equal(t, "txn", proposalKind(&pb.ZeroProposal{Txn: &api.TxnContext{}}))
+	require.Equal(t, "lease", proposalKind(&pb.ZeroProposal{MaxTxnTs: 100}))
+	require.Equal(t, "timestamps", leaseName(pb.Num_TXN_TS))
+}
+
+func TestPlacement(t *testing.T) {
+	parse := func(flag string) (*placement, error) {
+		return parsePlacement(z.NewSuperFlag(flag).MergeAndCheckDefault(placementDefaults))
+	}
+	p, err := parse("affinity=name:disk:nvme,<http://ex.org/friend>:disk:nvme; spread=zone; " +
+		"target-size=1:2GB,2:1GB")
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]string{
+		"name":                   {"disk": "nvme"},
+		"<http://ex.org/friend>": {"disk": "nvme"},
+	}, p.affinity)
+	require.Equal(t, map[uint32]int64{1: 2e9, 2: 1e9}, p.targetSize)
+	for _, flag := range []string{"affinity=name:disk", "target-size=1", "target-size=0:1GB",
+		"target-size=1:lots"} {
+		_, err := parse(flag)
+		require.Error(t, err, flag)
+	}
+
+	member := func(id uint64, tags map[string]string) *pb.Member {
+		return &pb.Member{Id: id, Leader: id%2 == 1, Tags: tags}
+	}
+	nvme := map[string]string{"disk": "nvme", "zone": "a"}
+	ssd := map[string]string{"disk": "ssd", "zone": "b"}
+	server := &Server{
+		placement: p,
+		state: &pb.MembershipState{Groups: map[uint32]*pb.Group{
+			1: {
+				Members: map[uint64]*pb.Member{1: member(1, ssd)},
+				Tablets: map[string]*pb.Tablet{
+					x.NamespaceAttr(2, "name"): {OnDiskBytes: 100},
+					x.GalaxyAttr("age"):        {OnDiskBytes: 1000},
+				},
+			},
+			2: {
+				Members: map[uint64]*pb.Member{3: member(3, nvme), 4: member(4, ssd)},
+			},
+			3: {
+				Members: map[uint64]*pb.Member{5: member(5, nvme)},
+				Tablets: map[string]*pb.Tablet{x.GalaxyAttr("friend"): {OnDiskBytes: 800}},
+			},
+		}},
+	}
+
+	// The affinity applies to the predicate in every namespace, and needs all the members.
+	groups := server.state.Groups
+	require.False(t, p.allows(x.NamespaceAttr(2, "name"), groups[1]))
+	require.False(t, p.allows(x.GalaxyAttr("name"), groups[2]))
+	require.True(t, p.allows(x.GalaxyAttr("name"), groups[3]))
+	require.True(t, p.allows(x.GalaxyAttr("age"), groups[1]))
+	require.NoError(t, server.canServe(x.GalaxyAttr("age"), 2))
+	require.Error(t, server.canServe(x.GalaxyAttr("name"), 4))
+
+	// The replicas are spread by zone.
+	require.True(t, p.crowded(groups[1], member(6, map[string]string{"zone": "b"})))
+	require.False(t, p.crowded(groups[1], member(6, map[string]string{"zone": "a"})))
+	require.False(t, p.crowded(groups[1], member(6, nil)))
+
+	// Group 3 has no target, so it gets the average one.
+	server.RLock()
+	loads := server.groupLoads()
+	require.Equal(t, []uint32{2, 3, 1}, []uint32{loads[0].gid, loads[1].gid, loads[2].gid})
+	require.Equal(t, 1.5e9, loads[1].weight)
+
+	// The misplaced tablet moves to the least loaded group which satisfies its affinity.
+	pred, src, dst := server.misplacedTablet(loads)
+	server.RUnlock()
+	require.Equal(t, x.NamespaceAttr(2, "name"), pred)
+	require.Equal(t, uint32(1), src)
+	require.Equal(t, uint32(3), dst)
+
+	tablet := &pb.Tablet{GroupId: 2, Predicate: x.GalaxyAttr("name")}
+	server.placeTablet(tablet)
+	require.Equal(t, uint32(3), tablet.GroupId)
+}
+
+func TestRemovingGroup(t *testing.T) {
+	member := &pb.Member{Id: 1, Leader: true}
+	server := &Server{
+		state: &pb.MembershipState{Groups: map[uint32]*pb.Group{
+			1: {Members: map[uint64]*pb.Member{1: member}},
+			2: {Members: map[uint64]*pb.Member{2: member}, Removing: true},
+			3: {},
+		}},
+	}
+
+	// Neither the group being removed nor the one without members can take tablets.
+	server.RLock()
+	loads := server.groupLoads()
+	server.RUnlock()
+	require.Len(t, loads, 1)
+	require.Equal(t, uint32(1), loads[0].gid)
+
+	tablet := &pb.Tablet{GroupId: 2, Predicate: x.GalaxyAttr("name")}
+	server.placeTablet(tablet)
+	require.Equal(t, uint32(1), tablet.GroupId)
+	require.Error(t, server.canServe(x.GalaxyAttr("name"), 2))
+	require.NoError(t, server.canServe(x.GalaxyAttr("name"), 1))
+}
+
+func TestExtraMembers(t *testing.T) {
+	group := &pb.Group{Members: map[uint64]*pb.Member{
+		1: {Id: 1},
+		2: {Id: 2, Leader: true},
+		3: {Id: 3},
+		4: {Id: 4, Learner: true},
+	}}
+	ids := func(members []*pb.Member) []uint64 {
+		var ids []uint64
+		for _, m := range members {
+			ids = append(ids, m.Id)
+		}
+		return ids
+	}
+
+	// Neither the leader nor the learners are removed, and the newest members go first.
+	require.Empty(t, extraMembers(group, 3))
+	require.Equal(t, []uint64{3, 1}, ids(extraMembers(group, 1)))
+
+	// Without a leader, any member can be removed.
+	group.Members[2].Leader = false
+	require.Equal(t, []uint64{3, 2}, ids(extraMembers(group, 1)))
+
+	server := &Server{NumReplicas: 3, state: &pb.MembershipState{}}
+	require.False(t, server.replicasChanged(3))
+	require.True(t, server.replicasChanged(1))
+}
+
+func TestLeaseBatch(t *testing.T) {
+	batch := &pb.NumBatch{Nums: []*pb.Num{
+		{Val: 10, Type: pb.Num_UID},
+		{Val: 2, Type: pb.Num_NS_ID},
+		{Val: 5, Type: pb.Num_UID},
+	}}
+	totals, err := validateBatch(batch)
+	require.NoError(t, err)
+	require.Equal(t, map[pb.NumLeaseType]uint64{pb.Num_UID: 15, pb.Num_NS_ID: 2}, totals)
+
+	// Every range is carved out of the single lease of its type, in order.
+	ids := make([]*pb.AssignedIds, len(batch.Nums))
+	splitBatch(batch, pb.Num_UID, &pb.AssignedIds{StartId: 100, EndId: 114}, ids)
+	splitBatch(batch, pb.Num_NS_ID, &pb.AssignedIds{StartId: 7, EndId: 8}, ids)
+	require.Equal(t, []*pb.AssignedIds{
+		{StartId: 100, EndId: 109},
+		{StartId: 7, EndId: 8},
+		{StartId: 110, EndId: 114},
+	}, ids)
+
+	_, err = validateBatch(&pb.NumBatch{})
+	require.Error(t, err)
+	_, err = validateBatch(&pb.NumBatch{Nums: []*pb.Num{{Val: 1, Type: pb.Num_TXN_TS}}})
+	require.Error(t, err)
+	_, err = validateBatch(&pb.NumBatch{Nums: []*pb.Num{{Type: pb.Num_UID}}})
+	require.Error(t, err)
+	_, err = validateBatch(&pb.NumBatch{Nums: []*pb.Num{
+		{Val: math.MaxUint64, Type: pb.Num_UID}, {Val: 1, Type: pb.Num_UID}}})
+	require.Error(t, err)
+}
+
+func TestLicenseStatus(t *testing.T) {
+	defer func(old options) { opts = old }(opts)
+	expiry := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	status := func(now time.Time) []bool {
+		enabled, expired := licenseStatus(expiry, now)
+		return []bool{enabled, expired}
+	}
+
+	opts.licenseExpiry = "degrade"
+	require.Equal(t, []bool{true, false}, status(expiry.Add(-time.Second)))
+	require.Equal(t, []bool{false, true}, status(expiry))
+
+	opts.licenseExpiry = "warn"
+	opts.licenseGrace = 24 * time.Hour
+	require.Equal(t, []bool{true, false}, status(expiry.Add(-time.Second)))
+	require.Equal(t, []bool{true, true}, status(expiry))
+	require.Equal(t, []bool{true, true}, status(expiry.Add(23*time.Hour)))
+	require.Equal(t, []bool{false, true}, status(expiry.Add(24*time.Hour)))
+}
+
+func TestSameLicense(t *testing.T) {
+	l := &pb.License{User: "alice", MaxNodes: 3, ExpiryTs: 100}
+	require.True(t, sameLicense(l, &pb.License{User: "alice", MaxNodes: 3, ExpiryTs: 100,
+		Enabled: true, Expired: true}))
+	require.False(t, sameLicense(l, &pb.License{User: "alice", MaxNodes: 4, ExpiryTs: 100}))
+	require.False(t, sameLicense(nil, l))
+}
@@ -0,0 +1,252 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dql exposes the DQL parser for tools outside of Dgraph, like editors and CI checks. It
+// wraps the gql package behind a small API, and adds a linter and a formatter whose findings are
+// reported as diagnostics carrying the line and column they refer to.
+package dql
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/gql"
+)
+
+// Result is the parsed form of a query.
+type Result = gql.Result
+
+// GraphQuery is a single block, or a single field inside a block, of a parsed query.
+type GraphQuery = gql.GraphQuery
+
+// Severity tells how serious a Diagnostic is.
+type Severity string
+
+const (
+	// SeverityError is used for problems that would make Dgraph reject the query.
+	SeverityError Severity = "error"
+	// SeverityWarning is used for valid queries that are likely to be slow or wrong.
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found in a query. Line and Column are 1-based, and are zero if the
+// position isn't known.
+type Diagnostic struct {
+	Severity   Severity `json:"severity"`
+	Rule       string   `json:"rule"`
+	Message    string   `json:"message"`
+	Line       int      `json:"line"`
+	Column     int      `json:"column"`
+	Suggestion string   `json:"suggestion,omitempty"`
+}
+
+// Rules reported in Diagnostic.Rule.
+const (
+	RuleSyntax          = "syntax"
+	RuleUnknownFunction = "unknown-function"
+	RuleUnpaginatedHas  = "unpaginated-has"
+)
+
+// Parse parses a query, substituting the given GraphQL variables.
+func Parse(query string, variables map[string]string) (Result, error) {
+	return gql.Parse(gql.Request{Str: query, Variables: variables})
+}
+
+// Validate checks that the text is a valid query, or a valid mutation or upsert block, and
+// returns the syntax errors found in it.
+func Validate(text string) []Diagnostic {
+	var err error
+	if isMutation(scan(text)) {
+		_, err = gql.ParseMutation(text)
+	} else {
+		_, err = Parse(text, nil)
+	}
+	if err != nil {
+		return []Diagnostic{syntaxDiagnostic(err)}
+	}
+	return nil
+}
+
+// Lint validates the text and, if it is a valid query, also looks for patterns that are likely
+// to be slow or rejected when run.
+func Lint(text string) []Diagnostic {
+	if diags := Validate(text); len(diags) > 0 {
+		return diags
+	}
+	toks := scan(text)
+	if isMutation(toks) {
+		return nil
+	}
+	res, err := Parse(text, nil)
+	if err != nil {
+		return []Diagnostic{syntaxDiagnostic(err)}
+	}
+
+	l := &linter{calls: callPositions(toks)}
+	for _, gq := range res.Query {
+		l.lintBlock(gq)
+	}
+	return l.diags
+}
+
+var errPosition = regexp.MustCompile(`(?s)line (\d+) column (\d+): (.*)$`)
+
+var (
+	unknownDirective = regexp.MustCompile(`Unknown directive \[(.*)\]`)
+	invalidFunction  = regexp.MustCompile(`Function name: (.*) is not valid`)
+)
+
+// syntaxDiagnostic turns an error from the lexer or the parser into a Diagnostic.
+func syntaxDiagnostic(err error) Diagnostic {
+	d := Diagnostic{Severity: SeverityError, Rule: RuleSyntax, Message: err.Error()}
+	if m := errPosition.FindStringSubmatch(d.Message); m != nil {
+		d.Line, _ = strconv.Atoi(m[1])
+		// The parser counts columns from zero.
+		d.Column, _ = strconv.Atoi(m[2])
+		d.Column++
+		d.Message = m[3]
+	}
+	if m := unknownDirective.FindStringSubmatch(d.Message); m != nil {
+		if s := closest(m[1], directives); s != "" {
+			d.Suggestion = "did you mean @" + s + "?"
+		}
+	}
+	if m := invalidFunction.FindStringSubmatch(d.Message); m != nil {
+		if s := closest(m[1], functions); s != "" {
+			d.Suggestion = "did you mean " + s + "()?"
+		}
+	}
+	return d
+}
+
+// functions are the functions that can be used at the root of a block or in a filter, as listed
+// by parseFuncTypeHelper in worker/task.go. The worker takes any other name to be the function of
+// a tokenizer, which may come from a plugin, so unknown functions in filters are only warnings.
+var functions = []string{
+	"allof", "allofterms", "alloftext", "anyof", "anyofterms", "anyoftext", "between",
+	"checkpwd", "contains", "eq", "fuzzy", "ge", "gt", "has", "highlight", "intersects", "le",
+	"lt", "match", "near", "regexp", "relevance", "type", "uid", "uid_in", "within",
+}
+
+var directives = []string{
+	"cascade", "facets", "filter", "graph", "groupby", "ignorereflex", "normalize", "recurse",
+}
+
+type linter struct {
+	calls map[string][]position
+	diags []Diagnostic
+}
+
+// pos returns the position of the next call to the function with the given name. Blocks are
+// walked in the order they are written, so the calls are found in that order as well.
+func (l *linter) pos(name string) position {
+	ps := l.calls[name]
+	if len(ps) == 0 {
+		return position{}
+	}
+	l.calls[name] = ps[1:]
+	return ps[0]
+}
+
+func (l *linter) add(p position, sev Severity, rule, msg, suggestion string) {
+	l.diags = append(l.diags, Diagnostic{Severity: sev, Rule: rule, Message: msg,
+		Line: p.line, Column: p.col, Suggestion: suggestion})
+}
+
+func (l *linter) lintBlock(gq *GraphQuery) {
+	if gq.Func != nil {
+		// The parser already rejects unknown functions at the root.
+		p := l.pos(gq.Func.Name)
+		_, paginated := gq.Args["first"]
+		if gq.Func.Name == "has" && !paginated {
+			l.add(p, SeverityWarning, RuleUnpaginatedHas,
+				"has() at the root of a block reads every node with the predicate",
+				"add first: N to the block and page through the results with after")
+		}
+	}
+	l.lintFilter(gq.Filter)
+	for _, child := range gq.Children {
+		l.lintBlock(child)
+	}
+}
+
+func (l *linter) lintFilter(ft *gql.FilterTree) {
+	if ft == nil {
+		return
+	}
+	if ft.Func != nil {
+		l.lintFunc(ft.Func, l.pos(ft.Func.Name))
+	}
+	for _, child := range ft.Child {
+		l.lintFilter(child)
+	}
+}
+
+func (l *linter) lintFunc(fn *gql.Function, p position) {
+	name := strings.ToLower(fn.Name)
+	for _, f := range functions {
+		if f == name {
+			return
+		}
+	}
+	var suggestion string
+	if s := closest(name, functions); s != "" {
+		suggestion = "did you mean " + s + "()?"
+	}
+	l.add(p, SeverityWarning, RuleUnknownFunction, "unknown function "+fn.Name+"()", suggestion)
+}
+
+// closest returns the candidate within two edits of name, or the empty string if there is none.
+func closest(name string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := editDistance(name, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min(a, b, c int) int {
+	if a < b && a < c {
+		return a
+	} else if b < c {
+		return b
+	}
+	return c
+}
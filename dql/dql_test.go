@@ -0,0 +1,154 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		query string
+		diags []Diagnostic
+	}{
+		{
+			query: `{ me(func: uid(0x1)) { name } }`,
+		},
+		{
+			query: `{ me(func: eq(name, "x") { name } }`,
+			diags: []Diagnostic{{Severity: SeverityError, Rule: RuleSyntax, Line: 1, Column: 26,
+				Message: "Unrecognized character inside a func: U+007B '{'"}},
+		},
+		{
+			query: `{ me(func: eqq(name, "x")) { name } }`,
+			diags: []Diagnostic{{Severity: SeverityError, Rule: RuleSyntax, Line: 1, Column: 10,
+				Message: "Function name: eqq is not valid.", Suggestion: "did you mean eq()?"}},
+		},
+		{
+			query: "{\n  me(func: uid(0x1)) @filtr(eq(name, \"x\")) {\n    name\n  }\n}",
+			diags: []Diagnostic{{Severity: SeverityError, Rule: RuleSyntax, Line: 2, Column: 23,
+				Message: "Unknown directive [filtr]", Suggestion: "did you mean @filter?"}},
+		},
+		{
+			query: "{\n  me(func: has(name)) @filter(eq(age, 1) OR alloftxt(name, \"x\")) {\n" +
+				"    name\n  }\n}",
+			diags: []Diagnostic{
+				{Severity: SeverityWarning, Rule: RuleUnpaginatedHas, Line: 2, Column: 12,
+					Message:    "has() at the root of a block reads every node with the predicate",
+					Suggestion: "add first: N to the block and page through the results with after"},
+				{Severity: SeverityWarning, Rule: RuleUnknownFunction, Line: 2, Column: 45,
+					Message: "unknown function alloftxt()", Suggestion: "did you mean alloftext()?"},
+			},
+		},
+		{
+			query: `{ me(func: has(name), first: 10) { name } }`,
+		},
+		{
+			query: `{ me(func: eq(email, "a@b.c")) @filter(checkpwd(password, "x")) { name } }`,
+		},
+		{
+			query: `{ set { _:a <name> "x" . } }`,
+		},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.diags, Lint(tc.query), tc.query)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	query := `query q($name: string = "Alice") {me(func:eq(name,$name),first:10)@filter(ge(age,18) AND
+(lt(age, 60) OR has(x))){name@en:fr age # years
+friend(first:2){name}
+a as count(friend)   n : name
+}
+
+
+  # everyone with as many friends
+
+other(func: uid(a)) @normalize { uid  x: math(a+2 / 2) score:math(a < 3) }}`
+	expected := `query q($name: string = "Alice") {
+  me(func: eq(name, $name), first: 10) @filter(ge(age, 18) AND (lt(age, 60) OR has(x))) {
+    name@en:fr
+    age # years
+    friend(first: 2) {
+      name
+    }
+    a as count(friend)
+    n: name
+  }
+
+  # everyone with as many friends
+
+  other(func: uid(a)) @normalize {
+    uid
+    x: math(a+2 / 2)
+    score: math(a < 3)
+  }
+}
+`
+	out, diags := Format(query)
+	require.Empty(t, diags)
+	require.Equal(t, expected, out)
+
+	out, diags = Format(out)
+	require.Empty(t, diags)
+	require.Equal(t, expected, out)
+}
+
+func TestFormatUnknownFunction(t *testing.T) {
+	// Functions of tokenizer plugins aren't known, but the query is still formatted.
+	out, diags := Format(`{ me(func: uid(0x1)) @filter(mytok(name, "x")) { name } }`)
+	require.Len(t, diags, 1)
+	require.Equal(t, SeverityWarning, diags[0].Severity)
+	require.Equal(t, "{\n  me(func: uid(0x1)) @filter(mytok(name, \"x\")) {\n    name\n  }\n}\n", out)
+}
+
+func TestFormatMutation(t *testing.T) {
+	upsert := `upsert { query { q(func: eq(email, "a@b.c")) { v as uid } }
+mutation @if(eq(len(v), 0)) { set {
+      _:x <email> "a@b.c" .
+
+   _:x <name> "Alice"@en .  } } }`
+	expected := `upsert {
+  query {
+    q(func: eq(email, "a@b.c")) {
+      v as uid
+    }
+  }
+  mutation @if(eq(len(v), 0)) {
+    set {
+      _:x <email> "a@b.c" .
+
+      _:x <name> "Alice"@en .
+    }
+  }
+}
+`
+	out, diags := Format(upsert)
+	require.Empty(t, diags)
+	require.Equal(t, expected, out)
+}
+
+func TestFormatInvalid(t *testing.T) {
+	query := `{ me(func: uid(0x1)) { name }`
+	out, diags := Format(query)
+	require.Equal(t, query, out)
+	require.Len(t, diags, 1)
+	require.Equal(t, SeverityError, diags[0].Severity)
+}
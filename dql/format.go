@@ -0,0 +1,420 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dql
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const indent = "  "
+
+// Format validates the text and pretty-prints it: blocks are indented by two spaces, every field
+// goes on its own line, and arguments are separated by a single space. Comments are kept, and
+// the content of set and delete blocks is only re-indented. If the text has errors it is returned
+// as it is, along with the diagnostics.
+func Format(text string) (string, []Diagnostic) {
+	diags := Lint(text)
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return text, diags
+		}
+	}
+	toks := scan(text)
+	f := &formatter{text: text, toks: toks, lineStart: true, mutation: isMutation(toks)}
+	f.format()
+	out := f.b.String()
+	// Formatting must never turn a valid query into an invalid one.
+	if len(Validate(out)) > 0 {
+		return text, diags
+	}
+	return out, diags
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokString
+	tokIRI
+	tokRegex
+	tokComment
+	tokPunct
+)
+
+type position struct {
+	line, col int
+}
+
+type token struct {
+	kind       tokenKind
+	val        string
+	pos        position
+	start, end int  // byte offsets of the token in the text
+	space      bool // whether the token is preceded by spaces on the same line
+	lines      int  // number of line breaks before the token
+}
+
+func (t token) is(val string) bool {
+	return t.kind == tokPunct && t.val == val
+}
+
+func isNameRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+		r == '_' || r == '~' || r == '.' || r == '$'
+}
+
+// scan splits the text into tokens. Unlike the lexer used by the parser it keeps comments and
+// the position of every token, and it never fails: anything it doesn't know is punctuation.
+func scan(text string) []token {
+	var toks []token
+	line, col := 1, 1
+	i := 0
+	advance := func(end int) {
+		for _, r := range text[i:end] {
+			if r == '\n' {
+				line, col = line+1, 1
+			} else {
+				col++
+			}
+		}
+		i = end
+	}
+
+	var space bool
+	var lines int
+	// mathAt is the depth of parentheses at which math() was opened, or -1 outside of it. Inside
+	// math, < and / are operators instead of the start of an IRI or of a regular expression.
+	depth, mathAt := 0, -1
+	for i < len(text) {
+		r, w := utf8.DecodeRuneInString(text[i:])
+		if r == '\n' {
+			lines++
+			space = false
+			advance(i + w)
+			continue
+		}
+		if unicode.IsSpace(r) {
+			space = true
+			advance(i + w)
+			continue
+		}
+
+		t := token{kind: tokPunct, pos: position{line, col}, start: i, space: space, lines: lines}
+		inMath := mathAt >= 0 && depth > mathAt
+		end := i + w
+		switch {
+		case r == '#':
+			t.kind = tokComment
+			end = len(text)
+			if nl := strings.IndexByte(text[i:], '\n'); nl >= 0 {
+				end = i + nl
+			}
+		case r == '"':
+			t.kind = tokString
+			end = len(text)
+			for j := i + 1; j < len(text); j++ {
+				if text[j] == '\\' {
+					j++
+				} else if text[j] == '"' {
+					end = j + 1
+					break
+				}
+			}
+		case r == '<' && !inMath:
+			if n := strings.IndexAny(text[i:], "> \t\n"); n > 0 && text[i+n] == '>' {
+				t.kind = tokIRI
+				end = i + n + 1
+			}
+		case r == '/' && !inMath:
+			for j := i + 1; j < len(text) && text[j] != '\n'; j++ {
+				if text[j] == '\\' {
+					j++
+				} else if text[j] == '/' {
+					t.kind = tokRegex
+					for end = j + 1; end < len(text) && unicode.IsLetter(rune(text[end])); end++ {
+					}
+					break
+				}
+			}
+		case isNameRune(r):
+			t.kind = tokWord
+			for end < len(text) && isNameRune(rune(text[end])) {
+				end++
+			}
+		case r == '(':
+			if n := len(toks); mathAt < 0 && n > 0 && toks[n-1].kind == tokWord &&
+				toks[n-1].val == "math" {
+				mathAt = depth
+			}
+			depth++
+		case r == ')':
+			if depth > 0 {
+				depth--
+			}
+			if depth <= mathAt {
+				mathAt = -1
+			}
+		case inMath && strings.ContainsRune("<>=!", r):
+			if end < len(text) && text[end] == '=' {
+				end++
+			}
+		}
+		t.val = text[i:end]
+		t.end = end
+		toks = append(toks, t)
+		space, lines = false, 0
+		advance(end)
+	}
+	return toks
+}
+
+// significant returns the tokens that aren't comments.
+func significant(toks []token) []token {
+	var out []token
+	for _, t := range toks {
+		if t.kind != tokComment {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// isMutation returns whether the tokens are a mutation block or an upsert block rather than a
+// query. A query can also start with a block named set, so the block must be followed by a {.
+func isMutation(toks []token) bool {
+	toks = significant(toks)
+	if len(toks) > 0 && toks[0].kind == tokWord && toks[0].val == "upsert" {
+		return true
+	}
+	return len(toks) > 2 && toks[0].is("{") && isMutationOp(toks[1]) && toks[2].is("{")
+}
+
+func isMutationOp(t token) bool {
+	return t.kind == tokWord && (t.val == "set" || t.val == "delete")
+}
+
+// callPositions returns the positions of the function calls in the tokens, by function name.
+func callPositions(toks []token) map[string][]position {
+	calls := make(map[string][]position)
+	toks = significant(toks)
+	for i := 0; i+1 < len(toks); i++ {
+		if toks[i].kind == tokWord && toks[i+1].is("(") {
+			calls[toks[i].val] = append(calls[toks[i].val], toks[i].pos)
+		}
+	}
+	return calls
+}
+
+var directiveSet = map[string]bool{"if": true}
+
+func init() {
+	for _, d := range directives {
+		directiveSet[d] = true
+	}
+}
+
+type formatter struct {
+	text     string
+	toks     []token
+	mutation bool
+
+	b         strings.Builder
+	depth     int  // nesting of {}
+	args      int  // nesting of ()
+	lineStart bool // whether nothing was written on the current line yet
+	lang      bool // whether a language list like @en:fr is being written
+	prev      *token
+}
+
+func (f *formatter) write(s string) {
+	if f.lineStart {
+		f.b.WriteString(strings.Repeat(indent, f.depth))
+		f.lineStart = false
+	}
+	f.b.WriteString(s)
+}
+
+func (f *formatter) newline() {
+	if !f.lineStart {
+		f.b.WriteByte('\n')
+		f.lineStart = true
+	}
+}
+
+func (f *formatter) blankLine() {
+	f.newline()
+	if f.b.Len() > 0 && !strings.HasSuffix(f.b.String(), "\n\n") {
+		f.b.WriteByte('\n')
+	}
+}
+
+func (f *formatter) format() {
+	for i := 0; i < len(f.toks); i++ {
+		t := f.toks[i]
+		f.breakBefore(t)
+
+		switch {
+		case t.kind == tokComment:
+			if !f.lineStart {
+				f.write(" ")
+			}
+			f.write(strings.TrimRight(t.val, " \t\r"))
+			f.newline()
+		case t.is("{"):
+			if !f.lineStart {
+				f.write(" ")
+			}
+			f.write("{")
+			if i+1 < len(f.toks) && f.toks[i+1].is("}") {
+				i++
+				f.write("}")
+				f.newline()
+				break
+			}
+			if f.mutation && f.args == 0 && f.prev != nil && isMutationOp(*f.prev) {
+				if end := f.matching(i); end > 0 {
+					f.writeRaw(f.text[t.end:f.toks[end].start])
+					i = end
+					f.newline()
+					f.write("}")
+					f.newline()
+					t = f.toks[i]
+					break
+				}
+			}
+			f.depth++
+			f.newline()
+		case t.is("}"):
+			if f.depth > 0 {
+				f.depth--
+			}
+			f.newline()
+			f.write("}")
+			f.newline()
+		default:
+			if !f.lineStart && f.space(t, i) {
+				f.write(" ")
+			}
+			f.write(t.val)
+			switch {
+			case t.is("("):
+				f.args++
+			case t.is(")") && f.args > 0:
+				f.args--
+			}
+		}
+		f.prev = &f.toks[i]
+	}
+	f.newline()
+}
+
+// breakBefore starts a new line before the token where needed. Line breaks of the text are kept
+// outside of parentheses, with at most one blank line in a row, and fields written on the same
+// line are split.
+func (f *formatter) breakBefore(t token) {
+	if t.space || t.lines > 0 {
+		f.lang = false
+	}
+	if f.prev == nil {
+		return
+	}
+	if t.lines > 0 && (f.args == 0 || t.kind == tokComment) {
+		if t.lines > 1 && !f.prev.is("{") && !t.is("}") {
+			f.blankLine()
+		} else {
+			f.newline()
+		}
+		return
+	}
+	if f.lineStart {
+		return
+	}
+	p := *f.prev
+	isField := func(t token) bool { return t.kind == tokWord || t.kind == tokIRI }
+	if f.depth > 0 && f.args == 0 && !f.lang && isField(t) && (isField(p) || p.is(")")) &&
+		t.val != "as" && p.val != "as" {
+		f.newline()
+	}
+}
+
+// space returns whether a space must be written between the previous token and the i-th one.
+func (f *formatter) space(t token, i int) bool {
+	p := *f.prev
+	if f.lang {
+		return false
+	}
+	switch {
+	case p.is("(") || p.is("[") || p.is("@"):
+		return false
+	case t.is(")") || t.is("]") || t.is(",") || t.is(":"):
+		return false
+	case p.is(",") || p.is(":"):
+		return true
+	case t.is("("):
+		switch strings.ToLower(p.val) {
+		case "and", "or", "not":
+			return true
+		}
+		return p.kind != tokWord && (t.space || t.lines > 0)
+	case t.is("@"):
+		if i+1 < len(f.toks) && directiveSet[f.toks[i+1].val] {
+			return true
+		}
+		f.lang = true
+		return false
+	}
+	return t.space || t.lines > 0
+}
+
+// matching returns the index of the } closing the { at index i, or -1 if there is none.
+func (f *formatter) matching(i int) int {
+	depth := 0
+	for j := i; j < len(f.toks); j++ {
+		switch {
+		case f.toks[j].is("{"):
+			depth++
+		case f.toks[j].is("}"):
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// writeRaw writes the content of a set or delete block, one trimmed line at a time.
+func (f *formatter) writeRaw(content string) {
+	f.depth++
+	blank := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			blank = true
+			continue
+		}
+		if blank && !strings.HasSuffix(f.b.String(), "{") {
+			f.blankLine()
+		}
+		blank = false
+		f.newline()
+		f.write(line)
+	}
+	f.depth--
+}
@@ -16,6 +16,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -573,8 +574,12 @@ func upsertGroot(ctx context.Context, passwd string) error {
 	return nil
 }
 
-// extract the userId, groupIds from the accessJwt in the context
+// extract the userId, groupIds from the accessJwt in the context, or those of the guest profile
+// the request is served with
 func extractUserAndGroups(ctx context.Context) ([]string, error) {
+	if p := guestOf(ctx); p != nil {
+		return []string{guestUser, p.group}, nil
+	}
 	accessJwt, err := x.ExtractJwt(ctx)
 	if err != nil {
 		return nil, err
@@ -611,6 +616,9 @@ func authorizePreds(ctx context.Context, userData, preds []string,
 			blockedPreds[pred] = struct{}{}
 		}
 	}
+	if p := guestOf(ctx); p != nil {
+		return &authPredResult{allowed: guestPredicates(p, aclOp), blocked: blockedPreds}, nil
+	}
 	aclCachePtr.RLock()
 	allowedPreds := make([]string, len(aclCachePtr.userPredPerms[userId]))
 	// User can have multiple permission for same predicate, add predicate
@@ -624,6 +632,36 @@ func authorizePreds(ctx context.Context, userData, preds []string,
 	return &authPredResult{allowed: allowedPreds, blocked: blockedPreds}, nil
 }
 
+// authorizeScan authorizes reading all the edges of the predicates, for a partitioned scan.
+// Unlike queries, which silently drop the blocked predicates, the scan is rejected.
+func authorizeScan(ctx context.Context, preds []string) error {
+	if len(worker.Config.HmacSecret) == 0 {
+		// the user has not turned on the acl feature
+		return nil
+	}
+	userData, err := extractUserAndGroups(ctx)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	if x.IsGuardian(userData[1:]) {
+		return nil
+	}
+	result, err := authorizePreds(ctx, userData, preds, acl.Read)
+	if err != nil {
+		return err
+	}
+	if len(result.blocked) == 0 {
+		return nil
+	}
+	var blocked []string
+	for pred := range result.blocked {
+		blocked = append(blocked, pred)
+	}
+	sort.Strings(blocked)
+	return status.Errorf(codes.PermissionDenied, "unauthorized to scan following predicates: %s",
+		strings.Join(blocked, ", "))
+}
+
 // authorizeAlter parses the Schema in the operation and authorizes the operation
 // using the aclCachePtr. It will return error if any one of the predicates specified in alter
 // are not authorized.
@@ -656,6 +694,9 @@ func authorizeAlter(ctx context.Context, op *api.Operation) error {
 	// doAuthorizeAlter checks if alter of all the predicates are allowed
 	// as a byproduct, it also sets the userId, groups variables
 	doAuthorizeAlter := func() error {
+		if err := authorizeGuest(ctx, guestAlter); err != nil {
+			return err
+		}
 		userData, err := extractUserAndGroups(ctx)
 		if err != nil {
 			// We don't follow fail open approach anymore.
@@ -770,6 +811,9 @@ func authorizeMutation(ctx context.Context, gmu *gql.Mutation) error {
 	// doAuthorizeMutation checks if modification of all the predicates are allowed
 	// as a byproduct, it also sets the userId and groups
 	doAuthorizeMutation := func() error {
+		if err := authorizeGuest(ctx, guestMutate); err != nil {
+			return err
+		}
 		userData, err := extractUserAndGroups(ctx)
 		if err != nil {
 			// We don't follow fail open approach anymore.
@@ -924,6 +968,11 @@ func authorizeQuery(ctx context.Context, parsedReq *gql.Result, graphql bool) er
 	}
 
 	doAuthorizeQuery := func() (map[string]struct{}, []string, error) {
+		if len(parsedReq.Query) > 0 || parsedReq.Schema != nil {
+			if err := authorizeGuest(ctx, guestQuery); err != nil {
+				return nil, nil, err
+			}
+		}
 		userData, err := extractUserAndGroups(ctx)
 		if err != nil {
 			return nil, nil, status.Error(codes.Unauthenticated, err.Error())
@@ -958,8 +1007,9 @@ func authorizeQuery(ctx context.Context, parsedReq *gql.Result, graphql bool) er
 
 	if len(blockedPreds) != 0 {
 		// For GraphQL requests, we allow filtered access to the ACL predicates.
-		// Filter for user_id and group_id is applied for the currently logged in user.
-		if graphql {
+		// Filter for user_id and group_id is applied for the currently logged in user. Guests
+		// aren't logged in, so they don't get it.
+		if graphql && guestOf(ctx) == nil {
 			for _, gq := range parsedReq.Query {
 				addUserFilterToQuery(gq, userId, groupIds)
 			}
@@ -0,0 +1,279 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const (
+	// AuditTrailDefaults are the default values for the --audit_trail superflag.
+	AuditTrailDefaults = "types=; claim=; created-by=created_by; created-at=created_at; " +
+		"updated-by=updated_by; updated-at=updated_at"
+)
+
+// auditTrail records who created and last updated the nodes of some types, and when, in
+// predicates of those nodes which are maintained in the mutation path.
+type auditTrail struct {
+	types map[string]struct{}
+	// claim is the claim of the JWT of GraphQL requests recorded as the user when the request
+	// has no ACL identity.
+	claim     string
+	createdBy string
+	createdAt string
+	updatedBy string
+	updatedAt string
+}
+
+// trail is the audit trail set via the --audit_trail flag, or nil.
+var trail *auditTrail
+
+// auditUserKey is the context key of the user recorded in the audit trail of GraphQL requests.
+type auditUserKey struct{}
+
+// SetAuditTrail parses the --audit_trail superflag and maintains the audit trail of the nodes of
+// the types it lists in the mutations run afterwards.
+func SetAuditTrail(sf *z.SuperFlag) error {
+	t := &auditTrail{
+		types:     make(map[string]struct{}),
+		claim:     sf.GetString("claim"),
+		createdBy: sf.GetString("created-by"),
+		createdAt: sf.GetString("created-at"),
+		updatedBy: sf.GetString("updated-by"),
+		updatedAt: sf.GetString("updated-at"),
+	}
+	for _, typ := range strings.Split(sf.GetString("types"), ",") {
+		if typ = strings.TrimSpace(typ); typ != "" {
+			t.types[typ] = struct{}{}
+		}
+	}
+	if len(t.types) == 0 {
+		trail = nil
+		return nil
+	}
+	seen := make(map[string]struct{})
+	for _, pred := range t.predicates() {
+		switch _, ok := seen[pred]; {
+		case pred == "":
+			return errors.Errorf("The predicates of the audit trail can't be empty")
+		case x.IsReservedPredicate(x.GalaxyAttr(pred)):
+			return errors.Errorf("Can't use reserved predicate %s in the audit trail", pred)
+		case ok:
+			return errors.Errorf("Predicate %s is used twice in the audit trail", pred)
+		}
+		seen[pred] = struct{}{}
+	}
+	trail = t
+	return nil
+}
+
+// AuditTrailClaim returns the claim of the JWT of GraphQL requests which is recorded as the
+// user in the audit trail, or an empty string if there's none.
+func AuditTrailClaim() string {
+	if trail == nil {
+		return ""
+	}
+	return trail.claim
+}
+
+// WithAuditUser returns a context recording user in the audit trail of the mutations run with
+// it. The GraphQL layer sets it from the verified claims of the JWT of the request.
+func WithAuditUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, auditUserKey{}, user)
+}
+
+func (t *auditTrail) predicates() []string {
+	return []string{t.createdBy, t.createdAt, t.updatedBy, t.updatedAt}
+}
+
+// user returns the user doing the mutation: the ACL user if there's one, otherwise the user set
+// by the GraphQL layer.
+func (t *auditTrail) user(ctx context.Context) string {
+	return requestUser(ctx)
+}
+
+// requestUser returns the ACL user of the request if there's one, otherwise the user set by the
+// GraphQL layer via WithAuditUser, if any.
+func requestUser(ctx context.Context) string {
+	if x.WorkerConfig.AclEnabled {
+		if jwt, err := x.ExtractJwt(ctx); err == nil {
+			if user, err := x.ExtractUserName(jwt[0]); err == nil {
+				return user
+			}
+		}
+	}
+	user, _ := ctx.Value(auditUserKey{}).(string)
+	return user
+}
+
+// addAuditTrail adds to the edges the audit trail of the nodes of the types of the trail which
+// the edges set or delete, leaving out the edges the client sent for the predicates of the
+// trail. The types of a node are the ones it already has and the ones set by the edges. The
+// created-by and created-at predicates are only set for the nodes which don't have the latter
+// yet. The trail of the nodes which are deleted entirely is deleted too.
+func addAuditTrail(ctx context.Context, startTs uint64, edges []*pb.DirectedEdge,
+	newUids map[string]uint64) ([]*pb.DirectedEdge, error) {
+	t := trail
+	if t == nil || len(edges) == 0 {
+		return edges, nil
+	}
+
+	type node struct {
+		audited bool
+		created bool
+		deleted bool
+		ns      uint64
+	}
+	nodes := make(map[uint64]*node)
+	for _, edge := range edges {
+		n := nodes[edge.Entity]
+		if n == nil {
+			n = &node{ns: edge.Namespace}
+			nodes[edge.Entity] = n
+		}
+		switch {
+		case edge.Op == pb.DirectedEdge_DEL && edge.Attr == x.Star:
+			n.deleted = true
+		case edge.Op == pb.DirectedEdge_SET && edge.Attr == "dgraph.type":
+			if _, ok := t.types[string(edge.Value)]; ok {
+				n.audited = true
+			}
+		}
+	}
+
+	// Nodes created by this mutation have no types or trail yet, so only the other ones are
+	// read. The nodes which don't have the created-at predicate yet are considered created.
+	created := make(map[uint64]struct{}, len(newUids))
+	for _, uid := range newUids {
+		created[uid] = struct{}{}
+	}
+	var existing []string
+	for uid, n := range nodes {
+		n.created = true
+		if _, ok := created[uid]; !ok {
+			existing = append(existing, fmt.Sprintf("%#x", uid))
+		}
+	}
+	if len(existing) > 0 {
+		query := fmt.Sprintf("{ q(func: uid(%s)) { uid dgraph.type <%s> } }",
+			strings.Join(existing, ","), t.createdAt)
+		resp, err := (&Server{}).doQuery(ctx, &Request{
+			req:    &api.Request{Query: query, StartTs: startTs, ReadOnly: true},
+			doAuth: NoAuthorize,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "while reading the audit trail")
+		}
+		var res struct {
+			Q []map[string]interface{} `json:"q"`
+		}
+		if err := json.Unmarshal(resp.Json, &res); err != nil {
+			return nil, errors.Wrapf(err, "while reading the audit trail")
+		}
+		for _, m := range res.Q {
+			uidStr, _ := m["uid"].(string)
+			uid, err := strconv.ParseUint(uidStr, 0, 64)
+			if err != nil || nodes[uid] == nil {
+				continue
+			}
+			n := nodes[uid]
+			typs, _ := m["dgraph.type"].([]interface{})
+			for _, typ := range typs {
+				if _, ok := t.types[fmt.Sprint(typ)]; ok {
+					n.audited = true
+				}
+			}
+			if _, ok := m[t.createdAt]; ok {
+				n.created = false
+			}
+		}
+	}
+
+	now := types.ValueForType(types.BinaryID)
+	if err := types.Marshal(types.Val{Tid: types.DateTimeID, Value: time.Now().UTC()},
+		&now); err != nil {
+		return nil, err
+	}
+	user := t.user(ctx)
+	out := edges[:0]
+	for _, edge := range edges {
+		if n := nodes[edge.Entity]; n.audited && t.isTrailPredicate(edge.Attr) {
+			continue
+		}
+		out = append(out, edge)
+	}
+	for uid, n := range nodes {
+		if !n.audited {
+			continue
+		}
+		add := func(attr string, val []byte, typ pb.Posting_ValType) {
+			out = append(out, &pb.DirectedEdge{
+				Entity:    uid,
+				Attr:      attr,
+				Value:     val,
+				ValueType: typ,
+				Namespace: n.ns,
+				Op:        pb.DirectedEdge_SET,
+			})
+		}
+		if n.deleted {
+			// The predicates of the trail aren't part of the types, so they aren't deleted
+			// along with the predicates of the node.
+			for _, pred := range t.predicates() {
+				out = append(out, &pb.DirectedEdge{
+					Entity:    uid,
+					Attr:      pred,
+					Value:     []byte(x.Star),
+					ValueType: pb.Posting_DEFAULT,
+					Namespace: n.ns,
+					Op:        pb.DirectedEdge_DEL,
+				})
+			}
+			continue
+		}
+		at := now.Value.([]byte)
+		if n.created {
+			add(t.createdAt, at, pb.Posting_DATETIME)
+			if user != "" {
+				add(t.createdBy, []byte(user), pb.Posting_STRING)
+			}
+		}
+		add(t.updatedAt, at, pb.Posting_DATETIME)
+		if user != "" {
+			add(t.updatedBy, []byte(user), pb.Posting_STRING)
+		}
+	}
+	return out, nil
+}
+
+func (t *auditTrail) isTrailPredicate(attr string) bool {
+	return attr == t.createdBy || attr == t.createdAt || attr == t.updatedBy ||
+		attr == t.updatedAt
+}
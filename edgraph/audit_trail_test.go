@@ -0,0 +1,73 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+func TestSetAuditTrail(t *testing.T) {
+	defer func() { trail = nil }()
+	flag := func(s string) *z.SuperFlag {
+		return z.NewSuperFlag(s).MergeAndCheckDefault(AuditTrailDefaults)
+	}
+
+	require.NoError(t, SetAuditTrail(flag("")))
+	require.Nil(t, trail)
+	require.Equal(t, "", AuditTrailClaim())
+
+	require.NoError(t, SetAuditTrail(flag("types=Person, Post; claim=email")))
+	require.Len(t, trail.types, 2)
+	require.Equal(t, "email", AuditTrailClaim())
+
+	require.Error(t, SetAuditTrail(flag("types=Person; created-by=dgraph.user")))
+	require.Error(t, SetAuditTrail(flag("types=Person; created-by=; updated-by=")))
+	require.Error(t, SetAuditTrail(flag("types=Person; created-at=at; updated-at=at")))
+}
+
+func TestAddAuditTrail(t *testing.T) {
+	defer func() { trail = nil }()
+	require.NoError(t, SetAuditTrail(
+		z.NewSuperFlag("types=Person").MergeAndCheckDefault(AuditTrailDefaults)))
+
+	edges := []*pb.DirectedEdge{
+		{Entity: 1, Attr: "dgraph.type", Value: []byte("Person"), Op: pb.DirectedEdge_SET},
+		{Entity: 1, Attr: "name", Value: []byte("Ann"), Op: pb.DirectedEdge_SET},
+		{Entity: 1, Attr: "created_at", Value: []byte("2000-01-01"), Op: pb.DirectedEdge_SET},
+		{Entity: 2, Attr: "name", Value: []byte("Rock"), Op: pb.DirectedEdge_SET},
+	}
+	ctx := WithAuditUser(context.Background(), "ann@example.com")
+	edges, err := addAuditTrail(ctx, 1, edges, map[string]uint64{"a": 1, "b": 2})
+	require.NoError(t, err)
+
+	attrs := make(map[uint64][]string)
+	for _, edge := range edges {
+		attrs[edge.Entity] = append(attrs[edge.Entity], edge.Attr)
+		if edge.Attr == "created_by" || edge.Attr == "updated_by" {
+			require.Equal(t, "ann@example.com", string(edge.Value))
+		}
+	}
+	require.ElementsMatch(t, []string{"dgraph.type", "name", "created_at", "created_by",
+		"updated_at", "updated_by"}, attrs[1])
+	require.Equal(t, []string{"name"}, attrs[2])
+}
@@ -0,0 +1,35 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// Backpressure returns how loaded this Alpha is with writes. Loaders poll it to decide how many
+// concurrent mutations to send.
+func (s *Server) Backpressure(ctx context.Context,
+	req *pb.BackpressureRequest) (*pb.BackpressureState, error) {
+	if err := x.HealthCheck(); err != nil {
+		return nil, err
+	}
+	return worker.GetBackpressure(), nil
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// maxConcurrentUpserts is the number of upserts of a batch run at the same time.
+const maxConcurrentUpserts = 64
+
+// upsertStream stands for the gRPC stream of a batch in its upserts. It adds up their costs
+// instead of sending them as headers.
+type upsertStream struct {
+	cost uint64
+}
+
+func (s *upsertStream) Method() string { return "/pb.Upsert/BatchUpsert" }
+
+func (s *upsertStream) SendHeader(md metadata.MD) error {
+	for _, v := range md.Get(x.DgraphCostHeader) {
+		if cost, err := strconv.ParseUint(v, 10, 64); err == nil {
+			atomic.AddUint64(&s.cost, cost)
+		}
+	}
+	return nil
+}
+
+func (s *upsertStream) SetHeader(md metadata.MD) error  { return nil }
+func (s *upsertStream) SetTrailer(md metadata.MD) error { return nil }
+
+// BatchUpsert runs the upserts concurrently, each in its own transaction which is committed
+// right away. So, each upsert runs its query at the start timestamp of its own transaction, not at
+// a timestamp shared by the batch: Zero tells transactions apart by their start timestamps. The
+// concurrent requests for these timestamps are batched into fewer round trips to Zero, see
+// worker.ServerState.GetTimestamp. An upsert failing doesn't fail the others.
+func (s *Server) BatchUpsert(ctx context.Context,
+	req *pb.BatchUpsertRequest) (*pb.BatchUpsertResponse, error) {
+	if err := x.HealthCheck(); err != nil {
+		return nil, err
+	}
+	for i, r := range req.Requests {
+		switch {
+		case len(r.Mutations) == 0:
+			return nil, errors.Errorf("Upsert %d has no mutation", i)
+		case r.StartTs != 0:
+			return nil, errors.Errorf("Upsert %d can't be part of a transaction", i)
+		}
+	}
+
+	ctx = x.AttachJWTNamespace(ctx)
+	stream := &upsertStream{}
+	uctx := grpc.NewContextWithServerTransportStream(ctx, stream)
+
+	resp := &pb.BatchUpsertResponse{Results: make([]*pb.BatchUpsertResponse_Result,
+		len(req.Requests))}
+	limit := make(chan struct{}, maxConcurrentUpserts)
+	var wg sync.WaitGroup
+	for i, r := range req.Requests {
+		r.CommitNow = true
+		limit <- struct{}{}
+		wg.Add(1)
+		go func(i int, r *api.Request) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			res, err := s.doQuery(uctx, &Request{req: r, doAuth: getAuthMode(uctx)})
+			result := &pb.BatchUpsertResponse_Result{Response: res}
+			if err != nil {
+				result.Error = err.Error()
+				result.Code = uint32(status.Code(err))
+			}
+			resp.Results[i] = result
+		}(i, r)
+	}
+	wg.Wait()
+
+	md := metadata.Pairs(x.DgraphCostHeader, fmt.Sprint(atomic.LoadUint64(&stream.cost)))
+	_ = grpc.SendHeader(ctx, md)
+	return resp, nil
+}
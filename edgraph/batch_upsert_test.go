@@ -0,0 +1,51 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestUpsertStreamCost(t *testing.T) {
+	s := &upsertStream{}
+	require.NoError(t, s.SendHeader(metadata.Pairs(x.DgraphCostHeader, "3")))
+	require.NoError(t, s.SendHeader(metadata.Pairs(x.DgraphCostHeader, "4")))
+	require.NoError(t, s.SendHeader(metadata.Pairs("other", "5")))
+	require.Equal(t, uint64(7), s.cost)
+}
+
+func TestBatchUpsertInvalid(t *testing.T) {
+	x.UpdateHealthStatus(true)
+	defer x.UpdateHealthStatus(false)
+
+	mu := []*api.Mutation{{SetNquads: []byte(`_:a <name> "a" .`)}}
+	for _, r := range []*api.Request{
+		{Query: "{ q(func: has(name)) { uid } }"},
+		{Mutations: mu, StartTs: 10},
+	} {
+		_, err := (&Server{}).BatchUpsert(context.Background(),
+			&pb.BatchUpsertRequest{Requests: []*api.Request{{Mutations: mu}, r}})
+		require.Error(t, err)
+	}
+}
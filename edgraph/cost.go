@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/query"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+	otrace "go.opencensus.io/trace"
+)
+
+// EstimateCost estimates the cost of the query and of the mutations of the request, without
+// running them. The estimate is based on the planner statistics of the predicates, so that
+// gateways can reject the expensive requests, or route them to dedicated replicas.
+func (s *Server) EstimateCost(ctx context.Context, req *api.Request) (*query.CostEstimate, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	ctx, span := otrace.StartSpan(ctx, "Server.EstimateCost")
+	defer span.End()
+
+	if err := x.HealthCheck(); err != nil {
+		return nil, err
+	}
+	ctx = x.AttachJWTNamespace(ctx)
+	if getAuthMode(ctx) == NeedAuthorize {
+		var err error
+		if ctx, err = guestContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" && len(req.Mutations) == 0 {
+		return nil, errors.Errorf("empty request")
+	}
+	qc := &queryContext{req: req, latency: &query.Latency{}, span: span}
+	if err := parseRequest(ctx, qc); err != nil {
+		return nil, err
+	}
+	if getAuthMode(ctx) == NeedAuthorize {
+		if err := authorizeRequest(ctx, qc); err != nil {
+			return nil, err
+		}
+	}
+
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	preds := query.CostPredicates(qc.gqlRes.Query, qc.gmuList)
+	for i, pred := range preds {
+		preds[i] = x.NamespaceAttr(ns, pred)
+	}
+	stats := make(map[string]*pb.PlannerStats)
+	if len(preds) > 0 {
+		nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+			Predicates: preds,
+			Fields:     []string{"planner_stats"},
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "while getting the planner statistics")
+		}
+		for _, node := range nodes {
+			stats[x.ParseAttr(node.Predicate)] = node.PlannerStats
+		}
+	}
+	return query.EstimateCost(qc.gqlRes.Query, qc.gmuList, stats), nil
+}
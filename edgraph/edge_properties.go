@@ -0,0 +1,265 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/chunker"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/query"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// The properties of an edge, set with an N-Quad whose subject is the quoted triple
+// << subject predicate object >>, are stored on an edge node. The edge node points to the
+// subject and the object of the edge and records its predicate, so that its properties are
+// ordinary predicates with their own types and indexes. They can be reached from the subject
+// of the edge with
+//
+//	~dgraph.edge.src @filter(eq(dgraph.edge.pred, "friend") AND ge(since, 2019)) {
+//		since
+//		dgraph.edge.dst { name }
+//	}
+//
+// The edge nodes of edges between existing nodes also get the edge as their dgraph.edge.key. The
+// key is an @upsert predicate, so that concurrent txns creating the edge node of the same edge
+// conflict, instead of creating one each.
+const (
+	edgeSrc  = "dgraph.edge.src"
+	edgeDst  = "dgraph.edge.dst"
+	edgePred = "dgraph.edge.pred"
+	edgeKey  = "dgraph.edge.key"
+)
+
+// edgeKeyOf returns the dgraph.edge.key of the edge between the given nodes, if they are uids.
+func edgeKeyOf(s, p, o string) (string, bool) {
+	src, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return "", false
+	}
+	dst, err := strconv.ParseUint(o, 0, 64)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%#x %s %#x", src, p, dst), true
+}
+
+func isUidOrBlank(node string) bool {
+	if strings.HasPrefix(node, "_:") {
+		return true
+	}
+	_, err := strconv.ParseUint(node, 0, 64)
+	return err == nil
+}
+
+// assertQuotedEdges checks the quoted triples used as subjects and adds the edges they quote
+// to the set N-Quads, so that setting a property of an edge also sets the edge.
+func assertQuotedEdges(set, del []*api.NQuad) ([]*api.NQuad, error) {
+	check := func(nq *api.NQuad) (string, string, string, bool, error) {
+		s, p, o, ok := chunker.ParseQuotedTriple(nq.Subject)
+		if !ok {
+			return "", "", "", false, nil
+		}
+		if !isUidOrBlank(s) || !isUidOrBlank(o) {
+			return "", "", "", false, errors.Errorf("The subject and object of a quoted triple"+
+				" should be uids or blank nodes: %s", nq.Subject)
+		}
+		if err := validateKey(p); err != nil {
+			return "", "", "", false, errors.Wrapf(err, "predicate %q", p)
+		}
+		if x.IsEdgePredicate(p) {
+			return "", "", "", false, errors.Errorf("Can't quote an edge of %s", p)
+		}
+		return s, p, o, true, nil
+	}
+
+	asserted := make(map[string]struct{})
+	var edges []*api.NQuad
+	for _, nq := range set {
+		s, p, o, ok, err := check(nq)
+		if err != nil {
+			return nil, err
+		}
+		if _, seen := asserted[nq.Subject]; !ok || seen {
+			continue
+		}
+		asserted[nq.Subject] = struct{}{}
+		edges = append(edges, &api.NQuad{Subject: s, Predicate: p, ObjectId: o})
+	}
+	for _, nq := range del {
+		if _, _, _, ok, err := check(nq); err != nil {
+			return nil, err
+		} else if ok && nq.Predicate == x.Star {
+			return nil, errors.Errorf("Can't delete all the properties of an edge at once,"+
+				" delete them one at a time: %s", nq.Subject)
+		}
+	}
+	return append(set, edges...), nil
+}
+
+// ensureEdgeSchema creates the schema of the predicates of edge nodes if it doesn't exist yet,
+// which is only the case in namespaces created before they were pre-defined. It returns whether
+// the schema exists.
+func ensureEdgeSchema(ctx context.Context, dryRun bool) (bool, error) {
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return false, err
+	}
+	var updates []*pb.SchemaUpdate
+	var preds []string
+	for _, su := range schema.CompleteInitialSchema(ns) {
+		if x.IsEdgePredicate(x.ParseAttr(su.Predicate)) {
+			updates = append(updates, su)
+			preds = append(preds, su.Predicate)
+		}
+	}
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{Predicates: preds})
+	if err != nil {
+		return false, errors.Wrapf(err, "while reading the schema of edge nodes")
+	}
+	if len(nodes) == len(preds) {
+		return true, nil
+	}
+	if dryRun {
+		return false, nil
+	}
+
+	// Creating the schema is an alter, which the user must be allowed to do.
+	var sb strings.Builder
+	for _, su := range updates {
+		fmt.Fprintf(&sb, "%s: %s .\n", x.ParseAttr(su.Predicate),
+			types.TypeID(su.ValueType).Name())
+	}
+	if err := authorizeAlter(ctx, &api.Operation{Schema: sb.String()}); err != nil {
+		return false, errors.Wrapf(err, "while creating the schema of edge nodes")
+	}
+	m := &pb.Mutations{StartTs: worker.State.GetTimestamp(false), Schema: updates}
+	if _, err := query.ApplyMutations(ctx, m); err != nil {
+		return false, errors.Wrapf(err, "while creating the schema of edge nodes")
+	}
+	return true, worker.WaitForIndexing(ctx, true)
+}
+
+// resolveEdgeNodes replaces the quoted triples used as subjects in the mutations with the
+// edge nodes of the edges they quote. An edge node which doesn't exist yet is created by the
+// set N-Quads as a blank node, while delete N-Quads on it are dropped.
+func resolveEdgeNodes(ctx context.Context, qc *queryContext) error {
+	// nodes maps the quoted triples to their edge nodes.
+	nodes := make(map[string]string)
+	var existing []string
+	for _, gmu := range qc.gmuList {
+		for _, nq := range append(gmu.Set, gmu.Del...) {
+			s, _, o, ok := chunker.ParseQuotedTriple(nq.Subject)
+			if _, seen := nodes[nq.Subject]; !ok || seen {
+				continue
+			}
+			nodes[nq.Subject] = ""
+			if !strings.HasPrefix(s, "_:") && !strings.HasPrefix(o, "_:") {
+				existing = append(existing, nq.Subject)
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	ok, err := ensureEdgeSchema(ctx, qc.dryRun)
+	if err != nil {
+		return err
+	}
+	if ok && len(existing) > 0 {
+		var sb strings.Builder
+		sb.WriteString("{")
+		for i, quoted := range existing {
+			s, p, o, _ := chunker.ParseQuotedTriple(quoted)
+			fmt.Fprintf(&sb, " e%d(func: uid(%s)) { ~%s @filter(eq(%s, %s) AND uid_in(%s, %s))"+
+				" { uid } }", i, s, edgeSrc, edgePred, strconv.Quote(p), edgeDst, o)
+		}
+		sb.WriteString(" }")
+		resp, err := (&Server{}).doQuery(ctx, &Request{
+			req:    &api.Request{Query: sb.String(), StartTs: qc.req.StartTs, ReadOnly: true},
+			doAuth: NoAuthorize,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "while looking up edge nodes")
+		}
+		var res map[string][]map[string][]map[string]string
+		if err := json.Unmarshal(resp.Json, &res); err != nil {
+			return errors.Wrapf(err, "while looking up edge nodes")
+		}
+		for i, quoted := range existing {
+			for _, src := range res[fmt.Sprintf("e%d", i)] {
+				if edges := src["~"+edgeSrc]; len(edges) > 0 {
+					nodes[quoted] = edges[0]["uid"]
+				}
+			}
+		}
+	}
+
+	created := make(map[string]struct{})
+	for _, gmu := range qc.gmuList {
+		var set, del []*api.NQuad
+		for _, nq := range gmu.Set {
+			s, p, o, ok := chunker.ParseQuotedTriple(nq.Subject)
+			if !ok {
+				set = append(set, nq)
+				continue
+			}
+			node := nodes[nq.Subject]
+			if node == "" {
+				// The blank node is named after the quoted triple, so that the uid of the new edge
+				// node is returned for it.
+				node = "_:" + nq.Subject
+				if _, ok := created[node]; !ok {
+					created[node] = struct{}{}
+					set = append(set,
+						&api.NQuad{Subject: node, Predicate: edgeSrc, ObjectId: s},
+						&api.NQuad{Subject: node, Predicate: edgeDst, ObjectId: o},
+						&api.NQuad{Subject: node, Predicate: edgePred,
+							ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: p}}})
+					if key, ok := edgeKeyOf(s, p, o); ok {
+						set = append(set, &api.NQuad{Subject: node, Predicate: edgeKey,
+							ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: key}}})
+					}
+				}
+			}
+			nq.Subject = node
+			set = append(set, nq)
+		}
+		for _, nq := range gmu.Del {
+			if _, _, _, ok := chunker.ParseQuotedTriple(nq.Subject); ok {
+				if nq.Subject = nodes[nq.Subject]; nq.Subject == "" {
+					continue
+				}
+			}
+			del = append(del, nq)
+		}
+		gmu.Set, gmu.Del = set, del
+	}
+	return nil
+}
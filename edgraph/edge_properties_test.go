@@ -0,0 +1,80 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/chunker"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestAssertQuotedEdges(t *testing.T) {
+	since := &api.Value{Val: &api.Value_IntVal{IntVal: 2019}}
+	quoted := chunker.QuotedTriple("0x1", "friend", "_:bob")
+	set := []*api.NQuad{
+		{Subject: quoted, Predicate: "since", ObjectValue: since},
+		{Subject: quoted, Predicate: "weight", ObjectValue: since},
+		{Subject: "0x1", Predicate: "name", ObjectValue: since},
+	}
+	set, err := assertQuotedEdges(set, nil)
+	require.NoError(t, err)
+	// The quoted edge is set once, however many of its properties are.
+	require.Equal(t, 4, len(set))
+	require.Equal(t, &api.NQuad{Subject: "0x1", Predicate: "friend", ObjectId: "_:bob"}, set[3])
+
+	for _, bad := range []string{
+		chunker.QuotedTriple("alice", "friend", "0x2"),
+		chunker.QuotedTriple("0x1", edgeSrc, "0x2"),
+	} {
+		_, err := assertQuotedEdges([]*api.NQuad{{Subject: bad, Predicate: "since",
+			ObjectValue: since}}, nil)
+		require.Error(t, err, bad)
+	}
+	_, err = assertQuotedEdges(nil, []*api.NQuad{{Subject: quoted, Predicate: x.Star,
+		ObjectValue: &api.Value{Val: &api.Value_DefaultVal{DefaultVal: x.Star}}}})
+	require.Error(t, err)
+}
+
+func TestEdgeKey(t *testing.T) {
+	key, ok := edgeKeyOf("1", "friend", "0x2")
+	require.True(t, ok)
+	require.Equal(t, "0x1 friend 0x2", key)
+
+	// Edges to new nodes have no key, no other txn can create their edge node.
+	_, ok = edgeKeyOf("_:alice", "friend", "0x2")
+	require.False(t, ok)
+}
+
+func TestEdgeSchemaIsPreDefined(t *testing.T) {
+	var preds []string
+	for _, su := range schema.CompleteInitialSchema(x.GalaxyNamespace) {
+		if x.IsEdgePredicate(x.ParseAttr(su.Predicate)) {
+			require.True(t, x.IsPreDefinedPredicate(su.Predicate))
+			require.False(t, schema.IsPreDefPredChanged(su))
+			preds = append(preds, x.ParseAttr(su.Predicate))
+		}
+	}
+	require.ElementsMatch(t, []string{edgeSrc, edgeDst, edgePred, edgeKey}, preds)
+
+	// Edge nodes can be loaded back from an export.
+	require.False(t, x.IsGraphqlReservedPredicate(edgeSrc))
+}
@@ -0,0 +1,615 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/ee/enc"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const (
+	// ErasureDefaults are the default values for the --erasure superflag.
+	ErasureDefaults = "follow=; depth=3; signing-key-file="
+
+	// erasureBatch is the number of edges deleted per transaction by an erasure, and the number
+	// of nodes whose data is read per query.
+	erasureBatch = 1000
+)
+
+// erasurePolicy is the policy of the erasures of data subjects, set via the --erasure flag.
+type erasurePolicy struct {
+	// follow are the uid predicates, or the reverse ones with a ~ prefix, leading from a node to
+	// the nodes erased along with it.
+	follow []string
+	depth  int
+	key    ed25519.PrivateKey
+}
+
+var erasure = &erasurePolicy{depth: 3}
+
+// SetErasure parses the --erasure superflag and sets the policy of the erasures run afterwards.
+func SetErasure(sf *z.SuperFlag) error {
+	p := &erasurePolicy{
+		follow: erasureFollow(sf.GetString("follow")),
+		depth:  int(sf.GetInt64("depth")),
+	}
+	if p.depth < 0 {
+		return errors.Errorf("The depth of the erasures can't be negative")
+	}
+	for _, pred := range p.follow {
+		if x.IsReservedPredicate(x.GalaxyAttr(strings.TrimPrefix(pred, "~"))) {
+			return errors.Errorf("Can't follow reserved predicate %s in the erasures", pred)
+		}
+	}
+	if file := sf.GetString("signing-key-file"); file != "" {
+		key, err := enc.ReadSigningKey(file)
+		if err != nil {
+			return errors.Wrap(err, "while reading the signing key of the erasure reports")
+		}
+		if key == nil {
+			return errors.Errorf("Signing the erasure reports is an enterprise feature")
+		}
+		p.key = key
+	}
+	erasure = p
+	return nil
+}
+
+func erasureFollow(s string) []string {
+	follow := []string{}
+	for _, pred := range strings.Split(s, ",") {
+		if pred = strings.TrimSpace(pred); pred != "" {
+			follow = append(follow, pred)
+		}
+	}
+	return follow
+}
+
+// ErasureRequest is a request to erase a data subject.
+type ErasureRequest struct {
+	Subject uint64
+	// Follow and Depth replace the ones of the policy if Follow isn't nil and Depth isn't
+	// negative.
+	Follow []string
+	Depth  int
+}
+
+// ErasedNode is a node deleted by an erasure, with the predicates it had.
+type ErasedNode struct {
+	Uid        string   `json:"uid"`
+	Predicates []string `json:"predicates"`
+}
+
+// ErasedEdge is an edge from a node which isn't erased to an erased node, deleted by an erasure.
+type ErasedEdge struct {
+	From      string `json:"from"`
+	Predicate string `json:"predicate"`
+	To        string `json:"to"`
+}
+
+// ErasureReport tells what an erasure deleted. It holds no value of the deleted predicates.
+type ErasureReport struct {
+	Namespace    uint64        `json:"namespace"`
+	Subject      string        `json:"subject"`
+	Follow       []string      `json:"follow"`
+	Depth        int           `json:"depth"`
+	Nodes        []*ErasedNode `json:"nodes"`
+	InboundEdges []*ErasedEdge `json:"inboundEdges"`
+	Requester    string        `json:"requester"`
+	At           time.Time     `json:"at"`
+}
+
+// ErasureResult is the result of EraseSubject. The marker holds the JSON encoded ErasureReport
+// and its signature.
+type ErasureResult struct {
+	Marker *pb.ErasureMarker
+	TaskId uint64
+}
+
+// erasureKey returns the key signing the erasure reports: the one of the --erasure flag, or
+// else the signing key of the backups.
+func erasureKey() ed25519.PrivateKey {
+	if erasure.key != nil {
+		return erasure.key
+	}
+	return x.WorkerConfig.BackupSigningKey
+}
+
+// EraseSubject deletes the node of the data subject from the namespace in ctx, along with the
+// nodes reached from it through the followed predicates and the edges leading to all of them
+// from the other nodes. The signed report of the erasure is recorded in Zero as a marker before
+// any data is deleted, so that an erasure which fails halfway is still applied again after a
+// restore.
+func EraseSubject(ctx context.Context, r *ErasureRequest) (*ErasureResult, error) {
+	key := erasureKey()
+	if key == nil {
+		return nil, errors.Errorf("Erasing a subject needs the key signing the report, set " +
+			"with --erasure signing-key-file or --backup_signing_key_file")
+	}
+	namespace, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	follow, depth := erasure.follow, erasure.depth
+	if r.Follow != nil {
+		follow = r.Follow
+	}
+	if r.Depth >= 0 {
+		depth = r.Depth
+	}
+
+	res := &ErasureResult{}
+	opts := worker.TaskOptions{
+		Kind:        "erasure",
+		Description: fmt.Sprintf("Erase subject %#x of namespace %d", r.Subject, namespace),
+	}
+	err = worker.RunTask(ctx, opts, func(ctx context.Context, t *worker.Task) error {
+		res.TaskId = t.Id()
+		preds, err := erasurePredicates(ctx, namespace)
+		if err != nil {
+			return err
+		}
+		uids, err := erasureNodes(ctx, preds, r.Subject, follow, depth)
+		if err != nil {
+			return err
+		}
+		report := &ErasureReport{
+			Namespace: namespace,
+			Subject:   fmt.Sprintf("%#x", r.Subject),
+			Follow:    follow,
+			Depth:     depth,
+			Requester: requestUser(ctx),
+			At:        time.Now().UTC(),
+		}
+		if report.Nodes, report.InboundEdges, err = erasureData(ctx, preds, uids); err != nil {
+			return err
+		}
+		if len(report.Nodes[0].Predicates) == 0 {
+			return errors.Errorf("Subject %#x has no data in namespace %d", r.Subject, namespace)
+		}
+
+		data, err := json.Marshal(report)
+		if err != nil {
+			return err
+		}
+		res.Marker = &pb.ErasureMarker{
+			Report:    string(data),
+			Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)),
+		}
+		if err := worker.UpdateErasures(ctx, &pb.ErasureUpdate{Marker: res.Marker}); err != nil {
+			return errors.Wrap(err, "while recording the erasure marker")
+		}
+		return erase(ctx, report.Nodes, report.InboundEdges, passwordPredicates(preds))
+	})
+	return res, err
+}
+
+// erasurePredicates returns the predicates of the namespace which an erasure deletes, by name.
+// These are all the predicates but the reserved ones, except dgraph.type.
+func erasurePredicates(ctx context.Context, namespace uint64) (map[string]*pb.SchemaNode,
+	error) {
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{})
+	if err != nil {
+		return nil, err
+	}
+	preds := make(map[string]*pb.SchemaNode)
+	for _, node := range nodes {
+		ns, attr := x.ParseNamespaceAttr(node.Predicate)
+		if ns != namespace || (x.IsReservedPredicate(node.Predicate) && attr != "dgraph.type") {
+			continue
+		}
+		preds[attr] = node
+	}
+	return preds, nil
+}
+
+// erasureNodes returns the subject and the nodes reached from it through the followed
+// predicates, at most depth edges away. The subject comes first.
+func erasureNodes(ctx context.Context, preds map[string]*pb.SchemaNode, subject uint64,
+	follow []string, depth int) ([]uint64, error) {
+	for _, pred := range follow {
+		node, ok := preds[strings.TrimPrefix(pred, "~")]
+		switch {
+		case !ok || node.Type != "uid":
+			return nil, errors.Errorf("Can't follow %s, which isn't a uid predicate", pred)
+		case strings.HasPrefix(pred, "~") && !node.Reverse:
+			return nil, errors.Errorf("Can't follow %s, which has no @reverse index", pred)
+		}
+	}
+
+	uids := []uint64{subject}
+	seen := map[uint64]struct{}{subject: {}}
+	frontier := uids
+	for d := 0; d < depth && len(follow) > 0 && len(frontier) > 0; d++ {
+		var b strings.Builder
+		fmt.Fprintf(&b, "{\n\tq(func: uid(%s)) {\n", uidList(frontier))
+		for i, pred := range follow {
+			fmt.Fprintf(&b, "\t\tf%d: %s { uid }\n", i, erasurePredicate(pred))
+		}
+		b.WriteString("\t}\n}")
+		var res struct {
+			Q []map[string]json.RawMessage `json:"q"`
+		}
+		if err := erasureQuery(ctx, b.String(), &res); err != nil {
+			return nil, errors.Wrap(err, "while finding the nodes to erase")
+		}
+
+		var next []uint64
+		for _, m := range res.Q {
+			for _, raw := range m {
+				for _, uid := range uidsOf(raw) {
+					if _, ok := seen[uid]; !ok {
+						seen[uid] = struct{}{}
+						next = append(next, uid)
+					}
+				}
+			}
+		}
+		uids = append(uids, next...)
+		frontier = next
+	}
+	return uids, nil
+}
+
+// erasureData returns the predicates of the nodes, in the order of uids, and the edges leading
+// to them from the other nodes through the @reverse predicates. The data of the nodes is read
+// directly, in batches of erasureBatch nodes, so that the cost doesn't grow with the size of the
+// predicates. The edges through the other uid predicates can't be found without a scan, and are
+// left for the queries to skip, as they lead to nodes with no data.
+//
+// The values of the password predicates can't be read, so they're not listed. erase deletes
+// them from every node.
+func erasureData(ctx context.Context, preds map[string]*pb.SchemaNode, uids []uint64) (
+	[]*ErasedNode, []*ErasedEdge, error) {
+	names := make([]string, 0, len(preds))
+	for attr := range preds {
+		names = append(names, attr)
+	}
+	sort.Strings(names)
+	erased := make(map[uint64]struct{}, len(uids))
+	for _, uid := range uids {
+		erased[uid] = struct{}{}
+	}
+
+	has := make(map[uint64][]string)
+	var inbound []*ErasedEdge
+	for start := 0; start < len(uids); start += erasureBatch {
+		end := start + erasureBatch
+		if end > len(uids) {
+			end = len(uids)
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "{\n\tq(func: uid(%s)) {\n\t\tuid\n", uidList(uids[start:end]))
+		for i, attr := range names {
+			switch node := preds[attr]; {
+			case node.Type == "password":
+			case node.Type == "uid":
+				fmt.Fprintf(&b, "\t\tp%d: count(<%s>)\n", i, attr)
+				if node.Reverse {
+					fmt.Fprintf(&b, "\t\ti%d: <~%s> { uid }\n", i, attr)
+				}
+			case node.Lang:
+				fmt.Fprintf(&b, "\t\t<%s>@*\n", attr)
+			default:
+				fmt.Fprintf(&b, "\t\tp%d: <%s>\n", i, attr)
+			}
+		}
+		b.WriteString("\t}\n}")
+		var res struct {
+			Q []map[string]json.RawMessage `json:"q"`
+		}
+		if err := erasureQuery(ctx, b.String(), &res); err != nil {
+			return nil, nil, errors.Wrap(err, "while finding the data to erase")
+		}
+
+		for _, m := range res.Q {
+			to := uidsOf(m["uid"])
+			if len(to) != 1 {
+				continue
+			}
+			for i, attr := range names {
+				if erasedValue(m, i, attr, preds[attr]) {
+					has[to[0]] = append(has[to[0]], attr)
+				}
+				for _, from := range uidsOf(m[fmt.Sprintf("i%d", i)]) {
+					if _, ok := erased[from]; ok {
+						// The edges of the erased nodes are deleted anyway.
+						continue
+					}
+					inbound = append(inbound, &ErasedEdge{
+						From:      fmt.Sprintf("%#x", from),
+						Predicate: attr,
+						To:        fmt.Sprintf("%#x", to[0]),
+					})
+				}
+			}
+		}
+	}
+
+	nodes := make([]*ErasedNode, 0, len(uids))
+	for _, uid := range uids {
+		node := &ErasedNode{Uid: fmt.Sprintf("%#x", uid), Predicates: has[uid]}
+		if node.Predicates == nil {
+			node.Predicates = []string{}
+		}
+		nodes = append(nodes, node)
+	}
+	if inbound == nil {
+		inbound = []*ErasedEdge{}
+	}
+	return nodes, inbound, nil
+}
+
+// erasedValue tells whether the node m, read by erasureData, has a value of the i-th predicate.
+func erasedValue(m map[string]json.RawMessage, i int, attr string, node *pb.SchemaNode) bool {
+	raw, ok := m[fmt.Sprintf("p%d", i)]
+	switch {
+	case node.Type == "uid":
+		var count int
+		return ok && json.Unmarshal(raw, &count) == nil && count > 0
+	case node.Lang:
+		for key := range m {
+			if key == attr || strings.HasPrefix(key, attr+"@") {
+				return true
+			}
+		}
+		return false
+	default:
+		return ok
+	}
+}
+
+// passwordPredicates returns the password predicates among preds.
+func passwordPredicates(preds map[string]*pb.SchemaNode) []string {
+	var res []string
+	for attr, node := range preds {
+		if node.Type == "password" {
+			res = append(res, attr)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// erase deletes the predicates of the nodes, along with the password predicates, and the edges
+// leading to them, in transactions of at most erasureBatch edges.
+func erase(ctx context.Context, nodes []*ErasedNode, inbound []*ErasedEdge,
+	passwords []string) error {
+	var del []*api.NQuad
+	flush := func(force bool) error {
+		if len(del) == 0 || (!force && len(del) < erasureBatch) {
+			return nil
+		}
+		_, err := (&Server{}).doQuery(ctx, &Request{
+			req: &api.Request{
+				Mutations: []*api.Mutation{{Del: del}},
+				CommitNow: true,
+			},
+			doAuth: NoAuthorize,
+		})
+		del = nil
+		return errors.Wrap(err, "while erasing the data")
+	}
+	star := &api.Value{Val: &api.Value_DefaultVal{DefaultVal: x.Star}}
+	for _, node := range nodes {
+		for _, attr := range passwords {
+			del = append(del, &api.NQuad{Subject: node.Uid, Predicate: attr, ObjectValue: star})
+		}
+		if len(node.Predicates) > 0 {
+			// Deleting all the predicates of the node also deletes its audit trail.
+			del = append(del, &api.NQuad{Subject: node.Uid, Predicate: x.Star, ObjectValue: star})
+		}
+		for _, attr := range node.Predicates {
+			del = append(del, &api.NQuad{Subject: node.Uid, Predicate: attr, ObjectValue: star})
+		}
+		if err := flush(false); err != nil {
+			return err
+		}
+	}
+	for _, e := range inbound {
+		del = append(del, &api.NQuad{Subject: e.From, Predicate: e.Predicate, ObjectId: e.To})
+		if err := flush(false); err != nil {
+			return err
+		}
+	}
+	return flush(true)
+}
+
+func erasurePredicate(pred string) string {
+	if strings.HasPrefix(pred, "~") {
+		return "<~" + pred[1:] + ">"
+	}
+	return "<" + pred + ">"
+}
+
+func erasureQuery(ctx context.Context, query string, res interface{}) error {
+	resp, err := (&Server{}).doQuery(ctx, &Request{
+		req:    &api.Request{Query: query, ReadOnly: true},
+		doAuth: NoAuthorize,
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(resp.GetJson(), res)
+}
+
+func uidList(uids []uint64) string {
+	list := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		list = append(list, fmt.Sprintf("%#x", uid))
+	}
+	return strings.Join(list, ", ")
+}
+
+// uidsOf returns the uids of a result which is a uid, a node or a list of nodes.
+func uidsOf(raw json.RawMessage) []uint64 {
+	var nodes []struct {
+		Uid string `json:"uid"`
+	}
+	raw = bytes.TrimSpace(raw)
+	switch {
+	case len(raw) == 0:
+		return nil
+	case raw[0] == '"':
+		var uid string
+		if err := json.Unmarshal(raw, &uid); err != nil {
+			return nil
+		}
+		nodes = append(nodes, struct {
+			Uid string `json:"uid"`
+		}{uid})
+	case raw[0] == '{':
+		raw = append(append([]byte{'['}, raw...), ']')
+		fallthrough
+	default:
+		if err := json.Unmarshal(raw, &nodes); err != nil {
+			return nil
+		}
+	}
+	uids := make([]uint64, 0, len(nodes))
+	for _, n := range nodes {
+		if uid, err := strconv.ParseUint(n.Uid, 0, 64); err == nil {
+			uids = append(uids, uid)
+		}
+	}
+	return uids
+}
+
+// ErasureMarkers returns the markers of the erasures run in the namespace in ctx, or in all the
+// namespaces for the galaxy namespace.
+func ErasureMarkers(ctx context.Context) ([]*pb.ErasureMarker, error) {
+	namespace, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state, err := worker.Erasures(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "while reading the erasure markers")
+	}
+	markers := state.Markers
+	if namespace != x.GalaxyNamespace {
+		markers = markers[:0]
+		for _, m := range state.Markers {
+			var report ErasureReport
+			if err := json.Unmarshal([]byte(m.Report), &report); err == nil &&
+				report.Namespace == namespace {
+				markers = append(markers, m)
+			}
+		}
+	}
+	sort.Slice(markers, func(i, j int) bool { return markers[i].Report < markers[j].Report })
+	return markers, nil
+}
+
+// ReapplyErasures runs again all the erasures if the data was restored since they were last
+// applied, so that the restored backups don't bring back the erased data. It then records in
+// Zero that the erasures are applied up to the last restore.
+func ReapplyErasures(ctx context.Context) error {
+	state, err := worker.Erasures(ctx)
+	if err != nil {
+		return errors.Wrap(err, "while reading the erasure markers")
+	}
+	if state.Restores <= state.Reapplied {
+		return nil
+	}
+	glog.Infof("Applying %d erasures again after restore %d", len(state.Markers),
+		state.Restores)
+
+	preds := make(map[uint64]map[string]*pb.SchemaNode)
+	for _, m := range state.Markers {
+		var report ErasureReport
+		if err := json.Unmarshal([]byte(m.Report), &report); err != nil {
+			glog.Errorf("Unable to read the erasure report %q: %v", m.Report, err)
+			continue
+		}
+		uids := make([]uint64, 0, len(report.Nodes))
+		for _, node := range report.Nodes {
+			if uid, err := strconv.ParseUint(node.Uid, 0, 64); err == nil {
+				uids = append(uids, uid)
+			}
+		}
+		ctx := x.AttachNamespace(ctx, report.Namespace)
+		if _, ok := preds[report.Namespace]; !ok {
+			if preds[report.Namespace], err = erasurePredicates(ctx, report.Namespace); err != nil {
+				return err
+			}
+		}
+		nodes, inbound, err := erasureData(ctx, preds[report.Namespace], uids)
+		if err == nil {
+			err = erase(ctx, nodes, inbound, passwordPredicates(preds[report.Namespace]))
+		}
+		if err != nil {
+			return errors.Wrapf(err, "while applying again the erasure of subject %s of "+
+				"namespace %d", report.Subject, report.Namespace)
+		}
+	}
+	return worker.UpdateErasures(ctx, &pb.ErasureUpdate{Reapplied: state.Restores})
+}
+
+// ReapplyPendingErasures runs ReapplyErasures at startup, until it succeeds or the closer is
+// signaled, so that the erasures are applied again after a restore done by the restore command
+// while the Alphas were down. It runs on the Alphas of group 1 only.
+func ReapplyPendingErasures(closer *z.Closer) {
+	defer closer.Done()
+	if worker.GroupId() != 1 {
+		return
+	}
+	for {
+		ctx, cancel := context.WithTimeout(closer.Ctx(), 10*time.Minute)
+		err := ReapplyErasures(ctx)
+		cancel()
+		if err == nil {
+			return
+		}
+		glog.Warningf("Unable to apply the erasures again after a restore, retrying: %v", err)
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+// VerifyErasureMarker checks the signature of the report of the marker.
+func VerifyErasureMarker(m *pb.ErasureMarker, key ed25519.PublicKey) error {
+	sig, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return errors.Wrap(err, "while decoding the signature of the erasure report")
+	}
+	if !ed25519.Verify(key, []byte(m.Report), sig) {
+		return errors.Errorf("The signature of the erasure report is invalid")
+	}
+	return nil
+}
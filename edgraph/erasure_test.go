@@ -0,0 +1,72 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+func TestUidsOf(t *testing.T) {
+	require.Equal(t, []uint64{0x1}, uidsOf([]byte(`"0x1"`)))
+	require.Equal(t, []uint64{0x2}, uidsOf([]byte(`{"uid": "0x2"}`)))
+	require.Equal(t, []uint64{0x3, 0x4}, uidsOf([]byte(`[{"uid": "0x3"}, {"uid": "0x4"}]`)))
+	require.Empty(t, uidsOf(nil))
+	require.Empty(t, uidsOf([]byte(`"name"`)))
+}
+
+func TestVerifyErasureMarker(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	report := `{"namespace":0,"subject":"0x1"}`
+	m := &pb.ErasureMarker{
+		Report:    report,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(report))),
+	}
+	require.NoError(t, VerifyErasureMarker(m, pub))
+
+	m.Report = `{"namespace":0,"subject":"0x2"}`
+	require.Error(t, VerifyErasureMarker(m, pub))
+}
+
+func TestErasedValue(t *testing.T) {
+	preds := map[string]*pb.SchemaNode{
+		"friend": {Type: "uid"},
+		"name":   {Type: "string", Lang: true},
+		"age":    {Type: "int"},
+		"pass":   {Type: "password"},
+	}
+	var m map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal([]byte(`{"uid": "0x1", "p1": 0, "name@en": "Alice",
+		"p0": 30}`), &m))
+	require.True(t, erasedValue(m, 0, "age", preds["age"]))
+	require.False(t, erasedValue(m, 1, "friend", preds["friend"]))
+	require.True(t, erasedValue(m, 2, "name", preds["name"]))
+
+	m["p1"] = json.RawMessage(`2`)
+	delete(m, "name@en")
+	require.True(t, erasedValue(m, 1, "friend", preds["friend"]))
+	require.False(t, erasedValue(m, 2, "name", preds["name"]))
+
+	require.Equal(t, []string{"pass"}, passwordPredicates(preds))
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200"
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/query"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the ErrorInfo details attached to the errors returned to gRPC
+// clients.
+const ErrorDomain = "dgraph.io"
+
+// The reasons set in the ErrorInfo details attached to the errors returned to gRPC clients. The
+// metadata of the ErrorInfo has a "retryable" key which tells whether the request can be retried
+// as is. Errors for aborted transactions also carry the keys of the transaction in the
+// "conflict_keys" key.
+const (
+	ReasonTxnAborted       = "TXN_ABORTED"
+	ReasonTxnConflict      = "TXN_CONFLICT"
+	ReasonReadOnly         = "READ_ONLY"
+	ReasonOverloaded       = "OVERLOADED"
+	ReasonRateLimited      = "RATE_LIMITED"
+	ReasonQueryMemoryLimit = "QUERY_MEMORY_LIMIT"
+	ReasonDiskFull         = "DISK_FULL"
+	ReasonNotReady         = "NOT_READY"
+	ReasonDraining         = "DRAINING"
+	ReasonUnauthenticated  = "UNAUTHENTICATED"
+	ReasonPermissionDenied = "PERMISSION_DENIED"
+	ReasonCanceled         = "CANCELED"
+	ReasonUnknown          = "UNKNOWN"
+)
+
+// retryDelay is the delay suggested to clients before retrying a request rejected because the
+// server is overloaded or not ready yet.
+const retryDelay = time.Second
+
+// newStatusError returns a gRPC status error with the given code, carrying an ErrorInfo with the
+// given reason along with the extra details.
+func newStatusError(code codes.Code, reason string, err error, retryable bool,
+	extra ...proto.Message) error {
+	return withDetails(status.New(code, err.Error()), errorInfo(reason, retryable, nil), extra...)
+}
+
+// withDetails attaches info and the extra details to st. Retryable errors get a RetryInfo with
+// no delay, unless one is passed in extra.
+func withDetails(st *status.Status, info *errdetails.ErrorInfo, extra ...proto.Message) error {
+	details := append([]proto.Message{info}, extra...)
+	hasRetryInfo := false
+	for _, d := range extra {
+		if _, ok := d.(*errdetails.RetryInfo); ok {
+			hasRetryInfo = true
+		}
+	}
+	if info.Metadata["retryable"] == "true" && !hasRetryInfo {
+		details = append(details, retryInfo(0))
+	}
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		// The details couldn't be marshalled. Return the error without them.
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+func errorInfo(reason string, retryable bool, keys []string) *errdetails.ErrorInfo {
+	info := &errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   ErrorDomain,
+		Metadata: map[string]string{"retryable": strconv.FormatBool(retryable)},
+	}
+	if len(keys) > 0 {
+		info.Metadata["conflict_keys"] = strings.Join(keys, ",")
+	}
+	return info
+}
+
+func retryInfo(delay time.Duration) *errdetails.RetryInfo {
+	return &errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(delay)}
+}
+
+func quotaFailure(subject, description string) *errdetails.QuotaFailure {
+	return &errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{
+			{Subject: subject, Description: description},
+		},
+	}
+}
+
+// txnKeys returns the keys of the transaction the request or response belongs to.
+func txnKeys(req, resp interface{}) []string {
+	if r, ok := resp.(*api.Response); ok && len(r.GetTxn().GetKeys()) > 0 {
+		return r.Txn.Keys
+	}
+	if tc, ok := req.(*api.TxnContext); ok {
+		return tc.Keys
+	}
+	return nil
+}
+
+// WithErrorDetails attaches an ErrorInfo, and a RetryInfo or QuotaFailure where it applies, to an
+// error returned to a gRPC client, so that clients can implement uniform retry policies. Errors
+// which already carry details are returned as is. The code of the error isn't changed.
+func WithErrorDetails(err error, req, resp interface{}) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if ok && len(st.Details()) > 0 {
+		return err
+	}
+
+	cause := errors.Cause(err)
+	switch {
+	case st.Code() == codes.Aborted || cause == dgo.ErrAborted:
+		return withDetails(st, errorInfo(ReasonTxnAborted, true, txnKeys(req, resp)))
+	case cause == query.ErrQueryMemoryLimit:
+		return withDetails(st, errorInfo(ReasonQueryMemoryLimit, false, nil),
+			quotaFailure("query_memory_mb", err.Error()))
+	case cause == worker.ErrDiskFull:
+		return withDetails(st, errorInfo(ReasonDiskFull, false, nil),
+			quotaFailure("disk", err.Error()))
+	case cause == x.ErrHealth:
+		return withDetails(st, errorInfo(ReasonNotReady, true, nil), retryInfo(retryDelay))
+	case cause == x.ErrDrainingMode:
+		return withDetails(st, errorInfo(ReasonDraining, false, nil))
+	case st.Code() == codes.Unauthenticated:
+		return withDetails(st, errorInfo(ReasonUnauthenticated, false, nil))
+	case st.Code() == codes.PermissionDenied:
+		return withDetails(st, errorInfo(ReasonPermissionDenied, false, nil))
+	case st.Code() == codes.Canceled || st.Code() == codes.DeadlineExceeded ||
+		cause == context.Canceled || cause == context.DeadlineExceeded:
+		return withDetails(st, errorInfo(ReasonCanceled, false, nil))
+	}
+	return withDetails(st, errorInfo(ReasonUnknown, false, nil))
+}
+
+// ErrorDetailsInterceptor is a gRPC unary interceptor which attaches error details to the errors
+// returned by handler, as described in WithErrorDetails.
+func ErrorDetailsInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	return resp, WithErrorDetails(err, req, resp)
+}
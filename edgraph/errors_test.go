@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgo/v200"
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func errorDetails(t *testing.T, err error) (codes.Code, *errdetails.ErrorInfo,
+	*errdetails.RetryInfo) {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	var info *errdetails.ErrorInfo
+	var retry *errdetails.RetryInfo
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.RetryInfo:
+			retry = d
+		}
+	}
+	require.NotNil(t, info)
+	require.Equal(t, ErrorDomain, info.Domain)
+	return st.Code(), info, retry
+}
+
+func TestWithErrorDetails(t *testing.T) {
+	require.NoError(t, WithErrorDetails(nil, nil, nil))
+
+	tc := &api.TxnContext{StartTs: 10, Keys: []string{"a", "b"}}
+	code, info, retry := errorDetails(t,
+		WithErrorDetails(status.Error(codes.Aborted, dgo.ErrAborted.Error()), tc, nil))
+	require.Equal(t, codes.Aborted, code)
+	require.Equal(t, ReasonTxnAborted, info.Reason)
+	require.Equal(t, "true", info.Metadata["retryable"])
+	require.Equal(t, "a,b", info.Metadata["conflict_keys"])
+	require.NotNil(t, retry)
+
+	code, info, retry = errorDetails(t,
+		WithErrorDetails(errors.Wrapf(x.ErrHealth, "while querying"), nil, nil))
+	require.Equal(t, codes.Unknown, code)
+	require.Equal(t, ReasonNotReady, info.Reason)
+	require.Equal(t, int64(1), retry.RetryDelay.Seconds)
+
+	code, info, retry = errorDetails(t, WithErrorDetails(errors.New("bad query"), nil, nil))
+	require.Equal(t, codes.Unknown, code)
+	require.Equal(t, ReasonUnknown, info.Reason)
+	require.Equal(t, "false", info.Metadata["retryable"])
+	require.Nil(t, retry)
+
+	// Errors which already carry details are left as is.
+	err := newStatusError(codes.FailedPrecondition, ReasonReadOnly, errors.New("read-only"), false)
+	require.Equal(t, err, WithErrorDetails(err, nil, nil))
+	code, info, _ = errorDetails(t, err)
+	require.Equal(t, codes.FailedPrecondition, code)
+	require.Equal(t, ReasonReadOnly, info.Reason)
+}
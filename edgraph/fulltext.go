@@ -0,0 +1,118 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/query"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/tok"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// FulltextDictionary is the dictionary of the fulltext index of a predicate. The words of a group
+// of synonyms are indexed and searched as the first word of the group, and the protected words
+// are neither dropped as stop words nor stemmed.
+type FulltextDictionary struct {
+	Predicate string
+	Synonyms  [][]string
+	Protected []string
+}
+
+// GetFulltextDictionary returns the dictionary of the fulltext index of the predicate of the
+// namespace in the context.
+func GetFulltextDictionary(ctx context.Context, pred string) (*FulltextDictionary, error) {
+	node, err := fulltextSchema(ctx, pred, "fulltext_dictionary")
+	if err != nil {
+		return nil, err
+	}
+	d := &FulltextDictionary{Predicate: pred, Protected: node.FulltextProtected}
+	for _, group := range node.FulltextSynonyms {
+		d.Synonyms = append(d.Synonyms, group.Words)
+	}
+	return d, nil
+}
+
+// UpdateFulltextDictionary replaces the dictionary of the fulltext index of the predicate of the
+// namespace in the context, and waits until the index is rebuilt with it.
+func UpdateFulltextDictionary(ctx context.Context, d *FulltextDictionary) error {
+	if schema.State().IndexingInProgress() {
+		return errIndexingInProgress
+	}
+	if _, err := tok.NewDictionary(d.Synonyms, d.Protected); err != nil {
+		return err
+	}
+	if _, err := fulltextSchema(ctx, d.Predicate); err != nil {
+		return err
+	}
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The rest of the schema of the predicate is kept by the group serving it.
+	su := &pb.SchemaUpdate{
+		Predicate:         x.NamespaceAttr(ns, d.Predicate),
+		FulltextProtected: d.Protected,
+	}
+	for _, words := range d.Synonyms {
+		su.FulltextSynonyms = append(su.FulltextSynonyms, &pb.Synonyms{Words: words})
+	}
+	m := &pb.Mutations{
+		StartTs:            worker.State.GetTimestamp(false),
+		Schema:             []*pb.SchemaUpdate{su},
+		FulltextDictionary: true,
+	}
+	if _, err := query.ApplyMutations(ctx, m); err != nil {
+		return errors.Wrapf(err, "while updating the fulltext dictionary of %s", d.Predicate)
+	}
+	return worker.WaitForIndexing(ctx, true)
+}
+
+// fulltextSchema returns the schema of the predicate of the namespace in the context, with the
+// given fields besides its tokenizers. It fails unless the predicate has a fulltext index.
+func fulltextSchema(ctx context.Context, pred string, fields ...string) (*pb.SchemaNode, error) {
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attr := x.NamespaceAttr(ns, pred)
+	if x.IsReservedPredicate(attr) {
+		return nil, errors.Errorf("Predicate %s is reserved", pred)
+	}
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+		Predicates: []string{attr},
+		Fields:     append(fields, "tokenizer"),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading the schema of %s", pred)
+	}
+	if len(nodes) == 0 {
+		return nil, errors.Errorf("Predicate %s doesn't exist", pred)
+	}
+	for _, t := range nodes[0].Tokenizer {
+		if t == "fulltext" {
+			return nodes[0], nil
+		}
+	}
+	return nil, errors.Errorf("Predicate %s doesn't have a fulltext index", pred)
+}
@@ -0,0 +1,232 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. All rights reserved.
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package edgraph
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgraph/ee/acl"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// guestUser is the user id that requests served with a guest profile are authorized and logged as.
+const guestUser = "guest"
+
+// The operations a guest profile can allow.
+const (
+	guestQuery  = "query"
+	guestMutate = "mutate"
+	guestAlter  = "alter"
+)
+
+// guestProfile is the anonymous access allowed to a namespace. Requests to the namespace
+// without an access JWT are served with the permissions of the ACL group of the profile, for the
+// operations it allows, up to its rate of requests per second.
+type guestProfile struct {
+	namespace uint64
+	group     string
+	ops       map[string]bool
+	limiter   *guestLimiter
+}
+
+// guestProfiles holds the profiles of --acl_guest by namespace. It's only set at startup.
+var guestProfiles map[uint64]*guestProfile
+
+type guestKey struct{}
+
+// SetGuestProfiles parses and sets the guest profiles of --acl_guest. The profiles are separated
+// by semicolons, and each is a space separated list of key=value options, e.g.
+// "namespace=0 group=public ops=query rate=20; namespace=7 ops=query,mutate".
+func SetGuestProfiles(s string) error {
+	profiles, err := parseGuestProfiles(s)
+	if err != nil {
+		return err
+	}
+	guestProfiles = profiles
+	return nil
+}
+
+func parseGuestProfiles(s string) (map[uint64]*guestProfile, error) {
+	profiles := make(map[uint64]*guestProfile)
+	for _, spec := range strings.Split(s, ";") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		p, err := parseGuestProfile(spec)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing guest profile %q", strings.TrimSpace(spec))
+		}
+		if _, ok := profiles[p.namespace]; ok {
+			return nil, errors.Errorf("Namespace %d has more than one guest profile", p.namespace)
+		}
+		profiles[p.namespace] = p
+	}
+	return profiles, nil
+}
+
+func parseGuestProfile(spec string) (*guestProfile, error) {
+	p := &guestProfile{
+		group: "guest",
+		ops:   map[string]bool{guestQuery: true},
+	}
+	hasNamespace := false
+	for _, opt := range strings.Fields(spec) {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("Invalid option %q. Expected key=value", opt)
+		}
+		var err error
+		switch key, val := kv[0], kv[1]; key {
+		case "namespace":
+			p.namespace, err = strconv.ParseUint(val, 0, 64)
+			hasNamespace = true
+		case "group":
+			if val == "" || x.IsGuardian([]string{val}) {
+				return nil, errors.Errorf("Invalid guest group %q", val)
+			}
+			p.group = val
+		case "ops":
+			p.ops = make(map[string]bool)
+			for _, op := range strings.Split(val, ",") {
+				switch op {
+				case guestQuery, guestMutate, guestAlter:
+					p.ops[op] = true
+				default:
+					return nil, errors.Errorf("Invalid operation %q. Expected query, mutate "+
+						"or alter", op)
+				}
+			}
+		case "rate":
+			var rate float64
+			if rate, err = strconv.ParseFloat(val, 64); err != nil {
+				break
+			}
+			if !(rate > 0) {
+				return nil, errors.Errorf("Invalid rate %q. Expected a number above zero", val)
+			}
+			p.limiter = newGuestLimiter(rate)
+		default:
+			return nil, errors.Errorf("Unknown option %q", key)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing option %q", opt)
+		}
+	}
+	if !hasNamespace {
+		return nil, errors.Errorf("The namespace of the profile is missing")
+	}
+	return p, nil
+}
+
+// guestContext returns ctx for serving a request with the guest profile of its namespace, if it
+// has no access JWT. The namespace is the one the request was routed to, or else the galaxy
+// namespace. Requests of namespaces without a guest profile are left to fail authorization.
+func guestContext(ctx context.Context) (context.Context, error) {
+	if len(guestProfiles) == 0 {
+		return ctx, nil
+	}
+	if _, err := x.ExtractJwt(ctx); err != x.ErrNoJwt {
+		return ctx, nil
+	}
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		ns = x.GalaxyNamespace
+	}
+	p, ok := guestProfiles[ns]
+	if !ok {
+		return ctx, nil
+	}
+	if wait, ok := p.limiter.take(); !ok {
+		err := errors.Errorf("The rate limit of guest requests to namespace %d is exceeded", ns)
+		return nil, newStatusError(codes.ResourceExhausted, ReasonRateLimited, err, true,
+			retryInfo(wait), quotaFailure("guest_rate", err.Error()))
+	}
+	ctx = x.AttachNamespace(ctx, ns)
+	return context.WithValue(ctx, guestKey{}, p), nil
+}
+
+// guestOf returns the guest profile that the request of ctx is served with, if any.
+func guestOf(ctx context.Context) *guestProfile {
+	p, _ := ctx.Value(guestKey{}).(*guestProfile)
+	return p
+}
+
+// authorizeGuest checks that a request served with a guest profile is allowed to do op.
+func authorizeGuest(ctx context.Context, op string) error {
+	p := guestOf(ctx)
+	if p == nil || p.ops[op] {
+		return nil
+	}
+	return status.Errorf(codes.PermissionDenied,
+		"guests of namespace %d are not allowed to %s", p.namespace, op)
+}
+
+// guestPredicates returns the predicates that the guest profile p allows aclOp on.
+func guestPredicates(p *guestProfile, aclOp *acl.Operation) []string {
+	aclCachePtr.RLock()
+	defer aclCachePtr.RUnlock()
+	var preds []string
+	for pred, groupPerms := range aclCachePtr.predPerms {
+		if ns, _ := x.ParseNamespaceAttr(pred); ns != p.namespace {
+			continue
+		}
+		if groupPerms[p.group]&aclOp.Code != 0 {
+			preds = append(preds, pred)
+		}
+	}
+	return preds
+}
+
+// guestLimiter is a token bucket which limits the guest requests of a namespace to a rate per
+// second, allowing bursts of up to a second's worth of requests. The burst is at least one
+// request, so that rates below one request per second let a request through now and then.
+type guestLimiter struct {
+	sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newGuestLimiter(rate float64) *guestLimiter {
+	burst := math.Max(rate, 1)
+	return &guestLimiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// take takes a token for a request. If there's none, it returns how long to wait for the next.
+func (l *guestLimiter) take() (time.Duration, bool) {
+	if l == nil {
+		return 0, true
+	}
+	l.Lock()
+	defer l.Unlock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return time.Duration((1 - l.tokens) / l.rate * float64(time.Second)), false
+	}
+	l.tokens--
+	return 0, true
+}
@@ -0,0 +1,77 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. All rights reserved.
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package edgraph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGuestProfiles(t *testing.T) {
+	profiles, err := parseGuestProfiles("namespace=0 rate=2; namespace=7 group=public " +
+		"ops=query,mutate;")
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+
+	require.Equal(t, "guest", profiles[0].group)
+	require.Equal(t, map[string]bool{guestQuery: true}, profiles[0].ops)
+	require.NotNil(t, profiles[0].limiter)
+
+	require.Equal(t, "public", profiles[7].group)
+	require.Equal(t, map[string]bool{guestQuery: true, guestMutate: true}, profiles[7].ops)
+	require.Nil(t, profiles[7].limiter)
+
+	for _, s := range []string{
+		"group=public",
+		"namespace=x",
+		"namespace=1 ops=drop",
+		"namespace=1 group=guardians",
+		"namespace=1 colour=blue",
+		"namespace=1 rate=0",
+		"namespace=1 rate=-5",
+		"namespace=1 rate=fast",
+		"namespace=1; namespace=1 ops=mutate",
+	} {
+		_, err := parseGuestProfiles(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestGuestLimiter(t *testing.T) {
+	l := newGuestLimiter(2)
+	for i := 0; i < 2; i++ {
+		_, ok := l.take()
+		require.True(t, ok)
+	}
+	wait, ok := l.take()
+	require.False(t, ok)
+	require.True(t, wait > 0 && wait <= 500*time.Millisecond, wait)
+
+	// A rate below one request per second still lets one request through, then the next one
+	// after 1/rate seconds.
+	l = newGuestLimiter(0.5)
+	_, ok = l.take()
+	require.True(t, ok)
+	wait, ok = l.take()
+	require.False(t, ok)
+	require.True(t, wait > 1900*time.Millisecond && wait <= 2*time.Second, wait)
+	l.last = l.last.Add(-2 * time.Second)
+	_, ok = l.take()
+	require.True(t, ok)
+
+	var unlimited *guestLimiter
+	_, ok = unlimited.take()
+	require.True(t, ok)
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/graphql/lambda"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+)
+
+const lambdaScriptsPred = "dgraph.graphql.lambda_scripts"
+
+// GetLambdaScripts returns the versions of the lambda script of the namespace.
+func GetLambdaScripts(namespace uint64) (*lambda.Scripts, error) {
+	ctx := x.AttachNamespace(context.Background(), namespace)
+	scripts, _, _, err := getLambdaScripts(ctx, true)
+	return scripts, err
+}
+
+// getLambdaScripts reads the lambda scripts of the namespace in ctx. If readOnly is false, the
+// read is done in a new transaction, whose start ts is returned so that the scripts can be
+// updated in the same transaction. It also returns the uid of the node holding the scripts, which
+// is empty if no script has been deployed yet.
+func getLambdaScripts(ctx context.Context, readOnly bool) (*lambda.Scripts, string, uint64,
+	error) {
+	req := &Request{
+		req: &api.Request{
+			Query: `{
+				scripts(func: has(dgraph.graphql.lambda_scripts)) {
+					uid
+					dgraph.graphql.lambda_scripts
+				}
+			}`,
+			ReadOnly: readOnly,
+		},
+		doAuth: NoAuthorize,
+	}
+	resp, err := (&Server{}).doQuery(ctx, req)
+	if err != nil {
+		return nil, "", 0, errors.Wrap(err, "while reading the lambda scripts")
+	}
+
+	var result struct {
+		Scripts []struct {
+			Uid     string `json:"uid"`
+			Scripts string `json:"dgraph.graphql.lambda_scripts"`
+		} `json:"scripts"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &result); err != nil {
+		return nil, "", 0, errors.Wrap(err, "while reading the lambda scripts")
+	}
+
+	// Two Alphas deploying the first script of a namespace at the same time can both create a
+	// node for it. The one created last wins, and the other is never read again.
+	var uid, data string
+	var maxUid uint64
+	for _, s := range result.Scripts {
+		u, err := strconv.ParseUint(s.Uid, 0, 64)
+		if err != nil {
+			return nil, "", 0, errors.Wrapf(err, "while parsing uid %s", s.Uid)
+		}
+		if u >= maxUid {
+			maxUid, uid, data = u, s.Uid, s.Scripts
+		}
+	}
+	scripts, err := lambda.ParseScripts([]byte(data))
+	if err != nil {
+		return nil, "", 0, err
+	}
+	return scripts, uid, resp.GetTxn().GetStartTs(), nil
+}
+
+// UpdateLambdaScripts changes the lambda scripts of the namespace in ctx with update, and stores
+// them. The scripts are read and written in the same transaction, so an update racing with
+// another one is aborted instead of overwriting it.
+func UpdateLambdaScripts(ctx context.Context,
+	update func(scripts *lambda.Scripts) error) (*lambda.Scripts, error) {
+	namespace, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scripts, uid, startTs, err := getLambdaScripts(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := update(scripts); err != nil {
+		return nil, err
+	}
+	data, err := scripts.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	subject := "_:lambda"
+	if uid != "" {
+		subject = uid
+	}
+	nquads := []*api.NQuad{{
+		Subject:     subject,
+		Predicate:   lambdaScriptsPred,
+		ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: string(data)}},
+	}}
+	if uid == "" {
+		nquads = append(nquads, &api.NQuad{
+			Subject:     subject,
+			Predicate:   "dgraph.type",
+			ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: "dgraph.graphql.lambda"}},
+		})
+	}
+	req := &Request{
+		req: &api.Request{
+			Mutations: []*api.Mutation{{Set: nquads}},
+			StartTs:   startTs,
+			CommitNow: true,
+		},
+		doAuth: NoAuthorize,
+	}
+	if _, err := (&Server{}).doQuery(context.WithValue(ctx, IsGraphql, true), req); err != nil {
+		return nil, errors.Wrap(err, "while storing the lambda scripts")
+	}
+
+	lambda.SetScripts(namespace, scripts)
+	return scripts, nil
+}
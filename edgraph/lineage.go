@@ -0,0 +1,272 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/types/facets"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// The provenance of a mutation, given with the X-Dgraph-Lineage header or the lineage key of the
+// gRPC metadata, is stored once per mutation on a lineage node of type dgraph.lineage. Every edge
+// set by the mutation points to it with the dgraph.lineage facet, which only takes the 8 bytes
+// of its uid. The lineage of the edges of a predicate is read with the lineage function, e.g.
+//
+//	q(func: uid(0x1)) {
+//		name
+//		lineage(name) { dgraph.lineage.blob }
+//	}
+//
+// and that of single edges with @facets(dgraph.lineage). The lineage node links to the subjects
+// of the mutation with dgraph.lineage.subject, so that it is deleted along with the last of them.
+
+// lineageJSON validates the provenance of a request, which must be a JSON object, and returns it
+// compacted.
+func lineageJSON(lineage string) ([]byte, error) {
+	if len(lineage) > x.MaxLineageSize {
+		return nil, errors.Errorf("lineage of %d bytes is bigger than the limit of %d bytes",
+			len(lineage), x.MaxLineageSize)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(lineage), &obj); err != nil {
+		return nil, errors.Wrapf(err, "lineage must be a JSON object")
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(lineage)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// addLineage tags the edges set by the mutation with the lineage node holding the provenance of
+// the request, if it has one, and adds the edges of the lineage node. The lineage facets sent by
+// the client are dropped in any case, so that the lineage of an edge can't be forged. The lineage
+// nodes of the subjects deleted by the mutation are unlinked from them, and deleted if they have
+// no subject left.
+func addLineage(ctx context.Context, startTs uint64,
+	edges []*pb.DirectedEdge) ([]*pb.DirectedEdge, error) {
+	for _, edge := range edges {
+		out := edge.Facets[:0]
+		for _, f := range edge.Facets {
+			if f.Key != x.LineageFacet {
+				out = append(out, f)
+			}
+		}
+		edge.Facets = out
+	}
+
+	edges, err := unlinkLineage(ctx, startTs, edges)
+	if err != nil {
+		return nil, err
+	}
+
+	lineage := x.LineageFromContext(ctx)
+	if lineage == "" || len(edges) == 0 {
+		return edges, nil
+	}
+	blob, err := lineageJSON(lineage)
+	if err != nil {
+		return nil, err
+	}
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := worker.AssignUidsOverNetwork(ctx, &pb.Num{Val: 1, Type: pb.Num_UID})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while assigning the uid of the lineage node")
+	}
+	uid := ids.StartId
+	facet, err := facets.FacetFor(x.LineageFacet, strconv.FormatUint(uid, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	var subjects []uint64
+	seen := make(map[uint64]struct{})
+	for _, edge := range edges {
+		if edge.Op != pb.DirectedEdge_SET {
+			continue
+		}
+		edge.Facets = append(edge.Facets, facet)
+		sort.Slice(edge.Facets, func(i, j int) bool {
+			return edge.Facets[i].Key < edge.Facets[j].Key
+		})
+		if _, ok := seen[edge.Entity]; !ok {
+			seen[edge.Entity] = struct{}{}
+			subjects = append(subjects, edge.Entity)
+		}
+	}
+	if len(subjects) == 0 {
+		return edges, nil
+	}
+	for _, subject := range subjects {
+		edges = append(edges, &pb.DirectedEdge{
+			Entity:    uid,
+			Attr:      x.LineageSubject,
+			ValueId:   subject,
+			ValueType: pb.Posting_UID,
+			Namespace: ns,
+			Op:        pb.DirectedEdge_SET,
+		})
+	}
+	return append(edges, &pb.DirectedEdge{
+		Entity:    uid,
+		Attr:      x.LineageBlob,
+		Value:     blob,
+		ValueType: pb.Posting_STRING,
+		Namespace: ns,
+		Op:        pb.DirectedEdge_SET,
+	}, &pb.DirectedEdge{
+		Entity:    uid,
+		Attr:      "dgraph.type",
+		Value:     []byte("dgraph.lineage"),
+		ValueType: pb.Posting_STRING,
+		Namespace: ns,
+		Op:        pb.DirectedEdge_SET,
+	}), nil
+}
+
+// unlinkLineage adds the edges unlinking the subjects deleted by the mutation, with S * *, from
+// their lineage nodes, and deleting the lineage nodes left without any subject.
+func unlinkLineage(ctx context.Context, startTs uint64,
+	edges []*pb.DirectedEdge) ([]*pb.DirectedEdge, error) {
+	deleted := make(map[uint64]uint64)
+	var uids []string
+	for _, edge := range edges {
+		if edge.Op != pb.DirectedEdge_DEL || edge.Attr != x.Star {
+			continue
+		}
+		if _, ok := deleted[edge.Entity]; !ok {
+			deleted[edge.Entity] = edge.Namespace
+			uids = append(uids, fmt.Sprintf("%#x", edge.Entity))
+		}
+	}
+	if len(uids) == 0 {
+		return edges, nil
+	}
+
+	query := fmt.Sprintf("{ q(func: uid(%s)) { uid l: <~%s> { uid n: count(<%s>) } } }",
+		strings.Join(uids, ","), x.LineageSubject, x.LineageSubject)
+	resp, err := (&Server{}).doQuery(ctx, &Request{
+		req:    &api.Request{Query: query, StartTs: startTs, ReadOnly: true},
+		doAuth: NoAuthorize,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading the lineage of the deleted nodes")
+	}
+	var res struct {
+		Q []struct {
+			Uid string `json:"uid"`
+			L   []struct {
+				Uid string `json:"uid"`
+				N   int    `json:"n"`
+			} `json:"l"`
+		} `json:"q"`
+	}
+	if err := json.Unmarshal(resp.Json, &res); err != nil {
+		return nil, errors.Wrapf(err, "while reading the lineage of the deleted nodes")
+	}
+	var links []lineageLink
+	for _, q := range res.Q {
+		subject, err := strconv.ParseUint(q.Uid, 0, 64)
+		if err != nil {
+			continue
+		}
+		for _, l := range q.L {
+			if node, err := strconv.ParseUint(l.Uid, 0, 64); err == nil {
+				links = append(links, lineageLink{subject: subject, node: node, n: l.N})
+			}
+		}
+	}
+	return appendUnlinkEdges(edges, deleted, links), nil
+}
+
+// lineageLink is a link from a lineage node to one of its n subjects.
+type lineageLink struct {
+	subject uint64
+	node    uint64
+	n       int
+}
+
+// appendUnlinkEdges adds the edges deleting the lineage nodes all of whose subjects are deleted,
+// and unlinking the others from the deleted subjects. deleted maps the deleted subjects to their
+// namespace.
+func appendUnlinkEdges(edges []*pb.DirectedEdge, deleted map[uint64]uint64,
+	links []lineageLink) []*pb.DirectedEdge {
+	type lineageNode struct {
+		ns       uint64
+		n        int
+		subjects []uint64
+	}
+	nodes := make(map[uint64]*lineageNode)
+	var order []uint64
+	for _, link := range links {
+		ns, ok := deleted[link.subject]
+		if !ok {
+			continue
+		}
+		l := nodes[link.node]
+		if l == nil {
+			l = &lineageNode{ns: ns, n: link.n}
+			nodes[link.node] = l
+			order = append(order, link.node)
+		}
+		l.subjects = append(l.subjects, link.subject)
+	}
+
+	for _, uid := range order {
+		l := nodes[uid]
+		if len(l.subjects) < l.n {
+			for _, subject := range l.subjects {
+				edges = append(edges, &pb.DirectedEdge{
+					Entity:    uid,
+					Attr:      x.LineageSubject,
+					ValueId:   subject,
+					ValueType: pb.Posting_UID,
+					Namespace: l.ns,
+					Op:        pb.DirectedEdge_DEL,
+				})
+			}
+			continue
+		}
+		for _, attr := range []string{x.LineageBlob, x.LineageSubject, "dgraph.type"} {
+			edges = append(edges, &pb.DirectedEdge{
+				Entity:    uid,
+				Attr:      attr,
+				Value:     []byte(x.Star),
+				ValueType: pb.Posting_DEFAULT,
+				Namespace: l.ns,
+				Op:        pb.DirectedEdge_DEL,
+			})
+		}
+	}
+	return edges
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestLineageJSON(t *testing.T) {
+	blob, err := lineageJSON(`{ "source": "crm",
+		"batch": 17 }`)
+	require.NoError(t, err)
+	require.Equal(t, `{"source":"crm","batch":17}`, string(blob))
+
+	for _, bad := range []string{`"crm"`, `[1]`, `{"source":`,
+		`{"source":"` + strings.Repeat("a", x.MaxLineageSize) + `"}`} {
+		_, err := lineageJSON(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestAppendUnlinkEdges(t *testing.T) {
+	// 0x10 is the lineage node of 0x1 and 0x2, both deleted, and 0x20 that of 0x1 and 0x3.
+	deleted := map[uint64]uint64{0x1: 0, 0x2: 0}
+	links := []lineageLink{
+		{subject: 0x1, node: 0x10, n: 2},
+		{subject: 0x1, node: 0x20, n: 2},
+		{subject: 0x2, node: 0x10, n: 2},
+	}
+	edges := appendUnlinkEdges(nil, deleted, links)
+
+	var got []string
+	for _, edge := range edges {
+		require.Equal(t, pb.DirectedEdge_DEL, edge.Op)
+		if edge.ValueType == pb.Posting_UID {
+			got = append(got, fmt.Sprintf("%#x %s %#x", edge.Entity, edge.Attr, edge.ValueId))
+		} else {
+			got = append(got, fmt.Sprintf("%#x %s %s", edge.Entity, edge.Attr, edge.Value))
+		}
+	}
+	require.Equal(t, []string{
+		"0x10 dgraph.lineage.blob _STAR_ALL",
+		"0x10 dgraph.lineage.subject _STAR_ALL",
+		"0x10 dgraph.type _STAR_ALL",
+		"0x20 dgraph.lineage.subject 0x1",
+	}, got)
+}
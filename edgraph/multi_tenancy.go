@@ -34,6 +34,10 @@ func (s *Server) DeleteNamespace(ctx context.Context, namespace uint64) error {
 	return nil
 }
 
+func (s *Server) CloneNamespace(ctx context.Context, from uint64) (uint64, error) {
+	return 0, nil
+}
+
 func (s *Server) ResetPassword(ctx context.Context, ns *ResetPasswordInput) error {
 	return nil
 }
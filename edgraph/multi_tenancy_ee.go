@@ -137,3 +137,37 @@ func (s *Server) DeleteNamespace(ctx context.Context, namespace uint64) error {
 	glog.Info("Deleting namespace", namespace)
 	return worker.ProcessDeleteNsRequest(ctx, namespace)
 }
+
+// CloneNamespace creates a new namespace with a copy of the schema, types and data of the given
+// namespace, including its users and groups and its GraphQL schema. Only guardian of galaxy is
+// authorized to do so. Authorization is handled by middlewares.
+func (s *Server) CloneNamespace(ctx context.Context, from uint64) (uint64, error) {
+	glog.V(2).Infof("Got clone namespace request for namespace: %d", from)
+
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{Fields: []string{"type"}})
+	if err != nil {
+		return 0, errors.Wrapf(err, "Cloning namespace, got error:")
+	}
+	exists := false
+	for _, node := range nodes {
+		if ns, _ := x.ParseNamespaceAttr(node.Predicate); ns == from {
+			exists = true
+			break
+		}
+	}
+	if !exists {
+		return 0, errors.Errorf("Namespace %d doesn't exist", from)
+	}
+
+	num := &pb.Num{Val: 1, Type: pb.Num_NS_ID}
+	ids, err := worker.AssignNsIdsOverNetwork(ctx, num)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Cloning namespace, got error:")
+	}
+	ns := ids.StartId
+	if err := worker.ProcessCloneNsRequest(ctx, from, ns); err != nil {
+		return 0, errors.Wrapf(err, "Cloning namespace %d to %d, got error:", from, ns)
+	}
+	glog.Infof("Cloned namespace %d to %d", from, ns)
+	return ns, nil
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// dropGraphBatch is the number of nodes whose edges of a predicate are deleted per transaction
+// by DropGraph.
+const dropGraphBatch = 1000
+
+// DropGraph deletes the edges of the named graph, i.e. the edges whose graph facet is the given
+// graph, from all the predicates of the namespace in the context. The edges of a predicate are
+// deleted in transactions of dropGraphBatch nodes, so the graph is deleted gradually. It
+// returns the number of edges deleted.
+func DropGraph(ctx context.Context, graph string) (uint64, error) {
+	if graph == "" {
+		return 0, errors.Errorf("The graph to drop can't be empty")
+	}
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return 0, err
+	}
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+		Fields: []string{"type", "list", "lang"},
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "while reading the schema")
+	}
+
+	var deleted uint64
+	for _, node := range nodes {
+		pns, attr := x.ParseNamespaceAttr(node.Predicate)
+		// Besides dgraph.type, the reserved predicates are maintained by Dgraph.
+		if pns != ns || (x.IsReservedPredicate(node.Predicate) && attr != "dgraph.type") ||
+			node.Type == "password" {
+			continue
+		}
+		n, err := dropGraphEdges(ctx, graph, attr, node)
+		deleted += n
+		if err != nil {
+			return deleted, errors.Wrapf(err, "while dropping graph %q from %s", graph, attr)
+		}
+	}
+	glog.Infof("Dropped %d edges of graph %q in namespace %#x", deleted, graph, ns)
+	return deleted, nil
+}
+
+// dropGraphEdges deletes the edges of the named graph from the predicate.
+func dropGraphEdges(ctx context.Context, graph, attr string, node *pb.SchemaNode) (
+	uint64, error) {
+	pred := "<" + attr + ">"
+	if node.Lang {
+		pred += "@*"
+	}
+	sel := ""
+	if node.Type == "uid" {
+		sel = "{ uid }"
+	}
+
+	var deleted uint64
+	after := "0x0"
+	for {
+		query := fmt.Sprintf("{ q(func: has(<%s>), first: %d, after: %s) { uid %s"+
+			" @facets(eq(%s, %s)) %s } }", attr, dropGraphBatch, after, pred, x.GraphFacet,
+			strconv.Quote(graph), sel)
+		resp, err := (&Server{}).doQuery(ctx, &Request{
+			req:    &api.Request{Query: query, ReadOnly: true},
+			doAuth: NoAuthorize,
+		})
+		if err != nil {
+			return deleted, err
+		}
+		var res struct {
+			Q []map[string]interface{} `json:"q"`
+		}
+		dec := json.NewDecoder(bytes.NewReader(resp.Json))
+		dec.UseNumber()
+		if err := dec.Decode(&res); err != nil {
+			return deleted, err
+		}
+
+		var del []*api.NQuad
+		for _, m := range res.Q {
+			uid, _ := m["uid"].(string)
+			after = uid
+			for key, val := range m {
+				if key != attr && !strings.HasPrefix(key, attr+"@") {
+					continue
+				}
+				nq := api.NQuad{Subject: uid, Predicate: attr}
+				if node.Lang {
+					nq.Lang = strings.TrimPrefix(strings.TrimPrefix(key, attr), "@")
+				}
+				vals, ok := val.([]interface{})
+				if !ok {
+					vals = []interface{}{val}
+				}
+				for _, v := range vals {
+					edge := nq
+					switch {
+					case node.Type == "uid":
+						obj, _ := v.(map[string]interface{})
+						edge.ObjectId, _ = obj["uid"].(string)
+					case !node.List && edge.Lang == "":
+						// There is one value, so it's deleted whatever its format.
+						edge.ObjectValue = &api.Value{Val: &api.Value_DefaultVal{DefaultVal: x.Star}}
+					default:
+						edge.ObjectValue = &api.Value{Val: &api.Value_DefaultVal{
+							DefaultVal: graphValue(v)}}
+					}
+					del = append(del, &edge)
+				}
+			}
+		}
+		if len(del) > 0 {
+			_, err := (&Server{}).doQuery(ctx, &Request{
+				req: &api.Request{
+					Mutations: []*api.Mutation{{Del: del}},
+					CommitNow: true,
+				},
+				doAuth: NoAuthorize,
+			})
+			if err != nil {
+				return deleted, err
+			}
+			deleted += uint64(len(del))
+		}
+		if len(res.Q) < dropGraphBatch {
+			return deleted, nil
+		}
+	}
+}
+
+// graphValue returns the value of an edge read by DropGraph as it's given in a mutation.
+func graphValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case map[string]interface{}:
+		// A geo value is read as GeoJSON.
+		b, _ := json.Marshal(v)
+		return string(b)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// maxPreparedStatements is the number of prepared statements an alpha keeps for each
+// namespace. When it's reached, an arbitrary statement of the namespace is evicted to make room
+// for a new one, so that a namespace can't evict the statements of another.
+const maxPreparedStatements = 1000
+
+// preparedStatements caches the queries that have been prepared on this alpha, by namespace.
+// Queries are parsed and validated once, and only filled in with their variables when executed.
+type preparedStatements struct {
+	sync.RWMutex
+	byNs map[uint64]nsStatements
+}
+
+// nsStatements are the prepared statements of a namespace.
+type nsStatements struct {
+	byQuery map[string]*gql.Prepared
+	byID    map[string]string
+}
+
+var prepared = preparedStatements{
+	byNs: make(map[uint64]nsStatements),
+}
+
+func statementID(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:16])
+}
+
+// preparedNamespace returns the namespace of the caller, given by its access JWT. Without ACL,
+// there is only the galaxy namespace.
+func preparedNamespace(ctx context.Context) (uint64, error) {
+	if !x.WorkerConfig.AclEnabled {
+		return x.GalaxyNamespace, nil
+	}
+	ns, err := x.ExtractJWTNamespace(ctx)
+	if err != nil {
+		return 0, errors.Wrapf(err, "while preparing the statement")
+	}
+	return ns, nil
+}
+
+// Prepare prepares the given DQL query to be executed many times with different variables,
+// and returns its id. With ACL, the caller must be logged in, and the statement is only
+// visible to its namespace. A query whose text is the one of a prepared statement of the
+// namespace skips parsing and validation when it's executed, irrespective of how it was sent.
+// Prepared statements are kept in memory by each alpha, so a client must prepare its queries
+// on every alpha it sends them to.
+func (s *Server) Prepare(ctx context.Context, query string) (string, error) {
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	ns, err := preparedNamespace(ctx)
+	if err != nil {
+		return "", err
+	}
+	if query == "" {
+		return "", errors.Errorf("The query to prepare can't be empty")
+	}
+	id := statementID(query)
+
+	prepared.RLock()
+	_, ok := prepared.byNs[ns].byQuery[query]
+	prepared.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	p, err := gql.Prepare(query)
+	if err != nil {
+		return "", err
+	}
+
+	prepared.Lock()
+	defer prepared.Unlock()
+	stmts, ok := prepared.byNs[ns]
+	if !ok {
+		stmts = nsStatements{
+			byQuery: make(map[string]*gql.Prepared),
+			byID:    make(map[string]string),
+		}
+		prepared.byNs[ns] = stmts
+	}
+	if len(stmts.byQuery) >= maxPreparedStatements {
+		for evictID, evictQuery := range stmts.byID {
+			delete(stmts.byID, evictID)
+			delete(stmts.byQuery, evictQuery)
+			break
+		}
+	}
+	stmts.byQuery[query] = p
+	stmts.byID[id] = query
+	return id, nil
+}
+
+// PreparedQuery returns the text of the prepared statement with the given id, prepared by
+// the namespace of the caller.
+func PreparedQuery(ctx context.Context, id string) (string, error) {
+	ns, err := preparedNamespace(ctx)
+	if err != nil {
+		return "", err
+	}
+	prepared.RLock()
+	defer prepared.RUnlock()
+	query, ok := prepared.byNs[ns].byID[id]
+	if !ok {
+		return "", errors.Errorf("Prepared statement %s not found. It has to be prepared again",
+			id)
+	}
+	return query, nil
+}
+
+// parsePrepared parses the query using the prepared statement of the namespace of ctx if
+// there is one, else from scratch.
+func parsePrepared(ctx context.Context, r gql.Request, needVars []string) (gql.Result, error) {
+	var p *gql.Prepared
+	if ns, err := x.ExtractNamespace(ctx); err == nil {
+		prepared.RLock()
+		p = prepared.byNs[ns].byQuery[r.Str]
+		prepared.RUnlock()
+	}
+	if p != nil {
+		return p.ParseRequest(r, needVars)
+	}
+	return gql.ParseWithNeedVars(r, needVars)
+}
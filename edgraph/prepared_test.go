@@ -0,0 +1,71 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestPrepareNamespaces(t *testing.T) {
+	defer func(acl bool, secret x.SensitiveByteSlice) {
+		x.WorkerConfig.AclEnabled, x.WorkerConfig.HmacSecret = acl, secret
+	}(x.WorkerConfig.AclEnabled, x.WorkerConfig.HmacSecret)
+	x.WorkerConfig.AclEnabled = true
+	x.WorkerConfig.HmacSecret = []byte("0123456789abcdef0123456789abcdef")
+
+	nsContext := func(ns uint64) context.Context {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"userid":    "alice",
+			"namespace": ns,
+			"exp":       time.Now().Add(time.Minute).Unix(),
+		})
+		jwtStr, err := token.SignedString([]byte(x.WorkerConfig.HmacSecret))
+		require.NoError(t, err)
+		return metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs("accessJwt", jwtStr))
+	}
+
+	query := `{ q(func: has(name)) { name } }`
+	_, err := (&Server{}).Prepare(context.Background(), query)
+	require.Error(t, err)
+
+	id, err := (&Server{}).Prepare(nsContext(2), query)
+	require.NoError(t, err)
+	got, err := PreparedQuery(nsContext(2), id)
+	require.NoError(t, err)
+	require.Equal(t, query, got)
+	_, err = PreparedQuery(nsContext(3), id)
+	require.Error(t, err)
+
+	// A namespace evicts only its own statements.
+	ctx := nsContext(3)
+	for i := 0; i <= maxPreparedStatements; i++ {
+		_, err := (&Server{}).Prepare(ctx, fmt.Sprintf(`{ q(func: uid(%#x)) { name } }`, i+1))
+		require.NoError(t, err)
+	}
+	got, err = PreparedQuery(nsContext(2), id)
+	require.NoError(t, err)
+	require.Equal(t, query, got)
+}
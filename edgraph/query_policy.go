@@ -0,0 +1,191 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/tok"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// The constructs that a query policy can deny.
+const (
+	// denyUnindexedRegexp denies regexp functions on predicates without a trigram index, which
+	// are evaluated by reading the values of all the candidate nodes.
+	denyUnindexedRegexp = "unindexed_regexp"
+	// denyUnboundedRecurse denies @recurse without a depth.
+	denyUnboundedRecurse = "unbounded_recurse"
+)
+
+// queryPolicy restricts the DQL queries of a namespace. Queries must have the shape of one of
+// the allowed queries, if any are given, and must not use any of the denied constructs.
+type queryPolicy struct {
+	allow map[string]struct{}
+	deny  map[string]bool
+}
+
+// queryPolicies holds the policies of --query_policy by namespace. It's only set at startup.
+var queryPolicies map[uint64]*queryPolicy
+
+// queryPolicyFile is the format of the --query_policy file, which maps namespaces to their
+// policy, e.g.
+//
+//	{"0": {"allow": ["{ q(func: eq(name, \"x\")) { name } }"], "deny": ["unbounded_recurse"]}}
+//
+// Allowed queries are examples of the queries to allow. Queries are compared by their shape, so
+// the values given in them don't matter.
+type queryPolicyFile map[string]struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// SetQueryPolicies reads the query policies of the namespaces from the --query_policy file and
+// enforces them on the DQL queries parsed afterwards.
+func SetQueryPolicies(file string) error {
+	if file == "" {
+		queryPolicies = nil
+		return nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.Wrapf(err, "while reading the query policy file")
+	}
+	policies, err := parseQueryPolicies(data)
+	if err != nil {
+		return err
+	}
+	queryPolicies = policies
+	return nil
+}
+
+func parseQueryPolicies(data []byte) (map[uint64]*queryPolicy, error) {
+	var f queryPolicyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, errors.Wrapf(err, "while parsing the query policy file")
+	}
+	policies := make(map[uint64]*queryPolicy)
+	for key, spec := range f {
+		ns, err := strconv.ParseUint(key, 0, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing namespace %q of the query policy", key)
+		}
+		p := &queryPolicy{
+			allow: make(map[string]struct{}),
+			deny:  make(map[string]bool),
+		}
+		for _, query := range spec.Allow {
+			res, err := gql.Parse(gql.Request{Str: query})
+			if err != nil {
+				return nil, errors.Wrapf(err, "while parsing allowed query %q of namespace %d",
+					query, ns)
+			}
+			p.allow[gql.Shape(res)] = struct{}{}
+		}
+		for _, construct := range spec.Deny {
+			switch construct {
+			case denyUnindexedRegexp, denyUnboundedRecurse:
+				p.deny[construct] = true
+			default:
+				return nil, errors.Errorf("Unknown construct %q denied in namespace %d. Expected "+
+					"%s or %s", construct, ns, denyUnindexedRegexp, denyUnboundedRecurse)
+			}
+		}
+		policies[ns] = p
+	}
+	return policies, nil
+}
+
+// checkQueryPolicy checks the parsed DQL query of qc against the policy of its namespace.
+func checkQueryPolicy(ctx context.Context, qc *queryContext) error {
+	if len(queryPolicies) == 0 || qc.graphql {
+		return nil
+	}
+	if len(qc.gqlRes.Query) == 0 && qc.gqlRes.Schema == nil {
+		return nil
+	}
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "while checking the query policy")
+	}
+	p, ok := queryPolicies[ns]
+	if !ok {
+		return nil
+	}
+
+	if len(p.allow) > 0 {
+		if _, ok := p.allow[gql.Shape(qc.gqlRes)]; !ok {
+			return status.Errorf(codes.PermissionDenied,
+				"The query isn't allowed by the query policy of namespace %d", ns)
+		}
+	}
+	for _, gq := range qc.gqlRes.Query {
+		if construct := deniedConstruct(ctx, ns, p, gq); construct != "" {
+			return status.Errorf(codes.PermissionDenied,
+				"The query uses %s, which the query policy of namespace %d denies", construct, ns)
+		}
+	}
+	return nil
+}
+
+// deniedConstruct returns the first construct denied by p that the block gq or its children use.
+func deniedConstruct(ctx context.Context, ns uint64, p *queryPolicy,
+	gq *gql.GraphQuery) string {
+	if p.deny[denyUnboundedRecurse] && gq.Recurse && gq.RecurseArgs.Depth == 0 {
+		return denyUnboundedRecurse
+	}
+	if p.deny[denyUnindexedRegexp] {
+		if isUnindexedRegexp(ctx, ns, gq.Func) || hasUnindexedRegexp(ctx, ns, gq.Filter) {
+			return denyUnindexedRegexp
+		}
+	}
+	for _, child := range gq.Children {
+		if construct := deniedConstruct(ctx, ns, p, child); construct != "" {
+			return construct
+		}
+	}
+	return ""
+}
+
+func hasUnindexedRegexp(ctx context.Context, ns uint64, f *gql.FilterTree) bool {
+	if f == nil {
+		return false
+	}
+	if isUnindexedRegexp(ctx, ns, f.Func) {
+		return true
+	}
+	for _, child := range f.Child {
+		if hasUnindexedRegexp(ctx, ns, child) {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnindexedRegexp(ctx context.Context, ns uint64, f *gql.Function) bool {
+	return f != nil && f.Name == "regexp" &&
+		!schema.State().HasTokenizer(ctx, tok.IdentTrigram, x.NamespaceAttr(ns, f.Attr))
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestQueryPolicy(t *testing.T) {
+	policies, err := parseQueryPolicies([]byte(`{
+		"0": {"allow": ["{ q(func: eq(name, \"x\")) { name } }"]},
+		"7": {"deny": ["unbounded_recurse"]}
+	}`))
+	require.NoError(t, err)
+	defer func() { queryPolicies = nil }()
+	queryPolicies = policies
+
+	check := func(ns uint64, query string) error {
+		res, err := gql.Parse(gql.Request{Str: query})
+		require.NoError(t, err)
+		return checkQueryPolicy(x.AttachNamespace(context.Background(), ns),
+			&queryContext{gqlRes: res})
+	}
+	require.NoError(t, check(0, `{ q(func: eq(name, ["alice", "bob"])) { name } }`))
+	require.Error(t, check(0, `{ q(func: eq(name, "alice")) { name age } }`))
+
+	require.NoError(t, check(7, `{ q(func: uid(0x1)) @recurse(depth: 5) { friend } }`))
+	require.Error(t, check(7, `{ q(func: uid(0x1)) @recurse { friend } }`))
+
+	// Namespaces without a policy aren't restricted.
+	require.NoError(t, check(3, `{ q(func: uid(0x1)) @recurse { friend } }`))
+
+	for _, data := range []string{
+		`{"x": {}}`,
+		`{"0": {"deny": ["cartesian_product"]}}`,
+		`{"0": {"allow": ["{ q(func: eq(name, "]}}`,
+	} {
+		_, err := parseQueryPolicies([]byte(data))
+		require.Error(t, err, data)
+	}
+}
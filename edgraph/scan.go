@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+)
+
+// Partitions splits the predicates of the namespace of the caller into partitions, which can be
+// scanned concurrently on the Alphas of their groups. All the partitions must be scanned at the
+// returned read timestamp, so that together they are a consistent snapshot.
+func (s *Server) Partitions(ctx context.Context,
+	req *pb.ScanPartitionsRequest) (*pb.ScanPartitions, error) {
+	if err := x.HealthCheck(); err != nil {
+		return nil, err
+	}
+	ctx = x.AttachJWTNamespace(ctx)
+	namespace, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "While computing the scan partitions")
+	}
+	partitions, err := worker.ScanPartitions(worker.GetMembershipState(), namespace,
+		req.Predicates, req.PerGroup)
+	if err != nil {
+		return nil, err
+	}
+	// The partitions of a group all hold the predicates it serves.
+	var preds []string
+	for _, p := range partitions {
+		if p.StartUid == 1 {
+			preds = append(preds, p.Predicates...)
+		}
+	}
+	if err := authorizeScan(ctx, preds); err != nil {
+		return nil, err
+	}
+	return &pb.ScanPartitions{
+		ReadTs:     worker.State.GetTimestamp(true),
+		Partitions: partitions,
+	}, nil
+}
+
+// Scan streams the edges of a partition returned by Partitions, in batches.
+func (s *Server) Scan(req *pb.ScanRequest, stream pb.Scan_ScanServer) error {
+	if err := x.HealthCheck(); err != nil {
+		return err
+	}
+	ctx := x.AttachJWTNamespace(stream.Context())
+	namespace, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "While scanning")
+	}
+	if err := authorizeScan(ctx, req.GetPartition().GetPredicates()); err != nil {
+		return err
+	}
+	return worker.Scan(ctx, namespace, req, stream.Send)
+}
@@ -0,0 +1,528 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	gqlSchema "github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	schemaHistoryPred = "dgraph.schema_history"
+	// maxSchemaVersions is the number of versions of the schema of a namespace which are kept.
+	// The oldest versions are dropped when a change is recorded.
+	maxSchemaVersions = 100
+	// maxDiffLines is the max product of the numbers of lines of two schemas for which the diff
+	// is computed line by line. Bigger schemas are diffed as sets of lines.
+	maxDiffLines = 1 << 20
+)
+
+const (
+	// SchemaKindDQL versions are DQL schemas, changed via Alter.
+	SchemaKindDQL = "DQL"
+	// SchemaKindGraphQL versions are GraphQL schemas, changed via the admin API.
+	SchemaKindGraphQL = "GraphQL"
+)
+
+// SchemaVersion is a change of the DQL or of the GraphQL schema of a namespace.
+type SchemaVersion struct {
+	// Version is the start timestamp of the transaction which recorded the change. It orders the
+	// versions of both kinds.
+	Version uint64 `json:"version"`
+	Kind    string `json:"kind"`
+	// Schema is the whole schema of the kind after the change. The pre-defined predicates and
+	// types are left out of the DQL schemas.
+	Schema string `json:"schema"`
+	// Diff holds the lines removed by the change, prefixed with "-", and the lines it added,
+	// prefixed with "+".
+	Diff   string    `json:"diff"`
+	Author string    `json:"author,omitempty"`
+	At     time.Time `json:"at"`
+	// RollbackOf is the version the change rolled the schema back to, if it was a rollback.
+	RollbackOf uint64 `json:"rollbackOf,omitempty"`
+}
+
+// SchemaRollback is the result of RollbackSchema.
+type SchemaRollback struct {
+	// Version is the change recorded by the rollback. It is nil if the schema already was the one
+	// of the version rolled back to.
+	Version *SchemaVersion
+	// KeptPredicates are the predicates created after the version rolled back to. Their schema
+	// stays, since it can't be removed without dropping their data.
+	KeptPredicates []string
+	// DroppedTypes are the types created after the version rolled back to.
+	DroppedTypes []string
+	TaskId       uint64
+}
+
+type noSchemaHistoryKey struct{}
+
+// schemaAuthor returns who is changing the schema: the user of the request if there's one,
+// otherwise the address it comes from.
+func schemaAuthor(ctx context.Context) string {
+	if user := requestUser(ctx); user != "" {
+		return user
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// schemaVersions reads the versions of the schema of the namespace in ctx, oldest first, along
+// with the uids of the nodes holding them.
+func schemaVersions(ctx context.Context) ([]*SchemaVersion, []string, error) {
+	req := &Request{
+		req: &api.Request{
+			Query: `{
+				versions(func: has(dgraph.schema_history)) {
+					uid
+					dgraph.schema_history
+				}
+			}`,
+			ReadOnly: true,
+		},
+		doAuth: NoAuthorize,
+	}
+	resp, err := (&Server{}).doQuery(ctx, req)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "while reading the schema history")
+	}
+	var result struct {
+		Versions []struct {
+			Uid     string `json:"uid"`
+			Version string `json:"dgraph.schema_history"`
+		} `json:"versions"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &result); err != nil {
+		return nil, nil, errors.Wrap(err, "while reading the schema history")
+	}
+
+	versions := make([]*SchemaVersion, 0, len(result.Versions))
+	uids := make(map[*SchemaVersion]string, len(result.Versions))
+	for _, r := range result.Versions {
+		v := &SchemaVersion{}
+		if err := json.Unmarshal([]byte(r.Version), v); err != nil {
+			return nil, nil, errors.Wrapf(err, "while parsing schema version %s", r.Uid)
+		}
+		versions = append(versions, v)
+		uids[v] = r.Uid
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	sorted := make([]string, len(versions))
+	for i, v := range versions {
+		sorted[i] = uids[v]
+	}
+	return versions, sorted, nil
+}
+
+// SchemaHistory returns the versions of the DQL and GraphQL schemas of the namespace in ctx,
+// oldest first.
+func SchemaHistory(ctx context.Context) ([]*SchemaVersion, error) {
+	versions, _, err := schemaVersions(ctx)
+	return versions, err
+}
+
+// recordSchemaChange records the change of the schema of the given kind of the namespace in ctx
+// from before to after, and drops the oldest versions beyond maxSchemaVersions. Nothing is
+// recorded if the schema didn't change.
+func recordSchemaChange(ctx context.Context, kind, before, after string,
+	rollbackOf uint64) (*SchemaVersion, error) {
+	if before == after {
+		return nil, nil
+	}
+	versions, uids, err := schemaVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	startTs := worker.State.GetTimestamp(false)
+	v := &SchemaVersion{
+		Version:    startTs,
+		Kind:       kind,
+		Schema:     after,
+		Diff:       diffSchemas(before, after),
+		Author:     schemaAuthor(ctx),
+		At:         time.Now().UTC(),
+		RollbackOf: rollbackOf,
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	mu := &api.Mutation{Set: []*api.NQuad{{
+		Subject:     "_:version",
+		Predicate:   schemaHistoryPred,
+		ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: string(data)}},
+	}, {
+		Subject:     "_:version",
+		Predicate:   "dgraph.type",
+		ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: "dgraph.schema_version"}},
+	}}}
+	for i := 0; i < len(versions)+1-maxSchemaVersions; i++ {
+		mu.Del = append(mu.Del, &api.NQuad{
+			Subject:     uids[i],
+			Predicate:   x.Star,
+			ObjectValue: &api.Value{Val: &api.Value_DefaultVal{DefaultVal: x.Star}},
+		})
+	}
+	req := &Request{
+		req: &api.Request{
+			Mutations: []*api.Mutation{mu},
+			StartTs:   startTs,
+			CommitNow: true,
+		},
+		doAuth: NoAuthorize,
+	}
+	if _, err := (&Server{}).doQuery(context.WithValue(ctx, IsGraphql, true), req); err != nil {
+		return nil, errors.Wrap(err, "while recording the schema change")
+	}
+	return v, nil
+}
+
+// recordSchemaChangeOrLog is recordSchemaChange for the changes which are already applied, and
+// which shouldn't fail because they couldn't be recorded.
+func recordSchemaChangeOrLog(ctx context.Context, kind, before, after string) {
+	if ctx.Value(noSchemaHistoryKey{}) != nil {
+		return
+	}
+	if _, err := recordSchemaChange(ctx, kind, before, after, 0); err != nil {
+		glog.Errorf("Unable to record the change of the %s schema: %v", kind, err)
+	}
+}
+
+// dqlSchema returns the DQL schema of the namespace, without the pre-defined predicates and
+// types. The predicates and types of update, if given, replace the ones in the schema state, as
+// the state of an index may only change once the index is rebuilt. The predicates come first and
+// then the types, both sorted by name.
+func dqlSchema(ctx context.Context, namespace uint64, update *schema.ParsedSchema) (string,
+	error) {
+	nodes, err := worker.GetSchemaOverNetwork(ctx, &pb.SchemaRequest{})
+	if err != nil {
+		return "", err
+	}
+	types, err := worker.GetTypes(ctx, &pb.SchemaRequest{})
+	if err != nil {
+		return "", err
+	}
+
+	preds := make(map[string]string)
+	for _, node := range nodes {
+		preds[node.Predicate] = SchemaNodeString(x.ParseAttr(node.Predicate), node)
+	}
+	typeDefs := make(map[string]string)
+	for _, typ := range types {
+		typeDefs[typ.TypeName] = typeString(typ)
+	}
+	if update != nil {
+		for _, pred := range update.Preds {
+			preds[pred.Predicate] = SchemaNodeString(x.ParseAttr(pred.Predicate),
+				schemaUpdateNode(pred))
+		}
+		for _, typ := range update.Types {
+			typeDefs[typ.TypeName] = typeString(typ)
+		}
+	}
+
+	var predLines, typeLines []string
+	for pred, line := range preds {
+		if ns, _ := x.ParseNamespaceAttr(pred); ns == namespace && !x.IsPreDefinedPredicate(pred) {
+			predLines = append(predLines, line)
+		}
+	}
+	for name, def := range typeDefs {
+		if ns, _ := x.ParseNamespaceAttr(name); ns == namespace && !x.IsPreDefinedType(name) {
+			typeLines = append(typeLines, def)
+		}
+	}
+	sort.Strings(predLines)
+	sort.Strings(typeLines)
+	return strings.Join(append(predLines, typeLines...), "\n"), nil
+}
+
+func typeString(typ *pb.TypeUpdate) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "type <%s> {\n", x.ParseAttr(typ.TypeName))
+	for _, field := range typ.Fields {
+		fmt.Fprintf(&b, "\t<%s>\n", x.ParseAttr(field.Predicate))
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// schemaUpdateNode returns the schema node which describes the predicate once update is applied.
+func schemaUpdateNode(update *pb.SchemaUpdate) *pb.SchemaNode {
+	node := &pb.SchemaNode{
+		Type:       types.TypeID(update.ValueType).Name(),
+		List:       update.List,
+		Count:      update.Count,
+		Upsert:     update.Upsert,
+		Lang:       update.Lang,
+		NoConflict: update.NoConflict,
+		Presence:   update.Presence,
+		Collation:  update.Collation,
+		Reverse:    update.Directive == pb.SchemaUpdate_REVERSE,
+	}
+	if update.Directive == pb.SchemaUpdate_INDEX {
+		node.Index = true
+		node.Tokenizer = update.Tokenizer
+	}
+	return node
+}
+
+// SchemaNodeString returns the line of a DQL schema which defines attr as described by node.
+func SchemaNodeString(attr string, node *pb.SchemaNode) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%s>: ", attr)
+	if node.List {
+		fmt.Fprintf(&b, "[%s]", node.Type)
+	} else {
+		b.WriteString(node.Type)
+	}
+	if node.Index {
+		fmt.Fprintf(&b, " @index(%s)", strings.Join(node.Tokenizer, ", "))
+	}
+	if node.Reverse {
+		b.WriteString(" @reverse")
+	}
+	if node.Count {
+		b.WriteString(" @count")
+	}
+	if node.Presence {
+		b.WriteString(" @presence")
+	}
+	if node.Lang {
+		b.WriteString(" @lang")
+	}
+	if node.Collation != "" {
+		fmt.Fprintf(&b, " @collate(%s)", node.Collation)
+	}
+	if node.Upsert {
+		b.WriteString(" @upsert")
+	}
+	if node.NoConflict {
+		b.WriteString(" @noconflict")
+	}
+	b.WriteString(" .")
+	return b.String()
+}
+
+// diffSchemas returns the lines of before which aren't in after, prefixed with "-", and the lines
+// of after which aren't in before, prefixed with "+". The lines are matched by a longest common
+// subsequence, unless the schemas are too big for it.
+func diffSchemas(before, after string) string {
+	a, b := splitLines(before), splitLines(after)
+	var out []string
+	if len(a)*len(b) > maxDiffLines {
+		inA, inB := make(map[string]int), make(map[string]int)
+		for _, line := range a {
+			inA[line]++
+		}
+		for _, line := range b {
+			inB[line]++
+		}
+		for _, line := range a {
+			if inB[line] == 0 {
+				out = append(out, "-"+line)
+			} else {
+				inB[line]--
+			}
+		}
+		for _, line := range b {
+			if inA[line] == 0 {
+				out = append(out, "+"+line)
+			} else {
+				inA[line]--
+			}
+		}
+		return strings.Join(out, "\n")
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "-"+a[i])
+			i++
+		default:
+			out = append(out, "+"+b[j])
+			j++
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(s, "\n"), "\n")
+}
+
+// RollbackSchema rolls the DQL or GraphQL schema of the namespace in ctx back to the given
+// version, as a task. The rollback is recorded as a new version. Rolling back a DQL schema drops
+// the types created since the version, but keeps the predicates created since then, along with
+// their data.
+func RollbackSchema(ctx context.Context, version uint64) (*SchemaRollback, error) {
+	versions, err := SchemaHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var target *SchemaVersion
+	for _, v := range versions {
+		if v.Version == version {
+			target = v
+		}
+	}
+	if target == nil {
+		return nil, errors.Errorf("schema version %d doesn't exist", version)
+	}
+	namespace, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SchemaRollback{}
+	opts := worker.TaskOptions{
+		Kind:        "schema-rollback",
+		Description: fmt.Sprintf("Rollback of the %s schema to version %d", target.Kind, version),
+	}
+	err = worker.RunTask(ctx, opts, func(ctx context.Context, t *worker.Task) error {
+		res.TaskId = t.Id()
+		noHistory := context.WithValue(ctx, noSchemaHistoryKey{}, true)
+		var before, after string
+		switch target.Kind {
+		case SchemaKindDQL:
+			var err error
+			if before, err = dqlSchema(ctx, namespace, nil); err != nil {
+				return err
+			}
+			if err := rollbackDQLSchema(noHistory, namespace, before, target.Schema,
+				res); err != nil {
+				return err
+			}
+			if after, err = dqlSchema(ctx, namespace, nil); err != nil {
+				return err
+			}
+		case SchemaKindGraphQL:
+			var err error
+			if _, before, err = GetGQLSchema(namespace); err != nil {
+				return err
+			}
+			var dgSchema string
+			if target.Schema != "" {
+				handler, err := gqlSchema.NewHandler(target.Schema, false)
+				if err != nil {
+					return err
+				}
+				dgSchema = handler.DGSchema()
+			}
+			if _, err := UpdateGQLSchema(noHistory, target.Schema, dgSchema); err != nil {
+				return err
+			}
+			after = target.Schema
+		default:
+			return errors.Errorf("schema version %d has unknown kind %q", version, target.Kind)
+		}
+
+		var err error
+		res.Version, err = recordSchemaChange(ctx, target.Kind, before, after, version)
+		return err
+	})
+	return res, err
+}
+
+// rollbackDQLSchema applies the DQL schema target, and drops the types of current which target
+// doesn't have.
+func rollbackDQLSchema(ctx context.Context, namespace uint64, current, target string,
+	res *SchemaRollback) error {
+	currentSchema, err := schema.ParseWithNamespace(current, namespace)
+	if err != nil {
+		return err
+	}
+	targetSchema, err := schema.ParseWithNamespace(target, namespace)
+	if err != nil {
+		return errors.Wrapf(err, "while parsing the schema rolled back to")
+	}
+
+	inTarget := make(map[string]struct{})
+	for _, pred := range targetSchema.Preds {
+		inTarget[pred.Predicate] = struct{}{}
+	}
+	for _, typ := range targetSchema.Types {
+		inTarget[typ.TypeName] = struct{}{}
+	}
+	for _, pred := range currentSchema.Preds {
+		if _, ok := inTarget[pred.Predicate]; !ok {
+			res.KeptPredicates = append(res.KeptPredicates, x.ParseAttr(pred.Predicate))
+		}
+	}
+
+	s := &Server{}
+	if target != "" {
+		if _, err := s.Alter(ctx, &api.Operation{Schema: target}); err != nil {
+			return err
+		}
+	}
+	for _, typ := range currentSchema.Types {
+		if _, ok := inTarget[typ.TypeName]; ok {
+			continue
+		}
+		name := x.ParseAttr(typ.TypeName)
+		op := &api.Operation{DropOp: api.Operation_TYPE, DropValue: name}
+		if _, err := s.Alter(ctx, op); err != nil {
+			return errors.Wrapf(err, "while dropping type %s", name)
+		}
+		res.DroppedTypes = append(res.DroppedTypes, name)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	before := "<age>: int .\n<name>: string .\ntype <Person> {\n\t<name>\n}"
+	after := "<age>: int @index(int) .\n<name>: string .\ntype <Person> {\n\t<name>\n\t<age>\n}"
+	require.Equal(t, "-<age>: int .\n+<age>: int @index(int) .\n+\t<age>",
+		diffSchemas(before, after))
+	require.Equal(t, "+<name>: string .", diffSchemas("", "<name>: string ."))
+	require.Equal(t, "", diffSchemas(before, before))
+}
+
+func TestSchemaNodeString(t *testing.T) {
+	nodes := []*pb.SchemaNode{
+		{Type: "string", Index: true, Tokenizer: []string{"exact", "term"}, Lang: true,
+			Upsert: true},
+		{Type: "uid", List: true, Reverse: true, Count: true},
+		{Type: "string", Collation: "de", Presence: true, NoConflict: true},
+	}
+	var out []string
+	for _, node := range nodes {
+		out = append(out, SchemaNodeString("p", node))
+	}
+	require.Equal(t, []string{
+		"<p>: string @index(exact, term) @lang @upsert .",
+		"<p>: [uid] @reverse @count .",
+		"<p>: string @presence @collate(de) @noconflict .",
+	}, out)
+
+	// The rendered schema can be applied again.
+	for _, s := range out {
+		_, err := schema.Parse(s)
+		require.NoError(t, err, s)
+	}
+}
@@ -143,10 +143,17 @@ func PeriodicallyPostTelemetry() {
 // GetGQLSchema queries for the GraphQL schema node, and returns the uid and the GraphQL schema.
 // If multiple schema nodes were found, it returns an error.
 func GetGQLSchema(namespace uint64) (uid, graphQLSchema string, err error) {
+	return GetGQLSchemaAt(namespace, 0)
+}
+
+// GetGQLSchemaAt is like GetGQLSchema, but reads the GraphQL schema at the given startTs. A zero
+// startTs reads the latest schema.
+func GetGQLSchemaAt(namespace, startTs uint64) (uid, graphQLSchema string, err error) {
 	ctx := context.WithValue(context.Background(), Authorize, false)
 	ctx = x.AttachNamespace(ctx, namespace)
 	resp, err := (&Server{}).Query(ctx,
 		&api.Request{
+			StartTs: startTs,
 			Query: `
 			query {
 			  ExistingGQLSchema(func: has(dgraph.graphql.schema)) {
@@ -195,6 +202,14 @@ func GetGQLSchema(namespace uint64) (uid, graphQLSchema string, err error) {
 // it returns an error. All this is done on the alpha on which the update request is received.
 // Then it sends an update request to the worker, which is executed only on Group-1 leader.
 func UpdateGQLSchema(ctx context.Context, gqlSchema,
+	dgraphSchema string) (*pb.UpdateGraphQLSchemaResponse, error) {
+	return UpdateGQLSchemaAt(ctx, worker.State.GetTimestamp(false), gqlSchema, dgraphSchema)
+}
+
+// UpdateGQLSchemaAt is like UpdateGQLSchema, but performs the update in a transaction starting at
+// startTs. This is used for read-modify-write updates of the GraphQL schema: if the schema is
+// read at startTs and changed by someone else before the update commits, the update is aborted.
+func UpdateGQLSchemaAt(ctx context.Context, startTs uint64, gqlSchema,
 	dgraphSchema string) (*pb.UpdateGraphQLSchemaResponse, error) {
 	var err error
 	parsedDgraphSchema := &schema.ParsedSchema{}
@@ -213,12 +228,26 @@ func UpdateGQLSchema(ctx context.Context, gqlSchema,
 		}
 	}
 
-	return worker.UpdateGQLSchemaOverNetwork(ctx, &pb.UpdateGraphQLSchemaRequest{
-		StartTs:       worker.State.GetTimestamp(false),
+	namespace, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, before, err := GetGQLSchemaAt(namespace, startTs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading the GraphQL schema before the change")
+	}
+
+	resp, err := worker.UpdateGQLSchemaOverNetwork(ctx, &pb.UpdateGraphQLSchemaRequest{
+		StartTs:       startTs,
 		GraphqlSchema: gqlSchema,
 		DgraphPreds:   parsedDgraphSchema.Preds,
 		DgraphTypes:   parsedDgraphSchema.Types,
 	})
+	if err != nil {
+		return nil, err
+	}
+	recordSchemaChangeOrLog(ctx, SchemaKindGraphQL, before, gqlSchema)
+	return resp, nil
 }
 
 // validateAlterOperation validates the given operation for alter.
@@ -232,6 +261,9 @@ func validateAlterOperation(ctx context.Context, op *api.Operation) error {
 	if err := x.HealthCheck(); err != nil {
 		return err
 	}
+	if err := admitRequest(ctx); err != nil {
+		return err
+	}
 
 	if isDropAll(op) && op.DropOp == api.Operation_DATA {
 		return errors.Errorf("Only one of DropAll and DropData can be true")
@@ -240,6 +272,9 @@ func validateAlterOperation(ctx context.Context, op *api.Operation) error {
 	if !isMutationAllowed(ctx) {
 		return errors.Errorf("No mutations allowed by server.")
 	}
+	if err := checkReadOnly(); err != nil {
+		return err
+	}
 	if _, err := hasAdminAuth(ctx, "Alter"); err != nil {
 		glog.Warningf("Alter denied with error: %v\n", err)
 		return err
@@ -253,6 +288,32 @@ func validateAlterOperation(ctx context.Context, op *api.Operation) error {
 	return nil
 }
 
+// admitRequest returns a ResourceExhausted error if the request is shed because the server is
+// under memory or CPU pressure.
+func admitRequest(ctx context.Context) error {
+	// Guardians may ask for the admin class, so that they can operate the cluster under pressure.
+	if x.RequestedPriority(ctx) == x.PriorityAdmin && x.WorkerConfig.AclEnabled &&
+		AuthorizeGuardians(ctx) == nil {
+		ctx = x.WithPriority(ctx, x.PriorityAdmin)
+	}
+	if err := x.AdmitRequest(ctx); err != nil {
+		if err == x.ErrRequestShed {
+			return newStatusError(codes.ResourceExhausted, ReasonOverloaded, err, true,
+				retryInfo(retryDelay), quotaFailure("shedding", err.Error()))
+		}
+		return err
+	}
+	return nil
+}
+
+// checkReadOnly returns a FailedPrecondition error if Zero has put the cluster in read-only mode.
+func checkReadOnly() error {
+	if err := worker.CheckReadOnly(); err != nil {
+		return newStatusError(codes.FailedPrecondition, ReasonReadOnly, err, false)
+	}
+	return nil
+}
+
 // parseSchemaFromAlterOperation parses the string schema given in input operation to a Go
 // struct, and performs some checks to make sure that the schema is valid.
 func parseSchemaFromAlterOperation(ctx context.Context, op *api.Operation) (*schema.ParsedSchema,
@@ -356,6 +417,10 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 	defer span.End()
 
 	ctx = x.AttachJWTNamespace(ctx)
+	ctx, err := guestContext(ctx)
+	if err != nil {
+		return nil, err
+	}
 	span.Annotatef(nil, "Alter operation: %+v", op)
 
 	// Always print out Alter operations because they are important and rare.
@@ -507,6 +572,27 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 	}
 
 	glog.Infof("Got schema: %+v\n", result)
+	historyCtx := ctx
+	if x.IsGalaxyOperation(ctx) {
+		// The schema history is kept per namespace, and the schema is the one of the namespace
+		// the operation is forced to.
+		if ns, err := strconv.ParseUint(x.GetForceNamespace(ctx), 0, 64); err == nil {
+			historyCtx = x.AttachNamespace(ctx, ns)
+		}
+	}
+	historyNs, err := x.ExtractNamespace(historyCtx)
+	if err != nil {
+		return empty, err
+	}
+	before, err := dqlSchema(ctx, historyNs, nil)
+	if err != nil {
+		return empty, errors.Wrapf(err, "while reading the schema before the change")
+	}
+	after, err := dqlSchema(ctx, historyNs, result)
+	if err != nil {
+		return empty, errors.Wrapf(err, "while reading the schema after the change")
+	}
+
 	// TODO: Maybe add some checks about the schema.
 	m.Schema = result.Preds
 	m.Types = result.Types
@@ -514,6 +600,7 @@ func (s *Server) Alter(ctx context.Context, op *api.Operation) (*api.Payload, er
 	if err != nil {
 		return empty, err
 	}
+	recordSchemaChangeOrLog(historyCtx, SchemaKindDQL, before, after)
 
 	// wait for indexing to complete or context to be canceled.
 	if err = worker.WaitForIndexing(ctx, !op.RunInBackground); err != nil {
@@ -543,11 +630,18 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 	if !isMutationAllowed(ctx) {
 		return errors.Errorf("no mutations allowed")
 	}
+	if err := checkReadOnly(); err != nil {
+		return err
+	}
 
 	// update mutations from the query results before assigning UIDs
 	if err := updateMutations(qc); err != nil {
 		return err
 	}
+	// replace the quoted triples setting edge properties with their edge nodes
+	if err := resolveEdgeNodes(ctx, qc); err != nil {
+		return err
+	}
 
 	newUids, err := query.AssignUids(ctx, qc.gmuList)
 	if err != nil {
@@ -563,6 +657,12 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 	if err != nil {
 		return err
 	}
+	if edges, err = addLineage(ctx, qc.req.StartTs, edges); err != nil {
+		return err
+	}
+	if edges, err = addAuditTrail(ctx, qc.req.StartTs, edges, newUids); err != nil {
+		return err
+	}
 	ns, err := x.ExtractNamespace(ctx)
 	if err != nil {
 		return errors.Wrapf(err, "While doing mutations:")
@@ -583,12 +683,32 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 		Metadata: &pb.Metadata{
 			PredHints: predHints,
 		},
+		DryRun: qc.dryRun,
+	}
+	// calculateMutationMetrics calculate cost for the mutation.
+	calculateMutationMetrics := func() {
+		cost := uint64(len(newUids) + len(edges))
+		resp.Metrics.NumUids["mutation_cost"] = cost
+		resp.Metrics.NumUids["_total"] = resp.Metrics.NumUids["_total"] + cost
 	}
 
 	qc.span.Annotatef(nil, "Applying mutations: %+v", m)
 	resp.Txn, err = query.ApplyMutations(ctx, m)
 	qc.span.Annotatef(nil, "Txn Context: %+v. Err=%v", resp.Txn, err)
 
+	if qc.dryRun {
+		// Nothing was written, so there's nothing to commit or abort. Send back the edges which
+		// would have been written, along with their conflict keys.
+		if err != nil {
+			return err
+		}
+		if resp.Rdf, err = worker.MutationRdf(m.Edges); err != nil {
+			return err
+		}
+		calculateMutationMetrics()
+		return nil
+	}
+
 	if x.WorkerConfig.LudicrousMode {
 		// Mutations are automatically committed in case of ludicrous mode, so we don't
 		// need to manually commit.
@@ -599,16 +719,10 @@ func (s *Server) doMutate(ctx context.Context, qc *queryContext, resp *api.Respo
 		resp.Txn.CommitTs = qc.req.StartTs
 		return err
 	}
-	// calculateMutationMetrics calculate cost for the mutation.
-	calculateMutationMetrics := func() {
-		cost := uint64(len(newUids) + len(edges))
-		resp.Metrics.NumUids["mutation_cost"] = cost
-		resp.Metrics.NumUids["_total"] = resp.Metrics.NumUids["_total"] + cost
-	}
 	if !qc.req.CommitNow {
 		calculateMutationMetrics()
 		if err == x.ErrConflict {
-			err = status.Error(codes.FailedPrecondition, err.Error())
+			err = newStatusError(codes.FailedPrecondition, ReasonTxnConflict, err, true)
 		}
 
 		return err
@@ -940,6 +1054,8 @@ type queryContext struct {
 	// req is the incoming, not yet parsed request containing
 	// a query or more than one mutations or both (in case of upsert)
 	req *api.Request
+	// typedVars are the variables of req given in binary form, if any.
+	typedVars map[string]*pb.Variable
 	// gmuList is the list of mutations after parsing req.Mutations
 	gmuList []*gql.Mutation
 	// gqlRes contains result of parsing the req.Query
@@ -972,6 +1088,9 @@ type queryContext struct {
 	// 1B) and resulting in OOM. We are limiting number of nquads which can be inserted in
 	// a single request.
 	nquadsCount int
+	// dryRun is set when the mutations are only validated and their conflict keys computed,
+	// without writing them.
+	dryRun bool
 }
 
 // Request represents a query request sent to the doQuery() method on the Server.
@@ -979,6 +1098,8 @@ type queryContext struct {
 type Request struct {
 	// req is the incoming gRPC request
 	req *api.Request
+	// typedVars are the variables of req given in binary form, if any
+	typedVars map[string]*pb.Variable
 	// gqlField is the GraphQL field for which the request is being sent
 	gqlField gqlSchema.Field
 	// doAuth tells whether this request needs ACL authorization or not
@@ -1102,6 +1223,17 @@ func (s *Server) Query(ctx context.Context, req *api.Request) (*api.Response, er
 	return s.doQuery(ctx, &Request{req: req, doAuth: getAuthMode(ctx)})
 }
 
+// TypedQuery handles queries or mutations like Query, with the variables given in binary form.
+func (s *Server) TypedQuery(ctx context.Context, req *pb.TypedQueryRequest) (
+	*api.Response, error) {
+	if req.Request == nil {
+		return nil, errors.Errorf("Empty request")
+	}
+	ctx = x.AttachJWTNamespace(ctx)
+	return s.doQuery(ctx, &Request{req: req.Request, typedVars: req.Vars,
+		doAuth: getAuthMode(ctx)})
+}
+
 func (s *Server) doQuery(ctx context.Context, req *Request) (
 	resp *api.Response, rerr error) {
 	if bool(glog.V(3)) || worker.LogRequestEnabled() {
@@ -1145,6 +1277,23 @@ func (s *Server) doQuery(ctx context.Context, req *Request) (
 	if rerr = x.HealthCheck(); rerr != nil {
 		return
 	}
+	if rerr = admitRequest(ctx); rerr != nil {
+		return
+	}
+	if isMutation {
+		// The durability is only used at commit, but an invalid one must be rejected before any
+		// mutation is applied.
+		if _, rerr = x.DurabilityFromContext(ctx); rerr != nil {
+			return
+		}
+	}
+	if req.doAuth == NeedAuthorize {
+		guestCtx, err := guestContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ctx = guestCtx
+	}
 
 	req.req.Query = strings.TrimSpace(req.req.Query)
 	isQuery := len(req.req.Query) != 0
@@ -1172,18 +1321,28 @@ func (s *Server) doQuery(ctx context.Context, req *Request) (
 		}
 	}
 
-	qc := &queryContext{
-		req:      req.req,
-		latency:  l,
-		span:     span,
-		graphql:  isGraphQL,
-		gqlField: req.gqlField,
+	dryRun := isMutation && x.IsDryRun(ctx)
+	if dryRun && req.req.StartTs != 0 {
+		return nil, errors.Errorf("A dry run of mutations can't be part of a transaction")
 	}
-	if rerr = parseRequest(qc); rerr != nil {
+
+	qc := &queryContext{
+		req:       req.req,
+		typedVars: req.typedVars,
+		latency:   l,
+		span:      span,
+		graphql:   isGraphQL,
+		gqlField:  req.gqlField,
+		dryRun:    dryRun,
+	}
+	if rerr = parseRequest(ctx, qc); rerr != nil {
 		return
 	}
 
 	if req.doAuth == NeedAuthorize {
+		if rerr = checkQueryPolicy(ctx, qc); rerr != nil {
+			return
+		}
 		if rerr = authorizeRequest(ctx, qc); rerr != nil {
 			return
 		}
@@ -1235,6 +1394,10 @@ func (s *Server) doQuery(ctx context.Context, req *Request) (
 		EncodingNs:        uint64(l.Json.Nanoseconds()),
 		TotalNs:           uint64((time.Since(l.Start)).Nanoseconds()),
 	}
+	// Queries run by the Alpha itself aren't worth replaying.
+	if req.doAuth == NeedAuthorize {
+		logSlowQuery(ctx, qc, resp)
+	}
 	md := metadata.Pairs(x.DgraphCostHeader, fmt.Sprint(resp.Metrics.NumUids["_total"]))
 	grpc.SendHeader(ctx, md)
 	return resp, gqlErrs
@@ -1252,6 +1415,10 @@ func processQuery(ctx context.Context, qc *queryContext) (*api.Response, error)
 	if ctx.Err() != nil {
 		return resp, ctx.Err()
 	}
+	ns, _ := x.ExtractNamespace(ctx)
+	ctx, done := query.TrackMemory(ctx, ns, qc.req.Query)
+	defer done()
+
 	if x.WorkerConfig.LudicrousMode {
 		qc.req.StartTs = posting.Oracle().MaxAssigned()
 	}
@@ -1281,6 +1448,12 @@ func processQuery(ctx context.Context, qc *queryContext) (*api.Response, error)
 			qc.req.StartTs = posting.Oracle().MaxAssigned()
 		}
 		qr.Cache = worker.NoCache
+		// Let lagging replicas serve the query at the state they have, within the allowed lag.
+		qr.MaxLag = x.Config.BestEffortMaxLag
+	}
+	var servedTs func() uint64
+	if qr.MaxLag > 0 {
+		ctx, servedTs = worker.WithServedTs(ctx)
 	}
 
 	if qc.req.StartTs == 0 {
@@ -1296,6 +1469,11 @@ func processQuery(ctx context.Context, qc *queryContext) (*api.Response, error)
 	er, err := qr.Process(ctx)
 
 	if err != nil {
+		// Other parts of the query fail with a cancelled context once the memory limit is hit,
+		// so report the limit instead of whichever error came first.
+		if merr := query.MemoryLimitError(ctx); merr != nil {
+			err = merr
+		}
 		return resp, errors.Wrap(err, "")
 	}
 
@@ -1321,7 +1499,25 @@ func processQuery(ctx context.Context, qc *queryContext) (*api.Response, error)
 	} else if qc.req.RespFormat == api.Request_RDF {
 		resp.Rdf, err = query.ToRDF(qc.latency, er.Subgraphs)
 	} else {
-		resp.Json, err = query.ToJson(ctx, qc.latency, er.Subgraphs, qc.gqlField)
+		var cursor string
+		resp.Json, cursor, err = query.ToJsonWithLimit(ctx, qc.latency, er.Subgraphs, qc.gqlField,
+			x.Config.MaxResponseBytes)
+		if cursor != "" {
+			resp.Hdrs = map[string]*api.ListOfString{
+				query.PartialKey: {Value: []string{"true"}},
+				query.CursorKey:  {Value: []string{cursor}},
+			}
+		}
+	}
+	if servedTs != nil {
+		if ts := servedTs(); ts > 0 {
+			// Tell the client how many timestamps behind StartTs the oldest part of the response is.
+			if resp.Hdrs == nil {
+				resp.Hdrs = make(map[string]*api.ListOfString)
+			}
+			resp.Hdrs[query.StalenessKey] = &api.ListOfString{
+				Value: []string{strconv.FormatUint(qc.req.StartTs-ts, 10)}}
+		}
 	}
 	// if err is just some error from GraphQL encoding, then we need to continue the normal
 	// execution ignoring the error as we still need to assign metrics and latency info to resp.
@@ -1385,7 +1581,7 @@ func processQuery(ctx context.Context, qc *queryContext) (*api.Response, error)
 }
 
 // parseRequest parses the incoming request
-func parseRequest(qc *queryContext) error {
+func parseRequest(ctx context.Context, qc *queryContext) error {
 	start := time.Now()
 	defer func() {
 		qc.latency.Parsing = time.Since(start)
@@ -1420,9 +1616,10 @@ func parseRequest(qc *queryContext) error {
 
 	// parsing the updated query
 	var err error
-	qc.gqlRes, err = gql.ParseWithNeedVars(gql.Request{
-		Str:       upsertQuery,
-		Variables: qc.req.Vars,
+	qc.gqlRes, err = parsePrepared(ctx, gql.Request{
+		Str:            upsertQuery,
+		Variables:      qc.req.Vars,
+		TypedVariables: qc.typedVars,
 	}, needVars)
 	if err != nil {
 		return err
@@ -1460,6 +1657,11 @@ func (s *Server) CommitOrAbort(ctx context.Context, tc *api.TxnContext) (*api.Tx
 			"StartTs cannot be zero while committing a transaction")
 	}
 	annotateStartTs(span, tc.StartTs)
+	if !tc.Aborted {
+		if err := checkReadOnly(); err != nil {
+			return &api.TxnContext{}, err
+		}
+	}
 
 	span.Annotatef(nil, "Txn Context received: %+v", tc)
 	commitTs, err := worker.CommitOverNetwork(ctx, tc)
@@ -1583,6 +1785,10 @@ func parseMutationObject(mu *api.Mutation, qc *queryContext) (*gql.Mutation, err
 	if err := validateAndConvertFacets(res.Set); err != nil {
 		return nil, err
 	}
+	var err error
+	if res.Set, err = assertQuotedEdges(res.Set, res.Del); err != nil {
+		return nil, err
+	}
 
 	if err := validateNQuads(res.Set, res.Del, qc); err != nil {
 		return nil, err
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// SlowQueryDefaults are the default values for the --slow_query superflag.
+const SlowQueryDefaults = "file=; threshold=1s; redact-vars=true; size=100; days=10"
+
+// SlowQuery is an entry of the slow query log: a DQL query that took longer than the threshold,
+// with what is needed to run it again at the same read timestamp and to compare the outcome. If
+// the variables are redacted, Vars is empty and VarsHash is their hash instead.
+type SlowQuery struct {
+	Time       time.Time         `json:"time"`
+	Version    string            `json:"version"`
+	Namespace  uint64            `json:"namespace"`
+	Query      string            `json:"query"`
+	Vars       map[string]string `json:"vars,omitempty"`
+	VarsHash   string            `json:"vars_hash,omitempty"`
+	ReadTs     uint64            `json:"read_ts"`
+	BestEffort bool              `json:"best_effort,omitempty"`
+	// Latency is the total time the Alpha spent on the query, and Processing the part spent
+	// running it, in nanoseconds.
+	Latency    uint64 `json:"latency_ns"`
+	Processing uint64 `json:"processing_ns"`
+	NumUids    uint64 `json:"num_uids"`
+	// ResultHash is the hex-encoded SHA-256 of the JSON response, which must be the same when the
+	// query is run again at the same read timestamp over the same data.
+	ResultHash string `json:"result_hash"`
+}
+
+// HashResult returns the hash of a JSON response recorded in the slow query log.
+func HashResult(js []byte) string {
+	sum := sha256.Sum256(js)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashVars returns the hash of the variables of a query, recorded in the slow query log in
+// place of the variables when they are redacted.
+func HashVars(vars map[string]string) string {
+	// The keys of maps are encoded in order, so the same variables give the same hash.
+	js, err := json.Marshal(vars)
+	if err != nil {
+		return ""
+	}
+	return HashResult(js)
+}
+
+// slowQueryLog appends the queries slower than the threshold to a file, one JSON object per line.
+// The file is rotated once it reaches its maximum size.
+type slowQueryLog struct {
+	threshold  time.Duration
+	redactVars bool
+	sync.Mutex
+	w   *x.LogWriter
+	enc *json.Encoder
+}
+
+// slowQueries is the slow query log set via the --slow_query flag, or nil.
+var slowQueries *slowQueryLog
+
+// SetSlowQueryLog parses the --slow_query superflag, and opens the slow query log if it has a
+// file.
+func SetSlowQueryLog(sf *z.SuperFlag) error {
+	threshold, err := time.ParseDuration(sf.GetString("threshold"))
+	if err != nil {
+		return errors.Wrapf(err, "while parsing threshold")
+	}
+	if threshold < 0 {
+		return errors.Errorf("threshold can't be negative")
+	}
+	size, days := sf.GetInt64("size"), sf.GetInt64("days")
+	if size <= 0 || days <= 0 {
+		return errors.Errorf("size and days must be positive")
+	}
+	if slowQueries != nil {
+		if err := slowQueries.w.Close(); err != nil {
+			glog.Warningf("While closing the slow query log: %v", err)
+		}
+	}
+	file := sf.GetString("file")
+	if file == "" {
+		slowQueries = nil
+		return nil
+	}
+	w := &x.LogWriter{FilePath: file, MaxSize: size, MaxAge: days}
+	w, err = w.Init()
+	if err != nil {
+		return errors.Wrapf(err, "while opening the slow query log")
+	}
+	slowQueries = &slowQueryLog{
+		threshold:  threshold,
+		redactVars: sf.GetBool("redact-vars"),
+		w:          w,
+		enc:        json.NewEncoder(w),
+	}
+	glog.Infof("Logging the queries slower than %s to %s", threshold, file)
+	return nil
+}
+
+// logSlowQuery records the query in the slow query log if it took longer than the threshold.
+// Mutations and GraphQL queries aren't recorded, as they can't be replayed as they are.
+func logSlowQuery(ctx context.Context, qc *queryContext, resp *api.Response) {
+	l := slowQueries
+	if l == nil || qc.graphql || len(qc.req.Mutations) > 0 ||
+		resp.Latency.GetTotalNs() < uint64(l.threshold.Nanoseconds()) {
+		return
+	}
+	ns, _ := x.ExtractNamespace(ctx)
+	entry := &SlowQuery{
+		Time:       time.Now().UTC(),
+		Version:    x.Version(),
+		Namespace:  ns,
+		Query:      qc.req.Query,
+		Vars:       qc.req.Vars,
+		ReadTs:     qc.req.StartTs,
+		BestEffort: qc.req.BestEffort,
+		Latency:    resp.Latency.GetTotalNs(),
+		Processing: resp.Latency.GetProcessingNs(),
+		NumUids:    resp.Metrics.GetNumUids()["_total"],
+		ResultHash: HashResult(resp.Json),
+	}
+	// Variables may hold passwords checked with checkpwd and personal data.
+	if l.redactVars && len(entry.Vars) > 0 {
+		entry.Vars, entry.VarsHash = nil, HashVars(entry.Vars)
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	if err := l.enc.Encode(entry); err != nil {
+		glog.Warningf("While writing to the slow query log: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slow")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "slow.json")
+	defer func() { slowQueries = nil }()
+
+	sf := z.NewSuperFlag("threshold=x").MergeAndCheckDefault(SlowQueryDefaults)
+	require.Error(t, SetSlowQueryLog(sf))
+	sf = z.NewSuperFlag("file=" + file + "; size=0").MergeAndCheckDefault(SlowQueryDefaults)
+	require.Error(t, SetSlowQueryLog(sf))
+	sf = z.NewSuperFlag("file=" + file + "; threshold=10ms; redact-vars=false").
+		MergeAndCheckDefault(SlowQueryDefaults)
+	require.NoError(t, SetSlowQueryLog(sf))
+
+	query := func(q string, latency time.Duration, mutation bool) {
+		qc := &queryContext{req: &api.Request{Query: q, StartTs: 42,
+			Vars: map[string]string{"$a": "1"}}}
+		if mutation {
+			qc.req.Mutations = []*api.Mutation{{}}
+		}
+		resp := &api.Response{
+			Json:    []byte(`{"q":[]}`),
+			Latency: &api.Latency{TotalNs: uint64(latency), ProcessingNs: uint64(latency / 2)},
+			Metrics: &api.Metrics{NumUids: map[string]uint64{"_total": 7}},
+		}
+		logSlowQuery(context.Background(), qc, resp)
+	}
+	query("{ fast(func: uid(1)) { uid } }", time.Millisecond, false)
+	query("{ slow(func: uid(1)) { uid } }", 20*time.Millisecond, false)
+	query("{ q(func: uid(1)) { v as uid } }", 20*time.Millisecond, true)
+	// The writes are buffered until the log is closed.
+	require.NoError(t, slowQueries.w.Close())
+
+	data, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+	var entry SlowQuery
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	require.Equal(t, "{ slow(func: uid(1)) { uid } }", entry.Query)
+	require.Equal(t, map[string]string{"$a": "1"}, entry.Vars)
+	require.Equal(t, uint64(42), entry.ReadTs)
+	require.Equal(t, uint64(20*time.Millisecond), entry.Latency)
+	require.Equal(t, uint64(10*time.Millisecond), entry.Processing)
+	require.Equal(t, uint64(7), entry.NumUids)
+	require.Equal(t, HashResult([]byte(`{"q":[]}`)), entry.ResultHash)
+
+	// The variables are redacted by default.
+	require.NoError(t, os.Remove(file))
+	sf = z.NewSuperFlag("file=" + file + "; threshold=10ms").MergeAndCheckDefault(
+		SlowQueryDefaults)
+	require.NoError(t, SetSlowQueryLog(sf))
+	query("{ slow(func: uid(1)) { uid } }", 20*time.Millisecond, false)
+	require.NoError(t, slowQueries.w.Close())
+	data, err = ioutil.ReadFile(file)
+	require.NoError(t, err)
+	entry = SlowQuery{}
+	require.NoError(t, json.Unmarshal(data, &entry))
+	require.Empty(t, entry.Vars)
+	require.Equal(t, HashVars(map[string]string{"$a": "1"}), entry.VarsHash)
+	require.NotContains(t, string(data), `"$a"`)
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+)
+
+// mutationAssembler assembles the chunks of a streamed mutation into the mutations of a request.
+type mutationAssembler struct {
+	req     *api.Request
+	started bool
+	// set and del hold the N-Quads split by the end of the last chunk.
+	set, del []byte
+	size     int64
+}
+
+func newMutationAssembler() *mutationAssembler {
+	return &mutationAssembler{req: &api.Request{}}
+}
+
+// cutLines returns the whole lines of the N-Quads following the partial line, and keeps the
+// partial line at their end.
+func cutLines(partial *[]byte, nquads []byte) []byte {
+	end := bytes.LastIndexByte(nquads, '\n')
+	if end < 0 {
+		*partial = append(*partial, nquads...)
+		return nil
+	}
+	lines := append(*partial, nquads[:end+1]...)
+	*partial = append([]byte(nil), nquads[end+1:]...)
+	return lines
+}
+
+func (a *mutationAssembler) add(c *pb.MutationChunk) error {
+	if !a.started {
+		a.req.StartTs = c.StartTs
+		a.req.CommitNow = c.CommitNow
+		a.started = true
+	}
+	a.size += int64(len(c.SetNquads) + len(c.DelNquads) + len(c.SetJson) + len(c.DeleteJson))
+	if limit := x.Config.MutationStreamLimit; limit > 0 && a.size > limit {
+		return errors.Errorf("Streamed mutation is larger than the limit of %d bytes", limit)
+	}
+
+	mu := &api.Mutation{
+		SetNquads:  cutLines(&a.set, c.SetNquads),
+		DelNquads:  cutLines(&a.del, c.DelNquads),
+		SetJson:    c.SetJson,
+		DeleteJson: c.DeleteJson,
+	}
+	if len(mu.SetNquads)+len(mu.DelNquads)+len(mu.SetJson)+len(mu.DeleteJson) > 0 {
+		a.req.Mutations = append(a.req.Mutations, mu)
+	}
+	return nil
+}
+
+// request returns the request holding all the mutations once the stream ended.
+func (a *mutationAssembler) request() *api.Request {
+	if len(bytes.TrimSpace(a.set))+len(bytes.TrimSpace(a.del)) > 0 {
+		a.req.Mutations = append(a.req.Mutations, &api.Mutation{SetNquads: a.set, DelNquads: a.del})
+		a.set, a.del = nil, nil
+	}
+	return a.req
+}
+
+// StreamMutate runs the mutation streamed in chunks, so that it can be larger than the maximum
+// size of a gRPC message. The chunks are held in memory until the stream ends, and the mutation
+// is run in a single transaction, like Query would.
+func (s *Server) StreamMutate(stream pb.MutationStream_StreamMutateServer) error {
+	a := newMutationAssembler()
+	for {
+		c, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := a.add(c); err != nil {
+			return err
+		}
+	}
+	req := a.request()
+	if len(req.Mutations) == 0 {
+		return errors.New("Empty streamed mutation")
+	}
+
+	ctx := x.AttachJWTNamespace(stream.Context())
+	resp, err := s.doQuery(ctx, &Request{req: req, doAuth: getAuthMode(ctx)})
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(resp)
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMutationAssembler(t *testing.T) {
+	a := newMutationAssembler()
+	for _, c := range []*pb.MutationChunk{
+		{SetNquads: []byte("_:a <name> \"a\" .\n_:b <na"), StartTs: 5, CommitNow: true},
+		{SetNquads: []byte("me> \"b\""), DelNquads: []byte("<0x1> <name> * .")},
+		{SetNquads: []byte(" .\n_:c <name> \"c\" ."), SetJson: []byte(`{"name": "d"}`)},
+		{StartTs: 6},
+	} {
+		require.NoError(t, a.add(c))
+	}
+	req := a.request()
+	require.Equal(t, uint64(5), req.StartTs)
+	require.True(t, req.CommitNow)
+
+	var set, del, json string
+	for _, mu := range req.Mutations {
+		set += string(mu.SetNquads)
+		del += string(mu.DelNquads)
+		json += string(mu.SetJson)
+	}
+	require.Equal(t, "_:a <name> \"a\" .\n_:b <name> \"b\" .\n_:c <name> \"c\" .", set)
+	require.Equal(t, "<0x1> <name> * .", del)
+	require.Equal(t, `{"name": "d"}`, json)
+	for _, mu := range req.Mutations {
+		// The N-Quads are only split at the end of the lines.
+		if len(mu.SetNquads) > 0 && mu != req.Mutations[len(req.Mutations)-1] {
+			require.Equal(t, byte('\n'), mu.SetNquads[len(mu.SetNquads)-1])
+		}
+	}
+}
+
+func TestMutationAssemblerLimit(t *testing.T) {
+	defer func(limit int64) { x.Config.MutationStreamLimit = limit }(x.Config.MutationStreamLimit)
+	x.Config.MutationStreamLimit = 20
+
+	a := newMutationAssembler()
+	require.NoError(t, a.add(&pb.MutationChunk{SetNquads: []byte("_:a <name> \"a\" .\n")}))
+	require.Error(t, a.add(&pb.MutationChunk{SetNquads: []byte("_:b <name> \"b\" .\n")}))
+}
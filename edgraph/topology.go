@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// topologyPollInterval is how often the topology is checked for changes for watching clients.
+const topologyPollInterval = time.Second
+
+// Topology sends the groups serving the predicates of the namespace of the caller, along with the
+// external gRPC addresses of their Alphas. If watch is set, the topology is sent again every time
+// it changes, until the client goes away.
+func (s *Server) Topology(req *pb.TopologyRequest, stream pb.Topology_TopologyServer) error {
+	if err := x.HealthCheck(); err != nil {
+		return err
+	}
+	ctx := stream.Context()
+	namespace := x.GalaxyNamespace
+	if x.WorkerConfig.AclEnabled {
+		ns, err := x.ExtractJWTNamespace(ctx)
+		if err != nil {
+			return errors.Errorf("Namespace not found in JWT.")
+		}
+		namespace = ns
+	}
+
+	ticker := time.NewTicker(topologyPollInterval)
+	defer ticker.Stop()
+	var last *pb.ClusterTopology
+	for {
+		topology := buildTopology(worker.GetMembershipState(), namespace)
+		if last == nil || !proto.Equal(last, topology) {
+			if err := stream.Send(topology); err != nil {
+				return err
+			}
+			last = topology
+		}
+		if !req.Watch {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// buildTopology converts the membership state into the topology of the given namespace. Groups,
+// members and predicates are sorted, so that the topologies can be compared.
+func buildTopology(state *pb.MembershipState, namespace uint64) *pb.ClusterTopology {
+	topology := &pb.ClusterTopology{}
+	for gid, group := range state.GetGroups() {
+		g := &pb.ClusterTopology_Group{GroupId: gid}
+		for _, m := range group.GetMembers() {
+			if m.AmDead {
+				continue
+			}
+			g.Members = append(g.Members, &pb.ClusterTopology_Member{
+				Id:       m.Id,
+				GrpcAddr: m.GrpcAddr,
+				Leader:   m.Leader,
+				Learner:  m.Learner,
+			})
+		}
+		for pred := range group.GetTablets() {
+			if ns, attr := x.ParseNamespaceAttr(pred); ns == namespace {
+				g.Predicates = append(g.Predicates, attr)
+			}
+		}
+		sort.Slice(g.Members, func(i, j int) bool { return g.Members[i].Id < g.Members[j].Id })
+		sort.Strings(g.Predicates)
+		topology.Groups = append(topology.Groups, g)
+	}
+	sort.Slice(topology.Groups, func(i, j int) bool {
+		return topology.Groups[i].GroupId < topology.Groups[j].GroupId
+	})
+	return topology
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edgraph
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTopology(t *testing.T) {
+	state := &pb.MembershipState{Groups: map[uint32]*pb.Group{
+		2: {
+			Members: map[uint64]*pb.Member{
+				3: {Id: 3, GrpcAddr: "alpha3:9080", Leader: true},
+				4: {Id: 4, GrpcAddr: "alpha4:9080", AmDead: true},
+			},
+			Tablets: map[string]*pb.Tablet{
+				x.GalaxyAttr("name"):      {},
+				x.NamespaceAttr(1, "age"): {},
+			},
+		},
+		1: {
+			Members: map[uint64]*pb.Member{
+				2: {Id: 2, GrpcAddr: "alpha2:9080"},
+				1: {Id: 1, GrpcAddr: "alpha1:9080", Leader: true},
+			},
+			Tablets: map[string]*pb.Tablet{
+				x.GalaxyAttr("friend"):      {},
+				x.GalaxyAttr("dgraph.type"): {},
+			},
+		},
+	}}
+
+	topology := buildTopology(state, x.GalaxyNamespace)
+	require.Len(t, topology.Groups, 2)
+	g1, g2 := topology.Groups[0], topology.Groups[1]
+	require.Equal(t, uint32(1), g1.GroupId)
+	require.Equal(t, []string{"dgraph.type", "friend"}, g1.Predicates)
+	require.Equal(t, "alpha1:9080", g1.Members[0].GrpcAddr)
+	require.True(t, g1.Members[0].Leader)
+	require.Equal(t, "alpha2:9080", g1.Members[1].GrpcAddr)
+	// Dead members and the predicates of other namespaces are left out.
+	require.Equal(t, []string{"name"}, g2.Predicates)
+	require.Len(t, g2.Members, 1)
+
+	topology = buildTopology(state, 1)
+	require.Empty(t, topology.Groups[0].Predicates)
+	require.Equal(t, []string{"age"}, topology.Groups[1].Predicates)
+}
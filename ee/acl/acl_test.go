@@ -166,7 +166,8 @@ func TestInvalidGetUser(t *testing.T) {
 	require.Equal(t, x.GqlErrorList{{
 		Message: "couldn't rewrite query getCurrentUser because unable to parse jwt token: token" +
 			" contains an invalid number of segments",
-		Path: []interface{}{"getCurrentUser"},
+		Path:       []interface{}{"getCurrentUser"},
+		Extensions: map[string]interface{}{"code": "UNAUTHENTICATED", "retryable": false},
 	}}, currentUser.Errors)
 }
 
@@ -193,6 +194,7 @@ func TestPasswordReturn(t *testing.T) {
 			Line:   5,
 			Column: 4,
 		}},
+		Extensions: map[string]interface{}{"code": "GRAPHQL_VALIDATION_FAILED", "retryable": false},
 	}})
 }
 
@@ -2169,6 +2171,33 @@ func TestSchemaQueryWithACL(t *testing.T) {
 		"predicate":"dgraph.drop.op",
 		"type":"string"
 	},
+	{
+		"predicate":"dgraph.edge.dst",
+		"type":"uid",
+		"reverse":true
+	},
+	{
+		"predicate":"dgraph.edge.key",
+		"type":"string",
+		"index":true,
+		"tokenizer":["exact"],
+		"upsert":true
+	},
+	{
+		"predicate":"dgraph.edge.pred",
+		"type":"string",
+		"index":true,
+		"tokenizer":["exact"]
+	},
+	{
+		"predicate":"dgraph.edge.src",
+		"type":"uid",
+		"reverse":true
+	},
+	{
+		"predicate":"dgraph.graphql.lambda_scripts",
+		"type":"string"
+	},
 	{
 		"predicate":"dgraph.graphql.p_query",
 		"type":"string",
@@ -2188,6 +2217,16 @@ func TestSchemaQueryWithACL(t *testing.T) {
       ],
       "upsert": true
     },
+    {
+      "predicate": "dgraph.lineage.blob",
+      "type": "string"
+    },
+    {
+      "predicate": "dgraph.lineage.subject",
+      "type": "uid",
+      "list": true,
+      "reverse": true
+    },
     {
       "predicate": "dgraph.password",
       "type": "password"
@@ -2205,6 +2244,10 @@ func TestSchemaQueryWithACL(t *testing.T) {
       ],
       "upsert": true
     },
+    {
+      "predicate": "dgraph.schema_history",
+      "type": "string"
+    },
     {
       "predicate": "dgraph.type",
       "type": "string",
@@ -2242,6 +2285,14 @@ func TestSchemaQueryWithACL(t *testing.T) {
       ],
       "name": "dgraph.graphql"
 	},
+	{
+		"fields": [
+			{
+				"name": "dgraph.graphql.lambda_scripts"
+			}
+		],
+		"name": "dgraph.graphql.lambda"
+	},
 	{
 		"fields": [
 			{
@@ -2250,6 +2301,25 @@ func TestSchemaQueryWithACL(t *testing.T) {
 		],
 		"name": "dgraph.graphql.persisted_query"
 	},
+	{
+		"fields": [
+			{
+				"name": "dgraph.lineage.blob"
+			},
+			{
+				"name": "dgraph.lineage.subject"
+			}
+		],
+		"name": "dgraph.lineage"
+	},
+	{
+		"fields": [
+			{
+				"name": "dgraph.schema_history"
+			}
+		],
+		"name": "dgraph.schema_version"
+	},
     {
       "fields": [
         {
@@ -2304,10 +2374,22 @@ func TestSchemaQueryWithACL(t *testing.T) {
       "fields": [],
       "name": "dgraph.graphql"
 	},
+	{
+		"fields":[],
+		"name":"dgraph.graphql.lambda"
+	},
 	{
 		"fields":[],
 		"name":"dgraph.graphql.persisted_query"
 	},
+	{
+		"fields":[],
+		"name":"dgraph.lineage"
+	},
+	{
+		"fields":[],
+		"name":"dgraph.schema_version"
+	},
     {
       "fields": [],
       "name": "dgraph.type.Group"
@@ -16,6 +16,7 @@ import (
 	"io/ioutil"
 	"math"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -26,7 +27,9 @@ import (
 )
 
 const (
-	defaultAuditConf     = "dir=; compress=false; encrypt-file=; days=10; size=100"
+	defaultAuditConf = "dir=; compress=false; encrypt-file=; days=10; size=100; syslog=; " +
+		"syslog-format=cef; syslog-tls=true; syslog-ca-cert=; syslog-client-cert=; " +
+		"syslog-client-key=; syslog-buffer=10000; syslog-full=drop"
 	defaultAuditFilename = "dgraph_audit.log"
 )
 
@@ -57,6 +60,7 @@ var auditor *auditLogger = &auditLogger{}
 
 type auditLogger struct {
 	log    *x.Logger
+	syslog *syslogSink
 	tick   *time.Ticker
 	closer *z.Closer
 }
@@ -67,16 +71,34 @@ func GetAuditConf(conf string) *x.LoggerConf {
 	}
 	auditFlag := z.NewSuperFlag(conf).MergeAndCheckDefault(defaultAuditConf)
 	dir := auditFlag.GetString("dir")
-	x.AssertTruef(dir != "", "dir flag is not provided for the audit logs")
+	syslogAddr := auditFlag.GetString("syslog")
+	x.AssertTruef(dir != "" || syslogAddr != "",
+		"neither dir nor syslog flag is provided for the audit logs")
 	encBytes, err := readAuditEncKey(auditFlag)
 	x.Check(err)
-	return &x.LoggerConf{
+	loggerConf := &x.LoggerConf{
 		Compress:      auditFlag.GetBool("compress"),
 		Dir:           dir,
 		EncryptionKey: encBytes,
 		Days:          auditFlag.GetInt64("days"),
 		Size:          auditFlag.GetInt64("size"),
 	}
+	if syslogAddr != "" {
+		full := auditFlag.GetString("syslog-full")
+		x.AssertTruef(full == "drop" || full == "block",
+			"syslog-full flag of the audit logs should be drop or block, not %q", full)
+		loggerConf.Syslog = &x.SyslogConf{
+			Addr:       syslogAddr,
+			Format:     strings.ToLower(auditFlag.GetString("syslog-format")),
+			TLS:        auditFlag.GetBool("syslog-tls"),
+			CACert:     auditFlag.GetString("syslog-ca-cert"),
+			ClientCert: auditFlag.GetString("syslog-client-cert"),
+			ClientKey:  auditFlag.GetString("syslog-client-key"),
+			BufferSize: int(auditFlag.GetInt64("syslog-buffer")),
+			Block:      full == "block",
+		}
+	}
+	return loggerConf
 }
 
 func readAuditEncKey(conf *z.SuperFlag) ([]byte, error) {
@@ -117,8 +139,7 @@ func InitAuditorIfNecessary(conf *x.LoggerConf, eeEnabled func() bool) error {
 // This method doesnt keep track of whether cluster is part of enterprise edition or not.
 // Client has to keep track of that.
 func InitAuditor(conf *x.LoggerConf) error {
-	var err error
-	if auditor.log, err = x.InitLogger(conf, defaultAuditFilename); err != nil {
+	if err := auditor.open(conf); err != nil {
 		return err
 	}
 	atomic.StoreUint32(&auditEnabled, 1)
@@ -131,19 +152,17 @@ func InitAuditor(conf *x.LoggerConf) error {
 // That's why we needed to track if the current node is part of enterprise edition cluster
 func trackIfEEValid(conf *x.LoggerConf, eeEnabledFunc func() bool) {
 	defer auditor.closer.Done()
-	var err error
 	for {
 		select {
 		case <-auditor.tick.C:
 			if !eeEnabledFunc() && atomic.CompareAndSwapUint32(&auditEnabled, 1, 0) {
 				glog.Infof("audit logs are disabled")
-				auditor.log.Sync()
-				auditor.log = nil
+				auditor.close()
 				continue
 			}
 
 			if atomic.LoadUint32(&auditEnabled) != 1 {
-				if auditor.log, err = x.InitLogger(conf, defaultAuditFilename); err != nil {
+				if err := auditor.open(conf); err != nil {
 					continue
 				}
 				atomic.StoreUint32(&auditEnabled, 1)
@@ -168,11 +187,36 @@ func Close() {
 	if auditor.closer != nil {
 		auditor.closer.SignalAndWait()
 	}
-	auditor.log.Sync()
-	auditor.log = nil
+	auditor.close()
 	glog.Infoln("audit logs are closed.")
 }
 
+// open opens the audit log file and the syslog sink that conf has.
+func (a *auditLogger) open(conf *x.LoggerConf) error {
+	var err error
+	if conf.Dir != "" {
+		if a.log, err = x.InitLogger(conf, defaultAuditFilename); err != nil {
+			return err
+		}
+	}
+	if conf.Syslog != nil {
+		if a.syslog, err = newSyslogSink(conf.Syslog); err != nil {
+			a.log.Sync()
+			a.log = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// close syncs and closes the audit log file and the syslog sink.
+func (a *auditLogger) close() {
+	a.log.Sync()
+	a.log = nil
+	a.syslog.close()
+	a.syslog = nil
+}
+
 func (a *auditLogger) Audit(event *AuditEvent) {
 	a.log.AuditI(event.Endpoint,
 		"user", event.User,
@@ -183,4 +227,5 @@ func (a *auditLogger) Audit(event *AuditEvent) {
 		"req_body", event.Req,
 		"query_param", event.QueryParams,
 		"status", event.Status)
+	a.syslog.send(event)
 }
@@ -0,0 +1,368 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package audit
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const (
+	// syslogFacility is the log audit facility of RFC 5424, which the events are sent with.
+	syslogFacility = 13
+	syslogInfo     = 6
+	syslogWarning  = 4
+
+	syslogWriteTimeout = 10 * time.Second
+	syslogMaxBackoff   = 30 * time.Second
+	// syslogCloseTimeout is how long closing the sink waits for the buffered events to be sent.
+	syslogCloseTimeout = 5 * time.Second
+)
+
+// syslogSink sends audit events to a syslog server over TCP, or TLS, formatted as CEF or LEEF
+// for SIEM ingestion. Events are buffered, so that a slow or unreachable server doesn't slow
+// requests down. When the buffer is full, events are dropped, or the requests wait for room if
+// the sink blocks.
+type syslogSink struct {
+	conf     *x.SyslogConf
+	tls      *tls.Config
+	hostname string
+	msgs     chan string
+	dropped  uint64
+	closer   *z.Closer
+}
+
+func newSyslogSink(conf *x.SyslogConf) (*syslogSink, error) {
+	switch conf.Format {
+	case "cef", "leef":
+	default:
+		return nil, errors.Errorf("Invalid syslog format %q. Expected cef or leef", conf.Format)
+	}
+	if _, _, err := net.SplitHostPort(conf.Addr); err != nil {
+		return nil, errors.Wrapf(err, "while parsing the syslog address")
+	}
+	s := &syslogSink{
+		conf:   conf,
+		msgs:   make(chan string, conf.BufferSize),
+		closer: z.NewCloser(1),
+	}
+	if conf.TLS {
+		var err error
+		if s.tls, err = syslogTLSConfig(conf); err != nil {
+			return nil, err
+		}
+	}
+	if s.hostname, _ = os.Hostname(); s.hostname == "" {
+		s.hostname = "-"
+	}
+	go s.run()
+	return s, nil
+}
+
+func syslogTLSConfig(conf *x.SyslogConf) (*tls.Config, error) {
+	host, _, _ := net.SplitHostPort(conf.Addr)
+	tlsCfg := &tls.Config{ServerName: host}
+	if conf.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, err
+		}
+		caFile, err := ioutil.ReadFile(conf.CACert)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read the syslog ca cert file")
+		}
+		if !pool.AppendCertsFromPEM(caFile) {
+			return nil, errors.New("not able to append the syslog ca certificates")
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if conf.ClientCert != "" && conf.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(conf.ClientCert, conf.ClientKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to load the syslog client cert and key")
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// send formats the event and queues it to be sent.
+func (s *syslogSink) send(event *AuditEvent) {
+	if s == nil {
+		return
+	}
+	msg := s.format(event, time.Now())
+	if s.conf.Block {
+		select {
+		case s.msgs <- msg:
+		case <-s.closer.HasBeenClosed():
+		}
+		return
+	}
+	select {
+	case s.msgs <- msg:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// close stops the sink, after trying to send the buffered events for a while.
+func (s *syslogSink) close() {
+	if s == nil {
+		return
+	}
+	s.closer.SignalAndWait()
+}
+
+func (s *syslogSink) run() {
+	defer s.closer.Done()
+
+	var conn net.Conn
+	var w *bufio.Writer
+	var pending string
+	backoff := time.Second
+	lastDropped := uint64(0)
+	dropTicker := time.NewTicker(10 * time.Second)
+	defer dropTicker.Stop()
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	// write sends msg, connecting first if needed. The connection is dropped on errors.
+	write := func(msg string) error {
+		if conn == nil {
+			var err error
+			if conn, err = s.dial(); err != nil {
+				return err
+			}
+			w = bufio.NewWriter(conn)
+		}
+		err := conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+		if err == nil {
+			// Messages are framed by octet counting, as in RFC 5425 and RFC 6587.
+			_, err = fmt.Fprintf(w, "%d %s", len(msg), msg)
+		}
+		if err == nil && len(s.msgs) == 0 {
+			err = w.Flush()
+		}
+		if err != nil {
+			conn.Close()
+			conn = nil
+		}
+		return err
+	}
+
+	for {
+		if pending == "" {
+			select {
+			case pending = <-s.msgs:
+			case <-dropTicker.C:
+				if d := atomic.LoadUint64(&s.dropped); d != lastDropped {
+					glog.Warningf("Dropped %d audit events because the syslog buffer was full",
+						d-lastDropped)
+					lastDropped = d
+				}
+				continue
+			case <-s.closer.HasBeenClosed():
+				s.drain(write)
+				return
+			}
+		}
+		if err := write(pending); err != nil {
+			glog.Errorf("Unable to send audit events to syslog server %s, retrying in %s: %v",
+				s.conf.Addr, backoff, err)
+			select {
+			case <-time.After(backoff):
+			case <-s.closer.HasBeenClosed():
+				return
+			}
+			if backoff *= 2; backoff > syslogMaxBackoff {
+				backoff = syslogMaxBackoff
+			}
+			continue
+		}
+		pending = ""
+		backoff = time.Second
+	}
+}
+
+// drain sends the buffered events when the sink is closed, giving up after syslogCloseTimeout.
+func (s *syslogSink) drain(write func(string) error) {
+	deadline := time.Now().Add(syslogCloseTimeout)
+	for time.Now().Before(deadline) {
+		select {
+		case msg := <-s.msgs:
+			if err := write(msg); err != nil {
+				glog.Errorf("Unable to send audit events to syslog server %s: %v", s.conf.Addr, err)
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (s *syslogSink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogWriteTimeout}
+	if s.tls != nil {
+		return tls.DialWithDialer(dialer, "tcp", s.conf.Addr, s.tls)
+	}
+	return dialer.Dial("tcp", s.conf.Addr)
+}
+
+// format returns the RFC 5424 syslog message of the event at time t, whose content is the event
+// in CEF or LEEF.
+func (s *syslogSink) format(event *AuditEvent, t time.Time) string {
+	severity := syslogInfo
+	if !isSuccess(event.Status) {
+		severity = syslogWarning
+	}
+	var content string
+	if s.conf.Format == "leef" {
+		content = formatLEEF(event, t)
+	} else {
+		content = formatCEF(event, t)
+	}
+	return fmt.Sprintf("<%d>1 %s %s dgraph %d audit - %s", syslogFacility*8+severity,
+		t.UTC().Format(time.RFC3339Nano), s.hostname, os.Getpid(), content)
+}
+
+func isSuccess(status string) bool {
+	return status == "OK"
+}
+
+// formatCEF returns the event in the ArcSight Common Event Format.
+func formatCEF(event *AuditEvent, t time.Time) string {
+	severity := 3
+	if !isSuccess(event.Status) {
+		severity = 6
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|Dgraph|Dgraph|%s|%s|%s|%d|", cefHeader(x.Version()),
+		cefHeader(event.Endpoint), cefHeader(event.ReqType+" "+event.Endpoint), severity)
+
+	sep := ""
+	ext := func(key, value string) {
+		if value == "" {
+			return
+		}
+		b.WriteString(sep)
+		sep = " "
+		b.WriteString(key)
+		b.WriteString("=")
+		b.WriteString(cefExtension(value))
+	}
+	ext("rt", strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10))
+	ext("suser", event.User)
+	if event.Namespace != UnknownNamespace {
+		ext("cs1Label", "namespace")
+		ext("cs1", strconv.FormatUint(event.Namespace, 10))
+	}
+	src, spt := splitHostPort(event.ClientHost)
+	ext("src", src)
+	ext("spt", spt)
+	dst, dpt := splitHostPort(event.ServerHost)
+	ext("dhost", dst)
+	ext("dpt", dpt)
+	ext("app", event.ReqType)
+	ext("request", event.Endpoint)
+	ext("outcome", event.Status)
+	if len(event.QueryParams) > 0 {
+		ext("cs2Label", "queryParams")
+		ext("cs2", url.Values(event.QueryParams).Encode())
+	}
+	ext("msg", event.Req)
+	return b.String()
+}
+
+// formatLEEF returns the event in the QRadar Log Event Extended Format, with tab separated
+// attributes.
+func formatLEEF(event *AuditEvent, t time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "LEEF:1.0|Dgraph|Dgraph|%s|%s|", leefHeader(x.Version()),
+		leefHeader(event.Endpoint))
+
+	sep := ""
+	attr := func(key, value string) {
+		if value == "" {
+			return
+		}
+		b.WriteString(sep)
+		sep = "\t"
+		b.WriteString(key)
+		b.WriteString("=")
+		b.WriteString(leefAttribute(value))
+	}
+	attr("devTime", strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10))
+	attr("usrName", event.User)
+	if event.Namespace != UnknownNamespace {
+		attr("namespace", strconv.FormatUint(event.Namespace, 10))
+	}
+	src, spt := splitHostPort(event.ClientHost)
+	attr("src", src)
+	attr("srcPort", spt)
+	dst, dpt := splitHostPort(event.ServerHost)
+	attr("dst", dst)
+	attr("dstPort", dpt)
+	attr("proto", event.ReqType)
+	attr("url", event.Endpoint)
+	attr("status", event.Status)
+	if !isSuccess(event.Status) {
+		attr("sev", "6")
+	} else {
+		attr("sev", "3")
+	}
+	if len(event.QueryParams) > 0 {
+		attr("queryParams", url.Values(event.QueryParams).Encode())
+	}
+	attr("request", event.Req)
+	return b.String()
+}
+
+func splitHostPort(addr string) (string, string) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, ""
+	}
+	return host, port
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+	leefHeaderEscaper   = strings.NewReplacer(`|`, " ", "\n", " ", "\r", " ")
+	leefAttrEscaper     = strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`)
+)
+
+func cefHeader(s string) string     { return cefHeaderEscaper.Replace(s) }
+func cefExtension(s string) string  { return cefExtensionEscaper.Replace(s) }
+func leefHeader(s string) string    { return leefHeaderEscaper.Replace(s) }
+func leefAttribute(s string) string { return leefAttrEscaper.Replace(s) }
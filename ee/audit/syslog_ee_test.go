@@ -0,0 +1,108 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package audit
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestFormatCEF(t *testing.T) {
+	event := &AuditEvent{
+		User:       "alice",
+		Namespace:  7,
+		ServerHost: "alpha1:7080",
+		ClientHost: "10.0.0.1:5432",
+		Endpoint:   "/query",
+		ReqType:    Http,
+		Req:        "{ q(func: eq(name, \"a=b\")) {\n name } }",
+		Status:     "OK",
+	}
+	cef := formatCEF(event, time.Unix(1, 0))
+	require.True(t, strings.HasPrefix(cef, "CEF:0|Dgraph|Dgraph|"), cef)
+	require.Contains(t, cef, "|/query|Http /query|3|rt=1000 suser=alice cs1Label=namespace cs1=7 "+
+		"src=10.0.0.1 spt=5432 dhost=alpha1 dpt=7080 app=Http request=/query outcome=OK "+
+		`msg={ q(func: eq(name, "a\=b")) {\n name } }`)
+
+	event.Namespace = UnknownNamespace
+	event.Status = "PermissionDenied"
+	event.Endpoint = "/a|b"
+	cef = formatCEF(event, time.Unix(1, 0))
+	require.Contains(t, cef, `|/a\|b|Http /a\|b|6|`)
+	require.NotContains(t, cef, "cs1")
+
+	leef := formatLEEF(event, time.Unix(1, 0))
+	require.True(t, strings.HasPrefix(leef, "LEEF:1.0|Dgraph|Dgraph|"), leef)
+	require.Contains(t, leef, "|/a b|devTime=1000\tusrName=alice\tsrc=10.0.0.1\t")
+	require.Contains(t, leef, "\tsev=6\t")
+	require.NotContains(t, leef, "\n")
+}
+
+func TestSyslogSink(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	s, err := newSyslogSink(&x.SyslogConf{Addr: l.Addr().String(), Format: "cef", BufferSize: 10})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		s.send(&AuditEvent{User: "u" + strconv.Itoa(i), Endpoint: "/query", Status: "OK"})
+	}
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for i := 0; i < 3; i++ {
+		size, err := r.ReadString(' ')
+		require.NoError(t, err)
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		require.NoError(t, err)
+		msg := make([]byte, n)
+		_, err = io.ReadFull(r, msg)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(string(msg), "<110>1 "), string(msg))
+		require.Contains(t, string(msg), "suser=u"+strconv.Itoa(i))
+	}
+	s.close()
+}
+
+func TestSyslogSinkDrops(t *testing.T) {
+	// Nothing listens on the address, so the events pile up in the buffer.
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	s, err := newSyslogSink(&x.SyslogConf{Addr: addr, Format: "leef", BufferSize: 2})
+	require.NoError(t, err)
+	defer s.close()
+	for i := 0; i < 5; i++ {
+		s.send(&AuditEvent{User: "u", Endpoint: "/query", Status: "OK"})
+	}
+	dropped := atomic.LoadUint64(&s.dropped)
+	require.True(t, dropped >= 2, "dropped %d", dropped)
+
+	_, err = newSyslogSink(&x.SyslogConf{Addr: addr, Format: "json"})
+	require.Error(t, err)
+}
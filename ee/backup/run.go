@@ -14,6 +14,8 @@ package backup
 
 import (
 	"context"
+	"crypto"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -46,6 +48,8 @@ var opt struct {
 	pdir        string
 	zero        string
 	key         x.SensitiveByteSlice
+	privateKey  crypto.PrivateKey
+	verifyKey   string
 	forceZero   bool
 	destination string
 	format      string
@@ -85,7 +89,8 @@ Source URI parts:
 The --posting flag sets the posting list parent dir to store the loaded backup files.
 
 Using the --zero flag will use a Dgraph Zero address to update the start timestamp using
-the restored version. Otherwise, the timestamp must be manually updated through Zero's HTTP
+the restored version, and to record the restore so that the Alphas apply the erasures of data
+subjects again at startup. Otherwise, the timestamp must be manually updated through Zero's HTTP
 'assign' command.
 
 Dgraph backup creates a unique backup object for each node group, and restore will create
@@ -103,6 +108,12 @@ $ dgraph restore -p /var/db/dgraph -l s3://s3.us-west-2.amazonaws.com/srfrog/dgr
 # Restore from dir and update Ts:
 $ dgraph restore -p . -l /var/backups/dgraph -z localhost:5080
 
+# Restore backups encrypted with a public key:
+$ dgraph restore -p . -l /var/backups/dgraph --private_key_file=backup_key.pem
+
+# Restore after verifying the backup signatures:
+$ dgraph restore -p . -l /var/backups/dgraph --verify_key_file=backup_verify.pem
+
 		`,
 		Args: cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
@@ -131,8 +142,12 @@ $ dgraph restore -p . -l /var/backups/dgraph -z localhost:5080
 		"a zero in the cluster will be required. Keep in mind this requires you to manually "+
 		"update the timestamp and max uid when you start the cluster. The correct values are "+
 		"printed near the end of this command's output.")
+	flag.StringVar(&opt.verifyKey, "verify_key_file", "", "The PEM file that stores the "+
+		"Ed25519 public key to verify the signatures of the backup manifests and the checksums "+
+		"of the backup files with, before restoring any of them.")
 	x.RegisterClientTLSFlags(flag)
 	enc.RegisterFlags(flag)
+	enc.RegisterPrivateKeyFlags(flag)
 	_ = Restore.Cmd.MarkFlagRequired("postings")
 	_ = Restore.Cmd.MarkFlagRequired("location")
 }
@@ -169,6 +184,9 @@ func runRestoreCmd() error {
 	if opt.key, err = enc.ReadKey(Restore.Conf); err != nil {
 		return err
 	}
+	if opt.privateKey, err = enc.ReadPrivateKey(Restore.Conf); err != nil {
+		return err
+	}
 	fmt.Println("Restoring backups from:", opt.location)
 	fmt.Println("Writing postings to:", opt.pdir)
 
@@ -198,10 +216,16 @@ func runRestoreCmd() error {
 		zc = pb.NewZeroClient(zero)
 	}
 
+	pub, err := verifyBackup(opt.backupId)
+	if err != nil {
+		return err
+	}
+
 	ctype, clevel := x.ParseCompression(opt.compression)
 
 	start = time.Now()
-	result := worker.RunRestore(opt.pdir, opt.location, opt.backupId, opt.key, ctype, clevel)
+	result := worker.RunRestore(opt.pdir, opt.location, opt.backupId, opt.key, opt.privateKey,
+		pub, ctype, clevel)
 	if result.Err != nil {
 		return result.Err
 	}
@@ -241,12 +265,39 @@ func runRestoreCmd() error {
 		if err := leaseID(result.MaxLeaseNsId, pb.Num_NS_ID); err != nil {
 			return errors.Wrapf(err, "cannot update max namespace lease after restore.")
 		}
+
+		// The Alphas apply the erasures again at startup once the restore is recorded.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if _, err := zc.UpdateErasures(ctx, &pb.ErasureUpdate{Restored: true}); err != nil {
+			return errors.Wrapf(err, "cannot record the restore for the erasures.")
+		}
+	} else {
+		fmt.Println("Warning: the restore isn't recorded in Zero, the erasures of data " +
+			"subjects run since the backup won't be applied again.")
 	}
 
 	fmt.Printf("Restore: Time elapsed: %s\n", time.Since(start).Round(time.Second))
 	return nil
 }
 
+// verifyBackup checks the signatures of the backups at the location if a verify key is given,
+// and returns the key so that the backups are checked again while they're restored.
+func verifyBackup(backupId string) (ed25519.PublicKey, error) {
+	if opt.verifyKey == "" {
+		return nil, nil
+	}
+	pub, err := enc.ReadVerifyKey(opt.verifyKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := worker.VerifyBackupSignatures(opt.location, backupId, 0, nil, pub); err != nil {
+		return nil, errors.Wrapf(err, "cannot verify the backup")
+	}
+	fmt.Println("Verified the backup signatures with:", opt.verifyKey)
+	return pub, nil
+}
+
 func runLsbackupCmd() error {
 	manifests, err := worker.ListBackupManifests(opt.location, nil)
 	if err != nil {
@@ -267,6 +318,8 @@ func runLsbackupCmd() error {
 		BackupId       string              `json:"backup_id"`
 		BackupNum      uint64              `json:"backup_num"`
 		Encrypted      bool                `json:"encrypted"`
+		Envelope       bool                `json:"envelope"`
+		Signed         bool                `json:"signed"`
 		Type           string              `json:"type"`
 		Groups         map[uint32][]string `json:"groups,omitempty"`
 		DropOperations []*pb.DropOperation `json:"drop_operations,omitempty"`
@@ -285,6 +338,8 @@ func runLsbackupCmd() error {
 			BackupId:  manifest.BackupId,
 			BackupNum: manifest.BackupNum,
 			Encrypted: manifest.Encrypted,
+			Envelope:  manifest.Envelope,
+			Signed:    manifest.Signature != "",
 			Type:      manifest.Type,
 		}
 		if opt.verbose {
@@ -325,7 +380,11 @@ func initExportBackup() {
 		"The folder to which export the backups.")
 	flag.StringVarP(&opt.format, "format", "f", "rdf",
 		"The format of the export output. Accepts a value of either rdf or json")
+	flag.StringVar(&opt.verifyKey, "verify_key_file", "", "The PEM file that stores the "+
+		"Ed25519 public key to verify the signature of the backup manifest and the checksums "+
+		"of the backup files with, before exporting them.")
 	enc.RegisterFlags(flag)
+	enc.RegisterPrivateKeyFlags(flag)
 }
 
 func runExportBackup() error {
@@ -333,7 +392,15 @@ func runExportBackup() error {
 	if opt.key, err = enc.ReadKey(ExportBackup.Conf); err != nil {
 		return err
 	}
+	if opt.privateKey, err = enc.ReadPrivateKey(ExportBackup.Conf); err != nil {
+		return err
+	}
+
+	if _, err := verifyBackup(""); err != nil {
+		return err
+	}
 
 	exporter := worker.BackupExporter{}
-	return exporter.ExportBackup(opt.location, opt.destination, opt.format, opt.key)
+	return exporter.ExportBackup(opt.location, opt.destination, opt.format, opt.key,
+		opt.privateKey)
 }
@@ -0,0 +1,52 @@
+// +build oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package enc
+
+import (
+	"crypto"
+	"io"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// RegisterPrivateKeyFlags registers the private key flags. None for OSS.
+func RegisterPrivateKeyFlags(_ *pflag.FlagSet) {
+	return
+}
+
+// ReadPublicKey reads the public key. Nil for OSS.
+func ReadPublicKey(_ string) (crypto.PublicKey, error) {
+	return nil, nil
+}
+
+// ReadPrivateKey reads the private key. Nil for OSS.
+func ReadPrivateKey(_ *viper.Viper) (crypto.PrivateKey, error) {
+	return nil, nil
+}
+
+// GetEnvelopeWriter returns the Writer as is for OSS Builds.
+func GetEnvelopeWriter(_ crypto.PublicKey, w io.Writer) (io.Writer, error) {
+	return w, nil
+}
+
+// GetEnvelopeReader returns the reader as is for OSS Builds.
+func GetEnvelopeReader(_ crypto.PrivateKey, r io.Reader) (io.Reader, error) {
+	return r, nil
+}
@@ -0,0 +1,297 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package enc
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	privateKeyFile       = "private_key_file"
+	vaultPrivateKeyField = "vault_private_key_field"
+)
+
+// envelopeMagic starts every file encrypted with a public key. It's followed by the size of
+// the wrapped data key, the wrapped data key, and the data encrypted with GetWriter.
+var envelopeMagic = []byte("DGENV001")
+
+// maxWrappedKeySize bounds the wrapped data key read back from a file.
+const maxWrappedKeySize = 1 << 16
+
+// dataKeySize is the size of the random AES-256 key each file is encrypted with.
+const dataKeySize = 32
+
+// The ways of wrapping the data key, stored in the first byte of the wrapped key.
+const (
+	// wrapRSA encrypts the data key with RSA-OAEP and SHA-256.
+	wrapRSA byte = 1
+	// wrapEC encrypts the data key with AES-GCM, using a key derived from the ECDH secret
+	// of an ephemeral key pair and the public key. The ephemeral public key is stored
+	// along with it.
+	wrapEC byte = 2
+)
+
+// RegisterPrivateKeyFlags registers the flags to read the private key of the backups that were
+// encrypted with a public key.
+func RegisterPrivateKeyFlags(flag *pflag.FlagSet) {
+	flag.String(privateKeyFile, "",
+		"The PEM file that stores the RSA or EC private key matching the public key the backups "+
+			"were encrypted with. Enterprise feature.")
+	flag.String(vaultPrivateKeyField, "",
+		"Vault kv store field whose value is the PEM private key matching the public key the "+
+			"backups were encrypted with. It's read with the other Vault options.")
+}
+
+// ReadPublicKey reads the RSA or EC public key from the PEM file, which holds either the key
+// or a certificate.
+func ReadPublicKey(file string) (crypto.PublicKey, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading public key file %s", file)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.Errorf("no PEM data found in public key file %s", file)
+	}
+
+	var pub interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			pub = cert.PublicKey
+		}
+	case "RSA PUBLIC KEY":
+		pub, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing public key file %s", file)
+	}
+	switch pub.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return pub, nil
+	}
+	return nil, errors.Errorf("public key of type %T in %s isn't supported, it must be RSA or EC",
+		pub, file)
+}
+
+// ReadPrivateKey reads the private key of the backups encrypted with a public key, from a local
+// file or from Vault. It returns nil if neither is configured.
+func ReadPrivateKey(cfg *viper.Viper) (crypto.PrivateKey, error) {
+	keyFile := cfg.GetString(privateKeyFile)
+	field := cfg.GetString(vaultPrivateKeyField)
+
+	var b []byte
+	switch {
+	case keyFile != "" && field != "":
+		return nil, errors.Errorf("cannot have local and vault private keys. " +
+			"re-check the configuration")
+	case keyFile != "":
+		var err error
+		if b, err = ioutil.ReadFile(keyFile); err != nil {
+			return nil, errors.Wrapf(err, "while reading private key file %s", keyFile)
+		}
+	case field != "":
+		vkr, err := newVaultKeyReader(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if b, err = vkr.readField(field); err != nil {
+			return nil, err
+		}
+		if b == nil {
+			return nil, errors.Errorf("private key not found at %v", field)
+		}
+	default:
+		return nil, nil
+	}
+	return parsePrivateKey(b)
+}
+
+// parsePrivateKey parses a PKCS #8, PKCS #1 or SEC 1 private key, PEM or DER encoded.
+func parsePrivateKey(b []byte) (crypto.PrivateKey, error) {
+	der := b
+	if block, _ := pem.Decode(b); block != nil {
+		der = block.Bytes
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		switch key.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey:
+			return key, nil
+		}
+		return nil, errors.Errorf("private key of type %T isn't supported, it must be RSA or EC",
+			key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errors.Errorf("unable to parse the private key, it must be PKCS #8, PKCS #1 " +
+		"or SEC 1")
+}
+
+// GetEnvelopeWriter wraps a crypto StreamWriter on the input Writer, using a random data key.
+// The data key is encrypted with the public key and written first, so that the output can only
+// be read back with the private key.
+func GetEnvelopeWriter(pub crypto.PublicKey, w io.Writer) (io.Writer, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	wrapped, err := wrapKey(pub, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr := make([]byte, len(envelopeMagic)+4)
+	copy(hdr, envelopeMagic)
+	binary.BigEndian.PutUint32(hdr[len(envelopeMagic):], uint32(len(wrapped)))
+	if _, err = w.Write(hdr); err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(wrapped); err != nil {
+		return nil, err
+	}
+	return GetWriter(dataKey, w)
+}
+
+// GetEnvelopeReader wraps a crypto StreamReader on the input Reader, written by
+// GetEnvelopeWriter. The data key is decrypted with the private key.
+func GetEnvelopeReader(priv crypto.PrivateKey, r io.Reader) (io.Reader, error) {
+	hdr := make([]byte, len(envelopeMagic)+4)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, errors.Wrapf(err, "unable to read the envelope of the encrypted backup")
+	}
+	if !bytes.Equal(hdr[:len(envelopeMagic)], envelopeMagic) {
+		return nil, errors.Errorf("the backup isn't encrypted with a public key")
+	}
+	sz := binary.BigEndian.Uint32(hdr[len(envelopeMagic):])
+	if sz > maxWrappedKeySize {
+		return nil, errors.Errorf("bad wrapped key size %d in the encrypted backup", sz)
+	}
+	wrapped := make([]byte, sz)
+	if _, err := io.ReadFull(r, wrapped); err != nil {
+		return nil, errors.Wrapf(err, "unable to read the wrapped key of the encrypted backup")
+	}
+
+	dataKey, err := unwrapKey(priv, wrapped)
+	if err != nil {
+		return nil, errors.Wrapf(err,
+			"unable to decrypt the backup data key. Ensure the private key is correct")
+	}
+	return GetReader(dataKey, r)
+}
+
+func wrapKey(pub crypto.PublicKey, dataKey []byte) ([]byte, error) {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		out, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, dataKey, nil)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{wrapRSA}, out...), nil
+	case *ecdsa.PublicKey:
+		eph, err := ecdsa.GenerateKey(pub.Curve, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		ephPub := elliptic.Marshal(pub.Curve, eph.X, eph.Y)
+		aead, err := ecdhCipher(pub.Curve, pub.X, pub.Y, eph.D.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		out := append([]byte{wrapEC}, ephPub...)
+		out = append(out, nonce...)
+		return aead.Seal(out, nonce, dataKey, ephPub), nil
+	}
+	return nil, errors.Errorf("public key of type %T isn't supported, it must be RSA or EC", pub)
+}
+
+func unwrapKey(priv crypto.PrivateKey, wrapped []byte) ([]byte, error) {
+	if len(wrapped) == 0 {
+		return nil, errors.Errorf("empty wrapped key")
+	}
+	typ, wrapped := wrapped[0], wrapped[1:]
+	switch priv := priv.(type) {
+	case *rsa.PrivateKey:
+		if typ != wrapRSA {
+			return nil, errors.Errorf("the data key isn't wrapped with an RSA key")
+		}
+		return rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, wrapped, nil)
+	case *ecdsa.PrivateKey:
+		if typ != wrapEC {
+			return nil, errors.Errorf("the data key isn't wrapped with an EC key")
+		}
+		byteLen := (priv.Curve.Params().BitSize + 7) / 8
+		ptLen := 1 + 2*byteLen
+		if len(wrapped) < ptLen {
+			return nil, errors.Errorf("wrapped key is too short")
+		}
+		ephPub := wrapped[:ptLen]
+		x, y := elliptic.Unmarshal(priv.Curve, ephPub)
+		if x == nil {
+			return nil, errors.Errorf("bad ephemeral public key in the wrapped key")
+		}
+		aead, err := ecdhCipher(priv.Curve, x, y, priv.D.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		rest := wrapped[ptLen:]
+		if len(rest) < aead.NonceSize() {
+			return nil, errors.Errorf("wrapped key is too short")
+		}
+		return aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], ephPub)
+	}
+	return nil, errors.Errorf("private key of type %T isn't supported, it must be RSA or EC", priv)
+}
+
+// ecdhCipher returns the AES-GCM cipher keyed with the SHA-256 of the ECDH secret of the point
+// (x, y) and the scalar d.
+func ecdhCipher(curve elliptic.Curve, x, y *big.Int, d []byte) (cipher.AEAD, error) {
+	sx, _ := curve.ScalarMult(x, y, d)
+	secret := make([]byte, (curve.Params().BitSize+7)/8)
+	b := sx.Bytes()
+	copy(secret[len(secret)-len(b):], b)
+	kek := sha256.Sum256(secret)
+	c, err := aes.NewCipher(kek[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(c)
+}
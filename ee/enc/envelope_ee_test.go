@@ -0,0 +1,124 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package enc
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writePEM(t *testing.T, dir, name, typ string, der []byte) string {
+	file := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}),
+		0600))
+	return file
+}
+
+// envelopeKeys writes the public and private keys of priv to PEM files, and reads them back.
+func envelopeKeys(t *testing.T, priv crypto.Signer) (crypto.PublicKey, crypto.PrivateKey) {
+	dir, err := ioutil.TempDir("", "envelope")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pubDer, err := x509.MarshalPKIXPublicKey(priv.Public())
+	require.NoError(t, err)
+	privDer, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+
+	pub, err := ReadPublicKey(writePEM(t, dir, "pub.pem", "PUBLIC KEY", pubDer))
+	require.NoError(t, err)
+
+	config := getEncConfig()
+	config.Set(privateKeyFile, writePEM(t, dir, "priv.pem", "PRIVATE KEY", privDer))
+	key, err := ReadPrivateKey(config)
+	require.NoError(t, err)
+	return pub, key
+}
+
+func TestEnvelope(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	data := bytes.Repeat([]byte("dgraph backup "), 1000)
+	for _, signer := range []crypto.Signer{rsaKey, ecKey} {
+		pub, priv := envelopeKeys(t, signer)
+
+		var buf bytes.Buffer
+		w, err := GetEnvelopeWriter(pub, &buf)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+		require.False(t, bytes.Contains(buf.Bytes(), data[:100]))
+
+		r, err := GetEnvelopeReader(priv, bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		out, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, data, out)
+
+		// The data key can't be unwrapped with another private key.
+		_, err = GetEnvelopeReader(otherKey, bytes.NewReader(buf.Bytes()))
+		require.Error(t, err)
+	}
+
+	// Files encrypted with a symmetric key aren't envelopes.
+	var buf bytes.Buffer
+	w, err := GetWriter(make([]byte, 32), &buf)
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	_, err = GetEnvelopeReader(ecKey, bytes.NewReader(buf.Bytes()))
+	require.Error(t, err)
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	ecDer, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+
+	key, err := parsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}))
+	require.NoError(t, err)
+	require.Equal(t, rsaKey.D, key.(*rsa.PrivateKey).D)
+
+	key, err = parsePrivateKey(ecDer)
+	require.NoError(t, err)
+	require.Equal(t, ecKey.D, key.(*ecdsa.PrivateKey).D)
+
+	_, err = parsePrivateKey([]byte("not a key"))
+	require.Error(t, err)
+
+	// No private key configured.
+	key, err = ReadPrivateKey(getEncConfig())
+	require.NoError(t, err)
+	require.Nil(t, key)
+}
@@ -0,0 +1,31 @@
+// +build oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package enc
+
+import "crypto/ed25519"
+
+// ReadSigningKey reads the signing key. Nil for OSS.
+func ReadSigningKey(_ string) (ed25519.PrivateKey, error) {
+	return nil, nil
+}
+
+// ReadVerifyKey reads the verify key. Nil for OSS.
+func ReadVerifyKey(_ string) (ed25519.PublicKey, error) {
+	return nil, nil
+}
@@ -0,0 +1,68 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package enc
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// ReadSigningKey reads the Ed25519 private key from the PKCS #8 PEM file.
+func ReadSigningKey(file string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing signing key file %s", file)
+	}
+	if key, ok := key.(ed25519.PrivateKey); ok {
+		return key, nil
+	}
+	return nil, errors.Errorf("signing key of type %T in %s isn't supported, it must be Ed25519",
+		key, file)
+}
+
+// ReadVerifyKey reads the Ed25519 public key from the PKIX PEM file.
+func ReadVerifyKey(file string) (ed25519.PublicKey, error) {
+	der, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing verify key file %s", file)
+	}
+	if key, ok := key.(ed25519.PublicKey); ok {
+		return key, nil
+	}
+	return nil, errors.Errorf("verify key of type %T in %s isn't supported, it must be Ed25519",
+		key, file)
+}
+
+func readPEM(file string) ([]byte, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading key file %s", file)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.Errorf("no PEM data found in key file %s", file)
+	}
+	return block.Bytes, nil
+}
@@ -99,7 +99,6 @@ func ReadKey(cfg *viper.Viper) (x.SensitiveByteSlice, error) {
 func newKeyReader(cfg *viper.Viper) (keyReader, error) {
 	var keyReaders int
 	var keyReader keyReader
-	var err error
 
 	keyFile := cfg.GetString(encKeyFile)
 	roleID := cfg.GetString(vaultRoleIDFile)
@@ -112,11 +111,17 @@ func newKeyReader(cfg *viper.Viper) (keyReader, error) {
 		keyReaders++
 	}
 	if roleID != "" || secretID != "" {
-		keyReader, err = newVaultKeyReader(cfg)
+		vkr, err := newVaultKeyReader(cfg)
 		if err != nil {
 			return nil, err
 		}
-		keyReaders++
+		// The vault may only hold the private key of the backups being restored. The symmetric
+		// key is then optional, and a local one takes precedence.
+		vkr.optional = cfg.GetString(vaultPrivateKeyField) != ""
+		if keyFile == "" || !vkr.optional {
+			keyReader = vkr
+			keyReaders++
+		}
 	}
 	if keyReaders == 2 {
 		return nil, errors.Errorf("cannot have local and vault key readers. " +
@@ -65,6 +65,9 @@ type vaultKeyReader struct {
 	path     string
 	field    string
 	format   string
+	// optional is set when the key is read along with a private key, in which case a missing
+	// field means there's no symmetric key.
+	optional bool
 }
 
 func newVaultKeyReader(cfg *viper.Viper) (*vaultKeyReader, error) {
@@ -97,7 +100,27 @@ func (vkr *vaultKeyReader) readKey() (x.SensitiveByteSlice, error) {
 	if vkr == nil {
 		return nil, errors.Errorf("nil vaultKeyReader")
 	}
+	kbyte, err := vkr.readField(vkr.field)
+	if err != nil {
+		return nil, err
+	}
+	if kbyte == nil {
+		if vkr.optional {
+			return nil, nil
+		}
+		return nil, errors.Errorf("secret key not found at %v", vkr.field)
+	}
+	// Validate key length suitable for AES.
+	klen := len(kbyte)
+	if klen != 16 && klen != 32 && klen != 64 {
+		return nil, errors.Errorf("bad key length %v from vault", klen)
+	}
+	return kbyte, nil
+}
 
+// readField reads the value of the given field from the vault kv store. It returns nil if the
+// field doesn't exist.
+func (vkr *vaultKeyReader) readField(field string) ([]byte, error) {
 	// Read the files.
 	roleID, err := ioutil.ReadFile(vkr.roleID)
 	if err != nil {
@@ -145,9 +168,9 @@ func (vkr *vaultKeyReader) readKey() (x.SensitiveByteSlice, error) {
 		glog.Infof("Unable to extract key from kv v2 response. Trying kv v1.")
 		m = secret.Data
 	}
-	kVal, ok := m[vkr.field]
+	kVal, ok := m[field]
 	if !ok {
-		return nil, errors.Errorf("secret key not found at %v", vkr.field)
+		return nil, nil
 	}
 	kbyte := []byte(kVal.(string))
 	if vkr.format == "base64" {
@@ -156,10 +179,5 @@ func (vkr *vaultKeyReader) readKey() (x.SensitiveByteSlice, error) {
 			return nil, errors.Errorf("Unable to decode the Base64 Encoded key: err %v", err)
 		}
 	}
-	// Validate key length suitable for AES.
-	klen := len(kbyte)
-	if klen != 16 && klen != 32 && klen != 64 {
-		return nil, errors.Errorf("bad key length %v from vault", klen)
-	}
 	return kbyte, nil
 }
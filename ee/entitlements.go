@@ -0,0 +1,51 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ee
+
+import (
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// Entitlement is an enterprise feature, along with whether the license of the cluster allows it
+// and whether this node is configured to use it.
+type Entitlement struct {
+	Name       string
+	Allowed    bool
+	Configured bool
+}
+
+// Entitlements returns the enterprise features, whether the license allows them and whether this
+// node is configured to use them. None of them is allowed in OSS builds.
+func Entitlements() []Entitlement {
+	allowed := worker.EnterpriseEnabled()
+	acl := len(worker.Config.HmacSecret) > 0
+	features := []Entitlement{
+		{Name: "acl", Configured: acl},
+		{Name: "audit", Configured: x.WorkerConfig.Audit},
+		// Backups and restores don't need any configuration.
+		{Name: "backup_restore", Configured: true},
+		{Name: "cdc", Configured: worker.Config.ChangeDataConf != ""},
+		{Name: "encryption_at_rest", Configured: x.WorkerConfig.EncryptionKey != nil},
+		// Namespaces can only be used along with ACLs.
+		{Name: "multi_tenancy", Configured: acl},
+	}
+	for i := range features {
+		features[i].Allowed = allowed
+	}
+	return features
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package embedded runs Dgraph in the process of an application, like badger is embedded. It
+// starts a Zero and an Alpha in the process and exposes their queries, mutations and schema
+// changes as functions, so that the applications and the tests don't need a cluster.
+//
+// Only one Dgraph can be opened in a process, and it can't be reopened once closed, since Zero
+// and Alpha keep their state in globals. The fatal errors still exit the process. Alpha serves
+// its HTTP and gRPC endpoints as usual, Zero only serves gRPC.
+package embedded
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/dgraph/cmd/alpha"
+	"github.com/dgraph-io/dgraph/dgraph/cmd/zero"
+	"github.com/dgraph-io/dgraph/edgraph"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/peer"
+)
+
+// Options are the options of an embedded Dgraph.
+type Options struct {
+	// Dir is the directory holding the data. The data is kept in memory if it's empty, which is
+	// only supported on Linux.
+	Dir string
+	// PortOffset is added to the default ports of Zero and Alpha.
+	PortOffset int
+	// ZeroFlags and AlphaFlags set the other flags of Zero and Alpha, by their name.
+	ZeroFlags  map[string]string
+	AlphaFlags map[string]string
+	// StartTimeout is how long to wait for Alpha to be ready. It's a minute if zero.
+	StartTimeout time.Duration
+}
+
+// Dgraph is a Dgraph running in this process.
+type Dgraph struct {
+	server    edgraph.Server
+	tmp       string
+	stopZero  func()
+	stopAlpha func()
+}
+
+var opened uint32
+
+// Open starts Dgraph in this process and returns once it's ready to serve the requests. If it
+// isn't ready within StartTimeout, Zero and Alpha are stopped and the temporary data is removed.
+// Since they leave their global state behind, Dgraph can't be opened again in the process then.
+func Open(opts Options) (*Dgraph, error) {
+	if !atomic.CompareAndSwapUint32(&opened, 0, 1) {
+		return nil, errors.New("Dgraph can only be opened once in a process")
+	}
+	if opts.StartTimeout == 0 {
+		opts.StartTimeout = time.Minute
+	}
+
+	d := &Dgraph{}
+	dir := opts.Dir
+	if dir == "" {
+		tmp, err := ioutil.TempDir("", "dgraph")
+		if err != nil {
+			atomic.StoreUint32(&opened, 0)
+			return nil, errors.Wrap(err, "while creating the temporary directory")
+		}
+		d.tmp, dir = tmp, tmp
+	} else if err := os.MkdirAll(dir, 0700); err != nil {
+		atomic.StoreUint32(&opened, 0)
+		return nil, errors.Wrapf(err, "while creating %s", dir)
+	}
+
+	common := map[string]string{
+		"bindall":       "false",
+		"telemetry":     "false",
+		"enable_sentry": "false",
+		"port_offset":   fmt.Sprint(opts.PortOffset),
+	}
+	if opts.Dir == "" {
+		common["badger.in_memory"] = "true"
+	}
+	zeroConf := newConf(zero.Zero.Cmd, common, map[string]string{
+		"wal": filepath.Join(dir, "zw"),
+	}, opts.ZeroFlags)
+	alphaConf := newConf(alpha.Alpha.Cmd, common, map[string]string{
+		"postings": filepath.Join(dir, "p"),
+		"wal":      filepath.Join(dir, "w"),
+		"tmp":      filepath.Join(dir, "t"),
+		"export":   filepath.Join(dir, "export"),
+		"zero":     fmt.Sprintf("localhost:%d", x.PortZeroGrpc+opts.PortOffset),
+	}, opts.AlphaFlags)
+
+	// From now on, opened isn't reset on failure, as Zero and Alpha have set their globals.
+	d.stopZero = zero.RunEmbedded(zeroConf)
+	d.stopAlpha = alpha.RunEmbedded(alphaConf)
+
+	deadline := time.Now().Add(opts.StartTimeout)
+	for x.HealthCheck() != nil {
+		if time.Now().After(deadline) {
+			if err := d.Close(); err != nil {
+				glog.Warningf("While closing Dgraph: %v", err)
+			}
+			return nil, errors.Errorf("Dgraph isn't ready after %s. It can't be opened again "+
+				"in this process", opts.StartTimeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return d, nil
+}
+
+// newConf returns the configuration of the command, with its flags set by the maps. The later
+// maps take precedence.
+func newConf(cmd *cobra.Command, flags ...map[string]string) *viper.Viper {
+	conf := viper.New()
+	x.Check(conf.BindPFlags(cmd.Flags()))
+	for _, m := range flags {
+		for k, v := range m {
+			conf.Set(k, v)
+		}
+	}
+	return conf
+}
+
+// withPeer returns the context of the requests. They come from the loopback address, so they
+// pass the IP whitelist.
+func withPeer(ctx context.Context) context.Context {
+	return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}})
+}
+
+// Query runs the DQL query with the variables in a read-only transaction.
+func (d *Dgraph) Query(ctx context.Context, q string,
+	vars map[string]string) (*api.Response, error) {
+	return d.Do(ctx, &api.Request{Query: q, Vars: vars, ReadOnly: true})
+}
+
+// Mutate runs the mutation in its own transaction, which is committed right away.
+func (d *Dgraph) Mutate(ctx context.Context, mu *api.Mutation) (*api.Response, error) {
+	return d.Do(ctx, &api.Request{Mutations: []*api.Mutation{mu}, CommitNow: true})
+}
+
+// Do runs the request, like the Query endpoint of the gRPC API does.
+func (d *Dgraph) Do(ctx context.Context, req *api.Request) (*api.Response, error) {
+	return d.server.Query(withPeer(ctx), req)
+}
+
+// CommitOrAbort commits or aborts the transaction started by Do.
+func (d *Dgraph) CommitOrAbort(ctx context.Context,
+	tc *api.TxnContext) (*api.TxnContext, error) {
+	return d.server.CommitOrAbort(withPeer(ctx), tc)
+}
+
+// Alter changes the schema, or drops data.
+func (d *Dgraph) Alter(ctx context.Context, op *api.Operation) error {
+	_, err := d.server.Alter(withPeer(ctx), op)
+	return err
+}
+
+// Close stops Dgraph. The data is lost if it's kept in memory.
+func (d *Dgraph) Close() error {
+	d.stopAlpha()
+	d.stopZero()
+	if d.tmp != "" {
+		return os.RemoveAll(d.tmp)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package embedded
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedded(t *testing.T) {
+	d, err := Open(Options{PortOffset: 500})
+	require.NoError(t, err)
+	_, err = Open(Options{PortOffset: 600})
+	require.Error(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, d.Alter(ctx, &api.Operation{Schema: "name: string @index(exact) ."}))
+	_, err = d.Mutate(ctx, &api.Mutation{SetNquads: []byte(`_:a <name> "Alice" .`)})
+	require.NoError(t, err)
+
+	resp, err := d.Query(ctx, `query q($name: string) { q(func: eq(name, $name)) { name } }`,
+		map[string]string{"$name": "Alice"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"q": [{"name": "Alice"}]}`, string(resp.Json))
+
+	require.NoError(t, d.Close())
+}
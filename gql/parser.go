@@ -18,6 +18,7 @@ package gql
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strconv"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/dgraph-io/dgraph/lex"
 	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/types"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
@@ -57,6 +59,9 @@ type GraphQuery struct {
 	NeedsVar   []VarContext
 	Func       *Function
 	Expand     string // Which variable to expand with.
+	// Lineage is true for lineage(pred), whose children are the lineage nodes of the edges of
+	// pred, given by their dgraph.lineage facet.
+	Lineage bool
 
 	Args map[string]string
 	// Query can have multiple sort parameters.
@@ -72,9 +77,12 @@ type GraphQuery struct {
 	IgnoreReflex     bool
 	Facets           *pb.FacetParams
 	FacetsFilter     *FilterTree
-	GroupbyAttrs     []GroupByAttr
-	FacetVar         map[string]string
-	FacetsOrder      []*FacetOrder
+	// Graphs are the named graphs given with @graph, which the edges of the block and of its
+	// children are restricted to.
+	Graphs       []string
+	GroupbyAttrs []GroupByAttr
+	FacetVar     map[string]string
+	FacetsOrder  []*FacetOrder
 
 	// Used for ACL enabled queries to curtail results to only accessible params
 	AllowedPreds []string
@@ -153,6 +161,12 @@ type VarContext struct {
 type varInfo struct {
 	Value string
 	Type  string
+	// Values holds the elements of a list variable like [string] or [uid], parsed
+	// once from the JSON array given as its value.
+	Values []string
+	// typed holds the value of a variable given in binary form instead of as Value. It's
+	// turned into Value and Values once the type of the variable is known.
+	typed *pb.Variable
 }
 
 // varMap is a map with key as GQL variable name.
@@ -231,6 +245,16 @@ func (f *Function) IsPasswordVerifier() bool {
 	return f.Name == "checkpwd"
 }
 
+// IsRelevance returns true if the function name is "relevance".
+func (f *Function) IsRelevance() bool {
+	return f.Name == "relevance"
+}
+
+// IsHighlight returns true if the function name is "highlight".
+func (f *Function) IsHighlight() bool {
+	return f.Name == "highlight"
+}
+
 // DebugPrint is useful for debugging.
 func (gq *GraphQuery) DebugPrint(prefix string) {
 	glog.Infof("%s[%x %q %q]\n", prefix, gq.UID, gq.Attr, gq.Alias)
@@ -286,13 +310,19 @@ func (gq *GraphQuery) expandFragments(fmap fragmentMap) error {
 	return nil
 }
 
-func convertToVarMap(variables map[string]string) (vm varMap) {
+func convertToVarMap(variables map[string]string,
+	typedVariables map[string]*pb.Variable) (vm varMap) {
 	vm = make(map[string]varInfo)
 	for k, v := range variables {
 		vm[k] = varInfo{
 			Value: v,
 		}
 	}
+	for k, v := range typedVariables {
+		vm[k] = varInfo{
+			typed: v,
+		}
+	}
 	return vm
 }
 
@@ -300,9 +330,14 @@ func convertToVarMap(variables map[string]string) (vm varMap) {
 type Request struct {
 	Str       string
 	Variables map[string]string
+	// TypedVariables holds the variables given in binary form. A variable given here
+	// overrides the one of the same name in Variables.
+	TypedVariables map[string]*pb.Variable
 }
 
 func checkValueType(vm varMap) error {
+	// The keys of map variables, added to vm once it has been type checked.
+	fields := make(varMap)
 	for k, v := range vm {
 		typ := v.Type
 
@@ -312,14 +347,36 @@ func checkValueType(vm varMap) error {
 
 		// Ensure value is not nil if the variable is required.
 		if typ[len(typ)-1] == '!' {
-			if v.Value == "" {
+			if v.Value == "" && v.typed == nil {
 				return errors.Errorf("Variable %v should be initialised", k)
 			}
 			typ = typ[:len(typ)-1]
 		}
 
 		// Type check the values.
-		if v.Value != "" {
+		if v.typed != nil {
+			var err error
+			if v, err = checkTypedValue(k, typ, v, fields); err != nil {
+				return err
+			}
+			vm[k] = v
+		} else if typ == "map" {
+			if v.Value != "" {
+				if err := parseMapValue(k, v.Value, fields); err != nil {
+					return err
+				}
+			}
+		} else if v.Value != "" && isListType(typ) && v.Values == nil {
+			values, err := parseListValue(typ[1:len(typ)-1], v.Value)
+			if err != nil {
+				return errors.Wrapf(err, "while parsing variable %v", k)
+			}
+			v.Values = values
+			// Value is kept in the form accepted by parseID, so that a [uid] variable
+			// can be used wherever a list of uids is expected.
+			v.Value = "[" + strings.Join(values, ",") + "]"
+			vm[k] = v
+		} else if v.Value != "" {
 			switch typ {
 			case "int":
 				{
@@ -346,6 +403,252 @@ func checkValueType(vm varMap) error {
 		}
 	}
 
+	for k, v := range fields {
+		vm[k] = v
+	}
+	return nil
+}
+
+// parseMapValue parses the JSON object given as the value of the map variable name into
+// one variable per key, added to vm, so that $name.key can be used wherever a variable
+// can. A key holding an array is a [string] variable, any other key a string variable.
+func parseMapValue(name, val string, vm varMap) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(val), &fields); err != nil {
+		return errors.Errorf("Expected a map but got %v", val)
+	}
+
+	for key, raw := range fields {
+		field := name + "." + key
+		if raw = bytes.TrimSpace(raw); len(raw) > 0 && raw[0] == '[' {
+			values, err := parseListValue("string", string(raw))
+			if err != nil {
+				return errors.Wrapf(err, "while parsing variable %v", field)
+			}
+			vm[field] = varInfo{
+				Value:  "[" + strings.Join(values, ",") + "]",
+				Type:   "[string]",
+				Values: values,
+			}
+			continue
+		}
+
+		var elem interface{}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&elem); err != nil {
+			return errors.Wrapf(err, "while parsing variable %v", field)
+		}
+		var s string
+		switch e := elem.(type) {
+		case string:
+			s = e
+		case json.Number:
+			s = e.String()
+		case bool:
+			s = strconv.FormatBool(e)
+		default:
+			return errors.Errorf("Unexpected value %v for variable %v", elem, field)
+		}
+		vm[field] = varInfo{
+			Value: s,
+			Type:  "string",
+		}
+	}
+	return nil
+}
+
+// typedValues returns the elements of a variable given in binary form as strings, along
+// with their type. It's empty for a variable with no elements, which is of any type.
+func typedValues(v *pb.Variable) (string, []string, error) {
+	var typ string
+	var values []string
+	set := func(t string, n int) {
+		if n > 0 && typ == "" {
+			typ = t
+			values = make([]string, 0, n)
+		} else if n > 0 {
+			typ = "mixed"
+		}
+	}
+	set("string", len(v.Strings))
+	set("int", len(v.Ints))
+	set("float", len(v.Floats))
+	set("bool", len(v.Bools))
+	set("uid", len(v.Uids))
+	set("map", len(v.Entries))
+
+	switch typ {
+	case "mixed":
+		return "", nil, errors.Errorf("More than one type of value given")
+	case "string":
+		values = v.Strings
+	case "int":
+		for _, i := range v.Ints {
+			values = append(values, strconv.FormatInt(i, 10))
+		}
+	case "float":
+		for _, f := range v.Floats {
+			values = append(values, strconv.FormatFloat(f, 'g', -1, 64))
+		}
+	case "bool":
+		for _, b := range v.Bools {
+			values = append(values, strconv.FormatBool(b))
+		}
+	case "uid":
+		for _, uid := range v.Uids {
+			values = append(values, "0x"+strconv.FormatUint(uid, 16))
+		}
+	}
+	return typ, values, nil
+}
+
+// checkTypedValue type checks the value of the variable k given in binary form against
+// typ, and returns the variable with its Value and Values filled in, as if its value had
+// been given as text. The keys of a map variable are added to vm.
+func checkTypedValue(k, typ string, v varInfo, vm varMap) (varInfo, error) {
+	valTyp, values, err := typedValues(v.typed)
+	if err != nil {
+		return v, errors.Wrapf(err, "while parsing variable %v", k)
+	}
+	matches := func(typ string) bool {
+		return valTyp == "" || valTyp == typ || (typ == "float" && valTyp == "int")
+	}
+
+	switch {
+	case typ == "map":
+		if !matches("map") {
+			return v, errors.Errorf("Expected a map but got a %s for variable %v", valTyp, k)
+		}
+		for key, entry := range v.typed.Entries {
+			field := k + "." + key
+			entryTyp, entryValues, err := typedValues(entry)
+			switch {
+			case err != nil:
+				return v, errors.Wrapf(err, "while parsing variable %v", field)
+			case entryTyp == "map":
+				return v, errors.Errorf("Nested maps aren't supported for variable %v", field)
+			case entryTyp == "":
+				entryTyp = "string"
+			}
+			// A key holding a single element is a scalar, so that it can be used as a
+			// query argument like first: $m.first.
+			if len(entryValues) == 1 {
+				vm[field] = varInfo{Value: entryValues[0], Type: entryTyp}
+				continue
+			}
+			vm[field] = varInfo{
+				Value:  "[" + strings.Join(entryValues, ",") + "]",
+				Type:   "[" + entryTyp + "]",
+				Values: entryValues,
+			}
+		}
+	case isListType(typ):
+		if elemTyp := typ[1 : len(typ)-1]; !matches(elemTyp) {
+			return v, errors.Errorf("Expected a list of %s but got a %s for variable %v",
+				elemTyp, valTyp, k)
+		}
+		v.Values = values
+		v.Value = "[" + strings.Join(values, ",") + "]"
+	default:
+		switch typ {
+		case "int", "float", "bool", "string":
+		default:
+			return v, errors.Errorf("Type %q not supported", typ)
+		}
+		if !matches(typ) || len(values) > 1 {
+			return v, errors.Errorf("Expected a single %s for variable %v", typ, k)
+		}
+		if len(values) == 1 {
+			v.Value = values[0]
+		}
+	}
+	v.typed = nil
+	return v, nil
+}
+
+func isListType(typ string) bool {
+	return len(typ) > 2 && typ[0] == '[' && typ[len(typ)-1] == ']'
+}
+
+// parseListValue parses the JSON array given as the value of a list variable and
+// checks that every element is of type elemTyp. Lists with thousands of elements
+// are common with IN-like filters, so they are parsed once here rather than every
+// time the variable is substituted.
+func parseListValue(elemTyp, val string) ([]string, error) {
+	var elems []interface{}
+	dec := json.NewDecoder(strings.NewReader(val))
+	dec.UseNumber()
+	if err := dec.Decode(&elems); err != nil {
+		return nil, errors.Errorf("Expected a list of %s but got %v", elemTyp, val)
+	}
+
+	values := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		var s string
+		switch e := elem.(type) {
+		case string:
+			s = e
+		case json.Number:
+			s = e.String()
+		case bool:
+			s = strconv.FormatBool(e)
+		default:
+			return nil, errors.Errorf("Unexpected element %v in list of %s", elem, elemTyp)
+		}
+
+		var err error
+		switch elemTyp {
+		case "int":
+			_, err = strconv.ParseInt(s, 0, 64)
+		case "float":
+			_, err = strconv.ParseFloat(s, 64)
+		case "bool":
+			_, err = strconv.ParseBool(s)
+		case "uid":
+			_, err = strconv.ParseUint(s, 0, 64)
+		case "string":
+		default:
+			return nil, errors.Errorf("Type %q not supported", "["+elemTyp+"]")
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "Expected a %s but got %v", elemTyp, s)
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+// substituteFuncArgs replaces the GraphQL variables among the arguments of f with
+// their values. A list variable expands into one argument per element, so that
+// eq(name, $names) behaves as if every name had been written out.
+func substituteFuncArgs(f *Function, vmap varMap) error {
+	args := make([]Arg, 0, len(f.Args))
+	for _, v := range f.Args {
+		if !v.IsGraphQLVar {
+			args = append(args, v)
+			continue
+		}
+		if va, ok := vmap[v.Value]; ok && isListType(strings.TrimSuffix(va.Type, "!")) {
+			for _, val := range va.Values {
+				args = append(args, Arg{Value: val})
+			}
+			continue
+		}
+		if err := substituteVar(v.Value, &v.Value, vmap); err != nil {
+			return err
+		}
+		args = append(args, v)
+		// We need to parse the regexp after substituting it from a GraphQL Variable.
+		if f.Name == "regexp" {
+			f.Args = args
+			if err := regExpVariableFilter(f, len(args)-1); err != nil {
+				return err
+			}
+			args = f.Args
+		}
+	}
+	f.Args = args
 	return nil
 }
 
@@ -386,18 +689,8 @@ func substituteVariables(gq *GraphQuery, vmap varMap) error {
 			return err
 		}
 
-		for idx, v := range gq.Func.Args {
-			if !v.IsGraphQLVar {
-				continue
-			}
-			if err := substituteVar(v.Value, &gq.Func.Args[idx].Value, vmap); err != nil {
-				return err
-			}
-			if gq.Func.Name == "regexp" {
-				if err := regExpVariableFilter(gq.Func, idx); err != nil {
-					return err
-				}
-			}
+		if err := substituteFuncArgs(gq.Func, vmap); err != nil {
+			return err
 		}
 	}
 
@@ -474,11 +767,11 @@ func substituteVariablesFilter(f *FilterTree, vmap varMap) error {
 			return err
 		}
 
-		for idx, v := range f.Func.Args {
-			if !v.IsGraphQLVar {
-				continue
-			}
-			if f.Func.Name == uidFunc {
+		if f.Func.Name == uidFunc {
+			for _, v := range f.Func.Args {
+				if !v.IsGraphQLVar {
+					continue
+				}
 				// This is to support GraphQL variables in uid functions.
 				idVal, ok := vmap[v.Value]
 				if !ok {
@@ -489,20 +782,9 @@ func substituteVariablesFilter(f *FilterTree, vmap varMap) error {
 					return err
 				}
 				f.Func.UID = append(f.Func.UID, uids...)
-				continue
-			}
-
-			if err := substituteVar(v.Value, &f.Func.Args[idx].Value, vmap); err != nil {
-				return err
-			}
-
-			// We need to parse the regexp after substituting it from a GraphQL Variable.
-			_, ok := vmap[v.Value]
-			if f.Func.Name == "regexp" && ok {
-				if err := regExpVariableFilter(f.Func, idx); err != nil {
-					return err
-				}
 			}
+		} else if err := substituteFuncArgs(f.Func, vmap); err != nil {
+			return err
 		}
 	}
 
@@ -557,18 +839,40 @@ func Parse(r Request) (Result, error) {
 // The variable name v needs to be passed through the needVars parameter. Otherwise, an error
 // is reported complaining that the variable v is defined but not used in the query block.
 func ParseWithNeedVars(r Request, needVars []string) (res Result, rerr error) {
-	query := r.Str
-	vmap := convertToVarMap(r.Variables)
-
-	var lexer lex.Lexer
-	lexer.Reset(query)
-	lexer.Run(lexTopLevel)
-	if err := lexer.ValidateResult(); err != nil {
+	p, err := Prepare(r.Str)
+	if err != nil {
 		return res, err
 	}
+	// The prepared query is used only once, so its blocks needn't be copied.
+	return p.parse(r, needVars, false)
+}
+
+// Prepared is a query that has been lexed, parsed and validated once, so that it can be run
+// many times with different variables. Each run fills in the variables of a copy of the parsed
+// blocks. It is safe for concurrent use.
+type Prepared struct {
+	blocks []preparedBlock
+	schema *pb.SchemaRequest
+}
+
+// preparedBlock is a query block with its fragments expanded, along with the variables it
+// declares. vars is nil if the block doesn't declare variables.
+type preparedBlock struct {
+	gq   *GraphQuery
+	vars varMap
+}
 
-	var qu *GraphQuery
-	it := lexer.NewIterator()
+// Prepare lexes, parses and validates the given query for later runs with its variables.
+func Prepare(query string) (*Prepared, error) {
+	var l lex.Lexer
+	l.Reset(query)
+	l.Run(lexTopLevel)
+	if err := l.ValidateResult(); err != nil {
+		return nil, err
+	}
+
+	p := &Prepared{}
+	it := l.NewIterator()
 	fmap := make(fragmentMap)
 	for it.Next() {
 		item := it.Item()
@@ -576,76 +880,131 @@ func ParseWithNeedVars(r Request, needVars []string) (res Result, rerr error) {
 		case itemOpType:
 			switch item.Val {
 			case "mutation":
-				return res, item.Errorf("Mutation block no longer allowed.")
+				return nil, item.Errorf("Mutation block no longer allowed.")
 			case "schema":
-				if res.Schema != nil {
-					return res, item.Errorf("Only one schema block allowed ")
+				if p.schema != nil {
+					return nil, item.Errorf("Only one schema block allowed ")
 				}
-				if res.Query != nil {
-					return res, item.Errorf("Schema block is not allowed with query block")
+				if len(p.blocks) != 0 {
+					return nil, item.Errorf("Schema block is not allowed with query block")
 				}
-				if res.Schema, rerr = getSchema(it); rerr != nil {
-					return res, rerr
+				schema, err := getSchema(it)
+				if err != nil {
+					return nil, err
 				}
+				p.schema = schema
 			case "fragment":
 				// TODO(jchiu0): This is to be done in ParseSchema once it is ready.
-				fnode, rerr := getFragment(it)
-				if rerr != nil {
-					return res, rerr
+				fnode, err := getFragment(it)
+				if err != nil {
+					return nil, err
 				}
 				fmap[fnode.Name] = fnode
 			case "query":
-				if res.Schema != nil {
-					return res, item.Errorf("Schema block is not allowed with query block")
+				if p.schema != nil {
+					return nil, item.Errorf("Schema block is not allowed with query block")
 				}
-				if qu, rerr = getVariablesAndQuery(it, vmap); rerr != nil {
-					return res, rerr
+				qu, vars, err := getVariablesAndQuery(it)
+				if err != nil {
+					return nil, err
 				}
-				res.Query = append(res.Query, qu)
+				p.blocks = append(p.blocks, preparedBlock{gq: qu, vars: vars})
 			}
 		case itemLeftCurl:
-			if qu, rerr = getQuery(it); rerr != nil {
-				return res, rerr
+			qu, err := getQuery(it)
+			if err != nil {
+				return nil, err
 			}
-			res.Query = append(res.Query, qu)
+			p.blocks = append(p.blocks, preparedBlock{gq: qu})
 		case itemName:
 			it.Prev()
-			if qu, rerr = getQuery(it); rerr != nil {
-				return res, rerr
+			qu, err := getQuery(it)
+			if err != nil {
+				return nil, err
 			}
-			res.Query = append(res.Query, qu)
+			p.blocks = append(p.blocks, preparedBlock{gq: qu})
 		}
 	}
 
-	if len(res.Query) != 0 {
-		res.QueryVars = make([]*Vars, 0, len(res.Query))
-		for i := 0; i < len(res.Query); i++ {
-			qu := res.Query[i]
-			// Try expanding fragments using fragment map.
-			if err := qu.expandFragments(fmap); err != nil {
-				return res, err
-			}
+	for _, b := range p.blocks {
+		// Try expanding fragments using fragment map.
+		if err := b.gq.expandFragments(fmap); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
 
-			// Substitute all graphql variables with corresponding values
-			if err := substituteVariables(qu, vmap); err != nil {
-				return res, err
-			}
+// Parse parses the prepared query with the given variables.
+func (p *Prepared) Parse(variables map[string]string) (Result, error) {
+	return p.ParseWithNeedVars(variables, nil)
+}
 
-			res.QueryVars = append(res.QueryVars, &Vars{})
-			// Collect vars used and defined in Result struct.
-			qu.collectVars(res.QueryVars[i])
+// ParseWithNeedVars performs parsing of the prepared query with the given variables and
+// needVars. See ParseWithNeedVars for the use of needVars.
+func (p *Prepared) ParseWithNeedVars(variables map[string]string,
+	needVars []string) (res Result, rerr error) {
+	return p.ParseRequest(Request{Variables: variables}, needVars)
+}
+
+// ParseRequest performs parsing of the prepared query with the variables of r, which may
+// be given in binary form too. The query text of r is ignored.
+func (p *Prepared) ParseRequest(r Request, needVars []string) (res Result, rerr error) {
+	return p.parse(r, needVars, true)
+}
+
+// parse fills in the variables of r in the prepared blocks, which are copied first if reuse
+// is set. Otherwise, p can't be used again.
+func (p *Prepared) parse(r Request, needVars []string, reuse bool) (res Result, rerr error) {
+	vmap := convertToVarMap(r.Variables, r.TypedVariables)
+	for _, b := range p.blocks {
+		if b.vars == nil {
+			continue
+		}
+		declareVariables(vmap, b.vars)
+		if err := checkValueType(vmap); err != nil {
+			return res, err
 		}
+	}
 
-		allVars := res.QueryVars
-		// Add the variables that are needed outside the query block.
-		// For example, mutation block in upsert block will be using
-		// variables from the query block that is getting parsed here.
-		if len(needVars) != 0 {
-			allVars = append(allVars, &Vars{Needs: needVars})
+	res.Schema = p.schema
+	if reuse && p.schema != nil {
+		schema := *p.schema
+		res.Schema = &schema
+	}
+	if len(p.blocks) == 0 {
+		return res, nil
+	}
+
+	res.Query = make([]*GraphQuery, 0, len(p.blocks))
+	res.QueryVars = make([]*Vars, 0, len(p.blocks))
+	for i, b := range p.blocks {
+		qu := b.gq
+		if reuse {
+			qu = qu.clone()
 		}
-		if err := checkDependency(allVars); err != nil {
+		res.Query = append(res.Query, qu)
+
+		// Substitute all graphql variables with corresponding values
+		if err := substituteVariables(qu, vmap); err != nil {
 			return res, err
 		}
+		qu.applyGraphs(nil)
+
+		res.QueryVars = append(res.QueryVars, &Vars{})
+		// Collect vars used and defined in Result struct.
+		qu.collectVars(res.QueryVars[i])
+	}
+
+	allVars := res.QueryVars
+	// Add the variables that are needed outside the query block.
+	// For example, mutation block in upsert block will be using
+	// variables from the query block that is getting parsed here.
+	if len(needVars) != 0 {
+		allVars = append(allVars, &Vars{Needs: needVars})
+	}
+	if err := checkDependency(allVars); err != nil {
+		return res, err
 	}
 
 	if err := validateResult(&res); err != nil {
@@ -775,10 +1134,115 @@ func (f *FilterTree) hasVars() bool {
 	return false
 }
 
-// getVariablesAndQuery checks if the query has a variable list and stores it in
-// vmap. For variable list to be present, the query should have a name which is
+// clone returns a deep copy of gq, so that the variables of a prepared query can be filled in,
+// and the query processed, without changing the prepared one.
+func (gq *GraphQuery) clone() *GraphQuery {
+	if gq == nil {
+		return nil
+	}
+	c := *gq
+	c.UID = append([]uint64(nil), gq.UID...)
+	c.Langs = append([]string(nil), gq.Langs...)
+	c.NeedsVar = append([]VarContext(nil), gq.NeedsVar...)
+	c.Func = gq.Func.clone()
+	if gq.Args != nil {
+		c.Args = make(map[string]string, len(gq.Args))
+		for k, v := range gq.Args {
+			c.Args[k] = v
+		}
+	}
+	c.Order = nil
+	for _, order := range gq.Order {
+		o := *order
+		o.Langs = append([]string(nil), order.Langs...)
+		c.Order = append(c.Order, &o)
+	}
+	c.Children = nil
+	for _, child := range gq.Children {
+		c.Children = append(c.Children, child.clone())
+	}
+	c.Filter = gq.Filter.clone()
+	c.FacetsFilter = gq.FacetsFilter.clone()
+	c.MathExp = gq.MathExp.clone()
+	c.ShortestPathArgs.From = gq.ShortestPathArgs.From.clone()
+	c.ShortestPathArgs.To = gq.ShortestPathArgs.To.clone()
+	c.Cascade = append([]string(nil), gq.Cascade...)
+	if gq.Facets != nil {
+		facets := *gq.Facets
+		facets.Param = nil
+		for _, param := range gq.Facets.Param {
+			p := *param
+			facets.Param = append(facets.Param, &p)
+		}
+		c.Facets = &facets
+	}
+	c.Graphs = append([]string(nil), gq.Graphs...)
+	c.GroupbyAttrs = nil
+	for _, attr := range gq.GroupbyAttrs {
+		attr.Langs = append([]string(nil), attr.Langs...)
+		c.GroupbyAttrs = append(c.GroupbyAttrs, attr)
+	}
+	if gq.FacetVar != nil {
+		c.FacetVar = make(map[string]string, len(gq.FacetVar))
+		for k, v := range gq.FacetVar {
+			c.FacetVar[k] = v
+		}
+	}
+	c.FacetsOrder = nil
+	for _, order := range gq.FacetsOrder {
+		o := *order
+		c.FacetsOrder = append(c.FacetsOrder, &o)
+	}
+	c.AllowedPreds = append([]string(nil), gq.AllowedPreds...)
+	return &c
+}
+
+func (f *Function) clone() *Function {
+	if f == nil {
+		return nil
+	}
+	c := *f
+	c.Args = append([]Arg(nil), f.Args...)
+	c.UID = append([]uint64(nil), f.UID...)
+	c.NeedsVar = append([]VarContext(nil), f.NeedsVar...)
+	return &c
+}
+
+func (f *FilterTree) clone() *FilterTree {
+	if f == nil {
+		return nil
+	}
+	c := *f
+	c.Func = f.Func.clone()
+	c.Child = nil
+	for _, fch := range f.Child {
+		c.Child = append(c.Child, fch.clone())
+	}
+	return &c
+}
+
+func (f *MathTree) clone() *MathTree {
+	if f == nil {
+		return nil
+	}
+	c := *f
+	if f.Val != nil {
+		c.Val = make(map[uint64]types.Val, len(f.Val))
+		for k, v := range f.Val {
+			c.Val[k] = v
+		}
+	}
+	c.Child = nil
+	for _, fch := range f.Child {
+		c.Child = append(c.Child, fch.clone())
+	}
+	return &c
+}
+
+// getVariablesAndQuery checks if the query has a variable list and returns it in
+// vars. For variable list to be present, the query should have a name which is
 // also checked for. It also calls getQuery to create the GraphQuery object tree.
-func getVariablesAndQuery(it *lex.ItemIterator, vmap varMap) (gq *GraphQuery, rerr error) {
+func getVariablesAndQuery(it *lex.ItemIterator) (gq *GraphQuery, vars varMap, rerr error) {
 	var name string
 L2:
 	for it.Next() {
@@ -786,30 +1250,27 @@ L2:
 		switch item.Typ {
 		case itemName:
 			if name != "" {
-				return nil, item.Errorf("Multiple word query name not allowed.")
+				return nil, nil, item.Errorf("Multiple word query name not allowed.")
 			}
 			name = item.Val
 		case itemLeftRound:
 			if name == "" {
-				return nil, item.Errorf("Variables can be defined only in named queries.")
-			}
-
-			if rerr = parseGqlVariables(it, vmap); rerr != nil {
-				return nil, rerr
+				return nil, nil, item.Errorf("Variables can be defined only in named queries.")
 			}
 
-			if rerr = checkValueType(vmap); rerr != nil {
-				return nil, rerr
+			vars = make(varMap)
+			if rerr = parseGqlVariables(it, vars); rerr != nil {
+				return nil, nil, rerr
 			}
 		case itemLeftCurl:
 			if gq, rerr = getQuery(it); rerr != nil {
-				return nil, rerr
+				return nil, nil, rerr
 			}
 			break L2
 		}
 	}
 
-	return gq, nil
+	return gq, vars, nil
 }
 
 // parseVarName returns the variable name.
@@ -969,6 +1430,10 @@ L:
 				}
 			case "ignorereflex":
 				gq.IgnoreReflex = true
+			case "graph":
+				if err := parseGraphs(it, gq); err != nil {
+					return nil, err
+				}
 			case "recurse":
 				gq.Recurse = true
 				if err := parseRecurseArgs(it, gq); err != nil {
@@ -1195,15 +1660,27 @@ loop:
 			return item.Errorf("Expecting a colon. Got: %v", item)
 		}
 
-		// Get variable type.
+		// Get variable type. A list type like [string] is lexed as a name within
+		// square brackets.
 		it.Next()
 		item = it.Item()
+		isList := item.Typ == itemLeftSquare
+		if isList {
+			it.Next()
+			item = it.Item()
+		}
 		if item.Typ != itemName {
 			return item.Errorf("Expecting a variable type. Got: %v", item)
 		}
 
 		// Ensure that the type is not nil.
 		varType := item.Val
+		if isList {
+			if !it.Next() || it.Item().Typ != itemRightSquare {
+				return item.Errorf("Expecting ] after list type %v", varType)
+			}
+			varType = "[" + varType + "]"
+		}
 		if varType == "" {
 			return item.Errorf("Type of a variable can't be empty")
 		}
@@ -1216,11 +1693,9 @@ loop:
 		}
 		// Insert the variable into the map. The variable might already be defiend
 		// in the variable list passed with the query.
-		if _, ok := vmap[varName]; ok {
-			vmap[varName] = varInfo{
-				Value: vmap[varName].Value,
-				Type:  varType,
-			}
+		if v, ok := vmap[varName]; ok {
+			v.Type = varType
+			vmap[varName] = v
 		} else {
 			vmap[varName] = varInfo{
 				Type: varType,
@@ -1242,7 +1717,7 @@ loop:
 
 			// If value is empty replace, otherwise ignore the default value
 			// as the intialised value will override the default value.
-			if vmap[varName].Value == "" {
+			if vmap[varName].Value == "" && vmap[varName].typed == nil {
 				uq, err := unquoteIfQuoted(it.Val)
 				if err != nil {
 					return err
@@ -1263,6 +1738,21 @@ loop:
 	return nil
 }
 
+// declareVariables applies the variable declarations of a query block, parsed by
+// parseGqlVariables, to the variables given with the request. A default value is used only
+// for a variable that wasn't given a value.
+func declareVariables(vmap, vars varMap) {
+	for name, decl := range vars {
+		v, ok := vmap[name]
+		if decl.Value != "" && (!ok || (v.Value == "" && v.typed == nil)) {
+			vmap[name] = decl
+			continue
+		}
+		v.Type = decl.Type
+		vmap[name] = v
+	}
+}
+
 // unquoteIfQuoted checks if str is quoted (starts and ends with quotes). If
 // so, it tries to unquote str possibly returning an error. Otherwise, the
 // original value is returned.
@@ -1619,7 +2109,7 @@ func validFuncName(name string) bool {
 
 	switch name {
 	case "regexp", "anyofterms", "allofterms", "alloftext", "anyoftext",
-		"has", "uid", "uid_in", "anyof", "allof", "type", "match":
+		"has", "uid", "uid_in", "anyof", "allof", "type", "match", "fuzzy":
 		return true
 	}
 	return false
@@ -2111,6 +2601,83 @@ func tryParseFacetList(it *lex.ItemIterator) (res facetRes, parseOk bool, err er
 	}
 }
 
+// parseGraphs parses the named graphs of @graph(g1, "http://example.org/g2").
+func parseGraphs(it *lex.ItemIterator, gq *GraphQuery) error {
+	item := it.Item()
+	if len(gq.Graphs) > 0 {
+		return item.Errorf("Only one graph directive allowed")
+	}
+	it.Next()
+	if it.Item().Typ != itemLeftRound {
+		return item.Errorf("Expected a left round after graph, got: %s", it.Item().String())
+	}
+
+	expectArg := true
+loop:
+	for it.Next() {
+		item := it.Item()
+		switch item.Typ {
+		case itemRightRound:
+			break loop
+		case itemComma:
+			if expectArg {
+				return item.Errorf("Expected a graph but got comma")
+			}
+			expectArg = true
+		case itemName:
+			if !expectArg {
+				return item.Errorf("Expected a comma or right round but got: %v", item.Val)
+			}
+			graph, err := unquoteIfQuoted(collectName(it, item.Val))
+			if err != nil {
+				return err
+			}
+			gq.Graphs = append(gq.Graphs, graph)
+			expectArg = false
+		default:
+			return item.Errorf("Unexpected item while parsing: %v", item.Val)
+		}
+	}
+	if expectArg {
+		return item.Errorf("Expected a graph in the graph directive")
+	}
+	return nil
+}
+
+// applyGraphs restricts the edges of the block and of its children to the named graphs given
+// with @graph on the block or on one of its parents, by filtering on the graph facet.
+func (gq *GraphQuery) applyGraphs(graphs []string) {
+	if len(gq.Graphs) > 0 {
+		graphs = gq.Graphs
+	}
+	// Reverse edges don't store facets.
+	isEdge := gq.Attr != "" && gq.Attr != "uid" && gq.Attr[0] != '~' && !gq.IsInternal &&
+		len(gq.Expand) == 0
+	if len(graphs) > 0 && isEdge {
+		// The facets filter is evaluated as a binary tree.
+		var ft *FilterTree
+		for _, graph := range graphs {
+			eq := &FilterTree{Func: &Function{
+				Attr: x.GraphFacet,
+				Name: "eq",
+				Args: []Arg{{Value: graph}},
+			}}
+			if ft == nil {
+				ft = eq
+			} else {
+				ft = &FilterTree{Op: "or", Child: []*FilterTree{ft, eq}}
+			}
+		}
+		if gq.FacetsFilter != nil {
+			ft = &FilterTree{Op: "and", Child: []*FilterTree{gq.FacetsFilter, ft}}
+		}
+		gq.FacetsFilter = ft
+	}
+	for _, child := range gq.Children {
+		child.applyGraphs(graphs)
+	}
+}
+
 // parseCascade parses the cascade directive.
 // Two formats:
 // 	1. @cascade
@@ -2516,6 +3083,10 @@ func parseDirective(it *lex.ItemIterator, curp *GraphQuery) error {
 		if err := parseCascade(it, curp); err != nil {
 			return err
 		}
+	case item.Val == "graph":
+		if err := parseGraphs(it, curp); err != nil {
+			return err
+		}
 	case item.Val == "normalize":
 		curp.Normalize = true
 	case peek[0].Typ == itemLeftRound:
@@ -2595,7 +3166,7 @@ func parseLanguageList(it *lex.ItemIterator) ([]string, error) {
 
 func validKeyAtRoot(k string) bool {
 	switch k {
-	case "func", "orderasc", "orderdesc", "first", "offset", "after":
+	case "func", "orderasc", "orderdesc", "first", "offset", "after", "total":
 		return true
 	case "from", "to", "numpaths", "minweight", "maxweight":
 		// Specific to shortest path
@@ -2904,6 +3475,23 @@ func validateEmptyBlockItem(it *lex.ItemIterator, val string) error {
 	return nil
 }
 
+// isLineageFunc returns whether the items after lineage are a predicate between parentheses, so
+// that a predicate named lineage can still be queried with arguments.
+func isLineageFunc(it *lex.ItemIterator) bool {
+	items, err := it.Peek(3)
+	return err == nil && items[0].Typ == itemLeftRound && items[1].Typ == itemName &&
+		items[2].Typ == itemRightRound
+}
+
+// isTextFunc returns whether the items after relevance or highlight are the arguments of the
+// function rather than pagination arguments, so that predicates with these names can still be
+// queried.
+func isTextFunc(it *lex.ItemIterator) bool {
+	items, err := it.Peek(3)
+	return err == nil && items[0].Typ == itemLeftRound && items[1].Typ == itemName &&
+		items[2].Typ != itemColon
+}
+
 // godeep constructs the subgraph from the lexed items and a GraphQuery node.
 func godeep(it *lex.ItemIterator, gq *GraphQuery) error {
 	if gq == nil {
@@ -2994,6 +3582,45 @@ func godeep(it *lex.ItemIterator, gq *GraphQuery) error {
 				gq.Children = append(gq.Children, child)
 				curp = nil
 				continue
+			case (valLower == "relevance" || valLower == "highlight") && isTextFunc(it):
+				child := &GraphQuery{
+					Args:  make(map[string]string),
+					Var:   varName,
+					Alias: alias,
+				}
+				varName, alias = "", ""
+				it.Prev()
+				if child.Func, err = parseFunction(it, gq); err != nil {
+					return err
+				}
+				switch n := len(child.Func.Args); {
+				case valLower == "relevance" && n != 1:
+					return it.Errorf("relevance() takes a predicate and the text to score, "+
+						"got %d arguments", n+1)
+				case valLower == "highlight" && n != 1 && n != 3:
+					return it.Errorf("highlight() takes a predicate, the text to highlight and "+
+						"optionally the tags around the matches, got %d arguments", n+1)
+				}
+				child.Attr = child.Func.Attr
+				gq.Children = append(gq.Children, child)
+				curp = nil
+				continue
+			case valLower == "lineage" && isLineageFunc(it):
+				it.Next() // Consume the '('
+				it.Next()
+				child := &GraphQuery{
+					Args:    make(map[string]string),
+					Attr:    collectName(it, it.Item().Val),
+					Lineage: true,
+					Var:     varName,
+					Alias:   alias,
+				}
+				it.Next() // Consume the ')'
+				varName, alias = "", ""
+				gq.Children = append(gq.Children, child)
+				// Like for expand(), curp is set so that it can have children and filters.
+				curp = child
+				continue
 			case isAggregator(valLower):
 				child := &GraphQuery{
 					Attr:       valueFunc,
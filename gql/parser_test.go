@@ -18,13 +18,16 @@ package gql
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"runtime/debug"
+	"sync"
 	"testing"
 
 	"github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/dgraph-io/dgraph/chunker"
 	"github.com/dgraph-io/dgraph/lex"
+	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
@@ -4858,6 +4861,194 @@ func TestParseGraphQLVarArray(t *testing.T) {
 	}
 }
 
+func TestParseGraphQLListVar(t *testing.T) {
+	q := `query test($names: [string], $ids: [uid], $ages: [int]) {
+		q(func: eq(name, $names)) @filter(uid($ids) AND eq(age, $ages)) {
+			name
+		}
+		r(func: uid($ids)) {
+			name
+		}
+	}`
+	gq, err := Parse(Request{Str: q, Variables: map[string]string{
+		"$names": `["srfrog", "horseman", "a, b"]`,
+		"$ids":   `["0x1", "0xa"]`,
+		"$ages":  `[21, 42]`,
+	}})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(gq.Query))
+
+	var names []string
+	for _, arg := range gq.Query[0].Func.Args {
+		names = append(names, arg.Value)
+	}
+	require.Equal(t, []string{"srfrog", "horseman", "a, b"}, names)
+
+	filter := gq.Query[0].Filter
+	require.Equal(t, []uint64{1, 10}, filter.Child[0].Func.UID)
+	require.Equal(t, 2, len(filter.Child[1].Func.Args))
+	require.Equal(t, "42", filter.Child[1].Func.Args[1].Value)
+	require.Equal(t, []uint64{1, 10}, gq.Query[1].UID)
+}
+
+func TestParseGraphQLMapVar(t *testing.T) {
+	q := `query test($m: map) {
+		q(func: eq(name, $m.names), first: $m.first) @filter(uid($m.ids)) {
+			name
+		}
+	}`
+	gq, err := Parse(Request{Str: q, Variables: map[string]string{
+		"$m": `{"names": ["srfrog", "horseman"], "first": 10, "ids": ["0x1", "0xa"]}`,
+	}})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(gq.Query[0].Func.Args))
+	require.Equal(t, "horseman", gq.Query[0].Func.Args[1].Value)
+	require.Equal(t, "10", gq.Query[0].Args["first"])
+	require.Equal(t, []uint64{1, 10}, gq.Query[0].Filter.Func.UID)
+
+	_, err = Parse(Request{Str: q, Variables: map[string]string{
+		"$m": `{"names": ["srfrog"], "first": 10}`,
+	}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "$m.ids")
+
+	_, err = Parse(Request{Str: q, Variables: map[string]string{"$m": `["srfrog"]`}})
+	require.Error(t, err)
+}
+
+func TestParseGraphQLTypedVar(t *testing.T) {
+	q := `query test($names: [string], $ids: [uid], $ages: [float], $m: map, $n: int) {
+		q(func: eq(name, $names), first: $n) @filter(uid($ids) AND eq(age, $ages)) {
+			name
+		}
+		r(func: eq(name, $m.name), first: $m.first) @filter(uid($m.ids)) {
+			name
+		}
+	}`
+	ids := make([]uint64, 10000)
+	for i := range ids {
+		ids[i] = uint64(i + 1)
+	}
+	vars := func() map[string]*pb.Variable {
+		return map[string]*pb.Variable{
+			"$names": {Strings: []string{"srfrog", "a, b"}},
+			"$ids":   {Uids: ids},
+			"$ages":  {Ints: []int64{21, 42}},
+			"$n":     {Ints: []int64{10}},
+			"$m": {Entries: map[string]*pb.Variable{
+				"name":  {Strings: []string{"horseman"}},
+				"first": {Ints: []int64{5}},
+				"ids":   {Uids: []uint64{0x1, 0xa}},
+			}},
+		}
+	}
+	gq, err := Parse(Request{Str: q,
+		Variables:      map[string]string{"$n": "ten", "$names": `["alice"]`},
+		TypedVariables: vars()})
+	require.NoError(t, err)
+
+	var names []string
+	for _, arg := range gq.Query[0].Func.Args {
+		names = append(names, arg.Value)
+	}
+	require.Equal(t, []string{"srfrog", "a, b"}, names)
+	require.Equal(t, "10", gq.Query[0].Args["first"])
+	filter := gq.Query[0].Filter
+	require.Equal(t, ids, filter.Child[0].Func.UID)
+	require.Equal(t, "42", filter.Child[1].Func.Args[1].Value)
+
+	require.Equal(t, "horseman", gq.Query[1].Func.Args[0].Value)
+	require.Equal(t, "5", gq.Query[1].Args["first"])
+	require.Equal(t, []uint64{1, 10}, gq.Query[1].Filter.Func.UID)
+
+	tests := []map[string]*pb.Variable{
+		{"$ids": {Strings: []string{"0x1"}}},
+		{"$n": {Ints: []int64{1, 2}}},
+		{"$n": {Floats: []float64{1.5}}},
+		{"$names": {Strings: []string{"a"}, Ints: []int64{1}}},
+		{"$m": {Strings: []string{"a"}}},
+		{"$m": {Entries: map[string]*pb.Variable{
+			"name": {Entries: map[string]*pb.Variable{"a": {Strings: []string{"b"}}}},
+		}}},
+	}
+	for _, tc := range tests {
+		typed := vars()
+		for k, v := range tc {
+			typed[k] = v
+		}
+		_, err := Parse(Request{Str: q, TypedVariables: typed})
+		require.Error(t, err, "%+v", tc)
+	}
+}
+
+func TestParsePrepared(t *testing.T) {
+	p, err := Prepare(`query test($a: string, $n: int = 10) {
+		q(func: eq(name, $a), first: $n) {
+			name
+		}
+	}`)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("name%d", i)
+			res, err := p.Parse(map[string]string{"$a": name})
+			require.NoError(t, err)
+			require.Equal(t, name, res.Query[0].Func.Args[0].Value)
+			require.Equal(t, "10", res.Query[0].Args["first"])
+		}(i)
+	}
+	wg.Wait()
+
+	_, err = p.Parse(map[string]string{"$a": "alice", "$n": "ten"})
+	require.Error(t, err)
+
+	// The variables of a run don't stay in the prepared query.
+	p, err = Prepare(`query test($id: string, $f: string) {
+		q(func: uid($id)) @filter(uid($f)) {
+			...names
+		}
+	}
+	fragment names {
+		name
+	}`)
+	require.NoError(t, err)
+	for _, uid := range []uint64{1, 2} {
+		id := fmt.Sprintf("%#x", uid)
+		res, err := p.Parse(map[string]string{"$id": id, "$f": id})
+		require.NoError(t, err)
+		require.Equal(t, []uint64{uid}, res.Query[0].UID)
+		require.Equal(t, []uint64{uid}, res.Query[0].Filter.Func.UID)
+		require.Equal(t, "name", res.Query[0].Children[0].Attr)
+	}
+
+	_, err = Prepare(`{ q(func: eq(name, "alice") { name }`)
+	require.Error(t, err)
+}
+
+func TestParseGraphQLListVarError(t *testing.T) {
+	tests := []struct {
+		q    string
+		vars map[string]string
+	}{
+		{q: `query test($a: [int]){q(func: eq(age, $a)) {name}}`,
+			vars: map[string]string{"$a": `[1, "two"]`}},
+		{q: `query test($a: [uid]){q(func: uid($a)) {name}}`,
+			vars: map[string]string{"$a": `0x1`}},
+		{q: `query test($a: [int){q(func: eq(age, $a)) {name}}`,
+			vars: map[string]string{"$a": `[1]`}},
+		{q: `query test($a: [date]){q(func: eq(age, $a)) {name}}`,
+			vars: map[string]string{"$a": `["2021"]`}},
+	}
+	for _, tc := range tests {
+		_, err := Parse(Request{Str: tc.q, Variables: tc.vars})
+		require.Error(t, err, tc.q)
+	}
+}
+
 func TestParseGraphQLVarArrayUID_IN(t *testing.T) {
 	tests := []struct {
 		q    string
@@ -5390,6 +5581,46 @@ func TestCascadeParameterized(t *testing.T) {
 	require.Equal(t, gq.Query[0].Cascade[1], "age")
 }
 
+func TestGraphDirective(t *testing.T) {
+	query := `{
+		me(func: uid(0x1)) @graph(g1, "http://example.org/g2") {
+			name
+			friend @facets(eq(close, true)) {
+				name @graph(g3)
+				~friend
+			}
+		}
+	}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	me := res.Query[0]
+	require.Equal(t, []string{"g1", "http://example.org/g2"}, me.Graphs)
+	require.Nil(t, me.FacetsFilter)
+	require.Equal(t, `(OR (eq dgraph.graph "g1") (eq dgraph.graph "http://example.org/g2"))`,
+		me.Children[0].FacetsFilter.debugString())
+
+	friend := me.Children[1]
+	require.Equal(t, `(AND (eq close "true") (OR (eq dgraph.graph "g1")`+
+		` (eq dgraph.graph "http://example.org/g2")))`, friend.FacetsFilter.debugString())
+	require.Equal(t, `(eq dgraph.graph "g3")`,
+		friend.Children[0].FacetsFilter.debugString())
+	require.Nil(t, friend.Children[1].FacetsFilter)
+}
+
+func TestBadGraphDirective(t *testing.T) {
+	badQueries := []string{
+		`{ me(func: uid(0x1)) @graph { name } }`,
+		`{ me(func: uid(0x1)) @graph() { name } }`,
+		`{ me(func: uid(0x1)) @graph(g1,) { name } }`,
+		`{ me(func: uid(0x1)) { name @graph(g1 g2) } }`,
+		`{ me(func: uid(0x1)) { name @graph(g1) @graph(g2) } }`,
+	}
+	for _, query := range badQueries {
+		_, err := Parse(Request{Str: query})
+		require.Error(t, err, query)
+	}
+}
+
 func TestBadCascadeParameterized(t *testing.T) {
 	badQueries := []string{
 		`{
@@ -5453,3 +5684,84 @@ func TestEmptyId(t *testing.T) {
 	_, err := Parse(r)
 	require.Error(t, err, "ID cannot be empty")
 }
+
+func TestParseLineage(t *testing.T) {
+	query := `{
+		me(func: uid(0x1)) {
+			name
+			lineage(name) { uid dgraph.lineage.blob }
+			src: lineage(friend) @filter(has(dgraph.lineage.blob))
+			lineage(first: 10)
+		}
+	}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	children := res.Query[0].Children
+	require.Len(t, children, 4)
+
+	require.False(t, children[0].Lineage)
+	require.True(t, children[1].Lineage)
+	require.Equal(t, "name", children[1].Attr)
+	require.Len(t, children[1].Children, 2)
+	require.True(t, children[2].Lineage)
+	require.Equal(t, "friend", children[2].Attr)
+	require.Equal(t, "src", children[2].Alias)
+	require.NotNil(t, children[2].Filter)
+	// A predicate named lineage can still be queried.
+	require.False(t, children[3].Lineage)
+	require.Equal(t, "lineage", children[3].Attr)
+	require.Equal(t, "10", children[3].Args["first"])
+}
+
+func TestParseRelevance(t *testing.T) {
+	query := `{
+		me(func: anyoftext(title, "running shoes")) {
+			s as relevance(title@en, "running shoes")
+			score: relevance(title, "shoes")
+			relevance(first: 10)
+		}
+		ranked(func: uid(s), orderdesc: val(s)) { title }
+	}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	children := res.Query[0].Children
+	require.Len(t, children, 3)
+
+	require.True(t, children[0].Func.IsRelevance())
+	require.Equal(t, "title", children[0].Attr)
+	require.Equal(t, "en", children[0].Func.Lang)
+	require.Equal(t, "running shoes", children[0].Func.Args[0].Value)
+	require.Equal(t, "s", children[0].Var)
+	require.True(t, children[1].Func.IsRelevance())
+	require.Equal(t, "score", children[1].Alias)
+	// A predicate named relevance can still be queried.
+	require.Nil(t, children[2].Func)
+	require.Equal(t, "relevance", children[2].Attr)
+	require.Equal(t, "10", children[2].Args["first"])
+
+	_, err = Parse(Request{Str: `{ me(func: uid(1)) { relevance(title) } }`})
+	require.Error(t, err)
+}
+
+func TestParseHighlight(t *testing.T) {
+	query := `{
+		me(func: anyoftext(title, "running shoes")) {
+			highlight(title, "running shoes")
+			h: highlight(title@en, "shoes", "<b>", "</b>")
+		}
+	}`
+	res, err := Parse(Request{Str: query})
+	require.NoError(t, err)
+	children := res.Query[0].Children
+	require.Len(t, children, 2)
+
+	require.True(t, children[0].Func.IsHighlight())
+	require.Equal(t, "title", children[0].Attr)
+	require.Len(t, children[0].Func.Args, 1)
+	require.Equal(t, "h", children[1].Alias)
+	require.Equal(t, "en", children[1].Func.Lang)
+	require.Equal(t, "</b>", children[1].Func.Args[2].Value)
+
+	_, err = Parse(Request{Str: `{ me(func: uid(1)) { highlight(title, "a", "<b>") } }`})
+	require.Error(t, err)
+}
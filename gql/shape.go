@@ -0,0 +1,257 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gql
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Shape returns the normalized shape of a parsed request: its blocks, predicates, functions,
+// directives and names, without the values of arguments and without whitespace. Two requests
+// that differ only in the values they pass, directly or through variables, have the same shape.
+// The depth of @recurse is kept though, as it decides how much of the graph is traversed.
+func Shape(res Result) string {
+	var b strings.Builder
+	if res.Schema != nil {
+		b.WriteString("schema(")
+		b.WriteString(strings.Join(res.Schema.Predicates, ","))
+		b.WriteString(")(")
+		b.WriteString(strings.Join(res.Schema.Types, ","))
+		b.WriteString("){")
+		b.WriteString(strings.Join(res.Schema.Fields, ","))
+		b.WriteString("}")
+	}
+	for _, gq := range res.Query {
+		writeBlockShape(&b, gq)
+	}
+	return b.String()
+}
+
+func writeBlockShape(b *strings.Builder, gq *GraphQuery) {
+	if gq.Var != "" {
+		b.WriteString(gq.Var)
+		b.WriteString(" as ")
+	}
+	if gq.Alias != "" {
+		b.WriteString(gq.Alias)
+		b.WriteString(":")
+	}
+	if gq.IsCount {
+		b.WriteString("count:")
+	}
+	b.WriteString(gq.Attr)
+	if len(gq.Langs) > 0 {
+		b.WriteString("@")
+		b.WriteString(strings.Join(gq.Langs, ":"))
+	}
+	if gq.Expand != "" {
+		b.WriteString("expand(")
+		b.WriteString(gq.Expand)
+		b.WriteString(")")
+	}
+	if gq.MathExp != nil {
+		b.WriteString("(")
+		writeMathShape(b, gq.MathExp)
+		b.WriteString(")")
+	}
+
+	var args []string
+	if gq.Func != nil {
+		args = append(args, "func:"+functionShape(gq.Func))
+	}
+	if len(gq.UID) > 0 {
+		args = append(args, "id:?")
+	}
+	for k := range gq.Args {
+		args = append(args, k+":?")
+	}
+	for _, o := range gq.Order {
+		args = append(args, "order:"+o.Attr+":"+strconv.FormatBool(o.Desc))
+	}
+	if gq.ShortestPathArgs.From != nil {
+		args = append(args, "from:"+functionShape(gq.ShortestPathArgs.From))
+	}
+	if gq.ShortestPathArgs.To != nil {
+		args = append(args, "to:"+functionShape(gq.ShortestPathArgs.To))
+	}
+	for _, v := range gq.NeedsVar {
+		args = append(args, "var:"+v.Name)
+	}
+	sort.Strings(args)
+	if len(args) > 0 {
+		b.WriteString("(")
+		b.WriteString(strings.Join(args, ","))
+		b.WriteString(")")
+	}
+
+	if gq.Filter != nil {
+		b.WriteString("@filter(")
+		writeFilterShape(b, gq.Filter)
+		b.WriteString(")")
+	}
+	if gq.Facets != nil || gq.FacetsFilter != nil || len(gq.FacetsOrder) > 0 {
+		var keys []string
+		if gq.Facets != nil {
+			for _, p := range gq.Facets.Param {
+				keys = append(keys, p.Alias+":"+p.Key)
+			}
+		}
+		for _, o := range gq.FacetsOrder {
+			keys = append(keys, "order:"+o.Key+":"+strconv.FormatBool(o.Desc))
+		}
+		for v, k := range gq.FacetVar {
+			keys = append(keys, v+" as "+k)
+		}
+		sort.Strings(keys)
+		b.WriteString("@facets(")
+		b.WriteString(strings.Join(keys, ","))
+		if gq.FacetsFilter != nil {
+			b.WriteString(";")
+			writeFilterShape(b, gq.FacetsFilter)
+		}
+		b.WriteString(")")
+	}
+	if gq.IsGroupby {
+		b.WriteString("@groupby(")
+		for i, attr := range gq.GroupbyAttrs {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(attr.Alias)
+			b.WriteString(":")
+			b.WriteString(attr.Attr)
+		}
+		b.WriteString(")")
+	}
+	if gq.Recurse {
+		b.WriteString("@recurse(")
+		var args []string
+		if gq.RecurseArgs.Depth > 0 {
+			args = append(args, "depth:"+strconv.FormatUint(gq.RecurseArgs.Depth, 10))
+		}
+		if gq.RecurseArgs.AllowLoop {
+			args = append(args, "loop")
+		}
+		b.WriteString(strings.Join(args, ","))
+		b.WriteString(")")
+	}
+	if len(gq.Cascade) > 0 {
+		b.WriteString("@cascade(")
+		b.WriteString(strings.Join(gq.Cascade, ","))
+		b.WriteString(")")
+	}
+	if gq.Normalize {
+		b.WriteString("@normalize")
+	}
+	if gq.IgnoreReflex {
+		b.WriteString("@ignorereflex")
+	}
+	if len(gq.Graphs) > 0 {
+		b.WriteString("@graph(?)")
+	}
+
+	if len(gq.Children) == 0 {
+		return
+	}
+	b.WriteString("{")
+	for i, child := range gq.Children {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		writeBlockShape(b, child)
+	}
+	b.WriteString("}")
+}
+
+// functionShape returns the shape of f. Runs of literal arguments are collapsed into a single
+// placeholder, so that a function has the same shape whatever the number of values it's given.
+func functionShape(f *Function) string {
+	var b strings.Builder
+	b.WriteString(f.Name)
+	b.WriteString("(")
+	switch {
+	case f.IsCount:
+		b.WriteString("count(" + f.Attr + ")")
+	case f.IsValueVar:
+		b.WriteString("val(" + f.Attr + ")")
+	case f.IsLenVar:
+		b.WriteString("len(" + f.Attr + ")")
+	default:
+		b.WriteString(f.Attr)
+	}
+	if f.Lang != "" {
+		b.WriteString("@" + f.Lang)
+	}
+	literal := false
+	for _, arg := range f.Args {
+		if arg.IsValueVar {
+			b.WriteString(",val(" + arg.Value + ")")
+			literal = false
+			continue
+		}
+		if !literal {
+			b.WriteString(",?")
+		}
+		literal = true
+	}
+	if len(f.UID) > 0 {
+		b.WriteString(",?")
+	}
+	for _, v := range f.NeedsVar {
+		b.WriteString("," + v.Name)
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func writeFilterShape(b *strings.Builder, f *FilterTree) {
+	if f.Func != nil {
+		b.WriteString(functionShape(f.Func))
+		return
+	}
+	b.WriteString(f.Op)
+	b.WriteString("(")
+	for i, child := range f.Child {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		writeFilterShape(b, child)
+	}
+	b.WriteString(")")
+}
+
+func writeMathShape(b *strings.Builder, m *MathTree) {
+	switch {
+	case m.Var != "":
+		b.WriteString(m.Var)
+		return
+	case m.Fn == "":
+		b.WriteString("?")
+		return
+	}
+	b.WriteString(m.Fn)
+	b.WriteString("(")
+	for i, child := range m.Child {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		writeMathShape(b, child)
+	}
+	b.WriteString(")")
+}
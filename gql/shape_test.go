@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func shapeOf(t *testing.T, query string, vars map[string]string) string {
+	res, err := Parse(Request{Str: query, Variables: vars})
+	require.NoError(t, err)
+	return Shape(res)
+}
+
+func TestShape(t *testing.T) {
+	shape := shapeOf(t, `{
+		q(func: eq(name, "alice"), first: 10) @filter(gt(age, 20) and not has(dead)) {
+			name
+			friend(orderasc: name) @facets(since) { name }
+			total: math(2 * 3)
+		}
+	}`, nil)
+
+	// Values, whitespace and the number of values don't change the shape.
+	require.Equal(t, shape, shapeOf(t, `{ q(func: eq(name, ["bob", "carol"]), first: 1)
+		@filter(gt(age, 30) and not has(dead)) { name friend(orderasc: name) @facets(since)
+		{ name } total: math(4 * 5) } }`, nil))
+	require.Equal(t, shape, shapeOf(t, `query q($name: string, $age: int) {
+		q(func: eq(name, $name), first: 10) @filter(gt(age, $age) and not has(dead)) {
+			name friend(orderasc: name) @facets(since) { name } total: math(2 * 3) } }`,
+		map[string]string{"$name": "dave", "$age": "40"}))
+
+	for _, other := range []string{
+		`{ q(func: eq(name, "alice")) @filter(gt(age, 20) and not has(dead)) {
+			name friend(orderasc: name) @facets(since) { name } total: math(2 * 3) } }`,
+		`{ q(func: eq(name, "alice"), first: 10) @filter(gt(age, 20) or not has(dead)) {
+			name friend(orderasc: name) @facets(since) { name } total: math(2 * 3) } }`,
+		`{ q(func: eq(name, "alice"), first: 10) @filter(gt(age, 20) and not has(dead)) {
+			name friend(orderdesc: name) @facets(since) { name } total: math(2 * 3) } }`,
+		`{ q(func: eq(name, "alice"), first: 10) @filter(gt(age, 20) and not has(dead)) {
+			name friend(orderasc: name) @facets(since) { name secret }
+			total: math(2 * 3) } }`,
+	} {
+		require.NotEqual(t, shape, shapeOf(t, other, nil), other)
+	}
+}
+
+func TestShapeRecurse(t *testing.T) {
+	query := `query q($depth: int) {
+		q(func: uid(0x1)) @recurse(depth: $depth, loop: true) { friend name } }`
+	shape := shapeOf(t, query, map[string]string{"$depth": "3"})
+	require.Contains(t, shape, "@recurse(depth:3,loop)")
+	require.Equal(t, shape, shapeOf(t, `{ q(func: uid(0x2)) @recurse(depth: 3, loop: true) {
+		friend name } }`, nil))
+	require.NotEqual(t, shape, shapeOf(t, query, map[string]string{"$depth": "10"}))
+}
+
+func TestShapeSchema(t *testing.T) {
+	require.Equal(t, shapeOf(t, `schema(pred: [name, age]) { type }`, nil),
+		shapeOf(t, `schema(pred: [name,age]) {type}`, nil))
+	require.NotEqual(t, shapeOf(t, `schema(pred: [name]) { type }`, nil),
+		shapeOf(t, `schema {}`, nil))
+}
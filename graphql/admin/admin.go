@@ -47,6 +47,7 @@ const (
 	// GraphQL schema for /admin endpoint.
 	graphqlAdminSchema = `
 	scalar DateTime
+	scalar Int64
 
 	"""
 	Data about the GraphQL schema being served by Dgraph.
@@ -64,6 +65,20 @@ const (
 		This is the schema that is being served by Dgraph at /graphql.
 		"""
 		generatedSchema: String!
+
+		"""
+		The documents that the input schema is made up of. A schema uploaded as a whole
+		with updateGQLSchema is stored in the 'default' document.
+		"""
+		documents: [GQLSchemaDocument]
+	}
+
+	"""
+	A named part of the GraphQL schema which can be updated independently of the others.
+	"""
+	type GQLSchemaDocument {
+		name: String!
+		schema: String!
 	}
 
 	"""
@@ -134,6 +149,7 @@ const (
 		removed: [Member]
 		cid: String
 		license: License
+		readOnly: ReadOnlyMode
 	}
 
 	type ClusterGroup {
@@ -170,6 +186,68 @@ const (
 		maxNodes: Int
 		expiryTs: Int
 		enabled: Boolean
+		expired: Boolean
+	}
+
+	"""
+	The enterprise license of the cluster, the features it allows and how much of it is used.
+	"""
+	type LicenseStatus {
+		user: String
+
+		"""
+		Whether the enterprise features are enabled. They stay enabled for --license_grace after
+		the license expired if Zero runs with --license_expiry=warn.
+		"""
+		enabled: Boolean
+		expired: Boolean
+		expiryTs: Int
+
+		"""
+		Seconds until the license expires, negative once it has expired.
+		"""
+		expiresIn: Int
+
+		"""
+		Maximum number of Zeros and Alphas allowed by the license. Trial licenses don't have any
+		maximum, which is shown as the largest Int64.
+		"""
+		maxNodes: Int64
+
+		"""
+		Number of Zeros and Alphas in the cluster, which can't exceed maxNodes.
+		"""
+		nodes: Int
+
+		"""
+		Number of namespaces in the cluster.
+		"""
+		namespaces: Int
+		features: [Entitlement]
+	}
+
+	type Entitlement {
+		name: String
+
+		"""
+		Whether the license allows the feature.
+		"""
+		allowed: Boolean
+
+		"""
+		Whether this node is configured to use the feature.
+		"""
+		configured: Boolean
+	}
+
+	type ReadOnlyMode {
+		enabled: Boolean
+		reason: String
+
+		"""
+		Unix time at which read-only mode was enabled.
+		"""
+		since: Int
 	}
 
 	directive @dgraph(type: String, pred: String) on OBJECT | INTERFACE | FIELD_DEFINITION
@@ -200,6 +278,11 @@ const (
 		"""
 		namespace: Int
 
+		"""
+		Named graph to export, if given then only the edges of this graph are exported.
+		"""
+		graph: String
+
 		"""
 		Destination for the export: e.g. Minio or S3 bucket or /absolute/path
 		"""
@@ -236,10 +319,19 @@ const (
 		exportedFiles: [String]
 	}
 
+	type DropGraphPayload {
+		response: Response
+		numEdges: Int
+	}
+
 	type DrainingPayload {
 		response: Response
 	}
 
+	type ReadOnlyPayload {
+		response: Response
+	}
+
 	type ShutdownPayload {
 		response: Response
 	}
@@ -267,6 +359,592 @@ const (
 		cacheMb: Float
 	}
 
+	"""
+	A query running on this node, along with the memory it has used so far.
+	"""
+	type RunningQuery {
+		id: Int!
+		namespace: Int!
+		query: String
+		startedAt: DateTime
+		durationMs: Int
+
+		"""
+		Bytes used by the query. It is the sum of taskBytes and encodedBytes, and is what the
+		query_memory_mb limit is enforced against.
+		"""
+		memoryBytes: Int
+
+		"""
+		Bytes of posting lists materialized for the query.
+		"""
+		taskBytes: Int
+
+		"""
+		Bytes of the response built for the query so far.
+		"""
+		encodedBytes: Int
+	}
+
+	"""
+	A long-running operation, like an export, a restore, an index rebuild or a tablet move.
+	Tasks are kept by Zero, so every node of the cluster sees all of them.
+	"""
+	type Task {
+		"""
+		Id of the task, in hex.
+		"""
+		id: String!
+		kind: String
+		description: String
+
+		"""
+		Group of the Alpha running the task. It is 0 for the tasks run by Zero.
+		"""
+		groupId: Int
+		owner: String
+
+		"""
+		One of QUEUED, RUNNING, PAUSED, SUCCEEDED, FAILED or CANCELLED.
+		"""
+		state: String
+
+		"""
+		Fraction of the work done, from 0 to 1.
+		"""
+		progress: Float
+		message: String
+		startedAt: DateTime
+		updatedAt: DateTime
+		pausable: Boolean
+		cancellable: Boolean
+		pauseRequested: Boolean
+		cancelRequested: Boolean
+	}
+
+	type TaskPayload {
+		response: Response
+		task: Task
+	}
+
+	"""
+	Keys of this node which received a disproportionate share of the reads or writes during
+	the last window of the --hot_keys flag.
+	"""
+	type HotKeys {
+		"""
+		When the last window ended. It's null if hot keys aren't tracked, or if no window
+		ended yet.
+		"""
+		windowEnd: DateTime
+		keys: [HotKey]
+	}
+
+	type HotKey {
+		predicate: String
+		namespace: Int
+
+		"""
+		The kind of key, i.e. data, index, reverse or count.
+		"""
+		keyType: String
+
+		"""
+		The UID of the data and reverse keys.
+		"""
+		uid: String
+
+		"""
+		The key, hex encoded.
+		"""
+		key: String
+
+		"""
+		Estimated numbers of reads and writes of the key during the window.
+		"""
+		reads: Int
+		writes: Int
+
+		"""
+		Percentages of the reads and of the writes of this node which went to the key.
+		"""
+		readShare: Float
+		writeShare: Float
+	}
+
+	"""
+	A posting list of this node which failed to be unmarshalled. The queries and mutations
+	using it fail with a DATA_CORRUPTED error until it's purged.
+	"""
+	type QuarantinedKey {
+		predicate: String
+		namespace: Int
+
+		"""
+		The key, hex encoded.
+		"""
+		key: String
+
+		"""
+		The key of the corrupted part of the list, hex encoded, if the list is split.
+		"""
+		part: String
+
+		"""
+		The version of the corrupted value, and the error unmarshalling it.
+		"""
+		version: Int
+		error: String
+		since: DateTime
+
+		"""
+		The file on this node holding the raw bytes of the corrupted value.
+		"""
+		dumpFile: String
+	}
+
+	type PurgeQuarantinedKeysPayload {
+		response: Response
+
+		"""
+		The keys purged, hex encoded.
+		"""
+		keys: [String]
+	}
+
+	"""
+	The verification of the index of a predicate served by this node against its data, see the
+	--index_verify flag. The counts are since this node started.
+	"""
+	type IndexVerification {
+		predicate: String
+		namespace: Int
+
+		"""
+		Numbers of data keys and of index entries checked.
+		"""
+		dataKeys: Int
+		indexEntries: Int
+
+		"""
+		Numbers of index entries found missing, i.e. of values without their entries, and
+		dangling, i.e. of entries without the values they index.
+		"""
+		missing: Int
+		dangling: Int
+		repaired: Int
+		lastRun: DateTime
+
+		"""
+		The last divergences found.
+		"""
+		recent: [IndexDivergence]
+	}
+
+	type IndexDivergence {
+		"""
+		The index key, hex encoded.
+		"""
+		key: String
+		uid: String
+
+		"""
+		True if the entry was missing from the index, false if it was dangling.
+		"""
+		missing: Boolean
+	}
+
+	"""
+	Disk usage of this node, along with a forecast of when the limits set via the --disk
+	flag will be reached.
+	"""
+	type DiskUsage {
+		"""
+		Bytes used by the postings and WAL directories.
+		"""
+		size: Int
+
+		"""
+		Total and available bytes on the file system holding the postings directory.
+		"""
+		fsTotal: Int
+		fsFree: Int
+
+		"""
+		Bytes that can be written before a disk limit is reached.
+		"""
+		headroom: Int
+
+		"""
+		Growth rate in bytes per day, over the forecasting window.
+		"""
+		growthPerDay: Float
+
+		"""
+		Projected number of days until a disk limit is reached. It is -1 if the usage
+		isn't growing.
+		"""
+		daysUntilFull: Float
+
+		"""
+		Whether writes are being refused because a disk limit has been reached.
+		"""
+		writesBlocked: Boolean
+		lastCompaction: DateTime
+		tablets: [TabletUsage]
+	}
+
+	type TabletUsage {
+		predicate: String
+		bytes: Int
+		growthPerDay: Float
+	}
+
+	input StorageInput {
+		"""
+		Run value log GC now, rewriting the value log files which have at least
+		discardRatio of their space reclaimable. discardRatio defaults to 0.5.
+		"""
+		runVlogGC: Boolean
+		discardRatio: Float
+
+		"""
+		Pause (or resume) value log GC. A paused GC can't be run via runVlogGC either, and a
+		running one stops once the file it's rewriting is done.
+		"""
+		pauseVlogGC: Boolean
+
+		"""
+		Compact all the levels of the LSM tree into one, using flattenWorkers
+		goroutines. flattenWorkers defaults to 1.
+		"""
+		flatten: Boolean
+		flattenWorkers: Int
+
+		"""
+		Wait this many milliseconds after every batch of background rollups. Rollups
+		drive most of the compactions, so a larger delay throttles compaction I/O.
+		"""
+		rollupDelayMs: Int
+
+		"""
+		Rewrite the multi-part posting lists with at least minSplitParts parts, splitting them
+		again from scratch so that their parts are balanced. minSplitParts defaults to 2.
+		"""
+		rewriteSplitLists: Boolean
+		minSplitParts: Int
+	}
+
+	type StoragePayload {
+		response: Response
+	}
+
+	input SnapshotInput {
+		"""
+		Directory of this node to copy the postings and WAL directories to, as its p and w
+		subdirectories. It must not exist or be empty.
+		"""
+		destination: String!
+	}
+
+	"""
+	A crash consistent copy of the postings and WAL directories of this node. An Alpha started
+	from it joins the same group as a new member.
+	"""
+	type LocalSnapshot {
+		destination: String
+		groupId: Int
+
+		"""
+		The Raft index and read timestamp of the Raft snapshot of the copied WAL.
+		"""
+		raftIndex: Int
+		readTs: Int
+
+		"""
+		Number of immutable files which were hard linked instead of copied.
+		"""
+		linkedFiles: Int
+		copiedFiles: Int
+		bytesCopied: Int
+		tookMs: Int
+	}
+
+	type SnapshotPayload {
+		response: Response
+		snapshot: LocalSnapshot
+	}
+
+	"""
+	State of the postings directory of this node and of the jobs which reclaim space in it.
+	"""
+	type StorageStatus {
+		lsmSize: Int
+		vlogSize: Int
+
+		"""
+		Bytes of stale data in the LSM tree which can be reclaimed by compactions.
+		"""
+		lsmStaleBytes: Int
+		levels: [LevelStatus]
+		vlogGCPaused: Boolean
+		vlogGCRunning: Boolean
+		lastVlogGC: DateTime
+
+		"""
+		Number of value log files rewritten by the last value log GC run via the storage mutation.
+		"""
+		lastVlogGCRewrites: Int
+		flattenRunning: Boolean
+		lastFlatten: DateTime
+		rollupDelayMs: Int
+		rewriteSplitListsRunning: Boolean
+		lastRewriteSplitLists: DateTime
+
+		"""
+		Number of multi-part posting lists rewritten by the last rewrite run via the storage
+		mutation.
+		"""
+		lastRewrittenSplitLists: Int
+
+		"""
+		Predicates served by this node whose data is in object storage.
+		"""
+		coldTablets: [ColdTablet]
+	}
+
+	type ColdTablet {
+		predicate: String
+		namespace: Int
+		object: String
+		size: Int
+		demotedAt: DateTime
+
+		"""
+		Whether this node holds a local copy of the data.
+		"""
+		cached: Boolean
+
+		"""
+		Whether the predicate is archived, i.e. isn't served until it's attached.
+		"""
+		archived: Boolean
+	}
+
+	input TabletTierInput {
+		predicate: String!
+
+		"""
+		The namespace of the predicate. It defaults to the galaxy namespace.
+		"""
+		namespace: Int
+	}
+
+	type TabletTierPayload {
+		response: Response
+	}
+
+	type LevelStatus {
+		level: Int
+		numTables: Int
+		size: Int
+		targetSize: Int
+		staleBytes: Int
+		score: Float
+	}
+
+	"""
+	A deployed version of the lambda script of the namespace.
+	"""
+	type LambdaScript {
+		version: Int
+
+		"""
+		The JavaScript, or the base64 encoded WASM module, depending on the runtime.
+		"""
+		script: String
+		runtime: LambdaRuntime
+		deployedAt: DateTime
+
+		"""
+		Whether this version serves the @lambda fields.
+		"""
+		active: Boolean
+	}
+
+	"""
+	JS scripts are run by the lambda server. WASM scripts are modules compiled for WASI, which
+	are run by Alpha with the request on stdin and the result on stdout.
+	"""
+	enum LambdaRuntime {
+		JS
+		WASM
+	}
+
+	input UpdateLambdaScriptInput {
+		script: String!
+
+		"""
+		The runtime of the script. It defaults to JS.
+		"""
+		runtime: LambdaRuntime
+	}
+
+	"""
+	A version of the DQL or the GraphQL schema of the namespace, recorded when the schema
+	was changed.
+	"""
+	type SchemaVersion {
+		"""
+		The start timestamp of the change.
+		"""
+		version: Int
+
+		"""
+		Either DQL or GraphQL.
+		"""
+		kind: String
+
+		"""
+		The whole schema after the change.
+		"""
+		schema: String
+
+		"""
+		The lines removed from and added to the schema by the change, prefixed with - and +.
+		"""
+		diff: String
+		author: String
+		at: DateTime
+
+		"""
+		The version this change rolled the schema back to, if it was a rollback.
+		"""
+		rollbackOf: Int
+	}
+
+	type RollbackSchemaPayload {
+		response: Response
+		version: SchemaVersion
+
+		"""
+		Predicates created after the version, which are kept along with their data.
+		"""
+		keptPredicates: [String]
+
+		"""
+		Types created after the version, which were dropped.
+		"""
+		droppedTypes: [String]
+		taskId: String
+	}
+
+	input EraseSubjectInput {
+		"""
+		The uid of the node of the data subject.
+		"""
+		subject: String!
+
+		"""
+		The namespace of the subject, the one of the request if no value is given. Only
+		guardians of the galaxy can give another namespace.
+		"""
+		namespace: Int
+
+		"""
+		The uid predicates, or the reverse ones with a ~ prefix, leading from a node to the
+		nodes erased along with it. The ones of the --erasure flag are followed if no value
+		is given.
+		"""
+		follow: [String!]
+
+		"""
+		How many edges away from the subject the followed nodes may be. The depth of the
+		--erasure flag is used if no value is given.
+		"""
+		depth: Int
+	}
+
+	"""
+	The report of an erasure, which lists the deleted nodes along with their predicates, and
+	the deleted edges which led to them. It holds none of the deleted values.
+	"""
+	type ErasureReport {
+		"""
+		The JSON encoded report.
+		"""
+		report: String
+
+		"""
+		The base64 encoded Ed25519 signature of the report.
+		"""
+		signature: String
+	}
+
+	type EraseSubjectPayload {
+		response: Response
+		report: String
+		signature: String
+		taskId: String
+	}
+
+	"""
+	The dictionary of the fulltext index of a predicate. The words of a group of synonyms are
+	indexed and searched as the first word of the group, so that searching for any of them
+	matches the values containing the others. The protected words are neither dropped as stop
+	words nor stemmed.
+	"""
+	type FulltextDictionary {
+		predicate: String!
+		synonyms: [SynonymGroup!]
+		protectedWords: [String!]
+	}
+
+	type SynonymGroup {
+		words: [String!]!
+	}
+
+	input SynonymGroupInput {
+		words: [String!]!
+	}
+
+	input FulltextDictionaryInput {
+		"""
+		The predicate, which must have a fulltext index.
+		"""
+		predicate: String!
+
+		"""
+		The groups of synonyms. Every word must be a single term, every group must have at
+		least two words, and a word can't be in more than one group.
+		"""
+		synonyms: [SynonymGroupInput!]
+
+		"""
+		The words which are neither dropped as stop words nor stemmed.
+		"""
+		protectedWords: [String!]
+	}
+
+	type UpdateFulltextDictionaryPayload {
+		response: Response
+		dictionary: FulltextDictionary
+	}
+
+	type LambdaScriptPayload {
+		lambdaScript: LambdaScript
+	}
+
+	"""
+	A lambda server run by this node, see the --graphql_lambda_num flag.
+	"""
+	type LambdaServer {
+		url: String
+		healthy: Boolean
+		restarts: Int
+		lastError: String
+	}
+
 	` + adminTypes + `
 
 	type Query {
@@ -274,6 +952,43 @@ const (
 		health: [NodeState]
 		state: MembershipState
 		config: Config
+		diskUsage: DiskUsage
+		storage: StorageStatus
+		hotKeys: HotKeys
+		license: LicenseStatus
+		quarantinedKeys: [QuarantinedKey]
+		indexVerification: [IndexVerification]
+		runningQueries: [RunningQuery]
+		tasks: [Task]
+
+		"""
+		Get the given version of the lambda script of the namespace, or the active one if no
+		version is given.
+		"""
+		getLambdaScript(version: Int): LambdaScript
+
+		"""
+		List the versions of the lambda script of the namespace which are kept.
+		"""
+		lambdaScripts: [LambdaScript]
+		lambdaServers: [LambdaServer]
+
+		"""
+		List the recorded changes of the DQL and the GraphQL schema of the namespace, oldest
+		first. Only the last 100 changes are kept.
+		"""
+		schemaHistory: [SchemaVersion]
+
+		"""
+		List the signed reports of the erasures of the namespace, or of all the namespaces for
+		guardians of the galaxy.
+		"""
+		erasureReports: [ErasureReport]
+
+		"""
+		Get the dictionary of the fulltext index of the predicate.
+		"""
+		fulltextDictionary(predicate: String!): FulltextDictionary
 		` + adminQueries + `
 	}
 
@@ -285,6 +1000,18 @@ const (
 		"""
 		updateGQLSchema(input: UpdateGQLSchemaInput!) : UpdateGQLSchemaPayload
 
+		"""
+		Add or replace the document with the given name in the GraphQL schema. The document is
+		merged with the other documents, and the update fails if it defines a type that another
+		document defines too.
+		"""
+		updateGQLSchemaDocument(name: String!, schema: String!): UpdateGQLSchemaPayload
+
+		"""
+		Remove the document with the given name from the GraphQL schema.
+		"""
+		deleteGQLSchemaDocument(name: String!): UpdateGQLSchemaPayload
+
 		"""
 		Starts an export of all data in the cluster.  Export format should be 'rdf' (the default
 		if no format is given), or 'json'.
@@ -292,11 +1019,24 @@ const (
 		"""
 		export(input: ExportInput!): ExportPayload
 
+		"""
+		Delete the edges of the named graph, i.e. the triples loaded with the given graph label,
+		from all the predicates of the namespace. The edges are deleted in batches, so a failed
+		drop can be retried.
+		"""
+		dropGraph(graph: String!): DropGraphPayload
+
 		"""
 		Set (or unset) the cluster draining mode.  In draining mode no further requests are served.
 		"""
 		draining(enable: Boolean): DrainingPayload
 
+		"""
+		Set (or unset) read-only mode for the whole cluster. In read-only mode mutations, schema
+		changes and commits are rejected, while queries continue to be served.
+		"""
+		readOnly(enable: Boolean!, reason: String): ReadOnlyPayload
+
 		"""
 		Shutdown this node.
 		"""
@@ -307,6 +1047,92 @@ const (
 		"""
 		config(input: ConfigInput!): ConfigPayload
 
+		"""
+		Control value log GC, flattening, compaction throttling and the rewrite of the multi-part
+		posting lists of the postings directory of this node.
+		"""
+		storage(input: StorageInput!): StoragePayload
+
+		"""
+		Copy the postings and WAL directories of this node to a local directory, hard linking the
+		immutable files. Moving the copy to a new Alpha lets it join the group without streaming
+		all the data.
+		"""
+		snapshot(input: SnapshotInput!): SnapshotPayload
+
+		"""
+		Delete the data of quarantined posting lists from this node, and take them out of
+		quarantine. They read as empty until they're written again. The other replicas of the
+		group aren't changed. All the quarantined keys are purged if no keys are given.
+		"""
+		purgeQuarantinedKeys(keys: [String!]): PurgeQuarantinedKeysPayload
+
+		"""
+		Move the data of the predicate to object storage, see the --tiered_storage flag. The
+		predicate stays queryable, but is read-only until it's promoted back.
+		"""
+		demoteTablet(input: TabletTierInput!): TabletTierPayload
+
+		"""
+		Move the data of the predicate back from object storage to the Alphas.
+		"""
+		promoteTablet(input: TabletTierInput!): TabletTierPayload
+
+		"""
+		Move the data of the predicate to object storage, and stop serving it. The queries and
+		mutations using the predicate fail with a PREDICATE_ARCHIVED error until it's attached.
+		"""
+		archiveTablet(input: TabletTierInput!): TabletTierPayload
+
+		"""
+		Move the data of the archived predicate back from object storage to the Alphas, and serve
+		it again.
+		"""
+		attachTablet(input: TabletTierInput!): TabletTierPayload
+
+		"""
+		Pause, resume or cancel a task. Only the tasks which are pausable or cancellable can be
+		controlled. The node running the task acts on the request within a few seconds.
+		"""
+		pauseTask(id: String!): TaskPayload
+		resumeTask(id: String!): TaskPayload
+		cancelTask(id: String!): TaskPayload
+
+		"""
+		Deploy a new version of the lambda script of the namespace. The new version serves the
+		@lambda fields of the namespace from then on.
+		"""
+		updateLambdaScript(input: UpdateLambdaScriptInput!): LambdaScriptPayload
+
+		"""
+		Make a previously deployed version of the lambda script serve the @lambda fields again.
+		"""
+		activateLambdaScript(version: Int!): LambdaScriptPayload
+
+		"""
+		Roll the schema of the namespace back to the given version of schemaHistory. Types
+		created since the version are dropped, but predicates are kept along with their data.
+		The rollback is run as a task and recorded as a new version.
+		"""
+		rollbackSchema(version: Int!): RollbackSchemaPayload
+
+		"""
+		Erase the node of a data subject, the nodes reached from it through the followed
+		predicates, and the edges leading to all of them from other nodes. The erasure is run as
+		a task, and its report is signed with the key of the --erasure flag, or else the
+		backup signing key. The report is kept by Zero, and the erasures are applied again after a
+		restore, so that restoring a backup doesn't bring the erased data back.
+		"""
+		eraseSubject(input: EraseSubjectInput!): EraseSubjectPayload
+
+		"""
+		Replace the dictionary of the fulltext index of a predicate. The dictionary is used both
+		to index the values and to tokenize the arguments of anyoftext and alloftext, and the
+		index is rebuilt with it. It's kept as the schema of the predicate is altered, as long as
+		the predicate has a fulltext index.
+		"""
+		updateFulltextDictionary(input: FulltextDictionaryInput!): UpdateFulltextDictionaryPayload
+
 		` + adminMutations + `
 	}
  `
@@ -342,11 +1168,27 @@ var (
 		resolve.LoggingMWMutation,
 	}
 	adminQueryMWConfig = map[string]resolve.QueryMiddlewares{
-		"health":       {resolve.IpWhitelistingMW4Query, resolve.LoggingMWQuery}, // dgraph checks Guardian auth for health
-		"state":        {resolve.IpWhitelistingMW4Query, resolve.LoggingMWQuery}, // dgraph checks Guardian auth for state
-		"config":       commonAdminQueryMWs,
-		"listBackups":  guardianOfTheGalaxyQueryMWs,
-		"getGQLSchema": commonAdminQueryMWs,
+		"health":             {resolve.IpWhitelistingMW4Query, resolve.LoggingMWQuery}, // dgraph checks Guardian auth for health
+		"state":              {resolve.IpWhitelistingMW4Query, resolve.LoggingMWQuery}, // dgraph checks Guardian auth for state
+		"config":             commonAdminQueryMWs,
+		"diskUsage":          guardianOfTheGalaxyQueryMWs,
+		"listBackups":        guardianOfTheGalaxyQueryMWs,
+		"reEncryptStatus":    guardianOfTheGalaxyQueryMWs,
+		"storage":            guardianOfTheGalaxyQueryMWs,
+		"hotKeys":            guardianOfTheGalaxyQueryMWs,
+		"license":            guardianOfTheGalaxyQueryMWs,
+		"quarantinedKeys":    guardianOfTheGalaxyQueryMWs,
+		"indexVerification":  guardianOfTheGalaxyQueryMWs,
+		"runningQueries":     guardianOfTheGalaxyQueryMWs,
+		"tasks":              guardianOfTheGalaxyQueryMWs,
+		"getGQLSchema":       commonAdminQueryMWs,
+		"getLambdaScript":    commonAdminQueryMWs,
+		"lambdaScripts":      commonAdminQueryMWs,
+		"lambdaServers":      guardianOfTheGalaxyQueryMWs,
+		"schemaHistory":      commonAdminQueryMWs,
+		"erasureReports":     commonAdminQueryMWs,
+		"fulltextDictionary": commonAdminQueryMWs,
+		"searchConnectors":   commonAdminQueryMWs,
 		// for queries and mutations related to User/Group, dgraph handles Guardian auth,
 		// so no need to apply GuardianAuth Middleware
 		"queryUser":      {resolve.IpWhitelistingMW4Query, resolve.LoggingMWQuery},
@@ -356,17 +1198,42 @@ var (
 		"getGroup":       {resolve.IpWhitelistingMW4Query, resolve.LoggingMWQuery},
 	}
 	adminMutationMWConfig = map[string]resolve.MutationMiddlewares{
-		"backup":          guardianOfTheGalaxyMutationMWs,
-		"config":          guardianOfTheGalaxyMutationMWs,
-		"draining":        guardianOfTheGalaxyMutationMWs,
-		"export":          commonAdminMutationMWs, // dgraph handles the export for other namespaces by guardian of galaxy
-		"login":           {resolve.IpWhitelistingMW4Mutation, resolve.LoggingMWMutation},
-		"restore":         guardianOfTheGalaxyMutationMWs,
-		"shutdown":        guardianOfTheGalaxyMutationMWs,
-		"updateGQLSchema": commonAdminMutationMWs,
-		"addNamespace":    guardianOfTheGalaxyMutationMWs,
-		"deleteNamespace": guardianOfTheGalaxyMutationMWs,
-		"resetPassword":   guardianOfTheGalaxyMutationMWs,
+		"backup":                   guardianOfTheGalaxyMutationMWs,
+		"config":                   guardianOfTheGalaxyMutationMWs,
+		"draining":                 guardianOfTheGalaxyMutationMWs,
+		"readOnly":                 guardianOfTheGalaxyMutationMWs,
+		"export":                   commonAdminMutationMWs, // dgraph handles the export for other namespaces by guardian of galaxy
+		"dropGraph":                commonAdminMutationMWs,
+		"login":                    {resolve.IpWhitelistingMW4Mutation, resolve.LoggingMWMutation},
+		"restore":                  guardianOfTheGalaxyMutationMWs,
+		"shutdown":                 guardianOfTheGalaxyMutationMWs,
+		"updateGQLSchema":          commonAdminMutationMWs,
+		"updateGQLSchemaDocument":  commonAdminMutationMWs,
+		"deleteGQLSchemaDocument":  commonAdminMutationMWs,
+		"addNamespace":             guardianOfTheGalaxyMutationMWs,
+		"deleteNamespace":          guardianOfTheGalaxyMutationMWs,
+		"cloneNamespace":           guardianOfTheGalaxyMutationMWs,
+		"resetPassword":            guardianOfTheGalaxyMutationMWs,
+		"reEncrypt":                guardianOfTheGalaxyMutationMWs,
+		"cancelReEncrypt":          guardianOfTheGalaxyMutationMWs,
+		"storage":                  guardianOfTheGalaxyMutationMWs,
+		"snapshot":                 guardianOfTheGalaxyMutationMWs,
+		"purgeQuarantinedKeys":     guardianOfTheGalaxyMutationMWs,
+		"demoteTablet":             guardianOfTheGalaxyMutationMWs,
+		"promoteTablet":            guardianOfTheGalaxyMutationMWs,
+		"archiveTablet":            guardianOfTheGalaxyMutationMWs,
+		"attachTablet":             guardianOfTheGalaxyMutationMWs,
+		"pauseTask":                guardianOfTheGalaxyMutationMWs,
+		"resumeTask":               guardianOfTheGalaxyMutationMWs,
+		"cancelTask":               guardianOfTheGalaxyMutationMWs,
+		"updateLambdaScript":       commonAdminMutationMWs,
+		"activateLambdaScript":     commonAdminMutationMWs,
+		"rollbackSchema":           commonAdminMutationMWs,
+		"eraseSubject":             commonAdminMutationMWs,
+		"updateFulltextDictionary": commonAdminMutationMWs,
+		"updateSearchConnector":    commonAdminMutationMWs,
+		"removeSearchConnector":    commonAdminMutationMWs,
+		"backfillSearchConnector":  commonAdminMutationMWs,
 		// for queries and mutations related to User/Group, dgraph handles Guardian auth,
 		// so no need to apply GuardianAuth Middleware
 		"addUser":     {resolve.IpWhitelistingMW4Mutation, resolve.LoggingMWMutation},
@@ -581,16 +1448,39 @@ func newAdminResolver(
 func newAdminResolverFactory() resolve.ResolverFactory {
 
 	adminMutationResolvers := map[string]resolve.MutationResolverFunc{
-		"addNamespace":    resolveAddNamespace,
-		"backup":          resolveBackup,
-		"config":          resolveUpdateConfig,
-		"deleteNamespace": resolveDeleteNamespace,
-		"draining":        resolveDraining,
-		"export":          resolveExport,
-		"login":           resolveLogin,
-		"resetPassword":   resolveResetPassword,
-		"restore":         resolveRestore,
-		"shutdown":        resolveShutdown,
+		"activateLambdaScript":     resolveActivateLambdaScript,
+		"addNamespace":             resolveAddNamespace,
+		"archiveTablet":            resolveTabletTier(pb.TierTabletRequest_ARCHIVE),
+		"attachTablet":             resolveTabletTier(pb.TierTabletRequest_ATTACH),
+		"backfillSearchConnector":  resolveBackfillSearchConnector,
+		"backup":                   resolveBackup,
+		"cancelReEncrypt":          resolveCancelReEncrypt,
+		"cancelTask":               resolveControlTask(pb.TaskControl_CANCEL),
+		"config":                   resolveUpdateConfig,
+		"deleteNamespace":          resolveDeleteNamespace,
+		"cloneNamespace":           resolveCloneNamespace,
+		"demoteTablet":             resolveTabletTier(pb.TierTabletRequest_DEMOTE),
+		"draining":                 resolveDraining,
+		"dropGraph":                resolveDropGraph,
+		"eraseSubject":             resolveEraseSubject,
+		"export":                   resolveExport,
+		"login":                    resolveLogin,
+		"pauseTask":                resolveControlTask(pb.TaskControl_PAUSE),
+		"promoteTablet":            resolveTabletTier(pb.TierTabletRequest_PROMOTE),
+		"purgeQuarantinedKeys":     resolvePurgeQuarantinedKeys,
+		"readOnly":                 resolveReadOnly,
+		"reEncrypt":                resolveReEncrypt,
+		"removeSearchConnector":    resolveRemoveSearchConnector,
+		"resetPassword":            resolveResetPassword,
+		"restore":                  resolveRestore,
+		"resumeTask":               resolveControlTask(pb.TaskControl_RESUME),
+		"rollbackSchema":           resolveRollbackSchema,
+		"shutdown":                 resolveShutdown,
+		"snapshot":                 resolveSnapshot,
+		"storage":                  resolveStorage,
+		"updateFulltextDictionary": resolveUpdateFulltextDictionary,
+		"updateLambdaScript":       resolveUpdateLambdaScript,
+		"updateSearchConnector":    resolveUpdateSearchConnector,
 	}
 
 	rf := resolverFactoryWithErrorMsg(errResolverNotFound).
@@ -605,16 +1495,60 @@ func newAdminResolverFactory() resolve.ResolverFactory {
 		WithQueryResolver("config", func(q schema.Query) resolve.QueryResolver {
 			return resolve.QueryResolverFunc(resolveGetConfig)
 		}).
+		WithQueryResolver("diskUsage", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveDiskUsage)
+		}).
+		WithQueryResolver("storage", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveStorageStatus)
+		}).
+		WithQueryResolver("hotKeys", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveHotKeys)
+		}).
+		WithQueryResolver("license", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveLicense)
+		}).
+		WithQueryResolver("quarantinedKeys", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveQuarantinedKeys)
+		}).
+		WithQueryResolver("indexVerification", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveIndexVerification)
+		}).
+		WithQueryResolver("runningQueries", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveRunningQueries)
+		}).
+		WithQueryResolver("tasks", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveTasks)
+		}).
 		WithQueryResolver("listBackups", func(q schema.Query) resolve.QueryResolver {
 			return resolve.QueryResolverFunc(resolveListBackups)
 		}).
-		WithMutationResolver("updateGQLSchema", func(m schema.Mutation) resolve.MutationResolver {
-			return resolve.MutationResolverFunc(
-				func(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
-					return &resolve.Resolved{Err: errors.Errorf(errMsgServerNotReady), Field: m},
-						false
-				})
+		WithQueryResolver("reEncryptStatus", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveReEncryptStatus)
+		}).
+		WithQueryResolver("getLambdaScript", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveGetLambdaScript)
 		}).
+		WithQueryResolver("lambdaScripts", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveLambdaScripts)
+		}).
+		WithQueryResolver("lambdaServers", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveLambdaServers)
+		}).
+		WithQueryResolver("schemaHistory", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveSchemaHistory)
+		}).
+		WithQueryResolver("erasureReports", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveErasureReports)
+		}).
+		WithQueryResolver("fulltextDictionary", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveFulltextDictionary)
+		}).
+		WithQueryResolver("searchConnectors", func(q schema.Query) resolve.QueryResolver {
+			return resolve.QueryResolverFunc(resolveSearchConnectors)
+		}).
+		WithMutationResolver("updateGQLSchema", notReadyMutationResolver).
+		WithMutationResolver("updateGQLSchemaDocument", notReadyMutationResolver).
+		WithMutationResolver("deleteGQLSchemaDocument", notReadyMutationResolver).
 		WithQueryResolver("getGQLSchema", func(q schema.Query) resolve.QueryResolver {
 			return resolve.QueryResolverFunc(
 				func(ctx context.Context, query schema.Query) *resolve.Resolved {
@@ -634,6 +1568,16 @@ func newAdminResolverFactory() resolve.ResolverFactory {
 	return rf.WithSchemaIntrospection()
 }
 
+// notReadyMutationResolver errors for the mutations which need the current GraphQL schema, until
+// the server has read it.
+func notReadyMutationResolver(m schema.Mutation) resolve.MutationResolver {
+	return resolve.MutationResolverFunc(
+		func(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+			return &resolve.Resolved{Err: errors.Errorf(errMsgServerNotReady), Field: m},
+				false
+		})
+}
+
 func getCurrentGraphQLSchema(namespace uint64) (*gqlSchema, error) {
 	uid, graphQLSchema, err := edgraph.GetGQLSchema(namespace)
 	if err != nil {
@@ -717,6 +1661,14 @@ func (as *adminServer) addConnectedAdminResolvers() {
 		func(m schema.Mutation) resolve.MutationResolver {
 			return &updateSchemaResolver{admin: as}
 		}).
+		WithMutationResolver("updateGQLSchemaDocument",
+			func(m schema.Mutation) resolve.MutationResolver {
+				return &updateSchemaDocumentResolver{admin: as}
+			}).
+		WithMutationResolver("deleteGQLSchemaDocument",
+			func(m schema.Mutation) resolve.MutationResolver {
+				return &updateSchemaDocumentResolver{admin: as, delete: true}
+			}).
 		WithQueryResolver("getGQLSchema",
 			func(q schema.Query) resolve.QueryResolver {
 				return &getSchemaResolver{admin: as}
@@ -43,7 +43,7 @@ func (gsr *currentUserResolver) Rewrite(ctx context.Context,
 
 	name, err := extractName(ctx)
 	if err != nil {
-		return nil, err
+		return nil, schema.WithErrorCode(err, schema.CodeUnauthenticated)
 	}
 
 	gqlQuery.Rename("getUser")
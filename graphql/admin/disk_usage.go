@@ -0,0 +1,71 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func resolveDiskUsage(ctx context.Context, q schema.Query) *resolve.Resolved {
+	usage := worker.GetDiskUsage()
+	if usage == nil {
+		return resolve.DataResult(q, map[string]interface{}{q.Name(): nil}, nil)
+	}
+
+	int64Num := func(i int64) json.Number { return json.Number(strconv.FormatInt(i, 10)) }
+	floatNum := func(f float64) json.Number {
+		return json.Number(strconv.FormatFloat(f, 'f', 2, 64))
+	}
+
+	tablets := make([]interface{}, 0, len(usage.Tablets))
+	for _, t := range usage.Tablets {
+		_, attr := x.ParseNamespaceAttr(t.Predicate)
+		tablets = append(tablets, map[string]interface{}{
+			"predicate":    attr,
+			"bytes":        int64Num(t.Bytes),
+			"growthPerDay": floatNum(t.GrowthPerDay),
+		})
+	}
+	var lastCompaction interface{}
+	if !usage.LastCompaction.IsZero() {
+		lastCompaction = usage.LastCompaction.Format(time.RFC3339)
+	}
+
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): map[string]interface{}{
+			"size":           int64Num(usage.Size),
+			"fsTotal":        int64Num(usage.FsTotal),
+			"fsFree":         int64Num(usage.FsFree),
+			"headroom":       int64Num(usage.Headroom),
+			"growthPerDay":   floatNum(usage.GrowthPerDay),
+			"daysUntilFull":  floatNum(usage.DaysUntilFull),
+			"writesBlocked":  usage.WritesBlocked,
+			"lastCompaction": lastCompaction,
+			"tablets":        tablets,
+		}},
+		nil,
+	)
+}
@@ -113,6 +113,24 @@ const adminTypes = `
 		"""
 		vaultFormat: String
 
+		"""
+		Path to the PEM file of the private key needed to decrypt a backup encrypted with a
+		public key. This file should be accessible by all alphas in the group.
+		"""
+		privateKeyFile: String
+
+		"""
+		Vault kv store field whose value is the PEM private key needed to decrypt a backup
+		encrypted with a public key. It's read with the other Vault options.
+		"""
+		vaultPrivateKeyField: String
+
+		"""
+		Path to the PEM file of the Ed25519 public key to verify the signatures of the backup
+		manifests and the checksums of the backup files with, before restoring any of them.
+		"""
+		verifyKeyFile: String
+
 		"""
 		Access key credential for the destination.
 		"""
@@ -422,6 +440,13 @@ const adminTypes = `
 		namespaceId: Int!
 	}
 
+	input CloneNamespaceInput {
+		"""
+		The namespace to clone.
+		"""
+		namespaceId: Int!
+	}
+
 	type NamespacePayload {
 		namespaceId: Int
 		message: String
@@ -438,6 +463,126 @@ const adminTypes = `
 		message: String
 		namespace: Int
 	}
+
+	input ReEncryptInput {
+		"""
+		Path to the key file that the data should be encrypted with. Leave this and the Vault
+		options empty to decrypt the data.
+		"""
+		encryptionKeyFile: String
+
+		"""
+		Vault server address where the key is stored. Default "http://localhost:8200".
+		"""
+		vaultAddr: String
+
+		"""
+		Path to the Vault RoleID file.
+		"""
+		vaultRoleIDFile: String
+
+		"""
+		Path to the Vault SecretID file.
+		"""
+		vaultSecretIDFile: String
+
+		"""
+		Vault kv store path where the key lives. Default "secret/data/dgraph".
+		"""
+		vaultPath: String
+
+		"""
+		Vault kv store field whose value is the key. Default "enc_key".
+		"""
+		vaultField: String
+
+		"""
+		Vault kv store field's format. Must be "base64" or "raw". Default "base64".
+		"""
+		vaultFormat: String
+	}
+
+	type ReEncryptPayload {
+		response: Response
+	}
+
+	type ReEncryptStatus {
+		"""
+		One of RUNNING, READY, DONE, FAILED or CANCELLED. A READY copy is catching up, and is
+		swapped in once caught up. Once DONE, the node must be started with the new key.
+		"""
+		state: String
+		message: String
+
+		"""
+		Whether the staged copy is encrypted.
+		"""
+		encrypted: Boolean
+		startedAt: DateTime
+
+		"""
+		The timestamp up to which the staged copy is in sync.
+		"""
+		readTs: Int
+		passes: Int
+		keysCopied: Int
+		bytesCopied: Int
+	}
+
+	"""
+	A search connector mirrors predicates of the namespace into an Elasticsearch or OpenSearch
+	index, from the change data capture events of the groups. Every node with one of the
+	predicates is a document of the index, with the uid of the node as its id. The options used
+	to reach the cluster, like the credentials, are set by the --search_sync flag of the Alphas.
+	"""
+	type SearchConnector {
+		name: String!
+		url: String!
+		index: String!
+		predicates: [String!]!
+
+		"""
+		The JSON body the index was created with.
+		"""
+		mapping: String
+
+		"""
+		Whether some groups haven't copied the existing data to the index yet.
+		"""
+		backfillPending: Boolean!
+	}
+
+	input SearchConnectorInput {
+		name: String!
+
+		"""
+		Base URL of the Elasticsearch or OpenSearch cluster.
+		"""
+		url: String!
+
+		"""
+		The index, which is created if it doesn't exist.
+		"""
+		index: String!
+		predicates: [String!]!
+
+		"""
+		JSON body the index is created with, holding its settings and mappings. If it's not set,
+		the mappings are derived from the schema: strings with a fulltext or term index are text
+		fields, strings with only an exact or hash index are keyword fields, and so on.
+		"""
+		mapping: String
+
+		"""
+		Whether to copy the existing data of the predicates to the index. Default true.
+		"""
+		backfill: Boolean
+	}
+
+	type SearchConnectorPayload {
+		response: Response
+		connector: SearchConnector
+	}
 	`
 
 const adminMutations = `
@@ -500,11 +645,47 @@ const adminMutations = `
 	"""
 	deleteNamespace(input: DeleteNamespaceInput!): NamespacePayload
 
+	"""
+	Clone a namespace into a new one, with a copy of its schema, data, users and groups as of
+	the time of the request. The id of the new namespace is returned. To move a namespace to a
+	new id, clone it and delete it.
+	"""
+	cloneNamespace(input: CloneNamespaceInput!): NamespacePayload
+
 	"""
 	Reset password can only be used by the Guardians of the galaxy to reset password of
 	any user in any namespace.
 	"""
 	resetPassword(input: ResetPasswordInput!): ResetPasswordPayload
+
+	"""
+	Start re-encrypting the data on this node with a new key, or decrypting it. The node keeps
+	serving while a copy of its data is made in the background, and switches over to it once
+	it's caught up. Once DONE, start the node with the new key from then on.
+	"""
+	reEncrypt(input: ReEncryptInput!): ReEncryptPayload
+
+	"""
+	Cancel the re-encryption job on this node and remove its staged copy.
+	"""
+	cancelReEncrypt: ReEncryptPayload
+
+	"""
+	Add a search connector to the namespace, or replace the one with the same name. Replacing a
+	connector doesn't remove the data it already sent to its index.
+	"""
+	updateSearchConnector(input: SearchConnectorInput!): SearchConnectorPayload
+
+	"""
+	Remove a search connector. Its index is left as it is.
+	"""
+	removeSearchConnector(name: String!): SearchConnectorPayload
+
+	"""
+	Copy the existing data of the predicates of a search connector to its index again, e.g.
+	after the index was recreated. The backfill is run as a task by every group.
+	"""
+	backfillSearchConnector(name: String!): SearchConnectorPayload
 	`
 
 const adminQueries = `
@@ -523,4 +704,14 @@ const adminQueries = `
 	Get the information about the backups at a given location.
 	"""
 	listBackups(input: ListBackupsInput!) : [Manifest]
+
+	"""
+	Get the status of the re-encryption job on this node.
+	"""
+	reEncryptStatus: ReEncryptStatus
+
+	"""
+	List the search connectors of the namespace.
+	"""
+	searchConnectors: [SearchConnector]
 	`
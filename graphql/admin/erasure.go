@@ -0,0 +1,103 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/edgraph"
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+type eraseSubjectInput struct {
+	Subject   string
+	Namespace *uint64
+	Follow    []string
+	Depth     *int
+}
+
+func resolveEraseSubject(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	glog.Info("Got eraseSubject request through GraphQL admin API")
+
+	var input eraseSubjectInput
+	b, err := json.Marshal(m.ArgValue(schema.InputArgName))
+	if err == nil {
+		err = json.Unmarshal(b, &input)
+	}
+	if err != nil {
+		return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")), false
+	}
+	subject, err := strconv.ParseUint(input.Subject, 0, 64)
+	if err != nil || subject == 0 {
+		return resolve.EmptyResult(m, errors.Errorf("Invalid subject %q", input.Subject)), false
+	}
+
+	// Only the guardians of the galaxy may erase a subject of another namespace.
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	if input.Namespace != nil && *input.Namespace != ns {
+		if ns != x.GalaxyNamespace {
+			return resolve.EmptyResult(m, errors.Errorf(
+				"Only guardians of the galaxy can erase a subject of another namespace")), false
+		}
+		ctx = x.AttachNamespace(ctx, *input.Namespace)
+	}
+
+	req := &edgraph.ErasureRequest{Subject: subject, Follow: input.Follow, Depth: -1}
+	if input.Depth != nil {
+		if *input.Depth < 0 {
+			return resolve.EmptyResult(m, errors.Errorf("The depth can't be negative")), false
+		}
+		req.Depth = *input.Depth
+	}
+	res, err := edgraph.EraseSubject(ctx, req)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+
+	payload := response("Success", fmt.Sprintf("Subject %#x erased.", subject))
+	payload["report"] = res.Marker.Report
+	payload["signature"] = res.Marker.Signature
+	payload["taskId"] = fmt.Sprintf("%#x", res.TaskId)
+	return resolve.DataResult(m, map[string]interface{}{m.Name(): payload}, nil), true
+}
+
+func resolveErasureReports(ctx context.Context, q schema.Query) *resolve.Resolved {
+	markers, err := edgraph.ErasureMarkers(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+
+	reports := make([]interface{}, 0, len(markers))
+	for _, m := range markers {
+		reports = append(reports, map[string]interface{}{
+			"report":    m.Report,
+			"signature": m.Signature,
+		})
+	}
+	return resolve.DataResult(q, map[string]interface{}{q.Name(): reports}, nil)
+}
@@ -19,8 +19,11 @@ package admin
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"math"
+	"strconv"
 
+	"github.com/dgraph-io/dgraph/edgraph"
 	"github.com/dgraph-io/dgraph/graphql/resolve"
 	"github.com/dgraph-io/dgraph/graphql/schema"
 	"github.com/dgraph-io/dgraph/protos/pb"
@@ -33,6 +36,7 @@ import (
 type exportInput struct {
 	Format    string
 	Namespace uint64
+	Graph     string
 	DestinationFields
 }
 
@@ -65,6 +69,7 @@ func resolveExport(ctx context.Context, m schema.Mutation) (*resolve.Resolved, b
 	files, err := worker.ExportOverNetwork(ctx, &pb.ExportRequest{
 		Format:       format,
 		Namespace:    input.Namespace,
+		Graph:        input.Graph,
 		Destination:  input.Destination,
 		AccessKey:    input.AccessKey,
 		SecretKey:    input.SecretKey,
@@ -85,6 +90,24 @@ func resolveExport(ctx context.Context, m schema.Mutation) (*resolve.Resolved, b
 	), true
 }
 
+func resolveDropGraph(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	graph, _ := m.ArgValue("graph").(string)
+	glog.Infof("Got dropGraph request for graph %q through GraphQL admin API", graph)
+
+	deleted, err := edgraph.DropGraph(ctx, graph)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+
+	responseData := response("Success", fmt.Sprintf("Dropped graph %q.", graph))
+	responseData["numEdges"] = json.Number(strconv.FormatUint(deleted, 10))
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): responseData},
+		nil,
+	), true
+}
+
 // toInterfaceSlice converts []string to []interface{}
 func toInterfaceSlice(in []string) []interface{} {
 	out := make([]interface{}, 0, len(in))
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/glog"
+
+	"github.com/dgraph-io/dgraph/edgraph"
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+)
+
+type fulltextDictionaryInput struct {
+	Predicate string
+	Synonyms  []struct {
+		Words []string
+	}
+	ProtectedWords []string
+}
+
+func resolveUpdateFulltextDictionary(ctx context.Context, m schema.Mutation) (*resolve.Resolved,
+	bool) {
+	glog.Info("Got updateFulltextDictionary request through GraphQL admin API")
+
+	var input fulltextDictionaryInput
+	b, err := json.Marshal(m.ArgValue(schema.InputArgName))
+	if err == nil {
+		err = json.Unmarshal(b, &input)
+	}
+	if err != nil {
+		return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")), false
+	}
+
+	d := &edgraph.FulltextDictionary{Predicate: input.Predicate, Protected: input.ProtectedWords}
+	for _, group := range input.Synonyms {
+		d.Synonyms = append(d.Synonyms, group.Words)
+	}
+	if err := edgraph.UpdateFulltextDictionary(ctx, d); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+
+	payload := response("Success",
+		fmt.Sprintf("Fulltext dictionary of %s updated.", input.Predicate))
+	payload["dictionary"] = fulltextDictionaryResult(d)
+	return resolve.DataResult(m, map[string]interface{}{m.Name(): payload}, nil), true
+}
+
+func resolveFulltextDictionary(ctx context.Context, q schema.Query) *resolve.Resolved {
+	pred, _ := q.ArgValue("predicate").(string)
+	d, err := edgraph.GetFulltextDictionary(ctx, pred)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+	return resolve.DataResult(q, map[string]interface{}{q.Name(): fulltextDictionaryResult(d)},
+		nil)
+}
+
+func fulltextDictionaryResult(d *edgraph.FulltextDictionary) map[string]interface{} {
+	synonyms := make([]interface{}, 0, len(d.Synonyms))
+	for _, words := range d.Synonyms {
+		group := make([]interface{}, 0, len(words))
+		for _, w := range words {
+			group = append(group, w)
+		}
+		synonyms = append(synonyms, map[string]interface{}{"words": group})
+	}
+	protected := make([]interface{}, 0, len(d.Protected))
+	for _, w := range d.Protected {
+		protected = append(protected, w)
+	}
+	return map[string]interface{}{
+		"predicate":      d.Predicate,
+		"synonyms":       synonyms,
+		"protectedWords": protected,
+	}
+}
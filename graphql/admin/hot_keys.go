@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func resolveHotKeys(ctx context.Context, q schema.Query) *resolve.Resolved {
+	hot, windowEnd := posting.HotKeys()
+
+	uint64Num := func(i uint64) json.Number { return json.Number(strconv.FormatUint(i, 10)) }
+	floatNum := func(f float64) json.Number {
+		return json.Number(strconv.FormatFloat(f, 'f', 2, 64))
+	}
+
+	keys := make([]interface{}, 0, len(hot))
+	for _, k := range hot {
+		key := map[string]interface{}{
+			"key":        hex.EncodeToString(k.Key),
+			"reads":      uint64Num(k.Reads),
+			"writes":     uint64Num(k.Writes),
+			"readShare":  floatNum(k.ReadShare),
+			"writeShare": floatNum(k.WriteShare),
+		}
+		if pk, err := x.Parse(k.Key); err == nil {
+			ns, attr := x.ParseNamespaceAttr(pk.Attr)
+			key["predicate"] = attr
+			key["namespace"] = uint64Num(ns)
+			switch {
+			case pk.IsData():
+				key["keyType"] = "data"
+				key["uid"] = "0x" + strconv.FormatUint(pk.Uid, 16)
+			case pk.IsReverse():
+				key["keyType"] = "reverse"
+				key["uid"] = "0x" + strconv.FormatUint(pk.Uid, 16)
+			case pk.IsIndex():
+				key["keyType"] = "index"
+			case pk.IsCountOrCountRev():
+				key["keyType"] = "count"
+			}
+		}
+		keys = append(keys, key)
+	}
+	var end interface{}
+	if !windowEnd.IsZero() {
+		end = windowEnd.Format(time.RFC3339)
+	}
+
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): map[string]interface{}{
+			"windowEnd": end,
+			"keys":      keys,
+		}},
+		nil,
+	)
+}
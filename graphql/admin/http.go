@@ -201,17 +201,20 @@ func (gh *graphqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx = x.AttachRemoteIP(ctx, r)
 	ctx = x.AttachAuthToken(ctx, r)
 	ctx = x.AttachJWTNamespace(ctx)
+	ctx = x.AttachPriority(ctx, r)
 
 	var res *schema.Response
 	gqlReq, err := getRequest(r)
 
 	if err != nil {
-		write(w, schema.ErrorResponse(err), strings.Contains(r.Header.Get("Accept-Encoding"), "gzip"))
+		write(w, schema.ErrorResponse(schema.WithErrorCode(err, schema.CodeBadRequest)),
+			strings.Contains(r.Header.Get("Accept-Encoding"), "gzip"))
 		return
 	}
 
 	if err = edgraph.ProcessPersistedQuery(ctx, gqlReq); err != nil {
-		write(w, schema.ErrorResponse(err), strings.Contains(r.Header.Get("Accept-Encoding"), "gzip"))
+		write(w, schema.ErrorResponse(schema.WithErrorCode(err, schema.CodeBadRequest)),
+			strings.Contains(r.Header.Get("Accept-Encoding"), "gzip"))
 		return
 	}
 
@@ -319,7 +322,7 @@ func recoveryHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer api.PanicHandler(
 			func(err error) {
-				rr := schema.ErrorResponse(err)
+				rr := schema.ErrorResponse(schema.WithErrorCode(err, schema.CodeInternal))
 				write(w, rr, strings.Contains(r.Header.Get("Accept-Encoding"), "gzip"))
 			})
 
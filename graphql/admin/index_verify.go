@@ -0,0 +1,60 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func resolveIndexVerification(ctx context.Context, q schema.Query) *resolve.Resolved {
+	uint64Num := func(i uint64) json.Number { return json.Number(strconv.FormatUint(i, 10)) }
+
+	reports := worker.IndexVerifications()
+	preds := make([]interface{}, 0, len(reports))
+	for _, r := range reports {
+		recent := make([]interface{}, 0, len(r.Recent))
+		for _, div := range r.Recent {
+			recent = append(recent, map[string]interface{}{
+				"key":     hex.EncodeToString(div.Key),
+				"uid":     "0x" + strconv.FormatUint(div.Uid, 16),
+				"missing": div.Missing,
+			})
+		}
+		ns, attr := x.ParseNamespaceAttr(r.Predicate)
+		preds = append(preds, map[string]interface{}{
+			"predicate":    attr,
+			"namespace":    uint64Num(ns),
+			"dataKeys":     uint64Num(r.DataKeys),
+			"indexEntries": uint64Num(r.IndexEntries),
+			"missing":      uint64Num(r.Missing),
+			"dangling":     uint64Num(r.Dangling),
+			"repaired":     uint64Num(r.Repaired),
+			"lastRun":      r.LastRun.Format(time.RFC3339),
+			"recent":       recent,
+		})
+	}
+	return resolve.DataResult(q, map[string]interface{}{q.Name(): preds}, nil)
+}
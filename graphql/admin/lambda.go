@@ -0,0 +1,189 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgraph/edgraph"
+	"github.com/dgraph-io/dgraph/graphql/lambda"
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+type updateLambdaScriptInput struct {
+	Script  string
+	Runtime string
+}
+
+// versionArg reads the version argument of f into version. It leaves version as it is if the
+// argument isn't given.
+func versionArg(f schema.Field, version *int) error {
+	b, err := json.Marshal(f.ArgValue("version"))
+	if err != nil {
+		return schema.GQLWrapf(err, "couldn't get version argument")
+	}
+	if err := json.Unmarshal(b, version); err != nil {
+		return schema.GQLWrapf(err, "couldn't get version argument")
+	}
+	return nil
+}
+
+func lambdaScriptValue(s *lambda.Scripts, v *lambda.ScriptVersion) interface{} {
+	if v == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"version":    json.Number(strconv.Itoa(v.Version)),
+		"script":     v.Script,
+		"deployedAt": v.DeployedAt.Format(time.RFC3339),
+		"runtime":    runtimeValue(v),
+		"active":     v.Version == s.Active,
+	}
+}
+
+func runtimeValue(v *lambda.ScriptVersion) string {
+	if v.IsWasm() {
+		return lambda.RuntimeWasm
+	}
+	return lambda.RuntimeJS
+}
+
+func resolveGetLambdaScript(ctx context.Context, q schema.Query) *resolve.Resolved {
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+	scripts, err := edgraph.GetLambdaScripts(ns)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+
+	var version int
+	if err := versionArg(q, &version); err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): lambdaScriptValue(scripts, scripts.Get(version))},
+		nil,
+	)
+}
+
+func resolveLambdaScripts(ctx context.Context, q schema.Query) *resolve.Resolved {
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+	scripts, err := edgraph.GetLambdaScripts(ns)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+
+	versions := make([]interface{}, 0, len(scripts.Versions))
+	for i := range scripts.Versions {
+		versions = append(versions, lambdaScriptValue(scripts, &scripts.Versions[i]))
+	}
+	return resolve.DataResult(q, map[string]interface{}{q.Name(): versions}, nil)
+}
+
+func resolveLambdaServers(ctx context.Context, q schema.Query) *resolve.Resolved {
+	statuses := lambda.Status()
+	servers := make([]interface{}, 0, len(statuses))
+	for _, s := range statuses {
+		servers = append(servers, map[string]interface{}{
+			"url":       s.URL,
+			"healthy":   s.Healthy,
+			"restarts":  json.Number(strconv.Itoa(s.Restarts)),
+			"lastError": s.LastError,
+		})
+	}
+	return resolve.DataResult(q, map[string]interface{}{q.Name(): servers}, nil)
+}
+
+func resolveUpdateLambdaScript(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	glog.Info("Got updateLambdaScript request through GraphQL admin API")
+
+	inputArg := m.ArgValue(schema.InputArgName)
+	inputByts, err := json.Marshal(inputArg)
+	if err != nil {
+		return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")), false
+	}
+	var input updateLambdaScriptInput
+	if err := json.Unmarshal(inputByts, &input); err != nil {
+		return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")), false
+	}
+	if strings.TrimSpace(input.Script) == "" {
+		return resolve.EmptyResult(m, errors.New("lambda script can't be empty")), false
+	}
+	if input.Runtime == "" {
+		input.Runtime = lambda.RuntimeJS
+	}
+	if input.Runtime == lambda.RuntimeWasm {
+		// Compile the module now, so that a broken one is never deployed.
+		if err := lambda.ValidateWasm(input.Script); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+	}
+
+	var deployed *lambda.ScriptVersion
+	scripts, err := edgraph.UpdateLambdaScripts(ctx, func(s *lambda.Scripts) error {
+		deployed = s.Deploy(input.Script, input.Runtime, time.Now().UTC())
+		return nil
+	})
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return lambdaScriptPayload(m, scripts, deployed.Version), true
+}
+
+func resolveActivateLambdaScript(ctx context.Context, m schema.Mutation) (*resolve.Resolved,
+	bool) {
+	glog.Info("Got activateLambdaScript request through GraphQL admin API")
+
+	var version int
+	if err := versionArg(m, &version); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+
+	scripts, err := edgraph.UpdateLambdaScripts(ctx, func(s *lambda.Scripts) error {
+		_, err := s.Activate(version)
+		return err
+	})
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return lambdaScriptPayload(m, scripts, version), true
+}
+
+func lambdaScriptPayload(m schema.Mutation, scripts *lambda.Scripts,
+	version int) *resolve.Resolved {
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"lambdaScript": lambdaScriptValue(scripts, scripts.Get(version)),
+		}},
+		nil,
+	)
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/dgraph/ee"
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func resolveLicense(ctx context.Context, q schema.Query) *resolve.Resolved {
+	status := licenseStatus(worker.GetMembershipState(), ee.Entitlements(), time.Now())
+	return resolve.DataResult(q, map[string]interface{}{q.Name(): status}, nil)
+}
+
+// licenseStatus returns the LicenseStatus of the cluster with the given membership state and
+// entitlements.
+func licenseStatus(ms *pb.MembershipState, entitlements []ee.Entitlement,
+	now time.Time) map[string]interface{} {
+	intNum := func(i int64) json.Number { return json.Number(strconv.FormatInt(i, 10)) }
+
+	status := map[string]interface{}{}
+	var nodes int
+	namespaces := make(map[uint64]struct{})
+	if ms != nil {
+		nodes = len(ms.GetZeros())
+		for _, group := range ms.GetGroups() {
+			nodes += len(group.GetMembers())
+			for pred := range group.GetTablets() {
+				ns, _ := x.ParseNamespaceAttr(pred)
+				namespaces[ns] = struct{}{}
+			}
+		}
+		if l := ms.GetLicense(); l != nil {
+			// Trial licenses allow math.MaxUint64 nodes, which doesn't fit in an Int.
+			maxNodes := int64(math.MaxInt64)
+			if l.MaxNodes < math.MaxInt64 {
+				maxNodes = int64(l.MaxNodes)
+			}
+			status["user"] = l.User
+			status["enabled"] = l.Enabled
+			status["expired"] = l.Expired
+			status["expiryTs"] = intNum(l.ExpiryTs)
+			status["expiresIn"] = intNum(l.ExpiryTs - now.Unix())
+			status["maxNodes"] = intNum(maxNodes)
+		}
+	}
+	status["nodes"] = intNum(int64(nodes))
+	status["namespaces"] = intNum(int64(len(namespaces)))
+
+	features := make([]interface{}, 0)
+	for _, e := range entitlements {
+		features = append(features, map[string]interface{}{
+			"name":       e.Name,
+			"allowed":    e.Allowed,
+			"configured": e.Configured,
+		})
+	}
+	status["features"] = features
+	return status
+}
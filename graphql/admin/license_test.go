@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/ee"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLicenseStatus(t *testing.T) {
+	now := time.Unix(1000, 0)
+	status := licenseStatus(nil, nil, now)
+	require.Equal(t, map[string]interface{}{
+		"nodes":      json.Number("0"),
+		"namespaces": json.Number("0"),
+		"features":   []interface{}{},
+	}, status)
+
+	ms := &pb.MembershipState{
+		Zeros: map[uint64]*pb.Member{1: {}},
+		Groups: map[uint32]*pb.Group{
+			1: {
+				Members: map[uint64]*pb.Member{2: {}, 3: {}},
+				Tablets: map[string]*pb.Tablet{
+					x.NamespaceAttr(x.GalaxyNamespace, "name"): {},
+					x.NamespaceAttr(2, "name"):                 {},
+					x.NamespaceAttr(2, "age"):                  {},
+				},
+			},
+		},
+		License: &pb.License{
+			User:     "alice",
+			MaxNodes: math.MaxUint64,
+			ExpiryTs: 900,
+			Enabled:  true,
+			Expired:  true,
+		},
+	}
+	features := []ee.Entitlement{{Name: "acl", Allowed: true}}
+	require.Equal(t, map[string]interface{}{
+		"user":       "alice",
+		"enabled":    true,
+		"expired":    true,
+		"expiryTs":   json.Number("900"),
+		"expiresIn":  json.Number("-100"),
+		"maxNodes":   json.Number("9223372036854775807"),
+		"nodes":      json.Number("3"),
+		"namespaces": json.Number("2"),
+		"features": []interface{}{
+			map[string]interface{}{"name": "acl", "allowed": true, "configured": false},
+		},
+	}, licenseStatus(ms, features, now))
+}
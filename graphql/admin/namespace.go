@@ -37,6 +37,10 @@ type deleteNamespaceInput struct {
 	NamespaceId int
 }
 
+type cloneNamespaceInput struct {
+	NamespaceId int
+}
+
 func resolveAddNamespace(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
 	req, err := getAddNamespaceInput(m)
 	if err != nil {
@@ -86,6 +90,25 @@ func resolveDeleteNamespace(ctx context.Context, m schema.Mutation) (*resolve.Re
 	), true
 }
 
+func resolveCloneNamespace(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	req, err := getCloneNamespaceInput(m)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	ns, err := (&edgraph.Server{}).CloneNamespace(ctx, uint64(req.NamespaceId))
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): map[string]interface{}{
+			"namespaceId": json.Number(strconv.FormatUint(ns, 10)),
+			"message":     fmt.Sprintf("Cloned namespace %d successfully", req.NamespaceId),
+		}},
+		nil,
+	), true
+}
+
 func getAddNamespaceInput(m schema.Mutation) (*addNamespaceInput, error) {
 	inputArg := m.ArgValue(schema.InputArgName)
 	inputByts, err := json.Marshal(inputArg)
@@ -109,3 +132,15 @@ func getDeleteNamespaceInput(m schema.Mutation) (*deleteNamespaceInput, error) {
 	err = json.Unmarshal(inputByts, &input)
 	return &input, schema.GQLWrapf(err, "couldn't get input argument")
 }
+
+func getCloneNamespaceInput(m schema.Mutation) (*cloneNamespaceInput, error) {
+	inputArg := m.ArgValue(schema.InputArgName)
+	inputByts, err := json.Marshal(inputArg)
+	if err != nil {
+		return nil, schema.GQLWrapf(err, "couldn't get input argument")
+	}
+
+	var input cloneNamespaceInput
+	err = json.Unmarshal(inputByts, &input)
+	return &input, schema.GQLWrapf(err, "couldn't get input argument")
+}
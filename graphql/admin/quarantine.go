@@ -0,0 +1,97 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+func resolveQuarantinedKeys(ctx context.Context, q schema.Query) *resolve.Resolved {
+	uint64Num := func(i uint64) json.Number { return json.Number(strconv.FormatUint(i, 10)) }
+
+	quarantined := posting.QuarantinedKeys()
+	keys := make([]interface{}, 0, len(quarantined))
+	for _, k := range quarantined {
+		key := map[string]interface{}{
+			"key":      hex.EncodeToString(k.Key),
+			"version":  uint64Num(k.Version),
+			"error":    k.Err,
+			"since":    k.Since.Format(time.RFC3339),
+			"part":     nil,
+			"dumpFile": nil,
+		}
+		if len(k.Part) > 0 {
+			key["part"] = hex.EncodeToString(k.Part)
+		}
+		if k.DumpFile != "" {
+			key["dumpFile"] = k.DumpFile
+		}
+		if pk, err := x.Parse(k.Key); err == nil {
+			ns, attr := x.ParseNamespaceAttr(pk.Attr)
+			key["predicate"] = attr
+			key["namespace"] = uint64Num(ns)
+		}
+		keys = append(keys, key)
+	}
+	return resolve.DataResult(q, map[string]interface{}{q.Name(): keys}, nil)
+}
+
+func resolvePurgeQuarantinedKeys(ctx context.Context,
+	m schema.Mutation) (*resolve.Resolved, bool) {
+	glog.Info("Got purgeQuarantinedKeys request through GraphQL admin API")
+
+	var keys [][]byte
+	if arg, ok := m.ArgValue("keys").([]interface{}); ok && len(arg) > 0 {
+		for _, a := range arg {
+			s, _ := a.(string)
+			key, err := hex.DecodeString(s)
+			if err != nil || len(key) == 0 {
+				return resolve.EmptyResult(m, errors.Errorf("invalid key %q, keys must be "+
+					"hex encoded", s)), false
+			}
+			keys = append(keys, key)
+		}
+	} else {
+		for _, q := range posting.QuarantinedKeys() {
+			keys = append(keys, q.Key)
+		}
+	}
+
+	purged := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		if err := posting.PurgeQuarantined(key); err != nil {
+			return resolve.EmptyResult(m, errors.Wrapf(err, "purged %d keys, then failed",
+				len(purged))), false
+		}
+		purged = append(purged, hex.EncodeToString(key))
+	}
+
+	payload := response("Success", "Purged "+strconv.Itoa(len(purged))+" quarantined keys.")
+	payload["keys"] = purged
+	return resolve.DataResult(m, map[string]interface{}{m.Name(): payload}, nil), true
+}
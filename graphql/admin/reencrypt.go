@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/golang/glog"
+)
+
+func resolveReEncrypt(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	glog.Info("Got re-encrypt request through GraphQL admin API")
+
+	inputArg := m.ArgValue(schema.InputArgName)
+	inputByts, err := json.Marshal(inputArg)
+	if err != nil {
+		return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")), false
+	}
+	var input worker.ReEncryptRequest
+	if err := json.Unmarshal(inputByts, &input); err != nil {
+		return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")), false
+	}
+
+	if err := worker.StartReEncryption(&input); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): response("Success", "Re-encryption started.")},
+		nil,
+	), true
+}
+
+func resolveCancelReEncrypt(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	glog.Info("Got cancel re-encrypt request through GraphQL admin API")
+
+	if err := worker.CancelReEncryption(); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): response("Success", "Re-encryption cancelled.")},
+		nil,
+	), true
+}
+
+func resolveReEncryptStatus(ctx context.Context, q schema.Query) *resolve.Resolved {
+	s := worker.GetReEncryptionStatus()
+	if s == nil {
+		return resolve.DataResult(q, map[string]interface{}{q.Name(): nil}, nil)
+	}
+
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): map[string]interface{}{
+			"state":       s.State,
+			"message":     s.Message,
+			"encrypted":   s.Encrypted,
+			"startedAt":   s.StartedAt.Format(time.RFC3339),
+			"readTs":      json.Number(strconv.FormatUint(s.ReadTs, 10)),
+			"passes":      json.Number(strconv.Itoa(s.Passes)),
+			"keysCopied":  json.Number(strconv.FormatUint(s.KeysCopied, 10)),
+			"bytesCopied": json.Number(strconv.FormatUint(s.BytesCopied, 10)),
+		}},
+		nil,
+	)
+}
@@ -27,24 +27,28 @@ import (
 	"github.com/dgraph-io/dgraph/graphql/schema"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/worker"
+	"github.com/golang/glog"
 	"github.com/pkg/errors"
 )
 
 type restoreInput struct {
-	Location          string
-	BackupId          string
-	BackupNum         int
-	EncryptionKeyFile string
-	AccessKey         string
-	SecretKey         string
-	SessionToken      string
-	Anonymous         bool
-	VaultAddr         string
-	VaultRoleIDFile   string
-	VaultSecretIDFile string
-	VaultPath         string
-	VaultField        string
-	VaultFormat       string
+	Location             string
+	BackupId             string
+	BackupNum            int
+	EncryptionKeyFile    string
+	AccessKey            string
+	SecretKey            string
+	SessionToken         string
+	Anonymous            bool
+	VaultAddr            string
+	VaultRoleIDFile      string
+	VaultSecretIDFile    string
+	VaultPath            string
+	VaultField           string
+	VaultFormat          string
+	PrivateKeyFile       string
+	VaultPrivateKeyField string
+	VerifyKeyFile        string
 }
 
 func resolveRestore(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
@@ -54,20 +58,29 @@ func resolveRestore(ctx context.Context, m schema.Mutation) (*resolve.Resolved,
 	}
 
 	req := pb.RestoreRequest{
-		Location:          input.Location,
-		BackupId:          input.BackupId,
-		BackupNum:         uint64(input.BackupNum),
-		EncryptionKeyFile: input.EncryptionKeyFile,
-		AccessKey:         input.AccessKey,
-		SecretKey:         input.SecretKey,
-		SessionToken:      input.SessionToken,
-		Anonymous:         input.Anonymous,
-		VaultAddr:         input.VaultAddr,
-		VaultRoleidFile:   input.VaultRoleIDFile,
-		VaultSecretidFile: input.VaultSecretIDFile,
-		VaultPath:         input.VaultPath,
-		VaultField:        input.VaultField,
-		VaultFormat:       input.VaultFormat,
+		Location:             input.Location,
+		BackupId:             input.BackupId,
+		BackupNum:            uint64(input.BackupNum),
+		EncryptionKeyFile:    input.EncryptionKeyFile,
+		AccessKey:            input.AccessKey,
+		SecretKey:            input.SecretKey,
+		SessionToken:         input.SessionToken,
+		Anonymous:            input.Anonymous,
+		VaultAddr:            input.VaultAddr,
+		VaultRoleidFile:      input.VaultRoleIDFile,
+		VaultSecretidFile:    input.VaultSecretIDFile,
+		VaultPath:            input.VaultPath,
+		VaultField:           input.VaultField,
+		VaultFormat:          input.VaultFormat,
+		PrivateKeyFile:       input.PrivateKeyFile,
+		VaultPrivateKeyField: input.VaultPrivateKeyField,
+		VerifyKeyFile:        input.VerifyKeyFile,
+	}
+
+	// The restore is recorded first, so that the erasures run since the backups were taken are
+	// applied again once they're restored, even if this Alpha goes down in between.
+	if err := worker.UpdateErasures(ctx, &pb.ErasureUpdate{Restored: true}); err != nil {
+		return resolve.EmptyResult(m, errors.Wrap(err, "while recording the restore")), false
 	}
 
 	wg := &sync.WaitGroup{}
@@ -85,6 +98,9 @@ func resolveRestore(ctx context.Context, m schema.Mutation) (*resolve.Resolved,
 	go func() {
 		wg.Wait()
 		edgraph.ResetAcl(nil)
+		if err := edgraph.ReapplyErasures(context.Background()); err != nil {
+			glog.Errorf("Unable to apply the erasures again after the restore: %v", err)
+		}
 	}()
 
 	return resolve.DataResult(
@@ -0,0 +1,49 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/query"
+)
+
+func resolveRunningQueries(ctx context.Context, q schema.Query) *resolve.Resolved {
+	int64Num := func(i int64) json.Number { return json.Number(strconv.FormatInt(i, 10)) }
+
+	running := query.GetRunningQueries()
+	res := make([]interface{}, 0, len(running))
+	for _, rq := range running {
+		res = append(res, map[string]interface{}{
+			"id":           json.Number(strconv.FormatUint(rq.Id, 10)),
+			"namespace":    json.Number(strconv.FormatUint(rq.Namespace, 10)),
+			"query":        rq.Query,
+			"startedAt":    rq.StartedAt.Format(time.RFC3339),
+			"durationMs":   int64Num(time.Since(rq.StartedAt).Milliseconds()),
+			"memoryBytes":  int64Num(rq.MemoryBytes()),
+			"taskBytes":    int64Num(rq.TaskBytes),
+			"encodedBytes": int64Num(rq.EncodedBytes),
+		})
+	}
+
+	return resolve.DataResult(q, map[string]interface{}{q.Name(): res}, nil)
+}
@@ -19,13 +19,16 @@ package admin
 import (
 	"context"
 	"encoding/json"
+	"strings"
 
 	"github.com/dgraph-io/dgraph/edgraph"
 	"github.com/dgraph-io/dgraph/graphql/resolve"
 	"github.com/dgraph-io/dgraph/graphql/schema"
 	"github.com/dgraph-io/dgraph/query"
+	"github.com/dgraph-io/dgraph/worker"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/golang/glog"
+	"github.com/pkg/errors"
 )
 
 type getSchemaResolver struct {
@@ -40,6 +43,14 @@ type updateSchemaResolver struct {
 	admin *adminServer
 }
 
+// updateSchemaDocumentResolver updates a single document of the GraphQL schema, leaving the
+// documents owned by others as they are.
+type updateSchemaDocumentResolver struct {
+	admin *adminServer
+	// delete is set for deleteGQLSchemaDocument, which removes the document from the schema.
+	delete bool
+}
+
 func (usr *updateSchemaResolver) Resolve(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
 	glog.Info("Got updateGQLSchema request")
 
@@ -72,6 +83,68 @@ func (usr *updateSchemaResolver) Resolve(ctx context.Context, m schema.Mutation)
 					"id":              query.UidToHex(resp.Uid),
 					"schema":          input.Set.Schema,
 					"generatedSchema": schHandler.GQLSchema(),
+					"documents":       documentsToMaps(input.Set.Schema),
+				}}},
+		nil), true
+}
+
+func (udr *updateSchemaDocumentResolver) Resolve(ctx context.Context,
+	m schema.Mutation) (*resolve.Resolved, bool) {
+	name, _ := m.ArgValue("name").(string)
+	glog.Infof("Got %s request for document %q", m.Name(), name)
+
+	var docSchema string
+	if !udr.delete {
+		docSchema, _ = m.ArgValue("schema").(string)
+		if strings.TrimSpace(docSchema) == "" {
+			return resolve.EmptyResult(m, errors.Errorf("schema of document %q can't be empty, "+
+				"use deleteGQLSchemaDocument to remove it", name)), false
+		}
+	}
+
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	// The schema is read and updated in a transaction starting at the same timestamp. So, if
+	// another document is updated concurrently, one of the two updates is aborted instead of the
+	// other one being lost.
+	startTs := worker.State.GetTimestamp(false)
+	_, current, err := edgraph.GetGQLSchemaAt(ns, startTs)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	docs, err := schema.PutDocument(current, name, docSchema)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	sch, err := schema.MergeDocuments(docs)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+
+	schHandler, err := schema.NewHandler(sch, false)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	if _, err = schema.FromString(schHandler.GQLSchema()); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+
+	resp, err := edgraph.UpdateGQLSchemaAt(ctx, startTs, sch, schHandler.DGSchema())
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{
+			m.Name(): map[string]interface{}{
+				"gqlSchema": map[string]interface{}{
+					"id":              query.UidToHex(resp.Uid),
+					"schema":          sch,
+					"generatedSchema": schHandler.GQLSchema(),
+					"documents":       documentsToMaps(sch),
 				}}},
 		nil), true
 }
@@ -96,12 +169,25 @@ func (gsr *getSchemaResolver) Resolve(ctx context.Context, q schema.Query) *reso
 				"id":              cs.ID,
 				"schema":          cs.Schema,
 				"generatedSchema": cs.GeneratedSchema,
+				"documents":       documentsToMaps(cs.Schema),
 			}}
 	}
 
 	return resolve.DataResult(q, data, nil)
 }
 
+func documentsToMaps(sch string) []interface{} {
+	docs := schema.SplitDocuments(sch)
+	res := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		res = append(res, map[string]interface{}{
+			"name":   doc.Name,
+			"schema": doc.Schema,
+		})
+	}
+	return res
+}
+
 func getSchemaInput(m schema.Mutation) (*updateGQLSchemaInput, error) {
 	inputArg := m.ArgValue(schema.InputArgName)
 	inputByts, err := json.Marshal(inputArg)
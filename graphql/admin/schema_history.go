@@ -0,0 +1,99 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dgraph-io/dgraph/edgraph"
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/golang/glog"
+)
+
+func schemaVersionValue(v *edgraph.SchemaVersion) interface{} {
+	if v == nil {
+		return nil
+	}
+	res := map[string]interface{}{
+		"version": json.Number(strconv.FormatUint(v.Version, 10)),
+		"kind":    v.Kind,
+		"schema":  v.Schema,
+		"diff":    v.Diff,
+		"author":  v.Author,
+		"at":      v.At.Format(time.RFC3339),
+	}
+	if v.RollbackOf != 0 {
+		res["rollbackOf"] = json.Number(strconv.FormatUint(v.RollbackOf, 10))
+	}
+	return res
+}
+
+func resolveSchemaHistory(ctx context.Context, q schema.Query) *resolve.Resolved {
+	history, err := edgraph.SchemaHistory(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+
+	versions := make([]interface{}, 0, len(history))
+	for _, v := range history {
+		versions = append(versions, schemaVersionValue(v))
+	}
+	return resolve.DataResult(q, map[string]interface{}{q.Name(): versions}, nil)
+}
+
+func resolveRollbackSchema(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	glog.Info("Got rollbackSchema request through GraphQL admin API")
+
+	var version uint64
+	b, err := json.Marshal(m.ArgValue("version"))
+	if err == nil {
+		err = json.Unmarshal(b, &version)
+	}
+	if err != nil {
+		return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get version argument")),
+			false
+	}
+
+	rollback, err := edgraph.RollbackSchema(ctx, version)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+
+	var taskId interface{}
+	if rollback.TaskId != 0 {
+		taskId = fmt.Sprintf("%#x", rollback.TaskId)
+	}
+	payload := response("Success", fmt.Sprintf("Schema rolled back to version %d.", version))
+	payload["version"] = schemaVersionValue(rollback.Version)
+	payload["keptPredicates"] = stringList(rollback.KeptPredicates)
+	payload["droppedTypes"] = stringList(rollback.DroppedTypes)
+	payload["taskId"] = taskId
+	return resolve.DataResult(m, map[string]interface{}{m.Name(): payload}, nil), true
+}
+
+func stringList(l []string) []interface{} {
+	res := make([]interface{}, 0, len(l))
+	for _, s := range l {
+		res = append(res, s)
+	}
+	return res
+}
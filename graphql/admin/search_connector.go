@@ -0,0 +1,125 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/glog"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/worker"
+)
+
+type searchConnectorInput struct {
+	Name       string
+	Url        string
+	Index      string
+	Predicates []string
+	Mapping    string
+	Backfill   *bool
+}
+
+func resolveUpdateSearchConnector(ctx context.Context, m schema.Mutation) (*resolve.Resolved,
+	bool) {
+	glog.Info("Got updateSearchConnector request through GraphQL admin API")
+
+	var input searchConnectorInput
+	b, err := json.Marshal(m.ArgValue(schema.InputArgName))
+	if err == nil {
+		err = json.Unmarshal(b, &input)
+	}
+	if err != nil {
+		return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")), false
+	}
+
+	c, err := worker.UpdateSearchConnector(ctx, &pb.SearchConnector{
+		Name:       input.Name,
+		Url:        input.Url,
+		Index:      input.Index,
+		Predicates: input.Predicates,
+		Mapping:    input.Mapping,
+	}, input.Backfill == nil || *input.Backfill)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return searchConnectorResult(m, c, fmt.Sprintf("Search connector %s updated.", c.Name))
+}
+
+func resolveRemoveSearchConnector(ctx context.Context, m schema.Mutation) (*resolve.Resolved,
+	bool) {
+	glog.Info("Got removeSearchConnector request through GraphQL admin API")
+
+	name, _ := m.ArgValue("name").(string)
+	if err := worker.RemoveSearchConnector(ctx, name); err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return searchConnectorResult(m, nil, fmt.Sprintf("Search connector %s removed.", name))
+}
+
+func resolveBackfillSearchConnector(ctx context.Context, m schema.Mutation) (*resolve.Resolved,
+	bool) {
+	glog.Info("Got backfillSearchConnector request through GraphQL admin API")
+
+	name, _ := m.ArgValue("name").(string)
+	c, err := worker.BackfillSearchConnector(ctx, name)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+	return searchConnectorResult(m, c, fmt.Sprintf("Backfill of search connector %s requested.",
+		name))
+}
+
+func searchConnectorResult(m schema.Mutation, c *pb.SearchConnector,
+	msg string) (*resolve.Resolved, bool) {
+	payload := response("Success", msg)
+	if c != nil {
+		payload["connector"] = searchConnector(c)
+	}
+	return resolve.DataResult(m, map[string]interface{}{m.Name(): payload}, nil), true
+}
+
+func resolveSearchConnectors(ctx context.Context, q schema.Query) *resolve.Resolved {
+	conns, err := worker.GetSearchConnectors(ctx)
+	if err != nil {
+		return resolve.EmptyResult(q, err)
+	}
+	res := make([]interface{}, 0, len(conns))
+	for _, c := range conns {
+		res = append(res, searchConnector(c))
+	}
+	return resolve.DataResult(q, map[string]interface{}{q.Name(): res}, nil)
+}
+
+func searchConnector(c *pb.SearchConnector) map[string]interface{} {
+	preds := make([]interface{}, 0, len(c.Predicates))
+	for _, pred := range c.Predicates {
+		preds = append(preds, pred)
+	}
+	return map[string]interface{}{
+		"name":            c.Name,
+		"url":             c.Url,
+		"index":           c.Index,
+		"predicates":      preds,
+		"mapping":         c.Mapping,
+		"backfillPending": worker.SearchBackfillPending(c),
+	}
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/golang/glog"
+)
+
+type snapshotInput struct {
+	Destination string
+}
+
+func resolveSnapshot(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	glog.Info("Got snapshot request through GraphQL admin API")
+
+	inputArg := m.ArgValue(schema.InputArgName)
+	inputByts, err := json.Marshal(inputArg)
+	if err != nil {
+		return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")), false
+	}
+	var input snapshotInput
+	if err := json.Unmarshal(inputByts, &input); err != nil {
+		return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")), false
+	}
+
+	snap, err := worker.TakeLocalSnapshot(input.Destination)
+	if err != nil {
+		return resolve.EmptyResult(m, err), false
+	}
+
+	uint64Num := func(u uint64) json.Number { return json.Number(strconv.FormatUint(u, 10)) }
+	int64Num := func(i int64) json.Number { return json.Number(strconv.FormatInt(i, 10)) }
+	payload := response("Success", "Local snapshot taken.")
+	payload["snapshot"] = map[string]interface{}{
+		"destination": snap.Dir,
+		"groupId":     uint64Num(uint64(snap.GroupId)),
+		"raftIndex":   uint64Num(snap.RaftIndex),
+		"readTs":      uint64Num(snap.ReadTs),
+		"linkedFiles": int64Num(int64(snap.LinkedFiles)),
+		"copiedFiles": int64Num(int64(snap.CopiedFiles)),
+		"bytesCopied": int64Num(snap.BytesCopied),
+		"tookMs":      int64Num(snap.Took.Milliseconds()),
+	}
+	return resolve.DataResult(m, map[string]interface{}{m.Name(): payload}, nil), true
+}
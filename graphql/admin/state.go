@@ -15,16 +15,17 @@ import (
 )
 
 type membershipState struct {
-	Counter   uint64         `json:"counter,omitempty"`
-	Groups    []clusterGroup `json:"groups,omitempty"`
-	Zeros     []*pb.Member   `json:"zeros,omitempty"`
-	MaxUID    uint64         `json:"maxUID,omitempty"`
-	MaxNsID   uint64         `json:"maxNsID,omitempty"`
-	MaxTxnTs  uint64         `json:"maxTxnTs,omitempty"`
-	MaxRaftId uint64         `json:"maxRaftId,omitempty"`
-	Removed   []*pb.Member   `json:"removed,omitempty"`
-	Cid       string         `json:"cid,omitempty"`
-	License   *pb.License    `json:"license,omitempty"`
+	Counter   uint64           `json:"counter,omitempty"`
+	Groups    []clusterGroup   `json:"groups,omitempty"`
+	Zeros     []*pb.Member     `json:"zeros,omitempty"`
+	MaxUID    uint64           `json:"maxUID,omitempty"`
+	MaxNsID   uint64           `json:"maxNsID,omitempty"`
+	MaxTxnTs  uint64           `json:"maxTxnTs,omitempty"`
+	MaxRaftId uint64           `json:"maxRaftId,omitempty"`
+	Removed   []*pb.Member     `json:"removed,omitempty"`
+	Cid       string           `json:"cid,omitempty"`
+	License   *pb.License      `json:"license,omitempty"`
+	ReadOnly  *pb.ReadOnlyMode `json:"readOnly,omitempty"`
 }
 
 type clusterGroup struct {
@@ -107,6 +108,7 @@ func convertToGraphQLResp(ms pb.MembershipState) membershipState {
 	state.Removed = ms.Removed
 	state.Cid = ms.Cid
 	state.License = ms.License
+	state.ReadOnly = ms.ReadOnly
 
 	return state
 }
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+)
+
+type storageInput struct {
+	RunVlogGC         bool
+	DiscardRatio      *float64
+	PauseVlogGC       *bool
+	Flatten           bool
+	FlattenWorkers    *int
+	RollupDelayMs     *int64
+	RewriteSplitLists bool
+	MinSplitParts     *int
+}
+
+func resolveStorage(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+	glog.Info("Got storage request through GraphQL admin API")
+
+	inputArg := m.ArgValue(schema.InputArgName)
+	inputByts, err := json.Marshal(inputArg)
+	if err != nil {
+		return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")), false
+	}
+	var input storageInput
+	if err := json.Unmarshal(inputByts, &input); err != nil {
+		return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")), false
+	}
+
+	var done []string
+	if input.PauseVlogGC != nil {
+		x.PauseVlogGC(*input.PauseVlogGC)
+		if *input.PauseVlogGC {
+			done = append(done, "Value log GC paused.")
+		} else {
+			done = append(done, "Value log GC resumed.")
+		}
+	}
+	if input.RollupDelayMs != nil {
+		d := time.Duration(*input.RollupDelayMs) * time.Millisecond
+		if err := worker.SetRollupDelay(d); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+		done = append(done, "Rollup delay updated.")
+	}
+	if input.RunVlogGC {
+		ratio := 0.5
+		if input.DiscardRatio != nil {
+			ratio = *input.DiscardRatio
+		}
+		if err := worker.StartVlogGC(ratio); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+		done = append(done, "Value log GC started.")
+	}
+	if input.Flatten {
+		workers := 1
+		if input.FlattenWorkers != nil {
+			workers = *input.FlattenWorkers
+		}
+		if err := worker.StartFlatten(workers); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+		done = append(done, "Flatten started.")
+	}
+	if input.RewriteSplitLists {
+		minParts := 2
+		if input.MinSplitParts != nil {
+			minParts = *input.MinSplitParts
+		}
+		if err := worker.StartListRewrite(minParts); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+		done = append(done, "Rewrite of the multi-part lists started.")
+	}
+	if len(done) == 0 {
+		done = append(done, "Nothing to do.")
+	}
+
+	return resolve.DataResult(
+		m,
+		map[string]interface{}{m.Name(): response("Success", strings.Join(done, " "))},
+		nil,
+	), true
+}
+
+func resolveStorageStatus(ctx context.Context, q schema.Query) *resolve.Resolved {
+	st := worker.GetStorageStatus()
+
+	int64Num := func(i int64) json.Number { return json.Number(strconv.FormatInt(i, 10)) }
+	timeOrNil := func(t time.Time) interface{} {
+		if t.IsZero() {
+			return nil
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	cold := worker.GetColdTablets()
+	coldTablets := make([]interface{}, 0, len(cold))
+	for _, c := range cold {
+		ns, attr := x.ParseNamespaceAttr(c.Predicate)
+		coldTablets = append(coldTablets, map[string]interface{}{
+			"predicate": attr,
+			"namespace": json.Number(strconv.FormatUint(ns, 10)),
+			"object":    c.Object,
+			"size":      int64Num(c.Size),
+			"demotedAt": c.DemotedAt.UTC().Format(time.RFC3339),
+			"cached":    c.Cached,
+			"archived":  c.Archived,
+		})
+	}
+
+	levels := make([]interface{}, 0, len(st.Levels))
+	for _, l := range st.Levels {
+		levels = append(levels, map[string]interface{}{
+			"level":      json.Number(strconv.Itoa(l.Level)),
+			"numTables":  json.Number(strconv.Itoa(l.NumTables)),
+			"size":       int64Num(l.Size),
+			"targetSize": int64Num(l.TargetSize),
+			"staleBytes": int64Num(l.StaleBytes),
+			"score":      json.Number(strconv.FormatFloat(l.Score, 'f', 2, 64)),
+		})
+	}
+
+	return resolve.DataResult(
+		q,
+		map[string]interface{}{q.Name(): map[string]interface{}{
+			"lsmSize":                  int64Num(st.LsmSize),
+			"vlogSize":                 int64Num(st.VlogSize),
+			"lsmStaleBytes":            int64Num(st.LsmStaleBytes),
+			"levels":                   levels,
+			"vlogGCPaused":             st.VlogGCPaused,
+			"vlogGCRunning":            st.VlogGCRunning,
+			"lastVlogGC":               timeOrNil(st.LastVlogGC),
+			"lastVlogGCRewrites":       json.Number(strconv.Itoa(st.LastVlogGCRewrites)),
+			"flattenRunning":           st.FlattenRunning,
+			"lastFlatten":              timeOrNil(st.LastFlatten),
+			"rollupDelayMs":            int64Num(st.RollupDelay.Milliseconds()),
+			"rewriteSplitListsRunning": st.ListRewriteRunning,
+			"lastRewriteSplitLists":    timeOrNil(st.LastListRewrite),
+			"lastRewrittenSplitLists":  json.Number(strconv.Itoa(st.LastListRewrites)),
+			"coldTablets":              coldTablets,
+		}},
+		nil,
+	)
+}
+
+type tabletTierInput struct {
+	Predicate string
+	Namespace uint64
+}
+
+// tierMessages are the messages of the successful tier operations.
+var tierMessages = map[pb.TierTabletRequest_Op]string{
+	pb.TierTabletRequest_DEMOTE:  "Predicate %s demoted to object storage.",
+	pb.TierTabletRequest_PROMOTE: "Predicate %s promoted from object storage.",
+	pb.TierTabletRequest_ARCHIVE: "Predicate %s archived to object storage.",
+	pb.TierTabletRequest_ATTACH:  "Predicate %s attached from object storage.",
+}
+
+func resolveTabletTier(op pb.TierTabletRequest_Op) resolve.MutationResolverFunc {
+	return func(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+		glog.Infof("Got request to change the tier of a tablet through GraphQL admin API")
+
+		inputArg := m.ArgValue(schema.InputArgName)
+		inputByts, err := json.Marshal(inputArg)
+		if err != nil {
+			return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")),
+				false
+		}
+		var input tabletTierInput
+		if err := json.Unmarshal(inputByts, &input); err != nil {
+			return resolve.EmptyResult(m, schema.GQLWrapf(err, "couldn't get input argument")),
+				false
+		}
+
+		attr := x.NamespaceAttr(input.Namespace, input.Predicate)
+		if err := worker.MoveTabletTier(ctx, attr, op); err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+		return resolve.DataResult(
+			m,
+			map[string]interface{}{m.Name(): response("Success",
+				fmt.Sprintf(tierMessages[op], input.Predicate))},
+			nil,
+		), true
+	}
+}
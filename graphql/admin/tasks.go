@@ -0,0 +1,87 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgraph/graphql/resolve"
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/worker"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+func taskToMap(t *pb.Task) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              fmt.Sprintf("%#x", t.Id),
+		"kind":            t.Kind,
+		"description":     t.Description,
+		"groupId":         json.Number(strconv.FormatUint(uint64(t.GroupId), 10)),
+		"owner":           fmt.Sprintf("%#x", t.Owner),
+		"state":           t.State.String(),
+		"progress":        json.Number(strconv.FormatFloat(t.Progress, 'f', -1, 64)),
+		"message":         t.Message,
+		"startedAt":       time.Unix(t.StartedAt, 0).UTC().Format(time.RFC3339),
+		"updatedAt":       time.Unix(t.UpdatedAt, 0).UTC().Format(time.RFC3339),
+		"pausable":        t.Pausable,
+		"cancellable":     t.Cancellable,
+		"pauseRequested":  t.PauseRequested,
+		"cancelRequested": t.CancelRequested,
+	}
+}
+
+func resolveTasks(ctx context.Context, q schema.Query) *resolve.Resolved {
+	tasks := worker.GetTasks()
+	res := make([]interface{}, 0, len(tasks))
+	for _, t := range tasks {
+		res = append(res, taskToMap(t))
+	}
+	return resolve.DataResult(q, map[string]interface{}{q.Name(): res}, nil)
+}
+
+func resolveControlTask(op pb.TaskControl_Op) resolve.MutationResolverFunc {
+	return func(ctx context.Context, m schema.Mutation) (*resolve.Resolved, bool) {
+		glog.Infof("Got request to %s a task through GraphQL admin API", op)
+
+		idArg, _ := m.ArgValue("id").(string)
+		id, err := strconv.ParseUint(strings.TrimPrefix(idArg, "0x"), 16, 64)
+		if err != nil {
+			return resolve.EmptyResult(m, errors.Errorf("Invalid task id: %q", idArg)), false
+		}
+		task, err := worker.ControlTask(ctx, id, op)
+		if err != nil {
+			return resolve.EmptyResult(m, err), false
+		}
+
+		return resolve.DataResult(
+			m,
+			map[string]interface{}{m.Name(): map[string]interface{}{
+				"response": response("Success",
+					fmt.Sprintf("Requested to %s task %#x", strings.ToLower(op.String()), id)),
+				"task": taskToMap(task),
+			}},
+			nil,
+		), true
+	}
+}
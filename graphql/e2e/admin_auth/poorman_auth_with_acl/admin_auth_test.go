@@ -27,6 +27,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/dgraph-io/dgraph/graphql/e2e/common"
+	"github.com/dgraph-io/dgraph/graphql/schema"
 )
 
 const (
@@ -101,6 +102,8 @@ func assertMissingAclError(t *testing.T, resp *common.GraphQLResponse) {
 			Line:   2,
 			Column: 4,
 		}},
+		Path:       []interface{}{"updateGQLSchema"},
+		Extensions: common.ErrorExtensions(schema.CodeForbidden),
 	}}, resp.Errors)
 }
 
@@ -111,6 +114,8 @@ func assertBadAclError(t *testing.T, resp *common.GraphQLResponse) {
 			Line:   2,
 			Column: 4,
 		}},
+		Path:       []interface{}{"updateGQLSchema"},
+		Extensions: common.ErrorExtensions(schema.CodeUnauthenticated),
 	}}, resp.Errors)
 }
 
@@ -1179,6 +1179,12 @@ func RequireNoGQLErrors(t *testing.T, resp *GraphQLResponse) {
 	}
 }
 
+// ErrorExtensions returns the extensions of a GraphQL error with the given code, as they are
+// decoded from a response.
+func ErrorExtensions(code schema.ErrorCode) map[string]interface{} {
+	return map[string]interface{}{"code": string(code), "retryable": code.Retryable()}
+}
+
 func PopulateGraphQLData(client *dgo.Dgraph, data []byte) error {
 	mu := &api.Mutation{
 		CommitNow: true,
@@ -206,6 +206,10 @@ func requestValidationErrors(t *testing.T) {
 			}
 			gqlResponse := test.ExecuteAsPost(t, GraphqlURL)
 			require.Nil(t, gqlResponse.Data)
+			// All these errors are found while validating the request.
+			for _, e := range tcase.Errors {
+				e.Extensions = ErrorExtensions(schema.CodeValidationFailed)
+			}
 			if diff := cmp.Diff(tcase.Errors, gqlResponse.Errors); diff != "" {
 				t.Errorf("errors mismatch (-want +got):\n%s", diff)
 			}
@@ -300,11 +304,15 @@ func panicCatcher(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			gqlResponse := test.ExecuteAsPost(t, ts.URL)
 
-			require.Equal(t, x.GqlErrorList{
+			expected := x.GqlErrorList{
 				{Message: fmt.Sprintf("Internal Server Error - a panic was trapped.  " +
 					"This indicates a bug in the GraphQL server.  A stack trace was logged.  " +
-					"Please let us know by filing an issue with the stack trace.")}},
-				gqlResponse.Errors)
+					"Please let us know by filing an issue with the stack trace."),
+					Extensions: ErrorExtensions(schema.CodeInternal)}}
+			if name == "query" {
+				expected[0].Path = []interface{}{"queryCountry"}
+			}
+			require.Equal(t, expected, gqlResponse.Errors)
 
 			require.Nil(t, gqlResponse.Data, string(gqlResponse.Data))
 		})
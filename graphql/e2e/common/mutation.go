@@ -30,6 +30,7 @@ import (
 
 	"github.com/dgraph-io/dgo/v200"
 	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/graphql/schema"
 	"github.com/dgraph-io/dgraph/testutil"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/google/go-cmp/cmp"
@@ -2199,8 +2200,9 @@ func manyMutationsWithQueryError(t *testing.T) {
 	expectedErrors := x.GqlErrorList{
 		&x.GqlError{Message: `Non-nullable field 'name' (type String!) was not present ` +
 			`in result from Dgraph.  GraphQL error propagation triggered.`,
-			Locations: []x.Location{{Line: 18, Column: 25}},
-			Path:      []interface{}{"add2", "author", float64(0), "country", "name"}}}
+			Locations:  []x.Location{{Line: 18, Column: 25}},
+			Path:       []interface{}{"add2", "author", float64(0), "country", "name"},
+			Extensions: ErrorExtensions(schema.CodeCompletionFailed)}}
 
 	gqlResponse := multiMutationParams.ExecuteAsPost(t, GraphqlURL)
 
@@ -2086,8 +2086,9 @@ func erroredQueriesHaveTouchedUids(t *testing.T) {
 	require.Equal(t, x.GqlErrorList{{
 		Message: "Non-nullable field 'name' (type String!) was not present " +
 			"in result from Dgraph.  GraphQL error propagation triggered.",
-		Locations: []x.Location{{Line: 4, Column: 5}},
-		Path:      []interface{}{"queryCountry", float64(0), "name"},
+		Locations:  []x.Location{{Line: 4, Column: 5}},
+		Path:       []interface{}{"queryCountry", float64(0), "name"},
+		Extensions: ErrorExtensions(schema.CodeCompletionFailed),
 	}}, gqlResponse.Errors)
 
 	// response should have extensions
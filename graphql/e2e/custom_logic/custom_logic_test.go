@@ -75,6 +75,11 @@ const (
 	}`
 )
 
+// externalRequestFailed returns the extensions of the errors for a failed custom field.
+func externalRequestFailed() map[string]interface{} {
+	return common.ErrorExtensions(schema.CodeExternalRequestFailed)
+}
+
 func TestCustomGetQuery(t *testing.T) {
 	schema := customTypes + `
 	 type Query {
@@ -447,7 +452,9 @@ func TestCustomQueryWithNonExistentURLShouldReturnError(t *testing.T) {
 		{
 			Message: "Evaluation of custom field failed because external request returned an " +
 				"error: unexpected error with: 404 for field: myFavoriteMovies within type: Query.",
-			Locations: []x.Location{{Line: 3, Column: 3}},
+			Locations:  []x.Location{{Line: 3, Column: 3}},
+			Path:       []interface{}{"myFavoriteMovies"},
+			Extensions: externalRequestFailed(),
 		},
 	}, result.Errors)
 }
@@ -529,13 +536,15 @@ func TestCustomQueryShouldPropagateErrorFromFields(t *testing.T) {
 	expectedErrors := x.GqlErrorList{
 		&x.GqlError{Message: "Evaluation of custom field failed because external request " +
 			"returned an error: unexpected error with: 404 for field: cars within type: Person.",
-			Locations: []x.Location{{Line: 6, Column: 4}},
-			Path:      []interface{}{"queryPerson"},
+			Locations:  []x.Location{{Line: 6, Column: 4}},
+			Path:       []interface{}{"queryPerson"},
+			Extensions: externalRequestFailed(),
 		},
 		&x.GqlError{Message: "Evaluation of custom field failed because external request returned" +
 			" an error: unexpected error with: 404 for field: bikes within type: Person.",
-			Locations: []x.Location{{Line: 9, Column: 4}},
-			Path:      []interface{}{"queryPerson"},
+			Locations:  []x.Location{{Line: 9, Column: 4}},
+			Path:       []interface{}{"queryPerson"},
+			Extensions: externalRequestFailed(),
 		},
 	}
 	require.Contains(t, result.Errors, expectedErrors[0])
@@ -1253,6 +1262,7 @@ func TestCustomFieldResolutionShouldPropagateGraphQLErrors(t *testing.T) {
 	}
 	for _, err := range expectedErrs {
 		err.Path = []interface{}{"queryUser"}
+		err.Extensions = externalRequestFailed()
 	}
 	require.Equal(t, expectedErrs, result.Errors)
 
@@ -1550,12 +1560,18 @@ func TestCustomLogicWithErrorResponse(t *testing.T) {
 	result := params.ExecuteAsPost(t, common.GraphqlURL)
 	require.Equal(t, `{"getCountriesErr":[]}`, string(result.Data))
 	require.Equal(t, x.GqlErrorList{
-		&x.GqlError{Message: "dummy error"},
+		&x.GqlError{
+			Message:    "dummy error",
+			Path:       []interface{}{"getCountriesErr"},
+			Extensions: externalRequestFailed(),
+		},
 		&x.GqlError{
 			Message: "Evaluation of custom field failed because key: country could not be found " +
 				"in the JSON response returned by external request for field: getCountriesErr" +
 				" within type: Query.",
-			Locations: []x.Location{{Line: 3, Column: 3}},
+			Locations:  []x.Location{{Line: 3, Column: 3}},
+			Path:       []interface{}{"getCountriesErr"},
+			Extensions: externalRequestFailed(),
 		},
 	}, result.Errors)
 }
@@ -2773,9 +2789,10 @@ func TestCustomGetQuerywithRESTError(t *testing.T) {
 	result := params.ExecuteAsPost(t, common.GraphqlURL)
 	require.Equal(t, x.GqlErrorList{
 		{
-			Message:   "Rest API returns Error for myFavoriteMovies query",
-			Locations: []x.Location{{Line: 5, Column: 4}},
-			Path:      []interface{}{"Movies", "name"},
+			Message:    "Rest API returns Error for myFavoriteMovies query",
+			Locations:  []x.Location{{Line: 5, Column: 4}},
+			Path:       []interface{}{"Movies", "name"},
+			Extensions: externalRequestFailed(),
 		},
 	}, result.Errors)
 
@@ -2863,8 +2880,9 @@ func TestCustomFieldsWithRestError(t *testing.T) {
 
 	require.Equal(t, x.GqlErrorList{
 		{
-			Message: "Rest API returns Error for field name",
-			Path:    []interface{}{"queryUser"},
+			Message:    "Rest API returns Error for field name",
+			Path:       []interface{}{"queryUser"},
+			Extensions: externalRequestFailed(),
 		},
 	}, result.Errors)
 
@@ -2918,7 +2936,9 @@ func TestCustomPostMutationWithRESTError(t *testing.T) {
 	result := params.ExecuteAsPost(t, common.GraphqlURL)
 	require.Equal(t, x.GqlErrorList{
 		{
-			Message: "Rest API returns Error for FavoriteMoviesCreate query",
+			Message:    "Rest API returns Error for FavoriteMoviesCreate query",
+			Path:       []interface{}{"createMyFavouriteMovies"},
+			Extensions: externalRequestFailed(),
 		},
 	}, result.Errors)
 
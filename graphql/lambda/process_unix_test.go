@@ -0,0 +1,80 @@
+// +build !windows
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lambda
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/stretchr/testify/require"
+)
+
+// running returns whether the process is running, and not a zombie.
+func running(pid int) bool {
+	stat, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	// The state follows the command, which is in parentheses.
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return fields[0] != "Z"
+}
+
+func TestStopKillsChildren(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("Needs /proc")
+	}
+	dir, err := ioutil.TempDir("", "lambda")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	pidFile := filepath.Join(dir, "pid")
+	script := filepath.Join(dir, "server.sh")
+	require.NoError(t, ioutil.WriteFile(script,
+		[]byte("sleep 300 &\necho $! > "+pidFile+"\nwait\n"), 0700))
+
+	s := &server{port: 1}
+	closer := z.NewCloser(1)
+	stopped := make(chan error, 1)
+	go func() {
+		defer closer.Done()
+		stopped <- s.runOnce(Config{Cmd: "sh " + script}, closer)
+	}()
+
+	var pid int
+	require.Eventually(t, func() bool {
+		b, err := ioutil.ReadFile(pidFile)
+		if err != nil {
+			return false
+		}
+		pid, err = strconv.Atoi(strings.TrimSpace(string(b)))
+		return err == nil
+	}, 10*time.Second, 10*time.Millisecond)
+	require.True(t, running(pid))
+
+	closer.SignalAndWait()
+	require.NoError(t, <-stopped)
+	require.Eventually(t, func() bool { return !running(pid) }, 5*time.Second,
+		10*time.Millisecond)
+}
@@ -0,0 +1,290 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package lambda runs and supervises the lambda servers which resolve the GraphQL fields with the
+// @lambda directive, and keeps the versions of the lambda script of each namespace.
+package lambda
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+const (
+	healthCheckInterval = 5 * time.Second
+	healthCheckTimeout  = 2 * time.Second
+	// maxHealthCheckFailures is the number of health checks in a row a lambda server can fail
+	// before it is restarted.
+	maxHealthCheckFailures = 3
+)
+
+// Config is the configuration of the lambda servers run by Alpha.
+type Config struct {
+	// Num is the number of lambda servers to run. None is run if it is 0.
+	Num int
+	// Port is the port of the first lambda server. The others listen on the ports following it.
+	Port int
+	// Cmd is the command which runs a lambda server. The port to listen on and the URL of Alpha
+	// are passed to it in the PORT and DGRAPH_URL environment variables.
+	Cmd string
+	// DgraphURL is the URL the lambda servers reach Alpha at.
+	DgraphURL string
+	// RestartAfter is how long to wait before restarting a lambda server which exited.
+	RestartAfter time.Duration
+}
+
+// ServerStatus is the status of a lambda server run by Alpha.
+type ServerStatus struct {
+	URL       string
+	Healthy   bool
+	Restarts  int
+	LastError string
+}
+
+type server struct {
+	sync.Mutex
+	port     int
+	healthy  bool
+	restarts int
+	lastErr  string
+}
+
+func (s *server) url() string {
+	return fmt.Sprintf("http://localhost:%d/graphql-worker", s.port)
+}
+
+func (s *server) status() ServerStatus {
+	s.Lock()
+	defer s.Unlock()
+	return ServerStatus{URL: s.url(), Healthy: s.healthy, Restarts: s.restarts,
+		LastError: s.lastErr}
+}
+
+var lambdas struct {
+	// servers are the lambda servers run by Alpha. It is only set by Start.
+	servers []*server
+	// next is used to send the requests to the servers in turns.
+	next uint64
+
+	clientOnce sync.Once
+	client     *http.Client
+}
+
+// Start runs conf.Num lambda servers and restarts them whenever they exit or stop being healthy,
+// until closer is signalled. It calls closer.Done once for each of the servers after stopping it.
+func Start(conf Config, closer *z.Closer) error {
+	if conf.Num <= 0 {
+		return nil
+	}
+	if len(strings.Fields(conf.Cmd)) == 0 {
+		return errors.New("the command to run the lambda server isn't set")
+	}
+	if lambdas.servers != nil {
+		return errors.New("the lambda servers have already been started")
+	}
+	for i := 0; i < conf.Num; i++ {
+		lambdas.servers = append(lambdas.servers, &server{port: conf.Port + i})
+	}
+	for _, s := range lambdas.servers {
+		closer.AddRunning(1)
+		go s.run(conf, closer)
+	}
+	return nil
+}
+
+// Managed returns true if the lambda servers are run by Alpha.
+func Managed() bool {
+	return len(lambdas.servers) > 0
+}
+
+// URL returns the URL of the lambda server the next request should be sent to. Requests are sent
+// to the healthy servers in turns. It returns the URL of any of the servers if none is healthy,
+// and an empty string if the lambda servers aren't run by Alpha.
+func URL() string {
+	n := uint64(len(lambdas.servers))
+	if n == 0 {
+		return ""
+	}
+	start := atomic.AddUint64(&lambdas.next, 1)
+	for i := uint64(0); i < n; i++ {
+		s := lambdas.servers[(start+i)%n]
+		s.Lock()
+		healthy := s.healthy
+		s.Unlock()
+		if healthy {
+			return s.url()
+		}
+	}
+	return lambdas.servers[start%n].url()
+}
+
+// FirstURL returns the URL of the first lambda server run by Alpha.
+func FirstURL(conf Config) string {
+	return (&server{port: conf.Port}).url()
+}
+
+// Status returns the status of the lambda servers run by Alpha.
+func Status() []ServerStatus {
+	statuses := make([]ServerStatus, 0, len(lambdas.servers))
+	for _, s := range lambdas.servers {
+		statuses = append(statuses, s.status())
+	}
+	return statuses
+}
+
+// Client returns the HTTP client to send the requests to the lambda server with.
+func Client() *http.Client {
+	lambdas.clientOnce.Do(func() {
+		timeout := x.Config.GraphqlLambdaTimeout
+		if timeout <= 0 {
+			timeout = time.Minute
+		}
+		lambdas.client = &http.Client{Timeout: timeout}
+	})
+	return lambdas.client
+}
+
+// RecordInvocation records the latency of a request to the lambda server for resolver, which
+// started at start.
+func RecordInvocation(resolver string, start time.Time, failed bool) {
+	status := x.TagValueStatusOK
+	if failed {
+		status = x.TagValueStatusError
+	}
+	ctx, err := tag.New(context.Background(), tag.Upsert(x.KeyMethod, resolver),
+		tag.Upsert(x.KeyStatus, status))
+	if err != nil {
+		glog.Errorf("Unable to record the lambda invocation of %s: %v", resolver, err)
+		return
+	}
+	ostats.Record(ctx, x.LambdaLatencyMs.M(x.SinceMs(start)))
+}
+
+func (s *server) run(conf Config, closer *z.Closer) {
+	defer closer.Done()
+	for {
+		err := s.runOnce(conf, closer)
+
+		s.Lock()
+		s.healthy = false
+		if err != nil {
+			s.lastErr = err.Error()
+		}
+		s.Unlock()
+
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case <-time.After(conf.RestartAfter):
+		}
+		glog.Warningf("Restarting the lambda server on port %d after: %v", s.port, err)
+		s.Lock()
+		s.restarts++
+		s.Unlock()
+		ostats.Record(context.Background(), x.NumLambdaRestarts.M(1))
+	}
+}
+
+// runOnce runs the lambda server until it exits, fails too many health checks in a row, or closer
+// is signalled. It returns the reason the server stopped.
+func (s *server) runOnce(conf Config, closer *z.Closer) error {
+	args := strings.Fields(conf.Cmd)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("PORT=%d", s.port),
+		"DGRAPH_URL="+conf.DgraphURL)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return errors.Wrapf(err, "while starting the lambda server on port %d", s.port)
+	}
+	glog.Infof("Started the lambda server on port %d with pid %d", s.port, cmd.Process.Pid)
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	// The lambda server is killed along with the processes it started, which would otherwise keep
+	// running, and keep its port, after it's stopped or once it exits.
+	stop := func(reason error) error {
+		if err := killProcessGroup(cmd); err != nil {
+			glog.Errorf("Unable to kill the lambda server on port %d: %v", s.port, err)
+		}
+		<-exited
+		return reason
+	}
+
+	client := &http.Client{Timeout: healthCheckTimeout}
+	healthURL := fmt.Sprintf("http://localhost:%d/health", s.port)
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case err := <-exited:
+			// The processes left behind are killed too, if there are any.
+			_ = killProcessGroup(cmd)
+			if err == nil {
+				err = errors.Errorf("the lambda server on port %d exited", s.port)
+			}
+			return err
+		case <-closer.HasBeenClosed():
+			return stop(nil)
+		case <-ticker.C:
+			err := checkHealth(client, healthURL)
+			s.Lock()
+			s.healthy = err == nil
+			if err != nil {
+				s.lastErr = err.Error()
+			}
+			s.Unlock()
+			if err == nil {
+				failures = 0
+				continue
+			}
+			if failures++; failures >= maxHealthCheckFailures {
+				return stop(errors.Wrapf(err, "the lambda server on port %d failed %d health "+
+					"checks in a row", s.port, failures))
+			}
+		}
+	}
+}
+
+func checkHealth(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
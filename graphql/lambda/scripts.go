@@ -0,0 +1,179 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lambda
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxScriptVersions is the number of versions of the script of a namespace which are kept.
+	// The oldest versions are dropped when a new one is deployed.
+	maxScriptVersions = 10
+	// scriptRefreshInterval is how often the script of a namespace is read again from Dgraph, so
+	// that a script deployed via another Alpha gets served by this one too.
+	scriptRefreshInterval = 10 * time.Second
+)
+
+const (
+	// RuntimeJS scripts are JavaScript, and are run by the lambda server.
+	RuntimeJS = "JS"
+	// RuntimeWasm scripts are base64 encoded WASM modules, and are run by Alpha.
+	RuntimeWasm = "WASM"
+)
+
+// ScriptVersion is a deployed version of the lambda script of a namespace.
+type ScriptVersion struct {
+	Version int    `json:"version"`
+	Script  string `json:"script"`
+	// Runtime is either RuntimeJS or RuntimeWasm. It is empty for the scripts deployed before
+	// WASM was supported, which are JavaScript.
+	Runtime    string    `json:"runtime,omitempty"`
+	DeployedAt time.Time `json:"deployedAt"`
+}
+
+// IsWasm returns true if the script is a WASM module.
+func (v *ScriptVersion) IsWasm() bool {
+	return v.Runtime == RuntimeWasm
+}
+
+// Scripts are the versions of the lambda script of a namespace, as they are stored in Dgraph.
+// Requests for lambda fields are served by the active version.
+type Scripts struct {
+	Active   int             `json:"active"`
+	Versions []ScriptVersion `json:"versions"`
+}
+
+// ParseScripts parses the scripts stored in Dgraph. Empty input gives no scripts.
+func ParseScripts(b []byte) (*Scripts, error) {
+	s := &Scripts{}
+	if len(b) == 0 {
+		return s, nil
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, errors.Wrap(err, "while parsing lambda scripts")
+	}
+	return s, nil
+}
+
+// Get returns the given version of the script, or the active version if version is 0. It returns
+// nil if there is no such version.
+func (s *Scripts) Get(version int) *ScriptVersion {
+	if version == 0 {
+		version = s.Active
+	}
+	for i := range s.Versions {
+		if s.Versions[i].Version == version {
+			return &s.Versions[i]
+		}
+	}
+	return nil
+}
+
+// Deploy adds script as a new version for the given runtime and makes it the active one.
+func (s *Scripts) Deploy(script, runtime string, now time.Time) *ScriptVersion {
+	next := 1
+	if len(s.Versions) > 0 {
+		next = s.Versions[len(s.Versions)-1].Version + 1
+	}
+	s.Versions = append(s.Versions, ScriptVersion{Version: next, Script: script, Runtime: runtime,
+		DeployedAt: now})
+	s.Active = next
+
+	// Drop the oldest versions, but never the active one.
+	if len(s.Versions) > maxScriptVersions {
+		s.Versions = append(s.Versions[:0:0], s.Versions[len(s.Versions)-maxScriptVersions:]...)
+	}
+	return s.Get(next)
+}
+
+// Activate makes the given version the active one. It is used to roll back a deployment.
+func (s *Scripts) Activate(version int) (*ScriptVersion, error) {
+	v := s.Get(version)
+	if version == 0 || v == nil {
+		return nil, errors.Errorf("lambda script version %d doesn't exist", version)
+	}
+	s.Active = version
+	return v, nil
+}
+
+// Marshal returns the scripts in the format they are stored in Dgraph.
+func (s *Scripts) Marshal() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+type cachedScript struct {
+	// script is nil if no script has been deployed for the namespace.
+	script   *ScriptVersion
+	loadedAt time.Time
+}
+
+var scripts struct {
+	sync.RWMutex
+	// active maps a namespace to its active script.
+	active map[uint64]cachedScript
+	// load reads the scripts of a namespace from Dgraph.
+	load func(namespace uint64) (*Scripts, error)
+}
+
+// SetLoader sets the function which reads the scripts of a namespace from Dgraph.
+func SetLoader(load func(namespace uint64) (*Scripts, error)) {
+	scripts.Lock()
+	defer scripts.Unlock()
+	scripts.load = load
+}
+
+// SetScripts updates the script served for the namespace, after its scripts were changed by
+// this Alpha.
+func SetScripts(namespace uint64, s *Scripts) {
+	var script *ScriptVersion
+	if v := s.Get(0); v != nil {
+		active := *v
+		script = &active
+	}
+	scripts.Lock()
+	defer scripts.Unlock()
+	if scripts.active == nil {
+		scripts.active = make(map[uint64]cachedScript)
+	}
+	scripts.active[namespace] = cachedScript{script: script, loadedAt: time.Now()}
+}
+
+// Script returns the active lambda script of the namespace, or nil if no script has been deployed
+// for it. Such a namespace is served by the script the lambda server was started with.
+func Script(namespace uint64) *ScriptVersion {
+	scripts.RLock()
+	cached, ok := scripts.active[namespace]
+	load := scripts.load
+	scripts.RUnlock()
+	if (ok && time.Since(cached.loadedAt) < scriptRefreshInterval) || load == nil {
+		return cached.script
+	}
+
+	s, err := load(namespace)
+	if err != nil {
+		glog.Errorf("Unable to read the lambda script of namespace %#x: %v", namespace, err)
+		return cached.script
+	}
+	SetScripts(namespace, s)
+	return Script(namespace)
+}
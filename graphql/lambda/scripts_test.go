@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lambda
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScripts(t *testing.T) {
+	s, err := ParseScripts(nil)
+	require.NoError(t, err)
+	require.Nil(t, s.Get(0))
+
+	now := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	v := s.Deploy("script 1", RuntimeJS, now)
+	require.Equal(t, 1, v.Version)
+	v = s.Deploy("script 2", RuntimeJS, now)
+	require.Equal(t, 2, v.Version)
+	require.Equal(t, "script 2", s.Get(0).Script)
+
+	// Roll back to the first version.
+	_, err = s.Activate(1)
+	require.NoError(t, err)
+	require.Equal(t, "script 1", s.Get(0).Script)
+	_, err = s.Activate(3)
+	require.EqualError(t, err, "lambda script version 3 doesn't exist")
+
+	b, err := s.Marshal()
+	require.NoError(t, err)
+	parsed, err := ParseScripts(b)
+	require.NoError(t, err)
+	require.Equal(t, s, parsed)
+
+	// Only the latest versions are kept, and versions keep increasing.
+	for i := 3; i <= maxScriptVersions+5; i++ {
+		s.Deploy(fmt.Sprintf("script %d", i), RuntimeJS, now)
+	}
+	require.Len(t, s.Versions, maxScriptVersions)
+	require.Nil(t, s.Get(1))
+	require.Equal(t, maxScriptVersions+5, s.Active)
+	require.Equal(t, 6, s.Versions[0].Version)
+
+	_, err = ParseScripts([]byte("{"))
+	require.Error(t, err)
+}
+
+func TestScript(t *testing.T) {
+	loads := 0
+	SetLoader(func(namespace uint64) (*Scripts, error) {
+		loads++
+		s := &Scripts{}
+		if namespace == 1 {
+			s.Deploy("stored script", RuntimeJS, time.Now())
+		}
+		return s, nil
+	})
+	defer SetLoader(nil)
+
+	require.Nil(t, Script(0))
+	require.Equal(t, "stored script", Script(1).Script)
+	require.Equal(t, "stored script", Script(1).Script)
+	require.Equal(t, 2, loads)
+
+	// A script deployed by this Alpha is served without reading it again.
+	s := &Scripts{}
+	s.Deploy("new script", RuntimeWasm, time.Now())
+	SetScripts(0, s)
+	require.Equal(t, "new script", Script(0).Script)
+	require.True(t, Script(0).IsWasm())
+	require.Equal(t, 2, loads)
+}
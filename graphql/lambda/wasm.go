@@ -0,0 +1,165 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// A WASM lambda script is a module compiled for WASI, e.g. from Rust, Go or AssemblyScript. Alpha
+// runs it in an embedded runtime for every request to a lambda field. The module reads the
+// request from stdin, in the format the lambda server gets it, and writes its result to stdout,
+// in the format the lambda server responds with. If it exits with a non-zero code, the request
+// fails with the GraphQL errors it wrote to stdout, or with what it wrote to stderr.
+//
+// The memory of a module is limited by --graphql_lambda_wasm_memory_mb, and its run time by
+// --graphql_lambda_timeout.
+
+const (
+	wasmPageSize = 64 << 10
+	// maxCompiledModules is the number of compiled modules which are kept. All of them are
+	// dropped when there are more, which only happens after many deployments.
+	maxCompiledModules = 64
+)
+
+var wasm struct {
+	// The modules are run with a read lock held, so that they aren't closed while being run.
+	sync.RWMutex
+	runtime wazero.Runtime
+	// modules maps the sha256 of a module to its compiled form.
+	modules map[[sha256.Size]byte]wazero.CompiledModule
+}
+
+func decodeWasm(script string) ([]byte, error) {
+	module, err := base64.StdEncoding.DecodeString(strings.TrimSpace(script))
+	if err != nil {
+		return nil, errors.Wrap(err, "WASM lambda script must be base64 encoded")
+	}
+	return module, nil
+}
+
+// compileWasm compiles module and keeps it in wasm.modules.
+func compileWasm(module []byte, sum [sha256.Size]byte) error {
+	wasm.Lock()
+	defer wasm.Unlock()
+	ctx := context.Background()
+	if wasm.runtime == nil {
+		config := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+		if limit := x.Config.GraphqlLambdaWasmMemoryMB << 20 / wasmPageSize; limit > 0 {
+			config = config.WithMemoryLimitPages(uint32(limit))
+		}
+		wasm.runtime = wazero.NewRuntimeWithConfig(ctx, config)
+		wasi_snapshot_preview1.MustInstantiate(ctx, wasm.runtime)
+		wasm.modules = make(map[[sha256.Size]byte]wazero.CompiledModule)
+	}
+	if _, ok := wasm.modules[sum]; ok {
+		return nil
+	}
+
+	compiled, err := wasm.runtime.CompileModule(ctx, module)
+	if err != nil {
+		return errors.Wrap(err, "while compiling WASM lambda script")
+	}
+	if len(wasm.modules) >= maxCompiledModules {
+		for k, m := range wasm.modules {
+			_ = m.Close(ctx)
+			delete(wasm.modules, k)
+		}
+	}
+	wasm.modules[sum] = compiled
+	return nil
+}
+
+// ValidateWasm returns an error if script isn't a base64 encoded WASM module which can be run
+// within the memory limit.
+func ValidateWasm(script string) error {
+	module, err := decodeWasm(script)
+	if err != nil {
+		return err
+	}
+	return compileWasm(module, sha256.Sum256(module))
+}
+
+// RunWasm runs the base64 encoded WASM module script with input as its stdin, and returns what it
+// wrote to stdout.
+func RunWasm(ctx context.Context, script string, input []byte) ([]byte, error) {
+	module, err := decodeWasm(script)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(module)
+	for {
+		wasm.RLock()
+		if compiled, ok := wasm.modules[sum]; ok {
+			defer wasm.RUnlock()
+			return runWasm(ctx, compiled, input)
+		}
+		wasm.RUnlock()
+		if err := compileWasm(module, sum); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func runWasm(ctx context.Context, compiled wazero.CompiledModule, input []byte) ([]byte, error) {
+	timeout := x.Config.GraphqlLambdaTimeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	config := wazero.NewModuleConfig().
+		// Modules without a name can be run concurrently.
+		WithName("").
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(&stdout).
+		WithStderr(&stderr)
+	mod, err := wasm.runtime.InstantiateModule(ctx, compiled, config)
+	if mod != nil {
+		_ = mod.Close(ctx)
+	}
+	if err == nil {
+		return stdout.Bytes(), nil
+	}
+
+	if exitErr, ok := err.(*sys.ExitError); ok {
+		switch exitErr.ExitCode() {
+		case sys.ExitCodeDeadlineExceeded:
+			return nil, errors.Errorf("WASM lambda didn't finish within %s", timeout)
+		case sys.ExitCodeContextCanceled:
+			return nil, errors.New("WASM lambda was cancelled")
+		}
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		err = errors.Errorf("%v: %s", err, msg)
+	}
+	return stdout.Bytes(), errors.Wrap(err, "while running WASM lambda")
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lambda
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// helloModule writes "hello" as JSON to stdout.
+	helloModule = []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		// types: (i32, i32, i32, i32) -> i32 and () -> ()
+		0x01, 0x0c, 0x02, 0x60, 0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x01, 0x7f, 0x60, 0x00, 0x00,
+		// import wasi_snapshot_preview1.fd_write
+		0x02, 0x23, 0x01, 0x16,
+		'w', 'a', 's', 'i', '_', 's', 'n', 'a', 'p', 's', 'h', 'o', 't', '_',
+		'p', 'r', 'e', 'v', 'i', 'e', 'w', '1',
+		0x08, 'f', 'd', '_', 'w', 'r', 'i', 't', 'e', 0x00, 0x00,
+		// functions: _start
+		0x03, 0x02, 0x01, 0x01,
+		// memory of 1 page
+		0x05, 0x03, 0x01, 0x00, 0x01,
+		// exports: memory and _start
+		0x07, 0x13, 0x02,
+		0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+		0x06, '_', 's', 't', 'a', 'r', 't', 0x00, 0x01,
+		// _start: fd_write(1, iovs=0, iovs_len=1, nwritten=8)
+		0x0a, 0x0f, 0x01, 0x0d, 0x00,
+		0x41, 0x01, 0x41, 0x00, 0x41, 0x01, 0x41, 0x08, 0x10, 0x00, 0x1a, 0x0b,
+		// data: the iovec at 0, pointing to the 7 bytes at 16
+		0x0b, 0x1a, 0x02,
+		0x00, 0x41, 0x00, 0x0b, 0x08, 0x10, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00,
+		0x00, 0x41, 0x10, 0x0b, 0x07, '"', 'h', 'e', 'l', 'l', 'o', '"',
+	}
+
+	// loopModule never finishes.
+	loopModule = []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+		0x03, 0x02, 0x01, 0x00,
+		0x07, 0x0a, 0x01, 0x06, '_', 's', 't', 'a', 'r', 't', 0x00, 0x00,
+		// _start: loop br 0 end
+		0x0a, 0x09, 0x01, 0x07, 0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b,
+	}
+
+	// bigMemoryModule needs 2000 pages of memory.
+	bigMemoryModule = []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x05, 0x04, 0x01, 0x00, 0xd0, 0x0f,
+	}
+)
+
+func TestRunWasm(t *testing.T) {
+	x.Config.GraphqlLambdaWasmMemoryMB = 1
+	x.Config.GraphqlLambdaTimeout = 100 * time.Millisecond
+	encode := base64.StdEncoding.EncodeToString
+	ctx := context.Background()
+
+	out, err := RunWasm(ctx, encode(helloModule), []byte(`{"resolver":"Query.hello"}`))
+	require.NoError(t, err)
+	require.Equal(t, `"hello"`, string(out))
+
+	_, err = RunWasm(ctx, encode(loopModule), nil)
+	require.EqualError(t, err, "WASM lambda didn't finish within 100ms")
+
+	require.NoError(t, ValidateWasm(encode(helloModule)))
+	err = ValidateWasm(encode(bigMemoryModule))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "while compiling WASM lambda script")
+	require.Error(t, ValidateWasm("not base64"))
+}
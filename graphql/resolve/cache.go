@@ -0,0 +1,164 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolve
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dgraph-io/dgraph/graphql/schema"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto"
+	"github.com/golang/glog"
+	ostats "go.opencensus.io/stats"
+)
+
+// queryCache holds the results of the queries with the @cache directive. It is shared by all the
+// namespaces and schemas, so a key starts with the id of the RequestResolver it was cached by.
+// A schema update creates a new RequestResolver, and so the results cached for the old schema are
+// never served again and just wait to be evicted.
+var queryCache struct {
+	sync.Once
+	cache *ristretto.Cache
+}
+
+// lastResolverID is used to give each RequestResolver a unique id.
+var lastResolverID uint64
+
+func nextResolverID() uint64 {
+	return atomic.AddUint64(&lastResolverID, 1)
+}
+
+// getQueryCache returns the query cache, or nil if it is disabled.
+func getQueryCache() *ristretto.Cache {
+	queryCache.Do(func() {
+		if x.Config.GraphqlCacheMB <= 0 {
+			return
+		}
+		maxCost := x.Config.GraphqlCacheMB << 20
+		cache, err := ristretto.NewCache(&ristretto.Config{
+			// Assume that an average result is about 1KB, and keep 10 counters per result.
+			NumCounters: maxCost / 100,
+			MaxCost:     maxCost,
+			BufferItems: 64,
+		})
+		if err != nil {
+			glog.Errorf("Unable to create the GraphQL query cache: %v", err)
+			return
+		}
+		queryCache.cache = cache
+	})
+	return queryCache.cache
+}
+
+// resolveQuery resolves the query q. If q has the @cache directive, its result is served from
+// the query cache while it is there, and is added to it otherwise.
+func (r *RequestResolver) resolveQuery(ctx context.Context, q schema.Query) *Resolved {
+	resolver := r.resolvers.queryResolverFor(q)
+	policy := q.CachePolicy()
+	if policy == nil {
+		return resolver.Resolve(ctx, q)
+	}
+	cache := getQueryCache()
+	if cache == nil {
+		return resolver.Resolve(ctx, q)
+	}
+	key, err := r.cacheKey(ctx, q, policy.Scope)
+	if err != nil {
+		// The query would fail while being resolved anyway, so let that report the error.
+		return resolver.Resolve(ctx, q)
+	}
+
+	if data, ok := cache.Get(key); ok {
+		ostats.Record(ctx, x.NumGraphQLCacheHits.M(1))
+		return &Resolved{Data: data.([]byte), Field: q}
+	}
+	ostats.Record(ctx, x.NumGraphQLCacheMisses.M(1))
+
+	resolved := resolver.Resolve(ctx, q)
+	// Results with errors aren't cached, as the errors might be temporary.
+	if resolved != nil && (resolved.Err == nil || resolved.Err.Error() == "") &&
+		len(resolved.Data) > 0 {
+		cache.SetWithTTL(key, resolved.Data, int64(len(resolved.Data)), policy.TTL)
+	}
+	return resolved
+}
+
+// cacheKey returns the key of the result of q in the query cache. Results are shared by the
+// requests for the same query in the same namespace. Results with the PER_AUTH scope are only
+// shared by the requests which also have the same JWT claims and ACL access token.
+func (r *RequestResolver) cacheKey(ctx context.Context, q schema.Query,
+	scope schema.CacheScope) (string, error) {
+	var sb strings.Builder
+	ns, _ := x.ExtractNamespace(ctx)
+	sb.WriteString(strconv.FormatUint(r.id, 10))
+	sb.WriteByte('-')
+	sb.WriteString(strconv.FormatUint(ns, 10))
+	sb.WriteByte('-')
+	sb.WriteString(string(scope))
+
+	if scope != schema.CachePublic {
+		customClaims, err := q.GetAuthMeta().ExtractCustomClaims(ctx)
+		if err != nil {
+			return "", schema.WithErrorCode(err, schema.CodeUnauthenticated)
+		}
+		claims, err := json.Marshal(customClaims.AuthVariables)
+		if err != nil {
+			return "", err
+		}
+		sb.Write(claims)
+		accessJwt, _ := x.ExtractJwt(ctx)
+		sb.WriteString(strings.Join(accessJwt, ","))
+	}
+
+	if err := writeFieldKey(&sb, q); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// writeFieldKey writes everything about the field f that its result depends on to sb.
+func writeFieldKey(sb *strings.Builder, f schema.Field) error {
+	sb.WriteByte(' ')
+	sb.WriteString(f.ResponseName())
+	sb.WriteByte(':')
+	sb.WriteString(f.GetObjectName())
+	sb.WriteByte('.')
+	sb.WriteString(f.Name())
+	args, err := json.Marshal(f.Arguments())
+	if err != nil {
+		return err
+	}
+	sb.Write(args)
+	sb.WriteString(strconv.FormatBool(f.Skip() || !f.Include()))
+	if cascade := f.Cascade(); cascade != nil {
+		sb.WriteString("@cascade" + strings.Join(cascade, ","))
+	}
+
+	sb.WriteByte('{')
+	for _, child := range f.SelectionSet() {
+		if err := writeFieldKey(sb, child); err != nil {
+			return err
+		}
+	}
+	sb.WriteByte('}')
+	return nil
+}
@@ -112,7 +112,7 @@ func (mws MutationMiddlewares) Then(resolver MutationResolver) MutationResolver
 // Guardian of Galaxy auth, otherwise it returns nil
 func resolveGuardianOfTheGalaxyAuth(ctx context.Context, f schema.Field) *Resolved {
 	if err := edgraph.AuthGuardianOfTheGalaxy(ctx); err != nil {
-		return EmptyResult(f, err)
+		return EmptyResult(f, withErrorCode(err, schema.CodeForbidden))
 	}
 	return nil
 }
@@ -121,14 +121,14 @@ func resolveGuardianOfTheGalaxyAuth(ctx context.Context, f schema.Field) *Resolv
 // otherwise it returns nil
 func resolveGuardianAuth(ctx context.Context, f schema.Field) *Resolved {
 	if err := edgraph.AuthorizeGuardians(ctx); err != nil {
-		return EmptyResult(f, err)
+		return EmptyResult(f, withErrorCode(err, schema.CodeForbidden))
 	}
 	return nil
 }
 
 func resolveIpWhitelisting(ctx context.Context, f schema.Field) *Resolved {
 	if _, err := x.HasWhitelistedIP(ctx); err != nil {
-		return EmptyResult(f, err)
+		return EmptyResult(f, withErrorCode(err, schema.CodeForbidden))
 	}
 	return nil
 }
@@ -25,6 +25,7 @@ import (
 	"strconv"
 
 	dgoapi "github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/edgraph"
 	"github.com/dgraph-io/dgraph/gql"
 	"github.com/dgraph-io/dgraph/graphql/dgraph"
 	"github.com/dgraph-io/dgraph/graphql/schema"
@@ -222,6 +223,15 @@ func (mr *dgraphResolver) rewriteAndExecute(
 		}
 	}()
 
+	// Record the user set in the JWT in the audit trail of the nodes of the mutation.
+	if claim := edgraph.AuditTrailClaim(); claim != "" {
+		if claims, err := mutation.GetAuthMeta().ExtractCustomClaims(ctx); err == nil {
+			if user, ok := claims.AuthVariables[claim]; ok {
+				ctx = edgraph.WithAuditUser(ctx, fmt.Sprint(user))
+			}
+		}
+	}
+
 	dgraphMutationDuration := &schema.LabeledOffsetDuration{Label: "mutation"}
 	dgraphQueryDuration := &schema.LabeledOffsetDuration{Label: "query"}
 	ext := &schema.Extensions{
@@ -261,8 +271,8 @@ func (mr *dgraphResolver) rewriteAndExecute(
 	var queries []*gql.GraphQuery
 	queries, err = mr.mutationRewriter.RewriteQueries(ctx, mutation)
 	if err != nil {
-		return emptyResult(schema.GQLWrapf(err, "couldn't rewrite mutation %s", mutation.Name())),
-			resolverFailed
+		return emptyResult(schema.GQLWrapf(withErrorCode(err, schema.CodeBadUserInput),
+			"couldn't rewrite mutation %s", mutation.Name())), resolverFailed
 	}
 	// Execute queries and parse its result into a map
 	qry := dgraph.AsString(queries)
@@ -275,8 +285,8 @@ func (mr *dgraphResolver) rewriteAndExecute(
 		mutResp, err = mr.executor.Execute(ctx, req, nil)
 	}
 	if err != nil {
-		gqlErr := schema.GQLWrapLocationf(
-			err, mutation.Location(), "mutation %s failed", mutation.Name())
+		gqlErr := schema.GQLWrapLocationf(withErrorCode(err, schema.CodeExecutionFailed),
+			mutation.Location(), "mutation %s failed", mutation.Name())
 		return emptyResult(gqlErr), resolverFailed
 	}
 
@@ -303,8 +313,8 @@ func (mr *dgraphResolver) rewriteAndExecute(
 		err = json.Unmarshal(mutResp.Json, &queryResultMap)
 	}
 	if err != nil {
-		gqlErr := schema.GQLWrapLocationf(
-			err, mutation.Location(), "mutation %s failed", mutation.Name())
+		gqlErr := schema.GQLWrapLocationf(withErrorCode(err, schema.CodeExecutionFailed),
+			mutation.Location(), "mutation %s failed", mutation.Name())
 		return emptyResult(gqlErr), resolverFailed
 	}
 
@@ -324,8 +334,8 @@ func (mr *dgraphResolver) rewriteAndExecute(
 			// Found multiple UIDs for query. This should ideally not happen.
 			// This indicates that there are multiple nodes with same XIDs / UIDs. Throw an error.
 			err = errors.New(fmt.Sprintf("Found multiple nodes with ID: %s", result[0]["uid"]))
-			gqlErr := schema.GQLWrapLocationf(
-				err, mutation.Location(), "mutation %s failed", mutation.Name())
+			gqlErr := schema.GQLWrapLocationf(schema.WithErrorCode(err, schema.CodeExecutionFailed),
+				mutation.Location(), "mutation %s failed", mutation.Name())
 			return emptyResult(gqlErr), resolverFailed
 		}
 	}
@@ -334,8 +344,8 @@ func (mr *dgraphResolver) rewriteAndExecute(
 	upserts, err = mr.mutationRewriter.Rewrite(ctx, mutation, qNameToUID)
 
 	if err != nil {
-		return emptyResult(schema.GQLWrapf(err, "couldn't rewrite mutation %s", mutation.Name())),
-			resolverFailed
+		return emptyResult(schema.GQLWrapf(withErrorCode(err, schema.CodeBadUserInput),
+			"couldn't rewrite mutation %s", mutation.Name())), resolverFailed
 	}
 	if len(upserts) == 0 {
 		return &Resolved{
@@ -362,10 +372,10 @@ func (mr *dgraphResolver) rewriteAndExecute(
 			queryTimer.Stop()
 
 			if err != nil && !x.IsGqlErrorList(err) {
-				return emptyResult(schema.GQLWrapf(err, "couldn't execute query for mutation %s",
-					mutation.Name())), resolverFailed
+				return emptyResult(schema.GQLWrapf(withErrorCode(err, schema.CodeExecutionFailed),
+					"couldn't execute query for mutation %s", mutation.Name())), resolverFailed
 			} else {
-				queryErrs = err
+				queryErrs = schema.WithErrorCode(err, schema.CodeCompletionFailed)
 			}
 			ext.TouchedUids += qryResp.GetMetrics().GetNumUids()[touchedUidsKey]
 		}
@@ -382,8 +392,8 @@ func (mr *dgraphResolver) rewriteAndExecute(
 		req.Mutations = upsert.Mutations
 		mutResp, err = mr.executor.Execute(ctx, req, nil)
 		if err != nil {
-			gqlErr := schema.GQLWrapLocationf(
-				err, mutation.Location(), "mutation %s failed", mutation.Name())
+			gqlErr := schema.GQLWrapLocationf(withErrorCode(err, schema.CodeExecutionFailed),
+				mutation.Location(), "mutation %s failed", mutation.Name())
 			return emptyResult(gqlErr), resolverFailed
 
 		}
@@ -403,22 +413,23 @@ func (mr *dgraphResolver) rewriteAndExecute(
 
 	authErr := authorizeNewNodes(ctx, mutation, mutResp.Uids, newNodes, mr.executor, mutResp.Txn)
 	if authErr != nil {
-		return emptyResult(schema.GQLWrapf(authErr, "mutation failed")), resolverFailed
+		return emptyResult(schema.GQLWrapf(schema.WithErrorCode(authErr, schema.CodeForbidden),
+			"mutation failed")), resolverFailed
 	}
 
 	var dgQuery []*gql.GraphQuery
 	dgQuery, err = mr.mutationRewriter.FromMutationResult(ctx, mutation, mutResp.GetUids(), result)
-	queryErrs = schema.AppendGQLErrs(queryErrs, schema.GQLWrapf(err,
-		"couldn't rewrite query for mutation %s", mutation.Name()))
+	queryErrs = schema.AppendGQLErrs(queryErrs, schema.GQLWrapf(
+		schema.WithErrorCode(err, schema.CodeInternal), "couldn't rewrite query for mutation %s",
+		mutation.Name()))
 	if dgQuery == nil && err != nil {
 		return emptyResult(queryErrs), resolverFailed
 	}
 
 	err = mr.executor.CommitOrAbort(ctx, mutResp.Txn)
 	if err != nil {
-		return emptyResult(
-				schema.GQLWrapf(err, "mutation failed, couldn't commit transaction")),
-			resolverFailed
+		return emptyResult(schema.GQLWrapf(withErrorCode(err, schema.CodeExecutionFailed),
+			"mutation failed, couldn't commit transaction")), resolverFailed
 	}
 	commit = true
 
@@ -431,8 +442,10 @@ func (mr *dgraphResolver) rewriteAndExecute(
 		queryTimer.Stop()
 
 		if !x.IsGqlErrorList(err) {
-			err = schema.GQLWrapf(err, "couldn't execute query for mutation %s", mutation.Name())
+			err = schema.GQLWrapf(withErrorCode(err, schema.CodeExecutionFailed),
+				"couldn't execute query for mutation %s", mutation.Name())
 		}
+		err = schema.WithErrorCode(err, schema.CodeCompletionFailed)
 		queryErrs = schema.AppendGQLErrs(queryErrs, err)
 		ext.TouchedUids += qryResp.GetMetrics().GetNumUids()[touchedUidsKey]
 	}
@@ -523,7 +536,8 @@ func authorizeNewNodes(
 
 	customClaims, err := m.GetAuthMeta().ExtractCustomClaims(ctx)
 	if err != nil {
-		return schema.GQLWrapf(err, "authorization failed")
+		return schema.GQLWrapf(schema.WithErrorCode(err, schema.CodeUnauthenticated),
+			"authorization failed")
 	}
 	authVariables := customClaims.AuthVariables
 	newRw := &authRewriter{
@@ -108,6 +108,12 @@ type xidMetadata struct {
 	seenAtTopLevel map[string]bool
 	// seenUIDs tells whether the UID is previously been seen during DFS traversal
 	seenUIDs map[string]bool
+	// nestedUpsert is set if existing nodes referenced by xid in a deep mutation should be
+	// updated with the rest of their object, instead of only being linked.
+	nestedUpsert bool
+	// strictReferences is set if nodes referenced by xid in a deep mutation must already exist,
+	// instead of being created.
+	strictReferences bool
 }
 
 // A mutationBuilder can build a json mutation []byte from a mutationFragment
@@ -219,6 +225,13 @@ func (xidMetadata *xidMetadata) isDuplicateXid(atTopLevel bool, xidVar string,
 	return false
 }
 
+// setReferenceOptions reads the arguments of m which control how the nodes referenced by xid
+// in a deep mutation are handled.
+func (xidMetadata *xidMetadata) setReferenceOptions(m schema.Mutation) {
+	xidMetadata.nestedUpsert, _ = m.ArgValue(schema.NestedUpsertArgName).(bool)
+	xidMetadata.strictReferences, _ = m.ArgValue(schema.StrictReferencesArgName).(bool)
+}
+
 // RewriteQueries takes a GraphQL schema.Mutation add and creates queries to find out if
 // referenced nodes by XID and UID exist or not.
 // m must have a single argument called 'input' that carries the mutation data.
@@ -248,6 +261,9 @@ func (xidMetadata *xidMetadata) isDuplicateXid(atTopLevel bool, xidVar string,
 // If it is found out that there is an existing country, no modifications are made to
 // the country's attributes and its children. Mutations of the country's children are
 // simply ignored.
+// If the mutation has nestedUpsert set, the existing country is instead updated with the given
+// attributes and children. If it has strictReferences set, the mutation fails if the country
+// doesn't exist instead of creating it.
 // If it is found out that the Person with id 0x123 does not exist, the corresponding
 // mutation will fail.
 func (mrw *AddRewriter) RewriteQueries(
@@ -256,6 +272,7 @@ func (mrw *AddRewriter) RewriteQueries(
 
 	mrw.VarGen = NewVariableGenerator()
 	mrw.XidMetadata = NewXidMetadata()
+	mrw.XidMetadata.setReferenceOptions(m)
 
 	mutatedType := m.MutatedType()
 	val, _ := m.ArgValue(schema.InputArgName).([]interface{})
@@ -305,6 +322,7 @@ func (urw *UpdateRewriter) RewriteQueries(
 
 	urw.VarGen = NewVariableGenerator()
 	urw.XidMetadata = NewXidMetadata()
+	urw.XidMetadata.setReferenceOptions(m)
 
 	inp := m.ArgValue(schema.InputArgName).(map[string]interface{})
 	setArg := inp["set"]
@@ -451,7 +469,7 @@ func (mrw *AddRewriter) Rewrite(
 			// Add auth queries for upsert mutation.
 			customClaims, err := m.GetAuthMeta().ExtractCustomClaims(ctx)
 			if err != nil {
-				return ret, err
+				return ret, schema.WithErrorCode(err, schema.CodeUnauthenticated)
 			}
 
 			authRw := &authRewriter{
@@ -575,7 +593,7 @@ func (urw *UpdateRewriter) Rewrite(
 
 	customClaims, err := m.GetAuthMeta().ExtractCustomClaims(ctx)
 	if err != nil {
-		return ret, err
+		return ret, schema.WithErrorCode(err, schema.CodeUnauthenticated)
 	}
 
 	authRw := &authRewriter{
@@ -592,6 +610,10 @@ func (urw *UpdateRewriter) Rewrite(
 	if setArg == nil && delArg == nil {
 		return ret, nil
 	}
+	if setArg == nil && hasDefaults(mutatedType, schema.DefaultUpdate) {
+		// The fields with a @default value for update are set even if the update only removes.
+		setArg = map[string]interface{}{}
+	}
 
 	if setArg != nil {
 		obj := setArg.(map[string]interface{})
@@ -766,7 +788,7 @@ func (mrw *AddRewriter) FromMutationResult(
 
 	customClaims, err := mutation.GetAuthMeta().ExtractCustomClaims(ctx)
 	if err != nil {
-		return nil, err
+		return nil, schema.WithErrorCode(err, schema.CodeUnauthenticated)
 	}
 
 	authRw := &authRewriter{
@@ -814,7 +836,7 @@ func (urw *UpdateRewriter) FromMutationResult(
 
 	customClaims, err := mutation.GetAuthMeta().ExtractCustomClaims(ctx)
 	if err != nil {
-		return nil, err
+		return nil, schema.WithErrorCode(err, schema.CodeUnauthenticated)
 	}
 
 	authRw := &authRewriter{
@@ -1011,7 +1033,7 @@ func (drw *deleteRewriter) Rewrite(
 
 	customClaims, err := m.GetAuthMeta().ExtractCustomClaims(ctx)
 	if err != nil {
-		return nil, err
+		return nil, schema.WithErrorCode(err, schema.CodeUnauthenticated)
 	}
 
 	authRw := &authRewriter{
@@ -1329,6 +1351,8 @@ func rewriteObject(
 	// don't have to report the same errors.
 
 	upsertVar := ""
+	// refUID is the uid of an existing node referenced by xid in a nested upsert.
+	refUID := ""
 	atTopLevel := srcField == nil
 	var retErrors []error
 	variable := ""
@@ -1426,11 +1450,27 @@ func rewriteObject(
 						retErrors = append(retErrors, err)
 						return nil, upsertVar, retErrors
 					}
+				} else if xidMetadata.nestedUpsert && mutationType != UpdateWithRemove &&
+					!strings.HasPrefix(uid, "_:") {
+					// This is a nested upsert. The existing node is linked to its parent and is
+					// updated with the rest of obj, like the top level node of an upsert.
+					if updateAuthSelector(typ) != nil {
+						retErrors = append(retErrors, errors.Errorf("nested upsert isn't "+
+							"supported for type %s as it has update auth rules", typ.Name()))
+						return nil, upsertVar, retErrors
+					}
+					refUID = uid
+					obj = withoutField(obj, xid.Name())
 				} else {
 					// As we are not at top level, we return the XID reference. We don't update this node
 					// further.
 					return asIDReference(ctx, uid, srcField, srcUID, varGen, mutationType == UpdateWithRemove), upsertVar, nil
 				}
+			} else if !atTopLevel && xidMetadata.strictReferences && mutationType != UpdateWithRemove {
+				// The node must already exist as references aren't allowed to create nodes.
+				err := errors.Errorf("%s with %s %s doesn't exist", typ.Name(), xid.Name(), xidString)
+				retErrors = append(retErrors, err)
+				return nil, upsertVar, retErrors
 			} else {
 				// Node with XID does not exist. It means this is a new node.
 				// This node will be created later.
@@ -1486,6 +1526,9 @@ func rewriteObject(
 	dgraphTypes := []string{typ.DgraphName()}
 	dgraphTypes = append(dgraphTypes, typ.Interfaces()...)
 
+	// defaultAction is the @default action whose values are set for the fields not in obj.
+	defaultAction := ""
+
 	// Create newObj map. This map will be returned as part of mutationFragment.
 	newObj := make(map[string]interface{}, len(obj))
 
@@ -1503,6 +1546,18 @@ func rewriteObject(
 		// equal to uid(variable) in this case. Eg. uid(State1)
 		newObj["uid"] = srcUID
 		myUID = srcUID
+		if mutationType != UpdateWithRemove {
+			defaultAction = schema.DefaultUpdate
+		} else if err := ensureRequiredNotRemoved(typ, obj); err != nil {
+			retErrors = append(retErrors, err)
+			return nil, upsertVar, retErrors
+		}
+	} else if refUID != "" {
+		// This is a nested upsert of an existing node, so no new node is created. myUID is
+		// the uid of the existing node.
+		newObj["uid"] = refUID
+		myUID = refUID
+		defaultAction = schema.DefaultUpdate
 	} else if mutationType == UpdateWithRemove {
 		// It's a remove. As remove can only be part of Update Mutation. It can
 		// be inferred that this is an Update Mutation.
@@ -1517,8 +1572,16 @@ func rewriteObject(
 		// If we have reached this stage, we can be sure that we need to create a new
 		// node as part of the mutation. The new node is referenced as a blank node like
 		// "_:Project2" . myUID will store the variable generated to reference this node.
+		if err := ensureRequired(typ, obj, srcField); err != nil {
+			retErrors = append(retErrors, err)
+			return nil, upsertVar, retErrors
+		}
 		newObj["dgraph.type"] = dgraphTypes
 		newObj["uid"] = myUID
+		defaultAction = schema.DefaultAdd
+	}
+	if defaultAction != "" {
+		obj = withDefaults(typ, obj, defaultAction)
 	}
 
 	// Add Inverse Link if necessary
@@ -1526,6 +1589,10 @@ func rewriteObject(
 	addInverseLink(newObj, srcField, srcUID)
 
 	frag := newFragment(newObj)
+	if refUID != "" {
+		// Delete any old edges from inverse nodes, like for any other reference.
+		addAdditionalDeletes(ctx, frag, varGen, srcField, srcUID, refUID)
+	}
 	// TODO(Rajas)L Check if newNodes only needs to be set in case new nodes have been added.
 	frag.newNodes[variable] = typ
 
@@ -1627,6 +1694,89 @@ func rewriteObject(
 	return frag, upsertVar, retErrors
 }
 
+// ensureRequired returns an error if obj, which is the input for a new node of typ, has no value
+// for one of the @required fields of typ. The inverse of srcField isn't required in obj, as it is
+// set to the parent of the new node.
+func ensureRequired(typ schema.Type, obj map[string]interface{},
+	srcField schema.FieldDefinition) error {
+	exclude := ""
+	if srcField != nil && srcField.Inverse() != nil {
+		exclude = srcField.Inverse().Name()
+	}
+	for _, fld := range typ.Fields() {
+		if !fld.IsRequired() || fld.Name() == exclude {
+			continue
+		}
+		if val, ok := obj[fld.Name()]; ok && val != nil {
+			if list, ok := val.([]interface{}); !ok || len(list) > 0 {
+				continue
+			}
+		}
+		return errors.Errorf("type %s requires a value for field %s, as it is @required",
+			typ.Name(), fld.Name())
+	}
+	return nil
+}
+
+// ensureRequiredNotRemoved returns an error if obj, which is the remove patch of an update of typ,
+// removes the value of a @required field which can only have a single value.
+func ensureRequiredNotRemoved(typ schema.Type, obj map[string]interface{}) error {
+	for field := range obj {
+		fld := typ.Field(field)
+		if fld != nil && fld.IsRequired() && fld.Type().ListType() == nil {
+			return errors.Errorf("field %s of type %s can't be removed, as it is @required",
+				field, typ.Name())
+		}
+	}
+	return nil
+}
+
+// hasDefaults returns true if a field of typ has a @default value for action.
+func hasDefaults(typ schema.Type, action string) bool {
+	for _, fld := range typ.Fields() {
+		if fld.DefaultValue(action) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// withDefaults returns obj with the fields of typ which aren't in obj set to their @default
+// value for action, if they have one.
+func withDefaults(typ schema.Type, obj map[string]interface{},
+	action string) map[string]interface{} {
+	var res map[string]interface{}
+	for _, fld := range typ.Fields() {
+		if _, ok := obj[fld.Name()]; ok {
+			continue
+		}
+		val := fld.DefaultValue(action)
+		if val == nil {
+			continue
+		}
+		if res == nil {
+			// Copy obj before changing it, as it can be shared with xidMetadata.
+			res = withoutField(obj, "")
+		}
+		res[fld.Name()] = val
+	}
+	if res == nil {
+		return obj
+	}
+	return res
+}
+
+// withoutField returns a copy of obj without the given field.
+func withoutField(obj map[string]interface{}, field string) map[string]interface{} {
+	res := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		if k != field {
+			res[k] = v
+		}
+	}
+	return res
+}
+
 // existenceQueries takes a GraphQL JSON object as obj and creates queries to find
 // out if referenced nodes by XID and UID exist or not.
 // This is done in recursive fashion using a dfs.
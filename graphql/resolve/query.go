@@ -114,8 +114,8 @@ func (qr *queryResolver) rewriteAndExecute(ctx context.Context, query schema.Que
 
 	dgQuery, err := qr.queryRewriter.Rewrite(ctx, query)
 	if err != nil {
-		return emptyResult(schema.GQLWrapf(err, "couldn't rewrite query %s",
-			query.ResponseName()))
+		return emptyResult(schema.GQLWrapf(withErrorCode(err, schema.CodeBadUserInput),
+			"couldn't rewrite query %s", query.ResponseName()))
 	}
 	qry := dgraph.AsString(dgQuery)
 
@@ -125,9 +125,11 @@ func (qr *queryResolver) rewriteAndExecute(ctx context.Context, query schema.Que
 	queryTimer.Stop()
 
 	if err != nil && !x.IsGqlErrorList(err) {
-		err = schema.GQLWrapf(err, "Dgraph query failed")
+		err = schema.GQLWrapf(withErrorCode(err, schema.CodeExecutionFailed), "Dgraph query failed")
 		glog.Infof("Dgraph query execution failed : %s", err)
 	}
+	// The errors in a GqlErrorList are found while completing the result from Dgraph.
+	err = schema.WithErrorCode(err, schema.CodeCompletionFailed)
 
 	ext.TouchedUids = resp.GetMetrics().GetNumUids()[touchedUidsKey]
 	resolved := &Resolved{
@@ -196,7 +198,8 @@ func (qr *customDQLQueryResolver) rewriteAndExecute(ctx context.Context,
 		// so need to convert all variable values to string
 		vStr, err := convertScalarToString(v)
 		if err != nil {
-			return emptyResult(schema.GQLWrapf(err, "couldn't convert argument %s to string", k))
+			return emptyResult(schema.GQLWrapf(schema.WithErrorCode(err, schema.CodeBadUserInput),
+				"couldn't convert argument %s to string", k))
 		}
 		// the keys in dgoapi.Request{}.Vars are assumed to be prefixed with $
 		vars["$"+k] = vStr
@@ -209,7 +212,8 @@ func (qr *customDQLQueryResolver) rewriteAndExecute(ctx context.Context,
 	queryTimer.Stop()
 
 	if err != nil {
-		return emptyResult(schema.GQLWrapf(err, "Dgraph query failed"))
+		return emptyResult(schema.GQLWrapf(withErrorCode(err, schema.CodeExecutionFailed),
+			"Dgraph query failed"))
 	}
 	ext.TouchedUids = resp.GetMetrics().GetNumUids()[touchedUidsKey]
 
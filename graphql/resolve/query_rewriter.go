@@ -115,7 +115,7 @@ func (qr *queryRewriter) Rewrite(
 
 	customClaims, err := gqlQuery.GetAuthMeta().ExtractCustomClaims(ctx)
 	if err != nil {
-		return nil, err
+		return nil, schema.WithErrorCode(err, schema.CodeUnauthenticated)
 	}
 
 	authRw := &authRewriter{
@@ -294,6 +294,7 @@ func aggregateQuery(query schema.Query, authRw *authRewriter) []*gql.GraphQuery
 	// Add filter
 	filter, _ := query.ArgValue("filter").(map[string]interface{})
 	_ = addFilter(dgQuery[0], mainType, filter)
+	addRequiredFilter(dgQuery[0], mainType)
 
 	dgQuery = authRw.addAuthQueries(mainType, dgQuery, rbac)
 
@@ -511,6 +512,7 @@ func rewriteAsQueryByIds(
 	}
 
 	addArgumentsToField(dgQuery[0], field)
+	addRequiredFilter(dgQuery[0], field.Type())
 
 	// The function getQueryByIds is called for passwordQuery or fetching query result types
 	// after making a mutation. In both cases, we want the selectionSet to use the `query` auth
@@ -625,6 +627,7 @@ func rewriteAsGet(
 
 	addUID(dgQuery[0])
 	addTypeFilter(dgQuery[0], query.Type())
+	addRequiredFilter(dgQuery[0], query.Type())
 	addCascadeDirective(dgQuery[0], query)
 
 	dgQuery = auth.addAuthQueries(query.Type(), dgQuery, rbac)
@@ -686,6 +689,7 @@ func rewriteAsQuery(field schema.Field, authRw *authRewriter) []*gql.GraphQuery
 	// selection set.
 	if !authRw.writingAuth() {
 		addUID(dgQuery[0])
+		addRequiredFilter(dgQuery[0], field.Type())
 	}
 	addCascadeDirective(dgQuery[0], field)
 
@@ -1049,6 +1053,25 @@ func addTypeFilter(q *gql.GraphQuery, typ schema.Type) {
 	addToFilterTree(q, thisFilter)
 }
 
+// addRequiredFilter adds a has() filter to q for each @required field of typ, so that the
+// nodes missing a required edge aren't returned.
+func addRequiredFilter(q *gql.GraphQuery, typ schema.Type) {
+	if typ.IsInbuiltOrEnumType() {
+		return
+	}
+	for _, fld := range typ.Fields() {
+		if !fld.IsRequired() {
+			continue
+		}
+		addToFilterTree(q, &gql.FilterTree{
+			Func: &gql.Function{
+				Name: "has",
+				Args: []gql.Arg{{Value: fld.DgraphPredicate()}},
+			},
+		})
+	}
+}
+
 func addToFilterTree(q *gql.GraphQuery, filter *gql.FilterTree) {
 	if q.Filter == nil {
 		q.Filter = filter
@@ -1379,6 +1402,9 @@ func addSelectionSetFrom(
 		if includeField := addFilter(child, f.Type(), filter); !includeField {
 			continue
 		}
+		if !auth.isWritingAuth {
+			addRequiredFilter(child, f.Type())
+		}
 		addOrder(child, f)
 		addPagination(child, f)
 		addCascadeDirective(child, f)
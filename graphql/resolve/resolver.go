@@ -24,6 +24,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/dgraph-io/dgo/v200"
 	dgoapi "github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/dgraph-io/dgraph/edgraph"
 	"github.com/dgraph-io/dgraph/graphql/api"
@@ -31,6 +32,8 @@ import (
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/pkg/errors"
 	otrace "go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/golang/glog"
 
@@ -91,6 +94,8 @@ type ResolverFactory interface {
 type RequestResolver struct {
 	schema    schema.Schema
 	resolvers ResolverFactory
+	// id tells apart the query cache entries of different RequestResolvers.
+	id uint64
 }
 
 // A resolverFactory is the main implementation of ResolverFactory.  It stores a
@@ -327,6 +332,7 @@ func New(s schema.Schema, resolverFactory ResolverFactory) *RequestResolver {
 	return &RequestResolver{
 		schema:    s,
 		resolvers: resolverFactory,
+		id:        nextResolverID(),
 	}
 }
 
@@ -341,12 +347,14 @@ func (r *RequestResolver) Resolve(ctx context.Context, gqlReq *schema.Request) *
 
 	if r == nil {
 		glog.Errorf("Call to Resolve with nil RequestResolver")
-		return schema.ErrorResponse(errors.New(ErrInternal))
+		return schema.ErrorResponse(schema.WithErrorCode(errors.New(ErrInternal),
+			schema.CodeInternal))
 	}
 
 	if r.schema == nil {
 		glog.Errorf("Call to Resolve with no schema")
-		return schema.ErrorResponse(errors.New(ErrInternal))
+		return schema.ErrorResponse(schema.WithErrorCode(errors.New(ErrInternal),
+			schema.CodeInternal))
 	}
 
 	startTime := time.Now()
@@ -408,7 +416,11 @@ func (r *RequestResolver) Resolve(ctx context.Context, gqlReq *schema.Request) *
 							Err:   err,
 						}
 					})
-				allResolved[storeAt] = r.resolvers.queryResolverFor(q).Resolve(ctx, q)
+				if op.IsQuery() {
+					allResolved[storeAt] = r.resolveQuery(ctx, q)
+				} else {
+					allResolved[storeAt] = r.resolvers.queryResolverFor(q).Resolve(ctx, q)
+				}
 			}(q, i)
 		}
 		wg.Wait()
@@ -452,11 +464,11 @@ func (r *RequestResolver) Resolve(ctx context.Context, gqlReq *schema.Request) *
 
 		for _, m := range op.Mutations() {
 			if !allSuccessful {
-				resp.WithError(x.GqlErrorf(
+				resp.WithError(schema.WithErrorCode(x.GqlErrorf(
 					"Mutation %s was not executed because of a previous error.",
 					m.ResponseName()).
 					WithLocations(m.Location()).
-					WithPath([]interface{}{m.ResponseName()}))
+					WithPath([]interface{}{m.ResponseName()}), schema.CodeNotExecuted))
 
 				continue
 			}
@@ -480,13 +492,14 @@ func (r *RequestResolver) ValidateSubscription(req *schema.Request) error {
 	}
 
 	if !op.IsSubscription() {
-		return errors.New("given GraphQL operation is not a subscription")
+		return schema.WithErrorCode(errors.New("given GraphQL operation is not a subscription"),
+			schema.CodeValidationFailed)
 	}
 
 	for _, q := range op.Queries() {
 		for _, field := range q.SelectionSet() {
 			if err := validateCustomFieldsRecursively(field); err != nil {
-				return err
+				return schema.WithErrorCode(err, schema.CodeValidationFailed)
 			}
 		}
 	}
@@ -530,10 +543,45 @@ func addResult(resp *schema.Response, res *Resolved) {
 		resp.AddData(res.Data)
 	}
 
+	// Every error gets a path and a code, even if it came from a resolver which didn't set them.
+	if res.Err != nil {
+		res.Err = schema.WithErrorCode(schema.SetPathIfEmpty(res.Err, res.Field.ResponseName()),
+			schema.CodeInternal)
+	}
 	resp.WithError(res.Err)
 	resp.MergeExtensions(res.Extensions)
 }
 
+// errorCode returns the code for an error from Dgraph, or def if the error doesn't tell more
+// about what went wrong. It must be called before the error is wrapped as a GraphQL error, as
+// that loses its cause.
+func errorCode(err error, def schema.ErrorCode) schema.ErrorCode {
+	st, _ := status.FromError(err)
+	cause := errors.Cause(err)
+	switch {
+	case st.Code() == codes.Aborted || cause == dgo.ErrAborted || cause == x.ErrConflict:
+		return schema.CodeConflict
+	case st.Code() == codes.DeadlineExceeded || cause == context.DeadlineExceeded:
+		return schema.CodeTimeout
+	case st.Code() == codes.Unavailable || cause == x.ErrHealth:
+		return schema.CodeUnavailable
+	case x.IsArchivedPredicate(err):
+		return schema.CodePredicateArchived
+	case x.IsCorruptedPosting(err):
+		return schema.CodeDataCorrupted
+	case st.Code() == codes.Unauthenticated:
+		return schema.CodeUnauthenticated
+	case st.Code() == codes.PermissionDenied:
+		return schema.CodeForbidden
+	}
+	return def
+}
+
+// withErrorCode sets the code of the GraphQL errors in err, as found by errorCode.
+func withErrorCode(err error, def schema.ErrorCode) error {
+	return schema.WithErrorCode(err, errorCode(err, def))
+}
+
 // a httpResolver can resolve a single GraphQL field from an HTTP endpoint
 type httpResolver struct {
 	*http.Client
@@ -573,7 +621,7 @@ func (hr *httpResolver) rewriteAndExecute(ctx context.Context, field schema.Fiel
 		hrc.Template = schema.GetBodyForLambda(ctx, field, nil, hrc.Template)
 	}
 
-	fieldData, errs, hardErrs := hrc.MakeAndDecodeHTTPRequest(hr.Client, hrc.URL, hrc.Template,
+	fieldData, errs, hardErrs := hrc.MakeAndDecodeHTTPRequest(ctx, hr.Client, hrc.URL, hrc.Template,
 		field)
 	if hardErrs != nil {
 		// Not using EmptyResult() here as we don't want to wrap the errors returned from remote
@@ -242,8 +242,9 @@ func TestAddMutationUsesErrorPropagation(t *testing.T) {
 			errors: x.GqlErrorList{&x.GqlError{
 				Message: `Non-nullable field 'name' (type String!) ` +
 					`was not present in result from Dgraph.  GraphQL error propagation triggered.`,
-				Locations: []x.Location{{Column: 6, Line: 7}},
-				Path:      []interface{}{"addPost", "post", 0, "author", "name"}}},
+				Locations:  []x.Location{{Column: 6, Line: 7}},
+				Path:       []interface{}{"addPost", "post", 0, "author", "name"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed)}},
 		},
 	}
 
@@ -316,8 +317,9 @@ func TestUpdateMutationUsesErrorPropagation(t *testing.T) {
 			errors: x.GqlErrorList{&x.GqlError{
 				Message: `Non-nullable field 'name' (type String!) ` +
 					`was not present in result from Dgraph.  GraphQL error propagation triggered.`,
-				Locations: []x.Location{{Column: 6, Line: 7}},
-				Path:      []interface{}{"updatePost", "post", 0, "author", "name"}}},
+				Locations:  []x.Location{{Column: 6, Line: 7}},
+				Path:       []interface{}{"updatePost", "post", 0, "author", "name"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed)}},
 		},
 	}
 
@@ -390,12 +392,14 @@ func TestManyMutationsWithError(t *testing.T) {
 			errors: x.GqlErrorList{
 				&x.GqlError{Message: `mutation addPost failed because ` +
 					`Dgraph mutation failed because _bad stuff happend_`,
-					Locations: []x.Location{{Line: 6, Column: 4}},
-					Path:      []interface{}{"add2"}},
+					Locations:  []x.Location{{Line: 6, Column: 4}},
+					Path:       []interface{}{"add2"},
+					Extensions: errorExtensions(schema.CodeExecutionFailed)},
 				&x.GqlError{Message: `Mutation add3 was not executed because of ` +
 					`a previous error.`,
-					Locations: []x.Location{{Line: 10, Column: 4}},
-					Path:      []interface{}{"add3"}}},
+					Locations:  []x.Location{{Line: 10, Column: 4}},
+					Path:       []interface{}{"add3"},
+					Extensions: errorExtensions(schema.CodeNotExecuted)}},
 		},
 		"Rewriting error": {
 			explanation: "The reference ID is not a uint64, so can't be converted to a uid",
@@ -412,11 +416,13 @@ func TestManyMutationsWithError(t *testing.T) {
 				&x.GqlError{Message: `couldn't rewrite mutation addPost because ` +
 					`failed to rewrite mutation payload because ` +
 					`ID argument (hi) was not able to be parsed`,
-					Path: []interface{}{"add2"}},
+					Path:       []interface{}{"add2"},
+					Extensions: errorExtensions(schema.CodeBadUserInput)},
 				&x.GqlError{Message: `Mutation add3 was not executed because of ` +
 					`a previous error.`,
-					Locations: []x.Location{{Line: 10, Column: 4}},
-					Path:      []interface{}{"add3"}}},
+					Locations:  []x.Location{{Line: 10, Column: 4}},
+					Path:       []interface{}{"add3"},
+					Extensions: errorExtensions(schema.CodeNotExecuted)}},
 		},
 	}
 
@@ -447,7 +453,12 @@ func TestSubscriptionErrorWhenNoneDefined(t *testing.T) {
 	gqlSchema := test.LoadSchemaFromString(t, testGQLSchema)
 	resp := resolveWithClient(gqlSchema, `subscription { foo }`, nil, nil)
 	test.RequireJSONEq(t, x.GqlErrorList{{Message: "Not resolving subscription because schema" +
-		" doesn't have any fields defined for subscription operation."}}, resp.Errors)
+		" doesn't have any fields defined for subscription operation.",
+		Extensions: errorExtensions(schema.CodeValidationFailed)}}, resp.Errors)
+}
+
+func errorExtensions(code schema.ErrorCode) map[string]interface{} {
+	return map[string]interface{}{"code": string(code), "retryable": code.Retryable()}
 }
 
 func resolve(gqlSchema schema.Schema, gqlQuery string, dgResponse string) *schema.Response {
@@ -17,6 +17,7 @@
 package resolve
 
 import (
+	"context"
 	"testing"
 
 	"github.com/dgraph-io/dgraph/graphql/schema"
@@ -34,9 +35,10 @@ func TestErrorOnIncorrectValueType(t *testing.T) {
 			Response: `{ "getAuthor": { "dob": {"id": "0x1"} }}`,
 			Expected: `{ "getAuthor": { "dob": null }}`,
 			Errors: x.GqlErrorList{{
-				Message:   schema.ErrExpectedScalar,
-				Locations: []x.Location{x.Location{Line: 1, Column: 32}},
-				Path:      []interface{}{"getAuthor", "dob"},
+				Message:    schema.ErrExpectedScalar,
+				Locations:  []x.Location{x.Location{Line: 1, Column: 32}},
+				Path:       []interface{}{"getAuthor", "dob"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}}},
 
 		{Name: "return error when array is returned instead of scalar value",
@@ -44,9 +46,10 @@ func TestErrorOnIncorrectValueType(t *testing.T) {
 			Response: `{ "getAuthor": { "dob": [{"id": "0x1"}] }}`,
 			Expected: `{ "getAuthor": { "dob": null }}`,
 			Errors: x.GqlErrorList{{
-				Message:   schema.ErrExpectedScalar,
-				Locations: []x.Location{x.Location{Line: 1, Column: 32}},
-				Path:      []interface{}{"getAuthor", "dob"},
+				Message:    schema.ErrExpectedScalar,
+				Locations:  []x.Location{x.Location{Line: 1, Column: 32}},
+				Path:       []interface{}{"getAuthor", "dob"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}}},
 
 		{Name: "return error when scalar is returned instead of object value",
@@ -54,18 +57,20 @@ func TestErrorOnIncorrectValueType(t *testing.T) {
 			Response: `{ "getAuthor": { "country": "Rwanda" }}`,
 			Expected: `{ "getAuthor": { "country": null }}`,
 			Errors: x.GqlErrorList{{
-				Message:   "Error coercing value 'Rwanda' for field 'country' to type Country.",
-				Locations: []x.Location{x.Location{Line: 1, Column: 32}},
-				Path:      []interface{}{"getAuthor", "country"},
+				Message:    "Error coercing value 'Rwanda' for field 'country' to type Country.",
+				Locations:  []x.Location{x.Location{Line: 1, Column: 32}},
+				Path:       []interface{}{"getAuthor", "country"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}}},
 		{Name: "return error when array is returned instead of object value",
 			GQLQuery: `query { getAuthor(id: "0x1") { country { name } } }`,
 			Response: `{ "getAuthor": { "country": [{"name": "Rwanda"},{"name": "Rwanda"}] }}`,
 			Expected: `{ "getAuthor": { "country": null }}`,
 			Errors: x.GqlErrorList{{
-				Message:   schema.ErrExpectedSingleItem,
-				Locations: []x.Location{x.Location{Line: 1, Column: 32}},
-				Path:      []interface{}{"getAuthor", "country"},
+				Message:    schema.ErrExpectedSingleItem,
+				Locations:  []x.Location{x.Location{Line: 1, Column: 32}},
+				Path:       []interface{}{"getAuthor", "country"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}}},
 
 		{Name: "return error when scalar is returned instead of array value",
@@ -73,18 +78,20 @@ func TestErrorOnIncorrectValueType(t *testing.T) {
 			Response: `{ "getAuthor": { "posts": "Rwanda" }}`,
 			Expected: `{ "getAuthor": null}`,
 			Errors: x.GqlErrorList{{
-				Message:   schema.ErrExpectedList,
-				Locations: []x.Location{x.Location{Line: 1, Column: 32}},
-				Path:      []interface{}{"getAuthor"},
+				Message:    schema.ErrExpectedList,
+				Locations:  []x.Location{x.Location{Line: 1, Column: 32}},
+				Path:       []interface{}{"getAuthor"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}}},
 		{Name: "return error when object is returned instead of array value",
 			GQLQuery: `query { getAuthor(id: "0x1") { posts { text } } }`,
 			Response: `{ "getAuthor": { "posts": {"text": "Random post"} }}`,
 			Expected: `{ "getAuthor": null}`,
 			Errors: x.GqlErrorList{{
-				Message:   schema.ErrExpectedList,
-				Locations: []x.Location{x.Location{Line: 1, Column: 32}},
-				Path:      []interface{}{"getAuthor"},
+				Message:    schema.ErrExpectedList,
+				Locations:  []x.Location{x.Location{Line: 1, Column: 32}},
+				Path:       []interface{}{"getAuthor"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}}},
 	}
 	gqlSchema := test.LoadSchemaFromFile(t, "schema.graphql")
@@ -122,36 +129,40 @@ func TestValueCoercion(t *testing.T) {
 			GQLQuery: `query { getPost(postID: "0x1") { postType } }`,
 			Response: `{ "getPost": { "postType": [2] }}`,
 			Errors: x.GqlErrorList{{
-				Message:   "Error coercing value '2' for field 'postType' to type PostType.",
-				Locations: []x.Location{x.Location{Line: 1, Column: 34}},
-				Path:      []interface{}{"getPost", "postType", 0},
+				Message:    "Error coercing value '2' for field 'postType' to type PostType.",
+				Locations:  []x.Location{x.Location{Line: 1, Column: 34}},
+				Path:       []interface{}{"getPost", "postType", 0},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}},
 			Expected: `{ "getPost": { "postType": [null] }}`},
 		{Name: "float value should raise error when coerced to postType",
 			GQLQuery: `query { getPost(postID: "0x1") { postType } }`,
 			Response: `{ "getPost": { "postType": [2.134] }}`,
 			Errors: x.GqlErrorList{{
-				Message:   "Error coercing value '2.134' for field 'postType' to type PostType.",
-				Locations: []x.Location{x.Location{Line: 1, Column: 34}},
-				Path:      []interface{}{"getPost", "postType", 0},
+				Message:    "Error coercing value '2.134' for field 'postType' to type PostType.",
+				Locations:  []x.Location{x.Location{Line: 1, Column: 34}},
+				Path:       []interface{}{"getPost", "postType", 0},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}},
 			Expected: `{ "getPost": { "postType": [null] }}`},
 		{Name: "bool value should raise error when coerced to postType",
 			GQLQuery: `query { getPost(postID: "0x1") { postType } }`,
 			Response: `{ "getPost": { "postType": [false] }}`,
 			Errors: x.GqlErrorList{{
-				Message:   "Error coercing value 'false' for field 'postType' to type PostType.",
-				Locations: []x.Location{x.Location{Line: 1, Column: 34}},
-				Path:      []interface{}{"getPost", "postType", 0},
+				Message:    "Error coercing value 'false' for field 'postType' to type PostType.",
+				Locations:  []x.Location{x.Location{Line: 1, Column: 34}},
+				Path:       []interface{}{"getPost", "postType", 0},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}},
 			Expected: `{ "getPost": { "postType": [null] }}`},
 		{Name: "string value should raise error it has invalid enum value",
 			GQLQuery: `query { getPost(postID: "0x1") { postType } }`,
 			Response: `{ "getPost": { "postType": ["Random"] }}`,
 			Errors: x.GqlErrorList{{
-				Message:   "Error coercing value 'Random' for field 'postType' to type PostType.",
-				Locations: []x.Location{x.Location{Line: 1, Column: 34}},
-				Path:      []interface{}{"getPost", "postType", 0},
+				Message:    "Error coercing value 'Random' for field 'postType' to type PostType.",
+				Locations:  []x.Location{x.Location{Line: 1, Column: 34}},
+				Path:       []interface{}{"getPost", "postType", 0},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}},
 			Expected: `{ "getPost": { "postType": [null] }}`},
 		{Name: "string value should be coerced to valid enum value",
@@ -213,8 +224,9 @@ func TestValueCoercion(t *testing.T) {
 			Errors: x.GqlErrorList{{
 				Message: "Error coercing value '2147483648' for field 'numLikes' to type" +
 					" Int.",
-				Locations: []x.Location{x.Location{Line: 1, Column: 34}},
-				Path:      []interface{}{"getPost", "numLikes"},
+				Locations:  []x.Location{x.Location{Line: 1, Column: 34}},
+				Path:       []interface{}{"getPost", "numLikes"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}},
 			Expected: `{"getPost": {"numLikes": null}}`,
 		},
@@ -223,9 +235,10 @@ func TestValueCoercion(t *testing.T) {
 			GQLQuery: `query { getPost(postID: "0x1") { numLikes } }`,
 			Response: `{ "getPost": { "numLikes": 123.23 }}`,
 			Errors: x.GqlErrorList{{
-				Message:   "Error coercing value '123.23' for field 'numLikes' to type Int.",
-				Locations: []x.Location{x.Location{Line: 1, Column: 34}},
-				Path:      []interface{}{"getPost", "numLikes"},
+				Message:    "Error coercing value '123.23' for field 'numLikes' to type Int.",
+				Locations:  []x.Location{x.Location{Line: 1, Column: 34}},
+				Path:       []interface{}{"getPost", "numLikes"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}},
 			Expected: `{"getPost": {"numLikes": null}}`,
 		},
@@ -234,9 +247,10 @@ func TestValueCoercion(t *testing.T) {
 			GQLQuery: `query { getPost(postID: "0x1") { numLikes } }`,
 			Response: `{ "getPost": { "numLikes": "123.23" }}`,
 			Errors: x.GqlErrorList{{
-				Message:   "Error coercing value '123.23' for field 'numLikes' to type Int.",
-				Locations: []x.Location{x.Location{Line: 1, Column: 34}},
-				Path:      []interface{}{"getPost", "numLikes"},
+				Message:    "Error coercing value '123.23' for field 'numLikes' to type Int.",
+				Locations:  []x.Location{x.Location{Line: 1, Column: 34}},
+				Path:       []interface{}{"getPost", "numLikes"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}},
 			Expected: `{"getPost": {"numLikes": null}}`,
 		},
@@ -264,27 +278,30 @@ func TestValueCoercion(t *testing.T) {
 			GQLQuery: `query { getAuthor(id: "0x1") { dob } }`,
 			Response: `{ "getAuthor": { "dob": "23.123" }}`,
 			Errors: x.GqlErrorList{{
-				Message:   "Error coercing value '23.123' for field 'dob' to type DateTime.",
-				Locations: []x.Location{x.Location{Line: 1, Column: 32}},
-				Path:      []interface{}{"getAuthor", "dob"},
+				Message:    "Error coercing value '23.123' for field 'dob' to type DateTime.",
+				Locations:  []x.Location{x.Location{Line: 1, Column: 32}},
+				Path:       []interface{}{"getAuthor", "dob"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}},
 			Expected: `{ "getAuthor": { "dob": null }}`},
 		{Name: "bool value should raise an error when coerced as datetime",
 			GQLQuery: `query { getAuthor(id: "0x1") { dob } }`,
 			Response: `{ "getAuthor": { "dob": true }}`,
 			Errors: x.GqlErrorList{{
-				Message:   "Error coercing value 'true' for field 'dob' to type DateTime.",
-				Locations: []x.Location{x.Location{Line: 1, Column: 32}},
-				Path:      []interface{}{"getAuthor", "dob"},
+				Message:    "Error coercing value 'true' for field 'dob' to type DateTime.",
+				Locations:  []x.Location{x.Location{Line: 1, Column: 32}},
+				Path:       []interface{}{"getAuthor", "dob"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}},
 			Expected: `{ "getAuthor": { "dob": null }}`},
 		{Name: "invalid string value should raise an error when tried to be coerced to datetime",
 			GQLQuery: `query { getAuthor(id: "0x1") { dob } }`,
 			Response: `{ "getAuthor": { "dob": "123" }}`,
 			Errors: x.GqlErrorList{{
-				Message:   "Error coercing value '123' for field 'dob' to type DateTime.",
-				Locations: []x.Location{x.Location{Line: 1, Column: 32}},
-				Path:      []interface{}{"getAuthor", "dob"},
+				Message:    "Error coercing value '123' for field 'dob' to type DateTime.",
+				Locations:  []x.Location{x.Location{Line: 1, Column: 32}},
+				Path:       []interface{}{"getAuthor", "dob"},
+				Extensions: errorExtensions(schema.CodeCompletionFailed),
 			}},
 			Expected: `{ "getAuthor": { "dob": null}}`},
 		{Name: "int value should be coerced to datetime",
@@ -489,3 +506,50 @@ func TestResponseOrder(t *testing.T) {
 		})
 	}
 }
+
+func TestQueryCache(t *testing.T) {
+	x.Config.GraphqlCacheMB = 1
+	gqlSchema := test.LoadSchemaFromString(t, `
+	type Player {
+		id: ID!
+		name: String
+	}
+
+	type Query {
+		topPlayers: [Player] @custom(dql: """
+		query {
+			topPlayers(func: type(Player)) {
+				id: uid
+				name: Player.name
+			}
+		}
+		""") @cache(ttl: "1m")
+	}`)
+
+	ex := &executor{resp: `{"topPlayers": [{"id": "0x1", "name": "Alice"}]}`}
+	resolver := New(gqlSchema, NewResolverFactory(nil, nil).WithConventionResolvers(gqlSchema,
+		&ResolverFns{Qrw: NewQueryRewriter(), Ex: ex}))
+	resolveQuery := func(query string) *schema.Response {
+		resp := resolver.Resolve(context.Background(), &schema.Request{Query: query})
+		require.Nil(t, resp.Errors)
+		getQueryCache().Wait()
+		return resp
+	}
+
+	resp := resolveQuery(`query { topPlayers { name } }`)
+	require.Equal(t, `{"topPlayers":[{"name":"Alice"}]}`, resp.Data.String())
+	require.Equal(t, 1, ex.counter)
+
+	// The same query is served from the cache.
+	ex.resp = `{"topPlayers": [{"id": "0x2", "name": "Bob"}]}`
+	resp = resolveQuery(`query { topPlayers { name } }`)
+	require.Equal(t, `{"topPlayers":[{"name":"Alice"}]}`, resp.Data.String())
+	require.Equal(t, 1, ex.counter)
+
+	// Asking for different fields, or using an alias, is a different query.
+	resp = resolveQuery(`query { topPlayers { id name } }`)
+	require.Equal(t, `{"topPlayers":[{"id":"0x2","name":"Bob"}]}`, resp.Data.String())
+	resp = resolveQuery(`query { players: topPlayers { name } }`)
+	require.Equal(t, `{"players":[{"name":"Bob"}]}`, resp.Data.String())
+	require.Equal(t, 3, ex.counter)
+}
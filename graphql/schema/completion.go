@@ -153,7 +153,7 @@ func CompleteObject(
 			default:
 				// We were expecting a list but got a value which wasn't a list. Lets return an
 				// error.
-				return nil, x.GqlErrorList{f.GqlErrorf(path, ErrExpectedList)}
+				return nil, x.GqlErrorList{completionError(f, path, ErrExpectedList)}
 			}
 		}
 
@@ -184,11 +184,11 @@ func CompleteValue(
 	case map[string]interface{}:
 		switch field.Type().Name() {
 		case "String", "ID", "Boolean", "Float", "Int", "Int64", "DateTime":
-			return nil, x.GqlErrorList{field.GqlErrorf(path, ErrExpectedScalar)}
+			return nil, x.GqlErrorList{completionError(field, path, ErrExpectedScalar)}
 		}
 		enumValues := field.EnumValues()
 		if len(enumValues) > 0 {
-			return nil, x.GqlErrorList{field.GqlErrorf(path, ErrExpectedScalar)}
+			return nil, x.GqlErrorList{completionError(field, path, ErrExpectedScalar)}
 		}
 		return CompleteObject(path, field.SelectionSet(), val)
 	case []interface{}:
@@ -207,7 +207,7 @@ func CompleteValue(
 				return b, nil
 			}
 
-			return nil, x.GqlErrorList{field.GqlErrorf(path, ErrExpectedNonNull,
+			return nil, x.GqlErrorList{completionError(field, path, ErrExpectedNonNull,
 				field.Name(), field.Type())}
 		}
 
@@ -221,7 +221,7 @@ func CompleteValue(
 		// we just unmarshalled this val.
 		b, err := json.Marshal(val)
 		if err != nil {
-			gqlErr := x.GqlErrorList{field.GqlErrorf(path,
+			gqlErr := x.GqlErrorList{completionError(field, path,
 				"Error marshalling value for field '%s' (type %s).  "+
 					"Resolved as null (which may trigger GraphQL error propagation) ",
 				field.Name(), field.Type())}
@@ -310,6 +310,15 @@ func completeList(
 	return buf.Bytes(), errs
 }
 
+// completionError returns an error with the CodeCompletionFailed code, for the value of field at
+// path.
+func completionError(field Field, path []interface{}, message string,
+	args ...interface{}) *x.GqlError {
+	err := field.GqlErrorf(path, message, args...)
+	_ = WithErrorCode(err, CodeCompletionFailed)
+	return err
+}
+
 func mismatched(path []interface{}, field Field) ([]byte, x.GqlErrorList) {
 	glog.Errorf("completeList() called in resolving %s (Line: %v, Column: %v), "+
 		"but its type is %s.\n"+
@@ -317,7 +326,7 @@ func mismatched(path []interface{}, field Field) ([]byte, x.GqlErrorList) {
 		field.Name(), field.Location().Line, field.Location().Column, field.Type().Name())
 
 	val, errs := CompleteValue(path, field, nil)
-	return val, append(errs, field.GqlErrorf(path, ErrExpectedSingleItem))
+	return val, append(errs, completionError(field, path, ErrExpectedSingleItem))
 }
 
 // coerceScalar coerces a scalar value to field.Type() if possible according to the coercion rules
@@ -336,7 +345,7 @@ func coerceScalar(val interface{}, field Field, path []interface{}) (interface{}
 	x.GqlErrorList) {
 
 	valueCoercionError := func(val interface{}) x.GqlErrorList {
-		return x.GqlErrorList{field.GqlErrorf(path,
+		return x.GqlErrorList{completionError(field, path,
 			"Error coercing value '%+v' for field '%s' to type %s.",
 			val, field.Name(), field.Type().Name())}
 	}
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/dgraph-io/dgraph/graphql/authorization"
+	"github.com/dgraph-io/dgraph/graphql/lambda"
 
 	"github.com/dgraph-io/dgraph/x"
 )
@@ -81,7 +82,72 @@ func makeHttpRequest(client *http.Client, method, url string, header http.Header
 // For GraphQL requests, the GraphQL errors returned from the remote endpoint are considered soft
 // errors. Any other kind of error is a hard error.
 // For REST requests, any error is a hard error, including those returned from the remote endpoint.
-func (fconf *FieldHTTPConfig) MakeAndDecodeHTTPRequest(client *http.Client, url string,
+//
+// Requests for the fields with @lambda are run by Alpha if the namespace has a WASM lambda script.
+// Otherwise, they are sent with the lambda client if no client is provided, to one of the lambda
+// servers run by Alpha if there are any. Their latency is recorded per resolver.
+//
+// All the returned errors have the CodeExternalRequestFailed code, unless the remote endpoint
+// gave them a code.
+func (fconf *FieldHTTPConfig) MakeAndDecodeHTTPRequest(ctx context.Context, client *http.Client,
+	url string, body interface{}, field Field) (interface{}, x.GqlErrorList, x.GqlErrorList) {
+	if !field.HasLambdaDirective() {
+		response, softErrs, hardErrs := fconf.makeAndDecodeHTTPRequest(client, url, body, field)
+		return response, externalErrors(softErrs), externalErrors(hardErrs)
+	}
+
+	var response interface{}
+	var softErrs, hardErrs x.GqlErrorList
+	start := time.Now()
+	ns, _ := x.ExtractNamespace(ctx)
+	if script := lambda.Script(ns); script != nil && script.IsWasm() {
+		response, hardErrs = runWasmLambda(ctx, script.Script, body, field)
+	} else {
+		if lambda.Managed() {
+			url = lambda.URL()
+		}
+		if client == nil {
+			client = lambda.Client()
+		}
+		response, softErrs, hardErrs = fconf.makeAndDecodeHTTPRequest(client, url, body, field)
+	}
+	lambda.RecordInvocation(field.GetObjectName()+"."+field.Name(), start, hardErrs != nil)
+	return response, externalErrors(softErrs), externalErrors(hardErrs)
+}
+
+// externalErrors sets the CodeExternalRequestFailed code on errs. A nil list stays nil.
+func externalErrors(errs x.GqlErrorList) x.GqlErrorList {
+	if errs == nil {
+		return nil
+	}
+	return AsGQLErrors(WithErrorCode(errs, CodeExternalRequestFailed))
+}
+
+// runWasmLambda runs the WASM lambda script with body as its input, and decodes its output like
+// a response from the lambda server.
+func runWasmLambda(ctx context.Context, script string, body interface{},
+	field Field) (interface{}, x.GqlErrorList) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, x.GqlErrorList{jsonMarshalError(err, field, body)}
+	}
+	b, err = lambda.RunWasm(ctx, script, b)
+	if err != nil {
+		// A script which failed can report GraphQL errors, like the lambda server does.
+		var resp graphqlResp
+		if Unmarshal(b, &resp) == nil && len(resp.Errors) > 0 {
+			return nil, resp.Errors
+		}
+		return nil, x.GqlErrorList{externalRequestError(err, field)}
+	}
+	var response interface{}
+	if err := Unmarshal(b, &response); err != nil {
+		return nil, x.GqlErrorList{jsonUnmarshalError(err, field)}
+	}
+	return response, nil
+}
+
+func (fconf *FieldHTTPConfig) makeAndDecodeHTTPRequest(client *http.Client, url string,
 	body interface{}, field Field) (interface{}, x.GqlErrorList, x.GqlErrorList) {
 	var b []byte
 	var err error
@@ -170,6 +236,13 @@ func GetBodyForLambda(ctx context.Context, field Field, parents,
 		"key":   field.GetAuthMeta().GetHeader(),
 		"value": authorization.GetJwtToken(ctx),
 	}
+	// The lambda server runs the JavaScript deployed for the namespace if there is one, and the
+	// script it was started with otherwise.
+	ns, _ := x.ExtractNamespace(ctx)
+	body["namespace"] = ns
+	if script := lambda.Script(ns); script != nil && !script.IsWasm() {
+		body["source"] = script.Script
+	}
 	if parents != nil {
 		body["parents"] = parents
 	}
@@ -0,0 +1,188 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/dgraph-io/gqlparser/v2/ast"
+	"github.com/dgraph-io/gqlparser/v2/parser"
+	"github.com/pkg/errors"
+)
+
+const (
+	// documentHeader marks the start of a named document in a GraphQL schema which is made up of
+	// multiple documents, e.g. `# Dgraph.Document users`.
+	documentHeader = "Dgraph.Document"
+	// DefaultDocument is the name of the document holding the part of a schema which comes before
+	// the first document header. A schema uploaded as a whole is stored in this document.
+	DefaultDocument = "default"
+)
+
+// Document is a named part of a GraphQL schema. Documents are owned and updated independently
+// and are merged together to form the schema of a namespace.
+type Document struct {
+	Name   string
+	Schema string
+}
+
+// SplitDocuments splits a GraphQL schema into the documents it was merged from.
+func SplitDocuments(sch string) []Document {
+	var docs []Document
+	cur := Document{Name: DefaultDocument}
+	var sb strings.Builder
+	flush := func() {
+		cur.Schema = strings.TrimSpace(sb.String())
+		sb.Reset()
+		if cur.Schema != "" || cur.Name != DefaultDocument {
+			docs = append(docs, cur)
+		}
+	}
+
+	for _, line := range strings.Split(sch, "\n") {
+		if name, ok := documentName(line); ok {
+			flush()
+			cur = Document{Name: name}
+			continue
+		}
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	flush()
+	return docs
+}
+
+func documentName(line string) (string, bool) {
+	text := strings.TrimSpace(line)
+	if !strings.HasPrefix(text, "#") {
+		return "", false
+	}
+	header := strings.TrimSpace(text[1:])
+	if !strings.HasPrefix(header, documentHeader) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(header, documentHeader)), true
+}
+
+// ValidateDocumentName returns an error if name can't be used as the name of a document.
+func ValidateDocumentName(name string) error {
+	if name == "" {
+		return errors.New("document name can't be empty")
+	}
+	if strings.ContainsAny(name, " \t\r\n#") {
+		return errors.Errorf("document name %q can't contain whitespace or '#'", name)
+	}
+	return nil
+}
+
+// MergeDocuments checks the documents for conflicting definitions and merges them into a single
+// GraphQL schema from which they can be split again with SplitDocuments. A document with an empty
+// schema is dropped.
+func MergeDocuments(docs []Document) (string, error) {
+	docs = append([]Document{}, docs...)
+	sort.Slice(docs, func(i, j int) bool {
+		// The default document has no header, so it has to come first.
+		if docs[i].Name == DefaultDocument || docs[j].Name == DefaultDocument {
+			return docs[i].Name == DefaultDocument && docs[j].Name != DefaultDocument
+		}
+		return docs[i].Name < docs[j].Name
+	})
+
+	// definedIn maps the name of each type and directive definition to the document defining it.
+	definedIn := make(map[string]string)
+	seen := make(map[string]bool)
+	var sb strings.Builder
+	for _, doc := range docs {
+		if err := ValidateDocumentName(doc.Name); err != nil {
+			return "", err
+		}
+		if seen[doc.Name] {
+			return "", errors.Errorf("document %q is specified more than once", doc.Name)
+		}
+		seen[doc.Name] = true
+
+		sch := strings.TrimSpace(doc.Schema)
+		if sch == "" {
+			continue
+		}
+		for _, line := range strings.Split(sch, "\n") {
+			if _, ok := documentName(line); ok {
+				return "", errors.Errorf("document %q can't contain a `# %s` header",
+					doc.Name, documentHeader)
+			}
+		}
+		parsed, gqlErr := parser.ParseSchema(&ast.Source{Name: doc.Name, Input: sch})
+		if gqlErr != nil {
+			return "", errors.Wrapf(gqlErr, "while parsing document %q", doc.Name)
+		}
+		define := func(kind, name string) error {
+			key := kind + " " + name
+			if other, ok := definedIn[key]; ok && other != doc.Name {
+				return errors.Errorf("%s %s is defined in both document %q and document %q",
+					kind, name, other, doc.Name)
+			}
+			definedIn[key] = doc.Name
+			return nil
+		}
+		// Extensions are allowed across documents, so that a document can add fields to
+		// a type owned by another one.
+		for _, def := range parsed.Definitions {
+			if err := define("type", def.Name); err != nil {
+				return "", err
+			}
+		}
+		for _, dir := range parsed.Directives {
+			if err := define("directive", "@"+dir.Name); err != nil {
+				return "", err
+			}
+		}
+
+		if doc.Name != DefaultDocument {
+			sb.WriteString("# " + documentHeader + " " + doc.Name + "\n")
+		}
+		sb.WriteString(sch)
+		sb.WriteString("\n\n")
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// PutDocument returns the documents of the GraphQL schema sch with the document name replaced
+// by the given document schema. The document is removed if docSchema is empty.
+func PutDocument(sch, name, docSchema string) ([]Document, error) {
+	if err := ValidateDocumentName(name); err != nil {
+		return nil, err
+	}
+	docs := SplitDocuments(sch)
+	found := false
+	for i := 0; i < len(docs); i++ {
+		if docs[i].Name != name {
+			continue
+		}
+		found = true
+		if strings.TrimSpace(docSchema) == "" {
+			docs = append(docs[:i], docs[i+1:]...)
+			i--
+			continue
+		}
+		docs[i].Schema = docSchema
+	}
+	if !found && strings.TrimSpace(docSchema) != "" {
+		docs = append(docs, Document{Name: name, Schema: docSchema})
+	}
+	return docs, nil
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeDocuments(t *testing.T) {
+	sch := "type Author {\n  name: String\n}"
+	require.Equal(t, []Document{{Name: DefaultDocument, Schema: sch}}, SplitDocuments(sch))
+
+	docs, err := PutDocument(sch, "posts",
+		"type Post {\n  title: String\n}\nextend type Author {\n  posts: [Post]\n}")
+	require.NoError(t, err)
+	merged, err := MergeDocuments(docs)
+	require.NoError(t, err)
+	require.Equal(t, "type Author {\n  name: String\n}\n\n# Dgraph.Document posts\n"+
+		"type Post {\n  title: String\n}\nextend type Author {\n  posts: [Post]\n}", merged)
+	require.Equal(t, docs, SplitDocuments(merged))
+
+	// Updating the default document leaves the others as they were.
+	docs, err = PutDocument(merged, DefaultDocument, "type Author {\n  id: ID!\n}")
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	require.Equal(t, "type Author {\n  id: ID!\n}", docs[0].Schema)
+	require.Equal(t, "posts", docs[1].Name)
+
+	// A type can only be defined by a single document.
+	docs, err = PutDocument(merged, "comments", "type Post {\n  text: String\n}")
+	require.NoError(t, err)
+	_, err = MergeDocuments(docs)
+	require.EqualError(t, err,
+		`type Post is defined in both document "comments" and document "posts"`)
+
+	_, err = MergeDocuments([]Document{{Name: "users", Schema: "type User {"}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `while parsing document "users"`)
+
+	// An empty document is removed.
+	docs, err = PutDocument(merged, "posts", "")
+	require.NoError(t, err)
+	merged, err = MergeDocuments(docs)
+	require.NoError(t, err)
+	require.Equal(t, sch, merged)
+
+	_, err = PutDocument(merged, "my posts", "type Post {\n  title: String\n}")
+	require.Error(t, err)
+}
@@ -25,6 +25,114 @@ import (
 	"github.com/dgraph-io/gqlparser/v2/gqlerror"
 )
 
+// ErrorCode is a machine-readable code for a GraphQL error. It is sent in the "code" extension of
+// the error, along with the "retryable" extension, so that clients can handle an error without
+// matching its message. For example:
+//
+//	{
+//	  "message": "couldn't rewrite query getAuthor because ...",
+//	  "path": ["getAuthor"],
+//	  "extensions": {"code": "BAD_USER_INPUT", "retryable": false}
+//	}
+type ErrorCode string
+
+const (
+	// CodeBadRequest is for an HTTP request which isn't a valid GraphQL request, e.g. because
+	// its body can't be read.
+	CodeBadRequest ErrorCode = "BAD_REQUEST"
+	// CodeParseFailed is for a GraphQL request which can't be parsed.
+	CodeParseFailed ErrorCode = "GRAPHQL_PARSE_FAILED"
+	// CodeValidationFailed is for a GraphQL request, or its variables, which isn't valid against
+	// the schema.
+	CodeValidationFailed ErrorCode = "GRAPHQL_VALIDATION_FAILED"
+	// CodeBadUserInput is for arguments which are valid against the schema, but are still
+	// rejected, e.g. an ID which isn't a valid uid, or a node which already exists.
+	CodeBadUserInput ErrorCode = "BAD_USER_INPUT"
+	// CodeUnauthenticated is for a request without the JWT it needs, or with an invalid one.
+	CodeUnauthenticated ErrorCode = "UNAUTHENTICATED"
+	// CodeForbidden is for a request which isn't allowed for its user, IP address or JWT.
+	CodeForbidden ErrorCode = "FORBIDDEN"
+	// CodeConflict is for a mutation whose transaction was aborted because of a concurrent one.
+	// It can be retried.
+	CodeConflict ErrorCode = "CONFLICT"
+	// CodeTimeout is for a request which didn't finish within its deadline. It can be retried.
+	CodeTimeout ErrorCode = "TIMEOUT"
+	// CodeUnavailable is for a request which Dgraph isn't ready to serve, e.g. while starting
+	// up. It can be retried.
+	CodeUnavailable ErrorCode = "UNAVAILABLE"
+	// CodePredicateArchived is for a query or mutation using a predicate which was archived to
+	// object storage. It succeeds once the predicate is attached again.
+	CodePredicateArchived ErrorCode = "PREDICATE_ARCHIVED"
+	// CodeDataCorrupted is for a query or mutation reading a posting list which is corrupted.
+	// It succeeds once the posting list is purged, see the purgeQuarantinedKeys admin mutation.
+	CodeDataCorrupted ErrorCode = "DATA_CORRUPTED"
+	// CodeExternalRequestFailed is for a @custom or @lambda field whose remote endpoint or
+	// lambda script failed, or returned errors.
+	CodeExternalRequestFailed ErrorCode = "EXTERNAL_REQUEST_FAILED"
+	// CodeExecutionFailed is for a query or mutation which Dgraph failed to execute.
+	CodeExecutionFailed ErrorCode = "DGRAPH_EXECUTION_FAILED"
+	// CodeCompletionFailed is for a result from Dgraph which doesn't fit the schema, e.g. a
+	// missing value for a non-nullable field.
+	CodeCompletionFailed ErrorCode = "RESULT_COMPLETION_FAILED"
+	// CodeNotExecuted is for a mutation which was skipped because a previous mutation in the same
+	// request failed.
+	CodeNotExecuted ErrorCode = "NOT_EXECUTED"
+	// CodeInternal is for all the other errors.
+	CodeInternal ErrorCode = "INTERNAL_SERVER_ERROR"
+)
+
+// Retryable returns true if a request which failed with the code can be sent again as is.
+func (c ErrorCode) Retryable() bool {
+	switch c {
+	case CodeConflict, CodeTimeout, CodeUnavailable:
+		return true
+	}
+	return false
+}
+
+// WithErrorCode sets the "code" and "retryable" extensions of the GraphQL errors in err, which
+// don't have a code yet. The code of an error is set where it is first known, so codes set
+// deeper in the stack are kept. GraphQL errors are returned as they are, with their extensions
+// set, and any other error is returned as an x.GqlError.  If err is nil, WithErrorCode returns nil.
+func WithErrorCode(err error, code ErrorCode) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *gqlerror.Error:
+		if e != nil {
+			e.Extensions = withErrorCode(e.Extensions, code)
+		}
+	case gqlerror.List:
+		for _, ge := range e {
+			_ = WithErrorCode(ge, code)
+		}
+	case *x.GqlError:
+		if e != nil {
+			e.Extensions = withErrorCode(e.Extensions, code)
+		}
+	case x.GqlErrorList:
+		for _, ge := range e {
+			_ = WithErrorCode(ge, code)
+		}
+	default:
+		return &x.GqlError{Message: err.Error(), Extensions: withErrorCode(nil, code)}
+	}
+	return err
+}
+
+func withErrorCode(ext map[string]interface{}, code ErrorCode) map[string]interface{} {
+	if _, ok := ext["code"]; ok {
+		return ext
+	}
+	result := make(map[string]interface{}, len(ext)+2)
+	for k, v := range ext {
+		result[k] = v
+	}
+	result["code"] = string(code)
+	result["retryable"] = code.Retryable()
+	return result
+}
+
 // AsGQLErrors formats an error as a list of GraphQL errors.
 // A []*x.GqlError (x.GqlErrorList) gets returned as is, an x.GqlError gets returned as a one
 // item list, and all other errors get printed into a x.GqlError .  A nil input results
@@ -50,9 +158,10 @@ func AsGQLErrors(err error) x.GqlErrorList {
 
 func toGqlError(err *gqlerror.Error) *x.GqlError {
 	return &x.GqlError{
-		Message:   err.Message,
-		Locations: convertLocations(err.Locations),
-		Path:      convertPath(err.Path),
+		Message:    err.Message,
+		Locations:  convertLocations(err.Locations),
+		Path:       convertPath(err.Path),
+		Extensions: err.Extensions,
 	}
 }
 
@@ -82,8 +191,8 @@ func convertPath(path ast.Path) []interface{} {
 }
 
 // GQLWrapf takes an existing error and wraps it as a GraphQL error.
-// If err is already a GraphQL error, any location information is kept in the
-// new error.  If err is nil, GQLWrapf returns nil.
+// If err is already a GraphQL error, any location, path and extensions information is kept in
+// the new error.  If err is nil, GQLWrapf returns nil.
 //
 // Wrapping GraphQL errors like this allows us to bubble errors up the stack
 // and add context, location and path info to them as we go.
@@ -94,9 +203,11 @@ func GQLWrapf(err error, format string, args ...interface{}) error {
 
 	switch err := err.(type) {
 	case *x.GqlError:
-		return x.GqlErrorf("%s because %s", fmt.Sprintf(format, args...), err.Message).
+		wrapped := x.GqlErrorf("%s because %s", fmt.Sprintf(format, args...), err.Message).
 			WithLocations(err.Locations...).
 			WithPath(err.Path)
+		wrapped.Extensions = err.Extensions
+		return wrapped
 	case x.GqlErrorList:
 		var errs x.GqlErrorList
 		for _, e := range err {
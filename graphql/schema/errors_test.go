@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/gqlparser/v2/ast"
 	"github.com/dgraph-io/gqlparser/v2/gqlerror"
 	"github.com/stretchr/testify/require"
 
@@ -231,3 +232,53 @@ func TestAppendGQLErrs(t *testing.T) {
 		})
 	}
 }
+
+func TestWithErrorCode(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		code ErrorCode
+		req  string
+	}{
+		"an error": {
+			err:  errors.New("An error occurred"),
+			code: CodeExecutionFailed,
+			req: `[{"message": "An error occurred",
+				"extensions": {"code": "DGRAPH_EXECUTION_FAILED", "retryable": false}}]`,
+		},
+		"a retryable error": {
+			err:  errors.New("Transaction has been aborted"),
+			code: CodeConflict,
+			req: `[{"message": "Transaction has been aborted",
+				"extensions": {"code": "CONFLICT", "retryable": true}}]`,
+		},
+		"a parser error keeps its path and extensions": {
+			err: &gqlerror.Error{Message: "must be defined",
+				Path:       ast.Path{ast.PathName("variable"), ast.PathName("name")},
+				Extensions: map[string]interface{}{"field": "name"}},
+			code: CodeValidationFailed,
+			req: `[{"message": "must be defined", "path": ["variable", "name"],
+				"extensions": {"code": "GRAPHQL_VALIDATION_FAILED", "retryable": false,
+					"field": "name"}}]`,
+		},
+		"the code set first is kept, also when wrapped": {
+			err: GQLWrapf(WithErrorCode(x.GqlErrorList{x.GqlErrorf("An error occurred"),
+				x.GqlErrorf("Another error")}, CodeUnauthenticated), "authorization failed"),
+			code: CodeForbidden,
+			req: `[{"message": "authorization failed because An error occurred",
+				"extensions": {"code": "UNAUTHENTICATED", "retryable": false}},
+				{"message": "authorization failed because Another error",
+				"extensions": {"code": "UNAUTHENTICATED", "retryable": false}}]`,
+		},
+	}
+
+	for name, tcase := range tests {
+		t.Run(name, func(t *testing.T) {
+			gqlErrs, err := json.Marshal(AsGQLErrors(WithErrorCode(tcase.err, tcase.code)))
+			require.NoError(t, err)
+
+			assert.JSONEq(t, tcase.req, string(gqlErrs))
+		})
+	}
+
+	require.Nil(t, WithErrorCode(nil, CodeInternal))
+}
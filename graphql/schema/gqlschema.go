@@ -64,6 +64,22 @@ const (
 	cacheControlDirective = "cacheControl"
 	CacheControlHeader    = "Cache-Control"
 
+	requiredDirective = "required"
+
+	cacheDirective = "cache"
+	cacheTTLArg    = "ttl"
+	cacheScopeArg  = "scope"
+
+	defaultDirective = "default"
+	defaultValueArg  = "value"
+	// DefaultAdd and DefaultUpdate are the arguments of @default giving the value of a field
+	// when its node is added, and when it is updated.
+	DefaultAdd    = "add"
+	DefaultUpdate = "update"
+	// defaultNow is the @default value of a DateTime field which stands for the time of the
+	// mutation.
+	defaultNow = "$now"
+
 	// Directives to support Apollo Federation
 	apolloKeyDirective      = "key"
 	apolloKeyArg            = "fields"
@@ -267,6 +283,15 @@ input GenerateMutationParams {
 	update: Boolean
 	delete: Boolean
 }
+
+input DgraphDefault {
+	value: String
+}
+
+enum DgraphCacheScope {
+	PUBLIC
+	PER_AUTH
+}
 `
 	directiveDefs = `
 directive @hasInverse(field: String!) on FIELD_DEFINITION
@@ -286,6 +311,9 @@ directive @remote on OBJECT | INTERFACE | UNION | INPUT_OBJECT | ENUM
 directive @cascade(fields: [String]) on FIELD
 directive @lambda on FIELD_DEFINITION
 directive @cacheControl(maxAge: Int!) on QUERY
+directive @default(add: DgraphDefault, update: DgraphDefault) on FIELD_DEFINITION
+directive @required on FIELD_DEFINITION
+directive @cache(ttl: String!, scope: DgraphCacheScope) on FIELD_DEFINITION
 directive @generate(
 	query: GenerateQueryParams,
 	mutation: GenerateMutationParams,
@@ -303,6 +331,9 @@ directive @remote on OBJECT | INTERFACE | UNION | INPUT_OBJECT | ENUM
 directive @cascade(fields: [String]) on FIELD
 directive @lambda on FIELD_DEFINITION
 directive @cacheControl(maxAge: Int!) on QUERY
+directive @default(add: DgraphDefault, update: DgraphDefault) on FIELD_DEFINITION
+directive @required on FIELD_DEFINITION
+directive @cache(ttl: String!, scope: DgraphCacheScope) on FIELD_DEFINITION
 `
 	filterInputs = `
 input IntFilter {
@@ -557,6 +588,9 @@ var directiveValidators = map[string]directiveValidator{
 	deprecatedDirective:     ValidatorNoOp,
 	lambdaDirective:         lambdaDirectiveValidation,
 	generateDirective:       ValidatorNoOp,
+	defaultDirective:        defaultValidation,
+	requiredDirective:       requiredValidation,
+	cacheDirective:          cacheValidation,
 	apolloKeyDirective:      ValidatorNoOp,
 	apolloExtendsDirective:  ValidatorNoOp,
 	apolloExternalDirective: apolloExternalValidation,
@@ -577,6 +611,9 @@ var directiveLocationMap = map[string]map[ast.DefinitionKind]bool{
 		ast.InputObject: true, ast.Enum: true},
 	cascadeDirective:  nil,
 	generateDirective: {ast.Object: true, ast.Interface: true},
+	defaultDirective:  nil,
+	requiredDirective: nil,
+	cacheDirective:    nil,
 }
 
 // Struct to store parameters of @generate directive
@@ -1163,6 +1200,21 @@ func addInputType(schema *ast.Schema, defn *ast.Definition) {
 		field = append(idField, field...)
 	}
 
+	// Fields with a @default value for add are optional in the input.
+	for _, fld := range field {
+		defnFld := defn.Fields.ForName(fld.Name)
+		if defnFld == nil {
+			continue
+		}
+		if _, ok := defaultValue(defnFld, DefaultAdd); ok {
+			fld.Type.NonNull = false
+		}
+		// Fields with @required are mandatory in the input, even if they are nullable.
+		if hasRequired(defnFld) {
+			fld.Type.NonNull = true
+		}
+	}
+
 	if len(field) != 0 {
 		schema.Types["Add"+defn.Name+"Input"] = &ast.Definition{
 			Kind:   ast.InputObject,
@@ -1603,6 +1655,45 @@ func hasXID(defn *ast.Definition) bool {
 	return fieldAny(nonExternalAndKeyFields(defn), hasIDDirective)
 }
 
+// referencesXID returns true if an object with an @id field can be nested, at any depth, in the
+// input of the mutations of defn.
+func referencesXID(sch *ast.Schema, defn *ast.Definition) bool {
+	seen := make(map[string]bool)
+	var visit func(defn *ast.Definition) bool
+	visit = func(defn *ast.Definition) bool {
+		seen[defn.Name] = true
+		for _, fld := range defn.Fields {
+			typ := sch.Types[fld.Type.Name()]
+			if typ == nil || hasCustomOrLambda(fld) {
+				continue
+			}
+			members := []*ast.Definition{typ}
+			switch typ.Kind {
+			case ast.Union:
+				members = members[:0]
+				for _, name := range typ.Types {
+					if member := sch.Types[name]; member != nil {
+						members = append(members, member)
+					}
+				}
+			case ast.Object, ast.Interface:
+			default:
+				continue
+			}
+			for _, member := range members {
+				if hasXID(member) {
+					return true
+				}
+				if !seen[member.Name] && visit(member) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return visit(defn)
+}
+
 // fieldAny returns true if any field in fields satisfies pred
 func fieldAny(fields ast.FieldList, pred func(*ast.FieldDefinition) bool) bool {
 	for _, fld := range fields {
@@ -2038,11 +2129,29 @@ func addAddMutation(schema *ast.Schema, defn *ast.Definition) {
 				Type: &ast.Type{NamedType: "Boolean"},
 			})
 	}
+	if referencesXID(schema, defn) {
+		add.Arguments = append(add.Arguments, referenceArguments()...)
+	}
 
 	schema.Mutation.Fields = append(schema.Mutation.Fields, add)
 
 }
 
+// referenceArguments returns the arguments of the add and update mutations which control how the
+// objects referenced by their @id field in a deep mutation are handled.
+func referenceArguments() []*ast.ArgumentDefinition {
+	return []*ast.ArgumentDefinition{
+		{
+			Name: NestedUpsertArgName,
+			Type: &ast.Type{NamedType: "Boolean"},
+		},
+		{
+			Name: StrictReferencesArgName,
+			Type: &ast.Type{NamedType: "Boolean"},
+		},
+	}
+}
+
 func addUpdateMutation(schema *ast.Schema, defn *ast.Definition) {
 	if !hasFilterable(defn) {
 		return
@@ -2067,6 +2176,9 @@ func addUpdateMutation(schema *ast.Schema, defn *ast.Definition) {
 			},
 		},
 	}
+	if referencesXID(schema, defn) {
+		upd.Arguments = append(upd.Arguments, referenceArguments()...)
+	}
 	schema.Mutation.Fields = append(schema.Mutation.Fields, upd)
 }
 
@@ -52,39 +52,40 @@ type PersistedQuery struct {
 // operation, all GraphQL errors encountered are returned.
 func (s *schema) Operation(req *Request) (Operation, error) {
 	if req == nil || req.Query == "" {
-		return nil, errors.New("no query string supplied in request")
+		return nil, WithErrorCode(errors.New("no query string supplied in request"),
+			CodeParseFailed)
 	}
 
 	doc, gqlErr := parser.ParseQuery(&ast.Source{Input: req.Query})
 	if gqlErr != nil {
-		return nil, gqlErr
+		return nil, WithErrorCode(gqlErr, CodeParseFailed)
 	}
 
 	listErr := validator.Validate(s.schema, doc)
 	if len(listErr) != 0 {
-		return nil, listErr
+		return nil, WithErrorCode(listErr, CodeValidationFailed)
 	}
 
 	if len(doc.Operations) == 1 && doc.Operations[0].Operation == ast.Subscription &&
 		s.schema.Subscription == nil {
-		return nil, errors.Errorf("Not resolving subscription because schema doesn't have any " +
-			"fields defined for subscription operation.")
+		return nil, WithErrorCode(errors.Errorf("Not resolving subscription because schema "+
+			"doesn't have any fields defined for subscription operation."), CodeValidationFailed)
 	}
 
 	if len(doc.Operations) > 1 && req.OperationName == "" {
-		return nil, errors.Errorf("Operation name must by supplied when query has more " +
-			"than 1 operation.")
+		return nil, WithErrorCode(errors.Errorf("Operation name must by supplied when query has "+
+			"more than 1 operation."), CodeValidationFailed)
 	}
 
 	op := doc.Operations.ForName(req.OperationName)
 	if op == nil {
-		return nil, errors.Errorf("Supplied operation name %s isn't present in the request.",
-			req.OperationName)
+		return nil, WithErrorCode(errors.Errorf("Supplied operation name %s isn't present in "+
+			"the request.", req.OperationName), CodeValidationFailed)
 	}
 
 	vars, gqlErr := validator.VariableValues(s.schema, op, req.Variables)
 	if gqlErr != nil {
-		return nil, gqlErr
+		return nil, WithErrorCode(gqlErr, CodeValidationFailed)
 	}
 
 	operation := &operation{op: op,
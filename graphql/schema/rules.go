@@ -23,6 +23,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/dgraph-io/gqlparser/v2/ast"
@@ -2027,6 +2028,151 @@ func idValidation(sch *ast.Schema,
 		typ.Name, field.Name, field.Type.String())}
 }
 
+func defaultValidation(sch *ast.Schema,
+	typ *ast.Definition,
+	field *ast.FieldDefinition,
+	dir *ast.Directive,
+	secrets map[string]x.SensitiveByteSlice) gqlerror.List {
+	if field.Type.Elem != nil || isID(field) || hasIDDirective(field) ||
+		hasCustomOrLambda(field) {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: @default directive can't be used on list, ID, @id, @custom or "+
+				"@lambda fields.", typ.Name, field.Name)}
+	}
+	fldType := sch.Types[field.Type.Name()]
+	if fldType == nil || (fldType.Kind != ast.Scalar && fldType.Kind != ast.Enum) {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: @default directive can only be used on scalar and enum fields.",
+			typ.Name, field.Name)}
+	}
+
+	var errs []*gqlerror.Error
+	for _, action := range []string{DefaultAdd, DefaultUpdate} {
+		arg := dir.Arguments.ForName(action)
+		if arg == nil {
+			continue
+		}
+		val, ok := defaultValue(field, action)
+		if !ok {
+			errs = append(errs, gqlerror.ErrorPosf(
+				arg.Position,
+				"Type %s; Field %s: argument %s of @default directive must have a value.",
+				typ.Name, field.Name, action))
+			continue
+		}
+		if _, err := parseDefaultValue(fldType, val); err != nil {
+			errs = append(errs, gqlerror.ErrorPosf(
+				arg.Position,
+				"Type %s; Field %s: argument %s of @default directive has an invalid value: %s",
+				typ.Name, field.Name, action, err))
+		}
+	}
+	if len(dir.Arguments) == 0 {
+		errs = append(errs, gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: @default directive must have at least one of %s or %s.",
+			typ.Name, field.Name, DefaultAdd, DefaultUpdate))
+	}
+	return errs
+}
+
+func requiredValidation(sch *ast.Schema,
+	typ *ast.Definition,
+	field *ast.FieldDefinition,
+	dir *ast.Directive,
+	secrets map[string]x.SensitiveByteSlice) gqlerror.List {
+	fldType := sch.Types[field.Type.Name()]
+	if fldType == nil || (fldType.Kind != ast.Object && fldType.Kind != ast.Interface &&
+		fldType.Kind != ast.Union) || isGeoType(field.Type) {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: @required directive can only be used on fields which refer to "+
+				"other types.", typ.Name, field.Name)}
+	}
+	if hasCustomOrLambda(field) {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: @required directive can't be used with @custom or @lambda.",
+			typ.Name, field.Name)}
+	}
+	if strings.HasPrefix(fieldName(field, typ.Name), "~") ||
+		strings.HasPrefix(fieldName(field, typ.Name), "<~") {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: @required directive can't be used on a reverse predicate, as it "+
+				"can't be set when adding a %s.", typ.Name, field.Name, typ.Name)}
+	}
+	return nil
+}
+
+func cacheValidation(sch *ast.Schema,
+	typ *ast.Definition,
+	field *ast.FieldDefinition,
+	dir *ast.Directive,
+	secrets map[string]x.SensitiveByteSlice) gqlerror.List {
+	if typ.Name != "Query" {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: @cache directive can only be used on queries.",
+			typ.Name, field.Name)}
+	}
+	ttlArg := dir.Arguments.ForName(cacheTTLArg)
+	if ttlArg == nil || ttlArg.Value == nil {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			dir.Position,
+			"Type %s; Field %s: @cache directive must have a ttl.", typ.Name, field.Name)}
+	}
+	if ttl, err := time.ParseDuration(ttlArg.Value.Raw); err != nil || ttl <= 0 {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			ttlArg.Position,
+			"Type %s; Field %s: ttl argument of @cache directive must be a positive duration, "+
+				"like \"30s\", but got %q.", typ.Name, field.Name, ttlArg.Value.Raw)}
+	}
+	scopeArg := dir.Arguments.ForName(cacheScopeArg)
+	if scopeArg == nil || scopeArg.Value == nil || scopeArg.Value.Raw != string(CachePublic) {
+		return nil
+	}
+	if authTyp := reachableAuthType(sch, field.Type.Name()); authTyp != "" {
+		return []*gqlerror.Error{gqlerror.ErrorPosf(
+			scopeArg.Position,
+			"Type %s; Field %s: @cache directive can't have the PUBLIC scope, as its result "+
+				"can include type %s, which has the @auth directive.",
+			typ.Name, field.Name, authTyp)}
+	}
+	return nil
+}
+
+// reachableAuthType returns the name of a type having the @auth directive, or implementing an
+// interface which has it, among the types reachable from the given type through their fields. It
+// returns "" if there's none.
+func reachableAuthType(sch *ast.Schema, name string) string {
+	seen := make(map[string]bool)
+	queue := []string{name}
+	for len(queue) > 0 {
+		name, queue = queue[0], queue[1:]
+		def := sch.Types[name]
+		if def == nil || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if def.Directives.ForName(authDirective) != nil {
+			return name
+		}
+		for _, iface := range def.Interfaces {
+			if i := sch.Types[iface]; i != nil && i.Directives.ForName(authDirective) != nil {
+				return name
+			}
+		}
+		for _, fld := range def.Fields {
+			queue = append(queue, fld.Type.Name())
+		}
+		queue = append(queue, def.Types...)
+	}
+	return ""
+}
+
 func apolloKeyValidation(sch *ast.Schema, typ *ast.Definition) gqlerror.List {
 	dirList := typ.Directives.ForNames(apolloKeyDirective)
 	if len(dirList) == 0 {
@@ -25,8 +25,10 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dgraph-io/dgraph/graphql/authorization"
+	dgTypes "github.com/dgraph-io/dgraph/types"
 
 	"github.com/dgraph-io/gqlparser/v2/parser"
 
@@ -74,26 +76,45 @@ type FieldHTTPConfig struct {
 	GraphqlBatchModeArgument string
 }
 
+// CacheScope tells who can be served a result cached because of the @cache directive.
+type CacheScope string
+
+const (
+	// CachePublic results are shared by every request. It can't be used for queries whose result
+	// can include types with @auth rules.
+	CachePublic CacheScope = "PUBLIC"
+	// CachePerAuth results are only shared by requests with the same JWT claims.
+	CachePerAuth CacheScope = "PER_AUTH"
+)
+
+// CachePolicy is the @cache configuration of a query.
+type CachePolicy struct {
+	TTL   time.Duration
+	Scope CacheScope
+}
+
 // Query/Mutation types and arg names
 const (
-	GetQuery             QueryType    = "get"
-	FilterQuery          QueryType    = "query"
-	AggregateQuery       QueryType    = "aggregate"
-	SchemaQuery          QueryType    = "schema"
-	EntitiesQuery        QueryType    = "entities"
-	PasswordQuery        QueryType    = "checkPassword"
-	HTTPQuery            QueryType    = "http"
-	DQLQuery             QueryType    = "dql"
-	NotSupportedQuery    QueryType    = "notsupported"
-	AddMutation          MutationType = "add"
-	UpdateMutation       MutationType = "update"
-	DeleteMutation       MutationType = "delete"
-	HTTPMutation         MutationType = "http"
-	NotSupportedMutation MutationType = "notsupported"
-	IDType                            = "ID"
-	InputArgName                      = "input"
-	UpsertArgName                     = "upsert"
-	FilterArgName                     = "filter"
+	GetQuery                QueryType    = "get"
+	FilterQuery             QueryType    = "query"
+	AggregateQuery          QueryType    = "aggregate"
+	SchemaQuery             QueryType    = "schema"
+	EntitiesQuery           QueryType    = "entities"
+	PasswordQuery           QueryType    = "checkPassword"
+	HTTPQuery               QueryType    = "http"
+	DQLQuery                QueryType    = "dql"
+	NotSupportedQuery       QueryType    = "notsupported"
+	AddMutation             MutationType = "add"
+	UpdateMutation          MutationType = "update"
+	DeleteMutation          MutationType = "delete"
+	HTTPMutation            MutationType = "http"
+	NotSupportedMutation    MutationType = "notsupported"
+	IDType                               = "ID"
+	InputArgName                         = "input"
+	UpsertArgName                        = "upsert"
+	NestedUpsertArgName                  = "nestedUpsert"
+	StrictReferencesArgName              = "strictReferences"
+	FilterArgName                        = "filter"
 )
 
 // Schema represents a valid GraphQL schema
@@ -210,6 +231,8 @@ type Query interface {
 	KeyField(typeName string) (string, bool, error)
 	BuildType(typeName string) Type
 	AuthFor(typ Type, jwtVars map[string]interface{}) Query
+	// CachePolicy returns how the results of this query are cached, or nil if they aren't.
+	CachePolicy() *CachePolicy
 }
 
 // A Type is a GraphQL type like: Float, T, T! and [T!]!.  If it's not a list, then
@@ -255,6 +278,12 @@ type FieldDefinition interface {
 	IsID() bool
 	IsExternal() bool
 	HasIDDirective() bool
+	// DefaultValue returns the @default value of the field for the given action, DefaultAdd or
+	// DefaultUpdate, or nil if it has none.
+	DefaultValue(action string) interface{}
+	// IsRequired returns true if the field has the @required directive. Nodes without a value
+	// for it can't be added, and aren't returned by queries.
+	IsRequired() bool
 	Inverse() FieldDefinition
 	WithMemberType(string) FieldDefinition
 	// TODO - It might be possible to get rid of ForwardEdge and just use Inverse() always.
@@ -1822,6 +1851,28 @@ func (q *query) DQLQuery() string {
 	return ""
 }
 
+func (q *query) CachePolicy() *CachePolicy {
+	if q.field.Definition == nil {
+		return nil
+	}
+	dir := q.field.Definition.Directives.ForName(cacheDirective)
+	if dir == nil {
+		return nil
+	}
+	// The ttl has already been validated along with the schema.
+	policy := &CachePolicy{Scope: CachePerAuth}
+	if arg := dir.Arguments.ForName(cacheTTLArg); arg != nil && arg.Value != nil {
+		policy.TTL, _ = time.ParseDuration(arg.Value.Raw)
+	}
+	if arg := dir.Arguments.ForName(cacheScopeArg); arg != nil && arg.Value != nil {
+		policy.Scope = CacheScope(arg.Value.Raw)
+	}
+	if policy.TTL <= 0 {
+		return nil
+	}
+	return policy
+}
+
 func queryType(name string, custom *ast.Directive) QueryType {
 	switch {
 	case custom != nil:
@@ -2138,6 +2189,90 @@ func hasIDDirective(fd *ast.FieldDefinition) bool {
 	return id != nil
 }
 
+func (fd *fieldDefinition) DefaultValue(action string) interface{} {
+	if fd.fieldDef == nil {
+		return nil
+	}
+	raw, ok := defaultValue(fd.fieldDef, action)
+	if !ok {
+		return nil
+	}
+	if raw == defaultNow {
+		return time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	// The value has already been validated along with the schema.
+	val, _ := parseDefaultValue(fd.inSchema.schema.Types[fd.fieldDef.Type.Name()], raw)
+	return val
+}
+
+func (fd *fieldDefinition) IsRequired() bool {
+	if fd.fieldDef == nil {
+		return false
+	}
+	return hasRequired(fd.fieldDef)
+}
+
+func hasRequired(fd *ast.FieldDefinition) bool {
+	return fd.Directives.ForName(requiredDirective) != nil
+}
+
+// defaultValue returns the raw value given for action in the @default directive of fd.
+func defaultValue(fd *ast.FieldDefinition, action string) (string, bool) {
+	dir := fd.Directives.ForName(defaultDirective)
+	if dir == nil {
+		return "", false
+	}
+	arg := dir.Arguments.ForName(action)
+	if arg == nil || arg.Value == nil {
+		return "", false
+	}
+	val := arg.Value.Children.ForName(defaultValueArg)
+	if val == nil || val.Kind == ast.NullValue {
+		return "", false
+	}
+	return val.Raw, true
+}
+
+// parseDefaultValue parses the raw @default value of a field of type typ into the value to
+// mutate.
+func parseDefaultValue(typ *ast.Definition, raw string) (interface{}, error) {
+	if typ == nil {
+		return nil, errors.Errorf("unknown type")
+	}
+	if raw == defaultNow && typ.Name != "DateTime" {
+		return nil, errors.Errorf("%s can only be used for DateTime fields", defaultNow)
+	}
+	if typ.Kind == ast.Enum {
+		if typ.EnumValues.ForName(raw) == nil {
+			return nil, errors.Errorf("%s isn't a value of enum %s", raw, typ.Name)
+		}
+		return raw, nil
+	}
+
+	switch typ.Name {
+	case "String":
+		return raw, nil
+	case "Int":
+		return strconv.ParseInt(raw, 10, 32)
+	case "Int64":
+		return strconv.ParseInt(raw, 10, 64)
+	case "Float":
+		return strconv.ParseFloat(raw, 64)
+	case "Boolean":
+		return strconv.ParseBool(raw)
+	case "DateTime":
+		if raw == defaultNow {
+			return raw, nil
+		}
+		if _, err := dgTypes.ParseTime(raw); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	default:
+		return nil, errors.Errorf("@default isn't supported for type %s", typ.Name)
+	}
+}
+
 func isID(fd *ast.FieldDefinition) bool {
 	return fd.Type.Name() == "ID"
 }
@@ -2504,6 +2639,9 @@ func (t *astType) ImplementingTypes() []Type {
 // satisfy a valid post.
 func (t *astType) EnsureNonNulls(obj map[string]interface{}, exclusion string) error {
 	for _, fld := range t.inSchema.schema.Types[t.Name()].Fields {
+		if _, ok := defaultValue(fld, DefaultAdd); ok {
+			continue
+		}
 		if fld.Type.NonNull && !isID(fld) && fld.Name != exclusion && t.inSchema.customDirectives[t.Name()][fld.Name] == nil {
 			if val, ok := obj[fld.Name]; !ok || val == nil {
 				return errors.Errorf(
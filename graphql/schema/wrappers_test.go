@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dgraph-io/dgraph/x"
 
@@ -456,6 +457,34 @@ func TestCheckNonNulls(t *testing.T) {
 	}
 }
 
+func TestDefaultValue(t *testing.T) {
+	schHandler, err := NewHandler(`
+	type T {
+		id: ID!
+		count: Int! @default(add: {value: "0"})
+		updatedAt: DateTime @default(update: {value: "$now"})
+	}`, false)
+	require.NoError(t, err)
+	gqlSchema, err := FromString(schHandler.GQLSchema())
+	require.NoError(t, err)
+
+	typ := &astType{
+		typ:      &ast.Type{NamedType: "T"},
+		inSchema: (gqlSchema.(*schema)),
+	}
+	require.Equal(t, int64(0), typ.Field("count").DefaultValue(DefaultAdd))
+	require.Nil(t, typ.Field("count").DefaultValue(DefaultUpdate))
+	require.Nil(t, typ.Field("updatedAt").DefaultValue(DefaultAdd))
+	now, ok := typ.Field("updatedAt").DefaultValue(DefaultUpdate).(string)
+	require.True(t, ok)
+	ts, err := time.Parse(time.RFC3339Nano, now)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), ts, time.Minute)
+
+	// A field with a @default value for add isn't required to add a node.
+	require.NoError(t, typ.EnsureNonNulls(map[string]interface{}{}, ""))
+}
+
 func TestSubstituteVarsInBody(t *testing.T) {
 	tcases := []struct {
 		name      string
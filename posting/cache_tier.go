@@ -0,0 +1,262 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+)
+
+// CacheTierDefaults are the default values for the --cache_tier superflag.
+const CacheTierDefaults = "memcached=; timeout=100ms; ttl=24h; min-kb=4; max-kb=1024; " +
+	"prefix=dgraph"
+
+// maxPendingTierSets is the max number of writes to the cache tier in flight. The lists read
+// while it's reached aren't written to the cache tier.
+const maxPendingTierSets = 64
+
+// CacheTier is an external cache, like memcached or redis, shared by the Alphas of a group. It
+// holds the complete posting lists, i.e. the rolled-up postings and the parts of the multi-part
+// lists, which are read from disk otherwise. It helps the clusters whose working set doesn't fit
+// in memory.
+//
+// A complete posting list is written once at a commit timestamp and never changed, so its entry
+// is keyed by the posting list key along with that timestamp. A commit or a rollup writes a new
+// version at a new timestamp, which implicitly invalidates the older entries. They then expire
+// from the cache tier. The keys are also scoped by the cluster ID and by the epoch of the data,
+// which changes whenever the data is dropped or rebuilt, as the same versions may then be written
+// again with other values.
+type CacheTier interface {
+	// Get returns the value of the key. The bool is false if the key isn't cached.
+	Get(key string) ([]byte, bool, error)
+	// Set stores the value of the key, which expires after ttl.
+	Set(key string, val []byte, ttl time.Duration) error
+}
+
+type cacheTier struct {
+	CacheTier
+	prefix  string
+	ttl     time.Duration
+	minSize int
+	maxSize int
+	// aead encrypts the cached values if the data is encrypted at rest, or is nil.
+	aead cipher.AEAD
+	// pendingSets limits the writes in flight.
+	pendingSets chan struct{}
+	// cid is the ID of the cluster, a string. The cache tier isn't used until it's known.
+	cid atomic.Value
+	// epoch is the Raft index of the last proposal which dropped or rebuilt the data.
+	epoch uint64
+}
+
+// tier is the cache tier set via SetCacheTier, or nil.
+var tier *cacheTier
+
+// InitCacheTier sets up the cache tier configured via the --cache_tier superflag. It doesn't
+// set up any if no memcached servers are given. The cached values are encrypted with the key, if
+// it's set.
+func InitCacheTier(sf *z.SuperFlag, key x.SensitiveByteSlice) error {
+	servers := sf.GetString("memcached")
+	if servers == "" {
+		return nil
+	}
+	timeout, err := time.ParseDuration(sf.GetString("timeout"))
+	if err != nil {
+		return errors.Wrapf(err, "while parsing timeout")
+	}
+	ttl, err := time.ParseDuration(sf.GetString("ttl"))
+	if err != nil {
+		return errors.Wrapf(err, "while parsing ttl")
+	}
+	minKB, maxKB := sf.GetInt64("min-kb"), sf.GetInt64("max-kb")
+	switch {
+	case timeout <= 0:
+		return errors.Errorf("timeout must be positive")
+	case ttl < time.Second:
+		return errors.Errorf("ttl must be at least a second")
+	case minKB < 0 || maxKB < minKB:
+		return errors.Errorf("min-kb must be non-negative and at most max-kb")
+	}
+	ring, err := x.NewMemcachedRing(strings.Split(servers, ","), timeout)
+	if err != nil {
+		return err
+	}
+	if err := SetCacheTier(ring, sf.GetString("prefix"), ttl, int(minKB<<10), int(maxKB<<10),
+		key); err != nil {
+		return err
+	}
+	glog.Infof("Caching posting lists in memcached at %s", servers)
+	return nil
+}
+
+// SetCacheTier makes the posting lists which are between minSize and maxSize bytes long cached
+// in t, under keys starting with prefix. If encKey is set, the values are encrypted with AES-GCM
+// under it, and can't be read or forged by the other users of the cache tier. A nil t removes the
+// cache tier. The cache tier is only used once SetCacheTierCluster is called.
+func SetCacheTier(t CacheTier, prefix string, ttl time.Duration, minSize, maxSize int,
+	encKey x.SensitiveByteSlice) error {
+	if t == nil {
+		tier = nil
+		return nil
+	}
+	ct := &cacheTier{CacheTier: t, prefix: prefix, ttl: ttl, minSize: minSize, maxSize: maxSize,
+		pendingSets: make(chan struct{}, maxPendingTierSets)}
+	if len(encKey) > 0 {
+		c, err := aes.NewCipher(encKey)
+		if err != nil {
+			return errors.Wrapf(err, "while creating the cipher of the cache tier")
+		}
+		if ct.aead, err = cipher.NewGCM(c); err != nil {
+			return errors.Wrapf(err, "while creating the cipher of the cache tier")
+		}
+	}
+	tier = ct
+	return nil
+}
+
+// SetCacheTierCluster sets the ID of the cluster, which scopes the keys of the cache tier.
+func SetCacheTierCluster(cid string) {
+	if t := tier; t != nil && cid != "" {
+		t.cid.Store(cid)
+	}
+}
+
+// SetCacheTierEpoch sets the epoch of the data, which scopes the keys of the cache tier. It's the
+// Raft index of the last proposal which dropped or rebuilt the data, so the replicas of a group
+// agree on it.
+func SetCacheTierEpoch(epoch uint64) {
+	if t := tier; t != nil {
+		atomic.StoreUint64(&t.epoch, epoch)
+	}
+}
+
+// CacheTierEpoch returns the epoch set via SetCacheTierEpoch.
+func CacheTierEpoch() uint64 {
+	if t := tier; t != nil {
+		return atomic.LoadUint64(&t.epoch)
+	}
+	return 0
+}
+
+// cacheKey returns the key of the version of the posting list in the cache tier, or false if the
+// cluster ID isn't known yet. The keys of the posting lists can be long and binary, so they are
+// hashed.
+func (t *cacheTier) cacheKey(key []byte, version uint64) (string, bool) {
+	cid, _ := t.cid.Load().(string)
+	if cid == "" {
+		return "", false
+	}
+	h := sha256.Sum256(key)
+	return t.prefix + "-" + cid + "-" + strconv.FormatUint(atomic.LoadUint64(&t.epoch), 36) +
+		"-" + hex.EncodeToString(h[:]) + "-" + strconv.FormatUint(version, 36), true
+}
+
+// seal encrypts the value cached under the key, if the cache tier is encrypted. The key is
+// authenticated along with the value, so the values can't be swapped between keys.
+func (t *cacheTier) seal(key string, val []byte) ([]byte, error) {
+	if t.aead == nil {
+		return val, nil
+	}
+	nonce := make([]byte, t.aead.NonceSize(), t.aead.NonceSize()+len(val)+t.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return t.aead.Seal(nonce, nonce, val, []byte(key)), nil
+}
+
+// open decrypts the value cached under the key, if the cache tier is encrypted.
+func (t *cacheTier) open(key string, val []byte) ([]byte, error) {
+	if t.aead == nil {
+		return val, nil
+	}
+	if len(val) < t.aead.NonceSize() {
+		return nil, errors.Errorf("the cached value is too short")
+	}
+	n := t.aead.NonceSize()
+	return t.aead.Open(nil, val[:n], val[n:], []byte(key))
+}
+
+// readCompletePosting reads the complete posting list stored in item, from the cache tier if
+// it's there. Failures of the cache tier are logged, and the list is read from disk then.
+func readCompletePosting(plist *pb.PostingList, item *badger.Item) error {
+	t := tier
+	if t == nil || item.ValueSize() < int64(t.minSize) || item.ValueSize() > int64(t.maxSize) {
+		return unmarshalOrCopy(plist, item)
+	}
+
+	key, ok := t.cacheKey(item.Key(), item.Version())
+	if !ok {
+		return unmarshalOrCopy(plist, item)
+	}
+	val, found, err := t.Get(key)
+	switch {
+	case err != nil:
+		glog.V(2).Infof("Unable to read posting list from the cache tier: %v", err)
+	case found:
+		if val, err = t.open(key, val); err == nil {
+			err = plist.Unmarshal(val)
+		}
+		if err == nil {
+			ostats.Record(context.Background(), x.NumCacheTierHits.M(1))
+			return nil
+		}
+		// The entry is corrupt. Read the list from disk, and overwrite the entry.
+		plist.Reset()
+	}
+	ostats.Record(context.Background(), x.NumCacheTierMisses.M(1))
+
+	val, err = item.ValueCopy(nil)
+	if err != nil {
+		return err
+	}
+	if len(val) > 0 {
+		if err := unmarshalPostingList(plist, val); err != nil {
+			return err
+		}
+	}
+	select {
+	case t.pendingSets <- struct{}{}:
+		go func() {
+			defer func() { <-t.pendingSets }()
+			sealed, err := t.seal(key, val)
+			if err == nil {
+				err = t.Set(key, sealed, t.ttl)
+			}
+			if err != nil {
+				glog.V(2).Infof("Unable to write posting list to the cache tier: %v", err)
+			}
+		}()
+	default:
+	}
+	return nil
+}
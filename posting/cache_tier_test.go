@@ -0,0 +1,135 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/codec"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+type mapTier struct {
+	sync.Mutex
+	data map[string][]byte
+}
+
+func (m *mapTier) Get(key string) ([]byte, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	val, ok := m.data[key]
+	return val, ok, nil
+}
+
+func (m *mapTier) Set(key string, val []byte, ttl time.Duration) error {
+	m.Lock()
+	defer m.Unlock()
+	m.data[key] = val
+	return nil
+}
+
+func TestCacheTier(t *testing.T) {
+	m := &mapTier{data: make(map[string][]byte)}
+	require.NoError(t, SetCacheTier(m, "test", time.Hour, 0, 1<<20, nil))
+	defer SetCacheTier(nil, "", 0, 0, 0, nil)
+
+	key := x.DataKey(x.GalaxyAttr("tiered"), 1)
+	marshal := func(uids ...uint64) []byte {
+		plist := &pb.PostingList{Pack: codec.Encode(uids, 256)}
+		data, err := plist.Marshal()
+		require.NoError(t, err)
+		return data
+	}
+	writer := NewTxnWriter(pstore)
+	require.NoError(t, writer.SetAt(key, marshal(2, 3), BitCompletePosting, 5))
+	require.NoError(t, writer.Flush())
+
+	uids := func() []uint64 {
+		l, err := getNew(key, pstore, math.MaxUint64)
+		require.NoError(t, err)
+		list, err := l.Uids(ListOptions{ReadTs: 6})
+		require.NoError(t, err)
+		return list.Uids
+	}
+	// The cache tier isn't used until the cluster ID is known.
+	require.Equal(t, []uint64{2, 3}, uids())
+	require.Empty(t, m.data)
+	SetCacheTierCluster("cid")
+	require.Equal(t, []uint64{2, 3}, uids())
+
+	// The list read from disk is written to the cache tier, keyed by its version.
+	tierKey, ok := tier.cacheKey(key, 5)
+	require.True(t, ok)
+	require.Eventually(t, func() bool {
+		_, ok, _ := m.Get(tierKey)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	// The next reads are served from the cache tier.
+	require.NoError(t, m.Set(tierKey, marshal(2, 3, 4), time.Hour))
+	require.Equal(t, []uint64{2, 3, 4}, uids())
+
+	// A new version isn't in the cache tier yet, so it's read from disk.
+	writer = NewTxnWriter(pstore)
+	require.NoError(t, writer.SetAt(key, marshal(7), BitCompletePosting, 8))
+	require.NoError(t, writer.Flush())
+	l, err := getNew(key, pstore, math.MaxUint64)
+	require.NoError(t, err)
+	list, err := l.Uids(ListOptions{ReadTs: 9})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{7}, list.Uids)
+
+	// The entries of the previous epoch aren't read anymore.
+	SetCacheTierEpoch(10)
+	newKey, ok := tier.cacheKey(key, 5)
+	require.True(t, ok)
+	require.NotEqual(t, tierKey, newKey)
+}
+
+func TestCacheTierEncryption(t *testing.T) {
+	m := &mapTier{data: make(map[string][]byte)}
+	encKey := x.SensitiveByteSlice("0123456789abcdef")
+	require.NoError(t, SetCacheTier(m, "test", time.Hour, 0, 1<<20, encKey))
+	defer SetCacheTier(nil, "", 0, 0, 0, nil)
+	SetCacheTierCluster("cid")
+
+	plist := &pb.PostingList{Pack: codec.Encode([]uint64{2, 3}, 256)}
+	data, err := plist.Marshal()
+	require.NoError(t, err)
+	key, ok := tier.cacheKey([]byte("key"), 5)
+	require.True(t, ok)
+	sealed, err := tier.seal(key, data)
+	require.NoError(t, err)
+	require.NotContains(t, string(sealed), string(data))
+
+	opened, err := tier.open(key, sealed)
+	require.NoError(t, err)
+	require.Equal(t, data, opened)
+
+	// The value can't be moved to another key, nor tampered with.
+	other, _ := tier.cacheKey([]byte("other"), 5)
+	_, err = tier.open(other, sealed)
+	require.Error(t, err)
+	sealed[len(sealed)-1] ^= 1
+	_, err = tier.open(key, sealed)
+	require.Error(t, err)
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+)
+
+// ColdStore returns the local copy of the data of a cold predicate, i.e. of a predicate whose
+// data was moved to object storage. The copy is fetched from object storage if needed.
+type ColdStore func(attr string, cold *pb.ColdTablet) (*badger.DB, error)
+
+var coldStore ColdStore
+
+// SetColdStore sets the function returning the local copies of the cold predicates.
+func SetColdStore(fn ColdStore) {
+	coldStore = fn
+}
+
+// ReadStore returns the DB to read the data of the predicate from. It's pstore, unless the
+// predicate is cold. The archived predicates can't be read.
+func ReadStore(attr string) (*badger.DB, error) {
+	cold := schema.State().ColdTablet(attr)
+	switch {
+	case cold.GetObject() == "":
+		return pstore, nil
+	case cold.Archived:
+		return nil, &x.ArchivedPredicateError{Attr: attr}
+	}
+	if coldStore == nil {
+		return nil, errors.Errorf("Predicate %s is in object storage, but tiered storage isn't "+
+			"enabled on this node", x.ParseAttr(attr))
+	}
+	return coldStore(attr, cold)
+}
+
+// readStore returns the DB to read the key from.
+func readStore(key []byte) (*badger.DB, error) {
+	if !schema.State().HasCold() {
+		return pstore, nil
+	}
+	pk, err := x.Parse(key)
+	if err != nil {
+		return nil, err
+	}
+	return ReadStore(pk.Attr)
+}
+
+// getFromStore reads the posting list of the key from the DB holding the data of its predicate.
+func getFromStore(key []byte, readTs uint64) (*List, error) {
+	db, err := readStore(key)
+	if err != nil {
+		return nil, err
+	}
+	return getNew(key, db, readTs)
+}
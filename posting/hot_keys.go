@@ -0,0 +1,270 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// HotKeysDefaults are the default values for the --hot_keys superflag.
+const HotKeysDefaults = "sample=16; window=1m; min-share=1; top=20; mitigate=false"
+
+const (
+	// maxTrackedKeys bounds the number of keys whose accesses are counted within a window. The
+	// accesses to the other keys still count towards the total traffic.
+	maxTrackedKeys = 100000
+	// minHotSamples is the number of sampled accesses a key needs within a window to be hot, so
+	// that a few accesses on an idle node don't make a key hot.
+	minHotSamples = 10
+)
+
+// HotKey is a key which received a disproportionate share of the reads or of the writes on this
+// node during the last window.
+type HotKey struct {
+	Key []byte
+	// Reads and Writes are the estimated numbers of reads and writes of the key.
+	Reads  uint64
+	Writes uint64
+	// ReadShare and WriteShare are the percentages of the reads and of the writes on this node
+	// which went to the key.
+	ReadShare  float64
+	WriteShare float64
+}
+
+type keyAccesses struct {
+	reads, writes uint64
+}
+
+type hotKeyTracker struct {
+	sample   uint32
+	window   time.Duration
+	minShare float64
+	top      int
+	mitigate bool
+
+	sync.RWMutex
+	// The sampled accesses of the current window.
+	counts        map[string]*keyAccesses
+	reads, writes uint64
+	// The result of the last window.
+	hot         []HotKey
+	hotWrites   map[string]struct{}
+	hotReadAttr map[string]struct{}
+	windowEnd   time.Time
+}
+
+// hotKeys is the tracker set up via InitHotKeys, or nil if hot keys aren't tracked.
+var hotKeys *hotKeyTracker
+
+// InitHotKeys sets up the detection of hot keys configured via the --hot_keys superflag. Hot
+// keys aren't tracked if sample is zero.
+func InitHotKeys(sf *z.SuperFlag) error {
+	t, err := newHotKeyTracker(sf)
+	if err != nil || t == nil {
+		return err
+	}
+	hotKeys = t
+	closer.AddRunning(1)
+	go func() {
+		defer closer.Done()
+		ticker := time.NewTicker(t.window)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.rotate()
+			case <-closer.HasBeenClosed():
+				return
+			}
+		}
+	}()
+	glog.Infof("Tracking hot keys over windows of %s. Mitigation enabled: %v", t.window,
+		t.mitigate)
+	return nil
+}
+
+func newHotKeyTracker(sf *z.SuperFlag) (*hotKeyTracker, error) {
+	sample := sf.GetInt64("sample")
+	if sample == 0 {
+		return nil, nil
+	}
+	window, err := time.ParseDuration(sf.GetString("window"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing window")
+	}
+	t := &hotKeyTracker{
+		sample:   uint32(sample),
+		window:   window,
+		minShare: sf.GetFloat64("min-share"),
+		top:      int(sf.GetInt64("top")),
+		mitigate: sf.GetBool("mitigate"),
+		counts:   make(map[string]*keyAccesses),
+	}
+	switch {
+	case sample < 0 || sample > 1<<20:
+		return nil, errors.Errorf("sample must be in the range [0, %d]", 1<<20)
+	case window < time.Second:
+		return nil, errors.Errorf("window must be at least a second")
+	case t.minShare <= 0 || t.minShare > 100:
+		return nil, errors.Errorf("min-share must be in the range (0, 100]")
+	case t.top <= 0:
+		return nil, errors.Errorf("top must be positive")
+	}
+	return t, nil
+}
+
+// record counts an access to the key, if it's sampled.
+func (t *hotKeyTracker) record(key []byte, write bool) {
+	if t == nil || z.FastRand()%t.sample != 0 {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	if write {
+		t.writes++
+	} else {
+		t.reads++
+	}
+	c, ok := t.counts[string(key)]
+	if !ok {
+		if len(t.counts) >= maxTrackedKeys {
+			return
+		}
+		c = &keyAccesses{}
+		t.counts[string(key)] = c
+	}
+	if write {
+		c.writes++
+	} else {
+		c.reads++
+	}
+}
+
+// rotate finds the hot keys of the window which just ended, and starts a new window.
+func (t *hotKeyTracker) rotate() {
+	t.Lock()
+	counts, reads, writes := t.counts, t.reads, t.writes
+	t.counts = make(map[string]*keyAccesses)
+	t.reads, t.writes = 0, 0
+	t.Unlock()
+
+	share := func(n, total uint64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return 100 * float64(n) / float64(total)
+	}
+	var hot []HotKey
+	for key, c := range counts {
+		if c.reads+c.writes < minHotSamples {
+			continue
+		}
+		k := HotKey{
+			Key:        []byte(key),
+			Reads:      c.reads * uint64(t.sample),
+			Writes:     c.writes * uint64(t.sample),
+			ReadShare:  share(c.reads, reads),
+			WriteShare: share(c.writes, writes),
+		}
+		if k.ReadShare >= t.minShare || k.WriteShare >= t.minShare {
+			hot = append(hot, k)
+		}
+	}
+	maxShare := func(k HotKey) float64 {
+		if k.ReadShare > k.WriteShare {
+			return k.ReadShare
+		}
+		return k.WriteShare
+	}
+	sort.Slice(hot, func(i, j int) bool { return maxShare(hot[i]) > maxShare(hot[j]) })
+	if len(hot) > t.top {
+		hot = hot[:t.top]
+	}
+
+	hotWrites := make(map[string]struct{})
+	hotReadAttr := make(map[string]struct{})
+	for _, k := range hot {
+		if k.WriteShare >= t.minShare {
+			hotWrites[string(k.Key)] = struct{}{}
+		}
+		if k.ReadShare >= t.minShare {
+			if pk, err := x.Parse(k.Key); err == nil {
+				hotReadAttr[pk.Attr] = struct{}{}
+			}
+		}
+	}
+
+	t.Lock()
+	t.hot, t.hotWrites, t.hotReadAttr = hot, hotWrites, hotReadAttr
+	t.windowEnd = time.Now()
+	t.Unlock()
+
+	if !t.mitigate {
+		return
+	}
+	// The deltas of the keys written often pile up quickly, which slows down their reads. Roll
+	// them up ahead of the other keys.
+	for key := range hotWrites {
+		IncrRollup.addKeyToBatch([]byte(key), 0)
+	}
+}
+
+// rollupPriority returns the priority with which the key should be rolled up after a write.
+func (t *hotKeyTracker) rollupPriority(key string) int {
+	if t == nil || !t.mitigate {
+		return 1
+	}
+	t.RLock()
+	defer t.RUnlock()
+	if _, ok := t.hotWrites[key]; ok {
+		return 0
+	}
+	return 1
+}
+
+// HotKeys returns the hot keys of the last window, sorted by decreasing share of the traffic,
+// along with when the window ended. The time is zero if hot keys aren't tracked, or if no window
+// ended yet.
+func HotKeys() ([]HotKey, time.Time) {
+	t := hotKeys
+	if t == nil {
+		return nil, time.Time{}
+	}
+	t.RLock()
+	defer t.RUnlock()
+	return append([]HotKey{}, t.hot...), t.windowEnd
+}
+
+// HasReadHotKeys returns true if the mitigation of hot keys is enabled, and a key of the
+// predicate was hot for reads during the last window.
+func HasReadHotKeys(attr string) bool {
+	t := hotKeys
+	if t == nil || !t.mitigate {
+		return false
+	}
+	t.RLock()
+	defer t.RUnlock()
+	_, ok := t.hotReadAttr[attr]
+	return ok
+}
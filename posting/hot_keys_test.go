@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHotKeysFlag(t *testing.T) {
+	parse := func(flag string) (*hotKeyTracker, error) {
+		return newHotKeyTracker(z.NewSuperFlag(flag).MergeAndCheckDefault(HotKeysDefaults))
+	}
+	tr, err := parse("")
+	require.NoError(t, err)
+	require.Equal(t, uint32(16), tr.sample)
+	require.False(t, tr.mitigate)
+
+	tr, err = parse("sample=0")
+	require.NoError(t, err)
+	require.Nil(t, tr)
+
+	for _, flag := range []string{"sample=-1", "window=10ms", "min-share=0", "min-share=101",
+		"top=0"} {
+		_, err = parse(flag)
+		require.Error(t, err, flag)
+	}
+}
+
+func TestHotKeys(t *testing.T) {
+	tr, err := newHotKeyTracker(z.NewSuperFlag("sample=1; min-share=20; mitigate=true").
+		MergeAndCheckDefault(HotKeysDefaults))
+	require.NoError(t, err)
+	hotKeys = tr
+	defer func() { hotKeys = nil }()
+
+	hot := x.DataKey(x.GalaxyAttr("hot"), 1)
+	written := x.DataKey(x.GalaxyAttr("written"), 1)
+	for i := 0; i < 100; i++ {
+		tr.record(hot, false)
+		tr.record(x.DataKey(x.GalaxyAttr("cold"), uint64(i)), false)
+		tr.record(written, true)
+	}
+	// Too few accesses to be hot.
+	tr.record(x.DataKey(x.GalaxyAttr("rare"), 1), true)
+
+	keys, end := HotKeys()
+	require.Empty(t, keys)
+	require.True(t, end.IsZero())
+
+	tr.rotate()
+	keys, end = HotKeys()
+	require.False(t, end.IsZero())
+	require.Len(t, keys, 2)
+	require.Equal(t, written, keys[0].Key)
+	require.Equal(t, uint64(100), keys[0].Writes)
+	require.InDelta(t, 99.0, keys[0].WriteShare, 0.1)
+	require.Equal(t, hot, keys[1].Key)
+	require.Equal(t, uint64(100), keys[1].Reads)
+	require.Equal(t, 50.0, keys[1].ReadShare)
+
+	require.True(t, HasReadHotKeys(x.GalaxyAttr("hot")))
+	require.False(t, HasReadHotKeys(x.GalaxyAttr("written")))
+	require.Equal(t, 0, tr.rollupPriority(string(written)))
+	require.Equal(t, 1, tr.rollupPriority(string(hot)))
+
+	// The next window starts from scratch.
+	tr.rotate()
+	keys, _ = HotKeys()
+	require.Empty(t, keys)
+	require.False(t, HasReadHotKeys(x.GalaxyAttr("hot")))
+}
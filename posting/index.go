@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
 	"github.com/pkg/errors"
 	ostats "go.opencensus.io/stats"
 	otrace "go.opencensus.io/trace"
@@ -71,9 +72,12 @@ func indexTokens(ctx context.Context, info *indexMutationInfo) ([]string, error)
 		return nil, err
 	}
 
+	collation := schema.State().Collation(ctx, attr)
+	dict := schema.State().FulltextDictionary(ctx, attr)
 	var tokens []string
 	for _, it := range info.tokenizers {
-		toks, err := tok.BuildTokens(sv.Value, tok.GetTokenizerForLang(it, lang))
+		toks, err := tok.BuildTokens(sv.Value, tok.GetTokenizerForDictionary(
+			tok.GetTokenizerForCollation(it, lang, collation), dict))
 		if err != nil {
 			return tokens, err
 		}
@@ -82,6 +86,24 @@ func indexTokens(ctx context.Context, info *indexMutationInfo) ([]string, error)
 	return tokens, nil
 }
 
+// IndexKeys returns the keys of the index entries of the value of the predicate, in the
+// language lang.
+func IndexKeys(ctx context.Context, attr, lang string, val types.Val) ([][]byte, error) {
+	tokens, err := indexTokens(ctx, &indexMutationInfo{
+		tokenizers: schema.State().Tokenizer(ctx, attr),
+		edge:       &pb.DirectedEdge{Attr: attr, Lang: lang},
+		val:        val,
+	})
+	if err != nil {
+		return nil, err
+	}
+	keys := make([][]byte, 0, len(tokens))
+	for _, token := range tokens {
+		keys = append(keys, x.IndexKey(attr, token))
+	}
+	return keys, nil
+}
+
 // addIndexMutations adds mutation(s) for a single term, to maintain the index,
 // but only for the given tokenizers.
 // TODO - See if we need to pass op as argument as t should already have Op.
@@ -271,12 +293,13 @@ func (l *List) handleDeleteAll(ctx context.Context, edge *pb.DirectedEdge, txn *
 	isReversed := schema.State().IsReversed(ctx, edge.Attr)
 	isIndexed := schema.State().IsIndexed(ctx, edge.Attr)
 	hasCount := schema.State().HasCount(ctx, edge.Attr)
+	hasPresence := schema.State().HasPresence(ctx, edge.Attr)
 	delEdge := &pb.DirectedEdge{
 		Attr:   edge.Attr,
 		Op:     edge.Op,
 		Entity: edge.Entity,
 	}
-	// To calculate length of posting list. Used for deletion of count index.
+	// To calculate length of posting list. Used for deletion of count and presence index.
 	var plen int
 	err := l.Iterate(txn.StartTs, 0, func(p *pb.Posting) error {
 		plen++
@@ -316,6 +339,14 @@ func (l *List) handleDeleteAll(ctx context.Context, edge *pb.DirectedEdge, txn *
 			return err
 		}
 	}
+	if hasPresence && plen > 0 {
+		if err := txn.updatePresence(ctx, countParams{
+			attr:   edge.Attr,
+			entity: edge.Entity,
+		}); err != nil {
+			return err
+		}
+	}
 
 	return l.addMutation(ctx, txn, edge)
 }
@@ -360,6 +391,37 @@ func (txn *Txn) updateCount(ctx context.Context, params countParams) error {
 	return nil
 }
 
+// presenceTerm is the term of the presence index. It can't be generated by any tokenizer.
+var presenceTerm = string([]byte{tok.IdentPresence})
+
+// PresenceKey returns the key of the presence index of the predicate, i.e. of the list of the
+// UIDs having a value for it. It's an index key whose term can't be generated by any tokenizer.
+func PresenceKey(attr string) []byte {
+	return x.IndexKey(attr, presenceTerm)
+}
+
+func (txn *Txn) addPresenceMutation(ctx context.Context, t *pb.DirectedEdge) error {
+	plist, err := txn.cache.GetFromDelta(PresenceKey(t.Attr))
+	if err != nil {
+		return err
+	}
+	return plist.addMutation(ctx, txn, t)
+}
+
+// updatePresence adds the entity to the presence index if it got its first value for the
+// predicate, and removes it if it lost its last one.
+func (txn *Txn) updatePresence(ctx context.Context, params countParams) error {
+	edge := pb.DirectedEdge{
+		ValueId: params.entity,
+		Attr:    params.attr,
+		Op:      pb.DirectedEdge_SET,
+	}
+	if params.countAfter == 0 {
+		edge.Op = pb.DirectedEdge_DEL
+	}
+	return txn.addPresenceMutation(ctx, &edge)
+}
+
 func countAfterMutation(countBefore int, found bool, op pb.DirectedEdge_Op) int {
 	if !found && op == pb.DirectedEdge_SET {
 		return countBefore + 1
@@ -373,7 +435,7 @@ func countAfterMutation(countBefore int, found bool, op pb.DirectedEdge_Op) int
 }
 
 func (txn *Txn) addMutationHelper(ctx context.Context, l *List, doUpdateIndex bool,
-	hasCountIndex bool, t *pb.DirectedEdge) (types.Val, bool, countParams, error) {
+	needCount bool, t *pb.DirectedEdge) (types.Val, bool, countParams, error) {
 
 	t1 := time.Now()
 	l.Lock()
@@ -392,9 +454,10 @@ func (txn *Txn) addMutationHelper(ctx context.Context, l *List, doUpdateIndex bo
 		return fingerprintEdge(t)
 	}
 
-	// For countIndex we need to check if some posting already exists for uid and length of posting
-	// list, hence will are calling l.getPostingAndLength(). If doUpdateIndex or delNonListPredicate
-	// is true, we just need to get the posting for uid, hence calling l.findPosting().
+	// For countIndex and presence index we need to check if some posting already exists for uid
+	// and length of posting list, hence will are calling l.getPostingAndLength(). If doUpdateIndex
+	// or delNonListPredicate is true, we just need to get the posting for uid, hence calling
+	// l.findPosting().
 	countBefore, countAfter := 0, 0
 	var currPost *pb.Posting
 	var val types.Val
@@ -405,7 +468,7 @@ func (txn *Txn) addMutationHelper(ctx context.Context, l *List, doUpdateIndex bo
 		t.Op == pb.DirectedEdge_DEL && string(t.Value) != x.Star
 
 	switch {
-	case hasCountIndex:
+	case needCount:
 		countBefore, found, currPost = l.getPostingAndLength(txn.StartTs, 0, getUID(t))
 		if countBefore == -1 {
 			return val, false, emptyCountParams, ErrTsTooOld
@@ -442,7 +505,7 @@ func (txn *Txn) addMutationHelper(ctx context.Context, l *List, doUpdateIndex bo
 		val = valueToTypesVal(currPost)
 	}
 
-	if hasCountIndex {
+	if needCount {
 		countAfter = countAfterMutation(countBefore, found, t.Op)
 		return val, found, countParams{
 			attr:        t.Attr,
@@ -468,6 +531,7 @@ func (l *List) AddMutationWithIndex(ctx context.Context, edge *pb.DirectedEdge,
 
 	doUpdateIndex := pstore != nil && schema.State().IsIndexed(ctx, edge.Attr)
 	hasCountIndex := schema.State().HasCount(ctx, edge.Attr)
+	hasPresence := schema.State().HasPresence(ctx, edge.Attr)
 
 	// Add reverse mutation irrespective of hasMutated, server crash can happen after
 	// mutation is synced and before reverse edge is synced
@@ -477,7 +541,8 @@ func (l *List) AddMutationWithIndex(ctx context.Context, edge *pb.DirectedEdge,
 		}
 	}
 
-	val, found, cp, err := txn.addMutationHelper(ctx, l, doUpdateIndex, hasCountIndex, edge)
+	val, found, cp, err := txn.addMutationHelper(ctx, l, doUpdateIndex,
+		hasCountIndex || hasPresence, edge)
 	if err != nil {
 		return err
 	}
@@ -487,6 +552,11 @@ func (l *List) AddMutationWithIndex(ctx context.Context, edge *pb.DirectedEdge,
 			return err
 		}
 	}
+	if hasPresence && (cp.countBefore == 0) != (cp.countAfter == 0) {
+		if err := txn.updatePresence(ctx, cp); err != nil {
+			return err
+		}
+	}
 	if doUpdateIndex {
 		// Exact matches.
 		if found && val.Value != nil {
@@ -726,6 +796,9 @@ type IndexRebuild struct {
 	StartTs       uint64
 	OldSchema     *pb.SchemaUpdate
 	CurrentSchema *pb.SchemaUpdate
+	// Checkpoint, if set, is called by BuildIndexes after each of the indexes is rebuilt, to
+	// report the progress of the rebuild.
+	Checkpoint func(ctx context.Context, done, total uint64) error
 }
 
 type indexOp int
@@ -765,6 +838,9 @@ func (rb *IndexRebuild) GetQuerySchema() *pb.SchemaUpdate {
 	if rb.needsReverseEdgesRebuild() == indexRebuild {
 		querySchema.Directive = pb.SchemaUpdate_NONE
 	}
+	if rb.needsPresenceIndexRebuild() == indexRebuild {
+		querySchema.Presence = false
+	}
 	return &querySchema
 }
 
@@ -776,6 +852,7 @@ func (rb *IndexRebuild) DropIndexes(ctx context.Context) error {
 	}
 	prefixes = append(prefixes, prefixesToDropReverseEdges(ctx, rb)...)
 	prefixes = append(prefixes, prefixesToDropCountIndex(ctx, rb)...)
+	prefixes = append(prefixes, prefixesToDropPresenceIndex(ctx, rb)...)
 	glog.Infof("Deleting indexes for %s", rb.Attr)
 	return pstore.DropPrefix(prefixes...)
 }
@@ -785,23 +862,31 @@ func (rb *IndexRebuild) BuildData(ctx context.Context) error {
 	return rebuildListType(ctx, rb)
 }
 
-// NeedIndexRebuild returns true if any of the tokenizer, reverse
-// or count indexes need to be rebuilt.
+// NeedIndexRebuild returns true if any of the tokenizer, reverse,
+// count or presence indexes need to be rebuilt.
 func (rb *IndexRebuild) NeedIndexRebuild() bool {
 	return rb.needsTokIndexRebuild().op == indexRebuild ||
 		rb.needsReverseEdgesRebuild() == indexRebuild ||
-		rb.needsCountIndexRebuild() == indexRebuild
+		rb.needsCountIndexRebuild() == indexRebuild ||
+		rb.needsPresenceIndexRebuild() == indexRebuild
 }
 
 // BuildIndexes builds indexes.
 func (rb *IndexRebuild) BuildIndexes(ctx context.Context) error {
-	if err := rebuildTokIndex(ctx, rb); err != nil {
-		return err
-	}
-	if err := rebuildReverseEdges(ctx, rb); err != nil {
-		return err
+	phases := []func(context.Context, *IndexRebuild) error{
+		rebuildTokIndex, rebuildReverseEdges, rebuildCountIndex, rebuildPresenceIndex}
+	for i, rebuild := range phases {
+		if err := rebuild(ctx, rb); err != nil {
+			return err
+		}
+		if rb.Checkpoint == nil {
+			continue
+		}
+		if err := rb.Checkpoint(ctx, uint64(i+1), uint64(len(phases))); err != nil {
+			return err
+		}
 	}
-	return rebuildCountIndex(ctx, rb)
+	return nil
 }
 
 type indexRebuildInfo struct {
@@ -864,6 +949,23 @@ func (rb *IndexRebuild) needsTokIndexRebuild() indexRebuildInfo {
 
 	newTokenizers, deletedTokenizers := x.Diff(currTokens, prevTokens)
 
+	// The exact index needs to be rebuilt if the collation of the untagged values has changed.
+	// It's deleted as well, so that it isn't used by the queries in the meantime.
+	_, currExact := currTokens["exact"]
+	_, prevExact := prevTokens["exact"]
+	if currExact && prevExact && rb.CurrentSchema.Collation != old.Collation {
+		newTokenizers = append(newTokenizers, "exact")
+		deletedTokenizers = append(deletedTokenizers, "exact")
+	}
+
+	// The fulltext index needs to be rebuilt if its dictionary has changed.
+	_, currFulltext := currTokens["fulltext"]
+	_, prevFulltext := prevTokens["fulltext"]
+	if currFulltext && prevFulltext && !sameFulltextDictionary(rb.CurrentSchema, old) {
+		newTokenizers = append(newTokenizers, "fulltext")
+		deletedTokenizers = append(deletedTokenizers, "fulltext")
+	}
+
 	// If the tokenizers are the same, nothing needs to be done.
 	if len(newTokenizers) == 0 && len(deletedTokenizers) == 0 {
 		return indexRebuildInfo{
@@ -878,6 +980,12 @@ func (rb *IndexRebuild) needsTokIndexRebuild() indexRebuildInfo {
 	}
 }
 
+func sameFulltextDictionary(a, b *pb.SchemaUpdate) bool {
+	return proto.Equal(
+		&pb.SchemaUpdate{FulltextSynonyms: a.FulltextSynonyms, FulltextProtected: a.FulltextProtected},
+		&pb.SchemaUpdate{FulltextSynonyms: b.FulltextSynonyms, FulltextProtected: b.FulltextProtected})
+}
+
 func prefixesForTokIndexes(ctx context.Context, rb *IndexRebuild) ([][]byte, error) {
 	rebuildInfo := rb.needsTokIndexRebuild()
 	prefixes := [][]byte{}
@@ -1076,6 +1184,74 @@ func rebuildCountIndex(ctx context.Context, rb *IndexRebuild) error {
 	return builder.Run(ctx)
 }
 
+func (rb *IndexRebuild) needsPresenceIndexRebuild() indexOp {
+	x.AssertTruef(rb.CurrentSchema != nil, "Current schema cannot be nil.")
+
+	// If the old schema is nil, treat it as an empty schema. Copy it to avoid
+	// overwriting it in rb.
+	old := rb.OldSchema
+	if old == nil {
+		old = &pb.SchemaUpdate{}
+	}
+
+	// Do nothing if the schema directive did not change.
+	if rb.CurrentSchema.Presence == old.Presence {
+		return indexNoop
+	}
+
+	// If the new schema does not require an index, delete the current index.
+	if !rb.CurrentSchema.Presence {
+		return indexDelete
+	}
+
+	// Otherwise, the index needs to be rebuilt.
+	return indexRebuild
+}
+
+func prefixesToDropPresenceIndex(ctx context.Context, rb *IndexRebuild) [][]byte {
+	// Exit early if indices do not need to be rebuilt.
+	if rb.needsPresenceIndexRebuild() == indexNoop {
+		return nil
+	}
+
+	// The presence index is a single list, but it might have been split into multiple parts.
+	prefix := PresenceKey(rb.Attr)
+	splitPrefix := PresenceKey(rb.Attr)
+	splitPrefix[0] = x.ByteSplit
+	return [][]byte{prefix, splitPrefix}
+}
+
+// rebuildPresenceIndex rebuilds the presence index for a given attribute.
+func rebuildPresenceIndex(ctx context.Context, rb *IndexRebuild) error {
+	if rb.needsPresenceIndexRebuild() != indexRebuild {
+		return nil
+	}
+
+	glog.Infof("Rebuilding presence index for %s", rb.Attr)
+	pk := x.ParsedKey{Attr: rb.Attr}
+	builder := rebuilder{attr: rb.Attr, prefix: pk.DataPrefix(), startTs: rb.StartTs}
+	builder.fn = func(uid uint64, pl *List, txn *Txn) error {
+		if pl.Length(rb.StartTs, 0) <= 0 {
+			return nil
+		}
+		t := &pb.DirectedEdge{
+			ValueId: uid,
+			Attr:    rb.Attr,
+			Op:      pb.DirectedEdge_SET,
+		}
+		for {
+			err := txn.addPresenceMutation(ctx, t)
+			switch err {
+			case ErrRetry:
+				time.Sleep(10 * time.Millisecond)
+			default:
+				return err
+			}
+		}
+	}
+	return builder.Run(ctx)
+}
+
 func (rb *IndexRebuild) needsReverseEdgesRebuild() indexOp {
 	x.AssertTruef(rb.CurrentSchema != nil, "Current schema cannot be nil.")
 
@@ -1236,6 +1412,13 @@ func DeleteData() error {
 	return pstore.DropPrefix([]byte{x.DefaultPrefix})
 }
 
+// DeletePredicateData deletes all entries and indices for a given predicate, but leaves its
+// schema intact.
+func DeletePredicateData(attr string) error {
+	glog.Infof("Dropping data of predicate: [%s]", attr)
+	return pstore.DropPrefix(x.PredicatePrefix(attr))
+}
+
 // DeletePredicate deletes all entries and indices for a given predicate.
 func DeletePredicate(ctx context.Context, attr string) error {
 	glog.Infof("Dropping predicate: [%s]", attr)
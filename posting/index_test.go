@@ -496,6 +496,92 @@ func TestNeedsCountIndexRebuild(t *testing.T) {
 	require.Equal(t, indexOp(indexDelete), rb.needsCountIndexRebuild())
 }
 
+func TestNeedsPresenceIndexRebuild(t *testing.T) {
+	rb := IndexRebuild{}
+	rb.OldSchema = &pb.SchemaUpdate{ValueType: pb.Posting_UID}
+	rb.CurrentSchema = &pb.SchemaUpdate{ValueType: pb.Posting_UID, Presence: true}
+	require.Equal(t, indexOp(indexRebuild), rb.needsPresenceIndexRebuild())
+
+	rb.OldSchema = nil
+	require.Equal(t, indexOp(indexRebuild), rb.needsPresenceIndexRebuild())
+
+	rb.OldSchema = &pb.SchemaUpdate{ValueType: pb.Posting_UID, Presence: true}
+	require.Equal(t, indexOp(indexNoop), rb.needsPresenceIndexRebuild())
+
+	rb.CurrentSchema = &pb.SchemaUpdate{ValueType: pb.Posting_UID}
+	require.Equal(t, indexOp(indexDelete), rb.needsPresenceIndexRebuild())
+}
+
+func TestPresenceIndex(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte("seen: [uid] @presence ."), 1))
+	attr := x.GalaxyAttr("seen")
+	presence := func(readTs uint64) []uint64 {
+		l, err := GetNoStore(PresenceKey(attr), readTs)
+		require.NoError(t, err)
+		return uids(l, readTs)
+	}
+	mutate := func(src, dst uint64, op uint32, startTs, commitTs uint64) {
+		edge := &pb.DirectedEdge{ValueId: dst, ValueType: pb.Posting_UID, Attr: attr, Entity: src}
+		if dst == 0 {
+			edge.Value = []byte(x.Star)
+		}
+		l, err := GetNoStore(x.DataKey(attr, src), startTs)
+		require.NoError(t, err)
+		addMutation(t, l, edge, op, startTs, commitTs, true)
+	}
+
+	mutate(1, 10, Set, 1, 2)
+	mutate(2, 10, Set, 3, 4)
+	mutate(1, 11, Set, 5, 6)
+	require.Equal(t, []uint64{1, 2}, presence(7))
+
+	// The UIDs are only removed once they have no value left.
+	mutate(1, 10, Del, 7, 8)
+	mutate(2, 10, Del, 9, 10)
+	require.Equal(t, []uint64{1}, presence(11))
+	mutate(1, 0, Del, 11, 12)
+	require.Empty(t, presence(13))
+}
+
+func TestPresenceIndexConflictKey(t *testing.T) {
+	require.NoError(t, schema.ParseBytes(
+		[]byte("seen3: string @index(exact) @upsert @presence ."), 1))
+	attr := x.GalaxyAttr("seen3")
+	edge := &pb.DirectedEdge{ValueId: 1, Attr: attr, Entity: 1}
+
+	// Writes to different UIDs of an @upsert predicate don't conflict on the presence index.
+	key := PresenceKey(attr)
+	pk, err := x.Parse(key)
+	require.NoError(t, err)
+	require.Zero(t, GetConflictKey(pk, key, edge))
+
+	key = x.DataKey(attr, 1)
+	pk, err = x.Parse(key)
+	require.NoError(t, err)
+	require.NotZero(t, GetConflictKey(pk, key, edge))
+}
+
+func TestRebuildPresenceIndex(t *testing.T) {
+	attr := x.GalaxyAttr("seen2")
+	addEdgeToUID(t, attr, 3, 10, 1, 2)
+	addEdgeToUID(t, attr, 4, 10, 3, 4)
+
+	require.NoError(t, schema.ParseBytes([]byte("seen2: [uid] @presence ."), 1))
+	currentSchema, _ := schema.State().Get(context.Background(), attr)
+	rb := IndexRebuild{
+		Attr:          attr,
+		StartTs:       5,
+		OldSchema:     &pb.SchemaUpdate{ValueType: pb.Posting_UID, List: true},
+		CurrentSchema: &currentSchema,
+	}
+	require.NoError(t, pstore.DropPrefix(prefixesToDropPresenceIndex(context.Background(), &rb)...))
+	require.NoError(t, rebuildPresenceIndex(context.Background(), &rb))
+
+	l, err := GetNoStore(PresenceKey(attr), 6)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{3, 4}, uids(l, 6))
+}
+
 func TestNeedsReverseEdgesRebuild(t *testing.T) {
 	rb := IndexRebuild{}
 	rb.OldSchema = &pb.SchemaUpdate{ValueType: pb.Posting_UID, Directive: pb.SchemaUpdate_INDEX}
@@ -0,0 +1,197 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+)
+
+// IndexDivergence is an entry of the index of a predicate which doesn't agree with its data.
+type IndexDivergence struct {
+	// Key is the index key.
+	Key []byte
+	Uid uint64
+	// Missing is true if the key should hold the UID but doesn't, and false if the key holds the
+	// UID but none of the values of the UID have its token.
+	Missing bool
+}
+
+// dataIndexKeys returns the index keys of the values of the uid for the predicate, at readTs.
+// Values which can't be indexed are skipped, like the mutations do.
+func dataIndexKeys(ctx context.Context, attr string, uid, readTs uint64) (
+	map[string]struct{}, error) {
+	l, err := getNew(x.DataKey(attr, uid), pstore, readTs)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]struct{})
+	err = l.Iterate(readTs, 0, func(p *pb.Posting) error {
+		if p.PostingType == pb.Posting_REF {
+			return nil
+		}
+		val := types.Val{Tid: types.TypeID(p.ValType), Value: p.Value}
+		indexKeys, err := IndexKeys(ctx, attr, string(p.LangTag), val)
+		if err != nil {
+			return nil
+		}
+		for _, key := range indexKeys {
+			keys[string(key)] = struct{}{}
+		}
+		return nil
+	})
+	return keys, err
+}
+
+func hasUid(key []byte, uid, readTs uint64) (bool, error) {
+	l, err := getNew(key, pstore, readTs)
+	if err != nil {
+		return false, err
+	}
+	uids, err := l.Uids(ListOptions{ReadTs: readTs})
+	if err != nil {
+		return false, err
+	}
+	i := sort.Search(len(uids.Uids), func(i int) bool { return uids.Uids[i] >= uid })
+	return i < len(uids.Uids) && uids.Uids[i] == uid, nil
+}
+
+// VerifyDataIndexes checks that the index keys of the values of the uid for the predicate hold
+// the uid, at readTs. It returns the entries missing from the index.
+func VerifyDataIndexes(ctx context.Context, attr string, uid, readTs uint64) (
+	[]IndexDivergence, error) {
+	keys, err := dataIndexKeys(ctx, attr, uid, readTs)
+	if err != nil {
+		return nil, err
+	}
+	var divs []IndexDivergence
+	for key := range keys {
+		ok, err := hasUid([]byte(key), uid, readTs)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			divs = append(divs, IndexDivergence{Key: []byte(key), Uid: uid, Missing: true})
+		}
+	}
+	return divs, nil
+}
+
+// VerifyIndexKey checks that the values of the UIDs held by the index key have its token, at
+// readTs. At most max UIDs, picked at random, are checked. It returns the dangling entries, and
+// the number of UIDs checked.
+func VerifyIndexKey(ctx context.Context, key []byte, readTs uint64, max int) (
+	[]IndexDivergence, int, error) {
+	pk, err := x.Parse(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !pk.IsIndex() {
+		return nil, 0, errors.Errorf("%x is not an index key", key)
+	}
+	l, err := getNew(key, pstore, readTs)
+	if err != nil {
+		return nil, 0, err
+	}
+	list, err := l.Uids(ListOptions{ReadTs: readTs})
+	if err != nil {
+		return nil, 0, err
+	}
+	uids := list.Uids
+	if len(uids) > max {
+		sample := make([]uint64, max)
+		for i, j := range rand.Perm(len(uids))[:max] {
+			sample[i] = uids[j]
+		}
+		uids = sample
+	}
+
+	var divs []IndexDivergence
+	for _, uid := range uids {
+		keys, err := dataIndexKeys(ctx, pk.Attr, uid, readTs)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, ok := keys[string(key)]; !ok {
+			divs = append(divs, IndexDivergence{Key: key, Uid: uid})
+		}
+	}
+	return divs, len(uids), nil
+}
+
+// Diverges returns whether the index entry still diverges from the data at readTs.
+func Diverges(ctx context.Context, div IndexDivergence, readTs uint64) (bool, error) {
+	pk, err := x.Parse(div.Key)
+	if err != nil {
+		return false, err
+	}
+	has, err := hasUid(div.Key, div.Uid, readTs)
+	if err != nil {
+		return false, err
+	}
+	keys, err := dataIndexKeys(ctx, pk.Attr, div.Uid, readTs)
+	if err != nil {
+		return false, err
+	}
+	_, want := keys[string(div.Key)]
+	if div.Missing {
+		return want && !has, nil
+	}
+	return has && !want, nil
+}
+
+// IndexRepairs returns the repairs of the divergences.
+func IndexRepairs(divs []IndexDivergence) []*pb.IndexRepair {
+	repairs := make([]*pb.IndexRepair, 0, len(divs))
+	for _, div := range divs {
+		repairs = append(repairs, &pb.IndexRepair{Key: div.Key, Uid: div.Uid, Missing: div.Missing})
+	}
+	return repairs
+}
+
+// RepairIndex adds the missing UIDs to their index, reverse or count keys and removes the
+// dangling ones, as mutations of the txn. They're committed like the other mutations, once the
+// txn is.
+func (txn *Txn) RepairIndex(ctx context.Context, repairs []*pb.IndexRepair) error {
+	for _, r := range repairs {
+		pk, err := x.Parse(r.Key)
+		if err != nil {
+			return err
+		}
+		if !pk.IsIndex() && !pk.IsReverse() && !pk.IsCountOrCountRev() {
+			return errors.Errorf("%x is not an index, reverse or count key", r.Key)
+		}
+		edge := &pb.DirectedEdge{ValueId: r.Uid, Attr: pk.Attr, Op: pb.DirectedEdge_SET}
+		if !r.Missing {
+			edge.Op = pb.DirectedEdge_DEL
+		}
+		l, err := txn.Get(r.Key)
+		if err != nil {
+			return err
+		}
+		if err := l.addMutation(ctx, txn, edge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
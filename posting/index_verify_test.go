@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyAndRepairIndex(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, schema.ParseBytes([]byte("verified: string @index(exact) ."), 1))
+	attr := x.GalaxyAttr("verified")
+	indexKey := func(s string) []byte {
+		keys, err := IndexKeys(ctx, attr, "", types.Val{Tid: types.StringID, Value: []byte(s)})
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		return keys[0]
+	}
+
+	// The value of 5 isn't indexed, and 6 is indexed under a value it doesn't have.
+	addEdgeToValue(t, attr, 5, "alice", 1, 2)
+	l, err := GetNoStore(indexKey("bob"), 3)
+	require.NoError(t, err)
+	addMutation(t, l, &pb.DirectedEdge{ValueId: 6, Attr: attr}, Set, 3, 4, false)
+
+	missing, err := VerifyDataIndexes(ctx, attr, 5, 10)
+	require.NoError(t, err)
+	require.Equal(t, []IndexDivergence{{Key: indexKey("alice"), Uid: 5, Missing: true}}, missing)
+	dangling, checked, err := VerifyIndexKey(ctx, indexKey("bob"), 10, 100)
+	require.NoError(t, err)
+	require.Equal(t, 1, checked)
+	require.Equal(t, []IndexDivergence{{Key: indexKey("bob"), Uid: 6}}, dangling)
+
+	divs := append(missing, dangling...)
+	for _, div := range divs {
+		ok, err := Diverges(ctx, div, 10)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	txn := Oracle().RegisterStartTs(11)
+	require.NoError(t, txn.RepairIndex(ctx, IndexRepairs(divs)))
+	txn.Update()
+	writer := NewTxnWriter(pstore)
+	require.NoError(t, txn.CommitToDisk(writer, 12))
+	require.NoError(t, writer.Flush())
+
+	missing, err = VerifyDataIndexes(ctx, attr, 5, 12)
+	require.NoError(t, err)
+	require.Empty(t, missing)
+	dangling, _, err = VerifyIndexKey(ctx, indexKey("bob"), 12, 100)
+	require.NoError(t, err)
+	require.Empty(t, dangling)
+	ok, err := hasUid(indexKey("alice"), 5, 12)
+	require.NoError(t, err)
+	require.True(t, ok)
+	for _, div := range divs {
+		ok, err := Diverges(ctx, div, 12)
+		require.NoError(t, err)
+		require.False(t, ok)
+	}
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/dgraph-io/ristretto/z"
 	"github.com/golang/protobuf/proto"
+	ostats "go.opencensus.io/stats"
 )
 
 var (
@@ -453,6 +454,11 @@ func GetConflictKey(pk x.ParsedKey, key []byte, t *pb.DirectedEdge) uint64 {
 	switch {
 	case schema.State().HasNoConflict(t.Attr):
 		break
+	case pk.IsIndex() && pk.Term == presenceTerm:
+		// The presence index is derived from the data keys, which already carry the conflicts.
+		// It's a single list per predicate, so under @upsert it would make every write to the
+		// predicate conflict with all the others.
+		break
 	case schema.State().HasUpsert(t.Attr):
 		// Consider checking to see if a email id is unique. A user adds:
 		// <uid> <email> "email@email.org", and there's a string equal tokenizer
@@ -824,13 +830,43 @@ func (l *List) Rollup(alloc *z.Allocator) ([]*bpb.KV, error) {
 		return nil, nil
 	}
 	defer out.free()
+	return l.marshalRollup(out, alloc)
+}
+
+// Rewrite rolls up the list at readTs and splits it again from scratch, so that the parts of a
+// multi-part list whose size changed a lot over time are balanced again. The returned KVs have
+// readTs as their version, so readTs must not be the commit timestamp of any transaction, and
+// all the transactions committed before readTs must have been applied.
+func (l *List) Rewrite(readTs uint64, alloc *z.Allocator) ([]*bpb.KV, error) {
+	l.RLock()
+	defer l.RUnlock()
+	out, err := l.rollup(readTs, false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed when calling List.rollup")
+	}
+	if out == nil {
+		return nil, nil
+	}
+	defer out.free()
+
+	out.newMinTs = readTs
+	out.recursiveSplit()
+	out.removeEmptySplits()
+	out.mergeSmallSplits()
+	return l.marshalRollup(out, alloc)
+}
 
+// marshalRollup returns the KVs to write to disk for the output of a rollup of the list. The
+// parts of the list which are no longer used are written as empty, so that they're removed by
+// the compactions.
+func (l *List) marshalRollup(out *rollupOutput, alloc *z.Allocator) ([]*bpb.KV, error) {
 	var kvs []*bpb.KV
 	kv := MarshalPostingList(out.plist, alloc)
 	kv.Version = out.newMinTs
 	kv.Key = alloc.Copy(l.key)
 	kvs = append(kvs, kv)
 
+	var created int64
 	for startUid, plist := range out.parts {
 		// Any empty posting list would still have BitEmpty set. And the main posting list
 		// would NOT have that posting list startUid in the splits list.
@@ -839,6 +875,27 @@ func (l *List) Rollup(alloc *z.Allocator) ([]*bpb.KV, error) {
 			return nil, errors.Wrapf(err, "cannot marshaling posting list parts")
 		}
 		kvs = append(kvs, kv)
+		if !hasSplit(l.plist.Splits, startUid) {
+			created++
+		}
+	}
+	var removed int64
+	for _, startUid := range l.plist.Splits {
+		if _, ok := out.parts[startUid]; ok {
+			continue
+		}
+		kv, err := out.marshalPostingListPart(alloc, l.key, startUid, &pb.PostingList{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot marshaling removed posting list parts")
+		}
+		kvs = append(kvs, kv)
+		removed++
+	}
+	if created > 0 {
+		ostats.Record(context.Background(), x.NumListPartsCreated.M(created))
+	}
+	if removed > 0 {
+		ostats.Record(context.Background(), x.NumListPartsRemoved.M(removed))
 	}
 
 	// Sort the KVs by their key so that the main part of the list is at the
@@ -946,6 +1003,9 @@ type rollupOutput struct {
 	plist    *pb.PostingList
 	parts    map[uint64]*pb.PostingList
 	newMinTs uint64
+	// merged are the parts which were merged into other parts. They're kept until the output
+	// is freed, because binSplit makes parts share their allocator.
+	merged []*pb.PostingList
 }
 
 func (out *rollupOutput) free() {
@@ -953,6 +1013,9 @@ func (out *rollupOutput) free() {
 	for _, part := range out.parts {
 		codec.FreePack(part.Pack)
 	}
+	for _, part := range out.merged {
+		codec.FreePack(part.Pack)
+	}
 }
 
 /*
@@ -1097,6 +1160,7 @@ func (l *List) rollup(readTs uint64, split bool) (*rollupOutput, error) {
 		// become too big. Split the list if that is the case.
 		out.recursiveSplit()
 		out.removeEmptySplits()
+		out.mergeSmallSplits()
 	} else {
 		out.plist.Splits = nil
 	}
@@ -1441,14 +1505,21 @@ func (l *List) readListPart(startUid uint64) (*pb.PostingList, error) {
 			"cannot generate key for list with base key %s and start UID %d",
 			hex.EncodeToString(l.key), startUid)
 	}
-	txn := pstore.NewTransactionAt(l.minTs, false)
+	db, err := readStore(l.key)
+	if err != nil {
+		return nil, err
+	}
+	txn := db.NewTransactionAt(l.minTs, false)
 	item, err := txn.Get(key)
 	if err != nil {
 		return nil, errors.Wrapf(err, "could not read list part with key %s",
 			hex.EncodeToString(key))
 	}
 	part := &pb.PostingList{}
-	if err := unmarshalOrCopy(part, item); err != nil {
+	if err := readCompletePosting(part, item); err != nil {
+		if err = quarantineIfCorrupted(l.key, item, err); x.IsCorruptedPosting(err) {
+			return nil, err
+		}
 		return nil, errors.Wrapf(err, "cannot unmarshal list part with key %s",
 			hex.EncodeToString(key))
 	}
@@ -1583,6 +1654,65 @@ func (out *rollupOutput) removeEmptySplits() {
 	}
 }
 
+// shouldMerge returns true if the given adjacent parts of a multi-part list should be merged
+// into one. At least one of them must be under-filled, and the merged part must stay well below
+// the size at which it would be split again.
+func shouldMerge(low, high *pb.PostingList) bool {
+	lowSize, highSize := low.Size(), high.Size()
+	if lowSize >= maxListSize/4 && highSize >= maxListSize/4 {
+		return false
+	}
+	return lowSize+highSize < maxListSize/2
+}
+
+// mergeParts returns a part holding the postings of the given adjacent parts.
+func mergeParts(low, high *pb.PostingList) *pb.PostingList {
+	enc := codec.Encoder{BlockSize: blockSize}
+	for _, part := range []*pb.PostingList{low, high} {
+		for _, uid := range codec.Decode(part.Pack, 0) {
+			enc.Add(uid)
+		}
+	}
+	postings := make([]*pb.Posting, 0, len(low.Postings)+len(high.Postings))
+	postings = append(postings, low.Postings...)
+	postings = append(postings, high.Postings...)
+	return &pb.PostingList{Pack: enc.Done(), Postings: postings}
+}
+
+// mergeSmallSplits merges the adjacent parts of the list which became under-filled, e.g. after
+// many deletions. If a single part remains, the list is no longer a multi-part list.
+func (out *rollupOutput) mergeSmallSplits() {
+	if len(out.parts) == 0 {
+		return
+	}
+	splits := out.splits()
+	for i := 1; i < len(splits); {
+		low, high := out.parts[splits[i-1]], out.parts[splits[i]]
+		if !shouldMerge(low, high) {
+			i++
+			continue
+		}
+		out.parts[splits[i-1]] = mergeParts(low, high)
+		out.merged = append(out.merged, low, high)
+		delete(out.parts, splits[i])
+		splits = append(splits[:i], splits[i+1:]...)
+	}
+	out.updateSplits()
+
+	if len(out.parts) == 1 {
+		// The first part always starts with UID 1.
+		out.plist = out.parts[1]
+		out.plist.Splits = nil
+		delete(out.parts, 1)
+	}
+}
+
+// hasSplit returns true if the given sorted list of start UIDs contains startUid.
+func hasSplit(splits []uint64, startUid uint64) bool {
+	i := sort.Search(len(splits), func(i int) bool { return splits[i] >= startUid })
+	return i < len(splits) && splits[i] == startUid
+}
+
 // Returns the sorted list of start UIDs based on the keys in out.parts.
 // out.parts is considered the source of truth so this method is considered
 // safer than using out.plist.Splits directly.
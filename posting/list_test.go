@@ -1136,6 +1136,10 @@ func TestMultiPartListWithPostings(t *testing.T) {
 
 // Verify marshaling of multi-part lists.
 func TestMultiPartListMarshal(t *testing.T) {
+	// Roll up with the threshold the list was split with, so that its parts aren't merged.
+	defer setMaxListSize(maxListSize)
+	maxListSize = 5000
+
 	size := int(1e5)
 	ol, _ := createMultiPartList(t, size, false)
 
@@ -1164,6 +1168,10 @@ func TestMultiPartListMarshal(t *testing.T) {
 
 // Verify that writing a multi-part list to disk works correctly.
 func TestMultiPartListWriteToDisk(t *testing.T) {
+	// Roll up with the threshold the list was split with, so that its parts aren't merged.
+	defer setMaxListSize(maxListSize)
+	maxListSize = 5000
+
 	size := int(1e5)
 	originalList, commits := createMultiPartList(t, size, false)
 
@@ -1187,6 +1195,103 @@ func TestMultiPartListWriteToDisk(t *testing.T) {
 	}
 }
 
+// Verify that the parts of a multi-part list which become under-filled are merged.
+func TestMultiPartListMerge(t *testing.T) {
+	defer setMaxListSize(maxListSize)
+	maxListSize = 5000
+
+	size := int(1e5)
+	ol, _ := createMultiPartList(t, size, false)
+	numParts := len(ol.plist.Splits)
+
+	// Delete all the UIDs but one out of ten.
+	baseTs := uint64(size) + 1
+	for i := 1; i <= size; i++ {
+		if i%10 == 0 {
+			continue
+		}
+		txn := Txn{StartTs: baseTs + uint64(i)}
+		addMutationHelper(t, ol, &pb.DirectedEdge{ValueId: uint64(i)}, Del, &txn)
+		require.NoError(t, ol.commitMutation(baseTs+uint64(i), baseTs+uint64(i)+1))
+	}
+	kvs, err := ol.Rollup(nil)
+	require.NoError(t, err)
+
+	// The removed parts are written as empty.
+	var empty int
+	for _, kv := range kvs {
+		if kv.UserMeta[0] == BitEmptyPosting {
+			empty++
+		}
+	}
+	require.NoError(t, writePostingListToDisk(kvs))
+	ol, err = getNew(ol.key, ps, math.MaxUint64)
+	require.NoError(t, err)
+	require.Less(t, len(ol.plist.Splits), numParts)
+	require.Equal(t, numParts-len(ol.plist.Splits), empty)
+	verifySplits(t, ol.plist.Splits)
+
+	// Once it's small enough, the list is no longer a multi-part list.
+	maxListSize = mb / 2
+	startTs := baseTs + uint64(size) + 2
+	txn := Txn{StartTs: startTs}
+	addMutationHelper(t, ol, &pb.DirectedEdge{ValueId: uint64(size + 1)}, Set, &txn)
+	require.NoError(t, ol.commitMutation(startTs, startTs+1))
+	kvs, err = ol.Rollup(nil)
+	require.NoError(t, err)
+	require.NoError(t, writePostingListToDisk(kvs))
+	ol, err = getNew(ol.key, ps, math.MaxUint64)
+	require.NoError(t, err)
+	require.Empty(t, ol.plist.Splits)
+
+	l, err := ol.Uids(ListOptions{ReadTs: math.MaxUint64})
+	require.NoError(t, err)
+	require.Equal(t, size/10+1, len(l.Uids))
+	for i, uid := range l.Uids[:size/10] {
+		require.Equal(t, uint64(10*(i+1)), uid)
+	}
+}
+
+// Verify that rewriting a multi-part list balances its parts without losing any data.
+func TestMultiPartListRewrite(t *testing.T) {
+	size := int(1e5)
+	ol, _ := createMultiPartList(t, size, true)
+	numParts := len(ol.plist.Splits)
+
+	defer setMaxListSize(maxListSize)
+	maxListSize = 20000
+	readTs := uint64(size) + 2
+	kvs, err := ol.Rewrite(readTs, nil)
+	require.NoError(t, err)
+	for _, kv := range kvs {
+		require.Equal(t, readTs, kv.Version)
+	}
+	require.NoError(t, writePostingListToDisk(kvs))
+
+	nl, err := getNew(ol.key, ps, readTs)
+	require.NoError(t, err)
+	require.Greater(t, len(nl.plist.Splits), 0)
+	require.Less(t, len(nl.plist.Splits), numParts)
+	verifySplits(t, nl.plist.Splits)
+
+	var uids []uint64
+	require.NoError(t, nl.Iterate(readTs, 0, func(p *pb.Posting) error {
+		require.Equal(t, strconv.Itoa(int(p.Uid)), p.Facets[0].Key)
+		uids = append(uids, p.Uid)
+		return nil
+	}))
+	require.Equal(t, size, len(uids))
+	for i, uid := range uids {
+		require.Equal(t, uint64(i+1), uid)
+	}
+
+	// The list is still readable at the older timestamps.
+	l, err := getNew(ol.key, ps, readTs-1)
+	require.NoError(t, err)
+	require.Equal(t, ol.plist.Splits, l.plist.Splits)
+	require.Equal(t, size, l.Length(readTs-1, 0))
+}
+
 // Verify that adding and deleting all the entries returns an empty list.
 func TestMultiPartListDelete(t *testing.T) {
 	size := int(1e4)
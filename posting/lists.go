@@ -47,7 +47,7 @@ var (
 func Init(ps *badger.DB, cacheSize int64) {
 	pstore = ps
 	closer = z.NewCloser(1)
-	go x.MonitorMemoryMetrics(closer)
+	go x.RunGovernor(closer)
 	// Initialize cache.
 	if cacheSize == 0 {
 		return
@@ -83,6 +83,12 @@ func UpdateMaxCost(maxCost int64) {
 	lCache.UpdateMaxCost(maxCost)
 }
 
+// SetPstore switches over to another store holding the same data, and drops the cached lists.
+func SetPstore(ps *badger.DB) {
+	pstore = ps
+	ResetCache()
+}
+
 // Cleanup waits until the closer has finished processing.
 func Cleanup() {
 	closer.SignalAndWait()
@@ -91,7 +97,7 @@ func Cleanup() {
 // GetNoStore returns the list stored in the key or creates a new one if it doesn't exist.
 // It does not store the list in any cache.
 func GetNoStore(key []byte, readTs uint64) (rlist *List, err error) {
-	return getNew(key, pstore, readTs)
+	return getFromStore(key, readTs)
 }
 
 // LocalCache stores a cache of posting lists and deltas.
@@ -158,7 +164,7 @@ func (lc *LocalCache) getInternal(key []byte, readFromDisk bool) (*List, error)
 		lc.RLock()
 		defer lc.RUnlock()
 		if lc.plists == nil {
-			return getNew(key, pstore, lc.startTs)
+			return getFromStore(key, lc.startTs)
 		}
 		return nil, nil
 	}
@@ -175,7 +181,7 @@ func (lc *LocalCache) getInternal(key []byte, readFromDisk bool) (*List, error)
 	var pl *List
 	if readFromDisk {
 		var err error
-		pl, err = getNew(key, pstore, lc.startTs)
+		pl, err = getFromStore(key, lc.startTs)
 		if err != nil {
 			return nil, err
 		}
@@ -198,6 +204,7 @@ func (lc *LocalCache) getInternal(key []byte, readFromDisk bool) (*List, error)
 
 // Get retrieves the cached version of the list associated with the given key.
 func (lc *LocalCache) Get(key []byte) (*List, error) {
+	hotKeys.record(key, false)
 	return lc.getInternal(key, true)
 }
 
@@ -49,6 +49,9 @@ type incrRollupi struct {
 	// while idx 1 represents low priority keys to be rolled up.
 	priorityKeys []*pooledKeys
 	count        uint64
+	// delay is an additional wait in nanoseconds after every batch of low priority keys. It is
+	// used to throttle the rollups, and the compactions they cause, at runtime.
+	delay int64
 }
 
 var (
@@ -91,6 +94,7 @@ func (ir *incrRollupi) rollUpKey(writer *TxnWriter, key []byte) error {
 	}
 	// Clear the list from the cache after a rollup.
 	RemoveCacheFor(key)
+	recordPredicateMetric(key, x.NumRollups.M(1))
 
 	const N = uint64(1000)
 	if glog.V(2) {
@@ -101,6 +105,16 @@ func (ir *incrRollupi) rollUpKey(writer *TxnWriter, key []byte) error {
 	return writer.Write(&bpb.KVList{Kv: kvs})
 }
 
+// SetDelay sets the additional wait after every batch of low priority keys rolled up.
+func (ir *incrRollupi) SetDelay(d time.Duration) {
+	atomic.StoreInt64(&ir.delay, int64(d))
+}
+
+// Delay returns the additional wait after every batch of low priority keys rolled up.
+func (ir *incrRollupi) Delay() time.Duration {
+	return time.Duration(atomic.LoadInt64(&ir.delay))
+}
+
 // TODO: When the opRollup is not running the keys from keysPool of ir are dropped. Figure out some
 // way to handle that.
 func (ir *incrRollupi) addKeyToBatch(key []byte, priority int) {
@@ -137,11 +151,11 @@ func (ir *incrRollupi) Process(closer *z.Closer) {
 	defer forceRollupTick.Stop()
 
 	doRollup := func(batch *[][]byte, priority int) {
-		currTs := time.Now().Unix()
+		currTs := time.Now().UnixNano()
 		for _, key := range *batch {
 			hash := z.MemHash(key)
-			if elem := m[hash]; currTs-elem >= 10 {
-				// Key not present or Key present but last roll up was more than 10 sec ago.
+			if elem := m[hash]; currTs-elem >= int64(rollups.interval(key)) {
+				// Key not present or Key present but last roll up was long enough ago.
 				// Add/Update map and rollup.
 				m[hash] = currTs
 				if err := ir.rollUpKey(writer, key); err != nil {
@@ -160,8 +174,9 @@ func (ir *incrRollupi) Process(closer *z.Closer) {
 		case <-cleanupTick.C:
 			currTs := time.Now().UnixNano()
 			for hash, ts := range m {
-				// Remove entries from map which have been there for there more than 10 seconds.
-				if currTs-ts >= int64(10*time.Second) {
+				// Remove entries from map which are older than the longest interval between
+				// rollups.
+				if currTs-ts >= int64(rollups.maxInterval()) {
 					delete(m, hash)
 				}
 			}
@@ -179,6 +194,13 @@ func (ir *incrRollupi) Process(closer *z.Closer) {
 			doRollup(batch, 1)
 			// throttle to 1 batch = 16 rollups per 1 ms.
 			<-limiter.C
+			if d := ir.Delay(); d > 0 {
+				select {
+				case <-time.After(d):
+				case <-closer.HasBeenClosed():
+					return
+				}
+			}
 		}
 	}
 }
@@ -243,7 +265,19 @@ func (txn *Txn) CommitToDisk(writer *TxnWriter, commitTs uint64) error {
 		// Add these keys to be rolled up after we're done writing. This is the right place for them
 		// to be rolled up, because we just pushed these deltas over to Badger.
 		for _, key := range keys {
-			IncrRollup.addKeyToBatch([]byte(key), 1)
+			hotKeys.record([]byte(key), true)
+			priority := hotKeys.rollupPriority(key)
+			switch rollups.recordWrite([]byte(key)) {
+			case rollupHot:
+				priority = 0
+			case rollupCold:
+				// Keys of cold predicates are rolled up once read instead, unless the key
+				// itself is hot.
+				if priority != 0 {
+					continue
+				}
+			}
+			IncrRollup.addKeyToBatch([]byte(key), priority)
 		}
 	}()
 
@@ -320,7 +354,7 @@ func unmarshalOrCopy(plist *pb.PostingList, item *badger.Item) error {
 			// empty pl
 			return nil
 		}
-		return plist.Unmarshal(val)
+		return unmarshalPostingList(plist, val)
 	})
 }
 
@@ -346,6 +380,10 @@ func ReadPostingList(key []byte, it *badger.Iterator) (*List, error) {
 		return nil, ErrInvalidKey
 	}
 
+	if err := checkQuarantine(key); err != nil {
+		return nil, err
+	}
+
 	l := new(List)
 	l.key = key
 	l.plist = new(pb.PostingList)
@@ -354,6 +392,7 @@ func ReadPostingList(key []byte, it *badger.Iterator) (*List, error) {
 	deltaCount := 0
 	defer func() {
 		if deltaCount > 0 {
+			recordPredicateMetric(key, x.PostingDeltaLength.M(int64(deltaCount)))
 			// If deltaCount is high, send it to high priority channel instead.
 			if deltaCount > 500 {
 				IncrRollup.addKeyToBatch(key, 0)
@@ -380,8 +419,8 @@ func ReadPostingList(key []byte, it *badger.Iterator) (*List, error) {
 			l.minTs = item.Version()
 			return l, nil
 		case BitCompletePosting:
-			if err := unmarshalOrCopy(l.plist, item); err != nil {
-				return nil, err
+			if err := readCompletePosting(l.plist, item); err != nil {
+				return nil, quarantineIfCorrupted(key, item, err)
 			}
 			l.minTs = item.Version()
 
@@ -391,7 +430,7 @@ func ReadPostingList(key []byte, it *badger.Iterator) (*List, error) {
 		case BitDeltaPosting:
 			err := item.Value(func(val []byte) error {
 				pl := &pb.PostingList{}
-				if err := pl.Unmarshal(val); err != nil {
+				if err := unmarshalPostingList(pl, val); err != nil {
 					return err
 				}
 				pl.CommitTs = item.Version()
@@ -407,7 +446,7 @@ func ReadPostingList(key []byte, it *badger.Iterator) (*List, error) {
 				return nil
 			})
 			if err != nil {
-				return nil, err
+				return nil, quarantineIfCorrupted(key, item, err)
 			}
 			deltaCount++
 		case BitSchemaPosting:
@@ -0,0 +1,207 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// QuarantinedKey is a posting list which failed to be unmarshalled. It isn't read anymore, and
+// the reads and writes of its data fail with a CorruptedPostingError, until it's purged.
+type QuarantinedKey struct {
+	// Key is the key of the posting list.
+	Key []byte
+	// Part is the key of the part of the list which is corrupted, if the list is split.
+	Part []byte
+	// Version is the version of the corrupted value.
+	Version uint64
+	// Err is the error unmarshalling the value.
+	Err   string
+	Since time.Time
+	// DumpFile is the file holding the raw bytes of the value, if they could be written.
+	DumpFile string
+}
+
+var quarantine = struct {
+	sync.RWMutex
+	keys map[string]*QuarantinedKey
+}{keys: make(map[string]*QuarantinedKey)}
+
+// unmarshalError is returned for a posting list value which can't be unmarshalled.
+type unmarshalError struct {
+	err error
+}
+
+func (e *unmarshalError) Error() string {
+	return fmt.Sprintf("while unmarshalling posting list: %v", e.err)
+}
+
+// unmarshalPostingList unmarshals the value of a posting list. It returns an unmarshalError if
+// the value is corrupted.
+func unmarshalPostingList(plist *pb.PostingList, val []byte) error {
+	if err := plist.Unmarshal(val); err != nil {
+		return &unmarshalError{err: err}
+	}
+	return nil
+}
+
+// checkQuarantine returns a CorruptedPostingError if the key is quarantined.
+func checkQuarantine(key []byte) error {
+	quarantine.RLock()
+	defer quarantine.RUnlock()
+	if len(quarantine.keys) == 0 {
+		return nil
+	}
+	if _, ok := quarantine.keys[string(key)]; ok {
+		return &x.CorruptedPostingError{Key: key}
+	}
+	return nil
+}
+
+// quarantineIfCorrupted quarantines the key if err is an unmarshalError for the value of the
+// item, which is the value of key or of one of its parts. It returns the error to return to the
+// reader of the key.
+func quarantineIfCorrupted(key []byte, item *badger.Item, err error) error {
+	var ue *unmarshalError
+	if !errors.As(err, &ue) {
+		return err
+	}
+
+	q := &QuarantinedKey{
+		Key:     append([]byte{}, key...),
+		Version: item.Version(),
+		Err:     ue.err.Error(),
+		Since:   time.Now(),
+	}
+	if part := item.Key(); string(part) != string(key) {
+		q.Part = append([]byte{}, part...)
+	}
+	quarantine.Lock()
+	if _, ok := quarantine.keys[string(key)]; ok {
+		quarantine.Unlock()
+		return &x.CorruptedPostingError{Key: key}
+	}
+	quarantine.keys[string(key)] = q
+	quarantine.Unlock()
+	RemoveCacheFor(key)
+
+	// Keep the raw bytes for analysis.
+	dumpErr := func() error {
+		val, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Join(x.WorkerConfig.TmpDir, "quarantine")
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+		f := filepath.Join(dir, fmt.Sprintf("%s-%d.bin", hex.EncodeToString(item.Key()),
+			item.Version()))
+		if err := ioutil.WriteFile(f, val, 0600); err != nil {
+			return err
+		}
+		quarantine.Lock()
+		q.DumpFile = f
+		quarantine.Unlock()
+		return nil
+	}()
+	glog.Errorf("Quarantined corrupted posting list with key %s at version %d: %v. Dump: %q, "+
+		"dump error: %v", hex.EncodeToString(item.Key()), item.Version(), ue.err, q.DumpFile,
+		dumpErr)
+	recordPredicateMetric(key, x.NumQuarantinedKeys.M(1))
+	return &x.CorruptedPostingError{Key: key}
+}
+
+// QuarantinedKeys returns the keys quarantined by this Alpha, sorted by key.
+func QuarantinedKeys() []QuarantinedKey {
+	quarantine.RLock()
+	defer quarantine.RUnlock()
+	keys := make([]QuarantinedKey, 0, len(quarantine.keys))
+	for _, q := range quarantine.keys {
+		keys = append(keys, *q)
+	}
+	sort.Slice(keys, func(i, j int) bool { return string(keys[i].Key) < string(keys[j].Key) })
+	return keys
+}
+
+// PurgeQuarantined deletes the data of the quarantined key from this Alpha, including the parts of
+// the list if it's split, and takes it out of quarantine. The key then reads as empty, until it's
+// written again. The other replicas of the group aren't changed.
+func PurgeQuarantined(key []byte) error {
+	quarantine.RLock()
+	q, ok := quarantine.keys[string(key)]
+	quarantine.RUnlock()
+	if !ok {
+		return errors.Errorf("key %s isn't quarantined", hex.EncodeToString(key))
+	}
+
+	// Delete all the versions up to now. The reads at older timestamps would find the
+	// corrupted value again, and quarantine the key again.
+	ts := x.Max(Oracle().MaxAssigned(), q.Version)
+	keys := [][]byte{key}
+	if key[0] == x.DefaultPrefix {
+		// The parts of the list have the same key with the ByteSplit prefix and a start UID.
+		prefix, err := x.SplitKey(key, 0)
+		if err != nil {
+			return err
+		}
+		prefix = prefix[:len(key)]
+		txn := pstore.NewTransactionAt(math.MaxUint64, false)
+		iopt := badger.DefaultIteratorOptions
+		iopt.PrefetchValues = false
+		iopt.Prefix = prefix
+		it := txn.NewIterator(iopt)
+		for it.Rewind(); it.Valid(); it.Next() {
+			if k := it.Item().Key(); len(k) == len(key)+8 {
+				keys = append(keys, it.Item().KeyCopy(nil))
+			}
+		}
+		it.Close()
+		txn.Discard()
+	}
+
+	writer := NewTxnWriter(pstore)
+	for _, k := range keys {
+		if err := writer.SetAt(k, nil, BitEmptyPosting, ts); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	quarantine.Lock()
+	delete(quarantine.keys, string(key))
+	quarantine.Unlock()
+	RemoveCacheFor(key)
+	glog.Infof("Purged quarantined posting list with key %s at ts %d", hex.EncodeToString(key), ts)
+	return nil
+}
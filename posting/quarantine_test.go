@@ -0,0 +1,64 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	x.WorkerConfig.TmpDir = dir
+	defer func() { x.WorkerConfig.TmpDir = "" }()
+
+	key := x.DataKey(x.GalaxyAttr("quarantine"), 1)
+	txn := ps.NewTransactionAt(1, true)
+	// A field number of zero can't be unmarshalled.
+	require.NoError(t, txn.SetEntry(badger.NewEntry(key, []byte{0, 1, 2}).
+		WithMeta(BitCompletePosting)))
+	require.NoError(t, txn.CommitAt(2, nil))
+
+	_, err = getNew(key, ps, math.MaxUint64)
+	require.True(t, x.IsCorruptedPosting(err), "%v", err)
+	keys := QuarantinedKeys()
+	require.Len(t, keys, 1)
+	require.Equal(t, key, keys[0].Key)
+	require.Equal(t, uint64(2), keys[0].Version)
+	dump, err := ioutil.ReadFile(keys[0].DumpFile)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 1, 2}, dump)
+
+	// The key isn't read again while it's quarantined.
+	_, err = getNew(key, ps, math.MaxUint64)
+	require.True(t, x.IsCorruptedPosting(err), "%v", err)
+
+	require.NoError(t, PurgeQuarantined(key))
+	require.Empty(t, QuarantinedKeys())
+	l, err := getNew(key, ps, math.MaxUint64)
+	require.NoError(t, err)
+	require.Equal(t, 0, l.Length(math.MaxUint64, 0))
+	require.Error(t, PurgeQuarantined(key))
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// RollupDefaults are the default values for the --rollup superflag.
+const RollupDefaults = "adaptive=false; window=1m; hot-writes=10000; cold-writes=100; " +
+	"hot-interval=1s; cold-interval=5m"
+
+// defaultRollupInterval is the min time between two rollups of the same key, unless its
+// predicate is hot or cold.
+const defaultRollupInterval = 10 * time.Second
+
+type rollupClass int
+
+const (
+	rollupNormal rollupClass = iota
+	// rollupHot is the class of the predicates written often. Their keys are rolled up ahead
+	// of the others and more often, so that their deltas don't pile up.
+	rollupHot
+	// rollupCold is the class of the predicates rarely written. Their keys aren't rolled up
+	// after writes, but only once read, and less often.
+	rollupCold
+)
+
+// rollupScheduler classifies the predicates by how often they're written, to adapt the
+// rollups of their keys.
+type rollupScheduler struct {
+	window       time.Duration
+	hotWrites    uint64
+	coldWrites   uint64
+	hotInterval  time.Duration
+	coldInterval time.Duration
+
+	// writes maps the predicates to the number of their keys written in the current window.
+	writes sync.Map
+	// classes holds the map[string]rollupClass of the predicates, from the last window.
+	// Predicates not in it weren't written, and are cold.
+	classes atomic.Value
+}
+
+// rollups is the scheduler set up via InitRollups, or nil if rollups aren't adaptive.
+var rollups *rollupScheduler
+
+// InitRollups sets up the scheduling of the rollups configured via the --rollup superflag.
+func InitRollups(sf *z.SuperFlag) error {
+	s, err := newRollupScheduler(sf)
+	if err != nil || s == nil {
+		return err
+	}
+	rollups = s
+	closer.AddRunning(1)
+	go func() {
+		defer closer.Done()
+		ticker := time.NewTicker(s.window)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.rotate()
+			case <-closer.HasBeenClosed():
+				return
+			}
+		}
+	}()
+	glog.Infof("Adaptive rollups: predicates with %d writes per %s are hot, with %d cold",
+		s.hotWrites, s.window, s.coldWrites)
+	return nil
+}
+
+func newRollupScheduler(sf *z.SuperFlag) (*rollupScheduler, error) {
+	if !sf.GetBool("adaptive") {
+		return nil, nil
+	}
+	s := &rollupScheduler{
+		hotWrites:  sf.GetUint64("hot-writes"),
+		coldWrites: sf.GetUint64("cold-writes"),
+	}
+	for opt, d := range map[string]*time.Duration{
+		"window":        &s.window,
+		"hot-interval":  &s.hotInterval,
+		"cold-interval": &s.coldInterval,
+	} {
+		var err error
+		if *d, err = time.ParseDuration(sf.GetString(opt)); err != nil {
+			return nil, errors.Wrapf(err, "while parsing %s", opt)
+		}
+	}
+	switch {
+	case s.window < time.Second:
+		return nil, errors.Errorf("window must be at least a second")
+	case s.coldWrites >= s.hotWrites:
+		return nil, errors.Errorf("cold-writes must be less than hot-writes")
+	case s.hotInterval <= 0 || s.hotInterval > defaultRollupInterval:
+		return nil, errors.Errorf("hot-interval must be in the range (0, %s]",
+			defaultRollupInterval)
+	case s.coldInterval < defaultRollupInterval:
+		return nil, errors.Errorf("cold-interval must be at least %s", defaultRollupInterval)
+	}
+	s.classes.Store(map[string]rollupClass{})
+	return s, nil
+}
+
+// recordWrite counts a write of the key, and returns the class of its predicate.
+func (s *rollupScheduler) recordWrite(key []byte) rollupClass {
+	if s == nil {
+		return rollupNormal
+	}
+	pk, err := x.Parse(key)
+	if err != nil {
+		return rollupNormal
+	}
+	n, ok := s.writes.Load(pk.Attr)
+	if !ok {
+		n, _ = s.writes.LoadOrStore(pk.Attr, new(uint64))
+	}
+	atomic.AddUint64(n.(*uint64), 1)
+	return s.class(pk.Attr)
+}
+
+func (s *rollupScheduler) class(attr string) rollupClass {
+	c, ok := s.classes.Load().(map[string]rollupClass)[attr]
+	if !ok {
+		return rollupCold
+	}
+	return c
+}
+
+// interval returns the min time between two rollups of the key.
+func (s *rollupScheduler) interval(key []byte) time.Duration {
+	if s == nil {
+		return defaultRollupInterval
+	}
+	pk, err := x.Parse(key)
+	if err != nil {
+		return defaultRollupInterval
+	}
+	switch s.class(pk.Attr) {
+	case rollupHot:
+		return s.hotInterval
+	case rollupCold:
+		return s.coldInterval
+	}
+	return defaultRollupInterval
+}
+
+// maxInterval returns the longest min time between two rollups of a key.
+func (s *rollupScheduler) maxInterval() time.Duration {
+	if s == nil {
+		return defaultRollupInterval
+	}
+	return s.coldInterval
+}
+
+// rotate classifies the predicates by their writes during the window which just ended, and
+// starts a new window.
+func (s *rollupScheduler) rotate() {
+	classes := make(map[string]rollupClass)
+	var hot, cold int
+	s.writes.Range(func(k, v interface{}) bool {
+		n := atomic.SwapUint64(v.(*uint64), 0)
+		switch {
+		case n >= s.hotWrites:
+			classes[k.(string)] = rollupHot
+			hot++
+		case n > s.coldWrites:
+			classes[k.(string)] = rollupNormal
+		case n == 0:
+			// Forget the predicates which weren't written.
+			s.writes.Delete(k)
+			cold++
+		default:
+			cold++
+		}
+		return true
+	})
+	s.classes.Store(classes)
+	glog.V(2).Infof("Adaptive rollups: %d hot and %d cold predicates", hot, cold)
+}
+
+// recordPredicateMetric records the measurement tagged with the predicate of the key.
+func recordPredicateMetric(key []byte, m ostats.Measurement) {
+	pk, err := x.Parse(key)
+	if err != nil {
+		return
+	}
+	ns, attr := x.ParseNamespaceAttr(pk.Attr)
+	ctx, _ := tag.New(context.Background(),
+		tag.Upsert(x.KeyPredicate, fmt.Sprintf("%d-%s", ns, attr)))
+	ostats.Record(ctx, m)
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package posting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollupFlag(t *testing.T) {
+	parse := func(flag string) (*rollupScheduler, error) {
+		return newRollupScheduler(z.NewSuperFlag(flag).MergeAndCheckDefault(RollupDefaults))
+	}
+	s, err := parse("")
+	require.NoError(t, err)
+	require.Nil(t, s)
+
+	s, err = parse("adaptive=true")
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, s.window)
+	require.Equal(t, uint64(10000), s.hotWrites)
+
+	for _, flag := range []string{"window=10ms", "hot-writes=10; cold-writes=10",
+		"hot-interval=0s", "hot-interval=1m", "cold-interval=1s", "window=x"} {
+		_, err = parse("adaptive=true; " + flag)
+		require.Error(t, err, flag)
+	}
+}
+
+func TestRollupClasses(t *testing.T) {
+	s, err := newRollupScheduler(z.NewSuperFlag("adaptive=true; hot-writes=50; cold-writes=5").
+		MergeAndCheckDefault(RollupDefaults))
+	require.NoError(t, err)
+
+	hot := x.DataKey(x.GalaxyAttr("hot"), 1)
+	normal := x.DataKey(x.GalaxyAttr("normal"), 1)
+	cold := x.DataKey(x.GalaxyAttr("cold"), 1)
+
+	// Predicates are cold until they're seen written.
+	require.Equal(t, rollupCold, s.recordWrite(hot))
+	for i := 0; i < 100; i++ {
+		s.recordWrite(hot)
+		if i < 10 {
+			s.recordWrite(normal)
+		}
+	}
+	s.recordWrite(cold)
+	s.rotate()
+
+	require.Equal(t, rollupHot, s.class(x.GalaxyAttr("hot")))
+	require.Equal(t, rollupNormal, s.class(x.GalaxyAttr("normal")))
+	require.Equal(t, rollupCold, s.class(x.GalaxyAttr("cold")))
+	require.Equal(t, s.hotInterval, s.interval(hot))
+	require.Equal(t, defaultRollupInterval, s.interval(normal))
+	require.Equal(t, s.coldInterval, s.interval(cold))
+
+	// Without writes, predicates turn cold.
+	s.rotate()
+	require.Equal(t, rollupCold, s.class(x.GalaxyAttr("hot")))
+
+	// Rollups aren't adaptive without a scheduler.
+	var none *rollupScheduler
+	require.Equal(t, rollupNormal, none.recordWrite(hot))
+	require.Equal(t, defaultRollupInterval, none.interval(cold))
+}
@@ -0,0 +1,444 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const (
+	// uidBytes is the size of a UID in the posting lists, ignoring their compression.
+	uidBytes = 8
+	// rangeSelectivity is the assumed fraction of the values of a predicate matched by an
+	// inequality, since the distribution of the values isn't known.
+	rangeSelectivity = 1.0 / 3
+	// patternSelectivity is the assumed fraction of the values of a predicate which are
+	// candidates for a regexp or a match, after the lookup of their trigrams.
+	patternSelectivity = 0.1
+)
+
+// Cost is the estimated cost of running a query or a mutation.
+type Cost struct {
+	// UidsTouched is the number of UIDs and values read from the posting lists, or the number
+	// of edges written by a mutation.
+	UidsTouched uint64 `json:"uids_touched"`
+	// IndexLookups is the number of index keys read, or updated by a mutation.
+	IndexLookups uint64 `json:"index_lookups"`
+	// Bytes is the number of bytes of the posting lists read, or written by a mutation.
+	Bytes uint64 `json:"bytes"`
+	// Unbounded is set if the cost depends on the data in a way which can't be estimated, e.g.
+	// for a @recurse without a depth. The estimate is then for a single level.
+	Unbounded bool `json:"unbounded,omitempty"`
+}
+
+func (c *Cost) add(o Cost) {
+	c.UidsTouched += o.UidsTouched
+	c.IndexLookups += o.IndexLookups
+	c.Bytes += o.Bytes
+	c.Unbounded = c.Unbounded || o.Unbounded
+}
+
+// BlockCost is the estimated cost of a query block, or of a mutation.
+type BlockCost struct {
+	Name string `json:"name"`
+	Cost
+}
+
+// CostEstimate is the estimated cost of a request, along with the cost of each of its blocks.
+type CostEstimate struct {
+	Cost
+	Blocks []*BlockCost `json:"blocks"`
+}
+
+// cost accumulates the estimate of a block. The estimates are kept as floats until they're
+// reported, since they're products of averages.
+type cost struct {
+	uids, lookups, bytes float64
+	unbounded            bool
+}
+
+func (c *cost) toCost() Cost {
+	toUint := func(f float64) uint64 {
+		if f >= math.MaxUint64 {
+			return math.MaxUint64
+		}
+		return uint64(math.Ceil(f))
+	}
+	return Cost{
+		UidsTouched:  toUint(c.uids),
+		IndexLookups: toUint(c.lookups),
+		Bytes:        toUint(c.bytes),
+		Unbounded:    c.unbounded,
+	}
+}
+
+type costEstimator struct {
+	stats map[string]*pb.PlannerStats
+	// vars is the estimated number of UIDs of each UID variable.
+	vars map[string]float64
+}
+
+// CostPredicates returns the predicates whose planner statistics are needed to estimate the cost
+// of the query blocks and of the mutations.
+func CostPredicates(queries []*gql.GraphQuery, mutations []*gql.Mutation) []string {
+	preds := make(map[string]struct{})
+	var addFunc func(fn *gql.Function)
+	addFunc = func(fn *gql.Function) {
+		switch {
+		case fn == nil:
+		case fn.Name == "type":
+			preds["dgraph.type"] = struct{}{}
+		case fn.Attr != "":
+			preds[strings.TrimPrefix(fn.Attr, "~")] = struct{}{}
+		}
+	}
+	var addFilter func(ft *gql.FilterTree)
+	addFilter = func(ft *gql.FilterTree) {
+		if ft == nil {
+			return
+		}
+		addFunc(ft.Func)
+		for _, child := range ft.Child {
+			addFilter(child)
+		}
+	}
+	var addBlock func(gq *gql.GraphQuery)
+	addBlock = func(gq *gql.GraphQuery) {
+		if isCostedEdge(gq) {
+			preds[strings.TrimPrefix(gq.Attr, "~")] = struct{}{}
+		}
+		addFunc(gq.Func)
+		addFilter(gq.Filter)
+		for _, child := range gq.Children {
+			addBlock(child)
+		}
+	}
+	for _, gq := range queries {
+		addBlock(gq)
+	}
+	for _, gmu := range mutations {
+		for _, nquads := range [][]*api.NQuad{gmu.Set, gmu.Del} {
+			for _, nq := range nquads {
+				if nq.Predicate != x.Star {
+					preds[nq.Predicate] = struct{}{}
+				}
+			}
+		}
+	}
+
+	var out []string
+	for pred := range preds {
+		out = append(out, pred)
+	}
+	return out
+}
+
+// isCostedEdge returns whether reading the child block requires reading the posting lists of its
+// predicate.
+func isCostedEdge(gq *gql.GraphQuery) bool {
+	switch gq.Attr {
+	case "", "uid", "val", "math":
+		return false
+	}
+	return gq.Expand == "" && gq.MathExp == nil
+}
+
+// EstimateCost estimates the cost of running the query blocks and the mutations, without running
+// them. It's based on the planner statistics of their predicates, keyed by the predicates without
+// their namespace. The predicates without statistics are assumed to be empty.
+func EstimateCost(queries []*gql.GraphQuery, mutations []*gql.Mutation,
+	stats map[string]*pb.PlannerStats) *CostEstimate {
+	e := &costEstimator{stats: stats, vars: make(map[string]float64)}
+	// The blocks can use the variables of the blocks after them. The first pass estimates the
+	// size of the variables, and the second one the cost of the blocks.
+	for _, gq := range queries {
+		e.block(gq, &cost{})
+	}
+
+	est := &CostEstimate{Blocks: make([]*BlockCost, 0, len(queries)+len(mutations))}
+	for _, gq := range queries {
+		var c cost
+		e.block(gq, &c)
+		est.Blocks = append(est.Blocks, &BlockCost{Name: gq.Alias, Cost: c.toCost()})
+	}
+	for _, gmu := range mutations {
+		var c cost
+		e.mutation(gmu, &c)
+		est.Blocks = append(est.Blocks, &BlockCost{Name: "mutation", Cost: c.toCost()})
+	}
+	for _, bc := range est.Blocks {
+		est.add(bc.Cost)
+	}
+	return est
+}
+
+// mutation adds the cost of running the mutation to c. The cost of its upsert block is counted
+// with the query blocks.
+func (e *costEstimator) mutation(gmu *gql.Mutation, c *cost) {
+	for _, nquads := range [][]*api.NQuad{gmu.Set, gmu.Del} {
+		for _, nq := range nquads {
+			s := e.predStats(nq.Predicate)
+			edges := 1.0
+			switch {
+			case nq.Predicate == x.Star:
+				// Deleting all the predicates of a node, which are only known once its type
+				// is read.
+				c.unbounded = true
+			case nq.GetObjectValue().GetDefaultVal() == x.Star:
+				// Deleting all the values of the predicate of a node.
+				edges = s.PostingsPerKey
+			}
+			c.uids += edges
+			c.lookups += edges * float64(s.Indexes)
+			c.bytes += edges * float64(nq.Size())
+		}
+	}
+}
+
+func (e *costEstimator) predStats(attr string) *pb.PlannerStats {
+	if s, ok := e.stats[strings.TrimPrefix(attr, "~")]; ok && s != nil {
+		return s
+	}
+	return &pb.PlannerStats{}
+}
+
+func (e *costEstimator) varUids(vars []gql.VarContext) float64 {
+	var n float64
+	for _, v := range vars {
+		if v.Typ == gql.UidVar || v.Typ == gql.AnyVar {
+			n += e.vars[v.Name]
+		}
+	}
+	return n
+}
+
+// first applies the pagination of the block to the estimated number of UIDs.
+func first(gq *gql.GraphQuery, n float64) float64 {
+	f, err := strconv.Atoi(gq.Args["first"])
+	if err != nil || f == 0 {
+		return n
+	}
+	return math.Min(n, math.Abs(float64(f)))
+}
+
+// block adds the cost of the query block to c.
+func (e *costEstimator) block(gq *gql.GraphQuery, c *cost) {
+	n := float64(len(gq.UID))
+	switch {
+	case gq.Func == nil:
+	case gq.Func.Name == "has" && gq.Filter == nil && len(gq.Order) == 0 && gq.Args["first"] != "":
+		// has() stops reading the keys once the page is full.
+		var fc cost
+		keys := e.function(gq.Func, &fc)
+		n = first(gq, keys)
+		if keys > 0 {
+			c.uids += fc.uids * n / keys
+			c.bytes += fc.bytes * n / keys
+		}
+	default:
+		n += e.function(gq.Func, c)
+	}
+	n *= e.filter(gq.Filter, n, c)
+	n = first(gq, n)
+	if gq.Var != "" {
+		e.vars[gq.Var] = n
+	}
+
+	switch {
+	case gq.Recurse || gq.Alias == "shortest":
+		depth := gq.RecurseArgs.Depth
+		if d, err := strconv.ParseUint(gq.Args["depth"], 10, 64); err == nil {
+			depth = d
+		}
+		if depth == 0 {
+			c.unbounded = true
+			depth = 1
+		}
+		for i := uint64(0); i < depth && n > 0; i++ {
+			n = e.children(gq.Children, n, c, false)
+		}
+	default:
+		e.children(gq.Children, n, c, true)
+	}
+}
+
+// children adds the cost of reading the child blocks for n UIDs to c, and returns the number of
+// UIDs and values reached.
+func (e *costEstimator) children(children []*gql.GraphQuery, n float64, c *cost,
+	nested bool) float64 {
+	var reached float64
+	for _, child := range children {
+		if !isCostedEdge(child) {
+			if child.Expand != "" {
+				// The predicates of expand() are only known once the types are read.
+				c.unbounded = true
+			}
+			continue
+		}
+		s := e.predStats(child.Attr)
+		childN := n * s.PostingsPerKey
+		c.uids += childN
+		c.bytes += n * s.BytesPerKey
+
+		childN *= e.filter(child.Filter, childN, c)
+		childN = math.Min(childN, n*first(child, childN/math.Max(n, 1)))
+		if child.Var != "" {
+			e.vars[child.Var] = childN
+		}
+		reached += childN
+		if nested && len(child.Children) > 0 {
+			e.children(child.Children, childN, c, true)
+		}
+	}
+	return reached
+}
+
+// filter adds the cost of applying the filter to n UIDs to c, and returns the estimated fraction
+// of them which pass it.
+func (e *costEstimator) filter(ft *gql.FilterTree, n float64, c *cost) float64 {
+	if ft == nil || n == 0 {
+		return 1
+	}
+	switch ft.Op {
+	case "and":
+		sel := 1.0
+		for _, child := range ft.Child {
+			sel *= e.filter(child, n, c)
+		}
+		return sel
+	case "or":
+		var sel float64
+		for _, child := range ft.Child {
+			sel += e.filter(child, n, c)
+		}
+		return math.Min(sel, 1)
+	case "not":
+		if len(ft.Child) == 0 {
+			return 1
+		}
+		return 1 - e.filter(ft.Child[0], n, c)
+	}
+	if ft.Func == nil {
+		return 1
+	}
+
+	// The matches of the function are found the same way as at the root, then intersected
+	// with the UIDs, unless reading the values of the UIDs is cheaper.
+	var fc cost
+	matches := e.function(ft.Func, &fc)
+	if ft.Func.Name == "uid" {
+		c.uids += n
+		return math.Min(matches/n, 1)
+	}
+	s := e.predStats(funcAttr(ft.Func))
+	if scan := n * s.BytesPerKey; scan < fc.bytes || s.Keys == 0 {
+		c.uids += n
+		c.bytes += scan
+	} else {
+		c.uids += fc.uids
+		c.lookups += fc.lookups
+		c.bytes += fc.bytes
+	}
+	if s.Keys == 0 {
+		return 0
+	}
+	return math.Min(matches/float64(s.Keys), 1)
+}
+
+func funcAttr(fn *gql.Function) string {
+	if fn.Name == "type" {
+		return "dgraph.type"
+	}
+	return fn.Attr
+}
+
+// tokens returns the number of index keys looked up by the function.
+func tokens(fn *gql.Function) float64 {
+	var n int
+	for _, arg := range fn.Args {
+		switch fn.Name {
+		case "anyofterms", "allofterms", "anyoftext", "alloftext":
+			n += len(strings.Fields(arg.Value))
+		default:
+			n++
+		}
+	}
+	return math.Max(float64(n), 1)
+}
+
+// function adds the cost of finding the UIDs matching the function to c, and returns how many
+// UIDs are estimated to match.
+func (e *costEstimator) function(fn *gql.Function, c *cost) float64 {
+	s := e.predStats(funcAttr(fn))
+	keys := float64(s.Keys)
+	scan := func(sel float64) float64 {
+		c.uids += keys * s.PostingsPerKey
+		c.bytes += keys * s.BytesPerKey
+		return keys * sel
+	}
+	lookup := func(lookups, uids float64) float64 {
+		uids = math.Min(uids, keys)
+		c.lookups += lookups
+		c.uids += uids
+		c.bytes += uids * uidBytes
+		return uids
+	}
+
+	name := fn.Name
+	if fn.IsCount || fn.IsValueVar || fn.IsLenVar {
+		name = ""
+	}
+	switch name {
+	case "uid":
+		// The UIDs are already known, nothing is read.
+		return float64(len(fn.UID)) + e.varUids(fn.NeedsVar)
+	case "has":
+		c.uids += keys
+		c.bytes += keys * uidBytes
+		return keys
+	case "type", "eq", "anyofterms", "allofterms", "anyoftext", "alloftext":
+		if s.IndexKeys == 0 {
+			return scan(math.Min(tokens(fn)/math.Max(keys, 1), 1))
+		}
+		t := tokens(fn)
+		return lookup(t, t*s.UidsPerIndexKey)
+	case "le", "lt", "ge", "gt", "between":
+		if s.IndexKeys == 0 {
+			return scan(rangeSelectivity)
+		}
+		return lookup(float64(s.IndexKeys)*rangeSelectivity, keys*rangeSelectivity)
+	case "regexp", "match":
+		if s.IndexKeys == 0 {
+			return scan(patternSelectivity)
+		}
+		// The candidates found with the trigrams are verified by reading their values.
+		candidates := keys * patternSelectivity
+		c.lookups += float64(s.IndexKeys) * patternSelectivity
+		c.uids += candidates
+		c.bytes += candidates * s.BytesPerKey
+		return candidates
+	default:
+		return scan(1)
+	}
+}
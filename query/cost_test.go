@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/gql"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateQueryCost(t *testing.T) {
+	stats := map[string]*pb.PlannerStats{
+		"name": {Keys: 1000, PostingsPerKey: 1, BytesPerKey: 20, IndexKeys: 500,
+			UidsPerIndexKey: 2, Indexes: 1},
+		"friend": {Keys: 1000, PostingsPerKey: 10, BytesPerKey: 100},
+	}
+	parse := func(q string) []*gql.GraphQuery {
+		res, err := gql.Parse(gql.Request{Str: q})
+		require.NoError(t, err)
+		return res.Query
+	}
+
+	// Two index keys are looked up, matching 4 UIDs, whose names and friends are read.
+	est := EstimateCost(parse(`{
+		q(func: eq(name, "a", "b")) { name friend { name } }
+	}`), nil, stats)
+	require.Equal(t, Cost{UidsTouched: 4 + 4 + 40 + 40, IndexLookups: 2,
+		Bytes: 4*8 + 4*20 + 4*100 + 40*20}, est.Cost)
+	require.Len(t, est.Blocks, 1)
+	require.Equal(t, "q", est.Blocks[0].Name)
+
+	// Pagination limits the UIDs whose predicates are read, and the variables carry the
+	// estimates across the blocks.
+	est = EstimateCost(parse(`{
+		q(func: uid(f), first: 2) { name }
+		var(func: has(friend)) { f as friend }
+	}`), nil, stats)
+	require.Equal(t, Cost{UidsTouched: 1000 + 10000, Bytes: 1000*8 + 1000*100},
+		est.Blocks[1].Cost)
+	require.Equal(t, Cost{UidsTouched: 2, Bytes: 2 * 20}, est.Blocks[0].Cost)
+
+	// has() stops once the page is full.
+	est = EstimateCost(parse(`{
+		q(func: has(friend), first: 10) { uid }
+	}`), nil, stats)
+	require.Equal(t, Cost{UidsTouched: 10, Bytes: 10 * 8}, est.Cost)
+
+	// A @recurse without depth can't be estimated.
+	est = EstimateCost(parse(`{
+		q(func: uid(0x1)) @recurse { friend }
+	}`), nil, stats)
+	require.True(t, est.Unbounded)
+	require.Equal(t, uint64(10), est.UidsTouched)
+}
+
+func TestEstimateMutationCost(t *testing.T) {
+	stats := map[string]*pb.PlannerStats{
+		"name": {Keys: 1000, PostingsPerKey: 1, Indexes: 2},
+	}
+	nq := &api.NQuad{Subject: "_:a", Predicate: "name",
+		ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: "a"}}}
+	est := EstimateCost(nil, []*gql.Mutation{{Set: []*api.NQuad{nq, nq}}}, stats)
+	require.Len(t, est.Blocks, 1)
+	require.Equal(t, "mutation", est.Blocks[0].Name)
+	require.Equal(t, Cost{UidsTouched: 2, IndexLookups: 4, Bytes: 2 * uint64(nq.Size())},
+		est.Cost)
+}
@@ -135,3 +135,34 @@ func TestEncode(t *testing.T) {
 		`, enc.buf.String())
 	})
 }
+
+func TestToJsonWithLimit(t *testing.T) {
+	uids := []uint64{1, 2, 3}
+	vals := make([]*pb.ValueList, 0, len(uids))
+	for range uids {
+		vals = append(vals, &pb.ValueList{Values: []*pb.TaskValue{task.FromString("ABCDEFGH")}})
+	}
+	sg := &SubGraph{
+		Params:    params{Alias: "query"},
+		SrcUIDs:   &pb.List{Uids: uids},
+		DestUIDs:  &pb.List{Uids: uids},
+		uidMatrix: []*pb.List{{Uids: uids}},
+		Children: []*SubGraph{{
+			Attr:        "val",
+			SrcUIDs:     &pb.List{Uids: uids},
+			uidMatrix:   []*pb.List{{}, {}, {}},
+			valueMatrix: vals,
+		}},
+	}
+
+	buf, cursor, err := ToJsonWithLimit(context.Background(), &Latency{}, []*SubGraph{sg}, nil, 0)
+	require.NoError(t, err)
+	require.Empty(t, cursor)
+	require.Equal(t, `{"query":[{"val":"ABCDEFGH"},{"val":"ABCDEFGH"},{"val":"ABCDEFGH"}]}`,
+		string(buf))
+
+	buf, cursor, err = ToJsonWithLimit(context.Background(), &Latency{}, []*SubGraph{sg}, nil, 1)
+	require.NoError(t, err)
+	require.Equal(t, "query:0x1", cursor)
+	require.Equal(t, `{"query":[{"val":"ABCDEFGH"}]}`, string(buf))
+}
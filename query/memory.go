@@ -0,0 +1,159 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+)
+
+// ErrQueryMemoryLimit is returned for queries which use more memory than allowed by the
+// query_memory_mb flag.
+var ErrQueryMemoryLimit = errors.New("Query was cancelled because it used more memory than " +
+	"allowed per query")
+
+// RunningQuery is a query running on this node along with the memory it has used so far.
+type RunningQuery struct {
+	Id        uint64
+	Namespace uint64
+	Query     string
+	StartedAt time.Time
+	// TaskBytes is the size of the posting lists materialized for the query, and EncodedBytes
+	// the size of the result buffers built while encoding the response.
+	TaskBytes    int64
+	EncodedBytes int64
+}
+
+// MemoryBytes returns the total memory used by the query.
+func (rq RunningQuery) MemoryBytes() int64 {
+	return rq.TaskBytes + rq.EncodedBytes
+}
+
+type queryMemory struct {
+	id        uint64
+	namespace uint64
+	query     string
+	startedAt time.Time
+	limit     int64
+	cancel    context.CancelFunc
+
+	taskBytes    int64
+	encodedBytes int64
+	exceeded     int32
+}
+
+type memoryKey struct{}
+
+var (
+	lastQueryId    uint64
+	runningQueries sync.Map // map from id to *queryMemory
+)
+
+// TrackMemory registers a query as running and returns a context which accounts for the memory
+// used by it. The returned context is cancelled if the query goes above the per query memory
+// limit. done must be called once the query has finished.
+func TrackMemory(ctx context.Context, namespace uint64, query string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	qm := &queryMemory{
+		id:        atomic.AddUint64(&lastQueryId, 1),
+		namespace: namespace,
+		query:     query,
+		startedAt: time.Now(),
+		limit:     x.Config.QueryMemoryLimit,
+		cancel:    cancel,
+	}
+	runningQueries.Store(qm.id, qm)
+	return context.WithValue(ctx, memoryKey{}, qm), func() {
+		runningQueries.Delete(qm.id)
+		cancel()
+	}
+}
+
+func memoryFromContext(ctx context.Context) *queryMemory {
+	qm, _ := ctx.Value(memoryKey{}).(*queryMemory)
+	return qm
+}
+
+// addTaskBytes accounts for n bytes of posting lists materialized for the query.
+func (qm *queryMemory) addTaskBytes(n int64) error {
+	if qm == nil {
+		return nil
+	}
+	atomic.AddInt64(&qm.taskBytes, n)
+	return qm.check()
+}
+
+// setEncodedBytes sets the size of the result buffers built for the query so far.
+func (qm *queryMemory) setEncodedBytes(n int64) error {
+	if qm == nil {
+		return nil
+	}
+	atomic.StoreInt64(&qm.encodedBytes, n)
+	return qm.check()
+}
+
+func (qm *queryMemory) check() error {
+	if qm.limit <= 0 {
+		return nil
+	}
+	used := atomic.LoadInt64(&qm.taskBytes) + atomic.LoadInt64(&qm.encodedBytes)
+	if used <= qm.limit {
+		return nil
+	}
+	if atomic.CompareAndSwapInt32(&qm.exceeded, 0, 1) {
+		// Cancel the query so that the other goroutines processing it stop as well.
+		qm.cancel()
+	}
+	return errors.Wrapf(ErrQueryMemoryLimit, "query used %s of memory with a limit of %s",
+		humanize.IBytes(uint64(used)), humanize.IBytes(uint64(qm.limit)))
+}
+
+// MemoryLimitError returns the error for the query tracked in ctx if it went above the per query
+// memory limit, and nil otherwise.
+func MemoryLimitError(ctx context.Context) error {
+	qm := memoryFromContext(ctx)
+	if qm == nil || atomic.LoadInt32(&qm.exceeded) == 0 {
+		return nil
+	}
+	return qm.check()
+}
+
+// GetRunningQueries returns the queries running on this node, with the longest running first.
+func GetRunningQueries() []RunningQuery {
+	var res []RunningQuery
+	runningQueries.Range(func(_, v interface{}) bool {
+		qm := v.(*queryMemory)
+		res = append(res, RunningQuery{
+			Id:           qm.id,
+			Namespace:    qm.namespace,
+			Query:        qm.query,
+			StartedAt:    qm.startedAt,
+			TaskBytes:    atomic.LoadInt64(&qm.taskBytes),
+			EncodedBytes: atomic.LoadInt64(&qm.encodedBytes),
+		})
+		return true
+	})
+	sort.Slice(res, func(i, j int) bool { return res[i].StartedAt.Before(res[j].StartedAt) })
+	return res
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryMemoryLimit(t *testing.T) {
+	defer func(limit int64) { x.Config.QueryMemoryLimit = limit }(x.Config.QueryMemoryLimit)
+	x.Config.QueryMemoryLimit = 100
+
+	ctx, done := TrackMemory(context.Background(), x.GalaxyNamespace, "{ q(func: uid(1)) { uid } }")
+	qm := memoryFromContext(ctx)
+	require.NotNil(t, qm)
+	require.Len(t, GetRunningQueries(), 1)
+
+	require.NoError(t, qm.addTaskBytes(60))
+	require.NoError(t, qm.setEncodedBytes(40))
+	require.NoError(t, MemoryLimitError(ctx))
+	require.Equal(t, int64(100), GetRunningQueries()[0].MemoryBytes())
+
+	err := qm.setEncodedBytes(41)
+	require.Equal(t, ErrQueryMemoryLimit, errors.Cause(err))
+	require.Equal(t, ErrQueryMemoryLimit, errors.Cause(MemoryLimitError(ctx)))
+	require.Equal(t, context.Canceled, ctx.Err())
+
+	done()
+	require.Len(t, GetRunningQueries(), 0)
+
+	// Queries which aren't tracked are never limited.
+	require.NoError(t, memoryFromContext(context.Background()).addTaskBytes(1<<30))
+}
@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -50,6 +51,18 @@ import (
 // ToJson converts the list of subgraph into a JSON response by calling toFastJSON.
 func ToJson(ctx context.Context, l *Latency, sgl []*SubGraph, field gqlSchema.Field) ([]byte,
 	error) {
+	data, _, err := ToJsonWithLimit(ctx, l, sgl, field, 0)
+	return data, err
+}
+
+// ToJsonWithLimit is like ToJson, but stops encoding a DQL response at a root node boundary once
+// its estimated size goes above limit bytes. For truncated responses it returns a cursor of the
+// form "<block>:<uid>", meaning that the block can be continued by running it again with
+// after: <uid>. The blocks following it are left out of the response. A limit of zero means that
+// the response is never truncated. Blocks whose nodes aren't in uid order, like the ones ordered
+// by a predicate, aren't truncated either, as after: couldn't continue them.
+func ToJsonWithLimit(ctx context.Context, l *Latency, sgl []*SubGraph, field gqlSchema.Field,
+	limit uint64) ([]byte, string, error) {
 	sgr := &SubGraph{}
 	for _, sg := range sgl {
 		if sg.Params.Alias == "var" || sg.Params.Alias == "shortest" {
@@ -60,16 +73,20 @@ func ToJson(ctx context.Context, l *Latency, sgl []*SubGraph, field gqlSchema.Fi
 		}
 		sgr.Children = append(sgr.Children, sg)
 	}
-	data, err := sgr.toFastJSON(ctx, l, field)
+	// Responses in GraphQL form aren't truncated, as a partial result isn't valid for them.
+	if field != nil {
+		limit = 0
+	}
+	data, cursor, err := sgr.toFastJSON(ctx, l, field, limit)
 
 	// don't log or wrap GraphQL errors
 	if x.IsGqlErrorList(err) {
-		return data, err
+		return data, cursor, err
 	}
 	if err != nil {
 		glog.Errorf("while running ToJson: %v\n", err)
 	}
-	return data, errors.Wrapf(err, "while running ToJson")
+	return data, cursor, errors.Wrapf(err, "while running ToJson")
 }
 
 // We are capping maxEncoded size to 4GB, as grpc encoding fails
@@ -104,6 +121,15 @@ type encoder struct {
 
 	// buf is the buffer which stores the JSON encoded response
 	buf *bytes.Buffer
+
+	// mem accounts the size of the response against the per query memory limit. It is nil for
+	// queries which aren't tracked.
+	mem *queryMemory
+
+	// limit is the size after which the response is truncated at a root node boundary. cursor
+	// is set to where the response can be continued from once that happens.
+	limit  uint64
+	cursor string
 }
 
 type node struct {
@@ -278,11 +304,12 @@ func (enc *encoder) setScalarVal(fj fastJsonNode, sv []byte) error {
 
 	// Also increase curSize.
 	enc.curSize += uint64(len(sv))
-	if size := uint64(enc.alloc.Size()) + enc.curSize; size > maxEncodedSize {
+	size := uint64(enc.alloc.Size()) + enc.curSize
+	if size > maxEncodedSize {
 		return fmt.Errorf("estimated response size: %d is bigger than threshold: %d",
 			size, maxEncodedSize)
 	}
-	return nil
+	return enc.mem.setEncodedBytes(int64(size))
 }
 
 func (enc *encoder) setList(fj fastJsonNode, list bool) {
@@ -998,6 +1025,19 @@ func (sg *SubGraph) handleCountUIDNodes(enc *encoder, n fastJsonNode, count int)
 	return addedNewChild, nil
 }
 
+// addTotal adds the number of results of the root query block before pagination, as asked for
+// with the "total" parameter, e.g. {"total": 1200}.
+func (sg *SubGraph) addTotal(enc *encoder, n fastJsonNode) error {
+	c := types.ValueForType(types.IntID)
+	c.Value = int64(sg.total)
+	fj := enc.newNode(enc.idForAttr(sg.fieldName()))
+	if err := enc.AddValue(fj, enc.idForAttr("total"), c); err != nil {
+		return err
+	}
+	enc.AddListChild(n, fj)
+	return nil
+}
+
 func processNodeUids(fj fastJsonNode, enc *encoder, sg *SubGraph) error {
 	if sg.Params.IsEmpty {
 		return sg.addAggregations(enc, fj)
@@ -1015,6 +1055,12 @@ func processNodeUids(fj fastJsonNode, enc *encoder, sg *SubGraph) error {
 	if err != nil {
 		return err
 	}
+	if sg.Params.Total != "" {
+		if err := sg.addTotal(enc, fj); err != nil {
+			return err
+		}
+		hasChild = true
+	}
 	if sg.Params.IsGroupBy {
 		if len(sg.GroupbyRes) == 0 {
 			return errors.Errorf("Expected GroupbyRes to have length > 0.")
@@ -1023,6 +1069,10 @@ func processNodeUids(fj fastJsonNode, enc *encoder, sg *SubGraph) error {
 	}
 
 	lenList := len(sg.uidMatrix[0].Uids)
+	truncate := enc.limit > 0 && len(sg.Params.Order) == 0 && sg.Params.Count >= 0 &&
+		sort.SliceIsSorted(sg.uidMatrix[0].Uids, func(i, j int) bool {
+			return sg.uidMatrix[0].Uids[i] < sg.uidMatrix[0].Uids[j]
+		})
 	for i := 0; i < lenList; i++ {
 		uid := sg.uidMatrix[0].Uids[i]
 		if algo.IndexOf(sg.DestUIDs, uid) < 0 {
@@ -1044,8 +1094,15 @@ func processNodeUids(fj fastJsonNode, enc *encoder, sg *SubGraph) error {
 		}
 
 		hasChild = true
+		if truncate && i < lenList-1 && uint64(enc.alloc.Size())+enc.curSize > enc.limit {
+			// This is the last root node which fits in the response. Break after adding it.
+			enc.cursor = fmt.Sprintf("%s:%#x", sg.Params.Alias, uid)
+		}
 		if !sg.Params.Normalize {
 			enc.AddListChild(fj, n1)
+			if enc.cursor != "" {
+				break
+			}
 			continue
 		}
 
@@ -1066,6 +1123,9 @@ func processNodeUids(fj fastJsonNode, enc *encoder, sg *SubGraph) error {
 			enc.addChildren(node, c)
 			enc.AddListChild(fj, node)
 		}
+		if enc.cursor != "" {
+			break
+		}
 	}
 
 	if !hasChild {
@@ -1080,16 +1140,33 @@ type Extensions struct {
 	Latency *api.Latency    `json:"server_latency,omitempty"`
 	Txn     *api.TxnContext `json:"txn,omitempty"`
 	Metrics *api.Metrics    `json:"metrics,omitempty"`
+	Partial bool            `json:"partial,omitempty"`
+	Cursor  string          `json:"cursor,omitempty"`
+	// Staleness is how many timestamps behind the start ts of a best effort query the oldest
+	// part of the response was served at by a lagging replica.
+	Staleness uint64 `json:"staleness,omitempty"`
 }
 
-func (sg *SubGraph) toFastJSON(ctx context.Context, l *Latency, field gqlSchema.Field) ([]byte,
-	error) {
+const (
+	// PartialKey and CursorKey are the keys in the headers of a response truncated because of
+	// the max_response_bytes limit. See ToJsonWithLimit for the format of the cursor.
+	PartialKey = "partial"
+	CursorKey  = "cursor"
+	// StalenessKey is the key in the headers of a best effort response which was served by
+	// replicas lagging behind its start ts. See Extensions.Staleness.
+	StalenessKey = "staleness"
+)
+
+func (sg *SubGraph) toFastJSON(ctx context.Context, l *Latency, field gqlSchema.Field,
+	limit uint64) ([]byte, string, error) {
 	encodingStart := time.Now()
 	defer func() {
 		l.Json = time.Since(encodingStart)
 	}()
 
 	enc := newEncoder()
+	enc.mem = memoryFromContext(ctx)
+	enc.limit = limit
 	defer func() {
 		// Put encoder's arena back to arena pool.
 		arenaPool.Put(enc.arena)
@@ -1099,9 +1176,13 @@ func (sg *SubGraph) toFastJSON(ctx context.Context, l *Latency, field gqlSchema.
 	var err error
 	n := enc.newNode(enc.idForAttr("_root_"))
 	for _, sg := range sg.Children {
+		if enc.cursor != "" {
+			// The response has been truncated, so leave out the remaining blocks.
+			break
+		}
 		err = processNodeUids(n, enc, sg)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 	}
 	enc.fixOrder(n)
@@ -1117,16 +1198,19 @@ func (sg *SubGraph) toFastJSON(ctx context.Context, l *Latency, field gqlSchema.
 		// with the data. So, don't return here if we get an error.
 		err = sg.toGraphqlJSON(newGraphQLEncoder(ctx, enc), n, field)
 	} else if err = sg.toDqlJSON(enc, n); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// Return error if encoded buffer size exceeds than a threshold size.
 	if uint64(enc.buf.Len()) > maxEncodedSize {
-		return nil, fmt.Errorf("while writing to buffer. Encoded response size: %d"+
+		return nil, "", fmt.Errorf("while writing to buffer. Encoded response size: %d"+
 			" is bigger than threshold: %d", enc.buf.Len(), maxEncodedSize)
 	}
+	if merr := enc.mem.setEncodedBytes(int64(enc.alloc.Size() + enc.buf.Len())); merr != nil {
+		return nil, "", merr
+	}
 
-	return enc.buf.Bytes(), err
+	return enc.buf.Bytes(), enc.cursor, err
 }
 
 func (sg *SubGraph) toDqlJSON(enc *encoder, n fastJsonNode) error {
@@ -1174,6 +1258,9 @@ func (sg *SubGraph) toGraphqlJSON(genc *graphQLEncoder, n fastJsonNode, f gqlSch
 
 func (sg *SubGraph) fieldName() string {
 	fieldName := sg.Attr
+	if sg.Params.Lineage {
+		fieldName = fmt.Sprintf("lineage(%s)", sg.Attr)
+	}
 	if sg.Params.Alias != "" {
 		fieldName = sg.Params.Alias
 	}
@@ -1231,6 +1318,42 @@ func (sg *SubGraph) addCheckPwd(enc *encoder, vals []*pb.TaskValue, dst fastJson
 	return enc.AddValue(dst, enc.idForAttr(fieldName), c)
 }
 
+func (sg *SubGraph) addRelevance(enc *encoder, vals []*pb.TaskValue, dst fastJsonNode) error {
+	// The uids without a value have no score.
+	if len(vals) == 0 {
+		return nil
+	}
+	score, err := convertWithBestEffort(vals[0], sg.Attr)
+	if err != nil {
+		return err
+	}
+
+	fieldName := sg.Params.Alias
+	if fieldName == "" {
+		fieldName = fmt.Sprintf("relevance(%s)", sg.Attr)
+	}
+	return enc.AddValue(dst, enc.idForAttr(fieldName), score)
+}
+
+func (sg *SubGraph) addHighlight(enc *encoder, vals []*pb.TaskValue, dst fastJsonNode) error {
+	fieldName := sg.Params.Alias
+	if fieldName == "" {
+		fieldName = fmt.Sprintf("highlight(%s)", sg.Attr)
+	}
+	attr := enc.idForAttr(fieldName)
+	// The values of a list predicate are highlighted one by one.
+	for _, v := range vals {
+		sv, err := convertWithBestEffort(v, sg.Attr)
+		if err != nil {
+			return err
+		}
+		if err := enc.AddListValue(dst, attr, sv, len(vals) > 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func alreadySeen(parentIds []uint64, uid uint64) bool {
 	for _, id := range parentIds {
 		if id == uid {
@@ -1313,6 +1436,16 @@ func (sg *SubGraph) preTraverse(enc *encoder, uid uint64, dst fastJsonNode) erro
 				return err
 			}
 
+		case pc.SrcFunc != nil && pc.SrcFunc.Name == "relevance":
+			if err := pc.addRelevance(enc, pc.valueMatrix[idx].Values, dst); err != nil {
+				return err
+			}
+
+		case pc.SrcFunc != nil && pc.SrcFunc.Name == "highlight":
+			if err := pc.addHighlight(enc, pc.valueMatrix[idx].Values, dst); err != nil {
+				return err
+			}
+
 		case idx < len(pc.uidMatrix) && len(pc.uidMatrix[idx].Uids) > 0:
 			var fcsList []*pb.Facets
 			if pc.Params.Facet != nil {
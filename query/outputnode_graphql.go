@@ -623,7 +623,8 @@ func (genc *graphQLEncoder) processCustomFields(field gqlSchema.Field, n fastJso
 		// keep collecting errors arising from custom field resolution until channel is closed
 		go func() {
 			for errs := range genc.errCh {
-				genc.errs = append(genc.errs, errs...)
+				genc.errs = append(genc.errs, gqlSchema.AsGQLErrors(
+					gqlSchema.WithErrorCode(errs, gqlSchema.CodeExternalRequestFailed))...)
 			}
 			wg.Done()
 		}()
@@ -855,8 +856,8 @@ func (genc *graphQLEncoder) resolveCustomField(childField gqlSchema.Field,
 
 				// Step-3 & 4: Make the request to external HTTP endpoint using the URL and
 				// body. Then, Decode the HTTP response.
-				response, errs, hardErrs := fconf.MakeAndDecodeHTTPRequest(nil, url, body,
-					childField)
+				response, errs, hardErrs := fconf.MakeAndDecodeHTTPRequest(genc.ctx, nil, url,
+					body, childField)
 				if hardErrs != nil {
 					genc.errCh <- hardErrs
 					return
@@ -913,7 +914,8 @@ func (genc *graphQLEncoder) resolveCustomField(childField gqlSchema.Field,
 
 		// Step-3 & 4: Make the request to external HTTP endpoint using the URL and
 		// body. Then, Decode the HTTP response.
-		response, errs, hardErrs := fconf.MakeAndDecodeHTTPRequest(nil, fconf.URL, body, childField)
+		response, errs, hardErrs := fconf.MakeAndDecodeHTTPRequest(genc.ctx, nil, fconf.URL, body,
+			childField)
 		if hardErrs != nil {
 			genc.errCh <- hardErrs
 			return
@@ -226,6 +226,10 @@ func validateSubGraphForRDF(sg *SubGraph) error {
 	if sg.SrcFunc != nil && sg.SrcFunc.Name == "checkpwd" {
 		return errors.New("chkpwd function is not supported in the rdf output format")
 	}
+	if sg.SrcFunc != nil && (sg.SrcFunc.Name == "relevance" || sg.SrcFunc.Name == "highlight") {
+		return errors.Errorf("%s function is not supported in the rdf output format",
+			sg.SrcFunc.Name)
+	}
 	if sg.Params.Facet != nil && !sg.Params.ExpandAll {
 		return errors.New("facets are not supported in the rdf output format")
 	}
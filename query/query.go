@@ -104,6 +104,13 @@ type Latency struct {
 	Json            time.Duration `json:"json_conversion"`
 }
 
+const (
+	// totalExact counts all the results before pagination.
+	totalExact = "exact"
+	// totalApprox lets has() at root stop after the asked page and estimate the rest.
+	totalApprox = "approx"
+)
+
 // params contains the list of parameters required to execute a SubGraph.
 type params struct {
 	// Alias is the value of the predicate's alias, if any.
@@ -114,6 +121,9 @@ type params struct {
 	Offset int
 	// AfterUID is the value of the "after" parameter.
 	AfterUID uint64
+	// Total is the value of the "total" parameter at root. If set, the number of results
+	// before pagination is returned along with them, either exactly or as an estimate.
+	Total string
 	// DoCount is true if the count of the predicate is requested instead of its value.
 	DoCount bool
 	// GetUid is true if the uid should be returned. Used for debug requests.
@@ -186,6 +196,9 @@ type params struct {
 	IgnoreResult bool
 	// Expand holds the argument passed to the expand function.
 	Expand string
+	// Lineage is true for lineage(pred). The dgraph.lineage facets of the edges of pred are read
+	// and replaced by the uids of the lineage nodes they point to.
+	Lineage bool
 
 	// IsGroupBy is true if @groupby is specified.
 	IsGroupBy bool // True if @groupby is specified.
@@ -252,6 +265,7 @@ type Function struct {
 type SubGraph struct {
 	ReadTs      uint64
 	Cache       int
+	MaxLag      uint64
 	Attr        string
 	UnknownAttr bool
 	// read only parameters which are populated before the execution of the query and are used to
@@ -261,6 +275,9 @@ type SubGraph struct {
 	// count stores the count of an edge (predicate). There would be one value corresponding to each
 	// uid in SrcUIDs.
 	counts []uint32
+	// total is the number of results of a root query block before pagination. It's only set if
+	// asked for with the "total" parameter.
+	total int
 	// valueMatrix is a slice of ValueList. If this SubGraph is for a scalar predicate type, then
 	// there would be one list for each uid in SrcUIDs storing the value of the predicate.
 	// The individual elements of the slice are a ValueList because we support scalar predicates
@@ -513,6 +530,9 @@ func uniqueKey(gchild *gql.GraphQuery) string {
 	if gchild.IsCount { // ignore count subgraphs..
 		key += "count"
 	}
+	if gchild.Lineage {
+		key = fmt.Sprintf("lineage(%s)", key)
+	}
 	if len(gchild.Langs) > 0 {
 		key += fmt.Sprintf("%v", gchild.Langs)
 	}
@@ -567,8 +587,12 @@ func treeCopy(gq *gql.GraphQuery, sg *SubGraph) error {
 			GroupbyAttrs: gchild.GroupbyAttrs,
 			IsGroupBy:    gchild.IsGroupby,
 			IsInternal:   gchild.IsInternal,
+			Lineage:      gchild.Lineage,
 			Cascade:      &CascadeArgs{},
 		}
+		if gchild.Lineage {
+			args.Facet = &pb.FacetParams{Param: []*pb.FacetParam{{Key: x.LineageFacet}}}
+		}
 
 		// Inherit from the parent.
 		if len(sg.Params.Cascade.Fields) > 0 {
@@ -601,10 +625,6 @@ func treeCopy(gq *gql.GraphQuery, sg *SubGraph) error {
 			return err
 		}
 
-		if len(args.Order) != 0 && len(args.FacetsOrder) != 0 {
-			return errors.Errorf("Cannot specify order at both args and facets")
-		}
-
 		dst := &SubGraph{
 			Attr:   gchild.Attr,
 			Params: args,
@@ -617,8 +637,8 @@ func treeCopy(gq *gql.GraphQuery, sg *SubGraph) error {
 			dst.MathExp = mathExp
 		}
 
-		if gchild.Func != nil &&
-			(gchild.Func.IsAggregator() || gchild.Func.IsPasswordVerifier()) {
+		if gchild.Func != nil && (gchild.Func.IsAggregator() || gchild.Func.IsPasswordVerifier() ||
+			gchild.Func.IsRelevance() || gchild.Func.IsHighlight()) {
 			if len(gchild.Children) != 0 {
 				return errors.Errorf("Node with %q cant have child attr", gchild.Func.Name)
 			}
@@ -739,6 +759,13 @@ func (args *params) fill(gq *gql.GraphQuery) error {
 		}
 		args.Count = int(first)
 	}
+	if v, ok := gq.Args["total"]; ok {
+		if v != totalExact && v != totalApprox {
+			return errors.Errorf("Invalid value for total: %s. It must be %s or %s",
+				v, totalExact, totalApprox)
+		}
+		args.Total = v
+	}
 	return nil
 }
 
@@ -939,6 +966,7 @@ func createTaskQuery(ctx context.Context, sg *SubGraph) (*pb.Query, error) {
 	out := &pb.Query{
 		ReadTs:       sg.ReadTs,
 		Cache:        int32(sg.Cache),
+		MaxLag:       sg.MaxLag,
 		Attr:         x.NamespaceAttr(namespace, attr),
 		Langs:        sg.Params.Langs,
 		Reverse:      reverse,
@@ -949,6 +977,8 @@ func createTaskQuery(ctx context.Context, sg *SubGraph) (*pb.Query, error) {
 		FacetsFilter: sg.facetsFilter,
 		ExpandAll:    sg.Params.ExpandAll,
 		First:        first,
+		// The estimate is only needed if the results were limited to the first ones.
+		EstimateTotal: sg.Params.Total == totalApprox,
 	}
 
 	if sg.SrcUIDs != nil {
@@ -985,7 +1015,8 @@ func calculateFirstN(sg *SubGraph) int32 {
 
 	// Manish: Shouldn't all functions allow this? If we don't have a order and we don't have a
 	// filter, then we can respect the first N, offset Y arguments when retrieving data.
-	isSupportedFunction := true
+	// The results can't be limited either if all of them have to be counted.
+	isSupportedFunction := sg.Params.Total != totalExact
 	if len(sg.Filters) == 0 && len(sg.Params.Order) == 0 &&
 		isSupportedFunction {
 		// Offset also added because, we need n results to trim the offset.
@@ -1910,6 +1941,7 @@ func recursiveCopy(dst *SubGraph, src *SubGraph) {
 
 	dst.copyFiltersRecurse(src)
 	dst.ReadTs = src.ReadTs
+	dst.MaxLag = src.MaxLag
 
 	for _, c := range src.Children {
 		copyChild := new(SubGraph)
@@ -2149,6 +2181,10 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 				rch <- err
 				return
 			}
+			if err := memoryFromContext(ctx).addTaskBytes(int64(result.Size())); err != nil {
+				rch <- err
+				return
+			}
 
 			sg.uidMatrix = result.UidMatrix
 			sg.valueMatrix = result.ValueMatrix
@@ -2156,6 +2192,7 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 			sg.counts = result.Counts
 			sg.LangTags = result.LangMatrix
 			sg.List = result.List
+			sg.total = int(result.EstimatedTotal)
 
 			if sg.Params.DoCount {
 				if len(sg.Filters) == 0 {
@@ -2171,6 +2208,9 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 			} else {
 				sg.DestUIDs = algo.MergeSorted(result.UidMatrix)
 			}
+			if sg.Params.Lineage {
+				sg.lineageUids()
+			}
 
 			if parent == nil {
 				// I'm root. We reach here if root had a function.
@@ -2252,6 +2292,11 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 		}
 	}
 
+	if parent == nil && sg.Params.Total != "" && len(sg.DestUIDs.GetUids()) > sg.total {
+		// The estimate of the worker, if any, accounts for the results it didn't return.
+		sg.total = len(sg.DestUIDs.GetUids())
+	}
+
 	if len(sg.Params.Order) == 0 && len(sg.Params.FacetsOrder) == 0 {
 		// There is no ordering. Just apply pagination and return.
 		if err = sg.applyPagination(ctx); err != nil {
@@ -2366,6 +2411,42 @@ func ProcessGraph(ctx context.Context, sg, parent *SubGraph, rch chan error) {
 	rch <- childErr
 }
 
+// lineageUids replaces the edges fetched for lineage(pred) by the lineage nodes given by their
+// dgraph.lineage facets, so that the children of the subgraph are read from the lineage nodes.
+// The edges which were set without a lineage have none.
+func (sg *SubGraph) lineageUids() {
+	sg.uidMatrix = make([]*pb.List, len(sg.facetsMatrix))
+	for i, fl := range sg.facetsMatrix {
+		var uids []uint64
+		for _, fs := range fl.FacetsList {
+			for _, f := range fs.GetFacets() {
+				if f.Key != x.LineageFacet {
+					continue
+				}
+				if val, err := facets.ValFor(f); err == nil {
+					if uid, ok := val.Value.(int64); ok && uid > 0 {
+						uids = append(uids, uint64(uid))
+					}
+				}
+			}
+		}
+		sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+		out := uids[:0]
+		for _, uid := range uids {
+			if len(out) == 0 || uid != out[len(out)-1] {
+				out = append(out, uid)
+			}
+		}
+		sg.uidMatrix[i] = &pb.List{Uids: out}
+	}
+	sg.DestUIDs = algo.MergeSorted(sg.uidMatrix)
+	sg.valueMatrix = nil
+	sg.facetsMatrix = nil
+	sg.LangTags = nil
+	sg.Params.Facet = nil
+	sg.List = true
+}
+
 // applyPagination applies count and offset to lists inside uidMatrix.
 func (sg *SubGraph) applyPagination(ctx context.Context) error {
 	if sg.Params.Count == 0 && sg.Params.Offset == 0 { // No pagination.
@@ -2392,27 +2473,48 @@ func (sg *SubGraph) applyOrderAndPagination(ctx context.Context) error {
 
 	sg.updateUidMatrix()
 
-	// See if we need to apply order based on facet.
-	if len(sg.Params.FacetsOrder) != 0 {
-		return sg.sortAndPaginateUsingFacet(ctx)
-	}
-
 	for _, it := range sg.Params.NeedsVar {
 		// TODO(pawan) - Return error if user uses var order with predicates.
 		if len(sg.Params.Order) > 0 && it.Name == sg.Params.Order[0].Attr &&
 			(it.Typ == gql.ValueVar) {
+			if len(sg.Params.FacetsOrder) != 0 {
+				return errors.Errorf("Cannot order by a value variable and facets together")
+			}
 			// If the Order name is same as var name and it's a value variable, we sort using that variable.
 			return sg.sortAndPaginateUsingVar(ctx)
 		}
 	}
 
+	// See if we need to apply order based on facet.
+	if len(sg.Params.FacetsOrder) != 0 {
+		if len(sg.Params.Order) != 0 {
+			// The predicates only break the ties between the facet values, so the lists are
+			// sorted by the predicates as a whole first, and paginated after the facet sort.
+			var count int
+			for _, ul := range sg.uidMatrix {
+				if len(ul.Uids) > count {
+					count = len(ul.Uids)
+				}
+			}
+			if err := sg.sortUsingWorker(ctx, 0, count); err != nil {
+				return err
+			}
+		}
+		return sg.sortAndPaginateUsingFacet(ctx)
+	}
+
 	if sg.Params.Count == 0 {
 		// Only retrieve up to 1000 results by default.
 		sg.Params.Count = 1000
 	}
 
 	x.AssertTrue(len(sg.Params.Order) > 0)
+	return sg.sortUsingWorker(ctx, sg.Params.Offset, sg.Params.Count)
+}
 
+// sortUsingWorker sorts each posting list by the order predicates in the sort worker, and
+// applies the given pagination.
+func (sg *SubGraph) sortUsingWorker(ctx context.Context, offset, count int) error {
 	ns, err := x.ExtractNamespace(ctx)
 	if err != nil {
 		return errors.Wrapf(err, "While ordering and paginating")
@@ -2421,8 +2523,8 @@ func (sg *SubGraph) applyOrderAndPagination(ctx context.Context) error {
 	sortMsg := &pb.SortMessage{
 		Order:     order,
 		UidMatrix: sg.uidMatrix,
-		Offset:    int32(sg.Params.Offset),
-		Count:     int32(sg.Params.Count),
+		Offset:    int32(offset),
+		Count:     int32(count),
 		ReadTs:    sg.ReadTs,
 	}
 	result, err := worker.SortOverNetwork(ctx, sortMsg)
@@ -2499,6 +2601,12 @@ func (sg *SubGraph) sortAndPaginateUsingFacet(ctx context.Context) error {
 		orderbyKeys[order.Key] = i
 		orderDesc = append(orderDesc, order.Desc)
 	}
+	// If the lists were sorted by predicates too, the position of the uids in them breaks the ties
+	// between the facet values.
+	numKeys := len(orderDesc)
+	if len(sg.Params.Order) != 0 {
+		orderDesc = append(orderDesc, false)
+	}
 
 	for i := 0; i < len(sg.uidMatrix); i++ {
 		ul := sg.uidMatrix[i]
@@ -2508,7 +2616,10 @@ func (sg *SubGraph) sortAndPaginateUsingFacet(ctx context.Context) error {
 
 		values := make([][]types.Val, len(ul.Uids))
 		for i := 0; i < len(values); i++ {
-			values[i] = make([]types.Val, len(sg.Params.FacetsOrder))
+			values[i] = make([]types.Val, len(orderDesc))
+			if len(orderDesc) > numKeys {
+				values[i][numKeys] = types.Val{Tid: types.IntID, Value: int64(i)}
+			}
 		}
 
 		for j := 0; j < len(ul.Uids); j++ {
@@ -2617,7 +2728,7 @@ func (sg *SubGraph) sortAndPaginateUsingVar(ctx context.Context) error {
 func isValidArg(a string) bool {
 	switch a {
 	case "numpaths", "from", "to", "orderasc", "orderdesc", "first", "offset", "after", "depth",
-		"minweight", "maxweight":
+		"minweight", "maxweight", "total":
 		return true
 	}
 	return false
@@ -2627,7 +2738,7 @@ func isValidArg(a string) bool {
 func isValidFuncName(f string) bool {
 	switch f {
 	case "anyofterms", "allofterms", "val", "regexp", "anyoftext", "alloftext",
-		"has", "uid", "uid_in", "anyof", "allof", "type", "match":
+		"has", "uid", "uid_in", "anyof", "allof", "type", "match", "fuzzy":
 		return true
 	}
 	return isInequalityFn(f) || types.IsGeoFunc(f)
@@ -2667,6 +2778,9 @@ func getNodeTypes(ctx context.Context, sg *SubGraph) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := memoryFromContext(ctx).addTaskBytes(int64(result.Size())); err != nil {
+		return nil, err
+	}
 	return getPredsFromVals(result.ValueMatrix), nil
 }
 
@@ -2724,6 +2838,7 @@ func UidToHex(uid uint64) string {
 type Request struct {
 	ReadTs   uint64 // ReadTs for the transaction.
 	Cache    int    // 0 represents use txn cache, 1 represents not to use cache.
+	MaxLag   uint64 // Replicas lagging this much behind ReadTs can serve best effort queries.
 	Latency  *Latency
 	GqlQuery *gql.Result
 
@@ -2760,6 +2875,7 @@ func (req *Request) ProcessQuery(ctx context.Context) (err error) {
 		sg.recurse(func(sg *SubGraph) {
 			sg.ReadTs = req.ReadTs
 			sg.Cache = req.Cache
+			sg.MaxLag = req.MaxLag
 		})
 		span.Annotate(nil, "Query parsed")
 		req.Subgraphs = append(req.Subgraphs, sg)
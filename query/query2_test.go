@@ -495,6 +495,33 @@ func TestToFastJSONFirstOffset(t *testing.T) {
 		js)
 }
 
+func TestToFastJSONFirstOffsetTotal(t *testing.T) {
+
+	query := `
+		{
+			me(func: uid(0x01, 0x17, 0x18, 0x19), offset: 1, first: 2, total: exact) @filter(has(name)) {
+				name
+			}
+		}
+	`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"me":[{"total":4},{"name":"Rick Grimes"},{"name":"Glenn Rhee"}]}}`,
+		js)
+
+	query = `
+		{
+			me(func: uid(0x01), total: all) {
+				name
+			}
+		}
+	`
+	_, err := processQuery(context.Background(), t, query)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Invalid value for total: all")
+}
+
 func TestToFastJSONFilterOrFirstOffset(t *testing.T) {
 
 	query := `
@@ -735,6 +762,62 @@ func TestCountReverseFilter(t *testing.T) {
 		js)
 }
 
+func TestCountReverseRangeFunc(t *testing.T) {
+
+	query := `
+		{
+			me(func: le(count(~friend), 1)) {
+				uid
+				count(~friend)
+			}
+		}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"me":[{"uid":"0x1","count(~friend)":1},{"uid":"0x17","count(~friend)":1},
+		{"uid":"0x19","count(~friend)":1},{"uid":"0x1f","count(~friend)":1},
+		{"uid":"0x65","count(~friend)":1}]}}`,
+		js)
+}
+
+func TestCountReverseBetweenFunc(t *testing.T) {
+
+	query := `
+		{
+			me(func: between(count(~friend), 2, 5)) {
+				name
+				count(~friend)
+			}
+		}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"me":[{"name":"Glenn Rhee","count(~friend)":2}]}}`,
+		js)
+}
+
+func TestCountReverseRangeFilter(t *testing.T) {
+
+	query := `
+		{
+			me(func: anyofterms(name, "Glenn Michonne Rick")) @filter(lt(count(~friend), 2)) {
+				name
+				count(~friend)
+			}
+			you(func: anyofterms(name, "Glenn Michonne Rick")) @filter(gt(count(~friend), 1)) {
+				name
+				count(~friend)
+			}
+		}
+	`
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t,
+		`{"data": {"me":[{"name":"Michonne","count(~friend)":1},
+		{"name":"Rick Grimes","count(~friend)":1}],
+		"you":[{"name":"Glenn Rhee","count(~friend)":2}]}}`,
+		js)
+}
+
 func TestCountReverse(t *testing.T) {
 
 	query := `
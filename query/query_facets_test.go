@@ -250,6 +250,50 @@ func TestOrderFacets(t *testing.T) {
 	`, js)
 }
 
+func TestOrderFacetsAndPredicate(t *testing.T) {
+	populateClusterWithFacets()
+	// The predicate breaks the ties between the facet values.
+	query := `
+		{
+			me(func: uid(1)) {
+				friend @facets(orderasc:since) (orderasc: name) {
+					name
+				}
+			}
+		}
+	`
+
+	js := processQueryNoErr(t, query)
+	require.JSONEq(t, `
+		{
+		  "data": {
+		    "me": [
+		      {
+		        "friend": [
+		          {
+		            "name": "Glenn Rhee",
+		            "friend|since": "2004-05-02T15:04:05Z"
+		          },
+		          {
+		            "name": "Andrea",
+		            "friend|since": "2006-01-02T15:04:05Z"
+		          },
+		          {
+		            "name": "Rick Grimes",
+		            "friend|since": "2006-01-02T15:04:05Z"
+		          },
+		          {
+		            "name": "Daryl Dixon",
+		            "friend|since": "2007-05-02T15:04:05Z"
+		          }
+		        ]
+		      }
+		    ]
+		  }
+		}
+	`, js)
+}
+
 func TestOrderdescFacets(t *testing.T) {
 	populateClusterWithFacets()
 	// to see how friend @facets are positioned in output.
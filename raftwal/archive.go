@@ -0,0 +1,55 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package raftwal
+
+// Segment is a log file of the write-ahead log, which only holds entries at or below the Raft
+// snapshot. The data of its entries is encrypted if the log is, with the data keys of the key
+// registry in the directory of the log.
+type Segment struct {
+	// Path is the path of the file.
+	Path string
+	// FirstIndex and LastIndex are the Raft indexes of the first and last entries in the file.
+	FirstIndex uint64
+	LastIndex  uint64
+}
+
+// Archiver archives a segment before it's deleted by a truncation of the log. The segment is
+// only deleted if it returns nil, otherwise it's passed again at the next truncation. It's
+// called with the storage locked, so it should hand the file over quickly, e.g. by copying it
+// into a directory from which it's archived asynchronously. It must not keep a hard link to the
+// file, which is truncated before it's removed.
+type Archiver func(seg Segment) error
+
+var archiver Archiver
+
+// SetArchiver sets the archiver of the segments of the logs kept on disk. It must be set before
+// the storage is initialized, because the initialization may truncate the log.
+func SetArchiver(fn Archiver) {
+	archiver = fn
+}
+
+// archive archives the file with the archiver, if any.
+func (lf *logFile) archive() error {
+	if archiver == nil || lf.inMemory {
+		return nil
+	}
+	return archiver(Segment{
+		Path:       lf.Fd.Name(),
+		FirstIndex: lf.firstIndex(),
+		LastIndex:  lf.lastEntry().Index(),
+	})
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package raftwal
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/raft/raftpb"
+)
+
+func TestArchiver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "raftwal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var archived []Segment
+	fail := true
+	SetArchiver(func(seg Segment) error {
+		if fail {
+			return errors.New("unavailable")
+		}
+		// The file must still be there.
+		_, err := os.Stat(seg.Path)
+		require.NoError(t, err)
+		archived = append(archived, seg)
+		return nil
+	})
+	defer SetArchiver(nil)
+
+	ds := Init(dir)
+	N := uint64(2*maxNumEntries + 100)
+	for idx := uint64(1); idx <= N; idx++ {
+		require.NoError(t, ds.wal.AddEntries([]raftpb.Entry{{Index: idx, Term: 1}}))
+	}
+	require.Equal(t, 2, ds.NumLogFiles())
+
+	// The segments which failed to be archived are kept.
+	cs := &raftpb.ConfState{Nodes: []uint64{1}}
+	require.NoError(t, ds.CreateSnapshot(N-10, cs, nil))
+	require.Equal(t, 2, ds.NumLogFiles())
+	require.Empty(t, archived)
+
+	fail = false
+	require.NoError(t, ds.CreateSnapshot(N-5, cs, nil))
+	require.Equal(t, 0, ds.NumLogFiles())
+	require.Equal(t, []Segment{
+		{Path: archived[0].Path, FirstIndex: 1, LastIndex: maxNumEntries},
+		{Path: archived[1].Path, FirstIndex: maxNumEntries + 1, LastIndex: 2 * maxNumEntries},
+	}, archived)
+	require.NoError(t, ds.Close())
+}
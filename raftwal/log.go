@@ -89,19 +89,35 @@ type logFile struct {
 	registry *badger.KeyRegistry
 	dataKey  *pb.DataKey
 	baseIV   []byte
+	// inMemory is whether the file lives in memory instead of on disk.
+	inMemory bool
 }
 
-func logFname(dir string, id int64) string {
-	return filepath.Join(dir, fmt.Sprintf("%05d%s", id, logSuffix))
+func logFname(id int64) string {
+	return fmt.Sprintf("%05d%s", id, logSuffix)
 }
 
-// openLogFile opens a logFile in the given directory. The filename is
-// constructed based on the value of fid.
+// openMmapFile opens the file in the given directory, and creates it with size sz if it doesn't
+// exist. An empty dir creates the file in memory. Like z.OpenMmapFile, it returns z.NewFile if
+// the file was created.
+func openMmapFile(dir, fname string, sz int) (*z.MmapFile, error) {
+	if dir != "" {
+		return z.OpenMmapFile(filepath.Join(dir, fname), os.O_RDWR|os.O_CREATE, sz)
+	}
+	fd, err := memFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	return z.OpenMmapFileUsing(fd, sz, true)
+}
+
+// openLogFile opens a logFile in the given directory, or in memory if dir is empty. The
+// filename is constructed based on the value of fid.
 func openLogFile(dir string, fid int64) (*logFile, error) {
 	glog.V(3).Infof("opening log file: %d\n", fid)
-	fpath := logFname(dir, fid)
 	lf := &logFile{
-		fid: fid,
+		fid:      fid,
+		inMemory: dir == "",
 	}
 	var err error
 	// Initialize the registry for logFile if encryption in enabled.
@@ -113,7 +129,7 @@ func openLogFile(dir string, fid int64) (*logFile, error) {
 			Dir:                           dir,
 			EncryptionKey:                 encryptionKey,
 			EncryptionKeyRotationDuration: 10 * 24 * time.Hour,
-			InMemory:                      false,
+			InMemory:                      lf.inMemory,
 		}
 		// This won't open Badger. It would only use its key registry.
 		if lf.registry, err = badger.OpenKeyRegistry(krOpt); err != nil {
@@ -121,7 +137,7 @@ func openLogFile(dir string, fid int64) (*logFile, error) {
 		}
 	}
 	// Open the file in read-write mode and create it if it doesn't exist yet.
-	lf.MmapFile, err = z.OpenMmapFile(fpath, os.O_RDWR|os.O_CREATE, logFileSize)
+	lf.MmapFile, err = openMmapFile(dir, logFname(fid), logFileSize)
 
 	if err == z.NewFile {
 		glog.V(3).Infof("New file: %d\n", fid)
@@ -251,7 +267,13 @@ func (lf *logFile) slotGe(raftIndex uint64) int {
 // delete unmaps and deletes the file.
 func (lf *logFile) delete() error {
 	glog.V(2).Infof("Deleting file: %s\n", lf.Fd.Name())
-	err := lf.Delete()
+	var err error
+	if lf.inMemory {
+		// The file has no path to remove. Closing it frees its memory.
+		err = lf.Close(0)
+	} else {
+		err = lf.Delete()
+	}
 	if err != nil {
 		glog.Errorf("while deleting file: %s, error: %v\n", lf.Fd.Name(), err)
 	}
@@ -261,6 +283,10 @@ func (lf *logFile) delete() error {
 // getLogFiles returns all the log files in the directory sorted by the first
 // index in each file.
 func getLogFiles(dir string) ([]*logFile, error) {
+	if dir == "" {
+		// The files in memory are gone.
+		return nil, nil
+	}
 	entryFiles := x.WalkPathFunc(dir, func(path string, isDir bool) bool {
 		if isDir {
 			return false
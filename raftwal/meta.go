@@ -19,8 +19,6 @@ package raftwal
 import (
 	"encoding/binary"
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/dgraph-io/ristretto/z"
@@ -95,11 +93,10 @@ type metaFile struct {
 	*z.MmapFile
 }
 
-// newMetaFile opens the meta file in the given directory.
+// newMetaFile opens the meta file in the given directory, or in memory if dir is empty.
 func newMetaFile(dir string) (*metaFile, error) {
-	fname := filepath.Join(dir, metaName)
 	// Open the file in read-write mode and creates it if it doesn't exist.
-	mf, err := z.OpenMmapFile(fname, os.O_RDWR|os.O_CREATE, metaFileSize)
+	mf, err := openMmapFile(dir, metaName, metaFileSize)
 	if err == z.NewFile {
 		z.ZeroOut(mf.Data, 0, snapshotOffset+4)
 	} else if err != nil {
@@ -17,10 +17,16 @@
 package raftwal
 
 import (
+	"bytes"
+	"io/ioutil"
 	"math"
+	"os"
+	"path/filepath"
 	"sync"
 
+	"github.com/dgraph-io/badger/v3"
 	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/raft"
@@ -87,6 +93,7 @@ func Init(dir string) *DiskStorage {
 
 // InitEncrypted initializes returns a properly initialized instance of DiskStorage.
 // To gracefully shutdown DiskStorage, store.Closer.SignalAndWait() should be called.
+// An empty dir keeps the files in memory, see InitInMemory.
 func InitEncrypted(dir string, encKey x.SensitiveByteSlice) (*DiskStorage, error) {
 	w := &DiskStorage{
 		dir: dir,
@@ -127,6 +134,12 @@ func InitEncrypted(dir string, encKey x.SensitiveByteSlice) (*DiskStorage, error
 	return w, nil
 }
 
+// InitInMemory initializes an instance of DiskStorage which keeps its files in memory instead of
+// on disk, so that nothing survives the process. It's only supported on Linux.
+func InitInMemory(encKey x.SensitiveByteSlice) (*DiskStorage, error) {
+	return InitEncrypted("", encKey)
+}
+
 func (w *DiskStorage) SetUint(info MetaInfo, id uint64) { w.meta.SetUint(info, id) }
 func (w *DiskStorage) Uint(info MetaInfo) uint64        { return w.meta.Uint(info) }
 
@@ -385,6 +398,143 @@ func (w *DiskStorage) Sync() error {
 	return nil
 }
 
+// Clone copies the files of the storage to the given directory, which must exist, for a new node
+// of the same group. No entries are written meanwhile, so the copy is consistent. The copy keeps
+// the group ID but not the Raft ID, so that the new node gets its own. Clone returns the snapshot
+// of the copy, and the number of bytes written.
+func (w *DiskStorage) Clone(dir string) (raftpb.Snapshot, int64, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var snap raftpb.Snapshot
+	if w.dir == "" {
+		return snap, 0, errors.Errorf("the WAL is kept in memory")
+	}
+	snap, err := w.meta.snapshot()
+	if err != nil {
+		return snap, 0, err
+	}
+	meta := append([]byte{}, w.meta.Data...)
+	offset := getOffset(RaftId)
+	z.ZeroOut(meta, offset, offset+8)
+	total, err := writeSparse(filepath.Join(dir, metaName), meta)
+	if err != nil {
+		return snap, 0, err
+	}
+	files := w.wal.files
+	if w.wal.current != nil {
+		files = append(files[:len(files):len(files)], w.wal.current)
+	}
+	for _, lf := range files {
+		n, err := writeSparse(filepath.Join(dir, logFname(lf.fid)), lf.Data)
+		if err != nil {
+			return snap, 0, err
+		}
+		total += n
+	}
+
+	// The key registry of the encrypted log files.
+	registry, err := ioutil.ReadFile(filepath.Join(w.dir, badger.KeyRegistryFileName))
+	switch {
+	case os.IsNotExist(err):
+		return snap, total, nil
+	case err != nil:
+		return snap, 0, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, badger.KeyRegistryFileName), registry,
+		0600); err != nil {
+		return snap, 0, err
+	}
+	return snap, total + int64(len(registry)), nil
+}
+
+// Rekey writes the log to the given directory, which must exist, encrypted with the given key, or
+// in plain text if the key is nil. The storage then switches over to the new files. The current
+// files are left as they are. No entries are written meanwhile.
+func (w *DiskStorage) Rekey(dir string, key x.SensitiveByteSlice) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.dir == "" {
+		return errors.Errorf("the WAL is kept in memory")
+	}
+	hs, err := w.meta.HardState()
+	if err != nil {
+		return err
+	}
+	snap, err := w.meta.snapshot()
+	if err != nil {
+		return err
+	}
+	var entries []raftpb.Entry
+	for lo := w.firstIndex(); ; {
+		es := w.wal.allEntries(lo, math.MaxUint64, 64<<20)
+		if len(es) == 0 {
+			break
+		}
+		entries = append(entries, es...)
+		lo = es[len(es)-1].Index + 1
+	}
+
+	// InitEncrypted sets the key used for the new log files. Put the current one back if the
+	// switch doesn't happen.
+	prevKey := encryptionKey
+	s, err := InitEncrypted(dir, key)
+	if err != nil {
+		encryptionKey = prevKey
+		return err
+	}
+	for _, info := range []MetaInfo{RaftId, GroupId, CheckpointIndex} {
+		s.meta.SetUint(info, w.meta.Uint(info))
+	}
+	if err := s.Save(&hs, entries, &snap); err != nil {
+		encryptionKey = prevKey
+		return err
+	}
+	if err := s.Sync(); err != nil {
+		encryptionKey = prevKey
+		return err
+	}
+	w.dir, w.meta, w.wal = dir, s.meta, s.wal
+	return nil
+}
+
+// writeSparse writes data to a new file, leaving holes instead of the zeroed blocks. The log
+// files are preallocated, and mostly zeroed. It returns the number of bytes written.
+func writeSparse(path string, data []byte) (int64, error) {
+	const blockSize = 64 << 10
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return 0, err
+	}
+	zero := make([]byte, blockSize)
+	var written int64
+	for off := 0; off < len(data); off += blockSize {
+		end := off + blockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		block := data[off:end]
+		if bytes.Equal(block, zero[:len(block)]) {
+			continue
+		}
+		if _, err := f.WriteAt(block, int64(off)); err != nil {
+			f.Close()
+			return 0, err
+		}
+		written += int64(len(block))
+	}
+	if err := f.Truncate(int64(len(data))); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return written, f.Close()
+}
+
 // Close closes the DiskStorage.
 func (w *DiskStorage) Close() error {
 	return w.Sync()
@@ -38,6 +38,7 @@ import (
 	"io/ioutil"
 	"math"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -588,3 +589,117 @@ func TestStorageBig(t *testing.T) {
 	t.Run("without encryption", func(t *testing.T) { test(t, nil) })
 	t.Run("with encryption", func(t *testing.T) { test(t, []byte("badger16byteskey")) })
 }
+
+func TestStorageInMemory(t *testing.T) {
+	ds, err := InitInMemory(nil)
+	require.NoError(t, err)
+	require.Equal(t, 0, ds.NumLogFiles())
+
+	// Write enough entries to rotate the log file.
+	N := uint64(maxNumEntries + 100)
+	for idx := uint64(1); idx <= N; idx++ {
+		require.NoError(t, ds.wal.AddEntries([]raftpb.Entry{{Index: idx, Term: 1}}))
+	}
+	require.Equal(t, 1, ds.NumLogFiles())
+	ents, err := ds.Entries(1, N+1, math.MaxInt64)
+	require.NoError(t, err)
+	require.Equal(t, int(N), len(ents))
+
+	// A snapshot bigger than the meta file grows it, and the rotated file is deleted.
+	data := make([]byte, 2*metaFileSize)
+	_, err = rand.Read(data)
+	require.NoError(t, err)
+	require.NoError(t, ds.CreateSnapshot(N-10, &pb.ConfState{Nodes: []uint64{1}}, data))
+	require.Equal(t, 0, ds.NumLogFiles())
+	snap, err := ds.Snapshot()
+	require.NoError(t, err)
+	require.Equal(t, data, snap.Data)
+	first, err := ds.FirstIndex()
+	require.NoError(t, err)
+	require.Equal(t, N-9, first)
+	require.NoError(t, ds.Close())
+}
+
+func TestStorageClone(t *testing.T) {
+	dir, err := ioutil.TempDir("", "raftwal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "w"), 0700))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "copy"), 0700))
+
+	ds := Init(filepath.Join(dir, "w"))
+	ds.SetUint(RaftId, 7)
+	ents := []pb.Entry{{Index: 1, Term: 1, Data: []byte("a")}, {Index: 2, Term: 1},
+		{Index: 3, Term: 2, Data: []byte("c")}}
+	require.NoError(t, ds.Save(&pb.HardState{Term: 2, Commit: 3}, ents, &pb.Snapshot{}))
+	require.NoError(t, ds.CreateSnapshot(1, &pb.ConfState{Nodes: []uint64{7}}, []byte("snap")))
+
+	ds.SetUint(GroupId, 2)
+	snap, n, err := ds.Clone(filepath.Join(dir, "copy"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), snap.Metadata.Index)
+	require.Greater(t, n, int64(0))
+	_, _, err = ds.Clone(filepath.Join(dir, "copy"))
+	require.Error(t, err)
+
+	// The copy belongs to the same group, but not to the same node.
+	cp := Init(filepath.Join(dir, "copy"))
+	require.Equal(t, uint64(0), cp.Uint(RaftId))
+	require.Equal(t, uint64(2), cp.Uint(GroupId))
+	hs, err := cp.HardState()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), hs.Commit)
+	snap, err = cp.Snapshot()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), snap.Metadata.Index)
+	require.Equal(t, []byte("snap"), snap.Data)
+	got, err := cp.Entries(2, 4, math.MaxUint64)
+	require.NoError(t, err)
+	require.Equal(t, ents[1:], got)
+}
+
+func TestStorageRekey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "raftwal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "w"), 0700))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "rekeyed"), 0700))
+
+	ds := Init(filepath.Join(dir, "w"))
+	ds.SetUint(RaftId, 7)
+	ds.SetUint(GroupId, 2)
+	ents := []pb.Entry{{Index: 1, Term: 1, Data: []byte("a")}, {Index: 2, Term: 1},
+		{Index: 3, Term: 2, Data: []byte("c")}}
+	require.NoError(t, ds.Save(&pb.HardState{Term: 2, Commit: 3}, ents, &pb.Snapshot{}))
+	require.NoError(t, ds.CreateSnapshot(1, &pb.ConfState{Nodes: []uint64{7}}, []byte("snap")))
+
+	key := []byte("badger16byteskey")
+	require.NoError(t, ds.Rekey(filepath.Join(dir, "rekeyed"), key))
+	ents = append(ents, pb.Entry{Index: 4, Term: 2, Data: []byte("d")})
+	require.NoError(t, ds.Save(&pb.HardState{Term: 2, Commit: 4}, ents[3:], &pb.Snapshot{}))
+	require.NoError(t, ds.Close())
+
+	// The entries written after the switch are in the new files, under the new key.
+	_, err = InitEncrypted(filepath.Join(dir, "rekeyed"), nil)
+	require.Error(t, err)
+	rk, err := InitEncrypted(filepath.Join(dir, "rekeyed"), key)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), rk.Uint(RaftId))
+	require.Equal(t, uint64(2), rk.Uint(GroupId))
+	hs, err := rk.HardState()
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), hs.Commit)
+	snap, err := rk.Snapshot()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), snap.Metadata.Index)
+	require.Equal(t, []byte("snap"), snap.Data)
+	got, err := rk.Entries(2, 5, math.MaxUint64)
+	require.NoError(t, err)
+	require.Equal(t, ents[1:], got)
+
+	// The old files are left as they were.
+	old := Init(filepath.Join(dir, "w"))
+	last, err := old.LastIndex()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), last)
+}
@@ -360,21 +360,23 @@ func (l *wal) deleteBefore(raftIndex uint64) {
 		return
 	}
 
-	var before []*logFile
+	n := fidx
 	if fidx == -1 { // current file
-		before = l.files
-		l.files = l.files[:0]
-	} else {
-		before = l.files[:fidx]
-		l.files = l.files[fidx:]
+		n = len(l.files)
 	}
-
-	for _, ef := range before {
+	// Only delete the files which got archived. The others are kept until the next truncation.
+	var deleted int
+	for _, ef := range l.files[:n] {
+		if err := ef.archive(); err != nil {
+			glog.Errorf("while archiving file: %s, err: %v\n", ef.Fd.Name(), err)
+			break
+		}
 		if err := ef.delete(); err != nil {
 			glog.Errorf("while deleting file: %s, err: %v\n", ef.Fd.Name(), err)
 		}
+		deleted++
 	}
-	return
+	l.files = l.files[deleted:]
 }
 
 // reset deletes all the previous log files, and resets the current log file.
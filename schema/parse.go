@@ -29,6 +29,7 @@ import (
 
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
+	"golang.org/x/text/language"
 )
 
 // ParseBytes parses the byte array which holds the schema. We will reset
@@ -75,6 +76,40 @@ func parseDirective(it *lex.ItemIterator, schema *pb.SchemaUpdate, t types.TypeI
 		schema.Upsert = true
 	case "noconflict":
 		schema.NoConflict = true
+	case "presence":
+		schema.Presence = true
+	case "collate":
+		if t != types.StringID {
+			return next.Errorf("@collate directive can only be specified for string type."+
+				" Got: [%v] for attr: [%v]", t.Name(), x.ParseAttr(schema.Predicate))
+		}
+		collation, err := parseCollateDirective(it, schema.Predicate)
+		if err != nil {
+			return err
+		}
+		schema.Collation = collation
+	case "synonyms", "protected":
+		if t != types.StringID {
+			return next.Errorf("@%s directive can only be specified for string type."+
+				" Got: [%v] for attr: [%v]", next.Val, t.Name(), x.ParseAttr(schema.Predicate))
+		}
+		groups, err := parseDictionaryDirective(it, next.Val, schema.Predicate)
+		if err != nil {
+			return err
+		}
+		if next.Val == "protected" {
+			for _, group := range groups {
+				if len(group) != 1 {
+					return next.Errorf("Expected a comma between the protected words of pred: %s",
+						x.ParseAttr(schema.Predicate))
+				}
+				schema.FulltextProtected = append(schema.FulltextProtected, group[0])
+			}
+			break
+		}
+		for _, group := range groups {
+			schema.FulltextSynonyms = append(schema.FulltextSynonyms, &pb.Synonyms{Words: group})
+		}
 	case "lang":
 		if t != types.StringID || schema.List {
 			return next.Errorf("@lang directive can only be specified for string type."+
@@ -89,6 +124,65 @@ func parseDirective(it *lex.ItemIterator, schema *pb.SchemaUpdate, t types.TypeI
 	return nil
 }
 
+// parseCollateDirective parses the language of a @collate directive, and returns its
+// canonical form.
+func parseCollateDirective(it *lex.ItemIterator, predicate string) (string, error) {
+	if !it.Next() || it.Item().Typ != itemLeftRound {
+		return "", it.Item().Errorf("Require a language for @collate of pred: %s",
+			x.ParseAttr(predicate))
+	}
+	it.Next()
+	next := it.Item()
+	if next.Typ != itemText {
+		return "", next.Errorf("Expected a language for @collate but got: %v", next.Val)
+	}
+	langTag, err := language.Parse(next.Val)
+	if err != nil {
+		return "", next.Errorf("Invalid language %s for @collate of pred: %s", next.Val,
+			x.ParseAttr(predicate))
+	}
+	it.Next()
+	if next = it.Item(); next.Typ != itemRightRound {
+		return "", next.Errorf("Expected ) after the language of @collate but got: %v", next.Val)
+	}
+	return langTag.String(), nil
+}
+
+// parseDictionaryDirective parses the words of a @synonyms or @protected directive, which are
+// separated by commas into groups, e.g. @synonyms(tv television, film movie). A word which isn't
+// a name is written as an IRI, e.g. <télé>.
+func parseDictionaryDirective(it *lex.ItemIterator, directive, predicate string) ([][]string,
+	error) {
+	if !it.Next() || it.Item().Typ != itemLeftRound {
+		return nil, it.Item().Errorf("Require words for @%s of pred: %s", directive,
+			x.ParseAttr(predicate))
+	}
+	groups := [][]string{nil}
+	for {
+		it.Next()
+		next := it.Item()
+		switch {
+		case next.Typ == itemRightRound && len(groups[len(groups)-1]) > 0:
+			return groups, nil
+		case next.Typ == itemComma && len(groups[len(groups)-1]) > 0:
+			groups = append(groups, nil)
+		case next.Typ == itemText || next.Typ == itemNumber:
+			word := next.Val
+			if strings.Contains(word, "\\") {
+				// The UCHARs of an IRI.
+				unquoted, err := strconv.Unquote(`"` + word + `"`)
+				if err != nil {
+					return nil, next.Errorf("Invalid word %s in @%s", word, directive)
+				}
+				word = unquoted
+			}
+			groups[len(groups)-1] = append(groups[len(groups)-1], word)
+		default:
+			return nil, next.Errorf("Expected a word in @%s but got: %v", directive, next.Val)
+		}
+	}
+}
+
 func parseScalarPair(it *lex.ItemIterator, predicate string, ns uint64) (*pb.SchemaUpdate, error) {
 	it.Next()
 	next := it.Item()
@@ -260,6 +354,9 @@ func resolveTokenizers(updates []*pb.SchemaUpdate) error {
 		if typ == types.UidID {
 			continue
 		}
+		if err := checkFulltextDictionary(schema); err != nil {
+			return err
+		}
 
 		if len(schema.Tokenizer) == 0 && schema.Directive == pb.SchemaUpdate_INDEX {
 			return errors.Errorf("Require type of tokenizer for pred: %s of type: %s for indexing.",
@@ -299,6 +396,31 @@ func resolveTokenizers(updates []*pb.SchemaUpdate) error {
 	return nil
 }
 
+// checkFulltextDictionary verifies that the predicate has a fulltext index if it has a dictionary,
+// and that the dictionary is valid.
+func checkFulltextDictionary(schema *pb.SchemaUpdate) error {
+	if len(schema.FulltextSynonyms) == 0 && len(schema.FulltextProtected) == 0 {
+		return nil
+	}
+	hasFulltext := false
+	for _, t := range schema.Tokenizer {
+		hasFulltext = hasFulltext || t == "fulltext"
+	}
+	if schema.Directive != pb.SchemaUpdate_INDEX || !hasFulltext {
+		return errors.Errorf("@synonyms and @protected require a fulltext index on attr %s",
+			x.ParseAttr(schema.Predicate))
+	}
+	synonyms := make([][]string, 0, len(schema.FulltextSynonyms))
+	for _, group := range schema.FulltextSynonyms {
+		synonyms = append(synonyms, group.Words)
+	}
+	if _, err := tok.NewDictionary(synonyms, schema.FulltextProtected); err != nil {
+		return errors.Wrapf(err, "invalid fulltext dictionary of attr %s",
+			x.ParseAttr(schema.Predicate))
+	}
+	return nil
+}
+
 func parseTypeDeclaration(it *lex.ItemIterator, ns uint64) (*pb.TypeUpdate, error) {
 	// Iterator is currently on the token corresponding to the keyword type.
 	if it.Item().Typ != itemText || it.Item().Val != "type" {
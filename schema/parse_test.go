@@ -368,6 +368,53 @@ func TestParseUpsert(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestParseCollate(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		name  : string @index(exact) @collate(de) .
+		city  : string @lang @collate(sv-FI) .
+	`)
+	require.NoError(t, err)
+	require.Equal(t, "de", result.Preds[0].Collation)
+	require.Equal(t, "sv-FI", result.Preds[1].Collation)
+
+	_, err = Parse("age: int @collate(de) .")
+	require.Error(t, err)
+	_, err = Parse("name: string @collate(notalanguage) .")
+	require.Error(t, err)
+	_, err = Parse("name: string @collate .")
+	require.Error(t, err)
+}
+
+func TestParseFulltextDictionary(t *testing.T) {
+	reset()
+	result, err := Parse(`
+		name: string @index(fulltext) @synonyms(tv television, film <pel\u00edcula>) .
+		bio: string @protected(news, <télé>) @index(term, fulltext) .
+	`)
+	require.NoError(t, err)
+	require.Equal(t, []*pb.Synonyms{
+		{Words: []string{"tv", "television"}},
+		{Words: []string{"film", "película"}},
+	}, result.Preds[0].FulltextSynonyms)
+	require.Equal(t, []string{"news", "télé"}, result.Preds[1].FulltextProtected)
+
+	for _, bad := range []string{
+		"name: string @synonyms(tv television) .",
+		"name: string @index(term) @protected(news) .",
+		"age: int @index(int) @protected(news) .",
+		"name: string @index(fulltext) @synonyms(tv) .",
+		"name: string @index(fulltext) @synonyms(tv television, tv telly) .",
+		"name: string @index(fulltext) @protected(news us) .",
+		"name: string @index(fulltext) @protected(news,) .",
+		"name: string @index(fulltext) @protected() .",
+		"name: string @index(fulltext) @protected .",
+	} {
+		_, err = Parse(bad)
+		require.Error(t, err, bad)
+	}
+}
+
 func TestParseEmptyType(t *testing.T) {
 	reset()
 	result, err := Parse(`
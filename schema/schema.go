@@ -22,6 +22,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/golang/glog"
 	"github.com/golang/protobuf/proto"
@@ -71,6 +72,18 @@ type state struct {
 	elog      trace.EventLog
 	// mutSchema holds the schema update that is being applied in the background.
 	mutSchema map[string]*pb.SchemaUpdate
+	// numCold is the number of predicates whose schema has a ColdTablet. It's updated under the
+	// lock, and read atomically.
+	numCold int32
+	// dicts maps a predicate to the *fulltextDictionary compiled from its schema.
+	dicts sync.Map
+}
+
+// fulltextDictionary is the dictionary of the fulltext index of a predicate, along with the
+// schema it was compiled from, so that it's compiled again once the schema changes.
+type fulltextDictionary struct {
+	su   *pb.SchemaUpdate
+	dict *tok.Dictionary
 }
 
 // State returns the struct holding the current schema.
@@ -85,6 +98,7 @@ func (s *state) DeleteAll() {
 	for pred := range s.predicate {
 		delete(s.predicate, pred)
 	}
+	atomic.StoreInt32(&s.numCold, 0)
 
 	for typ := range s.types {
 		delete(s.types, typ)
@@ -93,6 +107,11 @@ func (s *state) DeleteAll() {
 	for pred := range s.mutSchema {
 		delete(s.mutSchema, pred)
 	}
+
+	s.dicts.Range(func(pred, _ interface{}) bool {
+		s.dicts.Delete(pred)
+		return true
+	})
 }
 
 // Delete updates the schema in memory and disk
@@ -110,8 +129,12 @@ func (s *state) Delete(attr string) error {
 		return err
 	}
 
+	if s.predicate[attr].GetCold() != nil {
+		atomic.AddInt32(&s.numCold, -1)
+	}
 	delete(s.predicate, attr)
 	delete(s.mutSchema, attr)
+	s.dicts.Delete(attr)
 	return nil
 }
 
@@ -164,6 +187,12 @@ func (s *state) Set(pred string, schema *pb.SchemaUpdate) {
 
 	s.Lock()
 	defer s.Unlock()
+	if s.predicate[pred].GetCold() != nil {
+		atomic.AddInt32(&s.numCold, -1)
+	}
+	if schema.Cold != nil {
+		atomic.AddInt32(&s.numCold, 1)
+	}
 	s.predicate[pred] = schema
 	s.elog.Printf(logUpdate(schema, pred))
 }
@@ -376,6 +405,22 @@ func (s *state) HasCount(ctx context.Context, pred string) bool {
 	return false
 }
 
+// HasPresence returns whether we want to maintain a presence index for the given predicate or not.
+func (s *state) HasPresence(ctx context.Context, pred string) bool {
+	isWrite, _ := ctx.Value(isWrite).(bool)
+	s.RLock()
+	defer s.RUnlock()
+	if isWrite {
+		if schema, ok := s.mutSchema[pred]; ok && schema.Presence {
+			return true
+		}
+	}
+	if schema, ok := s.predicate[pred]; ok {
+		return schema.Presence
+	}
+	return false
+}
+
 // IsList returns whether the predicate is of list type.
 func (s *state) IsList(pred string) bool {
 	s.RLock()
@@ -395,6 +440,53 @@ func (s *state) HasUpsert(pred string) bool {
 	return false
 }
 
+// Collation returns the language whose collation orders the untagged values of the predicate,
+// or an empty string if they are ordered byte-wise.
+func (s *state) Collation(ctx context.Context, pred string) string {
+	isWrite, _ := ctx.Value(isWrite).(bool)
+	s.RLock()
+	defer s.RUnlock()
+	if isWrite {
+		if schema, ok := s.mutSchema[pred]; ok {
+			return schema.Collation
+		}
+	}
+	return s.predicate[pred].GetCollation()
+}
+
+// FulltextDictionary returns the dictionary of the fulltext index of the predicate, or nil if it
+// doesn't have one.
+func (s *state) FulltextDictionary(ctx context.Context, pred string) *tok.Dictionary {
+	isWrite, _ := ctx.Value(isWrite).(bool)
+	s.RLock()
+	su := s.predicate[pred]
+	if isWrite {
+		if schema, ok := s.mutSchema[pred]; ok {
+			su = schema
+		}
+	}
+	s.RUnlock()
+	if len(su.GetFulltextSynonyms()) == 0 && len(su.GetFulltextProtected()) == 0 {
+		return nil
+	}
+
+	if d, ok := s.dicts.Load(pred); ok && d.(*fulltextDictionary).su == su {
+		return d.(*fulltextDictionary).dict
+	}
+	synonyms := make([][]string, 0, len(su.FulltextSynonyms))
+	for _, group := range su.FulltextSynonyms {
+		synonyms = append(synonyms, group.Words)
+	}
+	// The dictionary is validated before it's set.
+	dict, err := tok.NewDictionary(synonyms, su.FulltextProtected)
+	if err != nil {
+		glog.Errorf("Invalid fulltext dictionary of predicate %s: %v", pred, err)
+		return nil
+	}
+	s.dicts.Store(pred, &fulltextDictionary{su: su, dict: dict})
+	return dict
+}
+
 func (s *state) HasLang(pred string) bool {
 	s.RLock()
 	defer s.RUnlock()
@@ -410,6 +502,23 @@ func (s *state) HasNoConflict(pred string) bool {
 	return s.predicate[pred].GetNoConflict()
 }
 
+// ColdTablet returns the state of the predicate if its data was moved to object storage, or is
+// being moved there. It returns nil otherwise.
+func (s *state) ColdTablet(pred string) *pb.ColdTablet {
+	if !s.HasCold() {
+		return nil
+	}
+	s.RLock()
+	defer s.RUnlock()
+	return s.predicate[pred].GetCold()
+}
+
+// HasCold returns whether the data of any predicate was moved to object storage, or is being
+// moved there.
+func (s *state) HasCold() bool {
+	return s != nil && atomic.LoadInt32(&s.numCold) > 0
+}
+
 // IndexingInProgress checks whether indexing is going on for a given predicate.
 func (s *state) IndexingInProgress() bool {
 	s.RLock()
@@ -453,6 +562,25 @@ func Load(predicate string) error {
 	return nil
 }
 
+// LoadType reads the given type from the DB and stores it in memory.
+func LoadType(typeName string) error {
+	txn := pstore.NewTransactionAt(1, false)
+	defer txn.Discard()
+	item, err := txn.Get(x.TypeKey(typeName))
+	if err == badger.ErrKeyNotFound || err == badger.ErrBannedKey {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var t pb.TypeUpdate
+	if err := item.Value(t.Unmarshal); err != nil {
+		return err
+	}
+	State().SetType(typeName, t)
+	return nil
+}
+
 // LoadFromDb reads schema information from db and stores it in memory
 func LoadFromDb() error {
 	if err := LoadSchemaFromDb(); err != nil {
@@ -574,6 +702,34 @@ func initialTypesInternal(namespace uint64, all bool) []*pb.TypeUpdate {
 					ValueType: pb.Posting_STRING,
 				},
 			},
+		}, &pb.TypeUpdate{
+			TypeName: "dgraph.graphql.lambda",
+			Fields: []*pb.SchemaUpdate{
+				{
+					Predicate: "dgraph.graphql.lambda_scripts",
+					ValueType: pb.Posting_STRING,
+				},
+			},
+		}, &pb.TypeUpdate{
+			TypeName: "dgraph.schema_version",
+			Fields: []*pb.SchemaUpdate{
+				{
+					Predicate: "dgraph.schema_history",
+					ValueType: pb.Posting_STRING,
+				},
+			},
+		}, &pb.TypeUpdate{
+			TypeName: "dgraph.lineage",
+			Fields: []*pb.SchemaUpdate{
+				{
+					Predicate: "dgraph.lineage.blob",
+					ValueType: pb.Posting_STRING,
+				},
+				{
+					Predicate: "dgraph.lineage.subject",
+					ValueType: pb.Posting_UID,
+				},
+			},
 		})
 
 	if all || x.WorkerConfig.AclEnabled {
@@ -676,6 +832,39 @@ func initialSchemaInternal(namespace uint64, all bool) []*pb.SchemaUpdate {
 			ValueType: pb.Posting_STRING,
 			Directive: pb.SchemaUpdate_INDEX,
 			Tokenizer: []string{"sha256"},
+		}, &pb.SchemaUpdate{
+			Predicate: "dgraph.graphql.lambda_scripts",
+			ValueType: pb.Posting_STRING,
+		}, &pb.SchemaUpdate{
+			Predicate: "dgraph.schema_history",
+			ValueType: pb.Posting_STRING,
+		}, &pb.SchemaUpdate{
+			Predicate: "dgraph.lineage.blob",
+			ValueType: pb.Posting_STRING,
+		}, &pb.SchemaUpdate{
+			Predicate: "dgraph.lineage.subject",
+			ValueType: pb.Posting_UID,
+			Directive: pb.SchemaUpdate_REVERSE,
+			List:      true,
+		}, &pb.SchemaUpdate{
+			Predicate: "dgraph.edge.src",
+			ValueType: pb.Posting_UID,
+			Directive: pb.SchemaUpdate_REVERSE,
+		}, &pb.SchemaUpdate{
+			Predicate: "dgraph.edge.dst",
+			ValueType: pb.Posting_UID,
+			Directive: pb.SchemaUpdate_REVERSE,
+		}, &pb.SchemaUpdate{
+			Predicate: "dgraph.edge.pred",
+			ValueType: pb.Posting_STRING,
+			Directive: pb.SchemaUpdate_INDEX,
+			Tokenizer: []string{"exact"},
+		}, &pb.SchemaUpdate{
+			Predicate: "dgraph.edge.key",
+			ValueType: pb.Posting_STRING,
+			Directive: pb.SchemaUpdate_INDEX,
+			Tokenizer: []string{"exact"},
+			Upsert:    true,
 		})
 
 	if all || x.WorkerConfig.AclEnabled {
@@ -329,13 +329,18 @@ func runRestore(t *testing.T, lastDir string, commitTs uint64) map[string]string
 	restoredPreds, err := testutil.GetPredicateNames(pdir)
 	require.NoError(t, err)
 	require.ElementsMatch(t, []string{"dgraph.graphql.schema", "dgraph.graphql.xid", "dgraph.type",
-		"movie", "dgraph.graphql.p_query", "dgraph.drop.op"},
+		"movie", "dgraph.graphql.p_query", "dgraph.drop.op", "dgraph.graphql.lambda_scripts",
+		"dgraph.schema_history", "dgraph.lineage.blob", "dgraph.lineage.subject",
+		"dgraph.edge.src", "dgraph.edge.dst", "dgraph.edge.pred",
+		"dgraph.edge.key"},
 		restoredPreds)
 
 	restoredTypes, err := testutil.GetTypeNames(pdir)
 	require.NoError(t, err)
 	require.ElementsMatch(t, []string{"Node", "dgraph.graphql",
-		"dgraph.graphql.persisted_query"}, restoredTypes)
+		"dgraph.graphql.persisted_query", "dgraph.graphql.lambda", "dgraph.schema_version",
+		"dgraph.lineage"},
+		restoredTypes)
 
 	require.NoError(t, err)
 	t.Logf("--- Restored values: %+v\n", restored)
@@ -356,7 +361,7 @@ func runFailingRestore(t *testing.T, backupLocation, lastDir string, commitTs ui
 	require.NotNil(t, k)
 	require.NoError(t, err)
 
-	result := worker.RunRestore("./data/restore", backupLocation, lastDir, k, options.Snappy, 0)
+	result := worker.RunRestore("./data/restore", backupLocation, lastDir, k, nil, nil, options.Snappy, 0)
 	require.Error(t, result.Err)
 	require.Contains(t, result.Err.Error(), "expected a BackupNum value of 1")
 }
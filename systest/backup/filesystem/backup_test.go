@@ -122,6 +122,64 @@ func TestBackupOfOldRestore(t *testing.T) {
 	require.JSONEq(t, "{\"authors\":[{\"count\":1}]}", string(resp.Json))
 }
 
+// This test erases a subject after a backup, restores the backup and checks that the erasure
+// is applied again, so that the restore doesn't bring the erased data back.
+func TestErasureAfterRestore(t *testing.T) {
+	common.DirSetup(t)
+
+	conn, err := grpc.Dial(testutil.SockAddr, grpc.WithTransportCredentials(credentials.NewTLS(testutil.GetAlphaClientConfig(t))))
+	require.NoError(t, err)
+	dg := dgo.NewDgraphClient(api.NewDgraphClient(conn))
+
+	testutil.DropAll(t, dg)
+	time.Sleep(2 * time.Second)
+
+	ctx := context.Background()
+	require.NoError(t, dg.Alter(ctx, &api.Operation{Schema: `name: string @index(exact) .`}))
+	resp, err := dg.NewTxn().Mutate(ctx, &api.Mutation{
+		SetNquads: []byte(`_:alice <name> "Alice" .
+			_:bob <name> "Bob" .`),
+		CommitNow: true,
+	})
+	require.NoError(t, err)
+	_ = runBackup(t, 3, 1)
+
+	params := testutil.GraphQLParams{
+		Query: `mutation erase($subject: String!) {
+			eraseSubject(input: {subject: $subject}) {
+				response {
+					code
+				}
+			}
+		}`,
+		Variables: map[string]interface{}{"subject": resp.Uids["alice"]},
+	}
+	gqlResp := testutil.MakeGQLRequestWithTLS(t, &params, testutil.GetAlphaClientConfig(t))
+	gqlResp.RequireNoGraphQLErrors(t)
+
+	names := func() string {
+		resp, err := dg.NewReadOnlyTxn().Query(ctx,
+			`{ q(func: has(name), orderasc: name) { name } }`)
+		require.NoError(t, err)
+		return string(resp.Json)
+	}
+	require.JSONEq(t, `{"q": [{"name": "Bob"}]}`, names())
+
+	sendRestoreRequest(t, alphaBackupDir)
+	testutil.WaitForRestore(t, dg)
+
+	// The erasure is applied again once the restore is done.
+	for i := 0; i < 30; i++ {
+		if names() == `{"q":[{"name":"Bob"}]}` {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	require.JSONEq(t, `{"q": [{"name": "Bob"}]}`, names())
+
+	common.DirCleanup(t)
+}
+
 func TestBackupFilesystem(t *testing.T) {
 	conn, err := grpc.Dial(testutil.SockAddr, grpc.WithTransportCredentials(credentials.NewTLS(testutil.GetAlphaClientConfig(t))))
 	require.NoError(t, err)
@@ -191,8 +249,12 @@ func TestBackupFilesystem(t *testing.T) {
 	// Check the predicates and types in the schema are as expected.
 	// TODO: refactor tests so that minio and filesystem tests share most of their logic.
 	preds := []string{"dgraph.graphql.schema", "name", "dgraph.graphql.xid", "dgraph.type",
-		"movie", "dgraph.graphql.p_query", "dgraph.drop.op"}
-	types := []string{"Node", "dgraph.graphql", "dgraph.graphql.persisted_query"}
+		"movie", "dgraph.graphql.p_query", "dgraph.drop.op", "dgraph.graphql.lambda_scripts",
+		"dgraph.schema_history", "dgraph.lineage.blob", "dgraph.lineage.subject",
+		"dgraph.edge.src", "dgraph.edge.dst", "dgraph.edge.pred",
+		"dgraph.edge.key"}
+	types := []string{"Node", "dgraph.graphql", "dgraph.graphql.persisted_query",
+		"dgraph.graphql.lambda", "dgraph.schema_version", "dgraph.lineage"}
 	testutil.CheckSchema(t, preds, types)
 
 	verifyUids := func(count int) {
@@ -423,7 +485,8 @@ func runRestore(t *testing.T, backupLocation, lastDir string, commitTs uint64) m
 	require.NoError(t, os.RemoveAll(restoreDir))
 
 	t.Logf("--- Restoring from: %q", backupLocation)
-	result := worker.RunRestore("./data/restore", backupLocation, lastDir, x.SensitiveByteSlice(nil), options.Snappy, 0)
+	result := worker.RunRestore("./data/restore", backupLocation, lastDir, x.SensitiveByteSlice(nil), nil, nil,
+		options.Snappy, 0)
 	require.NoError(t, result.Err)
 
 	for i, pdir := range []string{"p1", "p2", "p3"} {
@@ -123,8 +123,12 @@ func TestBackupMinio(t *testing.T) {
 	// Check the predicates and types in the schema are as expected.
 	// TODO: refactor tests so that minio and filesystem tests share most of their logic.
 	preds := []string{"dgraph.graphql.schema", "dgraph.graphql.xid", "dgraph.type", "movie",
-		"dgraph.graphql.p_query", "dgraph.drop.op"}
-	types := []string{"Node", "dgraph.graphql", "dgraph.graphql.persisted_query"}
+		"dgraph.graphql.p_query", "dgraph.drop.op", "dgraph.graphql.lambda_scripts",
+		"dgraph.schema_history", "dgraph.lineage.blob", "dgraph.lineage.subject",
+		"dgraph.edge.src", "dgraph.edge.dst", "dgraph.edge.pred",
+		"dgraph.edge.key"}
+	types := []string{"Node", "dgraph.graphql", "dgraph.graphql.persisted_query",
+		"dgraph.graphql.lambda", "dgraph.schema_version", "dgraph.lineage"}
 	testutil.CheckSchema(t, preds, types)
 
 	checks := []struct {
@@ -360,7 +364,8 @@ func runFailingRestore(t *testing.T, backupLocation, lastDir string, commitTs ui
 	// calling restore.
 	require.NoError(t, os.RemoveAll(restoreDir))
 
-	result := worker.RunRestore("./data/restore", backupLocation, lastDir, x.SensitiveByteSlice(nil), options.Snappy, 0)
+	result := worker.RunRestore("./data/restore", backupLocation, lastDir, x.SensitiveByteSlice(nil), nil, nil,
+		options.Snappy, 0)
 	require.Error(t, result.Err)
 	require.Contains(t, result.Err.Error(), "expected a BackupNum value of 1")
 }
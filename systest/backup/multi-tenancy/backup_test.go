@@ -116,11 +116,15 @@ func TestBackupMultiTenancy(t *testing.T) {
 	restored := runRestore(t, copyBackupDir, "", math.MaxUint64, []uint64{x.GalaxyNamespace, ns})
 
 	preds := []string{"dgraph.graphql.schema", "name", "dgraph.graphql.xid", "dgraph.type", "movie",
-		"dgraph.graphql.p_query", "dgraph.drop.op", "dgraph.xid", "dgraph.acl.rule",
-		"dgraph.password", "dgraph.user.group", "dgraph.rule.predicate", "dgraph.rule.permission"}
+		"dgraph.graphql.p_query", "dgraph.drop.op", "dgraph.graphql.lambda_scripts", "dgraph.xid",
+		"dgraph.schema_history", "dgraph.lineage.blob", "dgraph.lineage.subject",
+		"dgraph.edge.src", "dgraph.edge.dst", "dgraph.edge.pred",
+		"dgraph.edge.key", "dgraph.acl.rule", "dgraph.password", "dgraph.user.group",
+		"dgraph.rule.predicate", "dgraph.rule.permission"}
 	preds = append(preds, preds...)
 	types := []string{"Node", "dgraph.graphql", "dgraph.graphql.persisted_query",
-		"dgraph.type.Rule", "dgraph.type.User", "dgraph.type.Group"} // ACL
+		"dgraph.graphql.lambda", "dgraph.schema_version", "dgraph.lineage", "dgraph.type.Rule",
+		"dgraph.type.User", "dgraph.type.Group"} // ACL
 	types = append(types, types...)
 	testutil.CheckSchema(t, preds, types)
 
@@ -371,7 +375,8 @@ func runRestore(t *testing.T, backupLocation, lastDir string, commitTs uint64,
 	require.NoError(t, os.RemoveAll(restoreDir))
 
 	t.Logf("--- Restoring from: %q", backupLocation)
-	result := worker.RunRestore("./data/restore", backupLocation, lastDir, x.SensitiveByteSlice(nil), options.Snappy, 0)
+	result := worker.RunRestore("./data/restore", backupLocation, lastDir, x.SensitiveByteSlice(nil), nil, nil,
+		options.Snappy, 0)
 	require.NoError(t, result.Err)
 
 	for i, pdir := range []string{"p1", "p2", "p3"} {
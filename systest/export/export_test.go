@@ -58,7 +58,8 @@ func TestExportSchemaToMinio(t *testing.T) {
 	for _, f := range getFromJSON(result, "data", "export", "exportedFiles").([]interface{}) {
 		files = append(files, f.(string))
 	}
-	require.Equal(t, 3, len(files))
+	require.Equal(t, 4, len(files))
+	require.Contains(t, files[3], ".manifest.json.gz")
 
 	schemaFile := files[1]
 	require.Contains(t, schemaFile, ".schema.gz")
@@ -82,6 +83,14 @@ var expectedSchema = `[0x0] <movie>:string .` + " " + `
 [0x0] <dgraph.graphql.xid>:string @index(exact) @upsert .` + " " + `
 [0x0] <dgraph.graphql.schema>:string .` + " " + `
 [0x0] <dgraph.graphql.p_query>:string @index(sha256) .` + " " + `
+[0x0] <dgraph.graphql.lambda_scripts>:string .` + " " + `
+[0x0] <dgraph.schema_history>:string .` + " " + `
+[0x0] <dgraph.lineage.blob>:string .` + " " + `
+[0x0] <dgraph.lineage.subject>:[uid] @reverse .` + " " + `
+[0x0] <dgraph.edge.src>:uid @reverse .` + " " + `
+[0x0] <dgraph.edge.dst>:uid @reverse .` + " " + `
+[0x0] <dgraph.edge.pred>:string @index(exact) .` + " " + `
+[0x0] <dgraph.edge.key>:string @index(exact) @upsert .` + " " + `
 [0x0] type <Node> {
 	movie
 }
@@ -89,9 +98,19 @@ var expectedSchema = `[0x0] <movie>:string .` + " " + `
 	dgraph.graphql.schema
 	dgraph.graphql.xid
 }
+[0x0] type <dgraph.graphql.lambda> {
+	dgraph.graphql.lambda_scripts
+}
 [0x0] type <dgraph.graphql.persisted_query> {
 	dgraph.graphql.p_query
 }
+[0x0] type <dgraph.lineage> {
+	dgraph.lineage.blob
+	dgraph.lineage.subject
+}
+[0x0] type <dgraph.schema_version> {
+	dgraph.schema_history
+}
 `
 
 func setupDgraph(t *testing.T) {
@@ -22,6 +22,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -168,6 +169,98 @@ func TestDeleteNamespace(t *testing.T) {
 	require.Contains(t, err.Error(), "Cannot delete default namespace")
 }
 
+func TestCloneNamespace(t *testing.T) {
+	prepare(t)
+	galaxyToken := testutil.Login(t,
+		&testutil.LoginParams{UserID: "groot", Passwd: "password", Namespace: x.GalaxyNamespace})
+	ns, err := testutil.CreateNamespace(t, galaxyToken)
+	require.NoError(t, err)
+	dc := testutil.DgClientWithLogin(t, "groot", "password", ns)
+
+	require.NoError(t, dc.Alter(context.Background(), &api.Operation{Schema: `
+		name: string @index(exact) .
+		friend: [uid] @reverse @count .
+		type Person {
+			name
+			friend
+		}`}))
+	// Every edge carries a large facet, so that the posting list of friend is split when it is
+	// rolled up.
+	var rdfs strings.Builder
+	rdfs.WriteString(`_:a <name> "alice" .
+		_:a <dgraph.type> "Person" .
+	`)
+	note := strings.Repeat("x", 1<<10)
+	const numFriends = 600
+	for i := 0; i < numFriends; i++ {
+		fmt.Fprintf(&rdfs, "_:a <friend> _:f%d (note=%q) .\n", i, note)
+		fmt.Fprintf(&rdfs, "_:f%d <name> \"f%d\" .\n", i, i)
+	}
+	_, err = dc.NewTxn().Mutate(context.Background(), &api.Mutation{
+		SetNquads: []byte(rdfs.String()),
+		CommitNow: true,
+	})
+	require.NoError(t, err)
+
+	clone, err := testutil.CloneNamespace(t, galaxyToken, ns)
+	require.NoError(t, err)
+	require.NotEqual(t, ns, clone)
+	// The users are cloned along with the data.
+	cc := testutil.DgClientWithLogin(t, "groot", "password", clone)
+
+	for _, q := range []string{
+		`schema(pred: [name, friend]) { type index tokenizer reverse count list }`,
+		`schema(type: Person) {}`,
+	} {
+		resp := string(testutil.QueryData(t, dc, q))
+		require.Contains(t, resp, "friend")
+		testutil.CompareJSON(t, resp, string(testutil.QueryData(t, cc, q)))
+	}
+
+	query := fmt.Sprintf(`{
+		byName(func: eq(name, "alice")) {
+			name
+			count(friend)
+		}
+		byType(func: type(Person)) {
+			name
+		}
+		byCount(func: eq(count(friend), %d)) {
+			name
+		}
+		reverse(func: eq(name, "f42")) {
+			~friend {
+				name
+			}
+		}
+		facet(func: eq(name, "alice")) {
+			friend @facets(eq(note, %q)) @filter(eq(name, "f7")) {
+				name
+			}
+		}
+	}`, numFriends, note)
+	expected := fmt.Sprintf(`{
+		"byName": [{"name": "alice", "count(friend)": %d}],
+		"byType": [{"name": "alice"}],
+		"byCount": [{"name": "alice"}],
+		"reverse": [{"~friend": [{"name": "alice"}]}],
+		"facet": [{"friend": [{"name": "f7"}]}]
+	}`, numFriends)
+	testutil.CompareJSON(t, expected, string(testutil.QueryData(t, dc, query)))
+	testutil.CompareJSON(t, expected, string(testutil.QueryData(t, cc, query)))
+
+	// The clone is independent of the namespace it was cloned from.
+	_, err = cc.NewTxn().Mutate(context.Background(), &api.Mutation{
+		SetNquads: []byte(`_:b <name> "alice" .`),
+		CommitNow: true,
+	})
+	require.NoError(t, err)
+	resp := testutil.QueryData(t, cc, `{ q(func: eq(name, "alice")) { count(uid) } }`)
+	testutil.CompareJSON(t, `{"q": [{"count": 2}]}`, string(resp))
+	resp = testutil.QueryData(t, dc, `{ q(func: eq(name, "alice")) { count(uid) } }`)
+	testutil.CompareJSON(t, `{"q": [{"count": 1}]}`, string(resp))
+}
+
 type liveOpts struct {
 	rdfs      string
 	schema    string
@@ -52,6 +52,7 @@ func TestQuery(t *testing.T) {
 	t.Run("unmatched var assignment eval", wrap(UnmatchedVarEval))
 	t.Run("hash index queries", wrap(QueryHashIndex))
 	t.Run("fuzzy matching", wrap(FuzzyMatch))
+	t.Run("fuzzy function", wrap(FuzzyFunction))
 	t.Run("regexp with toggled trigram index", wrap(RegexpToggleTrigramIndex))
 	t.Run("eq with altering order of trigram and term index", wrap(EqWithAlteredIndexOrder))
 	t.Run("groupby uid that works", wrap(GroupByUidWorks))
@@ -378,6 +379,30 @@ func SchemaQueryTestPredicate1(t *testing.T, c *dgo.Dgraph) {
 	  {
 		"predicate": "dgraph.graphql.p_query"
 	  },
+	  {
+		"predicate": "dgraph.graphql.lambda_scripts"
+	  },
+	  {
+		"predicate": "dgraph.schema_history"
+	  },
+	  {
+		"predicate": "dgraph.lineage.blob"
+	  },
+	  {
+		"predicate": "dgraph.lineage.subject"
+	  },
+	  {
+		"predicate": "dgraph.edge.src"
+	  },
+	  {
+		"predicate": "dgraph.edge.dst"
+	  },
+	  {
+		"predicate": "dgraph.edge.pred"
+	  },
+	  {
+		"predicate": "dgraph.edge.key"
+	  },
       {
         "predicate": "dgraph.xid"
 	  },
@@ -697,6 +722,87 @@ func FuzzyMatch(t *testing.T, c *dgo.Dgraph) {
 	}
 }
 
+func FuzzyFunction(t *testing.T, c *dgo.Dgraph) {
+	ctx := context.Background()
+
+	op := &api.Operation{
+		Schema: `
+      term: string @index(trigram) .
+      name: string .
+    `,
+	}
+	require.NoError(t, c.Alter(ctx, op))
+
+	txn := c.NewTxn()
+	_, err := txn.Mutate(ctx, &api.Mutation{
+		SetNquads: []byte(`
+      _:t0 <term> "" .
+      _:t1 <term> "road" .
+      _:t2 <term> "route" .
+      _:t3 <term> "lane" .
+      _:t4 <term> "pathway" .
+      _:t5 <term> "parkway" .
+      _:t6 <term> "café" .
+      _:t7 <term> "dual carriageway" .
+      _:n0 <name> "srfrog" .
+    `),
+	})
+	require.NoError(t, err)
+	require.NoError(t, txn.Commit(ctx))
+
+	tests := []struct {
+		in, out, failure string
+	}{
+		{
+			in:  `{q(func:fuzzy(term, "lane", 0)) {term}}`,
+			out: `{"q":[{"term":"lane"}]}`,
+		},
+		{
+			// lame and lane share no trigram, which match requires.
+			in:  `{q(func:fuzzy(term, "lame", 1)) {term}}`,
+			out: `{"q":[{"term":"lane"}]}`,
+		},
+		{
+			in:  `{q(func:fuzzy(term, "rode", 2)) {term}}`,
+			out: `{"q":[{"term":"road"},{"term":"route"}]}`,
+		},
+		{
+			in:  `{q(func:fuzzy(term, "parcway", 1)) {term}}`,
+			out: `{"q":[{"term":"parkway"}]}`,
+		},
+		{
+			in:  `{q(func:fuzzy(term, "parcway", 2)) {term}}`,
+			out: `{"q":[{"term":"pathway"},{"term":"parkway"}]}`,
+		},
+		{
+			in:  `{q(func:fuzzy(term, "cafe", 1)) {term}}`,
+			out: `{"q":[{"term":"café"}]}`,
+		},
+		{
+			in:  `{q(func:fuzzy(term, "carriageway", 5)) {term}}`,
+			out: `{"q":[{"term":"dual carriageway"}]}`,
+		},
+		{
+			in:  `{q(func:has(term)) @filter(fuzzy(term, "lame", 1)) {term}}`,
+			out: `{"q":[{"term":"lane"}]}`,
+		},
+		{
+			in:      `{q(func:fuzzy(name, "someone", 8)) {name}}`,
+			failure: `Attribute name is not indexed with type trigram`,
+		},
+	}
+	for _, tc := range tests {
+		resp, err := c.NewTxn().Query(ctx, tc.in)
+		if tc.failure != "" {
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.failure)
+			continue
+		}
+		require.NoError(t, err)
+		testutil.CompareJSON(t, tc.out, string(resp.Json))
+	}
+}
+
 func CascadeParams(t *testing.T, c *dgo.Dgraph) {
 
 	ctx := context.Background()
@@ -121,6 +121,35 @@ func DeleteNamespace(t *testing.T, token *HttpToken, nsID uint64) error {
 	return nil
 }
 
+func CloneNamespace(t *testing.T, token *HttpToken, nsID uint64) (uint64, error) {
+	cloneReq := `mutation cloneNamespace($namespaceId: Int!) {
+			cloneNamespace(input: {namespaceId: $namespaceId}){
+			namespaceId
+			message
+		}
+	}`
+
+	params := GraphQLParams{
+		Query: cloneReq,
+		Variables: map[string]interface{}{
+			"namespaceId": nsID,
+		},
+	}
+	resp := MakeRequest(t, token, params)
+	if len(resp.Errors) > 0 {
+		return 0, errors.Errorf(resp.Errors.Error())
+	}
+	var result struct {
+		CloneNamespace struct {
+			NamespaceId int    `json:"namespaceId"`
+			Message     string `json:"message"`
+		}
+	}
+	require.NoError(t, json.Unmarshal(resp.Data, &result))
+	require.Contains(t, result.CloneNamespace.Message, "Cloned namespace")
+	return uint64(result.CloneNamespace.NamespaceId), nil
+}
+
 func CreateUser(t *testing.T, token *HttpToken, username,
 	password string) {
 	addUser := `
@@ -37,9 +37,17 @@ const (
 	otherInternalPreds = `
 {"predicate":"dgraph.type","type":"string","index":true,"tokenizer":["exact"],"list":true},
 {"predicate":"dgraph.drop.op", "type": "string"},
+{"predicate":"dgraph.edge.dst","type":"uid","reverse":true},
+{"predicate":"dgraph.edge.key","type":"string","index":true,"tokenizer":["exact"],"upsert":true},
+{"predicate":"dgraph.edge.pred","type":"string","index":true,"tokenizer":["exact"]},
+{"predicate":"dgraph.edge.src","type":"uid","reverse":true},
+{"predicate":"dgraph.graphql.lambda_scripts", "type": "string"},
 {"predicate":"dgraph.graphql.p_query","type":"string","index":true,"tokenizer":["sha256"]},
 {"predicate":"dgraph.graphql.schema", "type": "string"},
-{"predicate":"dgraph.graphql.xid","type":"string","index":true,"tokenizer":["exact"],"upsert":true}
+{"predicate":"dgraph.graphql.xid","type":"string","index":true,"tokenizer":["exact"],"upsert":true},
+{"predicate":"dgraph.lineage.blob", "type": "string"},
+{"predicate":"dgraph.lineage.subject","type":"uid","list":true,"reverse":true},
+{"predicate":"dgraph.schema_history", "type": "string"}
 `
 	aclTypes = `
 {
@@ -57,9 +65,18 @@ const (
 {
 	"fields": [{"name": "dgraph.graphql.schema"},{"name": "dgraph.graphql.xid"}],
 	"name": "dgraph.graphql"
+},{
+	"fields": [{"name": "dgraph.graphql.lambda_scripts"}],
+	"name": "dgraph.graphql.lambda"
 },{
 	"fields": [{"name": "dgraph.graphql.p_query"}],
 	"name": "dgraph.graphql.persisted_query"
+},{
+	"fields": [{"name": "dgraph.lineage.blob"},{"name": "dgraph.lineage.subject"}],
+	"name": "dgraph.lineage"
+},{
+	"fields": [{"name": "dgraph.schema_history"}],
+	"name": "dgraph.schema_version"
 }
 `
 )
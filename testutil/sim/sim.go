@@ -0,0 +1,482 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sim simulates a cluster of Raft nodes in a single process, with a simulated network
+// and a deterministic clock, so that the correctness of Raft and of the state machines built on
+// it can be tested reproducibly. The nodes run Raft the way Zero and Alpha do, on top of the
+// write-ahead log of Dgraph, and apply the committed entries to the state machines given by the
+// tests. The tests of Zero, for instance, apply them with the applyProposal of Zero.
+//
+// The simulation is single threaded and only moves forward when Step is called. Each step is a
+// tick of the clock, during which the messages due are delivered, and the nodes tick and handle
+// their updates. All the randomness, like the latency of the messages or the election timeouts,
+// comes from the seed, so the same seed always gives the same run.
+package sim
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/dgraph/raftwal"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+	"go.etcd.io/etcd/raft"
+	"go.etcd.io/etcd/raft/raftpb"
+)
+
+// StateMachine is the state replicated by the nodes.
+type StateMachine interface {
+	// Apply applies the data of the committed entry at the index. The entries without data,
+	// which the leaders append when they're elected, aren't applied.
+	Apply(index uint64, data []byte)
+}
+
+// Options are the options of a simulated cluster.
+type Options struct {
+	// Seed is the seed of all the randomness of the simulation.
+	Seed int64
+	// Nodes is the number of nodes of the cluster, which are numbered from 1. It's 3 if zero.
+	Nodes int
+	// Tick is the simulated time of a tick. It's 100ms if zero, like in Zero and Alpha.
+	Tick time.Duration
+	// ElectionTicks and HeartbeatTicks are the election timeout and the heartbeat interval, in
+	// ticks. They're 20 and 1 if zero, like in Zero and Alpha. The election timeouts are
+	// randomized between ElectionTicks and 2*ElectionTicks.
+	ElectionTicks  int
+	HeartbeatTicks int
+	// MinLatency and MaxLatency bound the time the messages take to be delivered, in ticks. They
+	// are 1 if zero. The messages are reordered if they're different.
+	MinLatency int
+	MaxLatency int
+	// DropRate is the probability of a message to be dropped.
+	DropRate float64
+	// NewStateMachine returns the state machine of the node. It's called whenever the node
+	// starts, and the restarted nodes apply all the committed entries again.
+	NewStateMachine func(id uint64) StateMachine
+	// Logf logs the events of the simulation, if set.
+	Logf func(format string, args ...interface{})
+}
+
+// Cluster is a simulated cluster.
+type Cluster struct {
+	opts      Options
+	rng       *rand.Rand
+	start     time.Time
+	ticks     uint64
+	seq       uint64
+	nodes     []*Node
+	queue     []message
+	cut       map[[2]uint64]bool
+	committed map[uint64]raftpb.Entry
+	err       error
+}
+
+// message is a message in flight.
+type message struct {
+	at, seq  uint64
+	from, to uint64
+	data     []byte
+}
+
+// Node is a node of a simulated cluster.
+type Node struct {
+	ID uint64
+
+	c       *Cluster
+	store   *raftwal.DiskStorage
+	rn      *raft.RawNode
+	sm      StateMachine
+	running bool
+	// elapsed is the number of ticks since the node heard from a leader, and timeout the number
+	// of ticks after which it campaigns.
+	elapsed, timeout int
+}
+
+// New returns a new cluster with all its nodes running. It must be closed once done.
+func New(opts Options) (*Cluster, error) {
+	if opts.Nodes == 0 {
+		opts.Nodes = 3
+	}
+	if opts.Tick == 0 {
+		opts.Tick = 100 * time.Millisecond
+	}
+	if opts.ElectionTicks == 0 {
+		opts.ElectionTicks = 20
+	}
+	if opts.HeartbeatTicks == 0 {
+		opts.HeartbeatTicks = 1
+	}
+	c := &Cluster{
+		opts:      opts,
+		rng:       rand.New(rand.NewSource(opts.Seed)),
+		start:     time.Unix(0, 0).UTC(),
+		cut:       make(map[[2]uint64]bool),
+		committed: make(map[uint64]raftpb.Entry),
+	}
+	c.SetLatency(opts.MinLatency, opts.MaxLatency)
+
+	peers := make([]raft.Peer, opts.Nodes)
+	for i := range peers {
+		peers[i].ID = uint64(i + 1)
+	}
+	for _, p := range peers {
+		store, err := raftwal.InitInMemory(nil)
+		if err != nil {
+			c.Close()
+			return nil, errors.Wrapf(err, "while creating the WAL of node %d", p.ID)
+		}
+		store.SetUint(raftwal.RaftId, p.ID)
+		n := &Node{ID: p.ID, c: c, store: store}
+		c.nodes = append(c.nodes, n)
+		if err := n.start(peers); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Close releases the resources of the cluster.
+func (c *Cluster) Close() {
+	for _, n := range c.nodes {
+		if err := n.store.Close(); err != nil {
+			c.logf("closing the WAL of node %d: %v", n.ID, err)
+		}
+	}
+}
+
+func (c *Cluster) logf(format string, args ...interface{}) {
+	if c.opts.Logf != nil {
+		c.opts.Logf("[tick %d] "+format, append([]interface{}{c.ticks}, args...)...)
+	}
+}
+
+// fail records the first violation of the guarantees of Raft.
+func (c *Cluster) fail(err error) {
+	c.logf("%v", err)
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// Err returns the first violation of the guarantees of Raft, i.e. two nodes applying different
+// entries at the same index, or nil.
+func (c *Cluster) Err() error {
+	return c.err
+}
+
+// Now returns the simulated time.
+func (c *Cluster) Now() time.Time {
+	return c.start.Add(time.Duration(c.ticks) * c.opts.Tick)
+}
+
+// Ticks returns the number of ticks since the cluster started.
+func (c *Cluster) Ticks() uint64 {
+	return c.ticks
+}
+
+// Node returns the node with the ID, or nil.
+func (c *Cluster) Node(id uint64) *Node {
+	if id == 0 || id > uint64(len(c.nodes)) {
+		return nil
+	}
+	return c.nodes[id-1]
+}
+
+// Nodes returns all the nodes, ordered by ID.
+func (c *Cluster) Nodes() []*Node {
+	return c.nodes
+}
+
+// Leader returns the ID of the running leader with the highest term, or 0 if there's none.
+func (c *Cluster) Leader() uint64 {
+	var leader, term uint64
+	for _, n := range c.nodes {
+		if !n.running {
+			continue
+		}
+		if st := n.rn.Status(); st.RaftState == raft.StateLeader && st.Term > term {
+			leader, term = n.ID, st.Term
+		}
+	}
+	return leader
+}
+
+// SetLatency sets the bounds of the time the messages take to be delivered, in ticks.
+func (c *Cluster) SetLatency(min, max int) {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	c.opts.MinLatency, c.opts.MaxLatency = min, max
+}
+
+// SetDropRate sets the probability of a message to be dropped.
+func (c *Cluster) SetDropRate(rate float64) {
+	c.opts.DropRate = rate
+}
+
+// Partition splits the network into the groups of nodes. The nodes of different groups can't
+// talk to each other, and the nodes which aren't in any group are isolated.
+func (c *Cluster) Partition(groups ...[]uint64) {
+	group := make(map[uint64]int)
+	for i, g := range groups {
+		for _, id := range g {
+			group[id] = i + 1
+		}
+	}
+	c.cut = make(map[[2]uint64]bool)
+	for _, a := range c.nodes {
+		for _, b := range c.nodes {
+			if ga, gb := group[a.ID], group[b.ID]; a != b && (ga == 0 || ga != gb) {
+				c.cut[[2]uint64{a.ID, b.ID}] = true
+			}
+		}
+	}
+	c.logf("partition %v", groups)
+}
+
+// Heal removes the partitions of the network.
+func (c *Cluster) Heal() {
+	c.cut = make(map[[2]uint64]bool)
+	c.logf("heal")
+}
+
+// Propose proposes the data to the node, which forwards it to its leader. The proposal is lost
+// if the node has no leader, or if the entry doesn't get committed.
+func (c *Cluster) Propose(id uint64, data []byte) error {
+	n := c.Node(id)
+	if n == nil || !n.running {
+		return errors.Errorf("node %d isn't running", id)
+	}
+	return n.rn.Propose(data)
+}
+
+// Stop crashes the node. Its write-ahead log is kept.
+func (c *Cluster) Stop(id uint64) {
+	if n := c.Node(id); n != nil && n.running {
+		n.running, n.rn, n.sm = false, nil, nil
+		c.logf("node %d stopped", id)
+	}
+}
+
+// Restart restarts the stopped node from its write-ahead log.
+func (c *Cluster) Restart(id uint64) error {
+	n := c.Node(id)
+	if n == nil || n.running {
+		return errors.Errorf("node %d isn't stopped", id)
+	}
+	c.logf("node %d restarted", id)
+	return n.start(nil)
+}
+
+// Run runs the simulation for the number of ticks.
+func (c *Cluster) Run(ticks int) {
+	for i := 0; i < ticks; i++ {
+		c.Step()
+	}
+}
+
+// RunUntil runs the simulation until cond holds, for at most maxTicks. It returns whether cond
+// holds.
+func (c *Cluster) RunUntil(cond func() bool, maxTicks int) bool {
+	for i := 0; i < maxTicks; i++ {
+		if cond() {
+			return true
+		}
+		c.Step()
+	}
+	return cond()
+}
+
+// Step runs the simulation for one tick.
+func (c *Cluster) Step() {
+	c.handleReady()
+	c.ticks++
+	c.deliver()
+	for _, n := range c.nodes {
+		if !n.running {
+			continue
+		}
+		n.rn.Tick()
+		if n.rn.Status().RaftState == raft.StateLeader {
+			n.elapsed = 0
+			continue
+		}
+		if n.elapsed++; n.elapsed >= n.timeout {
+			c.logf("node %d campaigns", n.ID)
+			if err := n.rn.Campaign(); err != nil {
+				c.logf("node %d can't campaign: %v", n.ID, err)
+			}
+			n.resetTimeout()
+		}
+	}
+	c.handleReady()
+}
+
+// handleReady handles the updates of the nodes until they have none.
+func (c *Cluster) handleReady() {
+	for more := true; more; {
+		more = false
+		for _, n := range c.nodes {
+			if n.running && n.rn.HasReady() {
+				n.handleReady()
+				more = true
+			}
+		}
+	}
+}
+
+// send puts the message in flight, unless it's lost.
+func (c *Cluster) send(m raftpb.Message) {
+	if c.opts.DropRate > 0 && c.rng.Float64() < c.opts.DropRate {
+		c.logf("dropped %s from %d to %d", m.Type, m.From, m.To)
+		return
+	}
+	data, err := m.Marshal()
+	x.Check(err)
+	c.seq++
+	latency := c.opts.MinLatency + c.rng.Intn(c.opts.MaxLatency-c.opts.MinLatency+1)
+	c.queue = append(c.queue, message{
+		at: c.ticks + uint64(latency), seq: c.seq, from: m.From, to: m.To, data: data})
+}
+
+// deliver delivers the messages due, in the order they arrive.
+func (c *Cluster) deliver() {
+	var due, rest []message
+	for _, m := range c.queue {
+		if m.at <= c.ticks {
+			due = append(due, m)
+		} else {
+			rest = append(rest, m)
+		}
+	}
+	c.queue = rest
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].at != due[j].at {
+			return due[i].at < due[j].at
+		}
+		return due[i].seq < due[j].seq
+	})
+
+	for _, m := range due {
+		n := c.Node(m.to)
+		if n == nil || !n.running || c.cut[[2]uint64{m.from, m.to}] {
+			continue
+		}
+		var msg raftpb.Message
+		x.Check(msg.Unmarshal(m.data))
+		switch msg.Type {
+		case raftpb.MsgApp, raftpb.MsgHeartbeat, raftpb.MsgSnap:
+			n.elapsed = 0
+		}
+		if err := n.rn.Step(msg); err != nil {
+			c.logf("node %d can't step %s from %d: %v", n.ID, msg.Type, msg.From, err)
+		}
+	}
+}
+
+// check checks that the entry is the same as the one the other nodes applied at its index.
+func (c *Cluster) check(n *Node, e raftpb.Entry) {
+	prev, ok := c.committed[e.Index]
+	if !ok {
+		c.committed[e.Index] = e
+		return
+	}
+	if prev.Term != e.Term || prev.Type != e.Type || !bytes.Equal(prev.Data, e.Data) {
+		c.fail(errors.Errorf("node %d applied the entry of term %d at index %d, but the entry "+
+			"of term %d was applied there before", n.ID, e.Term, e.Index, prev.Term))
+	}
+}
+
+func (n *Node) start(peers []raft.Peer) error {
+	rn, err := raft.NewRawNode(&raft.Config{
+		ID: n.ID,
+		// The simulation times out the elections itself, so that they're deterministic.
+		ElectionTick:             1 << 30,
+		HeartbeatTick:            n.c.opts.HeartbeatTicks,
+		Storage:                  n.store,
+		MaxInflightMsgs:          256,
+		MaxSizePerMsg:            256 << 10,
+		MaxCommittedSizePerReady: 64 << 20,
+		ReadOnlyOption:           raft.ReadOnlySafe,
+		PreVote:                  true,
+		Logger:                   &x.ToGlog{},
+	}, peers)
+	if err != nil {
+		return errors.Wrapf(err, "while starting node %d", n.ID)
+	}
+	n.rn, n.running = rn, true
+	if n.c.opts.NewStateMachine != nil {
+		n.sm = n.c.opts.NewStateMachine(n.ID)
+	}
+	n.resetTimeout()
+	return nil
+}
+
+// resetTimeout restarts the election timeout with a new random duration.
+func (n *Node) resetTimeout() {
+	n.elapsed = 0
+	n.timeout = n.c.opts.ElectionTicks + n.c.rng.Intn(n.c.opts.ElectionTicks)
+}
+
+func (n *Node) handleReady() {
+	rd := n.rn.Ready()
+	if err := n.store.Save(&rd.HardState, rd.Entries, &rd.Snapshot); err != nil {
+		n.c.fail(errors.Wrapf(err, "while saving the Raft update of node %d", n.ID))
+	}
+	// Raft broadcasts in the random order of a map, which must not leak into the simulation.
+	sort.SliceStable(rd.Messages, func(i, j int) bool { return rd.Messages[i].To < rd.Messages[j].To })
+	for _, m := range rd.Messages {
+		n.c.send(m)
+	}
+	for _, e := range rd.CommittedEntries {
+		n.c.check(n, e)
+		switch e.Type {
+		case raftpb.EntryConfChange:
+			var cc raftpb.ConfChange
+			x.Check(cc.Unmarshal(e.Data))
+			n.rn.ApplyConfChange(cc)
+		case raftpb.EntryNormal:
+			if n.sm != nil && len(e.Data) > 0 {
+				n.sm.Apply(e.Index, e.Data)
+			}
+		}
+	}
+	n.rn.Advance(rd)
+}
+
+// Running returns whether the node is running.
+func (n *Node) Running() bool {
+	return n.running
+}
+
+// Status returns the Raft status of the running node.
+func (n *Node) Status() raft.Status {
+	if !n.running {
+		return raft.Status{}
+	}
+	return *n.rn.Status()
+}
+
+// StateMachine returns the state machine of the running node.
+func (n *Node) StateMachine() StateMachine {
+	return n.sm
+}
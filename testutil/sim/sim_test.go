@@ -0,0 +1,139 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sim
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// entries is a state machine keeping the data of the applied entries.
+type entries []string
+
+func (es *entries) Apply(index uint64, data []byte) {
+	*es = append(*es, string(data))
+}
+
+func newCluster(t *testing.T, opts Options) *Cluster {
+	opts.NewStateMachine = func(id uint64) StateMachine { return &entries{} }
+	c, err := New(opts)
+	require.NoError(t, err)
+	return c
+}
+
+func applied(c *Cluster, id uint64) []string {
+	return *c.Node(id).StateMachine().(*entries)
+}
+
+// converged returns whether all the running nodes applied the number of entries.
+func converged(c *Cluster, num int) func() bool {
+	return func() bool {
+		for _, n := range c.Nodes() {
+			if n.Running() && len(applied(c, n.ID)) != num {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func hasLeader(c *Cluster) func() bool {
+	return func() bool { return c.Leader() != 0 }
+}
+
+func TestReplication(t *testing.T) {
+	c := newCluster(t, Options{Seed: 1})
+	defer c.Close()
+
+	require.True(t, c.RunUntil(hasLeader(c), 1000))
+	for i := 0; i < 10; i++ {
+		require.NoError(t, c.Propose(uint64(i%3+1), []byte(fmt.Sprint(i))))
+		c.Step()
+	}
+	require.True(t, c.RunUntil(converged(c, 10), 1000))
+	for _, n := range c.Nodes() {
+		require.Equal(t, applied(c, 1), applied(c, n.ID))
+	}
+	require.NoError(t, c.Err())
+}
+
+// chaos runs a cluster through lossy network, partitions and crashes, and returns the entries
+// applied by the nodes in the end, and the events of the simulation.
+func chaos(t *testing.T, seed int64) ([]string, []string) {
+	var events []string
+	c := newCluster(t, Options{Seed: seed, Nodes: 5, MinLatency: 1, MaxLatency: 5, DropRate: 0.05,
+		Logf: func(format string, args ...interface{}) {
+			events = append(events, fmt.Sprintf(format, args...))
+		}})
+	defer c.Close()
+
+	require.True(t, c.RunUntil(hasLeader(c), 1000))
+	propose := func(ticks int) {
+		for i := 0; i < ticks; i++ {
+			if leader := c.Leader(); leader != 0 {
+				require.NoError(t, c.Propose(leader, []byte(fmt.Sprint(c.Ticks()))))
+			}
+			c.Step()
+		}
+	}
+	propose(50)
+
+	// Cut the leader away from the majority, then heal.
+	leader := c.Leader()
+	var rest []uint64
+	for _, n := range c.Nodes() {
+		if n.ID != leader {
+			rest = append(rest, n.ID)
+		}
+	}
+	c.Partition([]uint64{leader, rest[0]}, rest[1:])
+	propose(200)
+	c.Heal()
+	propose(50)
+
+	// Crash a node, and restart it.
+	c.Stop(rest[1])
+	propose(50)
+	require.NoError(t, c.Restart(rest[1]))
+	propose(50)
+
+	c.SetDropRate(0)
+	c.Run(10)
+	num := len(applied(c, c.Leader()))
+	require.Greater(t, num, 0)
+	require.True(t, c.RunUntil(converged(c, num), 1000))
+	for _, n := range c.Nodes() {
+		require.Equal(t, applied(c, 1), applied(c, n.ID))
+	}
+	require.NoError(t, c.Err())
+	return applied(c, 1), events
+}
+
+func TestChaos(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		chaos(t, seed)
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	applied1, events1 := chaos(t, 42)
+	applied2, events2 := chaos(t, 42)
+	require.Equal(t, applied1, applied2)
+	require.Equal(t, events1, events2)
+}
@@ -21,8 +21,8 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"io/ioutil"
+	"net"
 	"net/http"
-	"strings"
 
 	"github.com/dgraph-io/dgo/v200"
 	"github.com/dgraph-io/dgo/v200/protos/api"
@@ -130,12 +130,12 @@ func GetClientToGroup(gid string) (*dgo.Dgraph, error) {
 		member = m
 		break
 	}
-	parts := strings.Split(member.Addr, ":")
-	if len(parts) != 2 {
+	host, _, err := net.SplitHostPort(member.Addr)
+	if err != nil {
 		return nil, errors.Errorf("the member has an invalid address: %v", member.Addr)
 	}
 
-	addr := ContainerAddr(parts[0], 9080)
+	addr := ContainerAddr(host, 9080)
 	conn, err := grpc.Dial(addr, grpc.WithInsecure())
 	if err != nil {
 		return nil, err
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tok
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/blevesearch/bleve/analysis"
+	"github.com/pkg/errors"
+)
+
+// Dictionary holds the synonyms and the protected words of the fulltext index of a predicate.
+// The words of a group of synonyms are all indexed and searched as the first word of the group,
+// so that searching for any of them matches the values containing the others. Protected words
+// are neither dropped as stop words nor stemmed.
+type Dictionary struct {
+	synonyms  [][]string
+	protected map[string]struct{}
+	// canonical maps a language to the map from the terms of the synonyms in that language to
+	// the term their group is indexed as. It's filled lazily, as the terms depend on the stemmer.
+	canonical sync.Map
+}
+
+// NewDictionary returns the dictionary with the given synonyms and protected words, or nil if
+// both are empty. Every word must be a single term, every group must have at least two words,
+// and a word can't be in more than one group.
+func NewDictionary(synonyms [][]string, protected []string) (*Dictionary, error) {
+	if len(synonyms) == 0 && len(protected) == 0 {
+		return nil, nil
+	}
+	d := &Dictionary{protected: make(map[string]struct{})}
+	for _, word := range protected {
+		term, err := dictionaryTerm(word)
+		if err != nil {
+			return nil, err
+		}
+		d.protected[term] = struct{}{}
+	}
+	seen := make(map[string]struct{})
+	for _, group := range synonyms {
+		if len(group) < 2 {
+			return nil, errors.Errorf("Synonym group [%s] must have at least two words",
+				strings.Join(group, ", "))
+		}
+		for _, word := range group {
+			term, err := dictionaryTerm(word)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := seen[term]; ok {
+				return nil, errors.Errorf("Word %q is in more than one synonym group", word)
+			}
+			seen[term] = struct{}{}
+		}
+		d.synonyms = append(d.synonyms, group)
+	}
+	return d, nil
+}
+
+// dictionaryTerm returns the normalized form of a word of a dictionary.
+func dictionaryTerm(word string) (string, error) {
+	tokens := fulltextAnalyzer.Analyze([]byte(word))
+	if len(tokens) != 1 {
+		return "", errors.Errorf("Dictionary entry %q must be a single word", word)
+	}
+	return string(tokens[0].Term), nil
+}
+
+// splitProtected splits the tokens into the protected ones and the others.
+func (d *Dictionary) splitProtected(
+	tokens analysis.TokenStream) (analysis.TokenStream, analysis.TokenStream) {
+	var protected, rest analysis.TokenStream
+	for _, token := range tokens {
+		if _, ok := d.protected[string(token.Term)]; ok {
+			protected = append(protected, token)
+		} else {
+			rest = append(rest, token)
+		}
+	}
+	return protected, rest
+}
+
+// canonicalTokens replaces the terms that are synonyms with the term of their group.
+func (d *Dictionary) canonicalTokens(lang string, tokens analysis.TokenStream) {
+	if len(d.synonyms) == 0 {
+		return
+	}
+	canonical, ok := d.canonical.Load(lang)
+	if !ok {
+		canonical, _ = d.canonical.LoadOrStore(lang, d.synonymTerms(lang))
+	}
+	for _, token := range tokens {
+		if c, ok := canonical.(map[string]string)[string(token.Term)]; ok {
+			token.Term = []byte(c)
+		}
+	}
+}
+
+// synonymTerms returns the map from the terms of the synonyms in the given language to the
+// term of their group, which is the one of its first word that isn't a stop word.
+func (d *Dictionary) synonymTerms(lang string) map[string]string {
+	t := FullTextTokenizer{lang: lang, dict: &Dictionary{protected: d.protected}}
+	canonical := make(map[string]string)
+	for _, group := range d.synonyms {
+		var term string
+		for _, word := range group {
+			terms, err := t.Tokens(word)
+			if err != nil || len(terms) != 1 {
+				continue
+			}
+			if term == "" {
+				term = terms[0]
+			}
+			if _, ok := canonical[terms[0]]; !ok {
+				canonical[terms[0]] = term
+			}
+		}
+	}
+	return canonical
+}
@@ -22,6 +22,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/blevesearch/bleve/analysis"
 	"github.com/golang/glog"
 	geom "github.com/twpayne/go-geom"
 	"golang.org/x/crypto/blake2b"
@@ -53,6 +54,7 @@ const (
 	IdentTrigram   = 0xA
 	IdentHash      = 0xB
 	IdentSha       = 0xC
+	IdentPresence  = 0xD // Not a tokenizer, it identifies the presence index of a predicate.
 	IdentCustom    = 0x80
 	IdentDelimiter = 0x1f // ASCII 31 - Unit seperator
 )
@@ -355,7 +357,10 @@ func (t ExactTokenizer) Prefix() []byte {
 }
 
 // FullTextTokenizer generates full-text tokens from string data.
-type FullTextTokenizer struct{ lang string }
+type FullTextTokenizer struct {
+	lang string
+	dict *Dictionary
+}
 
 func (t FullTextTokenizer) Name() string { return "fulltext" }
 func (t FullTextTokenizer) Type() string { return "string" }
@@ -364,15 +369,41 @@ func (t FullTextTokenizer) Tokens(v interface{}) ([]string, error) {
 	if !ok || str == "" {
 		return []string{}, nil
 	}
+	// finally, return the unique terms.
+	return x.RemoveDuplicates(t.terms(str)), nil
+}
+
+// terms returns the terms of the string, including the repeated ones.
+func (t FullTextTokenizer) terms(str string) []string {
+	tokens := t.analyze(str)
+	terms := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		terms = append(terms, string(token.Term))
+	}
+	return terms
+}
+
+// analyze returns the tokens of the string, including the repeated ones. They keep the offsets
+// of their words in the string.
+func (t FullTextTokenizer) analyze(str string) analysis.TokenStream {
 	lang := LangBase(t.lang)
 	// pass 1 - lowercase and normalize input
 	tokens := fulltextAnalyzer.Analyze([]byte(str))
+	// protected words skip the next two passes.
+	var protected analysis.TokenStream
+	if t.dict != nil {
+		protected, tokens = t.dict.splitProtected(tokens)
+	}
 	// pass 2 - filter stop words
 	tokens = filterStopwords(lang, tokens)
 	// pass 3 - filter stems
 	tokens = filterStemmers(lang, tokens)
-	// finally, return the terms.
-	return uniqueTerms(tokens), nil
+	tokens = append(tokens, protected...)
+	// pass 4 - map synonyms to the term of their group
+	if t.dict != nil {
+		t.dict.canonicalTokens(lang, tokens)
+	}
+	return tokens
 }
 func (t FullTextTokenizer) Identifier() byte { return IdentFullText }
 func (t FullTextTokenizer) IsSortable() bool { return false }
@@ -19,6 +19,7 @@ package tok
 import (
 	"math"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -166,6 +167,38 @@ func TestTermTokenizer(t *testing.T) {
 	*/
 }
 
+func TestExactTokenizerCollation(t *testing.T) {
+	exact, has := GetTokenizer("exact")
+	require.True(t, has)
+	tokenizer := GetTokenizerForCollation(exact, "", "de")
+	require.Equal(t, byte(IdentExactLang), tokenizer.Identifier())
+
+	// Byte-wise, "Ö" comes after "Z". With the German collation, it comes right after "O".
+	var tokens []string
+	for _, s := range []string{"Zebra", "Öl", "Ofen", "Pferd"} {
+		toks, err := BuildTokens(s, tokenizer)
+		require.NoError(t, err)
+		require.Len(t, toks, 1)
+		require.True(t, strings.HasPrefix(toks[0],
+			string(tokenizer.(ExactTokenizer).Prefix())))
+		tokens = append(tokens, toks[0])
+	}
+	sort.Strings(tokens)
+	ofen, _ := BuildTokens("Ofen", tokenizer)
+	oel, _ := BuildTokens("Öl", tokenizer)
+	zebra, _ := BuildTokens("Zebra", tokenizer)
+	require.Equal(t, ofen[0], tokens[0])
+	require.Equal(t, oel[0], tokens[1])
+	require.Equal(t, zebra[0], tokens[3])
+
+	// The tagged values and the other tokenizers ignore the collation.
+	require.Equal(t, GetTokenizerForLang(exact, "de").(ExactTokenizer).Prefix(),
+		GetTokenizerForCollation(exact, "de", "sv").(ExactTokenizer).Prefix())
+	require.Equal(t, exact, GetTokenizerForCollation(exact, "", ""))
+	term, _ := GetTokenizer("term")
+	require.Equal(t, term, GetTokenizerForCollation(term, "", "de"))
+}
+
 func TestTrigramTokenizer(t *testing.T) {
 	tokenizer, has := GetTokenizer("trigram")
 	require.True(t, has)
@@ -206,19 +239,60 @@ func TestGetFullTextTokens(t *testing.T) {
 }
 
 func TestGetFullTextTokens1(t *testing.T) {
-	tokens, err := GetFullTextTokens([]string{"Quick brown fox"}, "en")
+	tokens, err := GetFullTextTokens([]string{"Quick brown fox"}, "en", nil)
 	require.NoError(t, err)
 	require.NotNil(t, tokens)
 	require.Equal(t, 3, len(tokens))
 }
 
 func TestGetFullTextTokensInvalidLang(t *testing.T) {
-	tokens, err := GetFullTextTokens([]string{"Quick brown fox"}, "xxx_such_language")
+	tokens, err := GetFullTextTokens([]string{"Quick brown fox"}, "xxx_such_language", nil)
 	require.NoError(t, err)
 	require.NotNil(t, tokens)
 	require.Equal(t, 3, len(tokens))
 }
 
+func TestGetFullTextTokensWithDictionary(t *testing.T) {
+	dict, err := NewDictionary([][]string{{"Sneakers", "trainers", "kicks"}}, []string{"Running"})
+	require.NoError(t, err)
+
+	tokens, err := GetFullTextTokens([]string{"Running trainers for the road"}, "en", dict)
+	require.NoError(t, err)
+	// The synonym is indexed as the stem of the first word of its group, and the protected word
+	// isn't stemmed.
+	require.Equal(t, []string{encodeToken("road", IdentFullText),
+		encodeToken("running", IdentFullText), encodeToken("sneaker", IdentFullText)}, tokens)
+
+	tokens, err = GetFullTextTokens([]string{"KICKS"}, "en", dict)
+	require.NoError(t, err)
+	require.Equal(t, []string{encodeToken("sneaker", IdentFullText)}, tokens)
+}
+
+func TestGetFullTextMatches(t *testing.T) {
+	val := "Running shoes, and RUNNING socks"
+	terms := GetFullTextTerms("run", "en", nil)
+	require.Equal(t, [][2]int{{0, 7}, {19, 26}}, GetFullTextMatches(val, "en", nil, terms))
+
+	dict, err := NewDictionary([][]string{{"sneakers", "shoes"}}, nil)
+	require.NoError(t, err)
+	terms = GetFullTextTerms("sneakers", "en", dict)
+	require.Equal(t, [][2]int{{8, 13}}, GetFullTextMatches(val, "en", dict, terms))
+	require.Empty(t, GetFullTextMatches(val, "en", nil, terms))
+}
+
+func TestNewDictionary(t *testing.T) {
+	dict, err := NewDictionary(nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, dict)
+
+	_, err = NewDictionary([][]string{{"tv"}}, nil)
+	require.Error(t, err)
+	_, err = NewDictionary([][]string{{"tv", "television"}, {"telly", "TV"}}, nil)
+	require.Error(t, err)
+	_, err = NewDictionary(nil, []string{"two words"})
+	require.Error(t, err)
+}
+
 // NOTE: The Chinese/Japanese/Korean tests were are based on assuming that the
 // output is correct (and adding it to the test), with some verification using
 // Google translate.
@@ -17,6 +17,8 @@
 package tok
 
 import (
+	"sort"
+
 	"github.com/pkg/errors"
 	"golang.org/x/text/collate"
 	"golang.org/x/text/language"
@@ -35,7 +37,7 @@ func GetTokenizerForLang(t Tokenizer, lang string) Tokenizer {
 	case FullTextTokenizer:
 		// We must return a new instance because another goroutine might be calling this
 		// with a different lang.
-		return FullTextTokenizer{lang: lang}
+		return FullTextTokenizer{lang: lang, dict: t.(FullTextTokenizer).dict}
 	case TermTokenizer:
 		return TermTokenizer{lang: lang}
 	case ExactTokenizer:
@@ -52,6 +54,35 @@ func GetTokenizerForLang(t Tokenizer, lang string) Tokenizer {
 	}
 }
 
+// GetTokenizerForCollation returns the tokenizer for a value with the given language tag, of a
+// predicate whose untagged values are ordered by the given collation. Only the exact tokenizer
+// takes the collation into account. Its tokens for untagged values are collation keys with an
+// empty language prefix, so they don't mix with the tokens of any tagged value.
+func GetTokenizerForCollation(t Tokenizer, lang, collation string) Tokenizer {
+	if lang != "" || collation == "" {
+		return GetTokenizerForLang(t, lang)
+	}
+	if _, ok := t.(ExactTokenizer); !ok {
+		return t
+	}
+	// The collation is validated when the schema is parsed.
+	langTag, err := language.Parse(collation)
+	if err != nil {
+		return t
+	}
+	return ExactTokenizer{cl: collate.New(langTag), buffer: &collate.Buffer{}}
+}
+
+// GetTokenizerForDictionary returns the tokenizer using the given dictionary. Only the fulltext
+// tokenizer takes the dictionary into account.
+func GetTokenizerForDictionary(t Tokenizer, dict *Dictionary) Tokenizer {
+	if ft, ok := t.(FullTextTokenizer); ok && dict != nil {
+		ft.dict = dict
+		return ft
+	}
+	return t
+}
+
 // GetTokens returns the tokens for the given tokenizer ID and value.
 // funcArgs should only have one element which is the value that needs to be tokenized.
 func GetTokens(id byte, funcArgs ...string) ([]string, error) {
@@ -70,10 +101,41 @@ func GetTermTokens(funcArgs []string) ([]string, error) {
 	return GetTokens(IdentTerm, funcArgs...)
 }
 
-// GetFullTextTokens returns the full-text tokens for the given value.
-func GetFullTextTokens(funcArgs []string, lang string) ([]string, error) {
+// GetFullTextTokens returns the full-text tokens for the given value, using the given
+// dictionary if it isn't nil.
+func GetFullTextTokens(funcArgs []string, lang string, dict *Dictionary) ([]string, error) {
 	if l := len(funcArgs); l != 1 {
 		return nil, errors.Errorf("Function requires 1 arguments, but got %d", l)
 	}
-	return BuildTokens(funcArgs[0], FullTextTokenizer{lang: lang})
+	return BuildTokens(funcArgs[0], FullTextTokenizer{lang: lang, dict: dict})
+}
+
+// GetFullTextTerms returns the full-text terms of the given value in order, including the
+// repeated ones, using the given dictionary if it isn't nil. Unlike the tokens, they aren't
+// encoded.
+func GetFullTextTerms(val, lang string, dict *Dictionary) []string {
+	if val == "" {
+		return nil
+	}
+	return FullTextTokenizer{lang: lang, dict: dict}.terms(val)
+}
+
+// GetFullTextMatches returns the byte offsets of the start and the end of the words of the given
+// value whose full-text term is one of the given terms, in order.
+func GetFullTextMatches(val, lang string, dict *Dictionary, terms []string) [][2]int {
+	if val == "" || len(terms) == 0 {
+		return nil
+	}
+	want := make(map[string]struct{}, len(terms))
+	for _, term := range terms {
+		want[term] = struct{}{}
+	}
+	var matches [][2]int
+	for _, token := range (FullTextTokenizer{lang: lang, dict: dict}).analyze(val) {
+		if _, ok := want[string(token.Term)]; ok {
+			matches = append(matches, [2]int{token.Start, token.End})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i][0] < matches[j][0] })
+	return matches
 }
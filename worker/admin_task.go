@@ -0,0 +1,247 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgraph/conn"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// taskReportInterval is how often a running task reports its progress to Zero. Zero marks tasks
+// which haven't reported for a while as failed, so this also acts as a heartbeat.
+const taskReportInterval = 10 * time.Second
+
+// ErrTaskCancelled is returned by the tasks which got cancelled via the admin API.
+var ErrTaskCancelled = errors.New("Task was cancelled")
+
+// TaskOptions describe a task started via RunTask.
+type TaskOptions struct {
+	Kind        string
+	Description string
+	// Pausable tasks call Task.Progress often enough to be paused between the calls.
+	Pausable bool
+	// Cancellable tasks stop once their context is cancelled.
+	Cancellable bool
+}
+
+// Task is the handle passed to the function run by RunTask, to report its progress.
+type Task struct {
+	sync.Mutex
+	task       *pb.Task
+	lastReport time.Time
+	cancelled  bool
+}
+
+// RunTask runs fn as a task which can be seen, and depending on the options paused, resumed or
+// cancelled, via the admin API. The task is recorded in Zero, so that it outlives this node.
+func RunTask(ctx context.Context, opts TaskOptions, fn func(context.Context, *Task) error) error {
+	g := groups()
+	var owner uint64
+	if g.Node != nil {
+		owner = g.Node.Id
+	}
+	now := time.Now().Unix()
+	t := &Task{task: &pb.Task{
+		Id:          rand.Uint64(),
+		Kind:        opts.Kind,
+		Description: opts.Description,
+		GroupId:     g.groupId(),
+		Owner:       owner,
+		State:       pb.Task_RUNNING,
+		StartedAt:   now,
+		Pausable:    opts.Pausable,
+		Cancellable: opts.Cancellable,
+	}}
+	glog.Infof("Starting task %#x: %s", t.task.Id, opts.Description)
+	t.report(ctx)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go t.watch(ctx, cancel)
+
+	err := fn(ctx, t)
+
+	t.Lock()
+	switch {
+	case t.cancelled:
+		t.task.State = pb.Task_CANCELLED
+		err = ErrTaskCancelled
+	case err != nil:
+		t.task.State = pb.Task_FAILED
+		t.task.Message = err.Error()
+	default:
+		t.task.State = pb.Task_SUCCEEDED
+		t.task.Progress = 1
+		t.task.Message = ""
+	}
+	glog.Infof("Task %#x finished with state %s", t.task.Id, t.task.State)
+	t.Unlock()
+
+	// The context of the task could be done by now, but its final state should still be recorded.
+	rctx, rcancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer rcancel()
+	t.report(rctx)
+	return err
+}
+
+// Id returns the id of the task.
+func (t *Task) Id() uint64 {
+	return t.task.Id
+}
+
+// Progress records that done out of total units of work of the task are done. Pausable tasks block
+// in here while they are paused. It returns ErrTaskCancelled once the task has been cancelled.
+func (t *Task) Progress(ctx context.Context, done, total uint64, message string) error {
+	t.Lock()
+	if total > 0 {
+		t.task.Progress = float64(done) / float64(total)
+	}
+	t.task.Message = message
+	report := time.Since(t.lastReport) >= taskReportInterval || done == total
+	t.Unlock()
+	if report {
+		t.report(ctx)
+	}
+	return t.checkpoint(ctx)
+}
+
+// checkpoint returns once the task isn't paused anymore.
+func (t *Task) checkpoint(ctx context.Context) error {
+	paused := false
+	for {
+		ctl := taskFromState(t.task.Id)
+		if ctl.GetCancelRequested() && t.task.Cancellable {
+			t.Lock()
+			t.cancelled = true
+			t.Unlock()
+			return ErrTaskCancelled
+		}
+		if !ctl.GetPauseRequested() || !t.task.Pausable {
+			if paused {
+				t.setState(ctx, pb.Task_RUNNING)
+			}
+			return nil
+		}
+		if !paused {
+			glog.Infof("Pausing task %#x", t.task.Id)
+			t.setState(ctx, pb.Task_PAUSED)
+			paused = true
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (t *Task) setState(ctx context.Context, state pb.Task_State) {
+	t.Lock()
+	t.task.State = state
+	t.Unlock()
+	t.report(ctx)
+}
+
+// watch keeps reporting the task while it runs, and cancels it once that's requested.
+func (t *Task) watch(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if taskFromState(t.task.Id).GetCancelRequested() && t.task.Cancellable {
+			glog.Infof("Cancelling task %#x", t.task.Id)
+			t.Lock()
+			t.cancelled = true
+			t.Unlock()
+			cancel()
+			return
+		}
+		t.Lock()
+		report := time.Since(t.lastReport) >= taskReportInterval
+		t.Unlock()
+		if report {
+			t.report(ctx)
+		}
+	}
+}
+
+// report records the task in Zero. Failing to do so doesn't fail the task.
+func (t *Task) report(ctx context.Context) {
+	t.Lock()
+	t.lastReport = time.Now()
+	t.task.UpdatedAt = t.lastReport.Unix()
+	task := proto.Clone(t.task).(*pb.Task)
+	t.Unlock()
+
+	pl := groups().connToZeroLeader()
+	if pl == nil {
+		glog.Warningf("While recording task %#x: %v", task.Id, conn.ErrNoConnection)
+		return
+	}
+	if _, err := pb.NewZeroClient(pl.Get()).UpdateTask(ctx, task); err != nil {
+		glog.Warningf("While recording task %#x: %v", task.Id, err)
+	}
+}
+
+func taskFromState(id uint64) *pb.Task {
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	return g.state.GetTasks()[id]
+}
+
+// GetTasks returns the tasks known to Zero, with the most recently started first.
+func GetTasks() []*pb.Task {
+	state := GetMembershipState()
+	tasks := make([]*pb.Task, 0, len(state.GetTasks()))
+	for _, t := range state.GetTasks() {
+		tasks = append(tasks, t)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].StartedAt != tasks[j].StartedAt {
+			return tasks[i].StartedAt > tasks[j].StartedAt
+		}
+		return tasks[i].Id < tasks[j].Id
+	})
+	return tasks
+}
+
+// ControlTask asks Zero to pause, resume or cancel a task.
+func ControlTask(ctx context.Context, id uint64, op pb.TaskControl_Op) (*pb.Task, error) {
+	pl := groups().connToZeroLeader()
+	if pl == nil {
+		return nil, conn.ErrNoConnection
+	}
+	task, err := pb.NewZeroClient(pl.Get()).ControlTask(ctx, &pb.TaskControl{Id: id, Op: op})
+	if err != nil {
+		return nil, err
+	}
+	return task, UpdateMembershipState(ctx)
+}
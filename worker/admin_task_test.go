@@ -0,0 +1,145 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// withTaskState runs fn with a groupi whose membership state holds the tasks. Its closer is
+// done, so that reporting the tasks to Zero gives up right away.
+func withTaskState(t *testing.T, fn func(control func(*pb.Task))) {
+	old := gr
+	defer func() { gr = old }()
+	closer := z.NewCloser(0)
+	closer.Signal()
+	gr = &groupi{
+		gid:     1,
+		closer:  closer,
+		state:   &pb.MembershipState{Tasks: make(map[uint64]*pb.Task)},
+		tablets: make(map[string]*pb.Tablet),
+	}
+	fn(func(task *pb.Task) {
+		gr.Lock()
+		defer gr.Unlock()
+		gr.state.Tasks[task.Id] = task
+	})
+}
+
+func (t *Task) state() pb.Task_State {
+	t.Lock()
+	defer t.Unlock()
+	return t.task.State
+}
+
+func TestTaskPauseResume(t *testing.T) {
+	withTaskState(t, func(control func(*pb.Task)) {
+		task := &Task{task: &pb.Task{Id: 1, State: pb.Task_RUNNING, Pausable: true}}
+		control(&pb.Task{Id: 1, PauseRequested: true})
+
+		done := make(chan error, 1)
+		go func() { done <- task.Progress(context.Background(), 1, 2, "half") }()
+		require.Eventually(t, func() bool { return task.state() == pb.Task_PAUSED },
+			5*time.Second, 10*time.Millisecond)
+		select {
+		case err := <-done:
+			t.Fatalf("Progress returned while paused: %v", err)
+		default:
+		}
+
+		control(&pb.Task{Id: 1})
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Progress didn't return once resumed")
+		}
+		require.Equal(t, pb.Task_RUNNING, task.state())
+		require.Equal(t, 0.5, task.task.Progress)
+	})
+}
+
+func TestTaskNotPausable(t *testing.T) {
+	withTaskState(t, func(control func(*pb.Task)) {
+		task := &Task{task: &pb.Task{Id: 1, State: pb.Task_RUNNING}}
+		control(&pb.Task{Id: 1, PauseRequested: true, CancelRequested: true})
+		require.NoError(t, task.Progress(context.Background(), 1, 2, "half"))
+		require.Equal(t, pb.Task_RUNNING, task.state())
+	})
+}
+
+func TestTaskCancelWhilePaused(t *testing.T) {
+	withTaskState(t, func(control func(*pb.Task)) {
+		task := &Task{task: &pb.Task{Id: 1, State: pb.Task_RUNNING, Pausable: true,
+			Cancellable: true}}
+		control(&pb.Task{Id: 1, PauseRequested: true})
+
+		done := make(chan error, 1)
+		go func() { done <- task.Progress(context.Background(), 1, 2, "half") }()
+		require.Eventually(t, func() bool { return task.state() == pb.Task_PAUSED },
+			5*time.Second, 10*time.Millisecond)
+
+		control(&pb.Task{Id: 1, PauseRequested: true, CancelRequested: true})
+		select {
+		case err := <-done:
+			require.Equal(t, ErrTaskCancelled, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Progress didn't return once cancelled")
+		}
+	})
+}
+
+func TestRunTaskCancel(t *testing.T) {
+	withTaskState(t, func(control func(*pb.Task)) {
+		opts := TaskOptions{Kind: "test", Cancellable: true}
+		err := RunTask(context.Background(), opts, func(ctx context.Context, task *Task) error {
+			control(&pb.Task{Id: task.Id(), CancelRequested: true})
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		require.Equal(t, ErrTaskCancelled, err)
+	})
+}
+
+func TestRunTaskStates(t *testing.T) {
+	withTaskState(t, func(control func(*pb.Task)) {
+		var task *Task
+		require.NoError(t, RunTask(context.Background(), TaskOptions{Kind: "test"},
+			func(_ context.Context, t *Task) error {
+				task = t
+				return nil
+			}))
+		require.Equal(t, pb.Task_SUCCEEDED, task.state())
+		require.Equal(t, float64(1), task.task.Progress)
+
+		err := RunTask(context.Background(), TaskOptions{Kind: "test"},
+			func(_ context.Context, t *Task) error {
+				task = t
+				return errors.New("disk full")
+			})
+		require.EqualError(t, err, "disk full")
+		require.Equal(t, pb.Task_FAILED, task.state())
+		require.Equal(t, "disk full", task.task.Message)
+	})
+}
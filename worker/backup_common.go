@@ -64,6 +64,14 @@ type Manifest struct {
 	Path string `json:"-"`
 	// Encrypted indicates whether this backup was encrypted or not.
 	Encrypted bool `json:"encrypted"`
+	// Envelope indicates whether the data keys of this backup were encrypted with a public key,
+	// in which case restoring it needs the matching private key.
+	Envelope bool `json:"envelope"`
+	// Checksums maps the groups to the hex encoded SHA-256 of their backup files.
+	Checksums map[uint32]string `json:"checksums,omitempty"`
+	// Signature is the base64 encoded Ed25519 signature of the manifest, made by the Alpha
+	// that took the backup. It's empty if the backup isn't signed.
+	Signature string `json:"signature,omitempty"`
 	// DropOperations lists the various DROP operations that took place since the last backup.
 	// These are used during restore to redo those operations before applying the backup.
 	DropOperations []*pb.DropOperation `json:"drop_operations"`
@@ -92,8 +92,9 @@ var backupLock sync.Mutex
 // BackupRes is used to represent the response and error of the Backup gRPC call together to be
 // transported via a channel.
 type BackupRes struct {
-	res *pb.BackupResponse
-	err error
+	res     *pb.BackupResponse
+	err     error
+	groupId uint32
 }
 
 func ProcessBackupRequest(ctx context.Context, req *pb.BackupRequest, forceFull bool) error {
@@ -152,7 +153,7 @@ func ProcessBackupRequest(ctx context.Context, req *pb.BackupRequest, forceFull
 	if forceFull {
 		req.SinceTs = 0
 	} else {
-		if x.WorkerConfig.EncryptionKey != nil {
+		if x.WorkerConfig.EncryptionKey != nil || x.WorkerConfig.BackupPublicKey != nil {
 			// If encryption key given, latest backup should be encrypted.
 			if latestManifest.Type != "" && !latestManifest.Encrypted {
 				err = errors.Errorf("latest manifest indicates the last backup was not encrypted " +
@@ -167,6 +168,16 @@ func ProcessBackupRequest(ctx context.Context, req *pb.BackupRequest, forceFull
 				return err
 			}
 		}
+		// A series is restored with a single key, so it can't mix backups encrypted with the
+		// symmetric key and with the public key.
+		envelope := x.WorkerConfig.BackupPublicKey != nil
+		if latestManifest.Type != "" && latestManifest.Encrypted &&
+			latestManifest.Envelope != envelope {
+			err = errors.Errorf("latest manifest indicates the last backup was encrypted with "+
+				"a different kind of key (public key: %v) than this instance's (public key: %v). "+
+				"Try \"forceFull\" flag.", latestManifest.Envelope, envelope)
+			return err
+		}
 	}
 
 	// Update the membership state to get the latest mapping of groups to predicates.
@@ -197,22 +208,24 @@ func ProcessBackupRequest(ctx context.Context, req *pb.BackupRequest, forceFull
 		br.Predicates = predMap[gid]
 		go func(req *pb.BackupRequest) {
 			res, err := BackupGroup(ctx, req)
-			resCh <- BackupRes{res: res, err: err}
+			resCh <- BackupRes{res: res, err: err, groupId: req.GroupId}
 		}(br)
 	}
 
 	var dropOperations []*pb.DropOperation
+	checksums := make(map[uint32]string)
 	for range groups {
 		if backupRes := <-resCh; backupRes.err != nil {
 			glog.Errorf("Error received during backup: %v", backupRes.err)
 			return backupRes.err
 		} else {
 			dropOperations = append(dropOperations, backupRes.res.GetDropOperations()...)
+			checksums[backupRes.groupId] = backupRes.res.GetChecksum()
 		}
 	}
 
 	m := Manifest{Since: req.ReadTs, Groups: predMap, Version: x.DgraphVersion,
-		DropOperations: dropOperations}
+		DropOperations: dropOperations, Checksums: checksums}
 	if req.SinceTs == 0 {
 		m.Type = "full"
 		m.BackupId = x.GetRandomName(1)
@@ -222,7 +235,13 @@ func ProcessBackupRequest(ctx context.Context, req *pb.BackupRequest, forceFull
 		m.BackupId = latestManifest.BackupId
 		m.BackupNum = latestManifest.BackupNum + 1
 	}
-	m.Encrypted = (x.WorkerConfig.EncryptionKey != nil)
+	m.Encrypted = (x.WorkerConfig.EncryptionKey != nil || x.WorkerConfig.BackupPublicKey != nil)
+	m.Envelope = (x.WorkerConfig.BackupPublicKey != nil)
+	if key := x.WorkerConfig.BackupSigningKey; key != nil {
+		if err := signManifest(&m, key); err != nil {
+			return errors.Wrapf(err, "cannot sign the backup manifest")
+		}
+	}
 
 	bp := NewBackupProcessor(nil, req)
 	err = bp.CompleteBackup(ctx, &m)
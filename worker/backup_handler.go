@@ -13,6 +13,7 @@
 package worker
 
 import (
+	"crypto/ed25519"
 	"fmt"
 	"io"
 	"net/url"
@@ -98,6 +99,9 @@ type UriHandler interface {
 	// ReadManifest will read the manifest at the given location and load it into the given
 	// Manifest object.
 	ReadManifest(string, *Manifest) error
+
+	// OpenFile opens the backup file at the given path for reading.
+	OpenFile(string) (io.ReadCloser, error)
 }
 
 // NewUriHandler parses the requested URI and finds the corresponding UriHandler.
@@ -142,9 +146,10 @@ func NewUriHandler(uri *url.URL, creds *x.MinioCredentials) (UriHandler, error)
 type loadFn func(groupId uint32, in *loadBackupInput) (uint64, uint64, error)
 
 // LoadBackup will scan location l for backup files in the given backup series and load them
-// sequentially. Returns the maximum Since value on success, otherwise an error.
+// sequentially. Returns the maximum Since value on success, otherwise an error. If pub isn't nil,
+// the files are checked against the signatures of their manifests while they're loaded.
 func LoadBackup(location, backupId string, backupNum uint64, creds *x.MinioCredentials,
-	fn loadFn) LoadResult {
+	pub ed25519.PublicKey, fn loadFn) LoadResult {
 	uri, err := url.Parse(location)
 	if err != nil {
 		return LoadResult{Err: err}
@@ -155,6 +160,9 @@ func LoadBackup(location, backupId string, backupNum uint64, creds *x.MinioCrede
 		return LoadResult{Err: errors.Errorf("Unsupported URI: %v", uri)}
 	}
 
+	if pub != nil {
+		fn = verifiedLoadFn(fn, pub)
+	}
 	return h.Load(uri, backupId, backupNum, fn)
 }
 
@@ -15,6 +15,7 @@ package worker
 import (
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
@@ -23,6 +24,7 @@ import (
 	"net/url"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/dgraph-io/badger/v3"
 	bpb "github.com/dgraph-io/badger/v3/pb"
@@ -141,81 +143,39 @@ func (pr *BackupProcessor) WriteBackup(ctx context.Context) (*pb.BackupResponse,
 		predMap[pred] = struct{}{}
 	}
 
-	var maxVersion uint64
+	// The checksum of the file as stored goes into the manifest.
+	checksum := sha256.New()
+	hw := io.MultiWriter(handler, checksum)
 
-	newhandler, err := enc.GetWriter(x.WorkerConfig.EncryptionKey, handler)
+	var newhandler io.Writer
+	if pub := x.WorkerConfig.BackupPublicKey; pub != nil {
+		newhandler, err = enc.GetEnvelopeWriter(pub, hw)
+	} else {
+		newhandler, err = enc.GetWriter(x.WorkerConfig.EncryptionKey, hw)
+	}
 	if err != nil {
 		return &response, err
 	}
 	gzWriter := gzip.NewWriter(newhandler)
 
-	stream := pr.DB.NewStreamAt(pr.Request.ReadTs)
-	stream.LogPrefix = "Dgraph.Backup"
-	stream.NumGo = backupNumGo
-	// Ignore versions less than given sinceTs timestamp, or skip older versions of
-	// the given key by returning an empty list.
-	// Do not do this for schema and type keys. Those keys always have a
-	// version of one. They're handled separately.
-	stream.SinceTs = pr.Request.SinceTs
-	stream.Prefix = []byte{x.ByteData}
-
-	stream.KeyToList = func(key []byte, itr *badger.Iterator) (*bpb.KVList, error) {
-		tl := pr.threads[itr.ThreadId]
-		tl.alloc = itr.Alloc
-
-		bitr := itr
-		// Use the threadlocal iterator because "itr" has the sinceTs set and
-		// it will not be able to read all the data.
-		if tl.itr != nil {
-			bitr = tl.itr
-			bitr.Seek(key)
-		}
-
-		kvList, dropOp, err := tl.toBackupList(key, bitr)
-		if err != nil {
-			return nil, err
-		}
-		// we don't want to append a nil value to the slice, so need to check.
-		if dropOp != nil {
-			response.DropOperations = append(response.DropOperations, dropOp)
-		}
-		return kvList, nil
+	maxVersion, err := pr.writeData([]byte{x.ByteData}, predMap, &response, gzWriter)
+	if err != nil {
+		glog.Errorf("While taking backup: %v", err)
+		return &response, err
 	}
-
-	stream.ChooseKey = func(item *badger.Item) bool {
-		parsedKey, err := x.Parse(item.Key())
-		if err != nil {
-			glog.Errorf("error %v while parsing key %v during backup. Skip.", err, hex.EncodeToString(item.Key()))
-			return false
-		}
-
-		// Do not choose keys that contain parts of a multi-part list. These keys
-		// will be accessed from the main list.
-		if parsedKey.HasStartUid {
-			return false
-		}
-
-		// Skip backing up the schema and type keys. They will be backed up separately.
-		if parsedKey.IsSchema() || parsedKey.IsType() {
-			return false
-		}
-		_, ok := predMap[parsedKey.Attr]
+	// The data of the cold predicates is in object storage, not in pstore.
+	keep := func(attr string) bool {
+		_, ok := predMap[attr]
 		return ok
 	}
-	stream.Send = func(buf *z.Buffer) error {
-		list, err := badger.BufferToKVList(buf)
-		if err != nil {
-			return err
-		}
-		for _, kv := range list.Kv {
-			if maxVersion < kv.Version {
-				maxVersion = kv.Version
-			}
-		}
-		return writeKVList(list, gzWriter)
-	}
-
-	if err := stream.Orchestrate(context.Background()); err != nil {
+	err = streamColdTablets(keep, func(attr string, _ *pb.ColdTablet, db *badger.DB) error {
+		cpr := NewBackupProcessor(db, pr.Request)
+		defer cpr.Close()
+		version, err := cpr.writeData(x.PredicatePrefix(attr), predMap, &response, gzWriter)
+		maxVersion = x.Max(maxVersion, version)
+		return err
+	})
+	if err != nil {
 		glog.Errorf("While taking backup: %v", err)
 		return &response, err
 	}
@@ -260,6 +220,19 @@ func (pr *BackupProcessor) WriteBackup(ctx context.Context) (*pb.BackupResponse,
 			}); err != nil {
 				return errors.Wrapf(err, "while copying value")
 			}
+			if parsedKey.IsSchema() {
+				// The backup holds the data of the cold predicates, which is restored locally.
+				var update pb.SchemaUpdate
+				if err := update.Unmarshal(kv.Value); err != nil {
+					return errors.Wrapf(err, "while reading schema")
+				}
+				if update.Cold != nil {
+					update.Cold = nil
+					if kv.Value, err = update.Marshal(); err != nil {
+						return errors.Wrapf(err, "while writing schema")
+					}
+				}
+			}
 
 			backupKey, err := tl.toBackupKey(item.Key())
 			if err != nil {
@@ -296,10 +269,86 @@ func (pr *BackupProcessor) WriteBackup(ctx context.Context) (*pb.BackupResponse,
 		glog.Errorf("While closing handler: %v", err)
 		return &response, err
 	}
+	response.Checksum = hex.EncodeToString(checksum.Sum(nil))
 	glog.Infof("Backup complete: group %d at %d", pr.Request.GroupId, pr.Request.ReadTs)
 	return &response, nil
 }
 
+// writeData streams the data of the predicates in predMap under the prefix to w, and returns
+// the max version written.
+func (pr *BackupProcessor) writeData(prefix []byte, predMap map[string]struct{},
+	response *pb.BackupResponse, w io.Writer) (uint64, error) {
+	var maxVersion uint64
+	stream := pr.DB.NewStreamAt(pr.Request.ReadTs)
+	stream.LogPrefix = "Dgraph.Backup"
+	stream.NumGo = backupNumGo
+	// Ignore versions less than given sinceTs timestamp, or skip older versions of
+	// the given key by returning an empty list.
+	// Do not do this for schema and type keys. Those keys always have a
+	// version of one. They're handled separately.
+	stream.SinceTs = pr.Request.SinceTs
+	stream.Prefix = prefix
+
+	stream.KeyToList = func(key []byte, itr *badger.Iterator) (*bpb.KVList, error) {
+		tl := pr.threads[itr.ThreadId]
+		tl.alloc = itr.Alloc
+
+		bitr := itr
+		// Use the threadlocal iterator because "itr" has the sinceTs set and
+		// it will not be able to read all the data.
+		if tl.itr != nil {
+			bitr = tl.itr
+			bitr.Seek(key)
+		}
+
+		kvList, dropOp, err := tl.toBackupList(key, bitr)
+		if err != nil {
+			return nil, err
+		}
+		// we don't want to append a nil value to the slice, so need to check.
+		if dropOp != nil {
+			response.DropOperations = append(response.DropOperations, dropOp)
+		}
+		return kvList, nil
+	}
+
+	stream.ChooseKey = func(item *badger.Item) bool {
+		parsedKey, err := x.Parse(item.Key())
+		if err != nil {
+			glog.Errorf("error %v while parsing key %v during backup. Skip.", err, hex.EncodeToString(item.Key()))
+			return false
+		}
+
+		// Do not choose keys that contain parts of a multi-part list. These keys
+		// will be accessed from the main list.
+		if parsedKey.HasStartUid {
+			return false
+		}
+
+		// Skip backing up the schema and type keys. They will be backed up separately.
+		if parsedKey.IsSchema() || parsedKey.IsType() {
+			return false
+		}
+		_, ok := predMap[parsedKey.Attr]
+		return ok
+	}
+	stream.Send = func(buf *z.Buffer) error {
+		list, err := badger.BufferToKVList(buf)
+		if err != nil {
+			return err
+		}
+		for _, kv := range list.Kv {
+			if maxVersion < kv.Version {
+				maxVersion = kv.Version
+			}
+		}
+		return writeKVList(list, w)
+	}
+
+	err := stream.Orchestrate(context.Background())
+	return maxVersion, err
+}
+
 // CompleteBackup will finalize a backup by writing the manifest at the backup destination.
 func (pr *BackupProcessor) CompleteBackup(ctx context.Context, manifest *Manifest) error {
 	if err := ctx.Err(); err != nil {
@@ -333,8 +382,8 @@ func (pr *BackupProcessor) CompleteBackup(ctx context.Context, manifest *Manifes
 
 // GoString implements the GoStringer interface for Manifest.
 func (m *Manifest) GoString() string {
-	return fmt.Sprintf(`Manifest{Since: %d, Groups: %v, Encrypted: %v}`,
-		m.Since, m.Groups, m.Encrypted)
+	return fmt.Sprintf(`Manifest{Since: %d, Groups: %v, Encrypted: %v, Envelope: %v}`,
+		m.Since, m.Groups, m.Encrypted, m.Envelope)
 }
 
 func (tl *threadLocal) toBackupList(key []byte, itr *badger.Iterator) (
@@ -397,15 +446,30 @@ func (tl *threadLocal) toBackupKey(key []byte) ([]byte, error) {
 	return out[:n], err
 }
 
+// kvListBufPool has the buffers used to marshal the KV lists written to backups, so that a new
+// buffer isn't allocated for every batch.
+var kvListBufPool = sync.Pool{
+	New: func() interface{} {
+		return new([]byte)
+	},
+}
+
 func writeKVList(list *bpb.KVList, w io.Writer) error {
-	if err := binary.Write(w, binary.LittleEndian, uint64(list.Size())); err != nil {
+	size := list.Size()
+	if err := binary.Write(w, binary.LittleEndian, uint64(size)); err != nil {
 		return err
 	}
-	buf, err := list.Marshal()
+	bufp := kvListBufPool.Get().(*[]byte)
+	defer kvListBufPool.Put(bufp)
+	if cap(*bufp) < size {
+		*bufp = make([]byte, size)
+	}
+	buf := (*bufp)[:size]
+	n, err := list.MarshalToSizedBuffer(buf)
 	if err != nil {
 		return err
 	}
-	_, err = w.Write(buf)
+	_, err = w.Write(buf[len(buf)-n:])
 	return err
 }
 
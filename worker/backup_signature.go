@@ -0,0 +1,171 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package worker
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"net/url"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// signedBytes returns the bytes of the manifest covered by its signature, i.e. its JSON
+// encoding without the signature.
+func (m *Manifest) signedBytes() ([]byte, error) {
+	sig := m.Signature
+	m.Signature = ""
+	defer func() { m.Signature = sig }()
+	return json.Marshal(m)
+}
+
+// signManifest signs the manifest with the given key. The manifest holds the checksums of the
+// backup files, so they're covered by the signature too.
+func signManifest(m *Manifest, key ed25519.PrivateKey) error {
+	b, err := m.signedBytes()
+	if err != nil {
+		return err
+	}
+	m.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, b))
+	return nil
+}
+
+// verifyManifest checks the signature of the manifest with the given public key.
+func verifyManifest(m *Manifest, pub ed25519.PublicKey) error {
+	if m.Signature == "" {
+		return errors.Errorf("manifest %s isn't signed", m.Path)
+	}
+	sig, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return errors.Wrapf(err, "bad signature in manifest %s", m.Path)
+	}
+	b, err := m.signedBytes()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, b, sig) {
+		return errors.Errorf("invalid signature for manifest %s", m.Path)
+	}
+	return nil
+}
+
+// verifyBackupFile checks that the SHA-256 of the backup file at path matches the checksum.
+func verifyBackupFile(h UriHandler, path, checksum string) error {
+	r, err := h.OpenFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open backup file %s", path)
+	}
+	defer r.Close()
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, r); err != nil {
+		return errors.Wrapf(err, "cannot read backup file %s", path)
+	}
+	if hex.EncodeToString(sum.Sum(nil)) != checksum {
+		return errors.Errorf("checksum mismatch for backup file %s", path)
+	}
+	return nil
+}
+
+// verifiedLoadFn wraps fn so that the manifest of each backup file is checked against its
+// signature, and the file against the checksum of the manifest while fn reads it. Checking the
+// files as they're restored, and not only beforehand, catches a file replaced in between. A file
+// which fn doesn't read isn't restored, and isn't checked.
+func verifiedLoadFn(fn loadFn, pub ed25519.PublicKey) loadFn {
+	return func(groupId uint32, in *loadBackupInput) (uint64, uint64, error) {
+		m := in.manifest
+		if err := verifyManifest(m, pub); err != nil {
+			return 0, 0, err
+		}
+		checksum, ok := m.Checksums[groupId]
+		if !ok {
+			return 0, 0, errors.Errorf("manifest %s has no checksum for group %d", m.Path, groupId)
+		}
+
+		r := in.r
+		sum := &countingHash{Hash: sha256.New()}
+		in.r = io.TeeReader(r, sum)
+		maxUid, maxNsId, err := fn(groupId, in)
+		if err != nil || sum.n == 0 {
+			return maxUid, maxNsId, err
+		}
+		// The restore may stop reading before the end of the file, at the end of the gzip
+		// stream.
+		if _, err := io.Copy(sum, r); err != nil {
+			return 0, 0, errors.Wrapf(err, "cannot read backup file of group %d in %s",
+				groupId, m.Path)
+		}
+		if hex.EncodeToString(sum.Sum(nil)) != checksum {
+			return 0, 0, errors.Errorf("checksum mismatch for backup file of group %d in %s",
+				groupId, m.Path)
+		}
+		return maxUid, maxNsId, nil
+	}
+}
+
+// countingHash is a hash counting the bytes written to it.
+type countingHash struct {
+	hash.Hash
+	n int64
+}
+
+func (h *countingHash) Write(p []byte) (int, error) {
+	h.n += int64(len(p))
+	return h.Hash.Write(p)
+}
+
+// VerifyBackupSignatures checks the signatures of the manifests of the given backup series
+// with the public key, and the checksums of their backup files. It's meant to be called before
+// restoring any of them, so that a tampered backup is rejected as a whole.
+func VerifyBackupSignatures(location, backupId string, backupNum uint64,
+	creds *x.MinioCredentials, pub ed25519.PublicKey) error {
+	uri, err := url.Parse(location)
+	if err != nil {
+		return err
+	}
+	h, err := NewUriHandler(uri, creds)
+	if err != nil {
+		return errors.Wrap(err, "VerifyBackupSignatures")
+	}
+	manifests, err := h.GetManifests(uri, backupId, backupNum)
+	if err != nil {
+		return errors.Wrapf(err, "cannot retrieve manifests")
+	}
+
+	for _, manifest := range manifests {
+		if err := verifyManifest(manifest, pub); err != nil {
+			return err
+		}
+		path := filepath.Dir(manifest.Path)
+		for gid := range manifest.Groups {
+			checksum, ok := manifest.Checksums[gid]
+			if !ok {
+				return errors.Errorf("manifest %s has no checksum for group %d",
+					manifest.Path, gid)
+			}
+			file := filepath.Join(path, backupName(manifest.Since, gid))
+			if err := verifyBackupFile(h, file, checksum); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
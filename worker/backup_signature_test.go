@@ -0,0 +1,121 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package worker
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyBackupSignatures(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	location, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(location)
+	dir := filepath.Join(location, fmt.Sprintf(backupPathFmt, "20210101.000000.000"))
+	require.NoError(t, os.Mkdir(dir, 0700))
+
+	data := []byte("backup data")
+	backupFile := filepath.Join(dir, backupName(10, 1))
+	require.NoError(t, ioutil.WriteFile(backupFile, data, 0600))
+	sum := sha256.Sum256(data)
+
+	writeManifest := func(m *Manifest) {
+		b, err := json.Marshal(m)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, backupManifest), b, 0600))
+	}
+	newManifest := func() *Manifest {
+		return &Manifest{Type: "full", Since: 10, BackupId: "id", BackupNum: 1,
+			Groups:    map[uint32][]string{1: {"name"}},
+			Checksums: map[uint32]string{1: hex.EncodeToString(sum[:])}}
+	}
+
+	// Unsigned manifests are rejected.
+	writeManifest(newManifest())
+	require.Error(t, VerifyBackupSignatures(location, "", 0, nil, pub))
+
+	m := newManifest()
+	require.NoError(t, signManifest(m, key))
+	writeManifest(m)
+	require.NoError(t, VerifyBackupSignatures(location, "", 0, nil, pub))
+	require.Error(t, VerifyBackupSignatures(location, "", 0, nil, otherPub))
+
+	// A tampered manifest is rejected.
+	m.Groups[1] = append(m.Groups[1], "age")
+	writeManifest(m)
+	err = VerifyBackupSignatures(location, "", 0, nil, pub)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid signature")
+
+	// A tampered backup file is rejected.
+	m = newManifest()
+	require.NoError(t, signManifest(m, key))
+	writeManifest(m)
+	require.NoError(t, ioutil.WriteFile(backupFile, []byte("tampered data"), 0600))
+	err = VerifyBackupSignatures(location, "", 0, nil, pub)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "checksum mismatch")
+}
+
+func TestVerifiedLoadFn(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	data := []byte("backup data")
+	sum := sha256.Sum256(data)
+	m := &Manifest{Type: "full", Since: 10, BackupId: "id", BackupNum: 1,
+		Groups:    map[uint32][]string{1: {"name"}},
+		Checksums: map[uint32]string{1: hex.EncodeToString(sum[:])}}
+	require.NoError(t, signManifest(m, key))
+
+	// The restore reads only a part of the file, the rest is checked afterwards.
+	read := func(n int) loadFn {
+		return func(groupId uint32, in *loadBackupInput) (uint64, uint64, error) {
+			_, err := io.ReadFull(in.r, make([]byte, n))
+			return 1, 2, err
+		}
+	}
+	load := func(fn loadFn, data []byte) error {
+		_, _, err := verifiedLoadFn(fn, pub)(1,
+			&loadBackupInput{r: bytes.NewReader(data), manifest: m})
+		return err
+	}
+	require.NoError(t, load(read(4), data))
+	err = load(read(4), []byte("backup tampered"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "checksum mismatch")
+	// A file which isn't read isn't restored.
+	require.NoError(t, load(read(0), []byte("backup tampered")))
+
+	m.Groups[1] = append(m.Groups[1], "age")
+	err = load(read(4), data)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid signature")
+}
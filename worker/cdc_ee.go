@@ -48,9 +48,13 @@ const (
 //
 // Now, with ludicrous mode, Raft WAL does not help as well. It does NOT contain the commit
 // timestamps. So, for now we're going to not support it.
+//
+// The search connectors are fed from the same events, so the events are also processed without a
+// sink as long as there are search connectors.
 type CDC struct {
 	sync.Mutex
-	sink             Sink
+	sink             Sink // nil if the --cdc flag isn't set.
+	search           *searchSync
 	closer           *z.Closer
 	pendingTxnEvents map[uint64][]CDCEvent
 
@@ -64,26 +68,41 @@ type CDC struct {
 }
 
 func newCDC() *CDC {
-	if Config.ChangeDataConf == "" {
-		return nil
-	}
 	if x.WorkerConfig.LudicrousMode {
-		x.Fatalf("cdc is not supported in ludicrous mode")
+		if Config.ChangeDataConf != "" {
+			x.Fatalf("cdc is not supported in ludicrous mode")
+		}
+		return nil
 	}
 
-	cdcFlag := z.NewSuperFlag(Config.ChangeDataConf).MergeAndCheckDefault(defaultCDCConfig)
-	sink, err := GetSink(cdcFlag)
-	x.Check(err)
+	search, err := getSearchSync()
+	x.Checkf(err, "Invalid --search_sync flag")
 	cdc := &CDC{
-		sink:             sink,
+		search:           search,
 		closer:           z.NewCloser(1),
 		pendingTxnEvents: make(map[uint64][]CDCEvent),
 	}
+	if Config.ChangeDataConf != "" {
+		cdcFlag := z.NewSuperFlag(Config.ChangeDataConf).MergeAndCheckDefault(defaultCDCConfig)
+		cdc.sink, err = GetSink(cdcFlag)
+		x.Check(err)
+	}
 	return cdc
 }
 
+// hasConsumers returns whether the events are sent anywhere.
+func (cdc *CDC) hasConsumers() bool {
+	if cdc.sink != nil {
+		return true
+	}
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	return len(g.state.GetSearchConnectors()) > 0
+}
+
 func (cdc *CDC) getSeenIndex() uint64 {
-	if cdc == nil {
+	if cdc == nil || !cdc.hasConsumers() {
 		return math.MaxUint64
 	}
 	return atomic.LoadUint64(&cdc.seenIndex)
@@ -160,6 +179,9 @@ func (cdc *CDC) Close() {
 	}
 	glog.Infof("closing CDC events...")
 	cdc.closer.SignalAndWait()
+	if cdc.sink == nil {
+		return
+	}
 	err := cdc.sink.Close()
 	glog.Errorf("error while closing sink %v", err)
 }
@@ -183,10 +205,13 @@ func (cdc *CDC) processCDCEvents() {
 				Value: b,
 			}
 		}
-		if err := cdc.sink.Send(batch); err != nil {
-			glog.Errorf("error while sending cdc event to sink %+v", err)
-			return err
+		if cdc.sink != nil {
+			if err := cdc.sink.Send(batch); err != nil {
+				glog.Errorf("error while sending cdc event to sink %+v", err)
+				return err
+			}
 		}
+		cdc.search.send(groups().Ctx(), pending, commitTs)
 		// We successfully sent messages to sink.
 		atomic.StoreUint64(&cdc.sentTs, commitTs)
 		return nil
@@ -223,7 +248,9 @@ func (cdc *CDC) processCDCEvents() {
 			// TODO: For now, do NOT support ludicrous mode.
 
 			switch {
-			case proposal.Mutations.DropOp != pb.Mutations_NONE: // this means its a drop operation
+			// this means its a drop operation. Dropping a predicate is not a transaction, so
+			// it's not committed either.
+			case proposal.Mutations.DropOp != pb.Mutations_NONE || events[0].Type == EventTypeDrop:
 				// if there is DROP ALL or DROP DATA operation, clear pending events also.
 				if proposal.Mutations.DropOp == pb.Mutations_ALL ||
 					proposal.Mutations.DropOp == pb.Mutations_DATA {
@@ -299,6 +326,13 @@ func (cdc *CDC) processCDCEvents() {
 			return
 		case <-jobTick.C:
 			if groups().Node.AmLeader() && EnterpriseEnabled() {
+				if !cdc.hasConsumers() {
+					// Skip the entries, so that the Raft logs can still be truncated.
+					cdc.resetPendingEvents()
+					cdc.updateSeenIndex(groups().Node.Applied.DoneUntil())
+					continue
+				}
+				cdc.search.startBackfills()
 				if err := sendEvents(); err != nil {
 					glog.Errorf("unable to send events %+v", err)
 				}
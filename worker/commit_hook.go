@@ -0,0 +1,171 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// CommitHookDefaults are the default values for the --commit_hook superflag.
+	CommitHookDefaults = "addr=; timeout=5s; policy=fail; retries=0; tls=false"
+
+	// CommitHookMethod is the gRPC method called by the commit hook. It takes an api.TxnContext
+	// and returns an api.Payload, so the service can be written with the dgo protos as:
+	//
+	//	service CommitHook {
+	//	  rpc OnCommit (api.TxnContext) returns (api.Payload) {}
+	//	}
+	CommitHookMethod = "/dgraph.CommitHook/OnCommit"
+
+	// commitHookFail returns an error to the client if the hook fails. The transaction stays
+	// committed, and the error carries its commit timestamp.
+	commitHookFail = "fail"
+	// commitHookIgnore only logs the failures of the hook.
+	commitHookIgnore = "ignore"
+)
+
+// commitHook calls an external gRPC service for every transaction committed by a client, before
+// the commit is acknowledged. It lets applications implement the transactional outbox pattern,
+// e.g. by reading the outbox nodes written by the transaction at its commit timestamp.
+type commitHook struct {
+	addr    string
+	timeout time.Duration
+	policy  string
+	retries int
+	conn    *grpc.ClientConn
+}
+
+// hook is the commit hook set via the --commit_hook flag, or nil.
+var hook *commitHook
+
+func parseCommitHook(sf *z.SuperFlag) (*commitHook, error) {
+	h := &commitHook{
+		addr:    sf.GetString("addr"),
+		policy:  strings.ToLower(sf.GetString("policy")),
+		retries: int(sf.GetInt64("retries")),
+	}
+	if h.addr == "" {
+		return nil, nil
+	}
+	var err error
+	if h.timeout, err = time.ParseDuration(sf.GetString("timeout")); err != nil {
+		return nil, errors.Wrapf(err, "while parsing timeout")
+	}
+	switch {
+	case h.timeout <= 0:
+		return nil, errors.Errorf("timeout must be positive")
+	case h.policy != commitHookFail && h.policy != commitHookIgnore:
+		return nil, errors.Errorf("policy must be one of %s or %s", commitHookFail,
+			commitHookIgnore)
+	case h.retries < 0:
+		return nil, errors.Errorf("retries must be non-negative")
+	}
+	return h, nil
+}
+
+func initCommitHook(sf *z.SuperFlag) {
+	h, err := parseCommitHook(sf)
+	x.Checkf(err, "Invalid --commit_hook flag")
+	if h == nil {
+		return
+	}
+
+	opt := grpc.WithInsecure()
+	if sf.GetBool("tls") {
+		opt = grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{}))
+	}
+	h.conn, err = grpc.Dial(h.addr, opt)
+	x.Checkf(err, "While dialing the commit hook at %s", h.addr)
+	hook = h
+	glog.Infof("Calling the commit hook at %s with timeout %s and policy %s", h.addr,
+		h.timeout, h.policy)
+}
+
+// hookPredicates returns the namespace and the predicates of the transaction, without their
+// group and namespace, which are passed to the commit hook. The reserved predicates are left out.
+func hookPredicates(preds []string) (uint64, []string) {
+	seen := make(map[string]struct{})
+	var ns uint64
+	var res []string
+	for _, p := range preds {
+		// The predicates are prefixed with their group, i.e. gid-attr.
+		if i := strings.IndexByte(p, '-'); i >= 0 {
+			p = p[i+1:]
+		}
+		if len(p) < 8 {
+			continue
+		}
+		if x.IsReservedPredicate(p) {
+			continue
+		}
+		pns, attr := x.ParseNamespaceAttr(p)
+		if _, ok := seen[attr]; !ok {
+			seen[attr] = struct{}{}
+			ns = pns
+			res = append(res, attr)
+		}
+	}
+	return ns, res
+}
+
+// run calls the hook for the committed transaction. The transactions which only touched the
+// reserved predicates, like the GraphQL schema, are skipped. It returns an error only if the hook
+// failed with the fail policy.
+func (h *commitHook) run(ctx context.Context, tc *api.TxnContext, commitTs uint64) error {
+	ns, preds := hookPredicates(tc.Preds)
+	if len(preds) == 0 {
+		return nil
+	}
+	req := &api.TxnContext{StartTs: tc.StartTs, CommitTs: commitTs, Preds: preds}
+	md := metadata.Pairs("namespace", strconv.FormatUint(ns, 10))
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		cctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(ctx, md), h.timeout)
+		err = h.conn.Invoke(cctx, CommitHookMethod, req, &api.Payload{})
+		cancel()
+		if err == nil || attempt >= h.retries || ctx.Err() != nil {
+			break
+		}
+	}
+	if err == nil {
+		return nil
+	}
+
+	ostats.Record(ctx, x.NumCommitHookFailures.M(1))
+	glog.Errorf("Commit hook failed for the transaction committed at %d: %v", commitTs, err)
+	if h.policy == commitHookIgnore {
+		return nil
+	}
+	return errors.Wrapf(err, "transaction committed at %d, but the commit hook failed",
+		commitTs)
+}
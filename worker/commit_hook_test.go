@@ -0,0 +1,114 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestParseCommitHook(t *testing.T) {
+	parse := func(flag string) (*commitHook, error) {
+		return parseCommitHook(z.NewSuperFlag(flag).MergeAndCheckDefault(CommitHookDefaults))
+	}
+
+	h, err := parse("")
+	require.NoError(t, err)
+	require.Nil(t, h)
+
+	h, err = parse("addr=localhost:9000; policy=ignore; retries=2")
+	require.NoError(t, err)
+	require.Equal(t, commitHookIgnore, h.policy)
+	require.Equal(t, 2, h.retries)
+
+	_, err = parse("addr=localhost:9000; policy=retry")
+	require.Error(t, err)
+	_, err = parse("addr=localhost:9000; timeout=0s")
+	require.Error(t, err)
+}
+
+func TestCommitHook(t *testing.T) {
+	var calls []*api.TxnContext
+	var namespaces []string
+	fail := false
+	s := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{},
+		stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		if method != CommitHookMethod {
+			return errors.Errorf("unexpected method %s", method)
+		}
+		req := &api.TxnContext{}
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		calls = append(calls, req)
+		namespaces = append(namespaces, md.Get("namespace")...)
+		if fail {
+			return errors.New("outbox is down")
+		}
+		return stream.SendMsg(&api.Payload{})
+	}))
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go func() { _ = s.Serve(l) }()
+	defer s.Stop()
+
+	h, err := parseCommitHook(z.NewSuperFlag("addr=" + l.Addr().String()).
+		MergeAndCheckDefault(CommitHookDefaults))
+	require.NoError(t, err)
+	h.conn, err = grpc.Dial(h.addr, grpc.WithInsecure())
+	require.NoError(t, err)
+	defer h.conn.Close()
+
+	pred := func(ns uint64, attr string) string {
+		return fmt.Sprintf("1-%s", x.NamespaceAttr(ns, attr))
+	}
+	tc := &api.TxnContext{StartTs: 10, Preds: []string{pred(2, "name"), pred(2, "name"),
+		pred(2, "dgraph.type")}}
+	require.NoError(t, h.run(context.Background(), tc, 12))
+	require.Len(t, calls, 1)
+	require.Equal(t, uint64(12), calls[0].CommitTs)
+	require.Equal(t, []string{"name"}, calls[0].Preds)
+	require.Equal(t, []string{"2"}, namespaces)
+
+	// Only the reserved predicates were changed.
+	tc = &api.TxnContext{StartTs: 13, Preds: []string{pred(0, "dgraph.graphql.schema")}}
+	require.NoError(t, h.run(context.Background(), tc, 14))
+	require.Len(t, calls, 1)
+
+	fail = true
+	tc = &api.TxnContext{StartTs: 15, Preds: []string{pred(0, "name")}}
+	err = h.run(context.Background(), tc, 16)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "transaction committed at 16, but the commit hook failed")
+
+	h.policy = commitHookIgnore
+	h.retries = 1
+	require.NoError(t, h.run(context.Background(), tc, 16))
+	require.Len(t, calls, 4)
+}
@@ -18,7 +18,9 @@ package worker
 
 import (
 	"errors"
+	"time"
 
+	"github.com/dgraph-io/dgraph/types"
 	"github.com/dgraph-io/dgraph/x"
 )
 
@@ -38,3 +40,67 @@ func evalCompare(cmp string, lv, rv int64) bool {
 	x.Panic(errors.New("EvalCompare: unreachable"))
 	return false
 }
+
+// eqKey is the comparable form of a value, as used by eqSet.
+type eqKey struct {
+	tid types.TypeID
+	val interface{}
+}
+
+func toEqKey(v types.Val) (eqKey, bool) {
+	var ok bool
+	switch v.Tid {
+	case types.DateTimeID:
+		var t time.Time
+		// Times at the same instant are equal irrespective of their location.
+		if t, ok = v.Value.(time.Time); ok {
+			return eqKey{tid: v.Tid, val: [2]int64{t.Unix(), int64(t.Nanosecond())}}, true
+		}
+	case types.IntID:
+		_, ok = v.Value.(int64)
+	case types.FloatID:
+		_, ok = v.Value.(float64)
+	case types.StringID, types.DefaultID:
+		_, ok = v.Value.(string)
+	case types.BoolID:
+		_, ok = v.Value.(bool)
+	}
+	return eqKey{tid: v.Tid, val: v.Value}, ok
+}
+
+// eqSet holds the arguments of an eq function. eq can be given thousands of
+// arguments, e.g. through a list variable, so values are looked up in a set instead
+// of being compared against every argument.
+type eqSet map[eqKey]struct{}
+
+// newEqSet returns the set of vals, or nil if any of them can't be compared for
+// equality, in which case the caller should fall back to types.CompareVals.
+func newEqSet(vals []types.Val) eqSet {
+	set := make(eqSet, len(vals))
+	for _, v := range vals {
+		k, ok := toEqKey(v)
+		if !ok {
+			return nil
+		}
+		set[k] = struct{}{}
+	}
+	return set
+}
+
+// matchEq returns whether v is equal to any of vals, using set if it's not nil.
+func matchEq(set eqSet, vals []types.Val, v types.Val) bool {
+	if set != nil {
+		k, ok := toEqKey(v)
+		if !ok {
+			return false
+		}
+		_, ok = set[k]
+		return ok
+	}
+	for _, eqVal := range vals {
+		if types.CompareVals(eq, v, eqVal) {
+			return true
+		}
+	}
+	return false
+}
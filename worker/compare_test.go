@@ -0,0 +1,48 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEqSet(t *testing.T) {
+	var vals []types.Val
+	for i := 0; i < 10000; i++ {
+		vals = append(vals, types.Val{Tid: types.IntID, Value: int64(i * 2)})
+	}
+	set := newEqSet(vals)
+	require.NotNil(t, set)
+	require.True(t, matchEq(set, vals, types.Val{Tid: types.IntID, Value: int64(4242)}))
+	require.False(t, matchEq(set, vals, types.Val{Tid: types.IntID, Value: int64(4243)}))
+	require.False(t, matchEq(set, vals, types.Val{Tid: types.FloatID, Value: float64(4242)}))
+
+	now := time.Now()
+	dates := []types.Val{{Tid: types.DateTimeID, Value: now}}
+	set = newEqSet(dates)
+	require.True(t, matchEq(set, dates, types.Val{Tid: types.DateTimeID, Value: now.UTC()}))
+
+	// Types that can't be hashed fall back to comparing every value.
+	geo := []types.Val{{Tid: types.GeoID, Value: []byte("geo")}}
+	require.Nil(t, newEqSet(geo))
+	strs := []types.Val{{Tid: types.StringID, Value: "a"}, {Tid: types.StringID, Value: "b"}}
+	require.True(t, matchEq(nil, strs, types.Val{Tid: types.StringID, Value: "b"}))
+}
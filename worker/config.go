@@ -45,6 +45,12 @@ type Options struct {
 	PostingDirCompressionLevel int
 	// WALDir is the path to the directory storing the write-ahead log.
 	WALDir string
+	// StorageEngine is the name of the engine keeping the posting store and the write-ahead log.
+	// See StorageEngines.
+	StorageEngine string
+	// InMemory tells Dgraph to keep the postings and the write-ahead log in memory, instead of in
+	// PostingDir and WALDir. It's set by validate when the storage engine doesn't use the disk.
+	InMemory bool
 	// MutationsMode is the mode used to handle mutation requests.
 	MutationsMode int
 	// AuthToken is the token to be passed for Alter HTTP requests.
@@ -92,6 +98,16 @@ func SetConfiguration(newConfig *Options) {
 var AvailableMemory int64
 
 func (opt *Options) validate() {
+	if opt.InMemory && opt.StorageEngine == "" {
+		// The engine was selected with --badger in_memory=true.
+		opt.StorageEngine = MemoryStorageEngine
+	}
+	if opt.StorageEngine == "" {
+		opt.StorageEngine = DefaultStorageEngine
+	}
+	x.Check(checkStorageEngine(opt.StorageEngine))
+	opt.InMemory = opt.StorageEngine == MemoryStorageEngine
+
 	pd, err := filepath.Abs(opt.PostingDir)
 	x.Check(err)
 	wd, err := filepath.Abs(opt.WALDir)
@@ -104,7 +120,7 @@ func (opt *Options) validate() {
 		"Posting and Tmp directory cannot be the same ('%s').", opt.PostingDir)
 	x.AssertTruef(wd != td,
 		"WAL and Tmp directory cannot be the same ('%s').", opt.WALDir)
-	if opt.Audit != nil {
+	if opt.Audit != nil && opt.Audit.Dir != "" {
 		ad, err := filepath.Abs(opt.Audit.Dir)
 		x.Check(err)
 		x.AssertTruef(ad != pd,
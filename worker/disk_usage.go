@@ -0,0 +1,421 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/dustin/go-humanize"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+)
+
+const (
+	// DiskDefaults are the default values for the --disk superflag.
+	DiskDefaults = "max-mb=0; min-free-percent=5; compact-percent=90; window=24h; " +
+		"purge-interval=24h"
+
+	// maxDiskSamples is the number of samples kept over the forecasting window.
+	maxDiskSamples = 96
+	// compactCooldown is the minimum time between two compactions triggered by the disk monitor.
+	compactCooldown = time.Hour
+	// purgeCheckEvery is the number of keys after which the purge checks whether the monitor is
+	// being stopped.
+	purgeCheckEvery = 10000
+)
+
+// ErrDiskFull is returned for writes which are refused because the disk limits set via the
+// --disk flag have been reached.
+var ErrDiskFull = errors.New("Disk usage limit reached. Writes are refused until space is freed")
+
+// TabletUsage is the on-disk size and the growth rate of a single tablet on this node.
+type TabletUsage struct {
+	Predicate    string
+	Bytes        int64
+	GrowthPerDay float64
+}
+
+// DiskUsage reports the disk usage of this node along with a forecast of when the disk limits
+// will be reached.
+type DiskUsage struct {
+	// Size is the number of bytes used by the postings and WAL directories.
+	Size int64
+	// FsTotal and FsFree are the total and available bytes on the file system that holds the
+	// postings directory. They are zero if the file system can't be queried.
+	FsTotal int64
+	FsFree  int64
+	// Headroom is the number of bytes that can be written before a disk limit is reached.
+	Headroom int64
+	// GrowthPerDay is the growth rate of Size over the forecasting window.
+	GrowthPerDay float64
+	// DaysUntilFull is the projected number of days until a disk limit is reached. It is -1 if
+	// the usage isn't growing.
+	DaysUntilFull  float64
+	WritesBlocked  bool
+	LastCompaction time.Time
+	Tablets        []TabletUsage
+}
+
+type diskLimits struct {
+	maxBytes       int64
+	minFreePercent float64
+	compactPercent float64
+	window         time.Duration
+	purgeInterval  time.Duration
+}
+
+func parseDiskLimits(sf *z.SuperFlag) (diskLimits, error) {
+	l := diskLimits{
+		maxBytes:       sf.GetInt64("max-mb") << 20,
+		minFreePercent: sf.GetFloat64("min-free-percent"),
+		compactPercent: sf.GetFloat64("compact-percent"),
+	}
+	var err error
+	if l.window, err = time.ParseDuration(sf.GetString("window")); err != nil {
+		return l, errors.Wrapf(err, "while parsing window")
+	}
+	if l.purgeInterval, err = time.ParseDuration(sf.GetString("purge-interval")); err != nil {
+		return l, errors.Wrapf(err, "while parsing purge-interval")
+	}
+	switch {
+	case l.maxBytes < 0:
+		return l, errors.Errorf("max-mb must be non-negative")
+	case l.minFreePercent < 0 || l.minFreePercent >= 100:
+		return l, errors.Errorf("min-free-percent must be in the range [0, 100)")
+	case l.compactPercent <= 0 || l.compactPercent > 100:
+		return l, errors.Errorf("compact-percent must be in the range (0, 100]")
+	case l.window < time.Minute:
+		return l, errors.Errorf("window must be at least a minute")
+	case l.purgeInterval != 0 && l.purgeInterval < time.Minute:
+		return l, errors.Errorf("purge-interval must be zero or at least a minute")
+	}
+	return l, nil
+}
+
+// headroom returns the number of bytes that can still be written before hitting one of the
+// limits, and the total number of bytes the limit allows. fsTotal is zero if the file system
+// couldn't be queried. budget is zero if no limit applies.
+func (l diskLimits) headroom(size, fsTotal, fsFree int64) (headroom, budget int64) {
+	headroom = math.MaxInt64
+	if fsTotal > 0 {
+		reserve := int64(float64(fsTotal) * l.minFreePercent / 100)
+		headroom = fsFree - reserve
+		budget = size + headroom
+	}
+	if l.maxBytes > 0 && l.maxBytes-size < headroom {
+		headroom = l.maxBytes - size
+		budget = l.maxBytes
+	}
+	if headroom < 0 {
+		headroom = 0
+	}
+	return headroom, budget
+}
+
+type diskSample struct {
+	ts      time.Time
+	size    int64
+	tablets map[string]int64
+}
+
+// growthPerDay returns the least squares estimate of the growth rate of size in bytes per day.
+func growthPerDay(samples []diskSample) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	start := samples[0].ts
+	var n, sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.ts.Sub(start).Hours() / 24
+		y := float64(s.size)
+		n++
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denom
+}
+
+// tabletGrowth returns the growth rate of every tablet in the last sample, computed from the
+// oldest sample that has the tablet.
+func tabletGrowth(samples []diskSample) []TabletUsage {
+	if len(samples) == 0 {
+		return nil
+	}
+	last := samples[len(samples)-1]
+	res := make([]TabletUsage, 0, len(last.tablets))
+	for pred, sz := range last.tablets {
+		tu := TabletUsage{Predicate: pred, Bytes: sz}
+		for _, s := range samples[:len(samples)-1] {
+			old, ok := s.tablets[pred]
+			if !ok {
+				continue
+			}
+			if days := last.ts.Sub(s.ts).Hours() / 24; days > 0 {
+				tu.GrowthPerDay = float64(sz-old) / days
+			}
+			break
+		}
+		res = append(res, tu)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Bytes > res[j].Bytes })
+	return res
+}
+
+// daysUntilFull returns -1 if the usage isn't growing.
+func daysUntilFull(headroom int64, growth float64) float64 {
+	if growth <= 0 {
+		return -1
+	}
+	return float64(headroom) / growth
+}
+
+type diskMonitor struct {
+	db      *badger.DB
+	limits  diskLimits
+	blocked int32
+
+	sync.RWMutex
+	samples []diskSample
+	usage   DiskUsage
+}
+
+var dm *diskMonitor
+
+func dirSize(dir string) int64 {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		glog.V(2).Infof("Error while computing size of %s: %v", dir, err)
+	}
+	return size
+}
+
+func (m *diskMonitor) sample() {
+	lsm, vlog := m.db.Size()
+	s := diskSample{
+		ts:      time.Now(),
+		size:    lsm + vlog + dirSize(Config.WALDir),
+		tablets: make(map[string]int64),
+	}
+	var gid uint32
+	if g := groups(); g != nil {
+		gid = g.groupId()
+	}
+	tablets, _ := tabletSizes(m.db, gid)
+	for pred, t := range tablets {
+		s.tablets[pred] = t.OnDiskBytes
+	}
+
+	usage := DiskUsage{Size: s.size}
+	if total, free, err := x.DiskStats(Config.PostingDir); err == nil {
+		usage.FsTotal, usage.FsFree = total, free
+	}
+	var budget int64
+	usage.Headroom, budget = m.limits.headroom(usage.Size, usage.FsTotal, usage.FsFree)
+
+	m.Lock()
+	m.samples = append(m.samples, s)
+	for len(m.samples) > 1 && s.ts.Sub(m.samples[0].ts) > m.limits.window {
+		m.samples = m.samples[1:]
+	}
+	usage.GrowthPerDay = growthPerDay(m.samples)
+	usage.DaysUntilFull = daysUntilFull(usage.Headroom, usage.GrowthPerDay)
+	usage.Tablets = tabletGrowth(m.samples)
+	usage.LastCompaction = m.usage.LastCompaction
+	usage.WritesBlocked = budget > 0 && usage.Headroom == 0
+	m.usage = usage
+	m.Unlock()
+
+	if usage.WritesBlocked != m.writesBlocked() {
+		if usage.WritesBlocked {
+			glog.Errorf("Disk limit reached. Size: %s. Refusing writes until space is freed.",
+				humanize.IBytes(uint64(usage.Size)))
+		} else {
+			glog.Infof("Disk usage is back under the limit. Accepting writes.")
+		}
+	}
+	var blocked int64
+	if usage.WritesBlocked {
+		blocked = 1
+		atomic.StoreInt32(&m.blocked, 1)
+	} else {
+		atomic.StoreInt32(&m.blocked, 0)
+	}
+	ostats.Record(context.Background(), x.DiskDaysUntilFull.M(usage.DaysUntilFull),
+		x.DiskWritesBlocked.M(blocked))
+
+	if budget > 0 && float64(budget-usage.Headroom) >= float64(budget)*m.limits.compactPercent/100 {
+		m.maybeCompact()
+	}
+}
+
+// maybeCompact runs value log GC and flattens the LSM tree to reclaim space. It does nothing if
+// it has already been done within the compactCooldown.
+func (m *diskMonitor) maybeCompact() {
+	m.RLock()
+	last := m.usage.LastCompaction
+	m.RUnlock()
+	if time.Since(last) < compactCooldown {
+		return
+	}
+
+	glog.Infof("Disk usage is above %.0f%% of the limit. Compacting postings directory.",
+		m.limits.compactPercent)
+	m.compact()
+}
+
+// compact runs value log GC and flattens the LSM tree to reclaim space.
+func (m *diskMonitor) compact() {
+	// The jobs are shared with the admin controls, so that they don't run twice at once, and
+	// value log GC stays paused.
+	if err := sj.beginVlogGC(); err != nil {
+		glog.Infof("Skipping value log GC: %v", err)
+	} else {
+		sj.vlogGC(m.db, 0.5)
+	}
+	if err := sj.beginFlatten(); err != nil {
+		glog.Infof("Skipping flatten: %v", err)
+	} else {
+		sj.flatten(m.db, 1)
+	}
+
+	m.Lock()
+	m.usage.LastCompaction = time.Now()
+	m.Unlock()
+}
+
+// purgeExpired compacts the postings directory if it holds expired entries. Badger hides them
+// from reads, but only drops them from the tables they are compacted out of, which may never
+// happen for the tables which aren't written to anymore.
+func (m *diskMonitor) purgeExpired(closer *z.Closer) {
+	n, err := countExpired(m.db, closer)
+	if err != nil {
+		glog.Warningf("While looking for expired entries: %v", err)
+		return
+	}
+	if n == 0 {
+		return
+	}
+	glog.Infof("Found %d expired entries. Compacting postings directory to purge them.", n)
+	m.compact()
+}
+
+// countExpired returns the number of entries of db which have expired.
+func countExpired(db *badger.DB, closer *z.Closer) (int, error) {
+	txn := db.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+	iopt := badger.DefaultIteratorOptions
+	iopt.AllVersions = true
+	iopt.PrefetchValues = false
+	itr := txn.NewIterator(iopt)
+	defer itr.Close()
+
+	now := uint64(time.Now().Unix())
+	var n, seen int
+	for itr.Rewind(); itr.Valid(); itr.Next() {
+		if seen++; seen%purgeCheckEvery == 0 {
+			select {
+			case <-closer.HasBeenClosed():
+				return 0, errors.New("Stopped before the end of the scan")
+			default:
+			}
+		}
+		if exp := itr.Item().ExpiresAt(); exp > 0 && exp <= now {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *diskMonitor) writesBlocked() bool {
+	return atomic.LoadInt32(&m.blocked) == 1
+}
+
+func (m *diskMonitor) run(closer *z.Closer) {
+	defer closer.Done()
+
+	interval := m.limits.window / maxDiskSamples
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// A nil channel never fires, so there are no purges if they're disabled.
+	var purgeC <-chan time.Time
+	if m.limits.purgeInterval > 0 {
+		purgeTicker := time.NewTicker(m.limits.purgeInterval)
+		defer purgeTicker.Stop()
+		purgeC = purgeTicker.C
+	}
+
+	m.sample()
+	for {
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case <-ticker.C:
+			m.sample()
+		case <-purgeC:
+			m.purgeExpired(closer)
+		}
+	}
+}
+
+// checkDiskLimits returns ErrDiskFull if writes are being refused.
+func checkDiskLimits() error {
+	if dm != nil && dm.writesBlocked() {
+		return ErrDiskFull
+	}
+	return nil
+}
+
+// GetDiskUsage returns the disk usage of this node as of the last sample, or nil if the disk
+// monitor isn't running.
+func GetDiskUsage() *DiskUsage {
+	if dm == nil {
+		return nil
+	}
+	dm.RLock()
+	defer dm.RUnlock()
+	usage := dm.usage
+	return &usage
+}
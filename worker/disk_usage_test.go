@@ -0,0 +1,114 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskGrowthForecast(t *testing.T) {
+	start := time.Now()
+	var samples []diskSample
+	for i := 0; i < 5; i++ {
+		samples = append(samples, diskSample{
+			ts:   start.Add(time.Duration(i) * 6 * time.Hour),
+			size: int64(1000 + 250*i),
+			tablets: map[string]int64{
+				"name": int64(500 + 100*i),
+				"age":  500,
+			},
+		})
+	}
+	// The last sample has a tablet the others don't.
+	samples[4].tablets["friend"] = 10
+
+	require.InDelta(t, 1000, growthPerDay(samples), 1e-6)
+	require.Equal(t, float64(0), growthPerDay(samples[:1]))
+
+	tablets := tabletGrowth(samples)
+	require.Len(t, tablets, 3)
+	require.Equal(t, "name", tablets[0].Predicate)
+	require.InDelta(t, 400, tablets[0].GrowthPerDay, 1e-6)
+	require.Equal(t, "age", tablets[1].Predicate)
+	require.Equal(t, float64(0), tablets[1].GrowthPerDay)
+	require.Equal(t, "friend", tablets[2].Predicate)
+	require.Equal(t, float64(0), tablets[2].GrowthPerDay)
+
+	require.Equal(t, float64(-1), daysUntilFull(100, 0))
+	require.Equal(t, float64(2), daysUntilFull(2000, 1000))
+}
+
+func TestDiskLimitsHeadroom(t *testing.T) {
+	l, err := parseDiskLimits(z.NewSuperFlag("max-mb=1").MergeAndCheckDefault(DiskDefaults))
+	require.NoError(t, err)
+
+	// The max size is the tighter limit.
+	headroom, budget := l.headroom(1<<19, 100<<20, 50<<20)
+	require.Equal(t, int64(1<<19), headroom)
+	require.Equal(t, int64(1<<20), budget)
+
+	// The free space on the file system is the tighter limit. 5% of it is reserved.
+	l.maxBytes = 0
+	headroom, budget = l.headroom(1<<19, 100<<20, 6<<20)
+	require.Equal(t, int64(1<<20), headroom)
+	require.Equal(t, int64(1<<19+1<<20), budget)
+
+	// Over the limit.
+	l.maxBytes = 1 << 20
+	headroom, _ = l.headroom(2<<20, 100<<20, 50<<20)
+	require.Equal(t, int64(0), headroom)
+
+	// No limits apply.
+	l.maxBytes = 0
+	_, budget = l.headroom(2<<20, 0, 0)
+	require.Equal(t, int64(0), budget)
+
+	_, err = parseDiskLimits(z.NewSuperFlag("window=1s").MergeAndCheckDefault(DiskDefaults))
+	require.Error(t, err)
+	_, err = parseDiskLimits(z.NewSuperFlag("purge-interval=1s").
+		MergeAndCheckDefault(DiskDefaults))
+	require.Error(t, err)
+}
+
+func TestCountExpired(t *testing.T) {
+	db, err := badger.OpenManaged(badger.DefaultOptions("").WithInMemory(true).
+		WithLogger(nil))
+	require.NoError(t, err)
+	defer db.Close()
+
+	wb := db.NewManagedWriteBatch()
+	require.NoError(t, wb.SetEntryAt(badger.NewEntry([]byte("live"), []byte("v")), 1))
+	require.NoError(t, wb.SetEntryAt(badger.NewEntry([]byte("expired"), []byte("v")).
+		WithTTL(time.Second), 1))
+	require.NoError(t, wb.SetEntryAt(badger.NewEntry([]byte("later"), []byte("v")).
+		WithTTL(time.Hour), 1))
+	require.NoError(t, wb.Flush())
+
+	closer := z.NewCloser(0)
+	n, err := countExpired(db, closer)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	time.Sleep(2 * time.Second)
+	n, err = countExpired(db, closer)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
@@ -21,8 +21,12 @@ import (
 	"context"
 	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"math"
+	"os"
+	"path/filepath"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -41,6 +45,7 @@ import (
 
 	"github.com/dgraph-io/badger/v3"
 	bpb "github.com/dgraph-io/badger/v3/pb"
+	"github.com/dgraph-io/badger/v3/y"
 	"github.com/dgraph-io/dgraph/conn"
 	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
@@ -65,8 +70,22 @@ type node struct {
 	closer  *z.Closer
 
 	checkpointTs uint64 // Timestamp corresponding to checkpoint.
+	maxUid       uint64 // Max UID seen in applied mutations.
+	maxNsId      uint64 // Max namespace ID seen in applied mutations.
 	streaming    int32  // Used to avoid calculating snapshot
 
+	// observedGen is incremented when the stored ids are reset, so that the scans started before
+	// don't write their result. It's protected by observedMu, like the observedIdsFile.
+	observedMu  sync.Mutex
+	observedGen uint64
+
+	// The entries up to replayUntil were committed before the restart. Their mutations are
+	// applied by up to replayConcurrency transactions at once.
+	replayUntil       uint64
+	replayConcurrency int
+	// cloned is set if the WAL was copied from another member of the group.
+	cloned bool
+
 	// Used to track the ops going on in the system.
 	ops         map[op]*z.Closer
 	opsLock     sync.Mutex
@@ -93,6 +112,10 @@ func (id op) String() string {
 		return "opBackup"
 	case opPredMove:
 		return "opPredMove"
+	case opTierMove:
+		return "opTierMove"
+	case opReEncrypt:
+		return "opReEncrypt"
 	default:
 		return "opUnknown"
 	}
@@ -105,6 +128,8 @@ const (
 	opRestore
 	opBackup
 	opPredMove
+	opTierMove
+	opReEncrypt
 )
 
 // startTask is used to check whether an op is already running. If a rollup is running,
@@ -161,7 +186,14 @@ func (n *node) startTask(id op) (*z.Closer, error) {
 			delete(n.ops, otherId)
 			otherCloser.SignalAndWait()
 		}
-	case opSnapshot, opIndexing, opPredMove:
+	case opReEncrypt:
+		// The re-encryption cutover swaps the stores under the other operations, so it cancels
+		// all of them.
+		for otherId, otherCloser := range n.ops {
+			delete(n.ops, otherId)
+			otherCloser.SignalAndWait()
+		}
+	case opSnapshot, opIndexing, opPredMove, opTierMove:
 		for otherId, otherCloser := range n.ops {
 			if otherId == opRollup {
 				// Remove from map and signal the closer to cancel the operation.
@@ -251,6 +283,8 @@ func newNode(store *raftwal.DiskStorage, gid uint32, id uint64, myAddr string) *
 		closer:     z.NewCloser(4), // Matches CLOSER:1
 		ops:        make(map[op]*z.Closer),
 		cdcTracker: newCDC(),
+
+		replayConcurrency: int(x.WorkerConfig.Raft.GetInt64("replay-concurrency")),
 	}
 	if x.WorkerConfig.LudicrousMode {
 		n.ex = newExecutor(&m.Applied, x.WorkerConfig.LudicrousConcurrency)
@@ -305,6 +339,123 @@ func detectPendingTxns(attr string) error {
 	return errHasPendingTxns
 }
 
+// storeMax sets addr to val, if val is larger.
+func storeMax(addr *uint64, val uint64) {
+	for cur := atomic.LoadUint64(addr); val > cur; cur = atomic.LoadUint64(addr) {
+		if atomic.CompareAndSwapUint64(addr, cur, val) {
+			return
+		}
+	}
+}
+
+// observeIds keeps track of the max UID and namespace ID used in mutations, so that Zero can
+// refuse to move its leases below them.
+func (n *node) observeIds(edge *pb.DirectedEdge) {
+	// Mutations can be applied concurrently while replaying the WAL.
+	storeMax(&n.maxUid, x.Max(edge.Entity, edge.ValueId))
+	storeMax(&n.maxNsId, x.ParseNamespace(edge.Attr))
+}
+
+// observedIdsFile is the file of the postings directory holding the max UID and namespace ID
+// found in the stored data, so that the next start only scans the data written since.
+const observedIdsFile = "observed_ids.json"
+
+type observedIds struct {
+	MaxUid  uint64 `json:"max_uid"`
+	MaxNsId uint64 `json:"max_ns_id"`
+	// ScannedTs is the version up to which the data has been scanned.
+	ScannedTs uint64 `json:"scanned_ts"`
+}
+
+// observeStoredIds derives the max UID and namespace ID from the keys of the stored data, like
+// the restore of a backup does. The data may have been bulk loaded or restored without going
+// through the mutations seen by observeIds. Only the versions written since the last scan are
+// read, and badger skips the tables which don't have any.
+func (n *node) observeStoredIds() {
+	var ids observedIds
+	path := filepath.Join(Config.PostingDir, observedIdsFile)
+	n.observedMu.Lock()
+	gen := n.observedGen
+	if !Config.InMemory {
+		if data, err := ioutil.ReadFile(path); err == nil {
+			if err := json.Unmarshal(data, &ids); err != nil {
+				glog.Warningf("Scanning all the data for the ids in use. Invalid %s: %v",
+					path, err)
+				ids = observedIds{}
+			}
+		} else if !os.IsNotExist(err) {
+			glog.Warningf("Scanning all the data for the ids in use. Can't read %s: %v",
+				path, err)
+		}
+	}
+	n.observedMu.Unlock()
+
+	scannedTs := pstore.MaxVersion()
+	txn := pstore.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+	iopt := badger.DefaultIteratorOptions
+	iopt.PrefetchValues = false
+	iopt.SinceTs = ids.ScannedTs
+	it := txn.NewIterator(iopt)
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		pk, err := x.Parse(it.Item().Key())
+		if err != nil {
+			continue
+		}
+		ids.MaxUid = x.Max(ids.MaxUid, pk.Uid)
+		ids.MaxNsId = x.Max(ids.MaxNsId, x.ParseNamespace(pk.Attr))
+	}
+	storeMax(&n.maxUid, ids.MaxUid)
+	storeMax(&n.maxNsId, ids.MaxNsId)
+	glog.Infof("Max UID in the stored data: %d. Max namespace ID: %d", ids.MaxUid, ids.MaxNsId)
+
+	if Config.InMemory {
+		return
+	}
+	ids.ScannedTs = scannedTs
+	n.observedMu.Lock()
+	defer n.observedMu.Unlock()
+	if gen != n.observedGen {
+		return
+	}
+	if err := writeObservedIds(path, ids); err != nil {
+		glog.Warningf("While writing %s: %v", path, err)
+	}
+}
+
+// resetObservedIds removes the result of the previous scans, before the data is replaced with
+// data which keeps its versions, like a snapshot.
+func (n *node) resetObservedIds() {
+	n.observedMu.Lock()
+	defer n.observedMu.Unlock()
+	n.observedGen++
+	path := filepath.Join(Config.PostingDir, observedIdsFile)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		glog.Warningf("While removing %s: %v", path, err)
+	}
+}
+
+func writeObservedIds(path string, ids observedIds) error {
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// resetCaches clears the cached posting lists after the proposal at index dropped or rebuilt the
+// data. The index becomes the epoch of the cache tier, so the lists cached before aren't read.
+func resetCaches(index uint64) {
+	posting.ResetCache()
+	posting.SetCacheTierEpoch(index)
+}
+
 // We don't support schema mutations across nodes in a transaction.
 // Wait for all transactions to either abort or complete and all write transactions
 // involving the predicate are aborted until schema mutations are done.
@@ -317,9 +468,12 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 		if err := posting.DeleteData(); err != nil {
 			return err
 		}
+		if err := clearColdTablets(ctx); err != nil {
+			return err
+		}
 
 		// Clear entire cache.
-		posting.ResetCache()
+		resetCaches(proposal.Index)
 		return nil
 	}
 
@@ -333,7 +487,7 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 		}
 
 		// Clear entire cache.
-		posting.ResetCache()
+		resetCaches(proposal.Index)
 
 		if groups().groupId() == 1 {
 			initialSchema := schema.InitialSchema(x.GalaxyNamespace)
@@ -385,6 +539,9 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 			n.ex.waitForActiveMutations()
 		}
 
+		if err := setFulltextDictionaries(ctx, proposal.Mutations); err != nil {
+			return err
+		}
 		if err := runSchemaMutation(ctx, proposal.Mutations.Schema, startTs); err != nil {
 			return err
 		}
@@ -392,7 +549,7 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 		// Clear the entire cache if there is a schema update because the index rebuild
 		// will invalidate the state.
 		if len(proposal.Mutations.Schema) > 0 {
-			posting.ResetCache()
+			resetCaches(proposal.Index)
 		}
 
 		for _, tupdate := range proposal.Mutations.Types {
@@ -423,6 +580,10 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 			span.Annotatef(nil, "Deleting predicate: %s", edge.Attr)
 			return posting.DeletePredicate(ctx, edge.Attr)
 		}
+		if schema.State().ColdTablet(edge.Attr) != nil {
+			return errColdPredicate(edge.Attr)
+		}
+		n.observeIds(edge)
 		// Don't derive schema when doing deletion.
 		if edge.Op == pb.DirectedEdge_DEL {
 			continue
@@ -486,6 +647,14 @@ func (n *node) applyMutations(ctx context.Context, proposal *pb.Proposal) (rerr
 	// Discard the posting lists from cache to release memory at the end.
 	defer txn.Update()
 
+	if len(m.IndexRepairs) > 0 {
+		// The repairs of the index verification are the mutations of a txn of their own, so that
+		// every replica applies them, and they're committed at a commit ts handed out by Zero.
+		if err := txn.RepairIndex(ctx, m.IndexRepairs); err != nil {
+			return err
+		}
+	}
+
 	process := func(edges []*pb.DirectedEdge) error {
 		var retries int
 		for _, edge := range edges {
@@ -628,6 +797,7 @@ func (n *node) applyCommitted(proposal *pb.Proposal, key uint64) error {
 		if err := handleRestoreProposal(ctx, proposal.Restore); err != nil {
 			return err
 		}
+		posting.SetCacheTierEpoch(proposal.Index)
 
 		// Call commitOrAbort to update the group checksums.
 		ts := proposal.Restore.RestoreTs
@@ -637,6 +807,10 @@ func (n *node) applyCommitted(proposal *pb.Proposal, key uint64) error {
 			},
 		})
 
+	case proposal.TierTablet != nil:
+		n.elog.Printf("Changing tier of predicate: %s", proposal.TierTablet.Predicate)
+		return n.applyTierTablet(ctx, proposal.TierTablet)
+
 	case proposal.DeleteNs != nil:
 		x.AssertTrue(proposal.DeleteNs.Namespace != x.GalaxyNamespace)
 		n.elog.Printf("Deleting namespace: %d", proposal.DeleteNs.Namespace)
@@ -645,6 +819,13 @@ func (n *node) applyCommitted(proposal *pb.Proposal, key uint64) error {
 	case proposal.CdcState != nil:
 		n.cdcTracker.updateCDCState(proposal.CdcState)
 		return nil
+
+	case proposal.ReencryptCutover != nil:
+		if proposal.ReencryptCutover.NodeId != n.Id {
+			return nil
+		}
+		n.elog.Printf("Cutting over to the re-encrypted copy")
+		return n.applyReEncryptCutover()
 	}
 	x.Fatalf("Unknown proposal: %+v", proposal)
 	return nil
@@ -665,29 +846,28 @@ func (n *node) processTabletSizes() {
 	}
 }
 
+// appliedProposal records the outcome of a proposal, to skip it if it's applied again.
+type appliedProposal struct {
+	err  error
+	size int
+	seen time.Time
+}
+
 func (n *node) processApplyCh() {
 	defer n.closer.Done() // CLOSER:1
 
-	type P struct {
-		err  error
-		size int
-		seen time.Time
-	}
-	previous := make(map[uint64]*P)
+	previous := make(map[uint64]*appliedProposal)
 
 	// This function must be run serially.
 	handle := func(entries []raftpb.Entry) {
 		var totalSize int64
-		for _, entry := range entries {
+		proposals := make([]*pb.Proposal, len(entries))
+		keys := make([]uint64, len(entries))
+		for i, entry := range entries {
 			x.AssertTrue(len(entry.Data) > 0)
 
-			// We use the size as a double check to ensure that we're
-			// working with the same proposal as before.
-			psz := entry.Size()
-			totalSize += int64(psz)
-
 			var proposal pb.Proposal
-			key := binary.BigEndian.Uint64(entry.Data[:8])
+			keys[i] = binary.BigEndian.Uint64(entry.Data[:8])
 			x.Check(proposal.Unmarshal(entry.Data[8:]))
 			proposal.Index = entry.Index
 
@@ -699,6 +879,18 @@ func (n *node) processApplyCh() {
 			if x.WorkerConfig.LudicrousMode && proposal.Mutations != nil {
 				proposal.Mutations.StartTs = State.GetTimestamp(false)
 			}
+			proposals[i] = &proposal
+		}
+
+		// Errors of the proposals replayed concurrently, by index.
+		var replayed map[uint64]error
+		for i, entry := range entries {
+			proposal, key := proposals[i], keys[i]
+
+			// We use the size as a double check to ensure that we're
+			// working with the same proposal as before.
+			psz := entry.Size()
+			totalSize += int64(psz)
 
 			var perr error
 			p, ok := previous[key]
@@ -711,9 +903,16 @@ func (n *node) processApplyCh() {
 			} else {
 				// if this applyCommited fails, how do we ensure
 				start := time.Now()
-				perr = n.applyCommitted(&proposal, key)
+				if err, ok := replayed[proposal.Index]; ok {
+					perr = err
+				} else if num := n.replayable(proposals[i:], keys[i:], previous); num > 1 {
+					replayed = n.replayMutations(proposals[i:i+num], keys[i:i+num])
+					perr = replayed[proposal.Index]
+				} else {
+					perr = n.applyCommitted(proposal, key)
+				}
 				if key != 0 {
-					p := &P{err: perr, size: psz, seen: time.Now()}
+					p := &appliedProposal{err: perr, size: psz, seen: time.Now()}
 					previous[key] = p
 				}
 				if perr != nil {
@@ -766,6 +965,98 @@ func (n *node) processApplyCh() {
 	}
 }
 
+// replayable returns the number of proposals at the start of the given ones which can be replayed
+// concurrently. Those are the mutations committed before the restart which only touch predicates
+// with a known schema, so that applying them in a different order gives the same result. The
+// oracle deltas which don't commit or abort any transaction can be part of them too.
+func (n *node) replayable(proposals []*pb.Proposal, keys []uint64,
+	previous map[uint64]*appliedProposal) int {
+	if n.replayConcurrency < 2 || x.WorkerConfig.LudicrousMode {
+		return 0
+	}
+	seen := make(map[uint64]struct{})
+	for i, proposal := range proposals {
+		if proposal.Index > n.replayUntil {
+			return i
+		}
+		// The proposals applied more than once must be seen in order.
+		if key := keys[i]; key != 0 {
+			if _, ok := previous[key]; ok {
+				return i
+			}
+			if _, ok := seen[key]; ok {
+				return i
+			}
+			seen[key] = struct{}{}
+		}
+
+		m := proposal.Mutations
+		switch {
+		case proposal.Delta != nil && len(proposal.Delta.Txns) == 0:
+			continue
+		case m == nil, m.DropOp != pb.Mutations_NONE, m.StartTs == 0, len(m.Schema) > 0,
+			len(m.Types) > 0:
+			return i
+		}
+		for _, edge := range m.Edges {
+			if edge.Entity == 0 && bytes.Equal(edge.Value, []byte(x.Star)) {
+				return i
+			}
+			if _, err := schema.State().TypeOf(edge.Attr); err != nil {
+				return i
+			}
+		}
+	}
+	return len(proposals)
+}
+
+// replayMutations applies the given mutations, those of different transactions concurrently, and
+// then the oracle deltas among them. It returns the error of each proposal by index.
+func (n *node) replayMutations(proposals []*pb.Proposal, keys []uint64) map[uint64]error {
+	// The mutations of a transaction are applied in order.
+	var txns [][]int
+	var deltas []int
+	byTs := make(map[uint64]int)
+	for i, proposal := range proposals {
+		if proposal.Mutations == nil {
+			deltas = append(deltas, i)
+			continue
+		}
+		ts := proposal.Mutations.StartTs
+		if _, ok := byTs[ts]; !ok {
+			byTs[ts] = len(txns)
+			txns = append(txns, nil)
+		}
+		txns[byTs[ts]] = append(txns[byTs[ts]], i)
+	}
+
+	errs := make([]error, len(proposals))
+	throttle := y.NewThrottle(n.replayConcurrency)
+	for _, txn := range txns {
+		x.Check(throttle.Do())
+		go func(txn []int) {
+			defer throttle.Done(nil)
+			for _, i := range txn {
+				errs[i] = n.applyCommitted(proposals[i], keys[i])
+			}
+		}(txn)
+	}
+	x.Check(throttle.Finish())
+	for _, i := range deltas {
+		errs[i] = n.applyCommitted(proposals[i], keys[i])
+	}
+	if len(txns) > 0 {
+		glog.V(2).Infof("Replayed %d mutations of %d transactions concurrently",
+			len(proposals)-len(deltas), len(txns))
+	}
+
+	replayed := make(map[uint64]error, len(proposals))
+	for i, proposal := range proposals {
+		replayed[proposal.Index] = errs[i]
+	}
+	return replayed
+}
+
 // TODO(Anurag - 4 May 2020): Are we using pkey? Remove if unused.
 func (n *node) commitOrAbort(pkey uint64, delta *pb.OracleDelta) error {
 	// First let's commit all mutations to disk.
@@ -895,14 +1186,17 @@ func (n *node) retrieveSnapshot(snap pb.Snapshot) error {
 	// commits up until then have already been written to pstore. And the way we take snapshots, we
 	// keep all the pre-writes for a pending transaction, so they will come back to memory, as Raft
 	// logs are replayed.
+	n.resetObservedIds()
 	if err := n.populateSnapshot(snap, pool); err != nil {
 		return errors.Wrapf(err, "cannot retrieve snapshot from peer")
 	}
+	go n.observeStoredIds()
 	// Populate shard stores the streamed data directly into db, so we need to refresh
 	// schema for current group id
 	if err := schema.LoadFromDb(); err != nil {
 		return errors.Wrapf(err, "while initializing schema")
 	}
+	posting.SetCacheTierEpoch(snap.CacheEpoch)
 	groups().triggerMembershipSync()
 	return nil
 }
@@ -1397,6 +1691,31 @@ func (n *node) calculateTabletSizes() {
 		// Only leader sends the tablet size updates to Zero. No one else does.
 		return
 	}
+	tablets, total := tabletSizes(pstore, n.gid)
+
+	if len(tablets) == 0 {
+		glog.V(2).Infof("No tablets found.")
+		return
+	}
+	// Update Zero with the tablet sizes. If Zero sees a tablet which does not belong to
+	// this group, it would send instruction to delete that tablet. There's an edge case
+	// here if the followers are still running Rollup, and happen to read a key before and
+	// write after the tablet deletion, causing that tablet key to resurface. Then, only the
+	// follower would have that key, not the leader.
+	// However, if the follower then becomes the leader, we'd be able to get rid of that
+	// key then. Alternatively, we could look into cancelling the Rollup if we see a
+	// predicate deletion.
+	if err := groups().doSendMembership(tablets); err != nil {
+		glog.Warningf("While sending membership to Zero. Error: %v", err)
+	} else {
+		glog.V(2).Infof("Sent tablet size update to Zero. Total size: %s",
+			humanize.Bytes(uint64(total)))
+	}
+}
+
+// tabletSizes returns the on-disk size of the tablets in db, along with the total size of
+// the tables which were counted.
+func tabletSizes(db *badger.DB, gid uint32) (map[string]*pb.Tablet, int64) {
 	var total int64
 	tablets := make(map[string]*pb.Tablet)
 	updateSize := func(tinfo badger.TableInfo) {
@@ -1411,7 +1730,7 @@ func (n *node) calculateTabletSizes() {
 			tablet.UncompressedBytes += int64(tinfo.UncompressedSize)
 		} else {
 			tablets[pred] = &pb.Tablet{
-				GroupId:           n.gid,
+				GroupId:           gid,
 				Predicate:         pred,
 				OnDiskBytes:       int64(tinfo.OnDiskSize),
 				UncompressedBytes: int64(tinfo.UncompressedSize),
@@ -1420,7 +1739,7 @@ func (n *node) calculateTabletSizes() {
 		total += int64(tinfo.OnDiskSize)
 	}
 
-	tableInfos := pstore.Tables()
+	tableInfos := db.Tables()
 	glog.V(2).Infof("Calculating tablet sizes. Found %d tables\n", len(tableInfos))
 	for _, tinfo := range tableInfos {
 		left, err := x.Parse(tinfo.Left)
@@ -1441,25 +1760,7 @@ func (n *node) calculateTabletSizes() {
 			glog.V(3).Info("Skipping table not owned by one predicate")
 		}
 	}
-
-	if len(tablets) == 0 {
-		glog.V(2).Infof("No tablets found.")
-		return
-	}
-	// Update Zero with the tablet sizes. If Zero sees a tablet which does not belong to
-	// this group, it would send instruction to delete that tablet. There's an edge case
-	// here if the followers are still running Rollup, and happen to read a key before and
-	// write after the tablet deletion, causing that tablet key to resurface. Then, only the
-	// follower would have that key, not the leader.
-	// However, if the follower then becomes the leader, we'd be able to get rid of that
-	// key then. Alternatively, we could look into cancelling the Rollup if we see a
-	// predicate deletion.
-	if err := groups().doSendMembership(tablets); err != nil {
-		glog.Warningf("While sending membership to Zero. Error: %v", err)
-	} else {
-		glog.V(2).Infof("Sent tablet size update to Zero. Total size: %s",
-			humanize.Bytes(uint64(total)))
-	}
+	return tablets, total
 }
 
 var errNoConnection = errors.New("No connection exists")
@@ -1670,9 +1971,10 @@ func (n *node) calculateSnapshot(startIdx, lastIdx, minPendingStart uint64) (*pb
 	}
 
 	result := &pb.Snapshot{
-		Context: n.RaftContext,
-		Index:   snapshotIdx,
-		ReadTs:  maxCommitTs,
+		Context:    n.RaftContext,
+		Index:      snapshotIdx,
+		ReadTs:     maxCommitTs,
+		CacheEpoch: posting.CacheTierEpoch(),
 	}
 	span.Annotatef(nil, "Got snapshot: %+v", result)
 	return result, nil
@@ -1743,6 +2045,10 @@ func (n *node) InitAndStartNode() {
 
 	if restart {
 		glog.Infof("Restarting node for group: %d\n", n.gid)
+		hs, err := n.Store.HardState()
+		x.Checkf(err, "Unable to get the hard state")
+		n.replayUntil = hs.Commit
+
 		sp, err := n.Store.Snapshot()
 		x.Checkf(err, "Unable to get existing snapshot")
 		if !raft.IsEmptySnap(sp) {
@@ -1753,6 +2059,11 @@ func (n *node) InitAndStartNode() {
 			// zero-member Raft group.
 			n.SetConfState(&sp.Metadata.ConfState)
 
+			// The entries replayed after the snapshot update the epoch again if needed.
+			var snap pb.Snapshot
+			x.Check(snap.Unmarshal(sp.Data))
+			posting.SetCacheTierEpoch(snap.CacheEpoch)
+
 			members := groups().members(n.gid)
 			for _, id := range sp.Metadata.ConfState.Nodes {
 				m, ok := members[id]
@@ -1761,6 +2072,12 @@ func (n *node) InitAndStartNode() {
 				}
 			}
 		}
+		if n.cloned {
+			// The copied WAL doesn't make this node a member of the group yet. Once it is, the
+			// leader only sends the entries written after the copy.
+			glog.Infoln("Trying to join peers with the copied WAL.")
+			n.retryUntilSuccess(n.joinPeers, time.Second)
+		}
 		n.SetRaft(raft.RestartNode(n.Cfg))
 		glog.V(2).Infoln("Restart node complete")
 
@@ -21,9 +21,11 @@ import (
 	"os"
 	"testing"
 
+	"github.com/dgraph-io/badger/v3"
 	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/raftwal"
+	"github.com/dgraph-io/dgraph/schema"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/stretchr/testify/require"
 	"go.etcd.io/etcd/raft/raftpb"
@@ -114,3 +116,89 @@ func TestCalculateSnapshot(t *testing.T) {
 	require.NoError(t, err)
 	require.Nil(t, snap)
 }
+
+func TestReplayable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "raftwal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ds := raftwal.Init(dir)
+	defer ds.Close()
+
+	n := newNode(ds, 1, 1, "")
+	n.replayUntil = 6
+	n.replayConcurrency = 4
+	require.NoError(t, schema.ParseBytes([]byte("replayed: string ."), 1))
+
+	mutation := func(index, startTs uint64, attr string) *pb.Proposal {
+		edge := &pb.DirectedEdge{Entity: 1, Attr: x.GalaxyAttr(attr), Value: []byte("a")}
+		return &pb.Proposal{Index: index, Mutations: &pb.Mutations{StartTs: startTs,
+			Edges: []*pb.DirectedEdge{edge}}}
+	}
+	proposals := []*pb.Proposal{
+		mutation(1, 1, "replayed"),
+		mutation(2, 2, "replayed"),
+		{Index: 3, Delta: &pb.OracleDelta{MaxAssigned: 2}},
+		{Index: 4, Delta: &pb.OracleDelta{Txns: []*pb.TxnStatus{{StartTs: 1, CommitTs: 3}}}},
+		mutation(5, 3, "replayed"),
+		mutation(6, 4, "unknown"),
+		mutation(7, 5, "replayed"),
+	}
+	keys := []uint64{1, 2, 3, 4, 5, 6, 7}
+	previous := make(map[uint64]*appliedProposal)
+
+	// The commits, the predicates without schema and the new entries stop the replay, but not
+	// the deltas which only move MaxAssigned forward.
+	require.Equal(t, 3, n.replayable(proposals, keys, previous))
+	require.Equal(t, 0, n.replayable(proposals[3:], keys[3:], previous))
+	require.Equal(t, 1, n.replayable(proposals[4:], keys[4:], previous))
+	require.Equal(t, 0, n.replayable(proposals[6:], keys[6:], previous))
+
+	// The proposals seen before are applied serially.
+	require.Equal(t, 2, n.replayable(proposals, []uint64{1, 2, 1}, previous))
+	previous[2] = &appliedProposal{}
+	require.Equal(t, 1, n.replayable(proposals, keys, previous))
+
+	n.replayConcurrency = 1
+	require.Equal(t, 0, n.replayable(proposals, keys, previous))
+}
+
+func TestObserveStoredIds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "observed")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := badger.OpenManaged(badger.DefaultOptions(dir).WithLogger(nil))
+	require.NoError(t, err)
+	defer db.Close()
+
+	defer func(ps *badger.DB, pdir string, inMemory bool) {
+		pstore, Config.PostingDir, Config.InMemory = ps, pdir, inMemory
+	}(pstore, Config.PostingDir, Config.InMemory)
+	pstore, Config.PostingDir, Config.InMemory = db, dir, false
+
+	write := func(uid, ts uint64) {
+		wb := db.NewManagedWriteBatch()
+		require.NoError(t, wb.SetEntryAt(badger.NewEntry(
+			x.DataKey(x.NamespaceAttr(2, "name"), uid), nil), ts))
+		require.NoError(t, wb.Flush())
+	}
+	write(10, 5)
+	n := &node{}
+	n.observeStoredIds()
+	require.Equal(t, uint64(10), n.maxUid)
+	require.Equal(t, uint64(2), n.maxNsId)
+
+	// The next scans only read the versions written since, on top of the stored result.
+	write(20, 6)
+	write(30, 4)
+	n = &node{}
+	n.observeStoredIds()
+	require.Equal(t, uint64(20), n.maxUid)
+	require.Equal(t, uint64(2), n.maxNsId)
+
+	// A reset scans everything again, like after a snapshot.
+	n.resetObservedIds()
+	n = &node{}
+	n.observeStoredIds()
+	require.Equal(t, uint64(30), n.maxUid)
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/types/facets"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// dryRunMutations runs the edges of m in a transaction which isn't registered with the oracle,
+// so nothing gets written and there's nothing to commit or abort afterwards. The edges are
+// validated the way applying them would, against the schema which would be derived for the
+// predicates without one, and txnCtx is filled with the conflict keys and predicates which
+// committing them would touch.
+func dryRunMutations(ctx context.Context, txnCtx *api.TxnContext, m *pb.Mutations) error {
+	if len(m.Schema) > 0 || len(m.Types) > 0 || m.DropOp != pb.Mutations_NONE {
+		return errors.Errorf("Only mutations of edges can be dry run")
+	}
+	ctx = schema.GetWriteContext(ctx)
+
+	derived := make(map[string]pb.SchemaUpdate)
+	for _, edge := range m.Edges {
+		if schema.State().ColdTablet(edge.Attr) != nil {
+			return errColdPredicate(edge.Attr)
+		}
+		if edge.Op == pb.DirectedEdge_DEL {
+			continue
+		}
+		if _, ok := derived[edge.Attr]; ok {
+			continue
+		}
+		if _, err := schema.State().TypeOf(edge.Attr); err == nil {
+			continue
+		}
+		su := derivedSchema(edge.Attr, posting.TypeID(edge),
+			m.GetMetadata().GetPredHints()[edge.Attr])
+		if err := checkSchema(&su); err != nil {
+			return err
+		}
+		derived[edge.Attr] = su
+	}
+
+	txn := posting.NewTxn(m.StartTs)
+	run := func(edge *pb.DirectedEdge) error {
+		if su, ok := derived[edge.Attr]; ok {
+			return runMutationWithSchema(ctx, edge, su, txn)
+		}
+		return runMutation(ctx, edge, txn)
+	}
+	for _, edge := range m.Edges {
+		err := run(edge)
+		for err == posting.ErrRetry {
+			err = run(edge)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	txn.FillContext(txnCtx, groups().groupId())
+	return nil
+}
+
+// MutationRdf formats the edges as the set and delete blocks of an RDF mutation, which is how
+// a dry run of mutations shows the edges which would be written.
+func MutationRdf(edges []*pb.DirectedEdge) ([]byte, error) {
+	var set, del bytes.Buffer
+	for _, edge := range edges {
+		bp := &set
+		if edge.Op == pb.DirectedEdge_DEL {
+			bp = &del
+		}
+		fmt.Fprintf(bp, "\t\t"+uidFmtStrRdf+" <%s> ", edge.Entity, x.ParseAttr(edge.Attr))
+		switch {
+		case edge.ValueType == pb.Posting_UID:
+			fmt.Fprintf(bp, uidFmtStrRdf, edge.ValueId)
+		case bytes.Equal(edge.Value, []byte(x.Star)):
+			fmt.Fprint(bp, "*")
+		default:
+			tid := types.TypeID(edge.ValueType)
+			str, err := valToStr(types.Val{Tid: tid, Value: edge.Value})
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprint(bp, escapedString(str))
+			if len(edge.Lang) > 0 {
+				fmt.Fprint(bp, "@"+edge.Lang)
+			} else if rdfType, ok := rdfTypeMap[tid]; ok && tid != types.DefaultID {
+				fmt.Fprint(bp, "^^<"+rdfType+">")
+			}
+		}
+
+		if len(edge.Facets) != 0 {
+			fmt.Fprint(bp, " (")
+			for i, fct := range edge.Facets {
+				if i != 0 {
+					fmt.Fprint(bp, ",")
+				}
+				str, err := facetToString(fct)
+				if err != nil {
+					return nil, err
+				}
+				tid, err := facets.TypeIDFor(fct)
+				if err != nil {
+					return nil, err
+				}
+				if tid == types.StringID {
+					str = escapedString(str)
+				}
+				fmt.Fprint(bp, fct.Key+"="+str)
+			}
+			fmt.Fprint(bp, ")")
+		}
+		fmt.Fprint(bp, " .\n")
+	}
+
+	var out bytes.Buffer
+	out.WriteString("{\n")
+	if set.Len() > 0 {
+		out.WriteString("\tset {\n")
+		out.Write(set.Bytes())
+		out.WriteString("\t}\n")
+	}
+	if del.Len() > 0 {
+		out.WriteString("\tdelete {\n")
+		out.Write(del.Bytes())
+		out.WriteString("\t}\n")
+	}
+	out.WriteString("}\n")
+	return out.Bytes(), nil
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/types/facets"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestMutationRdf(t *testing.T) {
+	age := types.ValueForType(types.BinaryID)
+	require.NoError(t, types.Marshal(types.Val{Tid: types.IntID, Value: int64(31)}, &age))
+	since, err := facets.FacetFor("since", "2020")
+	require.NoError(t, err)
+
+	edges := []*pb.DirectedEdge{
+		{Entity: 0xa, Attr: x.GalaxyAttr("name"), Value: []byte("Ann"), Lang: "en",
+			ValueType: pb.Posting_DEFAULT, Op: pb.DirectedEdge_SET},
+		{Entity: 0xa, Attr: x.GalaxyAttr("age"), Value: []byte(age.Value.([]byte)),
+			ValueType: pb.Posting_INT, Op: pb.DirectedEdge_SET},
+		{Entity: 0xa, Attr: x.GalaxyAttr("friend"), ValueId: 0xb, ValueType: pb.Posting_UID,
+			Facets: []*api.Facet{since}, Op: pb.DirectedEdge_SET},
+		{Entity: 0xc, Attr: x.GalaxyAttr("name"), Value: []byte(x.Star),
+			Op: pb.DirectedEdge_DEL},
+	}
+	rdf, err := MutationRdf(edges)
+	require.NoError(t, err)
+	require.Equal(t, `{
+	set {
+		<0xa> <name> "Ann"@en .
+		<0xa> <age> "31"^^<xs:int> .
+		<0xa> <friend> <0xb> (since=2020) .
+	}
+	delete {
+		<0xc> <name> * .
+	}
+}
+`, string(rdf))
+}
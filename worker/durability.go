@@ -0,0 +1,132 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/conn"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/pkg/errors"
+	otrace "go.opencensus.io/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+// followerAckTimeout is how long a commit with replicated durability waits for the followers to
+// apply it.
+const followerAckTimeout = 10 * time.Second
+
+// WaitForApplied returns once this node has applied all the transactions committed at or before
+// the given timestamp.
+func (w *grpcWorker) WaitForApplied(ctx context.Context, num *pb.Num) (*api.Payload, error) {
+	if err := posting.Oracle().WaitForTs(ctx, num.Val); err != nil {
+		return nil, err
+	}
+	return &api.Payload{}, nil
+}
+
+// predGroups returns the groups in the predicate keys of a transaction, which are of the form
+// gid-attr.
+func predGroups(preds []string) ([]uint32, error) {
+	seen := make(map[uint32]struct{})
+	var gids []uint32
+	for _, pred := range preds {
+		splits := strings.SplitN(pred, "-", 2)
+		if len(splits) < 2 {
+			return nil, errors.Errorf("Unable to find group id in %s", pred)
+		}
+		gid, err := strconv.ParseUint(splits[0], 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse group id from %s", pred)
+		}
+		if _, ok := seen[uint32(gid)]; ok {
+			continue
+		}
+		seen[uint32(gid)] = struct{}{}
+		gids = append(gids, uint32(gid))
+	}
+	return gids, nil
+}
+
+// waitForFollowers waits until at least one follower of every group written to by the
+// transaction has applied its commit.
+func waitForFollowers(ctx context.Context, commitTs uint64, preds []string) error {
+	ctx, span := otrace.StartSpan(ctx, "worker.waitForFollowers")
+	defer span.End()
+
+	gids, err := predGroups(preds)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, followerAckTimeout)
+	defer cancel()
+
+	g, ctx := errgroup.WithContext(ctx)
+	for _, gid := range gids {
+		gid := gid
+		g.Go(func() error {
+			return waitForFollower(ctx, gid, commitTs)
+		})
+	}
+	return g.Wait()
+}
+
+func waitForFollower(ctx context.Context, gid uint32, commitTs uint64) error {
+	var addrs []string
+	for _, m := range groups().members(gid) {
+		// Learners don't vote, so they don't count towards the survivability of a write.
+		if m.Leader || m.Learner || m.AmDead {
+			continue
+		}
+		addrs = append(addrs, m.Addr)
+	}
+	if len(addrs) == 0 {
+		return errors.Errorf("group %d has no followers", gid)
+	}
+
+	errCh := make(chan error, len(addrs))
+	for _, addr := range addrs {
+		go func(addr string) {
+			errCh <- waitForApplied(ctx, addr, commitTs)
+		}(addr)
+	}
+	var err error
+	for range addrs {
+		if err = <-errCh; err == nil {
+			return nil
+		}
+	}
+	return errors.Wrapf(err, "while waiting for a follower of group %d", gid)
+}
+
+func waitForApplied(ctx context.Context, addr string, ts uint64) error {
+	if addr == x.WorkerConfig.MyAddr {
+		return posting.Oracle().WaitForTs(ctx, ts)
+	}
+	pl, err := conn.GetPools().Get(addr)
+	if err != nil {
+		return err
+	}
+	_, err = pb.NewWorkerClient(pl.Get()).WaitForApplied(ctx, &pb.Num{Val: ts})
+	return err
+}
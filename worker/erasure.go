@@ -0,0 +1,44 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/conn"
+	"github.com/dgraph-io/dgraph/protos/pb"
+)
+
+// UpdateErasures records the marker of an erasure, or a restore, in Zero.
+func UpdateErasures(ctx context.Context, u *pb.ErasureUpdate) error {
+	pl := groups().connToZeroLeader()
+	if pl == nil {
+		return conn.ErrNoConnection
+	}
+	_, err := pb.NewZeroClient(pl.Get()).UpdateErasures(ctx, u)
+	return err
+}
+
+// Erasures returns the markers of the erasures kept by Zero, and the restores since.
+func Erasures(ctx context.Context) (*pb.ErasureState, error) {
+	pl := groups().connToZeroLeader()
+	if pl == nil {
+		return nil, conn.ErrNoConnection
+	}
+	return pb.NewZeroClient(pl.Get()).Erasures(ctx, &api.Payload{})
+}
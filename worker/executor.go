@@ -80,8 +80,11 @@ func generateTokenKeys(nq *pb.DirectedEdge, tokenizers []tok.Tokenizer) ([]uint6
 		if err != nil {
 			errs = append(errs, err.Error())
 		}
-		toks, err := tok.BuildTokens(schemaVal.Value, tok.GetTokenizerForLang(token,
-			nq.Lang))
+		tokenizer := tok.GetTokenizerForCollation(token, nq.Lang,
+			schema.State().Collation(context.Background(), nq.Attr))
+		tokenizer = tok.GetTokenizerForDictionary(tokenizer,
+			schema.State().FulltextDictionary(context.Background(), nq.Attr))
+		toks, err := tok.BuildTokens(schemaVal.Value, tokenizer)
 		if err != nil {
 			errs = append(errs, err.Error())
 		}
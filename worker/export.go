@@ -30,6 +30,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
@@ -42,6 +43,7 @@ import (
 
 	"github.com/dgraph-io/dgo/v200/protos/api"
 
+	"github.com/dgraph-io/dgraph/conn"
 	"github.com/dgraph-io/dgraph/ee/enc"
 	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
@@ -78,6 +80,107 @@ type exporter struct {
 	attr      string
 	namespace uint64
 	readTs    uint64
+	// graph, when set, is the named graph whose edges are exported.
+	graph string
+	// lineage reads the provenance which replaces the lineage uids in the facets.
+	lineage *exportLineage
+}
+
+// exportLineage reads the provenance of the lineage nodes, which is exported inline as the
+// dgraph.lineage facet of the edges instead of the uid of the lineage node, so that loading the
+// export recreates the lineage nodes. The provenance is cached, as a lineage node usually has
+// many edges pointing to it.
+type exportLineage struct {
+	sync.Mutex
+	ctx    context.Context
+	db     *badger.DB
+	readTs uint64
+	// local reads the lineage nodes from db, for exports which don't talk to the cluster.
+	local bool
+	blobs map[uint64]string
+}
+
+// blob returns the provenance held by the lineage node with the given uid, or an empty string
+// if there is none.
+func (l *exportLineage) blob(ns, uid uint64) string {
+	l.Lock()
+	blob, ok := l.blobs[uid]
+	l.Unlock()
+	if ok {
+		return blob
+	}
+
+	blob, err := l.read(x.NamespaceAttr(ns, x.LineageBlob), uid)
+	if err != nil {
+		glog.Errorf("Ignoring error while reading the lineage node %#x: %+v", uid, err)
+		return ""
+	}
+	l.Lock()
+	l.blobs[uid] = blob
+	l.Unlock()
+	return blob
+}
+
+func (l *exportLineage) read(attr string, uid uint64) (string, error) {
+	if !l.local {
+		res, err := ProcessTaskOverNetwork(l.ctx, &pb.Query{
+			Attr:    attr,
+			UidList: &pb.List{Uids: []uint64{uid}},
+			ReadTs:  l.readTs,
+		})
+		if err != nil || len(res.ValueMatrix) == 0 || len(res.ValueMatrix[0].Values) == 0 {
+			return "", err
+		}
+		return string(res.ValueMatrix[0].Values[0].Val), nil
+	}
+
+	key := x.DataKey(attr, uid)
+	txn := l.db.NewTransactionAt(l.readTs, false)
+	defer txn.Discard()
+	itr := txn.NewKeyIterator(key, badger.IteratorOptions{AllVersions: true})
+	defer itr.Close()
+	itr.Rewind()
+	if !itr.Valid() {
+		return "", nil
+	}
+	pl, err := posting.ReadPostingList(key, itr)
+	if err != nil {
+		return "", err
+	}
+	val, err := pl.Value(l.readTs)
+	switch {
+	case err == posting.ErrNoValue:
+		return "", nil
+	case err != nil:
+		return "", err
+	}
+	blob, _ := val.Value.([]byte)
+	return string(blob), nil
+}
+
+// facets returns the facets of the posting to export, with the uid of the lineage node replaced
+// by its provenance. The lineage facet is dropped if the lineage node can't be read.
+func (e *exporter) facets(p *pb.Posting) []*api.Facet {
+	for i, fct := range p.Facets {
+		if fct.Key != x.LineageFacet || e.lineage == nil {
+			continue
+		}
+		out := make([]*api.Facet, 0, len(p.Facets))
+		out = append(out, p.Facets[:i]...)
+		val, err := facets.ValFor(fct)
+		uid, ok := val.Value.(int64)
+		if err == nil && ok {
+			if blob := e.lineage.blob(e.namespace, uint64(uid)); blob != "" {
+				out = append(out, &api.Facet{
+					Key:     x.LineageFacet,
+					Value:   []byte(blob),
+					ValType: api.Facet_STRING,
+				})
+			}
+		}
+		return append(out, p.Facets[i+1:]...)
+	}
+	return p.Facets
 }
 
 // Map from our types to RDF type. Useful when writing storage types
@@ -124,6 +227,20 @@ func facetToString(fct *api.Facet) (string, error) {
 	return v2.Value.(string), nil
 }
 
+// inGraph returns whether the posting is an edge of the exported named graph.
+func (e *exporter) inGraph(p *pb.Posting) bool {
+	if e.graph == "" {
+		return true
+	}
+	for _, fct := range p.Facets {
+		if fct.Key == x.GraphFacet {
+			graph, err := facets.ValFor(fct)
+			return err == nil && graph.Value == e.graph
+		}
+	}
+	return false
+}
+
 // escapedString converts a string into an escaped string for exports.
 func escapedString(str string) string {
 	// We use the Marshal function in the JSON package for all export formats
@@ -146,6 +263,9 @@ func (e *exporter) toJSON() (*bpb.KVList, error) {
 	continuing := false
 	mapStart := fmt.Sprintf("  {\"uid\":"+uidFmtStrJson+`,"namespace":"0x%x"`, e.uid, e.namespace)
 	err := e.pl.Iterate(e.readTs, 0, func(p *pb.Posting) error {
+		if !e.inGraph(p) {
+			return nil
+		}
 		if continuing {
 			fmt.Fprint(bp, ",\n")
 		} else {
@@ -181,7 +301,7 @@ func (e *exporter) toJSON() (*bpb.KVList, error) {
 			fmt.Fprint(bp, str)
 		}
 
-		for _, fct := range p.Facets {
+		for _, fct := range e.facets(p) {
 			fmt.Fprintf(bp, `,"%s|%s":`, e.attr, fct.Key)
 
 			str, err := facetToString(fct)
@@ -219,6 +339,9 @@ func (e *exporter) toRDF() (*bpb.KVList, error) {
 
 	prefix := fmt.Sprintf(uidFmtStrRdf+" <%s> ", e.uid, e.attr)
 	err := e.pl.Iterate(e.readTs, 0, func(p *pb.Posting) error {
+		if !e.inGraph(p) {
+			return nil
+		}
 		fmt.Fprint(bp, prefix)
 		if p.PostingType == pb.Posting_REF {
 			fmt.Fprint(bp, fmt.Sprintf(uidFmtStrRdf, p.Uid))
@@ -244,9 +367,9 @@ func (e *exporter) toRDF() (*bpb.KVList, error) {
 		fmt.Fprintf(bp, " <%#x>", e.namespace)
 
 		// Facets.
-		if len(p.Facets) != 0 {
+		if fcts := e.facets(p); len(fcts) != 0 {
 			fmt.Fprint(bp, " (")
-			for i, fct := range p.Facets {
+			for i, fct := range fcts {
 				if i != 0 {
 					fmt.Fprint(bp, ",")
 				}
@@ -311,9 +434,33 @@ func toSchema(attr string, update *pb.SchemaUpdate) *bpb.KV {
 	if update.GetCount() {
 		x.Check2(buf.WriteString(" @count"))
 	}
+	if update.GetPresence() {
+		x.Check2(buf.WriteString(" @presence"))
+	}
 	if update.GetLang() {
 		x.Check2(buf.WriteString(" @lang"))
 	}
+	if collation := update.GetCollation(); collation != "" {
+		x.Check2(buf.WriteString(fmt.Sprintf(" @collate(%s)", collation)))
+	}
+	if synonyms := update.GetFulltextSynonyms(); len(synonyms) > 0 {
+		groups := make([]string, 0, len(synonyms))
+		for _, group := range synonyms {
+			words := make([]string, 0, len(group.Words))
+			for _, word := range group.Words {
+				words = append(words, dictionaryWord(word))
+			}
+			groups = append(groups, strings.Join(words, " "))
+		}
+		x.Check2(buf.WriteString(fmt.Sprintf(" @synonyms(%s)", strings.Join(groups, ", "))))
+	}
+	if protected := update.GetFulltextProtected(); len(protected) > 0 {
+		words := make([]string, 0, len(protected))
+		for _, word := range protected {
+			words = append(words, dictionaryWord(word))
+		}
+		x.Check2(buf.WriteString(fmt.Sprintf(" @protected(%s)", strings.Join(words, ", "))))
+	}
 	if update.GetUpsert() {
 		x.Check2(buf.WriteString(" @upsert"))
 	}
@@ -325,6 +472,34 @@ func toSchema(attr string, update *pb.SchemaUpdate) *bpb.KV {
 	}
 }
 
+// dictionaryWord returns a word of the fulltext dictionary as written in the schema: as is if
+// it's a name, and as an IRI otherwise, with the characters not allowed in an IRI escaped.
+func dictionaryWord(word string) string {
+	isName := word != ""
+	for i, r := range word {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '_' || r == '-'):
+		default:
+			isName = false
+		}
+	}
+	if isName {
+		return word
+	}
+	var buf strings.Builder
+	x.Check2(buf.WriteRune('<'))
+	for _, r := range word {
+		if r <= ' ' || strings.ContainsRune(`<>"{}|^`+"`\\", r) {
+			x.Check2(buf.WriteString(fmt.Sprintf("\\u%04x", r)))
+			continue
+		}
+		x.Check2(buf.WriteRune(r))
+	}
+	x.Check2(buf.WriteRune('>'))
+	return buf.String()
+}
+
 func toType(attr string, update pb.TypeUpdate) *bpb.KV {
 	var buf bytes.Buffer
 	ns, attr := x.ParseNamespaceAttr(attr)
@@ -400,6 +575,22 @@ func (writer *fileWriter) Close() error {
 // ExportedFiles has the relative path of files that were written during export
 type ExportedFiles []string
 
+// ExportManifest is written by every group along with its exported files. All the groups of an
+// export share the same read timestamp, so together they form a consistent snapshot.
+type ExportManifest struct {
+	// ReadTs is the timestamp at which the data was read.
+	ReadTs uint64 `json:"read_ts"`
+	// GroupId is the group which wrote this manifest, and Groups all the groups in the export.
+	GroupId uint32   `json:"group_id"`
+	Groups  []uint32 `json:"groups"`
+	// Predicates are the predicates exported by this group.
+	Predicates []string `json:"predicates"`
+	Namespace  uint64   `json:"namespace"`
+	Format     string   `json:"format"`
+	// Graph is the named graph exported, if the export was restricted to one.
+	Graph string `json:"graph,omitempty"`
+}
+
 type exportStorage interface {
 	openFile(relativePath string) (*fileWriter, error)
 	finishWriting(fs ...*fileWriter) (ExportedFiles, error)
@@ -555,6 +746,16 @@ func export(ctx context.Context, in *pb.ExportRequest) (ExportedFiles, error) {
 	return exportInternal(ctx, in, pstore, false)
 }
 
+// exportGroup runs the export of this group requested by exportOverNetwork. Predicate moves are
+// blocked during that export, so we first make sure that we know about the last moves done
+// before that, to export exactly the predicates we serve.
+func exportGroup(ctx context.Context, in *pb.ExportRequest) (ExportedFiles, error) {
+	if err := UpdateMembershipState(ctx); err != nil {
+		return nil, err
+	}
+	return export(ctx, in)
+}
+
 // exportInternal contains the core logic to export a Dgraph database. If skipZero is set to
 // false, the parts of this method that require to talk to zero will be skipped. This is useful
 // when exporting a p directory directly from disk without a running cluster.
@@ -587,7 +788,29 @@ func exportInternal(ctx context.Context, in *pb.ExportRequest, db *badger.DB,
 		return nil, err
 	}
 
-	// This stream exports only the data and the graphQL schema.
+	manifestWriter, err := exportStorage.openFile(
+		fmt.Sprintf("g%02d%s", in.GroupId, ".manifest.json.gz"))
+	if err != nil {
+		return nil, err
+	}
+	manifest := &ExportManifest{
+		ReadTs:    in.ReadTs,
+		GroupId:   in.GroupId,
+		Groups:    in.Groups,
+		Namespace: in.Namespace,
+		Format:    in.Format,
+		Graph:     in.Graph,
+	}
+
+	// This stream exports only the data and the graphQL schema. The lists are read at readTs.
+	readTs := in.ReadTs
+	lineage := &exportLineage{
+		ctx:    ctx,
+		db:     db,
+		readTs: readTs,
+		local:  skipZero,
+		blobs:  make(map[uint64]string),
+	}
 	stream := db.NewStreamAt(in.ReadTs)
 	stream.Prefix = []byte{x.DefaultPrefix}
 	if in.Namespace != math.MaxUint64 {
@@ -635,7 +858,9 @@ func exportInternal(ctx context.Context, in *pb.ExportRequest, db *badger.DB,
 			return nil, err
 		}
 		e := &exporter{
-			readTs: in.ReadTs,
+			readTs:  readTs,
+			graph:   in.Graph,
+			lineage: lineage,
 		}
 		e.uid = pk.Uid
 		e.namespace, e.attr = x.ParseNamespaceAttr(pk.Attr)
@@ -647,15 +872,21 @@ func exportInternal(ctx context.Context, in *pb.ExportRequest, db *badger.DB,
 			// Ignore this predicate.
 		case e.attr == "dgraph.graphql.p_query":
 			// Ignore this predicate.
+		case e.attr == "dgraph.graphql.lambda_scripts":
+			// Ignore this predicate.
+		case e.attr == "dgraph.schema_history":
+			// Ignore this predicate.
 		case e.attr == "dgraph.graphql.p_sha256hash":
 			// Ignore this predicate.
+		case e.attr == x.LineageBlob || e.attr == x.LineageSubject:
+			// Ignore the lineage nodes, their provenance is exported in the lineage facets.
 		case pk.IsData() && e.attr == "dgraph.graphql.schema":
 			// Export the graphql schema.
 			pl, err := posting.ReadPostingList(key, itr)
 			if err != nil {
 				return nil, errors.Wrapf(err, "cannot read posting list for GraphQL schema")
 			}
-			vals, err := pl.AllValues(in.ReadTs)
+			vals, err := pl.AllValues(readTs)
 			if err != nil {
 				return nil, errors.Wrapf(err, "cannot read value of GraphQL schema")
 			}
@@ -694,9 +925,9 @@ func exportInternal(ctx context.Context, in *pb.ExportRequest, db *badger.DB,
 			}
 
 			// The GraphQL layer will create a node of type "dgraph.graphql". That entry
-			// should not be exported.
+			// should not be exported, and neither should the type of the lineage nodes.
 			if e.attr == "dgraph.type" {
-				vals, err := e.pl.AllValues(in.ReadTs)
+				vals, err := e.pl.AllValues(readTs)
 				if err != nil {
 					return nil, errors.Wrapf(err, "cannot read value of dgraph.type entry")
 				}
@@ -705,7 +936,7 @@ func exportInternal(ctx context.Context, in *pb.ExportRequest, db *badger.DB,
 					if !ok {
 						return nil, errors.Errorf("cannot read value of dgraph.type entry")
 					}
-					if string(val) == "dgraph.graphql" {
+					if string(val) == "dgraph.graphql" || string(val) == "dgraph.lineage" {
 						return nil, nil
 					}
 				}
@@ -821,6 +1052,7 @@ func exportInternal(ctx context.Context, in *pb.ExportRequest, db *badger.DB,
 					continue
 				}
 				kv = toSchema(pk.Attr, &update)
+				manifest.Predicates = append(manifest.Predicates, pk.Attr)
 
 			case x.ByteType:
 				var update pb.TypeUpdate
@@ -853,6 +1085,27 @@ func exportInternal(ctx context.Context, in *pb.ExportRequest, db *badger.DB,
 	if err := stream.Orchestrate(ctx); err != nil {
 		return nil, err
 	}
+	if !skipZero {
+		// The data of the cold predicates is in object storage. It was frozen since it was
+		// read at cold.ReadTs, so that it's read at that timestamp if the export is older.
+		keep := func(attr string) bool {
+			return in.Namespace == math.MaxUint64 || x.ParseNamespace(attr) == in.Namespace
+		}
+		err := streamColdTablets(keep, func(attr string, cold *pb.ColdTablet,
+			cdb *badger.DB) error {
+			readTs = x.Max(in.ReadTs, cold.ReadTs)
+			cstream := cdb.NewStreamAt(readTs)
+			cstream.Prefix = x.PredicatePrefix(attr)
+			cstream.LogPrefix = "Export"
+			cstream.ChooseKey = stream.ChooseKey
+			cstream.KeyToList = stream.KeyToList
+			cstream.Send = stream.Send
+			return cstream.Orchestrate(ctx)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 	if _, err = dataWriter.gw.Write([]byte(xfmt.post)); err != nil {
 		return nil, err
 	}
@@ -863,8 +1116,11 @@ func exportInternal(ctx context.Context, in *pb.ExportRequest, db *badger.DB,
 	if err := writePrefix(x.ByteType); err != nil {
 		return nil, err
 	}
+	if err := json.NewEncoder(manifestWriter.gw).Encode(manifest); err != nil {
+		return nil, err
+	}
 	glog.Infof("Export DONE for group %d at timestamp %d.", in.GroupId, in.ReadTs)
-	return exportStorage.finishWriting(dataWriter, schemaWriter, gqlSchemaWriter)
+	return exportStorage.finishWriting(dataWriter, schemaWriter, gqlSchemaWriter, manifestWriter)
 }
 
 // Export request is used to trigger exports for the request list of groups.
@@ -878,7 +1134,7 @@ func (w *grpcWorker) Export(ctx context.Context, req *pb.ExportRequest) (*pb.Exp
 	}
 
 	glog.Infof("Issuing export request...")
-	files, err := export(ctx, req)
+	files, err := exportGroup(ctx, req)
 	if err != nil {
 		glog.Errorf("While running export. Request: %+v. Error=%v\n", req, err)
 		return nil, err
@@ -889,7 +1145,7 @@ func (w *grpcWorker) Export(ctx context.Context, req *pb.ExportRequest) (*pb.Exp
 
 func handleExportOverNetwork(ctx context.Context, in *pb.ExportRequest) (ExportedFiles, error) {
 	if in.GroupId == groups().groupId() {
-		return export(ctx, in)
+		return exportGroup(ctx, in)
 	}
 
 	pl := groups().Leader(in.GroupId)
@@ -913,6 +1169,31 @@ func ExportOverNetwork(ctx context.Context, input *pb.ExportRequest) (ExportedFi
 		glog.Errorf("Rejecting export request due to health check error: %v\n", err)
 		return nil, err
 	}
+	var files ExportedFiles
+	opts := TaskOptions{
+		Kind:        "export",
+		Description: fmt.Sprintf("Export in %s format", input.Format),
+		Cancellable: true,
+	}
+	err := RunTask(ctx, opts, func(ctx context.Context, t *Task) error {
+		var err error
+		files, err = exportOverNetwork(ctx, input, t)
+		return err
+	})
+	return files, err
+}
+
+func exportOverNetwork(ctx context.Context, input *pb.ExportRequest,
+	t *Task) (ExportedFiles, error) {
+	// Block predicate moves until the export is done, so that every predicate is exported
+	// exactly once, by the group serving it at the read timestamp.
+	unblock, err := blockPredicateMoves(ctx, fmt.Sprintf("export-%d", time.Now().UnixNano()))
+	if err != nil {
+		glog.Errorf("Unable to block predicate moves for export: %v\n", err)
+		return nil, err
+	}
+	defer unblock()
+
 	// Get ReadTs from zero and wait for stream to catch up.
 	ts, err := Timestamps(ctx, &pb.Num{ReadOnly: true})
 	if err != nil {
@@ -930,15 +1211,19 @@ func ExportOverNetwork(ctx context.Context, input *pb.ExportRequest) (ExportedFi
 		ExportedFiles
 		error
 	}
+	// All the groups must use the same time, so that they export to the same directory.
+	unixTs := time.Now().Unix()
 	ch := make(chan filesAndError, len(gids))
 	for _, gid := range gids {
 		go func(group uint32) {
 			req := &pb.ExportRequest{
 				GroupId:   group,
+				Groups:    gids,
 				ReadTs:    readTs,
-				UnixTs:    time.Now().Unix(),
+				UnixTs:    unixTs,
 				Format:    input.Format,
 				Namespace: input.Namespace,
+				Graph:     input.Graph,
 
 				Destination:  input.Destination,
 				AccessKey:    input.AccessKey,
@@ -960,12 +1245,63 @@ func ExportOverNetwork(ctx context.Context, input *pb.ExportRequest) (ExportedFi
 			return nil, rerr
 		}
 		allFiles = append(allFiles, pair.ExportedFiles...)
+		if err := t.Progress(ctx, uint64(i+1), uint64(len(gids)),
+			fmt.Sprintf("Exported %d of %d groups", i+1, len(gids))); err != nil {
+			return nil, err
+		}
 	}
 
 	glog.Infof("Export at readTs %d DONE", readTs)
 	return allFiles, nil
 }
 
+// moveBlockTTL is how long a block on predicate moves lives without being renewed.
+const moveBlockTTL = time.Minute
+
+// blockPredicateMoves asks Zero to stop moving predicates, and keeps renewing the block until the
+// returned function is called.
+func blockPredicateMoves(ctx context.Context, id string) (func(), error) {
+	block := func(ctx context.Context, req *pb.BlockMovesRequest) error {
+		pl := groups().Leader(0)
+		if pl == nil {
+			return conn.ErrNoConnection
+		}
+		_, err := pb.NewZeroClient(pl.Get()).BlockMoves(ctx, req)
+		return err
+	}
+	req := &pb.BlockMovesRequest{Id: id, TtlSecs: int64(moveBlockTTL.Seconds())}
+	if err := block(ctx, req); err != nil {
+		return nil, err
+	}
+
+	closer := z.NewCloser(1)
+	go func() {
+		defer closer.Done()
+		ticker := time.NewTicker(moveBlockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-closer.HasBeenClosed():
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(closer.Ctx(), 10*time.Second)
+				if err := block(ctx, req); err != nil {
+					glog.Warningf("Unable to renew block on predicate moves: %v", err)
+				}
+				cancel()
+			}
+		}
+	}()
+	return func() {
+		closer.SignalAndWait()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := block(ctx, &pb.BlockMovesRequest{Id: id, Unblock: true}); err != nil {
+			glog.Warningf("Unable to unblock predicate moves: %v", err)
+		}
+	}, nil
+}
+
 // NormalizeExportFormat returns the normalized string for the export format if it is valid, an
 // empty string otherwise.
 func NormalizeExportFormat(format string) string {
@@ -170,7 +170,8 @@ func initTestExport(t *testing.T, schemaStr string) {
 	require.NoError(t, txn.CommitAt(1, nil))
 }
 
-func getExportFileList(t *testing.T, bdir string) (dataFiles, schemaFiles, gqlSchema []string) {
+func getExportFileList(t *testing.T, bdir string) (dataFiles, schemaFiles, gqlSchema,
+	manifests []string) {
 	searchDir := bdir
 	err := filepath.Walk(searchDir, func(path string, f os.FileInfo, err error) error {
 		if f.IsDir() {
@@ -178,6 +179,8 @@ func getExportFileList(t *testing.T, bdir string) (dataFiles, schemaFiles, gqlSc
 		}
 		if path != bdir {
 			switch {
+			case strings.Contains(path, "manifest"):
+				manifests = append(manifests, path)
 			case strings.Contains(path, "gql_schema"):
 				gqlSchema = append(gqlSchema, path)
 			case strings.Contains(path, "schema"):
@@ -190,6 +193,7 @@ func getExportFileList(t *testing.T, bdir string) (dataFiles, schemaFiles, gqlSc
 	})
 	require.NoError(t, err)
 	require.Equal(t, 1, len(dataFiles), "filelist=%v", dataFiles)
+	require.Equal(t, 1, len(manifests), "filelist=%v", manifests)
 
 	return
 }
@@ -254,8 +258,9 @@ func TestExportRdf(t *testing.T) {
 		Namespace: math.MaxUint64, Format: "rdf"})
 	require.NoError(t, err)
 
-	fileList, schemaFileList, gqlSchema := getExportFileList(t, bdir)
-	require.Equal(t, len(files), len(fileList)+len(schemaFileList)+len(gqlSchema))
+	fileList, schemaFileList, gqlSchema, manifests := getExportFileList(t, bdir)
+	require.Equal(t, len(files),
+		len(fileList)+len(schemaFileList)+len(gqlSchema)+len(manifests))
 
 	file := fileList[0]
 	f, err := os.Open(file)
@@ -356,8 +361,9 @@ func TestExportJson(t *testing.T) {
 	files, err := export(context.Background(), &req)
 	require.NoError(t, err)
 
-	fileList, schemaFileList, gqlSchema := getExportFileList(t, bdir)
-	require.Equal(t, len(files), len(fileList)+len(schemaFileList)+len(gqlSchema))
+	fileList, schemaFileList, gqlSchema, manifests := getExportFileList(t, bdir)
+	require.Equal(t, len(files),
+		len(fileList)+len(schemaFileList)+len(gqlSchema)+len(manifests))
 
 	file := fileList[0]
 	f, err := os.Open(file)
@@ -571,3 +577,48 @@ func TestToSchema(t *testing.T) {
 		require.Equal(t, testCase.expected, string(kv.Value))
 	}
 }
+
+func TestToSchemaFulltextDictionary(t *testing.T) {
+	su := &pb.SchemaUpdate{
+		ValueType: pb.Posting_STRING,
+		Directive: pb.SchemaUpdate_INDEX,
+		Tokenizer: []string{"fulltext"},
+		FulltextSynonyms: []*pb.Synonyms{
+			{Words: []string{"tv", "television"}},
+			{Words: []string{"film", "película", "2d"}},
+		},
+		FulltextProtected: []string{"news", "<us>"},
+	}
+	kv := toSchema(x.GalaxyAttr("title"), su)
+	require.Equal(t, "[0x0] <title>:string @index(fulltext) @synonyms(tv television, "+
+		"film <película> <2d>) @protected(news, <\\u003cus\\u003e>) . \n", string(kv.Value))
+
+	// The exported schema gives back the dictionary.
+	result, err := schema.Parse(strings.TrimPrefix(string(kv.Value), "[0x0] "))
+	require.NoError(t, err)
+	require.Equal(t, su.FulltextSynonyms, result.Preds[0].FulltextSynonyms)
+	require.Equal(t, su.FulltextProtected, result.Preds[0].FulltextProtected)
+}
+
+func TestExportLineageFacet(t *testing.T) {
+	lineage, err := facets.FacetFor(x.LineageFacet, "16")
+	require.NoError(t, err)
+	unknown, err := facets.FacetFor(x.LineageFacet, "17")
+	require.NoError(t, err)
+	weight, err := facets.FacetFor("weight", "0.5")
+	require.NoError(t, err)
+
+	e := &exporter{lineage: &exportLineage{
+		blobs: map[uint64]string{16: `{"source":"crm"}`, 17: ""},
+	}}
+	fcts := e.facets(&pb.Posting{Facets: []*api.Facet{lineage, weight}})
+	require.Len(t, fcts, 2)
+	require.Equal(t, x.LineageFacet, fcts[0].Key)
+	require.Equal(t, api.Facet_STRING, fcts[0].ValType)
+	require.Equal(t, `{"source":"crm"}`, string(fcts[0].Value))
+	require.Equal(t, weight, fcts[1])
+
+	// The lineage facet is dropped when the lineage node has no provenance.
+	fcts = e.facets(&pb.Posting{Facets: []*api.Facet{unknown, weight}})
+	require.Equal(t, []*api.Facet{weight}, fcts)
+}
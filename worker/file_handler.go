@@ -15,6 +15,7 @@ package worker
 import (
 	"compress/gzip"
 	"context"
+	"crypto"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -28,7 +29,6 @@ import (
 	"time"
 
 	"github.com/dgraph-io/badger/v3"
-	"github.com/dgraph-io/dgraph/ee/enc"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/x"
 
@@ -196,7 +196,7 @@ func (h *fileHandler) Load(uri *url.URL, backupId string, backupNum uint64, fn l
 
 			groupMaxUid, groupMaxNsId, err := fn(gid,
 				&loadBackupInput{r: fp, preds: predSet, dropOperations: manifest.DropOperations,
-					isOld: manifest.Version == 0})
+					isOld: manifest.Version == 0, envelope: manifest.Envelope, manifest: manifest})
 			if err != nil {
 				return LoadResult{Err: err}
 			}
@@ -240,6 +240,10 @@ func (h *fileHandler) ReadManifest(path string, m *Manifest) error {
 	return h.readManifest(path, m)
 }
 
+func (h *fileHandler) OpenFile(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
 func (h *fileHandler) Close() error {
 	if h.fp == nil {
 		return nil
@@ -267,7 +271,7 @@ func pathExist(path string) bool {
 }
 
 func (h *fileHandler) ExportBackup(backupDir, exportDir, format string,
-	key x.SensitiveByteSlice) error {
+	key x.SensitiveByteSlice, priv crypto.PrivateKey) error {
 	if format != "json" && format != "rdf" {
 		return errors.Errorf("invalid format %s", format)
 	}
@@ -287,10 +291,11 @@ func (h *fileHandler) ExportBackup(backupDir, exportDir, format string,
 	}
 
 	// Function to load the a single backup file.
-	loadFn := func(r io.Reader, groupId uint32, preds predicateSet, isOld bool) (uint64, error) {
+	loadFn := func(r io.Reader, groupId uint32, preds predicateSet, isOld,
+		envelope bool) (uint64, error) {
 		dir := filepath.Join(tmpDir, fmt.Sprintf("p%d", groupId))
 
-		r, err := enc.GetReader(key, r)
+		r, err := getBackupReader(r, envelope, key, priv)
 		if err != nil {
 			return 0, err
 		}
@@ -349,7 +354,7 @@ func (h *fileHandler) ExportBackup(backupDir, exportDir, format string,
 		// of the last backup.
 		predSet := manifest.getPredsInGroup(gid)
 
-		_, err = loadFn(fp, gid, predSet, manifest.Version == 0)
+		_, err = loadFn(fp, gid, predSet, manifest.Version == 0, manifest.Envelope)
 		if err != nil {
 			return err
 		}
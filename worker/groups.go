@@ -20,7 +20,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -29,6 +32,7 @@ import (
 	"github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/dgraph-io/dgraph/conn"
 	"github.com/dgraph-io/dgraph/ee/enc"
+	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/raftwal"
 	"github.com/dgraph-io/dgraph/schema"
@@ -37,6 +41,8 @@ import (
 	"github.com/golang/glog"
 	"github.com/golang/protobuf/proto"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type groupi struct {
@@ -53,6 +59,10 @@ type groupi struct {
 	// the membership information that the Alpha has. If so, Alpha cannot service a read.
 	deltaChecksum      uint64 // Checksum received by OracleDelta.
 	membershipChecksum uint64 // Checksum received by MembershipState.
+
+	clockSkewMs int64 // Clock skew relative to the Zero leader.
+	// Tags of this Alpha, which the placement constraints of Zero refer to.
+	tags map[string]string
 }
 
 var gr = &groupi{
@@ -60,7 +70,24 @@ var gr = &groupi{
 	tablets:      make(map[string]*pb.Tablet),
 }
 
-var RaftDefaults = "idx=0; group=0; learner=false; snapshot-after=10000"
+var RaftDefaults = "idx=0; group=0; learner=false; snapshot-after=10000; tags=; " +
+	"replay-concurrency=8"
+
+// parseMemberTags parses the tags of an Alpha, given as tag:value pairs separated by commas.
+func parseMemberTags(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, ":", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, errors.Errorf("Invalid tag %q. It must be of the form tag:value", pair)
+		}
+		tags[kv[0]] = kv[1]
+	}
+	return tags, nil
+}
 
 func groups() *groupi {
 	return gr
@@ -81,6 +108,10 @@ func StartRaftNodes(walStore *raftwal.DiskStorage, bindall bool) {
 		}
 	}
 
+	var err error
+	gr.tags, err = parseMemberTags(x.WorkerConfig.Raft.GetString("tags"))
+	x.Checkf(err, "Invalid tags in --raft flag")
+
 	x.AssertTruef(len(x.WorkerConfig.ZeroAddr) > 0, "Providing dgraphzero address is mandatory.")
 	for _, zeroAddr := range x.WorkerConfig.ZeroAddr {
 		x.AssertTruef(zeroAddr != x.WorkerConfig.MyAddr,
@@ -100,23 +131,32 @@ func StartRaftNodes(walStore *raftwal.DiskStorage, bindall bool) {
 	}
 	glog.Infof("Current Raft Id: %#x\n", raftIdx)
 
+	// A WAL with a group but no Raft ID was copied from another member of the group by a local
+	// snapshot, see TakeLocalSnapshot. This Alpha joins that group as a new member.
+	cloned := raftIdx == 0 && walStore.Uint(raftwal.GroupId) > 0
+	if cloned {
+		x.WorkerConfig.ProposedGroupId = uint32(walStore.Uint(raftwal.GroupId))
+		glog.Infof("Found a WAL copied from group %d", x.WorkerConfig.ProposedGroupId)
+	}
+
 	if x.WorkerConfig.ProposedGroupId == 0 {
 		x.WorkerConfig.ProposedGroupId = x.WorkerConfig.Raft.GetUint32("group")
 	}
 	// Successfully connect with dgraphzero, before doing anything else.
 	// Connect with Zero leader and figure out what group we should belong to.
 	m := &pb.Member{
-		Id:      raftIdx,
-		GroupId: x.WorkerConfig.ProposedGroupId,
-		Addr:    x.WorkerConfig.MyAddr,
-		Learner: x.WorkerConfig.Raft.GetBool("learner"),
+		Id:       raftIdx,
+		GroupId:  x.WorkerConfig.ProposedGroupId,
+		Addr:     x.WorkerConfig.MyAddr,
+		GrpcAddr: grpcAddr(),
+		Learner:  x.WorkerConfig.Raft.GetBool("learner"),
+		Tags:     gr.tags,
 	}
 	if m.GroupId > 0 {
 		m.ForceGroupId = true
 	}
 	glog.Infof("Sending member request to Zero: %+v\n", m)
 	var connState *pb.ConnectionState
-	var err error
 
 	for { // Keep on retrying. See: https://github.com/dgraph-io/dgraph/issues/2289
 		pl := gr.connToZeroLeader()
@@ -150,17 +190,22 @@ func StartRaftNodes(walStore *raftwal.DiskStorage, bindall bool) {
 	walStore.SetUint(raftwal.GroupId, uint64(gid))
 
 	gr.Node = newNode(walStore, gid, raftIdx, x.WorkerConfig.MyAddr)
+	gr.Node.cloned = cloned
 
 	x.Checkf(schema.LoadFromDb(), "Error while initializing schema")
 	raftServer.UpdateNode(gr.Node.Node)
 	gr.Node.InitAndStartNode()
+	// Let Zero know about the ids in use, even if they weren't written by mutations.
+	go gr.Node.observeStoredIds()
 
 	gr.closer = z.NewCloser(3) // Match CLOSER:1 in this file.
 	go gr.sendMembershipUpdates()
 	go gr.receiveMembershipUpdates()
 	go gr.processOracleDeltaStream()
 
-	gr.informZeroAboutTablets()
+	// The tablets which Zero doesn't know about yet are asked for on their first access, so
+	// there's no need to wait for Zero before serving.
+	go gr.informZeroAboutTablets()
 	gr.applyInitialSchema()
 	gr.applyInitialTypes()
 
@@ -177,7 +222,7 @@ func (g *groupi) IsClosed() bool {
 }
 
 func (g *groupi) informZeroAboutTablets() {
-	// Before we start this Alpha, let's pick up all the predicates we have in our postings
+	// As this Alpha starts, let's pick up all the predicates we have in our postings
 	// directory, and ask Zero if we are allowed to serve it. Do this irrespective of whether
 	// this node is the leader or the follower, because this early on, we might not have
 	// figured that out.
@@ -185,6 +230,9 @@ func (g *groupi) informZeroAboutTablets() {
 	defer ticker.Stop()
 
 	for range ticker.C {
+		if g.IsClosed() {
+			return
+		}
 		failed := false
 		preds := schema.State().Predicates()
 		for _, pred := range preds {
@@ -302,6 +350,16 @@ func UpdateMembershipState(ctx context.Context) error {
 	return nil
 }
 
+// grpcAddr returns the address of the external gRPC endpoint of this Alpha. It uses the host of
+// the internal address, which is the one other nodes reach this Alpha at.
+func grpcAddr() string {
+	host, _, err := net.SplitHostPort(x.WorkerConfig.MyAddr)
+	if err != nil {
+		return ""
+	}
+	return net.JoinHostPort(host, strconv.Itoa(x.Config.PortOffset+x.PortGrpc))
+}
+
 func (g *groupi) applyState(myId uint64, state *pb.MembershipState) {
 	x.AssertTrue(state != nil)
 	g.Lock()
@@ -419,11 +477,7 @@ func (g *groupi) BelongsToReadOnly(key string, ts uint64) (uint32, error) {
 	tablet := g.tablets[key]
 	g.RUnlock()
 	if tablet != nil {
-		if ts > 0 && ts < tablet.MoveTs {
-			return 0, errors.Errorf("StartTs: %d is from before MoveTs: %d for pred: %q",
-				ts, tablet.MoveTs, key)
-		}
-		return tablet.GetGroupId(), nil
+		return tabletGroupAt(tablet, ts)
 	}
 
 	// We don't know about this tablet. Talk to dgraphzero to find out who is
@@ -447,11 +501,20 @@ func (g *groupi) BelongsToReadOnly(key string, ts uint64) (uint32, error) {
 	g.Lock()
 	defer g.Unlock()
 	g.tablets[key] = out
-	if out != nil && ts > 0 && ts < out.MoveTs {
+	return tabletGroupAt(out, ts)
+}
+
+// tabletGroupAt returns the group which can serve reads of the tablet at the given timestamp.
+// Reads from before a move are served by the source group while it hands over the tablet.
+func tabletGroupAt(tablet *pb.Tablet, ts uint64) (uint32, error) {
+	if ts > 0 && ts < tablet.MoveTs {
+		if tablet.MovedFrom > 0 {
+			return tablet.MovedFrom, nil
+		}
 		return 0, errors.Errorf("StartTs: %d is from before MoveTs: %d for pred: %q",
-			ts, out.MoveTs, key)
+			ts, tablet.MoveTs, tablet.Predicate)
 	}
-	return out.GetGroupId(), nil
+	return tablet.GetGroupId(), nil
 }
 
 func (g *groupi) ServesTablet(key string) (bool, error) {
@@ -545,6 +608,34 @@ func (g *groupi) AnyTwoServers(gid uint32) []string {
 	return res
 }
 
+// AnyTwoFollowers returns the addresses of up to two followers of the group, falling back to the
+// leader if there aren't enough followers.
+func (g *groupi) AnyTwoFollowers(gid uint32) []string {
+	g.RLock()
+	defer g.RUnlock()
+
+	group, has := g.state.GetGroups()[gid]
+	if !has {
+		return []string{}
+	}
+	var res []string
+	var leader string
+	for _, m := range group.Members {
+		if m.Leader {
+			leader = m.Addr
+			continue
+		}
+		res = append(res, m.Addr)
+		if len(res) >= 2 {
+			return res
+		}
+	}
+	if len(leader) > 0 {
+		res = append(res, leader)
+	}
+	return res
+}
+
 func (g *groupi) members(gid uint32) map[uint64]*pb.Member {
 	g.RLock()
 	defer g.RUnlock()
@@ -562,6 +653,18 @@ func (g *groupi) members(gid uint32) map[uint64]*pb.Member {
 	return group.Members
 }
 
+// OtherServers returns the addresses of the members of the group other than this node, in no
+// particular order.
+func (g *groupi) OtherServers(gid uint32) []string {
+	var res []string
+	for _, m := range g.members(gid) {
+		if m.Id != g.Node.Id {
+			res = append(res, m.Addr)
+		}
+	}
+	return res
+}
+
 func (g *groupi) AnyServer(gid uint32) *conn.Pool {
 	members := g.members(gid)
 	for _, m := range members {
@@ -694,11 +797,14 @@ func (g *groupi) connToZeroLeader() *conn.Pool {
 func (g *groupi) doSendMembership(tablets map[string]*pb.Tablet) error {
 	leader := g.Node.AmLeader()
 	member := &pb.Member{
-		Id:         g.Node.Id,
-		GroupId:    g.groupId(),
-		Addr:       x.WorkerConfig.MyAddr,
-		Leader:     leader,
-		LastUpdate: uint64(time.Now().Unix()),
+		Id:          g.Node.Id,
+		GroupId:     g.groupId(),
+		Addr:        x.WorkerConfig.MyAddr,
+		Leader:      leader,
+		LastUpdate:  uint64(time.Now().Unix()),
+		ClockSkewMs: atomic.LoadInt64(&g.clockSkewMs),
+		GrpcAddr:    grpcAddr(),
+		Tags:        g.tags,
 	}
 	group := &pb.Group{
 		Members: make(map[uint64]*pb.Member),
@@ -711,6 +817,8 @@ func (g *groupi) doSendMembership(tablets map[string]*pb.Tablet) error {
 			group.SnapshotTs = snap.ReadTs
 		}
 		group.CheckpointTs = atomic.LoadUint64(&g.Node.checkpointTs)
+		group.MaxUid = atomic.LoadUint64(&g.Node.maxUid)
+		group.MaxNsid = atomic.LoadUint64(&g.Node.maxNsId)
 	}
 
 	pl := g.connToZeroLeader()
@@ -720,10 +828,16 @@ func (g *groupi) doSendMembership(tablets map[string]*pb.Tablet) error {
 	c := pb.NewZeroClient(pl.Get())
 	ctx, cancel := context.WithTimeout(g.Ctx(), 10*time.Second)
 	defer cancel()
-	reply, err := c.UpdateMembership(ctx, group)
+	var header metadata.MD
+	sent := time.Now()
+	reply, err := c.UpdateMembership(ctx, group, grpc.Header(&header))
 	if err != nil {
 		return err
 	}
+	if skew, ok := x.ClockSkew(header, sent, time.Now()); ok {
+		// Zero compares the skew against its threshold, we just report it with the next update.
+		atomic.StoreInt64(&g.clockSkewMs, skew.Milliseconds())
+	}
 	if string(reply.GetData()) == "OK" {
 		return nil
 	}
@@ -835,6 +949,7 @@ START:
 			if i == 0 {
 				glog.Infof("Received first state update from Zero: %+v", state)
 				x.WriteCidFile(state.Cid)
+				posting.SetCacheTierCluster(state.Cid)
 			}
 			select {
 			case stateCh <- state:
@@ -1062,6 +1177,38 @@ func EnterpriseEnabled() bool {
 	return state.GetLicense().GetEnabled()
 }
 
+// CheckReadOnly returns an *x.ReadOnlyError if Zero has put the cluster in read-only mode.
+func CheckReadOnly() error {
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	if ro := g.state.GetReadOnly(); ro.GetEnabled() {
+		return &x.ReadOnlyError{Reason: ro.GetReason()}
+	}
+	return nil
+}
+
+// UpdateReadOnly asks Zero to enable or disable read-only mode for the cluster, and waits for
+// this node to see the change. Other nodes see it as soon as Zero streams the new state to them.
+// The request goes through the ZeroAdmin service, so the credentials of the incoming request are
+// passed on to be authorized by Zero too.
+func UpdateReadOnly(ctx context.Context, enable bool, reason string) error {
+	pl := groups().connToZeroLeader()
+	if pl == nil {
+		return conn.ErrNoConnection
+	}
+	zc := pb.NewZeroAdminClient(pl.Get())
+	req := &pb.ReadOnlyMode{Enabled: enable, Reason: reason}
+	zctx := ctx
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		zctx = metadata.NewOutgoingContext(ctx, md)
+	}
+	if _, err := zc.UpdateReadOnly(zctx, req); err != nil {
+		return errors.Wrapf(err, "while updating read-only mode")
+	}
+	return UpdateMembershipState(ctx)
+}
+
 func (g *groupi) askZeroForEE() bool {
 	var err error
 	var connState *pb.ConnectionState
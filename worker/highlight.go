@@ -0,0 +1,82 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"html"
+	"strings"
+
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/tok"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const (
+	defaultHighlightPre  = "<em>"
+	defaultHighlightPost = "</em>"
+)
+
+// highlightMatches replaces the values in the value matrix of highlight(pred, text) by the values
+// with the words matching the full-text terms of the text between tags, <em> and </em> unless
+// they're given as the last two arguments. The text of the values with matches is HTML escaped,
+// so that only the tags are markup. The values without matches are left as they are.
+func highlightMatches(ctx context.Context, args funcArgs) {
+	q := args.q
+	lang := langForFunc(q.Langs)
+	if lang == "." {
+		lang = "en"
+	}
+	pre, post := defaultHighlightPre, defaultHighlightPost
+	if len(q.SrcFunc.Args) == 3 {
+		pre, post = q.SrcFunc.Args[1], q.SrcFunc.Args[2]
+	}
+	dict := schema.State().FulltextDictionary(ctx, q.Attr)
+	terms := x.RemoveDuplicates(tok.GetFullTextTerms(q.SrcFunc.Args[0], lang, dict))
+
+	for _, vl := range args.out.ValueMatrix {
+		for _, v := range vl.Values {
+			val := string(v.Val)
+			matches := tok.GetFullTextMatches(val, lang, dict, terms)
+			if len(matches) == 0 {
+				continue
+			}
+			var sb strings.Builder
+			var end int
+			for _, m := range matches {
+				// The matches of some languages can overlap, they're merged.
+				if m[0] < end {
+					if m[1] > end {
+						sb.WriteString(html.EscapeString(val[end:m[1]]))
+						end = m[1]
+					}
+					continue
+				}
+				if end > 0 {
+					sb.WriteString(post)
+				}
+				sb.WriteString(html.EscapeString(val[end:m[0]]))
+				sb.WriteString(pre)
+				sb.WriteString(html.EscapeString(val[m[0]:m[1]]))
+				end = m[1]
+			}
+			sb.WriteString(post)
+			sb.WriteString(html.EscapeString(val[end:]))
+			v.Val = []byte(sb.String())
+		}
+	}
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestHighlightMatches(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte("highlight_title: string ."), 1))
+	highlight := func(val string, args ...string) string {
+		out := &pb.Result{ValueMatrix: []*pb.ValueList{{Values: []*pb.TaskValue{
+			{Val: []byte(val), ValType: types.StringID.Enum()},
+		}}}}
+		q := &pb.Query{
+			Attr:    x.GalaxyAttr("highlight_title"),
+			SrcFunc: &pb.SrcFunction{Name: "highlight", Args: args},
+		}
+		highlightMatches(context.Background(), funcArgs{q: q, out: out})
+		return string(out.ValueMatrix[0].Values[0].Val)
+	}
+
+	require.Equal(t, "<em>Running</em> shoes for road <em>running</em>",
+		highlight("Running shoes for road running", "run"))
+	require.Equal(t, "Running [shoes], [shoes]!",
+		highlight("Running shoes, shoes!", "shoe", "[", "]"))
+	require.Equal(t, "Leather boots", highlight("Leather boots", "running shoes"))
+	// The text around the tags is escaped.
+	require.Equal(t, "&lt;b&gt;Shoes&lt;/b&gt; &amp; <em>running</em> &lt;script&gt;",
+		highlight("<b>Shoes</b> & running <script>", "run"))
+}
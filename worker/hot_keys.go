@@ -0,0 +1,53 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+)
+
+// offloadHotRead serves the task on another replica of the group now and then, if the mitigation
+// of hot keys is enabled and a key of the predicate was hot for reads on this node. The reads of
+// the hot keys are then balanced over all the replicas of the group, instead of being served by
+// the node which received the query. It returns false if the task must be served locally.
+func offloadHotRead(ctx context.Context, q *pb.Query, gid uint32) (*pb.Result, bool) {
+	if !posting.HasReadHotKeys(q.Attr) {
+		return nil, false
+	}
+	addrs := groups().OtherServers(gid)
+	// This node serves its own share of the tasks.
+	if len(addrs) == 0 || z.FastRand()%uint32(len(addrs)+1) == 0 {
+		return nil, false
+	}
+	if len(addrs) > 2 {
+		addrs = addrs[:2]
+	}
+	result, err := processWithBackupRequestTo(ctx, addrs,
+		func(ctx context.Context, c pb.WorkerClient) (interface{}, error) {
+			return c.ServeTask(ctx, q)
+		})
+	if err != nil {
+		glog.V(2).Infof("Serving task for hot predicate %s locally after error: %v", q.Attr, err)
+		return nil, false
+	}
+	return result.(*pb.Result), true
+}
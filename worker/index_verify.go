@@ -0,0 +1,330 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// IndexVerifyDefaults are the default values for the --index_verify superflag.
+const IndexVerifyDefaults = "every=0s; sample=1000; repair=true"
+
+// maxRecentDivergences is the number of divergences kept in the report of a predicate.
+const maxRecentDivergences = 10
+
+// IndexVerification is the report of the verification of the index of a predicate.
+type IndexVerification struct {
+	Predicate string
+	// DataKeys and IndexEntries are the numbers of data keys and index entries checked.
+	DataKeys     uint64
+	IndexEntries uint64
+	// Missing and Dangling are the numbers of index entries found missing and dangling.
+	Missing  uint64
+	Dangling uint64
+	Repaired uint64
+	LastRun  time.Time
+	// Recent holds the last divergences found.
+	Recent []posting.IndexDivergence
+}
+
+// indexVerifier checks the indexes of the predicates served by this node against their data in
+// the background, and repairs the entries which diverge. Every round checks the next sample data
+// keys and sample index entries of each indexed predicate, so that the whole index gets checked
+// over time. Since every replica may have diverged on its own, e.g. after a crash, every replica
+// checks its own store. The repairs are replicated to the whole group, where they're no-ops on
+// the replicas which didn't diverge.
+type indexVerifier struct {
+	every  time.Duration
+	sample int
+	repair bool
+
+	sync.Mutex
+	// dataCursors and indexCursors are the keys the next round starts from, by predicate.
+	dataCursors  map[string][]byte
+	indexCursors map[string][]byte
+	reports      map[string]*IndexVerification
+}
+
+// indexVerify is the verifier set up via the --index_verify flag, or nil.
+var indexVerify *indexVerifier
+
+func parseIndexVerify(sf *z.SuperFlag) (*indexVerifier, error) {
+	every, err := time.ParseDuration(sf.GetString("every"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing every")
+	}
+	if every == 0 {
+		return nil, nil
+	}
+	v := &indexVerifier{
+		every:        every,
+		sample:       int(sf.GetInt64("sample")),
+		repair:       sf.GetBool("repair"),
+		dataCursors:  make(map[string][]byte),
+		indexCursors: make(map[string][]byte),
+		reports:      make(map[string]*IndexVerification),
+	}
+	switch {
+	case every < 0:
+		return nil, errors.Errorf("every must not be negative")
+	case v.sample <= 0:
+		return nil, errors.Errorf("sample must be positive")
+	}
+	return v, nil
+}
+
+// IndexVerifications returns the reports of the verification of the indexes of this node, sorted
+// by predicate. It returns nil if the indexes aren't verified.
+func IndexVerifications() []IndexVerification {
+	v := indexVerify
+	if v == nil {
+		return nil
+	}
+	v.Lock()
+	defer v.Unlock()
+	reports := make([]IndexVerification, 0, len(v.reports))
+	for _, r := range v.reports {
+		report := *r
+		report.Recent = append([]posting.IndexDivergence{}, r.Recent...)
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Predicate < reports[j].Predicate })
+	return reports
+}
+
+func (v *indexVerifier) run(closer *z.Closer) {
+	defer closer.Done()
+
+	ticker := time.NewTicker(v.every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case <-ticker.C:
+			// The index entries are written by the mutations in any order in ludicrous mode.
+			if x.HealthCheck() != nil || x.WorkerConfig.LudicrousMode {
+				continue
+			}
+			if err := v.round(closer.Ctx()); err != nil {
+				glog.Errorf("While verifying the indexes: %v", err)
+			}
+		}
+	}
+}
+
+// freshTs returns a new timestamp from Zero, once the commits below it are applied.
+func freshTs(ctx context.Context) (uint64, error) {
+	ids, err := Timestamps(ctx, &pb.Num{Val: 1})
+	if err != nil {
+		return 0, errors.Wrapf(err, "while getting a timestamp")
+	}
+	return ids.StartId, posting.Oracle().WaitForTs(ctx, ids.StartId)
+}
+
+// round checks the next entries of the indexes of the predicates served by this group.
+func (v *indexVerifier) round(ctx context.Context) error {
+	ts, err := freshTs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, attr := range schema.State().Predicates() {
+		if !schema.State().IsIndexed(ctx, attr) || schema.State().ColdTablet(attr) != nil {
+			continue
+		}
+		if gid, err := groups().BelongsToReadOnly(attr, ts); err != nil ||
+			gid != groups().groupId() {
+			continue
+		}
+		if err := v.verify(ctx, attr, ts); err != nil {
+			return errors.Wrapf(err, "while verifying the index of %s", attr)
+		}
+	}
+	return nil
+}
+
+// repairIndex repairs the divergences which still hold, in a txn of its own. The txn is proposed
+// to the group like any other, so that every replica applies the repairs, and is committed via
+// Zero. It returns the number of entries repaired.
+func repairIndex(ctx context.Context, divs []posting.IndexDivergence) (int, error) {
+	startTs, err := freshTs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	// The divergences were found at an older ts, the mutations since may have fixed them.
+	var repairs []posting.IndexDivergence
+	for _, div := range divs {
+		ok, err := posting.Diverges(ctx, div, startTs)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			repairs = append(repairs, div)
+		}
+	}
+	if len(repairs) == 0 {
+		return 0, nil
+	}
+
+	m := &pb.Mutations{
+		GroupId:      groups().groupId(),
+		StartTs:      startTs,
+		IndexRepairs: posting.IndexRepairs(repairs),
+	}
+	tctx := &api.TxnContext{StartTs: startTs}
+	if err := (&grpcWorker{}).proposeAndWait(ctx, tctx, m); err != nil {
+		tctx.Aborted = true
+		_, _ = CommitOverNetwork(ctx, tctx)
+		return 0, err
+	}
+	if _, err := CommitOverNetwork(ctx, tctx); err != nil {
+		return 0, errors.Wrapf(err, "while committing the repairs")
+	}
+	return len(repairs), nil
+}
+
+// nextKeys returns the next n keys with the prefix from the cursor, at readTs. It wraps around
+// to the first keys once all the keys were returned.
+func nextKeys(prefix, cursor []byte, n int, readTs uint64) ([][]byte, error) {
+	txn := pstore.NewTransactionAt(readTs, false)
+	defer txn.Discard()
+	iopt := badger.DefaultIteratorOptions
+	iopt.PrefetchValues = false
+	iopt.Prefix = prefix
+	itr := txn.NewIterator(iopt)
+	defer itr.Close()
+
+	var keys [][]byte
+	seek := cursor
+	if len(seek) == 0 {
+		seek = prefix
+	}
+	for wrapped := false; len(keys) < n; wrapped = true {
+		for itr.Seek(seek); itr.ValidForPrefix(prefix) && len(keys) < n; itr.Next() {
+			if wrapped && bytes.Compare(itr.Item().Key(), cursor) >= 0 {
+				return keys, nil
+			}
+			keys = append(keys, itr.Item().KeyCopy(nil))
+		}
+		if wrapped || len(cursor) == 0 {
+			break
+		}
+		seek = prefix
+	}
+	return keys, nil
+}
+
+func (v *indexVerifier) verify(ctx context.Context, attr string, ts uint64) error {
+	v.Lock()
+	dataCursor, indexCursor := v.dataCursors[attr], v.indexCursors[attr]
+	v.Unlock()
+
+	pk := x.ParsedKey{Attr: attr}
+	var divs []posting.IndexDivergence
+	dataKeys, err := nextKeys(pk.DataPrefix(), dataCursor, v.sample, ts)
+	if err != nil {
+		return err
+	}
+	for _, key := range dataKeys {
+		pk, err := x.Parse(key)
+		if err != nil {
+			return err
+		}
+		missing, err := posting.VerifyDataIndexes(ctx, attr, pk.Uid, ts)
+		if err != nil {
+			return err
+		}
+		divs = append(divs, missing...)
+	}
+
+	var entries int
+	indexKeys, err := nextKeys(pk.IndexPrefix(), indexCursor, v.sample, ts)
+	if err != nil {
+		return err
+	}
+	for _, key := range indexKeys {
+		if entries >= v.sample {
+			break
+		}
+		dangling, checked, err := posting.VerifyIndexKey(ctx, key, ts, v.sample-entries)
+		if err != nil {
+			return err
+		}
+		entries += checked
+		divs = append(divs, dangling...)
+	}
+
+	var repaired int
+	if len(divs) > 0 {
+		glog.Warningf("Found %d index entries of %s which diverge from the data at ts %d",
+			len(divs), x.ParseAttr(attr), ts)
+		tagCtx, _ := tag.New(ctx, tag.Upsert(x.KeyPredicate, attr))
+		ostats.Record(tagCtx, x.NumIndexDivergences.M(int64(len(divs))))
+		if v.repair {
+			if repaired, err = repairIndex(ctx, divs); err != nil {
+				return errors.Wrapf(err, "while repairing the index")
+			}
+		}
+	}
+
+	v.Lock()
+	defer v.Unlock()
+	if n := len(dataKeys); n > 0 {
+		v.dataCursors[attr] = append(dataKeys[n-1], 0)
+	}
+	if n := len(indexKeys); n > 0 {
+		v.indexCursors[attr] = append(indexKeys[n-1], 0)
+	}
+	r, ok := v.reports[attr]
+	if !ok {
+		r = &IndexVerification{Predicate: attr}
+		v.reports[attr] = r
+	}
+	r.DataKeys += uint64(len(dataKeys))
+	r.IndexEntries += uint64(entries)
+	r.Repaired += uint64(repaired)
+	r.LastRun = time.Now()
+	for _, div := range divs {
+		if div.Missing {
+			r.Missing++
+		} else {
+			r.Dangling++
+		}
+		r.Recent = append(r.Recent, div)
+	}
+	if len(r.Recent) > maxRecentDivergences {
+		r.Recent = r.Recent[len(r.Recent)-maxRecentDivergences:]
+	}
+	return nil
+}
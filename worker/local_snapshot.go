@@ -0,0 +1,322 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/badger/v3/table"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// LocalSnapshot describes a copy of the postings and WAL directories of this Alpha, taken by
+// TakeLocalSnapshot.
+type LocalSnapshot struct {
+	Dir     string
+	GroupId uint32
+	// RaftIndex and ReadTs are those of the Raft snapshot of the copied WAL. The postings hold
+	// at least all the data up to ReadTs.
+	RaftIndex uint64
+	ReadTs    uint64
+	// LinkedFiles are the immutable files which were hard linked instead of copied.
+	LinkedFiles int
+	CopiedFiles int
+	BytesCopied int64
+	Took        time.Duration
+}
+
+// TakeLocalSnapshot copies the postings and WAL directories of this Alpha into the p and w
+// directories under dir, which must not exist or be empty. The copy is crash consistent: an Alpha
+// started from it recovers as if this one had crashed at that moment. The files of the LSM tree
+// are immutable, so they are hard linked when dir is on the same file system.
+//
+// The copy is meant to be moved to a new Alpha of the same group. It doesn't keep the Raft ID of
+// this Alpha, so the new one joins the group as a new member, and only has to catch up with the
+// entries written after the copy instead of streaming a snapshot of all the data.
+func TakeLocalSnapshot(dir string) (*LocalSnapshot, error) {
+	if Config.InMemory {
+		return nil, errors.Errorf("the postings and the WAL are kept in memory")
+	}
+	if groups().Node == nil {
+		return nil, errors.Errorf("this Alpha isn't ready yet")
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSnapshotDir(dir); err != nil {
+		return nil, err
+	}
+
+	sj.Lock()
+	if sj.snapshotRunning {
+		sj.Unlock()
+		return nil, errors.Errorf("a local snapshot is already being taken")
+	}
+	if sj.vlogGCRunning {
+		sj.Unlock()
+		return nil, errors.Errorf("value log GC is running, try again once it's done")
+	}
+	sj.snapshotRunning = true
+	sj.Unlock()
+	defer func() {
+		sj.Lock()
+		sj.snapshotRunning = false
+		sj.Unlock()
+	}()
+	// The value log GC deletes the value log files, which the copied tables may refer to.
+	paused := x.VlogGCPaused()
+	x.PauseVlogGC(true)
+	defer x.PauseVlogGC(paused)
+
+	start := time.Now()
+	pdir, wdir := filepath.Join(dir, "p"), filepath.Join(dir, "w")
+	for _, d := range []string{pdir, wdir} {
+		if err := os.MkdirAll(d, 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	// The WAL is copied first. The postings copied after it are newer, so replaying the WAL
+	// from its checkpoint on restart brings them up to date, like after a crash.
+	raftSnap, walBytes, err := State.WALstore.Clone(wdir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while copying the WAL")
+	}
+	snap := &LocalSnapshot{Dir: dir, GroupId: groups().groupId(), RaftIndex: raftSnap.Metadata.Index,
+		CopiedFiles: 1, BytesCopied: walBytes}
+	var data pb.Snapshot
+	if err := data.Unmarshal(raftSnap.Data); err != nil {
+		return nil, err
+	}
+	snap.ReadTs = data.ReadTs
+
+	if err := copyPostings(Config.PostingDir, pdir, snap); err != nil {
+		return nil, errors.Wrapf(err, "while copying the postings")
+	}
+	if err := x.WriteGroupIdFile(pdir, snap.GroupId); err != nil {
+		return nil, err
+	}
+	snap.Took = time.Since(start)
+	glog.Infof("Took a local snapshot in %s: %+v", snap.Took.Round(time.Millisecond), snap)
+	return snap, nil
+}
+
+// checkSnapshotDir checks that dir doesn't exist or is empty.
+func checkSnapshotDir(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return err
+	case len(entries) > 0:
+		return errors.Errorf("directory %s isn't empty", dir)
+	}
+	for _, d := range []string{Config.PostingDir, Config.WALDir} {
+		if abs, err := filepath.Abs(d); err == nil && strings.HasPrefix(dir+"/", abs+"/") {
+			return errors.Errorf("directory %s is inside %s", dir, d)
+		}
+	}
+	return nil
+}
+
+// copyPostings copies the Badger directory src into dst while Badger writes to it. The memtables
+// are copied first, and the value log last, so that all the values the copied tables and
+// memtables point to are copied. The tables are linked along with the manifest, until the copied
+// manifest only refers to copied tables.
+func copyPostings(src, dst string, snap *LocalSnapshot) error {
+	if err := copyFiles(src, dst, ".mem", snap); err != nil {
+		return err
+	}
+
+	const attempts = 10
+	var copied bool
+	for i := 0; i < attempts && !copied; i++ {
+		if err := linkTables(src, dst, snap); err != nil {
+			return err
+		}
+		if err := copyFile(filepath.Join(src, badger.ManifestFilename),
+			filepath.Join(dst, badger.ManifestFilename), snap); err != nil {
+			return err
+		}
+		// The tables written while the manifest was copied.
+		if err := linkTables(src, dst, snap); err != nil {
+			return err
+		}
+		var err error
+		if copied, err = hasAllTables(dst); err != nil {
+			return err
+		}
+	}
+	if !copied {
+		return errors.Errorf("the tables kept changing after %d attempts", attempts)
+	}
+
+	if err := copyFiles(src, dst, ".vlog", snap); err != nil {
+		return err
+	}
+	// The key registry of the encrypted files.
+	name := badger.KeyRegistryFileName
+	err := copyFile(filepath.Join(src, name), filepath.Join(dst, name), snap)
+	if err != nil && !os.IsNotExist(errors.Cause(err)) {
+		return err
+	}
+	return nil
+}
+
+// hasAllTables returns true if all the tables of the manifest in dir are there. The tables which
+// the manifest doesn't refer to are removed.
+func hasAllTables(dir string) (bool, error) {
+	f, err := os.Open(filepath.Join(dir, badger.ManifestFilename))
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	manifest, _, err := badger.ReplayManifestFile(f)
+	if err != nil {
+		return false, err
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	found := make(map[uint64]struct{})
+	for _, e := range entries {
+		id, ok := table.ParseFileID(e.Name())
+		if !ok {
+			continue
+		}
+		if _, ok := manifest.Tables[id]; !ok {
+			if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+				return false, err
+			}
+			continue
+		}
+		found[id] = struct{}{}
+	}
+	return len(found) == len(manifest.Tables), nil
+}
+
+// linkTables hard links the tables of src which aren't in dst yet, or copies them if the
+// directories are on different file systems.
+func linkTables(src, dst string, snap *LocalSnapshot) error {
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, ok := table.ParseFileID(e.Name()); !ok {
+			continue
+		}
+		from, to := filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())
+		if _, err := os.Stat(to); err == nil {
+			continue
+		}
+		err := os.Link(from, to)
+		switch {
+		case err == nil:
+			snap.LinkedFiles++
+			continue
+		case os.IsNotExist(err):
+			// Compacted meanwhile.
+			continue
+		case !errors.Is(err, syscall.EXDEV):
+			return err
+		}
+		if err := copyFile(from, to, snap); err != nil && !os.IsNotExist(errors.Cause(err)) {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFiles copies the files of src with the given suffix to dst.
+func copyFiles(src, dst, suffix string, snap *LocalSnapshot) error {
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), suffix) {
+			continue
+		}
+		err := copyFile(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name()), snap)
+		if err != nil && !os.IsNotExist(errors.Cause(err)) {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies the file src to dst, replacing it. The preallocated files are mostly zeroed, so
+// the zeroed blocks are left as holes.
+func copyFile(src, dst string, snap *LocalSnapshot) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.Wrapf(err, "while opening %s", src)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	const blockSize = 64 << 10
+	buf, zero := make([]byte, blockSize), make([]byte, blockSize)
+	var size int64
+	for {
+		n, err := io.ReadFull(in, buf)
+		if n > 0 && !bytes.Equal(buf[:n], zero[:n]) {
+			if _, werr := out.WriteAt(buf[:n], size); werr != nil {
+				out.Close()
+				return werr
+			}
+			snap.BytesCopied += int64(n)
+		}
+		size += int64(n)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err := out.Truncate(size); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	snap.CopiedFiles++
+	return out.Close()
+}
@@ -17,6 +17,12 @@
 package worker
 
 import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
 	"github.com/dgraph-io/dgraph/algo"
 	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
@@ -108,3 +114,58 @@ func uidsForMatch(attr string, arg funcArgs) (*pb.List, error) {
 	}
 	return algo.MergeSorted(uidMatrix), nil
 }
+
+// uidsForFuzzy collects the uids which may have a value within the Levenshtein distance of the
+// fuzzy function from its value. Unlike uidsForMatch, it doesn't miss any of them: an edit
+// changes at most as many trigrams of the value as the bytes of a rune plus two, so the values
+// which match share at least the other trigrams with it. All the uids having the predicate are
+// candidates if no trigram is left.
+func (qs *queryState) uidsForFuzzy(ctx context.Context, arg funcArgs) (*pb.List, error) {
+	attr := arg.q.Attr
+	query := strings.Join(arg.srcFn.tokens, "")
+	tokens, err := tok.GetTokens(tok.IdentTrigram, query)
+	if err != nil {
+		return nil, err
+	}
+	width := 1
+	for _, r := range query {
+		if n := utf8.RuneLen(r); n > width {
+			width = n
+		}
+	}
+	need := len(tokens) - int(arg.srcFn.threshold[0])*(width+2)
+
+	if need <= 0 {
+		// The candidates aren't limited to the first ones, as they're filtered afterwards.
+		q := &pb.Query{Attr: attr, ReadTs: arg.q.ReadTs, Langs: arg.q.Langs,
+			First: math.MaxInt32}
+		out := &pb.Result{}
+		if err := qs.handleHasFunction(ctx, q, out, arg.srcFn); err != nil {
+			return nil, err
+		}
+		return algo.MergeSorted(out.UidMatrix), nil
+	}
+
+	counts := make(map[uint64]int)
+	for _, t := range tokens {
+		pl, err := posting.GetNoStore(x.IndexKey(attr, t), arg.q.ReadTs)
+		if err != nil {
+			return nil, err
+		}
+		uids, err := pl.Uids(posting.ListOptions{ReadTs: arg.q.ReadTs})
+		if err != nil {
+			return nil, err
+		}
+		for _, uid := range uids.Uids {
+			counts[uid]++
+		}
+	}
+	res := &pb.List{}
+	for uid, n := range counts {
+		if n >= need {
+			res.Uids = append(res.Uids, uid)
+		}
+	}
+	sort.Slice(res.Uids, func(i, j int) bool { return res.Uids[i] < res.Uids[j] })
+	return res, nil
+}
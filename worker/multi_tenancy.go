@@ -37,3 +37,12 @@ func ProcessDeleteNsRequest(ctx context.Context, ns uint64) error {
 func proposeDeleteOrSend(ctx context.Context, req *pb.DeleteNsRequest) error {
 	return nil
 }
+
+func (w *grpcWorker) CloneNamespace(ctx context.Context,
+	req *pb.CloneNsRequest) (*pb.Status, error) {
+	return nil, x.ErrNotSupported
+}
+
+func ProcessCloneNsRequest(ctx context.Context, from, to uint64) error {
+	return x.ErrNotSupported
+}
@@ -13,12 +13,19 @@
 package worker
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/dgraph-io/badger/v3"
+	bpb "github.com/dgraph-io/badger/v3/pb"
 	"github.com/dgraph-io/dgraph/conn"
+	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
 	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
@@ -79,3 +86,193 @@ func proposeDeleteOrSend(ctx context.Context, req *pb.DeleteNsRequest) error {
 	_, err := c.DeleteNamespace(ctx, req)
 	return err
 }
+
+func (w *grpcWorker) CloneNamespace(ctx context.Context,
+	req *pb.CloneNsRequest) (*pb.Status, error) {
+	var emptyRes pb.Status
+	if !groups().ServesGroup(req.GroupId) {
+		return &emptyRes, errors.Errorf("The server doesn't serve group id: %v", req.GroupId)
+	}
+	if err := cloneNamespace(ctx, req); err != nil {
+		return &emptyRes, errors.Wrapf(err, "Clone of namespace %d to %d failed on group %d",
+			req.From, req.To, req.GroupId)
+	}
+	return &emptyRes, nil
+}
+
+// ProcessCloneNsRequest copies the schema, types and data of namespace from into namespace to,
+// as they are at the time of the request. Every group copies the predicates it serves.
+func ProcessCloneNsRequest(ctx context.Context, from, to uint64) error {
+	if err := UpdateMembershipState(ctx); err != nil {
+		return errors.Wrapf(err, "Failed to update membership state while cloning namespace")
+	}
+
+	readTs := State.GetTimestamp(true)
+	state := GetMembershipState()
+	g := new(errgroup.Group)
+	for gid := range state.Groups {
+		req := &pb.CloneNsRequest{GroupId: gid, From: from, To: to, ReadTs: readTs}
+		g.Go(func() error {
+			return x.RetryUntilSuccess(100, 10*time.Second, func() error {
+				return proposeCloneOrSend(ctx, req)
+			})
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return errors.Wrap(err, "Failed to process clone request")
+	}
+	return nil
+}
+
+func proposeCloneOrSend(ctx context.Context, req *pb.CloneNsRequest) error {
+	glog.V(2).Infof("Sending clone namespace request: %+v", req)
+	if groups().ServesGroup(req.GetGroupId()) && groups().Node.AmLeader() {
+		_, err := (&grpcWorker{}).CloneNamespace(ctx, req)
+		return err
+	}
+
+	pl := groups().Leader(req.GetGroupId())
+	if pl == nil {
+		return conn.ErrNoConnection
+	}
+	c := pb.NewWorkerClient(pl.Get())
+	_, err := c.CloneNamespace(ctx, req)
+	return err
+}
+
+// cloneNamespace copies the keys of namespace req.From that this group serves to namespace
+// req.To, as of req.ReadTs. The copies are proposed to the group, so that every replica writes
+// them. It must be run by the leader of the group.
+func cloneNamespace(ctx context.Context, req *pb.CloneNsRequest) error {
+	if !groups().Node.AmLeader() {
+		return errNotLeader
+	}
+	if err := posting.Oracle().WaitForTs(ctx, req.ReadTs); err != nil {
+		return err
+	}
+
+	from, to := x.NamespaceToBytes(req.From), x.NamespaceToBytes(req.To)
+	toAttr := func(attr string) string {
+		return x.NamespaceAttr(req.To, x.ParseAttr(attr))
+	}
+	// Only the predicates served by this group are cloned by it. The new predicates are served
+	// by the same group.
+	served := make(map[string]bool)
+	for _, attr := range schema.State().Predicates() {
+		if ns, _ := x.ParseNamespaceAttr(attr); ns != req.From {
+			continue
+		}
+		if gid, err := groups().BelongsToReadOnly(attr, req.ReadTs); err != nil {
+			return err
+		} else if gid != groups().groupId() {
+			continue
+		}
+		if _, err := groups().ForceTablet(toAttr(attr)); err != nil {
+			return errors.Wrapf(err, "while serving predicate %s", x.ParseAttr(attr))
+		}
+		served[attr] = true
+	}
+
+	stream := pstore.NewStreamAt(req.ReadTs)
+	stream.LogPrefix = fmt.Sprintf("Cloning namespace %#x to %#x", req.From, req.To)
+	stream.ChooseKey = func(item *badger.Item) bool {
+		key := item.Key()
+		if len(key) < 9 || !bytes.Equal(key[1:9], from) {
+			return false
+		}
+		pk, err := x.Parse(key)
+		if err != nil || pk.HasStartUid {
+			// The splits of a posting list are cloned along with it.
+			return false
+		}
+		return pk.IsType() || served[pk.Attr]
+	}
+	stream.KeyToList = func(key []byte, itr *badger.Iterator) (*bpb.KVList, error) {
+		pk, err := x.Parse(key)
+		if err != nil {
+			return nil, err
+		}
+		var kvs []*bpb.KV
+		switch {
+		case pk.IsSchema() || pk.IsType():
+			// Schema and types are written at timestamp 1, like updateSchema and updateType do.
+			item := itr.Item()
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				return nil, err
+			}
+			if pk.IsSchema() {
+				var su pb.SchemaUpdate
+				if err := su.Unmarshal(val); err != nil {
+					return nil, err
+				}
+				su.Predicate = toAttr(su.Predicate)
+				val, err = su.Marshal()
+			} else {
+				var tu pb.TypeUpdate
+				if err := tu.Unmarshal(val); err != nil {
+					return nil, err
+				}
+				tu.TypeName = toAttr(tu.TypeName)
+				for _, field := range tu.Fields {
+					field.Predicate = toAttr(field.Predicate)
+				}
+				val, err = tu.Marshal()
+			}
+			if err != nil {
+				return nil, err
+			}
+			kvs = append(kvs, &bpb.KV{Key: key, Value: val, UserMeta: []byte{item.UserMeta()},
+				Version: 1})
+		default:
+			l, err := posting.ReadPostingList(key, itr)
+			if err != nil {
+				return nil, err
+			}
+			if kvs, err = l.Rollup(itr.Alloc); err != nil {
+				return nil, err
+			}
+			for _, kv := range kvs {
+				kv.Version = req.ReadTs
+			}
+		}
+		for _, kv := range kvs {
+			kv.Key = append([]byte{}, kv.Key...)
+			copy(kv.Key[1:9], to)
+		}
+		return &bpb.KVList{Kv: kvs}, nil
+	}
+
+	n := groups().Node
+	proposal := &pb.Proposal{}
+	size := 0
+	stream.Send = func(buf *z.Buffer) error {
+		return buf.SliceIterate(func(s []byte) error {
+			kv := &bpb.KV{}
+			if err := kv.Unmarshal(s); err != nil {
+				return err
+			}
+			proposal.Kv = append(proposal.Kv, kv)
+			size += len(kv.Key) + len(kv.Value)
+			if size < 32<<20 {
+				return nil
+			}
+			if err := n.proposeAndWait(ctx, proposal); err != nil {
+				return err
+			}
+			proposal = &pb.Proposal{}
+			size = 0
+			return nil
+		})
+	}
+	if err := stream.Orchestrate(ctx); err != nil {
+		return err
+	}
+	if len(proposal.Kv) > 0 {
+		if err := n.proposeAndWait(ctx, proposal); err != nil {
+			return err
+		}
+	}
+	glog.Infof("Cloned %d predicates of namespace %#x to %#x", len(served), req.From, req.To)
+	return nil
+}
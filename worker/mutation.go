@@ -19,6 +19,7 @@ package worker
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"math"
 	"sync"
 	"sync/atomic"
@@ -27,6 +28,7 @@ import (
 	ostats "go.opencensus.io/stats"
 
 	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
 	"github.com/pkg/errors"
 	otrace "go.opencensus.io/trace"
 
@@ -69,7 +71,12 @@ func runMutation(ctx context.Context, edge *pb.DirectedEdge, txn *posting.Txn) e
 			return errors.Errorf("runMutation: Unable to find schema for %s", edge.Attr)
 		}
 	}
+	return runMutationWithSchema(ctx, edge, su, txn)
+}
 
+// runMutationWithSchema applies the edge, validating it against the schema su.
+func runMutationWithSchema(ctx context.Context, edge *pb.DirectedEdge, su pb.SchemaUpdate,
+	txn *posting.Txn) error {
 	if isDeletePredicateEdge(edge) {
 		return errors.New("We should never reach here")
 	}
@@ -85,13 +92,13 @@ func runMutation(ctx context.Context, edge *pb.DirectedEdge, txn *posting.Txn) e
 	// The following is a performance optimization which allows us to not read a posting list from
 	// disk. We calculate this based on how AddMutationWithIndex works. The general idea is that if
 	// we're not using the read posting list, we don't need to retrieve it. We need the posting list
-	// if we're doing indexing or count index or enforcing single UID, etc. In other cases, we can
-	// just create a posting list facade in memory and use it to store the delta in Badger. Later,
-	// the rollup operation would consolidate all these deltas into a posting list.
+	// if we're doing indexing or count or presence index or enforcing single UID, etc. In other
+	// cases, we can just create a posting list facade in memory and use it to store the delta in
+	// Badger. Later, the rollup operation would consolidate all these deltas into a posting list.
 	var getFn func(key []byte) (*posting.List, error)
 	switch {
-	case len(su.GetTokenizer()) > 0 || su.GetCount():
-		// Any index or count index.
+	case len(su.GetTokenizer()) > 0 || su.GetCount() || su.GetPresence():
+		// Any index, count or presence index.
 		getFn = txn.Get
 	case su.GetValueType() == pb.Posting_UID && !su.GetList():
 		// Single UID, not a list.
@@ -132,10 +139,60 @@ func undoSchemaUpdate(predicate string) {
 	}
 }
 
+// setFulltextDictionaries completes the schema updates of the mutations with the dictionaries of
+// the fulltext indexes. If the updates only set the dictionaries, the rest of the schema of their
+// predicates is kept. Otherwise, the dictionaries are kept as long as the predicates still have a
+// fulltext index, unless the updates give them with @synonyms or @protected.
+func setFulltextDictionaries(ctx context.Context, m *pb.Mutations) error {
+	for i, su := range m.Schema {
+		old, ok := schema.State().Get(ctx, su.Predicate)
+		if !m.FulltextDictionary {
+			if ok && hasFulltextIndex(su) && hasFulltextIndex(&old) &&
+				len(su.FulltextSynonyms) == 0 && len(su.FulltextProtected) == 0 {
+				su.FulltextSynonyms = old.FulltextSynonyms
+				su.FulltextProtected = old.FulltextProtected
+			}
+			continue
+		}
+		if !ok || !hasFulltextIndex(&old) {
+			return errors.Errorf("Predicate %s doesn't have a fulltext index",
+				x.ParseAttr(su.Predicate))
+		}
+		old.FulltextSynonyms = su.FulltextSynonyms
+		old.FulltextProtected = su.FulltextProtected
+		m.Schema[i] = &old
+	}
+	return nil
+}
+
+func hasFulltextIndex(su *pb.SchemaUpdate) bool {
+	if su.Directive != pb.SchemaUpdate_INDEX {
+		return false
+	}
+	for _, t := range su.Tokenizer {
+		if t == "fulltext" {
+			return true
+		}
+	}
+	return false
+}
+
 func runSchemaMutation(ctx context.Context, updates []*pb.SchemaUpdate, startTs uint64) error {
 	if len(updates) == 0 {
 		return nil
 	}
+	// The schema of the cold predicates can't change, but it can be set again as is, e.g. by
+	// a GraphQL schema update.
+	for _, su := range updates {
+		cold := schema.State().ColdTablet(su.Predicate)
+		if cold == nil {
+			continue
+		}
+		su.Cold = cold
+		if old, _ := schema.State().Get(ctx, su.Predicate); !proto.Equal(su, &old) {
+			return errColdPredicate(su.Predicate)
+		}
+	}
 	// Wait until schema modification for all predicates is complete. There cannot be two
 	// background tasks running as this is a race condition. We typically won't propose an
 	// index update if one is already going on. If that's not the case, then the receiver
@@ -179,8 +236,30 @@ func runSchemaMutation(ctx context.Context, updates []*pb.SchemaUpdate, startTs
 
 	buildIndexesHelper := func(update *pb.SchemaUpdate, rebuild posting.IndexRebuild) error {
 		wrtCtx := schema.GetWriteContext(context.Background())
-		if err := rebuild.BuildIndexes(wrtCtx); err != nil {
-			return err
+		if !gr.Node.AmLeader() {
+			if err := rebuild.BuildIndexes(wrtCtx); err != nil {
+				return err
+			}
+		} else {
+			// Every replica rebuilds the indexes, but only the leader reports it as a task. It
+			// can't be paused or cancelled, as that would only hold up or stop the rebuild on
+			// the leader, leaving the replicas with different indexes and schemas.
+			ns, attr := x.ParseNamespaceAttr(update.Predicate)
+			opts := TaskOptions{
+				Kind: "index-rebuild",
+				Description: fmt.Sprintf("Rebuild indexes of predicate %s of namespace %#x",
+					attr, ns),
+			}
+			err := RunTask(wrtCtx, opts, func(ctx context.Context, t *Task) error {
+				rebuild.Checkpoint = func(ctx context.Context, done, total uint64) error {
+					return t.Progress(ctx, done, total,
+						fmt.Sprintf("Rebuilt %d of %d kinds of indexes", done, total))
+				}
+				return rebuild.BuildIndexes(ctx)
+			})
+			if err != nil {
+				return err
+			}
 		}
 		if err := updateSchema(update); err != nil {
 			return err
@@ -288,20 +367,7 @@ func createSchema(attr string, typ types.TypeID, hint pb.Metadata_HintType) erro
 	if ok {
 		s.ValueType = typ.Enum()
 	} else {
-		s = pb.SchemaUpdate{ValueType: typ.Enum(), Predicate: attr}
-		// For type UidID, set List to true. This is done because previously
-		// all predicates of type UidID were implicitly considered lists.
-		if typ == types.UidID {
-			s.List = true
-		}
-
-		switch hint {
-		case pb.Metadata_SINGLE:
-			s.List = false
-		case pb.Metadata_LIST:
-			s.List = true
-		default:
-		}
+		s = derivedSchema(attr, typ, hint)
 	}
 	if err := checkSchema(&s); err != nil {
 		return err
@@ -309,6 +375,25 @@ func createSchema(attr string, typ types.TypeID, hint pb.Metadata_HintType) erro
 	return updateSchema(&s)
 }
 
+// derivedSchema returns the schema of a predicate first seen in a mutation of type typ.
+func derivedSchema(attr string, typ types.TypeID, hint pb.Metadata_HintType) pb.SchemaUpdate {
+	s := pb.SchemaUpdate{ValueType: typ.Enum(), Predicate: attr}
+	// For type UidID, set List to true. This is done because previously
+	// all predicates of type UidID were implicitly considered lists.
+	if typ == types.UidID {
+		s.List = true
+	}
+
+	switch hint {
+	case pb.Metadata_SINGLE:
+		s.List = false
+	case pb.Metadata_LIST:
+		s.List = true
+	default:
+	}
+	return s
+}
+
 func runTypeMutation(ctx context.Context, update *pb.TypeUpdate) error {
 	current := *update
 	schema.State().SetType(update.TypeName, current)
@@ -619,6 +704,7 @@ func populateMutationMap(src *pb.Mutations) (map[uint32]*pb.Mutations, error) {
 			mm[gid] = mu
 		}
 		mu.Schema = append(mu.Schema, schema)
+		mu.FulltextDictionary = src.FulltextDictionary
 	}
 
 	if src.DropOp > 0 {
@@ -676,6 +762,8 @@ func MutateOverNetwork(ctx context.Context, m *pb.Mutations) (*api.TxnContext, e
 			return tctx, errNonExistentTablet
 		}
 		mu.StartTs = m.StartTs
+		mu.Forwarded = m.Forwarded
+		mu.DryRun = m.DryRun
 		go proposeOrSend(ctx, gid, mu, resCh)
 	}
 
@@ -789,6 +877,13 @@ func CommitOverNetwork(ctx context.Context, tc *api.TxnContext) (uint64, error)
 	ctx, span := otrace.StartSpan(ctx, "worker.CommitOverNetwork")
 	defer span.End()
 
+	// Reject an invalid durability before the commit, so that the client isn't left guessing
+	// whether its transaction was committed.
+	durability, err := x.DurabilityFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	clientDiscard := false
 	if tc.Aborted {
 		// The client called Discard
@@ -820,6 +915,17 @@ func CommitOverNetwork(ctx context.Context, tc *api.TxnContext) (uint64, error)
 		return 0, dgo.ErrAborted
 	}
 	ostats.Record(ctx, x.TxnCommits.M(1))
+	if durability == x.DurabilityReplicated {
+		if err := waitForFollowers(ctx, tctx.CommitTs, tc.Preds); err != nil {
+			return tctx.CommitTs, errors.Wrapf(err, "transaction committed at %d, but could not "+
+				"confirm that it was applied by a follower", tctx.CommitTs)
+		}
+	}
+	if hook != nil {
+		if err := hook.run(ctx, tc, tctx.CommitTs); err != nil {
+			return tctx.CommitTs, err
+		}
+	}
 	return tctx.CommitTs, nil
 }
 
@@ -842,12 +948,58 @@ func (w *grpcWorker) proposeAndWait(ctx context.Context, txnCtx *api.TxnContext,
 		return err
 	}
 
+	if m.DryRun {
+		return dryRunMutations(ctx, txnCtx, m)
+	}
+
 	node := groups().Node
 	err := node.proposeAndWait(ctx, &pb.Proposal{Mutations: m})
+	if err == errUnservedTablet && !m.Forwarded {
+		// The tablets were moved away from this group after the mutation was routed here. Forward
+		// it to the groups serving them now, instead of failing the transaction.
+		return forwardMutation(ctx, txnCtx, m)
+	}
 	fillTxnContext(txnCtx, m.StartTs)
 	return err
 }
 
+func forwardMutation(ctx context.Context, txnCtx *api.TxnContext, m *pb.Mutations) error {
+	if err := checkForwardable(m); err != nil {
+		if err == x.ErrConflict {
+			// Abort the txn, so that the writes it already made in other groups aren't committed.
+			tryAbortTransactions([]uint64{m.StartTs})
+		}
+		return err
+	}
+	glog.V(2).Infof("Forwarding mutation at start ts %d to the groups serving its tablets",
+		m.StartTs)
+	m.Forwarded = true
+	tctx, err := MutateOverNetwork(ctx, m)
+	if tctx != nil {
+		txnCtx.Keys = append(txnCtx.Keys, tctx.Keys...)
+		txnCtx.Preds = append(txnCtx.Preds, tctx.Preds...)
+	}
+	return err
+}
+
+// checkForwardable returns x.ErrConflict if the mutation started before a tablet it writes to was
+// moved. The destination group applies writes on top of the snapshot of the tablet taken at the
+// move, so the writes from before it would be lost. The client retries the txn at a newer ts.
+func checkForwardable(m *pb.Mutations) error {
+	for _, edge := range m.Edges {
+		tablet, err := groups().Tablet(edge.Attr)
+		if err != nil {
+			return err
+		}
+		if tablet != nil && m.StartTs < tablet.MoveTs {
+			glog.V(2).Infof("Mutation at start ts %d writes to %q, moved at ts %d. Aborting it.",
+				m.StartTs, edge.Attr, tablet.MoveTs)
+			return x.ErrConflict
+		}
+	}
+	return nil
+}
+
 // Mutate is used to apply mutations over the network on other instances.
 func (w *grpcWorker) Mutate(ctx context.Context, m *pb.Mutations) (*api.TxnContext, error) {
 	ctx, span := otrace.StartSpan(ctx, "worker.Mutate")
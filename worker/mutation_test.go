@@ -17,6 +17,7 @@
 package worker
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
@@ -209,6 +210,50 @@ func TestCheckSchema(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestSetFulltextDictionaries(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte("title: string @index(fulltext) ."), 1))
+	attr := x.GalaxyAttr("title")
+	ctx := context.Background()
+
+	// The update only sets the dictionary, the rest of the schema is kept.
+	dict := &pb.SchemaUpdate{
+		Predicate:         attr,
+		FulltextSynonyms:  []*pb.Synonyms{{Words: []string{"tv", "television"}}},
+		FulltextProtected: []string{"news"},
+	}
+	m := &pb.Mutations{Schema: []*pb.SchemaUpdate{dict}, FulltextDictionary: true}
+	require.NoError(t, setFulltextDictionaries(ctx, m))
+	require.Equal(t, []string{"fulltext"}, m.Schema[0].Tokenizer)
+	require.Equal(t, dict.FulltextSynonyms, m.Schema[0].FulltextSynonyms)
+	schema.State().Set(attr, m.Schema[0])
+
+	// The dictionary is kept as long as the predicate has a fulltext index.
+	result, err := schema.Parse("title: string @index(fulltext, term) .")
+	require.NoError(t, err)
+	m = &pb.Mutations{Schema: result.Preds}
+	require.NoError(t, setFulltextDictionaries(ctx, m))
+	require.Equal(t, dict.FulltextProtected, m.Schema[0].FulltextProtected)
+
+	// The dictionary given in the schema, as exported, replaces it.
+	result, err = schema.Parse("title: string @index(fulltext) @protected(sports) .")
+	require.NoError(t, err)
+	m = &pb.Mutations{Schema: result.Preds}
+	require.NoError(t, setFulltextDictionaries(ctx, m))
+	require.Equal(t, []string{"sports"}, m.Schema[0].FulltextProtected)
+	require.Empty(t, m.Schema[0].FulltextSynonyms)
+
+	result, err = schema.Parse("title: string @index(term) .")
+	require.NoError(t, err)
+	m = &pb.Mutations{Schema: result.Preds}
+	require.NoError(t, setFulltextDictionaries(ctx, m))
+	require.Empty(t, m.Schema[0].FulltextProtected)
+
+	// A dictionary can't be set without a fulltext index.
+	require.NoError(t, schema.ParseBytes([]byte("title: string ."), 1))
+	m = &pb.Mutations{Schema: []*pb.SchemaUpdate{dict}, FulltextDictionary: true}
+	require.Error(t, setFulltextDictionaries(ctx, m))
+}
+
 func TestTypeSanityCheck(t *testing.T) {
 	// Empty field name check.
 	typeDef := &pb.TypeUpdate{
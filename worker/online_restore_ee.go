@@ -15,6 +15,8 @@ package worker
 import (
 	"compress/gzip"
 	"context"
+	"crypto/ed25519"
+	"fmt"
 	"net/url"
 	"strings"
 	"sync"
@@ -64,6 +66,16 @@ func ProcessRestoreRequest(ctx context.Context, req *pb.RestoreRequest, wg *sync
 	if err := VerifyBackup(req, &creds, currentGroups); err != nil {
 		return errors.Wrapf(err, "failed to verify backup")
 	}
+	if req.VerifyKeyFile != "" {
+		pub, err := enc.ReadVerifyKey(req.VerifyKeyFile)
+		if err != nil {
+			return errors.Wrapf(err, "cannot read verify key")
+		}
+		err = VerifyBackupSignatures(req.Location, req.BackupId, req.BackupNum, &creds, pub)
+		if err != nil {
+			return errors.Wrapf(err, "failed to verify backup signatures")
+		}
+	}
 	if err := FillRestoreCredentials(req.Location, req); err != nil {
 		return errors.Wrapf(err, "cannot fill restore proposal with the right credentials")
 	}
@@ -103,18 +115,43 @@ func ProcessRestoreRequest(ctx context.Context, req *pb.RestoreRequest, wg *sync
 		}()
 	}
 
+	opts := TaskOptions{
+		Kind:        "restore",
+		Description: fmt.Sprintf("Restore from %s", redactLocation(req.Location)),
+	}
 	go func() {
-		for range currentGroups {
-			if err := <-errCh; err != nil {
-				glog.Errorf("Error while restoring %v", err)
+		err := RunTask(ctx, opts, func(ctx context.Context, t *Task) error {
+			var rerr error
+			for i := range currentGroups {
+				if err := <-errCh; err != nil {
+					glog.Errorf("Error while restoring %v", err)
+					rerr = err
+				}
+				wg.Done()
+				_ = t.Progress(ctx, uint64(i+1), uint64(len(currentGroups)),
+					fmt.Sprintf("Restored %d of %d groups", i+1, len(currentGroups)))
 			}
-			wg.Done()
+			return rerr
+		})
+		if err != nil {
+			glog.Errorf("Restore failed: %v", err)
 		}
 	}()
 
 	return nil
 }
 
+// redactLocation removes the credentials which could be part of the URL of a backup location.
+func redactLocation(location string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "backup"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
+
 func proposeRestoreOrSend(ctx context.Context, req *pb.RestoreRequest) error {
 	if groups().ServesGroup(req.GetGroupId()) && groups().Node.AmLeader() {
 		_, err := (&grpcWorker{}).Restore(ctx, req)
@@ -286,6 +323,7 @@ func getEncConfig(req *pb.RestoreRequest) (*viper.Viper, error) {
 	config := viper.New()
 	flags := &pflag.FlagSet{}
 	enc.RegisterFlags(flags)
+	enc.RegisterPrivateKeyFlags(flags)
 	if err := config.BindPFlags(flags); err != nil {
 		return nil, errors.Wrapf(err, "bad config bind")
 	}
@@ -294,6 +332,8 @@ func getEncConfig(req *pb.RestoreRequest) (*viper.Viper, error) {
 	config.Set("encryption_key_file", req.EncryptionKeyFile)
 	config.Set("vault_roleid_file", req.VaultRoleidFile)
 	config.Set("vault_secretid_file", req.VaultSecretidFile)
+	config.Set("private_key_file", req.PrivateKeyFile)
+	config.Set("vault_private_key_field", req.VaultPrivateKeyField)
 
 	// Override only if non-nil
 	if req.VaultAddr != "" {
@@ -321,8 +361,17 @@ func getCredentialsFromRestoreRequest(req *pb.RestoreRequest) *x.MinioCredential
 }
 
 func writeBackup(ctx context.Context, req *pb.RestoreRequest) error {
+	// The backup was verified before the restore was proposed, but it's checked again while it's
+	// read, in case it changed in between.
+	var pub ed25519.PublicKey
+	if req.VerifyKeyFile != "" {
+		var err error
+		if pub, err = enc.ReadVerifyKey(req.VerifyKeyFile); err != nil {
+			return errors.Wrapf(err, "cannot read verify key")
+		}
+	}
 	res := LoadBackup(req.Location, req.BackupId, req.BackupNum,
-		getCredentialsFromRestoreRequest(req),
+		getCredentialsFromRestoreRequest(req), pub,
 		func(groupId uint32, in *loadBackupInput) (uint64, uint64, error) {
 			if groupId != req.GroupId {
 				// LoadBackup will try to call the backup function for every group.
@@ -338,7 +387,11 @@ func writeBackup(ctx context.Context, req *pb.RestoreRequest) error {
 			if err != nil {
 				return 0, 0, errors.Wrapf(err, "unable to read key")
 			}
-			in.r, err = enc.GetReader(key, in.r)
+			priv, err := enc.ReadPrivateKey(cfg)
+			if err != nil {
+				return 0, 0, errors.Wrapf(err, "unable to read private key")
+			}
+			in.r, err = getBackupReader(in.r, in.envelope, key, priv)
 			if err != nil {
 				return 0, 0, errors.Wrapf(err, "cannot get encrypted reader")
 			}
@@ -353,6 +406,9 @@ func writeBackup(ctx context.Context, req *pb.RestoreRequest) error {
 			if err != nil {
 				return 0, 0, errors.Wrapf(err, "cannot write backup")
 			}
+			// Report the restored ids to Zero along with the ones used by mutations.
+			storeMax(&groups().Node.maxUid, maxUid)
+			storeMax(&groups().Node.maxNsId, maxNsId)
 
 			if maxUid == 0 {
 				// No need to update the lease, return here.
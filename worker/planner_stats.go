@@ -0,0 +1,211 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/codec"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const (
+	// plannerStatsSample is the maximum number of keys of each kind scanned to compute the
+	// planner statistics of a predicate. The totals are extrapolated from the sample.
+	plannerStatsSample = 10000
+	// plannerStatsTTL is how long the planner statistics of a predicate are cached.
+	plannerStatsTTL = time.Minute
+)
+
+type cachedPlannerStats struct {
+	stats      *pb.PlannerStats
+	computedAt time.Time
+}
+
+var plannerStatsCache = struct {
+	sync.Mutex
+	byAttr map[string]cachedPlannerStats
+}{byAttr: make(map[string]cachedPlannerStats)}
+
+// getPlannerStats returns the planner statistics of the predicate, computing them if they aren't
+// cached or are too old.
+func getPlannerStats(ctx context.Context, attr string) (*pb.PlannerStats, error) {
+	plannerStatsCache.Lock()
+	cached, ok := plannerStatsCache.byAttr[attr]
+	plannerStatsCache.Unlock()
+	if ok && time.Since(cached.computedAt) < plannerStatsTTL {
+		return cached.stats, nil
+	}
+
+	stats, err := computePlannerStats(ctx, attr)
+	if err != nil {
+		return nil, err
+	}
+	plannerStatsCache.Lock()
+	plannerStatsCache.byAttr[attr] = cachedPlannerStats{stats: stats, computedAt: time.Now()}
+	plannerStatsCache.Unlock()
+	return stats, nil
+}
+
+// keySample describes the keys scanned under a prefix.
+type keySample struct {
+	keys     uint64
+	postings uint64
+	bytes    uint64
+	// complete is set if all the keys under the prefix were scanned.
+	complete bool
+}
+
+// estimateKeys extrapolates the number of keys under the prefix from the sample, using the size
+// of the prefix in the SSTables.
+func (s *keySample) estimateKeys(db *badger.DB, prefix []byte) uint64 {
+	if s.complete || s.bytes == 0 {
+		return s.keys
+	}
+	// The memtables aren't accounted for, so the estimate can't be less than the sample.
+	_, size := db.EstimateSize(prefix)
+	if size <= s.bytes {
+		return s.keys
+	}
+	return uint64(float64(s.keys) * float64(size) / float64(s.bytes))
+}
+
+// sampleKeys scans up to plannerStatsSample posting lists under the prefix, counting their
+// postings. The deltas which weren't rolled up yet are applied to the count of their list.
+func sampleKeys(txn *badger.Txn, prefix []byte) (*keySample, error) {
+	iopt := badger.DefaultIteratorOptions
+	iopt.AllVersions = true
+	iopt.PrefetchValues = false
+	iopt.Prefix = prefix
+	it := txn.NewIterator(iopt)
+	defer it.Close()
+
+	var sample keySample
+	var lastKey []byte
+	// done is set once the versions of the current key don't need to be read anymore.
+	var done bool
+	var postings int64
+	flush := func() {
+		if postings > 0 {
+			sample.keys++
+			sample.postings += uint64(postings)
+		}
+		postings = 0
+	}
+	for it.Rewind(); it.Valid(); it.Next() {
+		item := it.Item()
+		if !bytes.Equal(item.Key(), lastKey) {
+			flush()
+			if sample.keys >= plannerStatsSample {
+				return &sample, nil
+			}
+			lastKey = item.KeyCopy(lastKey)
+			done = false
+		}
+		if done {
+			continue
+		}
+		sample.bytes += uint64(item.EstimatedSize())
+
+		switch meta := item.UserMeta(); {
+		case item.IsDeletedOrExpired() || meta&posting.BitEmptyPosting > 0:
+			done = true
+			continue
+		case meta&posting.BitCompletePosting > 0:
+			done = true
+		}
+		err := item.Value(func(val []byte) error {
+			var pl pb.PostingList
+			if err := pl.Unmarshal(val); err != nil {
+				return err
+			}
+			if pl.Pack != nil {
+				// The UIDs of a complete list are all in its pack, including the UIDs of the
+				// postings which carry values or facets.
+				postings += int64(codec.ExactLen(pl.Pack))
+				return nil
+			}
+			for _, p := range pl.Postings {
+				if p.Op == posting.Del {
+					postings--
+				} else {
+					postings++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	flush()
+	sample.complete = true
+	return &sample, nil
+}
+
+func computePlannerStats(ctx context.Context, attr string) (*pb.PlannerStats, error) {
+	db, err := posting.ReadStore(attr)
+	switch {
+	case x.IsArchivedPredicate(err):
+		// The data of the archived predicates isn't kept on this node.
+		return &pb.PlannerStats{}, nil
+	case err != nil:
+		return nil, err
+	}
+	txn := db.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+
+	pk := x.ParsedKey{Attr: attr}
+	dataPrefix, indexPrefix := pk.DataPrefix(), pk.IndexPrefix()
+	data, err := sampleKeys(txn, dataPrefix)
+	if err != nil {
+		return nil, err
+	}
+	index, err := sampleKeys(txn, indexPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &pb.PlannerStats{
+		Keys:      data.estimateKeys(db, dataPrefix),
+		IndexKeys: index.estimateKeys(db, indexPrefix),
+		Indexes:   uint32(len(schema.State().Tokenizer(ctx, attr))),
+		Exact:     data.complete && index.complete,
+	}
+	if data.keys > 0 {
+		stats.PostingsPerKey = float64(data.postings) / float64(data.keys)
+		stats.BytesPerKey = float64(data.bytes) / float64(data.keys)
+	}
+	if index.keys > 0 {
+		stats.UidsPerIndexKey = float64(index.postings) / float64(index.keys)
+	}
+	if schema.State().IsReversed(ctx, attr) {
+		stats.Indexes++
+	}
+	if schema.State().HasCount(ctx, attr) {
+		stats.Indexes++
+	}
+	return stats, nil
+}
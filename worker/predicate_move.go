@@ -60,7 +60,26 @@ func populateKeyValues(ctx context.Context, kvs []*bpb.KV) error {
 	if err != nil {
 		return errors.Errorf("while parsing KV: %+v, got error: %v", kvs[0], err)
 	}
-	return schema.Load(pk.Attr)
+	if err := schema.Load(pk.Attr); err != nil {
+		return err
+	}
+	// The keys of a predicate move are all of the same predicate, but others, like those of a
+	// cloned namespace, can carry the schema and types of many predicates.
+	for _, kv := range kvs {
+		pk, err := x.Parse(kv.Key)
+		switch {
+		case err != nil:
+			return errors.Errorf("while parsing KV: %+v, got error: %v", kv, err)
+		case pk.IsSchema():
+			err = schema.Load(pk.Attr)
+		case pk.IsType():
+			err = schema.LoadType(pk.Attr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func batchAndProposeKeyValues(ctx context.Context, kvs chan *pb.KVS) error {
@@ -229,6 +248,11 @@ func (w *grpcWorker) MovePredicate(ctx context.Context,
 	case gid != groups().groupId():
 		return &emptyPayload, errUnservedTablet
 	}
+	// The data of a cold predicate isn't local, and the destination group couldn't serve it.
+	if schema.State().ColdTablet(in.Predicate) != nil {
+		return &emptyPayload, errors.Errorf("Predicate %s is in object storage and can't be "+
+			"moved. Promote it before moving it.", x.ParseAttr(in.Predicate))
+	}
 
 	msg := fmt.Sprintf("Move predicate request: %+v", in)
 	glog.Info(msg)
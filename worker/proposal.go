@@ -96,6 +96,35 @@ func (rl *rateLimiter) incr(ctx context.Context, retry int) error {
 	}
 }
 
+// GetBackpressure returns how loaded this Alpha is with proposals. The pressure is the larger of
+// the fraction of the pending proposals allowed, and the fraction of the committed entries that
+// can be queued for applying before Raft blocks.
+func GetBackpressure() *pb.BackpressureState {
+	limiter.c.L.Lock()
+	iou, max := limiter.iou, limiter.max
+	limiter.c.L.Unlock()
+
+	state := &pb.BackpressureState{
+		PendingProposals:    uint64(iou),
+		MaxPendingProposals: uint64(max),
+	}
+	if max > 0 {
+		state.Pressure = float64(iou) / float64(max)
+	}
+	n := groups().Node
+	if n == nil || n.Raft() == nil {
+		return state
+	}
+	state.PendingApplyBytes = atomic.LoadInt64(&n.pendingSize)
+	if p := float64(state.PendingApplyBytes) / float64(maxPendingSize); p > state.Pressure {
+		state.Pressure = p
+	}
+	if commit, applied := n.Raft().Status().Commit, n.Applied.DoneUntil(); commit > applied {
+		state.AppliedLag = commit - applied
+	}
+	return state
+}
+
 // Done would slowly bleed the retries out.
 func (rl *rateLimiter) decr(retry int) {
 	weight := 1 << uint(retry) // Ensure that the weight calculation is a copy of incr.
@@ -160,6 +189,12 @@ func (n *node) proposeAndWait(ctx context.Context, proposal *pb.Proposal) (perr
 	// be persisted, we do best effort schema check while writing
 	ctx = schema.GetWriteContext(ctx)
 	if proposal.Mutations != nil {
+		// Drop operations are still allowed, because they free up space.
+		if len(proposal.Mutations.Edges) > 0 {
+			if err := checkDiskLimits(); err != nil {
+				return err
+			}
+		}
 		for _, edge := range proposal.Mutations.Edges {
 			if err := checkTablet(edge.Attr); err != nil {
 				return err
@@ -180,6 +215,16 @@ func (n *node) proposeAndWait(ctx context.Context, proposal *pb.Proposal) (perr
 			}
 		}
 
+		for _, r := range proposal.Mutations.IndexRepairs {
+			pk, err := x.Parse(r.Key)
+			if err != nil {
+				return err
+			}
+			if err := checkTablet(pk.Attr); err != nil {
+				return err
+			}
+		}
+
 		for _, schema := range proposal.Mutations.Schema {
 			if err := checkTablet(schema.Predicate); err != nil {
 				return err
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import "time"
+
+const (
+	// ReEncryptRunning is the state of a re-encryption job doing its initial copy.
+	ReEncryptRunning = "RUNNING"
+	// ReEncryptReady is the state of a re-encryption job whose staged copy is catching up. The
+	// copy is swapped in once it's caught up.
+	ReEncryptReady = "READY"
+	// ReEncryptDone is the state of a re-encryption job whose staged copy was swapped in. The
+	// node must be started with the new key from then on.
+	ReEncryptDone = "DONE"
+	// ReEncryptFailed is the state of a re-encryption job that ran into an error.
+	ReEncryptFailed = "FAILED"
+	// ReEncryptCancelled is the state of a re-encryption job that was cancelled.
+	ReEncryptCancelled = "CANCELLED"
+)
+
+// ReEncryptRequest describes the key that the postings and WAL directories should be encrypted
+// with. Leaving both the key file and the Vault options empty decrypts the directories.
+type ReEncryptRequest struct {
+	EncryptionKeyFile string
+	VaultAddr         string
+	VaultRoleIDFile   string
+	VaultSecretIDFile string
+	VaultPath         string
+	VaultField        string
+	VaultFormat       string
+}
+
+// ReEncryptStatus reports the progress of a re-encryption job.
+type ReEncryptStatus struct {
+	State   string
+	Message string
+	// Encrypted is true if the staged copy is encrypted.
+	Encrypted bool
+	StartedAt time.Time
+	// ReadTs is the timestamp up to which the staged copy is in sync.
+	ReadTs      uint64
+	Passes      int
+	KeysCopied  uint64
+	BytesCopied uint64
+}
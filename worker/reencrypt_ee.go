@@ -0,0 +1,555 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package worker
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	bpb "github.com/dgraph-io/badger/v3/pb"
+	"github.com/dgraph-io/dgraph/ee/enc"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	// reEncryptSuffix is appended to the postings and WAL directories to get the directories
+	// in which the re-encrypted copies are staged.
+	reEncryptSuffix = ".reencrypt"
+	// preReEncryptSuffix is appended to the postings and WAL directories when they are moved
+	// aside after the cutover.
+	preReEncryptSuffix = ".pre-reencrypt"
+	// reEncryptDoneSuffix is appended to the postings directory to get the file marking that the
+	// staged directories are in use, and must be moved in place of the current ones.
+	reEncryptDoneSuffix = ".reencrypt-done"
+	// reEncryptCaughtUp is how long a catch-up pass may take for the staged copy to be deemed
+	// caught up. The last pass, during which writes are blocked, should take about as long.
+	reEncryptCaughtUp = time.Second
+	// reEncryptRetryInterval is how long to wait before proposing the cutover again.
+	reEncryptRetryInterval = 10 * time.Second
+	// reEncryptDeleted marks the KVs that pass a deletion on to the staged copy.
+	reEncryptDeleted byte = 1
+)
+
+type reEncryptJob struct {
+	newKey x.SensitiveByteSlice
+	db     *badger.DB
+	closer *z.Closer
+	// old is the postings store in use before the cutover. It's closed on shutdown.
+	old *badger.DB
+	// passLock serializes the passes and the cutover.
+	passLock sync.Mutex
+
+	// sinceTs is the timestamp up to which the staged copy is known to be in sync with pstore.
+	sinceTs uint64
+	// keys and bytes count what has been copied so far. They are accessed atomically.
+	keys  uint64
+	bytes uint64
+	// preds and types are the predicates and types present at the end of the last pass. They
+	// are used to detect drops, which the incremental passes can not see.
+	preds []string
+	types []string
+
+	sync.Mutex
+	status ReEncryptStatus
+}
+
+var reEncrypt struct {
+	sync.Mutex
+	job *reEncryptJob
+}
+
+func getReEncryptConfig(req *ReEncryptRequest) (*viper.Viper, error) {
+	config := viper.New()
+	flags := &pflag.FlagSet{}
+	enc.RegisterFlags(flags)
+	if err := config.BindPFlags(flags); err != nil {
+		return nil, errors.Wrapf(err, "bad config bind")
+	}
+
+	config.Set("encryption_key_file", req.EncryptionKeyFile)
+	config.Set("vault_roleid_file", req.VaultRoleIDFile)
+	config.Set("vault_secretid_file", req.VaultSecretIDFile)
+
+	// Override only if non-nil
+	if req.VaultAddr != "" {
+		config.Set("vault_addr", req.VaultAddr)
+	}
+	if req.VaultPath != "" {
+		config.Set("vault_path", req.VaultPath)
+	}
+	if req.VaultField != "" {
+		config.Set("vault_field", req.VaultField)
+	}
+	if req.VaultFormat != "" {
+		config.Set("vault_format", req.VaultFormat)
+	}
+	return config, nil
+}
+
+// StartReEncryption starts a background job that copies the postings directory into a staged
+// directory encrypted with the key described by the request. An empty key results in an
+// unencrypted copy. The node keeps serving while the copy is made and brought up to date. Once
+// it's caught up, the node switches over to it and to a copy of the WAL under the new key.
+func StartReEncryption(req *ReEncryptRequest) error {
+	if !EnterpriseEnabled() {
+		return errors.New("you must enable enterprise features first. " +
+			"Supply the appropriate license file to Dgraph Zero using the HTTP endpoint.")
+	}
+	if Config.InMemory {
+		return errors.New("there is nothing on disk to re-encrypt, as the data is kept in memory")
+	}
+
+	cfg, err := getReEncryptConfig(req)
+	if err != nil {
+		return err
+	}
+	newKey, err := enc.ReadKey(cfg)
+	if err != nil {
+		return errors.Wrapf(err, "while reading the new encryption key")
+	}
+	if string(newKey) == string(x.WorkerConfig.EncryptionKey) {
+		return errors.New("the new encryption key is the same as the current one")
+	}
+
+	reEncrypt.Lock()
+	defer reEncrypt.Unlock()
+	if j := reEncrypt.job; j != nil {
+		switch s := j.getStatus(); s.State {
+		case ReEncryptRunning, ReEncryptReady:
+			return errors.Errorf("a re-encryption job is already %s", s.State)
+		case ReEncryptDone:
+			return errors.New("a re-encryption job is already DONE. Restart the node with the " +
+				"new key first")
+		}
+		// The previous job has failed. Release its staged directory before starting over.
+		if err := j.db.Close(); err != nil {
+			glog.Warningf("Re-encryption: error while closing staged directory: %v", err)
+		}
+		reEncrypt.job = nil
+	}
+
+	dir := Config.PostingDir + reEncryptSuffix
+	if err := os.RemoveAll(dir); err != nil {
+		return errors.Wrapf(err, "while cleaning up staged directory %s", dir)
+	}
+	x.Check(os.MkdirAll(dir, 0700))
+
+	// The staged copy becomes the postings store on cutover.
+	opt := postingsOptions(badger.DefaultOptions(dir)).WithEncryptionKey(newKey)
+	db, err := badger.OpenManaged(opt)
+	if err != nil {
+		return errors.Wrapf(err, "while opening staged directory %s", dir)
+	}
+
+	j := &reEncryptJob{
+		newKey: newKey,
+		db:     db,
+		closer: z.NewCloser(1),
+	}
+	j.status = ReEncryptStatus{
+		State:     ReEncryptRunning,
+		Encrypted: newKey != nil,
+		StartedAt: time.Now(),
+	}
+	reEncrypt.job = j
+
+	glog.Infof("Re-encryption: staging postings directory at %s. Encrypted: %v",
+		dir, newKey != nil)
+	go j.run()
+	return nil
+}
+
+// CancelReEncryption stops the running re-encryption job, if any, and removes the staged copy.
+func CancelReEncryption() error {
+	reEncrypt.Lock()
+	defer reEncrypt.Unlock()
+
+	j := reEncrypt.job
+	if j == nil {
+		return errors.New("no re-encryption job is running")
+	}
+	if j.getStatus().State == ReEncryptDone {
+		return errors.New("the re-encryption job is DONE already")
+	}
+	j.closer.SignalAndWait()
+	j.setState(ReEncryptCancelled, "")
+	if err := j.db.Close(); err != nil {
+		glog.Warningf("Re-encryption: error while closing staged directory: %v", err)
+	}
+	reEncrypt.job = nil
+	return os.RemoveAll(Config.PostingDir + reEncryptSuffix)
+}
+
+// GetReEncryptionStatus returns the status of the current re-encryption job, or nil if no job
+// has been started.
+func GetReEncryptionStatus() *ReEncryptStatus {
+	reEncrypt.Lock()
+	defer reEncrypt.Unlock()
+
+	if reEncrypt.job == nil {
+		return nil
+	}
+	s := reEncrypt.job.getStatus()
+	return &s
+}
+
+func (j *reEncryptJob) getStatus() ReEncryptStatus {
+	j.Lock()
+	defer j.Unlock()
+	s := j.status
+	s.KeysCopied = atomic.LoadUint64(&j.keys)
+	s.BytesCopied = atomic.LoadUint64(&j.bytes)
+	return s
+}
+
+func (j *reEncryptJob) setState(state, msg string) {
+	j.Lock()
+	defer j.Unlock()
+	j.status.State = state
+	j.status.Message = msg
+}
+
+func (j *reEncryptJob) run() {
+	defer j.closer.Done()
+
+	ctx := j.closer.Ctx()
+	if err := j.pass(ctx, posting.Oracle().MaxAssigned()); err != nil {
+		if ctx.Err() == nil {
+			glog.Errorf("Re-encryption: initial pass failed: %v", err)
+			j.setState(ReEncryptFailed, err.Error())
+		}
+		return
+	}
+	j.setState(ReEncryptReady, "Staged copy is catching up. It's swapped in once caught up.")
+	glog.Infof("Re-encryption: initial pass done. Catching up.")
+
+	for {
+		start := time.Now()
+		if err := j.pass(ctx, posting.Oracle().MaxAssigned()); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			glog.Errorf("Re-encryption: catch-up pass failed: %v", err)
+			j.setState(ReEncryptFailed, err.Error())
+			return
+		}
+		if time.Since(start) > reEncryptCaughtUp {
+			continue
+		}
+
+		err := groups().Node.proposeAndWait(ctx, &pb.Proposal{
+			ReencryptCutover: &pb.ReEncryptCutover{NodeId: groups().Node.Id}})
+		switch {
+		case j.getStatus().State != ReEncryptReady:
+			// The cutover is done, or has failed.
+			return
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			glog.Warningf("Re-encryption: while proposing the cutover: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reEncryptRetryInterval):
+		}
+	}
+}
+
+// pass copies over all the versions in pstore in the range [j.sinceTs, readTs]. The first pass
+// uses a StreamWriter on the empty staged directory. The later ones use a write batch, and pass
+// the deletions on. Nothing is copied once the staged copy is in use.
+func (j *reEncryptJob) pass(ctx context.Context, readTs uint64) error {
+	j.passLock.Lock()
+	defer j.passLock.Unlock()
+
+	if j.getStatus().State == ReEncryptDone {
+		return nil
+	}
+	return j.doPass(ctx, readTs)
+}
+
+func (j *reEncryptJob) doPass(ctx context.Context, readTs uint64) error {
+	if readTs < j.sinceTs {
+		return nil
+	}
+	predicates := schema.State().Predicates()
+	types := schema.State().Types()
+
+	var writer badgerWriter
+	if j.sinceTs == 0 {
+		sw := j.db.NewStreamWriter()
+		defer sw.Cancel()
+		if err := sw.Prepare(); err != nil {
+			return err
+		}
+		writer = sw
+	} else {
+		wb := j.db.NewManagedWriteBatch()
+		defer wb.Cancel()
+		writer = reEncryptBatch{wb}
+	}
+
+	sinceTs := j.sinceTs
+	stream := pstore.NewStreamAt(readTs)
+	stream.LogPrefix = "Re-encryption"
+	stream.KeyToList = func(key []byte, itr *badger.Iterator) (*bpb.KVList, error) {
+		item := itr.Item()
+		if sinceTs == 0 || !item.IsDeletedOrExpired() {
+			return stream.ToList(key, itr)
+		}
+		// The key was deleted since the last pass.
+		kv := &bpb.KV{
+			Key:     item.KeyCopy(nil),
+			Version: item.Version(),
+			Meta:    []byte{reEncryptDeleted},
+		}
+		return &bpb.KVList{Kv: []*bpb.KV{kv}}, nil
+	}
+	stream.ChooseKey = func(item *badger.Item) bool {
+		if item.Version() >= sinceTs {
+			return true
+		}
+		if item.Version() != 1 {
+			return false
+		}
+		// Type and Schema keys always have a timestamp of 1, so they are always copied.
+		pk, err := x.Parse(item.Key())
+		if err != nil {
+			return false
+		}
+		return pk.IsSchema() || pk.IsType()
+	}
+	stream.Send = func(buf *z.Buffer) error {
+		atomic.AddUint64(&j.bytes, uint64(buf.LenNoPadding()))
+		err := buf.SliceIterate(func(_ []byte) error {
+			atomic.AddUint64(&j.keys, 1)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return writer.Write(buf)
+	}
+	if err := stream.Orchestrate(ctx); err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := j.dropStale(predicates, types); err != nil {
+		return err
+	}
+
+	j.Lock()
+	j.sinceTs = readTs + 1
+	j.preds, j.types = predicates, types
+	j.status.ReadTs = readTs
+	j.status.Passes++
+	j.Unlock()
+	return nil
+}
+
+// dropStale removes the predicates and types which were dropped from pstore since the last pass.
+func (j *reEncryptJob) dropStale(predicates, types []string) error {
+	current := make(map[string]struct{})
+	for _, pred := range predicates {
+		current[pred] = struct{}{}
+	}
+	for _, pred := range j.preds {
+		if _, ok := current[pred]; ok {
+			continue
+		}
+		if err := j.db.DropPrefix(x.PredicatePrefix(pred), x.SchemaKey(pred)); err != nil {
+			return errors.Wrapf(err, "while dropping predicate %s from staged copy", pred)
+		}
+	}
+
+	current = make(map[string]struct{})
+	for _, typ := range types {
+		current[typ] = struct{}{}
+	}
+	for _, typ := range j.types {
+		if _, ok := current[typ]; ok {
+			continue
+		}
+		if err := j.db.DropPrefix(x.TypeKey(typ)); err != nil {
+			return errors.Wrapf(err, "while dropping type %s from staged copy", typ)
+		}
+	}
+	return nil
+}
+
+// reEncryptBatch writes the KVs of the catch-up passes to the staged copy, including the
+// deletions.
+type reEncryptBatch struct {
+	*badger.WriteBatch
+}
+
+func (wb reEncryptBatch) Write(buf *z.Buffer) error {
+	return buf.SliceIterate(func(s []byte) error {
+		kv := &bpb.KV{}
+		if err := kv.Unmarshal(s); err != nil {
+			return err
+		}
+		if len(kv.Meta) > 0 && kv.Meta[0] == reEncryptDeleted {
+			return wb.DeleteAt(kv.Key, kv.Version)
+		}
+		return wb.WriteList(&bpb.KVList{Kv: []*bpb.KV{kv}})
+	})
+}
+
+// applyReEncryptCutover swaps in the staged copy of this node. It runs in the apply loop, so that
+// no writes happen meanwhile.
+func (n *node) applyReEncryptCutover() error {
+	reEncrypt.Lock()
+	defer reEncrypt.Unlock()
+
+	j := reEncrypt.job
+	if j == nil || j.getStatus().State != ReEncryptReady {
+		// The job was cancelled meanwhile, or the cutover was proposed again.
+		return nil
+	}
+
+	// Enable draining mode for the duration of the cutover.
+	x.UpdateDrainingMode(true)
+	defer x.UpdateDrainingMode(false)
+
+	closer, err := n.startTask(opReEncrypt)
+	if err != nil {
+		return errors.Wrapf(err, "cannot start re-encryption cutover")
+	}
+	defer closer.Done()
+
+	start := time.Now()
+	if err := j.cutover(&State); err != nil {
+		glog.Errorf("Re-encryption: cutover failed: %v", err)
+		j.setState(ReEncryptFailed, err.Error())
+		return err
+	}
+	glog.Infof("Re-encryption: cutover done in %s. Start the node with the new key from now on.",
+		time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// cutover brings the staged copy up to date, and switches the node over to it and to a copy of
+// the WAL under the new key. No writes must happen meanwhile. The old postings store is kept open
+// for the reads in flight. The directories are swapped on shutdown, or on the next start if the
+// node goes down before.
+func (j *reEncryptJob) cutover(s *ServerState) error {
+	j.passLock.Lock()
+	defer j.passLock.Unlock()
+
+	for _, dir := range []string{Config.PostingDir, Config.WALDir} {
+		if _, err := os.Stat(dir + preReEncryptSuffix); err == nil {
+			return errors.Errorf("%s already exists. Remove it to complete the cutover",
+				dir+preReEncryptSuffix)
+		}
+	}
+	if err := j.doPass(context.Background(), math.MaxUint64); err != nil {
+		return errors.Wrapf(err, "during final catch-up pass")
+	}
+	if err := j.db.Sync(); err != nil {
+		return errors.Wrapf(err, "while syncing staged copy")
+	}
+	walDir := Config.WALDir + reEncryptSuffix
+	if err := os.RemoveAll(walDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(walDir, 0700); err != nil {
+		return err
+	}
+	if err := s.WALstore.Rekey(walDir, j.newKey); err != nil {
+		return errors.Wrapf(err, "while writing raft log to %s", walDir)
+	}
+
+	// The WAL is now written under the new key, so there is no going back.
+	x.Check(ioutil.WriteFile(Config.PostingDir+reEncryptDoneSuffix, nil, 0600))
+	x.WorkerConfig.EncryptionKey = j.newKey
+	j.old = s.Pstore
+	s.Pstore, pstore = j.db, j.db
+	posting.SetPstore(j.db)
+	if dm != nil {
+		dm.db = j.db
+	}
+	s.gcCloser.AddRunning(2)
+	go x.RunVlogGC(j.db, s.gcCloser)
+	go x.MonitorCacheHealth(j.db, s.gcCloser)
+
+	j.setState(ReEncryptDone, "The node uses the new key. Start it with the new key from now on.")
+	return nil
+}
+
+// closeReEncryption stops the re-encryption job, if any, and closes the postings store it holds
+// which isn't in use.
+func closeReEncryption() {
+	reEncrypt.Lock()
+	j := reEncrypt.job
+	reEncrypt.job = nil
+	reEncrypt.Unlock()
+	if j == nil {
+		return
+	}
+
+	j.closer.SignalAndWait()
+	db := j.db
+	if j.getStatus().State == ReEncryptDone {
+		db = j.old
+	}
+	if err := db.Close(); err != nil {
+		glog.Warningf("Re-encryption: error while closing postings store: %v", err)
+	}
+}
+
+// completeReEncryption moves the staged directories in place of the current ones, if they were
+// swapped in. The current ones are moved aside, for the user to remove. It must run while neither
+// directory is open.
+func completeReEncryption() error {
+	done := Config.PostingDir + reEncryptDoneSuffix
+	if _, err := os.Stat(done); os.IsNotExist(err) {
+		return nil
+	}
+	for _, dir := range []string{Config.PostingDir, Config.WALDir} {
+		staged := dir + reEncryptSuffix
+		if _, err := os.Stat(staged); os.IsNotExist(err) {
+			// Moved in place already.
+			continue
+		}
+		if _, err := os.Stat(dir); err == nil {
+			if err := os.Rename(dir, dir+preReEncryptSuffix); err != nil {
+				return err
+			}
+		}
+		if err := os.Rename(staged, dir); err != nil {
+			return err
+		}
+	}
+	glog.Infof("Re-encryption: the old directories were moved to %s and %s. Remove them once "+
+		"the node is back up with the new key.",
+		Config.PostingDir+preReEncryptSuffix, Config.WALDir+preReEncryptSuffix)
+	return os.Remove(done)
+}
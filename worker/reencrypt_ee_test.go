@@ -0,0 +1,234 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package worker
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/raftwal"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/raft/raftpb"
+)
+
+var (
+	testOldKey = x.SensitiveByteSlice("0123456789abcdef")
+	testNewKey = x.SensitiveByteSlice("fedcba9876543210")
+)
+
+func openStagedCopy(t *testing.T, dir string, key x.SensitiveByteSlice) *badger.DB {
+	opt := badger.DefaultOptions(dir).
+		WithNumVersionsToKeep(math.MaxInt32).
+		WithIndexCacheSize(1 << 20).
+		WithEncryptionKey(key)
+	db, err := badger.OpenManaged(opt)
+	require.NoError(t, err)
+	return db
+}
+
+func countKeys(t *testing.T, db *badger.DB, prefix []byte) int {
+	txn := db.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+	itOpt := badger.DefaultIteratorOptions
+	itOpt.Prefix = prefix
+	itOpt.PrefetchValues = false
+	it := txn.NewIterator(itOpt)
+	defer it.Close()
+
+	var n int
+	for it.Rewind(); it.Valid(); it.Next() {
+		n++
+	}
+	return n
+}
+
+func TestReEncryptPasses(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte("reencrypt: [uid] ."), 1))
+	attr := x.GalaxyAttr("reencrypt")
+	for uid := uint64(1); uid <= 3; uid++ {
+		edge := &pb.DirectedEdge{ValueId: 100 + uid, Attr: attr, Entity: uid}
+		addEdge(t, edge, getOrCreate(x.DataKey(attr, uid)))
+	}
+
+	dir, err := ioutil.TempDir("", "reencrypt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	j := &reEncryptJob{newKey: testNewKey, db: openStagedCopy(t, dir, testNewKey)}
+
+	// The initial pass copies everything.
+	require.NoError(t, j.pass(context.Background(), timestamp()))
+	require.Equal(t, 3, countKeys(t, j.db, x.PredicatePrefix(attr)))
+	require.Equal(t, 1, j.getStatus().Passes)
+
+	// A catch-up pass copies what was written since.
+	addEdge(t, &pb.DirectedEdge{ValueId: 104, Attr: attr, Entity: 4},
+		getOrCreate(x.DataKey(attr, 4)))
+	require.NoError(t, j.pass(context.Background(), timestamp()))
+	require.Equal(t, 4, countKeys(t, j.db, x.PredicatePrefix(attr)))
+	require.Equal(t, 2, j.getStatus().Passes)
+
+	// A key deleted since the last pass is deleted from the staged copy.
+	wb := pstore.NewManagedWriteBatch()
+	require.NoError(t, wb.DeleteAt(x.DataKey(attr, 4), timestamp()))
+	require.NoError(t, wb.Flush())
+	require.NoError(t, j.pass(context.Background(), timestamp()))
+	require.Equal(t, 3, countKeys(t, j.db, x.PredicatePrefix(attr)))
+
+	// A predicate dropped since the last pass is removed from the staged copy.
+	require.NoError(t, schema.State().Delete(attr))
+	require.NoError(t, pstore.DropPrefix(x.PredicatePrefix(attr)))
+	require.NoError(t, j.pass(context.Background(), timestamp()))
+	require.Equal(t, 0, countKeys(t, j.db, x.PredicatePrefix(attr)))
+	require.NotContains(t, j.preds, attr)
+
+	// The staged copy can only be opened with the new key.
+	require.NoError(t, j.db.Close())
+	_, err = badger.OpenManaged(badger.DefaultOptions(dir).
+		WithIndexCacheSize(1 << 20).WithEncryptionKey(testOldKey))
+	require.Error(t, err)
+	db := openStagedCopy(t, dir, testNewKey)
+	require.NoError(t, db.Close())
+}
+
+func writeTestRaftLog(t *testing.T, dir string, key x.SensitiveByteSlice) []raftpb.Entry {
+	store, err := raftwal.InitEncrypted(dir, key)
+	require.NoError(t, err)
+	var entries []raftpb.Entry
+	for i := uint64(1); i <= 5; i++ {
+		entries = append(entries, raftpb.Entry{Index: i, Term: 1, Data: []byte("entry")})
+	}
+	store.SetUint(raftwal.RaftId, 2)
+	store.SetUint(raftwal.GroupId, 1)
+	require.NoError(t, store.Save(&raftpb.HardState{Term: 1, Commit: 5}, entries,
+		&raftpb.Snapshot{}))
+	require.NoError(t, store.Close())
+	return entries
+}
+
+// setReEncryptDirs points the postings and WAL directories to dir, and writes a raft log.
+func setReEncryptDirs(t *testing.T, dir string) func() {
+	postingDir, walDir := Config.PostingDir, Config.WALDir
+	Config.PostingDir, Config.WALDir = filepath.Join(dir, "p"), filepath.Join(dir, "w")
+	for _, d := range []string{Config.PostingDir, Config.WALDir} {
+		require.NoError(t, os.MkdirAll(d, 0700))
+	}
+	writeTestRaftLog(t, Config.WALDir, nil)
+	return func() {
+		Config.PostingDir, Config.WALDir = postingDir, walDir
+	}
+}
+
+func TestReEncryptCutover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reencrypt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer setReEncryptDirs(t, dir)()
+
+	store, err := raftwal.InitEncrypted(Config.WALDir, nil)
+	require.NoError(t, err)
+	j := &reEncryptJob{
+		newKey: testNewKey,
+		db:     openStagedCopy(t, Config.PostingDir+reEncryptSuffix, testNewKey),
+		closer: z.NewCloser(0),
+	}
+	j.status.State = ReEncryptReady
+	s := &ServerState{Pstore: pstore, WALstore: store, gcCloser: z.NewCloser(0)}
+
+	prevStore, prevKey := pstore, x.WorkerConfig.EncryptionKey
+	defer func() {
+		pstore, x.WorkerConfig.EncryptionKey = prevStore, prevKey
+		posting.SetPstore(prevStore)
+	}()
+	require.NoError(t, j.cutover(s))
+	s.gcCloser.SignalAndWait()
+	require.Equal(t, ReEncryptDone, j.getStatus().State)
+	require.Equal(t, j.db, s.Pstore)
+	require.Equal(t, j.db, pstore)
+	require.Equal(t, prevStore, j.old)
+	require.Equal(t, testNewKey, x.WorkerConfig.EncryptionKey)
+
+	// The passes stop once the staged copy is in use.
+	sinceTs := j.sinceTs
+	require.NoError(t, j.pass(context.Background(), math.MaxUint64))
+	require.Equal(t, sinceTs, j.sinceTs)
+
+	// The entries written after the cutover go to the new WAL.
+	require.NoError(t, store.Save(&raftpb.HardState{Term: 1, Commit: 6},
+		[]raftpb.Entry{{Index: 6, Term: 1}}, &raftpb.Snapshot{}))
+	require.NoError(t, store.Close())
+	require.NoError(t, j.db.Close())
+
+	// Once both are closed, the staged directories are moved in place.
+	require.NoError(t, completeReEncryption())
+	for _, d := range []string{Config.PostingDir, Config.WALDir} {
+		_, err := os.Stat(d + preReEncryptSuffix)
+		require.NoError(t, err)
+		_, err = os.Stat(d + reEncryptSuffix)
+		require.True(t, os.IsNotExist(err))
+	}
+	_, err = os.Stat(Config.PostingDir + reEncryptDoneSuffix)
+	require.True(t, os.IsNotExist(err))
+
+	store, err = raftwal.InitEncrypted(Config.WALDir, testNewKey)
+	require.NoError(t, err)
+	defer store.Close()
+	require.Equal(t, uint64(2), store.Uint(raftwal.RaftId))
+	last, err := store.LastIndex()
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), last)
+	db := openStagedCopy(t, Config.PostingDir, testNewKey)
+	require.NoError(t, db.Close())
+}
+
+func TestReEncryptCutoverRefusesLeftovers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reencrypt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer setReEncryptDirs(t, dir)()
+	require.NoError(t, os.MkdirAll(Config.PostingDir+preReEncryptSuffix, 0700))
+
+	store, err := raftwal.InitEncrypted(Config.WALDir, nil)
+	require.NoError(t, err)
+	defer store.Close()
+	j := &reEncryptJob{
+		newKey: testNewKey,
+		db:     openStagedCopy(t, Config.PostingDir+reEncryptSuffix, testNewKey),
+		closer: z.NewCloser(0),
+	}
+	defer j.db.Close()
+	j.status.State = ReEncryptReady
+	s := &ServerState{Pstore: pstore, WALstore: store}
+
+	err = j.cutover(s)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), Config.PostingDir+preReEncryptSuffix)
+
+	// Nothing was swapped, so the node keeps going with its current data.
+	require.Equal(t, pstore, s.Pstore)
+	require.Equal(t, ReEncryptReady, j.getStatus().State)
+	_, err = os.Stat(Config.WALDir + reEncryptSuffix)
+	require.True(t, os.IsNotExist(err))
+	require.NoError(t, completeReEncryption())
+	_, err = os.Stat(Config.PostingDir)
+	require.NoError(t, err)
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/tok"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+const (
+	// bm25K1 bounds how much the repetitions of a term raise the score, and bm25B how much the
+	// score of the long values is lowered.
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// scoreRelevance replaces the values in the value matrix of relevance(pred, text) by their BM25
+// score for the full-text terms of the text. The values of a uid are scored as one document.
+// The document frequencies and the average length are the ones of the scored documents, so that
+// the scores rank the results of a query, rather than the documents of the whole predicate.
+func scoreRelevance(ctx context.Context, args funcArgs) error {
+	q := args.q
+	lang := langForFunc(q.Langs)
+	if lang == "." {
+		lang = "en"
+	}
+	dict := schema.State().FulltextDictionary(ctx, q.Attr)
+	queryTerms := x.RemoveDuplicates(tok.GetFullTextTerms(q.SrcFunc.Args[0], lang, dict))
+
+	// The frequencies of the query terms in every document, the length of the documents, and
+	// the number of documents containing each query term.
+	freqs := make([]map[string]int, len(args.out.ValueMatrix))
+	lengths := make([]int, len(args.out.ValueMatrix))
+	docFreqs := make(map[string]int, len(queryTerms))
+	for _, term := range queryTerms {
+		docFreqs[term] = 0
+	}
+	var numDocs, totalLength int
+	for i, vl := range args.out.ValueMatrix {
+		if len(vl.Values) == 0 {
+			continue
+		}
+		freqs[i] = make(map[string]int)
+		for _, v := range vl.Values {
+			for _, term := range tok.GetFullTextTerms(string(v.Val), lang, dict) {
+				lengths[i]++
+				if _, ok := docFreqs[term]; ok {
+					freqs[i][term]++
+				}
+			}
+		}
+		for term := range freqs[i] {
+			docFreqs[term]++
+		}
+		numDocs++
+		totalLength += lengths[i]
+	}
+	if numDocs == 0 {
+		return nil
+	}
+	avgLength := math.Max(float64(totalLength)/float64(numDocs), 1)
+
+	for i, vl := range args.out.ValueMatrix {
+		if len(vl.Values) == 0 {
+			continue
+		}
+		var score float64
+		norm := bm25K1 * (1 - bm25B + bm25B*float64(lengths[i])/avgLength)
+		for term, freq := range freqs[i] {
+			n := float64(docFreqs[term])
+			idf := math.Log(1 + (float64(numDocs)-n+0.5)/(n+0.5))
+			score += idf * float64(freq) * (bm25K1 + 1) / (float64(freq) + norm)
+		}
+
+		data := types.ValueForType(types.BinaryID)
+		if err := types.Marshal(types.Val{Tid: types.FloatID, Value: score}, &data); err != nil {
+			return errors.Wrapf(err, "while encoding the relevance score")
+		}
+		vl.Values = []*pb.TaskValue{{Val: data.Value.([]byte), ValType: types.FloatID.Enum()}}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestScoreRelevance(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte("relevance_title: [string] ."), 1))
+	docs := [][]string{
+		{"Running shoes for road running"},
+		{"Leather shoes"},
+		{},
+		{"Trail running shoes with a grippy sole"},
+		{"Cheap", "running socks"},
+		{"Shoes"},
+	}
+	out := &pb.Result{}
+	for _, values := range docs {
+		vl := &pb.ValueList{}
+		for _, v := range values {
+			vl.Values = append(vl.Values, &pb.TaskValue{Val: []byte(v), ValType: types.StringID.Enum()})
+		}
+		out.ValueMatrix = append(out.ValueMatrix, vl)
+	}
+	q := &pb.Query{
+		Attr:    x.GalaxyAttr("relevance_title"),
+		SrcFunc: &pb.SrcFunction{Name: "relevance", Args: []string{"running shoes"}},
+	}
+	require.NoError(t, scoreRelevance(context.Background(), funcArgs{q: q, out: out}))
+
+	scores := make([]float64, len(docs))
+	for i, vl := range out.ValueMatrix {
+		if len(docs[i]) == 0 {
+			require.Empty(t, vl.Values)
+			continue
+		}
+		require.Len(t, vl.Values, 1)
+		v, err := types.Convert(types.Val{Tid: types.BinaryID, Value: vl.Values[0].Val},
+			types.FloatID)
+		require.NoError(t, err)
+		scores[i] = v.Value.(float64)
+	}
+	// Both terms, one of them twice, beat both terms in a longer value, which beat a single term.
+	require.Greater(t, scores[0], scores[3])
+	require.Greater(t, scores[3], scores[4])
+	// The values of a uid are scored together, and running is rarer than shoes.
+	require.Greater(t, scores[4], scores[1])
+}
@@ -15,6 +15,8 @@ package worker
 import (
 	"bufio"
 	"compress/gzip"
+	"crypto"
+	"crypto/ed25519"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
@@ -38,8 +40,11 @@ import (
 )
 
 // RunRestore calls badger.Load and tries to load data into a new DB.
-func RunRestore(pdir, location, backupId string, key x.SensitiveByteSlice,
-	ctype options.CompressionType, clevel int) LoadResult {
+// The backups encrypted with a public key are read with priv, the others with key. The restored
+// postings are encrypted with key. If pub isn't nil, the backups are checked against the
+// signatures of their manifests as they're read.
+func RunRestore(pdir, location, backupId string, key x.SensitiveByteSlice, priv crypto.PrivateKey,
+	pub ed25519.PublicKey, ctype options.CompressionType, clevel int) LoadResult {
 	// Create the pdir if it doesn't exist.
 	if err := os.MkdirAll(pdir, 0700); err != nil {
 		return LoadResult{Err: err}
@@ -47,11 +52,11 @@ func RunRestore(pdir, location, backupId string, key x.SensitiveByteSlice,
 
 	// Scan location for backup files and load them. Each file represents a node group,
 	// and we create a new p dir for each.
-	return LoadBackup(location, backupId, 0, nil,
+	return LoadBackup(location, backupId, 0, nil, pub,
 		func(groupId uint32, in *loadBackupInput) (uint64, uint64, error) {
 
 			dir := filepath.Join(pdir, fmt.Sprintf("p%d", groupId))
-			r, err := enc.GetReader(key, in.r)
+			r, err := getBackupReader(in.r, in.envelope, key, priv)
 			if err != nil {
 				return 0, 0, err
 			}
@@ -98,6 +103,24 @@ type loadBackupInput struct {
 	preds          predicateSet
 	dropOperations []*pb.DropOperation
 	isOld          bool
+	// envelope is set if the backup was encrypted with a public key.
+	envelope bool
+	// manifest is the manifest of the backup the file belongs to.
+	manifest *Manifest
+}
+
+// getBackupReader wraps the reader of a backup file to decrypt it. Backups encrypted with a
+// public key need the matching private key, the others the key they were taken with.
+func getBackupReader(r io.Reader, envelope bool, key x.SensitiveByteSlice,
+	priv crypto.PrivateKey) (io.Reader, error) {
+	if !envelope {
+		return enc.GetReader(key, r)
+	}
+	if priv == nil {
+		return nil, errors.Errorf("the backup was encrypted with a public key, " +
+			"the matching private key is needed to read it")
+	}
+	return enc.GetEnvelopeReader(priv, r)
 }
 
 // loadFromBackup reads the backup, converts the keys and values to the required format,
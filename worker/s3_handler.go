@@ -223,7 +223,7 @@ func (h *s3Handler) Load(uri *url.URL, backupId string, backupNum uint64, fn loa
 
 			groupMaxUid, groupMaxNsId, err := fn(gid,
 				&loadBackupInput{r: reader, preds: predSet, dropOperations: manifest.DropOperations,
-					isOld: manifest.Version == 0})
+					isOld: manifest.Version == 0, envelope: manifest.Envelope, manifest: manifest})
 			if err != nil {
 				return LoadResult{Err: err}
 			}
@@ -280,6 +280,10 @@ func (h *s3Handler) ReadManifest(path string, m *Manifest) error {
 	return json.NewDecoder(reader).Decode(m)
 }
 
+func (h *s3Handler) OpenFile(path string) (io.ReadCloser, error) {
+	return h.mc.GetObject(h.bucketName, path, minio.GetObjectOptions{})
+}
+
 // upload will block until it's done or an error occurs.
 func (h *s3Handler) upload(mc *x.MinioClient, object string) error {
 	start := time.Now()
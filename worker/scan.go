@@ -0,0 +1,231 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// defaultScanBatchSize is the number of edges sent per batch if the request doesn't set one.
+const defaultScanBatchSize = 1000
+
+// ScanPartitions splits the predicates of the namespace served by each group into perGroup UID
+// ranges. If preds is empty, all the predicates are used, except for the internal ones other than
+// dgraph.type. The last range of a group is unbounded, so that the partitions cover all the
+// subjects.
+func ScanPartitions(state *pb.MembershipState, namespace uint64, preds []string,
+	perGroup uint32) ([]*pb.ScanPartition, error) {
+	if perGroup == 0 {
+		perGroup = 1
+	}
+	wanted := make(map[string]bool)
+	for _, pred := range preds {
+		wanted[pred] = false
+	}
+
+	var gids []uint32
+	for gid := range state.GetGroups() {
+		gids = append(gids, gid)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+
+	// UIDs are leased from 1 up to MaxUID, so this range is split evenly.
+	step := state.GetMaxUID() / uint64(perGroup)
+	if step == 0 {
+		step = 1
+	}
+	var partitions []*pb.ScanPartition
+	for _, gid := range gids {
+		group := state.Groups[gid]
+		var served []string
+		for attr := range group.GetTablets() {
+			ns, pred := x.ParseNamespaceAttr(attr)
+			if ns != namespace {
+				continue
+			}
+			if len(preds) > 0 {
+				if _, ok := wanted[pred]; !ok {
+					continue
+				}
+				wanted[pred] = true
+			} else if x.IsReservedPredicate(attr) && pred != "dgraph.type" {
+				continue
+			}
+			served = append(served, pred)
+		}
+		if len(served) == 0 {
+			continue
+		}
+		sort.Strings(served)
+		var addrs []string
+		for _, m := range group.GetMembers() {
+			if !m.AmDead {
+				addrs = append(addrs, m.GrpcAddr)
+			}
+		}
+		sort.Strings(addrs)
+
+		start := uint64(1)
+		for i := uint32(0); i < perGroup; i++ {
+			p := &pb.ScanPartition{
+				GroupId:    gid,
+				StartUid:   start,
+				Predicates: served,
+				Addrs:      addrs,
+			}
+			if i < perGroup-1 {
+				p.EndUid = start + step
+				start = p.EndUid
+			}
+			partitions = append(partitions, p)
+		}
+	}
+	for pred, found := range wanted {
+		if !found {
+			return nil, errors.Errorf("Predicate %s is not served by any group", pred)
+		}
+	}
+	return partitions, nil
+}
+
+// Scan sends the edges of the partition in batches, as they were at the read timestamp of the
+// request. The partition must belong to the group of this Alpha. Passwords are never sent.
+func Scan(ctx context.Context, namespace uint64, req *pb.ScanRequest,
+	send func(*pb.ScanBatch) error) error {
+	p := req.GetPartition()
+	if p == nil {
+		return errors.Errorf("No partition to scan")
+	}
+	if req.ReadTs == 0 {
+		return errors.Errorf("The read timestamp of the partitions must be set")
+	}
+	if gid := groups().groupId(); p.GroupId != gid {
+		return errors.Errorf("Partition of group %d can't be scanned by an Alpha of group %d,"+
+			" use one of %v", p.GroupId, gid, p.Addrs)
+	}
+	if err := posting.Oracle().WaitForTs(ctx, req.ReadTs); err != nil {
+		return err
+	}
+	batchSize := int(req.BatchSize)
+	if batchSize == 0 {
+		batchSize = defaultScanBatchSize
+	}
+
+	batch := &pb.ScanBatch{}
+	flush := func() error {
+		if len(batch.Edges) == 0 {
+			return nil
+		}
+		if err := send(batch); err != nil {
+			return err
+		}
+		batch = &pb.ScanBatch{}
+		return nil
+	}
+	for _, pred := range p.Predicates {
+		attr := x.NamespaceAttr(namespace, pred)
+		// The predicate may have moved since the partitions were computed.
+		gid, err := groups().BelongsToReadOnly(attr, req.ReadTs)
+		if err != nil {
+			return err
+		}
+		if gid != p.GroupId {
+			return errors.Errorf("Predicate %s is not served by group %d anymore", pred,
+				p.GroupId)
+		}
+		err = scanPredicate(ctx, attr, pred, p.StartUid, p.EndUid, req.ReadTs,
+			func(e *pb.ScanEdge) error {
+				batch.Edges = append(batch.Edges, e)
+				if len(batch.Edges) < batchSize {
+					return nil
+				}
+				return flush()
+			})
+		if err != nil {
+			return errors.Wrapf(err, "while scanning %s", pred)
+		}
+	}
+	return flush()
+}
+
+// scanPredicate calls fn for every edge of the predicate whose subject is in [start, end). An end
+// of zero means there is no upper bound.
+func scanPredicate(ctx context.Context, attr, pred string, start, end, readTs uint64,
+	fn func(*pb.ScanEdge) error) error {
+	txn := pstore.NewTransactionAt(readTs, false)
+	defer txn.Discard()
+	prefix := x.ParsedKey{Attr: attr}.DataPrefix()
+	iopt := badger.DefaultIteratorOptions
+	iopt.PrefetchValues = false
+	iopt.Prefix = prefix
+	itr := txn.NewIterator(iopt)
+	defer itr.Close()
+
+	for itr.Seek(x.DataKey(attr, start)); itr.ValidForPrefix(prefix); itr.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		pk, err := x.Parse(itr.Item().Key())
+		if err != nil {
+			return err
+		}
+		if pk.HasStartUid {
+			continue
+		}
+		if end != 0 && pk.Uid >= end {
+			return nil
+		}
+		pl, err := posting.GetNoStore(itr.Item().KeyCopy(nil), readTs)
+		if err != nil {
+			return err
+		}
+		err = pl.Iterate(readTs, 0, func(p *pb.Posting) error {
+			e := &pb.ScanEdge{Subject: pk.Uid, Predicate: pred, Facets: p.Facets}
+			if p.PostingType == pb.Posting_REF {
+				e.ObjectId = p.Uid
+				return fn(e)
+			}
+			tid := types.TypeID(p.ValType)
+			if tid == types.PasswordID {
+				return nil
+			}
+			v, err := types.Convert(types.Val{Tid: tid, Value: p.Value}, tid)
+			if err == nil {
+				e.ObjectValue, err = types.ObjectValue(tid, v.Value)
+			}
+			if err != nil {
+				glog.Warningf("Scan: skipping a value of %s of %#x: %v", pred, pk.Uid, err)
+				return nil
+			}
+			e.Lang = string(p.LangTag)
+			return fn(e)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanPartitions(t *testing.T) {
+	state := &pb.MembershipState{
+		MaxUID: 1000,
+		Groups: map[uint32]*pb.Group{
+			1: {
+				Members: map[uint64]*pb.Member{
+					1: {Id: 1, GrpcAddr: "alpha1:9080"},
+					2: {Id: 2, GrpcAddr: "alpha2:9080", AmDead: true},
+				},
+				Tablets: map[string]*pb.Tablet{
+					x.GalaxyAttr("name"):              {},
+					x.GalaxyAttr("dgraph.type"):       {},
+					x.GalaxyAttr("dgraph.xid"):        {},
+					x.NamespaceAttr(1, "secret"):      {},
+					x.NamespaceAttr(1, "dgraph.type"): {},
+				},
+			},
+			2: {
+				Members: map[uint64]*pb.Member{3: {Id: 3, GrpcAddr: "alpha3:9080"}},
+				Tablets: map[string]*pb.Tablet{x.GalaxyAttr("friend"): {}},
+			},
+		},
+	}
+
+	partitions, err := ScanPartitions(state, x.GalaxyNamespace, nil, 4)
+	require.NoError(t, err)
+	require.Len(t, partitions, 8)
+	// Internal predicates other than dgraph.type and the predicates of other namespaces are left out.
+	require.Equal(t, []string{"dgraph.type", "name"}, partitions[0].Predicates)
+	require.Equal(t, []string{"alpha1:9080"}, partitions[0].Addrs)
+	var next uint64 = 1
+	for _, p := range partitions[:4] {
+		require.Equal(t, uint32(1), p.GroupId)
+		require.Equal(t, next, p.StartUid)
+		next = p.EndUid
+	}
+	require.Equal(t, uint64(751), partitions[3].StartUid)
+	require.Zero(t, partitions[3].EndUid)
+	require.Equal(t, uint32(2), partitions[4].GroupId)
+	require.Equal(t, []string{"friend"}, partitions[4].Predicates)
+
+	partitions, err = ScanPartitions(state, x.GalaxyNamespace, []string{"friend"}, 0)
+	require.NoError(t, err)
+	require.Len(t, partitions, 1)
+	require.Equal(t, uint32(2), partitions[0].GroupId)
+	require.Equal(t, uint64(1), partitions[0].StartUid)
+	require.Zero(t, partitions[0].EndUid)
+
+	_, err = ScanPartitions(state, x.GalaxyNamespace, []string{"friend", "age"}, 1)
+	require.EqualError(t, err, "Predicate age is not served by any group")
+}
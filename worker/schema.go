@@ -17,16 +17,21 @@
 package worker
 
 import (
+	"bytes"
 	"context"
+	"math"
 
+	"github.com/dgraph-io/badger/v3"
 	"github.com/golang/protobuf/proto"
 	"github.com/pkg/errors"
 	otrace "go.opencensus.io/trace"
 
 	"github.com/dgraph-io/dgraph/conn"
+	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/schema"
 	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
 )
 
 var (
@@ -57,7 +62,7 @@ func getSchema(ctx context.Context, s *pb.SchemaRequest) (*pb.SchemaResult, erro
 		fields = s.Fields
 	} else {
 		fields = []string{"type", "index", "tokenizer", "reverse", "count", "list", "upsert",
-			"lang", "noconflict"}
+			"lang", "noconflict", "presence", "collation"}
 	}
 
 	myGid := groups().groupId()
@@ -72,7 +77,11 @@ func getSchema(ctx context.Context, s *pb.SchemaRequest) (*pb.SchemaResult, erro
 			continue
 		}
 
-		if schemaNode := populateSchema(attr, fields); schemaNode != nil {
+		schemaNode, err := populateSchema(attr, fields)
+		if err != nil {
+			return nil, err
+		}
+		if schemaNode != nil {
 			result.Schema = append(result.Schema, schemaNode)
 		}
 	}
@@ -80,16 +89,17 @@ func getSchema(ctx context.Context, s *pb.SchemaRequest) (*pb.SchemaResult, erro
 }
 
 // populateSchema returns the information of asked fields for given attribute
-func populateSchema(attr string, fields []string) *pb.SchemaNode {
+func populateSchema(attr string, fields []string) (*pb.SchemaNode, error) {
 	var schemaNode pb.SchemaNode
 	var typ types.TypeID
 	var err error
 	if typ, err = schema.State().TypeOf(attr); err != nil {
 		// schema is not defined
-		return nil
+		return nil, nil
 	}
 	schemaNode.Predicate = attr
 	ctx := context.Background()
+	var stats *predicateStats
 	for _, field := range fields {
 		switch field {
 		case "type":
@@ -112,11 +122,147 @@ func populateSchema(attr string, fields []string) *pb.SchemaNode {
 			schemaNode.Lang = schema.State().HasLang(attr)
 		case "noconflict":
 			schemaNode.NoConflict = schema.State().HasNoConflict(attr)
+		case "presence":
+			schemaNode.Presence = schema.State().HasPresence(ctx, attr)
+		case "collation":
+			schemaNode.Collation = schema.State().Collation(ctx, attr)
+		case "fulltext_dictionary":
+			su, _ := schema.State().Get(ctx, attr)
+			schemaNode.FulltextSynonyms = su.FulltextSynonyms
+			schemaNode.FulltextProtected = su.FulltextProtected
+		case "size", "keys", "splits", "deleted", "tombstone_ratio":
+			if stats == nil {
+				if stats, err = getPredicateStats(attr); err != nil {
+					return nil, err
+				}
+			}
+			stats.populate(&schemaNode, field)
+		case "planner_stats":
+			if schemaNode.PlannerStats, err = getPlannerStats(ctx, attr); err != nil {
+				return nil, err
+			}
 		default:
 			//pass
 		}
 	}
-	return &schemaNode
+	return &schemaNode, nil
+}
+
+// predicateStats describes the data of a predicate on disk.
+type predicateStats struct {
+	// size is the estimated number of bytes taken by all the versions of the keys.
+	size int64
+	// keys is the number of posting lists which aren't deleted. The parts of the split lists
+	// aren't counted, they're counted in splits.
+	keys   uint64
+	splits uint64
+	// versions is the number of versions of the keys on disk, and stale is how many of them can
+	// no longer be read, i.e. the deleted versions and the versions replaced by a rollup. They
+	// take space until they're removed by a compaction.
+	versions uint64
+	stale    uint64
+	// deleted is the number of stale versions, plus the number of postings deleted by the deltas
+	// which weren't rolled up yet.
+	deleted uint64
+}
+
+func (s *predicateStats) populate(node *pb.SchemaNode, field string) {
+	switch field {
+	case "size":
+		node.Size_ = s.size
+	case "keys":
+		node.Keys = s.keys
+	case "splits":
+		node.Splits = s.splits
+	case "deleted":
+		node.Deleted = s.deleted
+	case "tombstone_ratio":
+		if s.versions > 0 {
+			node.TombstoneRatio = float64(s.stale) / float64(s.versions)
+		}
+	}
+}
+
+// getPredicateStats scans all the versions of the keys of the predicate to find how much space
+// the predicate takes on disk, and how much of it is held by data waiting to be compacted.
+func getPredicateStats(attr string) (*predicateStats, error) {
+	db, err := posting.ReadStore(attr)
+	switch {
+	case x.IsArchivedPredicate(err):
+		// The data of the archived predicates isn't kept on this node.
+		return &predicateStats{}, nil
+	case err != nil:
+		return nil, err
+	}
+	txn := db.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+
+	iopt := badger.DefaultIteratorOptions
+	iopt.AllVersions = true
+	iopt.PrefetchValues = false
+	iopt.Prefix = x.PredicatePrefix(attr)
+	it := txn.NewIterator(iopt)
+	defer it.Close()
+
+	var stats predicateStats
+	var lastKey []byte
+	// stale is set once a version hides all the older versions of the current key.
+	var stale bool
+	for it.Rewind(); it.Valid(); it.Next() {
+		item := it.Item()
+		stats.size += item.EstimatedSize()
+		stats.versions++
+
+		// The versions of a key are iterated from the newest to the oldest.
+		newest := !bytes.Equal(item.Key(), lastKey)
+		if newest {
+			lastKey = item.KeyCopy(lastKey)
+			stale = false
+		}
+		if stale {
+			stats.stale++
+			continue
+		}
+
+		switch meta := item.UserMeta(); {
+		case item.IsDeletedOrExpired() || meta&posting.BitEmptyPosting > 0:
+			stats.stale++
+			stale = true
+			continue
+		case meta&posting.BitCompletePosting > 0:
+			stale = true
+		case meta&posting.BitDeltaPosting > 0:
+			err := item.Value(func(val []byte) error {
+				var pl pb.PostingList
+				if err := pl.Unmarshal(val); err != nil {
+					return err
+				}
+				for _, p := range pl.Postings {
+					if p.Op == posting.Del {
+						stats.deleted++
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !newest {
+			continue
+		}
+		pk, err := x.Parse(item.Key())
+		if err != nil {
+			return nil, err
+		}
+		if pk.HasStartUid {
+			stats.splits++
+		} else {
+			stats.keys++
+		}
+	}
+	stats.deleted += stats.stale
+	return &stats, nil
 }
 
 // addToSchemaMap groups the predicates by group id, if list of predicates is
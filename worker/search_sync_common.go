@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/protobuf/proto"
+)
+
+// SearchSyncDefaults are the defaults of the --search_sync flag, holding the options used to
+// reach the Elasticsearch or OpenSearch clusters of the search connectors.
+const SearchSyncDefaults = "username=; password=; api-key=; ca-cert=; client-cert=; " +
+	"client-key=; batch-size=500; timeout=30s"
+
+// GetSearchConnectors returns the search connectors of the namespace in the context, sorted by
+// name.
+func GetSearchConnectors(ctx context.Context) ([]*pb.SearchConnector, error) {
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var conns []*pb.SearchConnector
+	for _, c := range searchConnectors() {
+		if c.Namespace == ns {
+			conns = append(conns, c)
+		}
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].Name < conns[j].Name })
+	return conns, nil
+}
+
+// SearchBackfillPending returns whether a group serving one of the predicates of the connector
+// hasn't completed its last requested backfill yet.
+func SearchBackfillPending(c *pb.SearchConnector) bool {
+	if c.BackfillId == 0 {
+		return false
+	}
+	state := GetMembershipState()
+	for _, pred := range c.Predicates {
+		attr := x.NamespaceAttr(c.Namespace, pred)
+		for gid, group := range state.GetGroups() {
+			if _, ok := group.GetTablets()[attr]; ok && c.Backfilled[gid] != c.BackfillId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// searchConnectors returns a copy of the search connectors of the membership state, keyed by
+// their name prefixed with their namespace.
+func searchConnectors() map[string]*pb.SearchConnector {
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	conns := make(map[string]*pb.SearchConnector, len(g.state.GetSearchConnectors()))
+	for key, c := range g.state.GetSearchConnectors() {
+		conns[key] = proto.Clone(c).(*pb.SearchConnector)
+	}
+	return conns
+}
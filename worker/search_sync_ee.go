@@ -0,0 +1,847 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/conn"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+const (
+	// searchBackfillRetry is how long a group leader waits before retrying a failed backfill.
+	searchBackfillRetry = time.Minute
+	// searchSendAttempts is how many times the changes of a transaction are sent to a connector
+	// before they're dropped, waiting searchSendBackoff longer after each failure.
+	searchSendAttempts = 3
+	searchSendBackoff  = time.Second
+)
+
+// searchSync mirrors the predicates of the search connectors into their Elasticsearch or
+// OpenSearch index. It's fed the CDC events of the group, but these only tell which nodes
+// changed: the values sent are read from the posting lists when they're sent. So, a node is
+// always sent with its latest values, and sending it again is harmless.
+type searchSync struct {
+	// Mutex is held while the values of nodes are read and sent, so that the values sent by a
+	// backfill can't overwrite newer ones sent for a CDC event, or the other way round.
+	sync.Mutex
+	client    *http.Client
+	username  string
+	password  string
+	apiKey    string
+	batchSize int
+
+	bfLock sync.Mutex
+	// backfills are the last backfills run by this node, keyed like the connectors in the
+	// membership state.
+	backfills map[string]*searchBackfill
+	// resyncs are the connectors which dropped the changes of a transaction, mapped to the
+	// number of transactions dropped so far. Their predicates are copied again by a backfill
+	// which isn't reported to Zero, and the connector leaves resyncs once a backfill started
+	// after the last drop completes.
+	resyncs map[string]uint64
+}
+
+type searchBackfill struct {
+	id uint64
+	// report is set for the backfills requested through Zero, whose completion is recorded
+	// there.
+	report bool
+	// drops is the value of resyncs for the connector when the backfill started.
+	drops   uint64
+	running bool
+	failed  time.Time
+}
+
+var (
+	searchSyncOnce sync.Once
+	searchSyncer   *searchSync
+	searchSyncErr  error
+)
+
+// getSearchSync returns the searchSync configured by the --search_sync flag.
+func getSearchSync() (*searchSync, error) {
+	searchSyncOnce.Do(func() {
+		sf := x.WorkerConfig.SearchSync
+		if sf == nil {
+			sf = z.NewSuperFlag("").MergeAndCheckDefault(SearchSyncDefaults)
+		}
+		searchSyncer, searchSyncErr = newSearchSync(sf)
+	})
+	return searchSyncer, searchSyncErr
+}
+
+func newSearchSync(sf *z.SuperFlag) (*searchSync, error) {
+	timeout, err := time.ParseDuration(sf.GetString("timeout"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing timeout")
+	}
+	s := &searchSync{
+		username:  sf.GetString("username"),
+		password:  sf.GetString("password"),
+		apiKey:    sf.GetString("api-key"),
+		batchSize: int(sf.GetInt64("batch-size")),
+		backfills: make(map[string]*searchBackfill),
+		resyncs:   make(map[string]uint64),
+	}
+	if s.batchSize <= 0 {
+		return nil, errors.Errorf("batch-size must be positive")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caCert := sf.GetString("ca-cert"); caCert != "" {
+		pem, err := ioutil.ReadFile(caCert)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read ca cert file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificate found in %s", caCert)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	if cert, key := sf.GetString("client-cert"), sf.GetString("client-key"); cert != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to load client cert and key")
+		}
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{pair}
+	}
+	s.client = &http.Client{Timeout: timeout, Transport: transport}
+	return s, nil
+}
+
+// searchRequestError is an error returned by Elasticsearch or OpenSearch.
+type searchRequestError struct {
+	Status int
+	Type   string
+	Reason string
+}
+
+func (e *searchRequestError) Error() string {
+	return fmt.Sprintf("search cluster returned status %d: %s: %s", e.Status, e.Type, e.Reason)
+}
+
+// request sends a request to an Elasticsearch or OpenSearch cluster, and decodes its JSON
+// response into out, unless out is nil.
+func (s *searchSync) request(ctx context.Context, method, reqURL, contentType string,
+	body []byte, out interface{}) error {
+	req, err := http.NewRequest(method, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", contentType)
+	switch {
+	case s.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+s.apiKey)
+	case s.username != "":
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "while reading the response of %s %s", method, reqURL)
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		var r struct {
+			Error struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(data, &r); err != nil || r.Error.Type == "" {
+			return &searchRequestError{Status: resp.StatusCode, Reason: string(data)}
+		}
+		return &searchRequestError{
+			Status: resp.StatusCode,
+			Type:   r.Error.Type,
+			Reason: r.Error.Reason,
+		}
+	}
+	if out == nil {
+		return nil
+	}
+	return errors.Wrapf(json.Unmarshal(data, out), "while decoding the response of %s %s",
+		method, reqURL)
+}
+
+func searchIndexURL(c *pb.SearchConnector) string {
+	return strings.TrimRight(c.Url, "/") + "/" + url.PathEscape(c.Index)
+}
+
+// createIndex creates the index of the connector with its mapping, unless it already exists.
+func (s *searchSync) createIndex(ctx context.Context, c *pb.SearchConnector) error {
+	err := s.request(ctx, http.MethodPut, searchIndexURL(c), "application/json",
+		[]byte(c.Mapping), nil)
+	if rerr, ok := err.(*searchRequestError); ok && rerr.Type == "resource_already_exists_exception" {
+		return nil
+	}
+	return err
+}
+
+// searchMapping derives the mapping of the index of a connector from the schema of its
+// predicates. Strings with a fulltext or term index are mapped to text fields, and those with
+// only an exact or hash index to keyword fields. The other strings are left to the dynamic
+// mapping of the index.
+func searchMapping(ctx context.Context, c *pb.SearchConnector) (string, error) {
+	nodes, err := GetSchemaOverNetwork(ctx, &pb.SchemaRequest{
+		Predicates: x.NamespaceAttrList(c.Namespace, c.Predicates),
+		Fields:     []string{"type", "tokenizer"},
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "while reading the schema of the predicates")
+	}
+	props := map[string]interface{}{
+		"uid": map[string]string{"type": "keyword"},
+	}
+	for _, n := range nodes {
+		pred := x.ParseAttr(n.Predicate)
+		var typ string
+		switch n.Type {
+		case "uid":
+			typ = "keyword"
+		case "int":
+			typ = "long"
+		case "float":
+			typ = "double"
+		case "bool":
+			typ = "boolean"
+		case "datetime":
+			typ = "date"
+		case "geo":
+			typ = "geo_shape"
+		case "string":
+			var text, keyword bool
+			for _, t := range n.Tokenizer {
+				switch t {
+				case "fulltext", "term":
+					text = true
+				case "exact", "hash":
+					keyword = true
+				}
+			}
+			switch {
+			case text && keyword:
+				props[pred] = map[string]interface{}{
+					"type":   "text",
+					"fields": map[string]interface{}{"keyword": map[string]string{"type": "keyword"}},
+				}
+			case text:
+				typ = "text"
+			case keyword:
+				typ = "keyword"
+			}
+		}
+		if typ != "" {
+			props[pred] = map[string]string{"type": typ}
+		}
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"mappings": map[string]interface{}{"properties": props},
+	})
+	return string(b), err
+}
+
+// searchValue converts a value to the form it takes in the documents of the index.
+func searchValue(p *pb.Posting) (interface{}, error) {
+	val := types.Val{Tid: types.TypeID(p.ValType), Value: p.Value}
+	switch val.Tid {
+	case types.IntID, types.FloatID, types.BoolID, types.DateTimeID:
+		v, err := types.Convert(val, val.Tid)
+		return v.Value, err
+	case types.GeoID:
+		v, err := types.Convert(val, types.StringID)
+		if err != nil {
+			return nil, err
+		}
+		return json.RawMessage(v.Value.(string)), nil
+	default:
+		v, err := types.Convert(val, types.StringID)
+		if err != nil {
+			return nil, err
+		}
+		return strings.TrimRight(v.Value.(string), "\x00"), nil
+	}
+}
+
+// searchDocument reads the values of the predicates of a node at readTs. Language tagged values
+// go to a field named after the predicate and the language, like name@fr. Predicates without a
+// value are set to null, so that deleted values are removed from the document. Passwords are
+// never sent.
+func searchDocument(ctx context.Context, c *pb.SearchConnector, uid uint64, preds []string,
+	readTs uint64) (map[string]interface{}, error) {
+	doc := map[string]interface{}{"uid": fmt.Sprintf("%#x", uid)}
+	for _, pred := range preds {
+		attr := x.NamespaceAttr(c.Namespace, pred)
+		su, _ := schema.State().Get(ctx, attr)
+		if su.ValueType == pb.Posting_PASSWORD {
+			continue
+		}
+		pl, err := posting.GetNoStore(x.DataKey(attr, uid), readTs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while reading %s of %#x", pred, uid)
+		}
+		fields := map[string][]interface{}{pred: nil}
+		err = pl.Iterate(readTs, 0, func(p *pb.Posting) error {
+			if p.PostingType == pb.Posting_REF {
+				fields[pred] = append(fields[pred], fmt.Sprintf("%#x", p.Uid))
+				return nil
+			}
+			v, err := searchValue(p)
+			if err != nil {
+				glog.Warningf("Search connector %s: skipping a value of %s of %#x: %v",
+					c.Name, pred, uid, err)
+				return nil
+			}
+			field := pred
+			if len(p.LangTag) > 0 {
+				field = pred + "@" + string(p.LangTag)
+			}
+			fields[field] = append(fields[field], v)
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "while reading %s of %#x", pred, uid)
+		}
+		for field, vals := range fields {
+			switch {
+			case len(vals) == 0:
+				doc[field] = nil
+			case len(vals) == 1 && !su.List:
+				doc[field] = vals[0]
+			default:
+				doc[field] = vals
+			}
+		}
+	}
+	return doc, nil
+}
+
+// sendNodes sends the given predicates of the nodes to the index of the connector, with their
+// values as of minTs or later.
+func (s *searchSync) sendNodes(ctx context.Context, c *pb.SearchConnector,
+	nodes map[uint64][]string, minTs uint64) error {
+	uids := make([]uint64, 0, len(nodes))
+	for uid := range nodes {
+		uids = append(uids, uid)
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	for len(uids) > 0 {
+		n := x.Min(uint64(s.batchSize), uint64(len(uids)))
+		if err := s.sendBatch(ctx, c, uids[:n], nodes, minTs); err != nil {
+			return err
+		}
+		uids = uids[n:]
+	}
+	return nil
+}
+
+func (s *searchSync) sendBatch(ctx context.Context, c *pb.SearchConnector, uids []uint64,
+	nodes map[uint64][]string, minTs uint64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	readTs := x.Max(minTs, posting.Oracle().MaxAssigned())
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, uid := range uids {
+		doc, err := searchDocument(ctx, c, uid, nodes[uid], readTs)
+		if err != nil {
+			return err
+		}
+		action := map[string]interface{}{
+			"update": map[string]string{"_index": c.Index, "_id": fmt.Sprintf("%#x", uid)},
+		}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(map[string]interface{}{"doc": doc, "doc_as_upsert": true}); err != nil {
+			return err
+		}
+	}
+
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID    string `json:"_id"`
+			Error *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := s.request(ctx, http.MethodPost, strings.TrimRight(c.Url, "/")+"/_bulk",
+		"application/x-ndjson", buf.Bytes(), &resp); err != nil {
+		return err
+	}
+	if !resp.Errors {
+		return nil
+	}
+	for _, item := range resp.Items {
+		for _, res := range item {
+			if res.Error != nil {
+				return errors.Errorf("while indexing %s: %s: %s", res.ID, res.Error.Type,
+					res.Error.Reason)
+			}
+		}
+	}
+	return errors.Errorf("bulk request failed")
+}
+
+// drop applies a drop operation to the index of the connector. Dropping all the data deletes all
+// the documents, and dropping a predicate removes its field from them.
+func (s *searchSync) drop(ctx context.Context, c *pb.SearchConnector, ev *DropEvent) error {
+	var path string
+	var body interface{}
+	switch ev.Operation {
+	case strings.ToLower(pb.Mutations_ALL.String()), strings.ToLower(pb.Mutations_DATA.String()):
+		path = "/_delete_by_query?conflicts=proceed"
+		body = map[string]interface{}{"query": map[string]interface{}{"match_all": struct{}{}}}
+	case OpDropPred:
+		path = "/_update_by_query?conflicts=proceed"
+		body = map[string]interface{}{
+			"query": map[string]interface{}{"exists": map[string]string{"field": ev.Pred}},
+			"script": map[string]interface{}{
+				"source": "ctx._source.remove(params.field)",
+				"params": map[string]string{"field": ev.Pred},
+			},
+		}
+	default:
+		return nil
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	return s.request(ctx, http.MethodPost, searchIndexURL(c)+path, "application/json", b, nil)
+}
+
+// send sends the nodes changed by the CDC events of a transaction, or applies the drop
+// operation, to the connectors they concern. A failing connector doesn't hold up the CDC events,
+// see sendConnector.
+func (s *searchSync) send(ctx context.Context, events []CDCEvent, commitTs uint64) {
+	for key, c := range searchConnectors() {
+		s.sendConnector(ctx, key, c, events, commitTs)
+	}
+}
+
+// sendConnector sends the CDC events of a transaction to the connector. If they still can't be
+// sent after searchSendAttempts attempts, they're dropped and the connector is marked for a
+// resync. The events of a connector waiting for its resync aren't sent at all, as the resync
+// copies their values anyway, and those sent while the resync runs get a single attempt.
+func (s *searchSync) sendConnector(ctx context.Context, key string, c *pb.SearchConnector,
+	events []CDCEvent, commitTs uint64) {
+	s.bfLock.Lock()
+	_, resync := s.resyncs[key]
+	bf := s.backfills[key]
+	running := bf != nil && bf.running
+	s.bfLock.Unlock()
+
+	attempts := searchSendAttempts
+	switch {
+	case resync && !running:
+		return
+	case resync:
+		attempts = 1
+	}
+	var err error
+	for i := 1; i <= attempts; i++ {
+		if err = s.sendEvents(ctx, c, events, commitTs); err == nil {
+			return
+		}
+		if i == attempts {
+			break
+		}
+		select {
+		case <-time.After(time.Duration(i) * searchSendBackoff):
+		case <-ctx.Done():
+			i = attempts
+		}
+	}
+	glog.Errorf("Dropped the changes of the transaction committed at %d for search "+
+		"connector %s, which will be resynced: %v", commitTs, c.Name, err)
+	s.bfLock.Lock()
+	s.resyncs[key]++
+	s.bfLock.Unlock()
+}
+
+func (s *searchSync) sendEvents(ctx context.Context, c *pb.SearchConnector, events []CDCEvent,
+	commitTs uint64) error {
+	preds := make(map[string]struct{}, len(c.Predicates))
+	for _, pred := range c.Predicates {
+		preds[pred] = struct{}{}
+	}
+	nodes := make(map[uint64][]string)
+	for _, e := range events {
+		var ns uint64
+		if len(e.Meta.Namespace) == 8 {
+			ns = binary.BigEndian.Uint64(e.Meta.Namespace)
+		}
+		switch ev := e.Event.(type) {
+		case *MutationEvent:
+			if _, ok := preds[ev.Attr]; !ok || ns != c.Namespace {
+				continue
+			}
+			if !x.HasString(nodes[ev.Uid], ev.Attr) {
+				nodes[ev.Uid] = append(nodes[ev.Uid], ev.Attr)
+			}
+		case *DropEvent:
+			if _, ok := preds[ev.Pred]; ev.Operation == OpDropPred &&
+				(!ok || ns != c.Namespace) {
+				continue
+			}
+			if err := s.sendNodes(ctx, c, nodes, commitTs); err != nil {
+				return err
+			}
+			nodes = make(map[uint64][]string)
+			if err := s.drop(ctx, c, ev); err != nil {
+				return err
+			}
+		}
+	}
+	return s.sendNodes(ctx, c, nodes, commitTs)
+}
+
+// servedSearchPredicates returns the predicates of the connector served by this group.
+func servedSearchPredicates(c *pb.SearchConnector) []string {
+	g := groups()
+	g.RLock()
+	defer g.RUnlock()
+	var preds []string
+	for _, pred := range c.Predicates {
+		if t := g.tablets[x.NamespaceAttr(c.Namespace, pred)]; t != nil && t.GroupId == g.gid {
+			preds = append(preds, pred)
+		}
+	}
+	return preds
+}
+
+// startBackfills starts the backfills requested for the predicates served by this group, and
+// those resyncing the connectors which dropped changes. It's called periodically on the group
+// leader.
+func (s *searchSync) startBackfills() {
+	gid := groups().groupId()
+	for key, c := range searchConnectors() {
+		requested := c.BackfillId != 0 && c.Backfilled[gid] != c.BackfillId
+		s.bfLock.Lock()
+		drops, resync := s.resyncs[key]
+		s.bfLock.Unlock()
+		if !requested && !resync {
+			continue
+		}
+		preds := servedSearchPredicates(c)
+		if len(preds) == 0 {
+			continue
+		}
+		s.bfLock.Lock()
+		bf := s.backfills[key]
+		if bf != nil && (bf.running || ((bf.id == c.BackfillId || !requested) &&
+			time.Since(bf.failed) < searchBackfillRetry)) {
+			s.bfLock.Unlock()
+			continue
+		}
+		bf = &searchBackfill{id: c.BackfillId, report: requested, drops: drops, running: true}
+		s.backfills[key] = bf
+		s.bfLock.Unlock()
+
+		go s.backfill(key, c, preds, bf)
+	}
+}
+
+// backfill copies the existing data of the predicates to the index, as a task. The completion
+// of a requested backfill by this group is recorded in Zero. A cancelled backfill counts as
+// completed.
+func (s *searchSync) backfill(key string, c *pb.SearchConnector, preds []string,
+	bf *searchBackfill) {
+	kind := "Backfill"
+	if !bf.report {
+		kind = "Resync"
+	}
+	opts := TaskOptions{
+		Kind: "search-backfill",
+		Description: fmt.Sprintf("%s of %s into index %s by search connector %s", kind,
+			strings.Join(preds, ", "), c.Index, c.Name),
+		Pausable:    true,
+		Cancellable: true,
+	}
+	err := RunTask(groups().Ctx(), opts, func(ctx context.Context, t *Task) error {
+		for i, pred := range preds {
+			if err := t.Progress(ctx, uint64(i), uint64(len(preds)),
+				"Copying "+pred); err != nil {
+				return err
+			}
+			if err := s.backfillPredicate(ctx, c, pred); err != nil {
+				return errors.Wrapf(err, "while copying %s", pred)
+			}
+		}
+		return nil
+	})
+	if err == ErrTaskCancelled {
+		err = nil
+	}
+	if err == nil && bf.report {
+		err = reportSearchBackfill(c)
+	}
+
+	s.bfLock.Lock()
+	defer s.bfLock.Unlock()
+	bf.running = false
+	switch {
+	case err != nil:
+		glog.Errorf("%s of search connector %s failed: %v", kind, c.Name, err)
+		bf.failed = time.Now()
+	case bf.drops > 0 && s.resyncs[key] == bf.drops:
+		delete(s.resyncs, key)
+	}
+}
+
+func (s *searchSync) backfillPredicate(ctx context.Context, c *pb.SearchConnector,
+	pred string) error {
+	prefix := x.ParsedKey{Attr: x.NamespaceAttr(c.Namespace, pred)}.DataPrefix()
+	var after []byte
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		readTs := posting.Oracle().MaxAssigned()
+		uids, last, err := dataKeyUids(prefix, after, s.batchSize, readTs)
+		if err != nil || len(uids) == 0 {
+			return err
+		}
+		nodes := make(map[uint64][]string, len(uids))
+		for _, uid := range uids {
+			nodes[uid] = []string{pred}
+		}
+		if err := s.sendNodes(ctx, c, nodes, readTs); err != nil {
+			return err
+		}
+		after = last
+	}
+}
+
+// dataKeyUids returns the uids of up to n data keys with the prefix which come after the given
+// key, along with the last key returned.
+func dataKeyUids(prefix, after []byte, n int, readTs uint64) ([]uint64, []byte, error) {
+	txn := pstore.NewTransactionAt(readTs, false)
+	defer txn.Discard()
+	iopt := badger.DefaultIteratorOptions
+	iopt.PrefetchValues = false
+	iopt.Prefix = prefix
+	itr := txn.NewIterator(iopt)
+	defer itr.Close()
+
+	seek := prefix
+	if len(after) > 0 {
+		seek = after
+	}
+	var uids []uint64
+	var last []byte
+	for itr.Seek(seek); itr.ValidForPrefix(prefix) && len(uids) < n; itr.Next() {
+		key := itr.Item().Key()
+		if bytes.Equal(key, after) {
+			continue
+		}
+		pk, err := x.Parse(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		last = itr.Item().KeyCopy(last[:0])
+		if pk.HasStartUid {
+			continue
+		}
+		uids = append(uids, pk.Uid)
+	}
+	return uids, last, nil
+}
+
+func reportSearchBackfill(c *pb.SearchConnector) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return updateSearchConnector(ctx, &pb.SearchConnectorUpdate{
+		Connector: &pb.SearchConnector{
+			Name:       c.Name,
+			Namespace:  c.Namespace,
+			BackfillId: c.BackfillId,
+		},
+		BackfilledGroup: groups().groupId(),
+	})
+}
+
+func updateSearchConnector(ctx context.Context, u *pb.SearchConnectorUpdate) error {
+	pl := groups().connToZeroLeader()
+	if pl == nil {
+		return conn.ErrNoConnection
+	}
+	if _, err := pb.NewZeroClient(pl.Get()).UpdateSearchConnector(ctx, u); err != nil {
+		return err
+	}
+	return UpdateMembershipState(ctx)
+}
+
+func searchConnector(ctx context.Context, name string) (*pb.SearchConnector, error) {
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := searchConnectors()[x.NamespaceAttr(ns, name)]
+	if c == nil {
+		return nil, errors.Errorf("Search connector %s not found", name)
+	}
+	return c, nil
+}
+
+// UpdateSearchConnector adds a search connector to the namespace in the context, or replaces the
+// connector with the same name. The index of the connector is created if it doesn't exist, with
+// the mapping of the connector, or else one derived from the schema of its predicates. If
+// backfill is set, the existing values of the predicates are copied to the index.
+func UpdateSearchConnector(ctx context.Context, c *pb.SearchConnector,
+	backfill bool) (*pb.SearchConnector, error) {
+	if !EnterpriseEnabled() {
+		return nil, errors.New("you must enable enterprise features first. " +
+			"Supply the appropriate license file to Dgraph Zero using the HTTP endpoint.")
+	}
+	if x.WorkerConfig.LudicrousMode {
+		return nil, errors.Errorf("Search connectors are not supported in ludicrous mode")
+	}
+	ns, err := x.ExtractNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c = proto.Clone(c).(*pb.SearchConnector)
+	c.Namespace = ns
+	if err := validateSearchConnector(c); err != nil {
+		return nil, err
+	}
+	if c.Mapping == "" {
+		if c.Mapping, err = searchMapping(ctx, c); err != nil {
+			return nil, err
+		}
+	}
+
+	s, err := getSearchSync()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.createIndex(ctx, c); err != nil {
+		return nil, errors.Wrapf(err, "while creating index %s", c.Index)
+	}
+
+	cur := searchConnectors()[x.NamespaceAttr(ns, c.Name)]
+	switch {
+	case backfill:
+		c.BackfillId = rand.Uint64()
+	case cur != nil:
+		c.BackfillId, c.Backfilled = cur.BackfillId, cur.Backfilled
+	}
+	glog.Infof("Updating search connector %s of namespace %#x", c.Name, ns)
+	if err := updateSearchConnector(ctx, &pb.SearchConnectorUpdate{Connector: c}); err != nil {
+		return nil, err
+	}
+	return searchConnector(ctx, c.Name)
+}
+
+func validateSearchConnector(c *pb.SearchConnector) error {
+	if c.Name == "" {
+		return errors.Errorf("Search connector name must be set")
+	}
+	if c.Index == "" {
+		return errors.Errorf("Index of search connector %s must be set", c.Name)
+	}
+	u, err := url.Parse(c.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("URL %q of search connector %s must be an http or https URL",
+			c.Url, c.Name)
+	}
+	if len(c.Predicates) == 0 {
+		return errors.Errorf("Search connector %s must have predicates", c.Name)
+	}
+	seen := make(map[string]bool, len(c.Predicates))
+	for _, pred := range c.Predicates {
+		if x.IsReservedPredicate(x.NamespaceAttr(c.Namespace, pred)) {
+			return errors.Errorf("Predicate %s is reserved", pred)
+		}
+		if seen[pred] {
+			return errors.Errorf("Predicate %s is listed twice", pred)
+		}
+		seen[pred] = true
+	}
+	if c.Mapping != "" && !json.Valid([]byte(c.Mapping)) {
+		return errors.Errorf("Mapping of search connector %s isn't valid JSON", c.Name)
+	}
+	return nil
+}
+
+// RemoveSearchConnector removes a search connector of the namespace in the context. Its index
+// is left as it is.
+func RemoveSearchConnector(ctx context.Context, name string) error {
+	c, err := searchConnector(ctx, name)
+	if err != nil {
+		return err
+	}
+	glog.Infof("Removing search connector %s of namespace %#x", c.Name, c.Namespace)
+	return updateSearchConnector(ctx, &pb.SearchConnectorUpdate{Connector: c, Remove: true})
+}
+
+// BackfillSearchConnector makes the groups copy the existing values of the predicates of a
+// search connector to its index again.
+func BackfillSearchConnector(ctx context.Context, name string) (*pb.SearchConnector, error) {
+	if !EnterpriseEnabled() {
+		return nil, errors.New("you must enable enterprise features first. " +
+			"Supply the appropriate license file to Dgraph Zero using the HTTP endpoint.")
+	}
+	c, err := searchConnector(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	c.BackfillId = rand.Uint64()
+	c.Backfilled = nil
+	if err := updateSearchConnector(ctx, &pb.SearchConnectorUpdate{Connector: c}); err != nil {
+		return nil, err
+	}
+	return searchConnector(ctx, name)
+}
@@ -0,0 +1,201 @@
+// +build !oss
+
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Dgraph Community License (the "License"); you
+ * may not use this file except in compliance with the License. You
+ * may obtain a copy of the License at
+ *
+ *     https://github.com/dgraph-io/dgraph/blob/master/licenses/DCL.txt
+ */
+
+package worker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/types"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+)
+
+func searchTestInt(t *testing.T, i int64) []byte {
+	v := types.ValueForType(types.BinaryID)
+	require.NoError(t, types.Marshal(types.Val{Tid: types.IntID, Value: i}, &v))
+	return v.Value.([]byte)
+}
+
+func TestSearchSyncSendNodes(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte(`
+		search_name: string @lang .
+		search_tags: [string] .
+		search_friend: [uid] .
+		search_age: int .
+		search_secret: password .
+	`), 1))
+	edges := []*pb.DirectedEdge{
+		{Entity: 1, Attr: "search_name", Value: []byte("Alice"), ValueType: pb.Posting_STRING},
+		{Entity: 1, Attr: "search_name", Value: []byte("Alix"), ValueType: pb.Posting_STRING,
+			Lang: "fr"},
+		{Entity: 1, Attr: "search_tags", Value: []byte("admin"), ValueType: pb.Posting_STRING},
+		{Entity: 1, Attr: "search_friend", ValueId: 2, ValueType: pb.Posting_UID},
+		{Entity: 1, Attr: "search_friend", ValueId: 3, ValueType: pb.Posting_UID},
+		{Entity: 1, Attr: "search_age", Value: searchTestInt(t, 30), ValueType: pb.Posting_INT},
+		{Entity: 1, Attr: "search_secret", Value: []byte("hunter2"), ValueType: pb.Posting_STRING},
+		{Entity: 2, Attr: "search_age", Value: searchTestInt(t, 41), ValueType: pb.Posting_INT},
+	}
+	for _, edge := range edges {
+		edge.Attr = x.GalaxyAttr(edge.Attr)
+		addEdge(t, edge, getOrCreate(x.DataKey(edge.Attr, edge.Entity)))
+	}
+
+	var docs []map[string]interface{}
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/_bulk", r.URL.Path)
+		require.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		user, pass, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "elastic", user)
+		require.Equal(t, "changeme", pass)
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		sc := bufio.NewScanner(strings.NewReader(string(body)))
+		for sc.Scan() {
+			var action map[string]map[string]string
+			require.NoError(t, json.Unmarshal(sc.Bytes(), &action))
+			require.Equal(t, "people", action["update"]["_index"])
+			require.True(t, sc.Scan())
+			var update struct {
+				Doc         map[string]interface{} `json:"doc"`
+				DocAsUpsert bool                   `json:"doc_as_upsert"`
+			}
+			require.NoError(t, json.Unmarshal(sc.Bytes(), &update))
+			require.True(t, update.DocAsUpsert)
+			require.Equal(t, action["update"]["_id"], update.Doc["uid"])
+			docs = append(docs, update.Doc)
+		}
+		if fail {
+			_, _ = w.Write([]byte(`{"errors":true,"items":[{"update":{"_id":"0x1",` +
+				`"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer srv.Close()
+
+	s, err := newSearchSync(z.NewSuperFlag("username=elastic; password=changeme").
+		MergeAndCheckDefault(SearchSyncDefaults))
+	require.NoError(t, err)
+	s.batchSize = 1
+	c := &pb.SearchConnector{
+		Name:       "people",
+		Url:        srv.URL + "/",
+		Index:      "people",
+		Predicates: []string{"search_name", "search_tags", "search_friend", "search_age"},
+	}
+	preds := append(c.Predicates, "search_secret")
+	nodes := map[uint64][]string{1: preds, 2: preds}
+	require.NoError(t, s.sendNodes(context.Background(), c, nodes, math.MaxUint64))
+
+	require.Equal(t, []map[string]interface{}{
+		{
+			"uid":            "0x1",
+			"search_name":    "Alice",
+			"search_name@fr": "Alix",
+			"search_tags":    []interface{}{"admin"},
+			"search_friend":  []interface{}{"0x2", "0x3"},
+			"search_age":     float64(30),
+		},
+		{
+			"uid":           "0x2",
+			"search_name":   nil,
+			"search_tags":   nil,
+			"search_friend": nil,
+			"search_age":    float64(41),
+		},
+	}, docs)
+
+	fail = true
+	err = s.sendNodes(context.Background(), c, map[uint64][]string{1: preds}, math.MaxUint64)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "mapper_parsing_exception")
+}
+
+func TestValidateSearchConnector(t *testing.T) {
+	valid := func() *pb.SearchConnector {
+		return &pb.SearchConnector{
+			Name:       "people",
+			Url:        "https://localhost:9200",
+			Index:      "people",
+			Predicates: []string{"name", "age"},
+		}
+	}
+	require.NoError(t, validateSearchConnector(valid()))
+
+	c := valid()
+	c.Url = "localhost:9200"
+	require.Error(t, validateSearchConnector(c))
+	c = valid()
+	c.Predicates = []string{"name", "name"}
+	require.Error(t, validateSearchConnector(c))
+	c = valid()
+	c.Predicates = []string{"dgraph.type"}
+	require.Error(t, validateSearchConnector(c))
+	c = valid()
+	c.Mapping = `{"mappings":`
+	require.Error(t, validateSearchConnector(c))
+	c = valid()
+	c.Mapping = `{"mappings":{"properties":{"name":{"type":"text"}}}}`
+	require.NoError(t, validateSearchConnector(c))
+}
+
+func TestSearchSyncSendConnectorFailing(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	s, err := newSearchSync(z.NewSuperFlag("").MergeAndCheckDefault(SearchSyncDefaults))
+	require.NoError(t, err)
+	c := &pb.SearchConnector{Name: "people", Url: srv.URL, Index: "people"}
+	key := x.GalaxyAttr(c.Name)
+	events := []CDCEvent{{
+		Event: &DropEvent{Operation: "all"},
+		Meta:  &EventMeta{Namespace: x.NamespaceToBytes(x.GalaxyNamespace)},
+	}}
+
+	// The changes are dropped after the last attempt, and the connector marked for a resync.
+	s.sendConnector(context.Background(), key, c, events, 10)
+	require.Equal(t, searchSendAttempts, requests)
+	require.Equal(t, uint64(1), s.resyncs[key])
+
+	// Nothing is sent until the resync starts.
+	s.sendConnector(context.Background(), key, c, events, 11)
+	require.Equal(t, searchSendAttempts, requests)
+	require.Equal(t, uint64(1), s.resyncs[key])
+
+	// While the resync runs, the changes get a single attempt, and a drop is counted so that
+	// the resync doesn't clear the mark.
+	bf := &searchBackfill{drops: 1, running: true}
+	s.backfills[key] = bf
+	s.sendConnector(context.Background(), key, c, events, 12)
+	require.Equal(t, searchSendAttempts+1, requests)
+	require.Equal(t, uint64(2), s.resyncs[key])
+}
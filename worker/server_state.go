@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/raftwal"
 	"github.com/dgraph-io/dgraph/x"
@@ -81,6 +82,16 @@ func setBadgerOptions(opt badger.Options) badger.Options {
 	return opt
 }
 
+// postingsOptions sets the options of the postings store on top of opt.
+func postingsOptions(opt badger.Options) badger.Options {
+	opt = opt.
+		WithNumVersionsToKeep(math.MaxInt32).
+		WithBlockCacheSize(Config.PBlockCacheSize).
+		WithIndexCacheSize(Config.PIndexCacheSize).
+		WithNamespaceOffset(x.NamespaceOffset)
+	return setBadgerOptions(opt)
+}
+
 func (s *ServerState) initStorage() {
 	var err error
 
@@ -95,29 +106,54 @@ func (s *ServerState) initStorage() {
 		}
 	}
 
+	if Config.InMemory {
+		glog.Warningf("Keeping the postings and the WAL in memory. All the data is lost when " +
+			"Alpha stops.")
+	}
+
+	if x.WorkerConfig.WALArchive != nil && !Config.InMemory {
+		a, err := parseWALArchive(x.WorkerConfig.WALArchive)
+		x.Checkf(err, "Invalid --wal_archive flag")
+		if a != nil {
+			walArchiver = a
+			raftwal.SetArchiver(a.spool)
+		}
+	}
+
+	if !Config.InMemory {
+		// A re-encryption cut over before the last shutdown may not have swapped the
+		// directories yet.
+		x.Checkf(completeReEncryption(), "Error while swapping in re-encrypted directories")
+	}
+
 	{
 		// Write Ahead Log directory
-		x.Checkf(os.MkdirAll(Config.WALDir, 0700), "Error while creating WAL dir.")
-		s.WALstore, err = raftwal.InitEncrypted(Config.WALDir, x.WorkerConfig.EncryptionKey)
+		if Config.InMemory {
+			s.WALstore, err = raftwal.InitInMemory(x.WorkerConfig.EncryptionKey)
+		} else {
+			x.Checkf(os.MkdirAll(Config.WALDir, 0700), "Error while creating WAL dir.")
+			s.WALstore, err = raftwal.InitEncrypted(Config.WALDir, x.WorkerConfig.EncryptionKey)
+		}
 		x.Check(err)
 	}
 	{
 		// Postings directory
 		// All the writes to posting store should be synchronous. We use batched writers
 		// for posting lists, so the cost of sync writes is amortized.
-		x.Check(os.MkdirAll(Config.PostingDir, 0700))
-		opt := badger.DefaultOptions(Config.PostingDir).
-			WithNumVersionsToKeep(math.MaxInt32).
-			WithBlockCacheSize(Config.PBlockCacheSize).
-			WithIndexCacheSize(Config.PIndexCacheSize).
-			WithNamespaceOffset(x.NamespaceOffset)
-		opt = setBadgerOptions(opt)
+		opt := badger.DefaultOptions(Config.PostingDir)
+		if Config.InMemory {
+			opt = badger.DefaultOptions("").WithInMemory(true)
+		} else {
+			x.Check(os.MkdirAll(Config.PostingDir, 0700))
+		}
+		opt = postingsOptions(opt)
 
 		// Print the options w/o exposing key.
 		// TODO: Build a stringify interface in Badger options, which is used to print nicely here.
 		key := opt.EncryptionKey
 		opt.EncryptionKey = nil
-		glog.Infof("Opening postings BadgerDB with options: %+v\n", opt)
+		glog.Infof("Opening postings with the %s storage engine and options: %+v\n",
+			Config.StorageEngine, opt)
 		opt.EncryptionKey = key
 
 		s.Pstore, err = badger.OpenManaged(opt)
@@ -129,22 +165,66 @@ func (s *ServerState) initStorage() {
 	// Temp directory
 	x.Check(os.MkdirAll(x.WorkerConfig.TmpDir, 0700))
 
-	s.gcCloser = z.NewCloser(3)
+	s.gcCloser = z.NewCloser(2)
 	go x.RunVlogGC(s.Pstore, s.gcCloser)
 	// Commenting this out because Badger is doing its own cache checks.
 	go x.MonitorCacheHealth(s.Pstore, s.gcCloser)
-	go x.MonitorDiskMetrics("postings_fs", Config.PostingDir, s.gcCloser)
+	if !Config.InMemory {
+		s.gcCloser.AddRunning(1)
+		go x.MonitorDiskMetrics("postings_fs", Config.PostingDir, s.gcCloser)
+	}
+
+	// The disk limits don't apply to the data kept in memory.
+	if x.WorkerConfig.Disk != nil && !Config.InMemory {
+		limits, err := parseDiskLimits(x.WorkerConfig.Disk)
+		x.Checkf(err, "Invalid --disk flag")
+		dm = &diskMonitor{db: s.Pstore, limits: limits}
+		s.gcCloser.AddRunning(1)
+		go dm.run(s.gcCloser)
+	}
+
+	if walArchiver != nil {
+		s.gcCloser.AddRunning(1)
+		go walArchiver.run(s.gcCloser)
+	}
+
+	if x.WorkerConfig.IndexVerify != nil {
+		v, err := parseIndexVerify(x.WorkerConfig.IndexVerify)
+		x.Checkf(err, "Invalid --index_verify flag")
+		if v != nil {
+			indexVerify = v
+			s.gcCloser.AddRunning(1)
+			go v.run(s.gcCloser)
+		}
+	}
+
+	if x.WorkerConfig.TieredStorage != nil {
+		t, err := parseTieredStorage(x.WorkerConfig.TieredStorage)
+		x.Checkf(err, "Invalid --tiered_storage flag")
+		if t != nil {
+			tiered = t
+			posting.SetColdStore(t.open)
+			s.gcCloser.AddRunning(1)
+			go t.run(s.gcCloser)
+		}
+	}
 }
 
 // Dispose stops and closes all the resources inside the server state.
 func (s *ServerState) Dispose() {
 	s.gcCloser.SignalAndWait()
+	closeReEncryption()
 	if err := s.Pstore.Close(); err != nil {
 		glog.Errorf("Error while closing postings store: %v", err)
 	}
 	if err := s.WALstore.Close(); err != nil {
 		glog.Errorf("Error while closing WAL store: %v", err)
 	}
+	if !Config.InMemory {
+		if err := completeReEncryption(); err != nil {
+			glog.Errorf("Error while swapping in re-encrypted directories: %v", err)
+		}
+	}
 }
 
 func (s *ServerState) GetTimestamp(readOnly bool) uint64 {
@@ -240,6 +240,14 @@ func sortWithIndex(ctx context.Context, ts *pb.SortMessage) *sortresult {
 				"Failed to get tokenizer for Attribute %s for language %s.", order.Attr, lang))
 		}
 		prefix = langTokenizer.Prefix()
+	} else if collation := schema.State().Collation(ctx, order.Attr); collation != "" {
+		// The untagged values are indexed by their collation keys.
+		tokenizer = tok.GetTokenizerForCollation(tokenizer, "", collation)
+		if exactTokenizer, ok := tokenizer.(tok.ExactTokenizer); ok {
+			prefix = exactTokenizer.Prefix()
+		} else {
+			prefix = []byte{tokenizer.Identifier()}
+		}
 	} else {
 		prefix = []byte{tokenizer.Identifier()}
 	}
@@ -249,7 +257,11 @@ func sortWithIndex(ctx context.Context, ts *pb.SortMessage) *sortresult {
 	iterOpt.PrefetchValues = false
 	iterOpt.Reverse = order.Desc
 	iterOpt.Prefix = x.IndexKey(order.Attr, string(prefix))
-	txn := pstore.NewTransactionAt(ts.ReadTs, false)
+	db, err := posting.ReadStore(order.Attr)
+	if err != nil {
+		return resultWithError(err)
+	}
+	txn := db.NewTransactionAt(ts.ReadTs, false)
 	defer txn.Discard()
 	var seekKey []byte
 	if !order.Desc {
@@ -451,7 +463,7 @@ func multiSort(ctx context.Context, r *sortresult, ts *pb.SortMessage) error {
 			x.AssertTrue(idx >= 0)
 			vals[j] = sortVals[idx]
 		}
-		if err := types.Sort(vals, &ul.Uids, desc, ""); err != nil {
+		if err := types.Sort(vals, &ul.Uids, desc, sortLang(ctx, ts.Order[0])); err != nil {
 			return err
 		}
 		// Paginate
@@ -749,6 +761,15 @@ func paginate(ts *pb.SortMessage, dest *pb.List, vals []types.Val) (int, int, er
 	return start, end, nil
 }
 
+// sortLang returns the language whose collation orders the string values of the sort
+// predicate. That's the language asked for, or the collation of the predicate otherwise.
+func sortLang(ctx context.Context, order *pb.Order) string {
+	if len(order.Langs) > 0 {
+		return order.Langs[0]
+	}
+	return schema.State().Collation(ctx, order.Attr)
+}
+
 // sortByValue fetches values and sort UIDList.
 func sortByValue(ctx context.Context, ts *pb.SortMessage, ul *pb.List,
 	typ types.TypeID) ([]types.Val, error) {
@@ -758,12 +779,10 @@ func sortByValue(ctx context.Context, ts *pb.SortMessage, ul *pb.List,
 	multiSortVals := make([]types.Val, 0, lenList)
 	order := ts.Order[0]
 
-	var lang string
-	if langCount := len(order.Langs); langCount == 1 {
-		lang = order.Langs[0]
-	} else if langCount > 1 {
+	if len(order.Langs) > 1 {
 		return nil, errors.Errorf("Sorting on multiple language is not supported.")
 	}
+	lang := sortLang(ctx, order)
 
 	// nullsList is the list of UIDs for which value doesn't exist.
 	var nullsList []uint64
@@ -0,0 +1,318 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	bpb "github.com/dgraph-io/badger/v3/pb"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+)
+
+// LevelStatus is the state of a single level of the LSM tree.
+type LevelStatus struct {
+	Level      int
+	NumTables  int
+	Size       int64
+	TargetSize int64
+	StaleBytes int64
+	Score      float64
+}
+
+// StorageStatus is the state of the postings directory and of the jobs which reclaim space
+// in it.
+type StorageStatus struct {
+	LsmSize       int64
+	VlogSize      int64
+	LsmStaleBytes int64
+	Levels        []LevelStatus
+
+	VlogGCPaused  bool
+	VlogGCRunning bool
+	// LastVlogGC is when the last GC triggered via StartVlogGC finished, and LastVlogGCRewrites
+	// the number of value log files it rewrote.
+	LastVlogGC         time.Time
+	LastVlogGCRewrites int
+	FlattenRunning     bool
+	LastFlatten        time.Time
+	RollupDelay        time.Duration
+	ListRewriteRunning bool
+	// LastListRewrite is when the last rewrite of the multi-part lists triggered via
+	// StartListRewrite finished, and LastListRewrites the number of lists it rewrote.
+	LastListRewrite  time.Time
+	LastListRewrites int
+}
+
+type storageJobs struct {
+	sync.Mutex
+	vlogGCRunning      bool
+	lastVlogGC         time.Time
+	lastVlogGCRewrites int
+	flattenRunning     bool
+	lastFlatten        time.Time
+	rewriteRunning     bool
+	lastRewrite        time.Time
+	lastRewrites       int
+	snapshotRunning    bool
+}
+
+var sj storageJobs
+
+// StartVlogGC runs value log GC on the postings directory in the background with the given
+// discard ratio. It fails if value log GC is paused.
+func StartVlogGC(discardRatio float64) error {
+	if discardRatio <= 0 || discardRatio >= 1 {
+		return errors.Errorf("discard ratio must be in the range (0, 1)")
+	}
+	if err := sj.beginVlogGC(); err != nil {
+		return err
+	}
+	go sj.vlogGC(pstore, discardRatio)
+	return nil
+}
+
+// beginVlogGC marks value log GC as running, unless it's paused or already running.
+func (sj *storageJobs) beginVlogGC() error {
+	sj.Lock()
+	defer sj.Unlock()
+	if x.VlogGCPaused() {
+		return errors.Errorf("value log GC is paused")
+	}
+	if sj.vlogGCRunning {
+		return errors.Errorf("value log GC is already running")
+	}
+	if sj.snapshotRunning {
+		return errors.Errorf("a local snapshot is being taken")
+	}
+	sj.vlogGCRunning = true
+	return nil
+}
+
+// vlogGC runs value log GC on db, which beginVlogGC must have marked as running.
+func (sj *storageJobs) vlogGC(db *badger.DB, discardRatio float64) {
+	glog.Infof("Running value log GC with discard ratio: %.2f", discardRatio)
+	rewrites, err := x.ValueLogGC(db, discardRatio)
+	if err != nil {
+		glog.Errorf("Error while running value log GC: %v", err)
+	}
+	glog.Infof("Value log GC done. Rewrote %d files.", rewrites)
+
+	sj.Lock()
+	defer sj.Unlock()
+	sj.vlogGCRunning = false
+	sj.lastVlogGC = time.Now()
+	sj.lastVlogGCRewrites = rewrites
+}
+
+// StartFlatten compacts all the levels of the LSM tree into one in the background, using the
+// given number of workers. Badger doesn't run its own compactions while flattening.
+func StartFlatten(workers int) error {
+	if workers <= 0 {
+		return errors.Errorf("number of workers must be positive")
+	}
+	if err := sj.beginFlatten(); err != nil {
+		return err
+	}
+	go sj.flatten(pstore, workers)
+	return nil
+}
+
+// beginFlatten marks flatten as running, unless it's already running.
+func (sj *storageJobs) beginFlatten() error {
+	sj.Lock()
+	defer sj.Unlock()
+	if sj.flattenRunning {
+		return errors.Errorf("flatten is already running")
+	}
+	sj.flattenRunning = true
+	return nil
+}
+
+// flatten flattens db, which beginFlatten must have marked as running.
+func (sj *storageJobs) flatten(db *badger.DB, workers int) {
+	glog.Infof("Flattening postings directory with %d workers", workers)
+	if err := db.Flatten(workers); err != nil {
+		glog.Errorf("Error while flattening postings directory: %v", err)
+	} else {
+		glog.Infof("Flatten done.")
+	}
+
+	sj.Lock()
+	defer sj.Unlock()
+	sj.flattenRunning = false
+	sj.lastFlatten = time.Now()
+}
+
+// StartListRewrite rewrites in the background the multi-part posting lists on this node which
+// have at least minParts parts. The lists are split again from scratch, so that the parts of the
+// lists which grew and shrank over time are balanced again.
+func StartListRewrite(minParts int) error {
+	if minParts <= 0 {
+		return errors.Errorf("minimum number of parts must be positive")
+	}
+	sj.Lock()
+	defer sj.Unlock()
+	if sj.rewriteRunning {
+		return errors.Errorf("rewrite of multi-part lists is already running")
+	}
+	sj.rewriteRunning = true
+
+	go func() {
+		glog.Infof("Rewriting the posting lists with at least %d parts", minParts)
+		rewrites, err := rewriteSplitLists(context.Background(), minParts)
+		if err != nil {
+			glog.Errorf("Error while rewriting the multi-part lists: %v", err)
+		}
+		glog.Infof("Rewrite of the multi-part lists done. Rewrote %d lists.", rewrites)
+
+		sj.Lock()
+		defer sj.Unlock()
+		sj.rewriteRunning = false
+		sj.lastRewrite = time.Now()
+		sj.lastRewrites = rewrites
+	}()
+	return nil
+}
+
+// rewriteSplitLists rewrites the multi-part lists with at least minParts parts, and returns the
+// number of lists it rewrote. The lists are rewritten at a new timestamp, which isn't the commit
+// timestamp of any transaction.
+func rewriteSplitLists(ctx context.Context, minParts int) (int, error) {
+	ids, err := Timestamps(ctx, &pb.Num{Val: 1})
+	if err != nil {
+		return 0, errors.Wrapf(err, "while getting a timestamp")
+	}
+	readTs := ids.StartId
+	if err := posting.Oracle().WaitForTs(ctx, readTs); err != nil {
+		return 0, err
+	}
+	keys, err := splitLists(readTs, minParts)
+	if err != nil {
+		return 0, err
+	}
+
+	writer := posting.NewTxnWriter(pstore)
+	var rewrites int
+	for _, key := range keys {
+		l, err := posting.GetNoStore(key, readTs)
+		if err != nil {
+			return rewrites, err
+		}
+		kvs, err := l.Rewrite(readTs, nil)
+		if err != nil {
+			return rewrites, err
+		}
+		if err := writer.Write(&bpb.KVList{Kv: kvs}); err != nil {
+			return rewrites, err
+		}
+		posting.RemoveCacheFor(key)
+		ostats.Record(ctx, x.NumListRewrites.M(1))
+		rewrites++
+	}
+	return rewrites, writer.Flush()
+}
+
+// splitLists returns the keys of the multi-part lists with at least minParts parts at readTs.
+func splitLists(readTs uint64, minParts int) ([][]byte, error) {
+	txn := pstore.NewTransactionAt(readTs, false)
+	defer txn.Discard()
+	iopt := badger.DefaultIteratorOptions
+	iopt.AllVersions = true
+	iopt.PrefetchValues = false
+	iopt.Prefix = []byte{x.DefaultPrefix}
+	it := txn.NewIterator(iopt)
+	defer it.Close()
+
+	var keys [][]byte
+	var lastKey []byte
+	for it.Rewind(); it.Valid(); it.Next() {
+		item := it.Item()
+		// Only the newest complete version of a key tells how many parts the list has.
+		if bytes.Equal(item.Key(), lastKey) {
+			continue
+		}
+		if item.IsDeletedOrExpired() || item.UserMeta()&posting.BitEmptyPosting > 0 {
+			lastKey = item.KeyCopy(lastKey)
+			continue
+		}
+		if item.UserMeta()&posting.BitCompletePosting == 0 {
+			continue
+		}
+		lastKey = item.KeyCopy(lastKey)
+
+		var plist pb.PostingList
+		if err := item.Value(func(val []byte) error { return plist.Unmarshal(val) }); err != nil {
+			return nil, err
+		}
+		if len(plist.Splits) >= minParts {
+			keys = append(keys, item.KeyCopy(nil))
+		}
+	}
+	return keys, nil
+}
+
+// SetRollupDelay throttles the background rollups of posting lists by waiting for d after
+// every batch. Rollups rewrite the posting lists, so they drive most of the compactions.
+func SetRollupDelay(d time.Duration) error {
+	if d < 0 {
+		return errors.Errorf("rollup delay must be non-negative")
+	}
+	glog.Infof("Setting rollup delay to %s", d)
+	posting.IncrRollup.SetDelay(d)
+	return nil
+}
+
+// GetStorageStatus returns the state of the postings directory.
+func GetStorageStatus() *StorageStatus {
+	st := &StorageStatus{
+		LsmStaleBytes: x.LSMStaleBytes(pstore),
+		VlogGCPaused:  x.VlogGCPaused(),
+		RollupDelay:   posting.IncrRollup.Delay(),
+	}
+	st.LsmSize, st.VlogSize = pstore.Size()
+	for _, l := range pstore.Levels() {
+		st.Levels = append(st.Levels, LevelStatus{
+			Level:      l.Level,
+			NumTables:  l.NumTables,
+			Size:       l.Size,
+			TargetSize: l.TargetSize,
+			StaleBytes: l.StaleDatSize,
+			Score:      l.Score,
+		})
+	}
+
+	sj.Lock()
+	defer sj.Unlock()
+	st.VlogGCRunning = sj.vlogGCRunning
+	st.LastVlogGC = sj.lastVlogGC
+	st.LastVlogGCRewrites = sj.lastVlogGCRewrites
+	st.FlattenRunning = sj.flattenRunning
+	st.LastFlatten = sj.lastFlatten
+	st.ListRewriteRunning = sj.rewriteRunning
+	st.LastListRewrite = sj.lastRewrite
+	st.LastListRewrites = sj.lastRewrites
+	return st
+}
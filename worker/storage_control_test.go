@@ -0,0 +1,113 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageControlArgs(t *testing.T) {
+	for _, ratio := range []float64{-1, 0, 1, 1.5} {
+		require.Error(t, StartVlogGC(ratio), "discard ratio %v", ratio)
+	}
+	require.Error(t, StartFlatten(0))
+	require.Error(t, StartFlatten(-2))
+	require.Error(t, StartListRewrite(0))
+	require.Error(t, StartListRewrite(-1))
+	require.Error(t, SetRollupDelay(-time.Millisecond))
+
+	// Nothing was started by the invalid requests.
+	st := GetStorageStatus()
+	require.False(t, st.VlogGCRunning)
+	require.False(t, st.FlattenRunning)
+	require.False(t, st.ListRewriteRunning)
+}
+
+func TestStorageControlState(t *testing.T) {
+	waitFor := func(done func(st *StorageStatus) bool) *StorageStatus {
+		for i := 0; i < 100; i++ {
+			if st := GetStorageStatus(); done(st) {
+				return st
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Fatalf("storage job didn't finish")
+		return nil
+	}
+
+	require.NoError(t, SetRollupDelay(25*time.Millisecond))
+	require.Equal(t, 25*time.Millisecond, GetStorageStatus().RollupDelay)
+	require.NoError(t, SetRollupDelay(0))
+	require.Equal(t, time.Duration(0), GetStorageStatus().RollupDelay)
+
+	x.PauseVlogGC(true)
+	require.True(t, GetStorageStatus().VlogGCPaused)
+	x.PauseVlogGC(false)
+	require.False(t, GetStorageStatus().VlogGCPaused)
+
+	// Value log GC doesn't run while it's paused, nor during a snapshot.
+	sj.Lock()
+	sj.snapshotRunning = true
+	sj.Unlock()
+	require.Error(t, StartVlogGC(0.5))
+	sj.Lock()
+	sj.snapshotRunning = false
+	sj.Unlock()
+	x.PauseVlogGC(true)
+	require.Error(t, StartVlogGC(0.5))
+	x.PauseVlogGC(false)
+
+	require.NoError(t, StartVlogGC(0.5))
+	st := waitFor(func(st *StorageStatus) bool { return !st.VlogGCRunning })
+	require.False(t, st.LastVlogGC.IsZero())
+
+	require.NoError(t, StartFlatten(2))
+	st = waitFor(func(st *StorageStatus) bool { return !st.FlattenRunning })
+	require.False(t, st.LastFlatten.IsZero())
+
+	// A job can't be started again while it's running.
+	sj.Lock()
+	sj.vlogGCRunning, sj.flattenRunning, sj.rewriteRunning = true, true, true
+	sj.Unlock()
+	require.Error(t, StartVlogGC(0.5))
+	require.Error(t, StartFlatten(1))
+	require.Error(t, StartListRewrite(2))
+	sj.Lock()
+	sj.vlogGCRunning, sj.flattenRunning, sj.rewriteRunning = false, false, false
+	sj.Unlock()
+
+	// The compaction of the disk monitor skips the jobs which are paused or already running.
+	st = GetStorageStatus()
+	x.PauseVlogGC(true)
+	sj.Lock()
+	sj.flattenRunning = true
+	sj.Unlock()
+	m := &diskMonitor{db: pstore}
+	m.maybeCompact()
+	require.False(t, m.usage.LastCompaction.IsZero())
+	after := GetStorageStatus()
+	require.Equal(t, st.LastVlogGC, after.LastVlogGC)
+	require.Equal(t, st.LastFlatten, after.LastFlatten)
+	x.PauseVlogGC(false)
+	sj.Lock()
+	sj.flattenRunning = false
+	sj.Unlock()
+}
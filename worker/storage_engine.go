@@ -0,0 +1,46 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultStorageEngine keeps the postings and the WAL in Badger, on local disk.
+	DefaultStorageEngine = "badger"
+	// MemoryStorageEngine keeps the postings and the WAL in an in-memory Badger. All the data is
+	// lost when Alpha stops.
+	MemoryStorageEngine = "memory"
+)
+
+// StorageEngines returns the names accepted by --storage_engine. Both keep the data in Badger,
+// since the posting and worker packages use its transactions, iterators and streams throughout.
+func StorageEngines() []string {
+	return []string{DefaultStorageEngine, MemoryStorageEngine}
+}
+
+func checkStorageEngine(name string) error {
+	switch name {
+	case DefaultStorageEngine, MemoryStorageEngine:
+		return nil
+	}
+	return errors.Errorf("unknown storage engine %q, the engines are: %s", name,
+		strings.Join(StorageEngines(), ", "))
+}
@@ -36,7 +36,9 @@ type stringFilter struct {
 	match     matchFunc
 	ineqValue types.Val
 	eqVals    []types.Val
+	eqSet     eqSet
 	tokName   string
+	dict      *tok.Dictionary
 }
 
 func matchStrings(uids *pb.List, values [][]types.Val, filter *stringFilter) *pb.List {
@@ -86,12 +88,7 @@ func defaultMatch(value types.Val, filter *stringFilter) bool {
 
 func ineqMatch(value types.Val, filter *stringFilter) bool {
 	if filter.funcName == eq {
-		for _, v := range filter.eqVals {
-			if types.CompareVals(filter.funcName, value, v) {
-				return true
-			}
-		}
-		return false
+		return matchEq(filter.eqSet, filter.eqVals, value)
 	} else if filter.funcName == between {
 		return types.CompareVals("ge", value, filter.eqVals[0]) &&
 			types.CompareVals("le", value, filter.eqVals[1])
@@ -105,7 +102,8 @@ func tokenizeValue(value types.Val, filter *stringFilter) []string {
 	// tokenizer was used in previous stages of query processing, it has to be available
 	x.AssertTrue(found)
 
-	tokens, err := tok.BuildTokens(value.Value, tok.GetTokenizerForLang(tokenizer, filter.lang))
+	tokens, err := tok.BuildTokens(value.Value, tok.GetTokenizerForDictionary(
+		tok.GetTokenizerForLang(tokenizer, filter.lang), filter.dict))
 	if err != nil {
 		glog.Errorf("Error while building tokens: %s", err)
 		return []string{}
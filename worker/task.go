@@ -19,9 +19,11 @@ package worker
 import (
 	"bytes"
 	"context"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/dgraph-io/badger/v3"
@@ -68,7 +70,13 @@ func processWithBackupRequest(
 	ctx context.Context,
 	gid uint32,
 	f func(context.Context, pb.WorkerClient) (interface{}, error)) (interface{}, error) {
-	addrs := groups().AnyTwoServers(gid)
+	return processWithBackupRequestTo(ctx, groups().AnyTwoServers(gid), f)
+}
+
+func processWithBackupRequestTo(
+	ctx context.Context,
+	addrs []string,
+	f func(context.Context, pb.WorkerClient) (interface{}, error)) (interface{}, error) {
 	if len(addrs) == 0 {
 		return nil, errors.New("No network connection")
 	}
@@ -146,11 +154,25 @@ func ProcessTaskOverNetwork(ctx context.Context, q *pb.Query) (*pb.Result, error
 	}
 
 	if groups().ServesGroup(gid) {
+		if reply, ok := offloadHotRead(ctx, q, gid); ok {
+			recordServedTs(ctx, q.ReadTs, reply.ReadTs)
+			return reply, nil
+		}
 		// No need for a network call, as this should be run from within this instance.
-		return processTask(ctx, q, gid)
+		readTs := q.ReadTs
+		reply, err := processTask(ctx, q, gid)
+		if err == nil {
+			recordServedTs(ctx, readTs, reply.ReadTs)
+		}
+		return reply, err
 	}
 
-	result, err := processWithBackupRequest(ctx, gid,
+	addrs := groups().AnyTwoServers(gid)
+	if q.MaxLag > 0 {
+		// Spread best effort queries which can be served by lagging replicas over the followers.
+		addrs = groups().AnyTwoFollowers(gid)
+	}
+	result, err := processWithBackupRequestTo(ctx, addrs,
 		func(ctx context.Context, c pb.WorkerClient) (interface{}, error) {
 			return c.ServeTask(ctx, q)
 		})
@@ -159,6 +181,7 @@ func ProcessTaskOverNetwork(ctx context.Context, q *pb.Query) (*pb.Result, error
 	}
 
 	reply := result.(*pb.Result)
+	recordServedTs(ctx, q.ReadTs, reply.ReadTs)
 	if span != nil {
 		span.Annotatef(nil, "Reply from server. len: %v gid: %v Attr: %v",
 			len(reply.UidMatrix), gid, attr)
@@ -166,6 +189,31 @@ func ProcessTaskOverNetwork(ctx context.Context, q *pb.Query) (*pb.Result, error
 	return reply, nil
 }
 
+type servedTsKey struct{}
+
+// WithServedTs returns a context which tracks the oldest timestamp that the tasks of a best effort
+// query were served at by lagging replicas, along with a function returning it. The function
+// returns zero if all the tasks were served at the timestamp they asked for.
+func WithServedTs(ctx context.Context) (context.Context, func() uint64) {
+	var minTs uint64
+	return context.WithValue(ctx, servedTsKey{}, &minTs), func() uint64 {
+		return atomic.LoadUint64(&minTs)
+	}
+}
+
+func recordServedTs(ctx context.Context, readTs, servedTs uint64) {
+	minTs, ok := ctx.Value(servedTsKey{}).(*uint64)
+	if !ok || servedTs == 0 || servedTs >= readTs {
+		return
+	}
+	for {
+		cur := atomic.LoadUint64(minTs)
+		if (cur != 0 && cur <= servedTs) || atomic.CompareAndSwapUint64(minTs, cur, servedTs) {
+			return
+		}
+	}
+}
+
 // convertValue converts the data to the schema.State() type of predicate.
 func convertValue(attr, data string) (types.Val, error) {
 	// Parse given value and get token. There should be only one token.
@@ -221,6 +269,8 @@ const (
 	uidInFn
 	customIndexFn
 	matchFn
+	relevanceFn
+	highlightFn
 	standardFn = 100
 )
 
@@ -261,8 +311,12 @@ func parseFuncTypeHelper(name string) (FuncType, string) {
 		return uidInFn, f
 	case "anyof", "allof":
 		return customIndexFn, f
-	case "match":
+	case "match", "fuzzy":
 		return matchFn, f
+	case "relevance":
+		return relevanceFn, f
+	case "highlight":
+		return highlightFn, f
 	default:
 		if types.IsGeoFunc(f) {
 			return geoFn, f
@@ -305,7 +359,7 @@ type funcArgs struct {
 // The function tells us whether we want to fetch value posting lists or uid posting lists.
 func (srcFn *functionContext) needsValuePostings(typ types.TypeID) (bool, error) {
 	switch srcFn.fnType {
-	case aggregatorFn, passwordFn:
+	case aggregatorFn, passwordFn, relevanceFn, highlightFn:
 		return true, nil
 	case compareAttrFn:
 		if len(srcFn.tokens) > 0 {
@@ -342,7 +396,7 @@ func (qs *queryState) handleValuePostings(ctx context.Context, args funcArgs) er
 	}
 
 	switch srcFn.fnType {
-	case notAFunction, aggregatorFn, passwordFn, compareAttrFn:
+	case notAFunction, aggregatorFn, passwordFn, compareAttrFn, relevanceFn, highlightFn:
 	default:
 		return errors.Errorf("Unhandled function in handleValuePostings: %s", srcFn.fname)
 	}
@@ -355,6 +409,12 @@ func (qs *queryState) handleValuePostings(ctx context.Context, args funcArgs) er
 		return errors.Errorf("checkpwd fn can only be used on attr: [%s] with schema type "+
 			"password. Got type: %s", x.ParseAttr(q.Attr), types.TypeID(srcFn.atype).Name())
 	}
+	if (srcFn.fnType == relevanceFn || srcFn.fnType == highlightFn) &&
+		srcFn.atype != types.StringID {
+		return errors.Errorf("%s fn can only be used on attr: [%s] with schema type "+
+			"string. Got type: %s", srcFn.fname, x.ParseAttr(q.Attr),
+			types.TypeID(srcFn.atype).Name())
+	}
 	if srcFn.n == 0 {
 		return nil
 	}
@@ -454,11 +514,8 @@ func (qs *queryState) handleValuePostings(ctx context.Context, args funcArgs) er
 					}
 					switch srcFn.fname {
 					case "eq":
-						for _, eqToken := range srcFn.eqTokens {
-							if types.CompareVals(srcFn.fname, val, eqToken) {
-								uidList.Uids = append(uidList.Uids, q.UidList.Uids[i])
-								break
-							}
+						if matchEq(srcFn.eqSet, srcFn.eqTokens, val) {
+							uidList.Uids = append(uidList.Uids, q.UidList.Uids[i])
 						}
 					case "between":
 						if types.CompareBetween(val, srcFn.eqTokens[0], srcFn.eqTokens[1]) {
@@ -480,7 +537,8 @@ func (qs *queryState) handleValuePostings(ctx context.Context, args funcArgs) er
 			out.FacetMatrix = append(out.FacetMatrix, fcs)
 
 			switch {
-			case srcFn.fnType == aggregatorFn:
+			case srcFn.fnType == aggregatorFn || srcFn.fnType == relevanceFn ||
+				srcFn.fnType == highlightFn:
 				// Add an empty UID list to make later processing consistent
 				out.UidMatrix = append(out.UidMatrix, &pb.List{})
 			case srcFn.fnType == passwordFn:
@@ -532,6 +590,12 @@ func (qs *queryState) handleValuePostings(ctx context.Context, args funcArgs) er
 		out.FacetMatrix = append(out.FacetMatrix, chunk.FacetMatrix...)
 		out.LangMatrix = append(out.LangMatrix, chunk.LangMatrix...)
 	}
+	switch srcFn.fnType {
+	case relevanceFn:
+		return scoreRelevance(ctx, args)
+	case highlightFn:
+		highlightMatches(ctx, args)
+	}
 	return nil
 }
 
@@ -883,11 +947,19 @@ func processTask(ctx context.Context, q *pb.Query, gid uint32) (*pb.Result, erro
 	stop := x.SpanTimer(span, "processTask"+q.Attr)
 	defer stop()
 
+	if maxAssigned := posting.Oracle().MaxAssigned(); q.MaxLag > 0 && maxAssigned < q.ReadTs &&
+		q.ReadTs-maxAssigned <= q.MaxLag {
+		// This replica is lagging, but not more than allowed. Serve the best effort query at the
+		// state it has, instead of waiting for it to catch up.
+		span.Annotatef(nil, "Serving best effort query at %d instead of %d", maxAssigned, q.ReadTs)
+		q.ReadTs = maxAssigned
+	}
 	span.Annotatef(nil, "Waiting for startTs: %d at node: %d, gid: %d",
 		q.ReadTs, groups().Node.Id, gid)
 	if err := posting.Oracle().WaitForTs(ctx, q.ReadTs); err != nil {
 		return nil, err
 	}
+	tiered.touch(q.Attr)
 	if span != nil {
 		maxAssigned := posting.Oracle().MaxAssigned()
 		span.Annotatef(nil, "Done waiting for maxAssigned. Attr: %q ReadTs: %d Max: %d",
@@ -927,6 +999,7 @@ func processTask(ctx context.Context, q *pb.Query, gid uint32) (*pb.Result, erro
 	if err != nil {
 		return nil, err
 	}
+	out.ReadTs = q.ReadTs
 	return out, nil
 }
 
@@ -1388,6 +1461,13 @@ func (qs *queryState) handleMatchFunction(ctx context.Context, arg funcArgs) err
 	case arg.q.UidList != nil && len(arg.q.UidList.Uids) != 0:
 		uids = arg.q.UidList
 
+	case schema.State().HasTokenizer(ctx, tok.IdentTrigram, attr) && arg.srcFn.fname == "fuzzy":
+		var err error
+		uids, err = qs.uidsForFuzzy(ctx, arg)
+		if err != nil {
+			return err
+		}
+
 	case schema.State().HasTokenizer(ctx, tok.IdentTrigram, attr):
 		var err error
 		uids, err = uidsForMatch(attr, arg)
@@ -1582,6 +1662,7 @@ func (qs *queryState) filterStringFunction(arg funcArgs) error {
 		filter.tokens = arg.srcFn.tokens
 		filter.match = defaultMatch
 		filter.tokName = "fulltext"
+		filter.dict = schema.State().FulltextDictionary(context.Background(), attr)
 		filtered = matchStrings(filtered, values, &filter)
 	case standardFn:
 		filter.tokens = arg.srcFn.tokens
@@ -1596,6 +1677,7 @@ func (qs *queryState) filterStringFunction(arg funcArgs) error {
 	case compareAttrFn:
 		// filter.ineqValue = arg.srcFn.ineqValue
 		filter.eqVals = arg.srcFn.eqTokens
+		filter.eqSet = arg.srcFn.eqSet
 		filter.match = ineqMatch
 		filtered = matchStrings(filtered, values, &filter)
 	}
@@ -1645,7 +1727,9 @@ type functionContext struct {
 	// other compareAttr functions.
 	// TODO(@Animesh): change field names which could explain their uses better. Check if we
 	// really need all of ineqValue, eqTokens, tokens
-	eqTokens       []types.Val
+	eqTokens []types.Val
+	// eqSet holds eqTokens of an eq function for faster lookups, when their type allows.
+	eqSet          eqSet
 	ineqValueToken []string
 	n              int
 	threshold      []int64
@@ -1779,17 +1863,20 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 			fc.tokens = append(fc.tokens, tokens...)
 		}
 
+		if fc.fname == eq {
+			fc.eqSet = newEqSet(fc.eqTokens)
+		}
+
 		// In case of non-indexed predicate, there won't be any tokens. We will fetch value
 		// from data keys.
 		// If number of index keys is more than no. of uids to filter, so its better to fetch values
-		// from data keys directly and compare. Lets make tokens empty.
-		// We don't do this for eq because eq could have multiple arguments and we would have to
-		// compare the value with all of them. Also eq would usually have less arguments, hence we
-		// won't be fetching many index keys.
+		// from data keys directly and compare. Lets make tokens empty. For eq with many
+		// arguments, the values are matched against eqSet rather than all of the arguments.
 		switch {
 		case q.UidList != nil && !isIndexedAttr:
 			fc.n = len(q.UidList.Uids)
-		case q.UidList != nil && len(fc.tokens) > len(q.UidList.Uids) && fc.fname != eq:
+		case q.UidList != nil && len(fc.tokens) > len(q.UidList.Uids) &&
+			(fc.fname != eq || fc.eqSet != nil):
 			fc.tokens = fc.tokens[:0]
 			fc.n = len(q.UidList.Uids)
 		default:
@@ -1827,6 +1914,17 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 			return nil, err
 		}
 		fc.n = len(q.UidList.Uids)
+	case relevanceFn:
+		if err = ensureArgsCount(q.SrcFunc, 1); err != nil {
+			return nil, err
+		}
+		fc.n = len(q.UidList.Uids)
+	case highlightFn:
+		if n := len(q.SrcFunc.Args); n != 1 && n != 3 {
+			return nil, errors.Errorf("Function '%s' requires 1 or 3 arguments, but got %d (%v)",
+				q.SrcFunc.Name, n, q.SrcFunc.Args)
+		}
+		fc.n = len(q.UidList.Uids)
 	case standardFn, fullTextSearchFn:
 		// srcfunc 0th val is func name and and [2:] are args.
 		// we tokenize the arguments of the query.
@@ -1838,7 +1936,9 @@ func parseSrcFn(ctx context.Context, q *pb.Query) (*functionContext, error) {
 			return nil, errors.Errorf("Attribute %s is not indexed with type %s", x.ParseAttr(attr),
 				required)
 		}
-		if fc.tokens, err = getStringTokens(q.SrcFunc.Args, langForFunc(q.Langs), fnType); err != nil {
+		fc.tokens, err = getStringTokens(q.SrcFunc.Args, langForFunc(q.Langs), fnType,
+			schema.State().FulltextDictionary(ctx, attr))
+		if err != nil {
 			return nil, err
 		}
 		fc.intersectDest = needsIntersect(f)
@@ -2265,7 +2365,11 @@ func (qs *queryState) evaluate(cp countParams, out *pb.Result) error {
 	x.AssertTrue(countl >= 1)
 	countKey = x.CountKey(cp.attr, uint32(countl), cp.reverse)
 
-	txn := pstore.NewTransactionAt(cp.readTs, false)
+	db, err := posting.ReadStore(cp.attr)
+	if err != nil {
+		return err
+	}
+	txn := db.NewTransactionAt(cp.readTs, false)
 	defer txn.Discard()
 
 	pk := x.ParsedKey{Attr: cp.attr}
@@ -2302,6 +2406,97 @@ func (qs *queryState) evaluate(cp countParams, out *pb.Result) error {
 	return nil
 }
 
+// estimateHasTotal estimates the number of UIDs having a predicate from the UIDs found among its
+// first keys, assuming that the UIDs are spread evenly up to its last key.
+func estimateHasTotal(txn *badger.Txn, prefix, endKey []byte, afterUid uint64,
+	found []uint64) (uint64, error) {
+	itOpt := badger.DefaultIteratorOptions
+	itOpt.PrefetchValues = false
+	itOpt.Reverse = true
+	itOpt.Prefix = prefix
+	it := txn.NewIterator(itOpt)
+	defer it.Close()
+
+	it.Seek(endKey)
+	if !it.Valid() || len(found) == 0 {
+		return uint64(len(found)), nil
+	}
+	pk, err := x.Parse(it.Item().Key())
+	if err != nil {
+		return 0, err
+	}
+	lastUid := found[len(found)-1]
+	if pk.Uid <= lastUid {
+		return uint64(len(found)), nil
+	}
+	density := float64(len(found)) / float64(lastUid-afterUid)
+	return uint64(len(found)) + uint64(density*float64(pk.Uid-lastUid)), nil
+}
+
+// handleHasWithPresence answers has() from the presence index of the predicate, so it only
+// reads the UIDs having a value instead of iterating over all the keys of the predicate.
+func (qs *queryState) handleHasWithPresence(ctx context.Context, q *pb.Query, out *pb.Result,
+	srcFn *functionContext) error {
+	span := otrace.FromContext(ctx)
+	pl, err := qs.cache.Get(posting.PresenceKey(q.Attr))
+	if err != nil {
+		return err
+	}
+
+	opts := posting.ListOptions{ReadTs: q.ReadTs, AfterUid: q.AfterUid}
+	if !needsStringFiltering(srcFn, q.Langs, q.Attr) {
+		opts.First = int(q.First)
+		uids, err := pl.Uids(opts)
+		if err != nil {
+			return err
+		}
+		if q.EstimateTotal && len(uids.Uids) >= int(q.First) {
+			if n := pl.Length(q.ReadTs, q.AfterUid); n > 0 {
+				out.EstimatedTotal = uint64(n)
+			}
+		}
+		if span != nil {
+			span.Annotatef(nil, "handleHasWithPresence found %d uids", len(uids.Uids))
+		}
+		out.UidMatrix = append(out.UidMatrix, uids)
+		return nil
+	}
+
+	// Only keep the UIDs having a value in the asked languages, e.g. for has(name@en).
+	lang := langForFunc(q.Langs)
+	result := &pb.List{}
+	err = pl.Iterate(q.ReadTs, q.AfterUid, func(p *pb.Posting) error {
+		_, err := qs.getValsForUID(q.Attr, lang, p.Uid, q.ReadTs)
+		switch {
+		case err == posting.ErrNoValue:
+			return nil
+		case err != nil:
+			return err
+		}
+		result.Uids = append(result.Uids, p.Uid)
+
+		// We'll stop fetching if we fetch the required count.
+		if len(result.Uids) >= int(q.First) {
+			return posting.ErrStopIteration
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if q.EstimateTotal && len(result.Uids) >= int(q.First) {
+		// Some of the remaining UIDs might not have a value in the asked languages.
+		if n := pl.Length(q.ReadTs, q.AfterUid); n > 0 {
+			out.EstimatedTotal = uint64(n)
+		}
+	}
+	if span != nil {
+		span.Annotatef(nil, "handleHasWithPresence found %d uids", len(result.Uids))
+	}
+	out.UidMatrix = append(out.UidMatrix, result)
+	return nil
+}
+
 func (qs *queryState) handleHasFunction(ctx context.Context, q *pb.Query, out *pb.Result,
 	srcFn *functionContext) error {
 	span := otrace.FromContext(ctx)
@@ -2310,19 +2505,28 @@ func (qs *queryState) handleHasFunction(ctx context.Context, q *pb.Query, out *p
 	if glog.V(3) {
 		glog.Infof("handleHasFunction query: %+v\n", q)
 	}
+	if !q.Reverse && schema.State().HasPresence(ctx, q.Attr) {
+		return qs.handleHasWithPresence(ctx, q, out, srcFn)
+	}
 
-	txn := pstore.NewTransactionAt(q.ReadTs, false)
+	db, err := posting.ReadStore(q.Attr)
+	if err != nil {
+		return err
+	}
+	txn := db.NewTransactionAt(q.ReadTs, false)
 	defer txn.Discard()
 
 	initKey := x.ParsedKey{
 		Attr: q.Attr,
 	}
 	startKey := x.DataKey(q.Attr, q.AfterUid+1)
+	endKey := x.DataKey(q.Attr, math.MaxUint64)
 	prefix := initKey.DataPrefix()
 	if q.Reverse {
 		// Reverse does not mean reverse iteration. It means we're looking for
 		// the reverse index.
 		startKey = x.ReverseKey(q.Attr, q.AfterUid+1)
+		endKey = x.ReverseKey(q.Attr, math.MaxUint64)
 		prefix = initKey.ReversePrefix()
 	}
 
@@ -2350,6 +2554,8 @@ func (qs *queryState) handleHasFunction(ctx context.Context, q *pb.Query, out *p
 		return err
 	}
 
+	// stopped is set if the iteration stopped after finding the first q.First UIDs.
+	var stopped bool
 loop:
 	// This function could be switched to the stream.Lists framework, but after the change to use
 	// BitCompletePosting, the speed here is already pretty fast. The slowdown for @lang predicates
@@ -2395,6 +2601,7 @@ loop:
 
 			// We'll stop fetching if we fetch the required count.
 			if len(result.Uids) >= int(q.First) {
+				stopped = true
 				break
 			}
 			continue
@@ -2421,6 +2628,7 @@ loop:
 
 			// We'll stop fetching if we fetch the required count.
 			if len(result.Uids) >= int(q.First) {
+				stopped = true
 				break loop
 			}
 		}
@@ -2433,6 +2641,13 @@ loop:
 			}
 		}
 	}
+	if stopped && q.EstimateTotal {
+		total, err := estimateHasTotal(txn, prefix, endKey, q.AfterUid, result.Uids)
+		if err != nil {
+			return err
+		}
+		out.EstimatedTotal = total
+	}
 	if span != nil {
 		span.Annotatef(nil, "handleHasFunction found %d uids", len(result.Uids))
 	}
@@ -0,0 +1,960 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	bpb "github.com/dgraph-io/badger/v3/pb"
+	"github.com/dgraph-io/dgo/v200/protos/api"
+	"github.com/dgraph-io/dgraph/conn"
+	"github.com/dgraph-io/dgraph/ee/enc"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/minio/minio-go/v6"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TieredStorageDefaults are the default values for the --tiered_storage superflag.
+	TieredStorageDefaults = "dest=; cache-dir=cold; cache-mb=10240; cold-after=0s; " +
+		"check-every=1h"
+
+	// coldCacheMinIdle is how long the local copy of a cold predicate must have been unused before
+	// it can be evicted, so that it isn't closed while being read.
+	coldCacheMinIdle = 10 * time.Minute
+	// coldCacheMarker is the file written in the directory of a local copy once it's complete.
+	// It holds the name of the object copied.
+	coldCacheMarker = "COMPLETE"
+	// maxTierProposalSize is the max size of the KVs proposed at once while promoting a
+	// predicate.
+	maxTierProposalSize = 32 << 20
+)
+
+// objectStore holds the data of the cold predicates.
+type objectStore interface {
+	Put(name string, r io.Reader) error
+	Get(name string) (io.ReadCloser, error)
+}
+
+// fileObjectStore keeps the objects in a directory, e.g. one on NFS.
+type fileObjectStore struct {
+	dir string
+}
+
+func (s *fileObjectStore) Put(name string, r io.Reader) error {
+	p := filepath.Join(s.dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(p), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+func (s *fileObjectStore) Get(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.dir, name))
+}
+
+// minioObjectStore keeps the objects in S3, minio or any other S3 compatible object storage.
+type minioObjectStore struct {
+	mc     *x.MinioClient
+	bucket string
+	prefix string
+}
+
+func (s *minioObjectStore) Put(name string, r io.Reader) error {
+	_, err := s.mc.PutObject(s.bucket, path.Join(s.prefix, name), r, -1, minio.PutObjectOptions{})
+	return err
+}
+
+func (s *minioObjectStore) Get(name string) (io.ReadCloser, error) {
+	return s.mc.GetObject(s.bucket, path.Join(s.prefix, name), minio.GetObjectOptions{})
+}
+
+// newObjectStore returns the object store at dest. Its URI takes the same forms as the
+// destination of backups. GCS is accessed via its S3 compatible API, e.g. with gs:///bucket/path,
+// and the MINIO_ACCESS_KEY and MINIO_SECRET_KEY environment variables set to an HMAC key.
+func newObjectStore(dest string) (objectStore, error) {
+	uri, err := url.Parse(dest)
+	if err != nil {
+		return nil, err
+	}
+	switch uri.Scheme {
+	case "file", "":
+		return &fileObjectStore{dir: uri.Path}, nil
+	case "gs":
+		// gs:///bucket/path or gs://bucket/path
+		uri.Scheme = "minio"
+		if uri.Host != "" {
+			uri.Path = "/" + uri.Host + uri.Path
+		}
+		uri.Host = "storage.googleapis.com"
+	case "s3", "minio":
+	default:
+		return nil, errors.Errorf("Unsupported destination: %s", dest)
+	}
+	mc, err := x.NewMinioClient(uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	bucket, prefix, err := mc.ValidateBucket(uri)
+	if err != nil {
+		return nil, err
+	}
+	return &minioObjectStore{mc: mc, bucket: bucket, prefix: prefix}, nil
+}
+
+// tieredStorage moves the data of cold predicates to object storage, so that it doesn't take
+// space on the local disks. The queries read a cold predicate from a local copy of its data,
+// which is fetched on demand and evicted when the copies take more than the cache size.
+//
+// A predicate is cold once its schema has a ColdTablet with an object, and cold predicates are
+// read-only: mutations and schema changes to them are refused until they are promoted back.
+// Predicates are demoted via the admin API, or automatically once no query served by this node
+// read them for the cold-after duration. Every node of the group must have the same destination.
+// Cold predicates can't be moved to another group, and backups and exports stream their local
+// copies along with pstore.
+type tieredStorage struct {
+	store      objectStore
+	cacheDir   string
+	cacheSize  int64
+	coldAfter  time.Duration
+	checkEvery time.Duration
+	started    time.Time
+
+	sync.Mutex
+	// caches holds the local copies of the cold predicates, by object name.
+	caches map[string]*coldCache
+	// lastRead holds when each predicate was last read by a query served by this node, as a
+	// *int64 of Unix nanoseconds.
+	lastRead sync.Map
+}
+
+type coldCache struct {
+	object string
+	dir    string
+	db     *badger.DB
+	size   int64
+	// lastUsed is the Unix time in nanoseconds the copy was last used.
+	lastUsed int64
+	// pins is the number of backups and exports streaming the copy. It isn't closed meanwhile.
+	pins int32
+	// ready is closed once the copy is fetched, or fetching it failed with err.
+	ready chan struct{}
+	err   error
+}
+
+// tiered is the tiered storage set up via the --tiered_storage flag, or nil.
+var tiered *tieredStorage
+
+func parseTieredStorage(sf *z.SuperFlag) (*tieredStorage, error) {
+	dest := sf.GetString("dest")
+	if dest == "" {
+		return nil, nil
+	}
+	t := &tieredStorage{
+		cacheDir:  sf.GetString("cache-dir"),
+		cacheSize: sf.GetInt64("cache-mb") << 20,
+		started:   time.Now(),
+		caches:    make(map[string]*coldCache),
+	}
+	var err error
+	if t.coldAfter, err = time.ParseDuration(sf.GetString("cold-after")); err != nil {
+		return nil, errors.Wrapf(err, "while parsing cold-after")
+	}
+	if t.checkEvery, err = time.ParseDuration(sf.GetString("check-every")); err != nil {
+		return nil, errors.Wrapf(err, "while parsing check-every")
+	}
+	switch {
+	case t.cacheDir == "":
+		return nil, errors.Errorf("cache-dir must be set")
+	case t.cacheSize <= 0:
+		return nil, errors.Errorf("cache-mb must be positive")
+	case t.coldAfter < 0:
+		return nil, errors.Errorf("cold-after must be non-negative")
+	case t.checkEvery <= 0:
+		return nil, errors.Errorf("check-every must be positive")
+	}
+	if t.store, err = newObjectStore(dest); err != nil {
+		return nil, errors.Wrapf(err, "while opening %s", dest)
+	}
+	return t, nil
+}
+
+// touch records that the predicate was read.
+func (t *tieredStorage) touch(attr string) {
+	if t == nil {
+		return
+	}
+	now := time.Now().UnixNano()
+	if v, ok := t.lastRead.Load(attr); ok {
+		atomic.StoreInt64(v.(*int64), now)
+		return
+	}
+	t.lastRead.Store(attr, &now)
+}
+
+func (t *tieredStorage) readSince(attr string) time.Duration {
+	if v, ok := t.lastRead.Load(attr); ok {
+		return time.Since(time.Unix(0, atomic.LoadInt64(v.(*int64))))
+	}
+	return time.Since(t.started)
+}
+
+// objectName returns the name of the object holding the data of the predicate at readTs.
+func objectName(attr string, readTs uint64) string {
+	return fmt.Sprintf("%s/r%d.kv.gz", hex.EncodeToString([]byte(attr)), readTs)
+}
+
+func (t *tieredStorage) dirOf(object string) string {
+	h := sha256.Sum256([]byte(object))
+	return filepath.Join(t.cacheDir, hex.EncodeToString(h[:8]))
+}
+
+// writeObject writes the data of the predicate at readTs to the object. The data is written as
+// rolled-up posting lists at readTs, as KVs prefixed by their length, which are gzipped and
+// encrypted with the encryption key if any. It returns the size of the object.
+func (t *tieredStorage) writeObject(ctx context.Context, attr string, readTs uint64,
+	object string) (int64, error) {
+	pr, pw := io.Pipe()
+	cw := &countingWriter{w: pw}
+	putErr := make(chan error, 1)
+	go func() {
+		err := t.store.Put(object, pr)
+		pr.CloseWithError(err)
+		putErr <- err
+	}()
+
+	write := func() error {
+		ew, err := enc.GetWriter(x.WorkerConfig.EncryptionKey, cw)
+		if err != nil {
+			return err
+		}
+		gw := gzip.NewWriter(ew)
+		stream := pstore.NewStreamAt(readTs)
+		stream.LogPrefix = fmt.Sprintf("Demoting predicate: [%s]", x.ParseAttr(attr))
+		stream.Prefix = x.PredicatePrefix(attr)
+		stream.KeyToList = func(key []byte, itr *badger.Iterator) (*bpb.KVList, error) {
+			l, err := posting.ReadPostingList(key, itr)
+			if err != nil {
+				return nil, err
+			}
+			kvs, err := l.Rollup(itr.Alloc)
+			for _, kv := range kvs {
+				kv.Version = readTs
+			}
+			return &bpb.KVList{Kv: kvs}, err
+		}
+		var lenBuf [binary.MaxVarintLen64]byte
+		stream.Send = func(buf *z.Buffer) error {
+			return buf.SliceIterate(func(s []byte) error {
+				n := binary.PutUvarint(lenBuf[:], uint64(len(s)))
+				if _, err := gw.Write(lenBuf[:n]); err != nil {
+					return err
+				}
+				_, err := gw.Write(s)
+				return err
+			})
+		}
+		if err := stream.Orchestrate(ctx); err != nil {
+			return err
+		}
+		return gw.Close()
+	}
+	err := write()
+	pw.CloseWithError(err)
+	if perr := <-putErr; err == nil {
+		err = perr
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "while writing predicate %s to object storage",
+			x.ParseAttr(attr))
+	}
+	return cw.n, nil
+}
+
+// readObject calls fn for every KV of the object.
+func (t *tieredStorage) readObject(object string, fn func(kv *bpb.KV) error) error {
+	r, err := t.store.Get(object)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	dr, err := enc.GetReader(x.WorkerConfig.EncryptionKey, r)
+	if err != nil {
+		return err
+	}
+	gr, err := gzip.NewReader(dr)
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReader(gr)
+	for {
+		sz, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		buf := make([]byte, sz)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return err
+		}
+		kv := &bpb.KV{}
+		if err := kv.Unmarshal(buf); err != nil {
+			return err
+		}
+		if err := fn(kv); err != nil {
+			return err
+		}
+	}
+}
+
+func openColdCache(dir string) (*badger.DB, error) {
+	return badger.OpenManaged(badger.DefaultOptions(dir).
+		WithSyncWrites(false).
+		WithBlockCacheSize(64 << 20).
+		WithIndexCacheSize(64 << 20).
+		WithNumVersionsToKeep(math.MaxInt32).
+		WithEncryptionKey(x.WorkerConfig.EncryptionKey).
+		WithNamespaceOffset(x.NamespaceOffset).
+		WithLogger(nil))
+}
+
+// fetch copies the object to dir, unless it's already there, and opens the copy.
+func (t *tieredStorage) fetch(dir, object string) (*badger.DB, error) {
+	if marker, err := ioutil.ReadFile(filepath.Join(dir, coldCacheMarker)); err == nil &&
+		string(marker) == object {
+		return openColdCache(dir)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	glog.Infof("Fetching %s from object storage", object)
+	db, err := openColdCache(dir)
+	if err != nil {
+		return nil, err
+	}
+	loader := db.NewKVLoader(16)
+	err = t.readObject(object, loader.Set)
+	if ferr := loader.Finish(); err == nil {
+		err = ferr
+	}
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(dir, coldCacheMarker), []byte(object), 0600)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// open returns the local copy of the cold predicate, and fetches it if needed. It's the
+// posting.ColdStore of the tiered storage.
+func (t *tieredStorage) open(attr string, cold *pb.ColdTablet) (*badger.DB, error) {
+	t.Lock()
+	c, ok := t.caches[cold.Object]
+	if !ok {
+		c = &coldCache{object: cold.Object, dir: t.dirOf(cold.Object),
+			ready: make(chan struct{})}
+		t.caches[cold.Object] = c
+		t.Unlock()
+
+		c.db, c.err = t.fetch(c.dir, cold.Object)
+		if c.err != nil {
+			t.Lock()
+			delete(t.caches, cold.Object)
+			t.Unlock()
+		} else {
+			c.size = dirSize(c.dir)
+			atomic.StoreInt64(&c.lastUsed, time.Now().UnixNano())
+		}
+		close(c.ready)
+		t.evict()
+	} else {
+		t.Unlock()
+		<-c.ready
+	}
+	if c.err != nil {
+		return nil, errors.Wrapf(c.err, "while fetching predicate %s from object storage",
+			x.ParseAttr(attr))
+	}
+	atomic.StoreInt64(&c.lastUsed, time.Now().UnixNano())
+	return c.db, nil
+}
+
+// evict removes the least recently used local copies until they fit in the cache size. The
+// copies used in the last coldCacheMinIdle are kept.
+func (t *tieredStorage) evict() {
+	t.Lock()
+	defer t.Unlock()
+	var total int64
+	var ready []*coldCache
+	for _, c := range t.caches {
+		select {
+		case <-c.ready:
+			total += c.size
+			ready = append(ready, c)
+		default:
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		return atomic.LoadInt64(&ready[i].lastUsed) < atomic.LoadInt64(&ready[j].lastUsed)
+	})
+	for _, c := range ready {
+		if total <= t.cacheSize {
+			return
+		}
+		if atomic.LoadInt32(&c.pins) > 0 {
+			continue
+		}
+		if time.Since(time.Unix(0, atomic.LoadInt64(&c.lastUsed))) < coldCacheMinIdle {
+			glog.Warningf("The local copies of the cold predicates take %d bytes, more than the "+
+				"cache size of %d bytes", total, t.cacheSize)
+			return
+		}
+		t.dropLocked(c.object)
+		total -= c.size
+	}
+}
+
+// drop removes the local copy of the object.
+func (t *tieredStorage) drop(object string) {
+	t.Lock()
+	defer t.Unlock()
+	t.dropLocked(object)
+}
+
+func (t *tieredStorage) dropLocked(object string) {
+	if c, ok := t.caches[object]; ok {
+		select {
+		case <-c.ready:
+		default:
+			// It's being fetched.
+			return
+		}
+		if atomic.LoadInt32(&c.pins) > 0 {
+			// It's being streamed. It's dropped by removeUnused once it's unused.
+			return
+		}
+		if c.db != nil {
+			if err := c.db.Close(); err != nil {
+				glog.Warningf("While closing the local copy of %s: %v", object, err)
+			}
+		}
+		delete(t.caches, object)
+	}
+	if err := os.RemoveAll(t.dirOf(object)); err != nil {
+		glog.Warningf("While removing the local copy of %s: %v", object, err)
+	}
+}
+
+// pin opens the local copy of the cold predicate like open, and keeps it open until unpin is
+// called.
+func (t *tieredStorage) pin(attr string, cold *pb.ColdTablet) (*badger.DB, func(), error) {
+	db, err := t.open(attr, cold)
+	if err != nil {
+		return nil, nil, err
+	}
+	t.Lock()
+	c, ok := t.caches[cold.Object]
+	if !ok || c.db != db {
+		t.Unlock()
+		return nil, nil, errors.Errorf("The local copy of predicate %s was evicted while "+
+			"being opened", x.ParseAttr(attr))
+	}
+	atomic.AddInt32(&c.pins, 1)
+	t.Unlock()
+	return db, func() { atomic.AddInt32(&c.pins, -1) }, nil
+}
+
+// streamColdTablets calls fn with the local copy of the data of every cold predicate for which
+// keep returns true, the archived ones included. Their data isn't in pstore anymore, so that
+// backups and exports stream these copies too.
+func streamColdTablets(keep func(attr string) bool,
+	fn func(attr string, cold *pb.ColdTablet, db *badger.DB) error) error {
+	for _, attr := range schema.State().Predicates() {
+		cold := schema.State().ColdTablet(attr)
+		if cold.GetObject() == "" || !keep(attr) {
+			continue
+		}
+		if tiered == nil {
+			return errors.Errorf("Predicate %s is in object storage, but tiered storage isn't "+
+				"enabled on this node", x.ParseAttr(attr))
+		}
+		db, unpin, err := tiered.pin(attr, cold)
+		if err != nil {
+			return err
+		}
+		err = fn(attr, cold, db)
+		unpin()
+		if err != nil {
+			return errors.Wrapf(err, "while streaming cold predicate %s", x.ParseAttr(attr))
+		}
+	}
+	return nil
+}
+
+// removeUnused removes the local copies which don't belong to a cold predicate anymore, e.g.
+// because the predicate was promoted or dropped.
+func (t *tieredStorage) removeUnused() {
+	used := make(map[string]bool)
+	for _, attr := range schema.State().Predicates() {
+		if cold := schema.State().ColdTablet(attr); cold.GetObject() != "" && !cold.Archived {
+			used[t.dirOf(cold.Object)] = true
+		}
+	}
+	t.Lock()
+	for object := range t.caches {
+		if !used[t.dirOf(object)] {
+			t.dropLocked(object)
+		}
+	}
+	t.Unlock()
+
+	entries, err := ioutil.ReadDir(t.cacheDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			glog.Warningf("While listing the local copies of the cold predicates: %v", err)
+		}
+		return
+	}
+	for _, e := range entries {
+		dir := filepath.Join(t.cacheDir, e.Name())
+		if !used[dir] {
+			if err := os.RemoveAll(dir); err != nil {
+				glog.Warningf("While removing %s: %v", dir, err)
+			}
+		}
+	}
+}
+
+// demoteUnread demotes the predicates of this group which weren't read for the cold-after
+// duration. Only the leader does it.
+func (t *tieredStorage) demoteUnread(ctx context.Context) {
+	g := groups()
+	if g.Node == nil || !g.Node.AmLeader() {
+		return
+	}
+	for _, attr := range schema.State().Predicates() {
+		if x.IsReservedPredicate(attr) || schema.State().ColdTablet(attr) != nil ||
+			t.readSince(attr) < t.coldAfter {
+			continue
+		}
+		if gid, err := g.BelongsToReadOnly(attr, 0); err != nil || gid != g.groupId() {
+			continue
+		}
+		glog.Infof("Predicate %s wasn't read for %s. Demoting it to object storage.",
+			x.ParseAttr(attr), t.coldAfter)
+		if err := changeTier(ctx, attr, pb.TierTabletRequest_DEMOTE); err != nil {
+			glog.Errorf("While demoting predicate %s: %v", x.ParseAttr(attr), err)
+		}
+	}
+}
+
+func (t *tieredStorage) run(closer *z.Closer) {
+	defer closer.Done()
+	t.removeUnused()
+
+	ticker := time.NewTicker(t.checkEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closer.HasBeenClosed():
+			t.Lock()
+			for object, c := range t.caches {
+				select {
+				case <-c.ready:
+				default:
+					continue
+				}
+				if c.db != nil {
+					if err := c.db.Close(); err != nil {
+						glog.Warningf("While closing the local copy of %s: %v", object, err)
+					}
+				}
+			}
+			t.caches = make(map[string]*coldCache)
+			t.Unlock()
+			return
+		case <-ticker.C:
+			t.removeUnused()
+			if t.coldAfter > 0 {
+				t.demoteUnread(closer.Ctx())
+			}
+		}
+	}
+}
+
+// MoveTabletTier demotes or archives the predicate to object storage, or promotes or attaches it
+// back to local storage. It's run by the leader of the group serving the predicate.
+func MoveTabletTier(ctx context.Context, attr string, op pb.TierTabletRequest_Op) error {
+	gid, err := groups().BelongsToReadOnly(attr, 0)
+	switch {
+	case err != nil:
+		return err
+	case gid == 0:
+		return errNonExistentTablet
+	}
+	req := &pb.TierTabletRequest{Predicate: attr, Op: op}
+	if groups().ServesGroup(gid) && groups().Node.AmLeader() {
+		_, err := (&grpcWorker{}).MoveTabletTier(ctx, req)
+		return err
+	}
+	pl := groups().Leader(gid)
+	if pl == nil {
+		return conn.ErrNoConnection
+	}
+	_, err = pb.NewWorkerClient(pl.Get()).MoveTabletTier(ctx, req)
+	return err
+}
+
+func (w *grpcWorker) MoveTabletTier(ctx context.Context,
+	req *pb.TierTabletRequest) (*api.Payload, error) {
+	if !groups().Node.AmLeader() {
+		return &emptyPayload, errNotLeader
+	}
+	return &emptyPayload, changeTier(ctx, req.Predicate, req.Op)
+}
+
+// changeTier moves the predicate to the other tier as a task.
+func changeTier(ctx context.Context, attr string, op pb.TierTabletRequest_Op) error {
+	if tiered == nil {
+		return errors.Errorf("Tiered storage isn't enabled, see the --tiered_storage flag")
+	}
+	n := groups().Node
+	closer, err := n.startTask(opTierMove)
+	if err != nil {
+		return err
+	}
+	defer closer.Done()
+
+	var fn func(ctx context.Context, attr string) error
+	var verb, dir string
+	switch op {
+	case pb.TierTabletRequest_DEMOTE:
+		fn, verb, dir = tiered.demote, "Demote", "to"
+	case pb.TierTabletRequest_PROMOTE:
+		fn, verb, dir = tiered.promote, "Promote", "from"
+	case pb.TierTabletRequest_ARCHIVE:
+		fn, verb, dir = tiered.archive, "Archive", "to"
+	case pb.TierTabletRequest_ATTACH:
+		fn, verb, dir = tiered.attach, "Attach", "from"
+	default:
+		return errors.Errorf("Unknown tier operation: %v", op)
+	}
+	ns, name := x.ParseNamespaceAttr(attr)
+	opts := TaskOptions{Kind: "tablet-" + strings.ToLower(verb),
+		Description: fmt.Sprintf("%s predicate %s of namespace %#x %s object storage", verb,
+			name, ns, dir)}
+	return RunTask(ctx, opts, func(ctx context.Context, _ *Task) error {
+		return fn(ctx, attr)
+	})
+}
+
+// demote moves the predicate to object storage. It's then served from local copies, which are
+// fetched when needed.
+func (t *tieredStorage) demote(ctx context.Context, attr string) error {
+	if schema.State().ColdTablet(attr) != nil {
+		return errors.Errorf("Predicate %s is already in object storage", x.ParseAttr(attr))
+	}
+	return t.moveOut(ctx, attr, false)
+}
+
+// archive moves the predicate to object storage like demote, but the predicate isn't served
+// anymore, not even from a local copy, until it's attached. A cold predicate is archived
+// without writing it again.
+func (t *tieredStorage) archive(ctx context.Context, attr string) error {
+	cold := schema.State().ColdTablet(attr)
+	switch {
+	case cold == nil:
+		return t.moveOut(ctx, attr, true)
+	case cold.Archived:
+		return errors.Errorf("Predicate %s is already archived", x.ParseAttr(attr))
+	case cold.Object == "":
+		return errors.Errorf("Predicate %s is being moved to object storage", x.ParseAttr(attr))
+	}
+	archived := *cold
+	archived.Archived = true
+	if err := groups().Node.proposeAndWait(ctx, &pb.Proposal{TierTablet: &pb.TierTablet{
+		Predicate: attr, Cold: &archived}}); err != nil {
+		return err
+	}
+	glog.Infof("Archived predicate %s in %s", x.ParseAttr(attr), cold.Object)
+	return nil
+}
+
+// moveOut writes the data of the predicate to object storage, and then drops the local data on
+// every replica. The predicate is frozen meanwhile, so that its data doesn't change.
+func (t *tieredStorage) moveOut(ctx context.Context, attr string, archive bool) error {
+	if x.IsReservedPredicate(attr) {
+		return errors.Errorf("Reserved predicate %s can't be moved to object storage",
+			x.ParseAttr(attr))
+	}
+	if _, ok := schema.State().Get(ctx, attr); !ok {
+		return errors.Errorf("Predicate %s not found", x.ParseAttr(attr))
+	}
+
+	n := groups().Node
+	freeze := &pb.Proposal{TierTablet: &pb.TierTablet{Predicate: attr, Cold: &pb.ColdTablet{}}}
+	if err := n.proposeAndWait(ctx, freeze); err != nil {
+		return err
+	}
+	// No transaction touching the predicate was pending when it got frozen, so the data is
+	// complete at any timestamp given by Zero after that, once this node has applied it. The
+	// timestamp is proposed with the object.
+	readTs, err := freshTs(ctx)
+	var object string
+	var size int64
+	if err == nil {
+		object = objectName(attr, readTs)
+		size, err = t.writeObject(ctx, attr, readTs, object)
+	}
+	if err == nil {
+		cold := &pb.ColdTablet{Object: object, ReadTs: readTs, ObjectSize: size,
+			DemotedAt: time.Now().Unix(), Archived: archive}
+		err = n.proposeAndWait(ctx, &pb.Proposal{TierTablet: &pb.TierTablet{Predicate: attr,
+			Cold: cold}})
+	}
+	if err != nil {
+		unfreeze := &pb.Proposal{TierTablet: &pb.TierTablet{Predicate: attr}}
+		if uerr := n.proposeAndWait(context.Background(), unfreeze); uerr != nil {
+			glog.Errorf("While unfreezing predicate %s: %v", x.ParseAttr(attr), uerr)
+		}
+		return err
+	}
+	glog.Infof("Moved predicate %s to %s (%d bytes, archived: %v)", x.ParseAttr(attr), object,
+		size, archive)
+	return nil
+}
+
+// promote brings the cold predicate back to local storage.
+func (t *tieredStorage) promote(ctx context.Context, attr string) error {
+	cold := schema.State().ColdTablet(attr)
+	switch {
+	case cold.GetObject() == "":
+		return errors.Errorf("Predicate %s isn't in object storage", x.ParseAttr(attr))
+	case cold.Archived:
+		return errors.Errorf("Predicate %s is archived. Attach it instead.", x.ParseAttr(attr))
+	}
+	return t.moveIn(ctx, attr, cold)
+}
+
+// attach brings the archived predicate back to local storage.
+func (t *tieredStorage) attach(ctx context.Context, attr string) error {
+	cold := schema.State().ColdTablet(attr)
+	if !cold.GetArchived() {
+		return errors.Errorf("Predicate %s isn't archived", x.ParseAttr(attr))
+	}
+	return t.moveIn(ctx, attr, cold)
+}
+
+// moveIn writes the data of the predicate back to every replica. The object is kept, so that
+// the replicas which are behind can still read it.
+func (t *tieredStorage) moveIn(ctx context.Context, attr string, cold *pb.ColdTablet) error {
+	n := groups().Node
+	// Write the data above the delete markers of the demotion at a timestamp given by Zero, like
+	// predicate moves do, so that every replica writes it at the same version.
+	ts, err := freshTs(ctx)
+	if err != nil {
+		return err
+	}
+	proposal := &pb.Proposal{}
+	size := 0
+	err = t.readObject(cold.Object, func(kv *bpb.KV) error {
+		kv.Version = ts
+		proposal.Kv = append(proposal.Kv, kv)
+		size += len(kv.Key) + len(kv.Value)
+		if size < maxTierProposalSize {
+			return nil
+		}
+		if err := n.proposeAndWait(ctx, proposal); err != nil {
+			return err
+		}
+		proposal = &pb.Proposal{}
+		size = 0
+		return nil
+	})
+	if err == nil && len(proposal.Kv) > 0 {
+		err = n.proposeAndWait(ctx, proposal)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "while moving predicate %s from object storage",
+			x.ParseAttr(attr))
+	}
+	if err := n.proposeAndWait(ctx, &pb.Proposal{TierTablet: &pb.TierTablet{
+		Predicate: attr}}); err != nil {
+		return err
+	}
+	glog.Infof("Moved predicate %s back from %s", x.ParseAttr(attr), cold.Object)
+	return nil
+}
+
+// applyTierTablet changes the state of the predicate on this replica.
+func (n *node) applyTierTablet(ctx context.Context, tt *pb.TierTablet) error {
+	su, ok := schema.State().Get(ctx, tt.Predicate)
+	if !ok {
+		return errors.Errorf("Predicate %s not found", x.ParseAttr(tt.Predicate))
+	}
+	old := su.Cold
+	switch {
+	case tt.Cold == nil:
+	case tt.Cold.Object == "":
+		if old != nil {
+			return errors.Errorf("Predicate %s is already in object storage",
+				x.ParseAttr(tt.Predicate))
+		}
+		if err := detectPendingTxns(tt.Predicate); err != nil {
+			return err
+		}
+	case tt.Cold.Archived && old.GetObject() == tt.Cold.Object && !old.Archived:
+		// A cold predicate is archived. Its local data is already gone.
+	default:
+		if old == nil || old.Object != "" {
+			return errors.Errorf("Predicate %s must be frozen before being demoted",
+				x.ParseAttr(tt.Predicate))
+		}
+		if err := posting.DeletePredicateData(tt.Predicate); err != nil {
+			return err
+		}
+	}
+
+	su.Cold = tt.Cold
+	if err := updateSchema(&su); err != nil {
+		return err
+	}
+	if (tt.Cold == nil || tt.Cold.Archived) && old.GetObject() != "" && tiered != nil {
+		tiered.drop(old.Object)
+	}
+	// The cached lists were read from the other storage.
+	posting.ResetCache()
+	return nil
+}
+
+// errColdPredicate returns the error for writes to the cold or archived predicate.
+func errColdPredicate(attr string) error {
+	if schema.State().ColdTablet(attr).GetArchived() {
+		return &x.ArchivedPredicateError{Attr: attr}
+	}
+	return errors.Errorf("Predicate %s is in object storage and is read-only. Promote it "+
+		"before changing it.", x.ParseAttr(attr))
+}
+
+// clearColdTablets forgets that the predicates are in object storage, once their data has been
+// dropped.
+func clearColdTablets(ctx context.Context) error {
+	for _, attr := range schema.State().Predicates() {
+		if schema.State().ColdTablet(attr) == nil {
+			continue
+		}
+		su, _ := schema.State().Get(ctx, attr)
+		su.Cold = nil
+		if err := updateSchema(&su); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ColdTabletStatus is the state of a predicate whose data is in object storage.
+type ColdTabletStatus struct {
+	Predicate string
+	Object    string
+	Size      int64
+	DemotedAt time.Time
+	// Cached is whether this node holds a local copy of the data.
+	Cached bool
+	// Archived is whether the predicate isn't served until it's attached.
+	Archived bool
+}
+
+// GetColdTablets returns the predicates served by this node whose data is in object storage.
+func GetColdTablets() []ColdTabletStatus {
+	var res []ColdTabletStatus
+	for _, attr := range schema.State().Predicates() {
+		cold := schema.State().ColdTablet(attr)
+		if cold.GetObject() == "" {
+			continue
+		}
+		st := ColdTabletStatus{
+			Predicate: attr,
+			Object:    cold.Object,
+			Size:      cold.ObjectSize,
+			DemotedAt: time.Unix(cold.DemotedAt, 0),
+			Archived:  cold.Archived,
+		}
+		if tiered != nil {
+			tiered.Lock()
+			_, st.Cached = tiered.caches[cold.Object]
+			tiered.Unlock()
+		}
+		res = append(res, st)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Predicate < res[j].Predicate })
+	return res
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/codec"
+	"github.com/dgraph-io/dgraph/posting"
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/schema"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTieredStorage(t *testing.T) {
+	parse := func(flag string) (*tieredStorage, error) {
+		return parseTieredStorage(z.NewSuperFlag(flag).MergeAndCheckDefault(TieredStorageDefaults))
+	}
+
+	ts, err := parse("")
+	require.NoError(t, err)
+	require.Nil(t, ts)
+
+	dir, err := ioutil.TempDir("", "tiered")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ts, err = parse("dest=" + dir + "; cache-mb=10; cold-after=720h")
+	require.NoError(t, err)
+	require.Equal(t, int64(10<<20), ts.cacheSize)
+	require.Equal(t, &fileObjectStore{dir: dir}, ts.store)
+
+	_, err = parse("dest=" + dir + "; cache-mb=0")
+	require.Error(t, err)
+	_, err = parse("dest=" + dir + "; check-every=0s")
+	require.Error(t, err)
+	_, err = parse("dest=azure://container/path")
+	require.Error(t, err)
+}
+
+func TestTieredStorageObject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiered")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ts, err := parseTieredStorage(z.NewSuperFlag("dest=" + filepath.Join(dir, "objects") +
+		"; cache-dir=" + filepath.Join(dir, "cache")).MergeAndCheckDefault(TieredStorageDefaults))
+	require.NoError(t, err)
+
+	require.NoError(t, schema.ParseBytes([]byte("tiered: [uid] ."), 1))
+	attr := x.GalaxyAttr("tiered")
+	for uid := uint64(1); uid <= 10; uid++ {
+		key := x.DataKey(attr, uid)
+		addEdge(t, &pb.DirectedEdge{ValueId: uid + 100, Attr: attr, Entity: uid}, getOrCreate(key))
+	}
+	readTs := timestamp()
+
+	object := objectName(attr, readTs)
+	size, err := ts.writeObject(context.Background(), attr, readTs, object)
+	require.NoError(t, err)
+	require.Greater(t, size, int64(0))
+
+	cold := &pb.ColdTablet{Object: object, ReadTs: readTs, ObjectSize: size}
+	db, err := ts.open(attr, cold)
+	require.NoError(t, err)
+	db2, err := ts.open(attr, cold)
+	require.NoError(t, err)
+	require.True(t, db == db2)
+
+	for uid := uint64(1); uid <= 10; uid++ {
+		txn := db.NewTransactionAt(math.MaxUint64, false)
+		item, err := txn.Get(x.DataKey(attr, uid))
+		require.NoError(t, err)
+		require.Equal(t, readTs, item.Version())
+		var pl pb.PostingList
+		require.NoError(t, item.Value(func(val []byte) error { return pl.Unmarshal(val) }))
+		require.Equal(t, []uint64{uid + 100}, codec.Decode(pl.Pack, 0))
+		txn.Discard()
+	}
+
+	// The local copy is reused once it's complete.
+	ts.Lock()
+	require.NoError(t, ts.caches[object].db.Close())
+	delete(ts.caches, object)
+	ts.Unlock()
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "objects")))
+	db, err = ts.open(attr, cold)
+	require.NoError(t, err)
+
+	// The queries read the cold predicates from their local copy.
+	posting.SetColdStore(ts.open)
+	defer posting.SetColdStore(nil)
+	su, _ := schema.State().Get(context.Background(), attr)
+	su.Cold = cold
+	schema.State().Set(attr, &su)
+	got, err := posting.ReadStore(attr)
+	require.NoError(t, err)
+	require.True(t, db == got)
+	ts.removeUnused()
+	_, err = os.Stat(ts.dirOf(object))
+	require.NoError(t, err)
+
+	// Backups and exports stream the copies, which aren't dropped meanwhile.
+	tiered = ts
+	defer func() { tiered = nil }()
+	var streamed []string
+	require.NoError(t, streamColdTablets(func(string) bool { return true },
+		func(a string, _ *pb.ColdTablet, cdb *badger.DB) error {
+			ts.drop(object)
+			require.True(t, db == cdb)
+			streamed = append(streamed, a)
+			return nil
+		}))
+	require.Equal(t, []string{attr}, streamed)
+	_, err = os.Stat(ts.dirOf(object))
+	require.NoError(t, err)
+
+	// The archived predicates can't be read, and their copies are removed.
+	su.Cold = &pb.ColdTablet{Object: object, ReadTs: readTs, ObjectSize: size, Archived: true}
+	schema.State().Set(attr, &su)
+	_, err = posting.ReadStore(attr)
+	require.True(t, x.IsArchivedPredicate(err))
+	require.True(t, x.IsArchivedPredicate(errColdPredicate(attr)))
+	ts.removeUnused()
+	_, err = os.Stat(ts.dirOf(object))
+	require.True(t, os.IsNotExist(err))
+
+	// The copies which don't belong to cold predicates are removed.
+	su.Cold = nil
+	schema.State().Set(attr, &su)
+	got, err = posting.ReadStore(attr)
+	require.NoError(t, err)
+	require.True(t, pstore == got)
+	ts.removeUnused()
+	_, err = os.Stat(ts.dirOf(object))
+	require.True(t, os.IsNotExist(err))
+	_, err = ts.open(attr, cold)
+	require.Error(t, err)
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/posting"
 	"github.com/dgraph-io/dgraph/schema"
 	"github.com/dgraph-io/dgraph/tok"
 	"github.com/dgraph-io/dgraph/types"
@@ -66,13 +67,15 @@ func verifyCustomIndex(ctx context.Context, attr string, tokenizerName string) b
 }
 
 // Return string tokens from function arguments. It maps function type to correct tokenizer.
+// The dictionary is only used by the full-text functions.
 // Note: regexp functions require regexp compilation of argument, not tokenization.
-func getStringTokens(funcArgs []string, lang string, funcType FuncType) ([]string, error) {
+func getStringTokens(funcArgs []string, lang string, funcType FuncType,
+	dict *tok.Dictionary) ([]string, error) {
 	if lang == "." {
 		lang = "en"
 	}
 	if funcType == fullTextSearchFn {
-		return tok.GetFullTextTokens(funcArgs, lang)
+		return tok.GetFullTextTokens(funcArgs, lang, dict)
 	}
 	return tok.GetTermTokens(funcArgs)
 }
@@ -132,7 +135,7 @@ func getInequalityTokens(ctx context.Context, readTs uint64, attr, f, lang strin
 
 	// Get the token for the value passed in function.
 	// XXX: the lang should be query.Langs, but it only matters in edge case test below.
-	tokenizer = tok.GetTokenizerForLang(tokenizer, lang)
+	tokenizer = tok.GetTokenizerForCollation(tokenizer, lang, schema.State().Collation(ctx, attr))
 
 	var ineqTokensFinal []string
 	for _, ineqValue := range ineqValues {
@@ -165,7 +168,11 @@ func getInequalityTokens(ctx context.Context, readTs uint64, attr, f, lang strin
 	// If some new index key was written as part of same transaction it won't be on disk
 	// until the txn is committed. This is OK, we don't need to overlay in-memory contents on the
 	// DB, to keep the design simple and efficient.
-	txn := pstore.NewTransactionAt(readTs, false)
+	db, err := posting.ReadStore(attr)
+	if err != nil {
+		return nil, nil, err
+	}
+	txn := db.NewTransactionAt(readTs, false)
 	defer txn.Discard()
 
 	seekKey := x.IndexKey(attr, ineqTokensFinal[0])
@@ -0,0 +1,174 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/dgraph/raftwal"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// WALArchiveDefaults are the default values for the --wal_archive superflag.
+const WALArchiveDefaults = "dest=; spool-dir=wal_archive; upload-every=1m"
+
+// walArchive archives the segments of the Raft write-ahead log to object storage before they're
+// truncated, so that external tooling can replay the log on top of a backup. The segments are
+// first copied into the spool directory and uploaded from there. They are uploaded as
+// wal/group-<gid>/node-<raft id>/<first index>-<last index>.wal.
+type walArchive struct {
+	store       objectStore
+	spoolDir    string
+	uploadEvery time.Duration
+	uploadCh    chan struct{}
+}
+
+// walArchiver is the archive set up via the --wal_archive flag, or nil.
+var walArchiver *walArchive
+
+func parseWALArchive(sf *z.SuperFlag) (*walArchive, error) {
+	dest := sf.GetString("dest")
+	if dest == "" {
+		return nil, nil
+	}
+	store, err := newObjectStore(dest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while opening %s", dest)
+	}
+	a := &walArchive{
+		store:    store,
+		spoolDir: sf.GetString("spool-dir"),
+		uploadCh: make(chan struct{}, 1),
+	}
+	if a.uploadEvery, err = time.ParseDuration(sf.GetString("upload-every")); err != nil {
+		return nil, errors.Wrapf(err, "while parsing upload-every")
+	}
+	switch {
+	case a.spoolDir == "":
+		return nil, errors.Errorf("spool-dir must be set")
+	case a.uploadEvery <= 0:
+		return nil, errors.Errorf("upload-every must be positive")
+	}
+	if err := os.MkdirAll(a.spoolDir, 0700); err != nil {
+		return nil, errors.Wrapf(err, "while creating spool dir %s", a.spoolDir)
+	}
+	return a, nil
+}
+
+// spool is the raftwal.Archiver. It copies the segment into the spool directory, and wakes up the
+// uploads. The segment can't be hard linked instead, because the log truncates its files before
+// removing them, which would empty the link as well.
+func (a *walArchive) spool(seg raftwal.Segment) error {
+	name := fmt.Sprintf("%020d-%020d.wal", seg.FirstIndex, seg.LastIndex)
+	if err := spoolCopy(seg.Path, filepath.Join(a.spoolDir, name)); err != nil {
+		return errors.Wrapf(err, "while spooling %s", seg.Path)
+	}
+	select {
+	case a.uploadCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func spoolCopy(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := ioutil.TempFile(filepath.Dir(dst), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), dst)
+}
+
+// upload uploads the spooled segments, and removes the ones uploaded. The segments stay in the
+// spool directory until this node knows its group, and across restarts.
+func (a *walArchive) upload() {
+	g := groups()
+	if g == nil || g.Node == nil || g.groupId() == 0 {
+		return
+	}
+	a.uploadTo(path.Join("wal", fmt.Sprintf("group-%d", g.groupId()),
+		fmt.Sprintf("node-%#x", g.Node.Id)))
+}
+
+func (a *walArchive) uploadTo(prefix string) {
+	files, err := ioutil.ReadDir(a.spoolDir)
+	if err != nil {
+		glog.Errorf("While listing the spooled WAL segments: %v", err)
+		return
+	}
+	for _, fi := range files {
+		name := fi.Name()
+		if fi.IsDir() || !strings.HasSuffix(name, ".wal") {
+			continue
+		}
+		p := filepath.Join(a.spoolDir, name)
+		f, err := os.Open(p)
+		if err != nil {
+			glog.Errorf("While opening the WAL segment %s: %v", p, err)
+			return
+		}
+		err = a.store.Put(path.Join(prefix, name), f)
+		f.Close()
+		if err != nil {
+			// Try again at the next upload.
+			glog.Errorf("While archiving the WAL segment %s: %v", name, err)
+			return
+		}
+		if err := os.Remove(p); err != nil {
+			glog.Warningf("While removing the archived WAL segment %s: %v", p, err)
+		}
+		glog.V(2).Infof("Archived WAL segment %s to %s", name, prefix)
+	}
+}
+
+func (a *walArchive) run(closer *z.Closer) {
+	defer closer.Done()
+	a.upload()
+
+	ticker := time.NewTicker(a.uploadEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case <-ticker.C:
+		case <-a.uploadCh:
+		}
+		a.upload()
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/dgraph/raftwal"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/raft/raftpb"
+)
+
+func TestWALArchiveSurvivesTruncation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "walarchive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := &walArchive{
+		store:    &fileObjectStore{dir: filepath.Join(dir, "dest")},
+		spoolDir: filepath.Join(dir, "spool"),
+		uploadCh: make(chan struct{}, 1),
+	}
+	require.NoError(t, os.MkdirAll(a.spoolDir, 0700))
+	raftwal.SetArchiver(a.spool)
+	defer raftwal.SetArchiver(nil)
+
+	// Write enough entries to fill a segment, and truncate the log past it.
+	walDir := filepath.Join(dir, "w")
+	require.NoError(t, os.MkdirAll(walDir, 0700))
+	store := raftwal.Init(walDir)
+	const n = 40000
+	var entries []raftpb.Entry
+	for idx := uint64(1); idx <= n; idx++ {
+		entries = append(entries, raftpb.Entry{Index: idx, Term: 1, Data: []byte("entry")})
+		if len(entries) == 1000 {
+			require.NoError(t, store.Save(&raftpb.HardState{}, entries, &raftpb.Snapshot{}))
+			entries = entries[:0]
+		}
+	}
+	require.NoError(t, store.CreateSnapshot(n-10,
+		&raftpb.ConfState{Nodes: []uint64{1}}, nil))
+	require.NoError(t, store.Close())
+
+	a.uploadTo("wal/group-1/node-0x1")
+	uploaded, err := filepath.Glob(filepath.Join(dir, "dest", "wal", "group-1", "node-0x1",
+		"*.wal"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(uploaded))
+	fi, err := os.Stat(uploaded[0])
+	require.NoError(t, err)
+	require.NotZero(t, fi.Size())
+
+	// Nothing is left in the spool directory once uploaded.
+	spooled, err := ioutil.ReadDir(a.spoolDir)
+	require.NoError(t, err)
+	require.Empty(t, spooled)
+}
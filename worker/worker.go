@@ -19,10 +19,10 @@
 package worker
 
 import (
-	"fmt"
 	"log"
 	"math"
 	"net"
+	"strconv"
 	"sync"
 	"sync/atomic"
 
@@ -67,12 +67,17 @@ func Init(ps *badger.DB) {
 		grpc.MaxSendMsgSize(x.GrpcMaxSize),
 		grpc.MaxConcurrentStreams(math.MaxInt32),
 		grpc.StatsHandler(&ocgrpc.ServerHandler{}),
+		conn.KeepaliveEnforcement(),
 	}
 
 	if x.WorkerConfig.TLSServerConfig != nil {
 		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(x.WorkerConfig.TLSServerConfig)))
 	}
 	workerServer = grpc.NewServer(grpcOpts...)
+
+	if x.WorkerConfig.CommitHook != nil {
+		initCommitHook(x.WorkerConfig.CommitHook)
+	}
 }
 
 // grpcWorker struct implements the gRPC server interface.
@@ -104,7 +109,7 @@ func RunServer(bindall bool) {
 	if bindall {
 		laddr = "0.0.0.0"
 	}
-	ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", laddr, workerPort()))
+	ln, err := net.Listen("tcp", net.JoinHostPort(laddr, strconv.Itoa(workerPort())))
 	if err != nil {
 		log.Fatalf("While running server: %v", err)
 	}
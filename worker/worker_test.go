@@ -361,6 +361,66 @@ func TestProcessTaskIndex(t *testing.T) {
 	)
 }
 
+func TestTabletGroupAt(t *testing.T) {
+	tablet := &pb.Tablet{GroupId: 2, Predicate: "name", MoveTs: 10}
+	gid, err := tabletGroupAt(tablet, 20)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), gid)
+
+	// Reads from before the move fail once the source group has handed over the tablet.
+	_, err = tabletGroupAt(tablet, 5)
+	require.Error(t, err)
+
+	// While handing over, the source group serves them.
+	tablet.MovedFrom = 1
+	gid, err = tabletGroupAt(tablet, 5)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), gid)
+}
+
+func TestCheckForwardable(t *testing.T) {
+	attr := x.GalaxyAttr("forwarded")
+	gr.Lock()
+	gr.tablets[attr] = &pb.Tablet{GroupId: 2, Predicate: attr, MoveTs: 10}
+	gr.Unlock()
+	defer func() {
+		gr.Lock()
+		delete(gr.tablets, attr)
+		gr.Unlock()
+	}()
+
+	m := &pb.Mutations{StartTs: 20, Edges: []*pb.DirectedEdge{{Attr: attr, Entity: 1}}}
+	require.NoError(t, checkForwardable(m))
+
+	// Writes from before the move can't be applied on top of the moved tablet.
+	m.StartTs = 5
+	require.Equal(t, x.ErrConflict, checkForwardable(m))
+}
+
+func TestServedTs(t *testing.T) {
+	ctx, servedTs := WithServedTs(context.Background())
+	recordServedTs(ctx, 20, 20)
+	require.Equal(t, uint64(0), servedTs())
+
+	recordServedTs(ctx, 20, 15)
+	recordServedTs(ctx, 20, 18)
+	require.Equal(t, uint64(15), servedTs())
+	recordServedTs(ctx, 20, 12)
+	require.Equal(t, uint64(12), servedTs())
+
+	// Contexts without a tracker are ignored.
+	recordServedTs(context.Background(), 20, 10)
+}
+
+func TestPredGroups(t *testing.T) {
+	gids, err := predGroups([]string{"1-0-name", "2-0-friend", "1-0-age"})
+	require.NoError(t, err)
+	require.Equal(t, []uint32{1, 2}, gids)
+
+	_, err = predGroups([]string{"name"})
+	require.Error(t, err)
+}
+
 func TestMain(m *testing.M) {
 	x.Init()
 	posting.Config.CommitFraction = 0.10
@@ -389,7 +449,62 @@ func TestMain(m *testing.M) {
 	pstore = ps
 	// Not using posting list cache
 	posting.Init(ps, 0)
+	schema.Init(ps)
 	Init(ps)
 
 	os.Exit(m.Run())
 }
+
+func TestPredicateStats(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte("stats: [uid] ."), 1))
+	attr := x.GalaxyAttr("stats")
+	for uid := uint64(1); uid <= 3; uid++ {
+		edge := &pb.DirectedEdge{ValueId: 100 + uid, Attr: attr, Entity: uid}
+		addEdge(t, edge, getOrCreate(x.DataKey(attr, uid)))
+	}
+	delEdge(t, &pb.DirectedEdge{ValueId: 101, Attr: attr, Entity: 1},
+		getOrCreate(x.DataKey(attr, 1)))
+
+	stats, err := getPredicateStats(attr)
+	require.NoError(t, err)
+	require.Greater(t, stats.size, int64(0))
+	require.Equal(t, uint64(3), stats.keys)
+	require.Equal(t, uint64(0), stats.splits)
+	require.Equal(t, uint64(4), stats.versions)
+	require.Equal(t, uint64(0), stats.stale)
+	require.Equal(t, uint64(1), stats.deleted)
+
+	// The deleted key and its older versions stay on disk until they're compacted.
+	txn := pstore.NewTransactionAt(math.MaxUint64, true)
+	require.NoError(t, txn.Delete(x.DataKey(attr, 1)))
+	require.NoError(t, txn.CommitAt(timestamp(), nil))
+
+	node, err := populateSchema(attr, []string{"keys", "deleted", "tombstone_ratio"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), node.Keys)
+	require.Equal(t, uint64(3), node.Deleted)
+	require.Equal(t, 0.6, node.TombstoneRatio)
+	require.Equal(t, int64(0), node.Size_)
+}
+
+func TestEstimateHasTotal(t *testing.T) {
+	require.NoError(t, schema.ParseBytes([]byte("estimate: [uid] ."), 1))
+	attr := x.GalaxyAttr("estimate")
+	for uid := uint64(10); uid <= 1000; uid += 10 {
+		edge := &pb.DirectedEdge{ValueId: 1, Attr: attr, Entity: uid}
+		addEdge(t, edge, getOrCreate(x.DataKey(attr, uid)))
+	}
+
+	txn := pstore.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+	prefix := x.ParsedKey{Attr: attr}.DataPrefix()
+	endKey := x.DataKey(attr, math.MaxUint64)
+	total, err := estimateHasTotal(txn, prefix, endKey, 0, []uint64{10, 20, 30, 40, 50})
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), total)
+
+	// Nothing is estimated if the last key was reached.
+	total, err = estimateHasTotal(txn, prefix, endKey, 900, []uint64{910, 1000})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), total)
+}
@@ -5,7 +5,10 @@ import (
 
 	"github.com/dgraph-io/dgraph/conn"
 	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func forwardAssignUidsToZero(ctx context.Context, in *pb.Num) (*pb.AssignedIds, error) {
@@ -17,6 +20,38 @@ func forwardAssignUidsToZero(ctx context.Context, in *pb.Num) (*pb.AssignedIds,
 	return zc.AssignIds(ctx, in)
 }
 
+// checkXidNamespace checks that the xids of the request belong to the namespace of the caller,
+// given by its access JWT. Without ACL, there is only the galaxy namespace.
+func checkXidNamespace(ctx context.Context, in *pb.XidRequest) error {
+	ns := x.GalaxyNamespace
+	if x.WorkerConfig.AclEnabled {
+		var err error
+		if ns, err = x.ExtractJWTNamespace(ctx); err != nil {
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+	}
+	if in.Namespace != ns {
+		return status.Errorf(codes.PermissionDenied,
+			"UIDs for the xids of namespace %#x can't be assigned from namespace %#x",
+			in.Namespace, ns)
+	}
+	return nil
+}
+
+func forwardAssignUidForXidToZero(ctx context.Context, in *pb.XidRequest) (*pb.XidMap, error) {
+	if err := checkXidNamespace(ctx, in); err != nil {
+		return nil, err
+	}
+	// Only Zeros forward requests to each other.
+	in.Forwarded = false
+	pl := groups().Leader(0)
+	if pl == nil {
+		return nil, conn.ErrNoConnection
+	}
+	zc := pb.NewZeroClient(pl.Get())
+	return zc.AssignUidForXid(ctx, in)
+}
+
 // RegisterZeroProxyServer forwards select GRPC calls over to Zero
 func RegisterZeroProxyServer(s *grpc.Server) {
 	s.RegisterService(&grpc.ServiceDesc{
@@ -34,6 +69,16 @@ func RegisterZeroProxyServer(s *grpc.Server) {
 					return forwardAssignUidsToZero(ctx, in)
 				},
 			},
+			{
+				MethodName: "AssignUidForXid",
+				Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+					in := new(pb.XidRequest)
+					if err := dec(in); err != nil {
+						return nil, err
+					}
+					return forwardAssignUidForXidToZero(ctx, in)
+				},
+			},
 		},
 	}, &struct{}{})
 }
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestCheckXidNamespace(t *testing.T) {
+	defer func(acl bool, secret x.SensitiveByteSlice) {
+		x.WorkerConfig.AclEnabled, x.WorkerConfig.HmacSecret = acl, secret
+	}(x.WorkerConfig.AclEnabled, x.WorkerConfig.HmacSecret)
+
+	// Without ACL, there is only the galaxy namespace.
+	x.WorkerConfig.AclEnabled = false
+	ctx := context.Background()
+	require.NoError(t, checkXidNamespace(ctx, &pb.XidRequest{Namespace: x.GalaxyNamespace}))
+	err := checkXidNamespace(ctx, &pb.XidRequest{Namespace: 2})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// With ACL, the namespace is the one of the access JWT.
+	x.WorkerConfig.AclEnabled = true
+	x.WorkerConfig.HmacSecret = []byte("0123456789abcdef0123456789abcdef")
+	err = checkXidNamespace(ctx, &pb.XidRequest{Namespace: x.GalaxyNamespace})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userid":    "alice",
+		"namespace": 2,
+		"exp":       time.Now().Add(time.Minute).Unix(),
+	})
+	jwtStr, err := token.SignedString([]byte(x.WorkerConfig.HmacSecret))
+	require.NoError(t, err)
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("accessJwt", jwtStr))
+	require.NoError(t, checkXidNamespace(ctx, &pb.XidRequest{Namespace: 2}))
+	err = checkXidNamespace(ctx, &pb.XidRequest{Namespace: x.GalaxyNamespace})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ClockHeader is the gRPC response header carrying the wall clock of the server, in milliseconds
+// since the epoch. It is used to measure the clock skew between nodes.
+const ClockHeader = "dgraph-clock-ms"
+
+// SetClockHeader sends the current wall clock of this node along with the gRPC response.
+func SetClockHeader(ctx context.Context) error {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	return grpc.SetHeader(ctx, metadata.Pairs(ClockHeader, strconv.FormatInt(now, 10)))
+}
+
+// ClockSkew estimates how far the local clock is ahead of the remote one, given the time a request
+// was sent, the time its response was received and the response header. Like NTP, it assumes
+// that the remote clock was read halfway through the round trip. It returns false if the header
+// is missing.
+func ClockSkew(header metadata.MD, sent, received time.Time) (time.Duration, bool) {
+	vals := header.Get(ClockHeader)
+	if len(vals) == 0 {
+		return 0, false
+	}
+	remote, err := strconv.ParseInt(vals[0], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	mid := sent.Add(received.Sub(sent) / 2)
+	return mid.Sub(time.Unix(0, remote*int64(time.Millisecond))), true
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestClockSkew(t *testing.T) {
+	sent := time.Unix(1000, 0)
+	received := sent.Add(200 * time.Millisecond)
+
+	_, ok := ClockSkew(metadata.MD{}, sent, received)
+	require.False(t, ok)
+
+	// The remote clock was read 100ms into the round trip, but it is 2s behind.
+	remote := sent.Add(100*time.Millisecond - 2*time.Second)
+	md := metadata.Pairs(ClockHeader,
+		strconv.FormatInt(remote.UnixNano()/int64(time.Millisecond), 10))
+	skew, ok := ClockSkew(md, sent, received)
+	require.True(t, ok)
+	require.Equal(t, 2*time.Second, skew)
+}
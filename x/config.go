@@ -17,6 +17,8 @@
 package x
 
 import (
+	"crypto"
+	"crypto/ed25519"
 	"crypto/tls"
 	"net"
 	"time"
@@ -32,11 +34,23 @@ type Options struct {
 	// QueryEdgeLimit is the maximum number of edges that will be traversed during
 	// recurse and shortest-path queries.
 	QueryEdgeLimit uint64
+	// QueryMemoryLimit is the maximum number of bytes a single query can use for the posting
+	// lists it materializes and the response it builds. Zero means no limit.
+	QueryMemoryLimit int64
+	// MaxResponseBytes is the size after which DQL responses are truncated and marked as
+	// partial. Zero means no limit.
+	MaxResponseBytes uint64
+	// BestEffortMaxLag is the number of timestamps a replica can lag behind the read timestamp
+	// of a best effort query and still serve it. Zero means replicas always catch up first.
+	BestEffortMaxLag uint64
 	// NormalizeNodeLimit is the maximum number of nodes allowed in a normalize query.
 	NormalizeNodeLimit int
 	// MutationsNQuadLimit is maximum number of nquads that can be present in a single
 	// mutation request.
 	MutationsNQuadLimit int
+	// MutationStreamLimit is the maximum number of bytes of a streamed mutation. Zero means no
+	// limit.
+	MutationStreamLimit int64
 	// PollInterval is the polling interval for graphql subscription.
 	PollInterval time.Duration
 	// GraphqlExtension will be set to see extensions in graphql results
@@ -45,6 +59,12 @@ type Options struct {
 	GraphqlDebug bool
 	// GraphqlLambdaUrl stores the URL of lambda functions for custom GraphQL resolvers
 	GraphqlLambdaUrl string
+	// GraphqlLambdaTimeout is the timeout for the requests to the lambda server.
+	GraphqlLambdaTimeout time.Duration
+	// GraphqlLambdaWasmMemoryMB is the memory limit of a WASM lambda script.
+	GraphqlLambdaWasmMemoryMB int64
+	// GraphqlCacheMB is the size of the cache for the results of GraphQL queries with @cache.
+	GraphqlCacheMB int64
 }
 
 // Config stores the global instance of this package's options.
@@ -80,6 +100,26 @@ type WorkerOptions struct {
 	TLSServerConfig *tls.Config
 	// Raft stores options related to Raft.
 	Raft *z.SuperFlag
+	// Disk stores the disk usage limits and forecasting options.
+	Disk *z.SuperFlag
+	// Shedding stores the memory and CPU limits above which low priority requests are shed.
+	Shedding *z.SuperFlag
+	// CacheTier stores the memcached servers and the options of the cache tier of posting lists.
+	CacheTier *z.SuperFlag
+	// HotKeys stores the options of the detection and mitigation of hot keys.
+	HotKeys *z.SuperFlag
+	// Rollup stores the options of the scheduling of the rollups of posting lists.
+	Rollup *z.SuperFlag
+	// CommitHook stores the address, timeout and failure policy of the commit hook.
+	CommitHook *z.SuperFlag
+	// TieredStorage stores the object storage and local cache options of the cold predicates.
+	TieredStorage *z.SuperFlag
+	// WALArchive stores the options of the archiving of the Raft write-ahead log segments.
+	WALArchive *z.SuperFlag
+	// SearchSync stores the options used to reach the clusters of the search connectors.
+	SearchSync *z.SuperFlag
+	// IndexVerify stores the options of the background verification of the indexes.
+	IndexVerify *z.SuperFlag
 	// WhiteListedIPRanges is a list of IP ranges from which requests will be allowed.
 	WhiteListedIPRanges []IPRange
 	// MaxRetries is the maximum number of times to retry a commit before giving up.
@@ -103,6 +143,11 @@ type WorkerOptions struct {
 	LudicrousConcurrency int
 	// EncryptionKey is the key used for encryption at rest, backups, exports. Enterprise only feature.
 	EncryptionKey SensitiveByteSlice
+	// BackupPublicKey is the public key backups are encrypted with instead of EncryptionKey, so
+	// that restoring them needs the private key. Enterprise only feature.
+	BackupPublicKey crypto.PublicKey
+	// BackupSigningKey is the key backup manifests are signed with. Enterprise only feature.
+	BackupSigningKey ed25519.PrivateKey
 	// LogRequest indicates whether alpha should log all query/mutation requests coming to it.
 	// Ideally LogRequest should be a bool value. But we are reading it using atomics across
 	// queries hence it has been kept as int32. LogRequest value 1 enables logging of requests
@@ -119,7 +164,7 @@ type WorkerOptions struct {
 var WorkerConfig WorkerOptions
 
 func (w *WorkerOptions) Parse(conf *viper.Viper) {
-	w.MyAddr = conf.GetString("my")
+	w.MyAddr = NormalizeAddress(conf.GetString("my"))
 	w.Tracing = conf.GetFloat64("trace")
 
 	if w.LudicrousMode {
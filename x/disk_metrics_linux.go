@@ -32,16 +32,25 @@ func MonitorDiskMetrics(dirTag string, dir string, lc *z.Closer) {
 		case <-lc.HasBeenClosed():
 			return
 		case <-fastTicker.C:
-			s := syscall.Statfs_t{}
-			err = syscall.Statfs(dir, &s)
+			total, free, err := DiskStats(dir)
 			if err != nil {
 				continue
 			}
-			reservedBlocks := s.Bfree - s.Bavail
-			total := int64(s.Frsize) * int64(s.Blocks-reservedBlocks)
-			free := int64(s.Frsize) * int64(s.Bavail)
 			stats.Record(ctx, DiskFree.M(free), DiskUsed.M(total-free), DiskTotal.M(total))
 		}
 	}
 
 }
+
+// DiskStats returns the total and the available number of bytes on the file system which
+// holds the given directory. Blocks reserved for the root user are not counted.
+func DiskStats(dir string) (total, free int64, err error) {
+	s := syscall.Statfs_t{}
+	if err = syscall.Statfs(dir, &s); err != nil {
+		return 0, 0, err
+	}
+	reservedBlocks := s.Bfree - s.Bavail
+	total = int64(s.Frsize) * int64(s.Blocks-reservedBlocks)
+	free = int64(s.Frsize) * int64(s.Bavail)
+	return total, free, nil
+}
@@ -5,9 +5,15 @@ package x
 import (
 	"github.com/dgraph-io/ristretto/z"
 	"github.com/golang/glog"
+	"github.com/pkg/errors"
 )
 
 func MonitorDiskMetrics(_ string, _ string, lc *z.Closer) {
 	defer lc.Done()
 	glog.Infoln("File system metrics are not currently supported on non-Linux platforms")
 }
+
+// DiskStats is not supported on non-Linux platforms.
+func DiskStats(_ string) (total, free int64, err error) {
+	return 0, 0, errors.New("file system stats are not supported on non-Linux platforms")
+}
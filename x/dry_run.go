@@ -0,0 +1,54 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// dryRunKey is the key in the context metadata asking to dry run the mutations of a request.
+// Over HTTP, the dryRun query parameter of /mutate sets it.
+const dryRunKey = "dry-run"
+
+// WithDryRun marks the request in the context as a dry run. The mutations of a dry run are
+// parsed, validated against the schema and authorized, and their conflict keys are computed,
+// but nothing is written.
+func WithDryRun(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.New(nil)
+	}
+	md.Set(dryRunKey, "true")
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+// IsDryRun returns whether the request in the context is a dry run.
+func IsDryRun(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	vals := md.Get(dryRunKey)
+	if len(vals) == 0 {
+		return false
+	}
+	dryRun, err := strconv.ParseBool(vals[0])
+	return err == nil && dryRun
+}
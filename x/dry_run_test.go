@@ -0,0 +1,35 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestIsDryRun(t *testing.T) {
+	require.False(t, IsDryRun(context.Background()))
+	require.True(t, IsDryRun(WithDryRun(context.Background())))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("dry-run", "false"))
+	require.False(t, IsDryRun(ctx))
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("dry-run", "1"))
+	require.True(t, IsDryRun(ctx))
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DurabilityHeader is the HTTP header used to set the durability of a commit. gRPC clients set it
+// via the "durability" key in the context metadata.
+const DurabilityHeader = "X-Dgraph-Durability"
+
+// Durability is how durable a commit must be before it is acknowledged to the client.
+type Durability int
+
+const (
+	// DurabilityCommitted acknowledges a commit once it has been committed by Raft and applied
+	// by the leader of the groups it touched. This is the default.
+	DurabilityCommitted Durability = iota
+	// DurabilityReplicated additionally waits for at least one follower of each group touched by
+	// the transaction to have applied the commit, so that losing the disk of the leader doesn't
+	// lose an acknowledged write.
+	DurabilityReplicated
+)
+
+func (d Durability) String() string {
+	if d == DurabilityReplicated {
+		return "replicated"
+	}
+	return "committed"
+}
+
+// ParseDurability parses the name of a durability level.
+func ParseDurability(s string) (Durability, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "committed":
+		return DurabilityCommitted, nil
+	case "replicated":
+		return DurabilityReplicated, nil
+	}
+	return DurabilityCommitted, errors.Errorf("invalid durability: %q. Valid values are "+
+		"committed and replicated", s)
+}
+
+// AttachDurability adds the durability from the incoming HTTP header into the grpc context
+// metadata.
+func AttachDurability(ctx context.Context, r *http.Request) context.Context {
+	if durability := r.Header.Get(DurabilityHeader); durability != "" {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			md = metadata.New(nil)
+		} else {
+			// The metadata may be shared with other contexts, so it must not be modified.
+			md = md.Copy()
+		}
+
+		md.Append("durability", durability)
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	return ctx
+}
+
+// DurabilityFromContext returns the durability set in the context metadata. Requests without a
+// durability use DurabilityCommitted, and requests with an invalid one get an InvalidArgument
+// error.
+func DurabilityFromContext(ctx context.Context) (Durability, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return DurabilityCommitted, nil
+	}
+	vals := md.Get("durability")
+	if len(vals) == 0 {
+		return DurabilityCommitted, nil
+	}
+	d, err := ParseDurability(vals[0])
+	if err != nil {
+		return DurabilityCommitted, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return d, nil
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestDurabilityFromContext(t *testing.T) {
+	d, err := DurabilityFromContext(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, DurabilityCommitted, d)
+
+	r, err := http.NewRequest(http.MethodPost, "/mutate", nil)
+	require.NoError(t, err)
+	r.Header.Set(DurabilityHeader, "Replicated")
+	d, err = DurabilityFromContext(AttachDurability(context.Background(), r))
+	require.NoError(t, err)
+	require.Equal(t, DurabilityReplicated, d)
+
+	r.Header.Set(DurabilityHeader, "fsync")
+	_, err = DurabilityFromContext(AttachDurability(context.Background(), r))
+	require.Error(t, err)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAttachDurabilityCopiesMetadata(t *testing.T) {
+	md := metadata.New(map[string]string{"durability": "committed"})
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+
+	r, err := http.NewRequest(http.MethodPost, "/mutate", nil)
+	require.NoError(t, err)
+	r.Header.Set(DurabilityHeader, "replicated")
+	AttachDurability(ctx, r)
+	require.Equal(t, []string{"committed"}, md.Get("durability"))
+}
@@ -0,0 +1,504 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/dustin/go-humanize"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	ostats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// This file has the resource governor. It samples the memory and CPU used by this process,
+// records them as metrics, and consults its policies to decide whether the process is under
+// pressure. The query and mutation paths call AdmitRequest before doing any work, which sheds
+// low priority requests while the process is under pressure. The governor also runs the
+// periodic value log GC, which is skipped under pressure.
+
+const (
+	// ShedDefaults are the default values for the --shedding superflag.
+	ShedDefaults = "memory-mb=0; cpu-percent=0; queue-timeout=5s"
+
+	// sampleInterval is the interval at which the resource usage is sampled.
+	sampleInterval = 500 * time.Millisecond
+	// memStatsInterval is the interval at which the Go runtime memory stats are recorded.
+	// ReadMemStats stops the world which is expensive especially when the heap is large. So
+	// don't call it too frequently.
+	memStatsInterval = time.Minute
+	// clockTicks is the number of clock ticks per second used in /proc/self/stat.
+	clockTicks = 100
+)
+
+// ErrRequestShed is returned for requests which are rejected because the server is under
+// memory or CPU pressure.
+var ErrRequestShed = errors.New("Server is overloaded and the request was shed. Please retry later")
+
+// Usage is a snapshot of the resources used by this process.
+type Usage struct {
+	// Memory is the resident set size of the process in bytes.
+	Memory int64
+	// Alloc is the number of bytes allocated outside of the Go heap via z.Calloc.
+	Alloc int64
+	// CPU is the fraction of the available CPU used since the previous sample.
+	CPU float64
+}
+
+// Policy decides whether the process is under pressure given its resource usage. Policies are
+// added to the governor via AddPolicy, and are consulted every time the usage is sampled.
+type Policy interface {
+	// Name is used to report which policies are reporting pressure.
+	Name() string
+	UnderPressure(u Usage) bool
+}
+
+type memoryPolicy struct {
+	limit int64
+}
+
+// MemoryPolicy reports pressure when the resident set size of the process goes above limit bytes.
+func MemoryPolicy(limit int64) Policy {
+	return &memoryPolicy{limit: limit}
+}
+
+func (p *memoryPolicy) Name() string {
+	return "memory"
+}
+
+func (p *memoryPolicy) UnderPressure(u Usage) bool {
+	return u.Memory > p.limit
+}
+
+type cpuPolicy struct {
+	limit float64
+}
+
+// CPUPolicy reports pressure when the process uses more than percent of the available CPU.
+func CPUPolicy(percent float64) Policy {
+	return &cpuPolicy{limit: percent / 100}
+}
+
+func (p *cpuPolicy) Name() string {
+	return "cpu"
+}
+
+func (p *cpuPolicy) UnderPressure(u Usage) bool {
+	return u.CPU > p.limit
+}
+
+type governor struct {
+	sync.RWMutex
+	policies     []Policy
+	queueTimeout time.Duration
+	usage        Usage
+
+	// pressure is 1 when any of the policies reports pressure.
+	pressure int32
+
+	// lastCPU and lastTs are the CPU time and the time of the previous sample. They are only
+	// accessed by RunGovernor.
+	lastCPU time.Duration
+	lastTs  time.Time
+}
+
+var gov = &governor{queueTimeout: 5 * time.Second}
+
+// InitGovernor parses the --shedding superflag and adds the memory and CPU policies for the
+// limits set in it.
+func InitGovernor(sf *z.SuperFlag) error {
+	memLimit := sf.GetInt64("memory-mb") << 20
+	cpuLimit := sf.GetFloat64("cpu-percent")
+	queueTimeout, err := time.ParseDuration(sf.GetString("queue-timeout"))
+	if err != nil {
+		return errors.Wrapf(err, "while parsing queue-timeout")
+	}
+	switch {
+	case memLimit < 0:
+		return errors.Errorf("memory-mb must be non-negative")
+	case cpuLimit < 0 || cpuLimit > 100:
+		return errors.Errorf("cpu-percent must be in the range [0, 100]")
+	case queueTimeout < 0:
+		return errors.Errorf("queue-timeout must be non-negative")
+	}
+
+	gov.Lock()
+	gov.queueTimeout = queueTimeout
+	gov.Unlock()
+	if memLimit > 0 {
+		AddPolicy(MemoryPolicy(memLimit))
+	}
+	if cpuLimit > 0 {
+		AddPolicy(CPUPolicy(cpuLimit))
+	}
+	return nil
+}
+
+// AddPolicy adds a policy to the governor. It takes effect from the next sample.
+func AddPolicy(p Policy) {
+	gov.Lock()
+	defer gov.Unlock()
+	gov.policies = append(gov.policies, p)
+}
+
+// CurrentUsage returns the resource usage as of the last sample.
+func CurrentUsage() Usage {
+	gov.RLock()
+	defer gov.RUnlock()
+	return gov.usage
+}
+
+// UnderPressure returns whether any of the policies of the governor reported pressure in the last
+// sample.
+func UnderPressure() bool {
+	return atomic.LoadInt32(&gov.pressure) == 1
+}
+
+// governorOnce starts the sampling of the governor only once per process, since Zero and Alpha
+// can run in the same process, see the embedded package.
+var governorOnce sync.Once
+
+// RunGovernor makes sure that the resource usage of this process is sampled, and returns once
+// closer is signalled. The usage is recorded as metrics and checked against the policies of the
+// governor. The first call starts the sampling, which then goes on for the life of the process.
+func RunGovernor(closer *z.Closer) {
+	defer closer.Done()
+
+	governorOnce.Do(func() {
+		// Sample immediately so that Dgraph reports memory stats without having to wait for the
+		// first tick.
+		gov.lastCPU, gov.lastTs = cpuTime(), time.Now()
+		gov.sample(true)
+		go gov.run()
+	})
+	<-closer.HasBeenClosed()
+}
+
+// run samples the resource usage every sampleInterval, and the Go runtime memory stats every
+// memStatsInterval.
+func (g *governor) run() {
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+	statsTicker := time.NewTicker(memStatsInterval)
+	defer statsTicker.Stop()
+
+	for {
+		select {
+		case <-statsTicker.C:
+			g.sample(true)
+		case <-ticker.C:
+			g.sample(false)
+		}
+	}
+}
+
+// sample samples the resource usage and updates the pressure. Reading the resident set size
+// needs a command to be run outside of Linux, so it is only read along with the Go runtime
+// memory stats, unless there are policies to check it against.
+func (g *governor) sample(full bool) {
+	g.RLock()
+	policies := g.policies
+	u := g.usage
+	g.RUnlock()
+
+	u.Alloc = z.NumAllocBytes()
+	ms := []ostats.Measurement{MemoryAlloc.M(u.Alloc)}
+	if full || len(policies) > 0 || runtime.GOOS == "linux" {
+		u.Memory = int64(getMemUsage())
+		ms = append(ms, MemoryProc.M(u.Memory))
+	}
+	if full {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		// From runtime/mstats.go:
+		// HeapIdle minus HeapReleased estimates the amount of memory
+		// that could be returned to the OS, but is being retained by
+		// the runtime so it can grow the heap without requesting more
+		// memory from the OS. If this difference is significantly
+		// larger than the heap size, it indicates there was a recent
+		// transient spike in live heap size.
+		ms = append(ms,
+			MemoryInUse.M(int64(stats.HeapInuse+stats.StackInuse)),
+			MemoryIdle.M(int64(stats.HeapIdle-stats.HeapReleased)))
+	}
+
+	cpu, ts := cpuTime(), time.Now()
+	if cpu > 0 && ts.After(g.lastTs) {
+		u.CPU = float64(cpu-g.lastCPU) / float64(ts.Sub(g.lastTs)) / float64(runtime.NumCPU())
+	}
+	g.lastCPU, g.lastTs = cpu, ts
+
+	var exceeded []string
+	for _, p := range policies {
+		if p.UnderPressure(u) {
+			exceeded = append(exceeded, p.Name())
+		}
+	}
+	g.Lock()
+	g.usage = u
+	g.Unlock()
+
+	pressure := len(exceeded) > 0
+	switch {
+	case pressure && !UnderPressure():
+		glog.Warningf("Resource usage is above the limits of policies: %v. "+
+			"Shedding low priority requests.", exceeded)
+		atomic.StoreInt32(&g.pressure, 1)
+	case !pressure && UnderPressure():
+		glog.Infof("Resource usage is back under the limits. Serving all requests.")
+		atomic.StoreInt32(&g.pressure, 0)
+	}
+	ms = append(ms, ResourcePressure.M(int64(atomic.LoadInt32(&g.pressure))))
+	ostats.Record(context.Background(), ms...)
+}
+
+// AdmitRequest decides whether a request with the priority class set in ctx should be served.
+// Under memory or CPU pressure batch requests are shed, and interactive requests wait for up to
+// the queue-timeout for the pressure to go away before being shed. Admin requests are always
+// served. It returns ErrRequestShed for requests which are shed.
+func AdmitRequest(ctx context.Context) error {
+	if !UnderPressure() {
+		return nil
+	}
+	p := PriorityFromContext(ctx)
+	shed := func() error {
+		cctx, _ := tag.New(ctx, tag.Upsert(KeyPriority, p.String()))
+		ostats.Record(cctx, NumRequestsShed.M(1))
+		return ErrRequestShed
+	}
+
+	switch p {
+	case PriorityAdmin:
+		return nil
+	case PriorityBatch:
+		return shed()
+	}
+
+	gov.RLock()
+	queueTimeout := gov.queueTimeout
+	gov.RUnlock()
+	timer := time.NewTimer(queueTimeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(sampleInterval / 10)
+	defer ticker.Stop()
+	for UnderPressure() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return shed()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// cpuTime returns the CPU time used by this process. It returns zero if it can't be read.
+func cpuTime() time.Duration {
+	if runtime.GOOS != "linux" {
+		return 0
+	}
+	contents, err := ioutil.ReadFile("/proc/self/stat")
+	if err != nil {
+		return 0
+	}
+	// The command name can have spaces, so skip past it before splitting. utime and stime are
+	// the 14th and 15th entries of the file.
+	if i := strings.LastIndexByte(string(contents), ')'); i >= 0 {
+		contents = contents[i+1:]
+	}
+	cont := strings.Fields(string(contents))
+	if len(cont) < 13 {
+		return 0
+	}
+	utime, err1 := strconv.ParseInt(cont[11], 10, 64)
+	stime, err2 := strconv.ParseInt(cont[12], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	return time.Duration(utime+stime) * time.Second / clockTicks
+}
+
+// getMemUsage returns the resident set size of this process in bytes.
+func getMemUsage() int {
+	if runtime.GOOS != "linux" {
+		pid := os.Getpid()
+		cmd := fmt.Sprintf("ps -ao rss,pid | grep %v", pid)
+		c1, err := exec.Command("bash", "-c", cmd).Output()
+		if err != nil {
+			// In case of error running the command, resort to go way
+			var ms runtime.MemStats
+			runtime.ReadMemStats(&ms)
+			megs := ms.Alloc
+			return int(megs)
+		}
+
+		rss := strings.Split(string(c1), " ")[0]
+		kbs, err := strconv.Atoi(rss)
+		if err != nil {
+			return 0
+		}
+
+		megs := kbs << 10
+		return megs
+	}
+
+	contents, err := ioutil.ReadFile("/proc/self/stat")
+	if err != nil {
+		glog.Errorf("Can't read the proc file. Err: %v\n", err)
+		return 0
+	}
+
+	cont := strings.Split(string(contents), " ")
+	// 24th entry of the file is the RSS which denotes the number of pages
+	// used by the process.
+	if len(cont) < 24 {
+		glog.Errorln("Error in RSS from stat")
+		return 0
+	}
+
+	rss, err := strconv.Atoi(cont[23])
+	if err != nil {
+		glog.Errorln(err)
+		return 0
+	}
+
+	return rss * os.Getpagesize()
+}
+
+// JemallocHandler writes the memory allocated via z.Calloc, along with the allocators in use.
+func JemallocHandler(w http.ResponseWriter, r *http.Request) {
+	AddCorsHeaders(w)
+
+	na := z.NumAllocBytes()
+	fmt.Fprintf(w, "Num Allocated Bytes: %s [%d]\n",
+		humanize.IBytes(uint64(na)), na)
+	fmt.Fprintf(w, "Allocators:\n%s\n", z.Allocators())
+	fmt.Fprintf(w, "%s\n", z.Leaks())
+}
+
+// LSMStaleBytes returns the bytes of stale data across all the levels of the LSM tree.
+func LSMStaleBytes(db *badger.DB) int64 {
+	var stale int64
+	for _, l := range db.Levels() {
+		stale += l.StaleDatSize
+	}
+	return stale
+}
+
+// RecordStorageMetrics records the amount of space which can be reclaimed in db.
+func RecordStorageMetrics(db *badger.DB) {
+	ostats.Record(context.Background(), BadgerLSMStaleBytes.M(LSMStaleBytes(db)))
+}
+
+var vlogGCPaused int32
+
+// PauseVlogGC pauses or resumes value log GC. A GC which is already running stops once the file
+// it's rewriting is done.
+func PauseVlogGC(pause bool) {
+	if pause {
+		atomic.StoreInt32(&vlogGCPaused, 1)
+		return
+	}
+	atomic.StoreInt32(&vlogGCPaused, 0)
+}
+
+// VlogGCPaused returns whether value log GC is paused.
+func VlogGCPaused() bool {
+	return atomic.LoadInt32(&vlogGCPaused) == 1
+}
+
+// ValueLogGC runs value log GC on store until no more files can be rewritten, or until it's
+// paused, and returns the number of files that were rewritten.
+func ValueLogGC(store *badger.DB, discardRatio float64) (int, error) {
+	var rewrites int
+	defer func() {
+		ostats.Record(context.Background(), BadgerVlogGCRewrites.M(int64(rewrites)))
+	}()
+	for !VlogGCPaused() {
+		// If a GC is successful, immediately run it again.
+		switch err := store.RunValueLogGC(discardRatio); err {
+		case nil:
+			rewrites++
+		case badger.ErrNoRewrite:
+			return rewrites, nil
+		default:
+			return rewrites, err
+		}
+	}
+	return rewrites, nil
+}
+
+// RunVlogGC runs value log gc on store. It runs GC unconditionally after every 10 minutes.
+// Additionally it also runs GC if vLogSize has grown more than 1 GB in last minute. No GC is
+// run while it is paused via PauseVlogGC, or while the governor is under pressure.
+func RunVlogGC(store *badger.DB, closer *z.Closer) {
+	defer closer.Done()
+
+	// Runs every 1m, checks size of vlog and runs GC conditionally.
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	abs := func(a, b int64) int64 {
+		if a > b {
+			return a - b
+		}
+		return b - a
+	}
+
+	var lastSz int64
+	runGC := func() {
+		RecordStorageMetrics(store)
+		if VlogGCPaused() {
+			return
+		}
+		if UnderPressure() {
+			glog.V(2).Infof("Skipping value log GC as resource usage is above the limits")
+			return
+		}
+		_, _ = ValueLogGC(store, 0.7)
+		_, sz := store.Size()
+		if abs(lastSz, sz) > 512<<20 {
+			glog.V(2).Infof("Value log size: %s\n", humanize.IBytes(uint64(sz)))
+			lastSz = sz
+		}
+	}
+
+	runGC()
+	for {
+		select {
+		case <-closer.HasBeenClosed():
+			return
+		case <-ticker.C:
+			runGC()
+		}
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmitRequest(t *testing.T) {
+	defer func(g *governor) { gov = g }(gov)
+	gov = &governor{queueTimeout: 100 * time.Millisecond, pressure: 1}
+
+	batch := WithPriority(context.Background(), PriorityBatch)
+	admin := WithPriority(context.Background(), PriorityAdmin)
+	require.Equal(t, ErrRequestShed, AdmitRequest(batch))
+	require.NoError(t, AdmitRequest(admin))
+	require.Equal(t, ErrRequestShed, AdmitRequest(context.Background()))
+
+	// Interactive requests are served once the pressure goes away.
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		atomic.StoreInt32(&gov.pressure, 0)
+	}()
+	require.NoError(t, AdmitRequest(context.Background()))
+	require.NoError(t, AdmitRequest(batch))
+}
+
+func TestGovernorPolicies(t *testing.T) {
+	defer func(g *governor) { gov = g }(gov)
+	gov = &governor{queueTimeout: time.Second}
+
+	var limit int64 = 1 << 40
+	AddPolicy(MemoryPolicy(limit))
+	gov.sample(false)
+	require.False(t, UnderPressure())
+	require.Greater(t, CurrentUsage().Memory, int64(0))
+
+	// A policy which always reports pressure.
+	AddPolicy(MemoryPolicy(-1))
+	gov.sample(false)
+	require.True(t, UnderPressure())
+	require.Equal(t, ErrRequestShed, AdmitRequest(WithPriority(context.Background(),
+		PriorityBatch)))
+
+	gov.policies = gov.policies[:1]
+	gov.sample(false)
+	require.False(t, UnderPressure())
+}
+
+func TestCPUPolicy(t *testing.T) {
+	p := CPUPolicy(50)
+	require.Equal(t, "cpu", p.Name())
+	require.False(t, p.UnderPressure(Usage{CPU: 0.4}))
+	require.True(t, p.UnderPressure(Usage{CPU: 0.6}))
+}
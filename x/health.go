@@ -28,13 +28,31 @@ var (
 	// mode is enabled
 	drainingMode uint32
 
-	healthCheck     uint32
-	errHealth       = errors.New("Please retry again, server is not ready to accept requests")
-	errDrainingMode = errors.New("the server is in draining mode " +
+	healthCheck uint32
+
+	// ErrHealth is returned for requests received before the server is ready to serve them.
+	ErrHealth = errors.New("Please retry again, server is not ready to accept requests")
+	// ErrDrainingMode is returned for requests received while the server is in draining mode.
+	ErrDrainingMode = errors.New("the server is in draining mode " +
 		"and client requests will only be allowed after exiting the mode " +
 		" by sending a GraphQL draining(enable: false) mutation to /admin")
 )
 
+// ReadOnlyError is returned for writes which are rejected because the cluster is in read-only
+// mode.
+type ReadOnlyError struct {
+	Reason string
+}
+
+func (e *ReadOnlyError) Error() string {
+	msg := "the cluster is in read-only mode and writes are rejected until it is disabled " +
+		"by sending a GraphQL readOnly(enable: false) mutation to /admin"
+	if e.Reason != "" {
+		msg += ". Reason: " + e.Reason
+	}
+	return msg
+}
+
 // UpdateHealthStatus updates the server's health status so it can start accepting requests.
 func UpdateHealthStatus(ok bool) {
 	setStatus(&healthCheck, ok)
@@ -50,10 +68,10 @@ func UpdateDrainingMode(enable bool) {
 // returning true
 func HealthCheck() error {
 	if atomic.LoadUint32(&healthCheck) == 0 {
-		return errHealth
+		return ErrHealth
 	}
 	if atomic.LoadUint32(&drainingMode) == 1 {
-		return errDrainingMode
+		return ErrDrainingMode
 	}
 	return nil
 }
@@ -24,12 +24,18 @@ import (
 )
 
 func ParseJWT(jwtStr string) (jwt.MapClaims, error) {
+	return ParseJWTWithSecret(jwtStr, WorkerConfig.HmacSecret)
+}
+
+// ParseJWTWithSecret is like ParseJWT, but verifies the signature of the token with secret
+// instead of the HMAC secret in WorkerConfig.
+func ParseJWTWithSecret(jwtStr string, secret []byte) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(jwtStr, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.Errorf("unexpected signing method: %v",
 				token.Header["alg"])
 		}
-		return []byte(WorkerConfig.HmacSecret), nil
+		return secret, nil
 	})
 
 	if err != nil {
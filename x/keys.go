@@ -656,10 +656,24 @@ var aclPredicateMap = map[string]struct{}{
 // predicates, but for all those which are PreDefined and whose value is not allowed to be mutated
 // by users. When renaming this also rename the IsGraphql context key in edgraph/server.go.
 var graphqlReservedPredicate = map[string]struct{}{
-	"dgraph.graphql.xid":     {},
-	"dgraph.graphql.schema":  {},
-	"dgraph.drop.op":         {},
-	"dgraph.graphql.p_query": {},
+	"dgraph.graphql.xid":            {},
+	"dgraph.graphql.schema":         {},
+	"dgraph.drop.op":                {},
+	"dgraph.graphql.p_query":        {},
+	"dgraph.graphql.lambda_scripts": {},
+	"dgraph.schema_history":         {},
+	"dgraph.lineage.blob":           {},
+	"dgraph.lineage.subject":        {},
+}
+
+// edgePredicateMap stores the predicates of the edge nodes holding the properties of edges. Unlike
+// the GraphQL reserved predicates, they can be set directly, so that exported edge nodes can be
+// loaded back.
+var edgePredicateMap = map[string]struct{}{
+	"dgraph.edge.src":  {},
+	"dgraph.edge.dst":  {},
+	"dgraph.edge.pred": {},
+	"dgraph.edge.key":  {},
 }
 
 // internalPredicateMap stores a set of Dgraph's internal predicate. An internal
@@ -676,6 +690,9 @@ var preDefinedTypeMap = map[string]struct{}{
 	"dgraph.type.Group":              {},
 	"dgraph.type.Rule":               {},
 	"dgraph.graphql.persisted_query": {},
+	"dgraph.graphql.lambda":          {},
+	"dgraph.schema_version":          {},
+	"dgraph.lineage":                 {},
 }
 
 // IsGraphqlReservedPredicate returns true if it is the predicate is reserved by graphql.
@@ -720,7 +737,14 @@ func IsReservedPredicate(pred string) bool {
 func IsPreDefinedPredicate(pred string) bool {
 	pred = ParseAttr(pred)
 	_, ok := starAllPredicateMap[strings.ToLower(pred)]
-	return ok || IsAclPredicate(pred) || IsGraphqlReservedPredicate(pred)
+	return ok || IsAclPredicate(pred) || IsGraphqlReservedPredicate(pred) || IsEdgePredicate(pred)
+}
+
+// IsEdgePredicate returns true if the predicate is one of those of the edge nodes holding the
+// properties of edges.
+func IsEdgePredicate(pred string) bool {
+	_, ok := edgePredicateMap[pred]
+	return ok
 }
 
 // IsAclPredicate returns true if the predicate is in the list of reserved
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// LineageHeader is the HTTP header used to attach the provenance of the mutations of a request,
+// a JSON object like {"source": "crm", "batch": "2021-05-04", "run": "nightly-17"}. gRPC clients
+// set it via the "lineage" key in the context metadata.
+const LineageHeader = "X-Dgraph-Lineage"
+
+// MaxLineageSize is the max size of the provenance of a request.
+const MaxLineageSize = 64 << 10
+
+// AttachLineage adds the provenance from the incoming HTTP header into the grpc context metadata.
+func AttachLineage(ctx context.Context, r *http.Request) context.Context {
+	if lineage := r.Header.Get(LineageHeader); lineage != "" {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			md = metadata.New(nil)
+		}
+
+		md.Set("lineage", lineage)
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	return ctx
+}
+
+// LineageFromContext returns the provenance set in the context metadata, or an empty string.
+func LineageFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	vals := md.Get("lineage")
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
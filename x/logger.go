@@ -30,6 +30,28 @@ type LoggerConf struct {
 	EncryptionKey SensitiveByteSlice
 	Size          int64
 	Days          int64
+	// Syslog, if set, is the syslog server the logs are sent to, with or instead of the files
+	// in Dir.
+	Syslog *SyslogConf
+}
+
+// SyslogConf holds the options of sending logs to a syslog server, for SIEM ingestion.
+type SyslogConf struct {
+	// Addr is the host:port of the server, which is connected to over TCP.
+	Addr string
+	// Format is the format of the messages, cef or leef.
+	Format string
+	// TLS connects to the server with TLS, verified with CACert, or the system CAs. ClientCert
+	// and ClientKey are the client certificate, if the server requires one.
+	TLS        bool
+	CACert     string
+	ClientCert string
+	ClientKey  string
+	// BufferSize is the number of messages buffered while the server is slow or unreachable.
+	BufferSize int
+	// Block makes logging wait for room in the buffer when it's full, instead of dropping the
+	// message.
+	Block bool
 }
 
 func InitLogger(conf *LoggerConf, filename string) (*Logger, error) {
@@ -0,0 +1,228 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"bufio"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// memcachedVirtualNodes is the number of points of each server on the hash ring, so that
+	// the keys are spread evenly across the servers.
+	memcachedVirtualNodes = 160
+	// memcachedMaxIdle is the number of idle connections kept per server.
+	memcachedMaxIdle = 16
+	// memcachedMaxTTL is the longest expiration time memcached takes as relative to now.
+	memcachedMaxTTL = 30 * 24 * time.Hour
+)
+
+// MemcachedRing is a client for a set of memcached servers, which spreads the keys across the
+// servers with consistent hashing. Adding or removing a server only moves the keys of that server.
+// It speaks the memcached text protocol.
+type MemcachedRing struct {
+	servers []*memcachedServer
+	// ring holds the points of the servers on the hash ring, sorted by hash.
+	ring []ringPoint
+}
+
+type ringPoint struct {
+	hash   uint32
+	server int
+}
+
+type memcachedServer struct {
+	addr    string
+	timeout time.Duration
+	idle    chan *memcachedConn
+}
+
+type memcachedConn struct {
+	nc net.Conn
+	rw *bufio.ReadWriter
+}
+
+// NewMemcachedRing returns a client for the memcached servers at addrs. Every request to a server,
+// including dialing it, must finish within timeout.
+func NewMemcachedRing(addrs []string, timeout time.Duration) (*MemcachedRing, error) {
+	if len(addrs) == 0 {
+		return nil, errors.Errorf("no memcached servers given")
+	}
+	r := &MemcachedRing{}
+	for i, addr := range addrs {
+		r.servers = append(r.servers, &memcachedServer{
+			addr:    addr,
+			timeout: timeout,
+			idle:    make(chan *memcachedConn, memcachedMaxIdle),
+		})
+		for v := 0; v < memcachedVirtualNodes; v++ {
+			h := crc32.ChecksumIEEE([]byte(fmt.Sprintf("%s-%d", addr, v)))
+			r.ring = append(r.ring, ringPoint{hash: h, server: i})
+		}
+	}
+	sort.Slice(r.ring, func(i, j int) bool { return r.ring[i].hash < r.ring[j].hash })
+	return r, nil
+}
+
+// server returns the server owning the key, i.e. the first one on the ring from the hash of
+// the key.
+func (r *MemcachedRing) server(key string) *memcachedServer {
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.ring), func(i int) bool { return r.ring[i].hash >= h })
+	if i == len(r.ring) {
+		i = 0
+	}
+	return r.servers[r.ring[i].server]
+}
+
+// Get returns the value of the key. The bool is false if the key isn't cached.
+func (r *MemcachedRing) Get(key string) ([]byte, bool, error) {
+	if err := checkMemcachedKey(key); err != nil {
+		return nil, false, err
+	}
+	var val []byte
+	var found bool
+	err := r.server(key).do(func(rw *bufio.ReadWriter) error {
+		if _, err := fmt.Fprintf(rw, "get %s\r\n", key); err != nil {
+			return err
+		}
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		for {
+			line, err := readMemcachedLine(rw)
+			if err != nil {
+				return err
+			}
+			if line == "END" {
+				return nil
+			}
+			// VALUE <key> <flags> <bytes>
+			fields := strings.Fields(line)
+			if len(fields) != 4 || fields[0] != "VALUE" {
+				return errors.Errorf("unexpected memcached response: %q", line)
+			}
+			size, err := strconv.Atoi(fields[3])
+			if err != nil {
+				return errors.Wrapf(err, "while parsing memcached response %q", line)
+			}
+			buf := make([]byte, size+2)
+			if _, err := io.ReadFull(rw, buf); err != nil {
+				return err
+			}
+			val, found = buf[:size], true
+		}
+	})
+	return val, found, err
+}
+
+// Set stores the value of the key, which expires after ttl.
+func (r *MemcachedRing) Set(key string, val []byte, ttl time.Duration) error {
+	if err := checkMemcachedKey(key); err != nil {
+		return err
+	}
+	if ttl > memcachedMaxTTL {
+		ttl = memcachedMaxTTL
+	}
+	return r.server(key).do(func(rw *bufio.ReadWriter) error {
+		if _, err := fmt.Fprintf(rw, "set %s 0 %d %d\r\n", key, int64(ttl.Seconds()),
+			len(val)); err != nil {
+			return err
+		}
+		if _, err := rw.Write(val); err != nil {
+			return err
+		}
+		if _, err := rw.WriteString("\r\n"); err != nil {
+			return err
+		}
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		return expectMemcachedReply(rw, "STORED")
+	})
+}
+
+// do runs fn on an idle connection to the server, or on a new one. The connection is closed if
+// fn fails, as it might be left in the middle of a response.
+func (s *memcachedServer) do(fn func(rw *bufio.ReadWriter) error) error {
+	var c *memcachedConn
+	select {
+	case c = <-s.idle:
+	default:
+		nc, err := net.DialTimeout("tcp", s.addr, s.timeout)
+		if err != nil {
+			return errors.Wrapf(err, "while connecting to memcached at %s", s.addr)
+		}
+		c = &memcachedConn{nc: nc, rw: bufio.NewReadWriter(bufio.NewReader(nc),
+			bufio.NewWriter(nc))}
+	}
+
+	if err := c.nc.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		c.nc.Close()
+		return err
+	}
+	if err := fn(c.rw); err != nil {
+		c.nc.Close()
+		return err
+	}
+	select {
+	case s.idle <- c:
+	default:
+		c.nc.Close()
+	}
+	return nil
+}
+
+func checkMemcachedKey(key string) error {
+	if len(key) == 0 || len(key) > 250 || strings.ContainsAny(key, " \r\n\t") {
+		return errors.Errorf("invalid memcached key: %q", key)
+	}
+	return nil
+}
+
+func readMemcachedLine(rw *bufio.ReadWriter) (string, error) {
+	line, err := rw.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "ERROR" || strings.HasPrefix(line, "SERVER_ERROR") ||
+		strings.HasPrefix(line, "CLIENT_ERROR") {
+		return "", errors.Errorf("memcached error: %s", line)
+	}
+	return line, nil
+}
+
+func expectMemcachedReply(rw *bufio.ReadWriter, want string) error {
+	line, err := readMemcachedLine(rw)
+	if err != nil {
+		return err
+	}
+	if line != want {
+		return errors.Errorf("unexpected memcached response: %q", line)
+	}
+	return nil
+}
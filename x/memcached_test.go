@@ -0,0 +1,120 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMemcached serves the get and set commands of the memcached text protocol.
+func fakeMemcached(t *testing.T) (string, map[string][]byte) {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	var mu sync.Mutex
+	data := make(map[string][]byte)
+	serve := func(c net.Conn) {
+		defer c.Close()
+		rw := bufio.NewReadWriter(bufio.NewReader(c), bufio.NewWriter(c))
+		for {
+			line, err := rw.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			mu.Lock()
+			switch fields[0] {
+			case "get":
+				if val, ok := data[fields[1]]; ok {
+					fmt.Fprintf(rw, "VALUE %s 0 %d\r\n%s\r\n", fields[1], len(val), val)
+				}
+				fmt.Fprint(rw, "END\r\n")
+			case "set":
+				size, _ := strconv.Atoi(fields[4])
+				buf := make([]byte, size+2)
+				if _, err := io.ReadFull(rw, buf); err != nil {
+					mu.Unlock()
+					return
+				}
+				data[fields[1]] = buf[:size]
+				fmt.Fprint(rw, "STORED\r\n")
+			default:
+				fmt.Fprint(rw, "ERROR\r\n")
+			}
+			mu.Unlock()
+			if err := rw.Flush(); err != nil {
+				return
+			}
+		}
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(c)
+		}
+	}()
+	return l.Addr().String(), data
+}
+
+func TestMemcachedRing(t *testing.T) {
+	addr1, data1 := fakeMemcached(t)
+	addr2, data2 := fakeMemcached(t)
+	r, err := NewMemcachedRing([]string{addr1, addr2}, time.Second)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, r.Set(fmt.Sprintf("key-%d", i), []byte(fmt.Sprintf("val\r\n%d", i)),
+			time.Minute))
+	}
+	// The keys are spread across the servers.
+	require.Equal(t, 100, len(data1)+len(data2))
+	require.NotEmpty(t, data1)
+	require.NotEmpty(t, data2)
+
+	val, found, err := r.Get("key-42")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "val\r\n42", string(val))
+
+	_, found, err = r.Get("missing")
+	require.NoError(t, err)
+	require.False(t, found)
+
+	_, _, err = r.Get("invalid key")
+	require.Error(t, err)
+
+	// Keys don't move between the remaining servers when a server is removed.
+	r1, err := NewMemcachedRing([]string{addr1}, time.Second)
+	require.NoError(t, err)
+	for key := range data1 {
+		require.Equal(t, r.server(key).addr, r1.server(key).addr)
+	}
+}
@@ -19,15 +19,8 @@ package x
 import (
 	"context"
 	"expvar"
-	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
-	"os"
-	"os/exec"
-	"runtime"
-	"strconv"
-	"strings"
 	"time"
 
 	"go.opencensus.io/trace"
@@ -37,7 +30,6 @@ import (
 	datadog "github.com/DataDog/opencensus-go-exporter-datadog"
 	"github.com/dgraph-io/badger/v3"
 	"github.com/dgraph-io/ristretto/z"
-	"github.com/dustin/go-humanize"
 	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/viper"
@@ -68,6 +60,72 @@ var (
 	// NumBackupsFailed is the number of backups failed
 	NumBackupsFailed = stats.Int64("num_backups_failed_total",
 		"Total number of backups failed", stats.UnitDimensionless)
+	// NumGraphQLCacheHits is the number of GraphQL queries served from the @cache cache.
+	NumGraphQLCacheHits = stats.Int64("num_graphql_cache_hits_total",
+		"Total number of GraphQL queries served from the cache", stats.UnitDimensionless)
+	// NumGraphQLCacheMisses is the number of GraphQL queries with @cache not found in the cache.
+	NumGraphQLCacheMisses = stats.Int64("num_graphql_cache_misses_total",
+		"Total number of cacheable GraphQL queries not found in the cache", stats.UnitDimensionless)
+	// NumLambdaRestarts is the number of times a lambda server run by Alpha was restarted.
+	NumLambdaRestarts = stats.Int64("num_lambda_restarts_total",
+		"Total number of lambda server restarts", stats.UnitDimensionless)
+	// LambdaLatencyMs is the latency of the requests to the lambda server, per resolver.
+	LambdaLatencyMs = stats.Float64("lambda_latency",
+		"Latency of the lambda resolvers", stats.UnitMilliseconds)
+	// ZeroProposalLatencyMs is the latency of the Raft proposals of Zero, per kind of proposal.
+	ZeroProposalLatencyMs = stats.Float64("zero_proposal_latency",
+		"Latency of the Zero proposals", stats.UnitMilliseconds)
+	// ZeroLeaseLatencyMs is the latency of handing out timestamps, UIDs and namespace IDs.
+	ZeroLeaseLatencyMs = stats.Float64("zero_lease_latency",
+		"Latency of the leases of timestamps, UIDs and namespace IDs", stats.UnitMilliseconds)
+	// ZeroLeaseExtensions is the number of times Zero extended a lease via Raft.
+	ZeroLeaseExtensions = stats.Int64("zero_lease_extensions_total",
+		"Total number of lease extensions by Zero", stats.UnitDimensionless)
+	// ZeroLeasedIds is the number of timestamps, UIDs and namespace IDs handed out by Zero. Its
+	// rate is the lease assignment throughput.
+	ZeroLeasedIds = stats.Int64("zero_leased_ids_total",
+		"Total number of timestamps, UIDs and namespace IDs leased by Zero",
+		stats.UnitDimensionless)
+	// ZeroTxnCommits is the number of transactions committed by Zero.
+	ZeroTxnCommits = stats.Int64("zero_txn_commits_total",
+		"Total number of transactions committed by Zero", stats.UnitDimensionless)
+	// ZeroTxnAborts is the number of transactions aborted by Zero, per reason.
+	ZeroTxnAborts = stats.Int64("zero_txn_aborts_total",
+		"Total number of transactions aborted by Zero", stats.UnitDimensionless)
+	// NumCacheTierHits is the number of posting lists read from the cache tier.
+	NumCacheTierHits = stats.Int64("num_cache_tier_hits_total",
+		"Total number of posting lists read from the cache tier", stats.UnitDimensionless)
+	// NumCacheTierMisses is the number of posting lists not found in the cache tier.
+	NumCacheTierMisses = stats.Int64("num_cache_tier_misses_total",
+		"Total number of posting lists not found in the cache tier", stats.UnitDimensionless)
+	// NumCommitHookFailures is the number of commits for which the commit hook failed.
+	NumCommitHookFailures = stats.Int64("num_commit_hook_failures_total",
+		"Total number of commit hook failures", stats.UnitDimensionless)
+	// NumListPartsCreated is the number of parts of multi-part posting lists created by splits.
+	NumListPartsCreated = stats.Int64("num_posting_list_parts_created_total",
+		"Total number of posting list parts created by splits", stats.UnitDimensionless)
+	// NumListPartsRemoved is the number of parts of multi-part posting lists removed because
+	// they became empty or were merged into other parts.
+	NumListPartsRemoved = stats.Int64("num_posting_list_parts_removed_total",
+		"Total number of posting list parts removed by merges", stats.UnitDimensionless)
+	// NumListRewrites is the number of multi-part posting lists rewritten via the admin API.
+	NumListRewrites = stats.Int64("num_posting_list_rewrites_total",
+		"Total number of multi-part posting lists rewritten", stats.UnitDimensionless)
+	// PostingDeltaLength is the number of deltas on top of the complete posting list of the
+	// lists read from disk, per predicate. Each of them is an extra read until the list is
+	// rolled up.
+	PostingDeltaLength = stats.Int64("posting_delta_length",
+		"Number of deltas of the posting lists read from disk", stats.UnitDimensionless)
+	// NumRollups is the number of posting lists rolled up, per predicate.
+	NumRollups = stats.Int64("num_rollups_total",
+		"Total number of posting lists rolled up", stats.UnitDimensionless)
+	// NumQuarantinedKeys is the number of corrupted posting lists quarantined, per predicate.
+	NumQuarantinedKeys = stats.Int64("num_quarantined_keys_total",
+		"Total number of corrupted posting lists quarantined", stats.UnitDimensionless)
+	// NumIndexDivergences is the number of index entries found diverging from the data by the
+	// index verifier, per predicate.
+	NumIndexDivergences = stats.Int64("num_index_divergences_total",
+		"Total number of index entries found diverging from the data", stats.UnitDimensionless)
 	// LatencyMs is the latency of the various Dgraph operations.
 	LatencyMs = stats.Float64("latency",
 		"Latency of the various methods", stats.UnitMilliseconds)
@@ -104,6 +162,37 @@ var (
 	// DiskTotal records the number of bytes free on the disk
 	DiskTotal = stats.Int64("disk_total_bytes",
 		"Total number of bytes on disk", stats.UnitBytes)
+	// DiskDaysUntilFull records the projected number of days until the disk limit is reached.
+	DiskDaysUntilFull = stats.Float64("disk_days_until_full",
+		"Projected number of days until the disk limit is reached", stats.UnitDimensionless)
+	// DiskWritesBlocked records whether writes are being refused because of the disk limit.
+	DiskWritesBlocked = stats.Int64("disk_writes_blocked",
+		"Whether writes are refused because the disk limit was reached", stats.UnitDimensionless)
+	// BadgerLSMStaleBytes records the bytes of stale data in the LSM tree, which compactions
+	// can reclaim.
+	BadgerLSMStaleBytes = stats.Int64("badger_lsm_stale_bytes",
+		"Bytes of stale data in the LSM tree which can be reclaimed by compactions",
+		stats.UnitBytes)
+	// BadgerVlogGCRewrites records the number of value log files rewritten by value log GC.
+	BadgerVlogGCRewrites = stats.Int64("badger_vlog_gc_rewrites_total",
+		"Number of value log files rewritten by value log GC", stats.UnitDimensionless)
+	// NumRequestsShed is the number of requests shed because of memory or CPU pressure.
+	NumRequestsShed = stats.Int64("num_requests_shed_total",
+		"Number of requests shed because of memory or CPU pressure", stats.UnitDimensionless)
+	// ResourcePressure records whether the resource governor is shedding requests.
+	ResourcePressure = stats.Int64("resource_pressure",
+		"Whether requests are shed because of memory or CPU pressure", stats.UnitDimensionless)
+	// LicenseEnabled records whether the enterprise features are enabled by the license.
+	LicenseEnabled = stats.Int64("enterprise_license_enabled",
+		"Whether the enterprise features are enabled by the license", stats.UnitDimensionless)
+	// LicenseExpiresIn records the seconds until the enterprise license expires.
+	LicenseExpiresIn = stats.Int64("enterprise_license_expires_in_seconds",
+		"Seconds until the enterprise license expires, negative once it has expired",
+		stats.UnitSeconds)
+	// LicenseNodes records the number of Zeros and Alphas counted against the license.
+	LicenseNodes = stats.Int64("enterprise_license_nodes",
+		"Number of Zeros and Alphas counted against the maximum of the license",
+		stats.UnitDimensionless)
 	// ActiveMutations is the current number of active mutations.
 	ActiveMutations = stats.Int64("active_mutations_total",
 		"Number of active mutations", stats.UnitDimensionless)
@@ -147,6 +236,13 @@ var (
 	// RaftLeaderChanges records the total number of leader changes seen.
 	RaftLeaderChanges = stats.Int64("raft_leader_changes_total",
 		"Total number of leader changes seen", stats.UnitDimensionless)
+	// ZeroTabletSize records the on-disk size of the tablets, as last reported to Zero.
+	ZeroTabletSize = stats.Int64("zero_tablet_size_bytes",
+		"On-disk size of the tablets", stats.UnitBytes)
+	// ZeroOracleCommits records the number of transaction statuses kept by the oracle of Zero,
+	// until the Alphas have applied them.
+	ZeroOracleCommits = stats.Int64("zero_oracle_commits",
+		"Number of transaction statuses kept by the oracle", stats.UnitDimensionless)
 
 	// Conf holds the metrics config.
 	// TODO: Request statistics, latencies, 500, timeouts
@@ -162,9 +258,21 @@ var (
 	// KeyMethod is the tag key used to record the method (e.g read or mutate).
 	KeyMethod, _ = tag.NewKey("method")
 
+	// KeyPriority is the tag key used to record the priority class of a request.
+	KeyPriority, _ = tag.NewKey("priority")
+
 	// KeyDirType is the tag key used to record the group for FileSystem metrics
 	KeyDirType, _ = tag.NewKey("dir")
 
+	// KeyLease is the tag key used to record the kind of lease, i.e. timestamps, uids or nsids.
+	KeyLease, _ = tag.NewKey("lease")
+
+	// KeyReason is the tag key used to record why a transaction was aborted.
+	KeyReason, _ = tag.NewKey("reason")
+
+	// KeyPredicate is the tag key used to record the predicate of a tablet.
+	KeyPredicate, _ = tag.NewKey("predicate")
+
 	// Tag values.
 
 	// TagValueStatusOK is the tag value used to signal a successful operation.
@@ -223,6 +331,146 @@ var (
 			Aggregation: view.Count(),
 			TagKeys:     nil,
 		},
+		{
+			Name:        NumGraphQLCacheHits.Name(),
+			Measure:     NumGraphQLCacheHits,
+			Description: NumGraphQLCacheHits.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        NumGraphQLCacheMisses.Name(),
+			Measure:     NumGraphQLCacheMisses,
+			Description: NumGraphQLCacheMisses.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        NumLambdaRestarts.Name(),
+			Measure:     NumLambdaRestarts,
+			Description: NumLambdaRestarts.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        LambdaLatencyMs.Name(),
+			Measure:     LambdaLatencyMs,
+			Description: LambdaLatencyMs.Description(),
+			Aggregation: defaultLatencyMsDistribution,
+			TagKeys:     allTagKeys,
+		},
+		{
+			Name:        NumCacheTierHits.Name(),
+			Measure:     NumCacheTierHits,
+			Description: NumCacheTierHits.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        NumCacheTierMisses.Name(),
+			Measure:     NumCacheTierMisses,
+			Description: NumCacheTierMisses.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        NumCommitHookFailures.Name(),
+			Measure:     NumCommitHookFailures,
+			Description: NumCommitHookFailures.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        NumListPartsCreated.Name(),
+			Measure:     NumListPartsCreated,
+			Description: NumListPartsCreated.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        NumListPartsRemoved.Name(),
+			Measure:     NumListPartsRemoved,
+			Description: NumListPartsRemoved.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        NumListRewrites.Name(),
+			Measure:     NumListRewrites,
+			Description: NumListRewrites.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        PostingDeltaLength.Name(),
+			Measure:     PostingDeltaLength,
+			Description: PostingDeltaLength.Description(),
+			Aggregation: view.Distribution(1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000),
+			TagKeys:     []tag.Key{KeyPredicate},
+		},
+		{
+			Name:        NumRollups.Name(),
+			Measure:     NumRollups,
+			Description: NumRollups.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{KeyPredicate},
+		},
+		{
+			Name:        NumQuarantinedKeys.Name(),
+			Measure:     NumQuarantinedKeys,
+			Description: NumQuarantinedKeys.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{KeyPredicate},
+		},
+		{
+			Name:        NumIndexDivergences.Name(),
+			Measure:     NumIndexDivergences,
+			Description: NumIndexDivergences.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{KeyPredicate},
+		},
+		{
+			Name:        ZeroProposalLatencyMs.Name(),
+			Measure:     ZeroProposalLatencyMs,
+			Description: ZeroProposalLatencyMs.Description(),
+			Aggregation: defaultLatencyMsDistribution,
+			TagKeys:     []tag.Key{KeyMethod},
+		},
+		{
+			Name:        ZeroLeaseLatencyMs.Name(),
+			Measure:     ZeroLeaseLatencyMs,
+			Description: ZeroLeaseLatencyMs.Description(),
+			Aggregation: defaultLatencyMsDistribution,
+			TagKeys:     []tag.Key{KeyLease, KeyStatus},
+		},
+		{
+			Name:        ZeroLeaseExtensions.Name(),
+			Measure:     ZeroLeaseExtensions,
+			Description: ZeroLeaseExtensions.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{KeyLease},
+		},
+		{
+			Name:        ZeroLeasedIds.Name(),
+			Measure:     ZeroLeasedIds,
+			Description: ZeroLeasedIds.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{KeyLease},
+		},
+		{
+			Name:        ZeroTxnCommits.Name(),
+			Measure:     ZeroTxnCommits,
+			Description: ZeroTxnCommits.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        ZeroTxnAborts.Name(),
+			Measure:     ZeroTxnAborts,
+			Description: ZeroTxnAborts.Description(),
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{KeyReason},
+		},
 		{
 			Name:        TxnCommits.Name(),
 			Measure:     TxnCommits,
@@ -323,6 +571,69 @@ var (
 			Aggregation: view.LastValue(),
 			TagKeys:     allFSKeys,
 		},
+		{
+			Name:        DiskDaysUntilFull.Name(),
+			Measure:     DiskDaysUntilFull,
+			Description: DiskDaysUntilFull.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        DiskWritesBlocked.Name(),
+			Measure:     DiskWritesBlocked,
+			Description: DiskWritesBlocked.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        NumRequestsShed.Name(),
+			Measure:     NumRequestsShed,
+			Description: NumRequestsShed.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     []tag.Key{KeyPriority},
+		},
+		{
+			Name:        ResourcePressure.Name(),
+			Measure:     ResourcePressure,
+			Description: ResourcePressure.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        LicenseEnabled.Name(),
+			Measure:     LicenseEnabled,
+			Description: LicenseEnabled.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        LicenseExpiresIn.Name(),
+			Measure:     LicenseExpiresIn,
+			Description: LicenseExpiresIn.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        LicenseNodes.Name(),
+			Measure:     LicenseNodes,
+			Description: LicenseNodes.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        BadgerLSMStaleBytes.Name(),
+			Measure:     BadgerLSMStaleBytes,
+			Description: BadgerLSMStaleBytes.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     nil,
+		},
+		{
+			Name:        BadgerVlogGCRewrites.Name(),
+			Measure:     BadgerVlogGCRewrites,
+			Description: BadgerVlogGCRewrites.Description(),
+			Aggregation: view.Sum(),
+			TagKeys:     nil,
+		},
 		{
 			Name:        AlphaHealth.Name(),
 			Measure:     AlphaHealth,
@@ -358,6 +669,20 @@ var (
 			Aggregation: view.LastValue(),
 			TagKeys:     allTagKeys,
 		},
+		{
+			Name:        ZeroTabletSize.Name(),
+			Measure:     ZeroTabletSize,
+			Description: ZeroTabletSize.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{KeyGroup, KeyPredicate},
+		},
+		{
+			Name:        ZeroOracleCommits.Name(),
+			Measure:     ZeroOracleCommits,
+			Description: ZeroOracleCommits.Description(),
+			Aggregation: view.LastValue(),
+			TagKeys:     nil,
+		},
 		// Raft metrics
 		{
 			Name:        RaftAppliedIndex.Name(),
@@ -597,108 +922,3 @@ func MonitorCacheHealth(db *badger.DB, closer *z.Closer) {
 		}
 	}
 }
-
-func MonitorMemoryMetrics(lc *z.Closer) {
-	defer lc.Done()
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
-	fastTicker := time.NewTicker(time.Second)
-	defer fastTicker.Stop()
-
-	update := func() {
-		// ReadMemStats stops the world which is expensive especially when the
-		// heap is large. So don't call it too frequently. Calling it every
-		// minute is OK.
-		var ms runtime.MemStats
-		runtime.ReadMemStats(&ms)
-
-		inUse := ms.HeapInuse + ms.StackInuse
-		// From runtime/mstats.go:
-		// HeapIdle minus HeapReleased estimates the amount of memory
-		// that could be returned to the OS, but is being retained by
-		// the runtime so it can grow the heap without requesting more
-		// memory from the OS. If this difference is significantly
-		// larger than the heap size, it indicates there was a recent
-		// transient spike in live heap size.
-		idle := ms.HeapIdle - ms.HeapReleased
-
-		ostats.Record(context.Background(),
-			MemoryInUse.M(int64(inUse)),
-			MemoryIdle.M(int64(idle)),
-			MemoryProc.M(int64(getMemUsage())))
-	}
-	updateAlloc := func() {
-		ostats.Record(context.Background(), MemoryAlloc.M(z.NumAllocBytes()))
-	}
-	// Call update immediately so that Dgraph reports memory stats without
-	// having to wait for the first tick.
-	update()
-	updateAlloc()
-
-	for {
-		select {
-		case <-lc.HasBeenClosed():
-			return
-		case <-fastTicker.C:
-			updateAlloc()
-		case <-ticker.C:
-			update()
-		}
-	}
-}
-
-func getMemUsage() int {
-	if runtime.GOOS != "linux" {
-		pid := os.Getpid()
-		cmd := fmt.Sprintf("ps -ao rss,pid | grep %v", pid)
-		c1, err := exec.Command("bash", "-c", cmd).Output()
-		if err != nil {
-			// In case of error running the command, resort to go way
-			var ms runtime.MemStats
-			runtime.ReadMemStats(&ms)
-			megs := ms.Alloc
-			return int(megs)
-		}
-
-		rss := strings.Split(string(c1), " ")[0]
-		kbs, err := strconv.Atoi(rss)
-		if err != nil {
-			return 0
-		}
-
-		megs := kbs << 10
-		return megs
-	}
-
-	contents, err := ioutil.ReadFile("/proc/self/stat")
-	if err != nil {
-		glog.Errorf("Can't read the proc file. Err: %v\n", err)
-		return 0
-	}
-
-	cont := strings.Split(string(contents), " ")
-	// 24th entry of the file is the RSS which denotes the number of pages
-	// used by the process.
-	if len(cont) < 24 {
-		glog.Errorln("Error in RSS from stat")
-		return 0
-	}
-
-	rss, err := strconv.Atoi(cont[23])
-	if err != nil {
-		glog.Errorln(err)
-		return 0
-	}
-
-	return rss * os.Getpagesize()
-}
-
-func JemallocHandler(w http.ResponseWriter, r *http.Request) {
-	AddCorsHeaders(w)
-
-	na := z.NumAllocBytes()
-	fmt.Fprintf(w, "Num Allocated Bytes: %s [%d]\n",
-		humanize.IBytes(uint64(na)), na)
-	fmt.Fprintf(w, "Allocators:\n%s\n", z.Allocators())
-	fmt.Fprintf(w, "%s\n", z.Leaks())
-}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+)
+
+// PriorityHeader is the HTTP header used to set the priority class of a request. gRPC clients
+// set it via the "priority" key in the context metadata. The admin class is only granted to the
+// /admin endpoint and to guardians.
+const PriorityHeader = "X-Dgraph-Priority"
+
+// Priority is the priority class of a request. Under memory or CPU pressure, batch requests
+// are shed first, interactive requests are queued, and admin requests are always served.
+type Priority int
+
+const (
+	// PriorityInteractive is the default priority of a request.
+	PriorityInteractive Priority = iota
+	// PriorityBatch is used for bulk work like loaders and reports.
+	PriorityBatch
+	// PriorityAdmin is used for the requests needed to operate the cluster.
+	PriorityAdmin
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityBatch:
+		return "batch"
+	case PriorityAdmin:
+		return "admin"
+	default:
+		return "interactive"
+	}
+}
+
+// ParsePriority parses the name of a priority class.
+func ParsePriority(s string) (Priority, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "interactive":
+		return PriorityInteractive, nil
+	case "batch":
+		return PriorityBatch, nil
+	case "admin":
+		return PriorityAdmin, nil
+	}
+	return PriorityInteractive, errors.Errorf("invalid priority class: %q. Valid classes are "+
+		"interactive, batch and admin", s)
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context carrying the given priority class. Unlike the class asked for
+// by the client, it is trusted, so this is how the server grants the admin class to a request.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// AttachPriority adds the priority class from the incoming HTTP header into the grpc context
+// metadata.
+func AttachPriority(ctx context.Context, r *http.Request) context.Context {
+	if priority := r.Header.Get(PriorityHeader); priority != "" {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			md = metadata.New(nil)
+		} else {
+			// The metadata may be shared with other contexts, so it must not be modified.
+			md = md.Copy()
+		}
+
+		md.Append("priority", priority)
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	return ctx
+}
+
+// RequestedPriority returns the priority class asked for by the client in the context
+// metadata. Requests without a valid priority class are interactive.
+func RequestedPriority(ctx context.Context) Priority {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return PriorityInteractive
+	}
+	vals := md.Get("priority")
+	if len(vals) == 0 {
+		return PriorityInteractive
+	}
+	p, err := ParsePriority(vals[0])
+	if err != nil {
+		glog.V(2).Infof("Treating request as interactive: %v", err)
+	}
+	return p
+}
+
+// PriorityFromContext returns the priority class of the request. It is the one set by
+// WithPriority if any, else the one asked for by the client. Clients can't grant themselves the
+// admin class though: asking for it without the server granting it gets the interactive class.
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	if p := RequestedPriority(ctx); p != PriorityAdmin {
+		return p
+	}
+	return PriorityInteractive
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestPriorityFromContext(t *testing.T) {
+	require.Equal(t, PriorityInteractive, PriorityFromContext(context.Background()))
+
+	r, err := http.NewRequest(http.MethodPost, "/query", nil)
+	require.NoError(t, err)
+	r.Header.Set(PriorityHeader, "Batch")
+	require.Equal(t, PriorityBatch, PriorityFromContext(AttachPriority(context.Background(), r)))
+
+	ctx := WithPriority(AttachPriority(context.Background(), r), PriorityAdmin)
+	require.Equal(t, PriorityAdmin, PriorityFromContext(ctx))
+
+	// Clients can ask for the admin class, but don't get it unless the server grants it.
+	r.Header.Set(PriorityHeader, "admin")
+	ctx = AttachPriority(context.Background(), r)
+	require.Equal(t, PriorityAdmin, RequestedPriority(ctx))
+	require.Equal(t, PriorityInteractive, PriorityFromContext(ctx))
+
+	// The metadata of the parent context is left as it is.
+	parent := metadata.NewIncomingContext(context.Background(), metadata.Pairs("a", "b"))
+	_ = AttachPriority(parent, r)
+	md, _ := metadata.FromIncomingContext(parent)
+	require.Empty(t, md.Get("priority"))
+
+	r.Header.Set(PriorityHeader, "urgent")
+	require.Equal(t, PriorityInteractive,
+		PriorityFromContext(AttachPriority(context.Background(), r)))
+}
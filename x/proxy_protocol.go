@@ -0,0 +1,233 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ProxyProtocolDefaults are the defaults of the --proxy_protocol flag.
+	ProxyProtocolDefaults = `trusted=; timeout=5s;`
+
+	// proxyV1MaxLen is the max length of a PROXY protocol v1 header, including the CRLF.
+	proxyV1MaxLen = 107
+)
+
+// proxyV2Signature starts the PROXY protocol v2 headers.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ParseProxyProtocol parses the --proxy_protocol flag. It returns the IP ranges of the trusted
+// load balancers, which are empty if the PROXY protocol is disabled, and the timeout to read the
+// header.
+func ParseProxyProtocol(sf *z.SuperFlag) ([]IPRange, time.Duration, error) {
+	trusted, err := GetIPsFromString(sf.GetString("trusted"))
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "invalid trusted")
+	}
+	timeout, err := time.ParseDuration(sf.GetString("timeout"))
+	if err != nil || timeout < 0 {
+		return nil, 0, errors.Errorf("invalid timeout %q", sf.GetString("timeout"))
+	}
+	return trusted, timeout, nil
+}
+
+// NewProxyProtocolListener returns a listener which reads the PROXY protocol v1 or v2 header sent
+// by the load balancers in front of the server, so that the remote address of the connections
+// is the address of the client instead of the load balancer's. The header is only read from the
+// connections from the trusted IP ranges, where it's required, and the other connections are
+// served as they are. timeout limits the time to read the header.
+func NewProxyProtocolListener(l net.Listener, trusted []IPRange,
+	timeout time.Duration) net.Listener {
+	return &proxyListener{Listener: l, trusted: trusted, timeout: timeout}
+}
+
+type proxyListener struct {
+	net.Listener
+	trusted []IPRange
+	timeout time.Duration
+}
+
+// Accept returns the next connection. The PROXY protocol header is read on the first Read or
+// RemoteAddr of the connection, so that a slow load balancer doesn't hold up the others.
+func (l *proxyListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	addr, ok := c.RemoteAddr().(*net.TCPAddr)
+	if !ok || !ipInRanges(addr.IP, l.trusted) {
+		return c, nil
+	}
+	return &proxyConn{Conn: c, timeout: l.timeout}, nil
+}
+
+// proxyConn is a connection from a load balancer, which starts with a PROXY protocol header.
+type proxyConn struct {
+	net.Conn
+	timeout time.Duration
+
+	once   sync.Once
+	r      *bufio.Reader
+	remote net.Addr
+	err    error
+}
+
+func (c *proxyConn) readHeader() {
+	c.once.Do(func() {
+		c.r = bufio.NewReader(c.Conn)
+		if c.timeout > 0 {
+			if c.err = c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); c.err != nil {
+				return
+			}
+		}
+		c.remote, c.err = readProxyHeader(c.r)
+		if c.err != nil {
+			glog.Warningf("Invalid PROXY protocol header from %s: %v", c.Conn.RemoteAddr(), c.err)
+			return
+		}
+		if c.timeout > 0 {
+			c.err = c.Conn.SetReadDeadline(time.Time{})
+		}
+	})
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr returns the address of the client, or the address of the load balancer if the
+// header doesn't have one, e.g. in the health checks of the load balancer.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.readHeader()
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// readProxyHeader reads the PROXY protocol v1 or v2 header from r and returns the source address
+// in it. The address is nil if the header doesn't have one.
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	sig, err := r.Peek(len(proxyV2Signature))
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading the header")
+	}
+	switch {
+	case bytes.Equal(sig, proxyV2Signature):
+		return readProxyHeaderV2(r)
+	case bytes.HasPrefix(sig, []byte("PROXY ")):
+		return readProxyHeaderV1(r)
+	default:
+		return nil, errors.New("missing header")
+	}
+}
+
+// readProxyHeaderV1 reads the human-readable header, e.g.
+// "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n".
+func readProxyHeaderV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, errors.Wrapf(err, "while reading the header")
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		if len(line) >= proxyV1MaxLen {
+			return nil, errors.New("v1 header is too long")
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("v1 header doesn't end with CRLF")
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errors.Errorf("invalid v1 header %q", line)
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil || (fields[1] == "TCP4") != (ip.To4() != nil) {
+		return nil, errors.Errorf("invalid source address in v1 header %q", line)
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyHeaderV2 reads the binary header.
+func readProxyHeaderV2(r *bufio.Reader) (net.Addr, error) {
+	var hdr [16]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, errors.Wrapf(err, "while reading the header")
+	}
+	verCmd, family := hdr[12], hdr[13]
+	payload := make([]byte, binary.BigEndian.Uint16(hdr[14:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, errors.Wrapf(err, "while reading the header")
+	}
+	if verCmd>>4 != 2 {
+		return nil, errors.Errorf("invalid v2 header version %d", verCmd>>4)
+	}
+	switch verCmd & 0xf {
+	case 0:
+		// LOCAL, the connection was made by the load balancer itself.
+		return nil, nil
+	case 1:
+		// PROXY
+	default:
+		return nil, errors.Errorf("invalid v2 header command %d", verCmd&0xf)
+	}
+	switch family >> 4 {
+	case 1:
+		// AF_INET: the source and destination addresses, then the ports.
+		if len(payload) < 12 {
+			return nil, errors.New("v2 header is too short for IPv4 addresses")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]).To16(),
+			Port: int(binary.BigEndian.Uint16(payload[8:]))}, nil
+	case 2:
+		// AF_INET6
+		if len(payload) < 36 {
+			return nil, errors.New("v2 header is too short for IPv6 addresses")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]),
+			Port: int(binary.BigEndian.Uint16(payload[32:]))}, nil
+	default:
+		// AF_UNSPEC or AF_UNIX, which have no IP address.
+		return nil, nil
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2021 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package x
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func proxyHeaderV2(cmd, family byte, addrs []byte) []byte {
+	var b bytes.Buffer
+	b.Write(proxyV2Signature)
+	b.WriteByte(0x20 | cmd)
+	b.WriteByte(family)
+	var l [2]byte
+	binary.BigEndian.PutUint16(l[:], uint16(len(addrs)))
+	b.Write(l[:])
+	b.Write(addrs)
+	return b.Bytes()
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	v4 := []byte{10, 1, 2, 3, 10, 0, 0, 1, 0x1f, 0x90, 0x23, 0x78}
+	v6 := append(append(net.ParseIP("2001:db8::1").To16(), net.ParseIP("fd00::1").To16()...),
+		0x1f, 0x90, 0x23, 0x78)
+	for _, tc := range []struct {
+		header string
+		addr   string
+	}{
+		{"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n", "192.168.0.1:56324"},
+		{"PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\n", "[2001:db8::1]:56324"},
+		{"PROXY UNKNOWN\r\n", ""},
+		{"PROXY UNKNOWN ffff:f...f:ffff ffff:f...f:ffff 65535 65535\r\n", ""},
+		{string(proxyHeaderV2(1, 0x11, v4)), "10.1.2.3:8080"},
+		{string(proxyHeaderV2(1, 0x21, v6)), "[2001:db8::1]:8080"},
+		// TLVs after the addresses are skipped.
+		{string(proxyHeaderV2(1, 0x11, append(v4, 0x04, 0, 1, 'x'))), "10.1.2.3:8080"},
+		{string(proxyHeaderV2(0, 0x00, nil)), ""},
+		{string(proxyHeaderV2(1, 0x00, nil)), ""},
+	} {
+		r := bufio.NewReader(bytes.NewBufferString(tc.header + "GET / HTTP/1.1\r\n"))
+		addr, err := readProxyHeader(r)
+		require.NoError(t, err, "%q", tc.header)
+		if tc.addr == "" {
+			require.Nil(t, addr, "%q", tc.header)
+		} else {
+			require.Equal(t, tc.addr, addr.String(), "%q", tc.header)
+		}
+		rest, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, "GET / HTTP/1.1\r\n", string(rest))
+	}
+
+	for _, header := range []string{
+		"GET / HTTP/1.1\r\nHost: localhost\r\n",
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324\r\n",
+		"PROXY TCP4 2001:db8::1 192.168.0.11 56324 443\r\n",
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\n",
+		"PROXY TCP4 192.168.0.1 192.168.0.11 99999 443\r\n",
+		"PROXY TCP4 " + string(bytes.Repeat([]byte("1"), 120)) + "\r\n",
+		string(proxyHeaderV2(1, 0x11, v4[:8])),
+		string(proxyHeaderV2(2, 0x11, v4)),
+		string(proxyHeaderV2(1, 0x11, v4))[:20],
+	} {
+		_, err := readProxyHeader(bufio.NewReader(bytes.NewBufferString(header)))
+		require.Error(t, err, "%q", header)
+	}
+}
+
+func TestProxyProtocolListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	trusted, err := GetIPsFromString("127.0.0.1")
+	require.NoError(t, err)
+
+	serve := func(l net.Listener, send string) (string, string, error) {
+		c, err := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, err)
+		defer c.Close()
+		_, err = c.Write([]byte(send))
+		require.NoError(t, err)
+
+		sc, err := l.Accept()
+		require.NoError(t, err)
+		defer sc.Close()
+		b := make([]byte, 5)
+		_, err = sc.Read(b)
+		return sc.RemoteAddr().String(), string(b), err
+	}
+
+	l := NewProxyProtocolListener(ln, trusted, time.Second)
+	addr, data, err := serve(l, "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\nhello")
+	require.NoError(t, err)
+	require.Equal(t, "192.168.0.1:56324", addr)
+	require.Equal(t, "hello", data)
+
+	// The header is required from the trusted addresses.
+	_, _, err = serve(l, "hello, without a header")
+	require.Error(t, err)
+
+	// The connections from the other addresses are served as they are.
+	other, err := GetIPsFromString("10.0.0.0/8")
+	require.NoError(t, err)
+	l = NewProxyProtocolListener(ln, other, time.Second)
+	addr, data, err = serve(l, "hello")
+	require.NoError(t, err)
+	require.Contains(t, addr, "127.0.0.1:")
+	require.Equal(t, "hello", data)
+	require.NoError(t, ln.Close())
+}
@@ -130,10 +130,9 @@ func SlashTLSConfig(endpoint string) (*tls.Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	hostWithoutPort := strings.Split(endpoint, ":")[0]
 	return &tls.Config{
 		RootCAs:    pool,
-		ServerName: hostWithoutPort,
+		ServerName: HostFromAddress(endpoint),
 	}, nil
 }
 
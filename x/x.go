@@ -22,6 +22,7 @@ import (
 	builtinGzip "compress/gzip"
 	"context"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -40,14 +41,12 @@ import (
 
 	"google.golang.org/grpc/peer"
 
-	"github.com/dgraph-io/badger/v3"
 	bo "github.com/dgraph-io/badger/v3/options"
 	"github.com/dgraph-io/badger/v3/pb"
 	badgerpb "github.com/dgraph-io/badger/v3/pb"
 	"github.com/dgraph-io/dgo/v200"
 	"github.com/dgraph-io/dgo/v200/protos/api"
 	"github.com/dgraph-io/ristretto/z"
-	"github.com/dustin/go-humanize"
 
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
@@ -56,6 +55,7 @@ import (
 	"go.opencensus.io/trace"
 	"golang.org/x/crypto/ssh/terminal"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/metadata"
@@ -74,6 +74,68 @@ var (
 	ErrConflict = errors.New("Transaction conflict")
 )
 
+// archivedPredicateMsg starts the message of an ArchivedPredicateError.
+const archivedPredicateMsg = "Predicate is archived"
+
+// ArchivedPredicateError is returned when reading a predicate which was archived to object
+// storage. The Alphas only pass its message to each other, so use IsArchivedPredicate to
+// recognize it.
+type ArchivedPredicateError struct {
+	Attr string
+}
+
+func (e *ArchivedPredicateError) Error() string {
+	return fmt.Sprintf("%s: %s. Attach it before using it.", archivedPredicateMsg,
+		ParseAttr(e.Attr))
+}
+
+// GRPCStatus returns the gRPC status for the error.
+func (e *ArchivedPredicateError) GRPCStatus() *status.Status {
+	return status.New(codes.FailedPrecondition, e.Error())
+}
+
+// IsArchivedPredicate returns whether err is, or is caused by, an ArchivedPredicateError,
+// possibly one returned by another Alpha.
+func IsArchivedPredicate(err error) bool {
+	if err == nil {
+		return false
+	}
+	var ae *ArchivedPredicateError
+	return errors.As(err, &ae) || strings.Contains(err.Error(), archivedPredicateMsg)
+}
+
+// corruptedPostingMsg starts the message of a CorruptedPostingError.
+const corruptedPostingMsg = "Posting list is corrupted"
+
+// CorruptedPostingError is returned when reading a posting list which can't be unmarshalled. The
+// key is quarantined on the Alpha which failed to read it, until it's purged via the admin API.
+type CorruptedPostingError struct {
+	Key []byte
+}
+
+func (e *CorruptedPostingError) Error() string {
+	msg := fmt.Sprintf("%s: key %s is quarantined", corruptedPostingMsg, hex.EncodeToString(e.Key))
+	if pk, err := Parse(e.Key); err == nil {
+		msg += fmt.Sprintf(" (predicate %s)", ParseAttr(pk.Attr))
+	}
+	return msg
+}
+
+// GRPCStatus returns the gRPC status for the error.
+func (e *CorruptedPostingError) GRPCStatus() *status.Status {
+	return status.New(codes.DataLoss, e.Error())
+}
+
+// IsCorruptedPosting returns whether err is, or is caused by, a CorruptedPostingError, possibly
+// one returned by another Alpha.
+func IsCorruptedPosting(err error) bool {
+	if err == nil {
+		return false
+	}
+	var ce *CorruptedPostingError
+	return errors.As(err, &ce) || strings.Contains(err.Error(), corruptedPostingMsg)
+}
+
 const (
 	// Success is equivalent to the HTTP 200 error code.
 	Success = "Success"
@@ -93,6 +155,17 @@ const (
 	// Star is equivalent to using * in a mutation.
 	// When changing this value also remember to change in in client/client.go:DeleteEdges.
 	Star = "_STAR_ALL"
+	// GraphFacet is the facet storing the graph label of an edge, set by the fourth element of
+	// an N-Quad when it isn't a namespace.
+	GraphFacet = "dgraph.graph"
+	// LineageFacet is the facet storing the uid of the lineage node of an edge, which holds the
+	// provenance of the mutation which set the edge.
+	LineageFacet = "dgraph.lineage"
+	// LineageBlob is the predicate of a lineage node holding the provenance, a JSON object.
+	LineageBlob = "dgraph.lineage.blob"
+	// LineageSubject is the predicate linking a lineage node to the subjects whose edges point
+	// to it, so that the lineage node can be deleted along with its last subject.
+	LineageSubject = "dgraph.lineage.subject"
 
 	// GrpcMaxSize is the maximum possible size for a gRPC message.
 	// Dgraph uses the maximum size for the most flexibility (2GB - equal
@@ -131,7 +204,7 @@ const (
 	// DefaultCreds is the default credentials for login via dgo client.
 	DefaultCreds = "user=; password=; namespace=0;"
 
-	AccessControlAllowedHeaders = "X-Dgraph-AccessToken, X-Dgraph-AuthToken, " +
+	AccessControlAllowedHeaders = "X-Dgraph-AccessToken, X-Dgraph-AuthToken, X-Dgraph-Priority, " +
 		"Content-Type, Content-Length, Accept-Encoding, Cache-Control, " +
 		"X-CSRF-Token, X-Auth-Token, X-Requested-With"
 	DgraphCostHeader = "Dgraph-TouchedUids"
@@ -501,8 +574,13 @@ func AttachAccessJwt(ctx context.Context, r *http.Request) context.Context {
 	return ctx
 }
 
-// AttachRemoteIP adds any incoming IP data into the grpc context metadata
+// AttachRemoteIP adds any incoming IP data into the grpc context metadata. The requests received
+// on a Unix domain socket get the address of the socket instead.
 func AttachRemoteIP(ctx context.Context, r *http.Request) context.Context {
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok &&
+		addr.Network() == "unix" {
+		return peer.NewContext(ctx, &peer.Peer{Addr: addr})
+	}
 	if ip, port, err := net.SplitHostPort(r.RemoteAddr); err == nil {
 		if intPort, convErr := strconv.Atoi(port); convErr == nil {
 			ctx = peer.NewContext(ctx, &peer.Peer{
@@ -516,20 +594,99 @@ func AttachRemoteIP(ctx context.Context, r *http.Request) context.Context {
 	return ctx
 }
 
-// isIpWhitelisted checks if the given ipString is within the whitelisted ip range
-func isIpWhitelisted(ipString string) bool {
-	ip := net.ParseIP(ipString)
+// GetIPsFromString parses a comma-delimited list of IP addresses, IP ranges, CIDR blocks, or hostnames
+// and returns a slice of []IPRange.
+//
+// e.g. "144.142.126.222:144.142.126.244,144.142.126.254,192.168.0.0/16,host.docker.internal"
+func GetIPsFromString(str string) ([]IPRange, error) {
+	if str == "" {
+		return []IPRange{}, nil
+	}
+
+	var ipRanges []IPRange
+	rangeStrings := strings.Split(str, ",")
+
+	for _, s := range rangeStrings {
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, "[") {
+			// bracketed IPv6 address like [fd03:b188:0f3c:9ec4::babe:face] or [fd03::]/64
+			s = strings.Replace(strings.TrimPrefix(s, "["), "]", "", 1)
+		}
+		isIPv6 := strings.Count(s, ":") > 1
+		tuple := strings.Split(s, ":")
+		switch {
+		case isIPv6 || len(tuple) == 1:
+			if !strings.Contains(s, "/") {
+				// string is hostname like host.docker.internal,
+				// or IPv4 address like 144.124.126.254,
+				// or IPv6 address like fd03:b188:0f3c:9ec4::babe:face
+				ipAddr := net.ParseIP(s)
+				if ipAddr != nil {
+					ipRanges = append(ipRanges, IPRange{Lower: ipAddr, Upper: ipAddr})
+				} else {
+					ipAddrs, err := net.LookupIP(s)
+					if err != nil {
+						return nil, errors.Errorf("invalid IP address or hostname: %s", s)
+					}
+
+					for _, addr := range ipAddrs {
+						ipRanges = append(ipRanges, IPRange{Lower: addr, Upper: addr})
+					}
+				}
+			} else {
+				// string is CIDR block like 192.168.0.0/16 or fd03:b188:0f3c:9ec4::/64
+				rangeLo, network, err := net.ParseCIDR(s)
+				if err != nil {
+					return nil, errors.Errorf("invalid CIDR block: %s", s)
+				}
+
+				addrLen, maskLen := len(rangeLo), len(network.Mask)
+				rangeHi := make(net.IP, len(rangeLo))
+				copy(rangeHi, rangeLo)
+				for i := 1; i <= maskLen; i++ {
+					rangeHi[addrLen-i] |= ^network.Mask[maskLen-i]
+				}
+
+				ipRanges = append(ipRanges, IPRange{Lower: rangeLo, Upper: rangeHi})
+			}
+		case len(tuple) == 2:
+			// string is range like a.b.c.d:w.x.y.z
+			rangeLo := net.ParseIP(tuple[0])
+			rangeHi := net.ParseIP(tuple[1])
+			switch {
+			case rangeLo == nil:
+				return nil, errors.Errorf("invalid IP address: %s", tuple[0])
+			case rangeHi == nil:
+				return nil, errors.Errorf("invalid IP address: %s", tuple[1])
+			case bytes.Compare(rangeLo, rangeHi) > 0:
+				return nil, errors.Errorf("inverted IP address range: %s", s)
+			}
+			ipRanges = append(ipRanges, IPRange{Lower: rangeLo, Upper: rangeHi})
+		default:
+			return nil, errors.Errorf("invalid IP address range: %s", s)
+		}
+	}
+
+	return ipRanges, nil
+}
+
+// isIpWhitelisted checks if the given ipString is loopback or within one of the ip ranges
+func isIpWhitelisted(ipString string, ipRanges []IPRange) bool {
+	ip := parseIPWithZone(ipString)
 
 	if ip == nil {
 		return false
 	}
 
-	if ip.IsLoopback() {
-		return true
-	}
+	return ip.IsLoopback() || ipInRanges(ip, ipRanges)
+}
 
-	for _, ipRange := range WorkerConfig.WhiteListedIPRanges {
-		if bytes.Compare(ip, ipRange.Lower) >= 0 && bytes.Compare(ip, ipRange.Upper) <= 0 {
+// ipInRanges checks if the ip is within one of the ip ranges
+func ipInRanges(ip net.IP, ipRanges []IPRange) bool {
+	ip = ip.To16()
+	for _, ipRange := range ipRanges {
+		if bytes.Compare(ip, ipRange.Lower.To16()) >= 0 &&
+			bytes.Compare(ip, ipRange.Upper.To16()) <= 0 {
 			return true
 		}
 	}
@@ -537,17 +694,27 @@ func isIpWhitelisted(ipString string) bool {
 }
 
 // HasWhitelistedIP checks whether the source IP in ctx is whitelisted or not.
-// It returns the IP address if the IP is whitelisted, otherwise an error is returned.
+// It returns the IP address if the IP is whitelisted, otherwise an error is returned. The
+// requests received on a Unix domain socket are local, so they're always whitelisted.
 func HasWhitelistedIP(ctx context.Context) (net.Addr, error) {
+	return HasWhitelistedIPIn(ctx, WorkerConfig.WhiteListedIPRanges)
+}
+
+// HasWhitelistedIPIn is like HasWhitelistedIP, but checks the source IP against ipRanges instead
+// of the whitelist in WorkerConfig.
+func HasWhitelistedIPIn(ctx context.Context, ipRanges []IPRange) (net.Addr, error) {
 	peerInfo, ok := peer.FromContext(ctx)
 	if !ok {
 		return nil, errors.New("unable to find source ip")
 	}
+	if peerInfo.Addr.Network() == "unix" {
+		return peerInfo.Addr, nil
+	}
 	ip, _, err := net.SplitHostPort(peerInfo.Addr.String())
 	if err != nil {
 		return nil, err
 	}
-	if !isIpWhitelisted(ip) {
+	if !isIpWhitelisted(ip, ipRanges) {
 		return nil, errors.Errorf("unauthorized ip address: %s", ip)
 	}
 	return peerInfo.Addr, nil
@@ -699,16 +866,21 @@ func PageRange(count, offset, n int) (int, int) {
 	return start, end
 }
 
-// ValidateAddress checks whether given address can be used with grpc dial function
+// ValidateAddress checks whether given address can be used with grpc dial function. IPv6
+// addresses must be in brackets, e.g. [fd00::1]:7080.
 func ValidateAddress(addr string) error {
 	host, port, err := net.SplitHostPort(addr)
 	if err != nil {
+		if strings.Count(addr, ":") > 1 && !strings.HasPrefix(addr, "[") {
+			return errors.Errorf("Invalid address %q: IPv6 addresses must be in brackets, "+
+				"e.g. [::1]:7080", addr)
+		}
 		return err
 	}
 	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p >= 65536 {
 		return errors.Errorf("Invalid port: %v", p)
 	}
-	if ip := net.ParseIP(host); ip != nil {
+	if ip := parseIPWithZone(host); ip != nil {
 		return nil
 	}
 	// try to parse as hostname as per hostname RFC
@@ -721,6 +893,45 @@ func ValidateAddress(addr string) error {
 	return nil
 }
 
+// parseIPWithZone parses an IP address, which may have an IPv6 zone, e.g. fe80::1%eth0. It
+// returns nil if host isn't an IP address.
+func parseIPWithZone(host string) net.IP {
+	if i := strings.LastIndex(host, "%"); i > 0 && strings.Contains(host, ":") {
+		host = host[:i]
+	}
+	return net.ParseIP(host)
+}
+
+// NormalizeAddress returns the canonical form of the host:port address, so that addresses can be
+// compared as strings. IP addresses are written in their shortest form, e.g. [fd00::1]:7080 for
+// [FD00:0:0:0:0:0:0:1]:7080, and IPv4-mapped IPv6 addresses are written as IPv4. The address is
+// returned unchanged if it isn't a host:port address.
+func NormalizeAddress(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	zone := ""
+	if i := strings.LastIndex(host, "%"); i > 0 && strings.Contains(host, ":") {
+		host, zone = host[:i], host[i:]
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	} else {
+		host = strings.ToLower(host)
+	}
+	return net.JoinHostPort(host+zone, port)
+}
+
+// HostFromAddress returns the host of the host:port address, without the brackets of IPv6
+// addresses. The address is returned unchanged if it has no port.
+func HostFromAddress(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
 // RemoveDuplicates sorts the slice of strings and removes duplicates. changes the input slice.
 // This function should be called like: someSlice = RemoveDuplicates(someSlice)
 func RemoveDuplicates(s []string) (out []string) {
@@ -1103,46 +1314,6 @@ func IsGuardian(groups []string) bool {
 	return false
 }
 
-// RunVlogGC runs value log gc on store. It runs GC unconditionally after every 10 minutes.
-// Additionally it also runs GC if vLogSize has grown more than 1 GB in last minute.
-func RunVlogGC(store *badger.DB, closer *z.Closer) {
-	defer closer.Done()
-
-	// Runs every 1m, checks size of vlog and runs GC conditionally.
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-
-	abs := func(a, b int64) int64 {
-		if a > b {
-			return a - b
-		}
-		return b - a
-	}
-
-	var lastSz int64
-	runGC := func() {
-		for err := error(nil); err == nil; {
-			// If a GC is successful, immediately run it again.
-			err = store.RunValueLogGC(0.7)
-		}
-		_, sz := store.Size()
-		if abs(lastSz, sz) > 512<<20 {
-			glog.V(2).Infof("Value log size: %s\n", humanize.IBytes(uint64(sz)))
-			lastSz = sz
-		}
-	}
-
-	runGC()
-	for {
-		select {
-		case <-closer.HasBeenClosed():
-			return
-		case <-ticker.C:
-			runGC()
-		}
-	}
-}
-
 type DB interface {
 	Sync() error
 }
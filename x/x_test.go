@@ -17,11 +17,18 @@
 package x
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestSensitiveByteSlice(t *testing.T) {
@@ -99,6 +106,10 @@ func TestValidateAddress(t *testing.T) {
 			{"Invalid without port", "[2001:db8]", "address [2001:db8]: missing port in address"},
 			{"Invalid with port", "[2001:db8]:2222", "Invalid hostname: 2001:db8"},
 			{"Invalid port", "[2001:db8::1]:222222", "Invalid port: 222222"},
+			{"Valid with zone", "[fe80::1%eth0]:7080", ""},
+			{"Valid IPv4-mapped", "[::ffff:10.0.0.1]:7080", ""},
+			{"Invalid without brackets", "2001:db8::1:7080", "Invalid address " +
+				"\"2001:db8::1:7080\": IPv6 addresses must be in brackets, e.g. [::1]:7080"},
 		}
 		for _, st := range testData {
 			t.Run(st.name, func(t *testing.T) {
@@ -138,6 +149,74 @@ func TestValidateAddress(t *testing.T) {
 	})
 }
 
+func TestNormalizeAddress(t *testing.T) {
+	for addr, want := range map[string]string{
+		"localhost:7080":                 "localhost:7080",
+		"Alpha1.Example.com:7080":        "alpha1.example.com:7080",
+		"10.0.0.1:7080":                  "10.0.0.1:7080",
+		"[::ffff:10.0.0.1]:7080":         "10.0.0.1:7080",
+		"[2001:DB8:0:0:0:0:0:1]:7080":    "[2001:db8::1]:7080",
+		"[0:0:0:0:0:0:0:1]:5080":         "[::1]:5080",
+		"[fe80:0:0:0:0:0:0:1%eth0]:7080": "[fe80::1%eth0]:7080",
+		"no-port":                        "no-port",
+		"":                               "",
+	} {
+		require.Equal(t, want, NormalizeAddress(addr), addr)
+	}
+}
+
+func TestHostFromAddress(t *testing.T) {
+	for addr, want := range map[string]string{
+		"alpha1:9080":       "alpha1",
+		"10.0.0.1:9080":     "10.0.0.1",
+		"[2001:db8::1]:443": "2001:db8::1",
+		"[2001:db8::1]":     "2001:db8::1",
+		"alpha1":            "alpha1",
+	} {
+		require.Equal(t, want, HostFromAddress(addr), addr)
+	}
+}
+
+func TestGetIPsFromStringIPv6(t *testing.T) {
+	ranges, err := GetIPsFromString(
+		"2001:db8:0:0:0:0:0:1,[fd03::babe:face],fd00::/64, 10.0.0.1:10.0.0.9")
+	require.NoError(t, err)
+	require.Len(t, ranges, 4)
+	require.True(t, ranges[0].Lower.Equal(net.ParseIP("2001:db8::1")))
+	require.True(t, ranges[1].Lower.Equal(net.ParseIP("fd03::babe:face")))
+	require.True(t, ranges[2].Lower.Equal(net.ParseIP("fd00::")))
+	require.True(t, ranges[2].Upper.Equal(net.ParseIP("fd00::ffff:ffff:ffff:ffff")))
+
+	for ip, ok := range map[string]bool{
+		"::1":             true,
+		"2001:db8::1":     true,
+		"fd00::42":        true,
+		"fd00::42%eth0":   true,
+		"fd01::1":         false,
+		"::ffff:10.0.0.5": true,
+		"10.0.0.5":        true,
+		"10.0.0.10":       false,
+		"fe80::1%eth0":    false,
+	} {
+		require.Equal(t, ok, isIpWhitelisted(ip, ranges), ip)
+	}
+}
+
+func TestUnixSocketWhitelisted(t *testing.T) {
+	sock := &net.UnixAddr{Name: "/tmp/alpha.sock", Net: "unix"}
+	r := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	r.RemoteAddr = "@"
+	r = r.WithContext(context.WithValue(r.Context(), http.LocalAddrContextKey, sock))
+	addr, err := HasWhitelistedIPIn(AttachRemoteIP(context.Background(), r), nil)
+	require.NoError(t, err)
+	require.Equal(t, sock, addr)
+
+	r = httptest.NewRequest(http.MethodGet, "/admin", nil)
+	r.RemoteAddr = "192.168.1.1:4567"
+	_, err = HasWhitelistedIPIn(AttachRemoteIP(context.Background(), r), nil)
+	require.Error(t, err)
+}
+
 func TestGqlError(t *testing.T) {
 	tests := map[string]struct {
 		err error
@@ -169,6 +248,21 @@ func TestGqlError(t *testing.T) {
 	}
 }
 
+func TestArchivedPredicateError(t *testing.T) {
+	var err error = &ArchivedPredicateError{Attr: GalaxyAttr("events")}
+	require.Equal(t, "Predicate is archived: events. Attach it before using it.", err.Error())
+	require.True(t, IsArchivedPredicate(err))
+	require.True(t, IsArchivedPredicate(errors.Wrapf(err, "while reading")))
+
+	// The error returned by another Alpha only keeps the message.
+	st, _ := status.FromError(err)
+	require.Equal(t, codes.FailedPrecondition, st.Code())
+	require.True(t, IsArchivedPredicate(st.Err()))
+
+	require.False(t, IsArchivedPredicate(nil))
+	require.False(t, IsArchivedPredicate(ErrConflict))
+}
+
 func TestVersionString(t *testing.T) {
 	dgraphVersion = "v1.2.2-rc1-g1234567"
 	require.True(t, DevVersion())
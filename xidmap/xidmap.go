@@ -20,11 +20,14 @@ import (
 	"context"
 	"encoding/binary"
 	"math/rand"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/dgraph-io/badger/v3"
 	"github.com/dgraph-io/dgraph/protos/pb"
@@ -42,6 +45,10 @@ type XidMap struct {
 	newRanges  chan *pb.AssignedIds
 	zc         pb.ZeroClient
 	maxUidSeen uint64
+	// noBatch is set once Zero turns out not to support AssignIdsBatch.
+	noBatch int32
+	// registry is set to get the UIDs of xids from the xid registry of Zero, see UseRegistry.
+	registry bool
 
 	// Optionally, these can be set to persist the mappings.
 	writer *badger.WriteBatch
@@ -56,6 +63,9 @@ type shard struct {
 	block
 
 	tree *z.Tree
+	// fetched are the UIDs prefetched from the xid registry of Zero, by fingerprint of their xid,
+	// until AssignUid assigns them.
+	fetched map[uint64]uint64
 }
 
 type block struct {
@@ -132,30 +142,157 @@ func New(zero *grpc.ClientConn, db *badger.DB, dir string) *XidMap {
 	}
 	xm.zc = pb.NewZeroClient(zero)
 
-	go func() {
-		const initBackoff = 10 * time.Millisecond
-		const maxBackoff = 5 * time.Second
-		backoff := initBackoff
-		for {
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-			assigned, err := xm.zc.AssignIds(ctx, &pb.Num{Val: 1e5, Type: pb.Num_UID})
-			glog.V(2).Infof("Assigned Uids: %+v. Err: %v", assigned, err)
-			cancel()
-			if err == nil {
-				backoff = initBackoff
-				xm.updateMaxSeen(assigned.EndId)
-				xm.newRanges <- assigned
-				continue
+	for i := 0; i < leasePipeline; i++ {
+		go xm.leaseUids()
+	}
+	return xm
+}
+
+const (
+	// leaseRange is the number of UIDs in each range handed out to the shards.
+	leaseRange = 1e5
+	// leaseBatch is the number of ranges asked for in a single AssignIdsBatch call.
+	leaseBatch = 8
+	// leasePipeline is the number of lease requests kept in flight to Zero, so that loaders
+	// asking for millions of UIDs don't wait on one round trip at a time.
+	leasePipeline = 2
+)
+
+// leaseUids keeps newRanges filled with ranges of UIDs leased from Zero. It asks for a batch of
+// ranges per round trip, falling back to one range at a time on Zeros that don't support batches.
+func (m *XidMap) leaseUids() {
+	const initBackoff = 10 * time.Millisecond
+	const maxBackoff = 5 * time.Second
+	backoff := initBackoff
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		ranges, err := m.assignRanges(ctx)
+		cancel()
+		if err == nil {
+			backoff = initBackoff
+			for _, assigned := range ranges {
+				m.updateMaxSeen(assigned.EndId)
+				m.newRanges <- assigned
 			}
-			glog.Errorf("Error while getting lease: %v\n", err)
-			backoff *= 2
-			if backoff > maxBackoff {
-				backoff = maxBackoff
+			continue
+		}
+		glog.Errorf("Error while getting lease: %v\n", err)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		time.Sleep(backoff)
+	}
+}
+
+func (m *XidMap) assignRanges(ctx context.Context) ([]*pb.AssignedIds, error) {
+	if atomic.LoadInt32(&m.noBatch) == 0 {
+		batch := &pb.NumBatch{Nums: make([]*pb.Num, leaseBatch)}
+		for i := range batch.Nums {
+			batch.Nums[i] = &pb.Num{Val: leaseRange, Type: pb.Num_UID}
+		}
+		assigned, err := m.zc.AssignIdsBatch(ctx, batch)
+		glog.V(2).Infof("Assigned Uids: %+v. Err: %v", assigned, err)
+		if status.Code(err) != codes.Unimplemented {
+			return assigned.GetIds(), err
+		}
+		glog.Infof("Zero doesn't support batched leases. Leasing one range at a time.")
+		atomic.StoreInt32(&m.noBatch, 1)
+	}
+	assigned, err := m.zc.AssignIds(ctx, &pb.Num{Val: leaseRange, Type: pb.Num_UID})
+	glog.V(2).Infof("Assigned Uids: %+v. Err: %v", assigned, err)
+	if err != nil {
+		return nil, err
+	}
+	return []*pb.AssignedIds{assigned}, nil
+}
+
+// UseRegistry makes the XidMap get the UIDs of the xids from the xid registry of Zero, which
+// requires Zero to run with --xid_registry. This way, every loader gets the same UID for the same
+// xid. Blank nodes still get UIDs of their own. It must be called before any UID is assigned.
+func (m *XidMap) UseRegistry() {
+	m.registry = true
+}
+
+// inRegistry returns whether the UID of the xid, given as returned by x.NamespaceAttr, comes
+// from the xid registry of Zero.
+func (m *XidMap) inRegistry(key string) bool {
+	return m.registry && !strings.HasPrefix(x.ParseAttr(key), "_:")
+}
+
+// Prefetch gets the UIDs of the given xids from the xid registry of Zero, with one request per
+// namespace, if the XidMap uses it. This saves AssignUid a request per xid, so loaders call it for
+// each batch of N-Quads. The xids are given as returned by x.NamespaceAttr.
+func (m *XidMap) Prefetch(keys []string) {
+	byNs := make(map[uint64][]string)
+	for _, key := range keys {
+		if !m.inRegistry(key) || m.CheckUid(key) {
+			continue
+		}
+		ns, xid := x.ParseNamespaceAttr(key)
+		byNs[ns] = append(byNs[ns], xid)
+	}
+	for ns, xids := range byNs {
+		for xid, uid := range m.registryUids(ns, xids) {
+			key := x.NamespaceAttr(ns, xid)
+			sh := m.shardFor(key)
+			sh.Lock()
+			if sh.fetched == nil {
+				sh.fetched = make(map[uint64]uint64)
 			}
-			time.Sleep(backoff)
+			sh.fetched[farm.Fingerprint64([]byte(key))] = uid
+			sh.Unlock()
 		}
-	}()
-	return xm
+	}
+}
+
+// assignFromRegistry assigns the UID of the xid from the xid registry of Zero, unless it got
+// assigned meanwhile. The UID is the one prefetched for the xid, if any.
+func (m *XidMap) assignFromRegistry(sh *shard, key string) (uint64, bool) {
+	fp := farm.Fingerprint64([]byte(key))
+	sh.Lock()
+	uid, ok := sh.fetched[fp]
+	sh.Unlock()
+	if !ok {
+		ns, xid := x.ParseNamespaceAttr(key)
+		uid = m.registryUids(ns, []string{xid})[xid]
+		x.AssertTruef(uid > 0, "The xid registry returned no UID for xid %q", xid)
+	}
+
+	sh.Lock()
+	defer sh.Unlock()
+	delete(sh.fetched, fp)
+	if cur := sh.tree.Get(fp); cur > 0 {
+		return cur, false
+	}
+	sh.tree.Set(fp, uid)
+	return uid, true
+}
+
+// registryUids asks the xid registry of Zero for the UIDs of the xids of the namespace. It keeps
+// retrying while Zero can't be reached, and exits on any other error.
+func (m *XidMap) registryUids(ns uint64, xids []string) map[string]uint64 {
+	const initBackoff = 10 * time.Millisecond
+	const maxBackoff = 5 * time.Second
+	backoff := initBackoff
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		res, err := m.zc.AssignUidForXid(ctx, &pb.XidRequest{Namespace: ns, Xids: xids})
+		cancel()
+		switch status.Code(err) {
+		case codes.OK:
+			return res.GetUids()
+		case codes.Unavailable, codes.DeadlineExceeded:
+			glog.Errorf("While getting UIDs from the xid registry: %v", err)
+		default:
+			glog.Fatalf("While getting UIDs from the xid registry: %v", err)
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		time.Sleep(backoff)
+	}
 }
 
 func (m *XidMap) shardFor(xid string) *shard {
@@ -189,7 +326,8 @@ func (m *XidMap) dbWriter() {
 }
 
 // AssignUid creates new or looks up existing XID to UID mappings. It also returns if
-// UID was created.
+// UID was created. The UIDs of the xids from the xid registry of Zero aren't persisted in the
+// badger.DB, as the registry already keeps them.
 func (m *XidMap) AssignUid(xid string) (uint64, bool) {
 	sh := m.shardFor(xid)
 	sh.RLock()
@@ -200,6 +338,10 @@ func (m *XidMap) AssignUid(xid string) (uint64, bool) {
 		return uid, false
 	}
 
+	if m.inRegistry(xid) {
+		return m.assignFromRegistry(sh, xid)
+	}
+
 	sh.Lock()
 	defer sh.Unlock()
 
@@ -1,9 +1,11 @@
 package xidmap
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"net"
 	"os"
 	"runtime"
 	"strconv"
@@ -13,10 +15,12 @@ import (
 	"time"
 
 	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/dgraph/protos/pb"
 	"github.com/dgraph-io/dgraph/testutil"
 	"github.com/dgraph-io/dgraph/x"
 	"github.com/dgraph-io/ristretto/z"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 )
 
 // Opens a badger db and runs a a test on it.
@@ -73,6 +77,89 @@ func TestXidmap(t *testing.T) {
 	})
 }
 
+// registryZero is a Zero which only serves an xid registry.
+type registryZero struct {
+	pb.UnimplementedZeroServer
+	sync.Mutex
+	requests int
+	next     uint64
+	uids     map[string]uint64
+}
+
+func (z *registryZero) AssignUidForXid(ctx context.Context,
+	req *pb.XidRequest) (*pb.XidMap, error) {
+	z.Lock()
+	defer z.Unlock()
+	z.requests++
+	out := &pb.XidMap{Uids: make(map[string]uint64)}
+	for _, xid := range req.Xids {
+		key := x.NamespaceAttr(req.Namespace, xid)
+		if z.uids[key] == 0 {
+			z.next++
+			z.uids[key] = z.next
+		}
+		out.Uids[xid] = z.uids[key]
+	}
+	return out, nil
+}
+
+func (z *registryZero) numRequests() int {
+	z.Lock()
+	defer z.Unlock()
+	return z.requests
+}
+
+func TestXidmapRegistry(t *testing.T) {
+	zero := &registryZero{next: 1000, uids: make(map[string]uint64)}
+	srv := grpc.NewServer()
+	pb.RegisterZeroServer(srv, zero)
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	m1 := New(conn, nil, "")
+	m1.UseRegistry()
+	m2 := New(conn, nil, "")
+	m2.UseRegistry()
+
+	// Both maps get the UID of an xid from the registry.
+	uid, isNew := m1.AssignUid(x.NamespaceAttr(0, "alice"))
+	require.True(t, isNew)
+	require.Equal(t, uint64(1001), uid)
+	uid, isNew = m2.AssignUid(x.NamespaceAttr(0, "alice"))
+	require.True(t, isNew)
+	require.Equal(t, uint64(1001), uid)
+	uid, isNew = m1.AssignUid(x.NamespaceAttr(0, "alice"))
+	require.False(t, isNew)
+	require.Equal(t, uint64(1001), uid)
+	require.Equal(t, 2, zero.numRequests())
+
+	// The same xid is another node in another namespace.
+	uid, _ = m1.AssignUid(x.NamespaceAttr(2, "alice"))
+	require.Equal(t, uint64(1002), uid)
+	require.Equal(t, 3, zero.numRequests())
+
+	// The xids of a batch are prefetched with one request per namespace.
+	m1.Prefetch([]string{x.NamespaceAttr(0, "alice"), x.NamespaceAttr(0, "bob"),
+		x.NamespaceAttr(0, "carol"), x.NamespaceAttr(0, "_:blank")})
+	require.Equal(t, 4, zero.numRequests())
+	uid, isNew = m1.AssignUid(x.NamespaceAttr(0, "bob"))
+	require.True(t, isNew)
+	require.Equal(t, uint64(1003), uid)
+	uid, isNew = m1.AssignUid(x.NamespaceAttr(0, "bob"))
+	require.False(t, isNew)
+	require.Equal(t, uint64(1003), uid)
+	require.Equal(t, 4, zero.numRequests())
+
+	// Blank nodes don't go through the registry.
+	require.Len(t, zero.uids, 4)
+}
+
 func TestXidmapMemory(t *testing.T) {
 	var loop uint32
 	bToMb := func(b uint64) uint64 {